@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// openRepository connects to the storage backend selected by MEMEX_BACKEND
+func openRepository(ctx context.Context, backend string) (graph.Repository, error) {
+	switch backend {
+	case "sqlite":
+		sqlitePath := getEnv("SQLITE_PATH", "./memex.db")
+		log.Printf("Using SQLite backend: %s", sqlitePath)
+		repo, err := graph.NewSQLite(ctx, sqlitePath)
+		if err != nil {
+			return nil, fmt.Errorf("opening SQLite database: %w", err)
+		}
+		// Optional write-behind: group small inserts into periodic transactions
+		latency, err := time.ParseDuration(getEnv("MEMEX_WRITE_BEHIND_LATENCY", "0"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid MEMEX_WRITE_BEHIND_LATENCY: %w", err)
+		}
+		if latency > 0 {
+			cfg := graph.WriteBehindConfig{MaxLatency: latency, Async: getEnv("MEMEX_WRITE_BEHIND_ASYNC", "false") == "true"}
+			cfg.MaxBatch, _ = strconv.Atoi(getEnv("MEMEX_WRITE_BEHIND_BATCH", strconv.Itoa(graph.DefaultWriteBehindBatch)))
+			repo.EnableWriteBehind(cfg)
+			if cfg.Async {
+				log.Printf("Write-behind enabled (async, up to %s of writes lost on crash)", latency)
+			} else {
+				log.Printf("Write-behind enabled (writes wait up to %s to commit)", latency)
+			}
+		}
+		return repo, nil
+	case "neo4j":
+		neo4jURI := getEnv("NEO4J_URI", "bolt://localhost:7687")
+		neo4jUser := getEnv("NEO4J_USER", "neo4j")
+		neo4jPassword := getEnv("NEO4J_PASSWORD", "password")
+		batchSize, _ := strconv.Atoi(getEnv("NEO4J_BATCH_SIZE", "1000"))
+		var txTimeout time.Duration
+		if t := getEnv("NEO4J_TX_TIMEOUT", ""); t != "" {
+			var err error
+			if txTimeout, err = time.ParseDuration(t); err != nil {
+				return nil, fmt.Errorf("invalid NEO4J_TX_TIMEOUT: %w", err)
+			}
+		}
+
+		log.Printf("Using Neo4j backend: %s", neo4jURI)
+		repo, err := graph.NewNeo4j(ctx, graph.Config{
+			URI:       neo4jURI,
+			Username:  neo4jUser,
+			Password:  neo4jPassword,
+			Database:  "neo4j",
+			BatchSize: batchSize,
+			TxTimeout: txTimeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("connecting to Neo4j: %w", err)
+		}
+		return repo, nil
+	case "postgres":
+		dsn := getEnv("POSTGRES_DSN", os.Getenv("DATABASE_URL"))
+		if dsn == "" {
+			return nil, fmt.Errorf("POSTGRES_DSN (or DATABASE_URL) is required for the postgres backend")
+		}
+		log.Println("Using Postgres backend")
+		repo, err := graph.NewPostgres(ctx, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to Postgres: %w", err)
+		}
+		return repo, nil
+	case "memory":
+		log.Println("Using in-memory backend (data is discarded on exit)")
+		return graph.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown backend: %s (use 'sqlite', 'neo4j', 'postgres' or 'memory')", backend)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build faults
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/systemshift/memex/internal/server/faults"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// injectFaults wraps the backend with a fault injector, configured by
+// MEMEX_FAULTS (JSON) at startup and through /api/admin/faults after
+func injectFaults(repo graph.Repository) (graph.Repository, *faults.Injector) {
+	in := faults.New()
+	if spec := getEnv("MEMEX_FAULTS", ""); spec != "" {
+		var cfg faults.Config
+		if err := json.Unmarshal([]byte(spec), &cfg); err != nil {
+			log.Fatalf("Invalid MEMEX_FAULTS: %v", err)
+		}
+		if err := in.Configure(cfg); err != nil {
+			log.Fatalf("Invalid MEMEX_FAULTS: %v", err)
+		}
+	}
+	log.Println("Fault injection built in: repository calls may be delayed or fail on purpose")
+	return faults.Wrap(repo, in), in
+}
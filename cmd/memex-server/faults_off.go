@@ -0,0 +1,14 @@
+//go:build !faults
+
+package main
+
+import (
+	"github.com/systemshift/memex/internal/server/faults"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// injectFaults leaves the backend alone; build with -tags faults to
+// inject faults for resilience tests
+func injectFaults(repo graph.Repository) (graph.Repository, *faults.Injector) {
+	return repo, nil
+}
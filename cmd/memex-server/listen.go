@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor systemd passes to a
+// socket-activated service
+const listenFDsStart = 3
+
+// listen opens the server's socket. A socket passed by systemd socket
+// activation is used as is, so connections wait in its backlog while the
+// server restarts. Otherwise the server listens on addr, with SO_REUSEPORT
+// when reusePort is set so a new server can bind the port while the old
+// one drains.
+func listen(addr string, reusePort bool) (net.Listener, error) {
+	ln, err := activatedListener()
+	if ln != nil || err != nil {
+		return ln, err
+	}
+	if reusePort {
+		cfg := net.ListenConfig{Control: setReusePort}
+		return cfg.Listen(context.Background(), "tcp", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// activatedListener returns the socket systemd passed to this process
+// (LISTEN_PID and LISTEN_FDS), or nil when not socket-activated
+func activatedListener() (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("socket activation passed %d sockets, expected 1", n)
+	}
+	// Not inherited by child processes
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(listenFDsStart, "LISTEN_FD_3")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("using activated socket: %w", err)
+	}
+	log.Printf("Using socket %s passed by socket activation", ln.Addr())
+	return ln, nil
+}
@@ -2,58 +2,245 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/systemshift/memex/internal/server/analytics"
+	"github.com/systemshift/memex/internal/server/anomaly"
 	"github.com/systemshift/memex/internal/server/api"
+	"github.com/systemshift/memex/internal/server/apiversion"
+	"github.com/systemshift/memex/internal/server/autocomplete"
+	"github.com/systemshift/memex/internal/server/citations"
+	"github.com/systemshift/memex/internal/server/conflicts"
+	"github.com/systemshift/memex/internal/server/connectors"
+	"github.com/systemshift/memex/internal/server/dedup"
+	"github.com/systemshift/memex/internal/server/devices"
+	"github.com/systemshift/memex/internal/server/embeddings"
+	"github.com/systemshift/memex/internal/server/experiments"
+	"github.com/systemshift/memex/internal/server/export"
+	"github.com/systemshift/memex/internal/server/federation"
+	"github.com/systemshift/memex/internal/server/feedback"
+	"github.com/systemshift/memex/internal/server/finalizers"
+	"github.com/systemshift/memex/internal/server/github"
 	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/growth"
+	"github.com/systemshift/memex/internal/server/health"
+	"github.com/systemshift/memex/internal/server/idempotency"
+	"github.com/systemshift/memex/internal/server/infer"
+	"github.com/systemshift/memex/internal/server/ingest"
+	"github.com/systemshift/memex/internal/server/lanes"
+	"github.com/systemshift/memex/internal/server/memory"
+	"github.com/systemshift/memex/internal/server/modules"
+	"github.com/systemshift/memex/internal/server/normalize"
+	"github.com/systemshift/memex/internal/server/ontology"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/queries"
+	"github.com/systemshift/memex/internal/server/querycache"
+	"github.com/systemshift/memex/internal/server/rules"
+	"github.com/systemshift/memex/internal/server/sandbox"
+	"github.com/systemshift/memex/internal/server/sessions"
+	"github.com/systemshift/memex/internal/server/share"
+	"github.com/systemshift/memex/internal/server/signing"
+	"github.com/systemshift/memex/internal/server/snapshots"
 	"github.com/systemshift/memex/internal/server/subscriptions"
+	"github.com/systemshift/memex/internal/server/tasks"
+	"github.com/systemshift/memex/internal/server/tickets"
+	"github.com/systemshift/memex/internal/server/tracing"
+	"github.com/systemshift/memex/internal/server/vision"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "seed":
+			runSeed(os.Args[2:])
+			return
+		case "install-service", "service-status", "uninstall-service":
+			runService(os.Args[1], os.Args[2:])
+			return
+		}
+	}
+
+	// Flags, each defaulting to its environment variable
+	flags := flag.NewFlagSet("memex-server", flag.ExitOnError)
+	readOnly := flags.Bool("read-only", getEnv("MEMEX_READ_ONLY", "false") == "true", "reject every write with 403, serving only queries and views (MEMEX_READ_ONLY)")
+	reusePort := flags.Bool("reuse-port", getEnv("MEMEX_REUSE_PORT", "false") == "true", "listen with SO_REUSEPORT so a new server can start while the old one drains (MEMEX_REUSE_PORT)")
+	flags.Parse(os.Args[1:])
+
 	// Load configuration from environment
 	backend := getEnv("MEMEX_BACKEND", "sqlite")
 	port := getEnv("PORT", "8080")
 
 	ctx := context.Background()
-	var repo graph.Repository
-	var err error
-
-	switch backend {
-	case "sqlite":
-		sqlitePath := getEnv("SQLITE_PATH", "./memex.db")
-		log.Printf("Using SQLite backend: %s", sqlitePath)
-		repo, err = graph.NewSQLite(ctx, sqlitePath)
-		if err != nil {
-			log.Fatalf("Failed to open SQLite database: %v", err)
-		}
-	case "neo4j":
-		neo4jURI := getEnv("NEO4J_URI", "bolt://localhost:7687")
-		neo4jUser := getEnv("NEO4J_USER", "neo4j")
-		neo4jPassword := getEnv("NEO4J_PASSWORD", "password")
-
-		log.Printf("Using Neo4j backend: %s", neo4jURI)
-		repo, err = graph.NewNeo4j(ctx, graph.Config{
-			URI:      neo4jURI,
-			Username: neo4jUser,
-			Password: neo4jPassword,
-			Database: "neo4j",
-		})
+	repo, err := openRepository(ctx, backend)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := repo.Close(context.Background()); err != nil {
+			log.Printf("Warning: failed to close database cleanly: %v", err)
+		}
+		log.Println("Server exited")
+	}()
+	backendRepo := repo // Before wrapping, for the diagnostics endpoint
+
+	log.Println("Connected to database successfully")
+
+	// Optional synthetic data for development sandboxes
+	seedIfEmpty(ctx, repo)
+
+	// Fault injection for resilience tests, only in builds with -tags faults
+	repo, faultInjector := injectFaults(repo)
+
+	// Optional slow-query log (wraps the backend directly so SQLite plans can be captured)
+	var slowLog *graph.SlowQueryLog
+	if ms, err := strconv.Atoi(getEnv("MEMEX_SLOW_QUERY_MS", "0")); err == nil && ms > 0 {
+		slowLog = graph.NewSlowQueryLog(time.Duration(ms)*time.Millisecond, 200)
+		repo = graph.WithSlowQueryLog(repo, slowLog)
+		log.Printf("Slow-query log enabled (threshold %dms)", ms)
+	}
+
+	// Optional attention store: keeps ATTENDED edges out of the links table,
+	// saves them in batches and promotes strong ones to durable links
+	var attentionStore *graph.AttentionStore
+	if path := getEnv("MEMEX_ATTENTION_STORE", ""); path != "" {
+		cfg := graph.AttentionStoreConfig{Path: path}
+		if cfg.FlushInterval, err = time.ParseDuration(getEnv("MEMEX_ATTENTION_FLUSH_INTERVAL", "30s")); err != nil {
+			log.Fatalf("Invalid MEMEX_ATTENTION_FLUSH_INTERVAL: %v", err)
+		}
+		if cfg.ConsolidateInterval, err = time.ParseDuration(getEnv("MEMEX_ATTENTION_CONSOLIDATE_INTERVAL", "1h")); err != nil {
+			log.Fatalf("Invalid MEMEX_ATTENTION_CONSOLIDATE_INTERVAL: %v", err)
+		}
+		if cfg.PromoteWeight, err = strconv.ParseFloat(getEnv("MEMEX_ATTENTION_PROMOTE_WEIGHT", "0.7"), 64); err != nil {
+			log.Fatalf("Invalid MEMEX_ATTENTION_PROMOTE_WEIGHT: %v", err)
+		}
+		if cfg.PromoteQueries, err = strconv.Atoi(getEnv("MEMEX_ATTENTION_PROMOTE_QUERIES", "5")); err != nil {
+			log.Fatalf("Invalid MEMEX_ATTENTION_PROMOTE_QUERIES: %v", err)
+		}
+		repo, attentionStore, err = graph.WithAttentionStore(ctx, repo, cfg)
 		if err != nil {
-			log.Fatalf("Failed to connect to Neo4j: %v", err)
+			log.Fatalf("Failed to open attention store: %v", err)
 		}
-	default:
-		log.Fatalf("Unknown backend: %s (use 'sqlite' or 'neo4j')", backend)
+		defer attentionStore.Flush()
+		go attentionStore.Run(ctx, func(err error) { log.Printf("Attention store: %v", err) })
+		log.Printf("Attention store enabled (%s, %d edges)", path, attentionStore.Stats().Edges)
 	}
-	defer repo.Close(ctx)
 
-	log.Println("Connected to database successfully")
+	// Optional read cache for hot nodes, subgraphs and the graph map
+	if size, err := strconv.Atoi(getEnv("MEMEX_CACHE_SIZE", "0")); err == nil && size > 0 {
+		ttl, err := time.ParseDuration(getEnv("MEMEX_CACHE_TTL", "5m"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_CACHE_TTL: %v", err)
+		}
+		repo = graph.WithCache(repo, graph.CacheConfig{Size: size, TTL: ttl})
+		log.Printf("Read cache enabled (%d entries, TTL %s)", size, ttl)
+	}
+
+	// Graph revision counter for ETags on graph-wide views
+	repo, revision := graph.WithRevision(repo)
+
+	// Optional acyclicity constraint for derivation links
+	dagLinkTypes := splitList(getEnv("MEMEX_DAG_LINK_TYPES", ""))
+	if len(dagLinkTypes) > 0 {
+		repo = graph.WithDAGConstraint(repo, dagLinkTypes)
+		log.Printf("DAG constraint enabled for link types: %v", dagLinkTypes)
+	}
+
+	// Links that break an enforced link type definition in the ontology are rejected
+	repo = ontology.Enforce(repo)
+
+	// What soft deletes do with a node's links (keep, tombstone, rewire or restrict)
+	deleteCascade := getEnv("MEMEX_DELETE_CASCADE", graph.CascadeKeep)
+	if err := graph.ValidateCascade(deleteCascade); err != nil {
+		log.Fatalf("Invalid MEMEX_DELETE_CASCADE: %v", err)
+	}
+	repo = graph.WithDeleteCascade(repo, deleteCascade)
+
+	// Finalizers call cleanup webhooks for deleted nodes of registered types
+	var finalizerManager *finalizers.Manager
+	if getEnv("MEMEX_FINALIZERS_ENABLED", "true") == "true" {
+		timeout, err := time.ParseDuration(getEnv("MEMEX_FINALIZER_TIMEOUT", "10s"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_FINALIZER_TIMEOUT: %v", err)
+		}
+		finalizerManager = finalizers.NewManager(repo, timeout)
+		if err := finalizerManager.Load(ctx); err != nil {
+			log.Printf("Warning: Failed to load finalizers: %v", err)
+		}
+		defer finalizerManager.Wait()
+		repo = finalizers.Wrap(repo, finalizerManager)
+	}
+
+	// Optional storage quotas per namespace/type
+	var quotas []graph.Quota
+	if spec := getEnv("MEMEX_QUOTAS", ""); spec != "" {
+		quotas, err = graph.ParseQuotas(spec)
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_QUOTAS: %v", err)
+		}
+		repo = graph.WithQuotas(repo, quotas)
+		log.Printf("Storage quotas enabled: %s", spec)
+	}
+
+	// Namespaces published read-only beside writable ones
+	if namespaces := splitList(getEnv("MEMEX_READ_ONLY_NAMESPACES", "")); len(namespaces) > 0 {
+		repo = graph.WithReadOnlyNamespaces(repo, namespaces)
+		log.Printf("Read-only namespaces: %v", namespaces)
+	}
+
+	// Optional redaction of emails, card numbers, API keys and custom patterns before storage
+	if path := getEnv("MEMEX_REDACTION_POLICY", ""); path != "" {
+		policy, err := graph.LoadRedactionPolicy(path)
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_REDACTION_POLICY: %v", err)
+		}
+		repo = graph.WithRedaction(repo, policy)
+		log.Printf("Redaction enabled (%s)", path)
+	}
+
+	// Writes from a registered device are stamped with its ID
+	repo = devices.Stamp(repo)
+
+	// Access token scopes; inside aliases so they check resolved IDs
+	repo = graph.WithScopes(repo)
+
+	// Aliases and short IDs, accepted anywhere a node ID is
+	shortIDs := getEnv("MEMEX_SHORT_IDS", "false") == "true"
+	repo, aliases := graph.WithAliases(repo, shortIDs)
+	if shortIDs {
+		log.Println("Short node IDs enabled")
+	}
+
+	// Optional OpenTelemetry tracing (enabled by MEMEX_TRACING or a standard OTLP endpoint)
+	tracingEnabled := getEnv("MEMEX_TRACING", "false") == "true" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != ""
+	if tracingEnabled {
+		sampleRatio, err := strconv.ParseFloat(getEnv("MEMEX_TRACING_SAMPLE_RATIO", "1"), 64)
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_TRACING_SAMPLE_RATIO: %v", err)
+		}
+		shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "memex-server"),
+			Endpoint:    getEnv("MEMEX_OTLP_ENDPOINT", ""),
+			SampleRatio: sampleRatio,
+		})
+		if err != nil {
+			log.Fatalf("Failed to set up tracing: %v", err)
+		}
+		defer shutdownTracing(context.Background())
+
+		repo = graph.WithTracing(repo, backend)
+		log.Println("OpenTelemetry tracing enabled")
+	}
 
 	// Create indexes for performance
 	if err := repo.EnsureIndexes(ctx); err != nil {
@@ -64,16 +251,651 @@ func main() {
 
 	// Initialize subscription manager
 	subMgr := subscriptions.NewManager(repo)
+	if addr := getEnv("MEMEX_SMTP_ADDR", ""); addr != "" {
+		subMgr.SetSMTP(subscriptions.SMTPConfig{
+			Addr:     addr,
+			Username: getEnv("MEMEX_SMTP_USERNAME", ""),
+			Password: getEnv("MEMEX_SMTP_PASSWORD", ""),
+			From:     getEnv("MEMEX_SMTP_FROM", "memex@localhost"),
+		})
+	}
 	if err := subMgr.Start(ctx); err != nil {
 		log.Printf("Warning: Failed to start subscription manager: %v", err)
 	}
 	defer subMgr.Stop()
 
-	// Wire up event emission from repository to subscription manager
-	repo.SetEventEmitter(subMgr.GetEmitter())
+	// Optional image captioning processor (enabled when an API key is configured)
+	var visionProc *vision.Processor
+	if apiKey := getEnv("MEMEX_VISION_API_KEY", os.Getenv("OPENAI_API_KEY")); apiKey != "" && getEnv("MEMEX_VISION_ENABLED", "true") == "true" {
+		visionProc = vision.NewProcessor(repo, vision.Config{
+			URL:    getEnv("MEMEX_VISION_URL", "https://api.openai.com/v1/chat/completions"),
+			APIKey: apiKey,
+			Model:  getEnv("MEMEX_VISION_MODEL", "gpt-4o-mini"),
+		})
+		visionProc.Start()
+		defer visionProc.Stop()
+	}
+
+	// Reference parsing for papers (GROBID when configured, heuristic otherwise)
+	var citationProc *citations.Processor
+	if getEnv("MEMEX_CITATIONS_ENABLED", "true") == "true" {
+		citationProc = citations.NewProcessor(repo, citations.Config{GROBIDURL: getEnv("MEMEX_GROBID_URL", "")})
+		citationProc.Start()
+		defer citationProc.Stop()
+	}
+
+	// Task extraction from notes, transcripts and emails (regex, plus an
+	// LLM when a model endpoint is configured)
+	var taskProc *tasks.Processor
+	if getEnv("MEMEX_TASKS_ENABLED", "true") == "true" {
+		taskProc = tasks.NewProcessor(repo, tasks.Config{
+			LLMURL: getEnv("MEMEX_TASKS_LLM_URL", ""),
+			APIKey: getEnv("MEMEX_TASKS_API_KEY", os.Getenv("OPENAI_API_KEY")),
+			Model:  getEnv("MEMEX_TASKS_MODEL", "gpt-4o-mini"),
+		})
+		taskProc.Start()
+		defer taskProc.Stop()
+	}
+
+	// JSON sources typed by the stored type rules as they are ingested
+	var typeInference *infer.Processor
+	if getEnv("MEMEX_JSON_TYPES_ENABLED", "true") == "true" {
+		maxNodes, err := strconv.Atoi(getEnv("MEMEX_JSON_TYPES_MAX_NODES", "10000"))
+		if err != nil || maxNodes <= 0 {
+			log.Fatalf("Invalid MEMEX_JSON_TYPES_MAX_NODES: %v", getEnv("MEMEX_JSON_TYPES_MAX_NODES", "10000"))
+		}
+		typeInference = infer.NewProcessor(repo, infer.Config{MaxNodes: maxNodes})
+		typeInference.Start()
+		defer typeInference.Stop()
+	}
+
+	// User-defined rules that link nodes as they are created and updated
+	var ruleEngine *rules.Engine
+	if getEnv("MEMEX_RULES_ENABLED", "true") == "true" {
+		ruleEngine = rules.NewEngine(repo)
+	}
+
+	// Prefix index behind GET /api/autocomplete, built in the background
+	var autocompleteIndex *autocomplete.Index
+	if getEnv("MEMEX_AUTOCOMPLETE_ENABLED", "true") == "true" {
+		autocompleteIndex = autocomplete.NewIndex(repo)
+		autocompleteIndex.Start()
+		defer autocompleteIndex.Stop()
+	}
+
+	// Near-duplicate detection: SimHash fingerprints of node text, with
+	// NEAR_DUPLICATE_OF links for review through /api/duplicates
+	var dedupDetector *dedup.Detector
+	if getEnv("MEMEX_DEDUP_ENABLED", "true") == "true" {
+		cfg := dedup.Config{}
+		if cfg.Threshold, err = strconv.ParseFloat(getEnv("MEMEX_DEDUP_THRESHOLD", "0.85"), 64); err != nil || cfg.Threshold < 0.5 || cfg.Threshold > 1 {
+			log.Fatalf("Invalid MEMEX_DEDUP_THRESHOLD: %v", getEnv("MEMEX_DEDUP_THRESHOLD", "0.85"))
+		}
+		if cfg.MinWords, err = strconv.Atoi(getEnv("MEMEX_DEDUP_MIN_WORDS", "30")); err != nil {
+			log.Fatalf("Invalid MEMEX_DEDUP_MIN_WORDS: %v", err)
+		}
+		dedupDetector = dedup.NewDetector(repo, cfg)
+		dedupDetector.Start()
+		defer dedupDetector.Stop()
+	}
+
+	// Memory cards: distilled summaries of pinned nodes for agent context
+	var memoryStore *memory.Store
+	if getEnv("MEMEX_MEMORY_ENABLED", "true") == "true" {
+		memoryStore = memory.NewStore(repo, memory.Config{
+			LLMURL: getEnv("MEMEX_MEMORY_LLM_URL", ""),
+			APIKey: getEnv("MEMEX_MEMORY_API_KEY", os.Getenv("OPENAI_API_KEY")),
+			Model:  getEnv("MEMEX_MEMORY_MODEL", "gpt-4o-mini"),
+		})
+		if err := memoryStore.Load(ctx); err != nil {
+			log.Printf("Warning: Failed to load memory cards: %v", err)
+		}
+	}
+
+	// Structural node embeddings, trained over the links on request or on a schedule
+	var embeddingStore *embeddings.Store
+	if getEnv("MEMEX_EMBEDDINGS_ENABLED", "true") == "true" {
+		cfg := embeddings.StoreConfig{Path: getEnv("MEMEX_EMBEDDINGS_PATH", "")}
+		if cfg.Interval, err = time.ParseDuration(getEnv("MEMEX_EMBEDDINGS_INTERVAL", "0")); err != nil {
+			log.Fatalf("Invalid MEMEX_EMBEDDINGS_INTERVAL: %v", err)
+		}
+		if cfg.Training.Dimensions, err = strconv.Atoi(getEnv("MEMEX_EMBEDDINGS_DIMENSIONS", "64")); err != nil {
+			log.Fatalf("Invalid MEMEX_EMBEDDINGS_DIMENSIONS: %v", err)
+		}
+		if embeddingStore, err = embeddings.NewStore(repo, cfg); err != nil {
+			log.Fatalf("Failed to open embeddings: %v", err)
+		}
+		go embeddingStore.Run(ctx, func(err error) { log.Printf("Embedding training: %v", err) })
+	}
+
+	// Optional webhook fired when a source and its derived nodes finish processing
+	var ingestTracker *ingest.Tracker
+	if url := getEnv("MEMEX_INGEST_WEBHOOK_URL", ""); url != "" {
+		settle, err := time.ParseDuration(getEnv("MEMEX_INGEST_WEBHOOK_SETTLE", "30s"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_INGEST_WEBHOOK_SETTLE: %v", err)
+		}
+		maxWait, err := time.ParseDuration(getEnv("MEMEX_INGEST_WEBHOOK_MAX_WAIT", "10m"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_INGEST_WEBHOOK_MAX_WAIT: %v", err)
+		}
+		ingestTracker = ingest.NewTracker(repo, ingest.Config{WebhookURL: url, Settle: settle, MaxWait: maxWait})
+		if visionProc != nil {
+			visionProc.SetHold(ingestTracker.Hold)
+		}
+		if citationProc != nil {
+			citationProc.SetHold(ingestTracker.Hold)
+		}
+		if taskProc != nil {
+			taskProc.SetHold(ingestTracker.Hold)
+		}
+		if typeInference != nil {
+			typeInference.SetHold(ingestTracker.Hold)
+		}
+		if ruleEngine != nil {
+			ruleEngine.SetHold(ingestTracker.Hold)
+		}
+		ingestTracker.Start()
+		defer ingestTracker.Stop()
+	}
+	if ruleEngine != nil {
+		ruleEngine.Start()
+		defer ruleEngine.Stop()
+	}
+
+	// Optional periodic contradiction scan
+	if interval, err := time.ParseDuration(getEnv("MEMEX_CONFLICT_SCAN_INTERVAL", "0")); err == nil && interval > 0 {
+		scanner := conflicts.NewScanner(repo, interval)
+		scanner.Start()
+		defer scanner.Stop()
+	}
+
+	// Optional periodic CardDAV contact sync
+	if url := getEnv("MEMEX_CARDDAV_URL", ""); url != "" {
+		interval, err := time.ParseDuration(getEnv("MEMEX_CARDDAV_INTERVAL", "1h"))
+		if err != nil || interval <= 0 {
+			log.Fatalf("Invalid MEMEX_CARDDAV_INTERVAL: %v", getEnv("MEMEX_CARDDAV_INTERVAL", "1h"))
+		}
+		syncer := people.NewSyncer(repo, people.CardDAVOptions{
+			URL:      url,
+			Username: getEnv("MEMEX_CARDDAV_USERNAME", ""),
+			Password: getEnv("MEMEX_CARDDAV_PASSWORD", ""),
+		}, interval)
+		syncer.Start()
+		defer syncer.Stop()
+	}
+
+	// Wire up event emission from repository to subscription manager and
+	// the optional processors, registered as modules that can be switched
+	// off per namespace and sent commands through /api/modules
+	moduleRegistry := modules.NewRegistry(repo)
+	processors{
+		ingest:       ingestTracker,
+		vision:       visionProc,
+		citations:    citationProc,
+		tasks:        taskProc,
+		types:        typeInference,
+		rules:        ruleEngine,
+		autocomplete: autocompleteIndex,
+		memory:       memoryStore,
+		dedup:        dedupDetector,
+	}.register(moduleRegistry)
+	if err := moduleRegistry.Load(ctx); err != nil {
+		log.Printf("Warning: failed to load module settings: %v", err)
+	}
+
+	// Ingest anomaly detection: baselines per-connector rates and the mix
+	// of node types created, and reports sharp departures as events
+	var ingestMonitor *anomaly.Monitor
+	if getEnv("MEMEX_ANOMALY_ENABLED", "true") == "true" {
+		cfg := anomaly.Config{}
+		if cfg.Bucket, err = time.ParseDuration(getEnv("MEMEX_ANOMALY_BUCKET", "10m")); err != nil || cfg.Bucket <= 0 {
+			log.Fatalf("Invalid MEMEX_ANOMALY_BUCKET: %v", getEnv("MEMEX_ANOMALY_BUCKET", "10m"))
+		}
+		if cfg.Threshold, err = strconv.ParseFloat(getEnv("MEMEX_ANOMALY_THRESHOLD", "4"), 64); err != nil {
+			log.Fatalf("Invalid MEMEX_ANOMALY_THRESHOLD: %v", err)
+		}
+		if cfg.MinRate, err = strconv.ParseFloat(getEnv("MEMEX_ANOMALY_MIN_RATE", "5"), 64); err != nil {
+			log.Fatalf("Invalid MEMEX_ANOMALY_MIN_RATE: %v", err)
+		}
+		ingestMonitor = anomaly.NewMonitor(cfg, subMgr.EmitEvent)
+		ingestMonitor.Start()
+		defer ingestMonitor.Stop()
+	}
+
+	// Optional cache of subgraph, graph map and aggregate results, keyed by
+	// their parameters and the graph revision and emptied on changes
+	var queryCache *querycache.Cache
+	if getEnv("MEMEX_QUERY_CACHE_ENABLED", "false") == "true" {
+		size, err := strconv.Atoi(getEnv("MEMEX_QUERY_CACHE_SIZE", "500"))
+		if err != nil || size <= 0 {
+			log.Fatalf("Invalid MEMEX_QUERY_CACHE_SIZE: %v", getEnv("MEMEX_QUERY_CACHE_SIZE", "500"))
+		}
+		ttl, err := time.ParseDuration(getEnv("MEMEX_QUERY_CACHE_TTL", "10m"))
+		if err != nil || ttl < 0 {
+			log.Fatalf("Invalid MEMEX_QUERY_CACHE_TTL: %v", getEnv("MEMEX_QUERY_CACHE_TTL", "10m"))
+		}
+		queryCache = querycache.New(querycache.Config{Size: size, TTL: ttl})
+		log.Printf("Query cache enabled (%d entries, TTL %s)", size, ttl)
+	}
+	repo.SetEventEmitter(func(event subscriptions.Event) {
+		subMgr.EmitEvent(event)
+		moduleRegistry.EmitEvent(event)
+		if ingestMonitor != nil {
+			ingestMonitor.EmitEvent(event)
+		}
+		if queryCache != nil {
+			queryCache.EmitEvent(event)
+		}
+	})
+
+	// Reverse-proxy settings
+	basePath := api.NormalizeBasePath(getEnv("MEMEX_BASE_PATH", ""))
+	trustProxy := getEnv("MEMEX_TRUST_PROXY", "false") == "true"
 
 	// Initialize API server
 	apiServer := api.New(repo, subMgr)
+	apiServer.SetProxyConfig(basePath, trustProxy)
+	apiServer.SetWebURL(getEnv("MEMEX_WEB_URL", ""))
+	apiServer.SetSlowQueryLog(slowLog)
+	apiServer.SetRevision(revision)
+	if queryCache != nil {
+		apiServer.SetQueryCache(queryCache)
+	}
+	apiServer.SetAliases(aliases)
+
+	// Other memex servers searched alongside this one with ?federated=true
+	if spec := getEnv("MEMEX_PEERS", ""); spec != "" {
+		peers, err := federation.ParsePeers(spec, getEnv("MEMEX_PEER_KEYS", ""))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_PEERS: %v", err)
+		}
+		timeout, err := time.ParseDuration(getEnv("MEMEX_PEER_TIMEOUT", federation.DefaultPeerTimeout.String()))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_PEER_TIMEOUT: %v", err)
+		}
+		apiServer.SetPeers(&federation.Searcher{Peers: peers, Timeout: timeout})
+		for _, peer := range peers {
+			log.Printf("Federation peer %s: %s", peer.Name, peer.URL)
+		}
+	}
+	apiServer.SetBackend(backend, backendRepo)
+	if faultInjector != nil {
+		apiServer.SetFaults(faultInjector)
+	}
+
+	// Optional startup warm-up: reads the hot tables and the full-text
+	// indexes into the page cache and checks the indexes; /ready answers 503
+	// until it finishes
+	if getEnv("MEMEX_WARMUP", "true") == "true" && backend == "sqlite" {
+		if warmer, ok := backendRepo.(graph.Warmer); ok {
+			apiServer.StartWarmup(ctx, warmer)
+		}
+	}
+	apiServer.SetDAGLinkTypes(dagLinkTypes)
+	apiServer.SetQuotas(quotas)
+	apiServer.SetIngestTracker(ingestTracker)
+	apiServer.SetCitationProcessor(citationProc)
+	apiServer.SetTaskProcessor(taskProc)
+	apiServer.SetAutocompleteIndex(autocompleteIndex)
+	apiServer.SetRuleEngine(ruleEngine)
+	apiServer.SetModules(moduleRegistry)
+	apiServer.SetZoteroURL(getEnv("MEMEX_ZOTERO_URL", ""))
+	if spec := getEnv("MEMEX_API_KEY_AUTHORS", ""); spec != "" {
+		authors, err := parseKeyAuthors(spec)
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_API_KEY_AUTHORS: %v", err)
+		}
+		apiServer.SetAPIKeyAuthors(authors)
+	}
+
+	// Optional priority lanes: bulk traffic (batch ingest, imports, exports,
+	// and keys or tokens assigned to the bulk lane) gets its own small pool
+	// of request slots so it cannot starve interactive clients
+	lanesEnabled := getEnv("MEMEX_LANES_ENABLED", "false") == "true"
+	if lanesEnabled {
+		laneConfig := func(prefix string, limit, queue int, wait string) lanes.LaneConfig {
+			var c lanes.LaneConfig
+			var err error
+			if c.Limit, err = strconv.Atoi(getEnv(prefix+"_LIMIT", strconv.Itoa(limit))); err != nil || c.Limit <= 0 {
+				log.Fatalf("Invalid %s_LIMIT: %v", prefix, getEnv(prefix+"_LIMIT", ""))
+			}
+			if c.Queue, err = strconv.Atoi(getEnv(prefix+"_QUEUE", strconv.Itoa(queue))); err != nil || c.Queue < 0 {
+				log.Fatalf("Invalid %s_QUEUE: %v", prefix, getEnv(prefix+"_QUEUE", ""))
+			}
+			if c.Queue == 0 {
+				c.Queue = -1 // No queue: refuse when every slot is taken
+			}
+			if c.Wait, err = time.ParseDuration(getEnv(prefix+"_WAIT", wait)); err != nil || c.Wait <= 0 {
+				log.Fatalf("Invalid %s_WAIT: %v", prefix, getEnv(prefix+"_WAIT", ""))
+			}
+			return c
+		}
+		cfg := lanes.Config{
+			Interactive: laneConfig("MEMEX_LANE_INTERACTIVE", 64, 256, "10s"),
+			Bulk:        laneConfig("MEMEX_LANE_BULK", 4, 64, "60s"),
+		}
+		keyLanes, err := parseKeyAuthors(getEnv("MEMEX_LANE_KEYS", ""))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_LANE_KEYS: %v", err)
+		}
+		for _, lane := range keyLanes {
+			if !lanes.Valid(lane) {
+				log.Fatalf("Invalid MEMEX_LANE_KEYS: unknown lane %q (use %s)", lane, strings.Join(lanes.Names, " or "))
+			}
+		}
+		apiServer.SetLanes(lanes.New(cfg), keyLanes)
+		log.Printf("Priority lanes enabled (interactive %d, bulk %d at once)", cfg.Interactive.Limit, cfg.Bulk.Limit)
+	}
+	adminKey := getEnv("MEMEX_ADMIN_KEY", "")
+	if adminKey != "" {
+		apiServer.SetAccessTokens(adminKey)
+		log.Println("Authentication enabled: requests need an API key or access token")
+	}
+	if spec := getEnv("MEMEX_SIGNING_KEYS", ""); spec != "" {
+		keys, err := signing.ParseKeys(spec)
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_SIGNING_KEYS: %v", err)
+		}
+		window, err := time.ParseDuration(getEnv("MEMEX_SIGNING_WINDOW", "5m"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_SIGNING_WINDOW: %v", err)
+		}
+		scopes, err := api.ParseSigningScopes(getEnv("MEMEX_SIGNING_SCOPES", ""))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_SIGNING_SCOPES: %v", err)
+		}
+		for id := range scopes {
+			if _, ok := keys[id]; !ok {
+				log.Fatalf("Invalid MEMEX_SIGNING_SCOPES: no signing key %q", id)
+			}
+		}
+		apiServer.SetRequestSigning(signing.NewVerifier(keys, window), scopes)
+		log.Printf("Request signing enabled (%d keys)", len(keys))
+	}
+	// Device registry: capture agents and CLIs identify themselves with X-Memex-Device
+	if getEnv("MEMEX_DEVICES", "true") != "false" {
+		apiServer.SetDevices(devices.NewRegistry(repo))
+	}
+	// Deprecated routes keep answering, announce their sunset dates and count their calls
+	deprecatedRoutes, err := apiversion.ParseNotices(getEnv("MEMEX_DEPRECATED_ROUTES", ""))
+	if err != nil {
+		log.Fatalf("Invalid MEMEX_DEPRECATED_ROUTES: %v", err)
+	}
+	if sunset := getEnv("MEMEX_UNVERSIONED_SUNSET", ""); len(deprecatedRoutes) > 0 || sunset != "" {
+		tracker := apiversion.NewTracker(deprecatedRoutes)
+		if sunset != "" {
+			date, err := time.Parse(time.DateOnly, sunset)
+			if err != nil {
+				log.Fatalf("Invalid MEMEX_UNVERSIONED_SUNSET: %v", err)
+			}
+			tracker.DeprecateUnversioned(date, basePath+"/api/v"+strconv.Itoa(apiversion.Current))
+		}
+		apiServer.SetDeprecations(tracker)
+		log.Printf("Deprecation notices enabled (%d routes, unversioned /api sunset: %s)", len(deprecatedRoutes), getEnv("MEMEX_UNVERSIONED_SUNSET", "none"))
+	}
+
+	integrityExclude := graph.DefaultIntegrityExcludeTypes
+	if types := getEnv("MEMEX_INTEGRITY_EXCLUDE_TYPES", ""); types != "" {
+		integrityExclude = splitList(types)
+		apiServer.SetIntegrityExcludeTypes(integrityExclude)
+	}
+
+	// Graph health score; type definitions are unlinked by design
+	if getEnv("MEMEX_GRAPH_HEALTH", "true") != "false" {
+		cfg := health.Config{
+			ExcludeTypes: append(append([]string{}, integrityExclude...), ontology.NodeType, ontology.LinkNodeType),
+			Duplicates:   dedupDetector,
+		}
+		var err error
+		if cfg.StaleAfter, err = time.ParseDuration(getEnv("MEMEX_HEALTH_STALE_AFTER", "4320h")); err != nil {
+			log.Fatalf("Invalid MEMEX_HEALTH_STALE_AFTER: %v", err)
+		}
+		if cfg.CacheTTL, err = time.ParseDuration(getEnv("MEMEX_HEALTH_CACHE_TTL", "5m")); err != nil {
+			log.Fatalf("Invalid MEMEX_HEALTH_CACHE_TTL: %v", err)
+		}
+		apiServer.SetGraphHealth(health.NewChecker(repo, cfg))
+	}
+
+	// Scheduled saved queries post results as events and to webhooks
+	if getEnv("MEMEX_QUERY_SCHEDULER", "true") != "false" {
+		scheduler := queries.NewScheduler(repo, subMgr.EmitEvent)
+		scheduler.Start()
+		defer scheduler.Stop()
+		apiServer.SetQueryScheduler(scheduler)
+	}
+
+	// Daily rollups of graph size for capacity planning
+	if getEnv("MEMEX_GROWTH_STATS", "true") == "true" {
+		recorder := growth.NewRecorder(repo)
+		recorder.Start()
+		defer recorder.Stop()
+		apiServer.SetGrowthRecorder(recorder)
+	}
+
+	// GitHub issues, pull requests and reviews: periodic sync of
+	// MEMEX_GITHUB_REPOS and, with a secret, webhook deliveries
+	githubClient := &github.Client{
+		BaseURL: getEnv("MEMEX_GITHUB_API_URL", github.DefaultAPIURL),
+		Token:   getEnv("MEMEX_GITHUB_TOKEN", ""),
+	}
+	apiServer.SetGitHub(githubClient, getEnv("MEMEX_GITHUB_WEBHOOK_SECRET", ""))
+	if repos := splitList(getEnv("MEMEX_GITHUB_REPOS", "")); len(repos) > 0 {
+		for _, name := range repos {
+			if _, err := github.ParseRepo(name); err != nil {
+				log.Fatalf("Invalid MEMEX_GITHUB_REPOS: %v", err)
+			}
+		}
+		interval, err := time.ParseDuration(getEnv("MEMEX_GITHUB_INTERVAL", "15m"))
+		if err != nil || interval <= 0 {
+			log.Fatalf("Invalid MEMEX_GITHUB_INTERVAL: %v", getEnv("MEMEX_GITHUB_INTERVAL", "15m"))
+		}
+		syncer := github.NewSyncer(repo, githubClient, repos, interval)
+		syncer.Start()
+		defer syncer.Stop()
+	}
+
+	// Jira and Linear tickets synced into Task nodes; status changes reach
+	// subscriptions as node.updated events
+	var ticketSources []tickets.Source
+	if url := getEnv("MEMEX_JIRA_URL", ""); url != "" {
+		ticketSources = append(ticketSources, &tickets.Jira{
+			URL:   url,
+			Email: getEnv("MEMEX_JIRA_EMAIL", ""),
+			Token: getEnv("MEMEX_JIRA_TOKEN", ""),
+			JQL:   getEnv("MEMEX_JIRA_JQL", ""),
+		})
+	}
+	if key := getEnv("MEMEX_LINEAR_API_KEY", ""); key != "" {
+		ticketSources = append(ticketSources, &tickets.Linear{
+			APIKey: key,
+			Team:   getEnv("MEMEX_LINEAR_TEAM", ""),
+		})
+	}
+	if len(ticketSources) > 0 {
+		apiServer.SetTicketSources(ticketSources)
+		interval, err := time.ParseDuration(getEnv("MEMEX_TICKETS_INTERVAL", "10m"))
+		if err != nil || interval < 0 {
+			log.Fatalf("Invalid MEMEX_TICKETS_INTERVAL: %v", getEnv("MEMEX_TICKETS_INTERVAL", "10m"))
+		}
+		// An interval of 0 leaves syncing to POST /api/import/tickets
+		if interval > 0 {
+			syncer := tickets.NewSyncer(repo, ticketSources, interval)
+			syncer.Start()
+			defer syncer.Stop()
+		}
+	}
+
+	// Connectors defined through /api/connectors or MEMEX_CONNECTORS_FILE,
+	// each run on its own schedule; the file is reloaded when it changes
+	if getEnv("MEMEX_CONNECTORS", "true") == "true" {
+		connectorManager := connectors.NewManager(repo, connectors.DefaultKinds())
+		if path := getEnv("MEMEX_CONNECTORS_FILE", ""); path != "" {
+			connectorManager.SetFile(path)
+			if err := connectorManager.LoadFile(context.Background()); err != nil {
+				log.Fatalf("Invalid MEMEX_CONNECTORS_FILE: %v", err)
+			}
+		}
+		connectorManager.Start()
+		defer connectorManager.Stop()
+		apiServer.SetConnectors(connectorManager)
+	}
+
+	// Optional RDF vocabulary mapping for JSON-LD/Turtle export
+	if vocabPath := getEnv("MEMEX_RDF_VOCAB", ""); vocabPath != "" {
+		vocab, err := export.LoadVocabulary(vocabPath)
+		if err != nil {
+			log.Fatalf("Failed to load RDF vocabulary: %v", err)
+		}
+		apiServer.SetRDFVocabulary(vocab)
+	}
+
+	// Optional source trust by connector/domain for search ranking
+	if spec := getEnv("MEMEX_SOURCE_TRUST", ""); spec != "" {
+		policy, err := graph.ParseTrustPolicy(spec)
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_SOURCE_TRUST: %v", err)
+		}
+		apiServer.SetTrustPolicy(policy)
+	}
+
+	// Optional content normalization by connector, applied before hashing
+	// so copies that differ only in whitespace or markup dedupe
+	if spec := getEnv("MEMEX_INGEST_NORMALIZE", ""); spec != "" {
+		policy, err := normalize.ParsePolicy(spec)
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_INGEST_NORMALIZE: %v", err)
+		}
+		apiServer.SetNormalization(policy)
+	}
+
+	// Retrieval quality: which search and recommendation results get used
+	var feedbackTracker *feedback.Tracker
+	if getEnv("MEMEX_FEEDBACK_ENABLED", "true") == "true" {
+		retain, _ := strconv.Atoi(getEnv("MEMEX_FEEDBACK_RETAIN", "10000"))
+		feedbackTracker = feedback.NewTracker(retain)
+		apiServer.SetFeedback(feedbackTracker)
+	}
+
+	// A/B tests of search ranking strategies, measured by retrieval feedback
+	experimentMgr := experiments.NewManager(feedbackTracker)
+	if path := getEnv("MEMEX_EXPERIMENTS", ""); path != "" {
+		if err := experimentMgr.Load(path); err != nil {
+			log.Fatalf("Invalid MEMEX_EXPERIMENTS: %v", err)
+		}
+	}
+	apiServer.SetExperiments(experimentMgr)
+
+	if attentionStore != nil {
+		apiServer.SetAttentionStore(attentionStore)
+	}
+	if memoryStore != nil {
+		apiServer.SetMemory(memoryStore)
+	}
+	if embeddingStore != nil {
+		apiServer.SetEmbeddings(embeddingStore)
+	}
+	if ingestMonitor != nil {
+		apiServer.SetIngestMonitor(ingestMonitor)
+	}
+	if dedupDetector != nil {
+		apiServer.SetDedup(dedupDetector)
+	}
+	if typeInference != nil {
+		apiServer.SetTypeInference(typeInference)
+	}
+
+	// Registered analytics SQL on a read-only pool of its own, off the
+	// operational SQLite connection
+	if backend == "sqlite" && getEnv("MEMEX_ANALYTICS_ENABLED", "true") == "true" {
+		cfg := analytics.Config{}
+		if cfg.Timeout, err = time.ParseDuration(getEnv("MEMEX_ANALYTICS_TIMEOUT", "30s")); err != nil {
+			log.Fatalf("Invalid MEMEX_ANALYTICS_TIMEOUT: %v", err)
+		}
+		if cfg.MaxRows, err = strconv.Atoi(getEnv("MEMEX_ANALYTICS_MAX_ROWS", "10000")); err != nil {
+			log.Fatalf("Invalid MEMEX_ANALYTICS_MAX_ROWS: %v", err)
+		}
+		engine, err := analytics.Open(getEnv("SQLITE_PATH", "./memex.db"), cfg)
+		if err != nil {
+			log.Printf("Analytics disabled: %v", err)
+		} else {
+			defer engine.Close()
+			if path := getEnv("MEMEX_ANALYTICS_QUERIES", ""); path != "" {
+				n, err := engine.LoadFile(path)
+				if err != nil {
+					log.Fatalf("Invalid MEMEX_ANALYTICS_QUERIES: %v", err)
+				}
+				log.Printf("Loaded %d analytics queries from %s", n, path)
+			}
+			apiServer.SetAnalytics(engine)
+		}
+	}
+
+	// Soft quotas on traversal requests; a request can lower them, and one
+	// that runs out returns what it found marked truncated
+	traversalMaxTime, err := time.ParseDuration(getEnv("MEMEX_TRAVERSAL_MAX_TIME", "10s"))
+	if err != nil {
+		log.Fatalf("Invalid MEMEX_TRAVERSAL_MAX_TIME: %v", err)
+	}
+	traversalMaxNodes, _ := strconv.Atoi(getEnv("MEMEX_TRAVERSAL_MAX_NODES", "10000"))
+	traversalMaxEdges, _ := strconv.Atoi(getEnv("MEMEX_TRAVERSAL_MAX_EDGES", "100000"))
+	apiServer.SetTraversalBudget(graph.TraversalBudget{
+		MaxNodes: traversalMaxNodes,
+		MaxEdges: traversalMaxEdges,
+		MaxTime:  traversalMaxTime,
+	})
+
+	// Scratch sandbox graphs, kept in memory until merged, discarded or idle
+	if getEnv("MEMEX_SANDBOX_ENABLED", "true") == "true" {
+		ttl, err := time.ParseDuration(getEnv("MEMEX_SANDBOX_TTL", "24h"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_SANDBOX_TTL: %v", err)
+		}
+		max, _ := strconv.Atoi(getEnv("MEMEX_SANDBOX_MAX", "50"))
+		apiServer.SetSandboxes(sandbox.NewManager(repo, ttl, max))
+	}
+
+	// Agent working-memory sessions, kept in memory until closed or idle
+	if getEnv("MEMEX_SESSIONS_ENABLED", "true") == "true" {
+		ttl, err := time.ParseDuration(getEnv("MEMEX_SESSION_TTL", "24h"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_SESSION_TTL: %v", err)
+		}
+		max, _ := strconv.Atoi(getEnv("MEMEX_SESSION_MAX", "200"))
+		apiServer.SetSessions(sessions.NewManager(repo, ttl, max))
+	}
+
+	// Read snapshots pin the reads of a multi-call agent flow to one view;
+	// they hold a read transaction open, so their lifetime is bounded
+	if snapshotter, ok := backendRepo.(graph.Snapshotter); ok && getEnv("MEMEX_SNAPSHOTS_ENABLED", "true") == "true" {
+		ttl, err := time.ParseDuration(getEnv("MEMEX_SNAPSHOT_TTL", "30s"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_SNAPSHOT_TTL: %v", err)
+		}
+		maxTTL, err := time.ParseDuration(getEnv("MEMEX_SNAPSHOT_MAX_TTL", "5m"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_SNAPSHOT_MAX_TTL: %v", err)
+		}
+		max, _ := strconv.Atoi(getEnv("MEMEX_SNAPSHOT_MAX", "16"))
+		snapshotManager := snapshots.NewManager(snapshotter, ttl, maxTTL, max)
+		defer snapshotManager.Close()
+		apiServer.SetSnapshots(snapshotManager)
+	}
+	if finalizerManager != nil {
+		apiServer.SetFinalizers(finalizerManager)
+	}
+
+	// Signed public share links; without a configured secret they last until restart
+	if secret := getEnv("MEMEX_SHARE_SECRET", ""); secret != "" {
+		apiServer.SetShareSigner(share.NewSigner(secret))
+	} else {
+		signer, err := share.NewRandomSigner()
+		if err != nil {
+			log.Fatal(err)
+		}
+		apiServer.SetShareSigner(signer)
+		log.Println("MEMEX_SHARE_SECRET not set; share links will stop working on restart")
+	}
 
 	// Setup HTTP router
 	r := chi.NewRouter()
@@ -83,37 +905,332 @@ func main() {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	if tracingEnabled {
+		r.Use(tracing.RouteTagger)
+	}
+	if trustProxy {
+		r.Use(api.ForwardedHeaders)
+	}
+
+	// CORS (disabled unless origins are configured)
+	if origins := splitList(getEnv("MEMEX_CORS_ORIGINS", "")); len(origins) > 0 {
+		corsConfig := api.CORSConfig{
+			AllowedOrigins:   origins,
+			AllowedMethods:   splitList(getEnv("MEMEX_CORS_METHODS", "GET,POST,PATCH,DELETE,OPTIONS")),
+			AllowedHeaders:   splitList(getEnv("MEMEX_CORS_HEADERS", "Content-Type,Authorization,Idempotency-Key")),
+			AllowCredentials: getEnv("MEMEX_CORS_CREDENTIALS", "false") == "true",
+			MaxAge:           600,
+		}
+		if err := corsConfig.Validate(); err != nil {
+			log.Fatalf("Invalid CORS configuration: %v", err)
+		}
+		r.Use(api.CORS(corsConfig))
+		log.Printf("CORS enabled for origins: %v", origins)
+	}
 
 	// Routes
 	r.Get("/health", apiServer.HealthCheck)
+	r.Get("/ready", apiServer.Ready)
+	r.With(apiServer.Authenticate).Get("/metrics", apiServer.Metrics)
+	r.Get("/share/{token}", apiServer.ViewShare)
+	r.With(apiServer.Authenticate).Get("/n/{id}", apiServer.ViewNode)
+	r.With(apiServer.Authenticate).Get("/graph/diff", apiServer.ViewGraphDiff)
+
+	// Idempotency keys, so retried writes replay their first response
+	var idempotent *idempotency.Store
+	if getEnv("MEMEX_IDEMPOTENCY_ENABLED", "true") == "true" {
+		ttl, err := time.ParseDuration(getEnv("MEMEX_IDEMPOTENCY_TTL", "24h"))
+		if err != nil {
+			log.Fatalf("Invalid MEMEX_IDEMPOTENCY_TTL: %v", err)
+		}
+		max, _ := strconv.Atoi(getEnv("MEMEX_IDEMPOTENCY_MAX", "10000"))
+		idempotent = idempotency.NewStore(ttl, max)
+	}
+
+	if *readOnly {
+		log.Println("Read-only mode: writes are rejected with 403")
+	}
+
+	// Default deadline for API requests; ?timeout= sets one per request
+	requestTimeout, err := time.ParseDuration(getEnv("MEMEX_REQUEST_TIMEOUT", "0s"))
+	if err != nil || requestTimeout > api.MaxRequestTimeout {
+		log.Fatalf("Invalid MEMEX_REQUEST_TIMEOUT: must be a duration of at most %s", api.MaxRequestTimeout)
+	}
+
+	// The API is served at /api/v1 and, for clients from before versioning,
+	// at /api, which answers as version 1 unless asked for another. Errors
+	// are JSON bodies with a code clients can branch on.
+	apiRouter := r.Route("/api", func(r chi.Router) {
+		r.Use(api.Errors)
+		r.Use(apiServer.Versioning(r))
+		r.Use(apiServer.Authenticate)
+		r.Use(apiServer.IdentifyDevice)
+		r.Use(api.Timeout(requestTimeout))
+		if lanesEnabled {
+			r.Use(apiServer.Lanes)
+		}
+		if *readOnly {
+			r.Use(api.ReadOnly)
+		}
+		if idempotent != nil {
+			r.Use(idempotent.Middleware)
+		}
+		r.Use(apiServer.PinSnapshot)
 
-	r.Route("/api", func(r chi.Router) {
 		r.Post("/ingest", apiServer.Ingest)
+		r.Post("/ingest/bulk", apiServer.BulkIngest)
+		r.Post("/ingest/{id}/complete", apiServer.CompleteIngest)
+		r.Post("/hooks/{token}", apiServer.ReceiveHook)
+		r.Post("/capture", apiServer.Capture)
+		r.Post("/tokens", apiServer.CreateToken)
+		r.Get("/tokens/self", apiServer.GetTokenSelf)
+		r.Post("/devices", apiServer.RegisterDevice)
+		r.Get("/devices", apiServer.ListDevices)
+		r.Get("/devices/{id}", apiServer.GetDevice)
+		r.Post("/devices/{id}/revoke", apiServer.RevokeDevice)
 		r.Post("/nodes", apiServer.CreateNode)
+		r.Post("/nodes/bulk", apiServer.BulkCreateNodes)
+		r.Post("/nodes/bulk/delete", apiServer.BulkDeleteNodes)
+		r.Post("/nodes/batch-get", apiServer.BatchGetNodes)
 		r.Get("/nodes", apiServer.ListNodes)
+		r.Get("/nodes/resolve", apiServer.ResolveNodeID)
 		r.Get("/nodes/{id}", apiServer.GetNode)
 		r.Get("/nodes/{id}/history", apiServer.GetNodeHistory)
+		r.Get("/nodes/{id}/diff", apiServer.GetNodeDiff)
+		r.Get("/nodes/{id}/provenance", apiServer.GetProvenance)
+		r.Get("/nodes/{id}/trust", apiServer.GetTrust)
+		r.Put("/nodes/{id}/alias", apiServer.SetNodeAlias)
+		r.Delete("/nodes/{id}/alias", apiServer.RemoveNodeAlias)
+		r.Put("/nodes/{id}/trust", apiServer.SetTrust)
+		r.Patch("/nodes/{id}/protection", apiServer.SetProtection)
 		r.Patch("/nodes/{id}", apiServer.UpdateNode)
+		r.Post("/nodes/{id}/lock", apiServer.LockNode)
+		r.Get("/nodes/{id}/lock", apiServer.GetNodeLock)
+		r.Delete("/nodes/{id}/lock", apiServer.UnlockNode)
+		r.Post("/nodes/{id}/comments", apiServer.CreateComment)
+		r.Get("/nodes/{id}/comments", apiServer.ListComments)
+		r.Get("/nodes/{id}/annotations", apiServer.ListAnnotations)
+		r.Post("/nodes/{id}/evidence", apiServer.AddEvidence)
+		r.Get("/nodes/{id}/evidence", apiServer.GetEvidence)
+		r.Delete("/comments/{id}", apiServer.DeleteComment)
 		r.Delete("/nodes/{id}", apiServer.DeleteNode)
 		r.Get("/nodes/{id}/links", apiServer.GetLinks)
+		r.Get("/nodes/{id}/backlinks", apiServer.GetBacklinks)
+		r.Get("/nodes/{id}/related", apiServer.RelatedNodes)
+		r.Get("/nodes/{id}/embedding", apiServer.GetNodeEmbedding)
+		r.Get("/nodes/{id}/embedding/similar", apiServer.SimilarByEmbedding)
+		r.Post("/nodes/{id}/references", apiServer.ParseReferences)
+		r.Post("/nodes/{id}/tasks", apiServer.ExtractTasks)
 		r.Post("/links", apiServer.CreateLink)
+		r.Post("/links/bulk", apiServer.BulkCreateLinks)
 		r.Delete("/links", apiServer.DeleteLink)
+		r.Get("/trash", apiServer.ListTrash)
 
 		// Query endpoints
 		r.Get("/query/filter", apiServer.QueryFilter)
 		r.Get("/query/search", apiServer.QuerySearch)
 		r.Get("/query/traverse", apiServer.QueryTraverse)
 		r.Get("/query/subgraph", apiServer.QuerySubgraph)
+		r.Get("/query/subgraph/export", apiServer.ExportSubgraph)
 		r.Get("/query/attention_subgraph", apiServer.QueryAttentionSubgraph)
 		r.Get("/query/by_lens", apiServer.QueryByLens)
+		r.Get("/query/timerange", apiServer.QueryTimeRange)
+		r.Post("/query/pattern", apiServer.QueryPattern)
+		r.Get("/query/aggregate", apiServer.QueryAggregate)
+		r.Get("/query/trend", apiServer.QueryTrend)
+
+		// Ranking signals combined with caller weights
+		r.Post("/score", apiServer.Score)
+
+		// Saved queries
+		r.Post("/queries", apiServer.CreateSavedQuery)
+		r.Get("/queries", apiServer.ListSavedQueries)
+		r.Get("/queries/{id}", apiServer.GetSavedQuery)
+		r.Put("/queries/{id}", apiServer.UpdateSavedQuery)
+		r.Delete("/queries/{id}", apiServer.DeleteSavedQuery)
+		r.Post("/queries/{id}/run", apiServer.RunSavedQuery)
+
+		// Automatic linking rules
+		r.Post("/rules", apiServer.CreateRule)
+		r.Get("/rules", apiServer.ListRules)
+		r.Post("/rules/test", apiServer.TestRule)
+		r.Get("/rules/{id}", apiServer.GetRule)
+		r.Put("/rules/{id}", apiServer.UpdateRule)
+		r.Delete("/rules/{id}", apiServer.DeleteRule)
+
+		// JSON type inference rules
+		r.Post("/type-rules", apiServer.CreateTypeRule)
+		r.Get("/type-rules", apiServer.ListTypeRules)
+		r.Post("/type-rules/test", apiServer.TestTypeRules)
+		r.Post("/type-rules/apply", apiServer.ApplyTypeRules)
+		r.Get("/type-rules/{id}", apiServer.GetTypeRule)
+		r.Put("/type-rules/{id}", apiServer.UpdateTypeRule)
+		r.Delete("/type-rules/{id}", apiServer.DeleteTypeRule)
+
+		// Synonym groups searches expand with, global or per namespace
+		r.Get("/synonyms", apiServer.ListSynonyms)
+		r.Put("/synonyms", apiServer.PutSynonyms)
+		r.Post("/synonyms", apiServer.AddSynonyms)
+		r.Delete("/synonyms", apiServer.DeleteSynonyms)
+
+		// Node type definitions and the visualization hints graph views carry
+		r.Get("/ontology/types", apiServer.ListTypeDefs)
+		r.Get("/ontology/types/{type}", apiServer.GetTypeDef)
+		r.Put("/ontology/types/{type}", apiServer.PutTypeDef)
+		r.Delete("/ontology/types/{type}", apiServer.DeleteTypeDef)
+		r.Get("/ontology/link-types", apiServer.ListLinkTypeDefs)
+		r.Get("/ontology/link-types/{type}", apiServer.GetLinkTypeDef)
+		r.Put("/ontology/link-types/{type}", apiServer.PutLinkTypeDef)
+		r.Delete("/ontology/link-types/{type}", apiServer.DeleteLinkTypeDef)
+		r.Get("/ontology/link-violations", apiServer.LinkViolations)
+
+		// Server-side processors: list, switch off per namespace, run commands
+		r.Get("/modules", apiServer.ListModules)
+		r.Get("/modules/{name}", apiServer.GetModule)
+		r.Post("/modules/{name}/enable", apiServer.EnableModule)
+		r.Post("/modules/{name}/disable", apiServer.DisableModule)
+		r.Post("/modules/{name}/commands/{command}", apiServer.RunModuleCommand)
+
+		// Connectors: scheduled sources, their status and on-demand runs
+		r.Get("/connectors", apiServer.ListConnectors)
+		r.Post("/connectors", apiServer.CreateConnector)
+		r.Get("/connectors/{name}", apiServer.GetConnector)
+		r.Put("/connectors/{name}", apiServer.UpdateConnector)
+		r.Delete("/connectors/{name}", apiServer.DeleteConnector)
+		r.Post("/connectors/{name}/run", apiServer.RunConnector)
+
+		// Sandboxes: copy-on-write overlays merged back or discarded
+		r.Post("/sandboxes", apiServer.CreateSandbox)
+		r.Get("/sandboxes", apiServer.ListSandboxes)
+		r.Route("/sandboxes/{sandbox}", func(r chi.Router) {
+			r.Get("/", apiServer.GetSandbox)
+			r.Delete("/", apiServer.DiscardSandbox)
+			r.Get("/changes", apiServer.GetSandboxChanges)
+			r.Post("/merge", apiServer.MergeSandbox)
+
+			r.Post("/nodes", apiServer.InSandbox((*api.Server).CreateNode))
+			r.Post("/nodes/bulk", apiServer.InSandbox((*api.Server).BulkCreateNodes))
+			r.Get("/nodes", apiServer.InSandbox((*api.Server).ListNodes))
+			r.Post("/nodes/batch-get", apiServer.InSandbox((*api.Server).BatchGetNodes))
+			r.Get("/nodes/{id}", apiServer.InSandbox((*api.Server).GetNode))
+			r.Patch("/nodes/{id}", apiServer.InSandbox((*api.Server).UpdateNode))
+			r.Delete("/nodes/{id}", apiServer.InSandbox((*api.Server).DeleteNode))
+			r.Get("/nodes/{id}/links", apiServer.InSandbox((*api.Server).GetLinks))
+			r.Get("/nodes/{id}/backlinks", apiServer.InSandbox((*api.Server).GetBacklinks))
+			r.Post("/links", apiServer.InSandbox((*api.Server).CreateLink))
+			r.Post("/links/bulk", apiServer.InSandbox((*api.Server).BulkCreateLinks))
+			r.Delete("/links", apiServer.InSandbox((*api.Server).DeleteLink))
+			r.Get("/query/filter", apiServer.InSandbox((*api.Server).QueryFilter))
+			r.Get("/query/search", apiServer.InSandbox((*api.Server).QuerySearch))
+			r.Get("/query/traverse", apiServer.InSandbox((*api.Server).QueryTraverse))
+			r.Get("/query/subgraph", apiServer.InSandbox((*api.Server).QuerySubgraph))
+			r.Get("/query/timerange", apiServer.InSandbox((*api.Server).QueryTimeRange))
+		})
 
 		// Graph exploration
 		r.Get("/graph/map", apiServer.GraphMap)
 		r.Get("/graph/export", apiServer.ExportLens)
+		r.Get("/graph/timeline", apiServer.GraphTimeline)
+		r.Get("/graph/diff", apiServer.GraphDiff)
+		r.Get("/graph/diff/view", apiServer.GraphDiffView)
+		r.Get("/graph/dag", apiServer.CheckDAG)
+		r.Get("/sync/v1", apiServer.SyncInfo)
+		r.Get("/sync/v1/changes", apiServer.SyncChanges)
+		r.Post("/sync/v1/push", apiServer.SyncPush)
+		r.Get("/sync/v1/content/{id}", apiServer.SyncContent)
+		r.Get("/graph/health", apiServer.GraphHealth)
+		r.Get("/graph/integrity", apiServer.CheckIntegrity)
+		r.Post("/graph/integrity/cleanup", apiServer.EnqueueIntegrityCleanup)
+		r.Get("/graph/integrity/cleanup/{id}", apiServer.GetIntegrityCleanup)
+		r.Get("/graph/view", apiServer.GraphView)
+		r.Get("/graph/layers", apiServer.LayerStats)
+		r.Get("/citations/top", apiServer.MostCited)
+		r.Get("/citations/graph", apiServer.CitationView)
+
+		// Links table for offline analysis (csv, parquet)
+		r.Get("/export/links", apiServer.ExportLinks)
+
+		// Registered read-only analytics SQL
+		r.Get("/analytics", apiServer.ListAnalytics)
+		r.Get("/analytics/{name}", apiServer.RunAnalytics)
+
+		// Portable backup bundles of the graph and its environment
+		r.Get("/export/bundle", apiServer.ExportBundle)
+		r.Post("/import/bundle", apiServer.ImportBundle)
+		r.Post("/import/federated", apiServer.ImportFederated)
+		r.Get("/import/federated/{source}", apiServer.GetFederatedMappings)
+
+		// Snapshot export (graphml, gexf, dot, jsonld, turtle, csv) and tabular import
+		r.Get("/export/{format}", apiServer.ExportGraph)
+		r.Post("/import/csv", apiServer.ImportCSV)
+		r.Post("/import/bibtex", apiServer.ImportBibTeX)
+		r.Post("/import/zotero", apiServer.ImportZotero)
+		r.Post("/import/notion", apiServer.ImportNotion)
+		r.Post("/import/confluence", apiServer.ImportConfluence)
+		r.Post("/import/vcard", apiServer.ImportVCard)
+		r.Post("/import/carddav", apiServer.ImportCardDAV)
+		r.Post("/import/github", apiServer.ImportGitHub)
+		r.Get("/github/sync", apiServer.ListGitHubSyncs)
+		r.Post("/github/webhook", apiServer.GitHubWebhook)
+		r.Post("/import/tickets", apiServer.ImportTickets)
+		r.Get("/tickets/sync", apiServer.ListTicketSyncs)
+		r.Get("/people/resolve", apiServer.ResolvePeople)
+		r.Get("/autocomplete", apiServer.Autocomplete)
+		r.Post("/feedback", apiServer.Feedback)
+		r.Get("/feedback/stats", apiServer.FeedbackStats)
+		r.Get("/memory", apiServer.GetMemory)
+		r.Get("/memory/cards", apiServer.ListMemoryCards)
+		r.Post("/memory/cards", apiServer.AddMemoryCard)
+		r.Get("/memory/cards/{id}", apiServer.GetMemoryCard)
+		r.Delete("/memory/cards/{id}", apiServer.DeleteMemoryCard)
+		r.Post("/memory/cards/{id}/pin", apiServer.PinMemoryCard)
+		r.Post("/memory/cards/{id}/unpin", apiServer.UnpinMemoryCard)
+		r.Post("/memory/cards/{id}/expire", apiServer.ExpireMemoryCard)
+		r.Post("/memory/cards/{id}/refresh", apiServer.RefreshMemoryCard)
+
+		// Sessions: per-conversation working memory for agents
+		r.Post("/sessions", apiServer.CreateSession)
+		r.Get("/sessions", apiServer.ListSessions)
+		r.Get("/sessions/{id}", apiServer.GetSession)
+		r.Delete("/sessions/{id}", apiServer.DiscardSession)
+		r.Get("/sessions/{id}/context", apiServer.GetSessionContext)
+		r.Post("/sessions/{id}/nodes", apiServer.RecordSessionNodes)
+		r.Post("/sessions/{id}/notes", apiServer.AddSessionEntry)
+		r.Post("/sessions/{id}/close", apiServer.CloseSession)
+
+		// Snapshots: pinned read views for multi-call agent flows
+		r.Post("/snapshots", apiServer.CreateSnapshot)
+		r.Get("/snapshots", apiServer.ListSnapshots)
+		r.Get("/snapshots/{token}", apiServer.GetSnapshot)
+		r.Delete("/snapshots/{token}", apiServer.DeleteSnapshot)
+		r.Get("/tasks", apiServer.ListTasks)
+		r.Patch("/tasks/{id}/status", apiServer.SetTaskStatus)
+		r.Get("/tasks/{id}/history", apiServer.TaskHistory)
+		r.Get("/projects", apiServer.ListProjects)
+		r.Get("/projects/{id}/overview", apiServer.ProjectOverview)
+		r.Post("/projects/{id}/members", apiServer.AddProjectMembers)
+		r.Delete("/projects/{id}/members/{node}", apiServer.RemoveProjectMember)
+		r.Get("/projects/{id}/burndown", apiServer.ProjectBurndown)
+
+		// Review workflow for extracted entities
+		r.Get("/review/queue", apiServer.ReviewQueue)
+		r.Post("/review/{id}/{decision}", apiServer.ReviewDecide)
+		r.Get("/conflicts", apiServer.ListConflicts)
+		r.Post("/conflicts/scan", apiServer.ScanConflicts)
 
 		// Attention edge endpoints
 		r.Post("/edges/attention", apiServer.UpdateAttentionEdge)
 		r.Post("/edges/attention/prune", apiServer.PruneAttentionEdges)
+		r.Get("/edges/attention/explain", apiServer.ExplainAttentionEdge)
+		r.Get("/edges/attention/store", apiServer.AttentionStoreStats)
+		r.Post("/edges/attention/consolidate", apiServer.ConsolidateAttention)
+		r.Get("/edges/attention/export", apiServer.ExportAttention)
+		r.Post("/edges/attention/import", apiServer.ImportAttention)
+
+		// Structural embedding endpoints
+		r.Post("/embeddings/train", apiServer.TrainEmbeddings)
+		r.Get("/embeddings/status", apiServer.EmbeddingStatus)
+		r.Get("/embeddings/export", apiServer.ExportEmbeddings)
 
 		// Lens endpoints
 		r.Post("/lenses", apiServer.CreateLens)
@@ -129,40 +1246,143 @@ func main() {
 		r.Get("/subscriptions/{id}", apiServer.GetSubscription)
 		r.Patch("/subscriptions/{id}", apiServer.UpdateSubscription)
 		r.Delete("/subscriptions/{id}", apiServer.DeleteSubscription)
+		r.Post("/subscriptions/{id}/replay", apiServer.ReplaySubscription)
+		r.Get("/subscriptions/dead-letters", apiServer.ListDeadLetters)
+		r.Post("/subscriptions/dead-letters/{id}/retry", apiServer.RetryDeadLetter)
+		r.Delete("/subscriptions/dead-letters/{id}", apiServer.DeleteDeadLetter)
+
+		// Live event stream (Server-Sent Events)
+		r.Get("/events/stream", apiServer.StreamEvents)
+
+		// Admin endpoints
+		r.Get("/admin/usage", apiServer.GetUsage)
+		r.Get("/admin/deprecations", apiServer.Deprecations)
+		r.Get("/admin/growth", apiServer.GetGrowth)
+		r.Get("/admin/ingest/anomalies", apiServer.IngestAnomalies)
+
+		// Near-duplicate review
+		r.Get("/duplicates", apiServer.ListDuplicates)
+		r.Post("/duplicates/scan", apiServer.ScanDuplicates)
+		r.Post("/duplicates/dismiss", apiServer.DismissDuplicate)
+		r.Post("/duplicates/merge", apiServer.MergeDuplicate)
+		r.Post("/admin/hooks", apiServer.CreateHook)
+		r.Get("/admin/hooks", apiServer.ListHooks)
+		r.Get("/admin/hooks/{id}", apiServer.GetHook)
+		r.Put("/admin/hooks/{id}", apiServer.UpdateHook)
+		r.Delete("/admin/hooks/{id}", apiServer.DeleteHook)
+		r.Post("/admin/hooks/{id}/rotate", apiServer.RotateHookToken)
+		r.Post("/admin/hooks/{id}/test", apiServer.TestHook)
+		r.Post("/admin/finalizers", apiServer.CreateFinalizer)
+		r.Get("/admin/finalizers", apiServer.ListFinalizers)
+		r.Get("/admin/finalizers/failures", apiServer.ListFinalizerFailures)
+		r.Post("/admin/finalizers/failures/{id}/retry", apiServer.RetryFinalizerFailure)
+		r.Delete("/admin/finalizers/failures/{id}", apiServer.DeleteFinalizerFailure)
+		r.Get("/admin/finalizers/{id}", apiServer.GetFinalizer)
+		r.Delete("/admin/finalizers/{id}", apiServer.DeleteFinalizer)
+		r.Get("/admin/diagnostics", apiServer.GetDiagnostics)
+		r.Get("/admin/quarantine", apiServer.ListQuarantine)
+		r.Post("/admin/quarantine/check", apiServer.CheckQuarantine)
+		r.Get("/admin/quarantine/{id}", apiServer.GetQuarantine)
+		r.Post("/admin/quarantine/{id}/repair", apiServer.RepairQuarantine)
+		r.Post("/admin/recompute-degrees", apiServer.RecomputeDegrees)
+		r.Post("/admin/short-ids", apiServer.AssignShortIDs)
+		r.Get("/admin/slow-queries", apiServer.ListSlowQueries)
+		r.Delete("/admin/slow-queries", apiServer.ClearSlowQueries)
+		r.Get("/admin/cache", apiServer.QueryCacheStats)
+		r.Delete("/admin/cache", apiServer.BustQueryCache)
+		r.Get("/admin/lanes", apiServer.GetLanes)
+		r.Get("/admin/faults", apiServer.GetFaults)
+		r.Put("/admin/faults", apiServer.ConfigureFaults)
+		r.Delete("/admin/faults", apiServer.ClearFaults)
+		r.Post("/admin/erase", apiServer.EraseSubject)
+		r.Get("/admin/experiments", apiServer.ListExperiments)
+		r.Post("/admin/experiments", apiServer.CreateExperiment)
+		r.Post("/admin/experiments/strategies", apiServer.AddRankingStrategy)
+		r.Get("/admin/experiments/{id}", apiServer.GetExperiment)
+		r.Post("/admin/experiments/{id}/stop", apiServer.StopExperiment)
+		r.Delete("/admin/experiments/{id}", apiServer.DeleteExperiment)
+
+		// Public read-only share links
+		r.Post("/shares", apiServer.CreateShare)
 	})
+	for _, v := range apiversion.Supported {
+		r.Mount("/api/v"+strconv.Itoa(v), api.PathVersion(v, apiRouter))
+	}
+
+	// Mount under a base path (e.g. /memex) when serving behind a shared domain
+	var handler http.Handler = r
+	if basePath != "" {
+		root := chi.NewRouter()
+		root.Mount(basePath, r)
+		handler = root
+		log.Printf("Serving under base path %s", basePath)
+	}
+
+	if tracingEnabled {
+		handler = tracing.Middleware(handler)
+	}
 
 	// HTTP server
 	srv := &http.Server{
 		Addr:         ":" + port,
-		Handler:      r,
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Optional TLS / mTLS
+	tlsCfg := loadTLSSettings()
+	if tlsCfg.enabled() {
+		srv.TLSConfig, err = tlsCfg.serverConfig()
+		if err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
+		}
+	}
+
+	shutdownTimeout, err := time.ParseDuration(getEnv("MEMEX_SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil {
+		log.Fatalf("Invalid MEMEX_SHUTDOWN_TIMEOUT: %v", err)
+	}
+	ln, err := listen(srv.Addr, *reusePort)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
 	// Start server in goroutine
 	go func() {
-		log.Printf("Starting memex server on http://localhost:%s", port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if srv.TLSConfig != nil {
+			log.Printf("Starting memex server on https://localhost:%s (client certs: %v)", port, srv.TLSConfig.ClientCAs != nil)
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			log.Printf("Starting memex server on http://localhost:%s", port)
+			err = srv.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed: %v", err)
 		}
 	}()
 
-	// Graceful shutdown
+	// Graceful shutdown: stop accepting connections and let in-flight
+	// requests finish, then (in the deferred calls above) run queued jobs,
+	// deliver queued events and webhooks, flush stores and checkpoint the
+	// database
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	log.Printf("Shutting down server, draining requests for up to %s...", shutdownTimeout)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	drainCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Printf("Warning: requests still running after %s, closing their connections: %v", shutdownTimeout, err)
+		srv.Close()
 	}
-
-	log.Println("Server exited")
+	apiServer.FinishJobs()
+	log.Println("Requests drained, stopping background work...")
 }
 
 func getEnv(key, defaultValue string) string {
@@ -171,3 +1391,27 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// parseKeyAuthors parses "key=name,key=name" API key author mappings
+func parseKeyAuthors(spec string) (map[string]string, error) {
+	authors := make(map[string]string)
+	for _, item := range splitList(spec) {
+		key, name, ok := strings.Cut(item, "=")
+		if key, name = strings.TrimSpace(key), strings.TrimSpace(name); !ok || key == "" || name == "" {
+			return nil, fmt.Errorf("expected key=name, got %q", item)
+		}
+		authors[key] = name
+	}
+	return authors, nil
+}
+
+// splitList splits a comma-separated config value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
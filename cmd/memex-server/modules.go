@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/systemshift/memex/internal/server/autocomplete"
+	"github.com/systemshift/memex/internal/server/citations"
+	"github.com/systemshift/memex/internal/server/dedup"
+	"github.com/systemshift/memex/internal/server/infer"
+	"github.com/systemshift/memex/internal/server/ingest"
+	"github.com/systemshift/memex/internal/server/memory"
+	"github.com/systemshift/memex/internal/server/modules"
+	"github.com/systemshift/memex/internal/server/rules"
+	"github.com/systemshift/memex/internal/server/tasks"
+	"github.com/systemshift/memex/internal/server/vision"
+)
+
+// processors are the optional event-driven components; nil ones are off
+type processors struct {
+	ingest       *ingest.Tracker
+	vision       *vision.Processor
+	citations    *citations.Processor
+	tasks        *tasks.Processor
+	types        *infer.Processor
+	rules        *rules.Engine
+	autocomplete *autocomplete.Index
+	memory       *memory.Store
+	dedup        *dedup.Detector
+}
+
+// register adds the configured processors to reg as modules. The ingest
+// tracker comes first so processors can hold the nodes it tracks.
+func (p processors) register(reg *modules.Registry) {
+	if p.ingest != nil {
+		reg.Register(&modules.Module{
+			Name:        "ingest_webhook",
+			Description: "Fires a webhook once a source and its derived nodes finish processing",
+			Handle:      p.ingest.EmitEvent,
+			Commands: map[string]modules.Command{
+				"complete": {
+					Description: "Fire the webhook for source_id now",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						id, err := modules.StringArg(args, "source_id")
+						if err != nil {
+							return nil, err
+						}
+						if !p.ingest.Complete(id) {
+							return nil, fmt.Errorf("%w: source %s is not being tracked", modules.ErrInvalidArgs, id)
+						}
+						return map[string]string{"completed": id}, nil
+					},
+				},
+			},
+		})
+	}
+	if p.vision != nil {
+		reg.Register(&modules.Module{
+			Name:        "vision",
+			Description: "Captions new images with a vision model",
+			Handle:      p.vision.EmitEvent,
+			Commands: map[string]modules.Command{
+				"process": {
+					Description: "Caption node_id now",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						id, err := modules.StringArg(args, "node_id")
+						if err != nil {
+							return nil, err
+						}
+						if err := p.vision.ProcessNode(ctx, id); err != nil {
+							return nil, err
+						}
+						return map[string]string{"processed": id}, nil
+					},
+				},
+			},
+		})
+	}
+	if p.citations != nil {
+		reg.Register(&modules.Module{
+			Name:        "citations",
+			Description: "Parses the references of new papers into citation links",
+			Handle:      p.citations.EmitEvent,
+			Commands: map[string]modules.Command{
+				"process": {
+					Description: "Parse the references of node_id now; force re-parses",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						id, force, err := nodeArgs(args)
+						if err != nil {
+							return nil, err
+						}
+						return p.citations.ProcessNode(ctx, id, force)
+					},
+				},
+			},
+		})
+	}
+	if p.tasks != nil {
+		reg.Register(&modules.Module{
+			Name:        "tasks",
+			Description: "Extracts tasks from new notes, transcripts and emails",
+			Handle:      p.tasks.EmitEvent,
+			Commands: map[string]modules.Command{
+				"process": {
+					Description: "Extract tasks from node_id now; force re-extracts",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						id, force, err := nodeArgs(args)
+						if err != nil {
+							return nil, err
+						}
+						return p.tasks.ProcessNode(ctx, id, force)
+					},
+				},
+			},
+		})
+	}
+	if p.types != nil {
+		reg.Register(&modules.Module{
+			Name:        "json_types",
+			Description: "Types new JSON sources into nodes and relations by the stored type rules",
+			Handle:      p.types.EmitEvent,
+			Commands: map[string]modules.Command{
+				"process": {
+					Description: "Type the JSON source node_id now; force types it again",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						id, force, err := nodeArgs(args)
+						if err != nil {
+							return nil, err
+						}
+						return p.types.ProcessNode(ctx, id, force)
+					},
+				},
+			},
+		})
+	}
+	if p.rules != nil {
+		reg.Register(&modules.Module{
+			Name:        "rules",
+			Description: "Links nodes by the stored link rules as they are created and updated",
+			Handle:      p.rules.EmitEvent,
+			Commands: map[string]modules.Command{
+				"reload": {
+					Description: "Read the link rules from the graph again",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						if err := p.rules.Reload(ctx); err != nil {
+							return nil, err
+						}
+						return map[string]int{"rules": len(p.rules.Rules())}, nil
+					},
+				},
+			},
+		})
+	}
+	if p.autocomplete != nil {
+		reg.Register(&modules.Module{
+			Name:        "autocomplete",
+			Description: "Keeps the prefix index behind GET /api/autocomplete up to date",
+			Handle:      p.autocomplete.EmitEvent,
+			Commands: map[string]modules.Command{
+				"rebuild": {
+					Description: "Rebuild the index from every node",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						p.autocomplete.Rebuild()
+						return map[string]bool{"scheduled": true}, nil
+					},
+				},
+				"status": {
+					Description: "Report whether the index is built and its size",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						return map[string]interface{}{"ready": p.autocomplete.Ready(), "nodes": p.autocomplete.Len()}, nil
+					},
+				},
+			},
+		})
+	}
+	if p.memory != nil {
+		reg.Register(&modules.Module{
+			Name:        "memory",
+			Description: "Marks memory cards stale when their nodes change",
+			Handle:      p.memory.EmitEvent,
+			Commands: map[string]modules.Command{
+				"refresh": {
+					Description: "Distill the card for node_id again",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						id, err := modules.StringArg(args, "node_id")
+						if err != nil {
+							return nil, err
+						}
+						return p.memory.Refresh(ctx, id)
+					},
+				},
+				"reload": {
+					Description: "Read the stored cards from the graph again",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						if err := p.memory.Load(ctx); err != nil {
+							return nil, err
+						}
+						return map[string]int{"cards": len(p.memory.List(true))}, nil
+					},
+				},
+			},
+		})
+	}
+	if p.dedup != nil {
+		reg.Register(&modules.Module{
+			Name:        "dedup",
+			Description: "Flags nodes whose text nearly matches an earlier node's",
+			Handle:      p.dedup.EmitEvent,
+			Commands: map[string]modules.Command{
+				"scan": {
+					Description: "Compare every node and flag the near-duplicates not yet flagged",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						flagged, err := p.dedup.Scan(ctx)
+						if err != nil {
+							return nil, err
+						}
+						return map[string]int{"flagged": flagged}, nil
+					},
+				},
+				"status": {
+					Description: "Report whether the index is built, its size and the pending pairs",
+					Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+						return map[string]interface{}{
+							"ready":   p.dedup.Ready(),
+							"nodes":   p.dedup.Len(),
+							"pending": len(p.dedup.Pairs(dedup.StatusPending)),
+						}, nil
+					},
+				},
+			},
+		})
+	}
+}
+
+// nodeArgs reads the node_id and force arguments of a processing command
+func nodeArgs(args map[string]interface{}) (string, bool, error) {
+	id, err := modules.StringArg(args, "node_id")
+	if err != nil {
+		return "", false, err
+	}
+	force, err := modules.BoolArg(args, "force")
+	return id, force, err
+}
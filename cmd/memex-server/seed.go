@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/seed"
+)
+
+// runSeed implements `memex-server seed`: populate the configured backend
+// with a synthetic graph and exit
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	profile := fs.String("profile", "demo", "seed profile (small, demo, bench)")
+	seedValue := fs.Int64("seed", 1, "random seed; the same seed always yields the same graph")
+	fs.Parse(args)
+
+	p, err := seed.Lookup(*profile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	repo, err := openRepository(ctx, getEnv("MEMEX_BACKEND", "sqlite"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer repo.Close(ctx)
+
+	res, err := seed.Load(ctx, repo, p, *seedValue)
+	if err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+	fmt.Fprintf(os.Stdout, "Seeded profile %q (seed %d): %d nodes, %d links, %d attention updates\n",
+		res.Profile, res.Seed, res.Nodes, res.Links, res.AttentionEdges)
+}
+
+// seedIfEmpty loads MEMEX_SEED_PROFILE into an empty graph at startup, which
+// gives memory-backed sandboxes reproducible data
+func seedIfEmpty(ctx context.Context, repo graph.Repository) {
+	name := getEnv("MEMEX_SEED_PROFILE", "")
+	if name == "" {
+		return
+	}
+	p, err := seed.Lookup(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		log.Fatalf("Failed to check graph before seeding: %v", err)
+	}
+	if len(ids) > 0 {
+		log.Printf("Graph is not empty; skipping seed profile %q", name)
+		return
+	}
+
+	seedValue, _ := strconv.ParseInt(getEnv("MEMEX_SEED", "1"), 10, 64)
+	res, err := seed.Load(ctx, repo, p, seedValue)
+	if err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+	log.Printf("Seeded profile %q: %d nodes, %d links", res.Profile, res.Nodes, res.Links)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// serviceEnvPrefixes select the environment captured into service files
+var serviceEnvPrefixes = []string{"MEMEX_", "NEO4J_", "SQLITE_", "POSTGRES_", "OTEL_"}
+
+// serviceEnvKeys are individual variables captured into service files
+var serviceEnvKeys = map[string]bool{"PORT": true, "DATABASE_URL": true, "OPENAI_API_KEY": true}
+
+// serviceSpec describes the service to install
+type serviceSpec struct {
+	Name    string
+	Exec    string
+	WorkDir string
+	Env     []string // KEY=value, sorted
+	System  bool     // System-wide rather than per-user
+}
+
+// runService implements `memex-server install-service|service-status|uninstall-service`
+func runService(action string, args []string) {
+	fs := flag.NewFlagSet(action, flag.ExitOnError)
+	name := fs.String("name", "memex-server", "service name")
+	system := fs.Bool("system", false, "manage a system-wide service instead of a per-user one started at login")
+	dryRun := fs.Bool("dry-run", false, "print the service file and commands without changing anything")
+	fs.Parse(args)
+
+	spec := serviceSpec{Name: *name, System: *system}
+	path, err := servicePath(runtime.GOOS, spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch action {
+	case "install-service":
+		if spec.Exec, err = os.Executable(); err != nil {
+			log.Fatalf("Failed to locate executable: %v", err)
+		}
+		if spec.Exec, err = filepath.EvalSymlinks(spec.Exec); err != nil {
+			log.Fatalf("Failed to resolve executable: %v", err)
+		}
+		// Relative paths such as the default SQLITE_PATH resolve against this
+		if spec.WorkDir, err = os.Getwd(); err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+		spec.Env = serviceEnv(os.Environ())
+
+		content := renderSystemdUnit(spec)
+		if runtime.GOOS == "darwin" {
+			content = renderLaunchdPlist(spec)
+		}
+		cmds := serviceCommands(runtime.GOOS, action, spec, path)
+		if *dryRun {
+			fmt.Printf("# %s\n%s\n", path, content)
+			printCommands(cmds)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		// The captured environment may hold credentials
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			log.Fatalf("Failed to write service file: %v", err)
+		}
+		fmt.Printf("Wrote %s (%d environment variables captured)\n", path, len(spec.Env))
+		if err := runCommands(cmds); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Service %s installed and started\n", spec.Name)
+
+	case "service-status":
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("Service %s is not installed (%s)\n", spec.Name, path)
+			os.Exit(1)
+		}
+		fmt.Printf("Service file: %s\n", path)
+		if err := runCommands(serviceCommands(runtime.GOOS, action, spec, path)); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.ExitCode())
+			}
+			log.Fatal(err)
+		}
+
+	case "uninstall-service":
+		cmds := serviceCommands(runtime.GOOS, action, spec, path)
+		if *dryRun {
+			printCommands(cmds)
+			return
+		}
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("Service %s is not installed (%s)\n", spec.Name, path)
+			return
+		}
+		if err := runCommands(cmds); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		if err := os.Remove(path); err != nil {
+			log.Fatalf("Failed to remove service file: %v", err)
+		}
+		if runtime.GOOS == "linux" {
+			runCommands([][]string{systemctl(spec, "daemon-reload")})
+		}
+		fmt.Printf("Service %s uninstalled\n", spec.Name)
+	}
+}
+
+// serviceEnv keeps the server's configuration variables from environ
+func serviceEnv(environ []string) []string {
+	var env []string
+	for _, kv := range environ {
+		key, _, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		keep := serviceEnvKeys[key]
+		for _, prefix := range serviceEnvPrefixes {
+			keep = keep || strings.HasPrefix(key, prefix)
+		}
+		if keep {
+			env = append(env, kv)
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+// launchdLabel is the launchd job label for a service
+func launchdLabel(name string) string {
+	return "com.systemshift." + name
+}
+
+// servicePath returns where the service file for goos is installed
+func servicePath(goos string, spec serviceSpec) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil && !spec.System {
+		return "", err
+	}
+	switch goos {
+	case "linux":
+		if spec.System {
+			return filepath.Join("/etc/systemd/system", spec.Name+".service"), nil
+		}
+		return filepath.Join(home, ".config/systemd/user", spec.Name+".service"), nil
+	case "darwin":
+		if spec.System {
+			return filepath.Join("/Library/LaunchDaemons", launchdLabel(spec.Name)+".plist"), nil
+		}
+		return filepath.Join(home, "Library/LaunchAgents", launchdLabel(spec.Name)+".plist"), nil
+	default:
+		return "", fmt.Errorf("service management is supported on Linux (systemd) and macOS (launchd), not %s", goos)
+	}
+}
+
+// systemctl builds a systemctl command for the spec's scope
+func systemctl(spec serviceSpec, args ...string) []string {
+	cmd := []string{"systemctl"}
+	if !spec.System {
+		cmd = append(cmd, "--user")
+	}
+	return append(cmd, args...)
+}
+
+// serviceCommands lists the commands that carry out an action
+func serviceCommands(goos, action string, spec serviceSpec, path string) [][]string {
+	unit := spec.Name + ".service"
+	if goos == "darwin" {
+		switch action {
+		case "install-service":
+			return [][]string{{"launchctl", "load", "-w", path}}
+		case "service-status":
+			return [][]string{{"launchctl", "list", launchdLabel(spec.Name)}}
+		default:
+			return [][]string{{"launchctl", "unload", "-w", path}}
+		}
+	}
+	switch action {
+	case "install-service":
+		return [][]string{systemctl(spec, "daemon-reload"), systemctl(spec, "enable", "--now", unit)}
+	case "service-status":
+		return [][]string{systemctl(spec, "status", "--no-pager", unit)}
+	default:
+		return [][]string{systemctl(spec, "disable", "--now", unit)}
+	}
+}
+
+// runCommands runs each command in turn, stopping at the first failure
+func runCommands(cmds [][]string) error {
+	for _, args := range cmds {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+		}
+	}
+	return nil
+}
+
+// printCommands shows the commands a dry run would execute
+func printCommands(cmds [][]string) {
+	for _, args := range cmds {
+		fmt.Printf("$ %s\n", strings.Join(args, " "))
+	}
+}
+
+// renderSystemdUnit renders a systemd service unit
+func renderSystemdUnit(spec serviceSpec) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=Memex knowledge graph server\n")
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("Wants=network-online.target\n\n")
+	b.WriteString("[Service]\n")
+	// ExecStart also expands $VARIABLES; WorkingDirectory takes a bare path
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.ReplaceAll(systemdQuote(spec.Exec), "$", "$$"))
+	fmt.Fprintf(&b, "WorkingDirectory=%s\n", strings.ReplaceAll(spec.WorkDir, "%", "%%"))
+	for _, kv := range spec.Env {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(kv))
+	}
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=5\n\n")
+	b.WriteString("[Install]\n")
+	if spec.System {
+		b.WriteString("WantedBy=multi-user.target\n")
+	} else {
+		b.WriteString("WantedBy=default.target\n")
+	}
+	return b.String()
+}
+
+// systemdQuote double-quotes a value and escapes % specifiers
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(s)
+	return `"` + s + `"`
+}
+
+// renderLaunchdPlist renders a launchd job definition
+func renderLaunchdPlist(spec serviceSpec) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", xmlEscape(launchdLabel(spec.Name)))
+	fmt.Fprintf(&b, "\t<key>ProgramArguments</key>\n\t<array>\n\t\t<string>%s</string>\n\t</array>\n", xmlEscape(spec.Exec))
+	fmt.Fprintf(&b, "\t<key>WorkingDirectory</key>\n\t<string>%s</string>\n", xmlEscape(spec.WorkDir))
+	if len(spec.Env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, kv := range spec.Env {
+			key, value, _ := strings.Cut(kv, "=")
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", xmlEscape(key), xmlEscape(value))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<true/>\n")
+	logPath := filepath.Join(spec.WorkDir, spec.Name+".log")
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t<string>%s</string>\n", xmlEscape(logPath))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", xmlEscape(logPath))
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// xmlEscape escapes text for a plist string
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
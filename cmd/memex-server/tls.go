@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsSettings holds TLS configuration read from the environment
+type tlsSettings struct {
+	certFile   string
+	keyFile    string
+	clientCA   string // PEM bundle used to verify client certificates (mTLS)
+	clientAuth string // "require" (default when clientCA is set) or "optional"
+
+	autocertDomains []string // Obtain certificates from Let's Encrypt for these hosts
+	autocertCache   string
+	autocertEmail   string
+}
+
+// loadTLSSettings reads TLS settings from MEMEX_TLS_* environment variables
+func loadTLSSettings() tlsSettings {
+	return tlsSettings{
+		certFile:        getEnv("MEMEX_TLS_CERT", ""),
+		keyFile:         getEnv("MEMEX_TLS_KEY", ""),
+		clientCA:        getEnv("MEMEX_TLS_CLIENT_CA", ""),
+		clientAuth:      getEnv("MEMEX_TLS_CLIENT_AUTH", "require"),
+		autocertDomains: splitList(getEnv("MEMEX_TLS_AUTOCERT_DOMAINS", "")),
+		autocertCache:   getEnv("MEMEX_TLS_AUTOCERT_CACHE", "./certs"),
+		autocertEmail:   getEnv("MEMEX_TLS_AUTOCERT_EMAIL", ""),
+	}
+}
+
+// enabled reports whether the server should serve HTTPS
+func (t tlsSettings) enabled() bool {
+	return (t.certFile != "" && t.keyFile != "") || len(t.autocertDomains) > 0
+}
+
+// serverConfig builds the tls.Config for the HTTP server
+func (t tlsSettings) serverConfig() (*tls.Config, error) {
+	if (t.certFile == "") != (t.keyFile == "") {
+		return nil, fmt.Errorf("MEMEX_TLS_CERT and MEMEX_TLS_KEY must be set together")
+	}
+
+	var cfg *tls.Config
+	if len(t.autocertDomains) > 0 {
+		if t.certFile != "" {
+			return nil, fmt.Errorf("use either MEMEX_TLS_CERT/KEY or MEMEX_TLS_AUTOCERT_DOMAINS, not both")
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(t.autocertDomains...),
+			Cache:      autocert.DirCache(t.autocertCache),
+			Email:      t.autocertEmail,
+		}
+		// Handles TLS-ALPN-01 challenges on the same listener
+		cfg = m.TLSConfig()
+	} else {
+		cert, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS certificate: %w", err)
+		}
+		cfg = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	cfg.MinVersion = tls.VersionTLS12
+
+	if t.clientCA != "" {
+		pem, err := os.ReadFile(t.clientCA)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA %s", t.clientCA)
+		}
+		cfg.ClientCAs = pool
+
+		switch t.clientAuth {
+		case "require":
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		case "optional":
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("invalid MEMEX_TLS_CLIENT_AUTH %q (use 'require' or 'optional')", t.clientAuth)
+		}
+	}
+
+	return cfg, nil
+}
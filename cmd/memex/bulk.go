@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/server/api"
+)
+
+// Bulk request limits: a batch is sent when it reaches either
+const (
+	maxBatchSources = 100
+	maxBatchBytes   = 8 << 20
+)
+
+// deleteBatch is how many IDs are sent per bulk delete request
+const deleteBatch = 1000
+
+// runAdd implements `memex add`: ingests files, directories (recursively)
+// and glob patterns as sources
+func runAdd(args []string) {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	format := fs.String("format", "text", "format recorded on each source")
+	batch := fs.Int("batch", maxBatchSources, "sources per bulk request")
+	ids := fs.Bool("ids", false, "print each file's source ID")
+	checkpointPath := fs.String("checkpoint", ".memex-add.checkpoint", "file recording progress while the command runs")
+	resume := fs.Bool("resume", false, "continue the run recorded in the checkpoint file")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex add [--format F] [--batch N] [--ids] [--resume] PATH|DIR|GLOB...")
+		os.Exit(2)
+	}
+
+	paths, err := expandPaths(fs.Args())
+	if err != nil {
+		fail("add", err)
+	}
+	cp, err := openCheckpoint("add", *checkpointPath, *resume)
+	if err != nil {
+		fail("add", err)
+	}
+	b := &ingestBatcher{
+		command:    "add",
+		url:        strings.TrimRight(*server, "/") + "/api/ingest/bulk",
+		max:        *batch,
+		checkpoint: cp,
+		progress:   newProgress("add", "files", len(paths)),
+	}
+	skipped := 0
+	for _, path := range paths {
+		if b.finished(path) {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err == nil && !utf8.Valid(content) {
+			err = fmt.Errorf("not UTF-8 text")
+		}
+		if err == nil && len(content) == 0 {
+			err = fmt.Errorf("empty")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "memex add: skipping %s: %v\n", path, err)
+			skipped++
+			continue
+		}
+		abs, _ := filepath.Abs(path)
+		b.add(api.IngestRequest{Content: string(content), Format: *format, URL: "file://" + filepath.ToSlash(abs), Connector: "cli"}, path)
+	}
+	b.finish()
+
+	if *ids {
+		for _, r := range b.results {
+			fmt.Printf("%s\t%s\n", r.SourceID, r.label)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Added %d sources (%d already present, %d skipped)%s\n", b.created, len(b.results)-b.created, skipped, b.resumed())
+}
+
+// expandPaths resolves arguments to regular files: globs are expanded,
+// directories walked recursively (skipping hidden entries) and duplicates
+// dropped
+func expandPaths(args []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	addFile := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, fmt.Errorf("%s: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no files match", arg)
+			}
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				addFile(match)
+				continue
+			}
+			err = filepath.WalkDir(match, func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if path != match && strings.HasPrefix(d.Name(), ".") {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.Type().IsRegular() {
+					addFile(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// runIngest implements `memex ingest`: ingests a file, or stdin for "-",
+// as one source or as one source per line
+func runIngest(args []string) {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	format := fs.String("format", "text", "format recorded on each source")
+	origin := fs.String("url", "", "origin URL recorded on each source")
+	connector := fs.String("connector", "cli", "connector recorded on each source")
+	lines := fs.Bool("lines", false, "ingest each non-empty line as its own source")
+	batch := fs.Int("batch", maxBatchSources, "sources per bulk request")
+	checkpointPath := fs.String("checkpoint", ".memex-ingest.checkpoint", "file recording progress while --lines runs")
+	resume := fs.Bool("resume", false, "continue the --lines run recorded in the checkpoint file")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: memex ingest [--format F] [--url U] [--lines [--resume]] FILE|-")
+		os.Exit(2)
+	}
+	if *resume && !*lines {
+		fail("ingest", fmt.Errorf("--resume needs --lines"))
+	}
+
+	in := io.Reader(os.Stdin)
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fail("ingest", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	b := &ingestBatcher{command: "ingest", url: strings.TrimRight(*server, "/") + "/api/ingest/bulk", max: *batch}
+	source := func(content string) api.IngestRequest {
+		return api.IngestRequest{Content: content, Format: *format, URL: *origin, Connector: *connector}
+	}
+	if *lines {
+		cp, err := openCheckpoint("ingest", *checkpointPath, *resume)
+		if err != nil {
+			fail("ingest", err)
+		}
+		b.checkpoint, b.progress = cp, newProgress("ingest", "lines", 0)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxBatchBytes)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !b.finished(line) {
+				b.add(source(line), "")
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fail("ingest", err)
+		}
+	} else {
+		content, err := io.ReadAll(in)
+		if err != nil {
+			fail("ingest", err)
+		}
+		if len(bytes.TrimSpace(content)) == 0 {
+			fail("ingest", fmt.Errorf("no content"))
+		}
+		b.add(source(string(content)), "")
+	}
+	b.finish()
+
+	for _, r := range b.results {
+		fmt.Println(r.SourceID)
+	}
+	fmt.Fprintf(os.Stderr, "Ingested %d sources (%d already present)%s\n", b.created, len(b.results)-b.created, b.resumed())
+}
+
+// labeledResult is a bulk ingest result with the input it came from
+type labeledResult struct {
+	api.BulkIngestResult
+	label string
+}
+
+// ingestBatcher sends sources to the bulk ingest endpoint in batches. With
+// a checkpoint, each input is first passed to finished, and the
+// checkpoint is saved after every batch.
+type ingestBatcher struct {
+	command    string
+	url        string
+	max        int
+	checkpoint *checkpoint // Optional
+	progress   *progress   // Optional
+
+	pending []api.IngestRequest
+	labels  []string
+	bytes   int
+
+	results []labeledResult
+	created int
+}
+
+// finished records the next input by key and reports whether a resumed
+// run already sent it
+func (b *ingestBatcher) finished(key string) bool {
+	if b.checkpoint == nil {
+		return false
+	}
+	done, err := b.checkpoint.next(key)
+	if err != nil {
+		fail(b.command, err)
+	}
+	if done && b.progress != nil {
+		b.progress.set(b.checkpoint.seen)
+	}
+	return done
+}
+
+// add queues a source, sending the batch once it is full
+func (b *ingestBatcher) add(req api.IngestRequest, label string) {
+	b.pending = append(b.pending, req)
+	b.labels = append(b.labels, label)
+	b.bytes += len(req.Content)
+	if len(b.pending) >= b.max || b.bytes >= maxBatchBytes {
+		b.flush()
+	}
+}
+
+// flush sends the queued sources and checkpoints every input seen so far
+func (b *ingestBatcher) flush() {
+	if len(b.pending) == 0 {
+		return
+	}
+	var resp api.BulkIngestResponse
+	postJSON(b.command, b.url, api.BulkIngestRequest{Sources: b.pending}, &resp)
+	for i, r := range resp.Sources {
+		b.results = append(b.results, labeledResult{BulkIngestResult: r, label: b.labels[i]})
+	}
+	b.created += resp.Created
+	b.pending, b.labels, b.bytes = b.pending[:0], b.labels[:0], 0
+
+	if b.checkpoint != nil {
+		if err := b.checkpoint.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "memex %s: saving checkpoint: %v\n", b.command, err)
+		}
+		if b.progress != nil {
+			b.progress.set(b.checkpoint.seen)
+		}
+	}
+}
+
+// finish sends the last batch and, the run being complete, removes the
+// checkpoint
+func (b *ingestBatcher) finish() {
+	b.flush()
+	if b.checkpoint != nil {
+		if err := b.checkpoint.verify(); err != nil {
+			fail(b.command, err)
+		}
+		b.checkpoint.remove()
+	}
+	if b.progress != nil {
+		b.progress.finish()
+	}
+}
+
+// resumed describes the inputs a resumed run skipped, for the summary
+func (b *ingestBatcher) resumed() string {
+	if b.checkpoint == nil || b.checkpoint.skipped() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("; resumed after %d inputs", b.checkpoint.skipped())
+}
+
+// runDeleteNode implements `memex delete-node`: deletes the nodes named
+// on the command line (or on stdin, for "-") or matched by a filter, after
+// confirmation
+func runDeleteNode(args []string) {
+	fs := flag.NewFlagSet("delete-node", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	var types stringList
+	fs.Var(&types, "type", "match nodes of these types (repeatable or comma-separated)")
+	expr := fs.String("filter", "", `match nodes by expression, e.g. 'meta.status == "draft"'`)
+	limit := fs.Int("limit", 10000, "most nodes a filter may match")
+	force := fs.Bool("force", false, "also delete Source nodes")
+	cascade := fs.String("cascade", "", "what happens to the nodes' links (server default when empty)")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	namespace := fs.String("namespace", defaultNamespace(), "namespace for bare IDs and filters (profile default)")
+	fs.Parse(args)
+
+	filtered := len(types) > 0 || *expr != ""
+	if fs.NArg() > 0 == filtered {
+		fmt.Fprintln(os.Stderr, "Usage: memex delete-node [--force] [--yes] ID...|-\n       memex delete-node [--type T] [--filter EXPR] [--limit N] [--force] [--yes]")
+		os.Exit(2)
+	}
+	url := strings.TrimRight(*server, "/") + "/api/nodes/bulk/delete"
+
+	ns := strings.TrimSuffix(*namespace, ":")
+	var ids []string
+	switch {
+	case filtered:
+		filter := *expr
+		if ns != "" {
+			filter = fmt.Sprintf("node_id.startsWith(%q)", ns+":")
+			if *expr != "" {
+				filter += " && (" + *expr + ")"
+			}
+		}
+		var resp api.BulkDeleteResponse
+		postJSON("delete-node", url, api.BulkDeleteRequest{Types: types, Expr: filter, Limit: *limit, DryRun: true}, &resp)
+		if resp.Truncated {
+			fmt.Fprintf(os.Stderr, "memex delete-node: filter matches more than %d nodes; only the first %d are deleted\n", *limit, *limit)
+		}
+		ids = resp.Matched
+	case fs.NArg() == 1 && fs.Arg(0) == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fail("delete-node", err)
+		}
+		ids = strings.Fields(string(data))
+	default:
+		ids = fs.Args()
+	}
+	if !filtered {
+		for i, id := range ids {
+			ids[i] = qualifyID(id, ns)
+		}
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(os.Stderr, "No nodes to delete")
+		return
+	}
+	if !*yes && !confirmDelete(ids) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
+	deleted, failed := 0, 0
+	prog := newProgress("delete-node", "nodes", len(ids))
+	for start := 0; start < len(ids); start += deleteBatch {
+		end := min(start+deleteBatch, len(ids))
+		var resp api.BulkDeleteResponse
+		postJSON("delete-node", url, api.BulkDeleteRequest{IDs: ids[start:end], Force: *force, Cascade: *cascade}, &resp)
+		deleted += resp.Deleted
+		for _, f := range resp.Failed {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", f.ID, f.Error)
+			failed++
+		}
+		prog.set(end)
+	}
+	prog.finish()
+	fmt.Fprintf(os.Stderr, "Deleted %d nodes\n", deleted)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// confirmDelete lists the nodes about to be deleted and asks on the
+// terminal, so IDs can still be piped in on stdin
+func confirmDelete(ids []string) bool {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		fail("delete-node", fmt.Errorf("cannot ask for confirmation without a terminal; pass --yes"))
+	}
+	defer tty.Close()
+
+	const shown = 10
+	fmt.Fprintf(os.Stderr, "About to delete %d nodes:\n", len(ids))
+	for _, id := range ids[:min(shown, len(ids))] {
+		fmt.Fprintf(os.Stderr, "  %s\n", id)
+	}
+	if len(ids) > shown {
+		fmt.Fprintf(os.Stderr, "  ... and %d more\n", len(ids)-shown)
+	}
+	fmt.Fprint(os.Stderr, "Continue? [y/N] ")
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// postJSON posts v as JSON and decodes the result into out
+func postJSON(command, url string, v, out interface{}) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		fail(command, err)
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		fail(command, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	sendRequest(command, req, out)
+}
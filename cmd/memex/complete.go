@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/systemshift/memex/internal/server/autocomplete"
+)
+
+// runComplete implements `memex complete`
+func runComplete(args []string) {
+	fs := flag.NewFlagSet("complete", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	types := fs.String("types", "", "comma-separated node types to suggest")
+	limit := fs.Int("limit", 10, "maximum suggestions")
+	ids := fs.Bool("ids", false, "print node IDs only, one per line")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex complete [--types Concept,Person] [--limit N] [--ids] PREFIX")
+		os.Exit(2)
+	}
+
+	q := url.Values{"q": {strings.Join(fs.Args(), " ")}, "limit": {fmt.Sprint(*limit)}}
+	if *types != "" {
+		q.Set("types", *types)
+	}
+	req, err := http.NewRequest("GET", strings.TrimRight(*server, "/")+"/api/autocomplete?"+q.Encode(), nil)
+	if err != nil {
+		fail("complete", err)
+	}
+	var result struct {
+		Suggestions []autocomplete.Suggestion `json:"suggestions"`
+	}
+	sendRequest("complete", req, &result)
+
+	if *ids {
+		for _, s := range result.Suggestions {
+			fmt.Println(s.ID)
+		}
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range result.Suggestions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.ID, s.Type, s.Label)
+	}
+	w.Flush()
+}
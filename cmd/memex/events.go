@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/subscriptions"
+	"github.com/systemshift/memex/pkg/client"
+)
+
+// reconnectDelay is the pause before reconnecting a dropped stream
+const reconnectDelay = 2 * time.Second
+
+// stringList is a repeatable, comma-separated flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
+
+// runEvents implements `memex events <subcommand>`
+func runEvents(args []string) {
+	if len(args) == 0 || args[0] != "tail" {
+		fmt.Fprintln(os.Stderr, "Usage: memex events tail [--type node.created] [--node-type Screenshot] [--link-type T] [--expr EXPR] [--json]")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("events tail", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	var eventTypes, nodeTypes, linkTypes stringList
+	fs.Var(&eventTypes, "type", "event type to show (repeatable, e.g. node.created)")
+	fs.Var(&nodeTypes, "node-type", "node type to show (repeatable)")
+	fs.Var(&linkTypes, "link-type", "link type to show (repeatable)")
+	expr := fs.String("expr", "", "subscription expression events must satisfy")
+	raw := fs.Bool("json", false, "print each event as a JSON line")
+	fs.Parse(args[1:])
+
+	q := url.Values{}
+	for _, t := range eventTypes {
+		q.Add("type", t)
+	}
+	for _, t := range nodeTypes {
+		q.Add("node_type", t)
+	}
+	for _, t := range linkTypes {
+		q.Add("link_type", t)
+	}
+	if *expr != "" {
+		q.Set("expr", *expr)
+	}
+	streamURL := strings.TrimRight(*server, "/") + "/api/events/stream"
+	if len(q) > 0 {
+		streamURL += "?" + q.Encode()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	show := printEvent
+	if *raw {
+		show = printJSON
+	}
+
+	for {
+		err := tail(ctx, streamURL, show)
+		if ctx.Err() != nil {
+			return
+		}
+		var fatal *streamError
+		if errors.As(err, &fatal) && fatal.status >= 400 && fatal.status < 500 {
+			log.Fatal(err)
+		}
+		log.Printf("Stream disconnected (%v); reconnecting in %s", err, reconnectDelay)
+		select {
+		case <-time.After(reconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// streamError is a non-200 response from the stream endpoint
+type streamError struct {
+	status int
+	body   string
+}
+
+func (e *streamError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.status, e.body)
+}
+
+// tail reads Server-Sent Events until the stream ends or ctx is cancelled
+func tail(ctx context.Context, streamURL string, show func(subscriptions.Event)) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", streamURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &streamError{status: resp.StatusCode, body: client.ReadError(resp).Message}
+	}
+	log.Printf("Connected to %s", streamURL)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// A blank line terminates an event
+			if data.Len() > 0 {
+				var event subscriptions.Event
+				if err := json.Unmarshal([]byte(data.String()), &event); err == nil {
+					show(event)
+				}
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// printEvent writes one human-readable line per event
+func printEvent(e subscriptions.Event) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %-13s", e.Timestamp.Local().Format("15:04:05"), e.Type)
+	switch {
+	case e.LinkType != "":
+		fmt.Fprintf(&b, "  %s -[%s]-> %s", e.LinkSource, e.LinkType, e.LinkTarget)
+	case e.NodeType != "":
+		fmt.Fprintf(&b, "  %s (%s)", e.NodeID, e.NodeType)
+	default:
+		fmt.Fprintf(&b, "  %s", e.NodeID)
+	}
+
+	keys := make([]string, 0, len(e.Meta))
+	for k := range e.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, err := json.Marshal(e.Meta[k])
+		if err != nil {
+			continue
+		}
+		s := string(v)
+		if len(s) > 60 {
+			s = s[:57] + "..."
+		}
+		fmt.Fprintf(&b, "  %s=%s", k, s)
+	}
+	fmt.Println(b.String())
+}
+
+// printJSON writes one JSON object per line
+func printJSON(e subscriptions.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/pkg/client"
+)
+
+// runExport implements `memex export <format>`
+func runExport(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex export csv [--type TYPE]... [--meta KEY,...] [--out FILE [--resume]]")
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "csv":
+		runExportCSV(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "memex export: unknown format %q (use csv)\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// runExportCSV writes nodes as CSV, one column per meta field, in the
+// layout `memex import csv` reads back. Writing to a file, the rows written
+// are checkpointed so an interrupted export can be resumed with --resume.
+func runExportCSV(args []string) {
+	const command = "export csv"
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	var types stringList
+	fs.Var(&types, "type", "export only nodes of this type (repeatable)")
+	meta := fs.String("meta", "", "comma-separated meta fields to include (default all)")
+	limit := fs.Int("limit", 0, "maximum nodes (default the server's, 10000)")
+	out := fs.String("out", "-", `output file, or "-" for stdout`)
+	checkpointPath := fs.String("checkpoint", ".memex-export-csv.checkpoint", "file recording progress while --out is written")
+	resume := fs.Bool("resume", false, "continue the --out file recorded in the checkpoint file")
+	fs.Parse(args)
+	if *resume && *out == "-" {
+		fail(command, fmt.Errorf("--resume needs --out"))
+	}
+
+	q := url.Values{}
+	for _, t := range types {
+		q.Add("type", t)
+	}
+	if *meta != "" {
+		q.Set("meta", *meta)
+	}
+	if *limit > 0 {
+		q.Set("limit", fmt.Sprint(*limit))
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Minute}).Get(strings.TrimRight(*server, "/") + "/api/export/csv?" + q.Encode())
+	if err != nil {
+		fail(command, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fail(command, fmt.Errorf("server returned %s: %s", resp.Status, client.ReadError(resp).Message))
+	}
+
+	if *out == "-" {
+		if err := copyCSV(os.Stdout, resp.Body, nil, newProgress(command, "rows", 0)); err != nil {
+			fail(command, err)
+		}
+		return
+	}
+
+	cp, err := openCheckpoint(command, *checkpointPath, *resume)
+	if err != nil {
+		fail(command, err)
+	}
+	f, err := openExport(*out, cp)
+	if err != nil {
+		fail(command, err)
+	}
+	defer f.Close()
+	if err := copyCSV(f, resp.Body, cp, newProgress(command, "rows", 0)); err != nil {
+		fail(command, err)
+	}
+	if err := cp.verify(); err != nil {
+		fail(command, err)
+	}
+	cp.remove()
+}
+
+// openExport creates the output file or, resuming, opens it cut back to
+// the rows the checkpoint records as written
+func openExport(path string, cp *checkpoint) (*os.File, error) {
+	if cp.resume == nil {
+		return os.Create(path)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(cp.offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(cp.offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// exportSaveEvery is how many rows are written between checkpoint saves
+const exportSaveEvery = 1000
+
+// copyCSV copies CSV records from r to w, the header included. With a
+// checkpoint, records a resumed run already wrote are skipped and the
+// offset reached is saved every exportSaveEvery rows.
+func copyCSV(w io.Writer, r io.Reader, cp *checkpoint, p *progress) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	counter := &countingWriter{w: w}
+	if cp != nil {
+		counter.n = cp.offset
+	}
+	writer := csv.NewWriter(counter)
+
+	// save flushes what has been written and checkpoints it
+	save := func() error {
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+		if cp == nil {
+			return nil
+		}
+		cp.offset = counter.n
+		return cp.save()
+	}
+
+	for rows := 0; ; rows++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		p.set(rows) // The header is row 0
+		if cp != nil {
+			done, err := cp.next(strings.Join(record, "\x1f"))
+			if err != nil {
+				return err
+			}
+			if done {
+				continue
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		if rows > 0 && rows%exportSaveEvery == 0 {
+			if err := save(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := save(); err != nil {
+		return err
+	}
+	p.finish()
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportCSVResume(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("id,type,name\n")
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&b, "person:%d,Person,\"Name, %d\"\n", i, i)
+	}
+	full := b.String()
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "people.csv")
+	checkpointPath := filepath.Join(dir, "export.checkpoint")
+
+	// The connection drops part way through the third thousand rows
+	cp, err := openCheckpoint("export csv", checkpointPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := openExport(out, cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped := io.MultiReader(strings.NewReader(full[:len(full)*9/10]), &failingReader{})
+	if err := copyCSV(f, dropped, cp, newProgress("export csv", "rows", 0)); err == nil {
+		t.Fatal("copyCSV() succeeded on a dropped connection")
+	}
+	f.Close()
+	saved, err := os.ReadFile(checkpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err = openCheckpoint("export csv", checkpointPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.resume.Done != 2001 {
+		t.Errorf("checkpointed %d records, want 2001 (the header and 2000 rows)", cp.resume.Done)
+	}
+	f, err = openExport(out, cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := copyCSV(f, strings.NewReader(full), cp, newProgress("export csv", "rows", 0)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != full {
+		t.Errorf("resumed export differs from a full one (%d bytes, want %d)", len(got), len(full))
+	}
+
+	// A server whose rows changed since the checkpoint is not resumed
+	if err := os.WriteFile(checkpointPath, saved, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cp, err = openCheckpoint("export csv", checkpointPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := strings.Replace(full, "person:7,", "person:seven,", 1)
+	if err := copyCSV(io.Discard, strings.NewReader(changed), cp, newProgress("export csv", "rows", 0)); err == nil || !strings.Contains(err.Error(), "input differs") {
+		t.Errorf("copyCSV() of changed rows error = %v, want input differs", err)
+	}
+}
+
+// failingReader fails every read, like a dropped connection
+type failingReader struct{}
+
+func (*failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
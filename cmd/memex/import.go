@@ -0,0 +1,627 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/federation"
+	"github.com/systemshift/memex/internal/server/github"
+	"github.com/systemshift/memex/internal/server/importer"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/tickets"
+	"github.com/systemshift/memex/pkg/client"
+)
+
+// runImport implements `memex import <source>`
+func runImport(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex import csv --type TYPE --id-column COLUMN [--relation-column COLUMN] [--resume] FILE.csv\n       memex import bibtex FILE.bib [--no-files]\n       memex import zotero --library users/ID [--collection KEY] [--no-files]\n       memex import vcard FILE.vcf\n       memex import carddav --url URL [--username USER]\n       memex import github OWNER/REPO [--full]\n       memex import tickets [jira|linear] [--full]\n       memex import notion EXPORT.zip\n       memex import confluence EXPORT.zip\n       memex import federated --source NAME BUNDLE.json|SERVER_URL")
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "csv":
+		runImportCSV(args[1:])
+	case "bibtex":
+		runImportBibTeX(args[1:])
+	case "zotero":
+		runImportZotero(args[1:])
+	case "vcard":
+		runImportVCard(args[1:])
+	case "carddav":
+		runImportCardDAV(args[1:])
+	case "github":
+		runImportGitHub(args[1:])
+	case "tickets":
+		runImportTickets(args[1:])
+	case "notion", "confluence":
+		runImportWiki(args[0], args[1:])
+	case "federated":
+		runImportFederated(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "memex import: unknown source %q (use csv, bibtex, zotero, vcard, carddav, github, tickets, notion, confluence or federated)\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// runImportCSV uploads a spreadsheet export, one node per row, in batches.
+// The rows sent are checkpointed after each batch so a long import can be
+// resumed with --resume.
+func runImportCSV(args []string) {
+	const command = "import csv"
+	const usage = "Usage: memex import csv --type TYPE --id-column COLUMN [--relation-column COLUMN] [--batch N] [--resume] FILE.csv"
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	nodeType := fs.String("type", "", "node type for every row")
+	idColumn := fs.String("id-column", "", "column identifying each row's node")
+	idPrefix := fs.String("id-prefix", "", `prepended to IDs lacking it (default "<type>:")`)
+	contentColumn := fs.String("content-column", "", "column used as node content")
+	relationColumn := fs.String("relation-column", "", `column naming link targets (";" separates several)`)
+	relationType := fs.String("relation-type", "", "link type for the relation column (default RELATED_TO)")
+	relationPrefix := fs.String("relation-prefix", "", "prepended to each link target")
+	batch := fs.Int("batch", 1000, "rows per request")
+	checkpointPath := fs.String("checkpoint", ".memex-import-csv.checkpoint", "file recording progress while the command runs")
+	resume := fs.Bool("resume", false, "continue the run recorded in the checkpoint file")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	fs.Parse(fs.Args()[1:]) // Flags may follow the file
+	if *nodeType == "" || *idColumn == "" || *batch < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fail(command, err)
+	}
+	header, rows, err := readCSV(f)
+	f.Close()
+	if err != nil {
+		fail(command, fmt.Errorf("%s: %w", path, err))
+	}
+
+	cp, err := openCheckpoint(command, *checkpointPath, *resume)
+	if err != nil {
+		fail(command, err)
+	}
+
+	q := url.Values{}
+	q.Set("type", *nodeType)
+	q.Set("id_column", *idColumn)
+	for key, value := range map[string]string{
+		"id_prefix":      *idPrefix,
+		"content_column": *contentColumn,
+		"link_column":    *relationColumn,
+		"link_type":      *relationType,
+		"link_prefix":    *relationPrefix,
+	} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	u := &csvUpload{
+		command:    command,
+		url:        strings.TrimRight(*server, "/") + "/api/import/csv?" + q.Encode(),
+		header:     header,
+		rows:       rows,
+		checkpoint: cp,
+		progress:   newProgress(command, "rows", len(rows)),
+	}
+
+	u.run(*batch)
+	cp.remove()
+	u.progress.finish()
+
+	r := u.result
+	resumed := ""
+	if cp.skipped() > 0 {
+		resumed = fmt.Sprintf("; resumed after %d rows", cp.skipped())
+	}
+	fmt.Printf("Rows: %d created, %d updated, %d unchanged, %d links%s\n", r.Created, r.Updated, r.Unchanged, r.Links, resumed)
+	for _, e := range r.Errors {
+		fmt.Fprintf(os.Stderr, "row %d: %s\n", e.Row, e.Error)
+	}
+}
+
+// readCSV reads a CSV file the way the server's importer does, returning
+// the header and the records after it
+func readCSV(r io.Reader) ([]string, [][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, errors.New("empty file")
+	}
+	return records[0], records[1:], nil
+}
+
+// csvUpload sends a CSV file's rows to the server, a batch per request,
+// and totals the results. Rows are numbered from 0; the file row in
+// messages is the index plus 2, counting the header as row 1.
+type csvUpload struct {
+	command    string
+	url        string
+	header     []string
+	rows       [][]string
+	checkpoint *checkpoint
+	progress   *progress
+
+	result importer.CSVResult
+}
+
+// run sends the rows a resumed run did not finish, saving the checkpoint
+// after each batch, then retries the rows whose links failed
+func (u *csvUpload) run(batch int) {
+	var pending []int
+	for i, record := range u.rows {
+		done, err := u.checkpoint.next(strings.Join(record, "\x1f"))
+		if err != nil {
+			fail(u.command, err)
+		}
+		if !done {
+			pending = append(pending, i)
+		}
+		if len(pending) >= batch || (i == len(u.rows)-1 && len(pending) > 0) {
+			u.send(pending)
+			pending = pending[:0]
+			if err := u.checkpoint.save(); err != nil {
+				fmt.Fprintf(os.Stderr, "memex %s: saving checkpoint: %v\n", u.command, err)
+			}
+		}
+		u.progress.set(u.checkpoint.seen)
+	}
+	if err := u.checkpoint.verify(); err != nil {
+		fail(u.command, err)
+	}
+	// Links to rows in later batches failed the first time; try them again
+	// now that every row has been sent
+	for retry := u.checkpoint.retry; len(retry) > 0; retry = retry[min(batch, len(retry)):] {
+		u.retry(retry[:min(batch, len(retry))])
+	}
+}
+
+// send posts the rows at indexes. Rows whose link targets were not found are
+// left on the checkpoint's retry list, since the targets may come later in
+// the file; other row errors are kept for the summary.
+func (u *csvUpload) send(indexes []int) {
+	result := u.post(indexes)
+	u.result.Created += result.Created
+	u.result.Updated += result.Updated
+	u.result.Unchanged += result.Unchanged
+	u.result.Links += result.Links
+	for _, e := range result.Errors {
+		i := indexes[e.Row-2]
+		if !strings.HasPrefix(e.Error, "link target not found") {
+			u.result.Errors = append(u.result.Errors, importer.RowError{Row: i + 2, Error: e.Error})
+		} else if n := len(u.checkpoint.retry); n == 0 || u.checkpoint.retry[n-1] != i {
+			u.checkpoint.retry = append(u.checkpoint.retry, i)
+		}
+	}
+}
+
+// retry posts rows again for their links; the nodes were created the first
+// time, so only links and errors count
+func (u *csvUpload) retry(indexes []int) {
+	result := u.post(indexes)
+	u.result.Links += result.Links
+	for _, e := range result.Errors {
+		u.result.Errors = append(u.result.Errors, importer.RowError{Row: indexes[e.Row-2] + 2, Error: e.Error})
+	}
+}
+
+// post sends the header and the rows at indexes as one CSV import
+func (u *csvUpload) post(indexes []int) importer.CSVResult {
+	var body bytes.Buffer
+	w := csv.NewWriter(&body)
+	w.Write(u.header)
+	for _, i := range indexes {
+		w.Write(u.rows[i])
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		fail(u.command, err)
+	}
+
+	req, err := http.NewRequest("POST", u.url, &body)
+	if err != nil {
+		fail(u.command, err)
+	}
+	req.Header.Set("Content-Type", "text/csv")
+	var result importer.CSVResult
+	sendRequest(u.command, req, &result)
+	for _, e := range result.Errors {
+		if e.Row < 2 || e.Row-2 >= len(indexes) {
+			fail(u.command, fmt.Errorf("server reported an error for row %d of a %d-row batch: %s", e.Row, len(indexes), e.Error))
+		}
+	}
+	return result
+}
+
+// runImportBibTeX uploads a .bib file with the PDFs its entries name
+func runImportBibTeX(args []string) {
+	fs := flag.NewFlagSet("import bibtex", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	noFiles := fs.Bool("no-files", false, "do not upload the files entries name")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex import bibtex FILE.bib [--no-files]")
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	fs.Parse(fs.Args()[1:]) // Flags may follow the file
+
+	bib, err := os.ReadFile(path)
+	if err != nil {
+		fail("import bibtex", err)
+	}
+	var files []string
+	if !*noFiles {
+		works, err := importer.ParseBibTeX(bytes.NewReader(bib))
+		if err != nil {
+			fail("import bibtex", err)
+		}
+		files = localFiles(works, filepath.Dir(path))
+	}
+
+	// Stream the upload so large PDF collections are not held in memory
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeBibUpload(form, filepath.Base(path), bib, files))
+	}()
+
+	req, err := http.NewRequest("POST", strings.TrimRight(*server, "/")+"/api/import/bibtex", body)
+	if err != nil {
+		fail("import bibtex", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	printImportResult("import bibtex", req)
+}
+
+// localFiles resolves the files named by entries, relative to the .bib
+// file's directory, warning about any that are missing
+func localFiles(works []importer.Work, dir string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, w := range works {
+		for _, f := range w.Files {
+			if !filepath.IsAbs(f) {
+				f = filepath.Join(dir, f)
+			}
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+			if _, err := os.Stat(f); err != nil {
+				fmt.Fprintf(os.Stderr, "memex import bibtex: skipping %s: %v\n", f, err)
+				continue
+			}
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func writeBibUpload(form *multipart.Writer, name string, bib []byte, files []string) error {
+	part, err := form.CreateFormFile("bib", name)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(bib); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := copyFilePart(form, f); err != nil {
+			return err
+		}
+	}
+	return form.Close()
+}
+
+func copyFilePart(form *multipart.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
+
+// runImportZotero asks the server to import a Zotero library
+func runImportZotero(args []string) {
+	fs := flag.NewFlagSet("import zotero", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	library := fs.String("library", "", "Zotero library: users/<id> or groups/<id>")
+	collection := fs.String("collection", "", "import only this collection key")
+	apiKey := fs.String("api-key", os.Getenv("ZOTERO_API_KEY"), "Zotero API key (default $ZOTERO_API_KEY)")
+	noFiles := fs.Bool("no-files", false, "do not download stored PDFs")
+	fs.Parse(args)
+	if *library == "" {
+		fmt.Fprintln(os.Stderr, "Usage: memex import zotero --library users/ID [--collection KEY] [--no-files]")
+		os.Exit(2)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"library":     *library,
+		"api_key":     *apiKey,
+		"collection":  *collection,
+		"attachments": !*noFiles,
+	})
+	if err != nil {
+		fail("import zotero", err)
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(*server, "/")+"/api/import/zotero", bytes.NewReader(payload))
+	if err != nil {
+		fail("import zotero", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	printImportResult("import zotero", req)
+}
+
+// runImportVCard uploads a .vcf file
+func runImportVCard(args []string) {
+	fs := flag.NewFlagSet("import vcard", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex import vcard FILE.vcf")
+		os.Exit(2)
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fail("import vcard", err)
+	}
+	defer f.Close()
+
+	req, err := http.NewRequest("POST", strings.TrimRight(*server, "/")+"/api/import/vcard", f)
+	if err != nil {
+		fail("import vcard", err)
+	}
+	req.Header.Set("Content-Type", "text/vcard")
+	printContactResult("import vcard", req)
+}
+
+// runImportCardDAV asks the server to sync a CardDAV address book
+func runImportCardDAV(args []string) {
+	fs := flag.NewFlagSet("import carddav", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	url := fs.String("url", "", "address book collection URL")
+	username := fs.String("username", "", "CardDAV username")
+	password := fs.String("password", os.Getenv("CARDDAV_PASSWORD"), "CardDAV password (default $CARDDAV_PASSWORD)")
+	fs.Parse(args)
+	if *url == "" {
+		fmt.Fprintln(os.Stderr, "Usage: memex import carddav --url URL [--username USER]")
+		os.Exit(2)
+	}
+
+	payload, err := json.Marshal(map[string]string{"url": *url, "username": *username, "password": *password})
+	if err != nil {
+		fail("import carddav", err)
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(*server, "/")+"/api/import/carddav", bytes.NewReader(payload))
+	if err != nil {
+		fail("import carddav", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	printContactResult("import carddav", req)
+}
+
+// runImportGitHub asks the server to sync a repository's issues and pull requests
+func runImportGitHub(args []string) {
+	fs := flag.NewFlagSet("import github", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	full := fs.Bool("full", false, "refetch everything, not just what changed since the last sync")
+	token := fs.String("token", os.Getenv("GITHUB_TOKEN"), "GitHub token overriding the server's (default $GITHUB_TOKEN)")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex import github OWNER/REPO [--full]")
+		os.Exit(2)
+	}
+	repo := fs.Arg(0)
+	fs.Parse(fs.Args()[1:]) // Flags may follow the repository
+
+	payload, err := json.Marshal(map[string]interface{}{"repo": repo, "full": *full, "token": *token})
+	if err != nil {
+		fail("import github", err)
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(*server, "/")+"/api/import/github", bytes.NewReader(payload))
+	if err != nil {
+		fail("import github", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var result github.Result
+	sendRequest("import github", req, &result)
+	fmt.Printf("%s: %d created, %d updated, %d unchanged, %d links\n", result.Repo, result.Created, result.Updated, result.Unchanged, result.Links)
+	if result.SyncedTo != "" {
+		fmt.Printf("Synced through %s\n", result.SyncedTo)
+	}
+}
+
+// runImportTickets asks the server to sync tickets from its configured trackers
+func runImportTickets(args []string) {
+	fs := flag.NewFlagSet("import tickets", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	full := fs.Bool("full", false, "refetch everything, not just what changed since the last sync")
+	fs.Parse(args)
+	tracker := fs.Arg(0)
+	if fs.NArg() > 0 {
+		fs.Parse(fs.Args()[1:]) // Flags may follow the tracker
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"tracker": tracker, "full": *full})
+	if err != nil {
+		fail("import tickets", err)
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(*server, "/")+"/api/import/tickets", bytes.NewReader(payload))
+	if err != nil {
+		fail("import tickets", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var result struct {
+		Results []tickets.Result `json:"results"`
+	}
+	sendRequest("import tickets", req, &result)
+	for _, r := range result.Results {
+		fmt.Printf("%s: %d created, %d updated, %d unchanged, %d links\n", r.Tracker, r.Created, r.Updated, r.Unchanged, r.Links)
+		if r.SyncedTo != "" {
+			fmt.Printf("Synced through %s\n", r.SyncedTo)
+		}
+	}
+}
+
+// runImportWiki uploads a Notion or Confluence export ZIP
+func runImportWiki(source string, args []string) {
+	command := "import " + source
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: memex import %s EXPORT.zip\n", source)
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	fs.Parse(fs.Args()[1:]) // Flags may follow the file
+
+	f, err := os.Open(path)
+	if err != nil {
+		fail(command, err)
+	}
+	defer f.Close()
+
+	req, err := http.NewRequest("POST", strings.TrimRight(*server, "/")+"/api/import/"+source, f)
+	if err != nil {
+		fail(command, err)
+	}
+	req.Header.Set("Content-Type", "application/zip")
+	var result importer.WikiResult
+	sendRequest(command, req, &result)
+	fmt.Printf("Pages: %d created, %d updated, %d unchanged, %d links\n", result.Created, result.Updated, result.Unchanged, result.Links)
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", e.Key, e.Error)
+	}
+}
+
+// runImportFederated imports a bundle from another server, read from a
+// file or fetched from the server itself
+func runImportFederated(args []string) {
+	const command = "import federated"
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	source := fs.String("source", "", "name of the exporting server, the same on every sync")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex import federated --source NAME BUNDLE.json|SERVER_URL")
+		os.Exit(2)
+	}
+	from := fs.Arg(0)
+	fs.Parse(fs.Args()[1:]) // Flags may follow the bundle
+	if *source == "" {
+		fmt.Fprintln(os.Stderr, "memex import federated: --source is required")
+		os.Exit(2)
+	}
+
+	var body io.Reader
+	if strings.HasPrefix(from, "http://") || strings.HasPrefix(from, "https://") {
+		resp, err := http.Get(strings.TrimRight(from, "/") + "/api/export/bundle")
+		if err != nil {
+			fail(command, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			fail(command, fmt.Errorf("exporting from %s: %s", from, resp.Status))
+		}
+		body = resp.Body
+	} else {
+		f, err := os.Open(from)
+		if err != nil {
+			fail(command, err)
+		}
+		defer f.Close()
+		body = f
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimRight(*server, "/")+"/api/import/federated?source="+url.QueryEscape(*source), body)
+	if err != nil {
+		fail(command, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var resp struct {
+		Result federation.Result `json:"result"`
+	}
+	sendRequest(command, req, &resp)
+	result := resp.Result
+	fmt.Printf("Nodes: %d created, %d updated, %d unchanged, %d skipped\n", result.Created, result.Updated, result.Unchanged, result.Skipped)
+	fmt.Printf("Links: %d, remapped IDs: %d\n", result.Links, len(result.Remapped))
+	for original, local := range result.Remapped {
+		fmt.Printf("  %s -> %s\n", original, local)
+	}
+	for _, e := range result.Errors {
+		fmt.Fprintln(os.Stderr, e)
+	}
+}
+
+// printContactResult sends a contact import request and summarizes the result
+func printContactResult(command string, req *http.Request) {
+	var result people.SyncResult
+	sendRequest(command, req, &result)
+	fmt.Printf("People: %d created, %d updated, %d unchanged (%d skipped)\n", result.Created, result.Updated, result.Unchanged, result.Skipped)
+	fmt.Printf("Aliases: %d\n", result.Aliases)
+	for _, c := range result.Conflicts {
+		fmt.Fprintf(os.Stderr, "%s matches several people: %s\n", c.Contact, strings.Join(c.People, ", "))
+	}
+}
+
+// printImportResult sends a bibliography import request and summarizes the result
+func printImportResult(command string, req *http.Request) {
+	var result importer.BibliographyResult
+	sendRequest(command, req, &result)
+	fmt.Printf("Papers: %d created, %d updated, %d unchanged\n", result.Created, result.Updated, result.Unchanged)
+	fmt.Printf("Authors: %d, venues: %d, attachments: %d, links: %d\n", result.Authors, result.Venues, result.Attachments, result.Links)
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", e.Key, e.Error)
+	}
+}
+
+// sendRequest sends a request to the server and decodes the result into v
+func sendRequest(command string, req *http.Request, v interface{}) {
+	resp, err := (&http.Client{Timeout: 30 * time.Minute}).Do(req)
+	if err != nil {
+		fail(command, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fail(command, fmt.Errorf("server returned %s: %s", resp.Status, client.ReadError(resp).Message))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		fail(command, err)
+	}
+}
+
+func fail(command string, err error) {
+	fmt.Fprintf(os.Stderr, "memex %s: %v\n", command, err)
+	os.Exit(1)
+}
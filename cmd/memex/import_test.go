@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/importer"
+)
+
+// csvServer imports CSV uploads into repo the way POST /api/import/csv does
+func csvServer(t *testing.T, repo graph.Repository) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		result, err := importer.ImportCSV(r.Context(), repo, r.Body, importer.CSVOptions{
+			Type:       q.Get("type"),
+			IDColumn:   q.Get("id_column"),
+			LinkColumn: q.Get("link_column"),
+			LinkType:   q.Get("link_type"),
+			LinkPrefix: q.Get("link_prefix"),
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestImportCSVBatches(t *testing.T) {
+	ctx := context.Background()
+	repo, err := graph.NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close(ctx)
+	srv := csvServer(t, repo)
+
+	// ada's manager comes two batches later; erin's never does
+	header, rows, err := readCSV(strings.NewReader("name,manager\nada,cy\nbob,ada\ncy,\ndee,\nerin,nobody\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	upload := func(resume bool) *csvUpload {
+		cp, err := openCheckpoint("import csv", filepath.Join(dir, "import.checkpoint"), resume)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &csvUpload{
+			command:    "import csv",
+			url:        srv.URL + "/api/import/csv?type=Person&id_column=name&link_column=manager&link_type=REPORTS_TO&link_prefix=person:",
+			header:     header,
+			rows:       rows,
+			checkpoint: cp,
+			progress:   newProgress("import csv", "rows", len(rows)),
+		}
+	}
+
+	// A run that stops after its first batch of two rows
+	first := upload(false)
+	first.rows = rows[:2]
+	first.run(2)
+
+	second := upload(true)
+	second.run(2)
+	if second.checkpoint.skipped() != 2 {
+		t.Errorf("skipped() = %d, want 2", second.checkpoint.skipped())
+	}
+	if second.result.Created != 3 {
+		t.Errorf("resumed run created %d, want 3 (cy, dee, erin)", second.result.Created)
+	}
+	if second.result.Links != 1 {
+		t.Errorf("resumed run created %d links, want 1 (ada's, on retry)", second.result.Links)
+	}
+	if errs := second.result.Errors; len(errs) != 1 || errs[0].Row != 6 {
+		t.Errorf("errors = %+v, want only erin's missing manager on row 6", errs)
+	}
+
+	links, err := repo.GetLinks(ctx, "person:ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[0].Target != "person:cy" {
+		t.Errorf("ada's links = %+v, want REPORTS_TO person:cy", links)
+	}
+}
@@ -0,0 +1,113 @@
+// Command memex is a command-line client for a running memex-server.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const usage = `Usage: memex [--profile NAME] <command> [arguments]
+
+Commands:
+  add            Ingest files, directories and globs as sources
+  complete       Suggest nodes matching a prefix
+  delete-node    Delete nodes by ID or filter expression
+  doctor         Diagnose configuration and server problems
+  events tail    Print graph events live as they happen
+  export         Export nodes as CSV
+  import         Import CSV rows, papers (BibTeX, Zotero) or contacts (vCard, CardDAV)
+  ingest         Ingest a file or stdin ("-") as sources
+  profile        Manage named server profiles (list, add, switch, show, remove)
+  publish        Render selected nodes as a static HTML site
+  secret         Store API keys and passphrases in the OS keychain
+
+Set MEMEX_URL (and MEMEX_API_KEY) to point at a server other than
+http://localhost:8080, or save one as a profile with memex profile add.
+Set MEMEX_SIGNING_KEY=id=secret to sign requests instead of sending a key.
+Set MEMEX_CLIENT_CERT and MEMEX_CLIENT_KEY to present a client certificate,
+and MEMEX_CA_CERT to trust a private CA.
+Set MEMEX_DEVICE_KEY to the key from POST /api/devices to stamp writes
+with this machine's device ID.
+--profile (or MEMEX_PROFILE) picks a profile for one run.
+`
+
+func main() {
+	args, profileName := globalFlags(os.Args[1:])
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := useProfile(profileName); err != nil {
+		fmt.Fprintf(os.Stderr, "memex: %v\n", err)
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "profile", "secret", "help", "-h", "--help":
+		// These must work with a locked keychain
+	default:
+		if err := sendAPIKey(); err != nil {
+			fmt.Fprintf(os.Stderr, "memex: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch args[0] {
+	case "add":
+		runAdd(args[1:])
+	case "complete":
+		runComplete(args[1:])
+	case "delete-node":
+		runDeleteNode(args[1:])
+	case "doctor":
+		runDoctor(args[1:])
+	case "events":
+		runEvents(args[1:])
+	case "export":
+		runExport(args[1:])
+	case "import":
+		runImport(args[1:])
+	case "ingest":
+		runIngest(args[1:])
+	case "profile":
+		runProfile(args[1:])
+	case "publish":
+		runPublish(args[1:])
+	case "secret":
+		runSecret(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "memex: unknown command %q\n\n%s", args[0], usage)
+		os.Exit(2)
+	}
+}
+
+// globalFlags strips the flags that come before the command, returning
+// the rest of the arguments and the --profile value
+func globalFlags(args []string) ([]string, string) {
+	profileName := ""
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--profile" || args[0] == "-profile":
+			if len(args) < 2 {
+				fmt.Fprintln(os.Stderr, "memex: --profile needs a name")
+				os.Exit(2)
+			}
+			profileName, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--profile="), strings.HasPrefix(args[0], "-profile="):
+			profileName, args = args[0][strings.Index(args[0], "=")+1:], args[1:]
+		default:
+			return args, profileName
+		}
+	}
+	return args, profileName
+}
+
+// getEnv returns an environment variable or a default
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/systemshift/memex/internal/secrets"
+	"github.com/systemshift/memex/internal/server/signing"
+)
+
+// cliConfig is the CLI's config file: named profiles, each pointing at a
+// server (and so a graph), and the one used by default
+type cliConfig struct {
+	Current  string              `json:"current,omitempty"`
+	Profiles map[string]*profile `json:"profiles"`
+}
+
+// profile is how the CLI reaches one server
+type profile struct {
+	URL         string `json:"url"`
+	APIKey      string `json:"api_key,omitempty"`       // Plaintext, from before keys moved to a secret store
+	APIKeyStore string `json:"api_key_store,omitempty"` // Secret store holding the key: keychain or file
+	Namespace   string `json:"namespace,omitempty"`     // Prefix for bare node IDs
+	ClientCert  string `json:"client_cert,omitempty"`   // PEM certificate presented to servers requiring mTLS
+	ClientKey   string `json:"client_key,omitempty"`    // PEM private key for ClientCert
+	CACert      string `json:"ca_cert,omitempty"`       // PEM CA bundle trusted for the server's certificate
+}
+
+// secretName is the profile's API key's name in the secret store
+func secretName(profileName string) string {
+	return "profile:" + profileName
+}
+
+// openSecrets opens a secret store ("" picks the keychain, else the
+// encrypted file kept beside the config file)
+func openSecrets(backend string) (secrets.Store, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	return secrets.Open(backend, filepath.Join(filepath.Dir(path), "secrets.enc"))
+}
+
+// The profile in use, set by useProfile before a command runs
+var (
+	active         *profile
+	activeName     string
+	activeExplicit bool // Chosen by --profile or MEMEX_PROFILE rather than the config's current
+)
+
+// configPath returns the CLI config file, $MEMEX_CONFIG or memex/config.json
+// in the user config directory
+func configPath() (string, error) {
+	if path := os.Getenv("MEMEX_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "memex", "config.json"), nil
+}
+
+// loadConfig reads the config file; a missing file is an empty config
+func loadConfig() (*cliConfig, error) {
+	c := &cliConfig{Profiles: make(map[string]*profile)}
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*profile)
+	}
+	return c, nil
+}
+
+// save writes the config file, readable only by the user as it holds API keys
+func (c *cliConfig) save() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// useProfile selects the profile for this run: name (from --profile),
+// else $MEMEX_PROFILE, else the config's current profile, if any
+func useProfile(name string) error {
+	if name == "" {
+		name = os.Getenv("MEMEX_PROFILE")
+	}
+	activeExplicit = name != ""
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		return nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile named %q (see memex profile list)", name)
+	}
+	active, activeName = p, name
+	return nil
+}
+
+// sendAPIKey makes requests to the default server carry the API key,
+// reading it from the secret store if the profile keeps it there
+func sendAPIKey() error {
+	if active != nil && active.APIKeyStore != "" && active.APIKey == "" {
+		store, err := openSecrets(active.APIKeyStore)
+		if err != nil {
+			return fmt.Errorf("reading API key for profile %s: %w", activeName, err)
+		}
+		if active.APIKey, err = store.Get(secretName(activeName)); err != nil {
+			return fmt.Errorf("reading API key for profile %s from %s: %w", activeName, store.Name(), err)
+		}
+	}
+	cfg, err := clientTLSConfig()
+	if err != nil {
+		return err
+	}
+	if cfg != nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = cfg
+		http.DefaultTransport = t
+	}
+	u, err := url.Parse(defaultServer())
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	if key := os.Getenv("MEMEX_DEVICE_KEY"); key != "" {
+		http.DefaultTransport = &deviceTransport{base: http.DefaultTransport, host: u.Host, key: key}
+	}
+	if spec := os.Getenv("MEMEX_SIGNING_KEY"); spec != "" {
+		keyID, secret, ok := strings.Cut(spec, "=")
+		if !ok || keyID == "" || secret == "" {
+			return fmt.Errorf("MEMEX_SIGNING_KEY: expected id=secret")
+		}
+		http.DefaultTransport = &signingTransport{base: http.DefaultTransport, host: u.Host, keyID: keyID, secret: secret}
+		return nil
+	}
+	if key := apiKey(); key != "" {
+		http.DefaultTransport = &apiKeyTransport{base: http.DefaultTransport, host: u.Host, key: key}
+	}
+	return nil
+}
+
+// defaultServer returns the server commands use unless given --server. A
+// profile chosen for this run beats $MEMEX_URL, which beats the current
+// profile.
+func defaultServer() string {
+	if active != nil && active.URL != "" && (activeExplicit || os.Getenv("MEMEX_URL") == "") {
+		return active.URL
+	}
+	return getEnv("MEMEX_URL", "http://localhost:8080")
+}
+
+// apiKey returns the API key sent to the server, by the same precedence
+// as defaultServer with $MEMEX_API_KEY
+func apiKey() string {
+	if active != nil && active.APIKey != "" && (activeExplicit || os.Getenv("MEMEX_API_KEY") == "") {
+		return active.APIKey
+	}
+	return os.Getenv("MEMEX_API_KEY")
+}
+
+// profileSetting returns a per-profile setting or its environment
+// variable, by the same precedence as defaultServer
+func profileSetting(value, env string) string {
+	if value != "" && (activeExplicit || os.Getenv(env) == "") {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// clientTLSConfig returns the TLS settings for reaching the server from
+// $MEMEX_CLIENT_CERT, $MEMEX_CLIENT_KEY and $MEMEX_CA_CERT or the active
+// profile, or nil when none are set
+func clientTLSConfig() (*tls.Config, error) {
+	var p profile
+	if active != nil {
+		p = *active
+	}
+	certFile := profileSetting(p.ClientCert, "MEMEX_CLIENT_CERT")
+	keyFile := profileSetting(p.ClientKey, "MEMEX_CLIENT_KEY")
+	caFile := profileSetting(p.CACert, "MEMEX_CA_CERT")
+	return loadClientTLS(certFile, keyFile, caFile)
+}
+
+// loadClientTLS builds a TLS config presenting the certificate in
+// certFile and trusting the CAs in caFile (the system roots if empty)
+func loadClientTLS(certFile, keyFile, caFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be set together")
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// defaultNamespace returns the active profile's namespace, if any
+func defaultNamespace() string {
+	if active == nil {
+		return ""
+	}
+	return active.Namespace
+}
+
+// qualifyID prefixes a bare node ID (one without a namespace) with ns
+func qualifyID(id, ns string) string {
+	if ns == "" || strings.Contains(id, ":") {
+		return id
+	}
+	return ns + ":" + id
+}
+
+// apiKeyTransport sends the API key with every request to the server's host
+type apiKeyTransport struct {
+	base http.RoundTripper
+	host string
+	key  string
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host || req.Header.Get("X-API-Key") != "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("X-API-Key", t.key)
+	return t.base.RoundTrip(req)
+}
+
+// deviceTransport identifies this installation as a registered device on
+// every request to the server's host
+type deviceTransport struct {
+	base http.RoundTripper
+	host string
+	key  string
+}
+
+func (t *deviceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Memex-Device", t.key)
+	return t.base.RoundTrip(req)
+}
+
+// signingTransport signs every request to the server's host in place of
+// sending an API key, so the credential never appears in a request
+type signingTransport struct {
+	base   http.RoundTripper
+	host   string
+	keyID  string
+	secret string
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	var body []byte
+	req = req.Clone(req.Context())
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+	signing.Sign(req, body, t.keyID, t.secret, time.Now())
+	return t.base.RoundTrip(req)
+}
+
+const profileUsage = `Usage: memex profile list
+       memex profile add NAME --url URL [--api-key KEY] [--store S] [--namespace NS]
+                         [--client-cert FILE --client-key FILE] [--ca-cert FILE]
+       memex profile switch NAME
+       memex profile show [NAME]
+       memex profile remove NAME`
+
+// runProfile implements `memex profile`
+func runProfile(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, profileUsage)
+		os.Exit(2)
+	}
+	c, err := loadConfig()
+	if err != nil {
+		fail("profile", err)
+	}
+
+	switch args[0] {
+	case "list":
+		names := make([]string, 0, len(c.Profiles))
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			marker := " "
+			if name == c.Current {
+				marker = "*"
+			}
+			p := c.Profiles[name]
+			fmt.Fprintf(w, "%s %s\t%s\t%s\n", marker, name, p.URL, p.Namespace)
+		}
+		w.Flush()
+	case "add":
+		fs := flag.NewFlagSet("profile add", flag.ExitOnError)
+		serverURL := fs.String("url", "", "memex-server base URL")
+		key := fs.String("api-key", "", "API key sent as X-API-Key")
+		store := fs.String("store", "", "where to keep the API key: keychain, file or plain (default keychain, else file)")
+		namespace := fs.String("namespace", "", "namespace for bare node IDs")
+		clientCert := fs.String("client-cert", "", "PEM client certificate for servers requiring mTLS")
+		clientKey := fs.String("client-key", "", "PEM private key for --client-cert")
+		caCert := fs.String("ca-cert", "", "PEM CA bundle trusted for the server's certificate")
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			fmt.Fprintln(os.Stderr, profileUsage)
+			os.Exit(2)
+		}
+		name := args[1]
+		fs.Parse(args[2:])
+		if u, err := url.Parse(*serverURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("profile", fmt.Errorf("--url must be an http(s) URL, e.g. http://localhost:8080"))
+		}
+		if _, err := loadClientTLS(*clientCert, *clientKey, *caCert); err != nil {
+			fail("profile", err)
+		}
+		p := &profile{URL: strings.TrimRight(*serverURL, "/"), Namespace: strings.TrimSuffix(*namespace, ":")}
+		for _, f := range []struct {
+			field *string
+			path  string
+		}{{&p.ClientCert, *clientCert}, {&p.ClientKey, *clientKey}, {&p.CACert, *caCert}} {
+			if f.path != "" {
+				if *f.field, err = filepath.Abs(f.path); err != nil {
+					fail("profile", err)
+				}
+			}
+		}
+		switch {
+		case *key == "":
+		case *store == "plain":
+			p.APIKey = *key
+			fmt.Fprintln(os.Stderr, "memex profile: warning: the API key is stored in plaintext in the config file")
+		default:
+			secretStore, err := openSecrets(*store)
+			if err != nil {
+				fail("profile", fmt.Errorf("%w (or pass --store plain)", err))
+			}
+			if err := secretStore.Set(secretName(name), *key); err != nil {
+				fail("profile", err)
+			}
+			p.APIKeyStore = secretStore.Name()
+		}
+		if old := c.Profiles[name]; old != nil && old.APIKeyStore != "" && old.APIKeyStore != p.APIKeyStore {
+			forgetAPIKey(name, old)
+		}
+		c.Profiles[name] = p
+		if c.Current == "" {
+			c.Current = name
+		}
+		saveConfig(c)
+		if p.APIKeyStore != "" {
+			fmt.Printf("Saved profile %s (API key in %s)\n", name, p.APIKeyStore)
+		} else {
+			fmt.Printf("Saved profile %s\n", name)
+		}
+	case "switch":
+		name := profileArg(args)
+		if _, ok := c.Profiles[name]; !ok {
+			fail("profile", fmt.Errorf("no profile named %q", name))
+		}
+		c.Current = name
+		saveConfig(c)
+		fmt.Printf("Switched to %s\n", name)
+	case "show":
+		name, p := activeName, active
+		if len(args) > 1 {
+			name, p = args[1], c.Profiles[args[1]]
+			if p == nil {
+				fail("profile", fmt.Errorf("no profile named %q", name))
+			}
+		}
+		path, _ := configPath()
+		fmt.Printf("config:    %s\n", path)
+		if p == nil {
+			fmt.Println("profile:   (none)")
+			fmt.Printf("server:    %s\n", defaultServer())
+			return
+		}
+		fmt.Printf("profile:   %s\n", name)
+		fmt.Printf("server:    %s\n", p.URL)
+		switch {
+		case p.APIKeyStore != "":
+			fmt.Printf("api key:   in %s\n", p.APIKeyStore)
+		case p.APIKey != "":
+			fmt.Printf("api key:   %s (plaintext; add the profile again to move it to the keychain)\n", maskKey(p.APIKey))
+		}
+		if p.Namespace != "" {
+			fmt.Printf("namespace: %s\n", p.Namespace)
+		}
+		if p.ClientCert != "" {
+			fmt.Printf("client:    %s\n", p.ClientCert)
+		}
+		if p.CACert != "" {
+			fmt.Printf("ca:        %s\n", p.CACert)
+		}
+	case "remove":
+		name := profileArg(args)
+		p, ok := c.Profiles[name]
+		if !ok {
+			fail("profile", fmt.Errorf("no profile named %q", name))
+		}
+		forgetAPIKey(name, p)
+		delete(c.Profiles, name)
+		if c.Current == name {
+			c.Current = ""
+		}
+		saveConfig(c)
+		fmt.Printf("Removed profile %s\n", name)
+	default:
+		fmt.Fprintf(os.Stderr, "memex profile: unknown command %q\n\n%s\n", args[0], profileUsage)
+		os.Exit(2)
+	}
+}
+
+// profileArg returns the profile name a subcommand requires
+func profileArg(args []string) string {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, profileUsage)
+		os.Exit(2)
+	}
+	return args[1]
+}
+
+// forgetAPIKey removes a profile's API key from its secret store
+func forgetAPIKey(name string, p *profile) {
+	if p.APIKeyStore == "" {
+		return
+	}
+	store, err := openSecrets(p.APIKeyStore)
+	if err == nil {
+		err = store.Delete(secretName(name))
+	}
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "memex profile: warning: could not remove the API key for %s: %v\n", name, err)
+	}
+}
+
+func saveConfig(c *cliConfig) {
+	if err := c.save(); err != nil {
+		fail("profile", err)
+	}
+}
+
+// maskKey shows only the end of an API key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
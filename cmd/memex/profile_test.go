@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeClientCert writes a self-signed client certificate and its key to
+// dir, returning the parsed certificate and both paths
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "memex-cli"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return cert, certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := writeClientCert(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	clients := x509.NewCertPool()
+	clients.AddCert(clientCert)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", srv.Certificate().Raw)
+
+	saved := http.DefaultTransport
+	t.Cleanup(func() { http.DefaultTransport = saved })
+	t.Setenv("MEMEX_CONFIG", filepath.Join(dir, "config.json"))
+	t.Setenv("MEMEX_URL", srv.URL)
+	active, activeExplicit = nil, false
+
+	// Without a certificate the server refuses the handshake
+	t.Setenv("MEMEX_CA_CERT", caFile)
+	if err := sendAPIKey(); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := http.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without a client certificate succeeded")
+	}
+
+	http.DefaultTransport = saved
+	t.Setenv("MEMEX_CLIENT_CERT", certFile)
+	t.Setenv("MEMEX_CLIENT_KEY", keyFile)
+	if err := sendAPIKey(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with a client certificate: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+
+	// A profile's files apply when the environment sets none
+	t.Setenv("MEMEX_CLIENT_CERT", "")
+	t.Setenv("MEMEX_CLIENT_KEY", "")
+	t.Setenv("MEMEX_CA_CERT", "")
+	active = &profile{URL: srv.URL, ClientCert: certFile, ClientKey: keyFile, CACert: caFile}
+	t.Cleanup(func() { active = nil })
+	cfg, err := clientTLSConfig()
+	if err != nil || cfg == nil || len(cfg.Certificates) != 1 || cfg.RootCAs == nil {
+		t.Errorf("profile TLS config = %+v, %v", cfg, err)
+	}
+}
+
+func TestLoadClientTLS(t *testing.T) {
+	dir := t.TempDir()
+	_, certFile, keyFile := writeClientCert(t, dir)
+
+	if cfg, err := loadClientTLS("", "", ""); cfg != nil || err != nil {
+		t.Errorf("no settings = %+v, %v; want nil", cfg, err)
+	}
+	if _, err := loadClientTLS(certFile, "", ""); err == nil {
+		t.Error("certificate without key accepted")
+	}
+	if _, err := loadClientTLS("", "", keyFile); err == nil {
+		t.Error("CA file without certificates accepted")
+	}
+}
+
+func TestProfilePrecedence(t *testing.T) {
+	t.Cleanup(func() { active, activeExplicit = nil, false })
+	work := &profile{URL: "http://work:8080", APIKey: "work-key"}
+
+	tests := []struct {
+		name     string
+		active   *profile
+		explicit bool
+		envURL   string
+		envKey   string
+		wantURL  string
+		wantKey  string
+	}{
+		{"nothing set", nil, false, "", "", "http://localhost:8080", ""},
+		{"env only", nil, false, "http://env:8080", "env-key", "http://env:8080", "env-key"},
+		{"current profile", work, false, "", "", "http://work:8080", "work-key"},
+		{"env beats current profile", work, false, "http://env:8080", "env-key", "http://env:8080", "env-key"},
+		{"explicit profile beats env", work, true, "http://env:8080", "env-key", "http://work:8080", "work-key"},
+		{"env fills what the profile lacks", &profile{URL: "http://work:8080"}, true, "", "env-key", "http://work:8080", "env-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MEMEX_URL", tt.envURL)
+			t.Setenv("MEMEX_API_KEY", tt.envKey)
+			active, activeExplicit = tt.active, tt.explicit
+			if got := defaultServer(); got != tt.wantURL {
+				t.Errorf("defaultServer() = %q, want %q", got, tt.wantURL)
+			}
+			if got := apiKey(); got != tt.wantKey {
+				t.Errorf("apiKey() = %q, want %q", got, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestUseProfile(t *testing.T) {
+	t.Cleanup(func() { active, activeName, activeExplicit = nil, "", false })
+	t.Setenv("MEMEX_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	t.Setenv("MEMEX_PROFILE", "")
+	c := &cliConfig{Current: "home", Profiles: map[string]*profile{
+		"home": {URL: "http://home:8080"},
+		"work": {URL: "http://work:8080"},
+	}}
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		flag, env    string
+		wantName     string
+		wantExplicit bool
+	}{
+		{"config default", "", "", "home", false},
+		{"environment", "", "work", "work", true},
+		{"flag beats environment", "home", "work", "home", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MEMEX_PROFILE", tt.env)
+			if err := useProfile(tt.flag); err != nil {
+				t.Fatal(err)
+			}
+			if activeName != tt.wantName || activeExplicit != tt.wantExplicit {
+				t.Errorf("active = %s (explicit %v), want %s (explicit %v)", activeName, activeExplicit, tt.wantName, tt.wantExplicit)
+			}
+		})
+	}
+
+	if err := useProfile("missing"); err == nil {
+		t.Error("unknown profile accepted")
+	}
+}
+
+func TestQualifyID(t *testing.T) {
+	tests := []struct {
+		id, ns, want string
+	}{
+		{"draft", "acme", "acme:draft"},
+		{"other:draft", "acme", "other:draft"},
+		{"acme:draft", "acme", "acme:draft"},
+		{"draft", "", "draft"},
+		{"person:ada", "", "person:ada"},
+	}
+	for _, tt := range tests {
+		if got := qualifyID(tt.id, tt.ns); got != tt.want {
+			t.Errorf("qualifyID(%q, %q) = %q, want %q", tt.id, tt.ns, got, tt.want)
+		}
+	}
+}
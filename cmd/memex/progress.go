@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"time"
+)
+
+// Progress redraw intervals: a terminal gets a live bar, anything else
+// (logs, CI) a line now and then
+const (
+	progressRedraw   = 200 * time.Millisecond
+	progressLogEvery = 10 * time.Second
+)
+
+// progress reports how far a long command has got on stderr
+type progress struct {
+	command string
+	unit    string
+	total   int // 0 when unknown
+	done    int
+	tty     bool
+	start   time.Time
+	last    time.Time
+}
+
+func newProgress(command, unit string, total int) *progress {
+	info, err := os.Stderr.Stat()
+	now := time.Now()
+	return &progress{
+		command: command,
+		unit:    unit,
+		total:   total,
+		tty:     err == nil && info.Mode()&os.ModeCharDevice != 0,
+		start:   now,
+		last:    now,
+	}
+}
+
+// set records the number of items done, redrawing when due
+func (p *progress) set(done int) {
+	p.done = done
+	now := time.Now()
+	if p.tty && now.Sub(p.last) >= progressRedraw {
+		p.last = now
+		fmt.Fprintf(os.Stderr, "\r%s\033[K", p.line(now))
+	} else if !p.tty && now.Sub(p.last) >= progressLogEvery {
+		p.last = now
+		fmt.Fprintf(os.Stderr, "memex %s: %s\n", p.command, p.line(now))
+	}
+}
+
+// finish draws the final state and ends the bar's line
+func (p *progress) finish() {
+	if p.tty {
+		fmt.Fprintf(os.Stderr, "\r%s\033[K\n", p.line(time.Now()))
+	}
+}
+
+// line renders the bar (with a known total), count, rate and remaining time
+func (p *progress) line(now time.Time) string {
+	var b strings.Builder
+	elapsed := now.Sub(p.start).Seconds()
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.done) / elapsed
+	}
+	if p.total > 0 {
+		const width = 30
+		filled := min(width*p.done/p.total, width)
+		fmt.Fprintf(&b, "[%s%s] %3d%%  %d/%d %s", strings.Repeat("#", filled), strings.Repeat("-", width-filled), 100*p.done/p.total, p.done, p.total, p.unit)
+	} else {
+		fmt.Fprintf(&b, "%d %s", p.done, p.unit)
+	}
+	fmt.Fprintf(&b, "  %.0f/s", rate)
+	if p.total > 0 && rate > 0 && p.done < p.total {
+		fmt.Fprintf(&b, "  ETA %s", (time.Duration(float64(p.total-p.done)/rate) * time.Second).Round(time.Second))
+	}
+	return b.String()
+}
+
+// checkpointState is what a checkpoint file holds: how many inputs a
+// command finished, in order, and a digest of them so a resumed run can
+// tell it was given the same input
+type checkpointState struct {
+	Command string    `json:"command"`
+	Done    int       `json:"done"`
+	Digest  string    `json:"digest"`           // sha256 over the keys of the finished inputs
+	Offset  int64     `json:"offset,omitempty"` // Output bytes written for the finished inputs
+	Retry   []int     `json:"retry,omitempty"`  // Finished inputs to send again at the end, by position
+	Updated time.Time `json:"updated"`
+}
+
+// checkpoint tracks a command's finished inputs in a file, so a run that
+// stops part way can be resumed with --resume. The file is removed once
+// the command completes.
+type checkpoint struct {
+	path    string
+	command string
+	hash    hash.Hash
+	seen    int // Inputs hashed so far
+	resume  *checkpointState
+
+	offset int64 // Saved as checkpointState.Offset
+	retry  []int // Saved as checkpointState.Retry
+}
+
+// openCheckpoint prepares the checkpoint at path. With resume it loads the
+// previous run's state; without, it refuses to overwrite an unfinished run.
+func openCheckpoint(command, path string, resume bool) (*checkpoint, error) {
+	c := &checkpoint{path: path, command: command, hash: sha256.New()}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if resume {
+			return nil, fmt.Errorf("no checkpoint at %s to resume", path)
+		}
+		return c, nil
+	case err != nil:
+		return nil, err
+	case !resume:
+		return nil, fmt.Errorf("an unfinished run left a checkpoint at %s; pass --resume to continue it, or delete it", path)
+	}
+
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+	if state.Command != command {
+		return nil, fmt.Errorf("checkpoint %s is for memex %s, not %s", path, state.Command, command)
+	}
+	c.resume = &state
+	c.offset, c.retry = state.Offset, state.Retry
+	return c, nil
+}
+
+// next records an input by key and reports whether the previous run already
+// finished it. Once the finished inputs are passed it checks they match.
+func (c *checkpoint) next(key string) (finished bool, err error) {
+	c.hash.Write([]byte(key))
+	c.hash.Write([]byte{0})
+	c.seen++
+	if c.resume == nil || c.seen > c.resume.Done {
+		return false, nil
+	}
+	if c.seen == c.resume.Done && c.digest() != c.resume.Digest {
+		return false, fmt.Errorf("input differs from the run checkpointed at %s", c.path)
+	}
+	return true, nil
+}
+
+// skipped returns how many inputs the previous run finished
+func (c *checkpoint) skipped() int {
+	if c.resume == nil {
+		return 0
+	}
+	return c.resume.Done
+}
+
+// verify fails when the input ended before the previous run's finished inputs
+func (c *checkpoint) verify() error {
+	if c.resume != nil && c.seen < c.resume.Done {
+		return fmt.Errorf("input is shorter than the run checkpointed at %s", c.path)
+	}
+	return nil
+}
+
+func (c *checkpoint) digest() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+// save records every input seen so far as finished
+func (c *checkpoint) save() error {
+	data, err := json.Marshal(checkpointState{
+		Command: c.command,
+		Done:    c.seen,
+		Digest:  c.digest(),
+		Offset:  c.offset,
+		Retry:   c.retry,
+		Updated: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// remove deletes the checkpoint after the command completes
+func (c *checkpoint) remove() {
+	os.Remove(c.path)
+}
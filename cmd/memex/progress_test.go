@@ -0,0 +1,96 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runCheckpoint passes keys to a checkpoint, returning which were finished
+func runCheckpoint(t *testing.T, c *checkpoint, keys ...string) []bool {
+	t.Helper()
+	var finished []bool
+	for _, key := range keys {
+		done, err := c.next(key)
+		if err != nil {
+			t.Fatalf("next(%q) error = %v", key, err)
+		}
+		finished = append(finished, done)
+	}
+	return finished
+}
+
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.checkpoint")
+	first, err := openCheckpoint("import csv", path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCheckpoint(t, first, "a", "b", "c")
+	first.offset, first.retry = 42, []int{1}
+	if err := first.save(); err != nil {
+		t.Fatal(err)
+	}
+	runCheckpoint(t, first, "d") // Seen, but the run stops before saving it
+
+	if _, err := openCheckpoint("import csv", path, false); err == nil {
+		t.Error("openCheckpoint() without resume overwrote an unfinished run")
+	}
+	if _, err := openCheckpoint("export csv", path, true); err == nil {
+		t.Error("openCheckpoint() resumed another command's run")
+	}
+
+	second, err := openCheckpoint("import csv", path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.offset != 42 || !reflect.DeepEqual(second.retry, []int{1}) {
+		t.Errorf("resumed offset, retry = %d, %v, want 42, [1]", second.offset, second.retry)
+	}
+	got := runCheckpoint(t, second, "a", "b", "c", "d", "e")
+	if want := []bool{true, true, true, false, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("finished = %v, want %v", got, want)
+	}
+	if second.skipped() != 3 {
+		t.Errorf("skipped() = %d, want 3", second.skipped())
+	}
+	if err := second.verify(); err != nil {
+		t.Errorf("verify() error = %v", err)
+	}
+
+	second.remove()
+	if _, err := openCheckpoint("import csv", path, true); err == nil {
+		t.Error("openCheckpoint() resumed a removed checkpoint")
+	}
+}
+
+func TestCheckpointRefusesOtherInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.checkpoint")
+	first, err := openCheckpoint("add", path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCheckpoint(t, first, "a", "b", "c")
+	if err := first.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := openCheckpoint("add", path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCheckpoint(t, changed, "a", "x")
+	if _, err := changed.next("c"); err == nil || !strings.Contains(err.Error(), "input differs") {
+		t.Errorf("next() with a changed input error = %v, want input differs", err)
+	}
+
+	short, err := openCheckpoint("add", path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCheckpoint(t, short, "a", "b")
+	if err := short.verify(); err == nil {
+		t.Error("verify() accepted an input shorter than the checkpointed run")
+	}
+}
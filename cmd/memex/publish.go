@@ -0,0 +1,430 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// publishPageSize is how many nodes are fetched per filter request
+const publishPageSize = 500
+
+// runPublish implements `memex publish`
+func runPublish(args []string) {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	server := fs.String("server", defaultServer(), "memex-server base URL")
+	var filters stringList
+	fs.Var(&filters, "filter", "node selection: type=T (repeatable) or one key=value property match")
+	out := fs.String("out", "site", "output directory")
+	title := fs.String("title", "Memex", "site title")
+	fs.Parse(args)
+
+	q := url.Values{}
+	for _, f := range filters {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || key == "" {
+			fmt.Fprintf(os.Stderr, "memex publish: invalid filter %q (use type=T or key=value)\n", f)
+			os.Exit(2)
+		}
+		if key == "type" {
+			q.Add("type", value)
+			continue
+		}
+		if q.Get("key") != "" {
+			fmt.Fprintln(os.Stderr, "memex publish: only one key=value property filter is supported")
+			os.Exit(2)
+		}
+		q.Set("key", key)
+		q.Set("value", value)
+	}
+	if len(q) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: memex publish --filter type=Note [--filter key=value] [--out site/] [--title T]")
+		os.Exit(2)
+	}
+
+	c := &publishClient{base: strings.TrimRight(*server, "/"), http: &http.Client{Timeout: 30 * time.Second}}
+	nodes, err := c.filterNodes(q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "memex publish: %v\n", err)
+		os.Exit(1)
+	}
+	links := make(map[string][]core.Link, len(nodes))
+	for _, n := range nodes {
+		if links[n.ID], err = c.links(n.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "memex publish: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	site := buildSite(*title, nodes, links)
+	if err := site.write(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "memex publish: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Published %d nodes and %d tags to %s\n", len(site.Pages), len(site.Tags), *out)
+}
+
+// publishClient reads nodes and links from a memex-server
+type publishClient struct {
+	base string
+	http *http.Client
+}
+
+// filterNodes pages through GET /api/query/filter
+func (c *publishClient) filterNodes(q url.Values) ([]core.Node, error) {
+	var all []core.Node
+	for offset := 0; ; offset += publishPageSize {
+		q.Set("limit", fmt.Sprint(publishPageSize))
+		q.Set("offset", fmt.Sprint(offset))
+		var page struct {
+			Nodes []core.Node `json:"nodes"`
+		}
+		if err := c.get("/api/query/filter?"+q.Encode(), &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Nodes...)
+		if len(page.Nodes) < publishPageSize {
+			return all, nil
+		}
+	}
+}
+
+// links returns a node's outgoing links
+func (c *publishClient) links(id string) ([]core.Link, error) {
+	var links []core.Link
+	if err := c.get("/api/nodes/"+url.PathEscape(id)+"/links", &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (c *publishClient) get(path string, v interface{}) error {
+	resp, err := c.http.Get(c.base + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("GET %s: invalid response: %w", path, err)
+	}
+	return nil
+}
+
+// site is a rendered set of cross-linked pages
+type site struct {
+	Title string
+	Pages []*sitePage
+	Tags  []*siteTag
+}
+
+// sitePage is one published node
+type sitePage struct {
+	ID        string
+	Type      string
+	Label     string
+	File      string
+	Content   string
+	Binary    int // Size of non-text content, which is not rendered
+	Meta      [][2]string
+	Tags      []*siteTag
+	Links     []siteRef
+	Backlinks []siteRef
+	Modified  time.Time
+}
+
+// siteRef is a link to another node; File is empty when that node is not published
+type siteRef struct {
+	Type  string
+	ID    string
+	Label string
+	File  string
+}
+
+// siteTag lists the pages carrying a tag
+type siteTag struct {
+	Name  string
+	File  string
+	Pages []*sitePage
+}
+
+// buildSite turns nodes and their outgoing links into pages with backlinks
+// and tag indexes. Only links between published nodes become hyperlinks.
+func buildSite(title string, nodes []core.Node, links map[string][]core.Link) *site {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	s := &site{Title: title}
+	byID := make(map[string]*sitePage, len(nodes))
+	files := map[string]bool{"index": true, "tags": true}
+	for _, n := range nodes {
+		p := &sitePage{
+			ID:       n.ID,
+			Type:     n.Type,
+			Label:    nodeLabel(n),
+			File:     uniqueSlug("node-"+slugify(n.ID), files) + ".html",
+			Modified: n.Modified,
+		}
+		// Nodes created over the API keep their text in the content property
+		text, _ := n.Meta["content"].(string)
+		if len(n.Content) > 0 {
+			if utf8.Valid(n.Content) {
+				p.Content = string(n.Content)
+			} else {
+				p.Binary = len(n.Content)
+			}
+		} else {
+			p.Content = text
+		}
+		for k, v := range n.Meta {
+			if k == "tags" || k == "content" && p.Content == text {
+				continue
+			}
+			p.Meta = append(p.Meta, [2]string{k, metaString(v)})
+		}
+		sort.Slice(p.Meta, func(i, j int) bool { return p.Meta[i][0] < p.Meta[j][0] })
+		s.Pages = append(s.Pages, p)
+		byID[n.ID] = p
+	}
+
+	ref := func(linkType, id string) siteRef {
+		r := siteRef{Type: linkType, ID: id, Label: id}
+		if p := byID[id]; p != nil {
+			r.Label, r.File = p.Label, p.File
+		}
+		return r
+	}
+	for _, p := range s.Pages {
+		for _, l := range links[p.ID] {
+			p.Links = append(p.Links, ref(l.Type, l.Target))
+			if target := byID[l.Target]; target != nil {
+				target.Backlinks = append(target.Backlinks, ref(l.Type, p.ID))
+			}
+		}
+	}
+
+	tags := make(map[string]*siteTag)
+	for _, n := range nodes {
+		p := byID[n.ID]
+		for _, name := range nodeTags(n.Meta["tags"]) {
+			t := tags[name]
+			if t == nil {
+				t = &siteTag{Name: name, File: uniqueSlug("tag-"+slugify(name), files) + ".html"}
+				tags[name] = t
+				s.Tags = append(s.Tags, t)
+			}
+			t.Pages = append(t.Pages, p)
+			p.Tags = append(p.Tags, t)
+		}
+	}
+	sort.Slice(s.Tags, func(i, j int) bool { return s.Tags[i].Name < s.Tags[j].Name })
+	return s
+}
+
+// write renders the site into dir
+func (s *site) write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	render := func(name, tmpl string, data interface{}) error {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := siteTemplates.ExecuteTemplate(f, tmpl, data); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to render %s: %w", name, err)
+		}
+		return f.Close()
+	}
+
+	if err := render("index.html", "index", s); err != nil {
+		return err
+	}
+	if err := render("tags.html", "tags", s); err != nil {
+		return err
+	}
+	for _, p := range s.Pages {
+		if err := render(p.File, "node", struct {
+			Site *site
+			*sitePage
+		}{s, p}); err != nil {
+			return err
+		}
+	}
+	for _, t := range s.Tags {
+		if err := render(t.File, "tag", struct {
+			Site *site
+			*siteTag
+		}{s, t}); err != nil {
+			return err
+		}
+	}
+	// Serve files as-is on GitHub Pages
+	return os.WriteFile(filepath.Join(dir, ".nojekyll"), nil, 0644)
+}
+
+// nodeLabel picks a display name for a node
+func nodeLabel(n core.Node) string {
+	for _, key := range []string{"name", "title", "label"} {
+		if s, ok := n.Meta[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return n.ID
+}
+
+// nodeTags reads a tags property stored as a list or a comma-separated string
+func nodeTags(v interface{}) []string {
+	var raw []string
+	switch v := v.(type) {
+	case string:
+		raw = strings.Split(v, ",")
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+	var tags []string
+	seen := make(map[string]bool)
+	for _, t := range raw {
+		if t = strings.TrimSpace(t); t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// metaString formats a property value for display
+func metaString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// slugify makes a string safe to use as a file name
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if r < utf8.RuneSelf && (r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimRight(b.String(), "-")
+	if len(slug) > 100 {
+		slug = slug[:100]
+	}
+	return slug
+}
+
+// uniqueSlug returns slug, suffixed with a number if it is already taken
+func uniqueSlug(slug string, taken map[string]bool) string {
+	candidate := slug
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", slug, i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+var siteTemplates = template.Must(template.New("site").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 46rem; margin: 2rem auto; padding: 0 1rem; color: #222; line-height: 1.5; }
+header a { color: inherit; text-decoration: none; font-weight: 600; }
+nav { font-size: 0.9rem; margin-bottom: 1.5rem; }
+.type { color: #777; font-size: 0.85rem; }
+.content { white-space: pre-wrap; overflow-wrap: anywhere; }
+.tag { display: inline-block; background: #eef; border-radius: 3px; padding: 0 0.4rem; margin-right: 0.3rem; font-size: 0.85rem; }
+table { border-collapse: collapse; font-size: 0.9rem; }
+td { padding: 0.2rem 0.8rem 0.2rem 0; vertical-align: top; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "nav"}}<header><a href="index.html">{{.Title}}</a></header>
+<nav><a href="index.html">All pages</a> · <a href="tags.html">Tags</a></nav>
+{{end}}
+
+{{define "refs"}}<ul>
+{{range .}}<li><span class="type">{{.Type}}</span> {{if .File}}<a href="{{.File}}">{{.Label}}</a>{{else}}{{.Label}}{{end}}</li>
+{{end}}</ul>
+{{end}}
+
+{{define "pages"}}<ul>
+{{range .}}<li><a href="{{.File}}">{{.Label}}</a> <span class="type">{{.Type}}</span></li>
+{{end}}</ul>
+{{end}}
+
+{{define "index"}}{{template "head" .Title}}{{template "nav" .}}
+<h1>{{.Title}}</h1>
+{{template "pages" .Pages}}
+</body>
+</html>
+{{end}}
+
+{{define "tags"}}{{template "head" (printf "Tags · %s" .Title)}}{{template "nav" .}}
+<h1>Tags</h1>
+<ul>
+{{range .Tags}}<li><a href="{{.File}}">{{.Name}}</a> ({{len .Pages}})</li>
+{{end}}</ul>
+</body>
+</html>
+{{end}}
+
+{{define "tag"}}{{template "head" (printf "%s · %s" .Name .Site.Title)}}{{template "nav" .Site}}
+<h1>Tagged <span class="tag">{{.Name}}</span></h1>
+{{template "pages" .Pages}}
+</body>
+</html>
+{{end}}
+
+{{define "node"}}{{template "head" (printf "%s · %s" .Label .Site.Title)}}{{template "nav" .Site}}
+<h1>{{.Label}}</h1>
+<p class="type">{{.Type}} · {{.ID}}{{if not .Modified.IsZero}} · updated {{.Modified.Format "2006-01-02"}}{{end}}</p>
+{{if .Tags}}<p>{{range .Tags}}<a class="tag" href="{{.File}}">{{.Name}}</a>{{end}}</p>{{end}}
+{{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{if .Binary}}<p class="type">Binary content ({{.Binary}} bytes) not published.</p>{{end}}
+{{if .Meta}}<h2>Properties</h2>
+<table>
+{{range .Meta}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Links}}<h2>Links</h2>
+{{template "refs" .Links}}{{end}}
+{{if .Backlinks}}<h2>Backlinks</h2>
+{{template "refs" .Backlinks}}{{end}}
+</body>
+</html>
+{{end}}
+`))
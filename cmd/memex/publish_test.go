@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestPublish(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	nodes := []core.Node{
+		{ID: "note:a", Type: "Note", Content: []byte("<script>alert(1)</script> & more"), Meta: map[string]interface{}{"title": "Alpha <b>", "tags": "go, web"}, Modified: now},
+		{ID: "note:b", Type: "Note", Meta: map[string]interface{}{"title": "Beta", "content": "plain text"}, Modified: now},
+	}
+	links := map[string][]core.Link{
+		"note:a": {{Source: "note:a", Target: "note:b", Type: "CITES"}, {Source: "note:a", Target: "person:private", Type: "MENTIONS"}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/query/filter":
+			json.NewEncoder(w).Encode(map[string]interface{}{"nodes": nodes})
+		case strings.HasSuffix(r.URL.Path, "/links"):
+			id, _ := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(r.URL.EscapedPath(), "/api/nodes/"), "/links"))
+			l := links[id]
+			if l == nil {
+				l = []core.Link{}
+			}
+			json.NewEncoder(w).Encode(l)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := &publishClient{base: srv.URL, http: srv.Client()}
+	fetched, err := c.filterNodes(url.Values{"type": {"Note"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetchedLinks := make(map[string][]core.Link)
+	for _, n := range fetched {
+		if fetchedLinks[n.ID], err = c.links(n.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	s := buildSite("Notes", fetched, fetchedLinks)
+	if err := s.write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// One page per node
+	pages, _ := filepath.Glob(filepath.Join(dir, "node-*.html"))
+	if len(pages) != 2 {
+		t.Fatalf("published %d node pages, want 2: %v", len(pages), pages)
+	}
+	a, b := read("node-note-a.html"), read("node-note-b.html")
+
+	// Cross-links between published pages, in both directions
+	if !strings.Contains(a, `<a href="node-note-b.html">Beta</a>`) {
+		t.Error("page a does not link to page b")
+	}
+	if !strings.Contains(b, `<h2>Backlinks</h2>`) || !strings.Contains(b, `href="node-note-a.html"`) {
+		t.Error("page b has no backlink to page a")
+	}
+	// Links to unpublished nodes are shown but not linked
+	if !strings.Contains(a, "person:private") || strings.Contains(a, `href="node-person`) {
+		t.Error("unpublished link target rendered as a hyperlink")
+	}
+	if !strings.Contains(read("index.html"), `href="node-note-a.html"`) {
+		t.Error("index does not list page a")
+	}
+	if !strings.Contains(read("tags.html"), `href="tag-go.html"`) {
+		t.Error("tag index does not list the go tag")
+	}
+
+	// Node content and properties are escaped
+	if strings.Contains(a, "<script>") || strings.Contains(a, "Alpha <b>") {
+		t.Error("node content rendered unescaped")
+	}
+	if !strings.Contains(a, "&lt;script&gt;alert(1)&lt;/script&gt; &amp; more") || !strings.Contains(a, "Alpha &lt;b&gt;") {
+		t.Error("escaped content missing")
+	}
+	if !strings.Contains(b, "plain text") {
+		t.Error("content property not rendered")
+	}
+}
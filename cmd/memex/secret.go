@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const secretUsage = `Usage: memex secret set [--store S] NAME    (reads the value from stdin)
+       memex secret get [--store S] NAME
+       memex secret delete [--store S] NAME
+       memex secret store`
+
+// runSecret implements `memex secret`: the secret store shared by profiles,
+// capture and connectors, e.g. capture:passphrase or connector:github
+func runSecret(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, secretUsage)
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("secret "+args[0], flag.ExitOnError)
+	backend := fs.String("store", "", "keychain or file (default keychain, else the encrypted file)")
+	fs.Parse(args[1:])
+
+	store, err := openSecrets(*backend)
+	if err != nil {
+		fail("secret", err)
+	}
+	if args[0] == "store" {
+		fmt.Println(store.Name())
+		return
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, secretUsage)
+		os.Exit(2)
+	}
+	name := fs.Arg(0)
+
+	switch args[0] {
+	case "set":
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = strings.TrimRight(value, "\r\n")
+		if value == "" {
+			if err != nil {
+				fail("secret", fmt.Errorf("reading the value from stdin: %w", err))
+			}
+			fail("secret", errors.New("empty value"))
+		}
+		if err := store.Set(name, value); err != nil {
+			fail("secret", err)
+		}
+		fmt.Fprintf(os.Stderr, "Stored %s in %s\n", name, store.Name())
+	case "get":
+		value, err := store.Get(name)
+		if err != nil {
+			fail("secret", fmt.Errorf("%s: %w", name, err))
+		}
+		fmt.Println(value)
+	case "delete":
+		if err := store.Delete(name); err != nil {
+			fail("secret", fmt.Errorf("%s: %w", name, err))
+		}
+		fmt.Fprintf(os.Stderr, "Deleted %s from %s\n", name, store.Name())
+	default:
+		fmt.Fprintf(os.Stderr, "memex secret: unknown command %q\n\n%s\n", args[0], secretUsage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,92 @@
+// Command attention-retrieval answers a question with context from the
+// graph, the way an agent loop would: full-text search finds the first
+// hits, the hits used together are recorded as attention edges, and the
+// attention subgraph around the best hit pulls in nodes earlier questions
+// found useful alongside it. Run it a few times with related questions to
+// see the attention edges grow.
+//
+//	go run ./examples/attention-retrieval "how does the analytical engine store numbers"
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/systemshift/memex/pkg/client"
+)
+
+func main() {
+	server := flag.String("server", getEnv("MEMEX_URL", "http://localhost:8080"), "memex-server base URL")
+	hits := flag.Int("hits", 5, "search hits to use")
+	minWeight := flag.Float64("min-weight", 0.3, "weakest attention edge to follow")
+	flag.Parse()
+	question := strings.Join(flag.Args(), " ")
+	if question == "" {
+		fmt.Fprintln(os.Stderr, "Usage: attention-retrieval [--server URL] [--hits N] QUESTION")
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	c := client.New(*server, os.Getenv("MEMEX_API_KEY"))
+
+	found, err := c.Search(ctx, question, *hits)
+	if err != nil {
+		log.Fatalf("search: %v", err)
+	}
+	if len(found.Nodes) == 0 {
+		fmt.Println("Nothing found.")
+		return
+	}
+
+	// The hits answered the same question, so each pair attended to each
+	// other; higher-ranked pairs get more weight
+	sum := sha256.Sum256([]byte(question))
+	queryID := "q:" + hex.EncodeToString(sum[:8])
+	for i, a := range found.Nodes {
+		for j := i + 1; j < len(found.Nodes); j++ {
+			weight := 1 / float64(1+i+j)
+			err := c.UpdateAttention(ctx, client.AttentionEdgeRequest{Source: a.ID, Target: found.Nodes[j].ID, QueryID: queryID, Weight: weight})
+			if err != nil {
+				log.Fatalf("recording attention: %v", err)
+			}
+		}
+	}
+
+	context := make(map[string]*client.Node)
+	var order []string
+	add := func(n *client.Node, why string) {
+		if context[n.ID] == nil {
+			context[n.ID] = n
+			order = append(order, fmt.Sprintf("%-10s %s", why, n.ID))
+		}
+	}
+	for _, n := range found.Nodes {
+		add(n, "search")
+	}
+	sub, err := c.AttentionSubgraph(ctx, found.Nodes[0].ID, *minWeight, 20)
+	if err != nil {
+		log.Fatalf("attention subgraph: %v", err)
+	}
+	for _, n := range sub.Nodes {
+		add(n, "attention")
+	}
+
+	fmt.Printf("Context for %q (%d nodes):\n", question, len(order))
+	for _, line := range order {
+		fmt.Println("  " + line)
+	}
+}
+
+// getEnv returns the environment variable key, or defaultValue when unset
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
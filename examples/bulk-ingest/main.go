@@ -0,0 +1,90 @@
+// Command bulk-ingest stores every text file under a directory as a source
+// node, a batch of files per request. Running it again is safe: files
+// already ingested come back deduplicated.
+//
+//	go run ./examples/bulk-ingest ~/notes
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/pkg/client"
+)
+
+func main() {
+	server := flag.String("server", getEnv("MEMEX_URL", "http://localhost:8080"), "memex-server base URL")
+	batch := flag.Int("batch", 100, "files per request")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: bulk-ingest [--server URL] [--batch N] DIR")
+		os.Exit(2)
+	}
+	dir := flag.Arg(0)
+
+	ctx := context.Background()
+	c := client.New(*server, os.Getenv("MEMEX_API_KEY"))
+
+	var pending []client.IngestRequest
+	var created, deduplicated, skipped int
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		res, err := c.BulkIngest(ctx, pending)
+		if err != nil {
+			log.Fatalf("bulk ingest: %v", err)
+		}
+		for _, src := range res.Sources {
+			if src.Deduplicated {
+				deduplicated++
+			}
+		}
+		created += res.Created
+		pending = pending[:0]
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if len(content) == 0 || !utf8.Valid(content) {
+			skipped++ // Empty or binary
+			return nil
+		}
+		abs, _ := filepath.Abs(path)
+		pending = append(pending, client.IngestRequest{
+			Content:   string(content),
+			Format:    "text",
+			URL:       "file://" + filepath.ToSlash(abs),
+			Connector: "bulk-ingest-example",
+		})
+		if len(pending) >= *batch {
+			flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	flush()
+	fmt.Printf("%d sources created, %d already ingested, %d files skipped\n", created, deduplicated, skipped)
+}
+
+// getEnv returns the environment variable key, or defaultValue when unset
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,215 @@
+// Command lens-extraction reads a document through a lens. It creates the
+// lens, ingests the document as a source, asks an LLM for the entities the
+// lens's primitives describe, and stores each one linked to the source
+// (EXTRACTED_FROM) and to the lens (INTERPRETED_THROUGH).
+//
+//	export OPENAI_API_KEY=...
+//	go run ./examples/lens-extraction article.txt
+//
+// Any OpenAI-compatible chat completions endpoint works; set LLM_URL and
+// --model for a local one.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/systemshift/memex/pkg/client"
+)
+
+// lens is the reading applied to the document: each primitive is a kind of
+// entity the LLM looks for
+var lens = client.CreateLensRequest{
+	ID:          "research-reading",
+	Name:        "Research reading",
+	Description: "Reads papers and notes for the claims, methods and results they contain",
+	Primitives: map[string]string{
+		"claim":  "an assertion the text argues for",
+		"method": "a technique, tool or procedure used",
+		"result": "a measured or observed outcome",
+	},
+	ExtractionHints: "Prefer short, specific names. Skip background the text does not argue.",
+}
+
+// entity is one extracted item
+type entity struct {
+	Primitive string `json:"primitive"`
+	Name      string `json:"name"`
+	Summary   string `json:"summary"`
+}
+
+func main() {
+	server := flag.String("server", getEnv("MEMEX_URL", "http://localhost:8080"), "memex-server base URL")
+	model := flag.String("model", "gpt-4o-mini", "chat model")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lens-extraction [--server URL] [--model M] FILE")
+		os.Exit(2)
+	}
+	content, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	c := client.New(*server, os.Getenv("MEMEX_API_KEY"))
+
+	lensID := "lens:" + lens.ID
+	if _, err := c.GetNode(ctx, lensID); client.IsNotFound(err) {
+		if lensID, err = c.CreateLens(ctx, lens); err != nil {
+			log.Fatalf("creating lens: %v", err)
+		}
+	} else if err != nil {
+		log.Fatal(err)
+	}
+
+	source, err := c.Ingest(ctx, client.IngestRequest{Content: string(content), Format: "text", Connector: "lens-extraction-example"})
+	if err != nil {
+		log.Fatalf("ingesting: %v", err)
+	}
+	fmt.Printf("Source %s\n", source.SourceID)
+
+	entities, err := extract(ctx, *model, string(content))
+	if err != nil {
+		log.Fatalf("extracting: %v", err)
+	}
+	for _, e := range entities {
+		id := e.Primitive + ":" + slug(e.Name)
+		if _, err := c.GetNode(ctx, id); client.IsNotFound(err) {
+			_, err = c.CreateNode(ctx, client.CreateNodeRequest{
+				ID:   id,
+				Type: strings.ToUpper(e.Primitive[:1]) + e.Primitive[1:],
+				Meta: map[string]interface{}{"name": e.Name, "summary": e.Summary},
+			})
+			if err != nil {
+				log.Fatalf("creating %s: %v", id, err)
+			}
+		} else if err != nil {
+			log.Fatal(err)
+		}
+		existing, err := c.GetLinks(ctx, id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		links := []client.CreateLinkRequest{
+			{Source: id, Target: source.SourceID, Type: "EXTRACTED_FROM"},
+			{Source: id, Target: lensID, Type: "INTERPRETED_THROUGH", Meta: map[string]interface{}{"primitive": e.Primitive}},
+		}
+		for _, link := range links {
+			if hasLink(existing, link) {
+				continue
+			}
+			if err := c.CreateLink(ctx, link); err != nil {
+				log.Fatalf("linking %s: %v", id, err)
+			}
+		}
+		fmt.Printf("  %-8s %s\n", e.Primitive, e.Name)
+	}
+
+	all, err := c.LensEntities(ctx, lensID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d entities extracted; %s now interprets %d\n", len(entities), lensID, len(all))
+}
+
+// extract asks the chat model for the lens's entities in text
+func extract(ctx context.Context, model, text string) ([]entity, error) {
+	var primitives strings.Builder
+	for name, desc := range lens.Primitives {
+		fmt.Fprintf(&primitives, "- %s: %s\n", name, desc)
+	}
+	prompt := fmt.Sprintf(`Extract entities from the text below. Entity kinds:
+%s
+%s
+Answer with JSON only: {"entities": [{"primitive": "<kind>", "name": "...", "summary": "<one sentence>"}]}
+
+Text:
+%s`, primitives.String(), lens.ExtractionHints, text)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model":           model,
+		"messages":        []map[string]string{{"role": "user", "content": prompt}},
+		"response_format": map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", getEnv("LLM_URL", "https://api.openai.com/v1/chat/completions"), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("OPENAI_API_KEY"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("model returned %d: %s", resp.StatusCode, msg)
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, err
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("model returned no choices")
+	}
+	var out struct {
+		Entities []entity `json:"entities"`
+	}
+	if err := json.Unmarshal([]byte(completion.Choices[0].Message.Content), &out); err != nil {
+		return nil, fmt.Errorf("model answer is not the JSON asked for: %w", err)
+	}
+	var entities []entity
+	for _, e := range out.Entities {
+		if _, ok := lens.Primitives[e.Primitive]; ok && slug(e.Name) != "" {
+			entities = append(entities, e)
+		}
+	}
+	return entities, nil
+}
+
+// hasLink reports whether links already holds link, so a rerun over the
+// same document adds nothing
+func hasLink(links []*client.Link, link client.CreateLinkRequest) bool {
+	for _, l := range links {
+		if l.Target == link.Target && l.Type == link.Type {
+			return true
+		}
+	}
+	return false
+}
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug turns a name into the ID part of a node ID
+func slug(name string) string {
+	return strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// getEnv returns the environment variable key, or defaultValue when unset
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
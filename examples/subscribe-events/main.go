@@ -0,0 +1,81 @@
+// Command subscribe-events prints graph changes as they happen, reconnecting
+// when the stream drops. Filters match the /api/events/stream parameters.
+//
+//	go run ./examples/subscribe-events --type node.created --node-type Person
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/pkg/client"
+)
+
+func main() {
+	server := flag.String("server", getEnv("MEMEX_URL", "http://localhost:8080"), "memex-server base URL")
+	eventTypes := flag.String("type", "", "comma-separated event types, e.g. node.created,link.created")
+	nodeTypes := flag.String("node-type", "", "comma-separated node types")
+	linkTypes := flag.String("link-type", "", "comma-separated link types")
+	expr := flag.String("expr", "", "subscription expression events must satisfy")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c := client.New(*server, os.Getenv("MEMEX_API_KEY"))
+	filter := client.EventFilter{
+		Types:     split(*eventTypes),
+		NodeTypes: split(*nodeTypes),
+		LinkTypes: split(*linkTypes),
+		Expr:      *expr,
+	}
+
+	for {
+		err := c.StreamEvents(ctx, filter, func(e client.Event) {
+			switch {
+			case e.NodeID != "":
+				fmt.Printf("%s  %-13s %s (%s)\n", e.Timestamp.Format(time.TimeOnly), e.Type, e.NodeID, e.NodeType)
+			default:
+				fmt.Printf("%s  %-13s %s -[%s]-> %s\n", e.Timestamp.Format(time.TimeOnly), e.Type, e.LinkSource, e.LinkType, e.LinkTarget)
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) && apiErr.Status < 500 {
+			log.Fatal(err) // A bad filter won't fix itself
+		}
+		log.Printf("stream ended (%v), reconnecting", err)
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// split splits a comma-separated flag, dropping empty entries
+func split(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// getEnv returns the environment variable key, or defaultValue when unset
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -35,12 +35,14 @@ type VersionInfo struct {
 
 // Link represents a relationship between nodes
 type Link struct {
-	Source   string
-	Target   string
-	Type     string
-	Meta     map[string]interface{}
-	Created  time.Time
-	Modified time.Time
+	Source        string
+	Target        string
+	Type          string
+	Meta          map[string]interface{}
+	Weight        float64 // Strength of the relationship; 0 when unweighted
+	Bidirectional bool    // The relationship holds from Target to Source too
+	Created       time.Time
+	Modified      time.Time
 }
 
 // Repository defines the interface for repository operations
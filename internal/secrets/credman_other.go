@@ -0,0 +1,7 @@
+//go:build !windows
+
+package secrets
+
+func windowsCredentials() (Store, error) {
+	return nil, ErrUnavailable
+}
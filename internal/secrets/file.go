@@ -0,0 +1,173 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// fileVersion is the encrypted file format version
+const fileVersion = 1
+
+// scrypt parameters for deriving the file key (the 2017 interactive
+// recommendation)
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// encryptedFile is the on-disk form: the secrets as a JSON object,
+// sealed with AES-256-GCM under a key derived from the passphrase
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileStore keeps secrets in a passphrase-encrypted file, for machines
+// without a keychain. Each write re-encrypts the whole file with a fresh
+// salt and nonce.
+type FileStore struct {
+	path       string
+	passphrase string
+	mu         sync.Mutex
+}
+
+// NewFileStore returns a store for the file at path; the file is created
+// on the first Set
+func NewFileStore(path, passphrase string) *FileStore {
+	return &FileStore{path: path, passphrase: passphrase}
+}
+
+// Name identifies the store
+func (s *FileStore) Name() string { return "file" }
+
+// Get returns the secret stored under name
+func (s *FileStore) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := all[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores value under name, replacing any previous value
+func (s *FileStore) Set(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	all[name] = value
+	return s.save(all)
+}
+
+// Delete removes the secret stored under name
+func (s *FileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := all[name]; !ok {
+		return ErrNotFound
+	}
+	delete(all, name)
+	return s.save(all)
+}
+
+// load decrypts the file; a missing file holds no secrets
+func (s *FileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f encryptedFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.path, err)
+	}
+	if f.Version != fileVersion {
+		return nil, fmt.Errorf("%s: unsupported format version %d", s.path, f.Version)
+	}
+	gcm, err := s.cipher(f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: wrong passphrase or corrupt file", s.path)
+	}
+	all := map[string]string{}
+	if err := json.Unmarshal(plain, &all); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.path, err)
+	}
+	return all, nil
+}
+
+// save encrypts all and replaces the file atomically
+func (s *FileStore) save(all map[string]string) error {
+	plain, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	f := encryptedFile{Version: fileVersion, Salt: make([]byte, 16)}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	gcm, err := s.cipher(f.Salt)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, plain, nil)
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// cipher derives the file key from the passphrase and salt
+func (s *FileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(s.passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
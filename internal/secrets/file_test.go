@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memex", "secrets.enc")
+	s := NewFileStore(path, "correct horse")
+
+	if _, err := s.Get("profile:work"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() on missing file error = %v, want ErrNotFound", err)
+	}
+	if err := s.Set("profile:work", "mk_secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := s.Set("capture:passphrase", "hunter2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, err := s.Get("profile:work"); err != nil || got != "mk_secret" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "mk_secret") || strings.Contains(string(data), "profile:work") {
+		t.Errorf("file holds plaintext: %s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if _, err := NewFileStore(path, "wrong").Get("profile:work"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() with wrong passphrase error = %v", err)
+	}
+
+	if err := s.Delete("profile:work"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get("profile:work"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+	if got, _ := s.Get("capture:passphrase"); got != "hunter2" {
+		t.Errorf("other secret = %q after Delete()", got)
+	}
+}
+
+func TestOpenFileNeedsPassphrase(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	if _, err := Open("file", filepath.Join(t.TempDir(), "secrets.enc")); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Open(file) without passphrase error = %v, want ErrUnavailable", err)
+	}
+	t.Setenv(PassphraseEnv, "pass")
+	if s, err := Open("file", filepath.Join(t.TempDir(), "secrets.enc")); err != nil || s.Name() != "file" {
+		t.Errorf("Open(file) = %v, %v", s, err)
+	}
+}
@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Keychain returns the OS keychain, or ErrUnavailable when this machine
+// has none. macOS uses the security tool and Linux and the BSDs use
+// secret-tool (libsecret), so neither needs cgo; Windows calls the
+// Credential Manager API.
+func Keychain() (Store, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil, fmt.Errorf("security tool not found: %w", ErrUnavailable)
+		}
+		return macKeychain{}, nil
+	case "windows":
+		return windowsCredentials()
+	default:
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return nil, fmt.Errorf("secret-tool not found (install libsecret-tools): %w", ErrUnavailable)
+		}
+		// secret-tool is installed on headless machines too; check a
+		// Secret Service is actually running
+		if _, err := run("", "secret-tool", "search", "service", Service); err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("no Secret Service running: %w", ErrUnavailable)
+		}
+		return secretService{}, nil
+	}
+}
+
+// macKeychain stores generic passwords in the login keychain
+type macKeychain struct{}
+
+func (macKeychain) Name() string { return "keychain" }
+
+func (macKeychain) Get(name string) (string, error) {
+	out, err := run("", "security", "find-generic-password", "-s", Service, "-a", name, "-w")
+	return strings.TrimSuffix(out, "\n"), err
+}
+
+// Set writes the command to security's interactive mode on stdin. Passing
+// -w on the command line would show the value to every user through ps.
+func (macKeychain) Set(name, value string) error {
+	line, err := securityAddCommand(name, value)
+	if err != nil {
+		return err
+	}
+
+	// Interactive mode reports a failed command on stderr but still exits 0
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(line + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if msg := strings.TrimSpace(strings.ReplaceAll(stderr.String(), "security> ", "")); msg != "" {
+		return fmt.Errorf("security: %s", msg)
+	}
+	return err
+}
+
+// securityAddCommand returns the interactive-mode line that stores value
+func securityAddCommand(name, value string) (string, error) {
+	if strings.ContainsAny(name+value, "\r\n") {
+		return "", errors.New("keychain names and values cannot contain line breaks")
+	}
+	return strings.Join([]string{
+		"add-generic-password", "-U",
+		"-s", securityQuote(Service),
+		"-a", securityQuote(name),
+		"-l", securityQuote(Service + ": " + name),
+		"-w", securityQuote(value),
+	}, " "), nil
+}
+
+// securityQuote quotes an argument for security's interactive mode
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (macKeychain) Delete(name string) error {
+	_, err := run("", "security", "delete-generic-password", "-s", Service, "-a", name)
+	return err
+}
+
+// secretService stores items in the default Secret Service collection
+// (GNOME Keyring, KWallet, KeePassXC)
+type secretService struct{}
+
+func (secretService) Name() string { return "keychain" }
+
+func (secretService) Get(name string) (string, error) {
+	out, err := run("", "secret-tool", "lookup", "service", Service, "account", name)
+	if err == nil && out == "" {
+		return "", ErrNotFound
+	}
+	return out, err
+}
+
+func (secretService) Set(name, value string) error {
+	_, err := run(value, "secret-tool", "store", "--label", Service+": "+name, "service", Service, "account", name)
+	return err
+}
+
+func (s secretService) Delete(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+	_, err := run("", "secret-tool", "clear", "service", Service, "account", name)
+	return err
+}
+
+// run runs a keychain tool with stdin, returning its stdout. An exit
+// status of 1 (secret-tool) or 44 (security) means the item is missing.
+func run(stdin, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		if code := exit.ExitCode(); (code == 1 && stderr.Len() == 0) || code == 44 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("%s: %s", name, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,42 @@
+package secrets
+
+import "testing"
+
+func TestSecurityQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"mk_secret", `"mk_secret"`},
+		{"two words", `"two words"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\path`, `"C:\\path"`},
+		{`\"`, `"\\\""`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := securityQuote(tt.in); got != tt.want {
+			t.Errorf("securityQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSecurityAddCommand(t *testing.T) {
+	line, err := securityAddCommand("profile:work", `p"a\ss word`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `add-generic-password -U -s "memex" -a "profile:work" -l "memex: profile:work" -w "p\"a\\ss word"`
+	if line != want {
+		t.Errorf("securityAddCommand() =\n%s\nwant\n%s", line, want)
+	}
+
+	for _, tt := range []struct{ name, value string }{
+		{"profile:work", "line\nbreak"},
+		{"profile:work", "carriage\rreturn"},
+		{"profile\nwork", "mk_secret"},
+	} {
+		if _, err := securityAddCommand(tt.name, tt.value); err == nil {
+			t.Errorf("securityAddCommand(%q, %q) accepted a line break", tt.name, tt.value)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package secrets keeps API keys and passphrases out of plaintext config.
+// Secrets go in the OS keychain (macOS Keychain, Windows Credential Manager,
+// or the Secret Service on Linux and the BSDs), or where none is available
+// in a file encrypted with a passphrase.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Service is the keychain service secrets are filed under
+const Service = "memex"
+
+// PassphraseEnv names the variable holding the encrypted file's passphrase
+const PassphraseEnv = "MEMEX_SECRETS_PASSPHRASE"
+
+var (
+	// ErrNotFound indicates no secret is stored under the name
+	ErrNotFound = errors.New("secret not found")
+	// ErrUnavailable indicates the store cannot be used on this machine
+	ErrUnavailable = errors.New("secret store unavailable")
+)
+
+// Store holds secrets by name
+type Store interface {
+	// Name identifies the store, "keychain" or "file"
+	Name() string
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Delete(name string) error
+}
+
+// Open returns the store named by backend: "keychain", "file", or "" for
+// the keychain when available and the encrypted file otherwise. The file
+// lives at path and needs $MEMEX_SECRETS_PASSPHRASE.
+func Open(backend, path string) (Store, error) {
+	switch backend {
+	case "keychain":
+		return Keychain()
+	case "file":
+		return openFile(path)
+	case "":
+		if kc, err := Keychain(); err == nil {
+			return kc, nil
+		}
+		store, err := openFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("no OS keychain found and %w", err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown secret store %q (keychain, file)", backend)
+	}
+}
+
+func openFile(path string) (Store, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("the encrypted secrets file needs %s: %w", PassphraseEnv, ErrUnavailable)
+	}
+	return NewFileStore(path, passphrase), nil
+}
+
+// DefaultFilePath returns memex/secrets.enc in the user config directory
+func DefaultFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "memex", "secrets.enc"), nil
+}
@@ -0,0 +1,332 @@
+// Package analytics runs registered, read-only SQL templates over the
+// SQLite database on a connection pool of their own, so heavy aggregations
+// do not queue behind or hold up the operational connection.
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLite driver
+)
+
+var (
+	// ErrNotFound is returned for unknown queries
+	ErrNotFound = errors.New("analytics query not found")
+	// ErrInvalidParams is returned for missing or malformed parameters
+	ErrInvalidParams = errors.New("invalid parameters")
+)
+
+// Parameter types
+const (
+	ParamString = "string"
+	ParamInt    = "int"
+	ParamFloat  = "float"
+	ParamTime   = "time" // Bound as epoch seconds
+)
+
+var (
+	validName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	// Statements other than reads, refused in templates even though the
+	// connection is read-only
+	forbidden = regexp.MustCompile(`(?i)\b(attach|detach|pragma|insert|update|delete|create|drop|alter|vacuum|reindex|analyze)\b`)
+)
+
+// Param is a named template parameter, bound as :name
+type Param struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`              // string, int, float or time
+	Default     string `json:"default,omitempty"` // For time, RFC3339, YYYY-MM-DD, now or a negative duration such as -720h
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Query is a registered analytical SQL template
+type Query struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	SQL         string  `json:"sql"`
+	Params      []Param `json:"params,omitempty"`
+	Builtin     bool    `json:"builtin,omitempty"`
+}
+
+// Result is a query's rows
+type Result struct {
+	Query     string          `json:"query"`
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	Count     int             `json:"count"`
+	Truncated bool            `json:"truncated,omitempty"` // MaxRows was reached
+	ElapsedMS int64           `json:"elapsed_ms"`
+}
+
+// Config holds analytics configuration
+type Config struct {
+	MaxRows  int           // Rows returned per query (default 10000)
+	Timeout  time.Duration // Per query (default 30s)
+	MaxConns int           // Connections in the pool (default 2)
+}
+
+// Engine runs registered queries on a read-only pool
+type Engine struct {
+	db      *sql.DB
+	cfg     Config
+	mu      sync.RWMutex
+	queries map[string]*Query
+}
+
+// Open opens a read-only pool on the SQLite file at path with the built-in
+// queries registered
+func Open(path string, cfg Config) (*Engine, error) {
+	if path == "" || strings.HasPrefix(path, ":memory:") || strings.HasPrefix(path, "file:") {
+		return nil, fmt.Errorf("analytics need a SQLite file, not %q", path)
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = 10000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxConns <= 0 {
+		cfg.MaxConns = 2
+	}
+	dsn := (&url.URL{
+		Scheme:   "file",
+		Path:     path,
+		RawQuery: "mode=ro&_pragma=busy_timeout(5000)&_pragma=query_only(1)",
+	}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening analytics pool: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxConns)
+	db.SetMaxIdleConns(cfg.MaxConns)
+
+	e := &Engine{db: db, cfg: cfg, queries: make(map[string]*Query)}
+	for _, q := range builtins {
+		q := q
+		q.Builtin = true
+		if err := e.Register(&q); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Close closes the pool
+func (e *Engine) Close() error {
+	return e.db.Close()
+}
+
+// Register validates and adds a query, replacing one of the same name
+func (e *Engine) Register(q *Query) error {
+	if err := q.validate(); err != nil {
+		return fmt.Errorf("query %q: %w", q.Name, err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queries[q.Name] = q
+	return nil
+}
+
+// LoadFile registers the queries in a JSON file holding a list of them
+func (e *Engine) LoadFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var list []*Query
+	if err := json.Unmarshal(data, &list); err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for _, q := range list {
+		q.Builtin = false
+		if err := e.Register(q); err != nil {
+			return 0, err
+		}
+	}
+	return len(list), nil
+}
+
+// List returns the registered queries by name
+func (e *Engine) List() []*Query {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make([]*Query, 0, len(e.queries))
+	for _, q := range e.queries {
+		out = append(out, q)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Run runs a registered query with its parameters given as strings;
+// absent ones take their defaults
+func (e *Engine) Run(ctx context.Context, name string, params map[string]string) (*Result, error) {
+	e.mu.RLock()
+	q, ok := e.queries[name]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	args, err := q.bind(params, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	rows, err := e.db.QueryContext(ctx, q.SQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	result := &Result{Query: name, Rows: [][]interface{}{}}
+	if result.Columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		if len(result.Rows) == e.cfg.MaxRows {
+			result.Truncated = true
+			break
+		}
+		values := make([]interface{}, len(result.Columns))
+		ptrs := make([]interface{}, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("running %s: %w", name, err)
+	}
+	result.Count = len(result.Rows)
+	result.ElapsedMS = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// validate checks a query is a single read with well-formed parameters
+func (q *Query) validate() error {
+	if !validName.MatchString(q.Name) {
+		return errors.New("invalid name (use 1-64 lower-case letters, digits or _)")
+	}
+	sqlText := strings.TrimSuffix(strings.TrimSpace(q.SQL), ";")
+	lower := strings.ToLower(sqlText)
+	if !strings.HasPrefix(lower, "select") && !strings.HasPrefix(lower, "with") {
+		return errors.New("sql must be a SELECT or WITH statement")
+	}
+	if strings.Contains(sqlText, ";") {
+		return errors.New("sql must be a single statement")
+	}
+	if m := forbidden.FindString(sqlText); m != "" {
+		return fmt.Errorf("sql must only read (found %s)", strings.ToUpper(m))
+	}
+	q.SQL = sqlText
+	seen := make(map[string]bool)
+	for _, p := range q.Params {
+		if !validName.MatchString(p.Name) || seen[p.Name] {
+			return fmt.Errorf("invalid or repeated parameter name %q", p.Name)
+		}
+		seen[p.Name] = true
+		if _, err := p.parse(p.Default, time.Now()); err != nil {
+			return fmt.Errorf("parameter %s: invalid default: %w", p.Name, err)
+		}
+		if !strings.Contains(sqlText, ":"+p.Name) {
+			return fmt.Errorf("parameter %s is not used in the sql", p.Name)
+		}
+	}
+	return nil
+}
+
+// bind converts parameters to named arguments
+func (q *Query) bind(params map[string]string, now time.Time) ([]interface{}, error) {
+	known := make(map[string]bool, len(q.Params))
+	args := make([]interface{}, 0, len(q.Params))
+	for _, p := range q.Params {
+		known[p.Name] = true
+		raw, ok := params[p.Name]
+		if !ok || raw == "" {
+			if p.Required {
+				return nil, fmt.Errorf("%w: %s is required", ErrInvalidParams, p.Name)
+			}
+			raw = p.Default
+		}
+		v, err := p.parse(raw, now)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidParams, p.Name, err)
+		}
+		args = append(args, sql.Named(p.Name, v))
+	}
+	for name := range params {
+		if !known[name] {
+			return nil, fmt.Errorf("%w: unknown parameter %s", ErrInvalidParams, name)
+		}
+	}
+	return args, nil
+}
+
+// parse converts a parameter value to its type; empty values are zero
+// (now, for times)
+func (p *Param) parse(raw string, now time.Time) (interface{}, error) {
+	switch p.Type {
+	case ParamString:
+		return raw, nil
+	case ParamInt:
+		if raw == "" {
+			return int64(0), nil
+		}
+		return strconv.ParseInt(raw, 10, 64)
+	case ParamFloat:
+		if raw == "" {
+			return 0.0, nil
+		}
+		return strconv.ParseFloat(raw, 64)
+	case ParamTime:
+		t, err := parseTime(raw, now)
+		if err != nil {
+			return nil, err
+		}
+		return t.Unix(), nil
+	}
+	return nil, fmt.Errorf("unknown type %q (use string, int, float or time)", p.Type)
+}
+
+// parseTime reads RFC3339, YYYY-MM-DD, now or a negative duration from now
+func parseTime(raw string, now time.Time) (time.Time, error) {
+	switch {
+	case raw == "" || raw == "now":
+		return now, nil
+	case strings.HasPrefix(raw, "-"):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", raw, time.UTC)
+}
@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestBuiltinQueries(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memex.db")
+	repo, err := graph.NewSQLite(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close(ctx)
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "person:ada", Type: "Person"},
+		{ID: "person:bob", Type: "Person"},
+		{ID: "paper:notes", Type: "Paper"},
+	} {
+		n.Meta, n.Created, n.Modified = map[string]interface{}{}, now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "person:ada", Target: "paper:notes", Type: "WROTE", Meta: map[string]interface{}{"weight": 0.5}},
+		{Source: "person:bob", Target: "paper:notes", Type: "WROTE"},
+		{Source: "person:ada", Target: "person:bob", Type: "KNOWS"},
+	} {
+		l.Created, l.Modified = now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e, err := Open(path, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	res, err := e.Run(ctx, "node_types", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != 2 || res.Rows[0][0] != "Person" || res.Rows[0][1] != int64(2) {
+		t.Errorf("node_types = %+v", res)
+	}
+	res, err = e.Run(ctx, "link_types", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows[0][0] != "WROTE" || res.Rows[0][2] != 0.75 {
+		t.Errorf("link_types = %+v, want WROTE first with mean weight 0.75", res)
+	}
+	res, err = e.Run(ctx, "top_degree", map[string]string{"type": "Paper", "limit": "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != 1 || res.Rows[0][0] != "paper:notes" || res.Rows[0][2] != int64(2) {
+		t.Errorf("top_degree = %+v", res)
+	}
+	res, err = e.Run(ctx, "daily_growth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != 1 || res.Rows[0][1] != int64(3) || res.Rows[0][2] != int64(3) {
+		t.Errorf("daily_growth = %+v, want one day of 3 nodes and 3 links", res)
+	}
+	if res, err := e.Run(ctx, "type_pairs", nil); err != nil || res.Count != 2 {
+		t.Errorf("type_pairs = %+v, %v", res, err)
+	}
+
+	if _, err := e.Run(ctx, "top_degree", map[string]string{"limit": "many"}); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("bad limit: err = %v", err)
+	}
+	if _, err := e.Run(ctx, "top_degree", map[string]string{"sort": "x"}); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("unknown parameter: err = %v", err)
+	}
+	if _, err := e.Run(ctx, "missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown query: err = %v", err)
+	}
+}
+
+func TestRegisterAndLoad(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memex.db")
+	repo, err := graph.NewSQLite(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close(ctx)
+	e, err := Open(path, Config{MaxRows: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	for _, bad := range []*Query{
+		{Name: "drop", SQL: "DROP TABLE nodes"},
+		{Name: "two", SQL: "SELECT 1; SELECT 2"},
+		{Name: "sneaky", SQL: "WITH x AS (SELECT 1) DELETE FROM nodes"},
+		{Name: "attach", SQL: "SELECT 1 FROM pragma_table_info('nodes') WHERE 1 IN (SELECT 1) -- ATTACH"},
+		{Name: "unused", SQL: "SELECT 1", Params: []Param{{Name: "x", Type: ParamInt}}},
+		{Name: "typed", SQL: "SELECT :x", Params: []Param{{Name: "x", Type: "date"}}},
+		{Name: "Bad Name", SQL: "SELECT 1"},
+	} {
+		if err := e.Register(bad); err == nil {
+			t.Errorf("registered %q: %s", bad.Name, bad.SQL)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "queries.json")
+	os.WriteFile(file, []byte(`[{"name": "numbers", "sql": "WITH RECURSIVE n(i) AS (SELECT :start UNION ALL SELECT i + 1 FROM n WHERE i < 5) SELECT i FROM n;",
+		"params": [{"name": "start", "type": "int", "required": true}]}]`), 0o644)
+	if n, err := e.LoadFile(file); err != nil || n != 1 {
+		t.Fatalf("LoadFile = %d, %v", n, err)
+	}
+	if _, err := e.Run(ctx, "numbers", nil); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("missing required parameter: err = %v", err)
+	}
+	res, err := e.Run(ctx, "numbers", map[string]string{"start": "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != 1 || !res.Truncated || res.Rows[0][0] != int64(3) {
+		t.Errorf("numbers = %+v, want one row of 3, truncated", res)
+	}
+}
@@ -0,0 +1,58 @@
+package analytics
+
+// builtins are registered on every engine. Nodes are counted at their
+// current, undeleted version; link times are RFC3339 text, so they are
+// compared as epoch seconds.
+var builtins = []Query{
+	{
+		Name:        "node_types",
+		Description: "Nodes and their total degree by type",
+		SQL: `SELECT type, COUNT(*) AS nodes, SUM(degree) AS degree
+FROM nodes WHERE is_current = 1 AND deleted = 0
+GROUP BY type ORDER BY nodes DESC, type`,
+	},
+	{
+		Name:        "link_types",
+		Description: "Links and their mean weight by type; unweighted links count as 1",
+		SQL: `SELECT type, COUNT(*) AS links,
+  AVG(CASE WHEN weight = 0 THEN 1 ELSE weight END) AS mean_weight
+FROM links GROUP BY type ORDER BY links DESC, type`,
+	},
+	{
+		Name:        "type_pairs",
+		Description: "Links by source type, link type and target type",
+		SQL: `SELECT s.type AS source_type, l.type AS link_type, t.type AS target_type, COUNT(*) AS links
+FROM links l
+JOIN nodes s ON s.id = l.source_id AND s.is_current = 1 AND s.deleted = 0
+JOIN nodes t ON t.id = l.target_id AND t.is_current = 1 AND t.deleted = 0
+GROUP BY 1, 2, 3 ORDER BY links DESC, 1, 2, 3 LIMIT :limit`,
+		Params: []Param{{Name: "limit", Type: ParamInt, Default: "100"}},
+	},
+	{
+		Name:        "top_degree",
+		Description: "The most linked nodes, optionally of one type",
+		SQL: `SELECT id, type, degree FROM nodes
+WHERE is_current = 1 AND deleted = 0 AND (:type = '' OR type = :type)
+ORDER BY degree DESC, id LIMIT :limit`,
+		Params: []Param{
+			{Name: "type", Type: ParamString},
+			{Name: "limit", Type: ParamInt, Default: "20"},
+		},
+	},
+	{
+		Name:        "daily_growth",
+		Description: "Nodes and links created per day (UTC) between from and to, inclusive",
+		SQL: `WITH created AS (
+  SELECT date(created_unix, 'unixepoch') AS day, 1 AS node, 0 AS link FROM nodes
+  WHERE version = 1 AND created_unix >= :from AND created_unix <= :to
+  UNION ALL
+  SELECT date(created_at), 0, 1 FROM links
+  WHERE CAST(strftime('%s', created_at) AS INTEGER) >= :from AND CAST(strftime('%s', created_at) AS INTEGER) <= :to
+)
+SELECT day, SUM(node) AS nodes, SUM(link) AS links FROM created GROUP BY day ORDER BY day`,
+		Params: []Param{
+			{Name: "from", Type: ParamTime, Default: "-720h"},
+			{Name: "to", Type: ParamTime, Default: "now"},
+		},
+	},
+}
@@ -0,0 +1,167 @@
+package annotations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// contextLength is how many characters around an anchor are kept to
+// relocate it after the content changes
+const contextLength = 32
+
+// Anchor states after rebasing onto the current content
+const (
+	AnchorExact    = "exact"    // The quoted text is still at the stored offsets
+	AnchorRebased  = "rebased"  // The quoted text moved; offsets point at its new place
+	AnchorOrphaned = "orphaned" // The quoted text is gone
+)
+
+// anchorKey is the property holding a comment's stored anchor
+const anchorKey = "anchor"
+
+// Range is a span of content in characters (Unicode code points), end exclusive
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Anchor ties a comment to a span of its node's content
+type Anchor struct {
+	Range
+	Quote   string `json:"quote"`   // The anchored text
+	Version int    `json:"version"` // Node version the anchor was made on
+	Status  string `json:"status"`  // exact, rebased or orphaned
+}
+
+// storedAnchor is the anchor as kept on the comment node
+type storedAnchor struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Quote   string `json:"quote"`
+	Prefix  string `json:"prefix,omitempty"`
+	Suffix  string `json:"suffix,omitempty"`
+	Version int    `json:"version"`
+}
+
+// Overlaps reports whether two ranges share at least one character
+func (r Range) Overlaps(o Range) bool {
+	return r.Start < o.End && o.Start < r.End
+}
+
+// NodeText returns the text annotations anchor to: the node's content, or
+// its content property when the content is empty
+func NodeText(node *core.Node) string {
+	if len(node.Content) > 0 {
+		return string(node.Content)
+	}
+	text, _ := node.Meta["content"].(string)
+	return text
+}
+
+// newAnchor checks a range against the node's text and captures the quote
+// and surrounding context needed to find it again
+func newAnchor(node *core.Node, r Range) (*storedAnchor, error) {
+	text := []rune(NodeText(node))
+	if r.Start < 0 || r.End <= r.Start || r.End > len(text) {
+		return nil, fmt.Errorf("anchor %d-%d is outside the content (0-%d)", r.Start, r.End, len(text))
+	}
+	return &storedAnchor{
+		Start:   r.Start,
+		End:     r.End,
+		Quote:   string(text[r.Start:r.End]),
+		Prefix:  string(text[max(0, r.Start-contextLength):r.Start]),
+		Suffix:  string(text[r.End:min(len(text), r.End+contextLength)]),
+		Version: node.Version,
+	}, nil
+}
+
+// readAnchor loads a comment's stored anchor, if it has one
+func readAnchor(meta map[string]interface{}) *storedAnchor {
+	raw, ok := meta[anchorKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var a storedAnchor
+	if err := json.Unmarshal(data, &a); err != nil || a.Quote == "" {
+		return nil
+	}
+	return &a
+}
+
+// rebase locates a stored anchor in the node's current text. When the
+// quote is no longer at its offsets, the occurrence whose surrounding text
+// best matches the stored context wins, then the one nearest the old spot.
+func (a *storedAnchor) rebase(text []rune) *Anchor {
+	out := &Anchor{Range: Range{Start: a.Start, End: a.End}, Quote: a.Quote, Version: a.Version, Status: AnchorExact}
+	if a.End <= len(text) && string(text[a.Start:a.End]) == a.Quote {
+		return out
+	}
+
+	quote := []rune(a.Quote)
+	best, bestScore, bestDistance := -1, -1, 0
+	for i := 0; i+len(quote) <= len(text); i++ {
+		if string(text[i:i+len(quote)]) != a.Quote {
+			continue
+		}
+		score := commonSuffix(text[:i], []rune(a.Prefix)) + commonPrefix(text[i+len(quote):], []rune(a.Suffix))
+		distance := i - a.Start
+		if distance < 0 {
+			distance = -distance
+		}
+		if score > bestScore || score == bestScore && distance < bestDistance {
+			best, bestScore, bestDistance = i, score, distance
+		}
+	}
+	if best < 0 {
+		out.Status = AnchorOrphaned
+		return out
+	}
+	out.Start, out.End, out.Status = best, best+len(quote), AnchorRebased
+	return out
+}
+
+// Annotations returns a node's anchored comments, with replies, rebased
+// onto its current content and ordered by position. When within is set,
+// only annotations overlapping it are returned; orphaned anchors never are.
+func Annotations(ctx context.Context, repo graph.Repository, nodeID string, within *Range) ([]*Comment, error) {
+	threads, _, err := Thread(ctx, repo, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	out := []*Comment{}
+	for _, c := range threads {
+		if c.Anchor == nil || within != nil && (c.Anchor.Status == AnchorOrphaned || !c.Anchor.Overlaps(*within)) {
+			continue
+		}
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Anchor.Start < out[j].Anchor.Start })
+	return out, nil
+}
+
+// commonPrefix counts the leading characters a and b share
+func commonPrefix(a, b []rune) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// commonSuffix counts the trailing characters a and b share
+func commonSuffix(a, b []rune) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
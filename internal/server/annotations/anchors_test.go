@@ -0,0 +1,70 @@
+package annotations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestAnchorsSurviveEdits(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	text := "The cat sat. The cat ran."
+	if err := repo.CreateNode(ctx, &core.Node{ID: "doc:t", Type: "Transcript", Meta: map[string]interface{}{"content": text}, Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := AddComment(ctx, repo, "doc:t", "ann", "second cat", "", &Range{Start: 17, End: 20})
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if ran.Anchor.Quote != "cat" || ran.Anchor.Status != AnchorExact {
+		t.Fatalf("anchor = %+v", ran.Anchor)
+	}
+	if _, err := AddComment(ctx, repo, "doc:t", "ann", "gone", "", &Range{Start: 8, End: 11}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddComment(ctx, repo, "doc:t", "ann", "bad", "", &Range{Start: 20, End: 99}); err == nil {
+		t.Error("expected out-of-range anchor to fail")
+	}
+
+	// Insert text before both cats and drop "sat"
+	if err := repo.UpdateNodeMeta(ctx, "doc:t", map[string]interface{}{"content": "Intro. The cat slept. The cat ran."}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := Annotations(ctx, repo, "doc:t", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("annotations = %d, want 2", len(all))
+	}
+	var moved *Anchor
+	for _, c := range all {
+		if c.ID == ran.ID {
+			moved = c.Anchor
+		} else if c.Anchor.Status != AnchorOrphaned {
+			t.Errorf("anchor on removed text = %+v, want orphaned", c.Anchor)
+		}
+	}
+	// The second "cat" is picked by its context, not the first one
+	if moved == nil || moved.Status != AnchorRebased || moved.Start != 26 || moved.End != 29 {
+		t.Fatalf("rebased anchor = %+v, want 26-29", moved)
+	}
+
+	hits, err := Annotations(ctx, repo, "doc:t", &Range{Start: 25, End: 27})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].ID != ran.ID {
+		t.Errorf("overlap query = %v, want only %s", hits, ran.ID)
+	}
+	if hits, _ := Annotations(ctx, repo, "doc:t", &Range{Start: 0, End: 6}); len(hits) != 0 {
+		t.Errorf("overlap query on intro = %d annotations, want 0", len(hits))
+	}
+}
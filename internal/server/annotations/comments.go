@@ -0,0 +1,214 @@
+// Package annotations attaches discussion to nodes as Comment nodes, so
+// conversations live beside entities instead of in their properties.
+package annotations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Node and link types used for comments
+const (
+	CommentType    = "Comment"
+	CommentsOnLink = "COMMENTS_ON" // Every comment, including replies, to the node discussed
+	RepliesToLink  = "REPLIES_TO"  // Reply to the comment it answers
+)
+
+// MaxBodyLength caps a comment's text, in characters
+const MaxBodyLength = 10000
+
+// Property keys on comment nodes
+const (
+	authorKey  = "author"
+	nodeIDKey  = "node_id"
+	replyToKey = "reply_to"
+)
+
+// ErrNotFound is returned when the node or comment replied to does not exist
+var ErrNotFound = errors.New("not found")
+
+// Comment is one comment with its replies, oldest first
+type Comment struct {
+	ID      string     `json:"id"`
+	NodeID  string     `json:"node_id"`
+	Author  string     `json:"author"`
+	Body    string     `json:"body"`
+	ReplyTo string     `json:"reply_to,omitempty"`
+	Created time.Time  `json:"created"`
+	Anchor  *Anchor    `json:"anchor,omitempty"`
+	Replies []*Comment `json:"replies,omitempty"`
+}
+
+// AddComment stores a comment on a node, or a reply when replyTo names a
+// comment on the same node. A top-level comment may be anchored to a range
+// of the node's content, making it an annotation.
+func AddComment(ctx context.Context, repo graph.Repository, nodeID, author, body, replyTo string, anchor *Range) (*Comment, error) {
+	author, body = strings.TrimSpace(author), strings.TrimSpace(body)
+	if author == "" {
+		return nil, fmt.Errorf("author is required")
+	}
+	if body == "" {
+		return nil, fmt.Errorf("body is required")
+	}
+	if utf8.RuneCountInString(body) > MaxBodyLength {
+		return nil, fmt.Errorf("body is longer than %d characters", MaxBodyLength)
+	}
+
+	target, err := repo.GetNode(ctx, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	if target.Type == CommentType {
+		return nil, fmt.Errorf("comment on the discussed node and reply with reply_to instead")
+	}
+	if replyTo != "" {
+		parent, err := repo.GetNode(ctx, replyTo)
+		if err != nil || parent.Type != CommentType {
+			return nil, fmt.Errorf("%w: comment %s", ErrNotFound, replyTo)
+		}
+		if on, _ := parent.Meta[nodeIDKey].(string); on != nodeID {
+			return nil, fmt.Errorf("comment %s is not on %s", replyTo, nodeID)
+		}
+		if anchor != nil {
+			return nil, fmt.Errorf("replies share their comment's anchor")
+		}
+	}
+	var stored *storedAnchor
+	if anchor != nil {
+		if stored, err = newAnchor(target, *anchor); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	c := &Comment{ID: "comment:" + uuid.New().String(), NodeID: nodeID, Author: author, Body: body, ReplyTo: replyTo, Created: now}
+	meta := map[string]interface{}{authorKey: author, nodeIDKey: nodeID}
+	if replyTo != "" {
+		meta[replyToKey] = replyTo
+	}
+	if stored != nil {
+		meta[anchorKey] = stored
+		c.Anchor = stored.rebase([]rune(NodeText(target)))
+	}
+	if err := repo.CreateNode(ctx, &core.Node{ID: c.ID, Type: CommentType, Content: []byte(body), Meta: meta, Created: now, Modified: now}); err != nil {
+		return nil, err
+	}
+
+	links := []*core.Link{{Source: c.ID, Target: nodeID, Type: CommentsOnLink, Created: now, Modified: now}}
+	if replyTo != "" {
+		links = append(links, &core.Link{Source: c.ID, Target: replyTo, Type: RepliesToLink, Created: now, Modified: now})
+	}
+	if err := repo.CreateLinks(ctx, links); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Thread returns a node's comments as threads (top-level comments with
+// nested replies, oldest first) and the total number of comments. Anchors
+// are rebased onto the node's current content.
+func Thread(ctx context.Context, repo graph.Repository, nodeID string) ([]*Comment, int, error) {
+	var text []rune
+	if target, err := repo.GetNode(ctx, nodeID); err == nil {
+		text = []rune(NodeText(target))
+	}
+	backlinks, err := repo.GetBacklinks(ctx, nodeID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[string]*Comment)
+	var all []*Comment
+	for _, l := range backlinks {
+		if l.Type != CommentsOnLink {
+			continue
+		}
+		node, err := repo.GetNode(ctx, l.Source)
+		if err != nil || node.Type != CommentType {
+			continue // Deleted comments drop out of the thread
+		}
+		c := fromNode(node)
+		if a := readAnchor(node.Meta); a != nil {
+			c.Anchor = a.rebase(text)
+		}
+		byID[c.ID] = c
+		all = append(all, c)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Created.Before(all[j].Created) })
+
+	roots := []*Comment{}
+	for _, c := range all {
+		if parent := byID[c.ReplyTo]; parent != nil {
+			parent.Replies = append(parent.Replies, c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	return roots, len(all), nil
+}
+
+// Get loads one comment, without its replies
+func Get(ctx context.Context, repo graph.Repository, id string) (*Comment, error) {
+	node, err := repo.GetNode(ctx, id)
+	if err != nil || node.Type != CommentType {
+		return nil, fmt.Errorf("%w: comment %s", ErrNotFound, id)
+	}
+	return fromNode(node), nil
+}
+
+// Delete tombstones a comment and its links. Replies stay, shown as
+// top-level comments.
+func Delete(ctx context.Context, repo graph.Repository, id string) (*Comment, error) {
+	c, err := Get(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tombstoned nodes keep their links, so remove those first
+	outgoing, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	incoming, err := repo.GetBacklinks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range append(outgoing, incoming...) {
+		if err := repo.DeleteLink(ctx, l.Source, l.Target, l.Type); err != nil {
+			return nil, err
+		}
+	}
+	if err := repo.DeleteNode(ctx, id, false); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Count returns how many comments a set of backlinks holds
+func Count(backlinks []*core.Link) int {
+	n := 0
+	for _, l := range backlinks {
+		if l.Type == CommentsOnLink {
+			n++
+		}
+	}
+	return n
+}
+
+// fromNode reads a comment back from its node
+func fromNode(node *core.Node) *Comment {
+	c := &Comment{ID: node.ID, Body: string(node.Content), Created: node.Created}
+	c.NodeID, _ = node.Meta[nodeIDKey].(string)
+	c.Author, _ = node.Meta[authorKey].(string)
+	c.ReplyTo, _ = node.Meta[replyToKey].(string)
+	return c
+}
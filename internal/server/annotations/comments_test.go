@@ -0,0 +1,69 @@
+package annotations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestCommentThreads(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, id := range []string{"doc:plan", "doc:other"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Document", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := AddComment(ctx, repo, "doc:plan", "ann", "Should we ship Friday?", "", nil)
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	reply, err := AddComment(ctx, repo, "doc:plan", "bob", "Monday is safer", first.ID, nil)
+	if err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if _, err := AddComment(ctx, repo, "doc:plan", "cy", "Second topic", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddComment(ctx, repo, "doc:other", "bob", "Wrong thread", first.ID, nil); err == nil {
+		t.Error("reply to a comment on another node was accepted")
+	}
+	if _, err := AddComment(ctx, repo, "doc:none", "bob", "Hello", "", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("comment on a missing node = %v, want ErrNotFound", err)
+	}
+	if _, err := AddComment(ctx, repo, reply.ID, "bob", "Nested", "", nil); err == nil {
+		t.Error("comment on a comment was accepted")
+	}
+
+	threads, count, err := Thread(ctx, repo, "doc:plan")
+	if err != nil {
+		t.Fatalf("Thread: %v", err)
+	}
+	if count != 3 || len(threads) != 2 || threads[0].ID != first.ID {
+		t.Fatalf("threads = %+v (count %d), want 2 threads of 3 comments starting with the first", threads, count)
+	}
+	if len(threads[0].Replies) != 1 || threads[0].Replies[0].Author != "bob" || threads[0].Replies[0].Body != "Monday is safer" {
+		t.Errorf("replies = %+v, want bob's reply", threads[0].Replies)
+	}
+
+	backlinks, _ := repo.GetBacklinks(ctx, "doc:plan")
+	if Count(backlinks) != 3 {
+		t.Errorf("Count = %d, want 3", Count(backlinks))
+	}
+
+	// Deleting the parent removes its links; the reply becomes top-level
+	if _, err := Delete(ctx, repo, first.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	threads, count, _ = Thread(ctx, repo, "doc:plan")
+	backlinks, _ = repo.GetBacklinks(ctx, "doc:plan")
+	if count != 2 || len(threads) != 2 || Count(backlinks) != 2 {
+		t.Errorf("after delete: %d comments in %d threads, %d counted; want 2, 2, 2", count, len(threads), Count(backlinks))
+	}
+}
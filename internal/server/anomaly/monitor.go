@@ -0,0 +1,573 @@
+// Package anomaly watches ingest for sharp departures from normal: a
+// connector that stops sending, one that floods the graph, or a sudden
+// change in the mix of node types being created.
+package anomaly
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Anomaly kinds
+const (
+	KindSilent = "connector.silent" // A connector that normally sends nodes sent none
+	KindFlood  = "connector.flood"  // A connector sent far more nodes than normal
+	KindShift  = "types.shift"      // The node types created differ sharply from normal
+)
+
+// NoConnector is the connector of nodes created without one, such as
+// direct API writes
+const NoConnector = "none"
+
+// internalTypes are settings and bookkeeping nodes the server writes
+// itself, which are not ingest
+var internalTypes = map[string]bool{
+	"Subscription": true, "Lens": true, "SavedQuery": true, "LinkRule": true, "MemoryCard": true, "ErasureAudit": true,
+	"Stats": true, "IngestHook": true, "GitHubSync": true, "TicketSync": true, "SynonymSet": true, "TypeDefinition": true,
+	"ModuleSettings": true, "TypeRule": true,
+}
+
+// maxRecent is how many past anomalies are kept
+const maxRecent = 100
+
+// maxShiftTypes is how many node types a shift anomaly reports
+const maxShiftTypes = 5
+
+// Config tunes detection. Zero fields take the defaults.
+type Config struct {
+	Bucket         time.Duration // Counting period (default 10m)
+	Warmup         int           // Buckets of baseline before alerting (default 6)
+	Alpha          float64       // Weight of the newest bucket in a baseline (default 0.1)
+	Threshold      float64       // Standard deviations over baseline that make a flood (default 4)
+	MinRate        float64       // Nodes per bucket below which a connector is too quiet to judge (default 5)
+	SilentBuckets  int           // Empty buckets in a row before a connector counts as silent (default 3)
+	ShiftThreshold float64       // Total variation distance between type mixes that makes a shift (default 0.5)
+}
+
+func (c Config) withDefaults() Config {
+	if c.Bucket <= 0 {
+		c.Bucket = 10 * time.Minute
+	}
+	if c.Warmup <= 0 {
+		c.Warmup = 6
+	}
+	if c.Alpha <= 0 || c.Alpha > 1 {
+		c.Alpha = 0.1
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 4
+	}
+	if c.MinRate <= 0 {
+		c.MinRate = 5
+	}
+	if c.SilentBuckets <= 0 {
+		c.SilentBuckets = 3
+	}
+	if c.ShiftThreshold <= 0 {
+		c.ShiftThreshold = 0.5
+	}
+	return c
+}
+
+// TypeShare is a node type's share of the nodes created
+type TypeShare struct {
+	Type     string  `json:"type"`
+	Share    float64 `json:"share"`    // In the bucket
+	Expected float64 `json:"expected"` // In the baseline
+}
+
+// Anomaly is one detected departure from baseline
+type Anomaly struct {
+	Kind      string      `json:"kind"`
+	Connector string      `json:"connector,omitempty"`
+	Count     int         `json:"count"`    // Nodes created in the bucket
+	Expected  float64     `json:"expected"` // Baseline nodes per bucket
+	Score     float64     `json:"score"`    // Standard deviations for a flood, empty buckets for silence, distance for a shift
+	Types     []TypeShare `json:"types,omitempty"`
+	Message   string      `json:"message"`
+	Detected  time.Time   `json:"detected"`
+	Resolved  *time.Time  `json:"resolved,omitempty"`
+}
+
+// key identifies an anomaly while it lasts
+func (a *Anomaly) key() string {
+	return a.Kind + "|" + a.Connector
+}
+
+// baseline is an exponentially weighted mean and variance of a count per
+// bucket
+type baseline struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Buckets  int     `json:"buckets"`
+}
+
+func (b *baseline) observe(x, alpha float64) {
+	if b.Buckets == 0 {
+		b.Mean = x
+	} else {
+		diff := x - b.Mean
+		incr := alpha * diff
+		b.Mean += incr
+		b.Variance = (1 - alpha) * (b.Variance + diff*incr)
+	}
+	b.Buckets++
+}
+
+func (b *baseline) stdDev() float64 {
+	return math.Sqrt(b.Variance)
+}
+
+// connectorState is a connector's counts and baseline
+type connectorState struct {
+	baseline
+	total   int64 // Nodes since the server started
+	current int   // Nodes in the open bucket
+	last    int   // Nodes in the last closed bucket
+	quiet   int   // Empty buckets in a row
+}
+
+// ConnectorStats describes a connector's ingest
+type ConnectorStats struct {
+	Connector string  `json:"connector"`
+	Total     int64   `json:"total"`
+	Current   int     `json:"current"` // Nodes in the open bucket
+	Last      int     `json:"last"`    // Nodes in the last closed bucket
+	Mean      float64 `json:"mean"`    // Baseline nodes per bucket
+	StdDev    float64 `json:"std_dev"`
+	Buckets   int     `json:"buckets"` // Buckets in the baseline
+}
+
+// Status describes the monitor
+type Status struct {
+	Bucket     string           `json:"bucket"`
+	Connectors []ConnectorStats `json:"connectors"`
+	Types      []TypeShare      `json:"types"` // Baseline mix of node types
+	Active     []Anomaly        `json:"active"`
+	Recent     []Anomaly        `json:"recent"` // Newest first
+}
+
+// Monitor baselines per-connector ingest rates and the mix of node types
+// created, bucket by bucket, and emits an ingest.anomaly event when a
+// bucket departs sharply from them and ingest.anomaly.resolved when it
+// returns to normal. Anomalous buckets are left out of the baselines, so
+// an anomaly lasts until ingest returns to normal rather than becoming
+// the new normal. Baselines are kept in memory and rebuilt after a
+// restart.
+type Monitor struct {
+	cfg  Config
+	emit func(subscriptions.Event)
+
+	mu         sync.Mutex
+	connectors map[string]*connectorState
+	types      map[string]*baseline // Nodes per bucket by type
+	current    map[string]int       // Nodes by type in the open bucket
+	buckets    int                  // Buckets closed
+	active     map[string]*Anomaly
+	recent     []Anomaly
+	counts     map[string]int64 // Anomalies since the server started, by kind
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewMonitor creates a monitor that passes anomaly events to emit
+func NewMonitor(cfg Config, emit func(subscriptions.Event)) *Monitor {
+	return &Monitor{
+		cfg:        cfg.withDefaults(),
+		emit:       emit,
+		connectors: make(map[string]*connectorState),
+		types:      make(map[string]*baseline),
+		current:    make(map[string]int),
+		active:     make(map[string]*Anomaly),
+		counts:     make(map[string]int64),
+		stop:       make(chan struct{}),
+	}
+}
+
+// EmitEvent counts created nodes
+func (m *Monitor) EmitEvent(event subscriptions.Event) {
+	if event.Type != subscriptions.EventNodeCreated || internalTypes[event.NodeType] {
+		return
+	}
+	connector, _ := event.Meta["connector"].(string)
+	if connector == "" {
+		connector = NoConnector
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.connectors[connector]
+	if c == nil {
+		c = &connectorState{}
+		m.connectors[connector] = c
+	}
+	c.current++
+	c.total++
+	m.current[event.NodeType]++
+}
+
+// Start closes a bucket every bucket period
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.cfg.Bucket)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				m.closeBucket(now)
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Ingest anomaly monitor started (%s buckets)", m.cfg.Bucket)
+}
+
+// Stop halts the monitor
+func (m *Monitor) Stop() {
+	close(m.stop)
+	m.wg.Wait()
+}
+
+// closeBucket compares the open bucket with the baselines, folds it into
+// them and starts the next
+func (m *Monitor) closeBucket(now time.Time) {
+	m.mu.Lock()
+	var found []*Anomaly
+	warm := m.buckets >= m.cfg.Warmup
+	for name, c := range m.connectors {
+		var a *Anomaly
+		if warm && c.Buckets >= m.cfg.Warmup {
+			a = m.checkConnector(name, c)
+		}
+		if a != nil {
+			found = append(found, a)
+		} else {
+			c.observe(float64(c.current), m.cfg.Alpha)
+		}
+		c.last, c.current = c.current, 0
+	}
+	var shift *Anomaly
+	if warm {
+		shift = m.checkTypes()
+	}
+	if shift != nil {
+		found = append(found, shift)
+	} else {
+		for t, b := range m.types {
+			b.observe(float64(m.current[t]), m.cfg.Alpha)
+		}
+		for t, n := range m.current {
+			if m.types[t] == nil {
+				// A type first seen now counted zero in earlier buckets
+				m.types[t] = &baseline{Buckets: min(m.buckets, 1)}
+				m.types[t].observe(float64(n), m.cfg.Alpha)
+			}
+		}
+	}
+	m.current = make(map[string]int)
+	m.buckets++
+
+	events := m.transition(found, now)
+	m.mu.Unlock()
+
+	if m.emit != nil {
+		for _, e := range events {
+			m.emit(e)
+		}
+	}
+}
+
+// checkConnector looks for silence or a flood in a connector's open bucket
+func (m *Monitor) checkConnector(name string, c *connectorState) *Anomaly {
+	count := float64(c.current)
+	if c.current == 0 {
+		c.quiet++
+	} else {
+		c.quiet = 0
+	}
+	if c.quiet >= m.cfg.SilentBuckets && c.Mean >= m.cfg.MinRate {
+		return &Anomaly{
+			Kind:      KindSilent,
+			Connector: name,
+			Expected:  c.Mean,
+			Score:     float64(c.quiet),
+			Message:   fmt.Sprintf("connector %s sent no nodes for %d buckets (normally %.1f per bucket)", name, c.quiet, c.Mean),
+		}
+	}
+
+	// A floor on the deviation keeps a steady connector from flagging
+	// every small rise
+	dev := math.Max(c.stdDev(), math.Sqrt(math.Max(c.Mean, 1)))
+	if score := (count - c.Mean) / dev; score >= m.cfg.Threshold && count >= m.cfg.MinRate {
+		return &Anomaly{
+			Kind:      KindFlood,
+			Connector: name,
+			Count:     c.current,
+			Expected:  c.Mean,
+			Score:     score,
+			Message:   fmt.Sprintf("connector %s sent %d nodes (normally %.1f per bucket)", name, c.current, c.Mean),
+		}
+	}
+	return nil
+}
+
+// checkTypes compares the open bucket's mix of node types with the
+// baseline mix by total variation distance
+func (m *Monitor) checkTypes() *Anomaly {
+	total := 0
+	for _, n := range m.current {
+		total += n
+	}
+	expected := 0.0
+	for _, b := range m.types {
+		expected += b.Mean
+	}
+	if float64(total) < m.cfg.MinRate || expected < m.cfg.MinRate {
+		return nil
+	}
+
+	var shares []TypeShare
+	distance := 0.0
+	seen := make(map[string]bool)
+	add := func(t string) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		s := TypeShare{Type: t, Share: float64(m.current[t]) / float64(total)}
+		if b := m.types[t]; b != nil {
+			s.Expected = b.Mean / expected
+		}
+		distance += math.Abs(s.Share - s.Expected)
+		shares = append(shares, s)
+	}
+	for t := range m.current {
+		add(t)
+	}
+	for t := range m.types {
+		add(t)
+	}
+	distance /= 2
+	if distance < m.cfg.ShiftThreshold {
+		return nil
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		di, dj := math.Abs(shares[i].Share-shares[i].Expected), math.Abs(shares[j].Share-shares[j].Expected)
+		if di != dj {
+			return di > dj
+		}
+		return shares[i].Type < shares[j].Type
+	})
+	if len(shares) > maxShiftTypes {
+		shares = shares[:maxShiftTypes]
+	}
+	return &Anomaly{
+		Kind:     KindShift,
+		Count:    total,
+		Expected: expected,
+		Score:    distance,
+		Types:    shares,
+		Message:  fmt.Sprintf("node types created shifted from normal (distance %.2f, most changed %s)", distance, shares[0].Type),
+	}
+}
+
+// transition records newly found anomalies and resolves those no longer
+// found, returning the events to emit
+func (m *Monitor) transition(found []*Anomaly, now time.Time) []subscriptions.Event {
+	var events []subscriptions.Event
+	still := make(map[string]bool)
+	for _, a := range found {
+		key := a.key()
+		still[key] = true
+		if m.active[key] != nil {
+			continue
+		}
+		a.Detected = now
+		m.active[key] = a
+		m.counts[a.Kind]++
+		m.remember(*a)
+		log.Printf("Ingest anomaly: %s", a.Message)
+		events = append(events, event(subscriptions.EventIngestAnomaly, a, now))
+	}
+	for key, a := range m.active {
+		if still[key] {
+			continue
+		}
+		delete(m.active, key)
+		resolved := now
+		a.Resolved = &resolved
+		m.remember(*a)
+		events = append(events, event(subscriptions.EventIngestAnomalyResolved, a, now))
+	}
+	return events
+}
+
+// remember adds an anomaly to the recent list
+func (m *Monitor) remember(a Anomaly) {
+	m.recent = append(m.recent, a)
+	if len(m.recent) > maxRecent {
+		m.recent = m.recent[len(m.recent)-maxRecent:]
+	}
+}
+
+// event describes an anomaly for subscriptions
+func event(eventType string, a *Anomaly, now time.Time) subscriptions.Event {
+	meta := map[string]interface{}{
+		"kind":     a.Kind,
+		"count":    a.Count,
+		"expected": a.Expected,
+		"score":    a.Score,
+		"message":  a.Message,
+	}
+	if a.Connector != "" {
+		meta["connector"] = a.Connector
+	}
+	if len(a.Types) > 0 {
+		meta["types"] = a.Types
+	}
+	return subscriptions.Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Timestamp: now,
+		Meta:      meta,
+	}
+}
+
+// Status describes the baselines and anomalies
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := Status{
+		Bucket:     m.cfg.Bucket.String(),
+		Connectors: make([]ConnectorStats, 0, len(m.connectors)),
+		Types:      m.typeShares(),
+		Active:     make([]Anomaly, 0, len(m.active)),
+		Recent:     make([]Anomaly, 0, len(m.recent)),
+	}
+	for _, name := range m.connectorNames() {
+		c := m.connectors[name]
+		status.Connectors = append(status.Connectors, ConnectorStats{
+			Connector: name,
+			Total:     c.total,
+			Current:   c.current,
+			Last:      c.last,
+			Mean:      c.Mean,
+			StdDev:    c.stdDev(),
+			Buckets:   c.Buckets,
+		})
+	}
+	for _, a := range m.active {
+		status.Active = append(status.Active, *a)
+	}
+	sort.Slice(status.Active, func(i, j int) bool { return status.Active[i].key() < status.Active[j].key() })
+	for i := len(m.recent) - 1; i >= 0; i-- {
+		status.Recent = append(status.Recent, m.recent[i])
+	}
+	return status
+}
+
+// connectorNames returns the connectors seen, sorted
+func (m *Monitor) connectorNames() []string {
+	names := make([]string, 0, len(m.connectors))
+	for name := range m.connectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// typeShares returns the baseline mix of node types, largest first
+func (m *Monitor) typeShares() []TypeShare {
+	total := 0.0
+	for _, b := range m.types {
+		total += b.Mean
+	}
+	shares := make([]TypeShare, 0, len(m.types))
+	if total == 0 {
+		return shares
+	}
+	for t, b := range m.types {
+		shares = append(shares, TypeShare{Type: t, Expected: b.Mean / total})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Expected != shares[j].Expected {
+			return shares[i].Expected > shares[j].Expected
+		}
+		return shares[i].Type < shares[j].Type
+	})
+	return shares
+}
+
+// WriteMetrics writes the monitor's counters and gauges in the Prometheus
+// text format
+func (m *Monitor) WriteMetrics(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	names := m.connectorNames()
+	metric := func(name, kind, help string, values func(emit func(labels string, v float64))) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		values(func(labels string, v float64) {
+			fmt.Fprintf(&b, "%s%s %g\n", name, labels, v)
+		})
+	}
+	metric("memex_ingest_nodes_total", "counter", "Nodes created since the server started, by connector.", func(emit func(string, float64)) {
+		for _, name := range names {
+			emit(label("connector", name), float64(m.connectors[name].total))
+		}
+	})
+	metric("memex_ingest_bucket_nodes", "gauge", "Nodes created in the last closed bucket, by connector.", func(emit func(string, float64)) {
+		for _, name := range names {
+			emit(label("connector", name), float64(m.connectors[name].last))
+		}
+	})
+	metric("memex_ingest_baseline_nodes", "gauge", "Baseline nodes created per bucket, by connector.", func(emit func(string, float64)) {
+		for _, name := range names {
+			emit(label("connector", name), m.connectors[name].Mean)
+		}
+	})
+	metric("memex_ingest_anomalies_total", "counter", "Ingest anomalies detected since the server started, by kind.", func(emit func(string, float64)) {
+		for _, kind := range []string{KindSilent, KindFlood, KindShift} {
+			emit(label("kind", kind), float64(m.counts[kind]))
+		}
+	})
+	metric("memex_ingest_anomaly_active", "gauge", "Ingest anomalies in progress, by kind and connector.", func(emit func(string, float64)) {
+		keys := make([]string, 0, len(m.active))
+		for key := range m.active {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			a := m.active[key]
+			emit("{kind="+quote(a.Kind)+",connector="+quote(a.Connector)+"}", 1)
+		}
+	})
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// label formats a single Prometheus label
+func label(name, value string) string {
+	return "{" + name + "=" + quote(value) + "}"
+}
+
+// quote escapes a Prometheus label value
+func quote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
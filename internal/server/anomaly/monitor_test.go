@@ -0,0 +1,90 @@
+package anomaly
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestMonitor(t *testing.T) {
+	var events []subscriptions.Event
+	m := NewMonitor(Config{Warmup: 3}, func(e subscriptions.Event) { events = append(events, e) })
+	now := time.Now()
+	ingest := func(connector, nodeType string, n int) {
+		for i := 0; i < n; i++ {
+			m.EmitEvent(subscriptions.Event{Type: subscriptions.EventNodeCreated, NodeType: nodeType, Meta: map[string]interface{}{"connector": connector}})
+		}
+	}
+	bucket := func(rss, agent int, agentType string) []subscriptions.Event {
+		events = nil
+		ingest("rss", "Source", rss)
+		ingest("agent", agentType, agent)
+		now = now.Add(10 * time.Minute)
+		m.closeBucket(now)
+		return events
+	}
+	kinds := func(events []subscriptions.Event) []string {
+		var out []string
+		for _, e := range events {
+			out = append(out, e.Type+" "+e.Meta["kind"].(string))
+		}
+		return out
+	}
+
+	// A baseline of 20 sources and 8 notes a bucket
+	for i := 0; i < 5; i++ {
+		if got := bucket(20+i%2, 8, "Note"); len(got) != 0 {
+			t.Fatalf("normal bucket %d raised %v", i, kinds(got))
+		}
+	}
+
+	// The rss connector dies: silent on the third empty bucket
+	for i := 0; i < 2; i++ {
+		if got := bucket(0, 8, "Note"); len(got) != 0 {
+			// Only notes in the bucket is also a shift in the type mix
+			if k := kinds(got); len(k) != 1 || k[0] != "ingest.anomaly types.shift" {
+				t.Fatalf("empty bucket %d raised %v", i, k)
+			}
+		}
+	}
+	got := bucket(0, 8, "Note")
+	if k := kinds(got); len(k) != 1 || k[0] != "ingest.anomaly connector.silent" || got[0].Meta["connector"] != "rss" {
+		t.Fatalf("third empty bucket raised %v, want rss silent", k)
+	}
+	if active := m.Status().Active; len(active) != 2 {
+		t.Errorf("active anomalies = %+v, want silence and the type shift", active)
+	}
+
+	// It recovers, and both anomalies resolve
+	got = bucket(20, 8, "Note")
+	if k := kinds(got); len(k) != 2 || !strings.HasPrefix(k[0], subscriptions.EventIngestAnomalyResolved) {
+		t.Fatalf("recovery raised %v, want two resolutions", k)
+	}
+
+	// A runaway agent floods the graph with a new type
+	got = bucket(20, 500, "Claim")
+	k := kinds(got)
+	if len(k) != 2 || k[0] != "ingest.anomaly connector.flood" || k[1] != "ingest.anomaly types.shift" {
+		t.Fatalf("flood raised %v, want a flood and a type shift", k)
+	}
+	if types := got[1].Meta["types"].([]TypeShare); types[0].Type != "Claim" {
+		t.Errorf("most changed type = %+v, want Claim", types[0])
+	}
+
+	var metrics strings.Builder
+	if err := m.WriteMetrics(&metrics); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`memex_ingest_nodes_total{connector="agent"} 572`,
+		`memex_ingest_bucket_nodes{connector="agent"} 500`,
+		`memex_ingest_anomalies_total{kind="connector.silent"} 1`,
+		`memex_ingest_anomaly_active{kind="connector.flood",connector="agent"} 1`,
+	} {
+		if !strings.Contains(metrics.String(), line+"\n") {
+			t.Errorf("metrics lack %q:\n%s", line, metrics.String())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// QueryAggregate handles GET /api/query/aggregate
+// Groups nodes by ?group_by= ("type", "layer" or property names, comma-separated) and
+// an optional ?bucket= (day or week) on creation time, counting them and
+// summarizing the numeric property ?field=. ?type= selects node types,
+// ?layer= node layers, and ?from=/?to= bound the creation time.
+func (s *Server) QueryAggregate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := graph.AggregateQuery{
+		Types:   listParam(query["type"]),
+		GroupBy: listParam(query["group_by"]),
+		Field:   query.Get("field"),
+		Bucket:  query.Get("bucket"),
+	}
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Layers = layers
+	if query.Get("from") != "" || query.Get("to") != "" {
+		from, to, err := parseTimeWindow(r, 30*24*time.Hour)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.From, q.To = from, to
+	}
+	if err := q.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Node types are a set; grouping order shapes the result
+	params := q
+	params.Types = append([]string(nil), q.Types...)
+	sort.Strings(params.Types)
+	agg, err := cachedResult(s, w, r, "aggregate", params, func() (*graph.Aggregate, bool, error) {
+		agg, err := graph.RunAggregate(r.Context(), s.repo, q)
+		return agg, true, err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(agg)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// SetAliasRequest is the body of PUT /api/nodes/{id}/alias
+type SetAliasRequest struct {
+	Alias     string `json:"alias"`
+	ChangedBy string `json:"changed_by,omitempty"`
+}
+
+// SetAliases enables node aliases and short IDs
+func (s *Server) SetAliases(aliases *graph.Aliases) {
+	s.aliases = aliases
+}
+
+// SetNodeAlias handles PUT /api/nodes/{id}/alias
+// Gives a node a unique alias such as person/ada-lovelace, which is then
+// accepted anywhere its ID is.
+func (s *Server) SetNodeAlias(w http.ResponseWriter, r *http.Request) {
+	var req SetAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Alias == "" {
+		http.Error(w, "alias is required; DELETE removes it", http.StatusBadRequest)
+		return
+	}
+	s.setNodeAlias(w, r, req)
+}
+
+// RemoveNodeAlias handles DELETE /api/nodes/{id}/alias
+func (s *Server) RemoveNodeAlias(w http.ResponseWriter, r *http.Request) {
+	s.setNodeAlias(w, r, SetAliasRequest{ChangedBy: r.URL.Query().Get("changed_by")})
+}
+
+func (s *Server) setNodeAlias(w http.ResponseWriter, r *http.Request, req SetAliasRequest) {
+	if s.aliases == nil {
+		notEnabled(w, "aliases are not enabled")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	node, err := s.repo.GetNode(r.Context(), id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("node not found: %s", id), http.StatusNotFound)
+		return
+	}
+	alias, err := s.aliases.SetAlias(r.Context(), node.ID, req.Alias, req.ChangedBy)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       node.ID,
+		"alias":    alias,
+		"short_id": node.Meta[graph.ShortIDKey],
+	})
+}
+
+// AssignShortIDs handles POST /api/admin/short-ids
+// Gives a short ID to every existing node that lacks one.
+func (s *Server) AssignShortIDs(w http.ResponseWriter, r *http.Request) {
+	if s.aliases == nil || !s.aliases.ShortIDs() {
+		notEnabled(w, "short IDs are not enabled (set MEMEX_SHORT_IDS=true)")
+		return
+	}
+	assigned, err := s.aliases.AssignShortIDs(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"assigned": assigned})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/analytics"
+)
+
+// SetAnalytics enables the registered analytics queries
+func (s *Server) SetAnalytics(e *analytics.Engine) {
+	s.analytics = e
+}
+
+// ListAnalytics handles GET /api/analytics
+// Lists the registered queries with their SQL and parameters.
+func (s *Server) ListAnalytics(w http.ResponseWriter, r *http.Request) {
+	if s.analytics == nil {
+		notEnabled(w, "analytics are disabled (they need the SQLite backend)")
+		return
+	}
+	list := s.analytics.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"queries": list,
+		"count":   len(list),
+	})
+}
+
+// RunAnalytics handles GET /api/analytics/{name}
+// Runs a registered query on the read-only analytics pool, taking its
+// parameters from the query string, and returns the columns and rows.
+func (s *Server) RunAnalytics(w http.ResponseWriter, r *http.Request) {
+	if s.analytics == nil {
+		notEnabled(w, "analytics are disabled (they need the SQLite backend)")
+		return
+	}
+	params := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		params[key] = values[0]
+	}
+
+	result, err := s.analytics.Run(r.Context(), chi.URLParam(r, "name"), params)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, analytics.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, analytics.ErrInvalidParams):
+			status = http.StatusBadRequest
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/anomaly"
+)
+
+// SetIngestMonitor enables ingest anomaly detection
+func (s *Server) SetIngestMonitor(m *anomaly.Monitor) {
+	s.ingestMonitor = m
+}
+
+// IngestAnomalies handles GET /api/admin/ingest/anomalies
+// Returns each connector's ingest counts and baseline, the baseline mix
+// of node types, the anomalies in progress and the recent ones.
+func (s *Server) IngestAnomalies(w http.ResponseWriter, r *http.Request) {
+	if s.ingestMonitor == nil {
+		notEnabled(w, "ingest anomaly detection is disabled")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.ingestMonitor.Status())
+}
+
+// Metrics handles GET /metrics
+// Serves ingest rates, baselines and anomalies, query cache hits and
+// misses, lane load and calls to deprecated routes in the Prometheus text
+// format.
+func (s *Server) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if s.ingestMonitor != nil {
+		if err := s.ingestMonitor.WriteMetrics(w); err != nil {
+			log.Printf("Writing metrics failed: %v", err)
+			return
+		}
+	}
+	if s.queryCache != nil {
+		if err := s.queryCache.WriteMetrics(w); err != nil {
+			log.Printf("Writing metrics failed: %v", err)
+			return
+		}
+	}
+	if s.lanes != nil {
+		if err := s.lanes.WriteMetrics(w); err != nil {
+			log.Printf("Writing metrics failed: %v", err)
+			return
+		}
+	}
+	if s.deprecations != nil {
+		if err := s.deprecations.WriteMetrics(w); err != nil {
+			log.Printf("Writing metrics failed: %v", err)
+		}
+	}
+}
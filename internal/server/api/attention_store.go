@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// SetAttentionStore exposes the decoupled attention store's stats and
+// consolidation
+func (s *Server) SetAttentionStore(store *graph.AttentionStore) {
+	s.attention = store
+}
+
+// AttentionStoreStats handles GET /api/edges/attention/store
+func (s *Server) AttentionStoreStats(w http.ResponseWriter, r *http.Request) {
+	if s.attention == nil {
+		notEnabled(w, "attention store is disabled")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.attention.Stats())
+}
+
+// ConsolidateAttention handles POST /api/edges/attention/consolidate
+// Promotes consistently strong attention edges to durable links now,
+// rather than waiting for the next scheduled run.
+func (s *Server) ConsolidateAttention(w http.ResponseWriter, r *http.Request) {
+	if s.attention == nil {
+		notEnabled(w, "attention store is disabled")
+		return
+	}
+	result, err := s.attention.Consolidate(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxAttentionUpload caps an attention import
+const maxAttentionUpload = 1 << 30
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ExportAttention handles GET /api/edges/attention/export
+// Downloads every ATTENDED edge with its weight and query count, one JSON
+// object per line. ?compress=gzip compresses the file and
+// ?contributions=true adds each edge's recent contributions, which
+// /api/edges/attention/explain reads.
+func (s *Server) ExportAttention(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	compress := query.Get("compress")
+	if compress != "" && compress != "gzip" {
+		http.Error(w, "unsupported compress parameter (use gzip)", http.StatusBadRequest)
+		return
+	}
+
+	filename := "attention-" + time.Now().Format("20060102-150405") + ".jsonl"
+	var out io.Writer = w
+	if compress == "gzip" {
+		filename += ".gz"
+		w.Header().Set("Content-Type", "application/gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	// Headers are sent with the first edge, so a later failure can only
+	// cut the file short
+	if _, err := graph.ExportAttention(r.Context(), s.repo, out, query.Get("contributions") == "true"); err != nil {
+		log.Printf("Attention export failed: %v", err)
+	}
+}
+
+// ImportAttention handles POST /api/edges/attention/import
+// Reads a file from /api/edges/attention/export, gzipped or not, so
+// attention survives a move to another instance or a graph rebuild. An
+// edge that exists is replaced, or with ?mode=merge combined with the
+// imported one. Edges between nodes that do not exist are skipped.
+func (s *Server) ImportAttention(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "replace" && mode != "merge" {
+		http.Error(w, "invalid mode (use replace or merge)", http.StatusBadRequest)
+		return
+	}
+
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, maxAttentionUpload))
+	var in io.Reader = body
+	if magic, _ := body.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		in = gz
+	}
+
+	result, err := graph.ImportAttention(r.Context(), s.repo, in, mode == "merge")
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, graph.ErrInvalidAttention) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("%v (%d edges imported before it)", err, result.Imported+result.Replaced+result.Merged), writeErrorStatus(err, status))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
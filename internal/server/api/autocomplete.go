@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/autocomplete"
+	"github.com/systemshift/memex/internal/server/feedback"
+)
+
+// SetAutocompleteIndex enables GET /api/autocomplete
+func (s *Server) SetAutocompleteIndex(x *autocomplete.Index) {
+	s.autocomplete = x
+}
+
+// Autocomplete handles GET /api/autocomplete
+// Suggests nodes whose ID, name, words of the name or aliases start with
+// ?q=, optionally only ?types= (comma-separated). Names beat aliases, words
+// and IDs; well-connected and recently changed nodes rank higher. ?limit=
+// defaults to 10.
+func (s *Server) Autocomplete(w http.ResponseWriter, r *http.Request) {
+	if s.autocomplete == nil {
+		notEnabled(w, "autocomplete is disabled")
+		return
+	}
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		http.Error(w, "q parameter is required", http.StatusBadRequest)
+		return
+	}
+	limit := 10
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 100 {
+			http.Error(w, "invalid limit parameter (1-100)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	var types []string
+	if t := query.Get("types"); t != "" {
+		for _, part := range strings.Split(t, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				types = append(types, part)
+			}
+		}
+	}
+	if !s.autocomplete.Ready() {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "autocomplete index is still building", http.StatusServiceUnavailable)
+		return
+	}
+
+	start := time.Now()
+	suggestions := s.autocomplete.Query(q, types, limit, start)
+
+	took := float64(time.Since(start).Microseconds()) / 1000
+
+	response := map[string]interface{}{
+		"query":       q,
+		"suggestions": suggestions,
+		"count":       len(suggestions),
+		"took_ms":     took,
+	}
+	hits := make([]feedback.Hit, len(suggestions))
+	for i, sg := range suggestions {
+		hits[i] = feedback.Hit{NodeID: sg.ID, Signals: map[string]float64{"score": sg.Score, "degree": float64(sg.Degree)}}
+	}
+	if id := s.recordRetrieval("autocomplete", nil, hits); id != "" {
+		response["retrieval_id"] = id
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// GetBacklinks handles GET /api/nodes/{id}/backlinks
+// Returns the links pointing at a node grouped by type, each with a preview of
+// its source node. ?type= (repeatable or comma-separated) selects link types;
+// ?layer= selects link layers; ?limit= caps how many links are listed
+// (default 100, max 1000).
+func (s *Server) GetBacklinks(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 1000 {
+			http.Error(w, "invalid limit parameter (1-1000)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	backlinks, err := graph.GetBacklinkGroups(r.Context(), s.repo, id, listParam(r.URL.Query()["type"]), layers, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(backlinks)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/backup"
+)
+
+// maxBundleUpload caps a backup bundle import request
+const maxBundleUpload = 1 << 30
+
+// backupEnvironment returns the server state saved alongside the graph
+func (s *Server) backupEnvironment() backup.Environment {
+	return backup.Environment{
+		Subscriptions: s.subMgr,
+		Rules:         s.ruleEngine,
+		Quotas:        s.quotas,
+		APIKeyAuthors: s.apiKeyAuthors,
+	}
+}
+
+// ExportBundle handles GET /api/export/bundle
+// Writes a portable backup: live nodes and their links with subscriptions,
+// saved queries, link rules, namespaces and hashed API keys, described by
+// a manifest.
+func (s *Server) ExportBundle(w http.ResponseWriter, r *http.Request) {
+	bundle, err := backup.Export(r.Context(), s.repo, s.backupEnvironment())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"memex-%s.bundle.json\"", bundle.Manifest.Created.Format("20060102-150405")))
+	json.NewEncoder(w).Encode(bundle)
+}
+
+// ImportBundle handles POST /api/import/bundle
+// Restores a bundle from GET /api/export/bundle. Entries already on this
+// server are skipped; API keys in the bundle that are not configured here
+// are listed in the response.
+func (s *Server) ImportBundle(w http.ResponseWriter, r *http.Request) {
+	var bundle backup.Bundle
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBundleUpload)).Decode(&bundle); err != nil {
+		http.Error(w, "invalid bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	result, err := backup.Import(r.Context(), s.repo, s.backupEnvironment(), &bundle)
+	if err != nil {
+		status := writeErrorStatus(err, http.StatusInternalServerError)
+		if errors.Is(err, backup.ErrUnsupported) {
+			status = http.StatusUnprocessableEntity
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"manifest": bundle.Manifest,
+		"result":   result,
+		"took_ms":  time.Since(start).Milliseconds(),
+	})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/systemshift/memex/internal/server/importer"
+)
+
+// maxBibliographyUpload caps a BibTeX import request, attachments included
+const maxBibliographyUpload = 512 << 20
+
+// ZoteroImportRequest is the request body for POST /api/import/zotero
+type ZoteroImportRequest struct {
+	Library     string `json:"library"` // users/<id> or groups/<id>
+	APIKey      string `json:"api_key,omitempty"`
+	Collection  string `json:"collection,omitempty"`
+	Attachments bool   `json:"attachments,omitempty"` // Download stored PDFs
+}
+
+// SetZoteroURL points Zotero imports at another API host, such as a
+// self-hosted data server
+func (s *Server) SetZoteroURL(u string) {
+	s.zoteroURL = u
+}
+
+// ImportBibTeX handles POST /api/import/bibtex
+// The body is a .bib file, or multipart/form-data with the file in a "bib"
+// part and attachments in "file" parts. An uploaded file is attached to
+// the entries whose file field names it (matched by base name).
+func (s *Server) ImportBibTeX(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBibliographyUpload)
+
+	var works []importer.Work
+	var err error
+	files := make(map[string]importer.Attachment)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		works, err = readBibMultipart(r, files)
+	} else {
+		works, err = importer.ParseBibTeX(r.Body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for i, work := range works {
+		for _, f := range work.Files {
+			if a, ok := files[path.Base(strings.ReplaceAll(f, `\`, "/"))]; ok {
+				works[i].Attachments = append(works[i].Attachments, a)
+			}
+		}
+	}
+
+	result, err := importer.ImportWorks(r.Context(), s.repo, works, importer.BibliographyOptions{Connector: "bibtex", ChangedBy: r.URL.Query().Get("changed_by")})
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// readBibMultipart parses the "bib" part and collects "file" parts by name
+func readBibMultipart(r *http.Request, files map[string]importer.Attachment) ([]importer.Work, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	var works []importer.Work
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return works, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch part.FormName() {
+		case "bib":
+			if works, err = importer.ParseBibTeX(part); err != nil {
+				return nil, err
+			}
+		case "file":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+			name := path.Base(part.FileName())
+			files[name] = importer.Attachment{Name: name, ContentType: part.Header.Get("Content-Type"), Data: data}
+		}
+	}
+}
+
+// ImportZotero handles POST /api/import/zotero
+// Imports a Zotero library (or one collection) through the Zotero Web API
+func (s *Server) ImportZotero(w http.ResponseWriter, r *http.Request) {
+	var req ZoteroImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	works, err := importer.FetchZotero(r.Context(), importer.ZoteroOptions{
+		BaseURL:     s.zoteroURL,
+		APIKey:      req.APIKey,
+		Library:     req.Library,
+		Collection:  req.Collection,
+		Attachments: req.Attachments,
+	})
+	if errors.Is(err, importer.ErrInvalidLibrary) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	result, err := importer.ImportWorks(r.Context(), s.repo, works, importer.BibliographyOptions{Connector: "zotero"})
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxBulkDelete caps how many nodes one bulk delete may match
+const maxBulkDelete = 100000
+
+// maxBatchGet caps how many nodes one batch get may ask for
+const maxBatchGet = 1000
+
+// BulkIngestRequest is the request body for ingesting many sources
+type BulkIngestRequest struct {
+	Sources []IngestRequest `json:"sources"`
+}
+
+// BulkIngestResult reports one source of a bulk ingest
+type BulkIngestResult struct {
+	SourceID     string               `json:"source_id"`
+	Created      time.Time            `json:"created"`
+	Deduplicated bool                 `json:"deduplicated,omitempty"` // Already ingested
+	Content      *graph.ContentDigest `json:"content"`
+}
+
+// BulkIngestResponse is the response for a bulk ingest
+type BulkIngestResponse struct {
+	Sources []BulkIngestResult `json:"sources"` // In request order
+	Created int                `json:"created"`
+}
+
+// BulkIngest handles POST /api/ingest/bulk
+// Creates a Source node per entry, as POST /api/ingest does, in one write.
+// Sources already ingested, or repeated in the request, are deduplicated.
+func (s *Server) BulkIngest(w http.ResponseWriter, r *http.Request) {
+	var req BulkIngestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range req.Sources {
+		req.Sources[i].normalize(s.normalization)
+		if err := req.Sources[i].validate(); err != nil {
+			http.Error(w, fmt.Sprintf("sources[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, span := tracer.Start(r.Context(), "ingest.bulk", trace.WithAttributes(
+		attribute.Int("memex.sources", len(req.Sources)),
+	))
+	defer span.End()
+
+	now := time.Now()
+	resp := BulkIngestResponse{Sources: make([]BulkIngestResult, len(req.Sources))}
+	pending := make(map[string]int) // Source ID -> index of its first entry
+	var nodes []*core.Node
+	for i := range req.Sources {
+		id := req.Sources[i].sourceID()
+		if first, ok := pending[id]; ok {
+			resp.Sources[i] = BulkIngestResult{SourceID: id, Created: resp.Sources[first].Created, Deduplicated: true, Content: resp.Sources[first].Content}
+			continue
+		}
+		if existing, err := s.repo.GetNode(ctx, id); err == nil && existing != nil {
+			if err := graph.VerifyContent(existing); err != nil {
+				writeContentError(w, fmt.Errorf("sources[%d]: %w", i, err), http.StatusInternalServerError)
+				return
+			}
+			resp.Sources[i] = BulkIngestResult{SourceID: id, Created: existing.Created, Deduplicated: true, Content: graph.DigestNode(existing)}
+			continue
+		}
+		pending[id] = i
+		node := req.Sources[i].sourceNode(now)
+		nodes = append(nodes, node)
+		resp.Sources[i] = BulkIngestResult{SourceID: id, Created: now, Content: graph.DigestNode(node)}
+	}
+
+	if len(nodes) > 0 {
+		if err := s.repo.CreateNodes(ctx, nodes); err != nil {
+			span.RecordError(err)
+			http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			return
+		}
+		ids := make([]string, len(nodes))
+		for i, n := range nodes {
+			ids[i] = n.ID
+		}
+		// Audit only; a failure does not fail the request
+		s.recordTransaction(ctx, "ingest_bulk", map[string]interface{}{"source_ids": ids})
+	}
+	resp.Created = len(nodes)
+	span.SetAttributes(attribute.Int("memex.created", resp.Created))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// BulkDeleteRequest is the request body for deleting many nodes. Nodes are
+// named by IDs, or matched by Types and Expr, an expression in the
+// subscription language over node_id, node_type, meta and timestamp (the
+// node's last modification).
+type BulkDeleteRequest struct {
+	IDs     []string `json:"ids,omitempty"`
+	Types   []string `json:"types,omitempty"`
+	Expr    string   `json:"expr,omitempty"`
+	Force   bool     `json:"force,omitempty"`
+	Cascade string   `json:"cascade,omitempty"`
+	DryRun  bool     `json:"dry_run,omitempty"` // Report the matches without deleting
+	Limit   int      `json:"limit,omitempty"`   // Most nodes a filter may match; default and cap 100000
+}
+
+// BulkDeleteFailure is a node a bulk delete could not delete
+type BulkDeleteFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// BulkDeleteResponse is the response for a bulk delete
+type BulkDeleteResponse struct {
+	Matched   []string            `json:"matched"`
+	Deleted   int                 `json:"deleted"`
+	Failed    []BulkDeleteFailure `json:"failed,omitempty"`
+	Truncated bool                `json:"truncated,omitempty"` // The filter matched more than the limit
+	DryRun    bool                `json:"dry_run,omitempty"`
+}
+
+// BulkDeleteNodes handles POST /api/nodes/bulk/delete
+// Tombstones each node as DELETE /api/nodes/{id} does. A node that cannot
+// be deleted is reported in failed without stopping the rest.
+func (s *Server) BulkDeleteNodes(w http.ResponseWriter, r *http.Request) {
+	var req BulkDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filtered := len(req.Types) > 0 || req.Expr != ""
+	if len(req.IDs) > 0 == filtered {
+		http.Error(w, "give either ids or a filter (types and/or expr)", http.StatusBadRequest)
+		return
+	}
+	if req.Limit < 0 || req.Limit > maxBulkDelete {
+		http.Error(w, fmt.Sprintf("invalid limit (1-%d)", maxBulkDelete), http.StatusBadRequest)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = maxBulkDelete
+	}
+	ctx := r.Context()
+	if req.Cascade != "" {
+		if err := graph.ValidateCascade(req.Cascade); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = graph.ContextWithCascade(ctx, req.Cascade)
+	}
+
+	resp := BulkDeleteResponse{Matched: req.IDs, DryRun: req.DryRun}
+	if filtered {
+		var err error
+		resp.Matched, resp.Truncated, err = s.matchNodes(r, req.Types, req.Expr, req.Limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !req.DryRun {
+		for _, id := range resp.Matched {
+			if err := s.repo.DeleteNode(ctx, id, req.Force); err != nil {
+				resp.Failed = append(resp.Failed, BulkDeleteFailure{ID: id, Error: err.Error()})
+				continue
+			}
+			resp.Deleted++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// matchNodes returns the IDs of current nodes of the given types (any type
+// when empty) that satisfy expr, at most limit of them
+func (s *Server) matchNodes(r *http.Request, types []string, expr string, limit int) (ids []string, truncated bool, err error) {
+	var cond *subscriptions.Expression
+	if expr != "" {
+		if cond, err = subscriptions.CompileExpression(expr); err != nil {
+			return nil, false, fmt.Errorf("invalid expr: %w", err)
+		}
+	}
+
+	const page = 500
+	ids = []string{}
+	for offset := 0; ; offset += page {
+		nodes, err := s.repo.FilterNodes(r.Context(), types, "", "", page, offset)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, n := range nodes {
+			if cond != nil {
+				ok, err := cond.Eval(subscriptions.Event{NodeID: n.ID, NodeType: n.Type, Meta: n.Meta, Timestamp: n.Modified})
+				if err != nil || !ok {
+					continue
+				}
+			}
+			if len(ids) == limit {
+				return ids, true, nil
+			}
+			ids = append(ids, n.ID)
+		}
+		if len(nodes) < page {
+			return ids, false, nil
+		}
+	}
+}
+
+// BatchGetRequest is the request body for fetching many nodes by ID.
+// Fields and MaxContentBytes shape each node as the fields and
+// max_content_bytes query parameters do on other node endpoints.
+type BatchGetRequest struct {
+	IDs             []string `json:"ids"`
+	Fields          []string `json:"fields,omitempty"`
+	MaxContentBytes int      `json:"max_content_bytes,omitempty"`
+	IncludeDeleted  bool     `json:"include_deleted,omitempty"`
+}
+
+// BatchGetResponse is the response for a batch get
+type BatchGetResponse struct {
+	Nodes   []interface{} `json:"nodes"` // In request order; *core.Node or a projection
+	Missing []string      `json:"missing,omitempty"`
+	Count   int           `json:"count"`
+}
+
+// BatchGetNodes handles POST /api/nodes/batch-get
+// Returns many nodes in one round trip, as GET /api/nodes/{id} would each.
+// IDs that are repeated are returned once; IDs not found are listed in
+// missing.
+func (s *Server) BatchGetNodes(w http.ResponseWriter, r *http.Request) {
+	var req BatchGetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxBatchGet {
+		http.Error(w, fmt.Sprintf("give between 1 and %d ids", maxBatchGet), http.StatusBadRequest)
+		return
+	}
+	view, err := newNodeView(req.Fields, req.MaxContentBytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	if req.IncludeDeleted {
+		ctx = graph.WithDeleted(ctx)
+	}
+
+	resp := BatchGetResponse{Nodes: []interface{}{}}
+	seen := make(map[string]bool, len(req.IDs))
+	var found []string
+	for _, id := range req.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		node, err := s.repo.GetNode(ctx, id)
+		if err != nil || node == nil {
+			resp.Missing = append(resp.Missing, id)
+			continue
+		}
+		found = append(found, node.ID)
+		resp.Nodes = append(resp.Nodes, view.node(node))
+	}
+	resp.Count = len(resp.Nodes)
+	s.recordSession(r, "", found...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
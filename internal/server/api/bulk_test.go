@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestBulkIngestAndDelete(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	s := New(repo, nil)
+
+	post := func(handler http.HandlerFunc, body string, v interface{}) int {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code
+	}
+
+	var ingested BulkIngestResponse
+	body := `{"sources": [{"content": "alpha", "format": "text"}, {"content": "beta", "url": "file:///b.txt"}, {"content": "alpha"}]}`
+	if code := post(s.BulkIngest, body, &ingested); code != http.StatusOK {
+		t.Fatalf("bulk ingest status = %d", code)
+	}
+	if ingested.Created != 2 || len(ingested.Sources) != 3 || !ingested.Sources[2].Deduplicated ||
+		ingested.Sources[2].SourceID != ingested.Sources[0].SourceID {
+		t.Fatalf("bulk ingest = %+v", ingested)
+	}
+	beta, err := repo.GetNode(ctx, ingested.Sources[1].SourceID)
+	if err != nil || beta.Type != "Source" || string(beta.Content) != "beta" || beta.Meta["url"] != "file:///b.txt" {
+		t.Errorf("ingested source = %+v, %v", beta, err)
+	}
+	if code := post(s.BulkIngest, body, &ingested); code != http.StatusOK || ingested.Created != 0 || !ingested.Sources[1].Deduplicated {
+		t.Errorf("repeat ingest = %d %+v", code, ingested)
+	}
+	if code := post(s.BulkIngest, `{"sources": [{"content": "ok"}, {"content": ""}]}`, &ingested); code != http.StatusBadRequest {
+		t.Errorf("empty source status = %d", code)
+	}
+
+	now := time.Now()
+	for id, status := range map[string]string{"note:a": "draft", "note:b": "draft", "note:c": "final"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Meta: map[string]interface{}{"status": status}, Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var deleted BulkDeleteResponse
+	if code := post(s.BulkDeleteNodes, `{"types": ["Note"], "expr": "meta.status == \"draft\"", "dry_run": true}`, &deleted); code != http.StatusOK {
+		t.Fatalf("dry run status = %d", code)
+	}
+	if len(deleted.Matched) != 2 || deleted.Deleted != 0 {
+		t.Fatalf("dry run = %+v", deleted)
+	}
+	if _, err := repo.GetNode(ctx, "note:a"); err != nil {
+		t.Fatal("dry run deleted a node")
+	}
+
+	if code := post(s.BulkDeleteNodes, `{"ids": ["note:a", "note:b", "`+beta.ID+`"]}`, &deleted); code != http.StatusOK {
+		t.Fatalf("delete status = %d", code)
+	}
+	if deleted.Deleted != 2 || len(deleted.Failed) != 1 || deleted.Failed[0].ID != beta.ID {
+		t.Errorf("delete = %+v, want the Source node refused without force", deleted)
+	}
+	if _, err := repo.GetNode(ctx, "note:b"); err == nil {
+		t.Error("note:b still live")
+	}
+
+	for _, bad := range []string{`{}`, `{"ids": ["note:c"], "types": ["Note"]}`, `{"expr": "meta.status =="}`} {
+		if code := post(s.BulkDeleteNodes, bad, &deleted); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", bad, code)
+		}
+	}
+}
+
+func TestBatchGetNodes(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	s := New(repo, nil)
+	for _, id := range []string{"note:a", "note:b"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Content: []byte("long text"), Meta: map[string]interface{}{"title": id, "status": "draft"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		s.BatchGetNodes(w, httptest.NewRequest("POST", "/api/nodes/batch-get", strings.NewReader(body)))
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	code, resp := get(`{"ids": ["note:b", "note:missing", "note:a", "note:b"], "fields": ["type", "meta.title"]}`)
+	if code != http.StatusOK || resp["count"] != 2.0 {
+		t.Fatalf("batch get = %d %v", code, resp)
+	}
+	nodes := resp["nodes"].([]interface{})
+	first := nodes[0].(map[string]interface{})
+	if first["ID"] != "note:b" || first["Type"] != "Note" || first["Content"] != nil {
+		t.Errorf("projected node = %v", first)
+	}
+	if meta := first["Meta"].(map[string]interface{}); len(meta) != 1 || meta["title"] != "note:b" {
+		t.Errorf("projected meta = %v", meta)
+	}
+	if missing := resp["missing"].([]interface{}); len(missing) != 1 || missing[0] != "note:missing" {
+		t.Errorf("missing = %v", missing)
+	}
+
+	if _, resp := get(`{"ids": ["note:a"]}`); resp["nodes"].([]interface{})[0].(map[string]interface{})["Content"] == nil {
+		t.Errorf("whole node = %v", resp)
+	}
+	_, resp = get(`{"ids": ["note:a"], "fields": ["content"], "max_content_bytes": 4}`)
+	if cut := resp["nodes"].([]interface{})[0].(map[string]interface{}); cut["Content"] != "bG9uZw==" || cut["ContentLength"] != 9.0 || cut["ContentTruncated"] != true {
+		t.Errorf("truncated node = %v", cut)
+	}
+	if code, _ := get(`{"ids": ["note:a"], "fields": ["colour"]}`); code != http.StatusBadRequest {
+		t.Errorf("unknown field status = %d", code)
+	}
+	if code, _ := get(`{"ids": []}`); code != http.StatusBadRequest {
+		t.Errorf("no ids status = %d", code)
+	}
+}
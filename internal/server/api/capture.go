@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/systemshift/memex/internal/server/capture"
+)
+
+// maxCaptureRequest caps a capture request; screenshots are base64 encoded
+const maxCaptureRequest = capture.MaxScreenshot*4/3 + 1<<20
+
+// CaptureResponse is returned by POST /api/capture
+type CaptureResponse struct {
+	capture.Result
+	Link string `json:"link"` // Where to view the highlight, or the page without one
+}
+
+// SetWebURL sets the web UI's node link; {id} is replaced with the node ID
+func (s *Server) SetWebURL(template string) {
+	s.webURL = template
+}
+
+// nodeLink returns the web UI link to a node, or its node page when no
+// web UI is configured
+func (s *Server) nodeLink(r *http.Request, id string) string {
+	if s.webURL == "" {
+		return s.BaseURL(r) + "/n/" + url.PathEscape(id)
+	}
+	return strings.ReplaceAll(s.webURL, "{id}", url.PathEscape(id))
+}
+
+// Capture handles POST /api/capture
+// Saves a page, a selection from it and a screenshot in one call, for
+// browser extensions.
+func (s *Server) Capture(w http.ResponseWriter, r *http.Request) {
+	var req capture.Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCaptureRequest)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := capture.Save(r.Context(), s.repo, &req)
+	if err != nil {
+		status := writeErrorStatus(err, http.StatusInternalServerError)
+		if errors.Is(err, capture.ErrInvalid) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	resp := CaptureResponse{Result: *result, Link: s.nodeLink(r, result.PageID)}
+	if result.HighlightID != "" {
+		resp.Link = s.nodeLink(r, result.HighlightID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+const (
+	// errorCodeHeader carries a machine-readable code for errors clients
+	// are expected to tell apart
+	errorCodeHeader = "X-Memex-Error-Code"
+
+	// contentCorruptCode marks stored content that failed verification
+	contentCorruptCode = "content_hash_mismatch"
+)
+
+// writeContentError reports content that failed verification as a 500
+// with the content_hash_mismatch code, or any other error with fallback
+func writeContentError(w http.ResponseWriter, err error, fallback int) {
+	if errors.Is(err, graph.ErrContentCorrupt) {
+		w.Header().Set(errorCodeHeader, contentCorruptCode)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	http.Error(w, err.Error(), fallback)
+}
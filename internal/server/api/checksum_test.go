@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestIngestPinsContentAndReadsVerify(t *testing.T) {
+	repo := graph.NewMemory()
+	s := New(repo, nil)
+	r := chi.NewRouter()
+	r.Post("/api/ingest", s.Ingest)
+	r.Get("/api/nodes/{id}", s.GetNode)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	for _, attempt := range []string{"new", "deduplicated"} {
+		w := do("POST", "/api/ingest", `{"content": "line one\nline two\n", "format": "text"}`)
+		var resp IngestResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s ingest: %d, %v", attempt, w.Code, err)
+		}
+		if resp.Content == nil || "sha256:"+resp.Content.SHA256 != resp.SourceID || resp.Content.Size != 18 || resp.Content.LineEndings != "lf" {
+			t.Errorf("%s ingest content = %+v", attempt, resp.Content)
+		}
+	}
+
+	// Content that no longer matches its content-addressed ID fails reads
+	now := time.Now()
+	id := "sha256:" + graph.DigestContent([]byte("original")).SHA256
+	repo.CreateNode(context.Background(), &core.Node{ID: id, Type: "Source", Content: []byte("bit rot"), Meta: map[string]interface{}{}, Created: now, Modified: now})
+	w := do("GET", "/api/nodes/"+id, "")
+	if w.Code != http.StatusInternalServerError || w.Header().Get(errorCodeHeader) != contentCorruptCode {
+		t.Errorf("corrupt read = %d %q: %s", w.Code, w.Header().Get(errorCodeHeader), w.Body)
+	}
+	w = do("POST", "/api/ingest", `{"content": "original"}`)
+	if w.Header().Get(errorCodeHeader) != contentCorruptCode {
+		t.Errorf("ingest over corrupt source = %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/citations"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// SetCitationProcessor enables on-demand reference parsing
+func (s *Server) SetCitationProcessor(p *citations.Processor) {
+	s.citationProc = p
+}
+
+// ParseReferences handles POST /api/nodes/{id}/references
+// Parses a paper's references now, even if it was parsed before, and
+// returns what was found and created
+func (s *Server) ParseReferences(w http.ResponseWriter, r *http.Request) {
+	if s.citationProc == nil {
+		notEnabled(w, "citation parsing is disabled")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	node, err := s.repo.GetNode(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if !citations.IsPaperNode(node) {
+		http.Error(w, "node is not a paper (type Paper, or a PDF source)", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.citationProc.ProcessNode(r.Context(), id, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// MostCited handles GET /api/citations/top
+// Ranks papers by how many nodes in the library cite them (?limit=, default 20)
+func (s *Server) MostCited(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 1000 {
+			http.Error(w, "invalid limit parameter (1-1000)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	works, err := citations.MostCited(r.Context(), s.repo, limit)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"papers": works,
+		"count":  len(works),
+	})
+}
+
+// CitationView handles GET /api/citations/graph
+// Returns a display-oriented citation graph: CITES links only, either
+// around ?focus= (with ?depth=, default 2) or across the library (?limit=
+// caps nodes, default 2000). ?cluster= and ?expand= work as on /graph/view.
+func (s *Server) CitationView(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 2000
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxViewLimit)
+	}
+
+	depth := 2
+	if d := query.Get("depth"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid depth parameter", http.StatusBadRequest)
+			return
+		}
+		depth = n
+	}
+
+	if checkETag(w, r, s.graphETag()) {
+		return
+	}
+
+	var sub *graph.Subgraph
+	var truncated bool
+	var err error
+	if focus := query.Get("focus"); focus != "" {
+		sub, err = s.repo.GetSubgraph(r.Context(), focus, depth, []string{citations.CitesLink})
+		if err == nil {
+			sub = citations.CitationSubgraph(sub, focus)
+		}
+	} else {
+		sub, truncated, err = citations.CitationGraph(r.Context(), s.repo, limit)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	view := graph.BuildView(sub, graph.ViewOptions{
+		ClusterBy: query.Get("cluster"),
+		Expand:    listParam(query["expand"]),
+	})
+	view.Truncated = truncated
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
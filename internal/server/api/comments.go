@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/annotations"
+)
+
+// CommentRequest is the request body for POST /api/nodes/{id}/comments
+type CommentRequest struct {
+	Body    string             `json:"body"`
+	Author  string             `json:"author,omitempty"`   // Ignored when API key authors are configured
+	ReplyTo string             `json:"reply_to,omitempty"` // Comment being answered
+	Anchor  *annotations.Range `json:"anchor,omitempty"`   // Content range annotated, in characters
+}
+
+// SetAPIKeyAuthors maps API keys to author names. When set, comment
+// endpoints require a key and attribute comments to its author.
+func (s *Server) SetAPIKeyAuthors(authors map[string]string) {
+	s.apiKeyAuthors = authors
+}
+
+// requestAuthor returns the author for a request: the name of its access
+// token or signing key, or of its API key (X-API-Key or a bearer token)
+// when keys are configured, otherwise the author the client gave
+func (s *Server) requestAuthor(r *http.Request, given string) (string, bool) {
+	if tok := requestToken(r.Context()); tok != nil {
+		return tok.Name, true
+	}
+	if keyID := requestSigner(r.Context()); keyID != "" {
+		return keyID, true
+	}
+	if len(s.apiKeyAuthors) == 0 {
+		return given, true
+	}
+	key := requestKey(r)
+	author, ok := s.apiKeyAuthors[key]
+	return author, ok && key != ""
+}
+
+// commentErrorStatus maps comment errors to HTTP status codes
+func commentErrorStatus(err error) int {
+	if errors.Is(err, annotations.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// CreateComment handles POST /api/nodes/{id}/comments
+// Adds a comment, or a reply when reply_to names a comment on the same node.
+// An anchor ({"start", "end"}) pins the comment to a range of the content.
+func (s *Server) CreateComment(w http.ResponseWriter, r *http.Request) {
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	author, ok := s.requestAuthor(r, req.Author)
+	if !ok {
+		http.Error(w, "a valid API key is required", http.StatusUnauthorized)
+		return
+	}
+
+	comment, err := annotations.AddComment(r.Context(), s.repo, chi.URLParam(r, "id"), author, req.Body, req.ReplyTo, req.Anchor)
+	if err != nil {
+		http.Error(w, err.Error(), commentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// ListComments handles GET /api/nodes/{id}/comments
+// Returns the node's comment threads, oldest first
+func (s *Server) ListComments(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := s.repo.GetNode(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	threads, count, err := annotations.Thread(r.Context(), s.repo, id)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"node_id":  id,
+		"comments": threads,
+		"count":    count,
+	})
+}
+
+// ListAnnotations handles GET /api/nodes/{id}/annotations
+// Returns the node's anchored comments, rebased onto its current content and
+// ordered by position. With ?start= and ?end=, only annotations overlapping
+// that range are returned.
+func (s *Server) ListAnnotations(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	node, err := s.repo.GetNode(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var within *annotations.Range
+	query := r.URL.Query()
+	if query.Has("start") || query.Has("end") {
+		start, err1 := strconv.Atoi(query.Get("start"))
+		end, err2 := strconv.Atoi(query.Get("end"))
+		if err1 != nil || err2 != nil || start < 0 || end <= start {
+			http.Error(w, "start and end must be offsets with start < end", http.StatusBadRequest)
+			return
+		}
+		within = &annotations.Range{Start: start, End: end}
+	}
+
+	list, err := annotations.Annotations(r.Context(), s.repo, id, within)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"node_id":     id,
+		"version":     node.Version,
+		"annotations": list,
+		"count":       len(list),
+	})
+}
+
+// DeleteComment handles DELETE /api/comments/{id}
+// With API key authors configured, only the comment's author may delete it
+func (s *Server) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if len(s.apiKeyAuthors) > 0 {
+		author, ok := s.requestAuthor(r, "")
+		if !ok {
+			http.Error(w, "a valid API key is required", http.StatusUnauthorized)
+			return
+		}
+		if c, err := annotations.Get(r.Context(), s.repo, id); err == nil && c.Author != author {
+			http.Error(w, "only the author may delete a comment", http.StatusForbidden)
+			return
+		}
+	}
+
+	comment, err := annotations.Delete(r.Context(), s.repo, id)
+	if err != nil {
+		http.Error(w, err.Error(), commentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      comment.ID,
+		"node_id": comment.NodeID,
+		"deleted": true,
+	})
+}
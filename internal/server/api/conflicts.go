@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/conflicts"
+)
+
+// ListConflicts handles GET /api/conflicts
+// Detects contradictory assertions (several current links of one type, or
+// claims disagreeing on a subject's property) without modifying the graph.
+func (s *Server) ListConflicts(w http.ResponseWriter, r *http.Request) {
+	found, err := conflicts.Detect(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	if found == nil {
+		found = []*conflicts.Conflict{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"conflicts": found,
+		"count":     len(found),
+	})
+}
+
+// ScanConflicts handles POST /api/conflicts/scan
+// Detects conflicts and records new ones as CONFLICTS_WITH links.
+func (s *Server) ScanConflicts(w http.ResponseWriter, r *http.Request) {
+	found, err := conflicts.Detect(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	created, err := conflicts.Record(r.Context(), s.repo, found)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"conflicts":     len(found),
+		"links_created": created,
+	})
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/connectors"
+)
+
+// ConnectorView is a definition with how its runs have gone
+type ConnectorView struct {
+	*connectors.Connector
+	Status *connectors.Status `json:"status"`
+}
+
+// SetConnectors enables connectors defined through the API or config file
+func (s *Server) SetConnectors(m *connectors.Manager) {
+	s.connectors = m
+}
+
+// connectorStatus maps connector errors to HTTP status codes
+func connectorStatus(err error) int {
+	switch {
+	case errors.Is(err, connectors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, connectors.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, connectors.ErrExists), errors.Is(err, connectors.ErrManaged), errors.Is(err, connectors.ErrRunning):
+		return http.StatusConflict
+	}
+	return writeErrorStatus(err, http.StatusInternalServerError)
+}
+
+// connectorsEnabled reports an error when connectors are disabled
+func (s *Server) connectorsEnabled(w http.ResponseWriter) bool {
+	if s.connectors == nil {
+		notEnabled(w, "connectors are disabled")
+		return false
+	}
+	return true
+}
+
+// ListConnectors handles GET /api/connectors
+// Lists every connector with its status, and the kinds available.
+func (s *Server) ListConnectors(w http.ResponseWriter, r *http.Request) {
+	if !s.connectorsEnabled(w) {
+		return
+	}
+	list, err := connectors.List(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	views := make([]ConnectorView, 0, len(list))
+	for _, c := range list {
+		views = append(views, ConnectorView{Connector: c, Status: s.connectors.Status(r.Context(), c)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"connectors": views,
+		"count":      len(views),
+		"kinds":      s.connectors.Kinds(),
+	})
+}
+
+// CreateConnector handles POST /api/connectors
+// Credentials are env:VAR or file:/path references, never values.
+func (s *Server) CreateConnector(w http.ResponseWriter, r *http.Request) {
+	if !s.connectorsEnabled(w) {
+		return
+	}
+	var c connectors.Connector
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := s.connectors.Create(r.Context(), &c); err != nil {
+		http.Error(w, err.Error(), connectorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ConnectorView{Connector: &c, Status: s.connectors.Status(r.Context(), &c)})
+}
+
+// GetConnector handles GET /api/connectors/{name}
+func (s *Server) GetConnector(w http.ResponseWriter, r *http.Request) {
+	if !s.connectorsEnabled(w) {
+		return
+	}
+	c, err := connectors.Get(r.Context(), s.repo, chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, err.Error(), connectorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConnectorView{Connector: c, Status: s.connectors.Status(r.Context(), c)})
+}
+
+// UpdateConnector handles PUT /api/connectors/{name}
+// Replaces the definition; ones from the config file are changed there.
+func (s *Server) UpdateConnector(w http.ResponseWriter, r *http.Request) {
+	if !s.connectorsEnabled(w) {
+		return
+	}
+	var c connectors.Connector
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	c.Name = chi.URLParam(r, "name")
+	if err := s.connectors.Update(r.Context(), &c); err != nil {
+		http.Error(w, err.Error(), connectorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConnectorView{Connector: &c, Status: s.connectors.Status(r.Context(), &c)})
+}
+
+// DeleteConnector handles DELETE /api/connectors/{name}
+// What the connector wrote stays in the graph.
+func (s *Server) DeleteConnector(w http.ResponseWriter, r *http.Request) {
+	if !s.connectorsEnabled(w) {
+		return
+	}
+	if err := s.connectors.Delete(r.Context(), chi.URLParam(r, "name")); err != nil {
+		http.Error(w, err.Error(), connectorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunConnector handles POST /api/connectors/{name}/run
+// Runs the connector now and returns its status; a failed run is
+// reported in the status's last_error.
+func (s *Server) RunConnector(w http.ResponseWriter, r *http.Request) {
+	if !s.connectorsEnabled(w) {
+		return
+	}
+	status, err := s.connectors.Run(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, err.Error(), connectorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// SetDAGLinkTypes sets the link types the DAG check endpoint inspects by default
+func (s *Server) SetDAGLinkTypes(types []string) {
+	s.dagLinkTypes = types
+}
+
+// linkErrorStatus maps link creation errors to HTTP status codes
+func linkErrorStatus(err error) int {
+	if errors.Is(err, graph.ErrCycle) {
+		return http.StatusConflict
+	}
+	return writeErrorStatus(err, http.StatusInternalServerError)
+}
+
+// CheckDAG handles GET /api/graph/dag
+// Reports existing cycles among derivation link types.
+// ?type= (repeatable) overrides the configured link types; ?max= caps the number of cycles returned.
+func (s *Server) CheckDAG(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	types := query["type"]
+	if len(types) == 0 {
+		types = s.dagLinkTypes
+	}
+	if len(types) == 0 {
+		types = graph.DefaultDAGLinkTypes
+	}
+
+	maxCycles := 100
+	if m := query.Get("max"); m != "" {
+		n, err := strconv.Atoi(m)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid max parameter", http.StatusBadRequest)
+			return
+		}
+		maxCycles = n
+	}
+
+	report, err := graph.FindCycles(r.Context(), s.repo, types, maxCycles)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
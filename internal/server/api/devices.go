@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/devices"
+)
+
+// DeviceHeader carries a device key, identifying the machine a request
+// comes from
+const DeviceHeader = "X-Memex-Device"
+
+// SetDevices enables the device registry and per-device provenance
+func (s *Server) SetDevices(r *devices.Registry) {
+	s.devices = r
+}
+
+// devicesEnabled reports an error when the device registry is disabled
+func (s *Server) devicesEnabled(w http.ResponseWriter) bool {
+	if s.devices == nil {
+		notEnabled(w, "devices are disabled")
+		return false
+	}
+	return true
+}
+
+// deviceStatus maps device errors to HTTP statuses
+func deviceStatus(err error) int {
+	switch {
+	case errors.Is(err, devices.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, devices.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, devices.ErrUnknown):
+		return http.StatusUnauthorized
+	case errors.Is(err, devices.ErrRevoked):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// IdentifyDevice is middleware that attributes a request's writes to the
+// device whose key it sends in X-Memex-Device. The key identifies the
+// device but grants no access of its own, so it runs after Authenticate.
+// Unknown keys get 401 and revoked devices 403; requests without the
+// header pass through.
+func (s *Server) IdentifyDevice(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(DeviceHeader)
+		if s.devices == nil || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		d, err := s.devices.Identify(r.Context(), key)
+		if err != nil {
+			http.Error(w, err.Error(), deviceStatus(err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(devices.WithDevice(r.Context(), d)))
+	})
+}
+
+// RegisterDeviceRequest is the body of POST /api/devices
+type RegisterDeviceRequest struct {
+	Name     string `json:"name"`
+	Platform string `json:"platform,omitempty"`
+}
+
+// RegisterDeviceResponse returns a new device with its key, which is only
+// ever shown here
+type RegisterDeviceResponse struct {
+	Device *devices.Device `json:"device"`
+	Key    string          `json:"key"`
+}
+
+// RegisterDevice handles POST /api/devices
+func (s *Server) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	if !s.devicesEnabled(w) {
+		return
+	}
+	var req RegisterDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d, key, err := s.devices.Register(r.Context(), req.Name, req.Platform)
+	if err != nil {
+		http.Error(w, err.Error(), deviceStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(RegisterDeviceResponse{Device: d, Key: key})
+}
+
+// ListDevices handles GET /api/devices
+func (s *Server) ListDevices(w http.ResponseWriter, r *http.Request) {
+	if !s.devicesEnabled(w) {
+		return
+	}
+	list, err := s.devices.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"devices": list})
+}
+
+// GetDevice handles GET /api/devices/{id}
+func (s *Server) GetDevice(w http.ResponseWriter, r *http.Request) {
+	if !s.devicesEnabled(w) {
+		return
+	}
+	d, err := s.devices.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), deviceStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// RevokeDevice handles POST /api/devices/{id}/revoke
+// The device's key stops working; what it already wrote keeps its stamp.
+func (s *Server) RevokeDevice(w http.ResponseWriter, r *http.Request) {
+	if !s.devicesEnabled(w) {
+		return
+	}
+	d, err := s.devices.Revoke(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), deviceStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Diagnostics is the response for GET /api/admin/diagnostics
+type Diagnostics struct {
+	Time       time.Time            `json:"time"` // Server clock, for skew checks
+	Backend    *graph.BackendHealth `json:"backend,omitempty"`
+	Components []string             `json:"components"` // Optional components that are enabled
+}
+
+// SetBackend names the configured backend and gives the diagnostics
+// endpoint the unwrapped repository to check
+func (s *Server) SetBackend(name string, backend graph.Repository) {
+	s.backendName = name
+	s.backend = backend
+}
+
+// GetDiagnostics handles GET /api/admin/diagnostics
+// Reports the server clock, backend health and schema version, and which
+// optional components are running, for `memex doctor`.
+func (s *Server) GetDiagnostics(w http.ResponseWriter, r *http.Request) {
+	d := Diagnostics{Components: s.components()}
+	if s.backend != nil {
+		d.Backend = graph.CheckBackend(r.Context(), s.backendName, s.backend)
+	}
+	d.Time = time.Now().UTC()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// components lists the optional components configured on the server
+func (s *Server) components() []string {
+	enabled := map[string]bool{
+		"subscriptions":   s.subMgr != nil,
+		"rules":           s.ruleEngine != nil,
+		"autocomplete":    s.autocomplete != nil,
+		"citations":       s.citationProc != nil,
+		"tasks":           s.taskProc != nil,
+		"sandboxes":       s.sandboxes != nil,
+		"feedback":        s.feedback != nil,
+		"growth_stats":    s.growthRecorder != nil,
+		"github_webhook":  s.githubWebhookSecret != "",
+		"ticket_sync":     len(s.ticketSources) > 0,
+		"experiments":     s.experiments != nil,
+		"attention_store": s.attention != nil,
+		"memory":          s.memory != nil,
+		"sessions":        s.sessions != nil,
+		"query_scheduler": s.queryScheduler != nil,
+		"ingest_webhook":  s.ingestTracker != nil,
+		"share_links":     s.shares != nil,
+		"short_ids":       s.aliases != nil && s.aliases.ShortIDs(),
+		"federation":      s.peers != nil,
+		"access_tokens":   s.tokenIssuer != nil,
+		"request_signing": s.requestVerifier != nil,
+		"slow_query_log":  s.slowLog != nil,
+	}
+	names := []string{}
+	for name, on := range enabled {
+		if on {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxDiffViewLimit caps the nodes a diff view shows
+const maxDiffViewLimit = 5000
+
+// diffView computes the laid-out diff for ?from= and ?to= (default the last
+// week), showing at most ?limit= nodes (default 500)
+func (s *Server) diffView(r *http.Request) (*graph.DiffView, int, error) {
+	limit := 500
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			return nil, http.StatusBadRequest, errors.New("invalid limit parameter")
+		}
+		limit = min(n, maxDiffViewLimit)
+	}
+	from, to, err := parseTimeWindow(r, 7*24*time.Hour)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	diff, err := s.repo.DiffGraph(r.Context(), from, to, true)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	view := graph.BuildDiffView(r.Context(), s.repo, diff, limit)
+	view.ApplyStyles(s.typeStyles(r))
+	return view, http.StatusOK, nil
+}
+
+// GraphDiffView handles GET /api/graph/diff/view
+// Returns the changes between ?from= and ?to= laid out for display: added,
+// removed and modified nodes with the unchanged ends of changed links, at
+// positions that stay the same across diffs.
+func (s *Server) GraphDiffView(w http.ResponseWriter, r *http.Request) {
+	view, status, err := s.diffView(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// ViewGraphDiff handles GET /graph/diff
+// Renders the diff view as a page, with a form to pick the two times.
+// Takes the same parameters as GET /api/graph/diff/view.
+func (s *Server) ViewGraphDiff(w http.ResponseWriter, r *http.Request) {
+	view, status, err := s.diffView(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	positions := make(map[string]graph.DiffViewNode, len(view.Nodes))
+	for _, n := range view.Nodes {
+		positions[n.ID] = n
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	diffPageTemplate.Execute(w, map[string]interface{}{
+		"View":      view,
+		"Positions": positions,
+		"Base":      s.BaseURL(r),
+		"Size":      graph.DiffViewSize,
+	})
+}
+
+// diffPageTemplate renders a diff view as SVG
+var diffPageTemplate = template.Must(template.New("diff").Funcs(template.FuncMap{
+	"nodeURL": func(base, id string) string { return base + "/n/" + url.PathEscape(id) },
+	"date":    func(t time.Time) string { return t.Format("2006-01-02T15:04") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>Graph diff · Memex</title>
+<style>
+body { font: 15px/1.5 system-ui, sans-serif; max-width: 64rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+form { margin-bottom: 1rem; } small { color: #777; }
+svg { width: 100%; height: auto; border: 1px solid #ddd; border-radius: 4px; background: #fcfcfc; }
+.added { fill: #2e9e44; stroke: #2e9e44; } .removed { fill: #d03b3b; stroke: #d03b3b; }
+.modified { fill: #e0a100; stroke: #e0a100; } .context { fill: #bbb; stroke: #bbb; }
+line { stroke-width: 2; } line.removed { stroke-dasharray: 6 4; }
+.legend span { margin-right: 1rem; } .legend b { display: inline-block; width: .8em; height: .8em; border-radius: 50%; }
+b.added { background: #2e9e44; } b.removed { background: #d03b3b; } b.modified { background: #e0a100; } b.context { background: #bbb; }
+</style>
+</head>
+<body>
+<h1>Graph diff</h1>
+<form method="get">
+<label>From <input type="datetime-local" name="from" value="{{date .View.From}}"></label>
+<label>To <input type="datetime-local" name="to" value="{{date .View.To}}"></label>
+<button>Compare</button>
+</form>
+<p class="legend"><span><b class="added"></b> added ({{.View.Summary.NodesAdded}} nodes, {{.View.Summary.LinksAdded}} links)</span>
+<span><b class="removed"></b> removed ({{.View.Summary.NodesDeleted}} nodes, {{.View.Summary.LinksRemoved}} links)</span>
+<span><b class="modified"></b> modified ({{.View.Summary.NodesModified}})</span>
+<span><b class="context"></b> unchanged</span></p>
+{{if .View.Truncated}}<p><small>Showing the first {{len .View.Nodes}} nodes; raise ?limit= to see more.</small></p>{{end}}
+<svg viewBox="0 0 {{.Size}} {{.Size}}" xmlns="http://www.w3.org/2000/svg">
+{{range .View.Edges}}{{$s := index $.Positions .Source}}{{$t := index $.Positions .Target}}<line class="{{.Status}}" x1="{{$s.X}}" y1="{{$s.Y}}" x2="{{$t.X}}" y2="{{$t.Y}}"><title>{{.Source}} -[{{.Type}}]-> {{.Target}}</title></line>
+{{end}}{{range .View.Nodes}}<a href="{{nodeURL $.Base .ID}}"><circle class="{{.Status}}" cx="{{.X}}" cy="{{.Y}}" r="6"><title>{{.Label}} ({{.Type}}, {{.Status}})</title></circle></a>
+{{end}}</svg>
+</body>
+</html>
+`))
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/dedup"
+)
+
+// DuplicatePairRequest is the body of POST /api/duplicates/dismiss and
+// POST /api/duplicates/merge
+type DuplicatePairRequest struct {
+	Duplicate string `json:"duplicate"`
+	Into      string `json:"into"` // The node kept; for a dismissal, the other node of the pair
+	ChangedBy string `json:"changed_by,omitempty"`
+}
+
+// SetDedup enables near-duplicate detection
+func (s *Server) SetDedup(d *dedup.Detector) {
+	s.dedup = d
+}
+
+// dedupReady reports an error when near-duplicate detection is disabled
+// or its index is still building
+func (s *Server) dedupReady(w http.ResponseWriter) bool {
+	if s.dedup == nil {
+		notEnabled(w, "near-duplicate detection is disabled")
+		return false
+	}
+	if !s.dedup.Ready() {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "near-duplicate index is still building", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// dedupStatus maps near-duplicate review errors to HTTP status codes
+func dedupStatus(err error) int {
+	switch {
+	case errors.Is(err, dedup.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, dedup.ErrInvalidMerge):
+		return http.StatusBadRequest
+	}
+	return writeErrorStatus(err, http.StatusInternalServerError)
+}
+
+// ListDuplicates handles GET /api/duplicates
+// Lists flagged near-duplicate pairs, most similar first. ?status= picks
+// pending, dismissed, merged or all pairs; the default is pending. A
+// merged pair is listed only while its duplicate survives, as a source
+// node does; otherwise the kept node's merged_from records it.
+func (s *Server) ListDuplicates(w http.ResponseWriter, r *http.Request) {
+	if !s.dedupReady(w) {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = dedup.StatusPending
+	case "all":
+		status = ""
+	case dedup.StatusPending, dedup.StatusDismissed, dedup.StatusMerged:
+	default:
+		http.Error(w, "status must be pending, dismissed, merged or all", http.StatusBadRequest)
+		return
+	}
+	pairs := s.dedup.Pairs(status)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pairs": pairs,
+		"count": len(pairs),
+	})
+}
+
+// ScanDuplicates handles POST /api/duplicates/scan
+// Compares every node with every other and flags the near-duplicates not
+// yet flagged, such as those ingested before detection was enabled.
+func (s *Server) ScanDuplicates(w http.ResponseWriter, r *http.Request) {
+	if !s.dedupReady(w) {
+		return
+	}
+	flagged, err := s.dedup.Scan(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), dedupStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"flagged": flagged,
+		"nodes":   s.dedup.Len(),
+	})
+}
+
+// decodeDuplicatePair reads and checks the body of a review request
+func decodeDuplicatePair(w http.ResponseWriter, r *http.Request) (DuplicatePairRequest, bool) {
+	var req DuplicatePairRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+	if req.Duplicate == "" || req.Into == "" {
+		http.Error(w, "duplicate and into are required", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// DismissDuplicate handles POST /api/duplicates/dismiss
+// Marks a flagged pair as not duplicates, so it is not flagged again.
+func (s *Server) DismissDuplicate(w http.ResponseWriter, r *http.Request) {
+	if !s.dedupReady(w) {
+		return
+	}
+	req, ok := decodeDuplicatePair(w, r)
+	if !ok {
+		return
+	}
+	pair, err := s.dedup.Dismiss(r.Context(), req.Duplicate, req.Into, req.ChangedBy)
+	if err != nil {
+		http.Error(w, err.Error(), dedupStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pair)
+}
+
+// MergeDuplicate handles POST /api/duplicates/merge
+// Folds duplicate into the node kept: its links move over and it is
+// deleted, or for an immutable source node marked merged_into.
+func (s *Server) MergeDuplicate(w http.ResponseWriter, r *http.Request) {
+	if !s.dedupReady(w) {
+		return
+	}
+	req, ok := decodeDuplicatePair(w, r)
+	if !ok {
+		return
+	}
+	result, err := s.dedup.Merge(r.Context(), req.Duplicate, req.Into, req.ChangedBy)
+	if err != nil {
+		http.Error(w, err.Error(), dedupStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/embeddings"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// SetEmbeddings enables structural node embeddings
+func (s *Server) SetEmbeddings(store *embeddings.Store) {
+	s.embeddings = store
+}
+
+// embeddingsEnabled reports an error when embeddings are disabled
+func (s *Server) embeddingsEnabled(w http.ResponseWriter) bool {
+	if s.embeddings == nil {
+		notEnabled(w, "embeddings are disabled")
+		return false
+	}
+	return true
+}
+
+// embeddingStatus maps embedding errors to HTTP status codes
+func embeddingStatus(err error) int {
+	switch {
+	case errors.Is(err, embeddings.ErrNotTrained), errors.Is(err, embeddings.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, embeddings.ErrBusy):
+		return http.StatusConflict
+	case errors.Is(err, embeddings.ErrInvalidConfig):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// TrainEmbeddings handles POST /api/embeddings/train
+// Queues a training run over the current links and returns at once with
+// the job status; GET /api/embeddings/status follows it. An optional body
+// overrides training settings, such as {"dimensions": 128, "q": 0.5}.
+func (s *Server) TrainEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if !s.embeddingsEnabled(w) {
+		return
+	}
+	cfg := s.embeddings.Config()
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.embeddings.Request(cfg); err != nil {
+		http.Error(w, err.Error(), embeddingStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(s.embeddings.Status())
+}
+
+// EmbeddingStatus handles GET /api/embeddings/status
+func (s *Server) EmbeddingStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.embeddingsEnabled(w) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.embeddings.Status())
+}
+
+// ExportEmbeddings handles GET /api/embeddings/export
+// Downloads every vector, one JSON object per line, or with ?format=tsv
+// the node ID and values tab separated.
+func (s *Server) ExportEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if !s.embeddingsEnabled(w) {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	contentType := "application/x-ndjson"
+	switch format {
+	case "", embeddings.FormatJSONL:
+		format = embeddings.FormatJSONL
+	case embeddings.FormatTSV:
+		contentType = "text/tab-separated-values"
+	default:
+		http.Error(w, "unsupported format (use jsonl or tsv)", http.StatusBadRequest)
+		return
+	}
+	if status := s.embeddings.Status(); status.Nodes == 0 {
+		http.Error(w, embeddings.ErrNotTrained.Error(), http.StatusNotFound)
+		return
+	}
+
+	filename := "embeddings-" + time.Now().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	if _, err := s.embeddings.Export(w, format); err != nil {
+		log.Printf("Embedding export failed: %v", err)
+	}
+}
+
+// GetNodeEmbedding handles GET /api/nodes/{id}/embedding
+func (s *Server) GetNodeEmbedding(w http.ResponseWriter, r *http.Request) {
+	if !s.embeddingsEnabled(w) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	vector, err := s.embeddings.Vector(id)
+	if err != nil {
+		http.Error(w, err.Error(), embeddingStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "vector": vector})
+}
+
+// embeddingNeighbor is a similar node with its type and label
+type embeddingNeighbor struct {
+	ID    string  `json:"id"`
+	Type  string  `json:"type"`
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+}
+
+// SimilarByEmbedding handles GET /api/nodes/{id}/embedding/similar
+// Returns the ?limit= nodes (default 10) whose structural vectors are
+// closest to the node's, so nodes with little text still get neighbors.
+// Nodes deleted since training are left out.
+func (s *Server) SimilarByEmbedding(w http.ResponseWriter, r *http.Request) {
+	if !s.embeddingsEnabled(w) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 100 {
+			http.Error(w, "invalid limit parameter (1-100)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	neighbors, err := s.embeddings.Similar(id, 0)
+	if err != nil {
+		http.Error(w, err.Error(), embeddingStatus(err))
+		return
+	}
+	out := make([]embeddingNeighbor, 0, limit)
+	for _, n := range neighbors {
+		if len(out) == limit {
+			break
+		}
+		node, err := s.repo.GetNode(r.Context(), n.ID)
+		if err != nil {
+			continue
+		}
+		out = append(out, embeddingNeighbor{ID: n.ID, Type: node.Type, Label: graph.NodeLabel(n.ID, node.Meta), Score: n.Score})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"node_id": id, "similar": out})
+}
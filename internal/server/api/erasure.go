@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// EraseRequest is the request body for POST /api/admin/erase
+type EraseRequest struct {
+	PersonNodeID string `json:"person_node_id"`
+	Mode         string `json:"mode,omitempty"`      // delete (default) or tombstone
+	DryRun       bool   `json:"dry_run,omitempty"`   // Export only
+	MaxNodes     int    `json:"max_nodes,omitempty"` // Default graph.DefaultErasureMaxNodes
+	RequestRef   string `json:"request_ref,omitempty"`
+	RequestedBy  string `json:"requested_by,omitempty"`
+}
+
+// EraseResponse returns the export bundle with the erasure result
+type EraseResponse struct {
+	Result *graph.ErasureResult `json:"result"`
+	Bundle *graph.ErasureBundle `json:"bundle"`
+}
+
+// EraseSubject handles POST /api/admin/erase
+// Exports a person node, everything derived from it and everything that
+// mentions it, then hard-deletes (or tombstones) them and writes an audit node.
+func (s *Server) EraseSubject(w http.ResponseWriter, r *http.Request) {
+	var req EraseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.PersonNodeID == "" {
+		http.Error(w, "person_node_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.Mode != "" && req.Mode != graph.ErasureDelete && req.Mode != graph.ErasureTombstone {
+		http.Error(w, "invalid mode (use 'delete' or 'tombstone')", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.repo.GetNode(r.Context(), req.PersonNodeID); err != nil {
+		http.Error(w, "node not found: "+req.PersonNodeID, http.StatusNotFound)
+		return
+	}
+
+	bundle, result, err := graph.Erase(r.Context(), s.repo, graph.ErasureRequest{
+		SubjectID:   req.PersonNodeID,
+		Mode:        req.Mode,
+		DryRun:      req.DryRun,
+		MaxNodes:    req.MaxNodes,
+		RequestRef:  req.RequestRef,
+		RequestedBy: req.RequestedBy,
+	})
+	if err != nil && result == nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, graph.ErrErasureTooLarge) {
+			status = http.StatusUnprocessableEntity
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if err != nil {
+		// Nodes were erased but the audit record failed; still return the bundle
+		w.Header().Set("X-Memex-Audit-Error", err.Error())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EraseResponse{Result: result, Bundle: bundle})
+}
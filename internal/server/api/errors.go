@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Error codes clients can branch on. Each status has a default code; a
+// handler picks a finer one with writeError or the ErrorCodeHeader.
+const (
+	CodeValidation         = "validation_failed"      // 400, 422: the request is malformed or breaks a rule
+	CodeUnsupportedVersion = "unsupported_version"    // 400: the API version asked for is not served
+	CodeUnauthorized       = "unauthorized"           // 401: no or bad credentials
+	CodeForbidden          = "forbidden"              // 403: read-only mode, a token's scope or a protected node
+	CodeNotFound           = "not_found"              // 404, 410
+	CodeMethodNotAllowed   = "method_not_allowed"     // 405
+	CodeNotAcceptable      = "not_acceptable"         // 406: no representation the client accepts
+	CodeConflict           = "conflict"               // 409: the write clashes with the graph's current state
+	CodePrecondition       = "precondition_failed"    // 412, 428: an If-Match or version check failed
+	CodeTooLarge           = "too_large"              // 413
+	CodeUnsupportedMedia   = "unsupported_media_type" // 415
+	CodeRateLimited        = "rate_limited"           // 429
+	CodeInternal           = "internal"               // 500
+	CodeNotImplemented     = "not_implemented"        // 501
+	CodeUpstreamFailed     = "upstream_failed"        // 502: a peer or external service failed
+	CodeBackendUnavailable = "backend_unavailable"    // 503: the store or an index cannot serve the request now
+	CodeNotEnabled         = "not_enabled"            // 503: the feature is switched off or not configured
+	CodeTimeout            = "timeout"                // 408, 504
+	CodeQuotaExceeded      = "quota_exceeded"         // 507
+)
+
+// ErrorCodeHeader carries an error's code on the response, so it is also
+// available without reading the body. Setting it before http.Error
+// overrides the status's default code.
+const ErrorCodeHeader = "X-Memex-Error-Code"
+
+// statusCodes are the default codes of error statuses
+var statusCodes = map[int]string{
+	http.StatusBadRequest:                   CodeValidation,
+	http.StatusUnauthorized:                 CodeUnauthorized,
+	http.StatusForbidden:                    CodeForbidden,
+	http.StatusNotFound:                     CodeNotFound,
+	http.StatusMethodNotAllowed:             CodeMethodNotAllowed,
+	http.StatusNotAcceptable:                CodeNotAcceptable,
+	http.StatusRequestTimeout:               CodeTimeout,
+	http.StatusConflict:                     CodeConflict,
+	http.StatusGone:                         CodeNotFound,
+	http.StatusPreconditionFailed:           CodePrecondition,
+	http.StatusRequestEntityTooLarge:        CodeTooLarge,
+	http.StatusUnsupportedMediaType:         CodeUnsupportedMedia,
+	http.StatusUnprocessableEntity:          CodeValidation,
+	http.StatusPreconditionRequired:         CodePrecondition,
+	http.StatusTooManyRequests:              CodeRateLimited,
+	http.StatusInternalServerError:          CodeInternal,
+	http.StatusNotImplemented:               CodeNotImplemented,
+	http.StatusBadGateway:                   CodeUpstreamFailed,
+	http.StatusServiceUnavailable:           CodeBackendUnavailable,
+	http.StatusGatewayTimeout:               CodeTimeout,
+	http.StatusInsufficientStorage:          CodeQuotaExceeded,
+	http.StatusRequestHeaderFieldsTooLarge:  CodeTooLarge,
+	http.StatusRequestedRangeNotSatisfiable: CodeValidation,
+}
+
+// retryableCodes are errors the same request may get past later
+var retryableCodes = map[string]bool{
+	CodeRateLimited:        true,
+	CodeUpstreamFailed:     true,
+	CodeBackendUnavailable: true,
+	CodeTimeout:            true,
+}
+
+// ErrorBody describes a failed request
+type ErrorBody struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Retryable bool                   `json:"retryable"`            // The same request may succeed later
+	RequestID string                 `json:"request_id,omitempty"` // Matches the server's log line
+}
+
+// ErrorResponse is the body of every API error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// codeForStatus returns a status's default error code
+func codeForStatus(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeValidation
+}
+
+// writeError writes an error response with a specific code and details
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]interface{}) {
+	if code == "" {
+		code = codeForStatus(status)
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set(ErrorCodeHeader, code)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		Retryable: retryableCodes[code],
+		RequestID: middleware.GetReqID(r.Context()),
+	}})
+}
+
+// notEnabled reports a feature that is switched off or not configured
+func notEnabled(w http.ResponseWriter, message string) {
+	w.Header().Set(ErrorCodeHeader, CodeNotEnabled)
+	http.Error(w, message, http.StatusServiceUnavailable)
+}
+
+// maxErrorMessage caps the plain-text error a handler may write
+const maxErrorMessage = 64 << 10
+
+// Errors is middleware that turns the plain-text errors handlers write
+// with http.Error into ErrorResponse bodies, so every API error has the
+// same shape. Other responses, errors already in JSON among them, pass
+// through untouched.
+func Errors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ew := &errorWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, r)
+		if ew.buf == nil {
+			return
+		}
+		message := strings.TrimSpace(ew.buf.String())
+		if message == "" {
+			message = http.StatusText(ew.status)
+		}
+		writeError(w, r, ew.status, w.Header().Get(ErrorCodeHeader), message, nil)
+	})
+}
+
+// errorWriter holds back a plain-text error body to rewrite it
+type errorWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	status      int
+	buf         *bytes.Buffer // Set while holding back an error
+}
+
+func (ew *errorWriter) WriteHeader(code int) {
+	if ew.wroteHeader {
+		return
+	}
+	ew.wroteHeader = true
+	if code >= http.StatusBadRequest && strings.HasPrefix(ew.Header().Get("Content-Type"), "text/plain") {
+		ew.status, ew.buf = code, &bytes.Buffer{}
+		return
+	}
+	ew.ResponseWriter.WriteHeader(code)
+}
+
+func (ew *errorWriter) Write(b []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if ew.buf != nil {
+		if room := maxErrorMessage - ew.buf.Len(); room > 0 {
+			ew.buf.Write(b[:min(len(b), room)])
+		}
+		return len(b), nil
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+func (ew *errorWriter) Flush() {
+	if ew.buf != nil {
+		return
+	}
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (ew *errorWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
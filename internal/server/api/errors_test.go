@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		handler   http.HandlerFunc
+		status    int
+		code      string
+		message   string
+		retryable bool
+	}{
+		"not found": {
+			handler: func(w http.ResponseWriter, r *http.Request) { http.Error(w, "Node not found", http.StatusNotFound) },
+			status:  http.StatusNotFound, code: CodeNotFound, message: "Node not found",
+		},
+		"quota": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				err := fmt.Errorf("creating node: %w", graph.ErrQuotaExceeded)
+				http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			},
+			status: http.StatusInsufficientStorage, code: CodeQuotaExceeded, message: "creating node: quota exceeded",
+		},
+		"backend unavailable": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				err := fmt.Errorf("writing: %w", graph.ErrWriteBehindClosed)
+				http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			},
+			status: http.StatusServiceUnavailable, code: CodeBackendUnavailable, message: "writing: repository closed", retryable: true,
+		},
+		"not enabled": {
+			handler: func(w http.ResponseWriter, r *http.Request) { notEnabled(w, "connectors are disabled") },
+			status:  http.StatusServiceUnavailable, code: CodeNotEnabled, message: "connectors are disabled",
+		},
+		"empty message": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusConflict)
+			},
+			status: http.StatusConflict, code: CodeConflict, message: "Conflict",
+		},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/nodes/x", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-1"))
+		Errors(tc.handler).ServeHTTP(rec, req)
+
+		var resp ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: body %q: %v", name, rec.Body.String(), err)
+		}
+		e := resp.Error
+		if rec.Code != tc.status || e.Code != tc.code || e.Message != tc.message || e.Retryable != tc.retryable || e.RequestID != "req-1" {
+			t.Errorf("%s: got %d %+v", name, rec.Code, e)
+		}
+		if rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get(ErrorCodeHeader) != tc.code {
+			t.Errorf("%s: headers %v", name, rec.Header())
+		}
+	}
+
+	// Successes and errors already in JSON pass through untouched
+	for name, handler := range map[string]http.HandlerFunc{
+		"success": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hello"))
+		},
+		"json error": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": "warming"}`))
+		},
+	} {
+		direct, wrapped := httptest.NewRecorder(), httptest.NewRecorder()
+		handler(direct, httptest.NewRequest("GET", "/", nil))
+		Errors(handler).ServeHTTP(wrapped, httptest.NewRequest("GET", "/", nil))
+		if wrapped.Code != direct.Code || wrapped.Body.String() != direct.Body.String() {
+			t.Errorf("%s: got %d %q, want %d %q", name, wrapped.Code, wrapped.Body, direct.Code, direct.Body)
+		}
+	}
+}
+
+func TestWriteErrorDetails(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeError(rec, httptest.NewRequest("GET", "/", nil), http.StatusBadRequest, CodeUnsupportedVersion, "unsupported API version 2", map[string]interface{}{"supported": []int{1}})
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Code != CodeUnsupportedVersion || fmt.Sprint(resp.Error.Details["supported"]) != "[1]" || resp.Error.Retryable {
+		t.Errorf("got %+v", resp.Error)
+	}
+}
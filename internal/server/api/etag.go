@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// checkETag sets the ETag header and reports whether the client's cached copy
+// is still current. When it returns true a 304 has been written and the
+// handler should return without a body. Responses pinned to a snapshot
+// get no ETag, since they do not describe the current graph.
+func checkETag(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if etag == "" || graph.SnapshotFrom(r.Context()) != nil {
+		return false
+	}
+	etag = `"` + etag + `"`
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// etagMatches reports whether an If-None-Match header matches etag,
+// using weak comparison as RFC 9110 specifies for If-None-Match
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// graphETag returns the ETag for views derived from the whole graph, or "" if
+// no revision counter is configured
+func (s *Server) graphETag() string {
+	if s.revision == nil {
+		return ""
+	}
+	return "g" + s.revision.Current()
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// streamKeepalive is how often an idle event stream sends a comment line
+const streamKeepalive = 30 * time.Second
+
+// StreamEvents handles GET /api/events/stream?type=&node_type=&link_type=&expr=
+// Streams graph events as Server-Sent Events. type, node_type and link_type
+// may be repeated or comma-separated; expr is a subscription expression.
+func (s *Server) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if s.subMgr == nil {
+		notEnabled(w, "subscription manager not initialized")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	pattern := subscriptions.SubscriptionPattern{
+		EventTypes: listParam(q["type"]),
+		NodeTypes:  listParam(q["node_type"]),
+		LinkTypes:  listParam(q["link_type"]),
+		Expr:       q.Get("expr"),
+	}
+	events, cancel, err := s.subMgr.Stream(pattern)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+
+	// The server's write timeout would otherwise cut the stream off
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// listParam flattens repeated and comma-separated query values
+func listParam(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/evidence"
+)
+
+// maxEvidenceContext caps the characters of context returned around a passage
+const maxEvidenceContext = 2000
+
+// AddEvidenceRequest is the body of POST /api/nodes/{id}/evidence
+type AddEvidenceRequest struct {
+	Source     string                 `json:"source"`
+	Spans      []evidence.SpanRequest `json:"spans"`
+	Extractor  string                 `json:"extractor,omitempty"`
+	Confidence *float64               `json:"confidence,omitempty"`
+}
+
+// evidenceErrorStatus maps evidence errors to HTTP status codes
+func evidenceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, evidence.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, evidence.ErrInvalidSpan):
+		return http.StatusBadRequest
+	}
+	return writeErrorStatus(err, http.StatusInternalServerError)
+}
+
+// AddEvidence handles POST /api/nodes/{id}/evidence
+// Records the passages of a source that support the node, on its
+// SUPPORTED_BY link to the source. Each span gives character offsets
+// ({"start", "end"}) or a quote to find in the source, and an optional
+// chunk number.
+func (s *Server) AddEvidence(w http.ResponseWriter, r *http.Request) {
+	var req AddEvidenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Source == "" {
+		http.Error(w, "source is required", http.StatusBadRequest)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	spans, err := evidence.Add(r.Context(), s.repo, id, req.Source, req.Spans, req.Extractor, req.Confidence)
+	if err != nil {
+		http.Error(w, err.Error(), evidenceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"node_id": id,
+		"source":  req.Source,
+		"spans":   spans,
+	})
+}
+
+// GetEvidence handles GET /api/nodes/{id}/evidence
+// Returns the source passages supporting the node, with ?context=
+// characters of surrounding text (default 80). Passages whose text moved
+// in the source are returned at the new place, and ones whose text is gone
+// are marked orphaned.
+func (s *Server) GetEvidence(w http.ResponseWriter, r *http.Request) {
+	contextChars := evidence.DefaultContext
+	if c := r.URL.Query().Get("context"); c != "" {
+		n, err := strconv.Atoi(c)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid context parameter", http.StatusBadRequest)
+			return
+		}
+		contextChars = min(n, maxEvidenceContext)
+	}
+
+	id := chi.URLParam(r, "id")
+	passages, err := evidence.Evidence(r.Context(), s.repo, id, contextChars)
+	if err != nil {
+		http.Error(w, err.Error(), evidenceErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"node_id":  id,
+		"passages": passages,
+		"count":    len(passages),
+	})
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/experiments"
+)
+
+// Headers that steer experiment assignment
+const (
+	variantHeader = "X-Memex-Variant" // Forces a variant; echoed on responses
+	subjectHeader = "X-Memex-Subject" // Stable user or session ID to split traffic by
+)
+
+// SetExperiments enables A/B tests of search ranking
+func (s *Server) SetExperiments(m *experiments.Manager) {
+	s.experiments = m
+}
+
+// searchStrategy picks the ranking for a search request: the variant of
+// the running experiment this caller is assigned to, or the default
+func (s *Server) searchStrategy(r *http.Request) (experiments.Strategy, *experiments.Assignment) {
+	if s.experiments == nil {
+		return experiments.Default(), nil
+	}
+	unit := r.Header.Get(subjectHeader)
+	if unit == "" {
+		unit = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			unit = host
+		}
+	}
+	return s.experiments.Assign(unit, r.Header.Get(variantHeader))
+}
+
+// experimentStatus maps experiment errors to HTTP status codes
+func experimentStatus(err error) int {
+	switch {
+	case errors.Is(err, experiments.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, experiments.ErrExists), errors.Is(err, experiments.ErrRunning):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// experimentsEnabled reports an error when experiments are disabled
+func (s *Server) experimentsEnabled(w http.ResponseWriter) bool {
+	if s.experiments == nil {
+		notEnabled(w, "ranking experiments are disabled")
+		return false
+	}
+	return true
+}
+
+// ListExperiments handles GET /api/admin/experiments
+// Lists experiments with per-variant precision, MRR and lift over the
+// control, and the ranking strategies available.
+func (s *Server) ListExperiments(w http.ResponseWriter, r *http.Request) {
+	if !s.experimentsEnabled(w) {
+		return
+	}
+	list := s.experiments.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"experiments": list,
+		"count":       len(list),
+		"strategies":  s.experiments.Strategies(),
+	})
+}
+
+// CreateExperiment handles POST /api/admin/experiments
+// Starts splitting search traffic between the variants; only one
+// experiment runs at a time.
+func (s *Server) CreateExperiment(w http.ResponseWriter, r *http.Request) {
+	if !s.experimentsEnabled(w) {
+		return
+	}
+	var e experiments.Experiment
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.experiments.Create(&e); err != nil {
+		http.Error(w, err.Error(), experimentStatus(err))
+		return
+	}
+	report, err := s.experiments.Get(e.ID)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetExperiment handles GET /api/admin/experiments/{id}
+func (s *Server) GetExperiment(w http.ResponseWriter, r *http.Request) {
+	if !s.experimentsEnabled(w) {
+		return
+	}
+	report, err := s.experiments.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), experimentStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// StopExperiment handles POST /api/admin/experiments/{id}/stop
+// Search goes back to the default ranking; the results are kept.
+func (s *Server) StopExperiment(w http.ResponseWriter, r *http.Request) {
+	if !s.experimentsEnabled(w) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if err := s.experiments.Stop(id); err != nil {
+		http.Error(w, err.Error(), experimentStatus(err))
+		return
+	}
+	report, err := s.experiments.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), experimentStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// DeleteExperiment handles DELETE /api/admin/experiments/{id}
+func (s *Server) DeleteExperiment(w http.ResponseWriter, r *http.Request) {
+	if !s.experimentsEnabled(w) {
+		return
+	}
+	if err := s.experiments.Delete(chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), experimentStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddRankingStrategy handles POST /api/admin/experiments/strategies
+// Registers or replaces a ranking strategy for use in experiments.
+func (s *Server) AddRankingStrategy(w http.ResponseWriter, r *http.Request) {
+	if !s.experimentsEnabled(w) {
+		return
+	}
+	var strategy experiments.Strategy
+	if err := json.NewDecoder(r.Body).Decode(&strategy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.experiments.AddStrategy(strategy); err != nil {
+		http.Error(w, err.Error(), experimentStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(strategy)
+}
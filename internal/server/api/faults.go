@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/faults"
+)
+
+// SetFaults enables the fault injection endpoints. Only builds with
+// -tags faults set an injector.
+func (s *Server) SetFaults(in *faults.Injector) {
+	s.faults = in
+}
+
+// FaultsResponse is the response for the /api/admin/faults endpoints
+type FaultsResponse struct {
+	Config faults.Config `json:"config"`
+	Stats  faults.Stats  `json:"stats"`
+}
+
+// writeFaults writes the injector's config and counts
+func (s *Server) writeFaults(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FaultsResponse{Config: s.faults.Config(), Stats: s.faults.Stats()})
+}
+
+// faultsDisabled answers 503 unless the server was built with fault injection
+func (s *Server) faultsDisabled(w http.ResponseWriter) bool {
+	if s.faults == nil {
+		notEnabled(w, "fault injection is not available (build memex-server with -tags faults)")
+		return true
+	}
+	return false
+}
+
+// GetFaults handles GET /api/admin/faults
+// Returns the faults being injected and how many so far.
+func (s *Server) GetFaults(w http.ResponseWriter, r *http.Request) {
+	if s.faultsDisabled(w) {
+		return
+	}
+	s.writeFaults(w)
+}
+
+// ConfigureFaults handles PUT /api/admin/faults
+// Replaces the faults injected into repository calls and resets the counts.
+func (s *Server) ConfigureFaults(w http.ResponseWriter, r *http.Request) {
+	if s.faultsDisabled(w) {
+		return
+	}
+	var cfg faults.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := s.faults.Configure(cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Fault injection configured: %+v", cfg)
+	s.writeFaults(w)
+}
+
+// ClearFaults handles DELETE /api/admin/faults
+// Stops injecting faults.
+func (s *Server) ClearFaults(w http.ResponseWriter, r *http.Request) {
+	if s.faultsDisabled(w) {
+		return
+	}
+	s.faults.Clear()
+	log.Println("Fault injection cleared")
+	s.writeFaults(w)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/faults"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestFaults(t *testing.T) {
+	w := httptest.NewRecorder()
+	New(graph.NewMemory(), nil).GetFaults(w, httptest.NewRequest("GET", "/api/admin/faults", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without an injector: %d", w.Code)
+	}
+
+	in := faults.New()
+	s := New(faults.Wrap(graph.NewMemory(), in), nil)
+	s.SetFaults(in)
+
+	w = httptest.NewRecorder()
+	s.ConfigureFaults(w, httptest.NewRequest("PUT", "/api/admin/faults", strings.NewReader(`{"error_rate": 2}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad rate: %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.ConfigureFaults(w, httptest.NewRequest("PUT", "/api/admin/faults", strings.NewReader(`{"error_rate": 1, "operations": ["CreateNode"]}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("configure: %d %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	s.Ingest(w, httptest.NewRequest("POST", "/api/ingest", strings.NewReader(`{"content": "hello"}`)))
+	if w.Code < 500 {
+		t.Errorf("ingest with failing writes: %d %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	s.ClearFaults(w, httptest.NewRequest("DELETE", "/api/admin/faults", nil))
+	var resp FaultsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Config.ErrorRate != 0 {
+		t.Errorf("after clear: %+v", resp)
+	}
+	w = httptest.NewRecorder()
+	s.Ingest(w, httptest.NewRequest("POST", "/api/ingest", strings.NewReader(`{"content": "hello"}`)))
+	if w.Code >= 300 {
+		t.Errorf("ingest after clear: %d %s", w.Code, w.Body)
+	}
+}
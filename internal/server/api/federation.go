@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/backup"
+	"github.com/systemshift/memex/internal/server/federation"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// ImportFederated handles POST /api/import/federated?source=<name>
+// Imports a bundle exported by another memex server, remapping IDs taken
+// here. Repeated syncs with the same source update the nodes they created.
+func (s *Server) ImportFederated(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("source")
+	if err := federation.ValidateSource(source); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var bundle backup.Bundle
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBundleUpload)).Decode(&bundle); err != nil {
+		http.Error(w, "invalid bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if bundle.Manifest.Format != backup.Format {
+		http.Error(w, "not a memex bundle (export one with GET /api/export/bundle)", http.StatusUnprocessableEntity)
+		return
+	}
+
+	start := time.Now()
+	result, err := federation.Import(r.Context(), s.repo, &bundle, federation.Options{
+		Source:    source,
+		ChangedBy: r.URL.Query().Get("changed_by"),
+	})
+	if err != nil {
+		status := writeErrorStatus(err, http.StatusInternalServerError)
+		if errors.Is(err, federation.ErrInvalid) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"result":  result,
+		"took_ms": time.Since(start).Milliseconds(),
+	})
+}
+
+// GetFederatedMappings handles GET /api/import/federated/{source}
+// Returns the source's mapping table: its node IDs -> the IDs used here.
+func (s *Server) GetFederatedMappings(w http.ResponseWriter, r *http.Request) {
+	source := chi.URLParam(r, "source")
+	if err := federation.ValidateSource(source); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mapping, err := federation.Mappings(r.Context(), s.repo, source)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"source":   source,
+		"count":    len(mapping),
+		"mappings": mapping,
+	})
+}
+
+// SetPeers sets the servers searched alongside this one by federated queries
+func (s *Server) SetPeers(searcher *federation.Searcher) {
+	s.peers = searcher
+}
+
+// federatedSearch answers GET /api/query/search?federated=true: this
+// server's results and every peer's, interleaved by rank, deduplicated and
+// labeled with their origin. Peers that fail are reported, not fatal.
+func (s *Server) federatedSearch(w http.ResponseWriter, r *http.Request, q string, limit int, layers graph.LayerFilter) {
+	if s.peers == nil || len(s.peers.Peers) == 0 {
+		notEnabled(w, "no peers are configured (set MEMEX_PEERS)")
+		return
+	}
+
+	start := time.Now()
+	local, err := s.repo.SearchNodes(readContext(r), q, limit, 0)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	localTook := time.Since(start)
+	results, peerResults := s.peers.Search(r.Context(), q, limit)
+	results[federation.LocalOrigin] = layers.Nodes(local)
+
+	origins := []string{federation.LocalOrigin}
+	baseURLs := map[string]string{federation.LocalOrigin: s.BaseURL(r)}
+	for _, peer := range s.peers.Peers {
+		origins = append(origins, peer.Name)
+		baseURLs[peer.Name] = peer.URL
+	}
+	hits := federation.Merge(origins, results, limit)
+	for i := range hits {
+		hits[i].URL = baseURLs[hits[i].Origin] + "/n/" + url.PathEscape(hits[i].Node.ID)
+	}
+	servers := append([]federation.PeerResult{{
+		Origin: federation.LocalOrigin,
+		Count:  len(results[federation.LocalOrigin]),
+		TookMS: localTook.Milliseconds(),
+	}}, peerResults...)
+
+	localHits := []*core.Node{}
+	for _, hit := range hits {
+		if hit.Origin == federation.LocalOrigin {
+			localHits = append(localHits, hit.Node)
+		}
+	}
+	s.recordSession(r, q, nodeIDs(localHits)...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hits":    hits,
+		"count":   len(hits),
+		"query":   q,
+		"servers": servers,
+	})
+}
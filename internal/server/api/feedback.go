@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/experiments"
+	"github.com/systemshift/memex/internal/server/feedback"
+)
+
+// SetFeedback enables retrieval quality tracking
+func (s *Server) SetFeedback(t *feedback.Tracker) {
+	s.feedback = t
+}
+
+// FeedbackRequest is the request body for POST /api/feedback
+type FeedbackRequest struct {
+	RetrievalID string   `json:"retrieval_id"`
+	NodeID      string   `json:"node_id,omitempty"`
+	NodeIDs     []string `json:"node_ids,omitempty"`
+	Action      string   `json:"action"` // click or cite
+}
+
+// recordRetrieval notes the results of a retrieval, and the experiment
+// variant that ranked them if any, and returns its ID, or "" when
+// tracking is off
+func (s *Server) recordRetrieval(endpoint string, assignment *experiments.Assignment, hits []feedback.Hit) string {
+	if s.feedback == nil {
+		return ""
+	}
+	if assignment != nil {
+		return s.feedback.RecordVariant(endpoint, assignment.Key, hits)
+	}
+	return s.feedback.Record(endpoint, hits)
+}
+
+// recencyDays is a node's age since its last change, in days
+func recencyDays(node *core.Node, now time.Time) float64 {
+	return math.Round(now.Sub(node.Modified).Hours()/24*10) / 10
+}
+
+// searchHits describes search results with their ranking signals; trust
+// is nil when results were not ranked by trust
+func searchHits(nodes []*core.Node, trust map[string]float64) []feedback.Hit {
+	now := time.Now()
+	hits := make([]feedback.Hit, len(nodes))
+	for i, n := range nodes {
+		signals := map[string]float64{"recency_days": recencyDays(n, now)}
+		if trust != nil {
+			signals["trust"] = trust[n.ID]
+		}
+		hits[i] = feedback.Hit{NodeID: n.ID, Signals: signals}
+	}
+	return hits
+}
+
+// Feedback handles POST /api/feedback
+// Reports that results of a search, related-nodes or autocomplete call
+// (identified by the retrieval_id in its response) were clicked or cited.
+func (s *Server) Feedback(w http.ResponseWriter, r *http.Request) {
+	if s.feedback == nil {
+		notEnabled(w, "retrieval feedback is disabled")
+		return
+	}
+	var req FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids := req.NodeIDs
+	if req.NodeID != "" {
+		ids = append(ids, req.NodeID)
+	}
+	if req.RetrievalID == "" || len(ids) == 0 {
+		http.Error(w, "retrieval_id and node_id or node_ids are required", http.StatusBadRequest)
+		return
+	}
+	for _, id := range ids {
+		if err := s.feedback.Feedback(req.RetrievalID, id, req.Action); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, feedback.ErrUnknownRetrieval) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"retrieval_id": req.RetrievalID,
+		"action":       req.Action,
+		"recorded":     len(ids),
+	})
+}
+
+// FeedbackStats handles GET /api/feedback/stats
+// Reports precision per endpoint, rank and node, and how each ranking
+// signal differs between used and ignored results. ?min_impressions=
+// hides rarely returned nodes; ?limit= caps the node list (default 100).
+func (s *Server) FeedbackStats(w http.ResponseWriter, r *http.Request) {
+	if s.feedback == nil {
+		notEnabled(w, "retrieval feedback is disabled")
+		return
+	}
+	query := r.URL.Query()
+	minImpressions, limit := 0, 100
+	if v := query.Get("min_impressions"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid min_impressions parameter", http.StatusBadRequest)
+			return
+		}
+		minImpressions = n
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.feedback.Stats(minImpressions, limit))
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/finalizers"
+)
+
+// SetFinalizers enables cleanup webhooks for deleted nodes
+func (s *Server) SetFinalizers(m *finalizers.Manager) {
+	s.finalizers = m
+}
+
+// FinalizerView is a finalizer as the admin endpoints return it: the
+// secret is never sent back
+type FinalizerView struct {
+	*finalizers.Finalizer
+	Signed bool `json:"signed"` // Webhook payloads carry an HMAC signature
+}
+
+// finalizerView hides a finalizer's secret
+func finalizerView(f *finalizers.Finalizer) FinalizerView {
+	hidden := *f
+	hidden.Secret = ""
+	return FinalizerView{Finalizer: &hidden, Signed: f.Secret != ""}
+}
+
+// finalizersEnabled reports an error when finalizers are disabled
+func (s *Server) finalizersEnabled(w http.ResponseWriter) bool {
+	if s.finalizers == nil {
+		notEnabled(w, "finalizers are disabled")
+		return false
+	}
+	return true
+}
+
+// finalizerStatus maps finalizer errors to HTTP statuses
+func finalizerStatus(err error) int {
+	switch {
+	case errors.Is(err, finalizers.ErrNotFound), errors.Is(err, finalizers.ErrFailureNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// CreateFinalizer handles POST /api/admin/finalizers
+// Registers a webhook called when nodes of a type are deleted.
+func (s *Server) CreateFinalizer(w http.ResponseWriter, r *http.Request) {
+	if !s.finalizersEnabled(w) {
+		return
+	}
+	var f finalizers.Finalizer
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.ID != "" {
+		if _, err := s.finalizers.Get(f.ID); err == nil {
+			http.Error(w, "finalizer already exists: "+f.ID, http.StatusConflict)
+			return
+		}
+	}
+	if err := s.finalizers.Register(r.Context(), &f); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(finalizerView(&f))
+}
+
+// ListFinalizers handles GET /api/admin/finalizers?node_type=
+func (s *Server) ListFinalizers(w http.ResponseWriter, r *http.Request) {
+	if !s.finalizersEnabled(w) {
+		return
+	}
+	list := s.finalizers.List(r.URL.Query().Get("node_type"))
+	views := make([]FinalizerView, 0, len(list))
+	for _, f := range list {
+		views = append(views, finalizerView(f))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"finalizers": views,
+		"count":      len(views),
+	})
+}
+
+// GetFinalizer handles GET /api/admin/finalizers/{id}
+func (s *Server) GetFinalizer(w http.ResponseWriter, r *http.Request) {
+	if !s.finalizersEnabled(w) {
+		return
+	}
+	f, err := s.finalizers.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), finalizerStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(finalizerView(f))
+}
+
+// DeleteFinalizer handles DELETE /api/admin/finalizers/{id}
+// Deletes after this no longer call the webhook; calls in flight finish.
+func (s *Server) DeleteFinalizer(w http.ResponseWriter, r *http.Request) {
+	if !s.finalizersEnabled(w) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if err := s.finalizers.Remove(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, finalizerStatus(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": id,
+	})
+}
+
+// ListFinalizerFailures handles GET /api/admin/finalizers/failures?finalizer=
+// Lists finalizer calls that failed after all retries; the external data
+// of those nodes may still need cleaning up.
+func (s *Server) ListFinalizerFailures(w http.ResponseWriter, r *http.Request) {
+	if !s.finalizersEnabled(w) {
+		return
+	}
+	failures := s.finalizers.Failures(r.URL.Query().Get("finalizer"))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"failures": failures,
+		"count":    len(failures),
+	})
+}
+
+// RetryFinalizerFailure handles POST /api/admin/finalizers/failures/{id}/retry
+// Re-sends a failed call; it is removed on success and kept (with the new
+// error) on failure.
+func (s *Server) RetryFinalizerFailure(w http.ResponseWriter, r *http.Request) {
+	if !s.finalizersEnabled(w) {
+		return
+	}
+	failure, err := s.finalizers.RetryFailure(chi.URLParam(r, "id"))
+	if errors.Is(err, finalizers.ErrFailureNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"failure":   failure,
+		"delivered": err == nil,
+	})
+}
+
+// DeleteFinalizerFailure handles DELETE /api/admin/finalizers/failures/{id}
+func (s *Server) DeleteFinalizerFailure(w http.ResponseWriter, r *http.Request) {
+	if !s.finalizersEnabled(w) {
+		return
+	}
+	if err := s.finalizers.DeleteFailure(chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), finalizerStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/finalizers"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestFinalizers(t *testing.T) {
+	s := New(graph.NewMemory(), nil)
+	r := chi.NewRouter()
+	r.Post("/api/admin/finalizers", s.CreateFinalizer)
+	r.Get("/api/admin/finalizers", s.ListFinalizers)
+	r.Get("/api/admin/finalizers/failures", s.ListFinalizerFailures)
+	r.Get("/api/admin/finalizers/{id}", s.GetFinalizer)
+	r.Delete("/api/admin/finalizers/{id}", s.DeleteFinalizer)
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do("GET", "/api/admin/finalizers", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET with finalizers disabled: %d, want 503", w.Code)
+	}
+	s.SetFinalizers(finalizers.NewManager(graph.NewMemory(), time.Second))
+
+	body := `{"id": "blobs", "node_type": "Blob", "url": "https://cleanup.example.com/blobs", "secret": "s3cret"}`
+	w := do("POST", "/api/admin/finalizers", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST: %d %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "s3cret") {
+		t.Error("response carries the secret")
+	}
+	var view FinalizerView
+	json.NewDecoder(w.Body).Decode(&view)
+	if !view.Signed || view.NodeType != "Blob" {
+		t.Errorf("created = %+v", view)
+	}
+
+	for _, step := range []struct {
+		method, url, body string
+		want              int
+	}{
+		{"POST", "/api/admin/finalizers", body, http.StatusConflict},
+		{"POST", "/api/admin/finalizers", `{"node_type": "Blob", "url": "not a url"}`, http.StatusBadRequest},
+		{"GET", "/api/admin/finalizers?node_type=Blob", "", http.StatusOK},
+		{"GET", "/api/admin/finalizers/failures", "", http.StatusOK},
+		{"GET", "/api/admin/finalizers/blobs", "", http.StatusOK},
+		{"DELETE", "/api/admin/finalizers/blobs", "", http.StatusOK},
+		{"GET", "/api/admin/finalizers/blobs", "", http.StatusNotFound},
+		{"DELETE", "/api/admin/finalizers/blobs", "", http.StatusNotFound},
+	} {
+		if w := do(step.method, step.url, step.body); w.Code != step.want {
+			t.Errorf("%s %s: %d %s, want %d", step.method, step.url, w.Code, w.Body, step.want)
+		}
+	}
+}
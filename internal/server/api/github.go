@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/github"
+)
+
+// maxGitHubDelivery caps a GitHub webhook body (GitHub sends at most 25MB)
+const maxGitHubDelivery = 25 << 20
+
+// GitHubImportRequest is the request body for POST /api/import/github
+type GitHubImportRequest struct {
+	Repo  string `json:"repo"`            // owner/name
+	Full  bool   `json:"full,omitempty"`  // Refetch everything, not just what changed since the last sync
+	Token string `json:"token,omitempty"` // Overrides the configured token
+}
+
+// SetGitHub configures GitHub API access and, with a secret, the webhook
+// endpoint
+func (s *Server) SetGitHub(client *github.Client, webhookSecret string) {
+	s.githubClient = client
+	s.githubWebhookSecret = webhookSecret
+}
+
+// ImportGitHub handles POST /api/import/github
+// Syncs a repository's issues, pull requests and reviews now.
+func (s *Server) ImportGitHub(w http.ResponseWriter, r *http.Request) {
+	var req GitHubImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if _, err := github.ParseRepo(req.Repo); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	client := &github.Client{}
+	if s.githubClient != nil {
+		*client = *s.githubClient
+	}
+	if req.Token != "" {
+		client.Token = req.Token
+	}
+
+	result, err := github.Sync(r.Context(), s.repo, client, req.Repo, req.Full)
+	if err != nil {
+		status := http.StatusBadGateway
+		if result != nil {
+			// Part of the repository was applied before the failure
+			status = writeErrorStatus(err, http.StatusInternalServerError)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListGitHubSyncs handles GET /api/github/sync
+// Returns each synced repository's cursor.
+func (s *Server) ListGitHubSyncs(w http.ResponseWriter, r *http.Request) {
+	states, err := github.ListStates(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"repos": states,
+		"count": len(states),
+	})
+}
+
+// GitHubWebhook handles POST /api/github/webhook
+// Applies issues, pull_request and pull_request_review deliveries signed
+// with the configured secret. Other events are acknowledged and ignored.
+func (s *Server) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.githubWebhookSecret == "" {
+		notEnabled(w, "GitHub webhook is not configured")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGitHubDelivery))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !github.VerifySignature(s.githubWebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	result, err := github.HandleEvent(r.Context(), s.repo, s.githubClient, event, body)
+	if errors.Is(err, github.ErrIgnoredEvent) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ignored": event,
+		})
+		return
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if result != nil {
+			status = writeErrorStatus(err, http.StatusInternalServerError)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxViewLimit caps the nodes a graph view loads
+const maxViewLimit = 20000
+
+// GraphView handles GET /api/graph/view
+// Returns a display-oriented graph for visualization clients.
+// ?type= (repeatable) limits node types and ?limit= caps loaded nodes (default 2000);
+// ?focus=&depth= centers the view on a node's neighborhood instead (e.g. a search hit);
+// ?hide= (repeatable or comma-separated) drops edge types and ?layer= keeps
+// node and edge layers; ?cluster=community_id
+// collapses nodes by that meta key and ?expand= keeps chosen clusters open.
+// Styles defined under /api/ontology/types are returned for the node types
+// shown, with each styled node's size_by metric as its value.
+// Full node details are fetched lazily from GET /api/nodes/{id}.
+func (s *Server) GraphView(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 2000
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if limit > maxViewLimit {
+		limit = maxViewLimit
+	}
+
+	depth := 2
+	if d := query.Get("depth"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid depth parameter", http.StatusBadRequest)
+			return
+		}
+		depth = n
+	}
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if checkETag(w, r, s.graphETag()) {
+		return
+	}
+
+	var sub *graph.Subgraph
+	if focus := query.Get("focus"); focus != "" {
+		sub, err = s.repo.GetSubgraph(r.Context(), focus, depth, nil)
+	} else {
+		sub, err = s.repo.GetGraphSnapshot(r.Context(), query["type"], limit)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	view := graph.BuildView(layers.Subgraph(sub, query.Get("focus")), graph.ViewOptions{
+		HideEdgeTypes: listParam(query["hide"]),
+		ClusterBy:     query.Get("cluster"),
+		Expand:        listParam(query["expand"]),
+	})
+	view.Truncated = query.Get("focus") == "" && len(sub.Nodes) >= limit
+	view.ApplyStyles(sub, s.typeStyles(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/systemshift/memex/internal/server/health"
+)
+
+// SetGraphHealth enables the graph health score
+func (s *Server) SetGraphHealth(c *health.Checker) {
+	s.graphHealth = c
+}
+
+// GraphHealth handles GET /api/graph/health
+// Scores the graph from 0 to 100 by integrity, orphans, pending duplicates,
+// link type violations and staleness, with each component's breakdown.
+// ?fail_below=N answers 503 when the score is below N, for alerting.
+func (s *Server) GraphHealth(w http.ResponseWriter, r *http.Request) {
+	if s.graphHealth == nil {
+		notEnabled(w, "graph health is not enabled")
+		return
+	}
+
+	failBelow := -1.0
+	if f := r.URL.Query().Get("fail_below"); f != "" {
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil || n < 0 || n > 100 {
+			http.Error(w, "invalid fail_below parameter (0-100)", http.StatusBadRequest)
+			return
+		}
+		failBelow = n
+	}
+
+	report, err := s.graphHealth.Check(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Score < failBelow {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/growth"
+)
+
+// maxGrowthDays caps the history GET /api/admin/growth returns
+const maxGrowthDays = 3650
+
+// GrowthResponse is the response for GET /api/admin/growth
+type GrowthResponse struct {
+	Rollups    []*growth.Rollup   `json:"rollups"`
+	Current    *growth.Rollup     `json:"current"` // Today so far; not stored
+	Projection *growth.Projection `json:"projection,omitempty"`
+}
+
+// SetGrowthRecorder records that daily rollups are being stored, for
+// diagnostics
+func (s *Server) SetGrowthRecorder(recorder *growth.Recorder) {
+	s.growthRecorder = recorder
+}
+
+// GetGrowth handles GET /api/admin/growth
+// Returns the daily rollups of the last ?days= days (default 90), today's
+// counts so far, and a projection of storage needs at each ?horizon= (days,
+// comma-separated; default 30,90,365) and against the configured quotas.
+func (s *Server) GetGrowth(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	days := 90
+	if v := query.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxGrowthDays {
+			http.Error(w, "invalid days (1-3650)", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	horizons := growth.DefaultHorizons
+	if v := query.Get("horizon"); v != "" {
+		horizons = nil
+		for _, item := range strings.Split(v, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(item))
+			if err != nil || n < 1 || n > maxGrowthDays {
+				http.Error(w, "invalid horizon (days, 1-3650)", http.StatusBadRequest)
+				return
+			}
+			horizons = append(horizons, n)
+		}
+	}
+
+	now := time.Now()
+	rollups, err := growth.List(r.Context(), s.repo, growth.Day(now.AddDate(0, 0, -days)), "")
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	current, err := growth.Collect(r.Context(), s.repo, now)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	// Today's counts are the newest point for the projection
+	series := rollups
+	if n := len(rollups); n == 0 || rollups[n-1].Date != current.Date {
+		series = append(append([]*growth.Rollup(nil), rollups...), current)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GrowthResponse{
+		Rollups:    rollups,
+		Current:    current,
+		Projection: growth.Project(series, horizons, s.quotas),
+	})
+}
@@ -5,21 +5,172 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/analytics"
+	"github.com/systemshift/memex/internal/server/annotations"
+	"github.com/systemshift/memex/internal/server/anomaly"
+	"github.com/systemshift/memex/internal/server/apiversion"
+	"github.com/systemshift/memex/internal/server/autocomplete"
+	"github.com/systemshift/memex/internal/server/citations"
+	"github.com/systemshift/memex/internal/server/connectors"
+	"github.com/systemshift/memex/internal/server/dedup"
+	"github.com/systemshift/memex/internal/server/devices"
+	"github.com/systemshift/memex/internal/server/embeddings"
+	"github.com/systemshift/memex/internal/server/experiments"
+	graphexport "github.com/systemshift/memex/internal/server/export"
+	"github.com/systemshift/memex/internal/server/faults"
+	"github.com/systemshift/memex/internal/server/federation"
+	"github.com/systemshift/memex/internal/server/feedback"
+	"github.com/systemshift/memex/internal/server/finalizers"
+	"github.com/systemshift/memex/internal/server/github"
 	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/growth"
+	"github.com/systemshift/memex/internal/server/health"
+	"github.com/systemshift/memex/internal/server/importer"
+	"github.com/systemshift/memex/internal/server/infer"
+	"github.com/systemshift/memex/internal/server/ingest"
+	"github.com/systemshift/memex/internal/server/lanes"
+	"github.com/systemshift/memex/internal/server/locks"
+	"github.com/systemshift/memex/internal/server/memory"
+	"github.com/systemshift/memex/internal/server/modules"
+	"github.com/systemshift/memex/internal/server/normalize"
+	"github.com/systemshift/memex/internal/server/queries"
+	"github.com/systemshift/memex/internal/server/querycache"
+	"github.com/systemshift/memex/internal/server/rules"
+	"github.com/systemshift/memex/internal/server/sandbox"
+	"github.com/systemshift/memex/internal/server/sessions"
+	"github.com/systemshift/memex/internal/server/share"
+	"github.com/systemshift/memex/internal/server/signing"
+	"github.com/systemshift/memex/internal/server/snapshots"
 	"github.com/systemshift/memex/internal/server/subscriptions"
+	"github.com/systemshift/memex/internal/server/tasks"
+	"github.com/systemshift/memex/internal/server/tickets"
+	"github.com/systemshift/memex/internal/server/tokens"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/systemshift/memex/internal/server/api")
+
 // Server holds the HTTP server dependencies
 type Server struct {
 	repo   graph.Repository
 	subMgr *subscriptions.Manager
+
+	basePath   string // Path prefix the API is mounted under (e.g. "/memex")
+	trustProxy bool   // Honor X-Forwarded-* headers when building URLs
+
+	slowLog  *graph.SlowQueryLog // Optional; nil when slow-query logging is off
+	revision *graph.Revision     // Optional; enables ETags on graph-wide views
+
+	queryCache *querycache.Cache // Optional; caches subgraph, graph map and aggregate results
+
+	rdfVocab *graphexport.Vocabulary // Optional; RDF term mapping for JSON-LD/Turtle export
+
+	dagLinkTypes []string // Link types kept acyclic; defaults for the DAG check endpoint
+
+	trustPolicy *graph.TrustPolicy // Optional; connector/domain trust for search ranking
+	quotas      []graph.Quota      // Configured quotas, reported by the usage endpoint
+
+	normalization *normalize.Policy // Optional; per-connector content normalization on ingest
+
+	ingestTracker *ingest.Tracker // Optional; fires ingest completion webhooks
+	shares        *share.Signer   // Optional; signs public read-only share links
+
+	queryScheduler *queries.Scheduler // Optional; runs saved queries on their schedules
+
+	growthRecorder *growth.Recorder // Optional; stores daily size rollups
+
+	githubClient        *github.Client // Optional; GitHub API access for syncs and webhook deliveries
+	githubWebhookSecret string         // Enables the GitHub webhook endpoint
+
+	ticketSources []tickets.Source // Optional; Jira and Linear trackers synced into tasks
+
+	webURL string // Optional; web UI node link with an {id} placeholder
+
+	aliases *graph.Aliases       // Optional; node aliases and short IDs
+	peers   *federation.Searcher // Optional; other servers searched with ?federated=true
+
+	integrityExclude []string     // Node types never reported as orphans; nil uses the defaults
+	cleanups         cleanupQueue // Background integrity cleanup jobs
+
+	nodeLocks locks.Manager // Advisory edit locks, shown on node responses
+
+	apiKeyAuthors map[string]string // Optional; API key -> author name for comments
+
+	lanes    *lanes.Limiter    // Optional; per-lane concurrency limits
+	keyLanes map[string]string // API key -> lane
+
+	warmup *warmupState // Optional; startup warm-up /ready waits for
+
+	faults *faults.Injector // Optional; only in builds with -tags faults
+
+	snapshots *snapshots.Manager // Optional; pinned read views for multi-call flows
+
+	finalizers *finalizers.Manager // Optional; cleanup webhooks for deleted nodes
+
+	adminKey        string                   // Enables authentication and access tokens
+	tokenIssuer     *tokens.Issuer           // Mints and verifies access tokens; set with adminKey
+	requestVerifier *signing.Verifier        // Optional; accepts HMAC-signed requests
+	signingScopes   map[string]*SigningScope // Signing key ID -> what its requests may do
+
+	citationProc *citations.Processor // Optional; parses paper references on demand
+	zoteroURL    string               // Zotero API base URL; empty uses api.zotero.org
+
+	taskProc *tasks.Processor // Optional; extracts tasks on demand
+
+	autocomplete *autocomplete.Index // Optional; prefix index for suggestions
+
+	ruleEngine *rules.Engine // Optional; reloaded when rules change, reports metrics
+
+	sandboxes *sandbox.Manager // Optional; scratch overlays over the graph
+
+	feedback *feedback.Tracker // Optional; records retrievals for quality stats
+
+	experiments *experiments.Manager // Optional; A/B tests of search ranking
+
+	attention *graph.AttentionStore // Optional; attention edges kept apart from links
+
+	memory *memory.Store // Optional; pinned node summaries for agent context
+
+	embeddings *embeddings.Store // Optional; structural node vectors trained over the links
+
+	ingestMonitor *anomaly.Monitor // Optional; baselines ingest and flags departures from it
+
+	dedup *dedup.Detector // Optional; flags nodes with nearly the same text
+
+	typeInference *infer.Processor // Optional; types ingested JSON sources by the stored type rules
+
+	graphHealth *health.Checker // Optional; scores the graph for status pages and alerts
+
+	devices *devices.Registry // Optional; identifies the machine each write comes from
+
+	deprecations *apiversion.Tracker // Optional; announces deprecated routes and counts their calls
+
+	analytics *analytics.Engine // Optional; registered read-only SQL on its own SQLite pool
+
+	sessions *sessions.Manager // Optional; per-conversation agent working memory
+
+	modules *modules.Registry // Optional; event processors managed at runtime
+
+	connectors *connectors.Manager // Optional; scheduled sources from the API and config file
+
+	traversalBudget graph.TraversalBudget // Most work one traverse or subgraph request may do
+
+	backendName string           // Configured MEMEX_BACKEND, reported by diagnostics
+	backend     graph.Repository // Optional; the unwrapped backend, checked by diagnostics
 }
 
 // New creates a new API server
@@ -27,17 +178,41 @@ func New(repo graph.Repository, subMgr *subscriptions.Manager) *Server {
 	return &Server{repo: repo, subMgr: subMgr}
 }
 
+// SetRevision enables ETags on graph views using the given change counter
+func (s *Server) SetRevision(revision *graph.Revision) {
+	s.revision = revision
+}
+
+// SetSlowQueryLog exposes a slow-query log through the admin endpoints
+func (s *Server) SetSlowQueryLog(l *graph.SlowQueryLog) {
+	s.slowLog = l
+}
+
+// SetRDFVocabulary sets the term mapping used by the RDF export formats
+func (s *Server) SetRDFVocabulary(v *graphexport.Vocabulary) {
+	s.rdfVocab = v
+}
+
+// SetProxyConfig sets the base path and whether to trust reverse-proxy headers
+func (s *Server) SetProxyConfig(basePath string, trustProxy bool) {
+	s.basePath = NormalizeBasePath(basePath)
+	s.trustProxy = trustProxy
+}
+
 // CreateNodeRequest is the request body for creating a node
 type CreateNodeRequest struct {
-	ID   string                 `json:"id"`
-	Type string                 `json:"type"`
-	Meta map[string]interface{} `json:"meta"`
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Meta      map[string]interface{} `json:"meta"`
+	ValidFrom string                 `json:"valid_from,omitempty"` // When the fact began to hold; stored as meta.valid_from
+	ValidTo   string                 `json:"valid_to,omitempty"`   // When it stopped (exclusive); stored as meta.valid_to
 }
 
 // CreateNodeResponse is the response for creating a node
 type CreateNodeResponse struct {
 	ID      string    `json:"id"`
 	Created time.Time `json:"created"`
+	URL     string    `json:"url"`
 }
 
 // CreateNode handles POST /api/nodes
@@ -47,38 +222,61 @@ func (s *Server) CreateNode(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := graph.ValidateLayer(req.Meta); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	meta, err := withValidTime(req.Meta, req.ValidFrom, req.ValidTo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	now := time.Now()
 	node := &core.Node{
 		ID:       req.ID,
 		Type:     req.Type,
-		Meta:     req.Meta,
+		Meta:     meta,
 		Created:  now,
 		Modified: now,
 	}
 
 	if err := s.repo.CreateNode(r.Context(), node); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	resp := CreateNodeResponse{
 		ID:      node.ID,
 		Created: node.Created,
+		URL:     s.BaseURL(r) + "/api/nodes/" + url.PathEscape(node.ID),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", resp.URL)
 	json.NewEncoder(w).Encode(resp)
 }
 
 // GetNode handles GET /api/nodes/{id}
-// Supports query params: ?version=N for specific version, ?as_of=RFC3339 for point-in-time
+// Supports query params: ?version=N for specific version, ?as_of=RFC3339 for point-in-time,
+// ?as_of_valid= and ?as_of_recorded= for the version valid at a time as recorded at another,
+// ?include_deleted=true to get a deleted node's last live version,
+// ?fields= and ?max_content_bytes= to return only part of it
 func (s *Server) GetNode(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	query := r.URL.Query()
+	view, err := nodeViewParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	times, err := timeFilterParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	var node *core.Node
-	var err error
 
 	// Check for version parameter
 	if vStr := query.Get("version"); vStr != "" {
@@ -96,18 +294,74 @@ func (s *Server) GetNode(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		node, err = s.repo.GetNodeAtTime(r.Context(), id, asOf)
+	} else if times != nil {
+		node, err = s.nodeAtTimes(r.Context(), id, times)
 	} else {
-		// Default: get current version
-		node, err = s.repo.GetNode(r.Context(), id)
+		// Default: get current version, or the last live one of a deleted node
+		node, err = s.repo.GetNode(readContext(r), id)
 	}
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if err := graph.VerifyContent(node); err != nil {
+		writeContentError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordSession(r, "", node.ID)
+
+	resp := nodeResponse{Node: node}
+	etag := node.VersionID
+	if node.IsCurrent {
+		// Backlinks change without a new version, so they are part of the validator
+		backlinks, err := s.repo.GetBacklinks(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			return
+		}
+		count, comments := len(backlinks), annotations.Count(backlinks)
+		resp.BacklinkCount, resp.CommentCount = &count, &comments
+		etag = fmt.Sprintf("%s-b%d", node.VersionID, count)
+
+		// Locks likewise come and go without a new version
+		if resp.Lock = s.nodeLocks.Get(id, time.Now()); resp.Lock != nil {
+			etag = fmt.Sprintf("%s-l%d", etag, resp.Lock.Expires.UnixNano())
+		}
+	}
+
+	if node.Deleted {
+		etag += "-deleted"
+	}
+	etag += view.etag()
+
+	// Versions are immutable, so the version ID is a strong validator
+	if checkETag(w, r, etag) {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(node)
+	if view != nil && view.fields != nil {
+		json.NewEncoder(w).Encode(view.node(node))
+		return
+	}
+	if cut, full := view.truncate(node); full > 0 {
+		resp.Node, resp.ContentLength, resp.ContentTruncated = cut, full, true
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// nodeResponse is a node as returned by GET /api/nodes/{id}; BacklinkCount,
+// CommentCount and Lock are only set for the current version, and
+// ContentLength only when the content was truncated
+type nodeResponse struct {
+	*core.Node
+	BacklinkCount    *int        `json:",omitempty"`
+	CommentCount     *int        `json:",omitempty"`
+	Lock             *locks.Lock `json:",omitempty"`
+	ContentLength    int         `json:",omitempty"`
+	ContentTruncated bool        `json:",omitempty"`
 }
 
 // GetNodeHistory handles GET /api/nodes/{id}/history
@@ -129,6 +383,61 @@ func (s *Server) GetNodeHistory(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetNodeDiff handles GET /api/nodes/{id}/diff?from=v3&to=v5
+// Defaults to comparing the current version against the one before it
+func (s *Server) GetNodeDiff(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	var toNode *core.Node
+	var err error
+	if toStr := query.Get("to"); toStr != "" {
+		toVersion, parseErr := parseVersionParam(toStr)
+		if parseErr != nil {
+			http.Error(w, "invalid to parameter", http.StatusBadRequest)
+			return
+		}
+		toNode, err = s.repo.GetNodeAtVersion(r.Context(), id, toVersion)
+	} else {
+		toNode, err = s.repo.GetNode(r.Context(), id)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	fromVersion := toNode.Version - 1
+	if fromStr := query.Get("from"); fromStr != "" {
+		fromVersion, err = parseVersionParam(fromStr)
+		if err != nil {
+			http.Error(w, "invalid from parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if fromVersion < 1 {
+		http.Error(w, "node has no earlier version to compare against", http.StatusBadRequest)
+		return
+	}
+
+	fromNode, err := s.repo.GetNodeAtVersion(r.Context(), id, fromVersion)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(graph.DiffNodes(fromNode, toNode))
+}
+
+// parseVersionParam parses a version given as "3" or "v3"
+func parseVersionParam(value string) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(value, "v"))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid version: %s", value)
+	}
+	return version, nil
+}
+
 // UpdateNodeRequest is the request body for updating a node's metadata
 type UpdateNodeRequest struct {
 	Meta       map[string]interface{} `json:"meta"`
@@ -137,7 +446,9 @@ type UpdateNodeRequest struct {
 }
 
 // UpdateNode handles PATCH /api/nodes/{id}
-// Creates a new version of the node with the updated metadata
+// Creates a new version of the node with the updated metadata. A node
+// locked by someone else is rejected with 409 unless changed_by is the owner,
+// as are status changes to tasks.
 func (s *Server) UpdateNode(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -147,32 +458,65 @@ func (s *Server) UpdateNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := graph.ValidateLayer(req.Meta); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := graph.NormalizeValidTime(req.Meta); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.nodeLocks.Check(id, req.ChangedBy, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// Task status changes go through the state machine
+	if _, ok := req.Meta["status"]; ok {
+		if node, err := s.repo.GetNode(r.Context(), id); err == nil && node.Type == tasks.TaskType {
+			http.Error(w, "task status changes go through PATCH /api/tasks/{id}/status", http.StatusConflict)
+			return
+		}
+	}
+
 	if err := s.repo.UpdateNodeMetaWithNote(r.Context(), id, req.Meta, req.ChangeNote, req.ChangedBy); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	// Return the updated node
 	node, err := s.repo.GetNode(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"id":      id,
 		"version": node.Version,
 		"updated": true,
-	})
+	}
+	if content, ok := req.Meta["content"].(string); ok {
+		resp["content"] = graph.DigestContent([]byte(content))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 // CreateLinkRequest is the request body for creating a link
 type CreateLinkRequest struct {
-	Source string                 `json:"source"`
-	Target string                 `json:"target"`
-	Type   string                 `json:"type"`
-	Meta   map[string]interface{} `json:"meta"`
+	Source          string                 `json:"source"`
+	Target          string                 `json:"target"`
+	Type            string                 `json:"type"`
+	Meta            map[string]interface{} `json:"meta"`
+	Confidence      *float64               `json:"confidence,omitempty"`        // Stored as meta.confidence
+	TargetVersionID string                 `json:"target_version_id,omitempty"` // Pins the link to one version of the target; stored as meta.target_version_id
+	ValidFrom       string                 `json:"valid_from,omitempty"`        // When the relationship began to hold; stored as meta.valid_from
+	ValidTo         string                 `json:"valid_to,omitempty"`          // When it stopped (exclusive); stored as meta.valid_to
+	Weight          float64                `json:"weight,omitempty"`            // Strength of the relationship; omitted or 0 when unweighted
+	Bidirectional   bool                   `json:"bidirectional,omitempty"`     // The relationship holds from target to source too
 }
 
 // CreateLink handles POST /api/links
@@ -183,18 +527,32 @@ func (s *Server) CreateLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	meta, err := linkMeta(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.checkTargetVersion(r.Context(), req.Target, meta); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	now := time.Now()
 	link := &core.Link{
-		Source:   req.Source,
-		Target:   req.Target,
-		Type:     req.Type,
-		Meta:     req.Meta,
-		Created:  now,
-		Modified: now,
+		Source:        req.Source,
+		Target:        req.Target,
+		Type:          req.Type,
+		Meta:          meta,
+		Weight:        req.Weight,
+		Bidirectional: req.Bidirectional,
+		Created:       now,
+		Modified:      now,
 	}
+	graph.PromoteLinkFields(link)
 
 	if err := s.repo.CreateLink(r.Context(), link); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), linkErrorStatus(err))
 		return
 	}
 
@@ -202,27 +560,144 @@ func (s *Server) CreateLink(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(link)
 }
 
+// BulkNodesRequest is the request body for creating many nodes
+type BulkNodesRequest struct {
+	Nodes []CreateNodeRequest `json:"nodes"`
+}
+
+// BulkLinksRequest is the request body for creating many links
+type BulkLinksRequest struct {
+	Links []CreateLinkRequest `json:"links"`
+}
+
+// BulkResponse is the response for bulk creation
+type BulkResponse struct {
+	Created int `json:"created"`
+}
+
+// BulkCreateNodes handles POST /api/nodes/bulk
+func (s *Server) BulkCreateNodes(w http.ResponseWriter, r *http.Request) {
+	var req BulkNodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	nodes := make([]*core.Node, len(req.Nodes))
+	for i, n := range req.Nodes {
+		if n.ID == "" || n.Type == "" {
+			http.Error(w, fmt.Sprintf("nodes[%d]: id and type are required", i), http.StatusBadRequest)
+			return
+		}
+		if err := graph.ValidateLayer(n.Meta); err != nil {
+			http.Error(w, fmt.Sprintf("nodes[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		meta, err := withValidTime(n.Meta, n.ValidFrom, n.ValidTo)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("nodes[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		nodes[i] = &core.Node{ID: n.ID, Type: n.Type, Meta: meta, Created: now, Modified: now}
+	}
+
+	if err := s.repo.CreateNodes(r.Context(), nodes); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(BulkResponse{Created: len(nodes)})
+}
+
+// BulkCreateLinks handles POST /api/links/bulk
+func (s *Server) BulkCreateLinks(w http.ResponseWriter, r *http.Request) {
+	var req BulkLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	links := make([]*core.Link, len(req.Links))
+	for i, l := range req.Links {
+		if l.Source == "" || l.Target == "" || l.Type == "" {
+			http.Error(w, fmt.Sprintf("links[%d]: source, target and type are required", i), http.StatusBadRequest)
+			return
+		}
+		meta, err := linkMeta(l)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("links[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		if err := s.checkTargetVersion(r.Context(), l.Target, meta); err != nil {
+			http.Error(w, fmt.Sprintf("links[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		links[i] = &core.Link{Source: l.Source, Target: l.Target, Type: l.Type, Meta: meta, Weight: l.Weight, Bidirectional: l.Bidirectional, Created: now, Modified: now}
+	}
+
+	if err := s.repo.CreateLinks(r.Context(), links); err != nil {
+		http.Error(w, err.Error(), linkErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(BulkResponse{Created: len(links)})
+}
+
 // GetLinks handles GET /api/nodes/{id}/links
+// ?layer= (repeatable or comma-separated) keeps links in those layers;
+// ?as_of_valid= and ?as_of_recorded= keep links that held, or existed, then.
 func (s *Server) GetLinks(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	times, err := timeFilterParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	links, err := s.repo.GetLinks(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(links)
+	json.NewEncoder(w).Encode(times.Links(layers.Links(links)))
 }
 
 // ListNodes handles GET /api/nodes
+// ?layer= (repeatable or comma-separated) keeps nodes in those layers.
 func (s *Server) ListNodes(w http.ResponseWriter, r *http.Request) {
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	ids, err := s.repo.ListNodes(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
+	if layers != nil {
+		kept := ids[:0]
+		for _, id := range ids {
+			if node, err := s.repo.GetNode(r.Context(), id); err == nil && layers.Node(node) {
+				kept = append(kept, id)
+			}
+		}
+		ids = kept
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -243,12 +718,68 @@ func (s *Server) HealthCheck(w http.ResponseWriter, r *http.Request) {
 type IngestRequest struct {
 	Content string `json:"content"`
 	Format  string `json:"format,omitempty"` // e.g. "text", "git-log", "json"
+
+	// Optional origin, used for trust weighting
+	URL       string   `json:"url,omitempty"`
+	Connector string   `json:"connector,omitempty"`
+	Trust     *float64 `json:"trust,omitempty"`
+
+	normalization normalize.Pipeline // Steps applied to Content, recorded on the source
 }
 
 // IngestResponse is the response for ingesting content
 type IngestResponse struct {
-	SourceID string    `json:"source_id"`
-	Created  time.Time `json:"created"`
+	SourceID string               `json:"source_id"`
+	Created  time.Time            `json:"created"`
+	Content  *graph.ContentDigest `json:"content"` // Hash, size and encoding of the stored content
+}
+
+// validate checks the content is present and the trust in range
+func (req *IngestRequest) validate() error {
+	if req.Content == "" {
+		return fmt.Errorf("content is required")
+	}
+	if req.Trust != nil && (*req.Trust < 0 || *req.Trust > 1) {
+		return fmt.Errorf("trust must be between 0 and 1")
+	}
+	return nil
+}
+
+// sourceID returns the content-addressed ID of the source
+func (req *IngestRequest) sourceID() string {
+	hash := sha256.Sum256([]byte(req.Content))
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// sourceNode builds the Source node for the request
+func (req *IngestRequest) sourceNode(now time.Time) *core.Node {
+	id := req.sourceID()
+	node := &core.Node{
+		ID:      id,
+		Type:    "Source",
+		Content: []byte(req.Content),
+		Meta: map[string]interface{}{
+			"format":             req.Format,
+			"ingested_at":        now.Format(time.RFC3339),
+			"size_bytes":         len(req.Content),
+			graph.ContentHashKey: strings.TrimPrefix(id, "sha256:"),
+		},
+		Created:  now,
+		Modified: now,
+	}
+	if req.URL != "" {
+		node.Meta["url"] = req.URL
+	}
+	if req.Connector != "" {
+		node.Meta["connector"] = req.Connector
+	}
+	if req.Trust != nil {
+		node.Meta[graph.TrustKey] = *req.Trust
+	}
+	if len(req.normalization) > 0 {
+		node.Meta[graph.ContentNormalizationKey] = req.normalization.String()
+	}
+	return node
 }
 
 // Ingest handles POST /api/ingest
@@ -259,25 +790,37 @@ func (s *Server) Ingest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Content == "" {
-		http.Error(w, "content is required", http.StatusBadRequest)
+	req.normalize(s.normalization)
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Compute SHA256 hash of content
-	hash := sha256.Sum256([]byte(req.Content))
-	hashStr := hex.EncodeToString(hash[:])
-	sourceID := "sha256:" + hashStr
+	ctx, span := tracer.Start(r.Context(), "ingest.source", trace.WithAttributes(
+		attribute.String("memex.format", req.Format),
+		attribute.Int("memex.size_bytes", len(req.Content)),
+	))
+	defer span.End()
+
+	sourceID := req.sourceID()
+	span.SetAttributes(attribute.String("memex.source_id", sourceID))
 
 	now := time.Now()
 
 	// Check if source already exists (dedup)
-	existing, err := s.repo.GetNode(r.Context(), sourceID)
+	existing, err := s.repo.GetNode(ctx, sourceID)
 	if err == nil && existing != nil {
-		// Source already exists, return existing ID
+		// Source already exists, return existing ID once its content checks out
+		span.SetAttributes(attribute.Bool("memex.deduplicated", true))
+		if err := graph.VerifyContent(existing); err != nil {
+			span.RecordError(err)
+			writeContentError(w, err, http.StatusInternalServerError)
+			return
+		}
 		resp := IngestResponse{
 			SourceID: existing.ID,
 			Created:  existing.Created,
+			Content:  graph.DigestNode(existing),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
@@ -285,26 +828,16 @@ func (s *Server) Ingest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create new Source node
-	node := &core.Node{
-		ID:       sourceID,
-		Type:     "Source",
-		Content:  []byte(req.Content),
-		Meta: map[string]interface{}{
-			"format":      req.Format,
-			"ingested_at": now.Format(time.RFC3339),
-			"size_bytes":  len(req.Content),
-		},
-		Created:  now,
-		Modified: now,
-	}
-
-	if err := s.repo.CreateNode(r.Context(), node); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	node := req.sourceNode(now)
+	if err := s.repo.CreateNode(ctx, node); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	// Record transaction
-	if err := s.recordTransaction(r.Context(), "ingest_source", map[string]interface{}{
+	if err := s.recordTransaction(ctx, "ingest_source", map[string]interface{}{
 		"source_id": sourceID,
 		"format":    req.Format,
 		"size":      len(req.Content),
@@ -316,6 +849,7 @@ func (s *Server) Ingest(w http.ResponseWriter, r *http.Request) {
 	resp := IngestResponse{
 		SourceID: node.ID,
 		Created:  node.Created,
+		Content:  graph.DigestNode(node),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -351,55 +885,274 @@ func parsePagination(r *http.Request) (limit int, offset int) {
 	if l := query.Get("limit"); l != "" {
 		fmt.Sscanf(l, "%d", &limit)
 	}
-	if o := query.Get("offset"); o != "" {
-		fmt.Sscanf(o, "%d", &offset)
+	if o := query.Get("offset"); o != "" {
+		fmt.Sscanf(o, "%d", &offset)
+	}
+
+	return limit, offset
+}
+
+// QueryFilter handles GET /api/query/filter
+// ?include_deleted=true also matches deleted nodes, by their last live version.
+// ?as_of_valid= and ?as_of_recorded= return the versions selected for those
+// times, leaving out nodes without one.
+func (s *Server) QueryFilter(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	query := r.URL.Query()
+	types := query["type"]           // can have multiple: ?type=Person&type=Concept
+	propertyKey := query.Get("key")  // e.g., ?key=extractor
+	propertyValue := query.Get("value") // e.g., ?value=openai
+	limit, offset := parsePagination(r)
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	view, err := nodeViewParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	times, err := timeFilterParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fetch := func(limit, offset int) ([]*core.Node, error) {
+		return times.Page(r.Context(), s.repo, limit, offset, func(limit, offset int) ([]*core.Node, error) {
+			return s.repo.FilterNodes(readContext(r), types, propertyKey, propertyValue, limit, offset)
+		})
+	}
+
+	if wantsNDJSON(r) {
+		streamNodes(w, r, view, layers, streamLimit(r), offset, fetch)
+		return
+	}
+
+	nodes, err := layers.Page(limit, offset, fetch)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"nodes": view.nodes(nodes),
+		"count": len(nodes),
+	})
+}
+
+// QuerySearch handles GET /api/query/search
+// Results are weighted by source trust unless ?trust=false.
+// ?include_deleted=true also matches deleted nodes, by their last live version.
+// ?stem=true matches words by stem, stored synonyms expand the query unless
+// ?synonyms=false, and ?namespace= keeps results in one namespace and adds
+// its synonyms. A first page with no hits carries "did you mean" suggestions
+// of nodes named like the query unless ?suggest=false.
+func (s *Server) QuerySearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		http.Error(w, "query parameter 'q' is required", http.StatusBadRequest)
+		return
+	}
+	limit, offset := parsePagination(r)
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, err := s.searchContext(r, q)
+	if err != nil {
+		http.Error(w, err.Error(), synonymStatus(err))
+		return
+	}
+	view, err := nodeViewParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("federated") == "true" {
+		s.federatedSearch(w, r, q, limit, layers)
+		return
+	}
+
+	// Streamed results come in backend order; ranking by trust needs them all
+	if wantsNDJSON(r) {
+		streamNodes(w, r, view, layers, streamLimit(r), offset, func(limit, offset int) ([]*core.Node, error) {
+			return s.repo.SearchNodes(ctx, q, limit, offset)
+		})
+		return
+	}
+
+	if r.URL.Query().Get("trust") == "false" {
+		nodes, err := layers.Page(limit, offset, func(limit, offset int) ([]*core.Node, error) {
+			return s.repo.SearchNodes(ctx, q, limit, offset)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			return
+		}
+
+		response := map[string]interface{}{
+			"nodes": view.nodes(nodes),
+			"count": len(nodes),
+			"query": q,
+		}
+		if len(nodes) == 0 && offset == 0 {
+			if suggestions := s.suggestions(ctx, r, q, layers); len(suggestions) > 0 {
+				response["suggestions"] = suggestions
+			}
+		}
+		if id := s.recordRetrieval("search", nil, searchHits(nodes, nil)); id != "" {
+			response["retrieval_id"] = id
+		}
+		s.recordSession(r, q, nodeIDs(nodes)...)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Rank a wider window so trusted hits from later pages can move up
+	nodes, err := s.repo.SearchNodes(ctx, q, (offset+limit)*trustCandidateFactor, 0)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	nodes = layers.Nodes(nodes)
+	strategy, assignment := s.searchStrategy(r)
+	trust, err := s.rankByTrust(r.Context(), nodes, strategy)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	if offset > len(nodes) {
+		offset = len(nodes)
+	}
+	nodes = nodes[offset:]
+	if limit < len(nodes) {
+		nodes = nodes[:limit]
+	}
+	pageTrust := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		pageTrust[node.ID] = trust[node.ID]
+	}
+
+	response := map[string]interface{}{
+		"nodes": view.nodes(nodes),
+		"count": len(nodes),
+		"query": q,
+		"trust": pageTrust,
+	}
+	if len(nodes) == 0 && offset == 0 {
+		if suggestions := s.suggestions(ctx, r, q, layers); len(suggestions) > 0 {
+			response["suggestions"] = suggestions
+		}
+	}
+	if assignment != nil {
+		response["experiment"] = assignment
+		w.Header().Set(variantHeader, assignment.Variant)
+	}
+	if id := s.recordRetrieval("search", assignment, searchHits(nodes, pageTrust)); id != "" {
+		response["retrieval_id"] = id
+	}
+	s.recordSession(r, q, nodeIDs(nodes)...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseTimeParam parses a time query parameter in RFC3339 or YYYY-MM-DD format
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local); err == nil {
+		return t, nil // As sent by datetime-local form inputs
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// parseTimeWindow reads the from/to query parameters. "to" defaults to now and
+// a date-only "to" covers the whole day; "from" defaults to defaultSpan before "to".
+func parseTimeWindow(r *http.Request, defaultSpan time.Duration) (from, to time.Time, err error) {
+	query := r.URL.Query()
+
+	to = time.Now()
+	if toStr := query.Get("to"); toStr != "" {
+		t, err := parseTimeParam(toStr)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid to parameter (use RFC3339 or YYYY-MM-DD)")
+		}
+		if len(toStr) == len("2006-01-02") {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		to = t
+	}
+
+	from = to.Add(-defaultSpan)
+	if fromStr := query.Get("from"); fromStr != "" {
+		t, err := parseTimeParam(fromStr)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid from parameter (use RFC3339 or YYYY-MM-DD)")
+		}
+		from = t
 	}
 
-	return limit, offset
+	if from.After(to) {
+		return from, to, fmt.Errorf("from must be before to")
+	}
+
+	return from, to, nil
 }
 
-// QueryFilter handles GET /api/query/filter
-func (s *Server) QueryFilter(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
+// QueryTimeRange handles GET /api/query/timerange
+// Returns nodes created or modified between from and to, defaulting to the last 24h
+func (s *Server) QueryTimeRange(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
-	types := query["type"]           // can have multiple: ?type=Person&type=Concept
-	propertyKey := query.Get("key")  // e.g., ?key=extractor
-	propertyValue := query.Get("value") // e.g., ?value=openai
-	limit, offset := parsePagination(r)
 
-	nodes, err := s.repo.FilterNodes(r.Context(), types, propertyKey, propertyValue, limit, offset)
+	from, to, err := parseTimeWindow(r, 24*time.Hour)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"nodes": nodes,
-		"count": len(nodes),
-	})
-}
+	types := query["type"]
+	limit, offset := parsePagination(r)
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	view, err := nodeViewParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-// QuerySearch handles GET /api/query/search
-func (s *Server) QuerySearch(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query().Get("q")
-	if q == "" {
-		http.Error(w, "query parameter 'q' is required", http.StatusBadRequest)
+	if wantsNDJSON(r) {
+		streamNodes(w, r, view, layers, streamLimit(r), offset, func(limit, offset int) ([]*core.Node, error) {
+			return s.repo.QueryTimeRange(r.Context(), from, to, types, limit, offset)
+		})
 		return
 	}
-	limit, offset := parsePagination(r)
 
-	nodes, err := s.repo.SearchNodes(r.Context(), q, limit, offset)
+	nodes, err := layers.Page(limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return s.repo.QueryTimeRange(r.Context(), from, to, types, limit, offset)
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"nodes": nodes,
+		"nodes": view.nodes(nodes),
 		"count": len(nodes),
-		"query": q,
+		"from":  from.Format(time.RFC3339),
+		"to":    to.Format(time.RFC3339),
 	})
 }
 
@@ -407,11 +1160,22 @@ func (s *Server) QuerySearch(w http.ResponseWriter, r *http.Request) {
 func (s *Server) DeleteNode(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	// Check query parameter for force delete (bypasses Source layer protection)
+	// Check query parameter for force delete (bypasses Source layer protection, not the protected flag)
 	force := r.URL.Query().Get("force") == "true"
 
-	if err := s.repo.DeleteNode(r.Context(), id, force); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// Optional per-request override of what a soft delete does with the node's links
+	ctx := r.Context()
+	cascade := r.URL.Query().Get("cascade")
+	if cascade != "" {
+		if err := graph.ValidateCascade(cascade); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = graph.ContextWithCascade(ctx, cascade)
+	}
+
+	if err := s.repo.DeleteNode(ctx, id, force); err != nil {
+		http.Error(w, err.Error(), deleteErrorStatus(err))
 		return
 	}
 
@@ -437,7 +1201,7 @@ func (s *Server) DeleteLink(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.repo.DeleteLink(r.Context(), source, target, linkType); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusNotFound))
 		return
 	}
 
@@ -452,6 +1216,10 @@ func (s *Server) DeleteLink(w http.ResponseWriter, r *http.Request) {
 }
 
 // QueryTraverse handles GET /api/query/traverse
+// Optionally walks ?hypothetical= edges as if they existed.
+// ?versions=pinned returns the versions the links between the nodes are
+// pinned to rather than the latest; ?as_of_valid= and ?as_of_recorded= the
+// versions selected for those times.
 func (s *Server) QueryTraverse(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	startNodeID := query.Get("start")
@@ -473,24 +1241,103 @@ func (s *Server) QueryTraverse(w http.ResponseWriter, r *http.Request) {
 	// Optional relationship type filters
 	relationshipTypes := query["rel_type"]
 	limit, offset := parsePagination(r)
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	view, err := nodeViewParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo, err := s.withHypothetical(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pinned, err := pinnedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	times, err := timeFilterParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, meter, err := s.withTraversalBudget(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if wantsNDJSON(r) {
+		if pinned {
+			http.Error(w, "versions=pinned is not supported when streaming", http.StatusBadRequest)
+			return
+		}
+		streamNodes(w, r, view, layers, streamLimit(r), offset, func(limit, offset int) ([]*core.Node, error) {
+			return times.Page(ctx, repo, limit, offset, func(limit, offset int) ([]*core.Node, error) {
+				nodes, err := repo.TraverseGraph(ctx, startNodeID, depth, relationshipTypes, limit, offset)
+				return sortedNodes(nodes), err
+			})
+		})
+		return
+	}
 
-	nodes, err := s.repo.TraverseGraph(r.Context(), startNodeID, depth, relationshipTypes, limit, offset)
+	nodes, err := repo.TraverseGraph(ctx, startNodeID, depth, relationshipTypes, limit, offset)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
+	if err := times.NodeMap(ctx, repo, nodes); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	for id, node := range nodes {
+		if !layers.Node(node) {
+			delete(nodes, id)
+		}
+	}
+
+	resp := map[string]interface{}{
+		"start":     startNodeID,
+		"depth":     depth,
+		"truncated": meter.Truncated(),
+		"cost":      meter.Cost(),
+	}
+	if pinned {
+		edges, err := graph.OutgoingEdges(ctx, repo, nodes)
+		if err != nil {
+			http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			return
+		}
+		conflicts, err := graph.PinnedVersions(ctx, repo, nodes, edges, startNodeID)
+		if err != nil {
+			http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			return
+		}
+		if len(conflicts) > 0 {
+			resp["pin_conflicts"] = conflicts
+		}
+	}
+	resp["nodes"], resp["count"] = view.nodeMap(nodes), len(nodes)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"nodes": nodes,
-		"count": len(nodes),
-		"start": startNodeID,
-		"depth": depth,
-	})
+	json.NewEncoder(w).Encode(resp)
 }
 
 // QuerySubgraph handles GET /api/query/subgraph
-// Returns nodes + ALL edges within a k-hop neighborhood
+// Returns nodes + ALL edges within a k-hop neighborhood, optionally as if
+// the ?hypothetical= edges existed. ?versions=pinned resolves nodes to
+// the versions their links are pinned to; ?as_of_valid= and
+// ?as_of_recorded= to the versions selected for those times, keeping the
+// edges valid then.
 func (s *Server) QuerySubgraph(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	startNodeID := query.Get("start")
@@ -511,15 +1358,97 @@ func (s *Server) QuerySubgraph(w http.ResponseWriter, r *http.Request) {
 
 	// Optional relationship type filters
 	relationshipTypes := query["rel_type"]
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	subgraph, err := s.repo.GetSubgraph(r.Context(), startNodeID, depth, relationshipTypes)
+	repo, err := s.withHypothetical(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pinned, err := pinnedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	times, err := timeFilterParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, meter, err := s.withTraversalBudget(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if checkETag(w, r, s.graphETag()) {
+		return
+	}
+
+	sortedTypes := append([]string(nil), relationshipTypes...)
+	sort.Strings(sortedTypes)
+	params := subgraphCacheParams{
+		Start:        startNodeID,
+		Depth:        depth,
+		RelTypes:     sortedTypes,
+		Layers:       layers,
+		Hypothetical: query.Get("hypothetical"),
+		Pinned:       pinned,
+		Times:        times,
+		Budget:       [3]string{query.Get("max_nodes"), query.Get("max_edges"), query.Get("max_time")},
+	}
+	result, err := cachedResult(s, w, r, "subgraph", params, func() (*subgraphResponse, bool, error) {
+		subgraph, err := repo.GetSubgraph(ctx, startNodeID, depth, relationshipTypes)
+		if err != nil {
+			return nil, false, err
+		}
+		subgraph = layers.Subgraph(subgraph, startNodeID)
+		if subgraph, err = times.Subgraph(ctx, repo, subgraph, startNodeID); err != nil {
+			return nil, false, err
+		}
+
+		var conflicts []string
+		if pinned {
+			if subgraph, conflicts, err = graph.PinnedSubgraph(ctx, repo, subgraph, startNodeID); err != nil {
+				return nil, false, err
+			}
+		}
+		// Truncated results depend on timing, so only whole ones are kept
+		return &subgraphResponse{subgraph, meter.Truncated(), meter.Cost(), conflicts}, !meter.Truncated(), nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(subgraph)
+	json.NewEncoder(w).Encode(result)
+}
+
+// subgraphResponse is the response for a subgraph query
+type subgraphResponse struct {
+	*graph.Subgraph
+	Truncated    bool                `json:"truncated"`
+	Cost         graph.TraversalCost `json:"cost"`
+	PinConflicts []string            `json:"pin_conflicts,omitempty"`
+}
+
+// subgraphCacheParams identify a subgraph query in the query cache
+type subgraphCacheParams struct {
+	Start        string
+	Depth        int
+	RelTypes     []string // Sorted
+	Layers       graph.LayerFilter
+	Hypothetical string
+	Pinned       bool
+	Times        *graph.TimeFilter
+	Budget       [3]string // max_nodes, max_edges, max_time
 }
 
 // UpdateAttentionEdgeRequest is the request body for updating attention edges
@@ -528,6 +1457,7 @@ type UpdateAttentionEdgeRequest struct {
 	Target   string  `json:"target"`
 	QueryID  string  `json:"query_id"`
 	Weight   float64 `json:"weight"`
+	Head     string  `json:"head,omitempty"` // Context label, e.g. "coding"; also weighted on its own
 }
 
 // UpdateAttentionEdge handles POST /api/edges/attention
@@ -549,8 +1479,17 @@ func (s *Server) UpdateAttentionEdge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.repo.UpdateAttentionEdge(r.Context(), req.Source, req.Target, req.QueryID, req.Weight); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	ctx := r.Context()
+	if req.Head != "" {
+		if err := graph.ValidateAttentionHead(req.Head); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = graph.WithAttentionHead(ctx, req.Head)
+	}
+
+	if err := s.repo.UpdateAttentionEdge(ctx, req.Source, req.Target, req.QueryID, req.Weight); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -561,11 +1500,13 @@ func (s *Server) UpdateAttentionEdge(w http.ResponseWriter, r *http.Request) {
 		"source":  req.Source,
 		"target":  req.Target,
 		"weight":  req.Weight,
+		"head":    req.Head,
 	})
 }
 
 // QueryAttentionSubgraph handles GET /api/query/attention_subgraph
-// Returns subgraph following high-weight attention edges
+// Returns subgraph following high-weight attention edges; with ?head= only
+// edges weighted for that head, by their weight for it
 func (s *Server) QueryAttentionSubgraph(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	startNodeID := query.Get("start")
@@ -594,14 +1535,33 @@ func (s *Server) QueryAttentionSubgraph(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	subgraph, err := s.repo.GetAttentionSubgraph(r.Context(), startNodeID, minWeight, maxNodes)
+	ctx := r.Context()
+	if head := query.Get("head"); head != "" {
+		if err := graph.ValidateAttentionHead(head); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = graph.WithAttentionHead(ctx, head)
+	}
+
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if checkETag(w, r, s.graphETag()) {
+		return
+	}
+
+	subgraph, err := s.repo.GetAttentionSubgraph(ctx, startNodeID, minWeight, maxNodes)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(subgraph)
+	json.NewEncoder(w).Encode(layers.Subgraph(subgraph, startNodeID))
 }
 
 // GraphMap handles GET /api/graph/map
@@ -622,14 +1582,101 @@ func (s *Server) GraphMap(w http.ResponseWriter, r *http.Request) {
 		sampleSize = 500
 	}
 
-	graphMap, err := s.repo.GetGraphMap(r.Context(), sampleSize)
+	if checkETag(w, r, s.graphETag()) {
+		return
+	}
+
+	graphMap, err := cachedResult(s, w, r, "graph_map", sampleSize, func() (*graph.GraphMap, bool, error) {
+		graphMap, err := s.repo.GetGraphMap(r.Context(), sampleSize)
+		return graphMap, true, err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	// Copy before adding styles; the map may be shared by the caches
+	styled := *graphMap
+	styled.Styles = make(map[string]graph.NodeStyle)
+	for nodeType, style := range s.typeStyles(r) {
+		if _, ok := graphMap.NodeTypes[nodeType]; ok {
+			styled.Styles[nodeType] = style
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(styled)
+}
+
+// GraphTimeline handles GET /api/graph/timeline
+// Returns nodes bucketed by creation day or week, defaulting to the last 30 days
+func (s *Server) GraphTimeline(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	bucket := query.Get("bucket")
+	if bucket == "" {
+		bucket = graph.BucketDay
+	}
+	if err := graph.ValidateBucket(bucket); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseTimeWindow(r, 30*24*time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Default 5 samples per bucket
+	sampleSize := 5
+	if ss := query.Get("samples"); ss != "" {
+		if _, err := fmt.Sscanf(ss, "%d", &sampleSize); err != nil {
+			http.Error(w, "invalid samples parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if sampleSize > 50 {
+		sampleSize = 50
+	}
+
+	timeline, err := s.repo.GetTimeline(r.Context(), from, to, bucket, query["type"], sampleSize)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timeline)
+}
+
+// GraphDiff handles GET /api/graph/diff
+// Reports nodes added/modified/deleted and links added/removed between from and to.
+// ?mode=detailed lists the individual changes; the default summary mode returns counts only.
+func (s *Server) GraphDiff(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = graph.DiffModeSummary
+	}
+	if mode != graph.DiffModeSummary && mode != graph.DiffModeDetailed {
+		http.Error(w, "invalid mode parameter (use 'summary' or 'detailed')", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseTimeWindow(r, 7*24*time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := s.repo.DiffGraph(r.Context(), from, to, mode == graph.DiffModeDetailed)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(graphMap)
+	json.NewEncoder(w).Encode(diff)
 }
 
 // PruneAttentionEdges handles POST /api/edges/attention/prune
@@ -659,7 +1706,7 @@ func (s *Server) PruneAttentionEdges(w http.ResponseWriter, r *http.Request) {
 
 	deletedCount, err := s.repo.PruneWeakAttentionEdges(r.Context(), minWeight, minQueryCount)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -671,6 +1718,31 @@ func (s *Server) PruneAttentionEdges(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ExplainAttentionEdge handles GET /api/edges/attention/explain
+// Returns the queries, times and weights that built up the ATTENDED edge
+// from ?source= to ?target=
+func (s *Server) ExplainAttentionEdge(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	source, target := query.Get("source"), query.Get("target")
+	if source == "" || target == "" {
+		http.Error(w, "source and target query parameters required", http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := graph.ExplainAttentionEdge(r.Context(), s.repo, source, target)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, graph.ErrNoAttentionEdge) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(explanation)
+}
+
 // ==================== Lens Handlers ====================
 
 // CreateLensRequest is the request body for creating a lens
@@ -735,7 +1807,7 @@ func (s *Server) CreateLens(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.repo.CreateNode(r.Context(), node); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -751,7 +1823,7 @@ func (s *Server) ListLenses(w http.ResponseWriter, r *http.Request) {
 	// Filter nodes by type "Lens"
 	nodes, err := s.repo.FilterNodes(r.Context(), []string{"Lens"}, "", "", 100, 0)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -837,7 +1909,7 @@ func (s *Server) UpdateLens(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.repo.UpdateNodeMeta(r.Context(), id, req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -869,7 +1941,7 @@ func (s *Server) DeleteLens(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.repo.DeleteNode(r.Context(), id, false); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), deleteErrorStatus(err))
 		return
 	}
 
@@ -892,7 +1964,7 @@ func (s *Server) GetLensEntities(w http.ResponseWriter, r *http.Request) {
 	// Get entities interpreted through this lens
 	entities, err := s.repo.GetEntitiesInterpretedThrough(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -922,10 +1994,17 @@ func (s *Server) QueryByLens(w http.ResponseWriter, r *http.Request) {
 	// Optional pattern filter (e.g., "commitment", "deadline")
 	pattern := query.Get("pattern")
 	limit, offset := parsePagination(r)
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	entities, err := s.repo.QueryByLens(r.Context(), lensID, pattern, limit, offset)
+	entities, err := layers.Page(limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return s.repo.QueryByLens(r.Context(), lensID, pattern, limit, offset)
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -966,12 +2045,86 @@ func (s *Server) ExportLens(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(export)
 }
 
+// ExportGraph handles GET /api/export/{format}
+// Writes current nodes and links as GraphML, GEXF, DOT, JSON-LD, Turtle or an
+// HTML viewer, or nodes as CSV.
+// Query params: type (repeatable), meta (comma-separated meta keys), limit
+func (s *Server) ExportGraph(w http.ResponseWriter, r *http.Request) {
+	format, ok := graphexport.Formats[chi.URLParam(r, "format")]
+	if !ok {
+		http.Error(w, "unsupported format (use graphml, gexf, dot, jsonld, turtle, csv or html)", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 10000
+	if l := query.Get("limit"); l != "" {
+		if _, err := fmt.Sscanf(l, "%d", &limit); err != nil || limit < 1 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if limit > 100000 {
+		limit = 100000
+	}
+
+	opts := graphexport.Options{Vocabulary: s.rdfVocab, Styles: s.typeStyles(r)}
+	if m := query.Get("meta"); m != "" {
+		for _, key := range strings.Split(m, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				opts.MetaKeys = append(opts.MetaKeys, key)
+			}
+		}
+	}
+
+	snapshot, err := s.repo.GetGraphSnapshot(r.Context(), query["type"], limit)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"memex.%s\"", format.Extension))
+	format.Write(w, snapshot, opts)
+}
+
+// ImportCSV handles POST /api/import/csv
+// The request body is CSV with a header row; each row creates or updates one node.
+// Query params: type, id_column (required), id_prefix, content_column,
+// link_column, link_type, link_prefix
+func (s *Server) ImportCSV(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := importer.CSVOptions{
+		Type:          query.Get("type"),
+		IDColumn:      query.Get("id_column"),
+		IDPrefix:      query.Get("id_prefix"),
+		ContentColumn: query.Get("content_column"),
+		LinkColumn:    query.Get("link_column"),
+		LinkType:      query.Get("link_type"),
+		LinkPrefix:    query.Get("link_prefix"),
+		ChangedBy:     query.Get("changed_by"),
+	}
+	if opts.Type == "" || opts.IDColumn == "" {
+		http.Error(w, "type and id_column parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := importer.ImportCSV(r.Context(), s.repo, r.Body, opts)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // ============== Subscription Handlers ==============
 
 // CreateSubscription handles POST /api/subscriptions
 func (s *Server) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 	if s.subMgr == nil {
-		http.Error(w, "subscription manager not initialized", http.StatusServiceUnavailable)
+		notEnabled(w, "subscription manager not initialized")
 		return
 	}
 
@@ -995,7 +2148,7 @@ func (s *Server) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 // ListSubscriptions handles GET /api/subscriptions
 func (s *Server) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
 	if s.subMgr == nil {
-		http.Error(w, "subscription manager not initialized", http.StatusServiceUnavailable)
+		notEnabled(w, "subscription manager not initialized")
 		return
 	}
 
@@ -1010,7 +2163,7 @@ func (s *Server) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
 // GetSubscription handles GET /api/subscriptions/{id}
 func (s *Server) GetSubscription(w http.ResponseWriter, r *http.Request) {
 	if s.subMgr == nil {
-		http.Error(w, "subscription manager not initialized", http.StatusServiceUnavailable)
+		notEnabled(w, "subscription manager not initialized")
 		return
 	}
 
@@ -1028,7 +2181,7 @@ func (s *Server) GetSubscription(w http.ResponseWriter, r *http.Request) {
 // UpdateSubscription handles PATCH /api/subscriptions/{id}
 func (s *Server) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 	if s.subMgr == nil {
-		http.Error(w, "subscription manager not initialized", http.StatusServiceUnavailable)
+		notEnabled(w, "subscription manager not initialized")
 		return
 	}
 
@@ -1042,7 +2195,11 @@ func (s *Server) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 
 	sub, err := s.subMgr.Update(r.Context(), id, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		status := http.StatusBadRequest
+		if errors.Is(err, subscriptions.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
 
@@ -1053,7 +2210,7 @@ func (s *Server) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 // DeleteSubscription handles DELETE /api/subscriptions/{id}
 func (s *Server) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 	if s.subMgr == nil {
-		http.Error(w, "subscription manager not initialized", http.StatusServiceUnavailable)
+		notEnabled(w, "subscription manager not initialized")
 		return
 	}
 
@@ -1065,3 +2222,32 @@ func (s *Server) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListSlowQueries handles GET /api/admin/slow-queries
+// Returns recent repository reads that exceeded the slow-query threshold, most recent first
+func (s *Server) ListSlowQueries(w http.ResponseWriter, r *http.Request) {
+	if s.slowLog == nil {
+		notEnabled(w, "slow-query log not enabled (set MEMEX_SLOW_QUERY_MS)")
+		return
+	}
+
+	entries := s.slowLog.Entries()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"threshold_ms": s.slowLog.Threshold().Milliseconds(),
+		"queries":      entries,
+		"count":        len(entries),
+	})
+}
+
+// ClearSlowQueries handles DELETE /api/admin/slow-queries
+func (s *Server) ClearSlowQueries(w http.ResponseWriter, r *http.Request) {
+	if s.slowLog == nil {
+		notEnabled(w, "slow-query log not enabled (set MEMEX_SLOW_QUERY_MS)")
+		return
+	}
+
+	s.slowLog.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -280,3 +280,44 @@ func TestParsePagination(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTimeParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "rfc3339", value: "2025-11-17T10:00:00Z"},
+		{name: "date only", value: "2025-11-17"},
+		{name: "invalid", value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTimeParam(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseTimeParam(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: `"node:a:v2"`, want: true},
+		{header: `W/"node:a:v2"`, want: true},
+		{header: `"node:a:v1", "node:a:v2"`, want: true},
+		{header: `"node:a:v1"`, want: false},
+		{header: "*", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"node:a:v2"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/hooks"
+)
+
+// maxHookPayload caps the body accepted by POST /api/hooks/{token}
+const maxHookPayload = 1 << 20
+
+// HookView is a hook as the admin endpoints return it: the secret and
+// token hash are never sent back
+type HookView struct {
+	*hooks.Hook
+	Signed bool   `json:"signed"`          // Payloads must carry an HMAC signature
+	Token  string `json:"token,omitempty"` // Only when created or rotated
+	URL    string `json:"url,omitempty"`   // Only when created or rotated
+}
+
+// UpdateHookRequest is the request body for PUT /api/admin/hooks/{id}
+type UpdateHookRequest struct {
+	hooks.Hook
+	ClearSecret bool `json:"clear_secret,omitempty"` // Stop requiring signatures
+}
+
+// TestHookRequest is the request body for POST /api/admin/hooks/{id}/test
+type TestHookRequest struct {
+	Payload interface{}       `json:"payload"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// hookView hides a hook's secrets, attaching a fresh token and its URL
+func (s *Server) hookView(r *http.Request, h *hooks.Hook, token string) HookView {
+	view := HookView{Signed: h.Secret != ""}
+	hidden := *h
+	hidden.Secret, hidden.TokenHash = "", ""
+	view.Hook = &hidden
+	if token != "" {
+		view.Token = token
+		view.URL = s.BaseURL(r) + "/api/hooks/" + token
+	}
+	return view
+}
+
+// ReceiveHook handles POST /api/hooks/{token}
+// External services (GitHub webhooks, Zapier, IFTTT, ...) post JSON here,
+// or a form whose payload field holds JSON. The hook the token belongs to
+// maps it into nodes and links; with a secret, the body must be signed in
+// X-Hub-Signature-256 or X-Memex-Signature.
+func (s *Server) ReceiveHook(w http.ResponseWriter, r *http.Request) {
+	hook, err := hooks.ByToken(r.Context(), s.repo, chi.URLParam(r, "token"))
+	if err != nil {
+		http.Error(w, err.Error(), hookStatus(err))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookPayload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	signature := r.Header.Get("X-Hub-Signature-256")
+	if signature == "" {
+		signature = r.Header.Get("X-Memex-Signature")
+	}
+	if err := hook.Verify(body, signature); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// Form posts (GitHub's form content type, some IFTTT actions) carry the
+	// JSON in a payload field
+	data := body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(body)); err == nil && form.Has("payload") {
+			data = []byte(form.Get("payload"))
+		}
+	}
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		http.Error(w, "invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := hook.Apply(r.Context(), s.repo, hooks.Request{Payload: payload, Headers: r.Header, Received: time.Now()}, false)
+
+	status := http.StatusOK
+	if res.NodesCreated+res.NodesUpdated+res.LinksCreated > 0 {
+		status = http.StatusCreated
+	} else if len(res.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+// CreateHook handles POST /api/admin/hooks
+// The response carries the hook's URL; it is not shown again.
+func (s *Server) CreateHook(w http.ResponseWriter, r *http.Request) {
+	var hook hooks.Hook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if hook.ID != "" {
+		if _, err := hooks.Get(r.Context(), s.repo, hook.ID); err == nil {
+			http.Error(w, "hook already exists: "+hook.ID, http.StatusConflict)
+			return
+		}
+	}
+	token, err := hooks.Save(r.Context(), s.repo, &hook)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s.hookView(r, &hook, token))
+}
+
+// ListHooks handles GET /api/admin/hooks
+func (s *Server) ListHooks(w http.ResponseWriter, r *http.Request) {
+	list, err := hooks.List(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	views := make([]HookView, 0, len(list))
+	for _, hook := range list {
+		views = append(views, s.hookView(r, hook, ""))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hooks": views,
+		"count": len(views),
+	})
+}
+
+// GetHook handles GET /api/admin/hooks/{id}
+func (s *Server) GetHook(w http.ResponseWriter, r *http.Request) {
+	hook, err := hooks.Get(r.Context(), s.repo, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), hookStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.hookView(r, hook, ""))
+}
+
+// UpdateHook handles PUT /api/admin/hooks/{id}
+// Replaces the hook's definition; its URL stays the same. Nodes and links
+// it already made stay.
+func (s *Server) UpdateHook(w http.ResponseWriter, r *http.Request) {
+	var req UpdateHookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hook := req.Hook
+	hook.ID = chi.URLParam(r, "id")
+	if err := hooks.Update(r.Context(), s.repo, &hook, req.ClearSecret); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, hookStatus(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.hookView(r, &hook, ""))
+}
+
+// DeleteHook handles DELETE /api/admin/hooks/{id}
+func (s *Server) DeleteHook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := hooks.Delete(r.Context(), s.repo, id); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, hookStatus(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": id,
+	})
+}
+
+// RotateHookToken handles POST /api/admin/hooks/{id}/rotate
+// Issues a new URL for the hook; the old one stops working.
+func (s *Server) RotateHookToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	token, err := hooks.RotateToken(r.Context(), s.repo, id)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, hookStatus(err)))
+		return
+	}
+	hook, err := hooks.Get(r.Context(), s.repo, id)
+	if err != nil {
+		http.Error(w, err.Error(), hookStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.hookView(r, hook, token))
+}
+
+// TestHook handles POST /api/admin/hooks/{id}/test
+// Renders a sample payload through the hook's templates and returns the
+// nodes and links it would write, with any template errors. Nothing is
+// written and no signature is needed.
+func (s *Server) TestHook(w http.ResponseWriter, r *http.Request) {
+	hook, err := hooks.Get(r.Context(), s.repo, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), hookStatus(err))
+		return
+	}
+	var req TestHookRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHookPayload)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	headers := http.Header{}
+	for name, value := range req.Headers {
+		headers.Set(name, value)
+	}
+
+	res := hook.Apply(r.Context(), s.repo, hooks.Request{Payload: req.Payload, Headers: headers, Received: time.Now()}, true)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// hookStatus maps hook errors to HTTP statuses
+func hookStatus(err error) int {
+	if errors.Is(err, hooks.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/sandbox"
+)
+
+// maxHypotheticalEdges caps the edges one query may assume
+const maxHypotheticalEdges = 100
+
+// HypotheticalEdge is a link assumed to exist for a single query
+type HypotheticalEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// withHypothetical returns the graph as it would be with the edges in
+// ?hypothetical= (a JSON array), or s.repo when none are given. The edges
+// live in a throwaway overlay and carry hypothetical: true; edges that
+// already exist are left as they are.
+func (s *Server) withHypothetical(r *http.Request) (graph.Repository, error) {
+	raw := r.URL.Query().Get("hypothetical")
+	if raw == "" {
+		return s.repo, nil
+	}
+	var edges []HypotheticalEdge
+	if err := json.Unmarshal([]byte(raw), &edges); err != nil {
+		return nil, fmt.Errorf("invalid hypothetical edges: %w", err)
+	}
+	if len(edges) > maxHypotheticalEdges {
+		return nil, fmt.Errorf("at most %d hypothetical edges", maxHypotheticalEdges)
+	}
+
+	ctx := r.Context()
+	view := sandbox.NewOverlay(s.repo)
+	now := time.Now()
+	for _, e := range edges {
+		if e.Source == "" || e.Target == "" || e.Type == "" {
+			return nil, fmt.Errorf("hypothetical edges need source, target and type")
+		}
+		existing, err := view.GetLinks(ctx, e.Source)
+		if err != nil {
+			return nil, err
+		}
+		if hasLink(existing, e) {
+			continue
+		}
+		link := &core.Link{
+			Source:   e.Source,
+			Target:   e.Target,
+			Type:     e.Type,
+			Meta:     map[string]interface{}{"hypothetical": true},
+			Created:  now,
+			Modified: now,
+		}
+		if err := view.CreateLink(ctx, link); err != nil {
+			return nil, err
+		}
+	}
+	return view, nil
+}
+
+func hasLink(links []*core.Link, e HypotheticalEdge) bool {
+	for _, l := range links {
+		if l.Source == e.Source && l.Target == e.Target && l.Type == e.Type {
+			return true
+		}
+	}
+	return false
+}
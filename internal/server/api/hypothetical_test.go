@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestHypotheticalEdges(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	s := New(repo, nil)
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "b", Target: "c", Type: "RELATED", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	subgraph := func(hypothetical string) (int, *graph.Subgraph) {
+		q := url.Values{"start": {"a"}, "depth": {"3"}}
+		if hypothetical != "" {
+			q.Set("hypothetical", hypothetical)
+		}
+		w := httptest.NewRecorder()
+		s.QuerySubgraph(w, httptest.NewRequest("GET", "/api/query/subgraph?"+q.Encode(), nil))
+		var sub graph.Subgraph
+		json.NewDecoder(w.Body).Decode(&sub)
+		return w.Code, &sub
+	}
+
+	if _, sub := subgraph(""); len(sub.Nodes) != 1 {
+		t.Errorf("without hypothetical edges a reaches %d nodes", len(sub.Nodes))
+	}
+	code, sub := subgraph(`[{"source":"a","target":"b","type":"RELATED"}]`)
+	if code != http.StatusOK || len(sub.Nodes) != 3 || len(sub.Edges) != 2 {
+		t.Fatalf("with a->b: %d, %d nodes, %d edges", code, len(sub.Nodes), len(sub.Edges))
+	}
+	for _, e := range sub.Edges {
+		if hyp := e.Meta["hypothetical"] == true; hyp != (e.Source == "a") {
+			t.Errorf("edge %s->%s hypothetical = %v", e.Source, e.Target, hyp)
+		}
+	}
+	if links, _ := repo.GetLinks(ctx, "a"); len(links) != 0 {
+		t.Errorf("hypothetical edge persisted: %+v", links)
+	}
+	if code, _ := subgraph(`[{"source":"a","target":"missing","type":"RELATED"}]`); code != http.StatusBadRequest {
+		t.Errorf("edge to a missing node: status %d", code)
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/ingest"
+)
+
+// SetIngestTracker enables the explicit ingest completion endpoint
+func (s *Server) SetIngestTracker(t *ingest.Tracker) {
+	s.ingestTracker = t
+}
+
+// CompleteIngest handles POST /api/ingest/{id}/complete
+// External pipelines call it once they have finished deriving nodes from a
+// source, firing the completion webhook without waiting for it to settle.
+func (s *Server) CompleteIngest(w http.ResponseWriter, r *http.Request) {
+	if s.ingestTracker == nil {
+		notEnabled(w, "ingest completion webhook is not configured")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if !s.ingestTracker.Complete(id) {
+		http.Error(w, fmt.Sprintf("no pending ingest for source: %s", id), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"source_id": id,
+		"status":    "completed",
+	})
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxCleanupJobs is how many finished cleanup jobs are kept for status queries
+const maxCleanupJobs = 100
+
+// Cleanup job states
+const (
+	CleanupQueued  = "queued"
+	CleanupRunning = "running"
+	CleanupDone    = "done"
+	CleanupFailed  = "failed"
+)
+
+// CleanupJob is a queued integrity repair
+type CleanupJob struct {
+	ID      string                        `json:"id"`
+	Status  string                        `json:"status"`
+	Options graph.IntegrityCleanupOptions `json:"options"`
+	Created time.Time                     `json:"created"`
+	Result  *graph.IntegrityCleanup       `json:"result,omitempty"`
+	Error   string                        `json:"error,omitempty"`
+
+	exclude []string // Node types never treated as orphans
+}
+
+// cleanupQueue runs integrity cleanups one at a time in the background
+type cleanupQueue struct {
+	mu    sync.Mutex
+	jobs  map[string]*CleanupJob
+	order []string // Job IDs, oldest first
+	queue chan *CleanupJob
+	start sync.Once
+
+	closed bool          // Set by finish; no more jobs are accepted
+	done   chan struct{} // Closed when the worker exits
+}
+
+// SetIntegrityExcludeTypes sets the node types never reported as orphans
+func (s *Server) SetIntegrityExcludeTypes(types []string) {
+	s.integrityExclude = types
+}
+
+// integrityExcludeTypes returns the configured exclusions, or the defaults
+func (s *Server) integrityExcludeTypes() []string {
+	if s.integrityExclude != nil {
+		return s.integrityExclude
+	}
+	return graph.DefaultIntegrityExcludeTypes
+}
+
+// CheckIntegrity handles GET /api/graph/integrity
+// Reports orphan nodes, links to missing or tombstoned nodes and broken
+// version chains. ?exclude_type= (repeatable) overrides the configured
+// orphan exclusions; ?limit= caps each list (default 100).
+func (s *Server) CheckIntegrity(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	exclude := query["exclude_type"]
+	if len(exclude) == 0 {
+		exclude = s.integrityExcludeTypes()
+	}
+
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 10000 {
+			http.Error(w, "invalid limit parameter (1-10000)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	report, err := s.repo.CheckIntegrity(r.Context(), graph.IntegrityOptions{ExcludeTypes: exclude, Limit: limit})
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// RecomputeDegrees handles POST /api/admin/recompute-degrees
+// Rebuilds stored node degrees from the links, a batch of nodes at a time,
+// and reports the nodes that had drifted. ?dry_run=true only reports;
+// ?batch= sets the nodes per batch (default 1000); ?limit= caps the listed
+// discrepancies (default 100).
+func (s *Server) RecomputeDegrees(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := graph.DegreeRepairOptions{DryRun: query.Get("dry_run") == "true", Limit: 100}
+
+	if b := query.Get("batch"); b != "" {
+		n, err := strconv.Atoi(b)
+		if err != nil || n < 1 || n > 10000 {
+			http.Error(w, "invalid batch parameter (1-10000)", http.StatusBadRequest)
+			return
+		}
+		opts.BatchSize = n
+	}
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 10000 {
+			http.Error(w, "invalid limit parameter (1-10000)", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = n
+	}
+
+	report, err := s.repo.RecomputeDegrees(r.Context(), opts)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// EnqueueIntegrityCleanup handles POST /api/graph/integrity/cleanup
+// Queues a background repair: {"links": true} deletes dangling links and
+// {"orphans": true} tombstones orphan nodes. Responds 202 with the job.
+func (s *Server) EnqueueIntegrityCleanup(w http.ResponseWriter, r *http.Request) {
+	var opts graph.IntegrityCleanupOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !opts.Links && !opts.Orphans {
+		http.Error(w, "nothing to clean up (set links and/or orphans)", http.StatusBadRequest)
+		return
+	}
+
+	job, ok := s.cleanups.enqueue(s.repo, opts, s.integrityExcludeTypes())
+	if !ok {
+		http.Error(w, "too many cleanup jobs queued", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", s.basePath+"/api/graph/integrity/cleanup/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetIntegrityCleanup handles GET /api/graph/integrity/cleanup/{id}
+func (s *Server) GetIntegrityCleanup(w http.ResponseWriter, r *http.Request) {
+	job := s.cleanups.get(chi.URLParam(r, "id"))
+	if job == nil {
+		http.Error(w, "cleanup job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// FinishJobs stops accepting background jobs and waits for the queued
+// ones to complete. Call it once the HTTP server has shut down.
+func (s *Server) FinishJobs() {
+	s.cleanups.finish()
+}
+
+// enqueue records a job and hands it to the worker, starting it on first
+// use; it reports false when the queue is full
+func (q *cleanupQueue) enqueue(repo graph.Repository, opts graph.IntegrityCleanupOptions, exclude []string) (CleanupJob, bool) {
+	q.start.Do(func() {
+		q.jobs = make(map[string]*CleanupJob)
+		q.queue = make(chan *CleanupJob, maxCleanupJobs)
+		q.done = make(chan struct{})
+		go q.work(repo)
+	})
+
+	job := &CleanupJob{ID: uuid.New().String(), Status: CleanupQueued, Options: opts, Created: time.Now(), exclude: exclude}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return CleanupJob{}, false
+	}
+	select {
+	case q.queue <- job:
+	default:
+		return CleanupJob{}, false
+	}
+
+	q.jobs[job.ID] = job
+	q.order = append(q.order, job.ID)
+	for len(q.order) > maxCleanupJobs {
+		if old := q.jobs[q.order[0]]; old.Status == CleanupQueued || old.Status == CleanupRunning {
+			break
+		}
+		delete(q.jobs, q.order[0])
+		q.order = q.order[1:]
+	}
+	return *job, true
+}
+
+// finish stops accepting jobs and waits for the queued ones to run
+func (q *cleanupQueue) finish() {
+	q.start.Do(func() {}) // A queue never started has nothing to run
+	q.mu.Lock()
+	if q.closed || q.queue == nil {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.queue)
+	q.mu.Unlock()
+	<-q.done
+}
+
+// get returns a copy of a job's current state
+func (q *cleanupQueue) get(id string) *CleanupJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil
+	}
+	snapshot := *job
+	return &snapshot
+}
+
+// work runs queued cleanups in order
+func (q *cleanupQueue) work(repo graph.Repository) {
+	defer close(q.done)
+	for job := range q.queue {
+		q.setStatus(job, CleanupRunning, nil, nil)
+		result, err := graph.CleanupIntegrity(context.Background(), repo, job.Options, job.exclude)
+		if err != nil {
+			log.Printf("Integrity cleanup %s failed: %v", job.ID, err)
+			q.setStatus(job, CleanupFailed, nil, err)
+			continue
+		}
+		q.setStatus(job, CleanupDone, result, nil)
+	}
+}
+
+func (q *cleanupQueue) setStatus(job *CleanupJob, status string, result *graph.IntegrityCleanup, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.Status = status
+	job.Result = result
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestFinishJobs(t *testing.T) {
+	s := New(graph.NewMemory(), nil)
+	s.FinishJobs() // Nothing queued yet
+
+	s = New(graph.NewMemory(), nil)
+	enqueue := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.EnqueueIntegrityCleanup(w, httptest.NewRequest("POST", "/api/graph/integrity/cleanup", strings.NewReader(`{"links": true}`)))
+		return w
+	}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		w := enqueue()
+		var job CleanupJob
+		if w.Code != http.StatusAccepted || json.NewDecoder(w.Body).Decode(&job) != nil {
+			t.Fatalf("enqueue = %d %s", w.Code, w.Body)
+		}
+		ids = append(ids, job.ID)
+	}
+
+	s.FinishJobs()
+	for _, id := range ids {
+		if job := s.cleanups.get(id); job == nil || job.Status != CleanupDone {
+			t.Errorf("job %s after FinishJobs = %+v", id, job)
+		}
+	}
+	if w := enqueue(); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("enqueue after FinishJobs = %d", w.Code)
+	}
+}
+
+func TestRecomputeDegreesETag(t *testing.T) {
+	repo, revision := graph.WithRevision(graph.NewMemory())
+	s := New(repo, nil)
+	s.SetRevision(revision)
+
+	mapETag := func() string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.GraphMap(w, httptest.NewRequest("GET", "/api/graph/map", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("graph map = %d %s", w.Code, w.Body)
+		}
+		return w.Header().Get("ETag")
+	}
+	repair := func(url string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.RecomputeDegrees(w, httptest.NewRequest("POST", url, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s = %d %s", url, w.Code, w.Body)
+		}
+	}
+
+	before := mapETag()
+	repair("/api/admin/recompute-degrees?dry_run=true")
+	if got := mapETag(); got != before {
+		t.Errorf("dry run changed ETag %s -> %s", before, got)
+	}
+	repair("/api/admin/recompute-degrees")
+	after := mapETag()
+	if after == before {
+		t.Error("degree repair kept the graph ETag")
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/graph/map", nil)
+	req.Header.Set("If-None-Match", before)
+	s.GraphMap(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match after repair = %d, want 200", w.Code)
+	}
+}
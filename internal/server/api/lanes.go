@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/systemshift/memex/internal/server/lanes"
+)
+
+// laneHeader asks for a request's lane, and reports the lane it ran in
+const laneHeader = "X-Memex-Lane"
+
+// bulkRoutes go in the bulk lane unless the credential or header says
+// otherwise: batch writes, imports and exports
+var bulkRoutes = regexp.MustCompile(`^/((sandboxes/[^/]+/)?(ingest|nodes|links)/bulk|import|export)(/|$)`)
+
+// SetLanes enables priority lanes, with keyLanes mapping API keys to the
+// lane their requests use
+func (s *Server) SetLanes(l *lanes.Limiter, keyLanes map[string]string) {
+	s.lanes = l
+	s.keyLanes = keyLanes
+}
+
+// Lanes is middleware that runs each request in its lane, so bulk traffic
+// waits for bulk slots and interactive traffic for its own. A request
+// whose lane is full for too long gets 503 with Retry-After.
+func (s *Server) Lanes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.lanes == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Event streams stay open for as long as the client listens
+		if strings.HasSuffix(routePath(r), "/events/stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		lane := s.requestLane(r)
+		release, err := s.lanes.Acquire(r.Context(), lane)
+		if err != nil {
+			if errors.Is(err, lanes.ErrQueueFull) || errors.Is(err, lanes.ErrWait) {
+				w.Header().Set("Retry-After", "1")
+				writeError(w, r, http.StatusServiceUnavailable, CodeBackendUnavailable, err.Error()+" "+lane, map[string]interface{}{
+					"lane": lane,
+				})
+			}
+			// A cancelled or timed-out request is answered by Timeout
+			return
+		}
+		defer release()
+		w.Header().Set(laneHeader, lane)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestLane classifies a request: a lane set on its access token,
+// signing key or API key, else the X-Memex-Lane header, else bulk for bulkRoutes and
+// interactive otherwise. A credential in the bulk lane cannot ask for the
+// interactive one.
+func (s *Server) requestLane(r *http.Request) string {
+	credential := ""
+	if tok := requestToken(r.Context()); tok != nil {
+		credential = tok.Scope.Lane
+	} else if keyID := requestSigner(r.Context()); keyID != "" {
+		credential = s.signingScope(keyID).Lane
+	} else if key := requestKey(r); key != "" {
+		credential = s.keyLanes[key]
+	}
+	asked := r.Header.Get(laneHeader)
+	switch {
+	case credential == lanes.Bulk:
+		return lanes.Bulk
+	case lanes.Valid(asked):
+		return asked
+	case credential != "":
+		return credential
+	case bulkRoutes.MatchString(routePath(r)):
+		return lanes.Bulk
+	}
+	return lanes.Interactive
+}
+
+// GetLanes handles GET /api/admin/lanes
+// Returns each lane's limit, requests served and waiting, and refusals.
+func (s *Server) GetLanes(w http.ResponseWriter, r *http.Request) {
+	if s.lanes == nil {
+		notEnabled(w, "priority lanes are disabled")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.lanes.Stats())
+}
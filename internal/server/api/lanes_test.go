@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/lanes"
+	"github.com/systemshift/memex/internal/server/tokens"
+)
+
+func TestRequestLane(t *testing.T) {
+	s := New(graph.NewMemory(), nil)
+	s.SetLanes(lanes.New(lanes.Config{}), map[string]string{"backfill": lanes.Bulk, "ui": lanes.Interactive})
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		token  string // Lane on the request's access token
+		header string
+		want   string
+	}{
+		{"read", "GET", "/api/nodes/note:1", "", "", "", lanes.Interactive},
+		{"bulk ingest", "POST", "/api/ingest/bulk", "", "", "", lanes.Bulk},
+		{"sandbox bulk", "POST", "/api/sandboxes/s1/nodes/bulk", "", "", "", lanes.Bulk},
+		{"export", "GET", "/api/export", "", "", "", lanes.Bulk},
+		{"header", "POST", "/api/ingest", "", "", "bulk", lanes.Bulk},
+		{"header overrides route", "POST", "/api/ingest/bulk", "", "", "interactive", lanes.Interactive},
+		{"unknown header", "POST", "/api/ingest/bulk", "", "", "urgent", lanes.Bulk},
+		{"bulk key", "GET", "/api/nodes/note:1", "backfill", "", "interactive", lanes.Bulk},
+		{"interactive key", "POST", "/api/ingest/bulk", "ui", "", "", lanes.Interactive},
+		{"bulk token", "GET", "/api/search", "", lanes.Bulk, "", lanes.Bulk},
+	} {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.key != "" {
+			r.Header.Set("X-API-Key", tc.key)
+		}
+		if tc.header != "" {
+			r.Header.Set(laneHeader, tc.header)
+		}
+		if tc.token != "" {
+			tok := &tokens.Token{Scope: graph.Scope{Lane: tc.token}}
+			r = r.WithContext(context.WithValue(r.Context(), tokenKey{}, tok))
+		}
+		if got := s.requestLane(r); got != tc.want {
+			t.Errorf("%s: lane %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestLanesRejectWhenFull(t *testing.T) {
+	s := New(graph.NewMemory(), nil)
+	s.SetLanes(lanes.New(lanes.Config{Bulk: lanes.LaneConfig{Limit: 1, Queue: -1}}), nil)
+
+	entered, unblock := make(chan struct{}), make(chan struct{})
+	handler := s.Lanes(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Block") != "" {
+			close(entered)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	go func() {
+		r := httptest.NewRequest("POST", "/api/ingest/bulk", nil)
+		r.Header.Set("X-Block", "1")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}()
+	select {
+	case <-entered:
+	case <-time.After(time.Second):
+		t.Fatal("first request never ran")
+	}
+	defer close(unblock)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/import", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("bulk while full: %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Interactive traffic still gets through
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/nodes/note:1", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get(laneHeader) != lanes.Interactive {
+		t.Errorf("interactive while bulk full: %d, lane %q", w.Code, w.Header().Get(laneHeader))
+	}
+}
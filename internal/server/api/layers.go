@@ -0,0 +1,28 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// layerParam reads ?layer= (repeatable or comma-separated); nil selects
+// every layer
+func layerParam(r *http.Request) (graph.LayerFilter, error) {
+	return graph.ParseLayers(r.URL.Query()["layer"])
+}
+
+// LayerStats handles GET /api/graph/layers
+// Counts nodes and links per layer (source, derived, attention, manual),
+// with their types.
+func (s *Server) LayerStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := graph.GetLayerStats(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	graphexport "github.com/systemshift/memex/internal/server/export"
+)
+
+// ExportLinks handles GET /api/export/links
+// Streams the links table (source, target, type, weight, created) as
+// ?format=csv (default) or parquet, for offline analysis in pandas or
+// DuckDB. Filters: type, source_type and target_type (repeatable or
+// comma-separated), layer, since and until on the creation time, and
+// min_weight. Links without a weight property have weight 1.
+func (s *Server) ExportLinks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := graphexport.LinkFormats[name]
+	if !ok {
+		http.Error(w, "unsupported format (use csv or parquet)", http.StatusBadRequest)
+		return
+	}
+
+	filter := graphexport.LinkFilter{
+		Types:       listParam(query["type"]),
+		SourceTypes: listParam(query["source_type"]),
+		TargetTypes: listParam(query["target_type"]),
+	}
+	var err error
+	if filter.Layers, err = layerParam(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(param); v != "" {
+			if *t, err = parseTimeParam(v); err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s parameter (use RFC3339 or YYYY-MM-DD)", param), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if v := query.Get("min_weight"); v != "" {
+		if filter.MinWeight, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "invalid min_weight parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filename := "links-" + time.Now().Format("20060102-150405") + "." + format.Extension
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	// Headers are sent with the first rows, so a later failure can only
+	// cut the file short
+	out := format.New(w)
+	if _, err := graphexport.WriteLinks(r.Context(), s.repo, filter, out); err != nil {
+		log.Printf("Links export failed: %v", err)
+		return
+	}
+	if err := out.Close(); err != nil {
+		log.Printf("Links export failed: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// checkTargetVersion verifies the version a link is pinned to exists
+func (s *Server) checkTargetVersion(ctx context.Context, target string, meta map[string]interface{}) error {
+	v, ok := graph.LinkTargetVersion(target, meta)
+	if !ok {
+		return nil
+	}
+	if _, err := s.repo.GetNodeAtVersion(ctx, target, v); err != nil {
+		return fmt.Errorf("target version %s not found", graph.VersionID(target, v))
+	}
+	return nil
+}
+
+// pinnedParam reads ?versions=: "pinned" resolves nodes to the versions
+// their links are pinned to, "current" (the default) keeps the latest
+func pinnedParam(r *http.Request) (bool, error) {
+	switch v := r.URL.Query().Get("versions"); v {
+	case "", "current":
+		return false, nil
+	case "pinned":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid versions %q: expected current or pinned", v)
+	}
+}
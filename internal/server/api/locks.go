@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/locks"
+)
+
+// LockRequest is the request body for POST /api/nodes/{id}/lock
+type LockRequest struct {
+	Owner string `json:"owner"`
+	TTL   string `json:"ttl,omitempty"` // Go duration; default 5m, max 1h
+}
+
+// lockErrorStatus maps lock errors to HTTP status codes
+func lockErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, locks.ErrLocked):
+		return http.StatusConflict
+	case errors.Is(err, locks.ErrNotHeld):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// LockNode handles POST /api/nodes/{id}/lock
+// Checks a node out for editing, or renews the owner's lock. While locked,
+// PATCH /api/nodes/{id} only accepts changes whose changed_by is the owner.
+func (s *Server) LockNode(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req LockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ttl := locks.DefaultTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil {
+			http.Error(w, "invalid ttl: "+req.TTL, http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+
+	if _, err := s.repo.GetNode(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	lock, err := s.nodeLocks.Acquire(id, req.Owner, ttl, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), lockErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lock)
+}
+
+// UnlockNode handles DELETE /api/nodes/{id}/lock?owner=
+// Releases the owner's lock; ?force=true releases anyone's
+func (s *Server) UnlockNode(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	if err := s.nodeLocks.Release(id, query.Get("owner"), query.Get("force") == "true", time.Now()); err != nil {
+		http.Error(w, err.Error(), lockErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"node_id":  id,
+		"released": true,
+	})
+}
+
+// GetNodeLock handles GET /api/nodes/{id}/lock
+func (s *Server) GetNodeLock(w http.ResponseWriter, r *http.Request) {
+	lock := s.nodeLocks.Get(chi.URLParam(r, "id"), time.Now())
+	if lock == nil {
+		http.Error(w, "node is not locked", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lock)
+}
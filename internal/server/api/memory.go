@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/memory"
+)
+
+// SetMemory enables memory cards
+func (s *Server) SetMemory(store *memory.Store) {
+	s.memory = store
+}
+
+// memoryEnabled reports an error when memory cards are disabled
+func (s *Server) memoryEnabled(w http.ResponseWriter) bool {
+	if s.memory == nil {
+		notEnabled(w, "memory cards are disabled")
+		return false
+	}
+	return true
+}
+
+// memoryStatus maps memory card errors to HTTP status codes
+func memoryStatus(err error) int {
+	switch {
+	case errors.Is(err, memory.ErrNotFound), errors.Is(err, memory.ErrNoNode):
+		return http.StatusNotFound
+	case errors.Is(err, memory.ErrDistill):
+		return http.StatusBadGateway
+	}
+	return writeErrorStatus(err, http.StatusBadRequest)
+}
+
+// GetMemory handles GET /api/memory
+// Returns the active memory cards that fit in ?budget= tokens (default
+// 2000), pinned cards first, with their text joined for a prompt.
+func (s *Server) GetMemory(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	budget := memory.DefaultBudget
+	if v := r.URL.Query().Get("budget"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid budget parameter", http.StatusBadRequest)
+			return
+		}
+		budget = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.memory.Active(budget))
+}
+
+// ListMemoryCards handles GET /api/memory/cards
+// Lists active cards in load order; ?all=true includes expired ones.
+func (s *Server) ListMemoryCards(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	cards := s.memory.List(r.URL.Query().Get("all") == "true")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cards": cards,
+		"count": len(cards),
+	})
+}
+
+// AddMemoryCard handles POST /api/memory/cards
+// Distills a node into a card (or uses the given summary), replacing the
+// node's existing card.
+func (s *Server) AddMemoryCard(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	var req memory.AddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	card, err := s.memory.Add(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), memoryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(card)
+}
+
+// GetMemoryCard handles GET /api/memory/cards/{id}
+func (s *Server) GetMemoryCard(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	card, err := s.memory.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), memoryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// PinMemoryCard handles POST /api/memory/cards/{id}/pin
+func (s *Server) PinMemoryCard(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	card, err := s.memory.Pin(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), memoryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// UnpinMemoryCard handles POST /api/memory/cards/{id}/unpin
+// With ?ttl= (a Go duration) the card expires after that long; without
+// it the card stays active behind pinned ones.
+func (s *Server) UnpinMemoryCard(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	var ttl time.Duration
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid ttl parameter", http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+	card, err := s.memory.Unpin(r.Context(), chi.URLParam(r, "id"), ttl)
+	if err != nil {
+		http.Error(w, err.Error(), memoryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// ExpireMemoryCard handles POST /api/memory/cards/{id}/expire
+// Drops the card from the memory set now; it is kept for re-pinning.
+func (s *Server) ExpireMemoryCard(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	card, err := s.memory.Expire(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), memoryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// RefreshMemoryCard handles POST /api/memory/cards/{id}/refresh
+// Distills the card again from its node's current state.
+func (s *Server) RefreshMemoryCard(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	card, err := s.memory.Refresh(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), memoryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// DeleteMemoryCard handles DELETE /api/memory/cards/{id}
+func (s *Server) DeleteMemoryCard(w http.ResponseWriter, r *http.Request) {
+	if !s.memoryEnabled(w) {
+		return
+	}
+	if err := s.memory.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), memoryStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
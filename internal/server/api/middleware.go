@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig configures cross-origin access for browser-based frontends
+type CORSConfig struct {
+	AllowedOrigins   []string // Exact origins, or "*" for any
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int // Preflight cache duration in seconds
+}
+
+// Validate rejects configurations that would let any site make credentialed requests
+func (c CORSConfig) Validate() error {
+	if c.AllowCredentials && originListed(c.AllowedOrigins, "*") {
+		return errors.New("CORS credentials cannot be combined with a wildcard origin; list origins explicitly")
+	}
+	return nil
+}
+
+// CORS returns middleware that answers preflight requests and sets
+// Access-Control-* headers for allowed origins. Explicitly listed origins are
+// echoed back (with credentials if enabled); a wildcard allows any origin
+// with a literal "*" and never with credentials.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			switch {
+			case originListed(cfg.AllowedOrigins, origin):
+				h.Set("Access-Control-Allow-Origin", origin)
+				if cfg.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			case originListed(cfg.AllowedOrigins, "*"):
+				h.Set("Access-Control-Allow-Origin", "*")
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.Set("Access-Control-Expose-Headers", "ETag, Location, Idempotent-Replayed")
+
+			// Preflight
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				h.Set("Access-Control-Allow-Methods", methods)
+				if headers != "" {
+					h.Set("Access-Control-Allow-Headers", headers)
+				} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+					h.Set("Access-Control-Allow-Headers", requested)
+				}
+				if cfg.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// originListed reports whether origin appears literally in the allow list
+func originListed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForwardedHeaders returns middleware that applies X-Forwarded-Proto,
+// X-Forwarded-Host and X-Forwarded-Prefix from a trusted reverse proxy,
+// so URLs built with BaseURL point at the public address
+func ForwardedHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if proto := firstForwardedValue(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			r.URL.Scheme = proto
+		}
+		if host := firstForwardedValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+			r.Host = host
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// firstForwardedValue returns the client-most value of a comma-separated forwarded header
+func firstForwardedValue(value string) string {
+	if i := strings.Index(value, ","); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// BaseURL returns the externally visible base URL of the server,
+// including any base path or proxy prefix (no trailing slash)
+func (s *Server) BaseURL(r *http.Request) string {
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+
+	prefix := s.basePath
+	if s.trustProxy {
+		if p := firstForwardedValue(r.Header.Get("X-Forwarded-Prefix")); p != "" {
+			prefix = NormalizeBasePath(p) + prefix
+		}
+	}
+
+	return scheme + "://" + r.Host + prefix
+}
+
+// NormalizeBasePath cleans a base path to the form "/memex", or "" for the root
+func NormalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	handler := CORS(CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		preflight  bool
+		wantStatus int
+		wantOrigin string
+	}{
+		{"allowed origin", "GET", "https://app.example.com", false, http.StatusOK, "https://app.example.com"},
+		{"disallowed origin", "GET", "https://evil.example.com", false, http.StatusOK, ""},
+		{"no origin", "GET", "", false, http.StatusOK, ""},
+		{"preflight", "OPTIONS", "https://app.example.com", true, http.StatusNoContent, "https://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/nodes", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	handler := CORS(CORSConfig{
+		AllowedOrigins:   []string{"*", "https://app.example.com"},
+		AllowedMethods:   []string{"GET"},
+		AllowCredentials: false,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/nodes", nil)
+	req.Header.Set("Origin", "https://other.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+
+	req = httptest.NewRequest("GET", "/api/nodes", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("listed origin: Access-Control-Allow-Origin = %q", got)
+	}
+}
+
+func TestCORSConfigValidate(t *testing.T) {
+	if err := (CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}).Validate(); err == nil {
+		t.Error("expected wildcard with credentials to be rejected")
+	}
+	if err := (CORSConfig{AllowedOrigins: []string{"*"}}).Validate(); err != nil {
+		t.Errorf("wildcard without credentials: %v", err)
+	}
+	if err := (CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true}).Validate(); err != nil {
+		t.Errorf("listed origin with credentials: %v", err)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	s := &Server{}
+	s.SetProxyConfig("memex/", true)
+
+	req := httptest.NewRequest("GET", "/memex/api/nodes", nil)
+	ForwardedHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+	})).ServeHTTP(httptest.NewRecorder(), withHeaders(req, map[string]string{
+		"X-Forwarded-Proto":  "https",
+		"X-Forwarded-Host":   "kb.example.com, internal:8080",
+		"X-Forwarded-Prefix": "/tools",
+	}))
+
+	if got, want := s.BaseURL(req), "https://kb.example.com/tools/memex"; got != want {
+		t.Errorf("BaseURL() = %q, want %q", got, want)
+	}
+}
+
+func withHeaders(r *http.Request, headers map[string]string) *http.Request {
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/modules"
+)
+
+// SetModules enables the module management endpoints
+func (s *Server) SetModules(reg *modules.Registry) {
+	s.modules = reg
+}
+
+// ModuleToggleRequest is the body of POST /api/modules/{name}/enable and
+// /disable; an empty namespace applies to every namespace
+type ModuleToggleRequest struct {
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// moduleStatus maps registry errors to HTTP statuses
+func moduleStatus(err error) int {
+	switch {
+	case errors.Is(err, modules.ErrNotFound), errors.Is(err, modules.ErrUnknownCommand):
+		return http.StatusNotFound
+	case errors.Is(err, modules.ErrInvalidArgs):
+		return http.StatusBadRequest
+	}
+	return writeErrorStatus(err, http.StatusInternalServerError)
+}
+
+// ListModules handles GET /api/modules
+// Lists the registered processors, their commands and where they are off.
+func (s *Server) ListModules(w http.ResponseWriter, r *http.Request) {
+	if s.modules == nil {
+		notEnabled(w, "modules not initialized")
+		return
+	}
+	list := s.modules.List()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"modules": list, "count": len(list)})
+}
+
+// GetModule handles GET /api/modules/{name}
+func (s *Server) GetModule(w http.ResponseWriter, r *http.Request) {
+	if s.modules == nil {
+		notEnabled(w, "modules not initialized")
+		return
+	}
+	info, err := s.modules.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		http.Error(w, err.Error(), moduleStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// EnableModule handles POST /api/modules/{name}/enable
+func (s *Server) EnableModule(w http.ResponseWriter, r *http.Request) {
+	s.toggleModule(w, r, true)
+}
+
+// DisableModule handles POST /api/modules/{name}/disable
+// Stops the module handling events for nodes in the namespace, or for all
+// nodes. Its commands still run on request.
+func (s *Server) DisableModule(w http.ResponseWriter, r *http.Request) {
+	s.toggleModule(w, r, false)
+}
+
+func (s *Server) toggleModule(w http.ResponseWriter, r *http.Request, enabled bool) {
+	if s.modules == nil {
+		notEnabled(w, "modules not initialized")
+		return
+	}
+	var req ModuleToggleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	info, err := s.modules.SetEnabled(r.Context(), chi.URLParam(r, "name"), req.Namespace, enabled)
+	if err != nil {
+		http.Error(w, err.Error(), moduleStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// RunModuleCommand handles POST /api/modules/{name}/commands/{command}
+// The body is the command's arguments as a JSON object.
+func (s *Server) RunModuleCommand(w http.ResponseWriter, r *http.Request) {
+	if s.modules == nil {
+		notEnabled(w, "modules not initialized")
+		return
+	}
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body: arguments must be a JSON object", http.StatusBadRequest)
+		return
+	}
+	name, command := chi.URLParam(r, "name"), chi.URLParam(r, "command")
+	result, err := s.modules.Run(r.Context(), name, command, args)
+	if err != nil {
+		http.Error(w, err.Error(), moduleStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"module":  name,
+		"command": command,
+		"result":  result,
+	})
+}
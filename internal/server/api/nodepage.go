@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxPageLinks caps the links and backlinks listed on a node page
+const maxPageLinks = 200
+
+// maxShortIDMatches caps the candidates listed for an ambiguous short ID
+const maxShortIDMatches = 20
+
+// pageLink is a link shown on a node page
+type pageLink struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Kind  string `json:"node_type,omitempty"`
+}
+
+// nodePage is the JSON form of a node page
+type nodePage struct {
+	URL       string             `json:"url"` // Stable link to this page
+	Node      *core.Node         `json:"node"`
+	Label     string             `json:"label"`
+	Content   string             `json:"content,omitempty"`
+	Binary    int                `json:"binary_bytes,omitempty"` // Size of non-text content, which is not shown
+	Links     []pageLink         `json:"links"`
+	Backlinks []pageLink         `json:"backlinks"`
+	History   []core.VersionInfo `json:"history"`
+}
+
+// ResolveNodeID handles GET /api/nodes/resolve?id=<short ID>
+// Returns the full IDs a short ID may name; id is set when exactly one does.
+func (s *Server) ResolveNodeID(w http.ResponseWriter, r *http.Request) {
+	short := r.URL.Query().Get("id")
+	if short == "" {
+		http.Error(w, "query parameter 'id' is required", http.StatusBadRequest)
+		return
+	}
+	matches, err := graph.ResolveShortID(r.Context(), s.repo, short, maxShortIDMatches+1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{
+		"query":     short,
+		"matches":   append([]string{}, matches...),
+		"ambiguous": len(matches) > 1,
+	}
+	if len(matches) > maxShortIDMatches {
+		resp["matches"] = matches[:maxShortIDMatches]
+		resp["truncated"] = true
+	}
+	if len(matches) == 1 {
+		resp["id"] = matches[0]
+		resp["url"] = s.BaseURL(r) + "/n/" + url.PathEscape(matches[0])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(matches) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ViewNode handles GET /n/{id}
+// Renders a node's content, properties, links, backlinks and history at a
+// stable URL. A short ID naming one node redirects to the node's full URL;
+// one naming several lists them. ?format=json returns JSON.
+func (s *Server) ViewNode(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	node, err := s.repo.GetNode(r.Context(), id)
+	if err != nil {
+		matches, err := graph.ResolveShortID(r.Context(), s.repo, id, maxShortIDMatches)
+		switch {
+		case err != nil || len(matches) == 0:
+			http.NotFound(w, r)
+		case len(matches) == 1:
+			http.Redirect(w, r, s.BaseURL(r)+"/n/"+url.PathEscape(matches[0]), http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusMultipleChoices)
+			nodePageTemplate.ExecuteTemplate(w, "choices", map[string]interface{}{"Query": id, "Matches": matches})
+		}
+		return
+	}
+
+	page := nodePage{
+		URL:       s.BaseURL(r) + "/n/" + url.PathEscape(node.ID),
+		Node:      node,
+		Label:     graph.NodeLabel(node.ID, node.Meta),
+		Links:     []pageLink{},
+		Backlinks: []pageLink{},
+	}
+	if utf8.Valid(node.Content) {
+		page.Content = string(node.Content)
+	} else {
+		page.Binary = len(node.Content)
+	}
+	if links, err := s.repo.GetLinks(r.Context(), node.ID); err == nil {
+		page.Links = s.pageLinks(r, links, true)
+	}
+	if backlinks, err := s.repo.GetBacklinks(r.Context(), node.ID); err == nil {
+		page.Backlinks = s.pageLinks(r, backlinks, false)
+	}
+	if history, err := s.repo.GetNodeHistory(r.Context(), node.ID); err == nil {
+		sort.Slice(history, func(i, j int) bool { return history[i].Version > history[j].Version })
+		page.History = history
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	nodePageTemplate.ExecuteTemplate(w, "node", page)
+}
+
+// pageLinks labels the other end of each link, sorted by type and label
+func (s *Server) pageLinks(r *http.Request, links []*core.Link, outgoing bool) []pageLink {
+	out := []pageLink{}
+	for _, l := range links {
+		if len(out) == maxPageLinks {
+			break
+		}
+		other := l.Source
+		if outgoing {
+			other = l.Target
+		}
+		link := pageLink{Type: l.Type, ID: other, Label: other}
+		if n, err := s.repo.GetNode(r.Context(), other); err == nil {
+			link.Label = graph.NodeLabel(n.ID, n.Meta)
+			link.Kind = n.Type
+		}
+		out = append(out, link)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		return out[i].Label < out[j].Label
+	})
+	return out
+}
+
+// nodePageTemplate renders node pages and short ID choices
+var nodePageTemplate = template.Must(template.New("").Funcs(template.FuncMap{
+	"nodeURL": func(id string) string { return "./" + url.PathEscape(id) },
+	"json": func(v interface{}) string {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	},
+}).Parse(`{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>{{.}} · Memex</title>
+<style>
+body { font: 15px/1.5 system-ui, sans-serif; max-width: 52rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h1 { margin-bottom: 0; } .type { color: #777; margin-top: 0; }
+pre { white-space: pre-wrap; background: #f6f6f6; padding: 1rem; border-radius: 4px; }
+table { border-collapse: collapse; } td { padding: 2px 12px 2px 0; vertical-align: top; } td:first-child { color: #777; }
+a { color: #0b5cad; text-decoration: none; } small { color: #777; }
+</style>
+</head>
+<body>
+{{end}}
+{{define "node"}}{{template "head" .Label}}<h1>{{.Label}}</h1>
+<p class="type">{{.Node.Type}} · <a href="{{.URL}}"><code>{{.Node.ID}}</code></a> · version {{.Node.Version}}</p>
+{{if .Content}}<pre>{{.Content}}</pre>{{end}}
+{{if .Binary}}<p><em>Binary content ({{.Binary}} bytes) is not shown.</em></p>{{end}}
+{{if .Node.Meta}}<table>{{range $k, $v := .Node.Meta}}<tr><td>{{$k}}</td><td>{{json $v}}</td></tr>{{end}}</table>{{end}}
+{{if .Links}}<h2>Links</h2>
+<ul>{{range .Links}}<li>→ {{.Type}} <a href="{{nodeURL .ID}}">{{.Label}}</a> <small>{{.Kind}}</small></li>{{end}}</ul>{{end}}
+{{if .Backlinks}}<h2>Backlinks</h2>
+<ul>{{range .Backlinks}}<li>← {{.Type}} <a href="{{nodeURL .ID}}">{{.Label}}</a> <small>{{.Kind}}</small></li>{{end}}</ul>{{end}}
+{{if .History}}<h2>History</h2>
+<table>{{range .History}}<tr><td>v{{.Version}}</td><td>{{.Modified.Format "2006-01-02 15:04 MST"}}</td><td>{{.ChangedBy}}</td><td>{{.ChangeNote}}</td></tr>{{end}}</table>{{end}}
+</body>
+</html>
+{{end}}
+{{define "choices"}}{{template "head" .Query}}<h1>{{.Query}}</h1>
+<p class="type">This short ID matches several nodes:</p>
+<ul>{{range .Matches}}<li><a href="{{nodeURL .}}"><code>{{.}}</code></a></li>{{end}}</ul>
+</body>
+</html>
+{{end}}`))
@@ -0,0 +1,14 @@
+package api
+
+import "github.com/systemshift/memex/internal/server/normalize"
+
+// SetNormalization sets the content normalization applied on ingest, by connector
+func (s *Server) SetNormalization(p *normalize.Policy) {
+	s.normalization = p
+}
+
+// normalize rewrites the request's content with its connector's pipeline
+func (req *IngestRequest) normalize(p *normalize.Policy) {
+	req.normalization = p.For(req.Connector)
+	req.Content = req.normalization.Apply(req.Content)
+}
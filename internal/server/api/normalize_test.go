@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/normalize"
+)
+
+func TestIngestNormalizesByConnector(t *testing.T) {
+	s := New(graph.NewMemory(), nil)
+	policy, err := normalize.ParsePolicy("default=newlines+trailing_whitespace,connector:raw=none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetNormalization(policy)
+
+	ingest := func(body string) IngestResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Ingest(w, httptest.NewRequest("POST", "/api/ingest", strings.NewReader(body)))
+		var resp IngestResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("ingest %s: %d, %v", body, w.Code, err)
+		}
+		return resp
+	}
+
+	first := ingest(`{"content": "same note\n"}`)
+	if first.Content.Normalization != "newlines+trailing_whitespace" || first.Content.Size != 10 {
+		t.Errorf("content = %+v", first.Content)
+	}
+	if again := ingest(`{"content": "same note  \r\n\r\n"}`); again.SourceID != first.SourceID {
+		t.Errorf("CRLF copy stored as %s, want %s", again.SourceID, first.SourceID)
+	}
+	raw := ingest(`{"content": "same note  \r\n", "connector": "raw"}`)
+	if raw.SourceID == first.SourceID || raw.Content.Normalization != "none" || raw.Content.LineEndings != "crlf" {
+		t.Errorf("raw connector = %s %+v", raw.SourceID, raw.Content)
+	}
+
+	w := httptest.NewRecorder()
+	s.Ingest(w, httptest.NewRequest("POST", "/api/ingest", strings.NewReader(`{"content": " \n\n"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("blank after normalization = %d", w.Code)
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/ontology"
+)
+
+// ListTypeDefs handles GET /api/ontology/types
+func (s *Server) ListTypeDefs(w http.ResponseWriter, r *http.Request) {
+	defs, err := ontology.List(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"types": defs,
+		"count": len(defs),
+	})
+}
+
+// GetTypeDef handles GET /api/ontology/types/{type}
+func (s *Server) GetTypeDef(w http.ResponseWriter, r *http.Request) {
+	def, err := ontology.Get(r.Context(), s.repo, chi.URLParam(r, "type"))
+	if err != nil {
+		http.Error(w, err.Error(), ontologyStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(def)
+}
+
+// PutTypeDef handles PUT /api/ontology/types/{type}
+// Creates or replaces a type's definition: a description and the color,
+// icon and size_by hints graph views return for nodes of the type.
+func (s *Server) PutTypeDef(w http.ResponseWriter, r *http.Request) {
+	var def ontology.TypeDef
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	def.Type = chi.URLParam(r, "type")
+	created, err := ontology.Put(r.Context(), s.repo, &def)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(def)
+}
+
+// DeleteTypeDef handles DELETE /api/ontology/types/{type}
+// Nodes of the type are untouched; they are just no longer styled.
+func (s *Server) DeleteTypeDef(w http.ResponseWriter, r *http.Request) {
+	nodeType := chi.URLParam(r, "type")
+	if err := ontology.Delete(r.Context(), s.repo, nodeType); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, ontologyStatus(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": nodeType,
+	})
+}
+
+// typeStyles loads the visualization hints of defined types. Views still
+// render without them, so a failed lookup just leaves them out.
+func (s *Server) typeStyles(r *http.Request) map[string]graph.NodeStyle {
+	styles, err := ontology.Styles(r.Context(), s.repo)
+	if err != nil {
+		return nil
+	}
+	return styles
+}
+
+// ListLinkTypeDefs handles GET /api/ontology/link-types
+func (s *Server) ListLinkTypeDefs(w http.ResponseWriter, r *http.Request) {
+	defs, err := ontology.ListLinkTypes(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"link_types": defs,
+		"count":      len(defs),
+	})
+}
+
+// GetLinkTypeDef handles GET /api/ontology/link-types/{type}
+func (s *Server) GetLinkTypeDef(w http.ResponseWriter, r *http.Request) {
+	def, err := ontology.GetLinkType(r.Context(), s.repo, chi.URLParam(r, "type"))
+	if err != nil {
+		http.Error(w, err.Error(), ontologyStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(def)
+}
+
+// PutLinkTypeDef handles PUT /api/ontology/link-types/{type}
+// Creates or replaces a link type's definition: the node types its links
+// may join, their properties and cardinality limits, and whether links
+// breaking them are rejected. Existing links are not rechecked; see
+// GET /api/ontology/link-violations.
+func (s *Server) PutLinkTypeDef(w http.ResponseWriter, r *http.Request) {
+	var def ontology.LinkTypeDef
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	def.Type = chi.URLParam(r, "type")
+	created, err := ontology.PutLinkType(r.Context(), s.repo, &def)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(def)
+}
+
+// DeleteLinkTypeDef handles DELETE /api/ontology/link-types/{type}
+// Links of the type are untouched; they are just no longer checked.
+func (s *Server) DeleteLinkTypeDef(w http.ResponseWriter, r *http.Request) {
+	linkType := chi.URLParam(r, "type")
+	if err := ontology.DeleteLinkType(r.Context(), s.repo, linkType); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, ontologyStatus(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": linkType,
+	})
+}
+
+// LinkViolations handles GET /api/ontology/link-violations?type=&limit=
+// Checks stored links against their type definitions, enforced or not.
+func (s *Server) LinkViolations(w http.ResponseWriter, r *http.Request) {
+	limit := 1000
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	report, err := ontology.Report(r.Context(), s.repo, r.URL.Query().Get("type"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ontologyStatus maps ontology errors to HTTP statuses
+func ontologyStatus(err error) int {
+	if errors.Is(err, ontology.ErrNotFound) || errors.Is(err, ontology.ErrLinkTypeNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
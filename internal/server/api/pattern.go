@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// PatternRequest is the body of POST /api/query/pattern
+type PatternRequest struct {
+	Pattern string                            `json:"pattern"`
+	Where   map[string]map[string]interface{} `json:"where,omitempty"`  // Variable -> property -> required value
+	Layers  []string                          `json:"layers,omitempty"` // Every bound node must be in one of these layers
+	Limit   int                               `json:"limit,omitempty"`
+}
+
+// QueryPattern handles POST /api/query/pattern
+// Matches a structural pattern such as
+// "A:Person -[WORKS_AT]-> B:Company, A -[KNOWS]-> C:Person" and returns
+// each binding of variables to node IDs along with the bound nodes.
+// With layers set, bindings are kept only when all their nodes are in them.
+func (s *Server) QueryPattern(w http.ResponseWriter, r *http.Request) {
+	var req PatternRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 100
+	}
+	if req.Limit < 1 || req.Limit > 1000 {
+		http.Error(w, "invalid limit (1-1000)", http.StatusBadRequest)
+		return
+	}
+
+	pattern, err := graph.ParsePattern(req.Pattern, req.Where)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	layers, err := graph.ParseLayers(req.Layers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Ask for one extra binding to tell whether the result was cut off. Layer
+	// filtering drops bindings afterwards, so read more to fill the page.
+	want := req.Limit + 1
+	if layers != nil {
+		want = req.Limit*4 + 1
+	}
+	bindings, err := s.repo.MatchPattern(r.Context(), pattern, want)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	// A full read may have left out bindings the filter would keep
+	truncated := layers != nil && len(bindings) == want
+
+	nodes := make(map[string]*core.Node)
+	kept := bindings[:0]
+	for _, b := range bindings {
+		if len(kept) > req.Limit {
+			break
+		}
+		inLayers := true
+		for _, id := range b {
+			node, ok := nodes[id]
+			if !ok {
+				node, err = s.repo.GetNode(r.Context(), id)
+				if err != nil {
+					http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+					return
+				}
+				nodes[id] = node
+			}
+			inLayers = inLayers && layers.Node(node)
+		}
+		if inLayers {
+			kept = append(kept, b)
+		}
+	}
+	bindings = kept
+	if len(bindings) > req.Limit {
+		truncated = true
+		bindings = bindings[:req.Limit]
+	}
+
+	// Only return the nodes of the bindings kept
+	bound := make(map[string]*core.Node)
+	for _, b := range bindings {
+		for _, id := range b {
+			bound[id] = nodes[id]
+		}
+	}
+	nodes = bound
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bindings":  bindings,
+		"nodes":     nodes,
+		"count":     len(bindings),
+		"truncated": truncated,
+	})
+}
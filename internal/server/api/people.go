@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/people"
+)
+
+// maxVCardUpload caps a vCard import request
+const maxVCardUpload = 64 << 20
+
+// CardDAVImportRequest is the request body for POST /api/import/carddav
+type CardDAVImportRequest struct {
+	URL      string `json:"url"` // Address book collection URL
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// ResolvedPerson is a participant matched to a Person node
+type ResolvedPerson struct {
+	Kind  string `json:"kind"` // email or tel
+	Value string `json:"value"`
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+}
+
+// ImportVCard handles POST /api/import/vcard
+// The body is a .vcf file with one or more contacts.
+func (s *Server) ImportVCard(w http.ResponseWriter, r *http.Request) {
+	contacts, err := people.ParseVCards(http.MaxBytesReader(w, r.Body, maxVCardUpload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.syncContacts(w, r, contacts, "vcard")
+}
+
+// ImportCardDAV handles POST /api/import/carddav
+func (s *Server) ImportCardDAV(w http.ResponseWriter, r *http.Request) {
+	var req CardDAVImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.URL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	contacts, err := people.FetchCardDAV(r.Context(), people.CardDAVOptions{URL: req.URL, Username: req.Username, Password: req.Password})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	s.syncContacts(w, r, contacts, "carddav")
+}
+
+func (s *Server) syncContacts(w http.ResponseWriter, r *http.Request, contacts []people.Contact, connector string) {
+	result, err := people.SyncContacts(r.Context(), s.repo, contacts, people.SyncOptions{Connector: connector, ChangedBy: r.URL.Query().Get("changed_by")})
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ResolvePeople handles GET /api/people/resolve?email=...&phone=...
+// Both parameters may repeat. Connectors use it to link email and
+// calendar participants to existing Person nodes; values that match no
+// one are listed as unresolved.
+func (s *Server) ResolvePeople(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if len(query["email"]) == 0 && len(query["phone"]) == 0 {
+		http.Error(w, "email or phone is required", http.StatusBadRequest)
+		return
+	}
+
+	resolved := []ResolvedPerson{}
+	unresolved := []string{}
+	lookup := func(kind string, values []string) {
+		for _, value := range values {
+			person, err := people.Resolve(r.Context(), s.repo, kind, value)
+			if err != nil {
+				unresolved = append(unresolved, value)
+				continue
+			}
+			name, _ := person.Meta["name"].(string)
+			resolved = append(resolved, ResolvedPerson{Kind: kind, Value: value, ID: person.ID, Name: name})
+		}
+	}
+	lookup(people.AliasEmail, query["email"])
+	lookup(people.AliasPhone, query["phone"])
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"resolved":   resolved,
+		"unresolved": unresolved,
+	})
+}
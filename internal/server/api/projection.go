@@ -0,0 +1,210 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// nodeFields are the fields a projection can select, by lowercase name
+// without underscores, with the key they have in a node's JSON
+var nodeFields = map[string]string{
+	"id":         "ID",
+	"type":       "Type",
+	"content":    "Content",
+	"meta":       "Meta",
+	"created":    "Created",
+	"modified":   "Modified",
+	"deleted":    "Deleted",
+	"deletedat":  "DeletedAt",
+	"versionid":  "VersionID",
+	"version":    "Version",
+	"iscurrent":  "IsCurrent",
+	"changenote": "ChangeNote",
+	"changedby":  "ChangedBy",
+}
+
+// nodeField returns a node's field by its JSON key
+func nodeField(n *core.Node, key string) interface{} {
+	switch key {
+	case "ID":
+		return n.ID
+	case "Type":
+		return n.Type
+	case "Content":
+		return n.Content
+	case "Meta":
+		return n.Meta
+	case "Created":
+		return n.Created
+	case "Modified":
+		return n.Modified
+	case "Deleted":
+		return n.Deleted
+	case "DeletedAt":
+		return n.DeletedAt
+	case "VersionID":
+		return n.VersionID
+	case "Version":
+		return n.Version
+	case "IsCurrent":
+		return n.IsCurrent
+	case "ChangeNote":
+		return n.ChangeNote
+	case "ChangedBy":
+		return n.ChangedBy
+	}
+	return nil
+}
+
+// nodeView shapes nodes in responses: projected onto some fields and
+// single meta properties, and with content cut to a preview. A nil view
+// leaves nodes whole.
+type nodeView struct {
+	fields     []string // JSON keys; nil keeps every field
+	metaKeys   []string
+	maxContent int // 0 keeps all content
+}
+
+// truncatedNode is a node whose content was cut, with its full length
+type truncatedNode struct {
+	*core.Node
+	ContentLength    int
+	ContentTruncated bool
+}
+
+// newNodeView parses field names (ID, Type, Content, Meta, Created, ...;
+// case-insensitive, underscores ignored, or "meta.<key>") and a content
+// limit, returning nil when neither shapes anything
+func newNodeView(fields []string, maxContent int) (*nodeView, error) {
+	if maxContent < 0 {
+		return nil, fmt.Errorf("max_content_bytes must not be negative")
+	}
+	if len(fields) == 0 && maxContent == 0 {
+		return nil, nil
+	}
+	v := &nodeView{maxContent: maxContent}
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if key, ok := strings.CutPrefix(f, "meta."); ok && key != "" {
+			v.metaKeys = append(v.metaKeys, key)
+			continue
+		}
+		key, ok := nodeFields[strings.ToLower(strings.ReplaceAll(f, "_", ""))]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		v.fields = append(v.fields, key)
+	}
+	if v.fields == nil && v.metaKeys != nil {
+		v.fields = []string{}
+	}
+	return v, nil
+}
+
+// nodeViewParam reads ?fields=id,type,meta.title and ?max_content_bytes=
+func nodeViewParam(r *http.Request) (*nodeView, error) {
+	query := r.URL.Query()
+	var fields []string
+	if f := query.Get("fields"); f != "" {
+		fields = strings.Split(f, ",")
+	}
+	maxContent := 0
+	if m := query.Get("max_content_bytes"); m != "" {
+		var err error
+		if maxContent, err = strconv.Atoi(m); err != nil {
+			return nil, fmt.Errorf("invalid max_content_bytes parameter")
+		}
+	}
+	return newNodeView(fields, maxContent)
+}
+
+// truncate returns n with its content cut to the view's limit, on a UTF-8
+// boundary, and the full content length if it was cut (0 otherwise)
+func (v *nodeView) truncate(n *core.Node) (*core.Node, int) {
+	if v == nil || v.maxContent == 0 || len(n.Content) <= v.maxContent {
+		return n, 0
+	}
+	end := v.maxContent
+	for end > 0 && end > v.maxContent-utf8.UTFMax && !utf8.RuneStart(n.Content[end]) {
+		end--
+	}
+	cut := *n
+	cut.Content = n.Content[:end]
+	return &cut, len(n.Content)
+}
+
+// node shapes one node for a response
+func (v *nodeView) node(n *core.Node) interface{} {
+	if v == nil {
+		return n
+	}
+	n, full := v.truncate(n)
+	if v.fields == nil {
+		if full > 0 {
+			return truncatedNode{Node: n, ContentLength: full, ContentTruncated: true}
+		}
+		return n
+	}
+
+	out := map[string]interface{}{"ID": n.ID}
+	for _, key := range v.fields {
+		out[key] = nodeField(n, key)
+		if key == "Content" && full > 0 {
+			out["ContentLength"] = full
+			out["ContentTruncated"] = true
+		}
+	}
+	if len(v.metaKeys) > 0 && out["Meta"] == nil {
+		meta := make(map[string]interface{}, len(v.metaKeys))
+		for _, k := range v.metaKeys {
+			if val, ok := n.Meta[k]; ok {
+				meta[k] = val
+			}
+		}
+		out["Meta"] = meta
+	}
+	return out
+}
+
+// nodes shapes a list of nodes for a response
+func (v *nodeView) nodes(nodes []*core.Node) interface{} {
+	if v == nil {
+		return nodes
+	}
+	out := make([]interface{}, len(nodes))
+	for i, n := range nodes {
+		out[i] = v.node(n)
+	}
+	return out
+}
+
+// nodeMap shapes nodes keyed by ID for a response
+func (v *nodeView) nodeMap(nodes map[string]*core.Node) interface{} {
+	if v == nil {
+		return nodes
+	}
+	out := make(map[string]interface{}, len(nodes))
+	for id, n := range nodes {
+		out[id] = v.node(n)
+	}
+	return out
+}
+
+// etag distinguishes a validator for a shaped response from the whole node's
+func (v *nodeView) etag() string {
+	if v == nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%q %q %d", v.fields, v.metaKeys, v.maxContent)))
+	return "-v" + hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestNodeView(t *testing.T) {
+	node := &core.Node{ID: "note:a", Type: "Note", Content: []byte("naïve café"), Meta: map[string]interface{}{"title": "A", "body": "long"}}
+
+	view, err := nodeViewParam(httptest.NewRequest("GET", "/?max_content_bytes=3", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "naï" is 4 bytes, so 3 bytes would split the ï
+	if cut, full := view.truncate(node); string(cut.Content) != "na" || full != len(node.Content) || string(node.Content) != "naïve café" {
+		t.Errorf("truncate() = %q, %d", cut.Content, full)
+	}
+	if got, ok := view.node(node).(truncatedNode); !ok || !got.ContentTruncated {
+		t.Errorf("node() = %#v", view.node(node))
+	}
+
+	view, err = nodeViewParam(httptest.NewRequest("GET", "/?fields=type,meta.title", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := view.node(node).(map[string]interface{})
+	if len(got) != 3 || got["Type"] != "Note" || len(got["Meta"].(map[string]interface{})) != 1 {
+		t.Errorf("projected node = %v", got)
+	}
+
+	if view, _ := nodeViewParam(httptest.NewRequest("GET", "/", nil)); view != nil || view.node(node) != node {
+		t.Error("no parameters should leave nodes whole")
+	}
+	for _, q := range []string{"fields=colour", "max_content_bytes=-1", "max_content_bytes=x"} {
+		if _, err := nodeViewParam(httptest.NewRequest("GET", "/?"+q, nil)); err == nil {
+			t.Errorf("nodeViewParam(%q) succeeded", q)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/projects"
+)
+
+// ProjectMembersRequest is the request body for POST /api/projects/{id}/members
+type ProjectMembersRequest struct {
+	NodeIDs []string `json:"node_ids"`
+}
+
+// projectError writes the status for a projects error
+func projectError(w http.ResponseWriter, err error) {
+	if errors.Is(err, projects.ErrNotProject) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusNotFound)
+}
+
+// ListProjects handles GET /api/projects
+// Lists Project nodes with their member counts, most recently modified first
+func (s *Server) ListProjects(w http.ResponseWriter, r *http.Request) {
+	list, err := projects.List(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"projects": list,
+		"count":    len(list),
+	})
+}
+
+// AddProjectMembers handles POST /api/projects/{id}/members
+// Links nodes to the project with MEMBER_OF. Existing members are skipped
+// and unknown IDs are reported as missing.
+func (s *Server) AddProjectMembers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req ProjectMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.NodeIDs) == 0 {
+		http.Error(w, "node_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	added, missing, err := projects.AddMembers(r.Context(), s.repo, id, req.NodeIDs)
+	if err != nil {
+		projectError(w, err)
+		return
+	}
+	if added == nil {
+		added = []string{}
+	}
+	if missing == nil {
+		missing = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"project": id,
+		"added":   added,
+		"missing": missing,
+	})
+}
+
+// RemoveProjectMember handles DELETE /api/projects/{id}/members/{node}
+func (s *Server) RemoveProjectMember(w http.ResponseWriter, r *http.Request) {
+	if err := projects.RemoveMember(r.Context(), s.repo, chi.URLParam(r, "id"), chi.URLParam(r, "node")); err != nil {
+		projectError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ProjectOverview handles GET /api/projects/{id}/overview
+// Rolls up the project's members (including those of sub-projects) and the
+// nodes linked to them: recent activity within ?days= (default 14), open
+// tasks, top documents, key people and attention hotspots, ?limit= (default
+// 10) entries each
+func (s *Server) ProjectOverview(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	opts := projects.OverviewOptions{}
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 100 {
+			http.Error(w, "invalid limit parameter (1-100)", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = n
+	}
+	if d := query.Get("days"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 || n > 366 {
+			http.Error(w, "invalid days parameter (1-366)", http.StatusBadRequest)
+			return
+		}
+		opts.Since = time.Duration(n) * 24 * time.Hour
+	}
+
+	if _, err := projects.GetProject(r.Context(), s.repo, id); err != nil {
+		projectError(w, err)
+		return
+	}
+	overview, err := projects.GetOverview(r.Context(), s.repo, id, opts, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overview)
+}
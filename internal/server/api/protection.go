@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// ProtectionRequest is the request body for PATCH /api/nodes/{id}/protection.
+// Omitted flags are left unchanged.
+type ProtectionRequest struct {
+	Protected *bool  `json:"protected,omitempty"`
+	Pinned    *bool  `json:"pinned,omitempty"`
+	ChangedBy string `json:"changed_by,omitempty"`
+}
+
+// SetProtection handles PATCH /api/nodes/{id}/protection
+// protected blocks deletion (even forced) and attention pruning; pinned
+// guarantees inclusion in graph map samples.
+func (s *Server) SetProtection(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req ProtectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Protected == nil && req.Pinned == nil {
+		http.Error(w, "protected or pinned is required", http.StatusBadRequest)
+		return
+	}
+
+	node, err := s.repo.GetNode(r.Context(), id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("node not found: %s", id), http.StatusNotFound)
+		return
+	}
+
+	meta := make(map[string]any)
+	var changes []string
+	if req.Protected != nil {
+		meta[graph.ProtectedKey] = *req.Protected
+		changes = append(changes, fmt.Sprintf("protected=%t", *req.Protected))
+	}
+	if req.Pinned != nil {
+		meta[graph.PinnedKey] = *req.Pinned
+		changes = append(changes, fmt.Sprintf("pinned=%t", *req.Pinned))
+	}
+	note := "Protection: " + strings.Join(changes, ", ")
+	if err := s.repo.UpdateNodeMetaWithNote(r.Context(), id, meta, note, req.ChangedBy); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	protected, pinned := graph.IsProtected(node.Meta), graph.IsPinned(node.Meta)
+	if req.Protected != nil {
+		protected = *req.Protected
+	}
+	if req.Pinned != nil {
+		pinned = *req.Pinned
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":        id,
+		"protected": protected,
+		"pinned":    pinned,
+	})
+}
+
+// deleteErrorStatus maps a DeleteNode error to an HTTP status
+func deleteErrorStatus(err error) int {
+	if errors.Is(err, graph.ErrProtected) || errors.Is(err, graph.ErrNodeHasLinks) {
+		return http.StatusConflict
+	}
+	return writeErrorStatus(err, http.StatusBadRequest)
+}
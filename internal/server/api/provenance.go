@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// GetProvenance handles GET /api/nodes/{id}/provenance
+// Returns the derivation tree from a node back to its Source nodes, with the
+// lenses and extractors used along the way. ?depth= limits the walk (default 10, max 50).
+func (s *Server) GetProvenance(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	depth := 10
+	if d := r.URL.Query().Get("depth"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 || n > 50 {
+			http.Error(w, "invalid depth parameter (1-50)", http.StatusBadRequest)
+			return
+		}
+		depth = n
+	}
+
+	prov, err := graph.TraceProvenance(r.Context(), s.repo, id, depth)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prov)
+}
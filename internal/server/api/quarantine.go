@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// quarantineStatuses are the statuses GET /api/admin/quarantine filters on
+var quarantineStatuses = map[string]bool{
+	graph.QuarantineOpen:      true,
+	graph.QuarantineRepaired:  true,
+	graph.QuarantineDropped:   true,
+	graph.QuarantineDismissed: true,
+}
+
+// quarantiner returns the backend's quarantine, reporting an error when it
+// has none
+func (s *Server) quarantiner(w http.ResponseWriter) (graph.Quarantiner, bool) {
+	q, ok := s.backend.(graph.Quarantiner)
+	if !ok {
+		notEnabled(w, "quarantine is not supported by this backend")
+		return nil, false
+	}
+	return q, true
+}
+
+// quarantineStatus maps quarantine errors to HTTP status codes
+func quarantineStatus(err error) int {
+	switch {
+	case errors.Is(err, graph.ErrQuarantineNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, graph.ErrInvalidRepair):
+		return http.StatusBadRequest
+	case errors.Is(err, graph.ErrNotRepaired):
+		return http.StatusUnprocessableEntity
+	}
+	return writeErrorStatus(err, http.StatusInternalServerError)
+}
+
+// quarantineID parses the {id} URL parameter
+func quarantineID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid quarantine entry ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// ListQuarantine handles GET /api/admin/quarantine
+// Lists stored rows reads could not use, newest first. ?status= filters
+// by open, repaired, dropped or dismissed.
+func (s *Server) ListQuarantine(w http.ResponseWriter, r *http.Request) {
+	q, ok := s.quarantiner(w)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && !quarantineStatuses[status] {
+		http.Error(w, "status must be open, repaired, dropped or dismissed", http.StatusBadRequest)
+		return
+	}
+	limit, offset := parsePagination(r)
+	entries, err := q.ListQuarantine(r.Context(), status, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), quarantineStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// GetQuarantine handles GET /api/admin/quarantine/{id}
+func (s *Server) GetQuarantine(w http.ResponseWriter, r *http.Request) {
+	q, ok := s.quarantiner(w)
+	if !ok {
+		return
+	}
+	id, ok := quarantineID(w, r)
+	if !ok {
+		return
+	}
+	entry, err := q.GetQuarantine(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), quarantineStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// CheckQuarantine handles POST /api/admin/quarantine/check
+// Reads every stored node version and link, quarantining the ones that
+// cannot be read, rather than waiting for a request to come across them.
+func (s *Server) CheckQuarantine(w http.ResponseWriter, r *http.Request) {
+	q, ok := s.quarantiner(w)
+	if !ok {
+		return
+	}
+	check, err := q.CheckStoredRows(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), quarantineStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(check)
+}
+
+// RepairQuarantine handles POST /api/admin/quarantine/{id}/repair
+// {"action": "replace", "properties": {...}} stores new properties in
+// place, {"action": "drop"} deletes the row so its source can write it
+// again, and {"action": "dismiss"} closes the entry without changes. A
+// replacement that leaves the row unreadable is refused with 422.
+func (s *Server) RepairQuarantine(w http.ResponseWriter, r *http.Request) {
+	q, ok := s.quarantiner(w)
+	if !ok {
+		return
+	}
+	id, ok := quarantineID(w, r)
+	if !ok {
+		return
+	}
+	var repair graph.QuarantineRepair
+	if err := json.NewDecoder(r.Body).Decode(&repair); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	entry, err := q.RepairQuarantine(r.Context(), id, repair)
+	if err != nil {
+		http.Error(w, err.Error(), quarantineStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
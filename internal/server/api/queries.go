@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/queries"
+)
+
+// SetQueryScheduler lets manual runs of scheduled queries see the
+// scheduler's last_run
+func (s *Server) SetQueryScheduler(scheduler *queries.Scheduler) {
+	s.queryScheduler = scheduler
+}
+
+// RunQueryRequest is the request body for POST /api/queries/{id}/run
+type RunQueryRequest struct {
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// CreateSavedQuery handles POST /api/queries
+func (s *Server) CreateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	var q queries.SavedQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.ID != "" {
+		if _, err := queries.Get(r.Context(), s.repo, q.ID); err == nil {
+			http.Error(w, "saved query already exists: "+q.ID, http.StatusConflict)
+			return
+		}
+	}
+	if err := queries.Save(r.Context(), s.repo, &q); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(q)
+}
+
+// ListSavedQueries handles GET /api/queries
+func (s *Server) ListSavedQueries(w http.ResponseWriter, r *http.Request) {
+	saved, err := queries.List(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"queries": saved,
+		"count":   len(saved),
+	})
+}
+
+// GetSavedQuery handles GET /api/queries/{id}
+func (s *Server) GetSavedQuery(w http.ResponseWriter, r *http.Request) {
+	q, err := queries.Get(r.Context(), s.repo, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), savedQueryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q)
+}
+
+// UpdateSavedQuery handles PUT /api/queries/{id}
+// Replaces the query's definition.
+func (s *Server) UpdateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	var q queries.SavedQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.ID = chi.URLParam(r, "id")
+	if err := queries.Update(r.Context(), s.repo, &q); err != nil {
+		http.Error(w, err.Error(), savedQueryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q)
+}
+
+// DeleteSavedQuery handles DELETE /api/queries/{id}
+func (s *Server) DeleteSavedQuery(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := queries.Delete(r.Context(), s.repo, id); err != nil {
+		http.Error(w, err.Error(), savedQueryStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": id,
+	})
+}
+
+// RunSavedQuery handles POST /api/queries/{id}/run
+// Runs the query with the given parameters over its defaults. The body is optional.
+func (s *Server) RunSavedQuery(w http.ResponseWriter, r *http.Request) {
+	q, err := queries.Get(r.Context(), s.repo, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), savedQueryStatus(err))
+		return
+	}
+
+	var req RunQueryRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	lastRun := q.Modified
+	if s.queryScheduler != nil && q.Schedule != nil {
+		lastRun = s.queryScheduler.LastRun(q.ID)
+	}
+	result, err := queries.Run(r.Context(), s.repo, q, req.Params, lastRun)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// savedQueryStatus maps saved query errors to HTTP statuses
+func savedQueryStatus(err error) int {
+	if errors.Is(err, queries.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
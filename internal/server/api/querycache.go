@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/querycache"
+)
+
+// cacheHeader reports whether a result came from the query cache
+const cacheHeader = "X-Memex-Cache"
+
+// SetQueryCache enables caching of subgraph, graph map and aggregate results
+func (s *Server) SetQueryCache(c *querycache.Cache) {
+	s.queryCache = c
+}
+
+// cachedResult returns an endpoint's result for normalized params from the
+// query cache, or computes it. compute reports whether its result may be
+// cached. Requests with Cache-Control: no-cache skip the lookup but still
+// refresh the entry. Requests pinned to a snapshot bypass the cache.
+func cachedResult[T any](s *Server, w http.ResponseWriter, r *http.Request, endpoint string, params interface{}, compute func() (T, bool, error)) (T, error) {
+	if s.queryCache == nil || graph.SnapshotFrom(r.Context()) != nil {
+		v, _, err := compute()
+		return v, err
+	}
+	revision := ""
+	if s.revision != nil {
+		revision = s.revision.Current()
+	}
+	key, err := querycache.Key(endpoint, revision, params)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if !strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+		if v, ok := s.queryCache.Get(endpoint, key); ok {
+			w.Header().Set(cacheHeader, "hit")
+			return v.(T), nil
+		}
+	}
+	v, cacheable, err := compute()
+	if err != nil {
+		return v, err
+	}
+	if cacheable {
+		s.queryCache.Put(endpoint, key, v)
+	}
+	w.Header().Set(cacheHeader, "miss")
+	return v, nil
+}
+
+// QueryCacheStats handles GET /api/admin/cache
+// Returns the query cache's size and hit and miss counts per endpoint.
+func (s *Server) QueryCacheStats(w http.ResponseWriter, r *http.Request) {
+	if s.queryCache == nil {
+		notEnabled(w, "query cache is disabled")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.queryCache.Stats())
+}
+
+// BustQueryCache handles DELETE /api/admin/cache
+// Empties the query cache.
+func (s *Server) BustQueryCache(w http.ResponseWriter, r *http.Request) {
+	if s.queryCache == nil {
+		notEnabled(w, "query cache is disabled")
+		return
+	}
+	n := s.queryCache.Bust()
+	log.Printf("Query cache busted (%d entries)", n)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"dropped": n})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/querycache"
+)
+
+func TestQueryCache(t *testing.T) {
+	ctx := context.Background()
+	repo, revision := graph.WithRevision(graph.NewMemory())
+	cache := querycache.New(querycache.Config{})
+	repo.SetEventEmitter(cache.EmitEvent)
+	s := New(repo, nil)
+	s.SetRevision(revision)
+	s.SetQueryCache(cache)
+
+	now := time.Now()
+	add := func(id, nodeType string) {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: nodeType, Meta: map[string]interface{}{}, Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("person:ada", "Person")
+	add("note:1", "Note")
+	if err := repo.CreateLink(ctx, &core.Link{Source: "note:1", Target: "person:ada", Type: "MENTIONS", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(handler http.HandlerFunc, url string) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", url, w.Code, w.Body)
+		}
+		return w.Header().Get(cacheHeader)
+	}
+
+	for _, step := range []struct {
+		handler http.HandlerFunc
+		url     string
+		want    string
+	}{
+		{s.QueryAggregate, "/api/query/aggregate?type=Person,Note", "miss"},
+		{s.QueryAggregate, "/api/query/aggregate?type=Note&type=Person", "hit"}, // Same set of types
+		{s.QueryAggregate, "/api/query/aggregate?type=Note", "miss"},
+		{s.QuerySubgraph, "/api/query/subgraph?start=note:1", "miss"},
+		{s.QuerySubgraph, "/api/query/subgraph?start=note:1&depth=2", "hit"}, // The default depth
+		{s.QuerySubgraph, `/api/query/subgraph?start=note:1&hypothetical=[{"source":"note:1","target":"person:ada","type":"CITES"}]`, "miss"},
+		{s.GraphMap, "/api/graph/map", "miss"},
+		{s.GraphMap, "/api/graph/map?sample_size=100", "hit"},
+	} {
+		if got := get(step.handler, step.url); got != step.want {
+			t.Errorf("%s: cache %s, want %s", step.url, got, step.want)
+		}
+	}
+
+	// A change empties the cache and moves the revision on
+	add("person:bob", "Person")
+	if got := get(s.QueryAggregate, "/api/query/aggregate?type=Person,Note"); got != "miss" {
+		t.Errorf("after a write: cache %s", got)
+	}
+	stats := cache.Stats()
+	if stats.Endpoints["aggregate"].Hits != 1 || stats.Endpoints["subgraph"].Misses != 2 || stats.Busts == 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// readOnlyPosts are the POST endpoints that only read, so stay available
+// in read-only mode
+var readOnlyPosts = regexp.MustCompile(`^/((sandboxes/[^/]+/)?nodes/batch-get|query/pattern|queries/[^/]+/run|rules/test|admin/hooks/[^/]+/test)/?$`)
+
+// ReadOnly returns middleware for the /api router that rejects every
+// request that could change state with 403, leaving queries, exports,
+// the event stream and the visualization endpoints available
+func ReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		case http.MethodPost:
+			if readOnlyPosts.MatchString(routePath(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "server is read-only", http.StatusForbidden)
+	})
+}
+
+// routePath returns the request path within the router the middleware is
+// mounted on
+func routePath(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		return rctx.RoutePath
+	}
+	if i := strings.Index(r.URL.Path, "/api/"); i >= 0 {
+		return r.URL.Path[i+len("/api"):]
+	}
+	return r.URL.Path
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestReadOnly(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	api := chi.NewRouter()
+	api.Route("/api", func(r chi.Router) {
+		r.Use(ReadOnly)
+		r.Get("/nodes", ok)
+		r.Post("/nodes", ok)
+		r.Delete("/nodes/{id}", ok)
+		r.Post("/query/pattern", ok)
+		r.Post("/queries/{id}/run", ok)
+		r.Post("/queries", ok)
+	})
+	root := chi.NewRouter()
+	root.Mount("/memex", api)
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/memex/api/nodes", http.StatusOK},
+		{"POST", "/memex/api/nodes", http.StatusForbidden},
+		{"DELETE", "/memex/api/nodes/a", http.StatusForbidden},
+		{"POST", "/memex/api/query/pattern", http.StatusOK},
+		{"POST", "/memex/api/queries/q1/run", http.StatusOK},
+		{"POST", "/memex/api/queries", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		root.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+}
+
+func TestReadOnlyNamespaceStatus(t *testing.T) {
+	s := New(graph.WithReadOnlyNamespaces(graph.NewMemory(), []string{"demo"}), nil)
+
+	for body, want := range map[string]int{
+		`{"id": "demo:a", "type": "Note"}`:  http.StatusForbidden,
+		`{"id": "notes:a", "type": "Note"}`: http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		s.CreateNode(w, httptest.NewRequest("POST", "/api/nodes", strings.NewReader(body)))
+		if w.Code != want {
+			t.Errorf("create %s = %d, want %d: %s", body, w.Code, want, w.Body)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/feedback"
+	"github.com/systemshift/memex/internal/server/related"
+)
+
+// RelatedNodes handles GET /api/nodes/{id}/related
+// Ranks nodes related to this one by shared neighbors, content similarity
+// and co-attention, with each signal's score. ?signals= (comma-separated
+// structural, content, attention) restricts the signals used; ?limit=
+// defaults to 10.
+func (s *Server) RelatedNodes(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	opts := related.Options{}
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 100 {
+			http.Error(w, "invalid limit parameter (1-100)", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = n
+	}
+	if v := query.Get("signals"); v != "" {
+		for _, signal := range strings.Split(v, ",") {
+			switch strings.TrimSpace(signal) {
+			case related.SignalStructural:
+				opts.Weights.Structural = related.DefaultWeights.Structural
+			case related.SignalContent:
+				opts.Weights.Content = related.DefaultWeights.Content
+			case related.SignalAttention:
+				opts.Weights.Attention = related.DefaultWeights.Attention
+			default:
+				http.Error(w, "invalid signals parameter (structural, content, attention)", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	if _, err := s.repo.GetNode(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	result, err := related.Find(r.Context(), s.repo, id, opts)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	hits := make([]feedback.Hit, len(result.Related))
+	ids := make([]string, len(result.Related))
+	for i, rel := range result.Related {
+		ids[i] = rel.ID
+		hits[i] = feedback.Hit{NodeID: rel.ID, Signals: map[string]float64{
+			"score":      rel.Score,
+			"structural": rel.Signals.Structural,
+			"content":    rel.Signals.Content,
+			"attention":  rel.Signals.Attention,
+		}}
+	}
+
+	s.recordSession(r, "", ids...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		*related.Result
+		RetrievalID string `json:"retrieval_id,omitempty"`
+	}{result, s.recordRetrieval("related", nil, hits)})
+}
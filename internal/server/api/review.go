@@ -0,0 +1,298 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/evidence"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Review decisions
+const (
+	ReviewAccept = "accept"
+	ReviewReject = "reject"
+)
+
+// ReviewItem is an extracted entity awaiting human review
+type ReviewItem struct {
+	Entity      *core.Node   `json:"entity"`
+	Reasons     []string     `json:"reasons"`              // "low_confidence" and/or "conflicting"
+	Confidence  *float64     `json:"confidence,omitempty"` // Lowest confidence among extractions
+	Extractions []*core.Link `json:"extractions"`
+	Conflicts   []string     `json:"conflicts,omitempty"` // Nodes linked via CONFLICTS_WITH
+}
+
+// ReviewDecisionRequest is the request body for accepting or rejecting an entity
+type ReviewDecisionRequest struct {
+	Reviewer string `json:"reviewer"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// linkMeta returns a link request's properties with the confidence, target
+// version and valid time fields standardized
+func linkMeta(req CreateLinkRequest) (map[string]interface{}, error) {
+	meta := req.Meta
+	if req.Confidence != nil {
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+		meta[graph.ConfidenceKey] = *req.Confidence
+	}
+	if req.TargetVersionID != "" {
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+		meta[graph.TargetVersionKey] = req.TargetVersionID
+	}
+	if err := graph.NormalizeTargetVersion(meta, req.Target); err != nil {
+		return nil, err
+	}
+	meta, err := withValidTime(meta, req.ValidFrom, req.ValidTo)
+	if err != nil {
+		return nil, err
+	}
+	if err := graph.NormalizeConfidence(meta); err != nil {
+		return nil, err
+	}
+	if err := evidence.NormalizeSpans(meta, req.Type); err != nil {
+		return nil, err
+	}
+	if err := graph.ValidateLayer(meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// reviewItem inspects an entity's extraction links. It returns nil when the
+// entity has no extractions or has already been reviewed.
+func (s *Server) reviewItem(ctx context.Context, id string, threshold float64) (*ReviewItem, error) {
+	links, err := s.repo.GetLinks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &ReviewItem{}
+	for _, link := range links {
+		switch link.Type {
+		case "EXTRACTED_FROM":
+			item.Extractions = append(item.Extractions, link)
+			if c, ok := graph.LinkConfidence(link.Meta); ok && (item.Confidence == nil || c < *item.Confidence) {
+				item.Confidence = &c
+			}
+		case "CONFLICTS_WITH":
+			item.Conflicts = append(item.Conflicts, link.Target)
+		}
+	}
+	if len(item.Extractions) == 0 {
+		return nil, nil
+	}
+
+	node, err := s.repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, nil
+	}
+	if _, reviewed := node.Meta["review_status"]; reviewed {
+		return nil, nil
+	}
+	item.Entity = node
+
+	if item.Confidence != nil && *item.Confidence < threshold {
+		item.Reasons = append(item.Reasons, "low_confidence")
+	}
+	if len(item.Conflicts) > 0 {
+		item.Reasons = append(item.Reasons, "conflicting")
+	}
+	return item, nil
+}
+
+// ReviewQueue handles GET /api/review/queue
+// Lists unreviewed extracted entities whose extraction confidence is below
+// ?threshold= (default 0.5) or that have CONFLICTS_WITH links, least confident first.
+func (s *Server) ReviewQueue(w http.ResponseWriter, r *http.Request) {
+	threshold := 0.5
+	if t := r.URL.Query().Get("threshold"); t != "" {
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil || v < 0 || v > 1 {
+			http.Error(w, "invalid threshold parameter (0-1)", http.StatusBadRequest)
+			return
+		}
+		threshold = v
+	}
+	limit, offset := parsePagination(r)
+
+	ids, err := s.repo.ListNodes(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	items := []*ReviewItem{}
+	for _, id := range ids {
+		item, err := s.reviewItem(r.Context(), id, threshold)
+		if err != nil {
+			http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			return
+		}
+		if item != nil && len(item.Reasons) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	// Conflicts first, then by ascending confidence
+	sort.SliceStable(items, func(i, j int) bool {
+		ci, cj := len(items[i].Conflicts) > 0, len(items[j].Conflicts) > 0
+		if ci != cj {
+			return ci
+		}
+		return confidenceOrOne(items[i].Confidence) < confidenceOrOne(items[j].Confidence)
+	})
+
+	total := len(items)
+	if offset > len(items) {
+		offset = len(items)
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":     items,
+		"count":     len(items),
+		"total":     total,
+		"threshold": threshold,
+	})
+}
+
+// ReviewDecide handles POST /api/review/{id}/{decision}
+// accept marks the entity as reviewed; reject tombstones it. Either way the
+// decision is stored as a ReviewDecision node (REVIEWED -> entity) so it can
+// be exported as training signal for extractors.
+func (s *Server) ReviewDecide(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	decision := chi.URLParam(r, "decision")
+	if decision != ReviewAccept && decision != ReviewReject {
+		http.Error(w, "decision must be 'accept' or 'reject'", http.StatusBadRequest)
+		return
+	}
+
+	var req ReviewDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Reviewer == "" {
+		req.Reviewer = "anonymous"
+	}
+
+	ctx := r.Context()
+	entity, err := s.repo.GetNode(ctx, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("node not found: %s", id), http.StatusNotFound)
+		return
+	}
+	if decision == ReviewReject && strings.HasPrefix(id, "sha256:") {
+		http.Error(w, "cannot reject Source layer node (content-addressed): "+id, http.StatusBadRequest)
+		return
+	}
+	if decision == ReviewReject && graph.IsProtected(entity.Meta) {
+		http.Error(w, "cannot reject protected node: "+id, http.StatusConflict)
+		return
+	}
+	item, err := s.reviewItem(ctx, id, 0)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	// Record the decision before acting so a rejected entity keeps its signal
+	now := time.Now()
+	meta := map[string]interface{}{
+		"entity_id":   id,
+		"entity_type": entity.Type,
+		"decision":    decision,
+		"reviewer":    req.Reviewer,
+		"reason":      req.Reason,
+		"decided_at":  now.Format(time.RFC3339),
+	}
+	if item != nil {
+		var sources, extractors []string
+		for _, link := range item.Extractions {
+			sources = append(sources, link.Target)
+			if e, ok := link.Meta["extractor"].(string); ok {
+				extractors = append(extractors, e)
+			}
+		}
+		meta["sources"] = sources
+		meta["extractors"] = extractors
+		meta["conflicts"] = item.Conflicts
+		if item.Confidence != nil {
+			meta["confidence"] = *item.Confidence
+		}
+	}
+
+	decisionNode := &core.Node{
+		ID:       "review:" + uuid.New().String(),
+		Type:     "ReviewDecision",
+		Meta:     meta,
+		Created:  now,
+		Modified: now,
+	}
+	if err := s.repo.CreateNode(ctx, decisionNode); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	if err := s.repo.CreateLink(ctx, &core.Link{
+		Source:   decisionNode.ID,
+		Target:   id,
+		Type:     "REVIEWED",
+		Meta:     map[string]interface{}{"decision": decision},
+		Created:  now,
+		Modified: now,
+	}); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	note := "Review: " + decision + "ed"
+	if decision == ReviewAccept {
+		err = s.repo.UpdateNodeMetaWithNote(ctx, id, map[string]any{
+			"review_status": "accepted",
+			"reviewed_by":   req.Reviewer,
+			"reviewed_at":   now.Format(time.RFC3339),
+		}, note, req.Reviewer)
+	} else {
+		// A rejected extraction takes its links with it, whatever the configured cascade
+		err = s.repo.DeleteNode(graph.ContextWithCascade(ctx, graph.CascadeTombstone), id, false)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), deleteErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          id,
+		"decision":    decision,
+		"decision_id": decisionNode.ID,
+	})
+}
+
+func confidenceOrOne(c *float64) float64 {
+	if c == nil {
+		return 1
+	}
+	return *c
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestReviewWorkflow(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	s := New(repo, nil)
+	now := time.Now()
+
+	for _, id := range []string{"sha256:src", "person:ada", "person:bob"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Person", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, conf := range map[string]float64{"person:ada": 0.3, "person:bob": 0.9} {
+		if err := repo.CreateLink(ctx, &core.Link{Source: id, Target: "sha256:src", Type: "EXTRACTED_FROM",
+			Meta: map[string]any{"confidence": conf}, Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Get("/api/review/queue", s.ReviewQueue)
+	r.Post("/api/review/{id}/{decision}", s.ReviewDecide)
+
+	queue := func() []*ReviewItem {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/review/queue", nil))
+		var resp struct {
+			Items []*ReviewItem `json:"items"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decoding queue: %v", err)
+		}
+		return resp.Items
+	}
+
+	items := queue()
+	if len(items) != 1 || items[0].Entity.ID != "person:ada" {
+		t.Fatalf("queue = %v, want only person:ada", items)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/review/person:ada/reject", strings.NewReader(`{"reviewer":"alice"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("reject status = %d: %s", w.Code, w.Body)
+	}
+	if _, err := repo.GetNode(ctx, "person:ada"); err == nil {
+		t.Error("rejected entity should be tombstoned")
+	}
+	if len(queue()) != 0 {
+		t.Error("queue should be empty after review")
+	}
+	decisions, _ := repo.FilterNodes(ctx, []string{"ReviewDecision"}, "decision", "reject", 10, 0)
+	if len(decisions) != 1 || decisions[0].Meta["confidence"] != 0.3 {
+		t.Errorf("decisions = %v, want one reject with confidence 0.3", decisions)
+	}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/rules"
+)
+
+// SetRuleEngine enables automatic linking and rule metrics
+func (s *Server) SetRuleEngine(e *rules.Engine) {
+	s.ruleEngine = e
+}
+
+// RuleView is a rule with its metrics
+type RuleView struct {
+	*rules.Rule
+	Metrics *rules.Metrics `json:"metrics,omitempty"` // Absent when the engine is disabled
+}
+
+// TestRuleRequest is the request body for POST /api/rules/test
+type TestRuleRequest struct {
+	Rule    *rules.Rule `json:"rule,omitempty"`    // A rule to try out
+	RuleID  string      `json:"rule_id,omitempty"` // Or a stored rule
+	NodeID  string      `json:"node_id,omitempty"` // An existing node
+	Node    *NodeInput  `json:"node,omitempty"`    // Or a node as it would be created
+	Trigger string      `json:"trigger,omitempty"` // created (default) or updated
+}
+
+// NodeInput is a node that does not need to exist
+type NodeInput struct {
+	ID      string                 `json:"id,omitempty"`
+	Type    string                 `json:"type"`
+	Content string                 `json:"content,omitempty"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+// ruleView attaches a rule's metrics when the engine is running
+func (s *Server) ruleView(r *rules.Rule) RuleView {
+	view := RuleView{Rule: r}
+	if s.ruleEngine != nil {
+		m := s.ruleEngine.Metrics(r.ID)
+		view.Metrics = &m
+	}
+	return view
+}
+
+// reloadRules makes rule changes take effect before responding
+func (s *Server) reloadRules(r *http.Request) {
+	if s.ruleEngine != nil {
+		s.ruleEngine.Reload(r.Context())
+	}
+}
+
+// CreateRule handles POST /api/rules
+func (s *Server) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var rule rules.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rule.ID != "" {
+		if _, err := rules.Get(r.Context(), s.repo, rule.ID); err == nil {
+			http.Error(w, "rule already exists: "+rule.ID, http.StatusConflict)
+			return
+		}
+	}
+	if err := rules.Save(r.Context(), s.repo, &rule); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+	s.reloadRules(r)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s.ruleView(&rule))
+}
+
+// ListRules handles GET /api/rules
+func (s *Server) ListRules(w http.ResponseWriter, r *http.Request) {
+	list, err := rules.List(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	views := make([]RuleView, 0, len(list))
+	for _, rule := range list {
+		views = append(views, s.ruleView(rule))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules": views,
+		"count": len(views),
+	})
+}
+
+// GetRule handles GET /api/rules/{id}
+func (s *Server) GetRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := rules.Get(r.Context(), s.repo, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), ruleStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.ruleView(rule))
+}
+
+// UpdateRule handles PUT /api/rules/{id}
+// Replaces the rule's definition. Links it already made stay.
+func (s *Server) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	var rule rules.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rule.ID = chi.URLParam(r, "id")
+	if err := rules.Update(r.Context(), s.repo, &rule); err != nil {
+		http.Error(w, err.Error(), ruleStatus(err))
+		return
+	}
+	s.reloadRules(r)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.ruleView(&rule))
+}
+
+// DeleteRule handles DELETE /api/rules/{id}
+func (s *Server) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := rules.Delete(r.Context(), s.repo, id); err != nil {
+		http.Error(w, err.Error(), ruleStatus(err))
+		return
+	}
+	s.reloadRules(r)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": id,
+	})
+}
+
+// TestRule handles POST /api/rules/test
+// Dry-runs a rule (given inline or by rule_id) against an existing node or
+// one described in the request, returning whether it applies and the links
+// it would create. Nothing is written.
+func (s *Server) TestRule(w http.ResponseWriter, r *http.Request) {
+	var req TestRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule := req.Rule
+	switch {
+	case rule != nil && req.RuleID != "":
+		http.Error(w, "give rule or rule_id, not both", http.StatusBadRequest)
+		return
+	case rule != nil:
+		if rule.ID == "" {
+			rule.ID = "test"
+		}
+		if err := rule.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case req.RuleID != "":
+		var err error
+		if rule, err = rules.Get(r.Context(), s.repo, req.RuleID); err != nil {
+			http.Error(w, err.Error(), ruleStatus(err))
+			return
+		}
+	default:
+		http.Error(w, "rule or rule_id is required", http.StatusBadRequest)
+		return
+	}
+
+	var node *core.Node
+	switch {
+	case req.NodeID != "" && req.Node != nil:
+		http.Error(w, "give node or node_id, not both", http.StatusBadRequest)
+		return
+	case req.NodeID != "":
+		var err error
+		if node, err = s.repo.GetNode(r.Context(), req.NodeID); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	case req.Node != nil:
+		if req.Node.Type == "" {
+			http.Error(w, "node type is required", http.StatusBadRequest)
+			return
+		}
+		id := req.Node.ID
+		if id == "" {
+			id = "test:node"
+		}
+		now := time.Now()
+		node = &core.Node{ID: id, Type: req.Node.Type, Content: []byte(req.Node.Content), Meta: req.Node.Meta, Created: now, Modified: now}
+	default:
+		http.Error(w, "node or node_id is required", http.StatusBadRequest)
+		return
+	}
+
+	trigger := req.Trigger
+	if trigger == "" {
+		trigger = rules.OnCreated
+	}
+	if trigger != rules.OnCreated && trigger != rules.OnUpdated {
+		http.Error(w, "invalid trigger (created or updated)", http.StatusBadRequest)
+		return
+	}
+
+	applies, proposals, err := rules.DryRun(r.Context(), s.repo, rule, node, trigger)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rule":    rule.ID,
+		"node_id": node.ID,
+		"applies": applies,
+		"links":   proposals,
+	})
+}
+
+// ruleStatus maps rule errors to HTTP statuses
+func ruleStatus(err error) int {
+	if errors.Is(err, rules.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/sandbox"
+)
+
+// SetSandboxes enables scratch sandbox graphs
+func (s *Server) SetSandboxes(m *sandbox.Manager) {
+	s.sandboxes = m
+}
+
+// CreateSandboxRequest is the request body for POST /api/sandboxes
+type CreateSandboxRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+// sandboxStatus maps sandbox errors to HTTP status codes
+func sandboxStatus(err error) int {
+	switch {
+	case errors.Is(err, sandbox.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, sandbox.ErrLimit):
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// sandboxesEnabled reports an error when sandboxes are disabled
+func (s *Server) sandboxesEnabled(w http.ResponseWriter) bool {
+	if s.sandboxes == nil {
+		notEnabled(w, "sandboxes are disabled")
+		return false
+	}
+	return true
+}
+
+// CreateSandbox handles POST /api/sandboxes
+// Opens an empty copy-on-write overlay over the graph. The node, link and
+// query endpoints under /api/sandboxes/{sandbox} read the union of the
+// graph and the sandbox and write only to the sandbox.
+func (s *Server) CreateSandbox(w http.ResponseWriter, r *http.Request) {
+	if !s.sandboxesEnabled(w) {
+		return
+	}
+	var req CreateSandboxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sb, err := s.sandboxes.Create(r.Context(), req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), sandboxStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sb)
+}
+
+// ListSandboxes handles GET /api/sandboxes
+func (s *Server) ListSandboxes(w http.ResponseWriter, r *http.Request) {
+	if !s.sandboxesEnabled(w) {
+		return
+	}
+	list, err := s.sandboxes.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sandboxes": list,
+		"count":     len(list),
+	})
+}
+
+// GetSandbox handles GET /api/sandboxes/{sandbox}
+func (s *Server) GetSandbox(w http.ResponseWriter, r *http.Request) {
+	if !s.sandboxesEnabled(w) {
+		return
+	}
+	sb, err := s.sandboxes.Get(r.Context(), chi.URLParam(r, "sandbox"))
+	if err != nil {
+		http.Error(w, err.Error(), sandboxStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sb)
+}
+
+// GetSandboxChanges handles GET /api/sandboxes/{sandbox}/changes
+// Lists the changes a merge would apply, in order.
+func (s *Server) GetSandboxChanges(w http.ResponseWriter, r *http.Request) {
+	if !s.sandboxesEnabled(w) {
+		return
+	}
+	sb, err := s.sandboxes.Get(r.Context(), chi.URLParam(r, "sandbox"))
+	if err != nil {
+		http.Error(w, err.Error(), sandboxStatus(err))
+		return
+	}
+	changes, err := sb.Overlay.Changes(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"changes": changes,
+		"count":   len(changes),
+	})
+}
+
+// MergeSandbox handles POST /api/sandboxes/{sandbox}/merge
+// Applies the selected changes (all of them without a body) to the graph
+// and closes the sandbox. Changes the graph rejects are reported.
+func (s *Server) MergeSandbox(w http.ResponseWriter, r *http.Request) {
+	if !s.sandboxesEnabled(w) {
+		return
+	}
+	var sel sandbox.Selection
+	if err := json.NewDecoder(r.Body).Decode(&sel); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := s.sandboxes.Merge(r.Context(), chi.URLParam(r, "sandbox"), sel)
+	if err != nil {
+		http.Error(w, err.Error(), sandboxStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DiscardSandbox handles DELETE /api/sandboxes/{sandbox}
+func (s *Server) DiscardSandbox(w http.ResponseWriter, r *http.Request) {
+	if !s.sandboxesEnabled(w) {
+		return
+	}
+	if err := s.sandboxes.Discard(chi.URLParam(r, "sandbox")); err != nil {
+		http.Error(w, err.Error(), sandboxStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// InSandbox serves a graph handler against a sandbox: reads see the graph
+// and the sandbox, writes go to the sandbox only
+func (s *Server) InSandbox(h func(*Server, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.sandboxesEnabled(w) {
+			return
+		}
+		sb, err := s.sandboxes.Get(r.Context(), chi.URLParam(r, "sandbox"))
+		if err != nil {
+			http.Error(w, err.Error(), sandboxStatus(err))
+			return
+		}
+		scoped := New(sb.Overlay, nil)
+		scoped.basePath = s.basePath
+		scoped.trustProxy = s.trustProxy
+		scoped.trustPolicy = s.trustPolicy
+		scoped.dagLinkTypes = s.dagLinkTypes
+		h(scoped, w, r)
+	}
+}
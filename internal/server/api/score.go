@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// ScoreRequest is the request body for scoring nodes
+type ScoreRequest struct {
+	IDs []string `json:"ids"`
+	graph.ScoreSpec
+}
+
+// ScoreResponse is the response for scoring nodes
+type ScoreResponse struct {
+	Scores  []graph.NodeScore `json:"scores"` // Highest first
+	Count   int               `json:"count"`
+	Missing []string          `json:"missing,omitempty"`
+}
+
+// Score handles POST /api/score
+// Combines the server's ranking signals for the given nodes with the
+// caller's weights: recency, degree, attention, trust and text relevance
+// to a query. Each signal is in [0, 1] and the score is their weighted mean.
+func (s *Server) Score(w http.ResponseWriter, r *http.Request) {
+	var req ScoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxBatchGet {
+		http.Error(w, fmt.Sprintf("give between 1 and %d ids", maxBatchGet), http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scores, missing, err := graph.ScoreNodes(r.Context(), s.repo, s.trustPolicy, req.IDs, req.ScoreSpec, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	if scores == nil {
+		scores = []graph.NodeScore{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ScoreResponse{Scores: scores, Count: len(scores), Missing: missing})
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/sessions"
+)
+
+// sessionHeader names the session that reads should be recorded in
+const sessionHeader = "X-Memex-Session"
+
+// SetSessions enables agent working-memory sessions
+func (s *Server) SetSessions(m *sessions.Manager) {
+	s.sessions = m
+}
+
+// CreateSessionRequest is the request body for POST /api/sessions
+type CreateSessionRequest struct {
+	Name  string `json:"name,omitempty"`
+	Agent string `json:"agent,omitempty"`
+}
+
+// RecordSessionNodesRequest is the request body for POST /api/sessions/{id}/nodes
+type RecordSessionNodesRequest struct {
+	NodeIDs []string `json:"node_ids"`
+	Query   string   `json:"query,omitempty"`
+}
+
+// CloseSessionRequest is the request body for POST /api/sessions/{id}/close
+type CloseSessionRequest struct {
+	Persist bool `json:"persist"`
+}
+
+// sessionStatus maps session errors to HTTP status codes
+func sessionStatus(err error) int {
+	switch {
+	case errors.Is(err, sessions.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, sessions.ErrLimit):
+		return http.StatusTooManyRequests
+	case errors.Is(err, sessions.ErrInvalid):
+		return http.StatusBadRequest
+	}
+	return writeErrorStatus(err, http.StatusInternalServerError)
+}
+
+// sessionsEnabled reports an error when sessions are disabled
+func (s *Server) sessionsEnabled(w http.ResponseWriter) bool {
+	if s.sessions == nil {
+		notEnabled(w, "sessions are disabled")
+		return false
+	}
+	return true
+}
+
+// recordSession adds nodes a read returned to the session named by the
+// X-Memex-Session header. Unknown sessions are ignored so reads never
+// fail because of them.
+func (s *Server) recordSession(r *http.Request, query string, nodeIDs ...string) {
+	id := r.Header.Get(sessionHeader)
+	if s.sessions == nil || id == "" || len(nodeIDs) == 0 {
+		return
+	}
+	s.sessions.Record(id, query, nodeIDs)
+}
+
+// nodeIDs returns the IDs of nodes
+func nodeIDs(nodes []*core.Node) []string {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+// CreateSession handles POST /api/sessions
+// Opens a working-memory session. Reads sent with an X-Memex-Session
+// header (search, node fetches, related nodes) add what they return.
+func (s *Server) CreateSession(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	var req CreateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	session, err := s.sessions.Create(req.Name, req.Agent)
+	if err != nil {
+		http.Error(w, err.Error(), sessionStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// ListSessions handles GET /api/sessions
+func (s *Server) ListSessions(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	list := s.sessions.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": list,
+		"count":    len(list),
+	})
+}
+
+// GetSession handles GET /api/sessions/{id}
+func (s *Server) GetSession(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	session, err := s.sessions.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), sessionStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// RecordSessionNodes handles POST /api/sessions/{id}/nodes
+// Adds nodes the agent retrieved some other way.
+func (s *Server) RecordSessionNodes(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	var req RecordSessionNodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.NodeIDs) == 0 {
+		http.Error(w, "node_ids is required", http.StatusBadRequest)
+		return
+	}
+	session, err := s.sessions.Record(chi.URLParam(r, "id"), req.Query, req.NodeIDs)
+	if err != nil {
+		http.Error(w, err.Error(), sessionStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// AddSessionEntry handles POST /api/sessions/{id}/notes
+// Adds a note or, with "kind": "conclusion", an intermediate conclusion.
+func (s *Server) AddSessionEntry(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	var entry sessions.Entry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry, err := s.sessions.Add(chi.URLParam(r, "id"), entry)
+	if err != nil {
+		http.Error(w, err.Error(), sessionStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetSessionContext handles GET /api/sessions/{id}/context
+// Returns the session subgraph: its retrieved nodes (most recently seen
+// first, up to ?max_nodes=, default 100), the links among them, and its
+// notes and conclusions.
+func (s *Server) GetSessionContext(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	maxNodes := 100
+	if v := r.URL.Query().Get("max_nodes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid max_nodes parameter", http.StatusBadRequest)
+			return
+		}
+		maxNodes = n
+	}
+	sessionContext, err := s.sessions.Context(r.Context(), chi.URLParam(r, "id"), maxNodes)
+	if err != nil {
+		http.Error(w, err.Error(), sessionStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionContext)
+}
+
+// CloseSession handles POST /api/sessions/{id}/close
+// With "persist": true the session, its notes and conclusions are written
+// into the graph and linked to the nodes they used.
+func (s *Server) CloseSession(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	var req CloseSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := s.sessions.Close(r.Context(), chi.URLParam(r, "id"), req.Persist)
+	if err != nil {
+		http.Error(w, err.Error(), sessionStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DiscardSession handles DELETE /api/sessions/{id}
+func (s *Server) DiscardSession(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	if err := s.sessions.Discard(chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), sessionStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/share"
+)
+
+// Share link lifetimes
+const (
+	defaultShareTTL = 7 * 24 * time.Hour
+	maxShareTTL     = 90 * 24 * time.Hour
+)
+
+// SetShareSigner enables public share links
+func (s *Server) SetShareSigner(signer *share.Signer) {
+	s.shares = signer
+}
+
+// CreateShareRequest is the request body for POST /api/shares
+type CreateShareRequest struct {
+	NodeID    string `json:"node_id"`
+	Depth     *int   `json:"depth,omitempty"`      // Neighborhood hops (default 1, max share.MaxDepth)
+	ExpiresIn string `json:"expires_in,omitempty"` // Duration such as 72h (default 168h, max 2160h)
+}
+
+// CreateShareResponse describes a created share link
+type CreateShareResponse struct {
+	URL       string    `json:"url"`
+	Token     string    `json:"token"`
+	NodeID    string    `json:"node_id"`
+	Depth     int       `json:"depth"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateShare handles POST /api/shares
+// Issues a signed, expiring URL granting read-only access to a node and its
+// neighborhood through the public /share view.
+func (s *Server) CreateShare(w http.ResponseWriter, r *http.Request) {
+	if s.shares == nil {
+		notEnabled(w, "share links are not configured")
+		return
+	}
+
+	var req CreateShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.NodeID == "" {
+		http.Error(w, "node_id is required", http.StatusBadRequest)
+		return
+	}
+	depth := 1
+	if req.Depth != nil {
+		depth = *req.Depth
+	}
+	ttl := defaultShareTTL
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 || d > maxShareTTL {
+			http.Error(w, "invalid expires_in (a duration up to 2160h)", http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+	if _, err := s.repo.GetNode(r.Context(), req.NodeID); err != nil {
+		http.Error(w, "node not found: "+req.NodeID, http.StatusNotFound)
+		return
+	}
+
+	grant := share.Grant{NodeID: req.NodeID, Depth: depth, Expires: time.Now().Add(ttl).Truncate(time.Second)}
+	token, err := s.shares.Sign(grant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateShareResponse{
+		URL:       s.BaseURL(r) + "/share/" + token,
+		Token:     token,
+		NodeID:    grant.NodeID,
+		Depth:     grant.Depth,
+		ExpiresAt: grant.Expires,
+	})
+}
+
+// sharedNode is a node as shown in the public view
+type sharedNode struct {
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Label    string                 `json:"label"`
+	Content  string                 `json:"content,omitempty"`
+	Binary   int                    `json:"binary_bytes,omitempty"` // Size of non-text content, which is not shown
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	Created  time.Time              `json:"created"`
+	Modified time.Time              `json:"modified"`
+}
+
+// sharedLink is an edge touching the viewed node
+type sharedLink struct {
+	Type     string `json:"type"`
+	Outgoing bool   `json:"outgoing"`
+	ID       string `json:"id"`
+	Label    string `json:"label"`
+}
+
+// sharedView is the JSON form of the public view
+type sharedView struct {
+	Root      string           `json:"root"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	Node      sharedNode       `json:"node"`
+	Links     []sharedLink     `json:"links"`
+	Nodes     []graph.ViewNode `json:"nodes"` // Everything reachable through the share
+}
+
+// ViewShare handles GET /share/{token}
+// Renders a minimal read-only page for one node of a shared neighborhood.
+// ?node= selects another node inside the share; ?format=json returns JSON.
+func (s *Server) ViewShare(w http.ResponseWriter, r *http.Request) {
+	if s.shares == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	grant, err := s.shares.Verify(chi.URLParam(r, "token"), time.Now())
+	if errors.Is(err, share.ErrExpired) {
+		http.Error(w, "this share link has expired", http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	sub, err := s.repo.GetSubgraph(r.Context(), grant.NodeID, grant.Depth, nil)
+	if err != nil {
+		http.Error(w, "failed to load shared nodes", http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]*core.Node, len(sub.Nodes))
+	for _, n := range sub.Nodes {
+		byID[n.ID] = n
+	}
+
+	selected := r.URL.Query().Get("node")
+	if selected == "" {
+		selected = grant.NodeID
+	}
+	node, ok := byID[selected]
+	if !ok {
+		// Outside the shared neighborhood (or deleted)
+		http.NotFound(w, r)
+		return
+	}
+
+	view := sharedView{
+		Root:      grant.NodeID,
+		ExpiresAt: grant.Expires,
+		Node:      publicNode(node),
+		Links:     []sharedLink{},
+		Nodes:     graph.BuildView(sub, graph.ViewOptions{}).Nodes,
+	}
+	for _, e := range sub.Edges {
+		var other string
+		switch selected {
+		case e.Source:
+			other = e.Target
+		case e.Target:
+			other = e.Source
+		default:
+			continue
+		}
+		if n, ok := byID[other]; ok {
+			view.Links = append(view.Links, sharedLink{Type: e.Type, Outgoing: e.Source == selected, ID: other, Label: graph.NodeLabel(n.ID, n.Meta)})
+		}
+	}
+	sort.Slice(view.Links, func(i, j int) bool {
+		if view.Links[i].Type != view.Links[j].Type {
+			return view.Links[i].Type < view.Links[j].Type
+		}
+		return view.Links[i].ID < view.Links[j].ID
+	})
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(view)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	shareTemplate.Execute(w, view)
+}
+
+// publicNode converts a node for the public view, dropping internal
+// properties and binary content
+func publicNode(n *core.Node) sharedNode {
+	out := sharedNode{
+		ID:       n.ID,
+		Type:     n.Type,
+		Label:    graph.NodeLabel(n.ID, n.Meta),
+		Meta:     make(map[string]interface{}),
+		Created:  n.Created,
+		Modified: n.Modified,
+	}
+	if utf8.Valid(n.Content) {
+		out.Content = string(n.Content)
+	} else {
+		out.Binary = len(n.Content)
+	}
+	for k, v := range n.Meta {
+		if !strings.HasPrefix(k, "_") {
+			out.Meta[k] = v
+		}
+	}
+	return out
+}
+
+// shareTemplate renders the public view
+var shareTemplate = template.Must(template.New("share").Funcs(template.FuncMap{
+	"nodeURL": func(id string) string { return "?node=" + url.QueryEscape(id) },
+	"json": func(v interface{}) string {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>{{.Node.Label}} · Memex</title>
+<style>
+body { font: 15px/1.5 system-ui, sans-serif; max-width: 52rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h1 { margin-bottom: 0; } .type { color: #777; margin-top: 0; }
+pre { white-space: pre-wrap; background: #f6f6f6; padding: 1rem; border-radius: 4px; }
+table { border-collapse: collapse; } td { padding: 2px 12px 2px 0; vertical-align: top; } td:first-child { color: #777; }
+a { color: #0b5cad; text-decoration: none; } footer { color: #999; font-size: 13px; margin-top: 2rem; }
+</style>
+</head>
+<body>
+<h1>{{.Node.Label}}</h1>
+<p class="type">{{.Node.Type}} · <code>{{.Node.ID}}</code></p>
+{{if .Node.Content}}<pre>{{.Node.Content}}</pre>{{end}}
+{{if .Node.Binary}}<p><em>Binary content ({{.Node.Binary}} bytes) is not shown.</em></p>{{end}}
+{{if .Node.Meta}}<table>{{range $k, $v := .Node.Meta}}<tr><td>{{$k}}</td><td>{{json $v}}</td></tr>{{end}}</table>{{end}}
+{{if .Links}}<h2>Links</h2>
+<ul>{{range .Links}}<li>{{if .Outgoing}}→{{else}}←{{end}} {{.Type}} <a href="{{nodeURL .ID}}">{{.Label}}</a></li>{{end}}</ul>{{end}}
+<h2>In this share</h2>
+<ul>{{range .Nodes}}<li><a href="{{nodeURL .ID}}">{{.Label}}</a> <small>{{.Type}}</small></li>{{end}}</ul>
+<footer>Shared read-only from Memex · <a href="{{nodeURL .Root}}">start</a> · expires {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}</footer>
+</body>
+</html>
+`))
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/share"
+)
+
+func TestShareLinks(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	s := New(repo, nil)
+	s.SetShareSigner(share.NewSigner("secret"))
+	now := time.Now()
+
+	for _, id := range []string{"note:trail", "note:step", "note:private"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Content: []byte("<b>" + id + "</b>"),
+			Meta: map[string]interface{}{"_internal": "x"}, Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "note:trail", Target: "note:step", Type: "NEXT"},
+		{Source: "note:step", Target: "note:private", Type: "NEXT"},
+	} {
+		l.Created, l.Modified = now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Post("/api/shares", s.CreateShare)
+	r.Get("/share/{token}", s.ViewShare)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/shares", strings.NewReader(`{"node_id":"note:trail","depth":1}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body)
+	}
+	var created CreateShareResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if !strings.HasSuffix(created.URL, "/share/"+created.Token) {
+		t.Errorf("unexpected share URL %s", created.URL)
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/share/"+created.Token+query, nil))
+		return w
+	}
+
+	w = get("")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "&lt;b&gt;note:trail&lt;/b&gt;") || strings.Contains(w.Body.String(), "_internal") {
+		t.Errorf("unexpected page %d: %s", w.Code, w.Body)
+	}
+	if w = get("?node=note:step&format=json"); w.Code != http.StatusOK {
+		t.Errorf("node inside the share: status %d", w.Code)
+	}
+	if w = get("?node=note:private"); w.Code != http.StatusNotFound {
+		t.Errorf("node outside the share: status %d, want 404", w.Code)
+	}
+
+	expired, _ := share.NewSigner("secret").Sign(share.Grant{NodeID: "note:trail", Expires: now.Add(-time.Minute)})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/share/"+expired, nil))
+	if w.Code != http.StatusGone {
+		t.Errorf("expired link: status %d, want 410", w.Code)
+	}
+}
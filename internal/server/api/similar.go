@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxSuggestions caps the "did you mean" suggestions of an empty search
+const maxSuggestions = 5
+
+// Suggestion is a node a search with no hits may have meant
+type Suggestion struct {
+	ID    string  `json:"id"`
+	Type  string  `json:"type"`
+	Label string  `json:"label"`
+	Match string  `json:"match"` // The name spelled like the query
+	Score float64 `json:"score"` // Trigram similarity, 0.3 to 1
+}
+
+// suggestions returns the nodes in layers whose names are spelled like q,
+// for a search that found nothing; ?suggest=false turns them off. A failed
+// lookup only costs the suggestions.
+func (s *Server) suggestions(ctx context.Context, r *http.Request, q string, layers graph.LayerFilter) []Suggestion {
+	if r.URL.Query().Get("suggest") == "false" {
+		return nil
+	}
+	similar, err := s.repo.SearchSimilar(ctx, q, maxSuggestions*2)
+	if err != nil {
+		log.Printf("Search suggestions for %q failed: %v", q, err)
+		return nil
+	}
+	var out []Suggestion
+	for _, n := range similar {
+		if !layers.Node(n.Node) || len(out) == maxSuggestions {
+			continue
+		}
+		out = append(out, Suggestion{
+			ID:    n.Node.ID,
+			Type:  n.Node.Type,
+			Label: graph.NodeLabel(n.Node.ID, n.Node.Meta),
+			Match: n.Match,
+			Score: n.Score,
+		})
+	}
+	return out
+}
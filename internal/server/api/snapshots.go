@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/snapshots"
+)
+
+// snapshotHeader names the snapshot a request's reads are pinned to
+const snapshotHeader = "X-Memex-Snapshot"
+
+// SetSnapshots enables read snapshots
+func (s *Server) SetSnapshots(m *snapshots.Manager) {
+	s.snapshots = m
+}
+
+// CreateSnapshotRequest is the request body for POST /api/snapshots
+type CreateSnapshotRequest struct {
+	TTLSeconds int `json:"ttl_seconds,omitempty"` // Lifetime; the server default when zero, cut to its maximum
+}
+
+// snapshotsEnabled reports an error when snapshots are disabled
+func (s *Server) snapshotsEnabled(w http.ResponseWriter) bool {
+	if s.snapshots == nil {
+		notEnabled(w, "snapshots are disabled or not supported by this backend")
+		return false
+	}
+	return true
+}
+
+// snapshotStatus maps snapshot errors to HTTP status codes
+func snapshotStatus(err error) int {
+	switch {
+	case errors.Is(err, snapshots.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, snapshots.ErrLimit):
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// PinSnapshot is middleware for the /api router that pins the reads of a
+// request sent with an X-Memex-Snapshot header (or ?snapshot=) to that
+// snapshot. Snapshots only pin reads, so writes sent with one are
+// rejected, and an expired or unknown snapshot answers 410.
+func (s *Server) PinSnapshot(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(snapshotHeader)
+		if token == "" {
+			token = r.URL.Query().Get("snapshot")
+		}
+		if token == "" || strings.HasPrefix(routePath(r), "/snapshots") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !s.snapshotsEnabled(w) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost:
+			if !readOnlyPosts.MatchString(routePath(r)) {
+				http.Error(w, "snapshots pin reads only; send writes without "+snapshotHeader, http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "snapshots pin reads only; send writes without "+snapshotHeader, http.StatusBadRequest)
+			return
+		}
+		snap, release, err := s.snapshots.Acquire(token)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusGone)
+			return
+		}
+		defer release()
+		w.Header().Set(snapshotHeader, token)
+		next.ServeHTTP(w, r.WithContext(graph.WithSnapshot(r.Context(), snap)))
+	})
+}
+
+// CreateSnapshot handles POST /api/snapshots
+// Pins a view of the graph. Reads sent with the returned token in an
+// X-Memex-Snapshot header see that view until it expires or is deleted.
+func (s *Server) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !s.snapshotsEnabled(w) {
+		return
+	}
+	var req CreateSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TTLSeconds < 0 {
+		http.Error(w, "ttl_seconds must not be negative", http.StatusBadRequest)
+		return
+	}
+	info, err := s.snapshots.Begin(r.Context(), time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), snapshotStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(info)
+}
+
+// ListSnapshots handles GET /api/snapshots
+func (s *Server) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	if !s.snapshotsEnabled(w) {
+		return
+	}
+	list := s.snapshots.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"snapshots": list,
+		"count":     len(list),
+	})
+}
+
+// GetSnapshot handles GET /api/snapshots/{token}
+func (s *Server) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !s.snapshotsEnabled(w) {
+		return
+	}
+	info, err := s.snapshots.Get(chi.URLParam(r, "token"))
+	if err != nil {
+		http.Error(w, err.Error(), snapshotStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// DeleteSnapshot handles DELETE /api/snapshots/{token}
+// Ends a snapshot before it expires; reads already using it finish first.
+func (s *Server) DeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !s.snapshotsEnabled(w) {
+		return
+	}
+	if err := s.snapshots.Release(chi.URLParam(r, "token")); err != nil {
+		http.Error(w, err.Error(), snapshotStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/snapshots"
+)
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	repo, err := graph.NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+	s := New(repo, nil)
+	m := snapshots.NewManager(repo, time.Minute, time.Minute, 4)
+	defer m.Close()
+	s.SetSnapshots(m)
+
+	r := chi.NewRouter()
+	r.Route("/api", func(r chi.Router) {
+		r.Use(s.PinSnapshot)
+		r.Post("/nodes", s.CreateNode)
+		r.Post("/nodes/batch-get", s.BatchGetNodes)
+		r.Get("/nodes/{id}", s.GetNode)
+		r.Post("/snapshots", s.CreateSnapshot)
+		r.Delete("/snapshots/{token}", s.DeleteSnapshot)
+	})
+	do := func(method, url, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set(snapshotHeader, token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	now := time.Now()
+	add := func(id string) {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Meta: map[string]interface{}{}, Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("note:before")
+
+	w := do("POST", "/api/snapshots", "", `{"ttl_seconds": 30}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /api/snapshots: %d %s", w.Code, w.Body)
+	}
+	var info snapshots.Info
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Consistency != graph.SnapshotIsolated || info.Expires.Sub(info.Created) != 30*time.Second {
+		t.Errorf("snapshot = %+v", info)
+	}
+	add("note:after")
+
+	for _, step := range []struct {
+		method, url, token, body string
+		want                     int
+	}{
+		{"GET", "/api/nodes/note:after", "", "", http.StatusOK},
+		{"GET", "/api/nodes/note:after", info.Token, "", http.StatusNotFound},
+		{"GET", "/api/nodes/note:after?snapshot=" + info.Token, "", "", http.StatusNotFound},
+		{"GET", "/api/nodes/note:before", info.Token, "", http.StatusOK},
+		{"POST", "/api/nodes/batch-get", info.Token, `{"ids": ["note:before"]}`, http.StatusOK},
+		{"POST", "/api/nodes", info.Token, `{"id": "note:pinned", "type": "Note"}`, http.StatusBadRequest},
+		{"GET", "/api/nodes/note:before", "snap_unknown", "", http.StatusGone},
+		{"DELETE", "/api/snapshots/" + info.Token, info.Token, "", http.StatusNoContent},
+		{"GET", "/api/nodes/note:before", info.Token, "", http.StatusGone},
+	} {
+		if w := do(step.method, step.url, step.token, step.body); w.Code != step.want {
+			t.Errorf("%s %s (token %q): %d %s, want %d", step.method, step.url, step.token, w.Code, w.Body, step.want)
+		}
+	}
+
+	var batch BatchGetResponse
+	w = do("POST", "/api/snapshots", "", "")
+	json.NewDecoder(w.Body).Decode(&info)
+	add("note:late")
+	w = do("POST", "/api/nodes/batch-get", info.Token, `{"ids": ["note:after", "note:late"]}`)
+	if err := json.NewDecoder(w.Body).Decode(&batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Count != 1 || len(batch.Missing) != 1 || batch.Missing[0] != "note:late" {
+		t.Errorf("pinned batch-get = %d found, missing %v; want note:late missing", batch.Count, batch.Missing)
+	}
+
+	// Without snapshots a token cannot be honored
+	s.SetSnapshots(nil)
+	if w := do("GET", "/api/nodes/note:before", info.Token, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("token with snapshots disabled: %d, want 503", w.Code)
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// ndjsonType is the media type of streamed results, one JSON value per line
+const ndjsonType = "application/x-ndjson"
+
+// streamPage is how many nodes are read from the repository per call
+// while streaming
+const streamPage = 1000
+
+// wantsNDJSON reports whether the client asked for streamed results
+func wantsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, _ := strings.Cut(part, ";")
+			if strings.TrimSpace(mediaType) == ndjsonType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamLimit returns ?limit= for a streamed response, where no limit
+// means every result
+func streamLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// ndjsonWriter writes one JSON value per line, flushing as it goes
+type ndjsonWriter struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	flusher http.Flusher
+}
+
+// newNDJSONWriter starts a streamed response
+func newNDJSONWriter(w http.ResponseWriter) *ndjsonWriter {
+	// The server's write timeout would otherwise cut a long stream off
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", ndjsonType)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &ndjsonWriter{w: w, enc: json.NewEncoder(w), flusher: flusher}
+}
+
+func (nw *ndjsonWriter) write(v interface{}) error {
+	return nw.enc.Encode(v)
+}
+
+func (nw *ndjsonWriter) flush() {
+	if nw.flusher != nil {
+		nw.flusher.Flush()
+	}
+}
+
+// fail ends a stream that broke after its status was sent, with a final
+// {"error": ...} line
+func (nw *ndjsonWriter) fail(err error) {
+	nw.write(map[string]string{"error": err.Error()})
+	nw.flush()
+}
+
+// streamNodes writes up to limit nodes after offset (0 for all) that are
+// in the selected layers as NDJSON, reading them from fetch a page at a
+// time so neither side holds the whole result
+func streamNodes(w http.ResponseWriter, r *http.Request, view *nodeView, layers graph.LayerFilter, limit, offset int, fetch func(limit, offset int) ([]*core.Node, error)) {
+	nw := newNDJSONWriter(w)
+	read, skipped, written := 0, 0, 0
+	if layers == nil {
+		read = offset // The backend can skip unfiltered results itself
+		skipped = offset
+	}
+	for limit == 0 || written < limit {
+		if r.Context().Err() != nil {
+			return
+		}
+		nodes, err := fetch(streamPage, read)
+		if err != nil {
+			nw.fail(err)
+			return
+		}
+		read += len(nodes)
+		for _, n := range layers.Nodes(nodes) {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			if limit > 0 && written == limit {
+				break
+			}
+			if err := nw.write(view.node(n)); err != nil {
+				return // Client went away
+			}
+			written++
+		}
+		nw.flush()
+		if len(nodes) < streamPage {
+			return
+		}
+	}
+}
+
+// sortedNodes returns the nodes of a map in ID order
+func sortedNodes(nodes map[string]*core.Node) []*core.Node {
+	out := make([]*core.Node, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
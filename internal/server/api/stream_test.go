@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestStreamNDJSON(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	s := New(repo, nil)
+	nodes := make([]*core.Node, streamPage*2+5)
+	for i := range nodes {
+		nodes[i] = &core.Node{ID: fmt.Sprintf("note:%05d", i), Type: "Note", Content: []byte("some content")}
+	}
+	if err := repo.CreateNodes(ctx, nodes); err != nil {
+		t.Fatal(err)
+	}
+
+	stream := func(url string) []map[string]interface{} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Accept", "application/x-ndjson")
+		s.QueryFilter(w, r)
+		if ct := w.Header().Get("Content-Type"); ct != ndjsonType {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		var rows []map[string]interface{}
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Fatalf("line %q: %v", scanner.Text(), err)
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}
+
+	// Without a limit every result is streamed, past the page size
+	if rows := stream("/api/query/filter?type=Note"); len(rows) != len(nodes) {
+		t.Errorf("streamed %d rows, want %d", len(rows), len(nodes))
+	}
+	rows := stream("/api/query/filter?type=Note&limit=3&offset=1500&fields=type")
+	if len(rows) != 3 || len(rows[0]) != 2 || rows[0]["Type"] != "Note" {
+		t.Errorf("limited rows = %v", rows)
+	}
+
+	// A plain JSON response is unchanged
+	w := httptest.NewRecorder()
+	s.QueryFilter(w, httptest.NewRequest("GET", "/api/query/filter?type=Note", nil))
+	var resp struct{ Count int }
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Count != 100 {
+		t.Errorf("JSON response count = %d, %v", resp.Count, err)
+	}
+}
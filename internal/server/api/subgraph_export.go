@@ -0,0 +1,97 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	graphexport "github.com/systemshift/memex/internal/server/export"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// unsafeFilename matches characters left out of download filenames
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// ExportSubgraph handles GET /api/query/subgraph/export
+// Downloads the subgraph around ?start= as one file. The default
+// ?format=html is a self-contained page with an interactive viewer that
+// opens without a server, for sharing by email or chat; graphml, gexf, dot,
+// jsonld, turtle and csv work as for /api/export/{format}. Takes depth,
+// rel_type, layer, as_of_valid and as_of_recorded as GET /api/query/subgraph
+// does, and meta to limit the node properties written.
+func (s *Server) ExportSubgraph(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	start := query.Get("start")
+	if start == "" {
+		http.Error(w, "query parameter 'start' is required", http.StatusBadRequest)
+		return
+	}
+	name := query.Get("format")
+	if name == "" {
+		name = "html"
+	}
+	format, ok := graphexport.Formats[name]
+	if !ok {
+		http.Error(w, "unsupported format (use html, graphml, gexf, dot, jsonld, turtle or csv)", http.StatusBadRequest)
+		return
+	}
+	depth := 2
+	if d := query.Get("depth"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid depth parameter", http.StatusBadRequest)
+			return
+		}
+		depth = n
+	}
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	times, err := timeFilterParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, _, err := s.withTraversalBudget(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	root, err := s.repo.GetNode(ctx, start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sub, err := s.repo.GetSubgraph(ctx, start, depth, query["rel_type"])
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	sub = layers.Subgraph(sub, start)
+	if sub, err = times.Subgraph(ctx, s.repo, sub, start); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	opts := graphexport.Options{
+		Vocabulary: s.rdfVocab,
+		Title:      "Subgraph of " + graph.NodeLabel(root.ID, root.Meta),
+		Start:      start,
+		Styles:     s.typeStyles(r),
+	}
+	for _, key := range strings.Split(query.Get("meta"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			opts.MetaKeys = append(opts.MetaKeys, key)
+		}
+	}
+
+	filename := strings.Trim(unsafeFilename.ReplaceAllString(start, "-"), "-")
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"subgraph-%s.%s\"", filename, format.Extension))
+	format.Write(w, sub, opts)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// maxReplayLimit caps the changelog events read by one replay
+const maxReplayLimit = 10000
+
+// ReplaySubscription handles POST /api/subscriptions/{id}/replay?since=&limit=
+// Re-delivers changelog events after since (RFC3339, YYYY-MM-DD, or a
+// duration such as 6h meaning that long ago) that match the subscription.
+func (s *Server) ReplaySubscription(w http.ResponseWriter, r *http.Request) {
+	if s.subMgr == nil {
+		notEnabled(w, "subscription manager not initialized")
+		return
+	}
+
+	sinceStr := r.URL.Query().Get("since")
+	if sinceStr == "" {
+		http.Error(w, "since parameter is required", http.StatusBadRequest)
+		return
+	}
+	since, err := parseTimeParam(sinceStr)
+	if err != nil {
+		d, durErr := time.ParseDuration(sinceStr)
+		if durErr != nil || d <= 0 {
+			http.Error(w, "invalid since parameter (use RFC3339, YYYY-MM-DD or a duration like 6h)", http.StatusBadRequest)
+			return
+		}
+		since = time.Now().Add(-d)
+	}
+
+	limit := graph.DefaultChangeLogLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxReplayLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxReplayLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := s.subMgr.Replay(r.Context(), chi.URLParam(r, "id"), since, limit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, subscriptions.ErrNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, subscriptions.ErrNotReplayable) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListDeadLetters handles GET /api/subscriptions/dead-letters?subscription=
+// Lists notifications whose delivery failed after all retries.
+func (s *Server) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if s.subMgr == nil {
+		notEnabled(w, "subscription manager not initialized")
+		return
+	}
+
+	deadLetters := s.subMgr.DeadLetters(r.URL.Query().Get("subscription"))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dead_letters": deadLetters,
+		"count":        len(deadLetters),
+	})
+}
+
+// RetryDeadLetter handles POST /api/subscriptions/dead-letters/{id}/retry
+// Re-sends a dead letter; it is removed on success and kept (with the new
+// error) on failure.
+func (s *Server) RetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.subMgr == nil {
+		notEnabled(w, "subscription manager not initialized")
+		return
+	}
+
+	dl, err := s.subMgr.RetryDeadLetter(chi.URLParam(r, "id"))
+	if errors.Is(err, subscriptions.ErrDeadLetterNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dead_letter": dl,
+		"delivered":   err == nil,
+	})
+}
+
+// DeleteDeadLetter handles DELETE /api/subscriptions/dead-letters/{id}
+func (s *Server) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.subMgr == nil {
+		notEnabled(w, "subscription manager not initialized")
+		return
+	}
+
+	if err := s.subMgr.DeleteDeadLetter(chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/syncapi"
+)
+
+// SyncInfo handles GET /api/sync/v1
+// Describes the sync protocol version and its limits, so a client can
+// check it speaks this server's version before syncing.
+func (s *Server) SyncInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"version":         syncapi.Version,
+		"default_changes": syncapi.DefaultChanges,
+		"max_changes":     syncapi.MaxChanges,
+		"max_push":        syncapi.MaxPush,
+	})
+}
+
+// SyncChanges handles GET /api/sync/v1/changes
+// Returns the changes after ?cursor= (from the beginning without one), up
+// to ?limit=, with the cursor to read on from.
+func (s *Server) SyncChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 0
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > syncapi.MaxChanges {
+			http.Error(w, fmt.Sprintf("invalid limit parameter (1-%d)", syncapi.MaxChanges), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	page, err := syncapi.Changes(r.Context(), s.repo, query.Get("cursor"), limit)
+	if errors.Is(err, syncapi.ErrBadCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// SyncPush handles POST /api/sync/v1/push
+// Applies a batch of node and link writes made offline, reporting each
+// one. Node changes must name the token of the version they were based on
+// and conflict when it is no longer current.
+func (s *Server) SyncPush(w http.ResponseWriter, r *http.Request) {
+	var req syncapi.PushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := syncapi.Push(r.Context(), s.repo, &req)
+	if errors.Is(err, syncapi.ErrTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// SyncContent handles GET /api/sync/v1/content/{id}
+// Serves a node's content on its own, left out of the changes feed. Range
+// requests resume an interrupted download and If-None-Match skips an
+// unchanged one. The node's token is sent in X-Memex-Sync-Token.
+func (s *Server) SyncContent(w http.ResponseWriter, r *http.Request) {
+	content, node, err := syncapi.Content(r.Context(), s.repo, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("X-Memex-Sync-Token", node.VersionID)
+	if content == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("ETag", fmt.Sprintf("%q", node.VersionID))
+	http.ServeContent(w, r, "", node.Modified, bytes.NewReader(content))
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/synonyms"
+)
+
+// SynonymGroupsRequest is the request body for replacing a synonym set
+type SynonymGroupsRequest struct {
+	Groups [][]string `json:"groups"`
+}
+
+// AddSynonymsRequest is the request body for adding a synonym group
+type AddSynonymsRequest struct {
+	Terms []string `json:"terms"`
+}
+
+// ListSynonyms handles GET /api/synonyms
+// Returns every stored set, or only ?namespace='s (the global set for
+// ?namespace=).
+func (s *Server) ListSynonyms(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Has("namespace") {
+		set, err := synonyms.Get(r.Context(), s.repo, r.URL.Query().Get("namespace"))
+		if err != nil {
+			http.Error(w, err.Error(), synonymStatus(err))
+			return
+		}
+		json.NewEncoder(w).Encode(set)
+		return
+	}
+
+	sets, err := synonyms.List(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sets":  sets,
+		"count": len(sets),
+	})
+}
+
+// PutSynonyms handles PUT /api/synonyms?namespace=
+// Replaces the namespace's groups, or the global ones without a namespace.
+// An empty list removes the set.
+func (s *Server) PutSynonyms(w http.ResponseWriter, r *http.Request) {
+	var req SynonymGroupsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	set, err := synonyms.Put(r.Context(), s.repo, r.URL.Query().Get("namespace"), req.Groups)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, synonymStatus(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(set)
+}
+
+// AddSynonyms handles POST /api/synonyms?namespace=
+// Adds one group of terms that mean the same, merged with any group that
+// shares a term.
+func (s *Server) AddSynonyms(w http.ResponseWriter, r *http.Request) {
+	var req AddSynonymsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	set, err := synonyms.Add(r.Context(), s.repo, r.URL.Query().Get("namespace"), req.Terms)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, synonymStatus(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(set)
+}
+
+// DeleteSynonyms handles DELETE /api/synonyms?namespace=&term=
+// Removes one term from its group, or the whole set without ?term=.
+func (s *Server) DeleteSynonyms(w http.ResponseWriter, r *http.Request) {
+	namespace := r.URL.Query().Get("namespace")
+	var set *synonyms.Set
+	var err error
+	if term := r.URL.Query().Get("term"); term != "" {
+		set, err = synonyms.Remove(r.Context(), s.repo, namespace, term)
+	} else {
+		set, err = synonyms.Put(r.Context(), s.repo, namespace, nil)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, synonymStatus(err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(set)
+}
+
+// searchContext returns the read context for a search of q, expanded with
+// ?stem=true, the synonyms of ?namespace= and the global ones (unless
+// ?synonyms=false), and restricted to ?namespace=
+func (s *Server) searchContext(r *http.Request, q string) (context.Context, error) {
+	ctx := readContext(r)
+	query := r.URL.Query()
+	opts := &graph.SearchOptions{Stem: query.Get("stem") == "true", Namespace: query.Get("namespace")}
+	if err := synonyms.ValidateNamespace(opts.Namespace); err != nil {
+		return nil, err
+	}
+	if query.Get("synonyms") != "false" {
+		groups, err := synonyms.ForSearch(r.Context(), s.repo, opts.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		opts.Synonyms = groups
+	}
+	if !opts.Expands(q) {
+		return ctx, nil
+	}
+	return graph.WithSearchOptions(ctx, opts), nil
+}
+
+// synonymStatus maps synonym errors to HTTP statuses
+func synonymStatus(err error) int {
+	switch {
+	case errors.Is(err, synonyms.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, synonyms.ErrInvalid):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
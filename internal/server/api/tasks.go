@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/tasks"
+)
+
+// validTaskStatuses are the statuses GET /api/tasks filters on
+var validTaskStatuses = map[string]bool{
+	tasks.StatusOpen:    true,
+	tasks.StatusDoing:   true,
+	tasks.StatusDone:    true,
+	tasks.StatusBlocked: true,
+}
+
+// TaskStatusRequest is the request body for PATCH /api/tasks/{id}/status
+type TaskStatusRequest struct {
+	Status    string `json:"status"`
+	ChangedBy string `json:"changed_by,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// SetTaskProcessor enables on-demand task extraction
+func (s *Server) SetTaskProcessor(p *tasks.Processor) {
+	s.taskProc = p
+}
+
+// ExtractTasks handles POST /api/nodes/{id}/tasks
+// Extracts a node's action items now, even if it was scanned before, and
+// returns what was found. Existing tasks are left as they are.
+func (s *Server) ExtractTasks(w http.ResponseWriter, r *http.Request) {
+	if s.taskProc == nil {
+		notEnabled(w, "task extraction is disabled")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	node, err := s.repo.GetNode(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if tasks.SourceText(node) == "" {
+		http.Error(w, "node has no text to extract tasks from (a Note, Transcript, Email, Message, Meeting or text Source)", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.taskProc.ProcessNode(r.Context(), id, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListTasks handles GET /api/tasks
+// Filters by ?status= (open, doing, done or blocked), ?assignee= (a Person
+// ID), ?source= (the node tasks came from) and ?project= (a node the tasks
+// or their sources link to). Tasks due soonest come first; ?limit=
+// (default 100) and ?offset= page through them.
+func (s *Server) ListTasks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := tasks.Filter{Status: query.Get("status"), AssigneeID: query.Get("assignee"), SourceID: query.Get("source"), ProjectID: query.Get("project")}
+	if filter.Status != "" && !validTaskStatuses[filter.Status] {
+		http.Error(w, "invalid status (open, doing, done or blocked)", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := 100, 0
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 1000 {
+			http.Error(w, "invalid limit parameter (1-1000)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if o := query.Get("offset"); o != "" {
+		n, err := strconv.Atoi(o)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	list, err := tasks.List(r.Context(), s.repo, filter, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	total := len(list)
+	if offset > total {
+		offset = total
+	}
+	list = list[offset:]
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tasks": list,
+		"count": len(list),
+		"total": total,
+	})
+}
+
+// SetTaskStatus handles PATCH /api/tasks/{id}/status
+// Moves a task through open → doing → done or blocked (blocked tasks go
+// back to doing; done and doing tasks can be reopened). Other moves are
+// rejected with 409 and the allowed statuses. The change is recorded as a
+// new version attributed to changed_by.
+func (s *Server) SetTaskStatus(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req TaskStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Status == "" {
+		http.Error(w, "status is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.repo.GetNode(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err := s.nodeLocks.Check(id, req.ChangedBy, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	transition, err := tasks.SetStatus(r.Context(), s.repo, id, req.Status, req.ChangedBy, req.Note)
+	var invalid *tasks.TransitionError
+	switch {
+	case errors.As(err, &invalid):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   invalid.Error(),
+			"from":    invalid.From,
+			"to":      invalid.To,
+			"allowed": invalid.Allowed,
+		})
+		return
+	case errors.Is(err, tasks.ErrNotTask):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"status":     transition.To,
+		"transition": transition,
+	})
+}
+
+// TaskHistory handles GET /api/tasks/{id}/history
+// Lists a task's status changes, oldest first
+func (s *Server) TaskHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	node, err := s.repo.GetNode(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if node.Type != tasks.TaskType {
+		http.Error(w, tasks.ErrNotTask.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := tasks.History(r.Context(), s.repo, id)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	if history == nil {
+		history = []tasks.Transition{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          id,
+		"status":      node.Meta["status"],
+		"transitions": history,
+	})
+}
+
+// ProjectBurndown handles GET /api/projects/{id}/burndown
+// Counts the project's tasks by status at the end of each day between
+// ?from= and ?to= (YYYY-MM-DD, default the last 14 days), along with the
+// current counts
+func (s *Server) ProjectBurndown(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	now := time.Now()
+	to := now
+	if v := query.Get("to"); v != "" {
+		day, err := time.ParseInLocation(tasks.DateFormat, v, now.Location())
+		if err != nil {
+			http.Error(w, "invalid to parameter (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		to = day.Add(24*time.Hour - time.Nanosecond)
+	}
+	from := to.AddDate(0, 0, -13)
+	if v := query.Get("from"); v != "" {
+		day, err := time.ParseInLocation(tasks.DateFormat, v, now.Location())
+		if err != nil {
+			http.Error(w, "invalid from parameter (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		from = day
+	}
+
+	if _, err := s.repo.GetNode(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	burndown, err := tasks.ProjectBurndown(r.Context(), s.repo, id, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(burndown)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/tickets"
+)
+
+// TicketImportRequest is the request body for POST /api/import/tickets
+type TicketImportRequest struct {
+	Tracker string `json:"tracker,omitempty"` // jira or linear; empty syncs every configured tracker
+	Full    bool   `json:"full,omitempty"`    // Refetch everything, not just what changed since the last sync
+}
+
+// SetTicketSources configures the issue trackers tickets are synced from
+func (s *Server) SetTicketSources(sources []tickets.Source) {
+	s.ticketSources = sources
+}
+
+// ImportTickets handles POST /api/import/tickets
+// Syncs tickets from the configured trackers now.
+func (s *Server) ImportTickets(w http.ResponseWriter, r *http.Request) {
+	var req TicketImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(s.ticketSources) == 0 {
+		notEnabled(w, "No ticket trackers configured")
+		return
+	}
+	var sources []tickets.Source
+	for _, src := range s.ticketSources {
+		if req.Tracker == "" || req.Tracker == src.Name() {
+			sources = append(sources, src)
+		}
+	}
+	if len(sources) == 0 {
+		http.Error(w, "Tracker not configured: "+req.Tracker, http.StatusBadRequest)
+		return
+	}
+
+	results := []*tickets.Result{}
+	for _, src := range sources {
+		result, err := tickets.Sync(r.Context(), s.repo, src, req.Full)
+		if err != nil {
+			status := http.StatusBadGateway
+			if result != nil {
+				// Part of the tracker was applied before the failure
+				status = writeErrorStatus(err, http.StatusInternalServerError)
+			}
+			http.Error(w, src.Name()+": "+err.Error(), status)
+			return
+		}
+		results = append(results, result)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+	})
+}
+
+// ListTicketSyncs handles GET /api/tickets/sync
+// Returns each synced tracker's cursor.
+func (s *Server) ListTicketSyncs(w http.ResponseWriter, r *http.Request) {
+	states, err := tickets.ListStates(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"trackers": states,
+		"count":    len(states),
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxRequestTimeout caps the ?timeout= a request may ask for
+const MaxRequestTimeout = 10 * time.Minute
+
+// Timeout returns middleware that bounds each request by ?timeout= (a Go
+// duration such as 2s, at most MaxRequestTimeout) or else by def (0 for
+// none), which streams are exempt from. The deadline cancels the request's
+// backend queries with it, and a request that runs out of time gets 504
+// Gateway Timeout in place of the error its handler reports.
+func Timeout(def time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := def
+			if wantsNDJSON(r) || strings.HasSuffix(routePath(r), "/events/stream") {
+				timeout = 0
+			}
+			if v := r.URL.Query().Get("timeout"); v != "" {
+				d, err := time.ParseDuration(v)
+				if err != nil || d <= 0 || d > MaxRequestTimeout {
+					http.Error(w, fmt.Sprintf("invalid timeout parameter (use a Go duration such as 5s, at most %s)", MaxRequestTimeout), http.StatusBadRequest)
+					return
+				}
+				timeout = d
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			tw := &timeoutWriter{ResponseWriter: w, ctx: ctx, timeout: timeout}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			if !tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				tw.timedOut()
+			}
+		})
+	}
+}
+
+// timeoutWriter turns an error response written after the deadline has
+// passed into a 504
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	timeout     time.Duration
+	wroteHeader bool
+	replaced    bool // The handler's response was swapped for a 504
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	if tw.wroteHeader {
+		return
+	}
+	if code >= http.StatusBadRequest && errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		tw.timedOut()
+		return
+	}
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.replaced {
+		return len(b), nil // Drop the handler's error body
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// timedOut writes the 504
+func (tw *timeoutWriter) timedOut() {
+	tw.wroteHeader, tw.replaced = true, true
+	h := tw.Header()
+	h.Del("Content-Length")
+	h.Del("ETag")
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	tw.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	fmt.Fprintf(tw.ResponseWriter, "request timed out after %s; its queries were cancelled (raise ?timeout=, at most %s)\n", tw.timeout, MaxRequestTimeout)
+}
+
+func (tw *timeoutWriter) Flush() {
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			http.Error(w, r.Context().Err().Error(), http.StatusInternalServerError)
+		case <-time.After(time.Second):
+			w.Write([]byte("done"))
+		}
+	})
+	serve := func(def time.Duration, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		Timeout(def)(slow).ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	if w := serve(0, "/api/query/search?timeout=20ms"); w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), "timed out after 20ms") {
+		t.Errorf("timed out request = %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(20*time.Millisecond, "/api/query/search"); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("default timeout = %d", w.Code)
+	}
+	if w := serve(20*time.Millisecond, "/api/query/search?timeout=5s"); w.Code != http.StatusOK || w.Body.String() != "done" {
+		t.Errorf("request within its timeout = %d %q", w.Code, w.Body.String())
+	}
+	for _, v := range []string{"soon", "-1s", "1h"} {
+		if w := serve(0, "/api/query/search?timeout="+v); w.Code != http.StatusBadRequest {
+			t.Errorf("timeout=%s status = %d", v, w.Code)
+		}
+	}
+}
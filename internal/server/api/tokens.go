@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/signing"
+	"github.com/systemshift/memex/internal/server/tokens"
+)
+
+// unauthenticatedRoutes check their own credentials (a hook token or a
+// webhook signature), so need no API key
+var unauthenticatedRoutes = regexp.MustCompile(`^/(hooks/[^/]+|github/webhook)/?$`)
+
+// tokenRoutes are the endpoints an access token may call: node, link and
+// query APIs whose data passes through the scoped repository. Admin,
+// import, export and background-job endpoints need a key.
+var tokenRoutes = regexp.MustCompile(`^/(nodes|links|edges|query|graph|ingest|capture|lenses|sync|tokens/self|n)(/|$)`)
+
+type tokenKey struct{}
+
+type signerKey struct{}
+
+// SetAccessTokens enables authentication: every API request then needs
+// the admin key, an author API key, or an access token minted with the
+// admin key
+func (s *Server) SetAccessTokens(adminKey string) {
+	s.adminKey = adminKey
+	s.tokenIssuer = tokens.NewIssuer(adminKey)
+}
+
+// SigningScope limits what requests signed with one key may do, the way
+// a scope limits an access token
+type SigningScope struct {
+	graph.Scope
+	Routes []string `json:"routes,omitempty"` // Path prefixes under /api the key may call; empty allows every token route
+}
+
+// DefaultSigningScope applies to signing keys without a configured scope:
+// reads and writes through the token routes, anywhere in the graph
+var DefaultSigningScope = SigningScope{Scope: graph.Scope{Write: true}}
+
+// ParseSigningScopes reads a JSON object mapping signing key IDs to scopes
+func ParseSigningScopes(spec string) (map[string]*SigningScope, error) {
+	scopes := make(map[string]*SigningScope)
+	if strings.TrimSpace(spec) == "" {
+		return scopes, nil
+	}
+	if err := json.Unmarshal([]byte(spec), &scopes); err != nil {
+		return nil, err
+	}
+	for id, scope := range scopes {
+		if scope == nil {
+			return nil, fmt.Errorf("signing key %s: scope is null", id)
+		}
+		for _, route := range scope.Routes {
+			if !strings.HasPrefix(route, "/") {
+				return nil, fmt.Errorf("signing key %s: route %q must start with /", id, route)
+			}
+		}
+	}
+	return scopes, nil
+}
+
+// SetRequestSigning accepts requests signed with one of the verifier's
+// keys in place of an API key. Each key is limited to its scope in
+// scopes, or to DefaultSigningScope.
+func (s *Server) SetRequestSigning(v *signing.Verifier, scopes map[string]*SigningScope) {
+	s.requestVerifier = v
+	s.signingScopes = scopes
+}
+
+// signingScope returns the scope of a signing key
+func (s *Server) signingScope(keyID string) *SigningScope {
+	if scope := s.signingScopes[keyID]; scope != nil {
+		return scope
+	}
+	scope := DefaultSigningScope
+	return &scope
+}
+
+// allowsRoute reports whether the scope's route prefixes admit path
+func (sc *SigningScope) allowsRoute(path string) bool {
+	if len(sc.Routes) == 0 {
+		return true
+	}
+	for _, route := range sc.Routes {
+		route = strings.TrimSuffix(route, "/")
+		if path == route || strings.HasPrefix(path, route+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// requestKey returns the credential sent with a request, from X-API-Key or
+// a bearer token
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// isAdmin reports whether a request carries the admin key
+func (s *Server) isAdmin(r *http.Request) bool {
+	key := requestKey(r)
+	return s.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.adminKey)) == 1
+}
+
+// requestSigner returns the ID of the key a request was signed with
+func requestSigner(ctx context.Context) string {
+	keyID, _ := ctx.Value(signerKey{}).(string)
+	return keyID
+}
+
+// requestToken returns the access token a request was authenticated with
+func requestToken(ctx context.Context) *tokens.Token {
+	tok, _ := ctx.Value(tokenKey{}).(*tokens.Token)
+	return tok
+}
+
+// Authenticate is middleware that rejects requests without a valid
+// credential with 401. Requests made with an access token or a signing
+// key are limited to tokenRoutes, to reads unless their scope may write,
+// and to that scope. Signed requests are checked whenever request signing
+// is set up; other requests only once SetAccessTokens is called.
+func (s *Server) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := routePath(r)
+		if r.Method == http.MethodOptions || unauthenticatedRoutes.MatchString(path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.requestVerifier != nil && signing.Signed(r) {
+			keyID, err := s.requestVerifier.Verify(r, time.Now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			scope := s.signingScope(keyID)
+			if !scope.allowsRoute(path) {
+				http.Error(w, "signing key "+keyID+" cannot call this endpoint", http.StatusForbidden)
+				return
+			}
+			if !checkScopedAccess(w, r, path, &scope.Scope, "signing key") {
+				return
+			}
+			ctx := context.WithValue(r.Context(), signerKey{}, keyID)
+			next.ServeHTTP(w, r.WithContext(graph.WithScope(ctx, &scope.Scope)))
+			return
+		}
+		if s.tokenIssuer == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := requestKey(r)
+		if s.isAdmin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !tokens.IsToken(key) {
+			if _, ok := s.apiKeyAuthors[key]; ok && key != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="memex"`)
+			http.Error(w, "a valid API key or access token is required", http.StatusUnauthorized)
+			return
+		}
+
+		tok, err := s.tokenIssuer.Verify(key, time.Now())
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="memex", error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !checkScopedAccess(w, r, path, &tok.Scope, "access token") {
+			return
+		}
+		ctx := context.WithValue(r.Context(), tokenKey{}, tok)
+		next.ServeHTTP(w, r.WithContext(graph.WithScope(ctx, &tok.Scope)))
+	})
+}
+
+// checkScopedAccess limits a scoped credential to tokenRoutes and to reads
+// unless its scope may write, writing a 403 and returning false otherwise
+func checkScopedAccess(w http.ResponseWriter, r *http.Request, path string, scope *graph.Scope, credential string) bool {
+	if !tokenRoutes.MatchString(path) {
+		http.Error(w, credential+"s cannot call this endpoint", http.StatusForbidden)
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		if !scope.Write && !readOnlyPosts.MatchString(path) {
+			http.Error(w, credential+" is read-only", http.StatusForbidden)
+			return false
+		}
+	default:
+		if !scope.Write {
+			http.Error(w, credential+" is read-only", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// CreateTokenRequest is the body of POST /api/tokens
+type CreateTokenRequest struct {
+	Name       string   `json:"name"`
+	Namespaces []string `json:"namespaces,omitempty"`
+	Types      []string `json:"types,omitempty"`
+	Write      bool     `json:"write"`
+	Lane       string   `json:"lane,omitempty"`       // interactive or bulk; default by route
+	ExpiresIn  string   `json:"expires_in,omitempty"` // Go duration; default 1h
+}
+
+// CreateToken handles POST /api/tokens
+// Mints an access token limited to the given namespaces, node types and
+// capabilities. Only the admin key may mint tokens.
+func (s *Server) CreateToken(w http.ResponseWriter, r *http.Request) {
+	if s.tokenIssuer == nil {
+		notEnabled(w, "access tokens are not enabled (set MEMEX_ADMIN_KEY)")
+		return
+	}
+	if !s.isAdmin(r) {
+		http.Error(w, "only the admin key may mint access tokens", http.StatusForbidden)
+		return
+	}
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			http.Error(w, "invalid expires_in: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+
+	scope := graph.Scope{Namespaces: req.Namespaces, Types: req.Types, Write: req.Write, Lane: req.Lane}
+	raw, tok, err := s.tokenIssuer.Issue(req.Name, scope, ttl, time.Now())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, tokens.ErrBadRequest) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      raw,
+		"id":         tok.ID,
+		"name":       tok.Name,
+		"scope":      tok.Scope,
+		"expires_at": tok.ExpiresAt,
+	})
+}
+
+// GetTokenSelf handles GET /api/tokens/self
+// Returns the scope and expiry of the access token used for the request.
+func (s *Server) GetTokenSelf(w http.ResponseWriter, r *http.Request) {
+	tok := requestToken(r.Context())
+	if tok == nil {
+		http.Error(w, "request was not made with an access token", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tok)
+}
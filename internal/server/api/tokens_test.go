@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/signing"
+)
+
+func TestSignedRequestScopes(t *testing.T) {
+	s := New(graph.NewMemory(), nil)
+	s.SetAccessTokens("admin-key")
+	scopes, err := ParseSigningScopes(`{
+		"reader":  {"write": false},
+		"capture": {"write": true, "namespaces": ["inbox"], "routes": ["/capture", "/ingest"]}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]string{"agent": "s1", "reader": "s2", "capture": "s3"}
+	s.SetRequestSigning(signing.NewVerifier(keys, 0), scopes)
+
+	var gotScope *graph.Scope
+	handler := s.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotScope = graph.ScopeFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		want   int
+	}{
+		{"default scope reads", "agent", "GET", "/api/nodes/note:1", http.StatusOK},
+		{"default scope writes", "agent", "POST", "/api/nodes", http.StatusOK},
+		{"admin route refused", "agent", "POST", "/api/admin/erase", http.StatusForbidden},
+		{"fault injection refused", "agent", "POST", "/api/admin/faults", http.StatusForbidden},
+		{"token minting refused", "agent", "POST", "/api/tokens", http.StatusForbidden},
+		{"read-only key reads", "reader", "GET", "/api/query/filter", http.StatusOK},
+		{"read-only key cannot write", "reader", "DELETE", "/api/nodes/note:1", http.StatusForbidden},
+		{"read-only key may run read-only POSTs", "reader", "POST", "/api/query/pattern", http.StatusOK},
+		{"route-limited key on its route", "capture", "POST", "/api/capture", http.StatusOK},
+		{"route-limited key elsewhere", "capture", "GET", "/api/query/filter", http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotScope = nil
+			req := httptest.NewRequest(tt.method, tt.path+"?n="+string(rune('a'+i)), strings.NewReader(""))
+			signing.Sign(req, nil, tt.key, keys[tt.key], time.Now())
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if w.Code == http.StatusOK && gotScope == nil {
+				t.Error("signed request reached the handler without a scope")
+			}
+		})
+	}
+
+	// The key's scope limits what the repository shows it
+	req := httptest.NewRequest("POST", "/api/capture", nil)
+	signing.Sign(req, nil, "capture", "s3", time.Now())
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if gotScope == nil || len(gotScope.Namespaces) != 1 || gotScope.Namespaces[0] != "inbox" {
+		t.Errorf("capture scope = %+v", gotScope)
+	}
+}
+
+func TestParseSigningScopes(t *testing.T) {
+	if scopes, err := ParseSigningScopes(""); err != nil || len(scopes) != 0 {
+		t.Errorf("empty spec = %v, %v", scopes, err)
+	}
+	for _, spec := range []string{`not json`, `{"a": null}`, `{"a": {"routes": ["capture"]}}`} {
+		if _, err := ParseSigningScopes(spec); err == nil {
+			t.Errorf("ParseSigningScopes(%s) accepted", spec)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// readContext returns the request context, extended to find deleted nodes
+// when the request has ?include_deleted=true
+func readContext(r *http.Request) context.Context {
+	if r.URL.Query().Get("include_deleted") == "true" {
+		return graph.WithDeleted(r.Context())
+	}
+	return r.Context()
+}
+
+// ListTrash handles GET /api/trash
+// Lists deleted nodes, most recently deleted first, each as its last live
+// version with Deleted and DeletedAt set. Supports ?limit= and ?offset=.
+func (s *Server) ListTrash(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+	if limit < 1 || offset < 0 {
+		http.Error(w, "invalid limit or offset parameter", http.StatusBadRequest)
+		return
+	}
+
+	nodes, err := s.repo.ListDeleted(r.Context(), limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"nodes": nodes,
+		"count": len(nodes),
+	})
+}
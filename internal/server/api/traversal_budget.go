@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// SetTraversalBudget sets the most work one traverse or subgraph request
+// may do; requests can ask for less
+func (s *Server) SetTraversalBudget(budget graph.TraversalBudget) {
+	s.traversalBudget = budget
+}
+
+// withTraversalBudget returns the request context limited to the budget
+// asked for with ?max_nodes=, ?max_edges= and ?max_time=, within the
+// server's, and the meter reporting its use
+func (s *Server) withTraversalBudget(r *http.Request) (context.Context, *graph.TraversalMeter, error) {
+	query := r.URL.Query()
+	var budget graph.TraversalBudget
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"max_nodes", &budget.MaxNodes}, {"max_edges", &budget.MaxEdges}} {
+		if v := query.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, nil, fmt.Errorf("invalid %s parameter", p.name)
+			}
+			*p.dst = n
+		}
+	}
+	if v := query.Get("max_time"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, nil, fmt.Errorf("invalid max_time parameter (use a Go duration such as 2s)")
+		}
+		budget.MaxTime = d
+	}
+	ctx, meter := graph.ContextWithTraversalBudget(r.Context(), budget.Within(s.traversalBudget))
+	return ctx, meter, nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// trendParams are the query parameters of GET /api/query/trend that are
+// not property filters
+var trendParams = map[string]bool{
+	"metric": true, "field": true, "type": true, "interval": true,
+	"from": true, "to": true, "layer": true, "timeout": true,
+}
+
+// QueryTrend handles GET /api/query/trend
+// Evaluates ?metric= (count, sum, avg, min or max of ?field=) over the
+// nodes of ?type= at the end of each ?interval= (day or week) between
+// ?from= and ?to= (default the last 12 weeks), as they were then. Other
+// parameters are property filters, e.g. ?status=open.
+func (s *Server) QueryTrend(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := graph.TrendQuery{
+		Types:    listParam(query["type"]),
+		Metric:   query.Get("metric"),
+		Field:    query.Get("field"),
+		Interval: query.Get("interval"),
+	}
+	for key, values := range query {
+		if !trendParams[key] && len(values) > 0 {
+			if q.Where == nil {
+				q.Where = make(map[string]string)
+			}
+			q.Where[key] = values[0]
+		}
+	}
+	layers, err := layerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Layers = layers
+	if q.From, q.To, err = parseTimeWindow(r, 12*7*24*time.Hour); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := q.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trend, err := graph.RunTrend(r.Context(), s.repo, q)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trend)
+}
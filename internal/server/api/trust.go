@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/experiments"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// trustCandidateFactor is how many extra search hits are fetched so
+// trust-weighted ranking can promote results from beyond the requested page
+const trustCandidateFactor = 3
+
+// SetTrustRequest is the request body for assigning trust to a node
+type SetTrustRequest struct {
+	Trust     *float64 `json:"trust"`
+	ChangedBy string   `json:"changed_by,omitempty"`
+}
+
+// SetTrustPolicy sets the connector/domain trust used for Source nodes
+func (s *Server) SetTrustPolicy(p *graph.TrustPolicy) {
+	s.trustPolicy = p
+}
+
+// GetTrust handles GET /api/nodes/{id}/trust
+// Returns the node's effective trust and whether it was set explicitly.
+func (s *Server) GetTrust(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	node, err := s.repo.GetNode(r.Context(), id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("node not found: %s", id), http.StatusNotFound)
+		return
+	}
+	trust, err := s.trustPolicy.EffectiveTrust(r.Context(), s.repo, id)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	_, explicit := graph.NodeTrust(node)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       id,
+		"trust":    trust,
+		"explicit": explicit,
+	})
+}
+
+// SetTrust handles PUT /api/nodes/{id}/trust
+// Stores an explicit trust score in [0, 1] on a node, typically a Source.
+func (s *Server) SetTrust(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req SetTrustRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Trust == nil || *req.Trust < 0 || *req.Trust > 1 {
+		http.Error(w, "trust must be a number between 0 and 1", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.repo.GetNode(r.Context(), id); err != nil {
+		http.Error(w, fmt.Sprintf("node not found: %s", id), http.StatusNotFound)
+		return
+	}
+	note := fmt.Sprintf("Trust set to %g", *req.Trust)
+	if err := s.repo.UpdateNodeMetaWithNote(r.Context(), id, map[string]any{graph.TrustKey: *req.Trust}, note, req.ChangedBy); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":    id,
+		"trust": *req.Trust,
+	})
+}
+
+// rankByTrust reorders search hits by a ranking strategy, by default
+// relevance x effective trust, where relevance decays with the backend's
+// rank (1, 1/2, 1/3, ...). With uniform trust the backend's order is
+// preserved.
+func (s *Server) rankByTrust(ctx context.Context, nodes []*core.Node, strategy experiments.Strategy) (map[string]float64, error) {
+	trust := make(map[string]float64, len(nodes))
+	score := make(map[string]float64, len(nodes))
+	now := time.Now()
+	for i, node := range nodes {
+		t, err := s.trustPolicy.EffectiveTrust(ctx, s.repo, node.ID)
+		if err != nil {
+			return nil, err
+		}
+		trust[node.ID] = t
+		score[node.ID] = strategy.Score(i+1, t, now.Sub(node.Modified).Hours()/24)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return score[nodes[i].ID] > score[nodes[j].ID]
+	})
+	return trust, nil
+}
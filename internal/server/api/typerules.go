@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/infer"
+)
+
+// TestTypeRulesRequest is the request body for POST /api/type-rules/test
+type TestTypeRulesRequest struct {
+	Rules    []*infer.Rule   `json:"rules,omitempty"`     // Rules to try out; default the stored ones
+	JSON     json.RawMessage `json:"json,omitempty"`      // A document
+	SourceID string          `json:"source_id,omitempty"` // Or an ingested one
+}
+
+// SetTypeInference enables typing of ingested JSON sources
+func (s *Server) SetTypeInference(p *infer.Processor) {
+	s.typeInference = p
+}
+
+// typeRuleStatus maps type rule errors to HTTP status codes
+func typeRuleStatus(err error) int {
+	if errors.Is(err, infer.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return writeErrorStatus(err, http.StatusBadRequest)
+}
+
+// CreateTypeRule handles POST /api/type-rules
+func (s *Server) CreateTypeRule(w http.ResponseWriter, r *http.Request) {
+	var rule infer.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rule.ID != "" {
+		if _, err := infer.Get(r.Context(), s.repo, rule.ID); err == nil {
+			http.Error(w, "type rule already exists: "+rule.ID, http.StatusConflict)
+			return
+		}
+	}
+	if err := infer.Save(r.Context(), s.repo, &rule); err != nil {
+		http.Error(w, err.Error(), typeRuleStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// ListTypeRules handles GET /api/type-rules
+// Returns the rules in the order they are tried
+func (s *Server) ListTypeRules(w http.ResponseWriter, r *http.Request) {
+	list, err := infer.List(r.Context(), s.repo)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules": list,
+		"count": len(list),
+	})
+}
+
+// GetTypeRule handles GET /api/type-rules/{id}
+func (s *Server) GetTypeRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := infer.Get(r.Context(), s.repo, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), typeRuleStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateTypeRule handles PUT /api/type-rules/{id}
+// Replaces the rule's definition. Nodes it already typed stay.
+func (s *Server) UpdateTypeRule(w http.ResponseWriter, r *http.Request) {
+	var rule infer.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rule.ID = chi.URLParam(r, "id")
+	if err := infer.Update(r.Context(), s.repo, &rule); err != nil {
+		http.Error(w, err.Error(), typeRuleStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteTypeRule handles DELETE /api/type-rules/{id}
+func (s *Server) DeleteTypeRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := infer.Delete(r.Context(), s.repo, id); err != nil {
+		http.Error(w, err.Error(), typeRuleStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": id,
+	})
+}
+
+// TestTypeRules handles POST /api/type-rules/test
+// Dry-runs rules (given inline, or the stored ones) against a JSON
+// document or an ingested source, returning the nodes and relations they
+// would create. Nothing is written.
+func (s *Server) TestTypeRules(w http.ResponseWriter, r *http.Request) {
+	var req TestTypeRulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var data []byte
+	switch {
+	case len(req.JSON) > 0 && req.SourceID != "":
+		http.Error(w, "give json or source_id, not both", http.StatusBadRequest)
+		return
+	case len(req.JSON) > 0:
+		data = req.JSON
+	case req.SourceID != "":
+		source, err := s.repo.GetNode(r.Context(), req.SourceID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		data = source.Content
+	default:
+		http.Error(w, "json or source_id is required", http.StatusBadRequest)
+		return
+	}
+	doc, err := infer.Decode(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rules := req.Rules
+	if len(rules) == 0 {
+		if rules, err = infer.List(r.Context(), s.repo); err != nil {
+			http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+			return
+		}
+	} else {
+		for i, rule := range rules {
+			if rule.ID == "" {
+				rule.ID = fmt.Sprintf("test-%d", i+1)
+			}
+			if err := rule.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		infer.Sort(rules)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infer.Infer(doc, rules, 0))
+}
+
+// ApplyTypeRules handles POST /api/type-rules/apply
+// Types an ingested JSON source by the stored rules now, including one
+// typed before, such as after the rules changed. The body is
+// {"source_id": "sha256:..."}.
+func (s *Server) ApplyTypeRules(w http.ResponseWriter, r *http.Request) {
+	if s.typeInference == nil {
+		notEnabled(w, "JSON type inference is disabled")
+		return
+	}
+	var req struct {
+		SourceID string `json:"source_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.SourceID == "" {
+		http.Error(w, "source_id is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.repo.GetNode(r.Context(), req.SourceID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	result, err := s.typeInference.ProcessNode(r.Context(), req.SourceID, true)
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	if result == nil {
+		http.Error(w, "not a JSON source: "+req.SourceID, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/ontology"
+)
+
+// QuotaStatus is a configured quota with its current usage
+type QuotaStatus struct {
+	graph.Quota
+	UsedNodes int64 `json:"used_nodes"`
+	UsedBytes int64 `json:"used_bytes"`
+	Exceeded  bool  `json:"exceeded"`
+}
+
+// SetQuotas records the configured quotas for reporting (enforcement is
+// done by graph.WithQuotas)
+func (s *Server) SetQuotas(quotas []graph.Quota) {
+	s.quotas = quotas
+}
+
+// GetUsage handles GET /api/admin/usage
+// Returns node counts and stored bytes overall, per namespace (ID prefix)
+// and per type, plus each configured quota's usage.
+func (s *Server) GetUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.repo.GetUsage(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	quotas := make([]QuotaStatus, 0, len(s.quotas))
+	for _, q := range s.quotas {
+		used := q.Used(usage)
+		quotas = append(quotas, QuotaStatus{
+			Quota:     q,
+			UsedNodes: used.Nodes,
+			UsedBytes: used.Bytes(),
+			Exceeded:  (q.MaxNodes > 0 && used.Nodes > q.MaxNodes) || (q.MaxBytes > 0 && used.Bytes() > q.MaxBytes),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"usage":  usage,
+		"quotas": quotas,
+	})
+}
+
+// writeErrorStatus maps a repository error to an HTTP status: 507 when a
+// quota would be exceeded, 403 for a read-only namespace or a write
+// outside an access token's scope, 400 for a bad alias, 409 for a taken
+// one, 503 when the backend cannot be reached, otherwise fallback
+func writeErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, graph.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, graph.ErrReadOnly), errors.Is(err, graph.ErrOutOfScope):
+		return http.StatusForbidden
+	case errors.Is(err, graph.ErrInvalidAlias), errors.Is(err, ontology.ErrViolation):
+		return http.StatusBadRequest
+	case errors.Is(err, graph.ErrAliasTaken):
+		return http.StatusConflict
+	case graph.IsUnavailable(err):
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// withValidTime stores the valid_from and valid_to fields of a request in
+// its properties and standardizes them
+func withValidTime(meta map[string]interface{}, from, to string) (map[string]interface{}, error) {
+	if from != "" || to != "" {
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+		if from != "" {
+			meta[graph.ValidFromKey] = from
+		}
+		if to != "" {
+			meta[graph.ValidToKey] = to
+		}
+	}
+	if err := graph.NormalizeValidTime(meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// timeFilterParam reads ?as_of_valid= (facts that held at that time) and
+// ?as_of_recorded= (the graph as recorded then), as RFC3339 times or
+// dates; nil selects current data
+func timeFilterParam(r *http.Request) (*graph.TimeFilter, error) {
+	query := r.URL.Query()
+	var f graph.TimeFilter
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"as_of_valid", &f.ValidAt}, {"as_of_recorded", &f.RecordedAt}} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := graph.ParseTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s parameter (use RFC3339 or YYYY-MM-DD)", p.name)
+		}
+		*p.dst = t
+	}
+	if f.ValidAt.IsZero() && f.RecordedAt.IsZero() {
+		return nil, nil
+	}
+	return &f, nil
+}
+
+// nodeAtTimes returns the version of a node selected by a time filter,
+// looking through deleted nodes' history too
+func (s *Server) nodeAtTimes(ctx context.Context, id string, times *graph.TimeFilter) (*core.Node, error) {
+	node, err := s.repo.GetNode(graph.WithDeleted(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if node, err = times.Node(ctx, s.repo, node); err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("node %s has no version for the requested time", id)
+	}
+	return node, nil
+}
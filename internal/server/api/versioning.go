@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/apiversion"
+)
+
+type pathVersionKey struct{}
+
+// SetDeprecations enables deprecation notices on the routes t covers
+func (s *Server) SetDeprecations(t *apiversion.Tracker) {
+	s.deprecations = t
+}
+
+// PathVersion serves next as version v of the API, for mounting the API
+// router under a versioned prefix such as /api/v1
+func PathVersion(v int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), pathVersionKey{}, v)))
+	})
+}
+
+// Versioning returns middleware for the API router that settles the
+// version each request is served at from its path and the
+// X-Memex-Api-Version header, rejecting unsupported ones with 400, and
+// reports it in the same header. Calls to a deprecated route, found in
+// routes, get Deprecation, Sunset and Link headers and are counted.
+func (s *Server) Versioning(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pathVersion, _ := r.Context().Value(pathVersionKey{}).(int)
+			v, err := apiversion.Negotiate(pathVersion, r.Header.Get(apiversion.Header))
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, CodeUnsupportedVersion, err.Error(), map[string]interface{}{
+					"supported": apiversion.Supported,
+				})
+				return
+			}
+			w.Header().Set(apiversion.Header, strconv.Itoa(v))
+
+			if s.deprecations != nil {
+				if route := routes.Find(chi.NewRouteContext(), r.Method, routePath(r)); route != "" {
+					if notice, ok := s.deprecations.Check(r.Method, route, pathVersion == 0); ok {
+						notice.SetHeaders(w.Header())
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(apiversion.WithVersion(r.Context(), v)))
+		})
+	}
+}
+
+// Deprecations handles GET /api/admin/deprecations
+// Lists the API versions served, the deprecated routes with their sunset
+// dates, and how often each has been called since the server started.
+func (s *Server) Deprecations(w http.ResponseWriter, r *http.Request) {
+	notices, usage := []apiversion.Notice{}, []apiversion.Usage{}
+	if s.deprecations != nil {
+		notices, usage = s.deprecations.Notices(), s.deprecations.Usage()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"current":      apiversion.Current,
+		"supported":    apiversion.Supported,
+		"deprecations": notices,
+		"usage":        usage,
+	})
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// warmupState tracks the startup warm-up /ready waits for
+type warmupState struct {
+	mu     sync.Mutex
+	done   bool
+	report *graph.WarmupReport
+	err    error
+}
+
+// StartWarmup warms the backend in the background. /ready answers 503
+// until it finishes.
+func (s *Server) StartWarmup(ctx context.Context, w graph.Warmer) {
+	state := &warmupState{}
+	s.warmup = state
+	go func() {
+		report, err := w.Warm(ctx)
+		state.mu.Lock()
+		state.done, state.report, state.err = true, report, err
+		state.mu.Unlock()
+		if err != nil {
+			log.Printf("Warm-up failed: %v", err)
+			return
+		}
+		log.Printf("Warm-up complete in %.0fms (%d tables, %d indexes)", report.DurationMS, len(report.Tables), len(report.Indexes))
+	}()
+}
+
+// ReadyResponse is the response for GET /ready
+type ReadyResponse struct {
+	Status string              `json:"status"` // "ready", "warming" or "failed"
+	Error  string              `json:"error,omitempty"`
+	Warmup *graph.WarmupReport `json:"warmup,omitempty"`
+}
+
+// Ready handles GET /ready
+// Answers 200 once the server has warmed up and can serve queries quickly,
+// and 503 while it is warming or if the warm-up failed. Without a warm-up
+// the server is ready as soon as it listens.
+func (s *Server) Ready(w http.ResponseWriter, r *http.Request) {
+	resp, code := ReadyResponse{Status: "ready"}, http.StatusOK
+	if state := s.warmup; state != nil {
+		state.mu.Lock()
+		switch {
+		case !state.done:
+			resp.Status, code = "warming", http.StatusServiceUnavailable
+			w.Header().Set("Retry-After", "1")
+		case state.err != nil:
+			resp.Status, resp.Error, code = "failed", state.err.Error(), http.StatusServiceUnavailable
+		default:
+			resp.Warmup = state.report
+		}
+		state.mu.Unlock()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// gatedWarmer finishes its warm-up when release is closed
+type gatedWarmer struct {
+	release chan struct{}
+	err     error
+}
+
+func (g *gatedWarmer) Warm(ctx context.Context) (*graph.WarmupReport, error) {
+	<-g.release
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &graph.WarmupReport{Tables: []graph.TableWarmup{{Name: "nodes", Rows: 3}}}, nil
+}
+
+func TestReady(t *testing.T) {
+	ready := func(s *Server) (int, ReadyResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Ready(w, httptest.NewRequest("GET", "/ready", nil))
+		var resp ReadyResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, resp
+	}
+	waitFor := func(s *Server, status string) (int, ReadyResponse) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if code, resp := ready(s); resp.Status == status {
+				return code, resp
+			}
+		}
+		t.Fatalf("never %s", status)
+		return 0, ReadyResponse{}
+	}
+
+	// Without a warm-up the server is ready straight away
+	if code, resp := ready(New(graph.NewMemory(), nil)); code != http.StatusOK || resp.Status != "ready" {
+		t.Errorf("no warm-up: %d %+v", code, resp)
+	}
+
+	s := New(graph.NewMemory(), nil)
+	warmer := &gatedWarmer{release: make(chan struct{})}
+	s.StartWarmup(context.Background(), warmer)
+	if code, resp := ready(s); code != http.StatusServiceUnavailable || resp.Status != "warming" {
+		t.Errorf("warming: %d %+v", code, resp)
+	}
+	close(warmer.release)
+	if code, resp := waitFor(s, "ready"); code != http.StatusOK || resp.Warmup == nil || resp.Warmup.Tables[0].Rows != 3 {
+		t.Errorf("warmed: %d %+v", code, resp)
+	}
+
+	s = New(graph.NewMemory(), nil)
+	warmer = &gatedWarmer{release: make(chan struct{}), err: errors.New("checking index idx_nodes_type: no query solution")}
+	close(warmer.release)
+	s.StartWarmup(context.Background(), warmer)
+	if code, resp := waitFor(s, "failed"); code != http.StatusServiceUnavailable || resp.Error == "" {
+		t.Errorf("failed: %d %+v", code, resp)
+	}
+}
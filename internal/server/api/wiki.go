@@ -0,0 +1,47 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/systemshift/memex/internal/server/importer"
+)
+
+// maxWikiUpload caps a Notion or Confluence export upload
+const maxWikiUpload = 512 << 20
+
+// ImportNotion handles POST /api/import/notion
+// The body is a Notion "Markdown & CSV" export ZIP.
+func (s *Server) ImportNotion(w http.ResponseWriter, r *http.Request) {
+	s.importWiki(w, r, "notion", importer.ParseNotionExport)
+}
+
+// ImportConfluence handles POST /api/import/confluence
+// The body is a Confluence space HTML export ZIP.
+func (s *Server) ImportConfluence(w http.ResponseWriter, r *http.Request) {
+	s.importWiki(w, r, "confluence", importer.ParseConfluenceExport)
+}
+
+// importWiki reads an export ZIP with parse and imports its pages
+func (s *Server) importWiki(w http.ResponseWriter, r *http.Request, connector string, parse func(io.ReaderAt, int64) ([]importer.Page, error)) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWikiUpload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	pages, err := parse(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := importer.ImportPages(r.Context(), s.repo, pages, importer.WikiOptions{Connector: connector, ChangedBy: r.URL.Query().Get("changed_by")})
+	if err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
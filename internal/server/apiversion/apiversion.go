@@ -0,0 +1,255 @@
+// Package apiversion settles which version of the API a request is served
+// at, and tracks calls to deprecated routes so a route can change or go
+// away on an announced date instead of breaking its clients silently.
+package apiversion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Current is the newest API version
+const Current = 1
+
+// Unversioned is the version the unversioned /api prefix serves when a
+// request does not ask for one. It stays at 1, so clients written before
+// versioning keep the behaviour they were written against.
+const Unversioned = 1
+
+// Supported lists the versions this server serves, oldest first
+var Supported = []int{1}
+
+// Header asks for an API version, and reports the version a response was
+// served at
+const Header = "X-Memex-Api-Version"
+
+// ErrUnsupported is returned when a request asks for a version this server
+// does not serve, or for one that disagrees with its path
+var ErrUnsupported = errors.New("unsupported API version")
+
+// Negotiate returns the version a request is served at. pathVersion is the
+// version in the request path, 0 for the unversioned prefix; header is
+// the request's Header value, which may be empty.
+func Negotiate(pathVersion int, header string) (int, error) {
+	v := pathVersion
+	if header = strings.TrimPrefix(strings.TrimSpace(header), "v"); header != "" {
+		n, err := strconv.Atoi(header)
+		if err != nil {
+			return 0, fmt.Errorf("%w %q", ErrUnsupported, header)
+		}
+		if pathVersion != 0 && n != pathVersion {
+			return 0, fmt.Errorf("%w: header asks for %d, path for %d", ErrUnsupported, n, pathVersion)
+		}
+		v = n
+	}
+	if v == 0 {
+		v = Unversioned
+	}
+	for _, s := range Supported {
+		if s == v {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("%w %d (supported: %s)", ErrUnsupported, v, supportedList())
+}
+
+func supportedList() string {
+	parts := make([]string, len(Supported))
+	for i, v := range Supported {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+type versionKey struct{}
+
+// WithVersion returns a context for a request served at version v
+func WithVersion(ctx context.Context, v int) context.Context {
+	return context.WithValue(ctx, versionKey{}, v)
+}
+
+// FromContext returns the version a request is served at, Unversioned if
+// none was settled
+func FromContext(ctx context.Context) int {
+	if v, ok := ctx.Value(versionKey{}).(int); ok {
+		return v
+	}
+	return Unversioned
+}
+
+// Notice deprecates a route: calls keep working, but responses announce
+// the deprecation, and the sunset date after which the route may go
+type Notice struct {
+	Method    string    `json:"method"`              // "*" for every method
+	Route     string    `json:"route"`               // Route pattern within the API, e.g. /lenses/{id}
+	Sunset    time.Time `json:"sunset,omitzero"`     // When the route may be removed
+	Successor string    `json:"successor,omitempty"` // What to call instead
+}
+
+// matches reports whether the notice covers a call
+func (n *Notice) matches(method, route string) bool {
+	return (n.Method == "*" || n.Method == method) && n.Route == route
+}
+
+// SetHeaders announces the notice on a response: Deprecation, Sunset
+// (RFC 8594) and a Link to the successor
+func (n *Notice) SetHeaders(h http.Header) {
+	h.Set("Deprecation", "true")
+	if !n.Sunset.IsZero() {
+		h.Set("Sunset", n.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if n.Successor != "" {
+		h.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", n.Successor))
+	}
+}
+
+// ParseNotices reads deprecations from a comma-separated list of
+// "METHOD /route [sunset] [successor]" entries, the sunset a date such as
+// 2027-01-31, e.g. "GET /query/by_lens 2027-01-31 /api/v1/lenses/{id}/entities"
+func ParseNotices(spec string) ([]Notice, error) {
+	var notices []Notice
+	for _, entry := range strings.Split(spec, ",") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 4 || !strings.HasPrefix(fields[1], "/") {
+			return nil, fmt.Errorf("deprecation %q: want METHOD /route [sunset] [successor]", strings.TrimSpace(entry))
+		}
+		n := Notice{Method: strings.ToUpper(fields[0]), Route: fields[1]}
+		for _, f := range fields[2:] {
+			switch {
+			case n.Successor == "" && (strings.HasPrefix(f, "/") || strings.Contains(f, "://")):
+				n.Successor = f
+			case n.Sunset.IsZero():
+				t, err := time.Parse(time.DateOnly, f)
+				if err != nil {
+					return nil, fmt.Errorf("deprecation %q: bad sunset date %q", strings.TrimSpace(entry), f)
+				}
+				n.Sunset = t
+			default:
+				return nil, fmt.Errorf("deprecation %q: want METHOD /route [sunset] [successor]", strings.TrimSpace(entry))
+			}
+		}
+		notices = append(notices, n)
+	}
+	return notices, nil
+}
+
+// Usage counts calls to one deprecated route. Unversioned calls are calls
+// through the unversioned prefix once it is deprecated.
+type Usage struct {
+	Method      string    `json:"method"`
+	Route       string    `json:"route"`
+	Unversioned bool      `json:"unversioned,omitempty"`
+	Calls       int64     `json:"calls"`
+	LastCall    time.Time `json:"last_call"`
+}
+
+type usageKey struct {
+	method, route string
+	unversioned   bool
+}
+
+// Tracker holds the deprecation notices and counts the calls each gets.
+// Counts are kept in memory, so they start over when the server restarts.
+type Tracker struct {
+	notices     []Notice
+	unversioned *Notice // Set once the unversioned prefix is deprecated
+
+	mu    sync.Mutex
+	usage map[usageKey]*Usage
+}
+
+// NewTracker creates a tracker for notices
+func NewTracker(notices []Notice) *Tracker {
+	return &Tracker{notices: notices, usage: make(map[usageKey]*Usage)}
+}
+
+// DeprecateUnversioned deprecates the unversioned /api prefix, with
+// successor the versioned prefix to move to. Calls through it are then
+// counted per route, showing which clients have yet to move.
+func (t *Tracker) DeprecateUnversioned(sunset time.Time, successor string) {
+	t.unversioned = &Notice{Method: "*", Route: "/*", Sunset: sunset, Successor: successor}
+}
+
+// Notices returns the configured notices, the unversioned prefix's last
+func (t *Tracker) Notices() []Notice {
+	notices := append([]Notice{}, t.notices...)
+	if t.unversioned != nil {
+		notices = append(notices, *t.unversioned)
+	}
+	return notices
+}
+
+// Check returns the notice covering a call to route, if any, and counts
+// the call against it. A notice for the route itself wins over the
+// unversioned prefix's.
+func (t *Tracker) Check(method, route string, unversioned bool) (*Notice, bool) {
+	var notice *Notice
+	for i := range t.notices {
+		if t.notices[i].matches(method, route) {
+			notice = &t.notices[i]
+			unversioned = false
+			break
+		}
+	}
+	if notice == nil {
+		if !unversioned || t.unversioned == nil {
+			return nil, false
+		}
+		notice = t.unversioned
+	}
+
+	key := usageKey{method: method, route: route, unversioned: unversioned}
+	t.mu.Lock()
+	u := t.usage[key]
+	if u == nil {
+		u = &Usage{Method: method, Route: route, Unversioned: unversioned}
+		t.usage[key] = u
+	}
+	u.Calls++
+	u.LastCall = time.Now().UTC()
+	t.mu.Unlock()
+	return notice, true
+}
+
+// Usage returns the calls counted so far, busiest route first
+func (t *Tracker) Usage() []Usage {
+	t.mu.Lock()
+	out := make([]Usage, 0, len(t.usage))
+	for _, u := range t.usage {
+		out = append(out, *u)
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Calls != out[j].Calls {
+			return out[i].Calls > out[j].Calls
+		}
+		if out[i].Route != out[j].Route {
+			return out[i].Route < out[j].Route
+		}
+		return out[i].Method < out[j].Method
+	})
+	return out
+}
+
+// WriteMetrics writes the call counts in the Prometheus text format
+func (t *Tracker) WriteMetrics(w io.Writer) error {
+	var b strings.Builder
+	name := "memex_deprecated_route_calls_total"
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, "Calls to deprecated API routes, by route.", name)
+	for _, u := range t.Usage() {
+		fmt.Fprintf(&b, "%s{method=%q,route=%q,unversioned=\"%t\"} %d\n", name, u.Method, u.Route, u.Unversioned, u.Calls)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,111 @@
+package apiversion
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		path   int
+		header string
+		want   int
+		ok     bool
+	}{
+		{0, "", Unversioned, true},
+		{0, "1", 1, true},
+		{0, "v1", 1, true},
+		{1, "", 1, true},
+		{1, "1", 1, true},
+		{0, "2", 0, false},
+		{0, "latest", 0, false},
+		{1, "2", 0, false},
+		{2, "", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := Negotiate(tt.path, tt.header)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("Negotiate(%d, %q) = %d, %v; want %d", tt.path, tt.header, got, err, tt.want)
+		}
+		if !tt.ok && !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Negotiate(%d, %q) error = %v, want ErrUnsupported", tt.path, tt.header, err)
+		}
+	}
+}
+
+func TestParseNotices(t *testing.T) {
+	notices, err := ParseNotices("GET /query/by_lens 2027-01-31 /api/v1/lenses/{id}/entities, * /edges/attention/store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notices) != 2 {
+		t.Fatalf("got %d notices, want 2", len(notices))
+	}
+	if n := notices[0]; n.Method != "GET" || n.Route != "/query/by_lens" || !n.Sunset.Equal(time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)) || n.Successor != "/api/v1/lenses/{id}/entities" {
+		t.Errorf("first notice = %+v", n)
+	}
+	if n := notices[1]; n.Method != "*" || !n.Sunset.IsZero() || n.Successor != "" {
+		t.Errorf("second notice = %+v", n)
+	}
+
+	for _, bad := range []string{"GET", "GET nodes", "GET /nodes soon", "GET /nodes 2027-01-31 2027-02-01"} {
+		if _, err := ParseNotices(bad); err == nil {
+			t.Errorf("ParseNotices(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	tr := NewTracker([]Notice{{Method: "GET", Route: "/query/by_lens", Sunset: sunset, Successor: "/api/v1/lenses/{id}/entities"}})
+
+	if _, ok := tr.Check("POST", "/query/by_lens", false); ok {
+		t.Error("notice matched another method")
+	}
+	if _, ok := tr.Check("GET", "/nodes/{id}", true); ok {
+		t.Error("unversioned call flagged before the prefix was deprecated")
+	}
+	n, ok := tr.Check("GET", "/query/by_lens", false)
+	if !ok {
+		t.Fatal("deprecated route not flagged")
+	}
+	h := http.Header{}
+	n.SetHeaders(h)
+	if h.Get("Deprecation") != "true" || h.Get("Sunset") != "Wed, 30 Jun 2027 00:00:00 GMT" || !strings.Contains(h.Get("Link"), "</api/v1/lenses/{id}/entities>") {
+		t.Errorf("headers = %v", h)
+	}
+
+	tr.DeprecateUnversioned(sunset, "/api/v1")
+	for range 2 {
+		if n, ok := tr.Check("GET", "/nodes/{id}", true); !ok || n.Successor != "/api/v1" {
+			t.Errorf("unversioned call = %+v, %v", n, ok)
+		}
+	}
+	if _, ok := tr.Check("GET", "/nodes/{id}", false); ok {
+		t.Error("versioned call flagged by the unversioned notice")
+	}
+	// The route's own notice wins, counted as a route call
+	tr.Check("GET", "/query/by_lens", true)
+
+	usage := tr.Usage()
+	if len(usage) != 2 {
+		t.Fatalf("usage = %+v", usage)
+	}
+	if u := usage[0]; u.Route != "/nodes/{id}" || !u.Unversioned || u.Calls != 2 {
+		t.Errorf("first usage = %+v", u)
+	}
+	if u := usage[1]; u.Route != "/query/by_lens" || u.Unversioned || u.Calls != 2 {
+		t.Errorf("second usage = %+v", u)
+	}
+
+	var b strings.Builder
+	if err := tr.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `memex_deprecated_route_calls_total{method="GET",route="/nodes/{id}",unversioned="true"} 2`) {
+		t.Errorf("metrics = %s", b.String())
+	}
+}
@@ -0,0 +1,546 @@
+// Package autocomplete suggests nodes as a user types, from an in-memory
+// prefix index kept current by repository events.
+package autocomplete
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/memory"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// maxCandidates caps the keys a query scores, so one-letter prefixes stay fast
+const maxCandidates = 50000
+
+// maxWords caps the word keys indexed per label
+const maxWords = 8
+
+// hiddenTypes are node types never suggested
+var hiddenTypes = map[string]bool{
+	people.AliasType: true, "Subscription": true, "SavedQuery": true, "LinkRule": true, graph.ErasureAuditType: true,
+	memory.NodeType: true, "Stats": true, "IngestHook": true, "GitHubSync": true, "TicketSync": true, "SynonymSet": true, "TypeRule": true,
+}
+
+// Match kinds, weakest first; a node scores by its strongest matching key
+const (
+	matchID = iota
+	matchWord
+	matchAlias
+	matchName
+)
+
+var matchNames = []string{"id", "word", "alias", "name"}
+
+// matchWeight is the base score of each match kind
+var matchWeight = []float64{1, 2, 2.5, 3}
+
+// Repository is the subset of graph operations the index needs
+type Repository interface {
+	ListNodes(ctx context.Context) ([]string, error)
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+}
+
+// Suggestion is a ranked autocomplete result
+type Suggestion struct {
+	ID     string  `json:"id"`
+	Type   string  `json:"type"`
+	Label  string  `json:"label"`
+	Match  string  `json:"match"`      // The indexed text that matched
+	Kind   string  `json:"match_kind"` // name, alias, word or id
+	Degree int     `json:"degree"`
+	Score  float64 `json:"score"`
+}
+
+// entry is an indexed node
+type entry struct {
+	id       string
+	typ      string
+	label    string
+	base     []key             // From the node itself
+	aliases  map[string]string // Alias node ID to its value
+	degree   int
+	modified time.Time
+}
+
+// key is one searchable string of a node; keys sort by text, then node
+type key struct {
+	text string
+	e    *entry
+	kind int
+}
+
+func (a key) less(b key) bool {
+	if a.text != b.text {
+		return a.text < b.text
+	}
+	if a.e.id != b.e.id {
+		return a.e.id < b.e.id
+	}
+	return a.kind < b.kind
+}
+
+// Index is a prefix index over node IDs, names and aliases
+type Index struct {
+	repo Repository
+
+	mu      sync.RWMutex
+	entries map[string]*entry
+	keys    []key             // Sorted
+	aliasOf map[string]string // Alias node ID to the node it names
+	ready   bool
+
+	events chan subscriptions.Event
+	stale  chan struct{} // Signals a rebuild after dropped events
+	wg     sync.WaitGroup
+}
+
+// NewIndex creates an empty index; Start builds it
+func NewIndex(repo Repository) *Index {
+	return &Index{
+		repo:    repo,
+		entries: make(map[string]*entry),
+		aliasOf: make(map[string]string),
+		events:  make(chan subscriptions.Event, 10000),
+		stale:   make(chan struct{}, 1),
+	}
+}
+
+// Start builds the index in the background and then applies events
+func (x *Index) Start() {
+	x.wg.Add(1)
+	go x.run()
+}
+
+// Stop waits for queued events to be applied
+func (x *Index) Stop() {
+	close(x.events)
+	x.wg.Wait()
+}
+
+// Ready reports whether the initial build has finished
+func (x *Index) Ready() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.ready
+}
+
+// Len returns the number of indexed nodes
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.entries)
+}
+
+// EmitEvent queues a graph change (non-blocking). When the queue is full
+// the event is dropped and the index rebuilt once the queue drains.
+func (x *Index) EmitEvent(event subscriptions.Event) {
+	select {
+	case x.events <- event:
+	default:
+		select {
+		case x.stale <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Rebuild schedules a fresh build of the index, made once the events
+// already queued are applied
+func (x *Index) Rebuild() {
+	select {
+	case x.stale <- struct{}{}:
+	default:
+	}
+	x.EmitEvent(subscriptions.Event{}) // Wakes the loop; applying it does nothing
+}
+
+func (x *Index) run() {
+	defer x.wg.Done()
+	x.rebuild()
+	for event := range x.events {
+		x.apply(event)
+		select {
+		case <-x.stale:
+			log.Printf("Autocomplete index fell behind, rebuilding")
+			x.rebuild()
+		default:
+		}
+	}
+}
+
+// rebuild reads every node and swaps in a fresh index
+func (x *Index) rebuild() {
+	start := time.Now()
+	ctx := context.Background()
+	fresh := &Index{entries: make(map[string]*entry), aliasOf: make(map[string]string)}
+	if err := fresh.Build(ctx, x.repo); err != nil {
+		log.Printf("Autocomplete index build failed: %v", err)
+		return
+	}
+	x.mu.Lock()
+	x.entries, x.keys, x.aliasOf, x.ready = fresh.entries, fresh.keys, fresh.aliasOf, true
+	x.mu.Unlock()
+	log.Printf("Autocomplete index built: %d nodes, %d keys in %s", len(fresh.entries), len(fresh.keys), time.Since(start).Round(time.Millisecond))
+}
+
+// Build indexes every node in repo. It is meant for an index not yet shared.
+func (x *Index) Build(ctx context.Context, repo Repository) error {
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		return err
+	}
+	var aliases []*core.Node
+	for _, id := range ids {
+		node, err := repo.GetNode(ctx, id)
+		if err != nil {
+			continue // Deleted since listing
+		}
+		if node.Type == people.AliasType {
+			aliases = append(aliases, node)
+			continue
+		}
+		if hiddenTypes[node.Type] {
+			continue
+		}
+		links, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return err
+		}
+		backlinks, err := repo.GetBacklinks(ctx, id)
+		if err != nil {
+			return err
+		}
+		e := newEntry(node)
+		e.degree = len(links) + len(backlinks)
+		x.entries[id] = e
+	}
+	for _, alias := range aliases {
+		links, err := repo.GetLinks(ctx, alias.ID)
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			if l.Type == people.AliasOfLink {
+				x.addAlias(alias, l.Target)
+			}
+		}
+	}
+
+	x.keys = x.keys[:0]
+	for _, e := range x.entries {
+		x.keys = append(x.keys, e.keys()...)
+	}
+	sort.Slice(x.keys, func(i, j int) bool { return x.keys[i].less(x.keys[j]) })
+	return nil
+}
+
+// apply updates the index for one event
+func (x *Index) apply(event subscriptions.Event) {
+	ctx := context.Background()
+	switch event.Type {
+	case subscriptions.EventNodeCreated, subscriptions.EventNodeUpdated:
+		node, err := x.repo.GetNode(ctx, event.NodeID)
+		if err != nil || hiddenTypes[node.Type] {
+			return
+		}
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		x.upsert(node)
+	case subscriptions.EventNodeDeleted:
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		x.remove(event.NodeID)
+	case subscriptions.EventLinkCreated, subscriptions.EventLinkDeleted:
+		var alias *core.Node
+		if event.LinkType == people.AliasOfLink && event.Type == subscriptions.EventLinkCreated {
+			alias, _ = x.repo.GetNode(ctx, event.LinkSource)
+		}
+		delta := 1
+		if event.Type == subscriptions.EventLinkDeleted {
+			delta = -1
+		}
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		for _, id := range []string{event.LinkSource, event.LinkTarget} {
+			if e := x.entries[id]; e != nil && e.degree+delta >= 0 {
+				e.degree += delta
+			}
+		}
+		if event.LinkType != people.AliasOfLink {
+			return
+		}
+		if alias != nil {
+			if e := x.entries[event.LinkTarget]; e != nil {
+				x.deleteKeys(e.keys())
+				x.addAlias(alias, event.LinkTarget)
+				x.insertKeys(e.keys())
+			}
+		} else if x.aliasOf[event.LinkSource] == event.LinkTarget {
+			x.dropAlias(event.LinkSource)
+		}
+	}
+}
+
+// upsert indexes a node, replacing its previous keys. Caller holds mu.
+func (x *Index) upsert(node *core.Node) {
+	e := newEntry(node)
+	if old := x.entries[node.ID]; old != nil {
+		x.deleteKeys(old.keys())
+		e.degree, e.aliases = old.degree, old.aliases
+	}
+	x.entries[node.ID] = e
+	x.insertKeys(e.keys())
+}
+
+// remove drops a node, and its value from the node it is an alias of.
+// Caller holds mu.
+func (x *Index) remove(id string) {
+	if e := x.entries[id]; e != nil {
+		x.deleteKeys(e.keys())
+		delete(x.entries, id)
+	}
+	x.dropAlias(id)
+}
+
+// addAlias records an Alias node's value on the node it names, without
+// updating the sorted keys
+func (x *Index) addAlias(alias *core.Node, target string) {
+	e := x.entries[target]
+	value, _ := alias.Meta["value"].(string)
+	if e == nil || value == "" {
+		return
+	}
+	if e.aliases == nil {
+		e.aliases = make(map[string]string)
+	}
+	e.aliases[alias.ID] = strings.ToLower(value)
+	x.aliasOf[alias.ID] = target
+}
+
+// dropAlias removes an Alias node's value from the node it names. Caller
+// holds mu.
+func (x *Index) dropAlias(aliasID string) {
+	target, ok := x.aliasOf[aliasID]
+	if !ok {
+		return
+	}
+	delete(x.aliasOf, aliasID)
+	if e := x.entries[target]; e != nil {
+		x.deleteKeys(e.keys())
+		delete(e.aliases, aliasID)
+		x.insertKeys(e.keys())
+	}
+}
+
+func (x *Index) insertKeys(keys []key) {
+	for _, k := range keys {
+		i := sort.Search(len(x.keys), func(i int) bool { return !x.keys[i].less(k) })
+		if i < len(x.keys) && x.keys[i] == k {
+			continue
+		}
+		x.keys = append(x.keys, key{})
+		copy(x.keys[i+1:], x.keys[i:])
+		x.keys[i] = k
+	}
+}
+
+func (x *Index) deleteKeys(keys []key) {
+	for _, k := range keys {
+		i := sort.Search(len(x.keys), func(i int) bool { return !x.keys[i].less(k) })
+		if i < len(x.keys) && x.keys[i] == k {
+			x.keys = append(x.keys[:i], x.keys[i+1:]...)
+		}
+	}
+}
+
+// Query returns up to limit nodes with a key starting with prefix
+// (case-insensitive), restricted to types when given. Stronger matches
+// rank first, boosted by the node's degree and how recently it changed.
+func (x *Index) Query(prefix string, types []string, limit int, now time.Time) []Suggestion {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" || limit <= 0 {
+		return []Suggestion{}
+	}
+	var allowed map[string]bool
+	if len(types) > 0 {
+		allowed = make(map[string]bool, len(types))
+		for _, t := range types {
+			allowed[t] = true
+		}
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	// top holds the best match per node so far, best first
+	var top []candidate
+	i := sort.Search(len(x.keys), func(i int) bool { return x.keys[i].text >= prefix })
+	for n := 0; i < len(x.keys) && n < maxCandidates && strings.HasPrefix(x.keys[i].text, prefix); i, n = i+1, n+1 {
+		k := x.keys[i]
+		if allowed != nil && !allowed[k.e.typ] {
+			continue
+		}
+		c := candidate{k: k, score: score(k, prefix, now)}
+		if len(top) == limit && !c.beats(top[limit-1]) {
+			continue
+		}
+		top = c.insertInto(top, limit)
+	}
+
+	suggestions := make([]Suggestion, 0, len(top))
+	for _, c := range top {
+		e := c.k.e
+		suggestions = append(suggestions, Suggestion{ID: e.id, Type: e.typ, Label: e.label, Match: c.k.text, Kind: matchNames[c.k.kind], Degree: e.degree, Score: math.Round(c.score*1000) / 1000})
+	}
+	return suggestions
+}
+
+// score rates a key matching prefix: the match kind, plus a bonus for an
+// exact match, the node's degree and how recently it changed
+func score(k key, prefix string, now time.Time) float64 {
+	s := matchWeight[k.kind]
+	if k.text == prefix {
+		s += 1
+	}
+	s += math.Min(math.Log1p(float64(k.e.degree))/2, 2)
+	if age := now.Sub(k.e.modified).Hours() / 24; age >= 0 {
+		s += 1 / (1 + age/30)
+	}
+	return s
+}
+
+// candidate is a scored key during a query
+type candidate struct {
+	k     key
+	score float64
+}
+
+// beats orders candidates by score, then shorter label, then ID
+func (c candidate) beats(o candidate) bool {
+	if c.score != o.score {
+		return c.score > o.score
+	}
+	if len(c.k.e.label) != len(o.k.e.label) {
+		return len(c.k.e.label) < len(o.k.e.label)
+	}
+	return c.k.e.id < o.k.e.id
+}
+
+// insertInto adds c to top, keeping the best candidate per node and at
+// most limit candidates
+func (c candidate) insertInto(top []candidate, limit int) []candidate {
+	for j, o := range top {
+		if o.k.e == c.k.e {
+			if !c.beats(o) {
+				return top
+			}
+			top = append(top[:j], top[j+1:]...)
+			break
+		}
+	}
+	j := sort.Search(len(top), func(j int) bool { return c.beats(top[j]) })
+	top = append(top, candidate{})
+	copy(top[j+1:], top[j:])
+	top[j] = c
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+// newEntry builds a node's entry and keys, without aliases
+func newEntry(node *core.Node) *entry {
+	e := &entry{id: node.ID, typ: node.Type, label: graph.NodeLabel(node.ID, node.Meta), modified: node.Modified}
+	e.base = entryKeys(e, node.Meta)
+	return e
+}
+
+// entryKeys lists a node's keys: its ID (whole and after the type prefix),
+// its label and the words in it, and alias-like meta
+func entryKeys(e *entry, meta map[string]interface{}) []key {
+	id, label := e.id, e.label
+	seen := make(map[string]int)
+	add := func(text string, kind int) {
+		text = strings.ToLower(strings.TrimSpace(text))
+		if text == "" {
+			return
+		}
+		if prev, ok := seen[text]; !ok || kind > prev {
+			seen[text] = kind
+		}
+	}
+
+	add(id, matchID)
+	if i := strings.LastIndex(id, ":"); i >= 0 {
+		add(id[i+1:], matchID)
+	}
+	if label != id {
+		add(label, matchName)
+		// The rest of the label from each later word, so "lovel" and
+		// "thing 12" find "Ada Lovelace" and "Kube thing 12"
+		words := 0
+		for i, r := range label {
+			if words >= maxWords {
+				break
+			}
+			if i > 0 && isWordStart(label, i, r) {
+				add(label[i:], matchWord)
+				words++
+			}
+		}
+	}
+	for _, k := range []string{"nickname", "email", "aliases", "emails", "alias"} {
+		switch v := meta[k].(type) {
+		case string:
+			add(v, matchAlias)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					add(s, matchAlias)
+				}
+			}
+		case []string:
+			for _, s := range v {
+				add(s, matchAlias)
+			}
+		}
+	}
+
+	keys := make([]key, 0, len(seen))
+	for text, kind := range seen {
+		keys = append(keys, key{text: text, e: e, kind: kind})
+	}
+	return keys
+}
+
+// isWordStart reports whether the rune r at byte offset i of s begins a
+// word: a letter or digit after a separator
+func isWordStart(s string, i int, r rune) bool {
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev)
+}
+
+// keys lists an entry's own keys and those from Alias nodes naming it
+func (e *entry) keys() []key {
+	keys := append([]key(nil), e.base...)
+	for _, value := range e.aliases {
+		keys = append(keys, key{text: value, e: e, kind: matchAlias})
+	}
+	return keys
+}
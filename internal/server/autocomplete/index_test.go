@@ -0,0 +1,93 @@
+package autocomplete
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func ids(suggestions []Suggestion) []string {
+	var out []string
+	for _, s := range suggestions {
+		out = append(out, s.ID)
+	}
+	return out
+}
+
+func TestQueryAndEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	old := now.AddDate(-1, 0, 0)
+	for _, n := range []*core.Node{
+		{ID: "concept:kubernetes", Type: "Concept", Meta: map[string]interface{}{"name": "Kubernetes"}, Created: now, Modified: now},
+		{ID: "concept:kudu", Type: "Concept", Meta: map[string]interface{}{"name": "Kudu"}, Created: old, Modified: old},
+		{ID: "person:kurt", Type: "Person", Meta: map[string]interface{}{"name": "Kurt Gödel", "nickname": "KG"}, Created: now, Modified: now},
+		{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"name": "Ada Lovelace"}, Created: now, Modified: now},
+		{ID: "alias:email:ada@example.com", Type: people.AliasType, Meta: map[string]interface{}{"kind": "email", "value": "ada@example.com"}, Created: now, Modified: now},
+		{ID: "doc:kubectl", Type: "Document", Created: now, Modified: now},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "alias:email:ada@example.com", Target: "person:ada", Type: people.AliasOfLink},
+		{Source: "doc:kubectl", Target: "concept:kudu", Type: "MENTIONS"},
+		{Source: "person:kurt", Target: "concept:kudu", Type: "MENTIONS"},
+	} {
+		l.Created, l.Modified = now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	x := NewIndex(repo)
+	if err := x.Build(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	x.ready = true
+
+	// Recent and linked beats recent beats a year old
+	got := ids(x.Query("Ku", []string{"Concept", "Person"}, 10, now))
+	if fmt.Sprint(got) != "[person:kurt concept:kubernetes concept:kudu]" {
+		t.Errorf("ku = %v", got)
+	}
+	if got := x.Query("lovel", nil, 10, now); len(got) != 1 || got[0].ID != "person:ada" || got[0].Kind != "word" {
+		t.Errorf("word match = %+v", got)
+	}
+	if got := x.Query("ada@", nil, 10, now); len(got) != 1 || got[0].ID != "person:ada" || got[0].Kind != "alias" {
+		t.Errorf("alias node match = %+v", got)
+	}
+	if got := x.Query("kg", nil, 10, now); len(got) != 1 || got[0].ID != "person:kurt" {
+		t.Errorf("nickname match = %+v", got)
+	}
+	if got := x.Query("alias:", nil, 10, now); len(got) != 0 {
+		t.Errorf("alias nodes should not be suggested: %+v", got)
+	}
+
+	// Events keep the index current
+	repo.UpdateNodeMeta(ctx, "concept:kubernetes", map[string]any{"name": "K8s"})
+	x.apply(subscriptions.Event{Type: subscriptions.EventNodeUpdated, NodeID: "concept:kubernetes"})
+	if got := ids(x.Query("kub", nil, 10, now)); fmt.Sprint(got) != "[doc:kubectl concept:kubernetes]" {
+		t.Errorf("after rename, kub = %v", got)
+	}
+	x.apply(subscriptions.Event{Type: subscriptions.EventNodeDeleted, NodeID: "doc:kubectl"})
+	x.apply(subscriptions.Event{Type: subscriptions.EventLinkDeleted, LinkSource: "doc:kubectl", LinkTarget: "concept:kudu", LinkType: "MENTIONS"})
+	if got := x.Query("kud", nil, 10, now); len(got) != 1 || got[0].Degree != 1 {
+		t.Errorf("after delete, kud = %+v", got)
+	}
+	x.apply(subscriptions.Event{Type: subscriptions.EventLinkDeleted, LinkSource: "alias:email:ada@example.com", LinkTarget: "person:ada", LinkType: people.AliasOfLink})
+	if got := x.Query("ada@", nil, 10, now); len(got) != 0 {
+		t.Errorf("unlinked alias still matches: %+v", got)
+	}
+	if got := x.Query("lovelace", nil, 10, now); len(got) != 1 {
+		t.Errorf("unlinking an alias dropped the node's own keys: %+v", got)
+	}
+}
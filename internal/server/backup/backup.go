@@ -0,0 +1,387 @@
+// Package backup writes the graph and the working environment around it
+// (subscriptions, saved queries, link rules, namespaces and API keys) to
+// one portable JSON bundle, and restores bundles into a server.
+//
+// A bundle holds the current version of each live node; history,
+// tombstones and attention edges are not carried over. API keys are only
+// recorded as hashes, so restoring a bundle reports which keys still need
+// configuring rather than recreating them.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/queries"
+	"github.com/systemshift/memex/internal/server/rules"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Format identifies memex bundles in their manifest
+const Format = "memex-bundle"
+
+// Version is the bundle layout this package writes and the newest it reads
+const Version = 1
+
+// Bundle sections, as counted in the manifest
+const (
+	SectionNodes         = "nodes"
+	SectionLinks         = "links"
+	SectionSubscriptions = "subscriptions"
+	SectionSavedQueries  = "saved_queries"
+	SectionRules         = "rules"
+	SectionNamespaces    = "namespaces"
+	SectionAPIKeys       = "api_keys"
+)
+
+// pageSize is how many nodes an export reads per FilterNodes call
+const pageSize = 500
+
+// ErrUnsupported is returned for bundles this server cannot read
+var ErrUnsupported = errors.New("unsupported bundle")
+
+// environmentTypes are node types exported in their own sections
+var environmentTypes = map[string]bool{
+	"Subscription": true, queries.NodeType: true, rules.NodeType: true,
+}
+
+// Manifest describes a bundle's contents
+type Manifest struct {
+	Format   string         `json:"format"`
+	Version  int            `json:"version"`
+	Created  time.Time      `json:"created"`
+	Sections map[string]int `json:"sections"` // Entries per section
+}
+
+// Bundle is a portable backup of a server
+type Bundle struct {
+	Manifest      Manifest                      `json:"manifest"`
+	Nodes         []Node                        `json:"nodes"`
+	Links         []Link                        `json:"links"`
+	Subscriptions []*subscriptions.Subscription `json:"subscriptions"`
+	SavedQueries  []*queries.SavedQuery         `json:"saved_queries"`
+	Rules         []*rules.Rule                 `json:"rules"`
+	Namespaces    []Namespace                   `json:"namespaces"`
+	APIKeys       []APIKey                      `json:"api_keys"`
+}
+
+// Node is the current version of a node
+type Node struct {
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Content  []byte                 `json:"content,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	Created  time.Time              `json:"created"`
+	Modified time.Time              `json:"modified"`
+}
+
+// Link is a link between two bundled nodes
+type Link struct {
+	Source        string                 `json:"source"`
+	Target        string                 `json:"target"`
+	Type          string                 `json:"type"`
+	Meta          map[string]interface{} `json:"meta,omitempty"`
+	Weight        float64                `json:"weight,omitempty"`
+	Bidirectional bool                   `json:"bidirectional,omitempty"`
+	Created       time.Time              `json:"created"`
+	Modified      time.Time              `json:"modified"`
+}
+
+// Namespace is a node ID prefix in use, with the quotas configured for it.
+// Namespaces follow from node IDs, so restoring recreates them by itself.
+type Namespace struct {
+	Name   string        `json:"name"`
+	Nodes  int           `json:"nodes"`
+	Quotas []graph.Quota `json:"quotas,omitempty"`
+}
+
+// APIKey is a configured API key, recorded by hash with its author
+type APIKey struct {
+	Hash   string `json:"hash"` // "sha256:" and the hex digest of the key
+	Author string `json:"author"`
+}
+
+// Environment is the server state exported beside the graph. Every field
+// is optional.
+type Environment struct {
+	Subscriptions *subscriptions.Manager
+	Rules         *rules.Engine // Reloaded after a restore
+	Quotas        []graph.Quota
+	APIKeyAuthors map[string]string // API key -> author
+}
+
+// Result reports a restore. Entries already present on the server are
+// skipped, never overwritten.
+type Result struct {
+	Created        map[string]int `json:"created"`
+	Skipped        map[string]int `json:"skipped"`
+	Errors         []string       `json:"errors,omitempty"`
+	MissingAPIKeys []APIKey       `json:"missing_api_keys,omitempty"` // Bundled keys not configured here
+}
+
+// HashKey returns the form an API key is recorded in
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Export builds a bundle of repo's live nodes, the links among them and env
+func Export(ctx context.Context, repo graph.Repository, env Environment) (*Bundle, error) {
+	b := &Bundle{
+		Manifest:      Manifest{Format: Format, Version: Version, Created: time.Now().UTC()},
+		Nodes:         []Node{},
+		Links:         []Link{},
+		Subscriptions: []*subscriptions.Subscription{},
+		Namespaces:    []Namespace{},
+		APIKeys:       []APIKey{},
+	}
+
+	included := make(map[string]bool)
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, nil, "", "", pageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("reading nodes: %w", err)
+		}
+		for _, n := range nodes {
+			if environmentTypes[n.Type] || included[n.ID] {
+				continue
+			}
+			included[n.ID] = true
+			b.Nodes = append(b.Nodes, Node{ID: n.ID, Type: n.Type, Content: n.Content, Meta: n.Meta, Created: n.Created, Modified: n.Modified})
+		}
+		if len(nodes) < pageSize {
+			break
+		}
+	}
+	sort.Slice(b.Nodes, func(i, j int) bool { return b.Nodes[i].ID < b.Nodes[j].ID })
+
+	for _, n := range b.Nodes {
+		links, err := repo.GetLinks(ctx, n.ID)
+		if err != nil {
+			return nil, fmt.Errorf("reading links of %s: %w", n.ID, err)
+		}
+		for _, l := range links {
+			if l.Type == "ATTENDED" || !included[l.Target] {
+				continue
+			}
+			b.Links = append(b.Links, Link{Source: l.Source, Target: l.Target, Type: l.Type, Meta: l.Meta, Weight: l.Weight, Bidirectional: l.Bidirectional, Created: l.Created, Modified: l.Modified})
+		}
+	}
+
+	if env.Subscriptions != nil {
+		b.Subscriptions = env.Subscriptions.List()
+		sort.Slice(b.Subscriptions, func(i, j int) bool { return b.Subscriptions[i].ID < b.Subscriptions[j].ID })
+	}
+	var err error
+	if b.SavedQueries, err = queries.List(ctx, repo); err != nil {
+		return nil, fmt.Errorf("reading saved queries: %w", err)
+	}
+	if b.Rules, err = rules.List(ctx, repo); err != nil {
+		return nil, fmt.Errorf("reading rules: %w", err)
+	}
+
+	b.Namespaces = namespaces(b.Nodes, env.Quotas)
+	for key, author := range env.APIKeyAuthors {
+		b.APIKeys = append(b.APIKeys, APIKey{Hash: HashKey(key), Author: author})
+	}
+	sort.Slice(b.APIKeys, func(i, j int) bool { return b.APIKeys[i].Hash < b.APIKeys[j].Hash })
+
+	b.Manifest.Sections = map[string]int{
+		SectionNodes:         len(b.Nodes),
+		SectionLinks:         len(b.Links),
+		SectionSubscriptions: len(b.Subscriptions),
+		SectionSavedQueries:  len(b.SavedQueries),
+		SectionRules:         len(b.Rules),
+		SectionNamespaces:    len(b.Namespaces),
+		SectionAPIKeys:       len(b.APIKeys),
+	}
+	return b, nil
+}
+
+// namespaces counts bundled nodes per namespace and attaches the quotas
+// configured for each
+func namespaces(nodes []Node, quotas []graph.Quota) []Namespace {
+	counts := make(map[string]int)
+	for _, n := range nodes {
+		counts[graph.Namespace(n.ID)]++
+	}
+	for _, q := range quotas {
+		if _, ok := counts[q.Name]; !ok && q.Scope == graph.QuotaNamespace {
+			counts[q.Name] = 0 // Quota configured ahead of any nodes
+		}
+	}
+
+	out := make([]Namespace, 0, len(counts))
+	for name, count := range counts {
+		ns := Namespace{Name: name, Nodes: count}
+		for _, q := range quotas {
+			if q.Scope == graph.QuotaNamespace && q.Name == name {
+				ns.Quotas = append(ns.Quotas, q)
+			}
+		}
+		out = append(out, ns)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Import restores a bundle into repo and env, skipping entries that
+// already exist. Nodes and links are written in batches; a failed batch
+// stops the restore, while a subscription, saved query or rule that fails
+// to restore is reported in Errors.
+func Import(ctx context.Context, repo graph.Repository, env Environment, b *Bundle) (*Result, error) {
+	if b.Manifest.Format != Format {
+		return nil, fmt.Errorf("%w: format %q", ErrUnsupported, b.Manifest.Format)
+	}
+	if b.Manifest.Version < 1 || b.Manifest.Version > Version {
+		return nil, fmt.Errorf("%w: version %d (this server reads up to %d)", ErrUnsupported, b.Manifest.Version, Version)
+	}
+	res := &Result{Created: make(map[string]int), Skipped: make(map[string]int)}
+
+	if err := importNodes(ctx, repo, b.Nodes, res); err != nil {
+		return nil, err
+	}
+	if err := importLinks(ctx, repo, b.Links, res); err != nil {
+		return nil, err
+	}
+
+	for _, sub := range b.Subscriptions {
+		if env.Subscriptions == nil {
+			res.Skipped[SectionSubscriptions]++
+			continue
+		}
+		res.record(SectionSubscriptions, sub.ID, env.Subscriptions.Restore(ctx, sub), subscriptions.ErrExists)
+	}
+	for _, q := range b.SavedQueries {
+		if _, err := queries.Get(ctx, repo, q.ID); err == nil {
+			res.Skipped[SectionSavedQueries]++
+			continue
+		}
+		res.record(SectionSavedQueries, q.ID, queries.Restore(ctx, repo, q), nil)
+	}
+	for _, r := range b.Rules {
+		if _, err := rules.Get(ctx, repo, r.ID); err == nil {
+			res.Skipped[SectionRules]++
+			continue
+		}
+		res.record(SectionRules, r.ID, rules.Restore(ctx, repo, r), nil)
+	}
+	if env.Rules != nil && res.Created[SectionRules] > 0 {
+		if err := env.Rules.Reload(ctx); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("reloading rules: %v", err))
+		}
+	}
+
+	configured := make(map[string]bool, len(env.APIKeyAuthors))
+	for key := range env.APIKeyAuthors {
+		configured[HashKey(key)] = true
+	}
+	for _, key := range b.APIKeys {
+		if !configured[key.Hash] {
+			res.MissingAPIKeys = append(res.MissingAPIKeys, key)
+		}
+	}
+	return res, nil
+}
+
+// record counts one restored entry; exists marks errors that mean the
+// entry was already present
+func (res *Result) record(section, id string, err, exists error) {
+	switch {
+	case err == nil:
+		res.Created[section]++
+	case exists != nil && errors.Is(err, exists):
+		res.Skipped[section]++
+	default:
+		res.Errors = append(res.Errors, fmt.Sprintf("%s %s: %v", section, id, err))
+	}
+}
+
+// importNodes creates the bundled nodes that do not exist yet, including
+// as tombstones
+func importNodes(ctx context.Context, repo graph.Repository, nodes []Node, res *Result) error {
+	withDeleted := graph.WithDeleted(ctx)
+	batch := make([]*core.Node, 0, pageSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := repo.CreateNodes(ctx, batch); err != nil {
+			return fmt.Errorf("restoring nodes: %w", err)
+		}
+		res.Created[SectionNodes] += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	seen := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if seen[n.ID] {
+			continue
+		}
+		seen[n.ID] = true
+		if _, err := repo.GetNode(withDeleted, n.ID); err == nil {
+			res.Skipped[SectionNodes]++
+			continue
+		}
+		batch = append(batch, &core.Node{ID: n.ID, Type: n.Type, Content: n.Content, Meta: n.Meta, Created: n.Created, Modified: n.Modified})
+		if len(batch) == pageSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// importLinks creates the bundled links that do not exist yet
+func importLinks(ctx context.Context, repo graph.Repository, links []Link, res *Result) error {
+	existing := make(map[string]map[string]bool) // Source -> "type\x00target"
+	batch := make([]*core.Link, 0, pageSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := repo.CreateLinks(ctx, batch); err != nil {
+			return fmt.Errorf("restoring links: %w", err)
+		}
+		res.Created[SectionLinks] += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, l := range links {
+		out, ok := existing[l.Source]
+		if !ok {
+			current, err := repo.GetLinks(ctx, l.Source)
+			if err != nil {
+				return fmt.Errorf("reading links of %s: %w", l.Source, err)
+			}
+			out = make(map[string]bool, len(current))
+			for _, c := range current {
+				out[c.Type+"\x00"+c.Target] = true
+			}
+			existing[l.Source] = out
+		}
+		key := l.Type + "\x00" + l.Target
+		if out[key] {
+			res.Skipped[SectionLinks]++
+			continue
+		}
+		out[key] = true
+		batch = append(batch, &core.Link{Source: l.Source, Target: l.Target, Type: l.Type, Meta: l.Meta, Weight: l.Weight, Bidirectional: l.Bidirectional, Created: l.Created, Modified: l.Modified})
+		if len(batch) == pageSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/queries"
+	"github.com/systemshift/memex/internal/server/rules"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := graph.NewMemory()
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, n := range []*core.Node{
+		{ID: "work:a", Type: "Note", Content: []byte("alpha"), Meta: map[string]interface{}{"tag": "x"}},
+		{ID: "work:b", Type: "Note", Content: []byte("beta")},
+		{ID: "home:c", Type: "Note", Content: []byte("gone")},
+	} {
+		n.Created, n.Modified = now, now
+		if err := src.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.CreateLink(ctx, &core.Link{Source: "work:a", Target: "work:b", Type: "CITES", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.DeleteNode(ctx, "home:c", false); err != nil {
+		t.Fatal(err)
+	}
+
+	subs := subscriptions.NewManager(src)
+	if _, err := subs.Register(ctx, &subscriptions.CreateSubscriptionRequest{Name: "notes", WebSocket: true, Pattern: subscriptions.SubscriptionPattern{NodeTypes: []string{"Note"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := queries.Save(ctx, src, &queries.SavedQuery{ID: "recent", Query: map[string]interface{}{"kind": "filter", "types": []interface{}{"Note"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rules.Save(ctx, src, &rules.Rule{ID: "cite", When: rules.Condition{NodeTypes: []string{"Note"}}, Match: rules.Matcher{Kind: rules.MatchIDs, Key: "cites"}, Link: rules.LinkSpec{Type: "CITES"}}); err != nil {
+		t.Fatal(err)
+	}
+	env := Environment{
+		Subscriptions: subs,
+		Quotas:        []graph.Quota{{Scope: graph.QuotaNamespace, Name: "work", MaxNodes: 10}, {Scope: graph.QuotaNamespace, Name: "empty", MaxNodes: 1}},
+		APIKeyAuthors: map[string]string{"secret-1": "ada", "secret-2": "bob"},
+	}
+
+	bundle, err := Export(ctx, src, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{SectionNodes: 2, SectionLinks: 1, SectionSubscriptions: 1, SectionSavedQueries: 1, SectionRules: 1, SectionNamespaces: 2, SectionAPIKeys: 2}
+	for section, n := range want {
+		if bundle.Manifest.Sections[section] != n {
+			t.Errorf("manifest %s = %d, want %d", section, bundle.Manifest.Sections[section], n)
+		}
+	}
+	if bundle.Namespaces[1].Name != "work" || bundle.Namespaces[1].Nodes != 2 || len(bundle.Namespaces[1].Quotas) != 1 {
+		t.Errorf("namespaces = %+v", bundle.Namespaces)
+	}
+	raw, _ := json.Marshal(bundle)
+	for _, key := range []string{"secret-1", "secret-2"} {
+		if bytes.Contains(raw, []byte(key)) {
+			t.Errorf("bundle holds API key %s in the clear", key)
+		}
+	}
+
+	var decoded Bundle
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	dst := graph.NewMemory()
+	dstEnv := Environment{Subscriptions: subscriptions.NewManager(dst), APIKeyAuthors: map[string]string{"secret-1": "ada"}}
+	res, err := Import(ctx, dst, dstEnv, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, section := range []string{SectionNodes, SectionLinks, SectionSubscriptions, SectionSavedQueries, SectionRules} {
+		if res.Created[section] != want[section] {
+			t.Errorf("created %s = %d, want %d", section, res.Created[section], want[section])
+		}
+	}
+	if len(res.Errors) != 0 {
+		t.Errorf("errors = %v", res.Errors)
+	}
+	if len(res.MissingAPIKeys) != 1 || res.MissingAPIKeys[0].Author != "bob" {
+		t.Errorf("missing keys = %+v", res.MissingAPIKeys)
+	}
+
+	node, err := dst.GetNode(ctx, "work:a")
+	if err != nil || string(node.Content) != "alpha" || node.Meta["tag"] != "x" || !node.Created.Equal(now) {
+		t.Errorf("restored node = %+v, %v", node, err)
+	}
+	if links, _ := dst.GetLinks(ctx, "work:a"); len(links) != 1 || links[0].Target != "work:b" {
+		t.Errorf("restored links = %+v", links)
+	}
+	if q, err := queries.Get(ctx, dst, "recent"); err != nil || !q.Created.Equal(bundle.SavedQueries[0].Created) {
+		t.Errorf("restored query = %+v, %v", q, err)
+	}
+	if _, err := rules.Get(ctx, dst, "cite"); err != nil {
+		t.Errorf("restored rule: %v", err)
+	}
+	if got := dstEnv.Subscriptions.List(); len(got) != 1 || got[0].ID != bundle.Subscriptions[0].ID {
+		t.Errorf("restored subscriptions = %+v", got)
+	}
+
+	// Restoring again changes nothing
+	res, err = Import(ctx, dst, dstEnv, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for section, n := range res.Created {
+		if n != 0 {
+			t.Errorf("second restore created %d %s", n, section)
+		}
+	}
+	if res.Skipped[SectionNodes] != 2 || res.Skipped[SectionLinks] != 1 || res.Skipped[SectionSubscriptions] != 1 {
+		t.Errorf("second restore skipped = %v", res.Skipped)
+	}
+}
+
+func TestImportRejectsUnknownBundles(t *testing.T) {
+	for _, m := range []Manifest{{Format: "other", Version: 1}, {Format: Format, Version: Version + 1}} {
+		_, err := Import(context.Background(), graph.NewMemory(), Environment{}, &Bundle{Manifest: m})
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Import(%+v) error = %v", m, err)
+		}
+	}
+}
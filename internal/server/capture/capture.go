@@ -0,0 +1,211 @@
+// Package capture saves what a browser extension sends in one call: the
+// page as a web page Source, the selected text as a Highlight and an
+// optional screenshot, each linked to the page.
+package capture
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Node and link types
+const (
+	HighlightType    = "Highlight"
+	ScreenshotType   = "Screenshot"
+	HighlightOfLink  = "HIGHLIGHT_OF"  // Highlight to the page it was selected on
+	ScreenshotOfLink = "SCREENSHOT_OF" // Screenshot to the page it shows
+)
+
+// Connector is recorded on captured nodes
+const Connector = "capture"
+
+// MaxScreenshot caps a decoded screenshot
+const MaxScreenshot = 10 << 20
+
+// ErrInvalid is returned for a request that cannot be captured
+var ErrInvalid = errors.New("invalid capture")
+
+// Request is what the extension sends
+type Request struct {
+	URL        string   `json:"url"`
+	Title      string   `json:"title,omitempty"`
+	Text       string   `json:"text,omitempty"`       // Selected text; creates a highlight
+	Note       string   `json:"note,omitempty"`       // The user's comment on the selection
+	Screenshot string   `json:"screenshot,omitempty"` // data:image/png;base64,...
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// Result names the nodes a capture created or reused
+type Result struct {
+	PageID       string `json:"page_id"`
+	PageCreated  bool   `json:"page_created"`
+	HighlightID  string `json:"highlight_id,omitempty"`
+	ScreenshotID string `json:"screenshot_id,omitempty"`
+}
+
+// PageID returns the ID of the Source node for a page URL. The fragment
+// is ignored, so captures from anywhere on a page share it.
+func PageID(pageURL string) string {
+	hash := sha256.Sum256([]byte(normalizeURL(pageURL)))
+	return "webpage:" + hex.EncodeToString(hash[:16])
+}
+
+func normalizeURL(pageURL string) string {
+	u, err := url.Parse(strings.TrimSpace(pageURL))
+	if err != nil {
+		return strings.TrimSpace(pageURL)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// Validate checks a request before anything is written
+func (req *Request) Validate() error {
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalid)
+	}
+	if req.Screenshot != "" {
+		if _, _, err := ParseDataURI(req.Screenshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes a capture. The page's Source node is created on its first
+// capture and reused after that, taking the newest title; identical
+// screenshots are stored once.
+func Save(ctx context.Context, repo graph.Repository, req *Request) (*Result, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	pageURL := normalizeURL(req.URL)
+	title := strings.TrimSpace(req.Title)
+	result := &Result{PageID: PageID(pageURL)}
+
+	if page, err := repo.GetNode(ctx, result.PageID); err != nil {
+		meta := map[string]any{
+			"format":      "webpage",
+			"url":         pageURL,
+			"connector":   Connector,
+			"ingested_at": now.Format(time.RFC3339),
+			"size_bytes":  0,
+		}
+		if title != "" {
+			meta["title"] = title
+		}
+		if err := repo.CreateNode(ctx, &core.Node{ID: result.PageID, Type: "Source", Meta: meta, Created: now, Modified: now}); err != nil {
+			return nil, fmt.Errorf("creating page: %w", err)
+		}
+		result.PageCreated = true
+	} else if current, _ := page.Meta["title"].(string); title != "" && title != current {
+		if err := repo.UpdateNodeMeta(ctx, result.PageID, map[string]any{"title": title}); err != nil {
+			return nil, fmt.Errorf("updating page: %w", err)
+		}
+	}
+
+	if text := strings.TrimSpace(req.Text); text != "" {
+		meta := map[string]any{
+			"url":         pageURL,
+			"connector":   Connector,
+			"captured_at": now.Format(time.RFC3339),
+		}
+		if title != "" {
+			meta["title"] = title
+		}
+		if note := strings.TrimSpace(req.Note); note != "" {
+			meta["note"] = note
+		}
+		if len(req.Tags) > 0 {
+			meta["tags"] = req.Tags
+		}
+		id := "highlight:" + uuid.New().String()
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: HighlightType, Content: []byte(text), Meta: meta, Created: now, Modified: now}); err != nil {
+			return result, fmt.Errorf("creating highlight: %w", err)
+		}
+		if err := repo.CreateLink(ctx, &core.Link{Source: id, Target: result.PageID, Type: HighlightOfLink, Created: now, Modified: now}); err != nil {
+			return result, fmt.Errorf("linking highlight: %w", err)
+		}
+		result.HighlightID = id
+	}
+
+	if req.Screenshot != "" {
+		id, err := saveScreenshot(ctx, repo, req.Screenshot, result.PageID, pageURL, now)
+		if err != nil {
+			return result, err
+		}
+		result.ScreenshotID = id
+	}
+	return result, nil
+}
+
+// saveScreenshot stores a screenshot by its content hash and links it to
+// the page
+func saveScreenshot(ctx context.Context, repo graph.Repository, dataURI, pageID, pageURL string, now time.Time) (string, error) {
+	contentType, data, err := ParseDataURI(dataURI)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	id := "screenshot:" + hex.EncodeToString(hash[:])
+	if _, err := repo.GetNode(ctx, id); err != nil {
+		meta := map[string]any{
+			"content_type": contentType,
+			"size_bytes":   len(data),
+			"url":          pageURL,
+			"connector":    Connector,
+			"captured_at":  now.Format(time.RFC3339),
+		}
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: ScreenshotType, Content: data, Meta: meta, Created: now, Modified: now}); err != nil {
+			return "", fmt.Errorf("creating screenshot: %w", err)
+		}
+	}
+
+	links, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	for _, l := range links {
+		if l.Target == pageID && l.Type == ScreenshotOfLink {
+			return id, nil
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: id, Target: pageID, Type: ScreenshotOfLink, Created: now, Modified: now}); err != nil {
+		return "", fmt.Errorf("linking screenshot: %w", err)
+	}
+	return id, nil
+}
+
+// ParseDataURI decodes a base64 image data URI
+func ParseDataURI(uri string) (contentType string, data []byte, err error) {
+	header, payload, ok := strings.Cut(strings.TrimSpace(uri), ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return "", nil, fmt.Errorf("%w: screenshot must be a base64 data URI", ErrInvalid)
+	}
+	contentType = strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", nil, fmt.Errorf("%w: screenshot must be an image, not %q", ErrInvalid, contentType)
+	}
+	if base64.StdEncoding.DecodedLen(len(payload)) > MaxScreenshot {
+		return "", nil, fmt.Errorf("%w: screenshot is larger than %d bytes", ErrInvalid, MaxScreenshot)
+	}
+	data, err = base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: screenshot: %v", ErrInvalid, err)
+	}
+	return contentType, data, nil
+}
@@ -0,0 +1,83 @@
+package capture
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestSave(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	shot := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("\x89PNG fake"))
+
+	res, err := Save(ctx, repo, &Request{
+		URL:        "https://example.com/post#intro",
+		Title:      "A post",
+		Text:       "  the quoted part ",
+		Note:       "Worth rereading",
+		Screenshot: shot,
+	})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if !res.PageCreated || res.PageID != PageID("https://example.com/post") || res.HighlightID == "" || res.ScreenshotID == "" {
+		t.Fatalf("Save() = %+v", res)
+	}
+
+	page, err := repo.GetNode(ctx, res.PageID)
+	if err != nil || page.Type != "Source" || page.Meta["format"] != "webpage" || page.Meta["url"] != "https://example.com/post" {
+		t.Fatalf("page = %+v, %v", page, err)
+	}
+	highlight, err := repo.GetNode(ctx, res.HighlightID)
+	if err != nil || string(highlight.Content) != "the quoted part" || highlight.Meta["note"] != "Worth rereading" {
+		t.Fatalf("highlight = %+v, %v", highlight, err)
+	}
+	screenshot, err := repo.GetNode(ctx, res.ScreenshotID)
+	if err != nil || screenshot.Type != ScreenshotType || screenshot.Meta["content_type"] != "image/png" || string(screenshot.Content) != "\x89PNG fake" {
+		t.Fatalf("screenshot = %+v, %v", screenshot, err)
+	}
+	for _, l := range []struct{ source, linkType string }{{res.HighlightID, HighlightOfLink}, {res.ScreenshotID, ScreenshotOfLink}} {
+		links, err := repo.GetLinks(ctx, l.source)
+		if err != nil || len(links) != 1 || links[0].Target != res.PageID || links[0].Type != l.linkType {
+			t.Errorf("links of %s = %+v, %v", l.source, links, err)
+		}
+	}
+
+	// A second capture of the page reuses it and the identical screenshot
+	again, err := Save(ctx, repo, &Request{URL: "https://example.com/post", Title: "A post, renamed", Text: "another part", Screenshot: shot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.PageCreated || again.PageID != res.PageID || again.HighlightID == res.HighlightID || again.ScreenshotID != res.ScreenshotID {
+		t.Errorf("second Save() = %+v", again)
+	}
+	if page, _ := repo.GetNode(ctx, res.PageID); page.Meta["title"] != "A post, renamed" {
+		t.Errorf("page title = %v", page.Meta["title"])
+	}
+
+	// A bare URL is a bookmark
+	bookmark, err := Save(ctx, repo, &Request{URL: "https://example.com/other"})
+	if err != nil || !bookmark.PageCreated || bookmark.HighlightID != "" || bookmark.ScreenshotID != "" {
+		t.Errorf("Save(bookmark) = %+v, %v", bookmark, err)
+	}
+}
+
+func TestSaveInvalid(t *testing.T) {
+	repo := graph.NewMemory()
+	for _, req := range []*Request{
+		{URL: ""},
+		{URL: "example.com/post"},
+		{URL: "javascript:alert(1)"},
+		{URL: "https://example.com", Screenshot: "https://example.com/shot.png"},
+		{URL: "https://example.com", Screenshot: "data:text/html;base64,PGI+"},
+		{URL: "https://example.com", Screenshot: "data:image/png;base64,!!!"},
+	} {
+		if _, err := Save(context.Background(), repo, req); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Save(%+v) error = %v, want ErrInvalid", req, err)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package citations
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Node and link types used for citations
+const (
+	PaperType = "Paper"
+	CitesLink = "CITES"
+)
+
+// Parsers recorded on CITES links and citing nodes
+const (
+	ParserGROBID    = "grobid"
+	ParserHeuristic = "heuristic"
+)
+
+// Property keys
+const (
+	parsedAtKey = "references_parsed_at" // On citing nodes, once processed
+	stubKey     = "citation_stub"        // On Paper nodes known only from a citation
+)
+
+// maxRawLength caps the raw reference kept on a CITES link
+const maxRawLength = 500
+
+// Repository is the subset of graph operations citation parsing needs
+type Repository interface {
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error)
+	CreateNode(ctx context.Context, node *core.Node) error
+	CreateLinks(ctx context.Context, links []*core.Link) error
+	UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error
+}
+
+// Config holds citation parsing configuration
+type Config struct {
+	GROBIDURL string // GROBID-compatible service base URL; empty parses text heuristically
+	Timeout   time.Duration
+}
+
+// Result summarizes the references parsed from one paper
+type Result struct {
+	NodeID     string      `json:"node_id"`
+	Parser     string      `json:"parser"`
+	References []Reference `json:"references"`
+	Papers     int         `json:"papers_created"`
+	Links      int         `json:"links_created"`
+}
+
+// Processor parses references out of papers as they are created
+type Processor struct {
+	repo       Repository
+	cfg        Config
+	httpClient *http.Client
+	eventChan  chan queuedEvent
+	hold       func(nodeID string) (release func())
+	wg         sync.WaitGroup
+}
+
+// queuedEvent is a queued node with the release for its processing hold
+type queuedEvent struct {
+	event   subscriptions.Event
+	release func()
+}
+
+// NewProcessor creates a new citation processor
+func NewProcessor(repo Repository, cfg Config) *Processor {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 120 * time.Second
+	}
+	cfg.GROBIDURL = strings.TrimRight(cfg.GROBIDURL, "/")
+	return &Processor{
+		repo:       repo,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		eventChan:  make(chan queuedEvent, 100),
+	}
+}
+
+// Start begins processing events
+func (p *Processor) Start() {
+	p.wg.Add(1)
+	go p.processEvents()
+	if p.cfg.GROBIDURL != "" {
+		log.Printf("Citation processor started (GROBID: %s)", p.cfg.GROBIDURL)
+	} else {
+		log.Printf("Citation processor started (heuristic parsing)")
+	}
+}
+
+// Stop waits for queued papers to finish processing
+func (p *Processor) Stop() {
+	close(p.eventChan)
+	p.wg.Wait()
+}
+
+// SetHold registers a callback invoked for each queued node; the returned
+// release is called once the node has been processed. Must be called
+// before Start.
+func (p *Processor) SetHold(hold func(nodeID string) (release func())) {
+	p.hold = hold
+}
+
+// EmitEvent queues an event for processing (non-blocking)
+func (p *Processor) EmitEvent(event subscriptions.Event) {
+	if event.Type != subscriptions.EventNodeCreated {
+		return
+	}
+	release := func() {}
+	if p.hold != nil {
+		release = p.hold(event.NodeID)
+	}
+	select {
+	case p.eventChan <- queuedEvent{event: event, release: release}:
+	default:
+		release()
+		log.Printf("Warning: citation queue full, skipping node %s", event.NodeID)
+	}
+}
+
+// processEvents is the main processing loop
+func (p *Processor) processEvents() {
+	defer p.wg.Done()
+
+	for queued := range p.eventChan {
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		if _, err := p.ProcessNode(ctx, queued.event.NodeID, false); err != nil {
+			log.Printf("Citation parsing failed for %s: %v", queued.event.NodeID, err)
+		}
+		cancel()
+		queued.release()
+	}
+}
+
+// ProcessNode parses a paper's references into Paper nodes and CITES
+// links. Nodes that are not papers are skipped (nil result), as are papers
+// already parsed unless force is set.
+func (p *Processor) ProcessNode(ctx context.Context, id string, force bool) (*Result, error) {
+	node, err := p.repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !IsPaperNode(node) {
+		return nil, nil
+	}
+	if _, done := node.Meta[parsedAtKey]; done && !force {
+		return nil, nil
+	}
+
+	result := &Result{NodeID: id}
+	if pdf := pdfContent(node); pdf != nil && p.cfg.GROBIDURL != "" {
+		result.Parser = ParserGROBID
+		if result.References, err = p.grobid(ctx, pdf); err != nil {
+			return nil, err
+		}
+	} else if text := paperText(node); text != "" {
+		result.Parser = ParserHeuristic
+		result.References = ParseReferences(text)
+	} else if force {
+		return nil, fmt.Errorf("node %s has no text to parse (PDFs need MEMEX_GROBID_URL)", id)
+	} else {
+		return nil, nil // Metadata-only papers, e.g. from a reference manager
+	}
+	if result.References == nil {
+		result.References = []Reference{}
+	}
+
+	if err := p.link(ctx, node.ID, result); err != nil {
+		return nil, err
+	}
+
+	meta := map[string]any{
+		parsedAtKey:        time.Now().Format(time.RFC3339),
+		"reference_count":  len(result.References),
+		"reference_parser": result.Parser,
+	}
+	if err := p.repo.UpdateNodeMetaWithNote(ctx, id, meta, "References parsed", "citations"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// link creates Paper nodes for cited works not yet in the graph and a
+// CITES link to each one the citing node does not already cite
+func (p *Processor) link(ctx context.Context, citingID string, result *Result) error {
+	existing, err := p.repo.GetLinks(ctx, citingID)
+	if err != nil {
+		return err
+	}
+	cited := make(map[string]bool)
+	for _, l := range existing {
+		if l.Type == CitesLink {
+			cited[l.Target] = true
+		}
+	}
+
+	now := time.Now()
+	var links []*core.Link
+	for _, ref := range result.References {
+		paperID := PaperID(ref)
+		if paperID == "" || paperID == citingID || cited[paperID] {
+			continue
+		}
+		cited[paperID] = true
+
+		if _, err := p.repo.GetNode(ctx, paperID); err != nil {
+			if err := p.repo.CreateNode(ctx, paperNode(paperID, ref, now)); err != nil {
+				return fmt.Errorf("creating %s: %w", paperID, err)
+			}
+			result.Papers++
+		}
+
+		raw := ref.Raw
+		if len(raw) > maxRawLength {
+			raw = strings.ToValidUTF8(raw[:maxRawLength], "")
+		}
+		links = append(links, &core.Link{
+			Source:   citingID,
+			Target:   paperID,
+			Type:     CitesLink,
+			Meta:     map[string]interface{}{"raw": raw, "parser": result.Parser, graph.LayerKey: graph.LayerDerived},
+			Created:  now,
+			Modified: now,
+		})
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	if err := p.repo.CreateLinks(ctx, links); err != nil {
+		return err
+	}
+	result.Links = len(links)
+	return nil
+}
+
+// paperNode builds the node for a work known only from a citation
+func paperNode(id string, ref Reference, now time.Time) *core.Node {
+	meta := map[string]interface{}{"title": ref.Title, stubKey: true}
+	if len(ref.Authors) > 0 {
+		meta["authors"] = ref.Authors
+	}
+	if ref.Year > 0 {
+		meta["year"] = ref.Year
+	}
+	if ref.Venue != "" {
+		meta["venue"] = ref.Venue
+	}
+	if ref.DOI != "" {
+		meta["doi"] = ref.DOI
+	}
+	return &core.Node{ID: id, Type: PaperType, Meta: meta, Created: now, Modified: now}
+}
+
+// IsPaperNode reports whether a node holds a paper whose references can
+// be parsed: a Paper with content, or a PDF source
+func IsPaperNode(node *core.Node) bool {
+	if stub, _ := node.Meta[stubKey].(bool); stub {
+		return false
+	}
+	if node.Type == PaperType {
+		return true
+	}
+	for _, key := range []string{"content_type", "mime_type"} {
+		if ct, ok := node.Meta[key].(string); ok && ct == "application/pdf" {
+			return true
+		}
+	}
+	format, _ := node.Meta["format"].(string)
+	return format == "pdf" || format == "paper"
+}
+
+// pdfContent returns the node's PDF bytes, decoding base64 content
+func pdfContent(node *core.Node) []byte {
+	data := node.Content
+	if decoded, err := base64.StdEncoding.DecodeString(string(data)); err == nil {
+		data = decoded
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return nil
+	}
+	return data
+}
+
+// paperText returns a paper's extracted text: a "text" property, or the
+// content itself when it is not a PDF
+func paperText(node *core.Node) string {
+	if text, ok := node.Meta["text"].(string); ok && text != "" {
+		return text
+	}
+	if pdfContent(node) != nil {
+		return ""
+	}
+	if len(node.Content) > 0 {
+		return string(node.Content)
+	}
+	text, _ := node.Meta["content"].(string)
+	return text
+}
+
+// grobid sends a PDF to the GROBID processReferences service and parses
+// the TEI it returns
+func (p *Processor) grobid(ctx context.Context, pdf []byte) ([]Reference, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("input", "paper.pdf")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(pdf); err != nil {
+		return nil, err
+	}
+	form.WriteField("includeRawCitations", "1")
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.GROBIDURL+"/api/processReferences", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Accept", "application/xml")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling GROBID: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("GROBID returned status %d: %s", resp.StatusCode, data)
+	}
+	return ParseTEI(data)
+}
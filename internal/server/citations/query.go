@@ -0,0 +1,139 @@
+package citations
+
+import (
+	"context"
+	"sort"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// pageSize is how many Paper nodes MostCited reads per FilterNodes call
+const pageSize = 1000
+
+// CitedWork is a paper with the number of nodes in the library citing it
+type CitedWork struct {
+	ID        string                 `json:"id"`
+	Title     string                 `json:"title"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+	CitedBy   int                    `json:"cited_by"`
+	InLibrary bool                   `json:"in_library"` // False for works known only from citations
+}
+
+// MostCited ranks papers by how many nodes cite them, most cited first.
+// Papers nobody cites are left out.
+func MostCited(ctx context.Context, repo Repository, limit int) ([]CitedWork, error) {
+	works := []CitedWork{}
+	for offset := 0; ; offset += pageSize {
+		papers, err := repo.FilterNodes(ctx, []string{PaperType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, paper := range papers {
+			backlinks, err := repo.GetBacklinks(ctx, paper.ID)
+			if err != nil {
+				return nil, err
+			}
+			citing := make(map[string]bool)
+			for _, l := range backlinks {
+				if l.Type == CitesLink {
+					citing[l.Source] = true
+				}
+			}
+			if len(citing) > 0 {
+				works = append(works, citedWork(paper, len(citing)))
+			}
+		}
+		if len(papers) < pageSize {
+			break
+		}
+	}
+
+	sort.SliceStable(works, func(i, j int) bool {
+		if works[i].CitedBy != works[j].CitedBy {
+			return works[i].CitedBy > works[j].CitedBy
+		}
+		return works[i].ID < works[j].ID
+	})
+	if limit > 0 && len(works) > limit {
+		works = works[:limit]
+	}
+	return works, nil
+}
+
+func citedWork(paper *core.Node, citedBy int) CitedWork {
+	title, _ := paper.Meta["title"].(string)
+	stub, _ := paper.Meta[stubKey].(bool)
+	return CitedWork{ID: paper.ID, Title: title, Meta: paper.Meta, CitedBy: citedBy, InLibrary: !stub}
+}
+
+// CitationSubgraph narrows a subgraph to its CITES edges and the nodes
+// they connect, keeping focus even when it has none
+func CitationSubgraph(sub *graph.Subgraph, focus string) *graph.Subgraph {
+	out := &graph.Subgraph{Nodes: []*core.Node{}, Edges: []*graph.SubgraphEdge{}, Stats: sub.Stats}
+	keep := map[string]bool{focus: focus != ""}
+	for _, e := range sub.Edges {
+		if e.Type == CitesLink {
+			out.Edges = append(out.Edges, e)
+			keep[e.Source], keep[e.Target] = true, true
+		}
+	}
+	for _, n := range sub.Nodes {
+		if keep[n.ID] {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	out.Stats.NodeCount, out.Stats.EdgeCount = len(out.Nodes), len(out.Edges)
+	return out
+}
+
+// CitationGraph builds the library's citation graph: cited Paper nodes,
+// the nodes citing them and the CITES links between. It stops adding
+// nodes at limit and reports whether it did.
+func CitationGraph(ctx context.Context, repo Repository, limit int) (*graph.Subgraph, bool, error) {
+	sub := &graph.Subgraph{Nodes: []*core.Node{}, Edges: []*graph.SubgraphEdge{}}
+	seen := make(map[string]bool)
+	add := func(node *core.Node) bool {
+		if seen[node.ID] {
+			return true
+		}
+		if len(sub.Nodes) >= limit {
+			return false
+		}
+		seen[node.ID] = true
+		sub.Nodes = append(sub.Nodes, node)
+		return true
+	}
+
+	for offset := 0; ; offset += pageSize {
+		papers, err := repo.FilterNodes(ctx, []string{PaperType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, paper := range papers {
+			backlinks, err := repo.GetBacklinks(ctx, paper.ID)
+			if err != nil {
+				return nil, false, err
+			}
+			for _, l := range backlinks {
+				if l.Type != CitesLink {
+					continue
+				}
+				citing, err := repo.GetNode(ctx, l.Source)
+				if err != nil {
+					continue // Tombstoned citing nodes drop out
+				}
+				if !add(paper) || !add(citing) {
+					sub.Stats = graph.SubgraphStats{NodeCount: len(sub.Nodes), EdgeCount: len(sub.Edges)}
+					return sub, true, nil
+				}
+				sub.Edges = append(sub.Edges, &graph.SubgraphEdge{Source: l.Source, Target: l.Target, Type: CitesLink})
+			}
+		}
+		if len(papers) < pageSize {
+			break
+		}
+	}
+	sub.Stats = graph.SubgraphStats{NodeCount: len(sub.Nodes), EdgeCount: len(sub.Edges)}
+	return sub, false, nil
+}
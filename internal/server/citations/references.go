@@ -0,0 +1,311 @@
+// Package citations parses the reference lists of ingested papers into
+// Paper nodes for the cited works, linked from the citing paper by CITES.
+package citations
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Reference is one entry of a paper's reference list
+type Reference struct {
+	Raw     string   `json:"raw,omitempty"` // The entry as printed
+	Title   string   `json:"title"`
+	Authors []string `json:"authors,omitempty"`
+	Year    int      `json:"year,omitempty"`
+	Venue   string   `json:"venue,omitempty"`
+	DOI     string   `json:"doi,omitempty"`
+}
+
+var (
+	referencesHeading = regexp.MustCompile(`(?im)^[ \t]*(?:\d+\.?[ \t]*)?(references|bibliography|works cited|literature cited)[ \t]*:?[ \t]*$`)
+	numberedEntry     = regexp.MustCompile(`(?m)^[ \t]*(?:\[\d+\]|\d+\.)[ \t]+`)
+	doiPattern        = regexp.MustCompile(`10\.\d{4,9}/[^\s"<>]+`)
+	yearPattern       = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+	quotedTitle       = regexp.MustCompile(`["“]([^"”]{4,})["”]`)
+	initialsOnly      = regexp.MustCompile(`^(?:[A-Z]\.[ -]?)+$`)
+	nonSlug           = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// maxSlugLength caps the title part of a Paper ID
+const maxSlugLength = 80
+
+// PaperID returns the node ID for a cited work: its DOI when known,
+// otherwise its normalized title, so every paper citing the same work
+// links to the same node
+func PaperID(ref Reference) string {
+	if ref.DOI != "" {
+		return "paper:doi:" + strings.ToLower(ref.DOI)
+	}
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(ref.Title), "-"), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		return ""
+	}
+	return "paper:" + slug
+}
+
+// ParseReferences finds the reference section of a paper's text and
+// parses its entries heuristically. Entries without a recognizable title
+// are dropped.
+func ParseReferences(text string) []Reference {
+	loc := referencesHeading.FindAllStringIndex(text, -1)
+	if len(loc) == 0 {
+		return nil
+	}
+	section := text[loc[len(loc)-1][1]:]
+
+	var refs []Reference
+	for _, entry := range splitEntries(section) {
+		if ref, ok := parseEntry(entry); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// splitEntries splits a reference section on numbered markers ([1] or 1.),
+// or else on blank lines, or else one entry per line
+func splitEntries(section string) []string {
+	var parts []string
+	if marks := numberedEntry.FindAllStringIndex(section, -1); len(marks) > 0 {
+		for i, m := range marks {
+			end := len(section)
+			if i+1 < len(marks) {
+				end = marks[i+1][0]
+			}
+			parts = append(parts, section[m[1]:end])
+		}
+	} else if strings.Contains(strings.TrimSpace(section), "\n\n") {
+		parts = strings.Split(section, "\n\n")
+	} else {
+		parts = strings.Split(section, "\n")
+	}
+
+	entries := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.Join(strings.Fields(p), " "); len(p) >= 20 {
+			entries = append(entries, p)
+		}
+	}
+	return entries
+}
+
+// parseEntry reads authors, year, title and DOI out of one entry, which
+// is usually "Authors (Year). Title. Venue." or "Authors. Title. Venue, Year."
+func parseEntry(entry string) (Reference, bool) {
+	ref := Reference{Raw: entry}
+	rest := entry
+	if doi := doiPattern.FindString(entry); doi != "" {
+		ref.DOI = strings.TrimRight(doi, ".,;)]")
+		rest = strings.Replace(rest, doi, "", 1)
+		rest = strings.NewReplacer("https://doi.org/", "", "doi:", "", "DOI:", "").Replace(rest)
+	}
+
+	var authors string
+	if m := quotedTitle.FindStringSubmatchIndex(rest); m != nil {
+		ref.Title = rest[m[2]:m[3]]
+		authors = rest[:m[0]]
+		ref.Venue = firstSentence(rest[m[1]:])
+	} else if y := yearPattern.FindStringIndex(rest); y != nil && y[0] < len(rest)/2 && strings.HasPrefix(strings.TrimLeft(rest[y[1]:], ")]"), ".") {
+		// Author-year style: the title follows the year
+		authors = strings.TrimRight(rest[:y[0]], " ([")
+		after := strings.TrimLeft(rest[y[1]:], ").] ")
+		ref.Title = firstSentence(after)
+		ref.Venue = firstSentence(strings.TrimPrefix(after, ref.Title))
+	} else {
+		sentences := splitSentences(rest)
+		if len(sentences) < 2 {
+			return ref, false
+		}
+		authors, ref.Title = sentences[0], sentences[1]
+		if len(sentences) > 2 {
+			ref.Venue = sentences[2]
+		}
+	}
+
+	if y := yearPattern.FindString(rest); y != "" {
+		ref.Year, _ = strconv.Atoi(y)
+	}
+	ref.Title = strings.Trim(strings.TrimSpace(ref.Title), ".,;: ")
+	ref.Venue = strings.Trim(strings.TrimSpace(yearPattern.ReplaceAllString(ref.Venue, "")), ".,;:() ")
+	ref.Authors = splitAuthors(authors)
+	if len(ref.Title) < 4 || !strings.ContainsFunc(ref.Title, unicode.IsLetter) {
+		return ref, false
+	}
+	return ref, true
+}
+
+// splitSentences splits on ". " while keeping author initials ("J. Smith")
+// with their names
+func splitSentences(s string) []string {
+	var out []string
+	start := 0
+	for i := 0; i+1 < len(s); i++ {
+		if s[i] != '.' || s[i+1] != ' ' {
+			continue
+		}
+		// A single capital before the dot is an initial, not a sentence end
+		if i >= 1 && unicode.IsUpper(rune(s[i-1])) && (i == 1 || !unicode.IsLetter(rune(s[i-2]))) {
+			continue
+		}
+		if part := strings.TrimSpace(s[start:i]); part != "" {
+			out = append(out, part)
+		}
+		start = i + 2
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		out = append(out, part)
+	}
+	return out
+}
+
+// firstSentence returns s up to its first sentence end
+func firstSentence(s string) string {
+	if sentences := splitSentences(strings.TrimSpace(s)); len(sentences) > 0 {
+		return sentences[0]
+	}
+	return ""
+}
+
+// splitAuthors splits an author list on ";", "and", "&" and commas,
+// rejoining initials with the surname they follow ("Smith, J.")
+func splitAuthors(s string) []string {
+	s = strings.NewReplacer(" and ", ";", "&", ";", " et al.", "", " et al", "").Replace(s)
+	var authors []string
+	for _, group := range strings.Split(s, ";") {
+		for _, part := range strings.Split(group, ",") {
+			part = strings.Trim(strings.TrimSpace(part), ".")
+			if part == "" {
+				continue
+			}
+			if n := len(authors); n > 0 && initialsOnly.MatchString(part+".") && !strings.Contains(authors[n-1], ",") {
+				authors[n-1] += ", " + part + "."
+				continue
+			}
+			authors = append(authors, part)
+		}
+	}
+	return authors
+}
+
+// TEI elements of a GROBID reference list
+type teiBibl struct {
+	Analytic struct {
+		Titles  []teiTitle  `xml:"title"`
+		Authors []teiAuthor `xml:"author"`
+		IDNos   []teiIDNo   `xml:"idno"`
+	} `xml:"analytic"`
+	Monogr struct {
+		Titles  []teiTitle  `xml:"title"`
+		Authors []teiAuthor `xml:"author"`
+		IDNos   []teiIDNo   `xml:"idno"`
+		Date    struct {
+			When string `xml:"when,attr"`
+		} `xml:"imprint>date"`
+	} `xml:"monogr"`
+	IDNos []teiIDNo `xml:"idno"`
+	Notes []struct {
+		Type string `xml:"type,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"note"`
+}
+
+type teiTitle struct {
+	Level string `xml:"level,attr"`
+	Text  string `xml:",chardata"`
+}
+
+type teiAuthor struct {
+	Forenames []string `xml:"persName>forename"`
+	Surname   string   `xml:"persName>surname"`
+}
+
+type teiIDNo struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// ParseTEI reads the biblStruct entries of a GROBID TEI document
+func ParseTEI(data []byte) ([]Reference, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var refs []Reference
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing TEI: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "biblStruct" {
+			continue
+		}
+		var b teiBibl
+		if err := dec.DecodeElement(&b, &start); err != nil {
+			return nil, fmt.Errorf("parsing TEI: %w", err)
+		}
+		if ref, ok := b.reference(); ok {
+			refs = append(refs, ref)
+		}
+	}
+}
+
+// reference converts a TEI entry, using the monograph title as the title
+// for books and as the venue for articles
+func (b *teiBibl) reference() (Reference, bool) {
+	var ref Reference
+	authors := b.Analytic.Authors
+	if title := clean(firstTitle(b.Analytic.Titles)); title != "" {
+		ref.Title = title
+		ref.Venue = clean(firstTitle(b.Monogr.Titles))
+	} else {
+		ref.Title = clean(firstTitle(b.Monogr.Titles))
+		authors = b.Monogr.Authors
+	}
+	for _, a := range authors {
+		name := clean(strings.Join(append(a.Forenames, a.Surname), " "))
+		if name != "" {
+			ref.Authors = append(ref.Authors, name)
+		}
+	}
+	if len(b.Monogr.Date.When) >= 4 {
+		ref.Year, _ = strconv.Atoi(b.Monogr.Date.When[:4])
+	}
+	for _, ids := range [][]teiIDNo{b.IDNos, b.Analytic.IDNos, b.Monogr.IDNos} {
+		for _, id := range ids {
+			if strings.EqualFold(id.Type, "DOI") && ref.DOI == "" {
+				ref.DOI = clean(id.Text)
+			}
+		}
+	}
+	for _, n := range b.Notes {
+		if n.Type == "raw_reference" {
+			ref.Raw = clean(n.Text)
+		}
+	}
+	return ref, ref.Title != ""
+}
+
+func firstTitle(titles []teiTitle) string {
+	for _, t := range titles {
+		if strings.TrimSpace(t.Text) != "" {
+			return t.Text
+		}
+	}
+	return ""
+}
+
+// clean collapses whitespace
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
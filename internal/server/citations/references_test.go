@@ -0,0 +1,121 @@
+package citations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+const samplePaper = `Attention Is Not All You Need
+
+1. Introduction
+We build on prior work [1, 2].
+
+References
+
+[1] Vaswani, A., Shazeer, N., and Parmar, N. (2017). Attention is all you need. In Advances in Neural Information Processing Systems.
+[2] J. Devlin, M. Chang, K. Lee. "BERT: Pre-training of deep bidirectional transformers for language understanding". NAACL, 2019. doi:10.18653/v1/N19-1423.
+[3] See above.
+`
+
+func TestParseReferences(t *testing.T) {
+	refs := ParseReferences(samplePaper)
+	if len(refs) != 2 {
+		t.Fatalf("parsed %d references, want 2: %+v", len(refs), refs)
+	}
+
+	if refs[0].Title != "Attention is all you need" || refs[0].Year != 2017 {
+		t.Errorf("ref 1 = %+v", refs[0])
+	}
+	if want := []string{"Vaswani, A.", "Shazeer, N.", "Parmar, N."}; len(refs[0].Authors) != 3 || refs[0].Authors[0] != want[0] || refs[0].Authors[2] != want[2] {
+		t.Errorf("ref 1 authors = %q, want %q", refs[0].Authors, want)
+	}
+	if PaperID(refs[0]) != "paper:attention-is-all-you-need" {
+		t.Errorf("ref 1 ID = %s", PaperID(refs[0]))
+	}
+
+	if refs[1].DOI != "10.18653/v1/N19-1423" || refs[1].Year != 2019 || len(refs[1].Authors) != 3 {
+		t.Errorf("ref 2 = %+v", refs[1])
+	}
+	if PaperID(refs[1]) != "paper:doi:10.18653/v1/n19-1423" {
+		t.Errorf("ref 2 ID = %s", PaperID(refs[1]))
+	}
+
+	if ParseReferences("No reference section here.") != nil {
+		t.Error("expected no references without a heading")
+	}
+}
+
+func TestParseTEI(t *testing.T) {
+	tei := `<TEI xmlns="http://www.tei-c.org/ns/1.0"><text><back><div><listBibl>
+<biblStruct xml:id="b0">
+  <analytic>
+    <title level="a" type="main">Deep residual learning for image recognition</title>
+    <author><persName><forename type="first">Kaiming</forename><surname>He</surname></persName></author>
+    <idno type="DOI">10.1109/CVPR.2016.90</idno>
+  </analytic>
+  <monogr><title level="m">CVPR</title><imprint><date type="published" when="2016-06"/></imprint></monogr>
+  <note type="raw_reference">K. He et al. Deep residual learning. CVPR 2016.</note>
+</biblStruct>
+<biblStruct xml:id="b1"><monogr><title level="m">Pattern Recognition and Machine Learning</title>
+  <author><persName><forename>Christopher</forename><surname>Bishop</surname></persName></author>
+  <imprint><date when="2006"/></imprint></monogr></biblStruct>
+</listBibl></div></back></text></TEI>`
+
+	refs, err := ParseTEI([]byte(tei))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 {
+		t.Fatalf("parsed %d references, want 2", len(refs))
+	}
+	if r := refs[0]; r.Title != "Deep residual learning for image recognition" || r.Venue != "CVPR" || r.Year != 2016 || r.DOI != "10.1109/CVPR.2016.90" || r.Authors[0] != "Kaiming He" || r.Raw == "" {
+		t.Errorf("article = %+v", r)
+	}
+	if r := refs[1]; r.Title != "Pattern Recognition and Machine Learning" || r.Venue != "" || r.Authors[0] != "Christopher Bishop" {
+		t.Errorf("book = %+v", r)
+	}
+}
+
+func TestProcessNodeLinksCitedPapers(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, id := range []string{"paper:a", "paper:b"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: PaperType, Content: []byte(samplePaper), Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := NewProcessor(repo, Config{})
+	result, err := p.ProcessNode(ctx, "paper:a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Parser != ParserHeuristic || result.Papers != 2 || result.Links != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	if again, err := p.ProcessNode(ctx, "paper:a", false); err != nil || again != nil {
+		t.Errorf("reprocessing = %+v, %v; want skipped", again, err)
+	}
+	// The second paper cites the same works, reusing their nodes
+	if result, err := p.ProcessNode(ctx, "paper:b", false); err != nil || result.Papers != 0 || result.Links != 2 {
+		t.Fatalf("second paper = %+v, %v", result, err)
+	}
+
+	top, err := MostCited(ctx, repo, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 2 || top[0].CitedBy != 2 || top[0].InLibrary {
+		t.Errorf("most cited = %+v", top)
+	}
+
+	sub, truncated, err := CitationGraph(ctx, repo, 100)
+	if err != nil || truncated || len(sub.Nodes) != 4 || len(sub.Edges) != 4 {
+		t.Errorf("citation graph = %d nodes, %d edges, truncated %v, err %v", len(sub.Nodes), len(sub.Edges), truncated, err)
+	}
+}
@@ -0,0 +1,325 @@
+// Package conflicts finds contradictory assertions in the graph and marks
+// them with CONFLICTS_WITH links.
+package conflicts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Conflict kinds
+const (
+	// KindLink: one entity has several links of the same type marked
+	// current (e.g. two current employers)
+	KindLink = "link"
+	// KindClaim: claim nodes give different values for the same
+	// subject and property
+	KindClaim = "claim"
+)
+
+// LinkType is the link type used to record conflicts
+const LinkType = "CONFLICTS_WITH"
+
+// Repository is the subset of graph operations conflict detection needs
+type Repository interface {
+	ListNodes(ctx context.Context) ([]string, error)
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	CreateLink(ctx context.Context, link *core.Link) error
+}
+
+// Assertion is one side of a conflict
+type Assertion struct {
+	NodeID    string      `json:"node_id"` // Link target (link kind) or claim node (claim kind)
+	Value     interface{} `json:"value"`
+	Sources   []string    `json:"sources,omitempty"`
+	Lenses    []string    `json:"lenses,omitempty"`
+	Extractor string      `json:"extractor,omitempty"`
+}
+
+// Conflict is a set of assertions that cannot all be true
+type Conflict struct {
+	ID         string       `json:"id"`
+	Kind       string       `json:"kind"`
+	Subject    string       `json:"subject"`
+	Property   string       `json:"property"`
+	Assertions []*Assertion `json:"assertions"`
+	Recorded   bool         `json:"recorded"` // CONFLICTS_WITH links already exist
+}
+
+// Detect scans all current nodes for conflicting assertions
+func Detect(ctx context.Context, repo Repository) ([]*Conflict, error) {
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+
+	var conflicts []*Conflict
+	claims := make(map[[2]string][]*Assertion)
+
+	for _, id := range ids {
+		links, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// Several current targets for the same link type
+		current := make(map[string][]*Assertion)
+		for _, link := range links {
+			if link.Type == LinkType || !isCurrent(link.Meta) {
+				continue
+			}
+			a := &Assertion{NodeID: link.Target, Value: link.Target}
+			if s, ok := link.Meta["source"].(string); ok {
+				a.Sources = []string{s}
+			}
+			if l, ok := link.Meta["lens"].(string); ok {
+				a.Lenses = []string{l}
+			}
+			a.Extractor, _ = link.Meta["extractor"].(string)
+			current[link.Type] = append(current[link.Type], a)
+		}
+		for linkType, assertions := range current {
+			if distinctValues(assertions) > 1 {
+				conflicts = append(conflicts, newConflict(KindLink, id, linkType, assertions))
+			}
+		}
+
+		// Claim nodes are grouped by (subject, property) and compared below
+		node, err := repo.GetNode(ctx, id)
+		if err != nil {
+			continue
+		}
+		subject, _ := node.Meta["subject"].(string)
+		property, _ := node.Meta["property"].(string)
+		value, hasValue := node.Meta["value"]
+		if subject == "" || property == "" || !hasValue {
+			continue
+		}
+		a := &Assertion{NodeID: id, Value: value}
+		a.Extractor, _ = node.Meta["extractor"].(string)
+		for _, link := range links {
+			switch link.Type {
+			case "EXTRACTED_FROM", "DERIVED_FROM":
+				a.Sources = append(a.Sources, link.Target)
+			case "INTERPRETED_THROUGH":
+				a.Lenses = append(a.Lenses, link.Target)
+			}
+		}
+		key := [2]string{subject, property}
+		claims[key] = append(claims[key], a)
+	}
+
+	for key, assertions := range claims {
+		if distinctValues(assertions) > 1 {
+			conflicts = append(conflicts, newConflict(KindClaim, key[0], key[1], assertions))
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Subject != conflicts[j].Subject {
+			return conflicts[i].Subject < conflicts[j].Subject
+		}
+		return conflicts[i].Property < conflicts[j].Property
+	})
+
+	for _, c := range conflicts {
+		recorded := true
+		for _, pair := range c.links() {
+			exists, err := hasLink(ctx, repo, pair[0], pair[1])
+			if err != nil {
+				return nil, err
+			}
+			recorded = recorded && exists
+		}
+		c.Recorded = recorded
+	}
+
+	return conflicts, nil
+}
+
+// Record creates the CONFLICTS_WITH links for conflicts that lack them and
+// returns the number of links created. For link conflicts the entity links
+// to each conflicting target; for claim conflicts each claim links to the
+// claims that disagree with it.
+func Record(ctx context.Context, repo Repository, conflicts []*Conflict) (int, error) {
+	created := 0
+	now := time.Now()
+	for _, c := range conflicts {
+		if c.Recorded {
+			continue
+		}
+		values := make([]interface{}, len(c.Assertions))
+		for i, a := range c.Assertions {
+			values[i] = a.Value
+		}
+		for _, pair := range c.links() {
+			exists, err := hasLink(ctx, repo, pair[0], pair[1])
+			if err != nil {
+				return created, err
+			}
+			if exists {
+				continue
+			}
+			if err := repo.CreateLink(ctx, &core.Link{
+				Source: pair[0],
+				Target: pair[1],
+				Type:   LinkType,
+				Meta: map[string]interface{}{
+					"conflict_id":  c.ID,
+					"kind":         c.Kind,
+					"subject":      c.Subject,
+					"property":     c.Property,
+					"values":       values,
+					"detected_at":  now.Format(time.RFC3339),
+					graph.LayerKey: graph.LayerDerived,
+				},
+				Created:  now,
+				Modified: now,
+			}); err != nil {
+				return created, fmt.Errorf("recording conflict %s: %w", c.ID, err)
+			}
+			created++
+		}
+		c.Recorded = true
+	}
+	return created, nil
+}
+
+// links returns the (source, target) pairs that record this conflict
+func (c *Conflict) links() [][2]string {
+	var pairs [][2]string
+	seen := make(map[[2]string]bool)
+	add := func(source, target string) {
+		pair := [2]string{source, target}
+		if source != target && !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+
+	switch c.Kind {
+	case KindLink:
+		for _, a := range c.Assertions {
+			add(c.Subject, a.NodeID)
+		}
+	case KindClaim:
+		for _, a := range c.Assertions {
+			for _, b := range c.Assertions {
+				if a.NodeID < b.NodeID && fmt.Sprint(a.Value) != fmt.Sprint(b.Value) {
+					add(a.NodeID, b.NodeID)
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// Scanner runs detection and recording periodically
+type Scanner struct {
+	repo     Repository
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewScanner creates a scanner that runs every interval
+func NewScanner(repo Repository, interval time.Duration) *Scanner {
+	return &Scanner{repo: repo, interval: interval, stop: make(chan struct{})}
+}
+
+// Start begins periodic scans
+func (s *Scanner) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.scan()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Conflict scanner started (every %s)", s.interval)
+}
+
+// Stop halts periodic scans and waits for a running scan to finish
+func (s *Scanner) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scanner) scan() {
+	ctx := context.Background()
+	found, err := Detect(ctx, s.repo)
+	if err != nil {
+		log.Printf("Conflict scan failed: %v", err)
+		return
+	}
+	created, err := Record(ctx, s.repo, found)
+	if err != nil {
+		log.Printf("Recording conflicts failed: %v", err)
+		return
+	}
+	if created > 0 {
+		log.Printf("Conflict scan: %d conflicts, %d new %s links", len(found), created, LinkType)
+	}
+}
+
+func newConflict(kind, subject, property string, assertions []*Assertion) *Conflict {
+	sort.Slice(assertions, func(i, j int) bool { return assertions[i].NodeID < assertions[j].NodeID })
+	sum := sha256.Sum256([]byte(kind + "\x00" + subject + "\x00" + property))
+	return &Conflict{
+		ID:         hex.EncodeToString(sum[:6]),
+		Kind:       kind,
+		Subject:    subject,
+		Property:   property,
+		Assertions: assertions,
+	}
+}
+
+// isCurrent reports whether link properties mark the assertion as current
+func isCurrent(meta map[string]interface{}) bool {
+	switch v := meta["current"].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+func distinctValues(assertions []*Assertion) int {
+	values := make(map[string]bool)
+	for _, a := range assertions {
+		values[fmt.Sprint(a.Value)] = true
+	}
+	return len(values)
+}
+
+func hasLink(ctx context.Context, repo Repository, source, target string) (bool, error) {
+	links, err := repo.GetLinks(ctx, source)
+	if err != nil {
+		return false, err
+	}
+	for _, link := range links {
+		if link.Type == LinkType && link.Target == target {
+			return true, nil
+		}
+	}
+	return false, nil
+}
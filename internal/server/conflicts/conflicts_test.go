@@ -0,0 +1,80 @@
+package conflicts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestDetectAndRecord(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+
+	nodes := []*core.Node{
+		{ID: "person:ada", Type: "Person"},
+		{ID: "org:acme", Type: "Organization"},
+		{ID: "org:globex", Type: "Organization"},
+		{ID: "claim:1", Type: "Claim", Meta: map[string]interface{}{"subject": "person:ada", "property": "birth_year", "value": 1815}},
+		{ID: "claim:2", Type: "Claim", Meta: map[string]interface{}{"subject": "person:ada", "property": "birth_year", "value": 1816}},
+		{ID: "claim:3", Type: "Claim", Meta: map[string]interface{}{"subject": "person:ada", "property": "birth_year", "value": 1815}},
+	}
+	for _, n := range nodes {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, target := range []string{"org:acme", "org:globex"} {
+		if err := repo.CreateLink(ctx, &core.Link{
+			Source: "person:ada", Target: target, Type: "WORKS_AT",
+			Meta:    map[string]interface{}{"current": true},
+			Created: now, Modified: now,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := Detect(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 conflicts, got %d", len(found))
+	}
+	kinds := map[string]*Conflict{}
+	for _, c := range found {
+		kinds[c.Kind] = c
+	}
+	if c := kinds[KindLink]; c == nil || c.Property != "WORKS_AT" || len(c.Assertions) != 2 {
+		t.Fatalf("unexpected link conflict: %+v", c)
+	}
+	if c := kinds[KindClaim]; c == nil || c.Property != "birth_year" || len(c.Assertions) != 3 {
+		t.Fatalf("unexpected claim conflict: %+v", c)
+	}
+
+	// Entity -> 2 targets, plus claim:1 -> claim:2 and claim:2 -> claim:3
+	created, err := Record(ctx, repo, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 4 {
+		t.Fatalf("expected 4 links created, got %d", created)
+	}
+
+	again, err := Detect(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range again {
+		if !c.Recorded {
+			t.Errorf("conflict %s not marked recorded", c.ID)
+		}
+	}
+	if created, _ := Record(ctx, repo, again); created != 0 {
+		t.Errorf("expected no new links on rescan, got %d", created)
+	}
+}
@@ -0,0 +1,294 @@
+// Package connectors runs the sources memex pulls from (feeds,
+// directories, GitHub repositories, mailboxes, issue trackers and
+// address books) from declarative definitions, each on its own
+// schedule. Definitions are stored as Connector nodes, written through
+// the API or synced from a JSON or YAML config file, and picked up while
+// the server runs.
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Node types for definitions and their run state
+const (
+	NodeType  = "Connector"
+	StateType = "ConnectorState"
+)
+
+// ID prefixes of the two node types
+const (
+	idPrefix      = "connector:"
+	stateIDPrefix = "connector-state:"
+)
+
+// MinInterval is the shortest allowed schedule
+const MinInterval = time.Minute
+
+// Where a definition comes from
+const (
+	OriginAPI  = "api"
+	OriginFile = "file"
+)
+
+// Errors for definitions that cannot be stored or run
+var (
+	ErrNotFound = errors.New("connector not found")
+	ErrExists   = errors.New("connector already exists")
+	ErrInvalid  = errors.New("invalid connector")
+	ErrManaged  = errors.New("connector is defined in the config file")
+	ErrRunning  = errors.New("connector is already running")
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Connector defines one source: its kind, the kind's settings, where its
+// credentials come from and how often it runs
+type Connector struct {
+	Name        string                 `json:"name"`
+	Kind        string                 `json:"kind"`                  // feed, directory, github, imap, jira, linear or carddav
+	Every       string                 `json:"every,omitempty"`       // Go duration between runs, at least a minute; empty runs only on demand
+	Disabled    bool                   `json:"disabled,omitempty"`    // Kept but not run on schedule
+	Settings    map[string]interface{} `json:"settings,omitempty"`    // Kind-specific, e.g. url for a feed
+	Credentials map[string]string      `json:"credentials,omitempty"` // Name -> env:VAR or file:/path, read at each run
+	Origin      string                 `json:"origin"`                // api or file
+	Created     time.Time              `json:"created"`
+	Modified    time.Time              `json:"modified"`
+}
+
+// interval parses the schedule; 0 when the connector only runs on demand
+func (c *Connector) interval() (time.Duration, error) {
+	if c.Every == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.Every)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid schedule %q", ErrInvalid, c.Every)
+	}
+	if d < MinInterval {
+		return 0, fmt.Errorf("%w: schedule must be at least %s", ErrInvalid, MinInterval)
+	}
+	return d, nil
+}
+
+// validate checks the fields every kind shares. Credentials must be
+// references, so secrets never end up in the graph.
+func (c *Connector) validate() error {
+	if !validName.MatchString(c.Name) {
+		return fmt.Errorf("%w: name %q (use 1-64 letters, digits, _ or -)", ErrInvalid, c.Name)
+	}
+	if _, err := c.interval(); err != nil {
+		return err
+	}
+	for name, ref := range c.Credentials {
+		if !strings.HasPrefix(ref, "env:") && !strings.HasPrefix(ref, "file:") {
+			return fmt.Errorf("%w: credential %q must be an env:VAR or file:/path reference", ErrInvalid, name)
+		}
+	}
+	return nil
+}
+
+// sameDefinition reports whether two definitions would run the same way
+func (c *Connector) sameDefinition(other *Connector) bool {
+	a, b := *c, *other
+	a.Created, a.Modified, b.Created, b.Modified = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	x, err1 := json.Marshal(a)
+	y, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(x) == string(y)
+}
+
+// resolveCredentials reads the values the credential references point at
+func (c *Connector) resolveCredentials() (map[string]string, error) {
+	out := make(map[string]string, len(c.Credentials))
+	for name, ref := range c.Credentials {
+		var value string
+		switch {
+		case strings.HasPrefix(ref, "env:"):
+			value = os.Getenv(strings.TrimPrefix(ref, "env:"))
+		case strings.HasPrefix(ref, "file:"):
+			data, err := os.ReadFile(strings.TrimPrefix(ref, "file:"))
+			if err != nil {
+				return nil, fmt.Errorf("credential %q: %w", name, err)
+			}
+			value = strings.TrimSpace(string(data))
+		}
+		if value == "" {
+			return nil, fmt.Errorf("credential %q: %s is empty", name, ref)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// Status reports how a connector's runs have gone
+type Status struct {
+	Name        string     `json:"name"`
+	Running     bool       `json:"running"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"` // Set while the last run failed
+	Items       int        `json:"items"`                // Written by the last successful run
+	TotalItems  int        `json:"total_items"`
+	Runs        int        `json:"runs"`
+	Failures    int        `json:"failures"`
+	Cursor      string     `json:"cursor,omitempty"` // Where the next run picks up
+	NextRun     *time.Time `json:"next_run,omitempty"`
+}
+
+// Save stores a new definition
+func Save(ctx context.Context, repo graph.Repository, c *Connector) error {
+	if _, err := repo.GetNode(ctx, idPrefix+c.Name); err == nil {
+		return fmt.Errorf("%w: %s", ErrExists, c.Name)
+	}
+	now := time.Now()
+	c.Created, c.Modified = now, now
+	meta, err := toMeta(c)
+	if err != nil {
+		return err
+	}
+	return repo.CreateNode(ctx, &core.Node{ID: idPrefix + c.Name, Type: NodeType, Meta: meta, Created: now, Modified: now})
+}
+
+// Update replaces a definition, keeping its creation time
+func Update(ctx context.Context, repo graph.Repository, c *Connector) error {
+	existing, err := Get(ctx, repo, c.Name)
+	if err != nil {
+		return err
+	}
+	c.Created, c.Modified = existing.Created, time.Now()
+	meta, err := toMeta(c)
+	if err != nil {
+		return err
+	}
+	// Absent optional fields are cleared rather than left from the old version
+	for _, key := range []string{"every", "disabled", "settings", "credentials"} {
+		if _, ok := meta[key]; !ok {
+			meta[key] = nil
+		}
+	}
+	return repo.UpdateNodeMeta(ctx, idPrefix+c.Name, meta)
+}
+
+// Get loads a definition
+func Get(ctx context.Context, repo graph.Repository, name string) (*Connector, error) {
+	node, err := repo.GetNode(ctx, idPrefix+name)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return fromNode(node)
+}
+
+// List returns every definition by name
+func List(ctx context.Context, repo graph.Repository) ([]*Connector, error) {
+	const pageSize = 500
+	out := []*Connector{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			c, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			out = append(out, c)
+		}
+		if len(nodes) < pageSize {
+			break
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Delete removes a definition and its run state. What the connector
+// wrote stays in the graph.
+func Delete(ctx context.Context, repo graph.Repository, name string) error {
+	if _, err := Get(ctx, repo, name); err != nil {
+		return err
+	}
+	if err := repo.DeleteNode(ctx, idPrefix+name, true); err != nil {
+		return err
+	}
+	if _, err := repo.GetNode(ctx, stateIDPrefix+name); err == nil {
+		return repo.DeleteNode(ctx, stateIDPrefix+name, true)
+	}
+	return nil
+}
+
+// toMeta stores a definition's fields as node properties
+func toMeta(c *Connector) (map[string]interface{}, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// fromNode reads a definition back from its node
+func fromNode(node *core.Node) (*Connector, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var c Connector
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("corrupt connector %s: %w", node.ID, err)
+	}
+	c.Name = strings.TrimPrefix(node.ID, idPrefix)
+	return &c, nil
+}
+
+// getState loads a connector's run state; one never run has an empty one
+func getState(ctx context.Context, repo graph.Repository, name string) *Status {
+	status := &Status{Name: name}
+	node, err := repo.GetNode(ctx, stateIDPrefix+name)
+	if err != nil {
+		return status
+	}
+	if data, err := json.Marshal(node.Meta); err == nil {
+		json.Unmarshal(data, status)
+	}
+	status.Name, status.Running, status.NextRun = name, false, nil
+	return status
+}
+
+// saveState stores a connector's run state
+func saveState(ctx context.Context, repo graph.Repository, status *Status) error {
+	stored := *status
+	stored.Running, stored.NextRun = false, nil
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return err
+	}
+	// A recovered connector clears its last error
+	if _, ok := meta["last_error"]; !ok {
+		meta["last_error"] = nil
+	}
+	id := stateIDPrefix + status.Name
+	if _, err := repo.GetNode(ctx, id); err == nil {
+		return repo.UpdateNodeMeta(ctx, id, meta)
+	}
+	now := time.Now()
+	return repo.CreateNode(ctx, &core.Node{ID: id, Type: StateType, Meta: meta, Created: now, Modified: now})
+}
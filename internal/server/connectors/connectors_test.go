@@ -0,0 +1,393 @@
+package connectors
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func sources(t *testing.T, repo graph.Repository) int {
+	t.Helper()
+	nodes, err := repo.FilterNodes(context.Background(), []string{"Source"}, "", "", 1000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(nodes)
+}
+
+func TestValidate(t *testing.T) {
+	m := NewManager(graph.NewMemory(), DefaultKinds())
+	good := &Connector{Name: "news", Kind: "feed", Every: "30m", Settings: map[string]interface{}{"url": "https://example.com/feed.xml"}}
+	if err := m.Validate(good); err != nil {
+		t.Fatalf("valid connector: %v", err)
+	}
+	for name, c := range map[string]*Connector{
+		"bad name":           {Name: "my feed", Kind: "feed", Settings: good.Settings},
+		"short schedule":     {Name: "news", Kind: "feed", Every: "10s", Settings: good.Settings},
+		"bad schedule":       {Name: "news", Kind: "feed", Every: "often", Settings: good.Settings},
+		"literal credential": {Name: "gh", Kind: "github", Settings: map[string]interface{}{"repo": "a/b"}, Credentials: map[string]string{"token": "ghp_secret"}},
+		"unknown kind":       {Name: "news", Kind: "gopher"},
+		"feed without url":   {Name: "news", Kind: "feed"},
+		"relative directory": {Name: "notes", Kind: "directory", Settings: map[string]interface{}{"path": "notes"}},
+		"imap without pass":  {Name: "mail", Kind: "imap", Settings: map[string]interface{}{"host": "mail.example.com", "username": "me"}},
+	} {
+		if err := m.Validate(c); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestAPIAndFileConnectors(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	m := NewManager(repo, DefaultKinds())
+
+	c := &Connector{Name: "notes", Kind: "directory", Every: "1h", Settings: map[string]interface{}{"path": "/srv/notes", "recursive": true}}
+	if err := m.Create(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Create(ctx, &Connector{Name: "notes", Kind: "directory", Settings: c.Settings}); !errors.Is(err, ErrExists) {
+		t.Fatalf("duplicate create: got %v, want ErrExists", err)
+	}
+	// An update replaces the definition; the schedule left out is cleared
+	if err := m.Update(ctx, &Connector{Name: "notes", Kind: "directory", Settings: map[string]interface{}{"path": "/srv/other"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Get(ctx, repo, "notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Every != "" || got.Settings["path"] != "/srv/other" || got.Settings["recursive"] != nil || got.Origin != OriginAPI {
+		t.Fatalf("after update: %+v", got)
+	}
+
+	// The config file takes over a connector of the same name
+	path := filepath.Join(t.TempDir(), "connectors.json")
+	os.WriteFile(path, []byte(`{"connectors": [{"name": "notes", "kind": "directory", "every": "2h", "settings": {"path": "/srv/notes"}}]}`), 0o600)
+	m.SetFile(path)
+	if err := m.LoadFile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = Get(ctx, repo, "notes")
+	if got.Origin != OriginFile || got.Every != "2h" {
+		t.Fatalf("after load: %+v", got)
+	}
+	if err := m.Update(ctx, &Connector{Name: "notes", Kind: "directory", Settings: got.Settings}); !errors.Is(err, ErrManaged) {
+		t.Fatalf("update of file connector: got %v, want ErrManaged", err)
+	}
+	if err := m.Delete(ctx, "notes"); !errors.Is(err, ErrManaged) {
+		t.Fatalf("delete of file connector: got %v, want ErrManaged", err)
+	}
+	if _, err := m.Run(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("run of missing connector: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestLoadFileReload(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	m := NewManager(repo, DefaultKinds())
+	if err := m.Create(ctx, &Connector{Name: "manual", Kind: "feed", Settings: map[string]interface{}{"url": "https://example.com/a.xml"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "connectors.yaml")
+	os.WriteFile(path, []byte(`connectors:
+  - name: news
+    kind: feed
+    every: 30m
+    settings:
+      url: https://example.com/feed.xml
+  - name: code
+    kind: github
+    every: 15m
+    settings:
+      repo: systemshift/memex
+    credentials:
+      token: env:GITHUB_TOKEN
+`), 0o600)
+	m.SetFile(path)
+	if err := m.LoadFile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m.fileChanged() {
+		t.Fatal("file reported changed right after loading")
+	}
+	list, _ := List(ctx, repo)
+	if len(list) != 3 {
+		t.Fatalf("got %d connectors, want 3", len(list))
+	}
+
+	// A file that does not validate changes nothing
+	os.WriteFile(path, []byte("connectors:\n  - name: news\n    kind: feed\n    evry: 1h\n"), 0o600)
+	if err := m.LoadFile(ctx); err == nil {
+		t.Fatal("misspelt field accepted")
+	}
+	if list, _ := List(ctx, repo); len(list) != 3 {
+		t.Fatalf("got %d connectors after a bad file, want 3", len(list))
+	}
+
+	// Removed from the file: deleted; changed: updated; API ones kept
+	os.WriteFile(path, []byte(`connectors:
+  - name: news
+    kind: feed
+    every: 1h
+    disabled: true
+    settings:
+      url: https://example.com/feed.xml
+`), 0o600)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+	if !m.fileChanged() {
+		t.Fatal("rewritten file not noticed")
+	}
+	if err := m.LoadFile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(ctx, repo, "code"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("connector removed from the file: got %v", err)
+	}
+	news, err := Get(ctx, repo, "news")
+	if err != nil || news.Every != "1h" || !news.Disabled {
+		t.Fatalf("news after reload: %+v, %v", news, err)
+	}
+	if _, err := Get(ctx, repo, "manual"); err != nil {
+		t.Fatalf("API connector after reload: %v", err)
+	}
+	// A disabled connector has no next run
+	if status := m.Status(ctx, news); status.NextRun != nil {
+		t.Fatalf("disabled connector scheduled at %v", status.NextRun)
+	}
+}
+
+func TestDirectoryRun(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	m := NewManager(repo, DefaultKinds())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("# Alpha"), 0o600)
+	os.WriteFile(filepath.Join(dir, "b.md"), []byte("# Beta"), 0o600)
+	os.WriteFile(filepath.Join(dir, "c.txt"), []byte("skipped by the pattern"), 0o600)
+	os.Mkdir(filepath.Join(dir, "sub"), 0o700)
+	os.WriteFile(filepath.Join(dir, "sub", "d.md"), []byte("# Delta"), 0o600)
+
+	if err := m.Create(ctx, &Connector{Name: "notes", Kind: "directory", Every: "1h", Settings: map[string]interface{}{"path": dir, "pattern": "*.md"}}); err != nil {
+		t.Fatal(err)
+	}
+	status, err := m.Run(ctx, "notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Items != 2 || status.Runs != 1 || status.LastError != "" || status.Cursor == "" || status.NextRun == nil {
+		t.Fatalf("first run: %+v", status)
+	}
+
+	// Only files changed since the cursor are read again
+	status, _ = m.Run(ctx, "notes")
+	if status.Items != 0 || status.TotalItems != 2 {
+		t.Fatalf("second run: %+v", status)
+	}
+	later := time.Now().Add(time.Hour)
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("# Alpha, revised"), 0o600)
+	os.Chtimes(filepath.Join(dir, "a.md"), later, later)
+	status, _ = m.Run(ctx, "notes")
+	if status.Items != 1 || status.TotalItems != 3 || sources(t, repo) != 3 {
+		t.Fatalf("run after an edit: %+v, %d sources", status, sources(t, repo))
+	}
+	nodes, _ := repo.FilterNodes(ctx, []string{"Source"}, "", "", 10, 0)
+	if nodes[0].Meta["connector_id"] != "connector:notes" || nodes[0].Meta["format"] != "markdown" {
+		t.Fatalf("source meta: %v", nodes[0].Meta)
+	}
+}
+
+func TestScheduledRuns(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	m := NewManager(repo, DefaultKinds())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("# Alpha"), 0o600)
+	for _, c := range []*Connector{
+		{Name: "scheduled", Kind: "directory", Every: "1h", Settings: map[string]interface{}{"path": dir}},
+		{Name: "on-demand", Kind: "directory", Settings: map[string]interface{}{"path": dir}},
+		{Name: "disabled", Kind: "directory", Every: "1h", Disabled: true, Settings: map[string]interface{}{"path": dir}},
+	} {
+		if err := m.Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Never run: due at once; then not again until the interval passes
+	for i := 0; i < 2; i++ {
+		m.check()
+		m.wg.Wait()
+	}
+	for name, runs := range map[string]int{"scheduled": 1, "on-demand": 0, "disabled": 0} {
+		if got := getState(ctx, repo, name).Runs; got != runs {
+			t.Errorf("%s ran %d times, want %d", name, got, runs)
+		}
+	}
+}
+
+func TestRunFailureIsReported(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(graph.NewMemory(), DefaultKinds())
+	c := &Connector{Name: "code", Kind: "github", Settings: map[string]interface{}{"repo": "a/b"},
+		Credentials: map[string]string{"token": "env:MEMEX_TEST_UNSET_TOKEN"}}
+	if err := m.Create(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	status, err := m.Run(ctx, "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Failures != 1 || !strings.Contains(status.LastError, "MEMEX_TEST_UNSET_TOKEN") || status.LastSuccess != nil {
+		t.Fatalf("failed run: %+v", status)
+	}
+}
+
+const rss = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Example</title>
+<item><title>First post</title><link>https://example.com/1</link><guid>1</guid>
+<pubDate>Mon, 02 Mar 2026 10:00:00 +0000</pubDate><description>Hello</description></item>
+<item><title>Second post</title><link>https://example.com/2</link><guid>2</guid>
+<pubDate>Tue, 03 Mar 2026 10:00:00 +0000</pubDate><description>Again</description></item>
+</channel></rss>`
+
+func TestFeedRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, rss)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	m := NewManager(repo, DefaultKinds())
+	if err := m.Create(ctx, &Connector{Name: "news", Kind: "feed", Settings: map[string]interface{}{"url": srv.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	status, err := m.Run(ctx, "news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Items != 2 || status.Cursor != "2026-03-03T10:00:00Z" {
+		t.Fatalf("first run: %+v", status)
+	}
+	if status, _ = m.Run(ctx, "news"); status.Items != 0 || sources(t, repo) != 2 {
+		t.Fatalf("second run: %+v", status)
+	}
+}
+
+// fakeIMAP serves messages to any login until the test ends
+func fakeIMAP(t *testing.T, messages map[int]string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprint(conn, "* OK fake IMAP ready\r\n")
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					tag, cmd, _ := strings.Cut(strings.TrimSpace(line), " ")
+					switch {
+					case strings.HasPrefix(cmd, "LOGIN"):
+						if cmd != `LOGIN "me" "s3cret"` {
+							fmt.Fprintf(conn, "%s NO bad login\r\n", tag)
+							continue
+						}
+					case strings.HasPrefix(cmd, "SELECT"):
+						fmt.Fprint(conn, "* 2 EXISTS\r\n* OK [UIDVALIDITY 7] UIDs valid\r\n")
+					case strings.HasPrefix(cmd, "UID SEARCH"):
+						var from int
+						fmt.Sscanf(cmd, "UID SEARCH UID %d:*", &from)
+						ids, newest := []string{}, 0
+						for uid := range messages {
+							if uid >= from {
+								ids = append(ids, fmt.Sprint(uid))
+							}
+							newest = max(newest, uid)
+						}
+						// Like a real server, n:* matches the newest message
+						if len(ids) == 0 {
+							ids = append(ids, fmt.Sprint(newest))
+						}
+						fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(ids, " "))
+					case strings.HasPrefix(cmd, "UID FETCH"):
+						var uid int
+						fmt.Sscanf(cmd, "UID FETCH %d", &uid)
+						msg := messages[uid]
+						fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, len(msg), msg)
+					case cmd == "LOGOUT":
+						fmt.Fprint(conn, "* BYE\r\n")
+					}
+					fmt.Fprintf(conn, "%s OK done\r\n", tag)
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestIMAPRun(t *testing.T) {
+	t.Setenv("MEMEX_TEST_IMAP_PASSWORD", "s3cret")
+	addr := fakeIMAP(t, map[int]string{
+		11: "From: Ada <ada@example.com>\r\nSubject: Plans\r\nDate: Mon, 02 Mar 2026 10:00:00 +0000\r\nMessage-Id: <1@example.com>\r\n\r\nLet's meet on Friday.\r\n",
+		12: "From: Bob <bob@example.com>\r\nSubject: =?UTF-8?Q?Caf=C3=A9?=\r\nContent-Type: multipart/alternative; boundary=b\r\n\r\n" +
+			"--b\r\nContent-Type: text/html\r\n\r\n<p>Hi</p>\r\n--b\r\nContent-Type: text/plain\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nSee you at the caf=C3=A9\r\n--b--\r\n",
+	})
+
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	m := NewManager(repo, DefaultKinds())
+	if err := m.Create(ctx, &Connector{Name: "mail", Kind: "imap",
+		Settings:    map[string]interface{}{"host": addr, "username": "me", "tls": false},
+		Credentials: map[string]string{"password": "env:MEMEX_TEST_IMAP_PASSWORD"}}); err != nil {
+		t.Fatal(err)
+	}
+	status, err := m.Run(ctx, "mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Items != 2 || status.Cursor != "7:12" || status.LastError != "" {
+		t.Fatalf("first run: %+v", status)
+	}
+	nodes, _ := repo.FilterNodes(ctx, []string{"Source"}, "", "", 10, 0)
+	var cafe bool
+	for _, n := range nodes {
+		if n.Meta["title"] == "Café" {
+			cafe = strings.Contains(string(n.Content), "See you at the café") && n.Meta["format"] == "email"
+		}
+	}
+	if !cafe {
+		t.Fatalf("multipart message not stored as its plain text: %+v", nodes)
+	}
+
+	// The next run asks from UID 13; the newest message returned anyway
+	// is not stored again
+	if status, _ = m.Run(ctx, "mail"); status.Items != 0 || status.LastError != "" || status.Cursor != "7:12" {
+		t.Fatalf("second run: %+v", status)
+	}
+}
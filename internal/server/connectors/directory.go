@@ -0,0 +1,112 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// defaultMaxFile caps the files a directory connector reads
+const defaultMaxFile = 10 << 20
+
+// directoryKind stores files as Sources. Settings: path (absolute),
+// pattern (a glob on file names, default *), recursive, max_bytes. The
+// cursor is the newest modification time read, so only changed files are
+// read again (files from that very moment too, which their content hash
+// keeps from being stored twice); a changed file becomes a new Source.
+type directoryKind struct{}
+
+func (directoryKind) Validate(c *Connector) error {
+	path := setting(c, "path", "")
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("%w: directory connector needs settings.path, an absolute path", ErrInvalid)
+	}
+	if _, err := filepath.Match(setting(c, "pattern", "*"), ""); err != nil {
+		return fmt.Errorf("%w: bad pattern: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+// fileFormat names a file's ingest format by its extension
+func fileFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return "markdown"
+	case ".json":
+		return "json"
+	case ".html", ".htm":
+		return "html"
+	}
+	return "text"
+}
+
+func (directoryKind) Run(ctx context.Context, repo graph.Repository, run *Run) (*Result, error) {
+	root := setting(run.Connector, "path", "")
+	pattern := setting(run.Connector, "pattern", "*")
+	recursive := flag(run.Connector, "recursive")
+	maxBytes := number(run.Connector, "max_bytes", defaultMaxFile)
+
+	var since time.Time
+	if run.Cursor != "" {
+		since, _ = time.Parse(time.RFC3339Nano, run.Cursor)
+	}
+	newest := since
+	result := &Result{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if path != root && (!recursive || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ok, _ := filepath.Match(pattern, d.Name()); !ok || !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(since) || info.Size() == 0 || info.Size() > maxBytes {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		created, err := saveSource(ctx, repo, run.Connector, content, fileFormat(path), map[string]interface{}{
+			"title":    d.Name(),
+			"path":     path,
+			"url":      "file://" + filepath.ToSlash(path),
+			"modified": info.ModTime().UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+		if created {
+			result.Items++
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("reading %s: %w", root, err)
+	}
+	if !newest.IsZero() {
+		result.Cursor = newest.UTC().Format(time.RFC3339Nano)
+	}
+	return result, nil
+}
@@ -0,0 +1,151 @@
+package connectors
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxFeed caps a fetched feed document
+const maxFeed = 20 << 20
+
+// feedKind stores each new RSS or Atom entry as a Source. Settings: url.
+// The cursor is the newest entry date stored, so older entries are
+// skipped; the content hash keeps the rest from being stored twice.
+type feedKind struct{}
+
+func (feedKind) Validate(c *Connector) error {
+	return requireURL(c, "url")
+}
+
+// feedDoc reads both RSS 2.0 (channel/item) and Atom (entry)
+type feedDoc struct {
+	Items   []feedEntry `xml:"channel>item"`
+	Entries []feedEntry `xml:"entry"`
+}
+
+type feedEntry struct {
+	Title       string `xml:"title"`
+	GUID        string `xml:"guid"`
+	ID          string `xml:"id"`
+	Description string `xml:"description"`
+	Summary     string `xml:"summary"`
+	Content     string `xml:"content"`
+	Encoded     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string `xml:"pubDate"`
+	Published   string `xml:"published"`
+	Updated     string `xml:"updated"`
+	Links       []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"link"`
+}
+
+// link returns the entry's page: RSS link text or the Atom alternate link
+func (e *feedEntry) link() string {
+	for _, l := range e.Links {
+		if href := strings.TrimSpace(l.Href); href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return href
+		}
+		if text := strings.TrimSpace(l.Text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// date returns when the entry was published or last updated
+func (e *feedEntry) date() time.Time {
+	for _, s := range []string{e.Updated, e.Published, e.PubDate} {
+		s = strings.TrimSpace(s)
+		for _, layout := range []string{time.RFC3339, time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// body returns the fullest text the entry carries
+func (e *feedEntry) body() string {
+	for _, s := range []string{e.Encoded, e.Content, e.Description, e.Summary} {
+		if s = strings.TrimSpace(s); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func (feedKind) Run(ctx context.Context, repo graph.Repository, run *Run) (*Result, error) {
+	feedURL := setting(run.Connector, "url", "")
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.5")
+	resp, err := run.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching feed: %s returned %d", feedURL, resp.StatusCode)
+	}
+	var doc feedDoc
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxFeed)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing feed: %w", err)
+	}
+
+	var since time.Time
+	if run.Cursor != "" {
+		since, _ = time.Parse(time.RFC3339, run.Cursor)
+	}
+	newest := since
+	result := &Result{}
+	for _, e := range append(doc.Items, doc.Entries...) {
+		date := e.date()
+		if date.Before(since) {
+			continue
+		}
+		title := trimmed(e.Title, 300)
+		content := strings.TrimSpace(title + "\n\n" + e.body())
+		if content == "" {
+			continue
+		}
+		meta := map[string]interface{}{"feed": feedURL}
+		if title != "" {
+			meta["title"] = title
+		}
+		if link := e.link(); link != "" {
+			meta["url"] = link
+		}
+		if id := strings.TrimSpace(e.GUID + e.ID); id != "" {
+			meta["entry_id"] = id
+		}
+		if !date.IsZero() {
+			meta["published"] = date.UTC().Format(time.RFC3339)
+		}
+		created, err := saveSource(ctx, repo, run.Connector, []byte(content), "feed", meta)
+		if err != nil {
+			return result, err
+		}
+		if created {
+			result.Items++
+		}
+		if date.After(newest) {
+			newest = date
+		}
+	}
+	if !newest.IsZero() {
+		result.Cursor = newest.UTC().Format(time.RFC3339)
+	}
+	return result, nil
+}
@@ -0,0 +1,334 @@
+package connectors
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Limits on one IMAP run
+const (
+	imapBatch      = 200      // Messages fetched per run; the next run carries on
+	imapMaxMessage = 25 << 20 // Larger messages are skipped
+)
+
+// imapKind stores new mail in a mailbox as Sources. Settings: host
+// (host or host:port), username, mailbox (default INBOX), tls (default
+// true; false only for a server on a trusted network); credentials:
+// password. The cursor is the mailbox's UIDVALIDITY and the last UID
+// stored, so each message is read once.
+type imapKind struct{}
+
+func (imapKind) Validate(c *Connector) error {
+	if setting(c, "host", "") == "" || setting(c, "username", "") == "" {
+		return fmt.Errorf("%w: imap connector needs settings.host and settings.username", ErrInvalid)
+	}
+	if _, ok := c.Credentials["password"]; !ok {
+		return fmt.Errorf("%w: imap connector needs credentials.password", ErrInvalid)
+	}
+	return nil
+}
+
+func (imapKind) Run(ctx context.Context, repo graph.Repository, run *Run) (*Result, error) {
+	useTLS := true
+	if v, ok := run.Connector.Settings["tls"].(bool); ok {
+		useTLS = v
+	}
+	addr := setting(run.Connector, "host", "")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		if useTLS {
+			addr = net.JoinHostPort(addr, "993")
+		} else {
+			addr = net.JoinHostPort(addr, "143")
+		}
+	}
+	conn, err := dialIMAP(ctx, addr, useTLS)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	mailbox := setting(run.Connector, "mailbox", "INBOX")
+	if _, err := conn.command("LOGIN %s %s", imapQuote(setting(run.Connector, "username", "")), imapQuote(run.Credentials["password"])); err != nil {
+		return nil, fmt.Errorf("imap login: %w", err)
+	}
+	lines, err := conn.command("SELECT %s", imapQuote(mailbox))
+	if err != nil {
+		return nil, fmt.Errorf("imap select %s: %w", mailbox, err)
+	}
+	validity := ""
+	for _, l := range lines {
+		if i := strings.Index(l.text, "[UIDVALIDITY "); i >= 0 {
+			validity = strings.TrimSuffix(strings.Fields(l.text[i+len("[UIDVALIDITY "):])[0], "]")
+		}
+	}
+
+	// A cursor from another UIDVALIDITY no longer names the same
+	// messages, so the mailbox is read again; content hashes keep what
+	// is already stored from being stored twice
+	var last uint64
+	if v, uid, ok := strings.Cut(run.Cursor, ":"); ok && v == validity {
+		last, _ = strconv.ParseUint(uid, 10, 64)
+	}
+	lines, err = conn.command("UID SEARCH UID %d:*", last+1)
+	if err != nil {
+		return nil, fmt.Errorf("imap search: %w", err)
+	}
+	var uids []uint64
+	for _, l := range lines {
+		if !strings.HasPrefix(l.text, "* SEARCH") {
+			continue
+		}
+		for _, f := range strings.Fields(strings.TrimPrefix(l.text, "* SEARCH")) {
+			// n:* always matches the newest message, even below n
+			if uid, err := strconv.ParseUint(f, 10, 64); err == nil && uid > last {
+				uids = append(uids, uid)
+			}
+		}
+	}
+	if len(uids) > imapBatch {
+		uids = uids[:imapBatch]
+	}
+
+	result := &Result{}
+	for _, uid := range uids {
+		lines, err := conn.command("UID FETCH %d (BODY.PEEK[])", uid)
+		if err != nil {
+			return result, fmt.Errorf("imap fetch %d: %w", uid, err)
+		}
+		for _, l := range lines {
+			if l.literal == nil {
+				continue
+			}
+			meta, body, err := parseMail(l.literal)
+			if err != nil || body == "" {
+				continue
+			}
+			meta["mailbox"] = mailbox
+			meta["uid"] = uid
+			created, err := saveSource(ctx, repo, run.Connector, []byte(body), "email", meta)
+			if err != nil {
+				return result, err
+			}
+			if created {
+				result.Items++
+			}
+		}
+		last = uid
+		result.Cursor = validity + ":" + strconv.FormatUint(last, 10)
+	}
+	conn.command("LOGOUT")
+	return result, nil
+}
+
+// imapQuote quotes a string argument
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// imapConn is the little of IMAP4rev1 a connector needs: tagged commands
+// whose untagged responses may carry one literal each
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapLine is an untagged response line and the literal it announced
+type imapLine struct {
+	text    string
+	literal []byte
+}
+
+func dialIMAP(ctx context.Context, addr string, useTLS bool) (*imapConn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	deadline := time.Now().Add(5 * time.Minute)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading greeting from %s: %w", addr, err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("%s refused the connection: %s", addr, strings.TrimSpace(greeting))
+	}
+	return c, nil
+}
+
+// command sends a command and returns its untagged responses, or the
+// server's reason when it does not answer OK
+func (c *imapConn) command(format string, args ...interface{}) ([]imapLine, error) {
+	c.tag++
+	tag := "m" + strconv.Itoa(c.tag)
+	if _, err := fmt.Fprintf(c.conn, tag+" "+format+"\r\n", args...); err != nil {
+		return nil, err
+	}
+	var lines []imapLine
+	for {
+		text, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		text = strings.TrimRight(text, "\r\n")
+		if rest, ok := strings.CutPrefix(text, tag+" "); ok {
+			if !strings.HasPrefix(rest, "OK") {
+				return nil, fmt.Errorf("%s", rest)
+			}
+			return lines, nil
+		}
+		line := imapLine{text: text}
+		if i := strings.LastIndex(text, "{"); i >= 0 && strings.HasSuffix(text, "}") {
+			n, err := strconv.Atoi(text[i+1 : len(text)-1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("bad literal in %q", text)
+			}
+			if n > imapMaxMessage {
+				if _, err := io.CopyN(io.Discard, c.r, int64(n)); err != nil {
+					return nil, err
+				}
+			} else {
+				line.literal = make([]byte, n)
+				if _, err := io.ReadFull(c.r, line.literal); err != nil {
+					return nil, err
+				}
+			}
+			// The rest of the response follows the literal
+			if _, err := c.r.ReadString('\n'); err != nil {
+				return nil, err
+			}
+		}
+		lines = append(lines, line)
+	}
+}
+
+func (c *imapConn) close() {
+	c.conn.Close()
+}
+
+// parseMail reads a message's headers into Source properties and returns
+// its text: the plain text part of a multipart message, else the body
+func parseMail(raw []byte) (map[string]interface{}, string, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		return nil, "", err
+	}
+	dec := new(mime.WordDecoder)
+	header := func(key string) string {
+		v := msg.Header.Get(key)
+		if decoded, err := dec.DecodeHeader(v); err == nil {
+			return decoded
+		}
+		return v
+	}
+	meta := map[string]interface{}{}
+	subject := trimmed(header("Subject"), 300)
+	if subject != "" {
+		meta["title"] = subject
+	}
+	for key, name := range map[string]string{"From": "from", "To": "to", "Message-Id": "message_id"} {
+		if v := strings.TrimSpace(header(key)); v != "" {
+			meta[name] = v
+		}
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		meta["sent"] = date.UTC().Format(time.RFC3339)
+	}
+
+	text, err := mailText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	text = strings.TrimSpace(text)
+	if subject != "" {
+		text = strings.TrimSpace(subject + "\n\n" + text)
+	}
+	return meta, text, nil
+}
+
+// mailText decodes a body, looking inside multipart ones for text/plain
+func mailText(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return "", nil
+			}
+			if err != nil {
+				return "", err
+			}
+			text, err := mailText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", err
+			}
+			if text != "" {
+				return text, nil
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return "", nil
+	}
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	}
+	data, err := io.ReadAll(io.LimitReader(body, imapMaxMessage))
+	return string(data), err
+}
+
+// newlineStripper drops the line breaks base64 bodies are wrapped with
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		count, err := n.r.Read(p)
+		j := 0
+		for _, b := range p[:count] {
+			if b != '\r' && b != '\n' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package connectors
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/github"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/tickets"
+)
+
+// Kind is one kind of connector
+type Kind interface {
+	// Validate checks a definition's settings and credential names
+	Validate(c *Connector) error
+	// Run fetches what is new since the run's cursor and writes it to repo
+	Run(ctx context.Context, repo graph.Repository, run *Run) (*Result, error)
+}
+
+// Run is one run of a connector
+type Run struct {
+	Connector   *Connector
+	Credentials map[string]string // Resolved from the definition's references
+	Cursor      string            // Left by the last successful run
+	HTTPClient  *http.Client
+}
+
+// Result is what a run wrote and where the next one picks up
+type Result struct {
+	Items  int    // Nodes written
+	Cursor string // Empty keeps the previous cursor
+}
+
+// DefaultKinds returns the built-in kinds
+func DefaultKinds() map[string]Kind {
+	return map[string]Kind{
+		"feed":      feedKind{},
+		"directory": directoryKind{},
+		"github":    githubKind{},
+		"jira":      jiraKind{},
+		"linear":    linearKind{},
+		"carddav":   carddavKind{},
+		"imap":      imapKind{},
+	}
+}
+
+// setting returns a string setting, or def when it is unset
+func setting(c *Connector, key, def string) string {
+	if s, ok := c.Settings[key].(string); ok && s != "" {
+		return s
+	}
+	return def
+}
+
+// flag returns a boolean setting
+func flag(c *Connector, key string) bool {
+	b, _ := c.Settings[key].(bool)
+	return b
+}
+
+// number returns a numeric setting, or def when it is unset
+func number(c *Connector, key string, def int64) int64 {
+	switch n := c.Settings[key].(type) {
+	case float64:
+		return int64(n)
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	}
+	return def
+}
+
+// requireURL checks a setting holds an absolute http(s) URL
+func requireURL(c *Connector, key string) error {
+	u, err := url.Parse(setting(c, key, ""))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s connector needs settings.%s, an absolute http(s) URL", ErrInvalid, c.Kind, key)
+	}
+	return nil
+}
+
+// saveSource stores content as a Source node the way /api/ingest does,
+// addressed by its hash, recording the connector it came from. It
+// reports false for content already stored.
+func saveSource(ctx context.Context, repo graph.Repository, c *Connector, content []byte, format string, meta map[string]interface{}) (bool, error) {
+	hash := sha256.Sum256(content)
+	id := "sha256:" + hex.EncodeToString(hash[:])
+	if _, err := repo.GetNode(ctx, id); err == nil {
+		return false, nil
+	}
+	now := time.Now()
+	node := &core.Node{ID: id, Type: "Source", Content: content, Meta: map[string]interface{}{
+		"format":             format,
+		"ingested_at":        now.Format(time.RFC3339),
+		"size_bytes":         len(content),
+		graph.ContentHashKey: hex.EncodeToString(hash[:]),
+		"connector":          c.Kind,
+		"connector_id":       idPrefix + c.Name,
+	}, Created: now, Modified: now}
+	for k, v := range meta {
+		node.Meta[k] = v
+	}
+	if err := repo.CreateNode(ctx, node); err != nil {
+		return false, fmt.Errorf("storing source: %w", err)
+	}
+	return true, nil
+}
+
+// githubKind syncs a repository's issues, pull requests and reviews.
+// Settings: repo (owner/name), api_url; credentials: token.
+type githubKind struct{}
+
+func (githubKind) Validate(c *Connector) error {
+	if _, err := github.ParseRepo(setting(c, "repo", "")); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+func (githubKind) Run(ctx context.Context, repo graph.Repository, run *Run) (*Result, error) {
+	client := &github.Client{
+		BaseURL:    setting(run.Connector, "api_url", github.DefaultAPIURL),
+		Token:      run.Credentials["token"],
+		HTTPClient: run.HTTPClient,
+	}
+	result, err := github.Sync(ctx, repo, client, setting(run.Connector, "repo", ""), false)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Items: result.Created + result.Updated, Cursor: result.SyncedTo}, nil
+}
+
+// jiraKind syncs Jira issues into tasks. Settings: url, email, jql;
+// credentials: token. Tickets keep one sync cursor per tracker, so run
+// one Jira connector per server.
+type jiraKind struct{}
+
+func (jiraKind) Validate(c *Connector) error {
+	if err := requireURL(c, "url"); err != nil {
+		return err
+	}
+	if _, ok := c.Credentials["token"]; !ok {
+		return fmt.Errorf("%w: jira connector needs credentials.token", ErrInvalid)
+	}
+	return nil
+}
+
+func (jiraKind) Run(ctx context.Context, repo graph.Repository, run *Run) (*Result, error) {
+	return syncTickets(ctx, repo, &tickets.Jira{
+		URL:        setting(run.Connector, "url", ""),
+		Email:      setting(run.Connector, "email", ""),
+		Token:      run.Credentials["token"],
+		JQL:        setting(run.Connector, "jql", ""),
+		HTTPClient: run.HTTPClient,
+	})
+}
+
+// linearKind syncs Linear issues into tasks. Settings: team, url;
+// credentials: api_key. Like Jira, one per server.
+type linearKind struct{}
+
+func (linearKind) Validate(c *Connector) error {
+	if _, ok := c.Credentials["api_key"]; !ok {
+		return fmt.Errorf("%w: linear connector needs credentials.api_key", ErrInvalid)
+	}
+	return nil
+}
+
+func (linearKind) Run(ctx context.Context, repo graph.Repository, run *Run) (*Result, error) {
+	return syncTickets(ctx, repo, &tickets.Linear{
+		APIKey:     run.Credentials["api_key"],
+		Team:       setting(run.Connector, "team", ""),
+		URL:        setting(run.Connector, "url", tickets.DefaultLinearURL),
+		HTTPClient: run.HTTPClient,
+	})
+}
+
+func syncTickets(ctx context.Context, repo graph.Repository, src tickets.Source) (*Result, error) {
+	result, err := tickets.Sync(ctx, repo, src, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Items: result.Created + result.Updated, Cursor: result.SyncedTo}, nil
+}
+
+// carddavKind syncs an address book into Person nodes. Settings: url,
+// username; credentials: password.
+type carddavKind struct{}
+
+func (carddavKind) Validate(c *Connector) error {
+	return requireURL(c, "url")
+}
+
+func (carddavKind) Run(ctx context.Context, repo graph.Repository, run *Run) (*Result, error) {
+	contacts, err := people.FetchCardDAV(ctx, people.CardDAVOptions{
+		URL:        setting(run.Connector, "url", ""),
+		Username:   setting(run.Connector, "username", ""),
+		Password:   run.Credentials["password"],
+		HTTPClient: run.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, err := people.SyncContacts(ctx, repo, contacts, people.SyncOptions{Connector: "carddav"})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Items: result.Created + result.Updated}, nil
+}
+
+// trimmed shortens text for a title
+func trimmed(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
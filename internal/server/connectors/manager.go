@@ -0,0 +1,372 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"gopkg.in/yaml.v3"
+)
+
+// checkInterval is how often the manager looks for due connectors and a
+// changed config file
+const checkInterval = 15 * time.Second
+
+// FileConfig is the config file: JSON, or YAML for a .yaml or .yml file
+type FileConfig struct {
+	Connectors []*Connector `json:"connectors"`
+}
+
+// Manager validates definitions against the registered kinds, runs each
+// connector on its schedule and keeps the config file's connectors in
+// step with the file. Definitions are read from the graph on every
+// check, so changes through the API apply without a restart.
+type Manager struct {
+	repo       graph.Repository
+	kinds      map[string]Kind
+	httpClient *http.Client
+	file       string // Optional config file
+
+	mu       sync.Mutex
+	running  map[string]bool
+	fileStat os.FileInfo // As last loaded, to notice changes
+
+	ctx    context.Context // Cancelled by Stop, ending running connectors
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a manager for the given kinds
+func NewManager(repo graph.Repository, kinds map[string]Kind) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		repo:       repo,
+		kinds:      kinds,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		running:    make(map[string]bool),
+		ctx:        ctx,
+		cancel:     cancel,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Kinds lists the registered kinds
+func (m *Manager) Kinds() []string {
+	out := make([]string, 0, len(m.kinds))
+	for k := range m.kinds {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Validate checks a definition and its kind's settings
+func (m *Manager) Validate(c *Connector) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	kind, ok := m.kinds[c.Kind]
+	if !ok {
+		return fmt.Errorf("%w: unknown kind %q (have %s)", ErrInvalid, c.Kind, strings.Join(m.Kinds(), ", "))
+	}
+	return kind.Validate(c)
+}
+
+// Create stores a definition made through the API
+func (m *Manager) Create(ctx context.Context, c *Connector) error {
+	c.Origin = OriginAPI
+	if err := m.Validate(c); err != nil {
+		return err
+	}
+	return Save(ctx, m.repo, c)
+}
+
+// Update replaces a definition made through the API. Connectors from
+// the config file are changed by editing the file.
+func (m *Manager) Update(ctx context.Context, c *Connector) error {
+	existing, err := Get(ctx, m.repo, c.Name)
+	if err != nil {
+		return err
+	}
+	if existing.Origin == OriginFile {
+		return fmt.Errorf("%w: %s", ErrManaged, c.Name)
+	}
+	c.Origin = OriginAPI
+	if err := m.Validate(c); err != nil {
+		return err
+	}
+	return Update(ctx, m.repo, c)
+}
+
+// Delete removes a definition made through the API
+func (m *Manager) Delete(ctx context.Context, name string) error {
+	existing, err := Get(ctx, m.repo, name)
+	if err != nil {
+		return err
+	}
+	if existing.Origin == OriginFile {
+		return fmt.Errorf("%w: %s", ErrManaged, name)
+	}
+	return Delete(ctx, m.repo, name)
+}
+
+// Status returns a connector's run state and, when it runs on a
+// schedule, its next run
+func (m *Manager) Status(ctx context.Context, c *Connector) *Status {
+	status := getState(ctx, m.repo, c.Name)
+	m.mu.Lock()
+	status.Running = m.running[c.Name]
+	m.mu.Unlock()
+	if every, err := c.interval(); err == nil && every > 0 && !c.Disabled {
+		next := time.Now()
+		if status.LastRun != nil && status.LastRun.Add(every).After(next) {
+			next = status.LastRun.Add(every)
+		}
+		status.NextRun = &next
+	}
+	return status
+}
+
+// Run runs a connector now, disabled or not, and returns its status
+// afterwards. A run failing is reported in the status, not as an error.
+func (m *Manager) Run(ctx context.Context, name string) (*Status, error) {
+	c, err := Get(ctx, m.repo, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.run(ctx, c); err != nil {
+		return nil, err
+	}
+	return m.Status(ctx, c), nil
+}
+
+// run runs one connector and records how it went; it only fails when the
+// connector is already running
+func (m *Manager) run(ctx context.Context, c *Connector) error {
+	m.mu.Lock()
+	if m.running[c.Name] {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrRunning, c.Name)
+	}
+	m.running[c.Name] = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, c.Name)
+		m.mu.Unlock()
+	}()
+
+	status := getState(ctx, m.repo, c.Name)
+	start := time.Now().UTC()
+	status.LastRun = &start
+	status.Runs++
+
+	result, err := m.runKind(ctx, c, status.Cursor)
+	if result != nil {
+		status.TotalItems += result.Items
+	}
+	if err != nil {
+		status.Failures++
+		status.LastError = err.Error()
+		log.Printf("Connector %s failed: %v", c.Name, err)
+	} else {
+		status.LastSuccess = &start
+		status.LastError = ""
+		status.Items = result.Items
+		if result.Cursor != "" {
+			status.Cursor = result.Cursor
+		}
+		if result.Items > 0 {
+			log.Printf("Connector %s: %d items", c.Name, result.Items)
+		}
+	}
+	if err := saveState(context.WithoutCancel(ctx), m.repo, status); err != nil {
+		log.Printf("Connector %s: saving run state failed: %v", c.Name, err)
+	}
+	return nil
+}
+
+func (m *Manager) runKind(ctx context.Context, c *Connector, cursor string) (*Result, error) {
+	kind, ok := m.kinds[c.Kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalid, c.Kind)
+	}
+	creds, err := c.resolveCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return kind.Run(ctx, m.repo, &Run{Connector: c, Credentials: creds, Cursor: cursor, HTTPClient: m.httpClient})
+}
+
+// SetFile names the config file whose connectors LoadFile syncs
+func (m *Manager) SetFile(path string) {
+	m.file = path
+}
+
+// LoadFile syncs the config file's connectors into the graph: new ones
+// are created, changed ones updated (taking over an API connector of the
+// same name) and ones gone from the file deleted. A file that does not
+// parse or validate changes nothing.
+func (m *Manager) LoadFile(ctx context.Context) error {
+	info, err := os.Stat(m.file)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.fileStat = info
+	m.mu.Unlock()
+
+	data, err := os.ReadFile(m.file)
+	if err != nil {
+		return err
+	}
+	cfg, err := parseFile(data, filepath.Ext(m.file))
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", m.file, err)
+	}
+	wanted := make(map[string]*Connector, len(cfg.Connectors))
+	for _, c := range cfg.Connectors {
+		c.Origin = OriginFile
+		if err := m.Validate(c); err != nil {
+			return fmt.Errorf("%s: %w", m.file, err)
+		}
+		if wanted[c.Name] != nil {
+			return fmt.Errorf("%s: connector %s is defined twice", m.file, c.Name)
+		}
+		wanted[c.Name] = c
+	}
+
+	existing, err := List(ctx, m.repo)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]*Connector, len(existing))
+	for _, c := range existing {
+		current[c.Name] = c
+		if c.Origin == OriginFile && wanted[c.Name] == nil {
+			if err := Delete(ctx, m.repo, c.Name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range cfg.Connectors {
+		old := current[c.Name]
+		switch {
+		case old == nil:
+			err = Save(ctx, m.repo, c)
+		case !old.sameDefinition(c):
+			if old.Origin != OriginFile {
+				log.Printf("Connector %s from %s replaces the one defined through the API", c.Name, m.file)
+			}
+			err = Update(ctx, m.repo, c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFile reads a config file, YAML when ext says so. Unknown fields
+// are refused, so a misspelt one is not silently ignored.
+func parseFile(data []byte, ext string) (*FileConfig, error) {
+	if ext == ".yaml" || ext == ".yml" {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+	var cfg FileConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// fileChanged reports whether the config file differs from the version
+// last loaded
+func (m *Manager) fileChanged() bool {
+	info, err := os.Stat(m.file)
+	if err != nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fileStat == nil || !info.ModTime().Equal(m.fileStat.ModTime()) || info.Size() != m.fileStat.Size()
+}
+
+// Start begins running connectors on their schedules and watching the
+// config file
+func (m *Manager) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.check()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.check()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Connectors started (kinds: %s)", strings.Join(m.Kinds(), ", "))
+}
+
+// Stop halts scheduling, cancels running connectors and waits for them
+func (m *Manager) Stop() {
+	close(m.stop)
+	m.cancel()
+	m.wg.Wait()
+}
+
+// check reloads a changed config file and starts the connectors due
+func (m *Manager) check() {
+	if m.file != "" && m.fileChanged() {
+		if err := m.LoadFile(m.ctx); err != nil {
+			log.Printf("Connector config not reloaded: %v", err)
+		} else {
+			log.Printf("Connector config reloaded from %s", m.file)
+		}
+	}
+
+	list, err := List(m.ctx, m.repo)
+	if err != nil {
+		log.Printf("Connectors: listing failed: %v", err)
+		return
+	}
+	for _, c := range list {
+		if c.Disabled {
+			continue
+		}
+		status := m.Status(m.ctx, c)
+		if status.Running || status.NextRun == nil || status.NextRun.After(time.Now()) {
+			continue
+		}
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.run(m.ctx, c)
+		}()
+	}
+}
@@ -0,0 +1,109 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+const (
+	memo = `The quarterly planning meeting agreed to move the storage migration to the second week of March.
+Alice will own the rollout plan, Bob will prepare the capacity report, and the team will review both on Friday.
+Any blocking issues with the old cluster should be raised in the infrastructure channel before then.
+Carol asked whether the backup window could shift to Sunday night, since the Saturday jobs overlap with
+the reporting batch. Dave will check the vendor contract for penalties if the cutover slips past the
+end of the quarter, and everyone agreed to freeze schema changes from the first of March onwards.`
+	recipe = `Whisk two eggs with a cup of milk and a pinch of salt, then fold in the flour until the batter is smooth.
+Let it rest for half an hour, heat a buttered pan and pour in a thin layer, turning once when the edges brown.
+Serve warm with lemon and sugar or keep the pancakes covered in a low oven until everyone is ready to eat.
+For a savoury version leave out the sugar, add chopped chives to the batter and fill each pancake with
+grated cheese and ham before folding it into quarters. Leftover batter keeps in the fridge for a day but
+will need a splash of milk and a good stir before it goes back into the pan.`
+)
+
+func TestNearDuplicates(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	start := time.Now().Add(-time.Hour)
+	node := func(id, text string, age int) {
+		created := start.Add(time.Duration(age) * time.Minute)
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Content: []byte(text), Created: created, Modified: created}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	edited := strings.Replace(memo, "Friday", "Thursday", 1) + "\n"
+	a, _ := Fingerprint(memo)
+	b, _ := Fingerprint(edited)
+	c, _ := Fingerprint(recipe)
+	if Distance(a, b) > 6 || Distance(a, c) < 16 {
+		t.Fatalf("distances: edited %d, unrelated %d", Distance(a, b), Distance(a, c))
+	}
+
+	// Duplicates already in the graph are found by a scan
+	node("note:memo", memo, 0)
+	node("note:recipe", recipe, 1)
+	node("note:recipe-copy", strings.ToUpper(recipe)+"\n", 2)
+	node("note:short", "Friday", 3)
+	d := NewDetector(repo, Config{})
+	if err := d.Build(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 3 {
+		t.Errorf("fingerprinted %d nodes, want 3", d.Len())
+	}
+	if n, err := d.Scan(ctx); err != nil || n != 1 {
+		t.Fatalf("Scan = %d, %v; want 1 pair", n, err)
+	}
+	if n, _ := d.Scan(ctx); n != 0 {
+		t.Errorf("second Scan flagged %d, want 0", n)
+	}
+	if _, err := d.Dismiss(ctx, "note:recipe", "note:recipe-copy", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new near-duplicate is flagged as it arrives
+	node("note:memo-v2", edited, 10)
+	d.apply(ctx, subscriptions.Event{Type: subscriptions.EventNodeCreated, NodeID: "note:memo-v2"})
+	pending := d.Pairs(StatusPending)
+	if len(pending) != 1 || pending[0].Duplicate != "note:memo-v2" || pending[0].Original != "note:memo" {
+		t.Fatalf("pending pairs = %+v, want memo-v2 of memo", pending)
+	}
+	links, _ := repo.GetLinks(ctx, "note:memo-v2")
+	if len(links) != 1 || links[0].Type != LinkType || links[0].Meta["status"] != StatusPending {
+		t.Errorf("links of memo-v2 = %+v, want one pending %s", links, LinkType)
+	}
+
+	// Merging moves the duplicate's links to the kept node
+	now := time.Now()
+	repo.CreateLink(ctx, &core.Link{Source: "note:memo-v2", Target: "note:recipe", Type: "MENTIONS", Created: now, Modified: now})
+	repo.CreateLink(ctx, &core.Link{Source: "note:recipe-copy", Target: "note:memo-v2", Type: "CITES", Created: now, Modified: now})
+	if _, err := d.Merge(ctx, "note:memo", "note:recipe", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Merge of an unflagged pair = %v, want ErrNotFound", err)
+	}
+	result, err := d.Merge(ctx, "note:memo-v2", "note:memo", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Moved != 2 || !result.Deleted {
+		t.Errorf("merge = %+v, want 2 links moved and the duplicate deleted", result)
+	}
+	links, _ = repo.GetLinks(ctx, "note:memo")
+	backlinks, _ := repo.GetBacklinks(ctx, "note:memo")
+	if len(links) != 1 || links[0].Target != "note:recipe" || len(backlinks) != 2 {
+		t.Errorf("memo links = %+v, backlinks = %+v; want MENTIONS recipe, and CITES and the merged pair", links, backlinks)
+	}
+	kept, _ := repo.GetNode(ctx, "note:memo")
+	if from, _ := kept.Meta[MergedFromKey].([]interface{}); len(from) != 1 || from[0] != "note:memo-v2" {
+		t.Errorf("merged_from = %v, want [note:memo-v2]", kept.Meta[MergedFromKey])
+	}
+	if merged := d.Pairs(StatusMerged); len(merged) != 1 {
+		t.Errorf("merged pairs = %+v, want 1", merged)
+	}
+}
@@ -0,0 +1,529 @@
+// Package dedup finds nodes whose text is nearly the same, such as a file
+// saved again after a trivial edit, which exact content addressing treats
+// as unrelated. Near-duplicates are marked with NEAR_DUPLICATE_OF links
+// for review, and can be merged or dismissed.
+package dedup
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// LinkType links a near-duplicate to the earlier node it duplicates
+const LinkType = "NEAR_DUPLICATE_OF"
+
+// Review states of a near-duplicate pair
+const (
+	StatusPending   = "pending"
+	StatusDismissed = "dismissed" // Reviewed and kept apart; never flagged again
+	StatusMerged    = "merged"
+)
+
+// Properties recording a merge
+const (
+	MergedIntoKey = "merged_into" // On the duplicate: the node it was merged into
+	MergedFromKey = "merged_from" // On the kept node: the nodes merged into it
+)
+
+var (
+	// ErrNotFound is returned for a pair that was never flagged
+	ErrNotFound = errors.New("near-duplicate pair not found")
+	// ErrInvalidMerge is returned for a merge that cannot be made
+	ErrInvalidMerge = errors.New("invalid merge")
+)
+
+// internalTypes are settings and bookkeeping nodes, never compared
+var internalTypes = map[string]bool{
+	"Subscription": true, "Lens": true, "SavedQuery": true, "LinkRule": true, "MemoryCard": true, "ErasureAudit": true,
+	"Stats": true, "IngestHook": true, "GitHubSync": true, "TicketSync": true, "SynonymSet": true, "TypeDefinition": true,
+	"ModuleSettings": true, "TypeRule": true,
+}
+
+// Config tunes detection. Zero fields take the defaults.
+type Config struct {
+	Threshold float64 // Lowest fingerprint similarity flagged, 0.5 to 1 (default 0.85)
+	MinWords  int     // Shorter texts are not compared (default 30)
+}
+
+// Repository is the subset of graph operations detection and merging need
+type Repository interface {
+	ListNodes(ctx context.Context) ([]string, error)
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	CreateLink(ctx context.Context, link *core.Link) error
+	DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error
+	UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error
+	DeleteNode(ctx context.Context, nodeID string, force bool) error
+}
+
+// Pair is a flagged near-duplicate
+type Pair struct {
+	Duplicate  string    `json:"duplicate"` // The later node
+	Original   string    `json:"original"`  // The earlier node
+	Similarity float64   `json:"similarity"`
+	Status     string    `json:"status"`
+	Detected   time.Time `json:"detected"`
+}
+
+type pairKey struct {
+	duplicate, original string
+}
+
+// entry is an indexed node's fingerprint
+type entry struct {
+	fp      uint64
+	created time.Time
+}
+
+// Detector fingerprints node text as nodes are created and updated and
+// links each new near-duplicate to the earlier node it resembles. The
+// fingerprints are kept in memory and rebuilt at startup; the pairs live
+// in the graph as links.
+type Detector struct {
+	repo        Repository
+	cfg         Config
+	maxDistance int
+	bands       []band
+
+	mu      sync.RWMutex
+	prints  map[string]entry
+	buckets []map[uint64][]string // Per band: key to node IDs
+	pairs   map[pairKey]*Pair
+	ready   bool
+
+	events chan subscriptions.Event
+	stale  chan struct{} // Signals a rebuild after dropped events
+	wg     sync.WaitGroup
+}
+
+// NewDetector creates an empty detector; Start builds its index
+func NewDetector(repo Repository, cfg Config) *Detector {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.85
+	}
+	cfg.Threshold = min(max(cfg.Threshold, 0.5), 1)
+	if cfg.MinWords <= 0 {
+		cfg.MinWords = 30
+	}
+	d := &Detector{
+		repo:        repo,
+		cfg:         cfg,
+		maxDistance: int(64*(1-cfg.Threshold) + 1e-9),
+		events:      make(chan subscriptions.Event, 10000),
+		stale:       make(chan struct{}, 1),
+	}
+	d.bands = splitBands(d.maxDistance + 1)
+	d.reset()
+	return d
+}
+
+// reset empties the index. Caller holds mu or owns d.
+func (d *Detector) reset() {
+	d.prints = make(map[string]entry)
+	d.buckets = make([]map[uint64][]string, len(d.bands))
+	for i := range d.buckets {
+		d.buckets[i] = make(map[uint64][]string)
+	}
+	d.pairs = make(map[pairKey]*Pair)
+}
+
+// Start builds the index in the background and then applies events
+func (d *Detector) Start() {
+	d.wg.Add(1)
+	go d.run()
+}
+
+// Stop waits for queued events to be applied
+func (d *Detector) Stop() {
+	close(d.events)
+	d.wg.Wait()
+}
+
+// Ready reports whether the initial build has finished
+func (d *Detector) Ready() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.ready
+}
+
+// Len returns the number of fingerprinted nodes
+func (d *Detector) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.prints)
+}
+
+// EmitEvent queues a graph change (non-blocking). When the queue is full
+// the event is dropped and the index rebuilt once the queue drains.
+func (d *Detector) EmitEvent(event subscriptions.Event) {
+	select {
+	case d.events <- event:
+	default:
+		select {
+		case d.stale <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (d *Detector) run() {
+	defer d.wg.Done()
+	d.rebuild()
+	for event := range d.events {
+		d.apply(context.Background(), event)
+		select {
+		case <-d.stale:
+			log.Printf("Near-duplicate index fell behind, rebuilding")
+			d.rebuild()
+		default:
+		}
+	}
+}
+
+// rebuild reads every node and its flagged pairs
+func (d *Detector) rebuild() {
+	start := time.Now()
+	if err := d.Build(context.Background()); err != nil {
+		log.Printf("Near-duplicate index build failed: %v", err)
+		return
+	}
+	log.Printf("Near-duplicate index built: %d nodes in %s", d.Len(), time.Since(start).Round(time.Millisecond))
+}
+
+// Build fingerprints every node and reads the pairs already flagged,
+// replacing the index. It does not flag new pairs; Scan does.
+func (d *Detector) Build(ctx context.Context) error {
+	ids, err := d.repo.ListNodes(ctx)
+	if err != nil {
+		return err
+	}
+	fresh := &Detector{bands: d.bands}
+	fresh.reset()
+	for _, id := range ids {
+		node, err := d.repo.GetNode(ctx, id)
+		if err != nil {
+			continue // Deleted since listing
+		}
+		if fp, ok := d.fingerprint(node); ok {
+			fresh.insert(id, entry{fp: fp, created: node.Created})
+		}
+		links, err := d.repo.GetLinks(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			if l.Type == LinkType {
+				fresh.setPair(l)
+			}
+		}
+	}
+
+	d.mu.Lock()
+	d.prints, d.buckets, d.pairs, d.ready = fresh.prints, fresh.buckets, fresh.pairs, true
+	d.mu.Unlock()
+	return nil
+}
+
+// fingerprint returns a node's fingerprint, unless it is not compared
+func (d *Detector) fingerprint(node *core.Node) (uint64, bool) {
+	if internalTypes[node.Type] || node.Meta[MergedIntoKey] != nil {
+		return 0, false
+	}
+	fp, n := Fingerprint(Text(node))
+	return fp, n >= d.cfg.MinWords
+}
+
+// insert indexes a fingerprint. Caller holds mu.
+func (d *Detector) insert(id string, p entry) {
+	d.prints[id] = p
+	for i, b := range d.bands {
+		k := b.key(p.fp)
+		d.buckets[i][k] = append(d.buckets[i][k], id)
+	}
+}
+
+// remove drops a node from the index. Caller holds mu.
+func (d *Detector) remove(id string) {
+	p, ok := d.prints[id]
+	if !ok {
+		return
+	}
+	delete(d.prints, id)
+	for i, b := range d.bands {
+		k := b.key(p.fp)
+		ids := d.buckets[i][k]
+		for j, other := range ids {
+			if other == id {
+				ids = append(ids[:j:j], ids[j+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(d.buckets[i], k)
+		} else {
+			d.buckets[i][k] = ids
+		}
+	}
+}
+
+// matches returns the indexed nodes within the distance limit of fp,
+// other than id. Caller holds mu.
+func (d *Detector) matches(id string, fp uint64) []string {
+	seen := map[string]bool{id: true}
+	var out []string
+	for i, b := range d.bands {
+		for _, other := range d.buckets[i][b.key(fp)] {
+			if seen[other] {
+				continue
+			}
+			seen[other] = true
+			if Distance(fp, d.prints[other].fp) <= d.maxDistance {
+				out = append(out, other)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// setPair records a flagged pair from its link. Caller holds mu.
+func (d *Detector) setPair(l *core.Link) {
+	p := &Pair{Duplicate: l.Source, Original: l.Target, Status: StatusPending, Detected: l.Created}
+	p.Similarity, _ = l.Meta["similarity"].(float64)
+	if s, ok := l.Meta["status"].(string); ok && s != "" {
+		p.Status = s
+	}
+	d.pairs[pairKey{l.Source, l.Target}] = p
+}
+
+// flagged reports whether two nodes already form a pair. Caller holds mu.
+func (d *Detector) flagged(a, b string) bool {
+	return d.pairs[pairKey{a, b}] != nil || d.pairs[pairKey{b, a}] != nil
+}
+
+// candidate is a pair found but not yet linked
+type candidate struct {
+	duplicate, original string
+	similarity          float64
+}
+
+// newPair orders two matching nodes, the later one being the duplicate.
+// Caller holds mu.
+func (d *Detector) newPair(a, b string) candidate {
+	pa, pb := d.prints[a], d.prints[b]
+	if pb.created.After(pa.created) || (pb.created.Equal(pa.created) && b > a) {
+		a, b = b, a
+	}
+	return candidate{duplicate: a, original: b, similarity: Similarity(pa.fp, pb.fp)}
+}
+
+// apply updates the index for one event and flags the near-duplicates a
+// new or changed node has
+func (d *Detector) apply(ctx context.Context, event subscriptions.Event) {
+	switch event.Type {
+	case subscriptions.EventNodeCreated, subscriptions.EventNodeUpdated:
+		node, err := d.repo.GetNode(ctx, event.NodeID)
+		if err != nil {
+			return
+		}
+		fp, ok := d.fingerprint(node)
+		d.mu.Lock()
+		old, had := d.prints[node.ID]
+		if had && ok && old.fp == fp {
+			d.mu.Unlock()
+			return // Only the properties changed
+		}
+		d.remove(node.ID)
+		var found []candidate
+		if ok {
+			d.insert(node.ID, entry{fp: fp, created: node.Created})
+			for _, other := range d.matches(node.ID, fp) {
+				if !d.flagged(node.ID, other) {
+					found = append(found, d.newPair(node.ID, other))
+				}
+			}
+		}
+		d.mu.Unlock()
+		d.flag(ctx, found)
+	case subscriptions.EventNodeDeleted:
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.remove(event.NodeID)
+		for key := range d.pairs {
+			if key.duplicate == event.NodeID || key.original == event.NodeID {
+				delete(d.pairs, key)
+			}
+		}
+	case subscriptions.EventLinkCreated:
+		if event.LinkType != LinkType {
+			return
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.setPair(&core.Link{Source: event.LinkSource, Target: event.LinkTarget, Type: event.LinkType, Meta: event.Meta, Created: event.Timestamp})
+	case subscriptions.EventLinkDeleted:
+		if event.LinkType != LinkType {
+			return
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.pairs, pairKey{event.LinkSource, event.LinkTarget})
+	}
+}
+
+// flag links each candidate pair
+func (d *Detector) flag(ctx context.Context, found []candidate) int {
+	flagged := 0
+	for _, c := range found {
+		now := time.Now()
+		link := &core.Link{
+			Source: c.duplicate,
+			Target: c.original,
+			Type:   LinkType,
+			Meta: map[string]interface{}{
+				"similarity": c.similarity,
+				"status":     StatusPending,
+			},
+			Created:  now,
+			Modified: now,
+		}
+		if err := d.repo.CreateLink(ctx, link); err != nil {
+			if !strings.Contains(err.Error(), "already exists") {
+				log.Printf("Flagging near-duplicate %s of %s failed: %v", c.duplicate, c.original, err)
+			}
+			continue
+		}
+		d.mu.Lock()
+		d.setPair(link)
+		d.mu.Unlock()
+		flagged++
+	}
+	return flagged
+}
+
+// Scan compares every fingerprinted node with every other and flags the
+// near-duplicates not yet flagged, such as those already in the graph
+// when detection was switched on. It returns how many it flagged.
+func (d *Detector) Scan(ctx context.Context) (int, error) {
+	d.mu.RLock()
+	var found []candidate
+	ids := make([]string, 0, len(d.prints))
+	for id := range d.prints {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	seen := make(map[pairKey]bool)
+	for _, id := range ids {
+		for _, other := range d.matches(id, d.prints[id].fp) {
+			c := d.newPair(id, other)
+			key := pairKey{c.duplicate, c.original}
+			if !seen[key] && !d.flagged(id, other) {
+				seen[key] = true
+				found = append(found, c)
+			}
+		}
+	}
+	d.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return d.flag(ctx, found), nil
+}
+
+// Pairs returns the flagged pairs with status, or all of them when status
+// is empty, most similar first
+func (d *Detector) Pairs(status string) []Pair {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := make([]Pair, 0, len(d.pairs))
+	for _, p := range d.pairs {
+		if status == "" || p.Status == status {
+			out = append(out, *p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Similarity != out[j].Similarity {
+			return out[i].Similarity > out[j].Similarity
+		}
+		if out[i].Duplicate != out[j].Duplicate {
+			return out[i].Duplicate < out[j].Duplicate
+		}
+		return out[i].Original < out[j].Original
+	})
+	return out
+}
+
+// pair finds the flagged pair of two nodes, in either direction
+func (d *Detector) pair(a, b string) (Pair, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if p := d.pairs[pairKey{a, b}]; p != nil {
+		return *p, nil
+	}
+	if p := d.pairs[pairKey{b, a}]; p != nil {
+		return *p, nil
+	}
+	return Pair{}, ErrNotFound
+}
+
+// setStatus replaces a pair's link with one in the new review state
+func (d *Detector) setStatus(ctx context.Context, p Pair, status, changedBy string) error {
+	links, err := d.repo.GetLinks(ctx, p.Duplicate)
+	if err != nil {
+		return err
+	}
+	var link *core.Link
+	for _, l := range links {
+		if l.Type == LinkType && l.Target == p.Original {
+			link = l
+			break
+		}
+	}
+	if link == nil {
+		return ErrNotFound
+	}
+
+	meta := make(map[string]interface{}, len(link.Meta)+2)
+	for k, v := range link.Meta {
+		meta[k] = v
+	}
+	meta["status"] = status
+	meta["reviewed"] = time.Now().Format(time.RFC3339)
+	if changedBy != "" {
+		meta["reviewed_by"] = changedBy
+	}
+	if err := d.repo.DeleteLink(ctx, p.Duplicate, p.Original, LinkType); err != nil {
+		return err
+	}
+	replaced := &core.Link{Source: p.Duplicate, Target: p.Original, Type: LinkType, Meta: meta, Created: link.Created, Modified: time.Now()}
+	if err := d.repo.CreateLink(ctx, replaced); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.setPair(replaced)
+	d.mu.Unlock()
+	return nil
+}
+
+// Dismiss marks two flagged nodes as not duplicates, so they are never
+// flagged again
+func (d *Detector) Dismiss(ctx context.Context, a, b, changedBy string) (*Pair, error) {
+	p, err := d.pair(a, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.setStatus(ctx, p, StatusDismissed, changedBy); err != nil {
+		return nil, err
+	}
+	p.Status = StatusDismissed
+	return &p, nil
+}
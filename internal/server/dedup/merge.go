@@ -0,0 +1,138 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// MergeResult reports a merge
+type MergeResult struct {
+	Duplicate string `json:"duplicate"`
+	Into      string `json:"into"`
+	Moved     int    `json:"moved"`   // Links moved to the kept node
+	Dropped   int    `json:"dropped"` // Links the kept node already had
+	Deleted   bool   `json:"deleted"` // Whether the duplicate was deleted; source nodes are kept, marked merged
+}
+
+// Merge folds duplicate into into, which must be a flagged pair in either
+// direction. The duplicate's links move to the kept node, which records
+// the duplicate under merged_from. The duplicate is marked merged_into and
+// then deleted, except content-addressed sources, which are immutable and
+// kept.
+func (d *Detector) Merge(ctx context.Context, duplicate, into, changedBy string) (*MergeResult, error) {
+	if duplicate == into {
+		return nil, fmt.Errorf("%w: a node cannot be merged into itself", ErrInvalidMerge)
+	}
+	p, err := d.pair(duplicate, into)
+	if err != nil {
+		return nil, err
+	}
+	dup, err := d.repo.GetNode(ctx, duplicate)
+	if err != nil {
+		return nil, err
+	}
+	kept, err := d.repo.GetNode(ctx, into)
+	if err != nil {
+		return nil, err
+	}
+	if dup.Meta[MergedIntoKey] != nil {
+		return nil, fmt.Errorf("%w: %s is already merged into %v", ErrInvalidMerge, duplicate, dup.Meta[MergedIntoKey])
+	}
+
+	result := &MergeResult{Duplicate: duplicate, Into: into}
+	if err := d.moveLinks(ctx, duplicate, into, result); err != nil {
+		return result, err
+	}
+
+	var mergedFrom []interface{}
+	if prior, ok := kept.Meta[MergedFromKey].([]interface{}); ok {
+		mergedFrom = prior
+	}
+	mergedFrom = append(mergedFrom, duplicate)
+	if err := d.repo.UpdateNodeMetaWithNote(ctx, into, map[string]any{MergedFromKey: mergedFrom}, "merged near-duplicate "+duplicate, changedBy); err != nil {
+		return result, err
+	}
+	if err := d.repo.UpdateNodeMetaWithNote(ctx, duplicate, map[string]any{MergedIntoKey: into}, "merged into "+into, changedBy); err != nil {
+		return result, err
+	}
+	if err := d.setStatus(ctx, p, StatusMerged, changedBy); err != nil {
+		return result, err
+	}
+	if graph.NodeLayer(dup) != graph.LayerSource {
+		if err := d.repo.DeleteNode(ctx, duplicate, false); err != nil {
+			return result, err
+		}
+		result.Deleted = true
+	}
+	return result, nil
+}
+
+// moveLinks re-points the duplicate's links, both ways, at the kept node.
+// Near-duplicate and attention links stay where they are.
+func (d *Detector) moveLinks(ctx context.Context, duplicate, into string, result *MergeResult) error {
+	outgoing, err := d.repo.GetLinks(ctx, duplicate)
+	if err != nil {
+		return err
+	}
+	incoming, err := d.repo.GetBacklinks(ctx, duplicate)
+	if err != nil {
+		return err
+	}
+	keptOut, err := d.repo.GetLinks(ctx, into)
+	if err != nil {
+		return err
+	}
+	keptIn, err := d.repo.GetBacklinks(ctx, into)
+	if err != nil {
+		return err
+	}
+	has := make(map[[3]string]bool)
+	for _, l := range append(keptOut, keptIn...) {
+		has[[3]string{l.Source, l.Target, l.Type}] = true
+	}
+
+	seen := make(map[[3]string]bool) // A self-link is both outgoing and incoming
+	for _, l := range append(outgoing, incoming...) {
+		original := [3]string{l.Source, l.Target, l.Type}
+		if l.Type == LinkType || l.Type == "ATTENDED" || seen[original] {
+			continue
+		}
+		seen[original] = true
+		moved := *l
+		moved.Meta = linkMeta(l)
+		if moved.Source == duplicate {
+			moved.Source = into
+		}
+		if moved.Target == duplicate {
+			moved.Target = into
+		}
+		key := [3]string{moved.Source, moved.Target, moved.Type}
+		if moved.Source != moved.Target && !has[key] {
+			moved.Modified = time.Now()
+			if err := d.repo.CreateLink(ctx, &moved); err != nil {
+				return fmt.Errorf("moving %s link %s -> %s: %w", l.Type, l.Source, l.Target, err)
+			}
+			has[key] = true
+			result.Moved++
+		} else {
+			result.Dropped++
+		}
+		if err := d.repo.DeleteLink(ctx, l.Source, l.Target, l.Type); err != nil {
+			return fmt.Errorf("removing %s link %s -> %s: %w", l.Type, l.Source, l.Target, err)
+		}
+	}
+	return nil
+}
+
+// linkMeta returns a copy of a link's properties
+func linkMeta(l *core.Link) map[string]interface{} {
+	meta := make(map[string]interface{}, len(l.Meta))
+	for k, v := range l.Meta {
+		meta[k] = v
+	}
+	return meta
+}
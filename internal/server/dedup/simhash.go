@@ -0,0 +1,100 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// shingleSize is the number of words in each feature of a fingerprint
+const shingleSize = 3
+
+// Text returns the text of a node fingerprints are taken from: its
+// extracted text, else its content when that is text, else its content
+// property
+func Text(node *core.Node) string {
+	if text, ok := node.Meta["text"].(string); ok && text != "" {
+		return text
+	}
+	if len(node.Content) > 0 && utf8.Valid(node.Content) {
+		return string(node.Content)
+	}
+	text, _ := node.Meta["content"].(string)
+	return text
+}
+
+// words splits text into lowercase words
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Fingerprint returns the 64-bit SimHash of text over word shingles, and
+// the number of words it saw. Texts that differ in a few words have
+// fingerprints that differ in a few bits.
+func Fingerprint(text string) (uint64, int) {
+	ws := words(text)
+	size := min(shingleSize, len(ws))
+	var votes [64]int
+	for i := 0; i+size <= len(ws) && size > 0; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(ws[i:i+size], " ")))
+		sum := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				votes[b]++
+			} else {
+				votes[b]--
+			}
+		}
+	}
+	var fp uint64
+	for b, v := range votes {
+		if v > 0 {
+			fp |= 1 << b
+		}
+	}
+	return fp, len(ws)
+}
+
+// Distance is the number of bits in which two fingerprints differ
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Similarity is the share of bits two fingerprints agree on, from 0 to 1
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(Distance(a, b))/64
+}
+
+// band is a slice of fingerprint bits used as a lookup key
+type band struct {
+	shift uint
+	mask  uint64
+}
+
+// splitBands divides the 64 bits into n bands. Fingerprints within n-1
+// bits of each other agree on at least one band, so looking up every
+// band finds all of them.
+func splitBands(n int) []band {
+	out := make([]band, n)
+	shift := uint(0)
+	for i := range out {
+		width := uint(64 / n)
+		if i < 64%n {
+			width++
+		}
+		out[i] = band{shift: shift, mask: 1<<width - 1}
+		shift += width
+	}
+	return out
+}
+
+func (b band) key(fp uint64) uint64 {
+	return fp >> b.shift & b.mask
+}
@@ -0,0 +1,269 @@
+// Package devices registers the machines that write to the graph (capture
+// agents, CLI installations) so each write can be traced back to the
+// device that made it. Devices are stored as graph nodes; each holds the
+// hash of a key the device sends with its requests.
+package devices
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// NodeType is the node type devices are stored as
+const NodeType = "Device"
+
+// idPrefix namespaces device node IDs
+const idPrefix = "device:"
+
+// KeyPrefix marks device keys, so they can be told apart from API keys
+const KeyPrefix = "mxd_"
+
+// seenInterval is how often a device's last-seen time is written back to
+// its node; in between it is only kept in memory
+const seenInterval = time.Hour
+
+// Errors returned by the registry
+var (
+	ErrNotFound = errors.New("device not found")
+	ErrUnknown  = errors.New("unknown device key")
+	ErrRevoked  = errors.New("device has been revoked")
+	ErrInvalid  = errors.New("invalid device")
+)
+
+// Device is a registered machine
+type Device struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Platform   string     `json:"platform,omitempty"` // e.g. linux/amd64, as the device reports it
+	Registered time.Time  `json:"registered"`
+	LastSeen   *time.Time `json:"last_seen,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Revoked reports whether the device's key is no longer accepted
+func (d *Device) Revoked() bool {
+	return d.RevokedAt != nil
+}
+
+// Registry registers devices and identifies them by key. Devices are
+// cached by key hash once seen, and last-seen times are kept in memory and
+// written back at most once per seenInterval.
+type Registry struct {
+	repo graph.Repository
+
+	mu     sync.Mutex
+	byHash map[string]*Device
+	seen   map[string]time.Time // Device ID -> last request
+	saved  map[string]time.Time // Device ID -> last-seen time last written
+}
+
+// NewRegistry creates a registry storing devices in repo
+func NewRegistry(repo graph.Repository) *Registry {
+	return &Registry{
+		repo:   repo,
+		byHash: make(map[string]*Device),
+		seen:   make(map[string]time.Time),
+		saved:  make(map[string]time.Time),
+	}
+}
+
+// Register stores a new device and returns it with its key. Only a hash
+// of the key is kept, so it cannot be shown again.
+func (r *Registry) Register(ctx context.Context, name, platform string) (*Device, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, "", fmt.Errorf("%w: name must be 1-100 characters", ErrInvalid)
+	}
+	if len(platform) > 100 {
+		return nil, "", fmt.Errorf("%w: platform must be at most 100 characters", ErrInvalid)
+	}
+
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	key := KeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	now := time.Now().UTC()
+	d := &Device{ID: idPrefix + hex.EncodeToString(id), Name: name, Platform: platform, Registered: now}
+
+	meta := map[string]interface{}{"name": d.Name, "key_hash": hashKey(key)}
+	if platform != "" {
+		meta["platform"] = platform
+	}
+	if err := r.repo.CreateNode(ctx, &core.Node{ID: d.ID, Type: NodeType, Meta: meta, Created: now, Modified: now}); err != nil {
+		return nil, "", fmt.Errorf("storing device: %w", err)
+	}
+	return d, key, nil
+}
+
+// Identify returns the device a key belongs to and records that it was
+// seen. Unknown keys fail with ErrUnknown and revoked devices with
+// ErrRevoked.
+func (r *Registry) Identify(ctx context.Context, key string) (*Device, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return nil, ErrUnknown
+	}
+	hash := hashKey(key)
+
+	r.mu.Lock()
+	d, ok := r.byHash[hash]
+	r.mu.Unlock()
+	if !ok {
+		nodes, err := r.repo.FilterNodes(ctx, []string{NodeType}, "key_hash", hash, 1, 0)
+		if err != nil {
+			return nil, fmt.Errorf("looking up device: %w", err)
+		}
+		if len(nodes) == 0 || nodes[0].Type != NodeType {
+			return nil, ErrUnknown
+		}
+		if d, err = fromNode(nodes[0]); err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		r.byHash[hash] = d
+		r.mu.Unlock()
+	}
+	if d.Revoked() {
+		return nil, fmt.Errorf("%w: %s", ErrRevoked, d.ID)
+	}
+
+	now := time.Now().UTC()
+	r.mu.Lock()
+	r.seen[d.ID] = now
+	due := now.Sub(r.saved[d.ID]) >= seenInterval
+	if due {
+		r.saved[d.ID] = now
+	}
+	r.mu.Unlock()
+	if due {
+		// Detached from the request, so the write is not attributed to
+		// the device itself
+		if err := r.repo.UpdateNodeMeta(context.Background(), d.ID, map[string]interface{}{"last_seen": now.Format(time.RFC3339)}); err != nil {
+			return nil, fmt.Errorf("recording last seen: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// Get loads a device
+func (r *Registry) Get(ctx context.Context, id string) (*Device, error) {
+	if !strings.HasPrefix(id, idPrefix) {
+		id = idPrefix + id
+	}
+	node, err := r.repo.GetNode(ctx, id)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	d, err := fromNode(node)
+	if err != nil {
+		return nil, err
+	}
+	r.lastSeen(d)
+	return d, nil
+}
+
+// List returns all devices, revoked ones included
+func (r *Registry) List(ctx context.Context) ([]*Device, error) {
+	const pageSize = 500
+	out := []*Device{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := r.repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			d, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			r.lastSeen(d)
+			out = append(out, d)
+		}
+		if len(nodes) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+// Revoke stops accepting a device's key. Its earlier writes keep their
+// device stamp. Revoking a revoked device changes nothing.
+func (r *Registry) Revoke(ctx context.Context, id string) (*Device, error) {
+	d, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Revoked() {
+		return d, nil
+	}
+	now := time.Now().UTC()
+	if err := r.repo.UpdateNodeMetaWithNote(ctx, d.ID, map[string]interface{}{"revoked_at": now.Format(time.RFC3339)}, "device revoked", ""); err != nil {
+		return nil, fmt.Errorf("revoking device: %w", err)
+	}
+	d.RevokedAt = &now
+
+	r.mu.Lock()
+	for hash, cached := range r.byHash {
+		if cached.ID == d.ID {
+			delete(r.byHash, hash)
+		}
+	}
+	r.mu.Unlock()
+	return d, nil
+}
+
+// lastSeen fills in a device's last-seen time from memory when it is
+// newer than the stored one
+func (r *Registry) lastSeen(d *Device) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.seen[d.ID]; ok && (d.LastSeen == nil || t.After(*d.LastSeen)) {
+		d.LastSeen = &t
+	}
+}
+
+// hashKey returns the stored form of a device key
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// fromNode reads a device back from its node
+func fromNode(node *core.Node) (*Device, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var stored struct {
+		Name      string     `json:"name"`
+		Platform  string     `json:"platform"`
+		LastSeen  *time.Time `json:"last_seen"`
+		RevokedAt *time.Time `json:"revoked_at"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("reading device %s: %w", node.ID, err)
+	}
+	return &Device{
+		ID:         node.ID,
+		Name:       stored.Name,
+		Platform:   stored.Platform,
+		Registered: node.Created,
+		LastSeen:   stored.LastSeen,
+		RevokedAt:  stored.RevokedAt,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	reg := NewRegistry(repo)
+
+	if _, _, err := reg.Register(ctx, " ", ""); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Register(blank name) error = %v", err)
+	}
+	laptop, key, err := reg.Register(ctx, "laptop", "linux/amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := reg.Register(ctx, "phone", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh registry finds the device from its stored key hash
+	reg = NewRegistry(repo)
+	d, err := reg.Identify(ctx, key)
+	if err != nil || d.ID != laptop.ID || d.Name != "laptop" {
+		t.Fatalf("Identify = %+v, %v", d, err)
+	}
+	if _, err := reg.Identify(ctx, KeyPrefix+"nope"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Identify(unknown key) error = %v", err)
+	}
+	got, err := reg.Get(ctx, laptop.ID)
+	if err != nil || got.LastSeen == nil || got.Platform != "linux/amd64" {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+	if list, _ := reg.List(ctx); len(list) != 2 {
+		t.Errorf("List = %d devices, want 2", len(list))
+	}
+
+	if d, err := reg.Revoke(ctx, laptop.ID); err != nil || !d.Revoked() {
+		t.Fatalf("Revoke = %+v, %v", d, err)
+	}
+	if _, err := reg.Identify(ctx, key); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Identify(revoked) error = %v", err)
+	}
+	if _, err := reg.Get(ctx, "device:missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) error = %v", err)
+	}
+}
+
+func TestStamp(t *testing.T) {
+	repo := Stamp(graph.NewMemory())
+	d := &Device{ID: "device:abc"}
+	ctx := WithDevice(context.Background(), d)
+	now := time.Now()
+
+	if err := repo.CreateNode(ctx, &core.Node{ID: "a", Type: "Note", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateNode(ctx, &core.Node{ID: "b", Type: "Note", Meta: map[string]interface{}{MetaKey: "device:other"}, Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateNode(context.Background(), &core.Node{ID: "c", Type: "Note", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "a", Target: "b", Type: "RELATED", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateNodeMetaWithNote(ctx, "c", map[string]any{"k": "v"}, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[string]interface{}{"a": "device:abc", "b": "device:other", "c": nil} {
+		node, _ := repo.GetNode(context.Background(), id)
+		if node.Meta[MetaKey] != want {
+			t.Errorf("node %s device = %v, want %v", id, node.Meta[MetaKey], want)
+		}
+	}
+	if links, _ := repo.GetLinks(context.Background(), "a"); len(links) != 1 || links[0].Meta[MetaKey] != "device:abc" {
+		t.Errorf("link meta = %+v", links)
+	}
+	if history, _ := repo.GetNodeHistory(context.Background(), "c"); len(history) != 2 || history[0].ChangedBy != "device:abc" {
+		t.Errorf("history = %+v, want the update changed by device:abc", history)
+	}
+}
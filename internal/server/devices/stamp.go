@@ -0,0 +1,88 @@
+package devices
+
+import (
+	"context"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// MetaKey is the property created nodes and links are stamped with: the
+// ID of the device that wrote them
+const MetaKey = "device"
+
+type deviceKey struct{}
+
+// WithDevice returns a context whose writes are attributed to d
+func WithDevice(ctx context.Context, d *Device) context.Context {
+	return context.WithValue(ctx, deviceKey{}, d)
+}
+
+// FromContext returns the device a context's writes come from, or nil
+func FromContext(ctx context.Context) *Device {
+	d, _ := ctx.Value(deviceKey{}).(*Device)
+	return d
+}
+
+// stampingRepository records the request's device on what it writes
+type stampingRepository struct {
+	graph.Repository
+}
+
+// Stamp wraps repo so that nodes and links created with a context from
+// WithDevice carry the device's ID under MetaKey, unless the caller set
+// one, and meta changes without a changed_by are attributed to it.
+// Contexts without a device pass straight through.
+func Stamp(repo graph.Repository) graph.Repository {
+	return &stampingRepository{Repository: repo}
+}
+
+// stamp sets the device on meta, allocating it if needed
+func stamp(meta map[string]interface{}, d *Device) map[string]interface{} {
+	if meta == nil {
+		meta = make(map[string]interface{}, 1)
+	}
+	if _, ok := meta[MetaKey]; !ok {
+		meta[MetaKey] = d.ID
+	}
+	return meta
+}
+
+func (r *stampingRepository) CreateNode(ctx context.Context, node *core.Node) error {
+	if d := FromContext(ctx); d != nil {
+		node.Meta = stamp(node.Meta, d)
+	}
+	return r.Repository.CreateNode(ctx, node)
+}
+
+func (r *stampingRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	if d := FromContext(ctx); d != nil {
+		for _, node := range nodes {
+			node.Meta = stamp(node.Meta, d)
+		}
+	}
+	return r.Repository.CreateNodes(ctx, nodes)
+}
+
+func (r *stampingRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	if d := FromContext(ctx); d != nil {
+		link.Meta = stamp(link.Meta, d)
+	}
+	return r.Repository.CreateLink(ctx, link)
+}
+
+func (r *stampingRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	if d := FromContext(ctx); d != nil {
+		for _, link := range links {
+			link.Meta = stamp(link.Meta, d)
+		}
+	}
+	return r.Repository.CreateLinks(ctx, links)
+}
+
+func (r *stampingRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	if d := FromContext(ctx); d != nil && changedBy == "" {
+		changedBy = d.ID
+	}
+	return r.Repository.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy)
+}
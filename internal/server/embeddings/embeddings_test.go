@@ -0,0 +1,106 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestTrainEmbeddings(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	node := func(id string) {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Concept", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(source, target string) {
+		if err := repo.CreateLink(ctx, &core.Link{Source: source, Target: target, Type: "RELATED", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two cliques joined by one bridge, and a node with no links
+	for _, side := range []string{"a", "b"} {
+		for i := 0; i < 6; i++ {
+			node(fmt.Sprintf("%s%d", side, i))
+			for j := 0; j < i; j++ {
+				link(fmt.Sprintf("%s%d", side, i), fmt.Sprintf("%s%d", side, j))
+			}
+		}
+	}
+	link("a0", "b0")
+	node("lonely")
+
+	path := filepath.Join(t.TempDir(), "embeddings.json")
+	store, err := NewStore(repo, StoreConfig{Path: path, Training: Config{Dimensions: 16}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Similar("a1", 3); !errors.Is(err, ErrNotTrained) {
+		t.Fatalf("Similar before training = %v, want ErrNotTrained", err)
+	}
+	if err := store.Train(ctx, store.Config()); err != nil {
+		t.Fatal(err)
+	}
+	status := store.Status()
+	if status.State != StateDone || status.Nodes != 12 || status.Dimensions != 16 {
+		t.Fatalf("status = %+v, want done with 12 nodes of 16 dimensions", status)
+	}
+
+	neighbors, err := store.Similar("a1", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range neighbors {
+		if !strings.HasPrefix(n.ID, "a") {
+			t.Errorf("neighbors of a1 = %+v, want only its own clique", neighbors)
+			break
+		}
+	}
+	if _, err := store.Similar("lonely", 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Similar(lonely) = %v, want ErrNotFound", err)
+	}
+
+	// The same seed trains the same vectors, and they survive a restart
+	reopened, err := NewStore(repo, StoreConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := reopened.Vector("b3")
+	if err := reopened.Train(ctx, Config{Dimensions: 16}); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := reopened.Vector("b3")
+	if len(before) != 16 || fmt.Sprint(before) != fmt.Sprint(after) {
+		t.Errorf("retrained b3 = %v, want %v", after, before)
+	}
+
+	var buf bytes.Buffer
+	if n, err := store.Export(&buf, FormatTSV); err != nil || n != 12 {
+		t.Fatalf("Export = %d, %v; want 12 vectors", n, err)
+	}
+	line, _, _ := strings.Cut(buf.String(), "\n")
+	if fields := strings.Split(line, "\t"); len(fields) != 17 || fields[0] != "a0" {
+		t.Errorf("first tsv line = %q, want a0 and 16 values", line)
+	}
+
+	if err := store.Request(Config{P: -1}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Request(p=-1) = %v, want ErrInvalidConfig", err)
+	}
+	if err := store.Request(Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Request(Config{}); !errors.Is(err, ErrBusy) {
+		t.Errorf("second Request = %v, want ErrBusy", err)
+	}
+}
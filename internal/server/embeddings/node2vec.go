@@ -0,0 +1,349 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// Config tunes training. Zero fields take the defaults of DefaultConfig;
+// P = Q = 1 walks uniformly, as DeepWalk does.
+type Config struct {
+	Dimensions   int      `json:"dimensions"`           // Vector length
+	WalkLength   int      `json:"walk_length"`          // Nodes per walk
+	WalksPerNode int      `json:"walks_per_node"`       // Walks started from each node
+	Window       int      `json:"window"`               // Nodes either side of a node that count as its context
+	Negative     int      `json:"negative"`             // Negative samples per context node
+	Epochs       int      `json:"epochs"`               // Passes over the walks
+	P            float64  `json:"p"`                    // Return parameter: higher walks back less
+	Q            float64  `json:"q"`                    // In-out parameter: lower explores further out
+	LearningRate float64  `json:"learning_rate"`        // Starting rate, decayed to near zero
+	LinkTypes    []string `json:"link_types,omitempty"` // Only walk these link types; all when empty
+	MaxNodes     int      `json:"max_nodes"`            // Refuse larger graphs
+	Seed         int64    `json:"seed"`                 // Random seed; the same graph and seed train the same vectors
+}
+
+// DefaultConfig returns the training defaults
+func DefaultConfig() Config {
+	return Config{
+		Dimensions:   64,
+		WalkLength:   40,
+		WalksPerNode: 10,
+		Window:       5,
+		Negative:     5,
+		Epochs:       1,
+		P:            1,
+		Q:            1,
+		LearningRate: 0.025,
+		MaxNodes:     200000,
+		Seed:         1,
+	}
+}
+
+// Limits on a training configuration
+const (
+	maxDimensions   = 1024
+	maxWalkLength   = 1000
+	maxWalksPerNode = 1000
+	maxWindow       = 50
+	maxNegative     = 50
+	maxEpochs       = 100
+)
+
+// ErrInvalidConfig is returned for a training configuration out of range
+var ErrInvalidConfig = errors.New("invalid embedding config")
+
+// withDefaults fills zero fields from DefaultConfig and checks the ranges
+func (c Config) withDefaults() (Config, error) {
+	d := DefaultConfig()
+	for _, f := range []struct {
+		v   *int
+		def int
+		max int
+		key string
+	}{
+		{&c.Dimensions, d.Dimensions, maxDimensions, "dimensions"},
+		{&c.WalkLength, d.WalkLength, maxWalkLength, "walk_length"},
+		{&c.WalksPerNode, d.WalksPerNode, maxWalksPerNode, "walks_per_node"},
+		{&c.Window, d.Window, maxWindow, "window"},
+		{&c.Negative, d.Negative, maxNegative, "negative"},
+		{&c.Epochs, d.Epochs, maxEpochs, "epochs"},
+		{&c.MaxNodes, d.MaxNodes, math.MaxInt32, "max_nodes"},
+	} {
+		if *f.v == 0 {
+			*f.v = f.def
+		}
+		if *f.v < 1 || *f.v > f.max {
+			return c, fmt.Errorf("%w: %s must be between 1 and %d", ErrInvalidConfig, f.key, f.max)
+		}
+	}
+	for _, f := range []struct {
+		v   *float64
+		def float64
+		key string
+	}{
+		{&c.P, d.P, "p"},
+		{&c.Q, d.Q, "q"},
+		{&c.LearningRate, d.LearningRate, "learning_rate"},
+	} {
+		if *f.v == 0 {
+			*f.v = f.def
+		}
+		if *f.v < 0 || math.IsNaN(*f.v) || math.IsInf(*f.v, 0) {
+			return c, fmt.Errorf("%w: %s must be a positive number", ErrInvalidConfig, f.key)
+		}
+	}
+	if c.Seed == 0 {
+		c.Seed = d.Seed
+	}
+	return c, nil
+}
+
+// Repository is the subset of graph operations training needs
+type Repository interface {
+	ListNodes(ctx context.Context) ([]string, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+}
+
+// linkGraph is the undirected link structure walks follow; node i is ids[i]
+type linkGraph struct {
+	ids       []string
+	neighbors [][]int32
+}
+
+// loadGraph reads the links of every node, in both directions. Nodes
+// without links are left out: no walk reaches them.
+func loadGraph(ctx context.Context, repo Repository, cfg Config) (*linkGraph, error) {
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	var types map[string]bool
+	if len(cfg.LinkTypes) > 0 {
+		types = make(map[string]bool, len(cfg.LinkTypes))
+		for _, t := range cfg.LinkTypes {
+			types[t] = true
+		}
+	}
+
+	index := make(map[string]int32, len(ids))
+	for i, id := range ids {
+		index[id] = int32(i)
+	}
+	adjacent := make([]map[int32]bool, len(ids))
+	for i, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		links, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("reading links of %s: %w", id, err)
+		}
+		for _, l := range links {
+			j, ok := index[l.Target]
+			if !ok || j == int32(i) || (types != nil && !types[l.Type]) {
+				continue
+			}
+			for _, e := range [][2]int32{{int32(i), j}, {j, int32(i)}} {
+				if adjacent[e[0]] == nil {
+					adjacent[e[0]] = make(map[int32]bool)
+				}
+				adjacent[e[0]][e[1]] = true
+			}
+		}
+	}
+
+	// Renumber the linked nodes
+	g := &linkGraph{}
+	renumber := make([]int32, len(ids))
+	for i, id := range ids {
+		renumber[i] = -1
+		if len(adjacent[i]) > 0 {
+			renumber[i] = int32(len(g.ids))
+			g.ids = append(g.ids, id)
+		}
+	}
+	if len(g.ids) > cfg.MaxNodes {
+		return nil, fmt.Errorf("%w: %d linked nodes is over max_nodes (%d)", ErrInvalidConfig, len(g.ids), cfg.MaxNodes)
+	}
+	g.neighbors = make([][]int32, len(g.ids))
+	for i := range ids {
+		if renumber[i] < 0 {
+			continue
+		}
+		out := make([]int32, 0, len(adjacent[i]))
+		for j := range adjacent[i] {
+			out = append(out, renumber[j])
+		}
+		sort.Slice(out, func(a, b int) bool { return out[a] < out[b] })
+		g.neighbors[renumber[i]] = out
+	}
+	return g, nil
+}
+
+// adjacent reports whether b is a neighbor of a
+func (g *linkGraph) adjacent(a, b int32) bool {
+	n := g.neighbors[a]
+	i := sort.Search(len(n), func(i int) bool { return n[i] >= b })
+	return i < len(n) && n[i] == b
+}
+
+// walk appends a node2vec walk from start to buf. Each step weighs a
+// neighbor 1/p to go back, 1 to stay near the previous node and 1/q to
+// move away from it.
+func (g *linkGraph) walk(rng *rand.Rand, cfg Config, start int32, buf []int32) []int32 {
+	walk := append(buf[:0], start)
+	uniform := cfg.P == 1 && cfg.Q == 1
+	weights := make([]float64, 0, 16)
+	for len(walk) < cfg.WalkLength {
+		cur := walk[len(walk)-1]
+		next := g.neighbors[cur]
+		if len(next) == 0 {
+			break
+		}
+		if uniform || len(walk) == 1 {
+			walk = append(walk, next[rng.Intn(len(next))])
+			continue
+		}
+		prev := walk[len(walk)-2]
+		weights = weights[:0]
+		total := 0.0
+		for _, n := range next {
+			w := 1 / cfg.Q
+			switch {
+			case n == prev:
+				w = 1 / cfg.P
+			case g.adjacent(prev, n):
+				w = 1
+			}
+			weights = append(weights, w)
+			total += w
+		}
+		r := rng.Float64() * total
+		chosen := next[len(next)-1]
+		for i, w := range weights {
+			if r < w {
+				chosen = next[i]
+				break
+			}
+			r -= w
+		}
+		walk = append(walk, chosen)
+	}
+	return walk
+}
+
+// Progress is called with the share of training done, from 0 to 1
+type Progress func(done float64)
+
+// train learns a vector per node with skip-gram and negative sampling over
+// random walks, as word2vec learns words from sentences
+func train(ctx context.Context, g *linkGraph, cfg Config, progress Progress) ([][]float32, error) {
+	n, dims := len(g.ids), cfg.Dimensions
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	in := make([]float32, n*dims)  // The vectors learned
+	out := make([]float32, n*dims) // Context weights
+	for i := range in {
+		in[i] = (rng.Float32() - 0.5) / float32(dims)
+	}
+
+	// Negative samples are drawn by degree^0.75, as word2vec draws words
+	table := make([]int32, 0, n*8)
+	var norm float64
+	for _, nb := range g.neighbors {
+		norm += math.Pow(float64(len(nb)), 0.75)
+	}
+	for i, nb := range g.neighbors {
+		count := int(math.Ceil(math.Pow(float64(len(nb)), 0.75) / norm * float64(cap(table))))
+		for k := 0; k < count; k++ {
+			table = append(table, int32(i))
+		}
+	}
+
+	total := float64(cfg.Epochs * cfg.WalksPerNode * n)
+	done := 0.0
+	grad := make([]float32, dims)
+	var walk []int32
+	order := make([]int32, n)
+	for i := range order {
+		order[i] = int32(i)
+	}
+	for epoch := 0; epoch < cfg.Epochs; epoch++ {
+		for round := 0; round < cfg.WalksPerNode; round++ {
+			rng.Shuffle(n, func(a, b int) { order[a], order[b] = order[b], order[a] })
+			for _, start := range order {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				rate := float32(cfg.LearningRate * math.Max(1-done/total, 0.0001))
+				walk = g.walk(rng, cfg, start, walk)
+				for i, center := range walk {
+					// A random window up to the configured one weighs near nodes more
+					window := 1 + rng.Intn(cfg.Window)
+					for j := max(0, i-window); j <= min(len(walk)-1, i+window); j++ {
+						if j != i {
+							trainPair(in, out, dims, center, walk[j], cfg.Negative, table, rng, rate, grad)
+						}
+					}
+				}
+				done++
+				if progress != nil && int(done)%1000 == 0 {
+					progress(done / total)
+				}
+			}
+		}
+	}
+
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = in[i*dims : (i+1)*dims : (i+1)*dims]
+	}
+	return vectors, nil
+}
+
+// trainPair moves center's vector towards context's and away from
+// negative samples
+func trainPair(in, out []float32, dims int, center, context int32, negative int, table []int32, rng *rand.Rand, rate float32, grad []float32) {
+	for k := range grad {
+		grad[k] = 0
+	}
+	v := in[int(center)*dims : int(center+1)*dims]
+	for s := 0; s <= negative; s++ {
+		target, label := context, float32(1)
+		if s > 0 {
+			target, label = table[rng.Intn(len(table))], 0
+			if target == context {
+				continue
+			}
+		}
+		u := out[int(target)*dims : int(target+1)*dims]
+		var dot float32
+		for k := range v {
+			dot += v[k] * u[k]
+		}
+		g := (label - sigmoid(dot)) * rate
+		for k := range v {
+			grad[k] += g * u[k]
+			u[k] += g * v[k]
+		}
+	}
+	for k := range v {
+		v[k] += grad[k]
+	}
+}
+
+func sigmoid(x float32) float32 {
+	switch {
+	case x > 6:
+		return 1
+	case x < -6:
+		return 0
+	}
+	return float32(1 / (1 + math.Exp(-float64(x))))
+}
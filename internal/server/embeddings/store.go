@@ -0,0 +1,395 @@
+// Package embeddings learns structural embeddings of nodes. A background
+// job walks the link graph node2vec-style and trains a vector per linked
+// node, so nodes that sit in similar places in the graph get similar
+// vectors even when they have little text of their own.
+package embeddings
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotTrained is returned before any vectors have been trained
+	ErrNotTrained = errors.New("no embeddings trained yet")
+	// ErrNotFound is returned for a node without a vector
+	ErrNotFound = errors.New("node has no embedding")
+	// ErrBusy is returned when a training job is already queued or running
+	ErrBusy = errors.New("embedding training already in progress")
+)
+
+// Training job states
+const (
+	StateIdle    = "idle"
+	StateQueued  = "queued"
+	StateRunning = "running"
+	StateDone    = "done"
+	StateFailed  = "failed"
+)
+
+// StoreConfig configures the embedding store
+type StoreConfig struct {
+	Path     string        // JSON file vectors are saved to; empty keeps them in memory only
+	Interval time.Duration // How often vectors are retrained; 0 only on request
+	Training Config        // Training defaults
+}
+
+// Status describes the training job and the vectors it last produced
+type Status struct {
+	State      string     `json:"state"`
+	Progress   float64    `json:"progress"` // Share of the running job done
+	Started    *time.Time `json:"started,omitempty"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Nodes      int        `json:"nodes"` // Nodes with a vector
+	Dimensions int        `json:"dimensions"`
+	Trained    *time.Time `json:"trained,omitempty"` // When the current vectors were trained
+	Config     *Config    `json:"config,omitempty"`  // What the current vectors were trained with
+	Path       string     `json:"path,omitempty"`
+}
+
+// Neighbor is a node and its cosine similarity to another
+type Neighbor struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// model is one training run's vectors; it is replaced whole, never modified
+type model struct {
+	Trained time.Time   `json:"trained"`
+	Config  Config      `json:"config"`
+	IDs     []string    `json:"ids"`
+	Vectors [][]float32 `json:"vectors"`
+
+	index map[string]int
+	norms []float64
+}
+
+// Store trains and serves node embeddings
+type Store struct {
+	cfg  StoreConfig
+	repo Repository
+
+	mu       sync.RWMutex
+	model    *model
+	status   Status
+	requests chan Config
+
+	saveMu sync.Mutex // Serializes file writes
+}
+
+// NewStore returns a store training over repo, with the vectors saved at
+// cfg.Path loaded
+func NewStore(repo Repository, cfg StoreConfig) (*Store, error) {
+	training, err := cfg.Training.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Training = training
+	s := &Store{
+		cfg:      cfg,
+		repo:     repo,
+		status:   Status{State: StateIdle, Path: cfg.Path},
+		requests: make(chan Config, 1),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the saved vectors, if any
+func (s *Store) load() error {
+	if s.cfg.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.cfg.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading embeddings: %w", err)
+	}
+	var m model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("parsing embeddings %s: %w", s.cfg.Path, err)
+	}
+	if len(m.IDs) != len(m.Vectors) {
+		return fmt.Errorf("parsing embeddings %s: %d ids for %d vectors", s.cfg.Path, len(m.IDs), len(m.Vectors))
+	}
+	m.prepare()
+	s.model = &m
+	return nil
+}
+
+// save writes the vectors to a temporary file and renames it over the
+// store, so a crash never leaves a half-written file behind
+func (s *Store) save(m *model) error {
+	if s.cfg.Path == "" {
+		return nil
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.cfg.Path), ".embeddings-*")
+	if err != nil {
+		return fmt.Errorf("saving embeddings: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving embeddings: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving embeddings: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving embeddings: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.cfg.Path); err != nil {
+		return fmt.Errorf("saving embeddings: %w", err)
+	}
+	return nil
+}
+
+// prepare indexes the vectors and computes their norms
+func (m *model) prepare() {
+	m.index = make(map[string]int, len(m.IDs))
+	m.norms = make([]float64, len(m.Vectors))
+	for i, id := range m.IDs {
+		m.index[id] = i
+		var sum float64
+		for _, x := range m.Vectors[i] {
+			sum += float64(x) * float64(x)
+		}
+		m.norms[i] = math.Sqrt(sum)
+	}
+}
+
+// Config returns the training defaults
+func (s *Store) Config() Config {
+	return s.cfg.Training
+}
+
+// Status describes the training job and the current vectors
+func (s *Store) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := s.status
+	if m := s.model; m != nil {
+		status.Nodes = len(m.IDs)
+		status.Dimensions = m.Config.Dimensions
+		trained, cfg := m.Trained, m.Config
+		status.Trained, status.Config = &trained, &cfg
+	}
+	return status
+}
+
+// Request queues a training run with cfg for Run to pick up. Zero fields
+// of cfg take DefaultConfig's values.
+func (s *Store) Request(cfg Config) error {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.State == StateQueued || s.status.State == StateRunning {
+		return ErrBusy
+	}
+	select {
+	case s.requests <- cfg:
+	default:
+		return ErrBusy
+	}
+	s.status = Status{State: StateQueued, Path: s.cfg.Path}
+	return nil
+}
+
+// Train trains vectors over the repository's links with cfg and replaces
+// the current ones when it succeeds
+func (s *Store) Train(ctx context.Context, cfg Config) error {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.status.State == StateRunning {
+		s.mu.Unlock()
+		return ErrBusy
+	}
+	started := time.Now()
+	s.status = Status{State: StateRunning, Started: &started, Path: s.cfg.Path}
+	s.mu.Unlock()
+
+	m, err := s.train(ctx, cfg)
+	if err == nil {
+		err = s.save(m)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := time.Now()
+	s.status.Finished = &finished
+	if err != nil {
+		s.status.State = StateFailed
+		s.status.Error = err.Error()
+		return err
+	}
+	s.model = m
+	s.status.State = StateDone
+	s.status.Progress = 1
+	return nil
+}
+
+// train runs one training job
+func (s *Store) train(ctx context.Context, cfg Config) (*model, error) {
+	g, err := loadGraph(ctx, s.repo, cfg)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := train(ctx, g, cfg, func(done float64) {
+		s.mu.Lock()
+		s.status.Progress = done
+		s.mu.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := &model{Trained: time.Now(), Config: cfg, IDs: g.ids, Vectors: vectors}
+	m.prepare()
+	return m, nil
+}
+
+// Vector returns a node's vector
+func (s *Store) Vector(id string) ([]float32, error) {
+	s.mu.RLock()
+	m := s.model
+	s.mu.RUnlock()
+	if m == nil {
+		return nil, ErrNotTrained
+	}
+	i, ok := m.index[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.Vectors[i], nil
+}
+
+// Similar returns the k nodes whose vectors are most similar to id's,
+// most similar first
+func (s *Store) Similar(id string, k int) ([]Neighbor, error) {
+	s.mu.RLock()
+	m := s.model
+	s.mu.RUnlock()
+	if m == nil {
+		return nil, ErrNotTrained
+	}
+	i, ok := m.index[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	v := m.Vectors[i]
+	out := make([]Neighbor, 0, len(m.IDs)-1)
+	for j, u := range m.Vectors {
+		if j == i || m.norms[i] == 0 || m.norms[j] == 0 {
+			continue
+		}
+		var dot float64
+		for d := range v {
+			dot += float64(v[d]) * float64(u[d])
+		}
+		out = append(out, Neighbor{ID: m.IDs[j], Score: dot / (m.norms[i] * m.norms[j])})
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if out[a].Score != out[b].Score {
+			return out[a].Score > out[b].Score
+		}
+		return out[a].ID < out[b].ID
+	})
+	if k > 0 && len(out) > k {
+		out = out[:k]
+	}
+	return out, nil
+}
+
+// Export formats
+const (
+	FormatJSONL = "jsonl" // One {"id", "vector"} object per line
+	FormatTSV   = "tsv"   // The id then the values, tab separated, one node per line
+)
+
+// Export writes every vector in format and returns how many it wrote
+func (s *Store) Export(w io.Writer, format string) (int, error) {
+	s.mu.RLock()
+	m := s.model
+	s.mu.RUnlock()
+	if m == nil {
+		return 0, ErrNotTrained
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i, id := range m.IDs {
+		switch format {
+		case FormatTSV:
+			bw.WriteString(id)
+			for _, x := range m.Vectors[i] {
+				bw.WriteByte('\t')
+				bw.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+			}
+			if err := bw.WriteByte('\n'); err != nil {
+				return i, err
+			}
+		default:
+			if err := enc.Encode(struct {
+				ID     string    `json:"id"`
+				Vector []float32 `json:"vector"`
+			}{id, m.Vectors[i]}); err != nil {
+				return i, err
+			}
+		}
+	}
+	return len(m.IDs), bw.Flush()
+}
+
+// Run trains on request and every retraining interval until ctx is done.
+// Errors are passed to onError.
+func (s *Store) Run(ctx context.Context, onError func(error)) {
+	var retrain <-chan time.Time
+	if s.cfg.Interval > 0 {
+		t := time.NewTicker(s.cfg.Interval)
+		defer t.Stop()
+		retrain = t.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case cfg := <-s.requests:
+			err = s.Train(ctx, cfg)
+		case <-retrain:
+			err = s.Train(ctx, s.cfg.Training)
+		}
+		if err != nil && ctx.Err() == nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+// Package evidence records which passages of a source support a node
+// extracted from it, so answers can cite the exact text rather than the
+// whole document.
+package evidence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// LinkType links an extracted node to a source, with the supporting
+// passages in its spans property
+const LinkType = "SUPPORTED_BY"
+
+// SpansKey is the link property holding the passages
+const SpansKey = "spans"
+
+// DefaultContext is how many characters around a passage are returned
+const DefaultContext = 80
+
+// Passage states against the source's current text
+const (
+	StatusExact    = "exact"    // The quoted text is at the stored offsets
+	StatusMoved    = "moved"    // The quoted text is elsewhere; offsets point at its new place
+	StatusOrphaned = "orphaned" // The quoted text is gone, or was never recorded and the offsets are out of range
+)
+
+var (
+	// ErrNotFound is returned when the node or source does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidSpan is returned for a passage that cannot be placed in the source
+	ErrInvalidSpan = errors.New("invalid span")
+)
+
+// Span is a passage of a source in characters (Unicode code points), end
+// exclusive. Chunk optionally numbers the piece of the source the
+// extractor was given.
+type Span struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Chunk *int   `json:"chunk,omitempty"`
+	Quote string `json:"quote,omitempty"` // The passage text when recorded
+}
+
+// SpanRequest places a passage by offsets, or by quote when the offsets
+// are left out. Occurrence picks among repeated quotes, counting from 1.
+type SpanRequest struct {
+	Start      *int   `json:"start,omitempty"`
+	End        *int   `json:"end,omitempty"`
+	Quote      string `json:"quote,omitempty"`
+	Occurrence int    `json:"occurrence,omitempty"`
+	Chunk      *int   `json:"chunk,omitempty"`
+}
+
+// Passage is a supporting passage as returned to readers
+type Passage struct {
+	Source     string   `json:"source"`
+	SourceType string   `json:"source_type,omitempty"`
+	Start      int      `json:"start"`
+	End        int      `json:"end"`
+	Chunk      *int     `json:"chunk,omitempty"`
+	Text       string   `json:"text"`
+	Before     string   `json:"before,omitempty"`
+	After      string   `json:"after,omitempty"`
+	Status     string   `json:"status"`
+	Extractor  string   `json:"extractor,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// SourceText returns the text passages are taken from: the source's
+// extracted text property, else its content when that is text, else its
+// content property
+func SourceText(node *core.Node) string {
+	if text, ok := node.Meta["text"].(string); ok && text != "" {
+		return text
+	}
+	if len(node.Content) > 0 && utf8.Valid(node.Content) {
+		return string(node.Content)
+	}
+	text, _ := node.Meta["content"].(string)
+	return text
+}
+
+// ReadSpans returns the passages stored on a link's properties
+func ReadSpans(meta map[string]interface{}) []Span {
+	raw, ok := meta[SpansKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var spans []Span
+	if err := json.Unmarshal(data, &spans); err != nil {
+		return nil
+	}
+	return spans
+}
+
+// NormalizeSpans checks the spans of a SUPPORTED_BY link written directly.
+// A link may give a single passage as top-level start and end properties,
+// which are moved into spans. Links of other types are left alone.
+func NormalizeSpans(meta map[string]interface{}, linkType string) error {
+	if linkType != LinkType {
+		return nil
+	}
+	if meta == nil {
+		return fmt.Errorf("%w: %s links need spans", ErrInvalidSpan, LinkType)
+	}
+	if _, ok := meta[SpansKey]; !ok {
+		if _, ok := meta["start"]; ok {
+			meta[SpansKey] = []interface{}{map[string]interface{}{"start": meta["start"], "end": meta["end"]}}
+			delete(meta, "start")
+			delete(meta, "end")
+		}
+	}
+	list, ok := meta[SpansKey].([]interface{})
+	if !ok || len(list) == 0 {
+		return fmt.Errorf("%w: %s links need spans", ErrInvalidSpan, LinkType)
+	}
+	spans := ReadSpans(meta)
+	if len(spans) != len(list) {
+		return fmt.Errorf("%w: spans must be objects with integer start and end", ErrInvalidSpan)
+	}
+	for _, s := range spans {
+		if s.Start < 0 || s.End <= s.Start {
+			return fmt.Errorf("%w: %d-%d", ErrInvalidSpan, s.Start, s.End)
+		}
+	}
+	return nil
+}
+
+// resolve places a requested passage in the source text
+func resolve(text []rune, req SpanRequest) (Span, error) {
+	span := Span{Chunk: req.Chunk}
+	switch {
+	case req.Start != nil && req.End != nil:
+		span.Start, span.End = *req.Start, *req.End
+		if span.Start < 0 || span.End <= span.Start || span.End > len(text) {
+			return span, fmt.Errorf("%w: %d-%d is outside the source (0-%d)", ErrInvalidSpan, span.Start, span.End, len(text))
+		}
+		if req.Quote != "" && string(text[span.Start:span.End]) != req.Quote {
+			return span, fmt.Errorf("%w: the text at %d-%d does not match the quote", ErrInvalidSpan, span.Start, span.End)
+		}
+	case req.Quote != "":
+		occurrence := max(req.Occurrence, 1)
+		start, n := -1, 0
+		quote := []rune(req.Quote)
+		for i := 0; i+len(quote) <= len(text); i++ {
+			if string(text[i:i+len(quote)]) == req.Quote {
+				if n++; n == occurrence {
+					start = i
+					break
+				}
+			}
+		}
+		if start < 0 {
+			return span, fmt.Errorf("%w: quote not found in the source", ErrInvalidSpan)
+		}
+		span.Start, span.End = start, start+len(quote)
+	default:
+		return span, fmt.Errorf("%w: give start and end, or a quote", ErrInvalidSpan)
+	}
+	span.Quote = string(text[span.Start:span.End])
+	return span, nil
+}
+
+// Add records passages of source that support node, on the node's
+// SUPPORTED_BY link to it. Passages already recorded are not repeated.
+// Extractor and confidence, when set, are stored on the link.
+func Add(ctx context.Context, repo graph.Repository, nodeID, sourceID string, reqs []SpanRequest, extractor string, confidence *float64) ([]Span, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%w: no spans given", ErrInvalidSpan)
+	}
+	if _, err := repo.GetNode(ctx, nodeID); err != nil {
+		return nil, fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	source, err := repo.GetNode(ctx, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: source %s", ErrNotFound, sourceID)
+	}
+	text := []rune(SourceText(source))
+
+	added := make([]Span, 0, len(reqs))
+	for i, req := range reqs {
+		span, err := resolve(text, req)
+		if err != nil {
+			return nil, fmt.Errorf("spans[%d]: %w", i, err)
+		}
+		added = append(added, span)
+	}
+
+	links, err := repo.GetLinks(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	var existing *core.Link
+	for _, l := range links {
+		if l.Type == LinkType && l.Target == sourceID {
+			existing = l
+			break
+		}
+	}
+
+	now := time.Now()
+	meta := map[string]interface{}{}
+	created := now
+	var spans []Span
+	if existing != nil {
+		for k, v := range existing.Meta {
+			meta[k] = v
+		}
+		spans = ReadSpans(existing.Meta)
+		created = existing.Created
+	}
+	for _, s := range added {
+		if !contains(spans, s) {
+			spans = append(spans, s)
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	meta[SpansKey] = spans
+	if extractor != "" {
+		meta["extractor"] = extractor
+	}
+	if confidence != nil {
+		meta[graph.ConfidenceKey] = *confidence
+		if err := graph.NormalizeConfidence(meta); err != nil {
+			return nil, err
+		}
+	}
+
+	if existing != nil {
+		if err := repo.DeleteLink(ctx, nodeID, sourceID, LinkType); err != nil {
+			return nil, err
+		}
+	}
+	link := &core.Link{Source: nodeID, Target: sourceID, Type: LinkType, Meta: meta, Created: created, Modified: now}
+	if err := repo.CreateLink(ctx, link); err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+// contains reports whether spans already holds a passage at s's offsets
+func contains(spans []Span, s Span) bool {
+	for _, o := range spans {
+		if o.Start == s.Start && o.End == s.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Evidence returns the passages supporting a node, by source and then
+// position, with up to contextChars characters of text either side. Each
+// is checked against the source's current text; a passage whose quote
+// moved is returned at its new place.
+func Evidence(ctx context.Context, repo graph.Repository, nodeID string, contextChars int) ([]Passage, error) {
+	if _, err := repo.GetNode(ctx, nodeID); err != nil {
+		return nil, fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	links, err := repo.GetLinks(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Target < links[j].Target })
+
+	passages := []Passage{}
+	for _, l := range links {
+		if l.Type != LinkType {
+			continue
+		}
+		var text []rune
+		sourceType := ""
+		if source, err := repo.GetNode(ctx, l.Target); err == nil {
+			text = []rune(SourceText(source))
+			sourceType = source.Type
+		}
+		extractor, _ := l.Meta["extractor"].(string)
+		var confidence *float64
+		if c, ok := graph.LinkConfidence(l.Meta); ok {
+			confidence = &c
+		}
+		for _, s := range ReadSpans(l.Meta) {
+			p := locate(text, s, contextChars)
+			p.Source, p.SourceType, p.Chunk = l.Target, sourceType, s.Chunk
+			p.Extractor, p.Confidence = extractor, confidence
+			passages = append(passages, p)
+		}
+	}
+	return passages, nil
+}
+
+// locate finds a stored span in the source text. A span whose quote is no
+// longer at its offsets is matched to the nearest occurrence of the quote.
+func locate(text []rune, s Span, contextChars int) Passage {
+	p := Passage{Start: s.Start, End: s.End, Text: s.Quote, Status: StatusOrphaned}
+	inRange := s.Start >= 0 && s.End > s.Start && s.End <= len(text)
+	switch {
+	case inRange && (s.Quote == "" || string(text[s.Start:s.End]) == s.Quote):
+		p.Status = StatusExact
+	case s.Quote != "":
+		quote := []rune(s.Quote)
+		best, bestDistance := -1, 0
+		for i := 0; i+len(quote) <= len(text); i++ {
+			if string(text[i:i+len(quote)]) != s.Quote {
+				continue
+			}
+			distance := i - s.Start
+			if distance < 0 {
+				distance = -distance
+			}
+			if best < 0 || distance < bestDistance {
+				best, bestDistance = i, distance
+			}
+		}
+		if best < 0 {
+			return p
+		}
+		p.Start, p.End, p.Status = best, best+len(quote), StatusMoved
+	default:
+		return p
+	}
+	p.Text = string(text[p.Start:p.End])
+	p.Before = string(text[max(0, p.Start-contextChars):p.Start])
+	p.After = string(text[p.End:min(len(text), p.End+contextChars)])
+	return p
+}
@@ -0,0 +1,75 @@
+package evidence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestEvidencePassages(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	text := "Ada Lovelace wrote the first program. Ada worked with Babbage on the engine."
+	if err := repo.CreateNode(ctx, &core.Node{ID: "sha256:doc", Type: "Source", Content: []byte(text), Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateNode(ctx, &core.Node{ID: "person:ada", Type: "Person", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	start, end, chunk := 0, 12, 2
+	spans, err := Add(ctx, repo, "person:ada", "sha256:doc", []SpanRequest{
+		{Start: &start, End: &end, Chunk: &chunk},
+		{Quote: "Ada", Occurrence: 2},
+	}, "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 2 || spans[0].Quote != "Ada Lovelace" || spans[1].Start != 38 || spans[1].End != 41 {
+		t.Fatalf("spans = %+v", spans)
+	}
+	// Repeating a passage does not duplicate it
+	if spans, err = Add(ctx, repo, "person:ada", "sha256:doc", []SpanRequest{{Quote: "Babbage"}, {Start: &start, End: &end}}, "", nil); err != nil || len(spans) != 3 {
+		t.Fatalf("second Add = %+v, %v; want 3 spans", spans, err)
+	}
+	if _, err := Add(ctx, repo, "person:ada", "sha256:doc", []SpanRequest{{Quote: "Turing"}}, "", nil); !errors.Is(err, ErrInvalidSpan) {
+		t.Errorf("missing quote: err = %v, want ErrInvalidSpan", err)
+	}
+	if _, err := Add(ctx, repo, "person:ada", "sha256:missing", []SpanRequest{{Quote: "Ada"}}, "", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing source: err = %v, want ErrNotFound", err)
+	}
+
+	passages, err := Evidence(ctx, repo, "person:ada", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passages) != 3 {
+		t.Fatalf("passages = %+v, want 3", passages)
+	}
+	first := passages[0]
+	if first.Text != "Ada Lovelace" || first.After != " wrot" || first.Status != StatusExact || first.Chunk == nil || *first.Chunk != 2 || first.Extractor != "test" {
+		t.Errorf("first passage = %+v", first)
+	}
+
+	// A passage follows its text when the source text changes
+	if err := repo.UpdateNodeMeta(ctx, "sha256:doc", map[string]interface{}{"text": "Notes. " + text[:37]}); err != nil {
+		t.Fatal(err)
+	}
+	passages, _ = Evidence(ctx, repo, "person:ada", 0)
+	if passages[0].Status != StatusMoved || passages[0].Start != 7 || passages[2].Status != StatusOrphaned || passages[2].Text != "Babbage" {
+		t.Errorf("after edit: %+v", passages)
+	}
+
+	if err := NormalizeSpans(map[string]interface{}{"start": 5.0, "end": 2.0}, LinkType); !errors.Is(err, ErrInvalidSpan) {
+		t.Errorf("NormalizeSpans of a reversed span = %v, want ErrInvalidSpan", err)
+	}
+	meta := map[string]interface{}{"start": 1.0, "end": 4.0}
+	if err := NormalizeSpans(meta, LinkType); err != nil || len(ReadSpans(meta)) != 1 {
+		t.Errorf("NormalizeSpans folding start/end: %v, %v", err, meta)
+	}
+}
@@ -0,0 +1,429 @@
+// Package experiments runs A/B tests of search ranking strategies. An
+// experiment splits search requests between variants, each ranking results
+// with a different strategy; outcomes come from retrieval feedback and are
+// compared per variant. Experiments live in memory, optionally seeded from
+// a JSON file at startup.
+package experiments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/feedback"
+)
+
+// Errors returned by the manager
+var (
+	ErrNotFound = errors.New("experiment not found")
+	ErrExists   = errors.New("experiment already exists")
+	ErrRunning  = errors.New("another experiment is running")
+)
+
+// Strategy ranks search candidates by
+//
+//	relevance^Relevance * trust^Trust * recency^Recency
+//
+// where relevance is 1/rank in the backend's order, trust is the source
+// trust score and recency halves every HalfLifeDays since the last change
+type Strategy struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description,omitempty"`
+	Relevance    float64 `json:"relevance"`
+	Trust        float64 `json:"trust"`
+	Recency      float64 `json:"recency"`
+	HalfLifeDays float64 `json:"half_life_days,omitempty"` // Default 30
+}
+
+// DefaultStrategy is the ranking search uses outside experiments
+const DefaultStrategy = "trust"
+
+// builtins are the strategies always available
+var builtins = []Strategy{
+	{Name: "trust", Description: "Backend relevance weighted by source trust", Relevance: 1, Trust: 1},
+	{Name: "relevance", Description: "Backend relevance order only", Relevance: 1},
+	{Name: "recency", Description: "Relevance and trust, favoring recently changed nodes", Relevance: 1, Trust: 1, Recency: 1, HalfLifeDays: 30},
+}
+
+// Default returns the default strategy
+func Default() Strategy {
+	return builtins[0]
+}
+
+// Score combines one candidate's signals
+func (s Strategy) Score(rank int, trust, ageDays float64) float64 {
+	score := math.Pow(1/float64(rank), s.Relevance) * math.Pow(trust, s.Trust)
+	if s.Recency != 0 {
+		halfLife := s.HalfLifeDays
+		if halfLife <= 0 {
+			halfLife = 30
+		}
+		score *= math.Pow(math.Pow(0.5, math.Max(ageDays, 0)/halfLife), s.Recency)
+	}
+	return score
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func (s Strategy) validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("invalid strategy name %q (letters, digits, _ . -)", s.Name)
+	}
+	if s.Relevance < 0 || s.Trust < 0 || s.Recency < 0 || s.HalfLifeDays < 0 {
+		return fmt.Errorf("strategy %s: weights must not be negative", s.Name)
+	}
+	return nil
+}
+
+// Variant is one arm of an experiment
+type Variant struct {
+	Name     string  `json:"name"`
+	Strategy string  `json:"strategy"`
+	Weight   float64 `json:"weight,omitempty"` // Share of traffic; default 1
+}
+
+// Experiment splits search traffic between ranking strategies. The first
+// variant is the control the others are compared to.
+type Experiment struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description,omitempty"`
+	Variants    []Variant  `json:"variants"`
+	Started     time.Time  `json:"started"`
+	Stopped     *time.Time `json:"stopped,omitempty"`
+}
+
+// Running reports whether the experiment still assigns traffic
+func (e *Experiment) Running() bool {
+	return e.Stopped == nil
+}
+
+// Key is the name a variant's retrievals are recorded under
+func (e *Experiment) Key(variant string) string {
+	return e.ID + "/" + variant
+}
+
+// Assignment is the variant a request was given
+type Assignment struct {
+	Experiment string   `json:"experiment"`
+	Variant    string   `json:"variant"`
+	Strategy   Strategy `json:"-"`
+	Key        string   `json:"-"`
+}
+
+// Config is the file format for MEMEX_EXPERIMENTS: extra strategies and
+// experiments to start with
+type Config struct {
+	Strategies  []Strategy    `json:"strategies"`
+	Experiments []*Experiment `json:"experiments"`
+}
+
+// Manager holds the ranking strategies and experiments
+type Manager struct {
+	tracker *feedback.Tracker // Optional; source of outcome metrics
+
+	mu          sync.RWMutex
+	strategies  map[string]Strategy
+	experiments map[string]*Experiment
+}
+
+// NewManager creates a manager with the built-in strategies
+func NewManager(tracker *feedback.Tracker) *Manager {
+	m := &Manager{
+		tracker:     tracker,
+		strategies:  make(map[string]Strategy),
+		experiments: make(map[string]*Experiment),
+	}
+	for _, s := range builtins {
+		m.strategies[s.Name] = s
+	}
+	return m
+}
+
+// Load reads strategies and experiments from a JSON config file
+func (m *Manager) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read experiments: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse experiments: %w", err)
+	}
+	for _, s := range cfg.Strategies {
+		if err := m.AddStrategy(s); err != nil {
+			return err
+		}
+	}
+	for _, e := range cfg.Experiments {
+		if err := m.Create(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddStrategy registers or replaces a strategy. A strategy the running
+// experiment uses cannot be replaced.
+func (m *Manager) AddStrategy(s Strategy) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.running(); e != nil {
+		for _, v := range e.Variants {
+			if v.Strategy == s.Name {
+				return fmt.Errorf("%w: %s uses strategy %s", ErrRunning, e.ID, s.Name)
+			}
+		}
+	}
+	m.strategies[s.Name] = s
+	return nil
+}
+
+// Strategies lists the registered strategies by name
+func (m *Manager) Strategies() []Strategy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Strategy, 0, len(m.strategies))
+	for _, s := range m.strategies {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Strategy returns a registered strategy
+func (m *Manager) Strategy(name string) (Strategy, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.strategies[name]
+	return s, ok
+}
+
+// Create starts an experiment. Only one runs at a time.
+func (m *Manager) Create(e *Experiment) error {
+	if !namePattern.MatchString(e.ID) {
+		return fmt.Errorf("invalid experiment id %q (letters, digits, _ . -)", e.ID)
+	}
+	if len(e.Variants) < 2 {
+		return fmt.Errorf("an experiment needs at least two variants")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool, len(e.Variants))
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		if !namePattern.MatchString(v.Name) || seen[v.Name] {
+			return fmt.Errorf("invalid or duplicate variant name %q", v.Name)
+		}
+		seen[v.Name] = true
+		if _, ok := m.strategies[v.Strategy]; !ok {
+			return fmt.Errorf("variant %s: unknown strategy %q", v.Name, v.Strategy)
+		}
+		if v.Weight < 0 {
+			return fmt.Errorf("variant %s: weight must not be negative", v.Name)
+		}
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+	}
+	if _, ok := m.experiments[e.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrExists, e.ID)
+	}
+	if running := m.running(); running != nil {
+		return fmt.Errorf("%w: %s", ErrRunning, running.ID)
+	}
+	e.Started = time.Now()
+	e.Stopped = nil
+	m.experiments[e.ID] = e
+	return nil
+}
+
+// running returns the running experiment, if any; callers hold m.mu
+func (m *Manager) running() *Experiment {
+	for _, e := range m.experiments {
+		if e.Running() {
+			return e
+		}
+	}
+	return nil
+}
+
+// Stop ends an experiment's traffic split, keeping its results
+func (m *Manager) Stop(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.experiments[id]
+	if e == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if e.Running() {
+		now := time.Now()
+		e.Stopped = &now
+	}
+	return nil
+}
+
+// Delete removes an experiment
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.experiments[id] == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(m.experiments, id)
+	return nil
+}
+
+// Assign picks the ranking for a search request. forced names a variant
+// of the running experiment to use; otherwise unit (a user, session or
+// client address) is hashed so the same unit keeps the same variant.
+// Without a running experiment it returns the default strategy and nil.
+func (m *Manager) Assign(unit, forced string) (Strategy, *Assignment) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e := m.running()
+	if e == nil {
+		return m.strategies[DefaultStrategy], nil
+	}
+
+	variant := e.Variants[0]
+	found := false
+	for _, v := range e.Variants {
+		if forced != "" && v.Name == forced {
+			variant, found = v, true
+			break
+		}
+	}
+	if !found {
+		var total float64
+		for _, v := range e.Variants {
+			total += v.Weight
+		}
+		h := fnv.New64a()
+		h.Write([]byte(e.ID + ":" + unit))
+		point := float64(h.Sum64()%1_000_000) / 1_000_000 * total
+		for _, v := range e.Variants {
+			if point < v.Weight {
+				variant = v
+				break
+			}
+			point -= v.Weight
+		}
+	}
+	strategy := m.strategies[variant.Strategy]
+	return strategy, &Assignment{Experiment: e.ID, Variant: variant.Name, Strategy: strategy, Key: e.Key(variant.Name)}
+}
+
+// VariantReport is one variant's outcomes compared to the control
+type VariantReport struct {
+	Variant
+	Metrics feedback.EndpointStats `json:"metrics"`
+	// Relative change from the control; absent for the control itself
+	PrecisionLift *float64 `json:"precision_lift,omitempty"`
+	MRRLift       *float64 `json:"mrr_lift,omitempty"`
+	// Two-proportion z statistic for precision against the control;
+	// |z| above about 2 is unlikely to be chance
+	PrecisionZ *float64 `json:"precision_z,omitempty"`
+}
+
+// Report is an experiment with per-variant outcomes
+type Report struct {
+	ID          string          `json:"id"`
+	Description string          `json:"description,omitempty"`
+	Started     time.Time       `json:"started"`
+	Stopped     *time.Time      `json:"stopped,omitempty"`
+	Running     bool            `json:"running"`
+	Control     string          `json:"control"`
+	Variants    []VariantReport `json:"variants"`
+}
+
+// Get returns an experiment's report
+func (m *Manager) Get(id string) (*Report, error) {
+	m.mu.RLock()
+	e := m.experiments[id]
+	m.mu.RUnlock()
+	if e == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return m.report(e), nil
+}
+
+// List returns every experiment's report, newest first
+func (m *Manager) List() []*Report {
+	m.mu.RLock()
+	list := make([]*Experiment, 0, len(m.experiments))
+	for _, e := range m.experiments {
+		list = append(list, e)
+	}
+	m.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Started.After(list[j].Started) })
+
+	out := make([]*Report, len(list))
+	for i, e := range list {
+		out[i] = m.report(e)
+	}
+	return out
+}
+
+func (m *Manager) report(e *Experiment) *Report {
+	r := &Report{
+		ID:          e.ID,
+		Description: e.Description,
+		Started:     e.Started,
+		Stopped:     e.Stopped,
+		Running:     e.Running(),
+		Control:     e.Variants[0].Name,
+	}
+	for i, v := range e.Variants {
+		vr := VariantReport{Variant: v}
+		if m.tracker != nil {
+			vr.Metrics = m.tracker.Variant(e.Key(v.Name))
+		}
+		if i > 0 {
+			control := r.Variants[0].Metrics
+			vr.PrecisionLift = lift(vr.Metrics.Precision, control.Precision)
+			vr.MRRLift = lift(vr.Metrics.MRR, control.MRR)
+			vr.PrecisionZ = zScore(vr.Metrics.Counts, control.Counts)
+		}
+		r.Variants = append(r.Variants, vr)
+	}
+	return r
+}
+
+// lift is the relative change of x over base, or nil without a base
+func lift(x, base float64) *float64 {
+	if base == 0 {
+		return nil
+	}
+	v := round((x - base) / base)
+	return &v
+}
+
+// zScore compares the used rates of two sets of impressions
+func zScore(a, b feedback.Counts) *float64 {
+	if a.Impressions == 0 || b.Impressions == 0 {
+		return nil
+	}
+	na, nb := float64(a.Impressions), float64(b.Impressions)
+	pooled := float64(a.Used+b.Used) / (na + nb)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/na + 1/nb))
+	if se == 0 {
+		return nil
+	}
+	v := round((float64(a.Used)/na - float64(b.Used)/nb) / se)
+	return &v
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
@@ -0,0 +1,95 @@
+package experiments
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/feedback"
+)
+
+func TestStrategyScore(t *testing.T) {
+	trust, _ := NewManager(nil).Strategy("trust")
+	if got := trust.Score(2, 0.8, 100); got != 0.4 {
+		t.Errorf("trust score = %v, want 0.4", got)
+	}
+	recency, _ := NewManager(nil).Strategy("recency")
+	if got := recency.Score(1, 1, 30); got != 0.5 {
+		t.Errorf("recency score after one half-life = %v, want 0.5", got)
+	}
+}
+
+func TestExperiment(t *testing.T) {
+	tracker := feedback.NewTracker(0)
+	m := NewManager(tracker)
+
+	if strategy, a := m.Assign("alice", ""); a != nil || strategy.Name != DefaultStrategy {
+		t.Errorf("without an experiment: %v, %+v", strategy.Name, a)
+	}
+	if err := m.Create(&Experiment{ID: "e1", Variants: []Variant{{Name: "control", Strategy: "trust"}, {Name: "fresh", Strategy: "nope"}}}); err == nil {
+		t.Error("accepted an unknown strategy")
+	}
+	if err := m.AddStrategy(Strategy{Name: "fresh", Relevance: 1, Recency: 2, HalfLifeDays: 7}); err != nil {
+		t.Fatal(err)
+	}
+	e := &Experiment{ID: "e1", Variants: []Variant{{Name: "control", Strategy: "trust"}, {Name: "fresh", Strategy: "fresh"}}}
+	if err := m.Create(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Create(&Experiment{ID: "e2", Variants: e.Variants}); !errors.Is(err, ErrRunning) {
+		t.Errorf("second running experiment: %v", err)
+	}
+
+	// Units stick to a variant and traffic is split
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		unit := fmt.Sprintf("user-%d", i)
+		_, a := m.Assign(unit, "")
+		if _, again := m.Assign(unit, ""); again.Variant != a.Variant {
+			t.Fatalf("%s moved from %s to %s", unit, a.Variant, again.Variant)
+		}
+		counts[a.Variant]++
+	}
+	if counts["control"] < 400 || counts["fresh"] < 400 {
+		t.Errorf("split = %v", counts)
+	}
+	if s, a := m.Assign("user-1", "fresh"); a.Variant != "fresh" || s.Recency != 2 {
+		t.Errorf("forced variant = %+v", a)
+	}
+
+	hits := []feedback.Hit{{NodeID: "a"}, {NodeID: "b"}}
+	for i := 0; i < 10; i++ {
+		id := tracker.RecordVariant("search", e.Key("control"), hits)
+		if i < 2 {
+			tracker.Feedback(id, "b", feedback.ActionClick)
+		}
+		id = tracker.RecordVariant("search", e.Key("fresh"), hits)
+		if i < 6 {
+			tracker.Feedback(id, "a", feedback.ActionClick)
+		}
+	}
+	r, err := m.Get("e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	control, fresh := r.Variants[0], r.Variants[1]
+	if control.Metrics.Precision != 0.1 || fresh.Metrics.Precision != 0.3 || control.Metrics.MRR != 0.5 || fresh.Metrics.MRR != 1 {
+		t.Errorf("metrics = %+v / %+v", control.Metrics, fresh.Metrics)
+	}
+	if control.PrecisionLift != nil || fresh.PrecisionLift == nil || *fresh.PrecisionLift != 2 || *fresh.MRRLift != 1 || fresh.PrecisionZ == nil || *fresh.PrecisionZ < 1.5 {
+		t.Errorf("comparison = %+v", fresh)
+	}
+
+	if err := m.Stop("e1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, a := m.Assign("user-1", ""); a != nil {
+		t.Error("stopped experiment still assigns traffic")
+	}
+	if err := m.Delete("e1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get("e1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted experiment: %v", err)
+	}
+}
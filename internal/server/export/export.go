@@ -0,0 +1,262 @@
+// Package export serializes graph snapshots to standard graph formats
+// (GraphML, GEXF, DOT) for analysis in tools like Gephi and Graphviz, to RDF
+// (JSON-LD, Turtle) for knowledge-graph tooling, to CSV for spreadsheets,
+// and to a self-contained HTML viewer for sharing.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Options controls which node data is written
+type Options struct {
+	MetaKeys   []string                   // Node meta fields to include as attributes (CSV, RDF, HTML: all fields when empty)
+	Vocabulary *Vocabulary                // RDF term mapping (default vocabulary when nil)
+	Title      string                     // HTML page title
+	Start      string                     // Node the HTML viewer opens on
+	Styles     map[string]graph.NodeStyle // Node type colors and sizes for HTML
+}
+
+// Format describes a supported export format
+type Format struct {
+	ContentType string
+	Extension   string
+	Write       func(w io.Writer, g *graph.Subgraph, opts Options) error
+}
+
+// Formats maps format names to their writers
+var Formats = map[string]Format{
+	"graphml": {ContentType: "application/graphml+xml", Extension: "graphml", Write: WriteGraphML},
+	"gexf":    {ContentType: "application/gexf+xml", Extension: "gexf", Write: WriteGEXF},
+	"dot":     {ContentType: "text/vnd.graphviz", Extension: "dot", Write: WriteDOT},
+	"csv":     {ContentType: "text/csv", Extension: "csv", Write: WriteCSV},
+	"jsonld":  {ContentType: "application/ld+json", Extension: "jsonld", Write: WriteJSONLD},
+	"turtle":  {ContentType: "text/turtle", Extension: "ttl", Write: WriteTurtle},
+	"html":    {ContentType: "text/html; charset=utf-8", Extension: "html", Write: WriteHTML},
+}
+
+// WriteGraphML writes g as GraphML
+func WriteGraphML(w io.Writer, g *graph.Subgraph, opts Options) error {
+	ew := &errWriter{w: w}
+
+	ew.printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	ew.printf("<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	ew.printf("  <key id=\"label\" for=\"node\" attr.name=\"label\" attr.type=\"string\"/>\n")
+	ew.printf("  <key id=\"type\" for=\"node\" attr.name=\"type\" attr.type=\"string\"/>\n")
+	for i, key := range opts.MetaKeys {
+		ew.printf("  <key id=\"m%d\" for=\"node\" attr.name=\"%s\" attr.type=\"string\"/>\n", i, xmlEscape(key))
+	}
+	ew.printf("  <key id=\"link_type\" for=\"edge\" attr.name=\"type\" attr.type=\"string\"/>\n")
+	ew.printf("  <key id=\"weight\" for=\"edge\" attr.name=\"weight\" attr.type=\"double\"/>\n")
+	ew.printf("  <graph id=\"memex\" edgedefault=\"directed\">\n")
+
+	for _, node := range g.Nodes {
+		ew.printf("    <node id=\"%s\">\n", xmlEscape(node.ID))
+		ew.printf("      <data key=\"label\">%s</data>\n", xmlEscape(nodeLabel(node)))
+		ew.printf("      <data key=\"type\">%s</data>\n", xmlEscape(node.Type))
+		for i, key := range opts.MetaKeys {
+			if value, ok := metaString(node, key); ok {
+				ew.printf("      <data key=\"m%d\">%s</data>\n", i, xmlEscape(value))
+			}
+		}
+		ew.printf("    </node>\n")
+	}
+
+	for i, edge := range g.Edges {
+		directed := ""
+		if edge.Bidirectional {
+			directed = " directed=\"false\""
+		}
+		ew.printf("    <edge id=\"e%d\" source=\"%s\" target=\"%s\"%s>\n", i, xmlEscape(edge.Source), xmlEscape(edge.Target), directed)
+		ew.printf("      <data key=\"link_type\">%s</data>\n", xmlEscape(edge.Type))
+		if edge.Weight != 0 {
+			ew.printf("      <data key=\"weight\">%s</data>\n", strconv.FormatFloat(edge.Weight, 'g', -1, 64))
+		}
+		ew.printf("    </edge>\n")
+	}
+
+	ew.printf("  </graph>\n")
+	ew.printf("</graphml>\n")
+	return ew.err
+}
+
+// WriteGEXF writes g as GEXF 1.3
+func WriteGEXF(w io.Writer, g *graph.Subgraph, opts Options) error {
+	ew := &errWriter{w: w}
+
+	ew.printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	ew.printf("<gexf xmlns=\"http://gexf.net/1.3\" version=\"1.3\">\n")
+	ew.printf("  <meta lastmodifieddate=\"%s\">\n", time.Now().Format("2006-01-02"))
+	ew.printf("    <creator>memex</creator>\n")
+	ew.printf("  </meta>\n")
+	ew.printf("  <graph defaultedgetype=\"directed\">\n")
+
+	ew.printf("    <attributes class=\"node\">\n")
+	ew.printf("      <attribute id=\"type\" title=\"type\" type=\"string\"/>\n")
+	for i, key := range opts.MetaKeys {
+		ew.printf("      <attribute id=\"m%d\" title=\"%s\" type=\"string\"/>\n", i, xmlEscape(key))
+	}
+	ew.printf("    </attributes>\n")
+
+	ew.printf("    <nodes>\n")
+	for _, node := range g.Nodes {
+		ew.printf("      <node id=\"%s\" label=\"%s\">\n", xmlEscape(node.ID), xmlEscape(nodeLabel(node)))
+		ew.printf("        <attvalues>\n")
+		ew.printf("          <attvalue for=\"type\" value=\"%s\"/>\n", xmlEscape(node.Type))
+		for i, key := range opts.MetaKeys {
+			if value, ok := metaString(node, key); ok {
+				ew.printf("          <attvalue for=\"m%d\" value=\"%s\"/>\n", i, xmlEscape(value))
+			}
+		}
+		ew.printf("        </attvalues>\n")
+		ew.printf("      </node>\n")
+	}
+	ew.printf("    </nodes>\n")
+
+	ew.printf("    <edges>\n")
+	for i, edge := range g.Edges {
+		var attrs string
+		if edge.Weight != 0 {
+			attrs += " weight=\"" + strconv.FormatFloat(edge.Weight, 'g', -1, 64) + "\""
+		}
+		if edge.Bidirectional {
+			attrs += " type=\"undirected\""
+		}
+		ew.printf("      <edge id=\"e%d\" source=\"%s\" target=\"%s\" label=\"%s\"%s/>\n",
+			i, xmlEscape(edge.Source), xmlEscape(edge.Target), xmlEscape(edge.Type), attrs)
+	}
+	ew.printf("    </edges>\n")
+
+	ew.printf("  </graph>\n")
+	ew.printf("</gexf>\n")
+	return ew.err
+}
+
+// WriteDOT writes g as a Graphviz digraph
+func WriteDOT(w io.Writer, g *graph.Subgraph, opts Options) error {
+	ew := &errWriter{w: w}
+
+	ew.printf("digraph memex {\n")
+	for _, node := range g.Nodes {
+		attrs := []string{
+			"label=" + dotQuote(nodeLabel(node)),
+			"type=" + dotQuote(node.Type),
+		}
+		for _, key := range opts.MetaKeys {
+			if value, ok := metaString(node, key); ok {
+				attrs = append(attrs, dotQuote(key)+"="+dotQuote(value))
+			}
+		}
+		ew.printf("  %s [%s];\n", dotQuote(node.ID), strings.Join(attrs, ", "))
+	}
+	for _, edge := range g.Edges {
+		attrs := []string{"label=" + dotQuote(edge.Type)}
+		if edge.Bidirectional {
+			attrs = append(attrs, "dir=both")
+		}
+		ew.printf("  %s -> %s [%s];\n", dotQuote(edge.Source), dotQuote(edge.Target), strings.Join(attrs, ", "))
+	}
+	ew.printf("}\n")
+	return ew.err
+}
+
+// WriteCSV writes the nodes of g as CSV with id and type columns followed by
+// one column per meta field. Links are not included.
+func WriteCSV(w io.Writer, g *graph.Subgraph, opts Options) error {
+	keys := opts.MetaKeys
+	if len(keys) == 0 {
+		seen := make(map[string]bool)
+		for _, node := range g.Nodes {
+			for key := range node.Meta {
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+		sort.Strings(keys)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"id", "type"}, keys...))
+	for _, node := range g.Nodes {
+		record := make([]string, 0, len(keys)+2)
+		record = append(record, node.ID, node.Type)
+		for _, key := range keys {
+			value, _ := metaString(node, key)
+			record = append(record, value)
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// nodeLabel picks a human-readable label for a node
+func nodeLabel(node *core.Node) string {
+	for _, key := range []string{"name", "title", "label"} {
+		if value, ok := node.Meta[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return node.ID
+}
+
+// metaString renders a meta value as a string, JSON-encoding non-scalars
+func metaString(node *core.Node, key string) (string, bool) {
+	value, ok := node.Meta[key]
+	if !ok || value == nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool, float64, int, int64:
+		return fmt.Sprint(v), true
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v), true
+		}
+		return string(data), true
+	}
+}
+
+// xmlEscape escapes text for use in XML attributes and character data
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// dotQuote returns s as a quoted DOT ID
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// errWriter remembers the first write error so formatting code stays linear
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
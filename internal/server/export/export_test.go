@@ -0,0 +1,141 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func sampleGraph() *graph.Subgraph {
+	return &graph.Subgraph{
+		Nodes: []*core.Node{
+			{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"name": `Ada "Countess" <Lovelace>`, "born": 1815.0}},
+			{ID: "paper:notes", Type: "Paper", Meta: map[string]interface{}{"title": "Notes & Sketches"}},
+		},
+		Edges: []*graph.SubgraphEdge{
+			{Source: "person:ada", Target: "paper:notes", Type: "WROTE"},
+		},
+	}
+}
+
+func TestXMLFormatsAreWellFormed(t *testing.T) {
+	for _, name := range []string{"graphml", "gexf"} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Formats[name].Write(&buf, sampleGraph(), Options{MetaKeys: []string{"born"}}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			dec := xml.NewDecoder(&buf)
+			for {
+				_, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("output is not well-formed XML: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, sampleGraph(), Options{MetaKeys: []string{"born"}}); err != nil {
+		t.Fatalf("WriteDOT() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`"person:ada" [label="Ada \"Countess\" <Lovelace>", type="Person", "born"="1815"];`,
+		`"person:ada" -> "paper:notes" [label="WROTE"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteDOT() output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestEdgeWeightAndDirection(t *testing.T) {
+	g := sampleGraph()
+	g.Edges = append(g.Edges, &graph.SubgraphEdge{Source: "person:ada", Target: "person:ada", Type: "KNOWS", Weight: 0.5, Bidirectional: true})
+	for name, wants := range map[string][]string{
+		"graphml": {`<edge id="e1" source="person:ada" target="person:ada" directed="false">`, `<data key="weight">0.5</data>`},
+		"gexf":    {`label="KNOWS" weight="0.5" type="undirected"/>`, `label="WROTE"/>`},
+		"dot":     {`[label="KNOWS", dir=both];`, `[label="WROTE"];`},
+	} {
+		var buf bytes.Buffer
+		if err := Formats[name].Write(&buf, g, Options{}); err != nil {
+			t.Fatalf("%s: Write() error = %v", name, err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s output missing %q:\n%s", name, want, buf.String())
+			}
+		}
+	}
+}
+
+func TestWriteTurtleUsesVocabulary(t *testing.T) {
+	vocab := DefaultVocabulary()
+	vocab.Prefixes = map[string]string{"schema": "https://schema.org/"}
+	vocab.Classes = map[string]string{"Person": "schema:Person"}
+	vocab.Predicates = map[string]string{"WROTE": "https://schema.org/author"}
+
+	var buf bytes.Buffer
+	if err := WriteTurtle(&buf, sampleGraph(), Options{MetaKeys: []string{"born"}, Vocabulary: vocab}); err != nil {
+		t.Fatalf("WriteTurtle() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`<urn:memex:person:ada> a <https://schema.org/Person> ;`,
+		`rdfs:label "Ada \"Countess\" <Lovelace>"`,
+		`<https://memex.systems/ns#born> "1815"^^xsd:integer`,
+		`<https://schema.org/author> <urn:memex:paper:notes>`,
+		`<urn:memex:paper:notes> a <https://memex.systems/ns#Paper> ;`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteTurtle() output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	g := sampleGraph()
+	g.Nodes[1].Meta["title"] = "</script><script>alert(1)</script>"
+	g.Nodes[1].Content = []byte(strings.Repeat("é", maxHTMLContent))
+
+	var buf bytes.Buffer
+	opts := Options{Title: "Ada & friends", Start: "person:ada", MetaKeys: []string{"name"}, Styles: map[string]graph.NodeStyle{"Person": {Color: "#4477aa"}}}
+	if err := WriteHTML(&buf, g, opts); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	out := buf.String()
+
+	if strings.Count(out, "</script>") != 1 {
+		t.Errorf("node data closed the script element:\n%s", out)
+	}
+	for _, want := range []string{
+		`<title>Ada &amp; friends</title>`,
+		`"start":"person:ada"`,
+		`"styles":{"Person":{"color":"#4477aa"}}`,
+		`"source":"person:ada","target":"paper:notes","type":"WROTE"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteHTML() output missing %q", want)
+		}
+	}
+	if strings.Contains(out, "1815") {
+		t.Error("WriteHTML() wrote meta outside MetaKeys")
+	}
+	if strings.Contains(out, "src=") || strings.Contains(out, "<link") {
+		t.Error("WriteHTML() page loads external resources")
+	}
+}
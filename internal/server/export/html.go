@@ -0,0 +1,297 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxHTMLContent caps the node content embedded in an HTML export
+const maxHTMLContent = 2000
+
+// htmlGraph is the data an HTML export embeds for its viewer
+type htmlGraph struct {
+	Title     string                     `json:"title"`
+	Start     string                     `json:"start,omitempty"`
+	Generated time.Time                  `json:"generated"`
+	Nodes     []htmlNode                 `json:"nodes"`
+	Edges     []*graph.SubgraphEdge      `json:"edges"`
+	Styles    map[string]graph.NodeStyle `json:"styles"`
+}
+
+type htmlNode struct {
+	ID      string                 `json:"id"`
+	Type    string                 `json:"type"`
+	Label   string                 `json:"label"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+	Content string                 `json:"content,omitempty"`
+}
+
+// WriteHTML writes g as a single HTML page with an embedded viewer, so it
+// can be shared with people who have no server to open it with. Nodes
+// carry their meta (only MetaKeys when set) and up to 2000 bytes of
+// text content.
+func WriteHTML(w io.Writer, g *graph.Subgraph, opts Options) error {
+	data := htmlGraph{
+		Title:     opts.Title,
+		Start:     opts.Start,
+		Generated: time.Now().UTC(),
+		Nodes:     make([]htmlNode, 0, len(g.Nodes)),
+		Edges:     g.Edges,
+		Styles:    opts.Styles,
+	}
+	if data.Title == "" {
+		data.Title = "Memex graph"
+	}
+	if data.Edges == nil {
+		data.Edges = []*graph.SubgraphEdge{}
+	}
+	if data.Styles == nil {
+		data.Styles = map[string]graph.NodeStyle{}
+	}
+	for _, node := range g.Nodes {
+		n := htmlNode{ID: node.ID, Type: node.Type, Label: nodeLabel(node), Meta: node.Meta}
+		if len(opts.MetaKeys) > 0 {
+			n.Meta = make(map[string]interface{}, len(opts.MetaKeys))
+			for _, key := range opts.MetaKeys {
+				if value, ok := node.Meta[key]; ok {
+					n.Meta[key] = value
+				}
+			}
+		}
+		if utf8.Valid(node.Content) {
+			content := node.Content
+			if len(content) > maxHTMLContent {
+				content = content[:maxHTMLContent]
+				for !utf8.Valid(content) {
+					content = content[:len(content)-1]
+				}
+			}
+			n.Content = string(content)
+		}
+		data.Nodes = append(data.Nodes, n)
+	}
+	return htmlTemplate.Execute(w, data)
+}
+
+// htmlTemplate is the viewer page. It loads nothing from the network: the
+// graph is embedded as JSON and laid out by a small force simulation.
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+html, body { margin: 0; height: 100%; font: 14px/1.4 system-ui, sans-serif; color: #222; }
+body { display: flex; }
+#main { flex: 1; display: flex; flex-direction: column; min-width: 0; }
+header { padding: .5rem 1rem; border-bottom: 1px solid #ddd; display: flex; gap: 1rem; align-items: center; }
+header h1 { font-size: 1rem; margin: 0; flex: 1; }
+header small { color: #777; }
+svg { flex: 1; width: 100%; background: #fcfcfc; cursor: grab; touch-action: none; }
+line { stroke: #bbb; stroke-width: 1.5; }
+circle { stroke: #fff; stroke-width: 1.5; cursor: pointer; }
+circle.start { stroke: #222; stroke-width: 3; }
+circle.selected { stroke: #e0a100; stroke-width: 4; }
+.dim { opacity: .15; }
+text { font-size: 11px; fill: #444; pointer-events: none; }
+aside { width: 20rem; border-left: 1px solid #ddd; padding: 1rem; overflow: auto; }
+aside h2 { font-size: 1rem; margin: 0 0 .25rem; word-break: break-word; }
+aside table { border-collapse: collapse; width: 100%; margin-top: .5rem; }
+aside td { border-top: 1px solid #eee; padding: .25rem; vertical-align: top; word-break: break-word; }
+aside td:first-child { color: #777; width: 35%; }
+aside pre { white-space: pre-wrap; background: #f5f5f5; padding: .5rem; }
+#legend span { margin-right: .75rem; white-space: nowrap; }
+#legend b { display: inline-block; width: .8em; height: .8em; border-radius: 50%; margin-right: .25em; }
+</style>
+</head>
+<body>
+<div id="main">
+<header>
+<h1>{{.Title}}</h1>
+<input id="search" type="search" placeholder="Find a node">
+<small>{{len .Nodes}} nodes, {{len .Edges}} links</small>
+</header>
+<svg id="graph" viewBox="0 0 1000 700" xmlns="http://www.w3.org/2000/svg"><g id="edges"></g><g id="nodes"></g></svg>
+</div>
+<aside id="details"><p id="legend"></p><p><small>Click a node for details. Drag to move it; scroll to zoom.</small></p></aside>
+<script>
+const data = {{.}};
+(function () {
+  const NS = "http://www.w3.org/2000/svg", W = 1000, H = 700;
+  const svg = document.getElementById("graph"), details = document.getElementById("details");
+  const palette = ["#4477aa", "#ee6677", "#228833", "#ccbb44", "#66ccee", "#aa3377", "#bbbbbb", "#ee8866", "#44bb99", "#99ddff"];
+  const byId = {};
+  data.nodes.forEach((n, i) => {
+    const a = 2 * Math.PI * i / Math.max(data.nodes.length, 1);
+    n.x = W / 2 + 250 * Math.cos(a); n.y = H / 2 + 250 * Math.sin(a); n.vx = 0; n.vy = 0; n.degree = 0;
+    byId[n.id] = n;
+  });
+  const edges = data.edges.filter(e => byId[e.source] && byId[e.target]);
+  edges.forEach(e => { byId[e.source].degree++; byId[e.target].degree++; });
+
+  const style = t => data.styles[t] || {};
+  function color(t) {
+    if (style(t).color) return style(t).color;
+    let h = 0;
+    for (const c of t) h = (h * 31 + c.charCodeAt(0)) >>> 0;
+    return palette[h % palette.length];
+  }
+  function radius(n) {
+    const by = style(n.type).size_by;
+    const v = by && by !== "degree" ? Number((n.meta || {})[by]) || 0 : n.degree;
+    return 5 + Math.min(15, 2 * Math.sqrt(Math.max(v, 0)));
+  }
+  function el(tag, attrs, parent) {
+    const e = document.createElementNS(NS, tag);
+    for (const k in attrs) e.setAttribute(k, attrs[k]);
+    parent.appendChild(e);
+    return e;
+  }
+
+  const legend = document.getElementById("legend");
+  [...new Set(data.nodes.map(n => n.type))].sort().forEach(t => {
+    const s = document.createElement("span"), b = document.createElement("b");
+    b.style.background = color(t);
+    s.append(b, t);
+    legend.append(s);
+  });
+
+  const edgeG = document.getElementById("edges"), nodeG = document.getElementById("nodes");
+  edges.forEach(e => {
+    e.line = el("line", {}, edgeG);
+    el("title", {}, e.line).textContent = e.source + " -[" + e.type + "]-> " + e.target;
+  });
+  const showLabels = data.nodes.length <= 200;
+  data.nodes.forEach(n => {
+    n.circle = el("circle", {r: radius(n), fill: color(n.type), class: n.id === data.start ? "start" : ""}, nodeG);
+    el("title", {}, n.circle).textContent = n.label + " (" + n.type + ")";
+    if (showLabels) n.text = el("text", {dx: radius(n) + 3, dy: 4}, nodeG);
+    if (n.text) n.text.textContent = n.label;
+    n.circle.addEventListener("pointerdown", ev => { ev.stopPropagation(); drag = n; select(n); });
+  });
+
+  function render() {
+    edges.forEach(e => {
+      const s = byId[e.source], t = byId[e.target];
+      e.line.setAttribute("x1", s.x); e.line.setAttribute("y1", s.y);
+      e.line.setAttribute("x2", t.x); e.line.setAttribute("y2", t.y);
+    });
+    data.nodes.forEach(n => {
+      n.circle.setAttribute("cx", n.x); n.circle.setAttribute("cy", n.y);
+      if (n.text) { n.text.setAttribute("x", n.x); n.text.setAttribute("y", n.y); }
+    });
+  }
+
+  let alpha = 1, running = false;
+  function tick() {
+    const ns = data.nodes;
+    for (let i = 0; i < ns.length; i++) {
+      for (let j = i + 1; j < ns.length; j++) {
+        const a = ns[i], b = ns[j];
+        let dx = a.x - b.x, dy = a.y - b.y, d2 = dx * dx + dy * dy;
+        if (d2 < 1) { dx = Math.random() - .5; dy = Math.random() - .5; d2 = 1; }
+        const f = 1500 * alpha / d2;
+        a.vx += dx * f; a.vy += dy * f; b.vx -= dx * f; b.vy -= dy * f;
+      }
+    }
+    edges.forEach(e => {
+      const s = byId[e.source], t = byId[e.target];
+      const dx = t.x - s.x, dy = t.y - s.y, d = Math.sqrt(dx * dx + dy * dy) || 1;
+      const f = (d - 80) * 0.05 * alpha / d;
+      s.vx += dx * f; s.vy += dy * f; t.vx -= dx * f; t.vy -= dy * f;
+    });
+    ns.forEach(n => {
+      n.vx += (W / 2 - n.x) * 0.005 * alpha; n.vy += (H / 2 - n.y) * 0.005 * alpha;
+      if (n !== drag) { n.x += n.vx; n.y += n.vy; }
+      n.vx *= 0.6; n.vy *= 0.6;
+    });
+    render();
+    alpha *= 0.985;
+    if (alpha > 0.005) requestAnimationFrame(tick); else running = false;
+  }
+  function reheat(a) {
+    alpha = Math.max(alpha, a);
+    if (!running) { running = true; requestAnimationFrame(tick); }
+  }
+
+  // Dragging a node moves it; dragging the background pans and the wheel zooms
+  let drag = null, pan = null, view = {x: 0, y: 0, w: W, h: H};
+  const setView = () => svg.setAttribute("viewBox", view.x + " " + view.y + " " + view.w + " " + view.h);
+  function point(ev) {
+    const p = svg.createSVGPoint();
+    p.x = ev.clientX; p.y = ev.clientY;
+    return p.matrixTransform(svg.getScreenCTM().inverse());
+  }
+  svg.addEventListener("pointerdown", ev => { pan = point(ev); });
+  svg.addEventListener("pointermove", ev => {
+    const p = point(ev);
+    if (drag) { drag.x = p.x; drag.y = p.y; reheat(0.3); }
+    else if (pan) { view.x -= p.x - pan.x; view.y -= p.y - pan.y; setView(); }
+  });
+  window.addEventListener("pointerup", () => { drag = null; pan = null; });
+  svg.addEventListener("wheel", ev => {
+    ev.preventDefault();
+    const p = point(ev), k = ev.deltaY > 0 ? 1.1 : 1 / 1.1;
+    view.x = p.x - (p.x - view.x) * k; view.y = p.y - (p.y - view.y) * k;
+    view.w *= k; view.h *= k;
+    setView();
+  }, {passive: false});
+
+  let selected = null;
+  function select(n) {
+    if (selected) selected.circle.classList.remove("selected");
+    selected = n;
+    n.circle.classList.add("selected");
+    details.replaceChildren();
+    const h = document.createElement("h2");
+    h.textContent = n.label;
+    const sub = document.createElement("small");
+    sub.textContent = n.type + " · " + n.id;
+    details.append(h, sub);
+    const table = document.createElement("table");
+    Object.keys(n.meta || {}).sort().forEach(k => {
+      const row = table.insertRow(), v = n.meta[k];
+      row.insertCell().textContent = k;
+      row.insertCell().textContent = typeof v === "string" ? v : JSON.stringify(v);
+    });
+    details.append(table);
+    if (n.content) {
+      const pre = document.createElement("pre");
+      pre.textContent = n.content;
+      details.append(pre);
+    }
+    const links = document.createElement("table");
+    edges.filter(e => e.source === n.id || e.target === n.id).forEach(e => {
+      const out = e.source === n.id, other = byId[out ? e.target : e.source];
+      const row = links.insertRow();
+      row.insertCell().textContent = (out ? "→ " : "← ") + e.type;
+      const a = document.createElement("a");
+      a.href = "#"; a.textContent = other.label;
+      a.addEventListener("click", ev => { ev.preventDefault(); select(other); });
+      row.insertCell().append(a);
+    });
+    details.append(links);
+  }
+
+  document.getElementById("search").addEventListener("input", ev => {
+    const q = ev.target.value.trim().toLowerCase();
+    data.nodes.forEach(n => {
+      const hit = !q || n.label.toLowerCase().includes(q) || n.id.toLowerCase().includes(q);
+      n.circle.classList.toggle("dim", !hit);
+      if (n.text) n.text.classList.toggle("dim", !hit);
+    });
+  });
+
+  render();
+  reheat(1);
+  if (data.start && byId[data.start]) select(byId[data.start]);
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,179 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// LinkRecord is one row of the links table
+type LinkRecord struct {
+	Source        string
+	Target        string
+	Type          string
+	Weight        float64 // The link's weight, or 1 when it is unweighted
+	Bidirectional bool
+	Created       time.Time
+}
+
+// LinkFilter selects the links exported; zero values select everything
+type LinkFilter struct {
+	Types       []string          // Link types
+	SourceTypes []string          // Node types of the source
+	TargetTypes []string          // Node types of the target
+	Layers      graph.LayerFilter // Link layers
+	Since       time.Time         // Created at or after
+	Until       time.Time         // Created before
+	MinWeight   float64
+}
+
+// LinkWriter writes link records in a tabular format
+type LinkWriter interface {
+	Write(rec *LinkRecord) error
+	Close() error // Flushes buffered rows and finishes the file
+}
+
+// LinkFormat describes a supported links table format
+type LinkFormat struct {
+	ContentType string
+	Extension   string
+	New         func(w io.Writer) LinkWriter
+}
+
+// LinkFormats maps format names to their writers
+var LinkFormats = map[string]LinkFormat{
+	"csv":     {ContentType: "text/csv", Extension: "csv", New: NewLinkCSVWriter},
+	"parquet": {ContentType: "application/vnd.apache.parquet", Extension: "parquet", New: NewLinkParquetWriter},
+}
+
+// WriteLinks streams every link matching f to out, node by node, and
+// returns how many it wrote. The caller closes out.
+func WriteLinks(ctx context.Context, repo graph.Repository, f LinkFilter, out LinkWriter) (int, error) {
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(ids)
+	types := stringSet(f.Types)
+	sourceTypes, targetTypes := stringSet(f.SourceTypes), stringSet(f.TargetTypes)
+	nodeTypes := make(map[string]string) // Node ID -> type, read when filtering by them
+	nodeType := func(id string) string {
+		t, ok := nodeTypes[id]
+		if !ok {
+			if node, err := repo.GetNode(ctx, id); err == nil {
+				t = node.Type
+			}
+			nodeTypes[id] = t
+		}
+		return t
+	}
+
+	written := 0
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		if sourceTypes != nil && !sourceTypes[nodeType(id)] {
+			continue
+		}
+		links, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return written, fmt.Errorf("reading links of %s: %w", id, err)
+		}
+		sort.Slice(links, func(i, j int) bool {
+			if links[i].Target != links[j].Target {
+				return links[i].Target < links[j].Target
+			}
+			return links[i].Type < links[j].Type
+		})
+		for _, l := range links {
+			if types != nil && !types[l.Type] || !f.Layers.Link(l) {
+				continue
+			}
+			if !f.Since.IsZero() && l.Created.Before(f.Since) || !f.Until.IsZero() && !l.Created.Before(f.Until) {
+				continue
+			}
+			rec := linkRecord(l)
+			if rec.Weight < f.MinWeight {
+				continue
+			}
+			if targetTypes != nil && !targetTypes[nodeType(l.Target)] {
+				continue
+			}
+			if err := out.Write(rec); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+	return written, nil
+}
+
+// linkRecord reads a link's row
+func linkRecord(l *core.Link) *LinkRecord {
+	rec := &LinkRecord{Source: l.Source, Target: l.Target, Type: l.Type, Weight: 1, Bidirectional: l.Bidirectional, Created: l.Created.UTC()}
+	if l.Weight != 0 {
+		rec.Weight = l.Weight
+	}
+	return rec
+}
+
+// stringSet returns a set of values, or nil when there are none
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// linkColumns are the columns of the links table, in order
+var linkColumns = []string{"source", "target", "type", "weight", "created", "bidirectional"}
+
+// linkCSVWriter writes the links table as CSV with a header row
+type linkCSVWriter struct {
+	cw     *csv.Writer
+	header bool
+}
+
+// NewLinkCSVWriter returns a writer of the links table as CSV. Created
+// times are RFC 3339 in UTC.
+func NewLinkCSVWriter(w io.Writer) LinkWriter {
+	return &linkCSVWriter{cw: csv.NewWriter(w)}
+}
+
+func (c *linkCSVWriter) Write(rec *LinkRecord) error {
+	if !c.header {
+		c.header = true
+		if err := c.cw.Write(linkColumns); err != nil {
+			return err
+		}
+	}
+	return c.cw.Write([]string{
+		rec.Source,
+		rec.Target,
+		rec.Type,
+		strconv.FormatFloat(rec.Weight, 'g', -1, 64),
+		rec.Created.Format(time.RFC3339Nano),
+		strconv.FormatBool(rec.Bidirectional),
+	})
+}
+
+func (c *linkCSVWriter) Close() error {
+	if !c.header {
+		c.header = true
+		c.cw.Write(linkColumns)
+	}
+	c.cw.Flush()
+	return c.cw.Error()
+}
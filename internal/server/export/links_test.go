@@ -0,0 +1,209 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func sampleLinks(t *testing.T) graph.Repository {
+	t.Helper()
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, n := range []*core.Node{
+		{ID: "person:ada", Type: "Person"},
+		{ID: "person:bob", Type: "Person"},
+		{ID: "paper:notes", Type: "Paper"},
+	} {
+		n.Meta, n.Created, n.Modified = map[string]interface{}{}, day, day
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, l := range []*core.Link{
+		{Source: "person:ada", Target: "paper:notes", Type: "WROTE"},
+		{Source: "person:ada", Target: "person:bob", Type: "KNOWS", Weight: 0.25, Bidirectional: true},
+		{Source: "person:bob", Target: "paper:notes", Type: "READ"},
+	} {
+		l.Created = day.Add(time.Duration(i) * time.Hour)
+		l.Modified = l.Created
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestWriteLinksCSV(t *testing.T) {
+	repo := sampleLinks(t)
+	for _, tc := range []struct {
+		name   string
+		filter LinkFilter
+		want   int
+	}{
+		{"all", LinkFilter{}, 3},
+		{"types", LinkFilter{Types: []string{"WROTE", "READ"}}, 2},
+		{"target types", LinkFilter{TargetTypes: []string{"Person"}}, 1},
+		{"source types", LinkFilter{SourceTypes: []string{"Paper"}}, 0},
+		{"min weight", LinkFilter{MinWeight: 0.5}, 2},
+		{"since", LinkFilter{Since: time.Date(2026, 10, 1, 13, 0, 0, 0, time.UTC)}, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			out := NewLinkCSVWriter(&buf)
+			n, err := WriteLinks(context.Background(), repo, tc.filter, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := out.Close(); err != nil {
+				t.Fatal(err)
+			}
+			rows, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tc.want || len(rows) != tc.want+1 || rows[0][0] != "source" {
+				t.Fatalf("wrote %d, rows %v; want %d", n, rows, tc.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	out := NewLinkCSVWriter(&buf)
+	WriteLinks(context.Background(), repo, LinkFilter{Types: []string{"KNOWS"}}, out)
+	out.Close()
+	if want := "source,target,type,weight,created,bidirectional\nperson:ada,person:bob,KNOWS,0.25,2026-10-01T13:00:00Z,true\n"; buf.String() != want {
+		t.Errorf("CSV = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteLinksParquet(t *testing.T) {
+	var buf bytes.Buffer
+	out := NewLinkParquetWriter(&buf)
+	if _, err := WriteLinks(context.Background(), sampleLinks(t), LinkFilter{}, out); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatal("missing Parquet magic")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{buf: data[len(data)-8-size : len(data)-8]}
+	meta := r.readStruct()
+	if meta[3] != int64(3) {
+		t.Fatalf("num_rows = %v", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != 7 || string(schema[4].(map[int16]interface{})[4].([]byte)) != "weight" {
+		t.Fatalf("schema = %v", schema)
+	}
+
+	// Read the weight column back through its page header
+	group := meta[4].([]interface{})[0].(map[int16]interface{})
+	chunk := group[1].([]interface{})[3].(map[int16]interface{})[3].(map[int16]interface{})
+	page := &thriftReader{buf: data[chunk[9].(int64):]}
+	header := page.readStruct()
+	values := page.buf[page.pos : page.pos+int(header[2].(int64))]
+	var weights []float64
+	for i := 0; i < len(values); i += 8 {
+		weights = append(weights, math.Float64frombits(binary.LittleEndian.Uint64(values[i:])))
+	}
+	if len(weights) != 3 || weights[0] != 1 || weights[1] != 0.25 || weights[2] != 1 {
+		t.Errorf("weights = %v, want [1 0.25 1] (ada's links sorted by target)", weights)
+	}
+
+	// The bidirectional column is bit-packed: only the second row is set
+	chunk = group[1].([]interface{})[5].(map[int16]interface{})[3].(map[int16]interface{})
+	page = &thriftReader{buf: data[chunk[9].(int64):]}
+	header = page.readStruct()
+	if values := page.buf[page.pos : page.pos+int(header[2].(int64))]; len(values) != 1 || values[0] != 0b010 {
+		t.Errorf("bidirectional = %08b, want 00000010", values)
+	}
+}
+
+func TestEmptyParquet(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewLinkParquetWriter(&buf).Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if 4+size+8 != len(data) {
+		t.Fatalf("footer size %d in %d bytes", size, len(data))
+	}
+	meta := (&thriftReader{buf: data[4 : 4+size]}).readStruct()
+	if meta[3] != int64(0) || len(meta[4].([]interface{})) != 0 {
+		t.Errorf("meta = %v", meta)
+	}
+}
+
+// thriftReader decodes the compact protocol subset the writer uses, with
+// integers as int64, binaries as []byte and structs as maps by field ID
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		u := r.varint()
+		return int64(u>>1) ^ -int64(u&1)
+	case thriftBinary:
+		n := int(r.varint())
+		r.pos += n
+		return r.buf[r.pos-n : r.pos]
+	case thriftList:
+		head := r.buf[r.pos]
+		r.pos++
+		n := int(head >> 4)
+		if n == 15 {
+			n = int(r.varint())
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = r.value(head & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic("unexpected thrift type")
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		head := r.buf[r.pos]
+		r.pos++
+		if head == 0 {
+			return fields
+		}
+		id := last + int16(head>>4)
+		if head>>4 == 0 {
+			u := r.varint()
+			id = int16(int64(u>>1) ^ -int64(u&1))
+		}
+		fields[id] = r.value(head & 0x0f)
+		last = id
+	}
+}
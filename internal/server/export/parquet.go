@@ -0,0 +1,272 @@
+package export
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// parquetRowGroup is how many rows are buffered before a row group is
+// written, bounding memory on large exports
+const parquetRowGroup = 50000
+
+// Parquet physical types, repetitions, converted types and page types used
+// by the links table
+const (
+	parquetBoolean    = 0
+	parquetInt64      = 2
+	parquetDouble     = 5
+	parquetByteArray  = 6
+	parquetRequired   = 0
+	parquetUTF8       = 0
+	parquetTimeMillis = 9 // TIMESTAMP_MILLIS
+	parquetDataPage   = 0
+	parquetPlain      = 0
+	parquetRLE        = 3
+	parquetCreatedBy  = "memex"
+)
+
+// parquetColumn describes one column of the links table
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32 // -1 for none
+}
+
+var linkParquetColumns = []parquetColumn{
+	{"source", parquetByteArray, parquetUTF8},
+	{"target", parquetByteArray, parquetUTF8},
+	{"type", parquetByteArray, parquetUTF8},
+	{"weight", parquetDouble, -1},
+	{"created", parquetInt64, parquetTimeMillis},
+	{"bidirectional", parquetBoolean, -1},
+}
+
+// parquetChunk records where a column chunk was written
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// linkParquetWriter writes the links table as an uncompressed Parquet file
+// with PLAIN-encoded required columns, one data page per column chunk
+type linkParquetWriter struct {
+	w         io.Writer
+	offset    int64
+	err       error
+	columns   [][]byte // Plain-encoded values of the buffered rows
+	rows      int
+	total     int64
+	rowGroups [][]parquetChunk
+}
+
+// NewLinkParquetWriter returns a writer of the links table as Parquet.
+// Rows are written in row groups as they fill, so the file streams.
+func NewLinkParquetWriter(w io.Writer) LinkWriter {
+	return &linkParquetWriter{w: w, columns: make([][]byte, len(linkParquetColumns))}
+}
+
+func (p *linkParquetWriter) Write(rec *LinkRecord) error {
+	if p.offset == 0 {
+		p.write([]byte("PAR1"))
+	}
+	for i, value := range []string{rec.Source, rec.Target, rec.Type} {
+		p.columns[i] = binary.LittleEndian.AppendUint32(p.columns[i], uint32(len(value)))
+		p.columns[i] = append(p.columns[i], value...)
+	}
+	p.columns[3] = binary.LittleEndian.AppendUint64(p.columns[3], math.Float64bits(rec.Weight))
+	p.columns[4] = binary.LittleEndian.AppendUint64(p.columns[4], uint64(rec.Created.UnixMilli()))
+	// Plain booleans are bit-packed, least significant bit first
+	if p.rows%8 == 0 {
+		p.columns[5] = append(p.columns[5], 0)
+	}
+	if rec.Bidirectional {
+		p.columns[5][len(p.columns[5])-1] |= 1 << (p.rows % 8)
+	}
+	p.rows++
+	if p.rows == parquetRowGroup {
+		p.flush()
+	}
+	return p.err
+}
+
+func (p *linkParquetWriter) Close() error {
+	if p.offset == 0 {
+		p.write([]byte("PAR1"))
+	}
+	p.flush()
+
+	var t thriftWriter
+	t.begin()
+	t.i32(1, 1) // version
+	t.list(2, thriftStruct, len(linkParquetColumns)+1)
+	t.begin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(linkParquetColumns)))
+	t.end()
+	for _, col := range linkParquetColumns {
+		t.begin()
+		t.i32(1, col.physical)
+		t.i32(3, parquetRequired)
+		t.binary(4, col.name)
+		if col.converted >= 0 {
+			t.i32(6, col.converted)
+		}
+		t.end()
+	}
+	t.i64(3, p.total)
+	t.list(4, thriftStruct, len(p.rowGroups))
+	for _, chunks := range p.rowGroups {
+		var size int64
+		for _, c := range chunks {
+			size += c.size
+		}
+		t.begin()
+		t.list(1, thriftStruct, len(chunks))
+		for i, c := range chunks {
+			col := linkParquetColumns[i]
+			t.begin()
+			t.i64(2, c.offset)
+			t.field(3, thriftStruct)
+			t.begin()
+			t.i32(1, col.physical)
+			t.list(2, thriftI32, 1)
+			t.varint(zigzag(parquetPlain))
+			t.list(3, thriftBinary, 1)
+			t.varint(uint64(len(col.name)))
+			t.buf = append(t.buf, col.name...)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, c.values)
+			t.i64(6, c.size)
+			t.i64(7, c.size)
+			t.i64(9, c.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, size)
+		t.i64(3, chunks[0].values)
+		t.end()
+	}
+	t.binary(6, parquetCreatedBy)
+	t.end()
+
+	p.write(t.buf)
+	p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(t.buf))))
+	p.write([]byte("PAR1"))
+	return p.err
+}
+
+// flush writes the buffered rows as a row group
+func (p *linkParquetWriter) flush() {
+	if p.rows == 0 {
+		return
+	}
+	chunks := make([]parquetChunk, len(p.columns))
+	for i, data := range p.columns {
+		var t thriftWriter
+		t.begin()
+		t.i32(1, parquetDataPage)
+		t.i32(2, int32(len(data)))
+		t.i32(3, int32(len(data)))
+		t.field(5, thriftStruct)
+		t.begin()
+		t.i32(1, int32(p.rows))
+		t.i32(2, parquetPlain)
+		t.i32(3, parquetRLE)
+		t.i32(4, parquetRLE)
+		t.end()
+		t.end()
+
+		chunks[i] = parquetChunk{offset: p.offset, size: int64(len(t.buf) + len(data)), values: int64(p.rows)}
+		p.write(t.buf)
+		p.write(data)
+		p.columns[i] = data[:0]
+	}
+	p.rowGroups = append(p.rowGroups, chunks)
+	p.total += int64(p.rows)
+	p.rows = 0
+}
+
+// write writes to the underlying writer, remembering the first error
+func (p *linkParquetWriter) write(data []byte) {
+	if p.err != nil {
+		return
+	}
+	var n int
+	n, p.err = p.w.Write(data)
+	p.offset += int64(n)
+}
+
+// Thrift compact protocol types, as used in Parquet metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol
+type thriftWriter struct {
+	buf  []byte
+	last []int16 // Last field ID of each open struct
+}
+
+// begin opens a struct, top-level or after its field or list header
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+// end closes the innermost struct
+func (t *thriftWriter) end() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+// field writes a field header
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// list writes a list field header; the n elements follow
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+	} else {
+		t.buf = append(t.buf, 0xf0|elem)
+		t.varint(uint64(n))
+	}
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
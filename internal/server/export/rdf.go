@@ -0,0 +1,243 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+const (
+	rdfNS  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rdfsNS = "http://www.w3.org/2000/01/rdf-schema#"
+	xsdNS  = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// Vocabulary maps memex node types, link types and meta keys to RDF terms.
+// Terms may be full IRIs or CURIEs using one of the declared prefixes;
+// anything unmapped is placed in the Vocab namespace.
+type Vocabulary struct {
+	Base       string            `json:"base"`       // IRI prefix for node IDs
+	Vocab      string            `json:"vocab"`      // Namespace for unmapped terms
+	Prefixes   map[string]string `json:"prefixes"`   // CURIE prefix -> namespace IRI
+	Classes    map[string]string `json:"classes"`    // Node type -> class
+	Predicates map[string]string `json:"predicates"` // Link type -> predicate
+	Properties map[string]string `json:"properties"` // Meta key -> predicate
+}
+
+// DefaultVocabulary returns the vocabulary used when none is configured
+func DefaultVocabulary() *Vocabulary {
+	return &Vocabulary{
+		Base:  "urn:memex:",
+		Vocab: "https://memex.systems/ns#",
+	}
+}
+
+// LoadVocabulary reads a JSON vocabulary mapping, filling unset fields from the default
+func LoadVocabulary(path string) (*Vocabulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+	v := DefaultVocabulary()
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("failed to parse vocabulary: %w", err)
+	}
+	return v, nil
+}
+
+// nodeIRI returns the IRI identifying a node
+func (v *Vocabulary) nodeIRI(id string) string {
+	return v.Base + url.PathEscape(id)
+}
+
+// class returns the class IRI for a node type
+func (v *Vocabulary) class(nodeType string) string {
+	return v.term(v.Classes[nodeType], nodeType)
+}
+
+// predicate returns the predicate IRI for a link type
+func (v *Vocabulary) predicate(linkType string) string {
+	return v.term(v.Predicates[linkType], linkType)
+}
+
+// property returns the predicate IRI for a meta key
+func (v *Vocabulary) property(key string) string {
+	return v.term(v.Properties[key], key)
+}
+
+// term expands a mapped term, falling back to name in the Vocab namespace
+func (v *Vocabulary) term(mapped, name string) string {
+	if mapped == "" {
+		return v.Vocab + url.PathEscape(name)
+	}
+	if prefix, local, ok := strings.Cut(mapped, ":"); ok {
+		if ns, known := v.Prefixes[prefix]; known {
+			return ns + local
+		}
+	}
+	return mapped
+}
+
+// rdfMetaKeys returns the meta keys to emit for a node
+func rdfMetaKeys(node *core.Node, opts Options) []string {
+	if len(opts.MetaKeys) > 0 {
+		return opts.MetaKeys
+	}
+	keys := make([]string, 0, len(node.Meta))
+	for key := range node.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteJSONLD writes g as a JSON-LD document with one object per node
+func WriteJSONLD(w io.Writer, g *graph.Subgraph, opts Options) error {
+	vocab := opts.Vocabulary
+	if vocab == nil {
+		vocab = DefaultVocabulary()
+	}
+
+	ldContext := map[string]interface{}{
+		"rdf":  rdfNS,
+		"rdfs": rdfsNS,
+		"xsd":  xsdNS,
+	}
+	for prefix, ns := range vocab.Prefixes {
+		ldContext[prefix] = ns
+	}
+
+	objects := make(map[string]map[string]interface{}, len(g.Nodes))
+	items := make([]interface{}, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		obj := map[string]interface{}{
+			"@id":            vocab.nodeIRI(node.ID),
+			"@type":          vocab.class(node.Type),
+			rdfsNS + "label": nodeLabel(node),
+		}
+		for _, key := range rdfMetaKeys(node, opts) {
+			if value, ok := node.Meta[key]; ok && value != nil {
+				obj[vocab.property(key)] = jsonLDValue(value)
+			}
+		}
+		objects[node.ID] = obj
+		items = append(items, obj)
+	}
+
+	for _, edge := range g.Edges {
+		obj, ok := objects[edge.Source]
+		if !ok {
+			continue
+		}
+		predicate := vocab.predicate(edge.Type)
+		ref := map[string]string{"@id": vocab.nodeIRI(edge.Target)}
+		switch existing := obj[predicate].(type) {
+		case nil:
+			obj[predicate] = ref
+		case []interface{}:
+			obj[predicate] = append(existing, ref)
+		default:
+			obj[predicate] = []interface{}{existing, ref}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"@context": ldContext,
+		"@graph":   items,
+	})
+}
+
+// jsonLDValue converts a meta value to a JSON-LD literal
+func jsonLDValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string, bool, float64, int, int64:
+		return v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// WriteTurtle writes g as RDF Turtle
+func WriteTurtle(w io.Writer, g *graph.Subgraph, opts Options) error {
+	vocab := opts.Vocabulary
+	if vocab == nil {
+		vocab = DefaultVocabulary()
+	}
+	ew := &errWriter{w: w}
+
+	ew.printf("@prefix rdf: <%s> .\n", rdfNS)
+	ew.printf("@prefix rdfs: <%s> .\n", rdfsNS)
+	ew.printf("@prefix xsd: <%s> .\n", xsdNS)
+	prefixes := make([]string, 0, len(vocab.Prefixes))
+	for prefix := range vocab.Prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		ew.printf("@prefix %s: <%s> .\n", prefix, vocab.Prefixes[prefix])
+	}
+
+	links := make(map[string][]*graph.SubgraphEdge)
+	for _, edge := range g.Edges {
+		links[edge.Source] = append(links[edge.Source], edge)
+	}
+
+	for _, node := range g.Nodes {
+		ew.printf("\n<%s> a <%s> ;\n", vocab.nodeIRI(node.ID), vocab.class(node.Type))
+		ew.printf("    rdfs:label %s", turtleString(nodeLabel(node)))
+		for _, key := range rdfMetaKeys(node, opts) {
+			if value, ok := node.Meta[key]; ok && value != nil {
+				ew.printf(" ;\n    <%s> %s", vocab.property(key), turtleLiteral(value))
+			}
+		}
+		for _, edge := range links[node.ID] {
+			ew.printf(" ;\n    <%s> <%s>", vocab.predicate(edge.Type), vocab.nodeIRI(edge.Target))
+		}
+		ew.printf(" .\n")
+	}
+	return ew.err
+}
+
+// turtleLiteral renders a meta value as a typed Turtle literal
+func turtleLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return turtleString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return fmt.Sprintf("\"%d\"^^xsd:integer", int64(v))
+		}
+		return fmt.Sprintf("\"%s\"^^xsd:double", strconv.FormatFloat(v, 'g', -1, 64))
+	case int, int64:
+		return fmt.Sprintf("\"%d\"^^xsd:integer", v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return turtleString(fmt.Sprint(v))
+		}
+		return turtleString(string(data))
+	}
+}
+
+// turtleString quotes s as a Turtle string literal
+func turtleString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
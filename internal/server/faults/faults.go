@@ -0,0 +1,163 @@
+// Package faults injects latency, transient errors and partial writes into
+// repository calls, so integration tests can check that connectors,
+// webhooks and sync clients retry and deduplicate correctly. memex-server
+// only wraps its backend with it when built with -tags faults.
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrInjected is returned by calls failed on purpose. Clients should treat
+// it like any other transient backend error.
+var ErrInjected = errors.New("injected transient fault")
+
+// maxLatency bounds the delay added to each call
+const maxLatency = time.Minute
+
+// Config selects which repository calls fail and how
+type Config struct {
+	Operations  []string `json:"operations,omitempty"`   // Repository methods affected, e.g. "CreateNode"; empty for all
+	WritesOnly  bool     `json:"writes_only,omitempty"`  // Leave reads alone
+	LatencyMS   int      `json:"latency_ms,omitempty"`   // Each call waits a random time up to this first
+	ErrorRate   float64  `json:"error_rate,omitempty"`   // Share of calls failing with ErrInjected before they run
+	PartialRate float64  `json:"partial_rate,omitempty"` // Share of batch writes storing only part of the batch, then failing
+	Seed        int64    `json:"seed,omitempty"`         // Repeats the same faults; 0 picks one
+}
+
+// Validate checks the rates and latency are in range
+func (c Config) Validate() error {
+	if c.LatencyMS < 0 || time.Duration(c.LatencyMS)*time.Millisecond > maxLatency {
+		return fmt.Errorf("latency_ms must be between 0 and %d", maxLatency.Milliseconds())
+	}
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return fmt.Errorf("error_rate must be between 0 and 1")
+	}
+	if c.PartialRate < 0 || c.PartialRate > 1 {
+		return fmt.Errorf("partial_rate must be between 0 and 1")
+	}
+	for _, op := range c.Operations {
+		if !slices.Contains(Operations, op) {
+			return fmt.Errorf("unknown operation %q", op)
+		}
+	}
+	return nil
+}
+
+// Stats counts the faults injected since the last Configure
+type Stats struct {
+	Calls   int64 `json:"calls"`   // Calls the config applied to
+	Delayed int64 `json:"delayed"` // Calls given added latency
+	Errors  int64 `json:"errors"`  // Calls failed before running
+	Partial int64 `json:"partial"` // Batch writes cut short
+}
+
+// Injector decides, call by call, which faults to inject
+type Injector struct {
+	mu    sync.Mutex
+	cfg   Config
+	rng   *rand.Rand
+	stats Stats
+}
+
+// New creates an injector that injects nothing until configured
+func New() *Injector {
+	return &Injector{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Configure replaces the faults injected and resets the counts
+func (in *Injector) Configure(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.cfg = cfg
+	in.rng = rand.New(rand.NewSource(seed))
+	in.stats = Stats{}
+	return nil
+}
+
+// Clear stops injecting faults
+func (in *Injector) Clear() {
+	in.Configure(Config{})
+}
+
+// Config returns the faults being injected
+func (in *Injector) Config() Config {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.cfg
+}
+
+// Stats returns the faults injected so far
+func (in *Injector) Stats() Stats {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.stats
+}
+
+// applies reports whether the config covers an operation
+func (in *Injector) applies(op string, write bool) bool {
+	if in.cfg.WritesOnly && !write {
+		return false
+	}
+	return len(in.cfg.Operations) == 0 || slices.Contains(in.cfg.Operations, op)
+}
+
+// before runs ahead of a call: it waits out any added latency, then fails
+// the call with ErrInjected if the dice say so
+func (in *Injector) before(ctx context.Context, op string, write bool) error {
+	in.mu.Lock()
+	if !in.applies(op, write) {
+		in.mu.Unlock()
+		return nil
+	}
+	in.stats.Calls++
+	var delay time.Duration
+	if in.cfg.LatencyMS > 0 {
+		delay = time.Duration(in.rng.Int63n(int64(in.cfg.LatencyMS)+1)) * time.Millisecond
+		in.stats.Delayed++
+	}
+	fail := in.cfg.ErrorRate > 0 && in.rng.Float64() < in.cfg.ErrorRate
+	if fail {
+		in.stats.Errors++
+	}
+	in.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if fail {
+		return fmt.Errorf("%s: %w", op, ErrInjected)
+	}
+	return nil
+}
+
+// partial decides whether a batch write of n items is cut short, and if so
+// how many items to store before failing
+func (in *Injector) partial(op string, n int) (int, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if n == 0 || in.cfg.PartialRate <= 0 || !in.applies(op, true) || in.rng.Float64() >= in.cfg.PartialRate {
+		return n, false
+	}
+	in.stats.Partial++
+	return in.rng.Intn(n), true
+}
@@ -0,0 +1,100 @@
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestWrap(t *testing.T) {
+	ctx := context.Background()
+	in := New()
+	repo := Wrap(graph.NewMemory(), in)
+	now := time.Now()
+	node := func(id string) *core.Node {
+		return &core.Node{ID: id, Type: "Note", Meta: map[string]interface{}{}, Created: now, Modified: now}
+	}
+
+	// Nothing is injected until configured
+	if err := repo.CreateNode(ctx, node("note:0")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := in.Configure(Config{ErrorRate: 1, WritesOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateNode(ctx, node("note:1")); !errors.Is(err, ErrInjected) {
+		t.Errorf("write: %v, want ErrInjected", err)
+	}
+	if _, err := repo.GetNode(ctx, "note:0"); err != nil {
+		t.Errorf("read with writes_only: %v", err)
+	}
+	if _, err := repo.GetNode(ctx, "note:1"); err == nil {
+		t.Error("failed write stored the node")
+	}
+
+	// Partial batch writes store a prefix, then fail
+	if err := in.Configure(Config{PartialRate: 1, Operations: []string{"CreateNodes"}, Seed: 7}); err != nil {
+		t.Fatal(err)
+	}
+	var batch []*core.Node
+	for i := 0; i < 10; i++ {
+		batch = append(batch, node(fmt.Sprintf("batch:%d", i)))
+	}
+	err := repo.CreateNodes(ctx, batch)
+	if !errors.Is(err, ErrInjected) {
+		t.Fatalf("CreateNodes: %v, want ErrInjected", err)
+	}
+	stored := 0
+	for i, n := range batch {
+		if _, err := repo.GetNode(ctx, n.ID); err == nil {
+			if stored != i {
+				t.Errorf("%s stored after a gap", n.ID)
+			}
+			stored++
+		}
+	}
+	if stored == len(batch) {
+		t.Error("whole batch stored")
+	}
+	if err := repo.CreateNode(ctx, node("note:2")); err != nil {
+		t.Errorf("operation outside the config: %v", err)
+	}
+
+	// Latency waits, but not past the caller's deadline
+	if err := in.Configure(Config{LatencyMS: 60000, Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	deadline, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := repo.GetNode(deadline, "note:0"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow read: %v", err)
+	}
+	if stats := in.Stats(); stats.Calls != 1 || stats.Delayed != 1 || stats.Errors != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	in.Clear()
+	if _, err := repo.GetNode(ctx, "note:0"); err != nil {
+		t.Errorf("after Clear: %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, cfg := range []Config{
+		{ErrorRate: 1.5},
+		{PartialRate: -0.1},
+		{LatencyMS: -1},
+		{LatencyMS: 120000},
+		{Operations: []string{"DropDatabase"}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v accepted", cfg)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package faults
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Operations lists the repository methods faults can be injected into
+var Operations = []string{
+	"GetNode", "GetLinks", "GetBacklinks", "SearchNodes", "FilterNodes",
+	"TraverseGraph", "QueryTimeRange", "ListNodes", "GetSubgraph", "GetGraphMap", "ChangeLog",
+	"CreateNode", "CreateLink", "DeleteLink", "CreateNodes", "CreateLinks",
+	"UpdateNodeMeta", "UpdateNodeMetaWithNote", "DeleteNode", "UpdateAttentionEdge",
+}
+
+// repository wraps a Repository, injecting faults ahead of its calls
+type repository struct {
+	graph.Repository
+	in *Injector
+}
+
+// Wrap returns repo with the injector's faults added to its reads and writes
+func Wrap(repo graph.Repository, in *Injector) graph.Repository {
+	return &repository{Repository: repo, in: in}
+}
+
+func (r *repository) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	if err := r.in.before(ctx, "GetNode", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetNode(ctx, id)
+}
+
+func (r *repository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	if err := r.in.before(ctx, "GetLinks", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetLinks(ctx, nodeID)
+}
+
+func (r *repository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	if err := r.in.before(ctx, "GetBacklinks", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetBacklinks(ctx, nodeID)
+}
+
+func (r *repository) SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
+	if err := r.in.before(ctx, "SearchNodes", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.SearchNodes(ctx, searchTerm, limit, offset)
+}
+
+func (r *repository) FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error) {
+	if err := r.in.before(ctx, "FilterNodes", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.FilterNodes(ctx, nodeTypes, propertyKey, propertyValue, limit, offset)
+}
+
+func (r *repository) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
+	if err := r.in.before(ctx, "TraverseGraph", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.TraverseGraph(ctx, startNodeID, depth, relationshipTypes, limit, offset)
+}
+
+func (r *repository) QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error) {
+	if err := r.in.before(ctx, "QueryTimeRange", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.QueryTimeRange(ctx, from, to, nodeTypes, limit, offset)
+}
+
+func (r *repository) ListNodes(ctx context.Context) ([]string, error) {
+	if err := r.in.before(ctx, "ListNodes", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.ListNodes(ctx)
+}
+
+func (r *repository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*graph.Subgraph, error) {
+	if err := r.in.before(ctx, "GetSubgraph", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetSubgraph(ctx, startNodeID, depth, relationshipTypes)
+}
+
+func (r *repository) GetGraphMap(ctx context.Context, sampleSize int) (*graph.GraphMap, error) {
+	if err := r.in.before(ctx, "GetGraphMap", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetGraphMap(ctx, sampleSize)
+}
+
+func (r *repository) ChangeLog(ctx context.Context, since time.Time, limit int) ([]subscriptions.Event, error) {
+	if err := r.in.before(ctx, "ChangeLog", false); err != nil {
+		return nil, err
+	}
+	return r.Repository.ChangeLog(ctx, since, limit)
+}
+
+func (r *repository) CreateNode(ctx context.Context, node *core.Node) error {
+	if err := r.in.before(ctx, "CreateNode", true); err != nil {
+		return err
+	}
+	return r.Repository.CreateNode(ctx, node)
+}
+
+func (r *repository) CreateLink(ctx context.Context, link *core.Link) error {
+	if err := r.in.before(ctx, "CreateLink", true); err != nil {
+		return err
+	}
+	return r.Repository.CreateLink(ctx, link)
+}
+
+func (r *repository) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
+	if err := r.in.before(ctx, "DeleteLink", true); err != nil {
+		return err
+	}
+	return r.Repository.DeleteLink(ctx, sourceID, targetID, linkType)
+}
+
+// CreateNodes may store only the first part of the batch before failing,
+// as a connection dropped between batches would
+func (r *repository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	if err := r.in.before(ctx, "CreateNodes", true); err != nil {
+		return err
+	}
+	n, cut := r.in.partial("CreateNodes", len(nodes))
+	if !cut {
+		return r.Repository.CreateNodes(ctx, nodes)
+	}
+	if n > 0 {
+		if err := r.Repository.CreateNodes(ctx, nodes[:n]); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("CreateNodes: stored %d of %d nodes: %w", n, len(nodes), ErrInjected)
+}
+
+// CreateLinks may store only the first part of the batch before failing
+func (r *repository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	if err := r.in.before(ctx, "CreateLinks", true); err != nil {
+		return err
+	}
+	n, cut := r.in.partial("CreateLinks", len(links))
+	if !cut {
+		return r.Repository.CreateLinks(ctx, links)
+	}
+	if n > 0 {
+		if err := r.Repository.CreateLinks(ctx, links[:n]); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("CreateLinks: stored %d of %d links: %w", n, len(links), ErrInjected)
+}
+
+func (r *repository) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	if err := r.in.before(ctx, "UpdateNodeMeta", true); err != nil {
+		return err
+	}
+	return r.Repository.UpdateNodeMeta(ctx, id, meta)
+}
+
+func (r *repository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	if err := r.in.before(ctx, "UpdateNodeMetaWithNote", true); err != nil {
+		return err
+	}
+	return r.Repository.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy)
+}
+
+func (r *repository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	if err := r.in.before(ctx, "DeleteNode", true); err != nil {
+		return err
+	}
+	return r.Repository.DeleteNode(ctx, nodeID, force)
+}
+
+func (r *repository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	if err := r.in.before(ctx, "UpdateAttentionEdge", true); err != nil {
+		return err
+	}
+	return r.Repository.UpdateAttentionEdge(ctx, source, target, queryID, weight)
+}
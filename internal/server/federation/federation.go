@@ -0,0 +1,279 @@
+// Package federation imports graphs exported by other memex servers as
+// backup bundles. Each imported node records the server it came from and
+// its ID there, which makes a mapping table: later syncs from the same
+// source update the nodes they created instead of duplicating them, and
+// an ID already taken on this server is remapped rather than overwritten.
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/backup"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Properties recording where an imported node came from
+const (
+	SourceKey     = "federated_source" // Name of the server the node was imported from
+	OriginalIDKey = "federated_id"     // The node's ID on that server
+)
+
+// batchSize is how many nodes or links are written per call
+const batchSize = 500
+
+// ErrInvalid is returned for an unusable source name
+var ErrInvalid = errors.New("invalid federated import")
+
+var sourcePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// serverTypes are node types holding a server's own state, never imported
+var serverTypes = map[string]bool{"Stats": true, "IngestHook": true, "GitHubSync": true, "TicketSync": true, graph.ErasureAuditType: true}
+
+// localKeys are properties naming a node on one server only, dropped on import
+var localKeys = []string{graph.AliasKey, graph.ShortIDKey}
+
+// Options configures an import
+type Options struct {
+	Source    string // Names the exporting server; required, the same on every sync
+	ChangedBy string
+}
+
+// Result reports an import
+type Result struct {
+	Source    string            `json:"source"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Skipped   int               `json:"skipped"` // Nodes deleted here since an earlier sync
+	Links     int               `json:"links"`
+	Remapped  map[string]string `json:"remapped"` // Original ID -> local ID, for IDs that were taken here
+	Errors    []string          `json:"errors,omitempty"`
+}
+
+// ValidateSource checks a source name
+func ValidateSource(source string) error {
+	if !sourcePattern.MatchString(source) {
+		return fmt.Errorf("%w: source %q must be lowercase letters, digits, '.', '_' or '-'", ErrInvalid, source)
+	}
+	return nil
+}
+
+// Mappings returns the mapping table for a source: original ID -> local
+// ID for every node imported from it, deleted ones included
+func Mappings(ctx context.Context, repo graph.Repository, source string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	ctx = graph.WithDeleted(ctx)
+	for offset := 0; ; offset += batchSize {
+		nodes, err := repo.FilterNodes(ctx, nil, SourceKey, source, batchSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("reading mappings: %w", err)
+		}
+		for _, n := range nodes {
+			original, _ := n.Meta[OriginalIDKey].(string)
+			if n.Meta[SourceKey] == source && original != "" {
+				mapping[original] = n.ID
+			}
+		}
+		if len(nodes) < batchSize {
+			return mapping, nil
+		}
+	}
+}
+
+// Import writes a bundle's nodes and links from another server. Nodes seen
+// on an earlier sync from the same source get their changed properties
+// updated; their content stays as first imported. New nodes keep their ID
+// unless it is taken here, in which case they become "<id>@<source>".
+// Nodes deleted here are not brought back, nor is the source's own state
+// (growth stats, hooks, sync cursors). Links are remapped to local IDs
+// and written once.
+func Import(ctx context.Context, repo graph.Repository, b *backup.Bundle, opts Options) (*Result, error) {
+	if err := ValidateSource(opts.Source); err != nil {
+		return nil, err
+	}
+	mapping, err := Mappings(ctx, repo, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Source: opts.Source, Remapped: make(map[string]string)}
+	note := "Synced from " + opts.Source
+
+	claimed := make(map[string]bool) // Local IDs given out in this import
+	var batch []*core.Node
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := repo.CreateNodes(ctx, batch); err != nil {
+			return fmt.Errorf("creating nodes: %w", err)
+		}
+		res.Created += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	seen := make(map[string]bool, len(b.Nodes))
+	for _, n := range b.Nodes {
+		if n.ID == "" || seen[n.ID] || serverTypes[n.Type] {
+			continue
+		}
+		seen[n.ID] = true
+		meta := importedMeta(n, opts.Source)
+
+		if local, ok := mapping[n.ID]; ok {
+			existing, err := repo.GetNode(graph.WithDeleted(ctx), local)
+			if err != nil || existing.Deleted {
+				delete(mapping, n.ID) // Nor are links to them
+				res.Skipped++
+				continue
+			}
+			changed := changedMeta(existing.Meta, meta)
+			if len(changed) == 0 {
+				res.Unchanged++
+				continue
+			}
+			if err := repo.UpdateNodeMetaWithNote(ctx, local, changed, note, opts.ChangedBy); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", n.ID, err))
+				continue
+			}
+			res.Updated++
+			continue
+		}
+
+		local, err := freeID(ctx, repo, n.ID, opts.Source, claimed)
+		if err != nil {
+			return res, err
+		}
+		claimed[local] = true
+		mapping[n.ID] = local
+		if local != n.ID {
+			res.Remapped[n.ID] = local
+		}
+		batch = append(batch, &core.Node{ID: local, Type: n.Type, Content: n.Content, Meta: meta, Created: n.Created, Modified: n.Modified})
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return res, err
+	}
+
+	if err := importLinks(ctx, repo, b.Links, mapping, res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// importedMeta returns a bundled node's properties as stored here
+func importedMeta(n backup.Node, source string) map[string]interface{} {
+	meta := make(map[string]interface{}, len(n.Meta)+2)
+	for k, v := range n.Meta {
+		meta[k] = v
+	}
+	for _, k := range localKeys {
+		delete(meta, k)
+	}
+	meta[SourceKey] = source
+	meta[OriginalIDKey] = n.ID
+	return meta
+}
+
+// changedMeta returns the entries of incoming that differ from current.
+// Values are compared by their JSON form, as stored values may have been
+// decoded into other Go types.
+func changedMeta(current, incoming map[string]interface{}) map[string]interface{} {
+	changed := make(map[string]interface{})
+	for k, v := range incoming {
+		old, ok := current[k]
+		if !ok {
+			changed[k] = v
+			continue
+		}
+		a, _ := json.Marshal(old)
+		b, _ := json.Marshal(v)
+		if string(a) != string(b) {
+			changed[k] = v
+		}
+	}
+	return changed
+}
+
+// freeID returns id if no node here has it, or else the first free one of
+// "<id>@<source>", "<id>@<source>-2", ...
+func freeID(ctx context.Context, repo graph.Repository, id, source string, claimed map[string]bool) (string, error) {
+	withDeleted := graph.WithDeleted(ctx)
+	for i := 1; ; i++ {
+		candidate := id
+		switch {
+		case i == 2:
+			candidate = id + "@" + source
+		case i > 2:
+			candidate = fmt.Sprintf("%s@%s-%d", id, source, i-1)
+		}
+		if claimed[candidate] {
+			continue
+		}
+		if _, err := repo.GetNode(withDeleted, candidate); err != nil {
+			return candidate, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+	}
+}
+
+// importLinks writes the bundled links between mapped nodes that do not
+// exist here yet
+func importLinks(ctx context.Context, repo graph.Repository, links []backup.Link, mapping map[string]string, res *Result) error {
+	existing := make(map[string]map[string]bool) // Local source -> "type\x00target"
+	var batch []*core.Link
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := repo.CreateLinks(ctx, batch); err != nil {
+			return fmt.Errorf("creating links: %w", err)
+		}
+		res.Links += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, l := range links {
+		source, target := mapping[l.Source], mapping[l.Target]
+		if source == "" || target == "" {
+			continue
+		}
+		out, ok := existing[source]
+		if !ok {
+			current, err := repo.GetLinks(ctx, source)
+			if err != nil {
+				return fmt.Errorf("reading links of %s: %w", source, err)
+			}
+			out = make(map[string]bool, len(current))
+			for _, c := range current {
+				out[c.Type+"\x00"+c.Target] = true
+			}
+			existing[source] = out
+		}
+		key := l.Type + "\x00" + target
+		if out[key] {
+			continue
+		}
+		out[key] = true
+		batch = append(batch, &core.Link{Source: source, Target: target, Type: l.Type, Meta: l.Meta, Weight: l.Weight, Bidirectional: l.Bidirectional, Created: l.Created, Modified: l.Modified})
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
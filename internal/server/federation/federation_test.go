@@ -0,0 +1,88 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/backup"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func labBundle(status string) *backup.Bundle {
+	return &backup.Bundle{
+		Nodes: []backup.Node{
+			{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"name": "Ada", "alias": "person/ada"}},
+			{ID: "note:plan", Type: "Note", Content: []byte("the plan"), Meta: map[string]interface{}{"status": status}},
+			{ID: "stats:2026-05-01", Type: "Stats"},
+		},
+		Links: []backup.Link{
+			{Source: "note:plan", Target: "person:ada", Type: "MENTIONS"},
+			{Source: "note:plan", Target: "note:elsewhere", Type: "MENTIONS"}, // Not in the bundle
+		},
+	}
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	// This server has its own person:ada
+	repo.CreateNode(ctx, &core.Node{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"name": "Ada Byron"}})
+
+	res, err := Import(ctx, repo, labBundle("draft"), Options{Source: "lab"})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if res.Created != 2 || res.Links != 1 || res.Remapped["person:ada"] != "person:ada@lab" {
+		t.Fatalf("Import() = %+v", res)
+	}
+	local, err := repo.GetNode(ctx, "person:ada")
+	if err != nil || local.Meta["name"] != "Ada Byron" {
+		t.Errorf("local node changed: %+v", local)
+	}
+	remote, err := repo.GetNode(ctx, "person:ada@lab")
+	if err != nil || remote.Meta[SourceKey] != "lab" || remote.Meta[OriginalIDKey] != "person:ada" || remote.Meta["alias"] != nil {
+		t.Fatalf("remapped node = %+v, %v", remote, err)
+	}
+	links, _ := repo.GetLinks(ctx, "note:plan")
+	if len(links) != 1 || links[0].Target != "person:ada@lab" {
+		t.Errorf("links = %+v", links)
+	}
+
+	// A second sync updates what changed and duplicates nothing
+	res, err = Import(ctx, repo, labBundle("done"), Options{Source: "lab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != 0 || res.Updated != 1 || res.Unchanged != 1 || res.Links != 0 || len(res.Remapped) != 0 {
+		t.Errorf("second Import() = %+v", res)
+	}
+	if plan, _ := repo.GetNode(ctx, "note:plan"); plan.Meta["status"] != "done" {
+		t.Errorf("status = %v, want done", plan.Meta["status"])
+	}
+
+	mapping, err := Mappings(ctx, repo, "lab")
+	if err != nil || mapping["person:ada"] != "person:ada@lab" || mapping["note:plan"] != "note:plan" {
+		t.Errorf("Mappings() = %v, %v", mapping, err)
+	}
+
+	// Another source gets its own copies
+	res, err = Import(ctx, repo, labBundle("draft"), Options{Source: "home"})
+	if err != nil || res.Created != 2 || res.Remapped["note:plan"] != "note:plan@home" || res.Remapped["person:ada"] != "person:ada@home" {
+		t.Errorf("Import(home) = %+v, %v", res, err)
+	}
+
+	// Nodes deleted here stay deleted
+	if err := repo.DeleteNode(ctx, "note:plan", false); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Import(ctx, repo, labBundle("archived"), Options{Source: "lab"})
+	if err != nil || res.Skipped != 1 || res.Created != 0 {
+		t.Errorf("Import(after delete) = %+v, %v", res, err)
+	}
+
+	if _, err := Import(ctx, repo, labBundle("x"), Options{Source: "Lab Server"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Import(bad source) error = %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package federation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// LocalOrigin labels results from this server
+const LocalOrigin = "local"
+
+// DefaultPeerTimeout bounds how long a federated query waits for a peer
+const DefaultPeerTimeout = 5 * time.Second
+
+// Peer is another memex server queried alongside this one. Nothing is
+// copied from a peer; its results are fetched per query and labeled with
+// its name.
+type Peer struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	APIKey string `json:"-"` // Sent as X-API-Key when set
+}
+
+// ParsePeers reads "name=url" pairs, separated by commas. keys holds
+// "name=key" pairs for the peers that need an API key.
+func ParsePeers(spec, keys string) ([]Peer, error) {
+	apiKeys := make(map[string]string)
+	for _, entry := range splitPairs(keys) {
+		name, key, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("peer key %q: expected name=key", entry)
+		}
+		apiKeys[strings.TrimSpace(name)] = strings.TrimSpace(key)
+	}
+
+	var peers []Peer
+	seen := map[string]bool{LocalOrigin: true}
+	for _, entry := range splitPairs(spec) {
+		name, raw, ok := strings.Cut(entry, "=")
+		name, raw = strings.TrimSpace(name), strings.TrimSpace(raw)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("peer %q: expected name=url", entry)
+		}
+		if err := ValidateSource(name); err != nil {
+			return nil, fmt.Errorf("peer %q: %w", entry, err)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("peer %q: name %s is already used", entry, name)
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("peer %q: url must be an absolute http(s) URL", entry)
+		}
+		seen[name] = true
+		peers = append(peers, Peer{Name: name, URL: strings.TrimRight(raw, "/"), APIKey: apiKeys[name]})
+	}
+	return peers, nil
+}
+
+func splitPairs(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// Hit is one result of a federated query
+type Hit struct {
+	Node   *core.Node `json:"node"`
+	Origin string     `json:"origin"`            // LocalOrigin or the peer's name
+	AlsoIn []string   `json:"also_in,omitempty"` // Other servers holding the same node
+	URL    string     `json:"url,omitempty"`     // The node's page on its server
+}
+
+// PeerResult reports how one server answered a federated query
+type PeerResult struct {
+	Origin string `json:"origin"`
+	Count  int    `json:"count"`
+	TookMS int64  `json:"took_ms"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Searcher fans search queries out to peers
+type Searcher struct {
+	Peers      []Peer
+	Timeout    time.Duration // Per query; default DefaultPeerTimeout
+	HTTPClient *http.Client
+}
+
+// peerResponse is the part of a peer's search response that is used
+type peerResponse struct {
+	Nodes []*core.Node `json:"nodes"`
+}
+
+// Search queries every peer for q in parallel. A peer that fails or times
+// out is reported in its PeerResult and contributes no nodes.
+func (s *Searcher) Search(ctx context.Context, q string, limit int) (map[string][]*core.Node, []PeerResult) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPeerTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	nodes := make(map[string][]*core.Node, len(s.Peers))
+	results := make([]PeerResult, len(s.Peers))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, peer := range s.Peers {
+		wg.Add(1)
+		go func(i int, peer Peer) {
+			defer wg.Done()
+			start := time.Now()
+			found, err := s.searchPeer(ctx, peer, q, limit)
+			results[i] = PeerResult{Origin: peer.Name, Count: len(found), TookMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			mu.Lock()
+			nodes[peer.Name] = found
+			mu.Unlock()
+		}(i, peer)
+	}
+	wg.Wait()
+	return nodes, results
+}
+
+// searchPeer runs a plain (non-federated) search on one peer
+func (s *Searcher) searchPeer(ctx context.Context, peer Peer, q string, limit int) ([]*core.Node, error) {
+	params := url.Values{"q": {q}, "limit": {strconv.Itoa(limit)}}
+	req, err := http.NewRequestWithContext(ctx, "GET", peer.URL+"/api/query/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if peer.APIKey != "" {
+		req.Header.Set("X-API-Key", peer.APIKey)
+	}
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", peer.Name, resp.Status)
+	}
+	var body peerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s: decoding response: %w", peer.Name, err)
+	}
+	return body.Nodes, nil
+}
+
+// Merge interleaves ranked results from several servers, best ranks first
+// and origins in the given order, keeping the first of any nodes that are
+// the same: equal content, the same alias, or one federated from the
+// other. Duplicates are listed in the kept hit's AlsoIn.
+func Merge(origins []string, results map[string][]*core.Node, limit int) []Hit {
+	hits := []Hit{}
+	byKey := make(map[string]int) // Dedup key -> index in hits
+	for rank := 0; ; rank++ {
+		more := false
+		for _, origin := range origins {
+			nodes := results[origin]
+			if rank >= len(nodes) {
+				continue
+			}
+			more = true
+			node := nodes[rank]
+			keys := dedupKeys(origin, node)
+			if i, ok := firstMatch(byKey, keys); ok {
+				if hits[i].Origin != origin && !contains(hits[i].AlsoIn, origin) {
+					hits[i].AlsoIn = append(hits[i].AlsoIn, origin)
+				}
+				continue
+			}
+			if limit > 0 && len(hits) == limit {
+				continue // Still note duplicates of kept hits
+			}
+			for _, k := range keys {
+				byKey[k] = len(hits)
+			}
+			hits = append(hits, Hit{Node: node, Origin: origin})
+		}
+		if !more {
+			return hits
+		}
+	}
+}
+
+// dedupKeys returns the keys under which a result counts as the same node
+// as another server's
+func dedupKeys(origin string, node *core.Node) []string {
+	var keys []string
+	if len(node.Content) > 0 {
+		sum := sha256.Sum256(node.Content)
+		keys = append(keys, "content:"+hex.EncodeToString(sum[:]))
+	}
+	if alias, _ := node.Meta[graph.AliasKey].(string); alias != "" {
+		keys = append(keys, "alias:"+alias)
+	}
+	// A node imported from another server is that server's node
+	if source, _ := node.Meta[SourceKey].(string); source != "" {
+		if id, _ := node.Meta[OriginalIDKey].(string); id != "" {
+			keys = append(keys, "node:"+source+"\x00"+id)
+		}
+	}
+	keys = append(keys, "node:"+origin+"\x00"+node.ID)
+	return keys
+}
+
+func firstMatch(byKey map[string]int, keys []string) (int, bool) {
+	for _, k := range keys {
+		if i, ok := byKey[k]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
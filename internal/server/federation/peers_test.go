@@ -0,0 +1,76 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestParsePeers(t *testing.T) {
+	peers, err := ParsePeers(" work=https://memex.work.example/ , home=http://localhost:8081", "work=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 || peers[0] != (Peer{Name: "work", URL: "https://memex.work.example", APIKey: "secret"}) || peers[1].APIKey != "" {
+		t.Errorf("ParsePeers() = %+v", peers)
+	}
+	for _, spec := range []string{"work", "work=ftp://x", "local=http://x", "a=http://x,a=http://y", "Work=http://x"} {
+		if _, err := ParsePeers(spec, ""); err == nil {
+			t.Errorf("ParsePeers(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	work := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/query/search" || r.URL.Query().Get("q") != "plan" || r.Header.Get("X-API-Key") != "k" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"nodes": []*core.Node{
+			{ID: "note:plan", Type: "Note", Content: []byte("Q3 plan")},
+			{ID: "note:shared", Type: "Note", Content: []byte("same text")},
+		}})
+	}))
+	defer work.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	s := &Searcher{Peers: []Peer{{Name: "work", URL: work.URL, APIKey: "k"}, {Name: "down", URL: down.URL}}}
+	results, status := s.Search(context.Background(), "plan", 10)
+	if len(results["work"]) != 2 || status[0].Error != "" || status[0].Count != 2 || status[1].Error == "" {
+		t.Fatalf("Search() = %v, %+v", results, status)
+	}
+
+	// The same node ID on two servers is two nodes; the same content is one
+	results[LocalOrigin] = []*core.Node{
+		{ID: "note:plan", Type: "Note", Content: []byte("my plan")},
+		{ID: "doc:copy", Type: "Note", Content: []byte("same text")},
+	}
+	hits := Merge([]string{LocalOrigin, "work", "down"}, results, 10)
+	if len(hits) != 3 {
+		t.Fatalf("Merge() = %d hits: %+v", len(hits), hits)
+	}
+	if hits[0].Origin != LocalOrigin || hits[1].Origin != "work" || hits[1].Node.ID != "note:plan" {
+		t.Errorf("Merge() order = %+v", hits)
+	}
+	if hits[2].Node.ID != "doc:copy" || len(hits[2].AlsoIn) != 1 || hits[2].AlsoIn[0] != "work" {
+		t.Errorf("deduplicated hit = %+v", hits[2])
+	}
+
+	// A node federated from a peer is that peer's node
+	imported := &core.Node{ID: "person:ada@work", Meta: map[string]interface{}{SourceKey: "work", OriginalIDKey: "person:ada"}}
+	hits = Merge([]string{LocalOrigin, "work"}, map[string][]*core.Node{
+		LocalOrigin: {imported},
+		"work":      {{ID: "person:ada"}},
+	}, 0)
+	if len(hits) != 1 || hits[0].AlsoIn[0] != "work" {
+		t.Errorf("Merge(federated copy) = %+v", hits)
+	}
+}
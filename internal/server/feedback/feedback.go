@@ -0,0 +1,409 @@
+// Package feedback measures retrieval quality. Search and recommendation
+// endpoints record which nodes they returned, at what rank and with what
+// ranking signals; callers report the ones they went on to open or cite.
+// The tracker turns that into precision per node, endpoint and rank, and
+// compares each signal between used and ignored results so the ranking
+// weights can be tuned against real use. Statistics live in memory and
+// cover the time since the server started.
+package feedback
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Feedback actions
+const (
+	ActionClick = "click" // The caller opened the result
+	ActionCite  = "cite"  // The caller cited the result in an answer
+)
+
+// Defaults for the tracker
+const (
+	DefaultRetained = 10000 // Retrievals kept for feedback
+	maxRankBuckets  = 20    // Ranks reported individually; lower ones share the last bucket
+)
+
+// Errors returned by the tracker
+var (
+	ErrUnknownRetrieval = errors.New("unknown or expired retrieval")
+	ErrNotReturned      = errors.New("node was not returned by this retrieval")
+)
+
+// Hit is one result returned to a caller, with the signals that ranked it
+type Hit struct {
+	NodeID  string             `json:"node_id"`
+	Signals map[string]float64 `json:"signals,omitempty"`
+}
+
+// retrieval is one recorded result list
+type retrieval struct {
+	endpoint string
+	variant  string
+	hits     []Hit
+	rank     map[string]int             // Node -> 1-based rank
+	used     map[string]map[string]bool // Node -> actions reported
+}
+
+// Counts is how often results were returned and used
+type Counts struct {
+	Impressions int     `json:"impressions"`
+	Clicks      int     `json:"clicks"`
+	Citations   int     `json:"citations"`
+	Used        int     `json:"used"`      // Impressions clicked or cited
+	Precision   float64 `json:"precision"` // Used / impressions
+}
+
+func (c *Counts) add(action string, firstUse bool) {
+	switch action {
+	case ActionClick:
+		c.Clicks++
+	case ActionCite:
+		c.Citations++
+	}
+	if firstUse {
+		c.Used++
+	}
+}
+
+func (c Counts) withPrecision() Counts {
+	if c.Impressions > 0 {
+		c.Precision = round(float64(c.Used) / float64(c.Impressions))
+	}
+	return c
+}
+
+// EndpointStats is retrieval quality for one endpoint
+type EndpointStats struct {
+	Counts
+	Retrievals int     `json:"retrievals"`
+	Judged     int     `json:"judged"` // Retrievals with any feedback
+	MRR        float64 `json:"mrr"`    // Mean reciprocal rank of the best-ranked used result, over judged retrievals
+
+	reciprocal float64
+}
+
+// RankStats is precision at one rank
+type RankStats struct {
+	Rank string `json:"rank"` // "1".."19", or "20+"
+	Counts
+}
+
+// SignalStats compares a ranking signal between used and ignored results
+// of judged retrievals. A signal that separates them well has a large gap.
+type SignalStats struct {
+	Signal      string  `json:"signal"`
+	Endpoint    string  `json:"endpoint"`
+	UsedMean    float64 `json:"used_mean"`
+	IgnoredMean float64 `json:"ignored_mean"`
+	Gap         float64 `json:"gap"` // used_mean - ignored_mean
+	UsedN       int     `json:"used_n"`
+	IgnoredN    int     `json:"ignored_n"`
+}
+
+// NodeStats is retrieval quality for one node
+type NodeStats struct {
+	NodeID string `json:"node_id"`
+	Counts
+}
+
+// Stats is a snapshot of retrieval quality
+type Stats struct {
+	Since     time.Time                `json:"since"`
+	Endpoints map[string]EndpointStats `json:"endpoints"`
+	Variants  map[string]EndpointStats `json:"variants,omitempty"` // Ranking experiment variants
+	Ranks     []RankStats              `json:"ranks"`
+	Signals   []SignalStats            `json:"signals"`
+	Nodes     []NodeStats              `json:"nodes"`
+}
+
+// Tracker records retrievals and feedback
+type Tracker struct {
+	mu         sync.Mutex
+	since      time.Time
+	retain     int
+	retrievals map[string]*retrieval
+	order      []string // Retrieval IDs, oldest first
+	nodes      map[string]*Counts
+	endpoints  map[string]*EndpointStats
+	variants   map[string]*EndpointStats
+	ranks      [maxRankBuckets]Counts
+}
+
+// NewTracker creates a tracker that keeps the last retain retrievals open
+// for feedback
+func NewTracker(retain int) *Tracker {
+	if retain <= 0 {
+		retain = DefaultRetained
+	}
+	return &Tracker{
+		since:      time.Now(),
+		retain:     retain,
+		retrievals: make(map[string]*retrieval),
+		nodes:      make(map[string]*Counts),
+		endpoints:  make(map[string]*EndpointStats),
+		variants:   make(map[string]*EndpointStats),
+	}
+}
+
+func rankBucket(rank int) int {
+	if rank > maxRankBuckets {
+		rank = maxRankBuckets
+	}
+	return rank - 1
+}
+
+// Record notes the results an endpoint returned, in rank order, and
+// returns the retrieval ID callers send feedback against
+func (t *Tracker) Record(endpoint string, hits []Hit) string {
+	return t.RecordVariant(endpoint, "", hits)
+}
+
+// RecordVariant records a retrieval ranked by an experiment variant, whose
+// statistics are also kept under the variant's name
+func (t *Tracker) RecordVariant(endpoint, variant string, hits []Hit) string {
+	id := uuid.New().String()
+	rv := &retrieval{
+		endpoint: endpoint,
+		variant:  variant,
+		hits:     hits,
+		rank:     make(map[string]int, len(hits)),
+		used:     make(map[string]map[string]bool),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ep := statsFor(t.endpoints, endpoint)
+	ep.Retrievals++
+	var vs *EndpointStats
+	if variant != "" {
+		vs = statsFor(t.variants, variant)
+		vs.Retrievals++
+	}
+	for i, h := range hits {
+		if _, dup := rv.rank[h.NodeID]; dup {
+			continue
+		}
+		rv.rank[h.NodeID] = i + 1
+		ep.Impressions++
+		if vs != nil {
+			vs.Impressions++
+		}
+		t.ranks[rankBucket(i+1)].Impressions++
+		c := t.nodes[h.NodeID]
+		if c == nil {
+			c = &Counts{}
+			t.nodes[h.NodeID] = c
+		}
+		c.Impressions++
+	}
+
+	t.retrievals[id] = rv
+	t.order = append(t.order, id)
+	if len(t.order) > t.retain {
+		delete(t.retrievals, t.order[0])
+		t.order = t.order[1:]
+	}
+	return id
+}
+
+func statsFor(m map[string]*EndpointStats, key string) *EndpointStats {
+	s := m[key]
+	if s == nil {
+		s = &EndpointStats{}
+		m[key] = s
+	}
+	return s
+}
+
+// Feedback reports that the caller acted on a node from a retrieval. An
+// action is counted once per node and retrieval.
+func (t *Tracker) Feedback(retrievalID, nodeID, action string) error {
+	if action != ActionClick && action != ActionCite {
+		return fmt.Errorf("invalid action %q (%s, %s)", action, ActionClick, ActionCite)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rv := t.retrievals[retrievalID]
+	if rv == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownRetrieval, retrievalID)
+	}
+	rank, ok := rv.rank[nodeID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotReturned, nodeID)
+	}
+	actions := rv.used[nodeID]
+	if actions[action] {
+		return nil
+	}
+	firstUse := actions == nil
+	firstJudgement := len(rv.used) == 0
+	if actions == nil {
+		actions = make(map[string]bool)
+		rv.used[nodeID] = actions
+	}
+	actions[action] = true
+
+	t.nodes[nodeID].add(action, firstUse)
+	t.ranks[rankBucket(rank)].add(action, firstUse)
+	// Keep the reciprocal rank of the best used result per retrieval
+	var gain float64
+	if firstUse {
+		best := 0
+		for id := range rv.used {
+			if id != nodeID && (best == 0 || rv.rank[id] < best) {
+				best = rv.rank[id]
+			}
+		}
+		if best == 0 {
+			gain = 1 / float64(rank)
+		} else if rank < best {
+			gain = 1/float64(rank) - 1/float64(best)
+		}
+	}
+	for _, ep := range []*EndpointStats{t.endpoints[rv.endpoint], t.variants[rv.variant]} {
+		if ep == nil {
+			continue
+		}
+		ep.add(action, firstUse)
+		if firstJudgement {
+			ep.Judged++
+		}
+		ep.reciprocal += gain
+	}
+	return nil
+}
+
+func (ep EndpointStats) view() EndpointStats {
+	ep.Counts = ep.Counts.withPrecision()
+	if ep.Judged > 0 {
+		ep.MRR = round(ep.reciprocal / float64(ep.Judged))
+	}
+	return ep
+}
+
+// Variant returns the statistics of one experiment variant
+func (t *Tracker) Variant(variant string) EndpointStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if vs := t.variants[variant]; vs != nil {
+		return vs.view()
+	}
+	return EndpointStats{}
+}
+
+// Stats returns a snapshot. minImpressions hides nodes returned fewer
+// times; the nodes are listed by impressions, at most limit of them.
+func (t *Tracker) Stats(minImpressions, limit int) *Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := &Stats{
+		Since:     t.since,
+		Endpoints: make(map[string]EndpointStats, len(t.endpoints)),
+		Ranks:     []RankStats{},
+		Signals:   []SignalStats{},
+		Nodes:     []NodeStats{},
+	}
+	for name, ep := range t.endpoints {
+		stats.Endpoints[name] = ep.view()
+	}
+	if len(t.variants) > 0 {
+		stats.Variants = make(map[string]EndpointStats, len(t.variants))
+		for name, vs := range t.variants {
+			stats.Variants[name] = vs.view()
+		}
+	}
+	for i, c := range t.ranks {
+		if c.Impressions == 0 {
+			continue
+		}
+		label := fmt.Sprint(i + 1)
+		if i == maxRankBuckets-1 {
+			label += "+"
+		}
+		stats.Ranks = append(stats.Ranks, RankStats{Rank: label, Counts: c.withPrecision()})
+	}
+	stats.Signals = t.signalStats()
+
+	for id, c := range t.nodes {
+		if c.Impressions >= minImpressions {
+			stats.Nodes = append(stats.Nodes, NodeStats{NodeID: id, Counts: c.withPrecision()})
+		}
+	}
+	sort.Slice(stats.Nodes, func(i, j int) bool {
+		a, b := stats.Nodes[i], stats.Nodes[j]
+		if a.Impressions != b.Impressions {
+			return a.Impressions > b.Impressions
+		}
+		return a.NodeID < b.NodeID
+	})
+	if limit > 0 && len(stats.Nodes) > limit {
+		stats.Nodes = stats.Nodes[:limit]
+	}
+	return stats
+}
+
+// signalStats compares signals over the retained judged retrievals;
+// callers hold t.mu
+func (t *Tracker) signalStats() []SignalStats {
+	type sums struct {
+		used, ignored   float64
+		usedN, ignoredN int
+	}
+	acc := make(map[[2]string]*sums)
+	for _, id := range t.order {
+		rv := t.retrievals[id]
+		if len(rv.used) == 0 {
+			continue
+		}
+		for _, h := range rv.hits {
+			for signal, v := range h.Signals {
+				key := [2]string{rv.endpoint, signal}
+				s := acc[key]
+				if s == nil {
+					s = &sums{}
+					acc[key] = s
+				}
+				if rv.used[h.NodeID] != nil {
+					s.used += v
+					s.usedN++
+				} else {
+					s.ignored += v
+					s.ignoredN++
+				}
+			}
+		}
+	}
+
+	out := make([]SignalStats, 0, len(acc))
+	for key, s := range acc {
+		st := SignalStats{Endpoint: key[0], Signal: key[1], UsedN: s.usedN, IgnoredN: s.ignoredN}
+		if s.usedN > 0 {
+			st.UsedMean = round(s.used / float64(s.usedN))
+		}
+		if s.ignoredN > 0 {
+			st.IgnoredMean = round(s.ignored / float64(s.ignoredN))
+		}
+		st.Gap = round(st.UsedMean - st.IgnoredMean)
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Endpoint != out[j].Endpoint {
+			return out[i].Endpoint < out[j].Endpoint
+		}
+		return out[i].Signal < out[j].Signal
+	})
+	return out
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
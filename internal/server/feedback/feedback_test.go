@@ -0,0 +1,61 @@
+package feedback
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker(2)
+	hits := []Hit{
+		{NodeID: "a", Signals: map[string]float64{"trust": 0.9}},
+		{NodeID: "b", Signals: map[string]float64{"trust": 0.2}},
+		{NodeID: "c", Signals: map[string]float64{"trust": 0.4}},
+	}
+	first := tr.Record("search", hits)
+	second := tr.Record("search", hits)
+
+	if err := tr.Feedback(first, "c", ActionClick); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Feedback(first, "a", ActionClick); err != nil {
+		t.Fatal(err)
+	}
+	// A second action on a used node counts the action but not the use
+	if err := tr.Feedback(first, "a", ActionCite); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Feedback(first, "a", ActionCite); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Feedback(second, "z", ActionClick); !errors.Is(err, ErrNotReturned) {
+		t.Errorf("feedback on a node not returned: %v", err)
+	}
+	if err := tr.Feedback(second, "a", "like"); err == nil {
+		t.Error("accepted an unknown action")
+	}
+
+	stats := tr.Stats(0, 0)
+	ep := stats.Endpoints["search"]
+	if ep.Retrievals != 2 || ep.Impressions != 6 || ep.Used != 2 || ep.Judged != 1 || ep.Precision != 0.333 || ep.MRR != 1 {
+		t.Errorf("search = %+v", ep)
+	}
+	if len(stats.Ranks) != 3 || stats.Ranks[0].Used != 1 || stats.Ranks[1].Used != 0 || stats.Ranks[2].Used != 1 {
+		t.Errorf("ranks = %+v", stats.Ranks)
+	}
+	if stats.Nodes[0].NodeID != "a" || stats.Nodes[0].Clicks != 1 || stats.Nodes[0].Citations != 1 || stats.Nodes[0].Precision != 0.5 {
+		t.Errorf("node a = %+v", stats.Nodes[0])
+	}
+	if len(stats.Signals) != 1 {
+		t.Fatalf("signals = %+v", stats.Signals)
+	}
+	if s := stats.Signals[0]; s.UsedMean != 0.65 || s.IgnoredMean != 0.2 || s.Gap != 0.45 || s.UsedN != 2 || s.IgnoredN != 1 {
+		t.Errorf("trust signal = %+v", s)
+	}
+
+	// Older retrievals expire
+	tr.Record("related", hits)
+	if err := tr.Feedback(first, "b", ActionClick); !errors.Is(err, ErrUnknownRetrieval) {
+		t.Errorf("feedback on an expired retrieval: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Package finalizers calls cleanup webhooks when nodes are deleted, so
+// external data a node refers to (blob objects, S3 keys, webhook
+// registrations) is released with it. Finalizers are registered per node
+// type and stored as graph nodes.
+package finalizers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// NodeType is the node type finalizers are stored as
+const NodeType = "Finalizer"
+
+// idPrefix namespaces finalizer node IDs
+const idPrefix = "finalizer:"
+
+// ErrNotFound is returned for unknown finalizers
+var ErrNotFound = errors.New("finalizer not found")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Finalizer calls a webhook when a node of one type is deleted
+type Finalizer struct {
+	ID          string    `json:"id"`
+	NodeType    string    `json:"node_type"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	Secret      string    `json:"secret,omitempty"`     // Optional HMAC-SHA256 key; payloads are signed in X-Memex-Signature
+	Tombstones  bool      `json:"tombstones,omitempty"` // Also fire on soft deletes, not only hard deletes
+	Created     time.Time `json:"created"`
+}
+
+// Validate checks a finalizer's ID, node type and URL
+func (f *Finalizer) Validate() error {
+	if !validID.MatchString(f.ID) {
+		return fmt.Errorf("invalid id %q (use 1-64 letters, digits, _ or -)", f.ID)
+	}
+	if f.NodeType == "" {
+		return fmt.Errorf("node_type is required")
+	}
+	if f.NodeType == NodeType {
+		return fmt.Errorf("finalizers cannot watch %s nodes", NodeType)
+	}
+	if !strings.HasPrefix(f.URL, "http://") && !strings.HasPrefix(f.URL, "https://") {
+		return fmt.Errorf("url must be an http(s) URL")
+	}
+	return nil
+}
+
+// Save validates and stores a new finalizer, generating an ID if needed
+func Save(ctx context.Context, repo graph.Repository, f *Finalizer) error {
+	if f.ID == "" {
+		f.ID = uuid.New().String()
+	}
+	f.Created = time.Now()
+	return Restore(ctx, repo, f)
+}
+
+// Restore validates and stores a finalizer from a backup, keeping its ID and timestamp
+func Restore(ctx context.Context, repo graph.Repository, f *Finalizer) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	meta, err := toMeta(f)
+	if err != nil {
+		return err
+	}
+	return repo.CreateNode(ctx, &core.Node{ID: idPrefix + f.ID, Type: NodeType, Meta: meta, Created: f.Created, Modified: f.Created})
+}
+
+// Get loads a finalizer
+func Get(ctx context.Context, repo graph.Repository, id string) (*Finalizer, error) {
+	node, err := repo.GetNode(ctx, idPrefix+id)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return fromNode(node)
+}
+
+// List returns all finalizers
+func List(ctx context.Context, repo graph.Repository) ([]*Finalizer, error) {
+	const pageSize = 500
+	out := []*Finalizer{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			f, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			out = append(out, f)
+		}
+		if len(nodes) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+// Delete removes a finalizer and its history
+func Delete(ctx context.Context, repo graph.Repository, id string) error {
+	if _, err := Get(ctx, repo, id); err != nil {
+		return err
+	}
+	return repo.DeleteNode(ctx, idPrefix+id, true)
+}
+
+// toMeta stores a finalizer's fields as node properties
+func toMeta(f *Finalizer) (map[string]interface{}, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	delete(meta, "id")
+	return meta, nil
+}
+
+// fromNode reads a finalizer back from its node
+func fromNode(node *core.Node) (*Finalizer, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var f Finalizer
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("corrupt finalizer %s: %w", node.ID, err)
+	}
+	f.ID = strings.TrimPrefix(node.ID, idPrefix)
+	return &f, nil
+}
@@ -0,0 +1,190 @@
+package finalizers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// receiver records the finalizer events posted to it
+type receiver struct {
+	mu     sync.Mutex
+	events []Event
+	fail   bool
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("X-Memex-Event") != EventFinalized {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if sig := r.Header.Get("X-Memex-Signature"); sig != "" {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		if sig != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	var e Event
+	json.Unmarshal(body, &e)
+	rc.events = append(rc.events, e)
+}
+
+func (rc *receiver) received() []Event {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]Event(nil), rc.events...)
+}
+
+func addNode(t *testing.T, repo graph.Repository, id, nodeType string, meta map[string]interface{}) {
+	t.Helper()
+	now := time.Now()
+	if err := repo.CreateNode(context.Background(), &core.Node{ID: id, Type: nodeType, Meta: meta, Created: now, Modified: now}); err != nil {
+		t.Fatalf("CreateNode(%s) error = %v", id, err)
+	}
+}
+
+func TestFinalizersFireOnDelete(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	base := graph.NewMemory()
+	m := NewManager(base, time.Second)
+	repo := Wrap(base, m)
+
+	if err := m.Register(ctx, &Finalizer{ID: "blobs", NodeType: "Blob", URL: srv.URL, Secret: "s3cret"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := m.Register(ctx, &Finalizer{ID: "hooks", NodeType: "Webhook", URL: srv.URL, Tombstones: true}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	addNode(t, repo, "blob:1", "Blob", map[string]interface{}{"s3_key": "bucket/1"})
+	addNode(t, repo, "blob:2", "Blob", map[string]interface{}{"s3_key": "bucket/2"})
+	addNode(t, repo, "hook:1", "Webhook", map[string]interface{}{"registration": "r-1"})
+	addNode(t, repo, "note:1", "Note", map[string]interface{}{})
+
+	// A soft delete only fires finalizers that ask for tombstones
+	for _, id := range []string{"blob:1", "hook:1", "note:1"} {
+		if err := repo.DeleteNode(ctx, id, false); err != nil {
+			t.Fatalf("DeleteNode(%s) error = %v", id, err)
+		}
+	}
+	if err := repo.DeleteNode(ctx, "blob:2", true); err != nil {
+		t.Fatalf("DeleteNode(blob:2) error = %v", err)
+	}
+	m.Wait()
+
+	got := map[string]Event{}
+	for _, e := range rc.received() {
+		got[e.NodeID] = e
+	}
+	if len(got) != 2 {
+		t.Fatalf("received events for %v, want hook:1 and blob:2", got)
+	}
+	if e := got["blob:2"]; e.FinalizerID != "blobs" || !e.HardDeleted || e.Meta["s3_key"] != "bucket/2" {
+		t.Errorf("blob:2 event = %+v", e)
+	}
+	if e := got["hook:1"]; e.FinalizerID != "hooks" || e.HardDeleted || e.Meta["registration"] != "r-1" {
+		t.Errorf("hook:1 event = %+v", e)
+	}
+
+	// Finalizers survive a restart
+	reloaded := NewManager(base, time.Second)
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if list := reloaded.List("Blob"); len(list) != 1 || list[0].Secret != "s3cret" {
+		t.Errorf("List(Blob) after Load() = %+v", list)
+	}
+
+	// A removed finalizer stops firing
+	if err := m.Remove(ctx, "hooks"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	addNode(t, repo, "hook:2", "Webhook", map[string]interface{}{})
+	if err := repo.DeleteNode(ctx, "hook:2", true); err != nil {
+		t.Fatal(err)
+	}
+	m.Wait()
+	if n := len(rc.received()); n != 2 {
+		t.Errorf("received %d events after Remove(), want 2", n)
+	}
+}
+
+func TestFailedDeliveriesCanBeRetried(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{fail: true}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	base := graph.NewMemory()
+	m := NewManager(base, time.Second)
+	m.backoff = time.Millisecond
+	repo := Wrap(base, m)
+	if err := m.Register(ctx, &Finalizer{ID: "blobs", NodeType: "Blob", URL: srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	addNode(t, repo, "blob:1", "Blob", map[string]interface{}{"path": "/tmp/1"})
+	if err := repo.DeleteNode(ctx, "blob:1", true); err != nil {
+		t.Fatal(err)
+	}
+	m.Wait()
+
+	failures := m.Failures("blobs")
+	if len(failures) != 1 || failures[0].Event.NodeID != "blob:1" {
+		t.Fatalf("Failures() = %+v, want one for blob:1", failures)
+	}
+	if _, err := m.RetryFailure(failures[0].ID); err == nil {
+		t.Error("RetryFailure() succeeded against a failing receiver")
+	}
+	if f := m.Failures(""); len(f) != 1 || f[0].Attempts != 2 {
+		t.Errorf("Failures() after a failed retry = %+v, want one with 2 attempts", f)
+	}
+
+	rc.mu.Lock()
+	rc.fail = false
+	rc.mu.Unlock()
+	if _, err := m.RetryFailure(failures[0].ID); err != nil {
+		t.Fatalf("RetryFailure() error = %v", err)
+	}
+	if len(m.Failures("")) != 0 || len(rc.received()) != 1 {
+		t.Errorf("after a successful retry: %d failures, %d received", len(m.Failures("")), len(rc.received()))
+	}
+	if _, err := m.RetryFailure(failures[0].ID); err != ErrFailureNotFound {
+		t.Errorf("RetryFailure() of a delivered failure error = %v, want ErrFailureNotFound", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, f := range []Finalizer{
+		{ID: "bad id", NodeType: "Blob", URL: "https://example.com"},
+		{ID: "ok", URL: "https://example.com"},
+		{ID: "ok", NodeType: NodeType, URL: "https://example.com"},
+		{ID: "ok", NodeType: "Blob", URL: "ftp://example.com"},
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", f)
+		}
+	}
+}
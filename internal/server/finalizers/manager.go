@@ -0,0 +1,346 @@
+package finalizers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// EventFinalized is the X-Memex-Event header value of finalizer webhooks
+const EventFinalized = "node.finalized"
+
+// maxFailures bounds the failed-delivery list; the oldest entries are dropped
+const maxFailures = 1000
+
+// maxConcurrent bounds webhook calls in flight, so a bulk delete does not
+// open a connection per node
+const maxConcurrent = 8
+
+// ErrFailureNotFound is returned for unknown failed-delivery IDs
+var ErrFailureNotFound = errors.New("failed delivery not found")
+
+// Event is the webhook payload for a deleted node. Meta is the node's
+// last live properties, so the receiver can find the external data.
+type Event struct {
+	Event       string                 `json:"event"`
+	FinalizerID string                 `json:"finalizer_id"`
+	NodeID      string                 `json:"node_id"`
+	NodeType    string                 `json:"node_type"`
+	Meta        map[string]interface{} `json:"meta,omitempty"`
+	HardDeleted bool                   `json:"hard_deleted"`
+	DeletedAt   time.Time              `json:"deleted_at"`
+}
+
+// Failure is a finalizer call that failed after all retries. Failures are
+// kept in memory until retried or dismissed.
+type Failure struct {
+	ID       string    `json:"id"`
+	URL      string    `json:"url"`
+	Event    Event     `json:"event"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"` // Delivery rounds, each with its own retries
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Manager holds the registered finalizers by node type and delivers their
+// webhooks
+type Manager struct {
+	repo       graph.Repository
+	httpClient *http.Client
+	backoff    time.Duration // Base delay between retries
+
+	mu     sync.RWMutex
+	byID   map[string]*Finalizer
+	byType map[string][]*Finalizer
+
+	fmu      sync.Mutex
+	failures []*Failure
+
+	sem     chan struct{}
+	pending sync.WaitGroup
+}
+
+// NewManager creates a manager storing finalizers in repo. Webhook calls
+// time out after timeout (default 10s).
+func NewManager(repo graph.Repository, timeout time.Duration) *Manager {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Manager{
+		repo:       repo,
+		httpClient: &http.Client{Timeout: timeout},
+		backoff:    time.Second,
+		byID:       make(map[string]*Finalizer),
+		byType:     make(map[string][]*Finalizer),
+		sem:        make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Load reads the stored finalizers
+func (m *Manager) Load(ctx context.Context) error {
+	list, err := List(ctx, m.repo)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.byID = make(map[string]*Finalizer, len(list))
+	for _, f := range list {
+		m.byID[f.ID] = f
+	}
+	m.index()
+	return nil
+}
+
+// Register validates and stores a new finalizer
+func (m *Manager) Register(ctx context.Context, f *Finalizer) error {
+	if err := Save(ctx, m.repo, f); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.byID[f.ID] = f
+	m.index()
+	m.mu.Unlock()
+	return nil
+}
+
+// Remove deletes a finalizer
+func (m *Manager) Remove(ctx context.Context, id string) error {
+	if err := Delete(ctx, m.repo, id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.byID, id)
+	m.index()
+	m.mu.Unlock()
+	return nil
+}
+
+// Get returns a registered finalizer
+func (m *Manager) Get(id string) (*Finalizer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return f, nil
+}
+
+// List returns the registered finalizers, optionally for one node type,
+// oldest first
+func (m *Manager) List(nodeType string) []*Finalizer {
+	m.mu.RLock()
+	list := make([]*Finalizer, 0, len(m.byID))
+	for _, f := range m.byID {
+		if nodeType == "" || f.NodeType == nodeType {
+			list = append(list, f)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list
+}
+
+// index rebuilds the by-type lookup; callers hold m.mu
+func (m *Manager) index() {
+	m.byType = make(map[string][]*Finalizer)
+	for _, f := range m.byID {
+		m.byType[f.NodeType] = append(m.byType[f.NodeType], f)
+	}
+}
+
+// empty reports whether no finalizer is registered, so deletes can skip
+// reading the node first
+func (m *Manager) empty() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.byID) == 0
+}
+
+// matching returns the finalizers that fire when a node of nodeType is
+// deleted, hard or soft
+func (m *Manager) matching(nodeType string, hard bool) []*Finalizer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*Finalizer
+	for _, f := range m.byType[nodeType] {
+		if hard || f.Tombstones {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// fire calls each finalizer's webhook for a deleted node in the background
+func (m *Manager) fire(list []*Finalizer, node *core.Node, hard bool) {
+	now := time.Now()
+	for _, f := range list {
+		event := Event{
+			Event:       EventFinalized,
+			FinalizerID: f.ID,
+			NodeID:      node.ID,
+			NodeType:    node.Type,
+			Meta:        node.Meta,
+			HardDeleted: hard,
+			DeletedAt:   now,
+		}
+		m.pending.Add(1)
+		go func(f *Finalizer) {
+			defer m.pending.Done()
+			m.sem <- struct{}{}
+			defer func() { <-m.sem }()
+			if err := m.send(f.URL, f.Secret, event); err != nil {
+				m.addFailure(f.URL, event, err)
+			}
+		}(f)
+	}
+}
+
+// send posts an event, retrying with backoff
+func (m *Manager) send(url, secret string, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt*attempt) * m.backoff)
+		}
+
+		req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Memex-Event", EventFinalized)
+		if secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(payload)
+			req.Header.Set("X-Memex-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+
+		resp, err := m.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return lastErr
+}
+
+// addFailure records a delivery that failed after all retries
+func (m *Manager) addFailure(url string, event Event, err error) {
+	m.fmu.Lock()
+	defer m.fmu.Unlock()
+
+	m.failures = append(m.failures, &Failure{
+		ID:       uuid.New().String(),
+		URL:      url,
+		Event:    event,
+		Error:    err.Error(),
+		Attempts: 1,
+		FailedAt: time.Now(),
+	})
+	if len(m.failures) > maxFailures {
+		m.failures = m.failures[len(m.failures)-maxFailures:]
+	}
+	log.Printf("Finalizer %s failed for node %s: %v", event.FinalizerID, event.NodeID, err)
+}
+
+// Failures lists failed deliveries, oldest first, optionally for one finalizer
+func (m *Manager) Failures(finalizerID string) []*Failure {
+	m.fmu.Lock()
+	defer m.fmu.Unlock()
+
+	result := make([]*Failure, 0, len(m.failures))
+	for _, f := range m.failures {
+		if finalizerID == "" || f.Event.FinalizerID == finalizerID {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// RetryFailure re-sends a failed delivery to the finalizer's current URL,
+// or the original one if the finalizer was removed. It is dropped from the
+// list on success and kept, with the new error, on failure.
+func (m *Manager) RetryFailure(id string) (*Failure, error) {
+	m.fmu.Lock()
+	var failure *Failure
+	for _, f := range m.failures {
+		if f.ID == id {
+			failure = f
+			break
+		}
+	}
+	m.fmu.Unlock()
+	if failure == nil {
+		return nil, ErrFailureNotFound
+	}
+
+	url, secret := failure.URL, ""
+	if f, err := m.Get(failure.Event.FinalizerID); err == nil {
+		url, secret = f.URL, f.Secret
+	}
+	err := m.send(url, secret, failure.Event)
+
+	m.fmu.Lock()
+	defer m.fmu.Unlock()
+	if err == nil {
+		m.remove(id)
+		return failure, nil
+	}
+	failure.URL = url
+	failure.Error = err.Error()
+	failure.Attempts++
+	failure.FailedAt = time.Now()
+	return failure, err
+}
+
+// DeleteFailure dismisses a failed delivery
+func (m *Manager) DeleteFailure(id string) error {
+	m.fmu.Lock()
+	defer m.fmu.Unlock()
+	if !m.remove(id) {
+		return ErrFailureNotFound
+	}
+	return nil
+}
+
+// remove drops a failure from the list; callers hold m.fmu
+func (m *Manager) remove(id string) bool {
+	for i, f := range m.failures {
+		if f.ID == id {
+			m.failures = append(m.failures[:i], m.failures[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Wait blocks until webhook calls in flight finish
+func (m *Manager) Wait() {
+	m.pending.Wait()
+}
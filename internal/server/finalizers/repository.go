@@ -0,0 +1,36 @@
+package finalizers
+
+import (
+	"context"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// repository wraps a Repository, firing finalizers for the nodes it deletes
+type repository struct {
+	graph.Repository
+	m *Manager
+}
+
+// Wrap returns repo with the manager's finalizers fired after each delete
+// of a node they watch
+func Wrap(repo graph.Repository, m *Manager) graph.Repository {
+	return &repository{Repository: repo, m: m}
+}
+
+func (r *repository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	if r.m.empty() {
+		return r.Repository.DeleteNode(ctx, nodeID, force)
+	}
+	// The tombstone keeps no properties, so read them while the node is live
+	node, err := r.Repository.GetNode(ctx, nodeID)
+	if err != nil {
+		return r.Repository.DeleteNode(ctx, nodeID, force)
+	}
+	list := r.m.matching(node.Type, force)
+	if err := r.Repository.DeleteNode(ctx, nodeID, force); err != nil {
+		return err
+	}
+	r.m.fire(list, node, force)
+	return nil
+}
@@ -0,0 +1,168 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the public GitHub REST API
+const DefaultAPIURL = "https://api.github.com"
+
+// maxPages caps how many pages one listing follows
+const maxPages = 100
+
+// Client calls the GitHub REST API
+type Client struct {
+	BaseURL    string // Default DefaultAPIURL; GitHub Enterprise uses https://HOST/api/v3
+	Token      string // Personal access or app token; public repositories work without
+	HTTPClient *http.Client
+}
+
+// User is a GitHub account
+type User struct {
+	Login   string `json:"login"`
+	HTMLURL string `json:"html_url"`
+	Type    string `json:"type"` // User, Bot or Organization
+}
+
+// Label is an issue label
+type Label struct {
+	Name string `json:"name"`
+}
+
+// Issue is an issue, or a pull request as the issues API lists it
+type Issue struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	State       string     `json:"state"`
+	HTMLURL     string     `json:"html_url"`
+	User        *User      `json:"user"`
+	Labels      []Label    `json:"labels"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	PullRequest *struct{}  `json:"pull_request"` // Set when the issue is a pull request
+}
+
+// PullRequest is a pull request
+type PullRequest struct {
+	Issue
+	Draft          bool       `json:"draft"`
+	Merged         bool       `json:"merged"`
+	MergedAt       *time.Time `json:"merged_at"`
+	MergeCommitSHA string     `json:"merge_commit_sha"`
+	Head           struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// Review is a pull request review
+type Review struct {
+	ID          int64     `json:"id"`
+	User        *User     `json:"user"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"` // APPROVED, CHANGES_REQUESTED, COMMENTED, ...
+	HTMLURL     string    `json:"html_url"`
+	CommitID    string    `json:"commit_id"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// Commit is a commit in a pull request
+type Commit struct {
+	SHA string `json:"sha"`
+}
+
+// File is a file a pull request changes
+type File struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+}
+
+// nextLink finds the next page in a Link header
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// get decodes one API response into out
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	_, err := c.do(ctx, c.url(path, query), out)
+	return err
+}
+
+// list decodes every page of a listing into out, a pointer to a slice
+func (c *Client) list(ctx context.Context, path string, query url.Values, out interface{}) error {
+	var all []json.RawMessage
+	next := c.url(path, query)
+	for i := 0; next != "" && i < maxPages; i++ {
+		var items []json.RawMessage
+		link, err := c.do(ctx, next, &items)
+		if err != nil {
+			return err
+		}
+		all = append(all, items...)
+		next = ""
+		if m := nextLink.FindStringSubmatch(link); m != nil {
+			next = m[1]
+		}
+	}
+	if all == nil {
+		all = []json.RawMessage{}
+	}
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	base := strings.TrimRight(c.BaseURL, "/")
+	if base == "" {
+		base = DefaultAPIURL
+	}
+	u := base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// do performs a GET, returning the response's Link header
+func (c *Client) do(ctx context.Context, u string, out interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calling GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("GitHub returned status %d for %s: %s", resp.StatusCode, req.URL.Path, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return "", fmt.Errorf("decoding GitHub response: %w", err)
+	}
+	return resp.Header.Get("Link"), nil
+}
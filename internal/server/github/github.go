@@ -0,0 +1,397 @@
+// Package github ingests GitHub issues, pull requests and reviews, with
+// their authors, as graph nodes. Repositories sync incrementally from the
+// REST API, and webhook deliveries apply changes as they happen. Issues
+// and pull requests link REFERENCES to the Commit and File nodes a git
+// connector has ingested.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Node and link types
+const (
+	IssueType       = "Issue"
+	PullRequestType = "PullRequest"
+	ReviewType      = "Review"
+	UserType        = "GitHubUser"
+	SyncStateType   = "GitHubSync" // Per-repository sync cursor
+
+	// Node types looked up, not created, for REFERENCES links
+	CommitType = "Commit" // Matched on its sha property
+	FileType   = "File"   // Matched on its path (and repo, when set) property
+
+	AuthoredByLink = "AUTHORED_BY" // Issue, pull request or review to its author
+	ReviewsLink    = "REVIEWS"     // Review to its pull request
+	ReferencesLink = "REFERENCES"  // To commits, files, issues and pull requests
+)
+
+// Connector is recorded on every node the package writes
+const Connector = "github"
+
+// changedBy is recorded on updates
+const changedBy = "github-sync"
+
+var validRepo = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// Mentions of full commit SHAs and same-repository #numbers in bodies
+var (
+	shaRef   = regexp.MustCompile(`\b[0-9a-f]{40}\b`)
+	issueRef = regexp.MustCompile(`(?:^|[^\w/])#(\d+)\b`)
+)
+
+// Result summarizes a sync or webhook delivery
+type Result struct {
+	Repo      string `json:"repo"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Links     int    `json:"links"`                    // Links created
+	SyncedTo  string `json:"synced_through,omitempty"` // Sync cursor after a sync (RFC 3339)
+}
+
+// ParseRepo normalizes an owner/name repository reference
+func ParseRepo(repo string) (string, error) {
+	repo = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(repo), "https://github.com/"), ".git")
+	if !validRepo.MatchString(repo) {
+		return "", fmt.Errorf("invalid repository %q (use owner/name)", repo)
+	}
+	return repo, nil
+}
+
+// Node IDs. Repository names are lowercased since GitHub ignores case, and
+// the slash becomes a colon so IDs stay usable in URL paths.
+func repoKey(repo string) string { return strings.ReplaceAll(strings.ToLower(repo), "/", ":") }
+
+// IssueID returns the node ID of an issue
+func IssueID(repo string, number int) string {
+	return fmt.Sprintf("github:issue:%s:%d", repoKey(repo), number)
+}
+
+// PullRequestID returns the node ID of a pull request
+func PullRequestID(repo string, number int) string {
+	return fmt.Sprintf("github:pr:%s:%d", repoKey(repo), number)
+}
+
+// ReviewID returns the node ID of a review
+func ReviewID(repo string, id int64) string {
+	return fmt.Sprintf("github:review:%s:%d", repoKey(repo), id)
+}
+
+// UserID returns the node ID of an account
+func UserID(login string) string { return "github:user:" + strings.ToLower(login) }
+
+func stateID(repo string) string { return "github:sync:" + repoKey(repo) }
+
+// writer upserts nodes and links for one sync or delivery
+type writer struct {
+	repo   graph.Repository
+	result *Result
+	links  map[string]map[string]bool // Existing links by source, "type target"
+	found  map[string]string          // Commit and file lookups
+}
+
+func newWriter(repo graph.Repository, result *Result) *writer {
+	return &writer{repo: repo, result: result, links: make(map[string]map[string]bool), found: make(map[string]string)}
+}
+
+// upsert creates a node, or updates the properties that changed
+func (w *writer) upsert(ctx context.Context, id, nodeType string, meta map[string]interface{}) error {
+	meta["connector"] = Connector
+	existing, err := w.repo.GetNode(ctx, id)
+	if err != nil {
+		now := time.Now()
+		if err := w.repo.CreateNode(ctx, &core.Node{ID: id, Type: nodeType, Meta: meta, Created: now, Modified: now}); err != nil {
+			return fmt.Errorf("creating %s: %w", id, err)
+		}
+		w.result.Created++
+		return nil
+	}
+	changes := make(map[string]any)
+	for key, value := range meta {
+		if !sameValue(existing.Meta[key], value) {
+			changes[key] = value
+		}
+	}
+	if len(changes) == 0 {
+		w.result.Unchanged++
+		return nil
+	}
+	if err := w.repo.UpdateNodeMetaWithNote(ctx, id, changes, "GitHub sync", changedBy); err != nil {
+		return fmt.Errorf("updating %s: %w", id, err)
+	}
+	w.result.Updated++
+	return nil
+}
+
+// sameValue compares property values as stored, since lists and numbers
+// come back from JSON as []interface{} and float64
+func sameValue(a, b interface{}) bool {
+	x, err1 := json.Marshal(a)
+	y, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(x) == string(y)
+}
+
+// link creates a link unless it exists
+func (w *writer) link(ctx context.Context, source, target, linkType string) error {
+	existing, ok := w.links[source]
+	if !ok {
+		links, err := w.repo.GetLinks(ctx, source)
+		if err != nil {
+			return err
+		}
+		existing = make(map[string]bool, len(links))
+		for _, l := range links {
+			existing[l.Type+" "+l.Target] = true
+		}
+		w.links[source] = existing
+	}
+	if existing[linkType+" "+target] {
+		return nil
+	}
+	now := time.Now()
+	if err := w.repo.CreateLink(ctx, &core.Link{Source: source, Target: target, Type: linkType, Created: now, Modified: now}); err != nil {
+		return fmt.Errorf("linking %s to %s: %w", source, target, err)
+	}
+	existing[linkType+" "+target] = true
+	w.result.Links++
+	return nil
+}
+
+// user upserts an author and links from to it
+func (w *writer) user(ctx context.Context, from string, u *User) error {
+	if u == nil || u.Login == "" {
+		return nil
+	}
+	id := UserID(u.Login)
+	if err := w.upsert(ctx, id, UserType, map[string]interface{}{"login": u.Login, "url": u.HTMLURL, "account_type": u.Type}); err != nil {
+		return err
+	}
+	return w.link(ctx, from, id, AuthoredByLink)
+}
+
+// commit returns the ID of the ingested commit with a SHA, or ""
+func (w *writer) commit(ctx context.Context, sha string) (string, error) {
+	key := "commit " + sha
+	if id, ok := w.found[key]; ok {
+		return id, nil
+	}
+	nodes, err := w.repo.FilterNodes(ctx, []string{CommitType}, "sha", sha, 1, 0)
+	if err != nil {
+		return "", err
+	}
+	w.found[key] = ""
+	if len(nodes) > 0 {
+		w.found[key] = nodes[0].ID
+	}
+	return w.found[key], nil
+}
+
+// file returns the ID of the ingested file at a path, preferring one from
+// the same repository, or ""
+func (w *writer) file(ctx context.Context, repo, path string) (string, error) {
+	key := "file " + repo + " " + path
+	if id, ok := w.found[key]; ok {
+		return id, nil
+	}
+	nodes, err := w.repo.FilterNodes(ctx, []string{FileType}, "path", path, 10, 0)
+	if err != nil {
+		return "", err
+	}
+	w.found[key] = ""
+	for _, n := range nodes {
+		r, _ := n.Meta["repo"].(string)
+		if strings.EqualFold(r, repo) {
+			w.found[key] = n.ID
+			break
+		}
+		if r == "" && w.found[key] == "" {
+			w.found[key] = n.ID
+		}
+	}
+	return w.found[key], nil
+}
+
+// references links from to the commits and files it names that have been
+// ingested, and to the issues and pull requests its body mentions
+func (w *writer) references(ctx context.Context, repo, from, body string, shas, paths []string) error {
+	shas = append(shas, shaRef.FindAllString(body, -1)...)
+	seen := make(map[string]bool)
+	for _, sha := range shas {
+		if sha == "" || seen[sha] {
+			continue
+		}
+		seen[sha] = true
+		id, err := w.commit(ctx, sha)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			if err := w.link(ctx, from, id, ReferencesLink); err != nil {
+				return err
+			}
+		}
+	}
+	for _, path := range paths {
+		id, err := w.file(ctx, repo, path)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			if err := w.link(ctx, from, id, ReferencesLink); err != nil {
+				return err
+			}
+		}
+	}
+	for _, m := range issueRef.FindAllStringSubmatch(body, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		for _, id := range []string{IssueID(repo, n), PullRequestID(repo, n)} {
+			if id == from {
+				continue
+			}
+			if _, err := w.repo.GetNode(ctx, id); err == nil {
+				if err := w.link(ctx, from, id, ReferencesLink); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// issueMeta holds the properties issues and pull requests share
+func issueMeta(repo string, is *Issue) map[string]interface{} {
+	labels := make([]string, 0, len(is.Labels))
+	for _, l := range is.Labels {
+		labels = append(labels, l.Name)
+	}
+	meta := map[string]interface{}{
+		"repo":   repo,
+		"number": is.Number,
+		"title":  is.Title,
+		"body":   is.Body,
+		"state":  is.State,
+		"url":    is.HTMLURL,
+		"labels": labels,
+	}
+	if !is.CreatedAt.IsZero() {
+		meta["created_at"] = is.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if !is.UpdatedAt.IsZero() {
+		meta["updated_at"] = is.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	if is.User != nil {
+		meta["author"] = is.User.Login
+	}
+	if is.ClosedAt != nil {
+		meta["closed_at"] = is.ClosedAt.UTC().Format(time.RFC3339)
+	}
+	return meta
+}
+
+// applyIssue upserts an issue, its author and its references
+func (w *writer) applyIssue(ctx context.Context, repo string, is *Issue) error {
+	id := IssueID(repo, is.Number)
+	if err := w.upsert(ctx, id, IssueType, issueMeta(repo, is)); err != nil {
+		return err
+	}
+	if err := w.user(ctx, id, is.User); err != nil {
+		return err
+	}
+	return w.references(ctx, repo, id, is.Body, nil, nil)
+}
+
+// PullDetails are what a pull request's own listings add to it
+type PullDetails struct {
+	Reviews []Review
+	Commits []Commit
+	Files   []File
+}
+
+// applyPull upserts a pull request, its author, reviews and references
+func (w *writer) applyPull(ctx context.Context, repo string, pr *PullRequest, details *PullDetails) error {
+	id := PullRequestID(repo, pr.Number)
+	meta := issueMeta(repo, &pr.Issue)
+	meta["draft"] = pr.Draft
+	meta["merged"] = pr.Merged || pr.MergedAt != nil
+	meta["head"] = pr.Head.Ref
+	meta["base"] = pr.Base.Ref
+	if pr.MergedAt != nil {
+		meta["merged_at"] = pr.MergedAt.UTC().Format(time.RFC3339)
+		meta["merge_commit_sha"] = pr.MergeCommitSHA
+	}
+	if err := w.upsert(ctx, id, PullRequestType, meta); err != nil {
+		return err
+	}
+	if err := w.user(ctx, id, pr.User); err != nil {
+		return err
+	}
+
+	var shas, paths []string
+	if pr.MergedAt != nil {
+		shas = append(shas, pr.MergeCommitSHA)
+	}
+	if details != nil {
+		for _, c := range details.Commits {
+			shas = append(shas, c.SHA)
+		}
+		for _, f := range details.Files {
+			paths = append(paths, f.Filename)
+		}
+	}
+	if err := w.references(ctx, repo, id, pr.Body, shas, paths); err != nil {
+		return err
+	}
+	if details != nil {
+		for i := range details.Reviews {
+			if err := w.applyReview(ctx, repo, pr.Number, &details.Reviews[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyReview upserts a review, linked to its pull request and author
+func (w *writer) applyReview(ctx context.Context, repo string, number int, rv *Review) error {
+	if rv.ID == 0 {
+		return nil
+	}
+	id := ReviewID(repo, rv.ID)
+	meta := map[string]interface{}{
+		"repo":         repo,
+		"pull_request": number,
+		"state":        rv.State,
+		"body":         rv.Body,
+		"url":          rv.HTMLURL,
+		"commit_id":    rv.CommitID,
+	}
+	if !rv.SubmittedAt.IsZero() {
+		meta["submitted_at"] = rv.SubmittedAt.UTC().Format(time.RFC3339)
+	}
+	if rv.User != nil {
+		meta["author"] = rv.User.Login
+	}
+	if err := w.upsert(ctx, id, ReviewType, meta); err != nil {
+		return err
+	}
+	if err := w.link(ctx, id, PullRequestID(repo, number), ReviewsLink); err != nil {
+		return err
+	}
+	if err := w.user(ctx, id, rv.User); err != nil {
+		return err
+	}
+	return w.references(ctx, repo, id, rv.Body, []string{rv.CommitID}, nil)
+}
@@ -0,0 +1,199 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+const mergeSHA = "0123456789abcdef0123456789abcdef01234567"
+
+// fakeGitHub serves one issue and one pull request, the issues listing
+// split over two pages, and records the since parameter of each listing
+func fakeGitHub(t *testing.T, since *[]string) *httptest.Server {
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/repos/acme/Widget/issues", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		*since = append(*since, r.URL.Query().Get("since"))
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"number": 2, "title": "Fix crash", "body": "Fixes #1", "state": "closed",
+				"user": {"login": "Bob"}, "updated_at": "2026-03-02T00:00:00Z", "pull_request": {}}]`)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/acme/Widget/issues?page=2>; rel="next"`, srv.URL))
+		fmt.Fprint(w, `[{"number": 1, "title": "Crash on start", "body": "Seen at `+mergeSHA+`", "state": "open",
+			"user": {"login": "Ada"}, "labels": [{"name": "bug"}], "updated_at": "2026-03-01T00:00:00Z"}]`)
+	})
+	mux.HandleFunc("/repos/acme/Widget/pulls/2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number": 2, "title": "Fix crash", "body": "Fixes #1", "state": "closed", "user": {"login": "Bob"},
+			"updated_at": "2026-03-02T00:00:00Z", "merged_at": "2026-03-02T00:00:00Z", "merge_commit_sha": "`+mergeSHA+`",
+			"head": {"ref": "fix"}, "base": {"ref": "main"}}`)
+	})
+	mux.HandleFunc("/repos/acme/Widget/pulls/2/reviews", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": 77, "user": {"login": "Ada"}, "state": "APPROVED", "submitted_at": "2026-03-02T00:00:00Z"}]`)
+	})
+	mux.HandleFunc("/repos/acme/Widget/pulls/2/commits", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"sha": "`+mergeSHA+`"}]`)
+	})
+	mux.HandleFunc("/repos/acme/Widget/pulls/2/files", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"filename": "main.go"}, {"filename": "README.md"}]`)
+	})
+	srv = httptest.NewServer(mux)
+	return srv
+}
+
+func hasLink(t *testing.T, repo graph.Repository, source, target, linkType string) bool {
+	t.Helper()
+	links, err := repo.GetLinks(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range links {
+		if l.Target == target && l.Type == linkType {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	// What a git connector would have ingested
+	for _, n := range []*core.Node{
+		{ID: "commit:0123456", Type: CommitType, Meta: map[string]interface{}{"sha": mergeSHA}, Created: now, Modified: now},
+		{ID: "file:main.go", Type: FileType, Meta: map[string]interface{}{"path": "main.go", "repo": "acme/widget"}, Created: now, Modified: now},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var since []string
+	srv := fakeGitHub(t, &since)
+	defer srv.Close()
+	client := &Client{BaseURL: srv.URL, Token: "tok"}
+
+	res, err := Sync(ctx, repo, client, "acme/Widget", false)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	// Issue, PR, review and two users
+	if res.Created != 5 || res.SyncedTo != "2026-03-02T00:00:00Z" {
+		t.Errorf("Sync() = %+v", res)
+	}
+
+	issue, err := repo.GetNode(ctx, IssueID("acme/Widget", 1))
+	if err != nil || issue.Type != IssueType || issue.Meta["title"] != "Crash on start" || issue.Meta["connector"] != Connector {
+		t.Fatalf("issue = %+v, %v", issue, err)
+	}
+	pr := PullRequestID("acme/widget", 2)
+	review := ReviewID("acme/widget", 77)
+	for _, l := range []struct{ source, target, linkType string }{
+		{IssueID("acme/widget", 1), UserID("ada"), AuthoredByLink},
+		{IssueID("acme/widget", 1), "commit:0123456", ReferencesLink},
+		{pr, UserID("bob"), AuthoredByLink},
+		{pr, "commit:0123456", ReferencesLink},
+		{pr, "file:main.go", ReferencesLink},
+		{pr, IssueID("acme/widget", 1), ReferencesLink},
+		{review, pr, ReviewsLink},
+		{review, UserID("ada"), AuthoredByLink},
+	} {
+		if !hasLink(t, repo, l.source, l.target, l.linkType) {
+			t.Errorf("missing link %s -[%s]-> %s", l.source, l.linkType, l.target)
+		}
+	}
+
+	// The second sync asks only for what changed and finds nothing new
+	res, err = Sync(ctx, repo, client, "acme/Widget", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != 0 || res.Updated != 0 || res.Links != 0 {
+		t.Errorf("second Sync() = %+v", res)
+	}
+	if len(since) != 4 || since[0] != "" || since[2] != "2026-03-02T00:00:00Z" {
+		t.Errorf("since parameters = %q", since)
+	}
+	states, err := ListStates(ctx, repo)
+	if err != nil || len(states) != 1 || states[0].Repo != "acme/Widget" {
+		t.Errorf("ListStates() = %+v, %v", states, err)
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	body := `{"action": "submitted", "repository": {"full_name": "acme/widget"},
+		"pull_request": {"number": 5, "title": "Docs", "state": "open", "user": {"login": "Cy"}, "updated_at": "2026-03-03T00:00:00Z"},
+		"review": {"id": 9, "state": "COMMENTED", "body": "Looks fine", "user": {"login": "Ada"}}}`
+	res, err := HandleEvent(ctx, repo, nil, "pull_request_review", []byte(body))
+	if err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+	if res.Created != 4 || !hasLink(t, repo, ReviewID("acme/widget", 9), PullRequestID("acme/widget", 5), ReviewsLink) {
+		t.Errorf("HandleEvent() = %+v", res)
+	}
+
+	issue := `{"action": "edited", "repository": {"full_name": "acme/widget"},
+		"issue": {"number": 6, "title": "Renamed", "state": "open", "user": {"login": "Cy"}}}`
+	if _, err := HandleEvent(ctx, repo, nil, "issues", []byte(issue)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.GetNode(ctx, IssueID("acme/widget", 6)); err != nil || n.Meta["title"] != "Renamed" {
+		t.Errorf("issue = %+v, %v", n, err)
+	}
+
+	if _, err := HandleEvent(ctx, repo, nil, "star", []byte(`{}`)); !errors.Is(err, ErrIgnoredEvent) {
+		t.Errorf("HandleEvent(star) error = %v", err)
+	}
+	if _, err := HandleEvent(ctx, repo, nil, "issues", []byte(`{"repository": {"full_name": "acme/widget"}}`)); err == nil {
+		t.Error("HandleEvent() accepted an issues event without an issue")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"zen": "Keep it logically awesome."}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	sig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !VerifySignature("secret", body, sig) {
+		t.Error("VerifySignature() rejected a valid signature")
+	}
+	if VerifySignature("other", body, sig) || VerifySignature("secret", body, "") {
+		t.Error("VerifySignature() accepted a bad signature")
+	}
+}
+
+func TestParseRepo(t *testing.T) {
+	for in, want := range map[string]string{
+		"acme/widget":                        "acme/widget",
+		"https://github.com/acme/widget.git": "acme/widget",
+		" acme/widget.js ":                   "acme/widget.js",
+	} {
+		if got, err := ParseRepo(in); err != nil || got != want {
+			t.Errorf("ParseRepo(%q) = %q, %v", in, got, err)
+		}
+	}
+	for _, bad := range []string{"", "widget", "acme/widget/issues", "acme/wid get"} {
+		if _, err := ParseRepo(bad); err == nil || !strings.Contains(err.Error(), "invalid repository") {
+			t.Errorf("ParseRepo(%q) error = %v", bad, err)
+		}
+	}
+}
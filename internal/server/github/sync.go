@@ -0,0 +1,282 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// ErrIgnoredEvent is returned for webhook events the connector does not handle
+var ErrIgnoredEvent = errors.New("event ignored")
+
+// State is a repository's sync cursor
+type State struct {
+	Repo          string    `json:"repo"`
+	SyncedThrough time.Time `json:"synced_through"` // Newest issue update applied
+	LastSync      time.Time `json:"last_sync"`
+}
+
+// Sync brings a repository's issues, pull requests and reviews up to date.
+// Only items updated since the last sync are fetched, unless full is set.
+// Items are applied oldest update first and the cursor advances as they
+// are, so a sync that fails part way resumes where it stopped.
+func Sync(ctx context.Context, repo graph.Repository, client *Client, name string, full bool) (*Result, error) {
+	name, err := ParseRepo(name)
+	if err != nil {
+		return nil, err
+	}
+	state, err := GetState(ctx, repo, name)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"state": {"all"}, "sort": {"updated"}, "direction": {"asc"}, "per_page": {"100"}}
+	if !full && !state.SyncedThrough.IsZero() {
+		query.Set("since", state.SyncedThrough.UTC().Format(time.RFC3339))
+	}
+	var issues []Issue
+	if err := client.list(ctx, "/repos/"+name+"/issues", query, &issues); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].UpdatedAt.Before(issues[j].UpdatedAt) })
+
+	result := &Result{Repo: name}
+	w := newWriter(repo, result)
+	for i := range issues {
+		is := &issues[i]
+		if is.PullRequest != nil {
+			err = syncPull(ctx, w, client, name, is.Number)
+		} else {
+			err = w.applyIssue(ctx, name, is)
+		}
+		if err != nil {
+			err = fmt.Errorf("%s#%d: %w", name, is.Number, err)
+			break
+		}
+		if is.UpdatedAt.After(state.SyncedThrough) {
+			state.SyncedThrough = is.UpdatedAt
+		}
+	}
+
+	state.LastSync = time.Now()
+	if saveErr := saveState(ctx, repo, state); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	if !state.SyncedThrough.IsZero() {
+		result.SyncedTo = state.SyncedThrough.UTC().Format(time.RFC3339)
+	}
+	return result, err
+}
+
+// syncPull fetches a pull request with its reviews, commits and files
+func syncPull(ctx context.Context, w *writer, client *Client, name string, number int) error {
+	var pr PullRequest
+	if err := client.get(ctx, fmt.Sprintf("/repos/%s/pulls/%d", name, number), nil, &pr); err != nil {
+		return err
+	}
+	details, err := fetchDetails(ctx, client, name, number)
+	if err != nil {
+		return err
+	}
+	return w.applyPull(ctx, name, &pr, details)
+}
+
+func fetchDetails(ctx context.Context, client *Client, name string, number int) (*PullDetails, error) {
+	var details PullDetails
+	base := fmt.Sprintf("/repos/%s/pulls/%d", name, number)
+	page := url.Values{"per_page": {"100"}}
+	if err := client.list(ctx, base+"/reviews", page, &details.Reviews); err != nil {
+		return nil, err
+	}
+	if err := client.list(ctx, base+"/commits", page, &details.Commits); err != nil {
+		return nil, err
+	}
+	if err := client.list(ctx, base+"/files", page, &details.Files); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// webhookPayload is the part of an issues, pull_request or
+// pull_request_review delivery the connector reads
+type webhookPayload struct {
+	Issue       *Issue       `json:"issue"`
+	PullRequest *PullRequest `json:"pull_request"`
+	Review      *Review      `json:"review"`
+	Repository  struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// HandleEvent applies a webhook delivery (the X-GitHub-Event header and
+// body). Pull request events fetch the commits and files when client is
+// set; reviews arrive as their own events. The sync cursor is left alone,
+// so a later sync still catches anything a missed delivery carried.
+func HandleEvent(ctx context.Context, repo graph.Repository, client *Client, event string, body []byte) (*Result, error) {
+	switch event {
+	case "issues", "pull_request", "pull_request_review":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrIgnoredEvent, event)
+	}
+	var p webhookPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	name, err := ParseRepo(p.Repository.FullName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Repo: name}
+	w := newWriter(repo, result)
+	switch {
+	case event == "issues" && p.Issue != nil:
+		err = w.applyIssue(ctx, name, p.Issue)
+	case event == "pull_request" && p.PullRequest != nil:
+		var details *PullDetails
+		if client != nil {
+			if details, err = fetchDetails(ctx, client, name, p.PullRequest.Number); err != nil {
+				log.Printf("GitHub webhook: %s#%d commits and files not fetched: %v", name, p.PullRequest.Number, err)
+				details = nil
+			}
+		}
+		err = w.applyPull(ctx, name, p.PullRequest, details)
+	case event == "pull_request_review" && p.PullRequest != nil && p.Review != nil:
+		if err = w.applyPull(ctx, name, p.PullRequest, nil); err == nil {
+			err = w.applyReview(ctx, name, p.PullRequest.Number, p.Review)
+		}
+	default:
+		return nil, fmt.Errorf("invalid payload: %s event without its object", event)
+	}
+	return result, err
+}
+
+// GetState returns a repository's sync cursor; a repository never synced
+// has a zero one
+func GetState(ctx context.Context, repo graph.Repository, name string) (*State, error) {
+	state := &State{Repo: name}
+	node, err := repo.GetNode(ctx, stateID(name))
+	if err != nil {
+		return state, nil
+	}
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("corrupt sync state %s: %w", node.ID, err)
+	}
+	return state, nil
+}
+
+// ListStates returns the cursors of every repository synced
+func ListStates(ctx context.Context, repo graph.Repository) ([]*State, error) {
+	nodes, err := repo.FilterNodes(ctx, []string{SyncStateType}, "", "", 1000, 0)
+	if err != nil {
+		return nil, err
+	}
+	states := []*State{}
+	for _, n := range nodes {
+		name, _ := n.Meta["repo"].(string)
+		state, err := GetState(ctx, repo, name)
+		if err != nil || name == "" {
+			continue
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Repo < states[j].Repo })
+	return states, nil
+}
+
+func saveState(ctx context.Context, repo graph.Repository, state *State) error {
+	meta := map[string]interface{}{
+		"repo":           state.Repo,
+		"synced_through": state.SyncedThrough.UTC().Format(time.RFC3339Nano),
+		"last_sync":      state.LastSync.UTC().Format(time.RFC3339Nano),
+	}
+	id := stateID(state.Repo)
+	if _, err := repo.GetNode(ctx, id); err == nil {
+		return repo.UpdateNodeMeta(ctx, id, meta)
+	}
+	now := time.Now()
+	return repo.CreateNode(ctx, &core.Node{ID: id, Type: SyncStateType, Meta: meta, Created: now, Modified: now})
+}
+
+// Syncer syncs repositories periodically
+type Syncer struct {
+	repo     graph.Repository
+	client   *Client
+	repos    []string
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSyncer creates a syncer that runs every interval
+func NewSyncer(repo graph.Repository, client *Client, repos []string, interval time.Duration) *Syncer {
+	return &Syncer{repo: repo, client: client, repos: repos, interval: interval, stop: make(chan struct{})}
+}
+
+// Start syncs once, then periodically
+func (s *Syncer) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.sync()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sync()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("GitHub sync started for %d repositories (every %s)", len(s.repos), s.interval)
+}
+
+// Stop halts periodic syncs and waits for a running sync to finish
+func (s *Syncer) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Syncer) sync() {
+	for _, name := range s.repos {
+		result, err := Sync(context.Background(), s.repo, s.client, name, false)
+		if err != nil {
+			log.Printf("GitHub sync of %s failed: %v", name, err)
+			continue
+		}
+		if result.Created > 0 || result.Updated > 0 {
+			log.Printf("GitHub sync of %s: %d nodes created, %d updated, %d links", name, result.Created, result.Updated, result.Links)
+		}
+	}
+}
+
+// VerifySignature checks a delivery's X-Hub-Signature-256 header against
+// the webhook secret
+func VerifySignature(secret string, body []byte, signature string) bool {
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || len(got) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
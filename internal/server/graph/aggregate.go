@@ -0,0 +1,199 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// aggregatePageSize is how many nodes an aggregation reads per FilterNodes call
+const aggregatePageSize = 1000
+
+// MaxAggregateGroupBy caps the number of group-by keys
+const MaxAggregateGroupBy = 3
+
+// AggregateGroupByType groups by node type rather than a property
+const AggregateGroupByType = "type"
+
+// AggregateGroupByLayer groups by node layer rather than a property
+const AggregateGroupByLayer = "layer"
+
+// AggregateQuery selects nodes and how to summarize them
+type AggregateQuery struct {
+	Types   []string    // Node types to include; empty includes all
+	Layers  LayerFilter // Node layers to include; nil includes all
+	GroupBy []string    // "type", "layer" or property names
+	Field   string      // Numeric property for sum/avg/min/max; empty counts only
+	Bucket  string      // Optional time bucket (day or week) on the creation time
+	From    time.Time   // Optional creation window; zero values are unbounded
+	To      time.Time
+}
+
+// Aggregate summarizes the selected nodes per group and time bucket
+type Aggregate struct {
+	GroupBy []string         `json:"group_by"`
+	Field   string           `json:"field,omitempty"`
+	Bucket  string           `json:"bucket,omitempty"`
+	Groups  []AggregateGroup `json:"groups"`
+	Total   int              `json:"total"` // Nodes aggregated
+}
+
+// AggregateGroup holds the statistics for one combination of group values
+type AggregateGroup struct {
+	Key    map[string]interface{} `json:"key"`              // Group-by key -> value (null when missing)
+	Bucket *time.Time             `json:"bucket,omitempty"` // Start of the time bucket
+	Count  int                    `json:"count"`
+	Values int                    `json:"values,omitempty"` // Nodes with a numeric Field
+	Sum    *float64               `json:"sum,omitempty"`
+	Avg    *float64               `json:"avg,omitempty"`
+	Min    *float64               `json:"min,omitempty"`
+	Max    *float64               `json:"max,omitempty"`
+}
+
+// Validate checks an aggregate query's options
+func (q *AggregateQuery) Validate() error {
+	if len(q.GroupBy) > MaxAggregateGroupBy {
+		return fmt.Errorf("too many group_by keys (max %d)", MaxAggregateGroupBy)
+	}
+	for _, key := range q.GroupBy {
+		if key == "" {
+			return fmt.Errorf("empty group_by key")
+		}
+	}
+	if q.Bucket != "" {
+		return ValidateBucket(q.Bucket)
+	}
+	return nil
+}
+
+// RunAggregate scans the selected nodes and groups them by type, property
+// values and time bucket, with count and, when Field is set, sum, average,
+// minimum and maximum of its numeric values
+func RunAggregate(ctx context.Context, repo Repository, q AggregateQuery) (*Aggregate, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	out := &Aggregate{GroupBy: q.GroupBy, Field: q.Field, Bucket: q.Bucket, Groups: []AggregateGroup{}}
+	if out.GroupBy == nil {
+		out.GroupBy = []string{}
+	}
+	groups := make(map[string]*AggregateGroup)
+	add := func(n *core.Node) {
+		if !q.From.IsZero() && n.Created.Before(q.From) || !q.To.IsZero() && n.Created.After(q.To) || !q.Layers.Node(n) {
+			return
+		}
+		key := make(map[string]interface{}, len(q.GroupBy))
+		for _, k := range q.GroupBy {
+			if k == AggregateGroupByType {
+				key[k] = n.Type
+			} else if k == AggregateGroupByLayer {
+				key[k] = NodeLayer(n)
+			} else {
+				key[k] = n.Meta[k]
+			}
+		}
+		var bucket *time.Time
+		if q.Bucket != "" {
+			loc := time.Local
+			if !q.From.IsZero() {
+				loc = q.From.Location()
+			}
+			start := bucketStart(n.Created.In(loc), q.Bucket)
+			bucket = &start
+		}
+		id, _ := json.Marshal([]interface{}{key, bucket})
+		g := groups[string(id)]
+		if g == nil {
+			g = &AggregateGroup{Key: key, Bucket: bucket}
+			groups[string(id)] = g
+		}
+		g.Count++
+		out.Total++
+
+		if q.Field == "" {
+			return
+		}
+		v, ok := numericValue(n.Meta[q.Field])
+		if !ok {
+			return
+		}
+		g.Values++
+		if g.Sum == nil {
+			g.Sum, g.Min, g.Max = new(float64), new(float64), new(float64)
+			*g.Min, *g.Max = v, v
+		}
+		*g.Sum += v
+		if v < *g.Min {
+			*g.Min = v
+		}
+		if v > *g.Max {
+			*g.Max = v
+		}
+	}
+
+	for offset := 0; ; offset += aggregatePageSize {
+		nodes, err := repo.FilterNodes(ctx, q.Types, "", "", aggregatePageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			add(n)
+		}
+		if len(nodes) < aggregatePageSize {
+			break
+		}
+	}
+
+	for _, g := range groups {
+		if g.Values > 0 {
+			avg := *g.Sum / float64(g.Values)
+			g.Avg = &avg
+		}
+		out.Groups = append(out.Groups, *g)
+	}
+	sort.Slice(out.Groups, func(i, j int) bool {
+		a, b := out.Groups[i], out.Groups[j]
+		if a.Bucket != nil && !a.Bucket.Equal(*b.Bucket) {
+			return a.Bucket.Before(*b.Bucket)
+		}
+		for _, k := range q.GroupBy {
+			ka, kb := fmt.Sprint(a.Key[k]), fmt.Sprint(b.Key[k])
+			if ka != kb {
+				return ka < kb
+			}
+		}
+		return false
+	})
+	return out, nil
+}
+
+// numericValue reads a property as a number; numeric strings count too,
+// since extracted properties are often stored as text
+func numericValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestRunAggregate(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	monday := time.Date(2025, 11, 3, 10, 0, 0, 0, time.UTC)
+
+	for _, n := range []*core.Node{
+		{ID: "doc:1", Type: "Document", Meta: map[string]any{"source": "gmail", "size": 10}, Created: monday},
+		{ID: "doc:2", Type: "Document", Meta: map[string]any{"source": "gmail", "size": "30"}, Created: monday.AddDate(0, 0, 2)},
+		{ID: "doc:3", Type: "Document", Meta: map[string]any{"source": "gmail", "size": "n/a"}, Created: monday.AddDate(0, 0, 7)},
+		{ID: "doc:4", Type: "Document", Meta: map[string]any{"source": "slack"}, Created: monday},
+		{ID: "note:1", Type: "Note", Meta: map[string]any{"source": "gmail", "size": 99}, Created: monday},
+	} {
+		n.Modified = n.Created
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	agg, err := RunAggregate(ctx, repo, AggregateQuery{
+		Types:   []string{"Document"},
+		GroupBy: []string{"source"},
+		Field:   "size",
+		Bucket:  BucketWeek,
+		From:    monday.AddDate(0, 0, -1),
+		To:      monday.AddDate(0, 0, 30),
+	})
+	if err != nil {
+		t.Fatalf("RunAggregate: %v", err)
+	}
+	if agg.Total != 4 || len(agg.Groups) != 3 {
+		t.Fatalf("got %d nodes in %d groups, want 4 in 3: %+v", agg.Total, len(agg.Groups), agg.Groups)
+	}
+	first := agg.Groups[0]
+	if first.Key["source"] != "gmail" || !first.Bucket.Equal(time.Date(2025, 11, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("first group = %v %v, want gmail in the week of Nov 3", first.Key, first.Bucket)
+	}
+	if first.Count != 2 || first.Values != 2 || *first.Sum != 40 || *first.Avg != 20 || *first.Min != 10 || *first.Max != 30 {
+		t.Errorf("first group stats = %+v, want count 2, sum 40, avg 20, min 10, max 30", first)
+	}
+	if g := agg.Groups[1]; g.Key["source"] != "slack" || g.Values != 0 || g.Sum != nil {
+		t.Errorf("second group = %+v, want slack without numeric stats", g)
+	}
+	if g := agg.Groups[2]; g.Count != 1 || g.Values != 0 {
+		t.Errorf("third group = %+v, want the following week's gmail document with no numeric size", g)
+	}
+
+	agg, err = RunAggregate(ctx, repo, AggregateQuery{GroupBy: []string{AggregateGroupByType}})
+	if err != nil || len(agg.Groups) != 2 || agg.Groups[0].Key["type"] != "Document" || agg.Groups[0].Count != 4 {
+		t.Errorf("group by type = %+v, %v; want 4 Documents and 1 Note", agg, err)
+	}
+
+	if _, err := RunAggregate(ctx, repo, AggregateQuery{Bucket: "month"}); err == nil {
+		t.Error("expected an unsupported bucket to be rejected")
+	}
+}
@@ -0,0 +1,438 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// Properties holding a node's human-friendly names
+const (
+	// AliasKey holds a node's custom slug, such as person/ada-lovelace
+	AliasKey = "alias"
+	// ShortIDKey holds a node's short ID, such as k3xq7m2a
+	ShortIDKey = "short_id"
+)
+
+// ShortIDLength is the length of a short ID that collides with no other;
+// colliding ones grow two characters at a time
+const ShortIDLength = 8
+
+var (
+	// ErrInvalidAlias is returned for a malformed alias, or for setting the
+	// alias or short ID properties directly
+	ErrInvalidAlias = errors.New("invalid alias")
+	// ErrAliasTaken is returned when an alias already names another node
+	ErrAliasTaken = errors.New("alias already in use")
+)
+
+// aliasPattern is lowercase words joined by "/", at least two of them, so
+// an alias can never be mistaken for a node or short ID
+var aliasPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*(/[a-z0-9][a-z0-9._-]*)+$`)
+
+var shortIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ShortIDFor returns the short ID a node ID gets when nothing collides
+// with it: base32 of the first bytes of the ID's SHA-256
+func ShortIDFor(id string) string {
+	return shortIDCandidates(id)[0]
+}
+
+// shortIDCandidates lists a node's possible short IDs, shortest first
+func shortIDCandidates(id string) []string {
+	sum := sha256.Sum256([]byte(id))
+	full := strings.ToLower(shortIDEncoding.EncodeToString(sum[:]))
+	var out []string
+	for n := ShortIDLength; n <= len(full); n += 2 {
+		out = append(out, full[:n])
+	}
+	return out
+}
+
+// NormalizeAlias lowercases an alias and checks its form. "%2F" is
+// accepted for "/", as aliases arrive escaped in URL paths.
+func NormalizeAlias(alias string) (string, error) {
+	if unescaped, err := url.PathUnescape(alias); err == nil {
+		alias = unescaped
+	}
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	if !aliasPattern.MatchString(alias) {
+		return "", fmt.Errorf("%w: %q must be lowercase words joined by \"/\", like person/ada-lovelace", ErrInvalidAlias, alias)
+	}
+	return alias, nil
+}
+
+// Aliases assigns short IDs and aliases to nodes and resolves them
+type Aliases struct {
+	repo     Repository // The wrapped repository, which sees only full IDs
+	shortIDs bool
+
+	mu sync.Mutex // Serializes uniqueness checks with the writes they guard
+}
+
+// aliasRepository accepts an alias or short ID anywhere a node ID is
+// expected, passing the node's full ID on. IDs containing ":" are always
+// full IDs and pass through untouched.
+type aliasRepository struct {
+	Repository
+	aliases *Aliases
+}
+
+// WithAliases wraps repo so node IDs may be given as aliases or short IDs.
+// With shortIDs set, every new node is given a short ID.
+func WithAliases(repo Repository, shortIDs bool) (Repository, *Aliases) {
+	a := &Aliases{repo: repo, shortIDs: shortIDs}
+	return &aliasRepository{Repository: repo, aliases: a}, a
+}
+
+// ShortIDs reports whether new nodes are given short IDs
+func (a *Aliases) ShortIDs() bool {
+	return a.shortIDs
+}
+
+// Resolve returns the full ID of the node id names, or id itself when it
+// names none
+func (a *Aliases) Resolve(ctx context.Context, id string) string {
+	if id == "" || strings.Contains(id, ":") {
+		return id
+	}
+	if alias, err := NormalizeAlias(id); err == nil {
+		if owner, _ := a.lookup(ctx, AliasKey, alias); owner != "" {
+			return owner
+		}
+		return id
+	}
+	if _, err := a.repo.GetNode(ctx, id); err == nil {
+		return id
+	}
+	if owner, _ := a.lookup(ctx, ShortIDKey, strings.ToLower(id)); owner != "" {
+		return owner
+	}
+	return id
+}
+
+// lookup returns the ID of the node whose property key is value, or ""
+func (a *Aliases) lookup(ctx context.Context, key, value string) (string, error) {
+	nodes, err := a.repo.FilterNodes(ctx, nil, key, value, 10, 0)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range nodes {
+		if v, _ := n.Meta[key].(string); v == value {
+			return n.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// SetAlias gives a node an alias, replacing any it had. An empty alias
+// removes it.
+func (a *Aliases) SetAlias(ctx context.Context, id, alias, changedBy string) (string, error) {
+	if alias != "" {
+		var err error
+		if alias, err = NormalizeAlias(alias); err != nil {
+			return "", err
+		}
+	}
+	id = a.Resolve(ctx, id)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	node, err := a.repo.GetNode(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if current, _ := node.Meta[AliasKey].(string); current == alias {
+		return alias, nil
+	}
+	if alias != "" {
+		if err := a.checkAlias(ctx, id, alias); err != nil {
+			return "", err
+		}
+	}
+	note := "set alias " + alias
+	if alias == "" {
+		note = "remove alias"
+	}
+	if err := a.repo.UpdateNodeMetaWithNote(ctx, id, map[string]any{AliasKey: alias}, note, changedBy); err != nil {
+		return "", err
+	}
+	return alias, nil
+}
+
+// checkAlias returns ErrAliasTaken if alias names a node other than id
+func (a *Aliases) checkAlias(ctx context.Context, id, alias string) error {
+	owner, err := a.lookup(ctx, AliasKey, alias)
+	if err != nil {
+		return err
+	}
+	if owner != "" && owner != id {
+		return fmt.Errorf("%w: %s names %s", ErrAliasTaken, alias, owner)
+	}
+	return nil
+}
+
+// shortID returns the shortest candidate short ID for id that no other
+// node, nor any in taken, already has
+func (a *Aliases) shortID(ctx context.Context, id string, taken map[string]bool) (string, error) {
+	candidates := shortIDCandidates(id)
+	for _, candidate := range candidates {
+		if taken[candidate] {
+			continue
+		}
+		owner, err := a.lookup(ctx, ShortIDKey, candidate)
+		if err != nil {
+			return "", err
+		}
+		if owner == "" || owner == id {
+			return candidate, nil
+		}
+	}
+	return candidates[len(candidates)-1], nil
+}
+
+// prepare checks a new node's alias and gives it a short ID; taken holds
+// the names claimed earlier in the same batch
+func (a *Aliases) prepare(ctx context.Context, node *core.Node, taken map[string]bool) error {
+	if raw, ok := node.Meta[AliasKey].(string); ok && raw != "" {
+		alias, err := NormalizeAlias(raw)
+		if err != nil {
+			return err
+		}
+		if taken[alias] {
+			return fmt.Errorf("%w: %s", ErrAliasTaken, alias)
+		}
+		if err := a.checkAlias(ctx, node.ID, alias); err != nil {
+			return err
+		}
+		node.Meta[AliasKey] = alias
+		taken[alias] = true
+	}
+	// A restored node keeps its short ID unless another node took it
+	if short, ok := node.Meta[ShortIDKey].(string); ok {
+		owner, err := a.lookup(ctx, ShortIDKey, short)
+		if err != nil {
+			return err
+		}
+		if short != "" && !taken[short] && (owner == "" || owner == node.ID) {
+			taken[short] = true
+			return nil
+		}
+		delete(node.Meta, ShortIDKey)
+	}
+	if a.shortIDs {
+		short, err := a.shortID(ctx, node.ID, taken)
+		if err != nil {
+			return err
+		}
+		node.Meta[ShortIDKey] = short
+		taken[short] = true
+	}
+	return nil
+}
+
+// AssignShortIDs gives a short ID to every node that lacks one, returning
+// how many were assigned. Each assignment adds a version to its node.
+func (a *Aliases) AssignShortIDs(ctx context.Context) (int, error) {
+	ids, err := a.repo.ListNodes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	assigned := 0
+	taken := make(map[string]bool)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return assigned, err
+		}
+		node, err := a.repo.GetNode(ctx, id)
+		if err != nil {
+			continue
+		}
+		if short, _ := node.Meta[ShortIDKey].(string); short != "" {
+			continue
+		}
+		short, err := a.shortID(ctx, id, taken)
+		if err != nil {
+			return assigned, err
+		}
+		if err := a.repo.UpdateNodeMetaWithNote(ctx, id, map[string]any{ShortIDKey: short}, "assign short ID", ""); err != nil {
+			return assigned, fmt.Errorf("assigning short ID to %s: %w", id, err)
+		}
+		taken[short] = true
+		assigned++
+	}
+	return assigned, nil
+}
+
+// checkMeta rejects updates that change the alias or short ID properties,
+// which only SetAlias and AssignShortIDs change. Updates carrying their
+// current values, as when a whole node is written back, have them dropped.
+func (r *aliasRepository) checkMeta(ctx context.Context, id string, meta map[string]any) (map[string]any, error) {
+	_, hasAlias := meta[AliasKey]
+	_, hasShort := meta[ShortIDKey]
+	if !hasAlias && !hasShort {
+		return meta, nil
+	}
+	node, err := r.Repository.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[string]any, len(meta))
+	for k, v := range meta {
+		kept[k] = v
+	}
+	for _, key := range []string{AliasKey, ShortIDKey} {
+		if v, ok := meta[key]; ok {
+			if v != node.Meta[key] {
+				return nil, fmt.Errorf("%w: %s cannot be updated directly", ErrInvalidAlias, key)
+			}
+			delete(kept, key)
+		}
+	}
+	return kept, nil
+}
+
+func (r *aliasRepository) CreateNode(ctx context.Context, node *core.Node) error {
+	if node.Meta == nil {
+		node.Meta = make(map[string]any)
+	}
+	r.aliases.mu.Lock()
+	defer r.aliases.mu.Unlock()
+	if err := r.aliases.prepare(ctx, node, make(map[string]bool)); err != nil {
+		return err
+	}
+	return r.Repository.CreateNode(ctx, node)
+}
+
+func (r *aliasRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	r.aliases.mu.Lock()
+	defer r.aliases.mu.Unlock()
+	taken := make(map[string]bool)
+	for _, node := range nodes {
+		if node.Meta == nil {
+			node.Meta = make(map[string]any)
+		}
+		if err := r.aliases.prepare(ctx, node, taken); err != nil {
+			return fmt.Errorf("%s: %w", node.ID, err)
+		}
+	}
+	return r.Repository.CreateNodes(ctx, nodes)
+}
+
+func (r *aliasRepository) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	node, err := r.Repository.GetNode(ctx, id)
+	if err == nil || strings.Contains(id, ":") {
+		return node, err
+	}
+	if full := r.aliases.Resolve(ctx, id); full != id {
+		return r.Repository.GetNode(ctx, full)
+	}
+	return node, err
+}
+
+func (r *aliasRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	return r.Repository.GetLinks(ctx, r.aliases.Resolve(ctx, nodeID))
+}
+
+func (r *aliasRepository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	return r.Repository.GetBacklinks(ctx, r.aliases.Resolve(ctx, nodeID))
+}
+
+func (r *aliasRepository) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
+	return r.Repository.TraverseGraph(ctx, r.aliases.Resolve(ctx, startNodeID), depth, relationshipTypes, limit, offset)
+}
+
+func (r *aliasRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	resolved := *link
+	resolved.Source = r.aliases.Resolve(ctx, link.Source)
+	resolved.Target = r.aliases.Resolve(ctx, link.Target)
+	return r.Repository.CreateLink(ctx, &resolved)
+}
+
+func (r *aliasRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	resolved := make([]*core.Link, len(links))
+	for i, link := range links {
+		l := *link
+		l.Source = r.aliases.Resolve(ctx, link.Source)
+		l.Target = r.aliases.Resolve(ctx, link.Target)
+		resolved[i] = &l
+	}
+	return r.Repository.CreateLinks(ctx, resolved)
+}
+
+func (r *aliasRepository) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
+	return r.Repository.DeleteLink(ctx, r.aliases.Resolve(ctx, sourceID), r.aliases.Resolve(ctx, targetID), linkType)
+}
+
+func (r *aliasRepository) GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error) {
+	return r.Repository.GetNodeAtVersion(ctx, r.aliases.Resolve(ctx, id), version)
+}
+
+func (r *aliasRepository) GetNodeAtTime(ctx context.Context, id string, asOf time.Time) (*core.Node, error) {
+	return r.Repository.GetNodeAtTime(ctx, r.aliases.Resolve(ctx, id), asOf)
+}
+
+func (r *aliasRepository) GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error) {
+	return r.Repository.GetNodeHistory(ctx, r.aliases.Resolve(ctx, id))
+}
+
+func (r *aliasRepository) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	id = r.aliases.Resolve(ctx, id)
+	meta, err := r.checkMeta(ctx, id, meta)
+	if err != nil {
+		return err
+	}
+	return r.Repository.UpdateNodeMeta(ctx, id, meta)
+}
+
+func (r *aliasRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	id = r.aliases.Resolve(ctx, id)
+	meta, err := r.checkMeta(ctx, id, meta)
+	if err != nil {
+		return err
+	}
+	return r.Repository.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy)
+}
+
+func (r *aliasRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	return r.Repository.DeleteNode(ctx, r.aliases.Resolve(ctx, nodeID), force)
+}
+
+func (r *aliasRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	return r.Repository.UpdateAttentionEdge(ctx, r.aliases.Resolve(ctx, source), r.aliases.Resolve(ctx, target), queryID, weight)
+}
+
+func (r *aliasRepository) GetAttentionSubgraph(ctx context.Context, startNodeID string, minWeight float64, maxNodes int) (*Subgraph, error) {
+	return r.Repository.GetAttentionSubgraph(ctx, r.aliases.Resolve(ctx, startNodeID), minWeight, maxNodes)
+}
+
+func (r *aliasRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
+	return r.Repository.GetSubgraph(ctx, r.aliases.Resolve(ctx, startNodeID), depth, relationshipTypes)
+}
+
+func (r *aliasRepository) GetEntitiesInterpretedThrough(ctx context.Context, lensID string) ([]*core.Node, error) {
+	return r.Repository.GetEntitiesInterpretedThrough(ctx, r.aliases.Resolve(ctx, lensID))
+}
+
+func (r *aliasRepository) CreateInterpretedThroughLink(ctx context.Context, entityID, lensID string, meta map[string]interface{}) error {
+	return r.Repository.CreateInterpretedThroughLink(ctx, r.aliases.Resolve(ctx, entityID), r.aliases.Resolve(ctx, lensID), meta)
+}
+
+func (r *aliasRepository) QueryByLens(ctx context.Context, lensID string, pattern string, limit int, offset int) ([]*core.Node, error) {
+	return r.Repository.QueryByLens(ctx, r.aliases.Resolve(ctx, lensID), pattern, limit, offset)
+}
+
+func (r *aliasRepository) ExportLens(ctx context.Context, lensID string, includeExtractedFrom bool) (*LensExport, error) {
+	return r.Repository.ExportLens(ctx, r.aliases.Resolve(ctx, lensID), includeExtractedFrom)
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestAliases(t *testing.T) {
+	ctx := context.Background()
+	repo, aliases := WithAliases(NewMemory(), true)
+
+	if err := repo.CreateNode(ctx, &core.Node{ID: "sha256:4c4b6a", Type: "Person", Meta: map[string]any{"name": "Ada"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateNode(ctx, &core.Node{ID: "paper:notes", Type: "Paper"}); err != nil {
+		t.Fatal(err)
+	}
+	ada, _ := repo.GetNode(ctx, "sha256:4c4b6a")
+	short, _ := ada.Meta[ShortIDKey].(string)
+	if short != ShortIDFor("sha256:4c4b6a") || len(short) != ShortIDLength {
+		t.Fatalf("short_id = %q, want %q", short, ShortIDFor("sha256:4c4b6a"))
+	}
+
+	if _, err := aliases.SetAlias(ctx, short, "Person/Ada-Lovelace", "alice"); err != nil {
+		t.Fatalf("SetAlias() error = %v", err)
+	}
+	for _, id := range []string{short, "person/ada-lovelace", "person%2Fada-lovelace"} {
+		if n, err := repo.GetNode(ctx, id); err != nil || n.ID != "sha256:4c4b6a" {
+			t.Errorf("GetNode(%q) = %v, %v", id, n, err)
+		}
+	}
+
+	// Aliases work wherever an ID does
+	if err := repo.CreateLink(ctx, &core.Link{Source: "paper:notes", Target: "person/ada-lovelace", Type: "MENTIONS"}); err != nil {
+		t.Fatal(err)
+	}
+	if backlinks, err := repo.GetBacklinks(ctx, short); err != nil || len(backlinks) != 1 || backlinks[0].Target != "sha256:4c4b6a" {
+		t.Errorf("GetBacklinks() = %+v, %v", backlinks, err)
+	}
+	if err := repo.UpdateNodeMeta(ctx, "person/ada-lovelace", map[string]any{"born": 1815}); err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := repo.GetNodeHistory(ctx, short); len(history) != 3 {
+		t.Errorf("history = %d versions, want 3", len(history))
+	}
+
+	if _, err := aliases.SetAlias(ctx, "paper:notes", "person/ada-lovelace", ""); !errors.Is(err, ErrAliasTaken) {
+		t.Errorf("SetAlias(taken) error = %v", err)
+	}
+	if _, err := aliases.SetAlias(ctx, "paper:notes", "notes", ""); !errors.Is(err, ErrInvalidAlias) {
+		t.Errorf("SetAlias(no slash) error = %v", err)
+	}
+	if err := repo.UpdateNodeMeta(ctx, "paper:notes", map[string]any{AliasKey: "paper/mine"}); !errors.Is(err, ErrInvalidAlias) {
+		t.Errorf("UpdateNodeMeta(alias) error = %v", err)
+	}
+	// Writing back a node's own values is allowed
+	if err := repo.UpdateNodeMeta(ctx, "sha256:4c4b6a", map[string]any{AliasKey: "person/ada-lovelace", ShortIDKey: short}); err != nil {
+		t.Errorf("UpdateNodeMeta(unchanged) error = %v", err)
+	}
+
+	// Removing the alias frees it
+	if _, err := aliases.SetAlias(ctx, "sha256:4c4b6a", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetNode(ctx, "person/ada-lovelace"); err == nil {
+		t.Error("removed alias still resolves")
+	}
+	if _, err := aliases.SetAlias(ctx, "paper:notes", "person/ada-lovelace", ""); err != nil {
+		t.Errorf("SetAlias(freed) error = %v", err)
+	}
+}
+
+func TestShortIDCollision(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	// Another node already holds the first candidate
+	taken := ShortIDFor("note:b")
+	mem.CreateNode(ctx, &core.Node{ID: "note:a", Type: "Note", Meta: map[string]any{ShortIDKey: taken}})
+	mem.CreateNode(ctx, &core.Node{ID: "note:old", Type: "Note"})
+
+	repo, aliases := WithAliases(mem, true)
+	if err := repo.CreateNode(ctx, &core.Node{ID: "note:b", Type: "Note"}); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := repo.GetNode(ctx, "note:b")
+	if short, _ := b.Meta[ShortIDKey].(string); short == taken || len(short) != ShortIDLength+2 {
+		t.Errorf("colliding short_id = %q", short)
+	}
+
+	// Batches check against each other too
+	if err := repo.CreateNodes(ctx, []*core.Node{{ID: "note:c", Type: "Note"}, {ID: "note:d", Type: "Note", Meta: map[string]any{ShortIDKey: ShortIDFor("note:c")}}}); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := repo.GetNode(ctx, "note:c")
+	d, _ := repo.GetNode(ctx, "note:d")
+	if c.Meta[ShortIDKey] == d.Meta[ShortIDKey] {
+		t.Errorf("batch short IDs collide: %v", c.Meta[ShortIDKey])
+	}
+
+	assigned, err := aliases.AssignShortIDs(ctx)
+	if err != nil || assigned != 1 {
+		t.Errorf("AssignShortIDs() = %d, %v, want 1", assigned, err)
+	}
+	if n, err := repo.GetNode(ctx, ShortIDFor("note:old")); err != nil || n.ID != "note:old" {
+		t.Errorf("GetNode(backfilled short ID) = %v, %v", n, err)
+	}
+}
@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// ErrNoAttentionEdge is returned when two nodes have no ATTENDED edge
+var ErrNoAttentionEdge = errors.New("no attention edge")
+
+// AttentionContributionLimit is how many contributions an ATTENDED edge
+// keeps; older ones are dropped but still counted in its weight
+const AttentionContributionLimit = 200
+
+// AttentionContribution is one query's observation of an attention edge
+type AttentionContribution struct {
+	QueryID string    `json:"query_id"`
+	Head    string    `json:"head,omitempty"`
+	Weight  float64   `json:"weight"`
+	At      time.Time `json:"at"`
+}
+
+// attentionContributions reads the contributions stored on an edge
+func attentionContributions(meta map[string]interface{}) []AttentionContribution {
+	raw, ok := meta["contributions"]
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var out []AttentionContribution
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// applyAttention folds one query's weight into an ATTENDED edge: the
+// running average weight, and in its properties the query count, the
+// weight and count of its head when there is one, and the contribution
+// itself. The edge's meta is replaced, not modified in place.
+func applyAttention(edge *core.Link, queryID, head string, weight float64, now time.Time) {
+	meta := make(map[string]interface{}, len(edge.Meta)+4)
+	for k, v := range edge.Meta {
+		meta[k] = v
+	}
+	currentCount := toFloat(meta["query_count"])
+
+	edge.Weight = (edge.Weight*currentCount + weight) / (currentCount + 1)
+	meta["query_count"] = currentCount + 1
+	meta["last_updated"] = now.Format(time.RFC3339)
+	meta["last_query_id"] = queryID
+	if head != "" {
+		applyAttentionHead(meta, head, weight, now)
+	}
+
+	contributions := append(attentionContributions(meta), AttentionContribution{QueryID: queryID, Head: head, Weight: weight, At: now})
+	if over := len(contributions) - AttentionContributionLimit; over > 0 {
+		contributions = contributions[over:]
+	}
+	meta["contributions"] = contributions
+	edge.Meta = meta
+}
+
+func toFloat(v interface{}) float64 {
+	f, _ := numericValue(v)
+	return f
+}
+
+// AttentionQuery sums one query's contributions to an edge
+type AttentionQuery struct {
+	QueryID    string    `json:"query_id"`
+	Count      int       `json:"count"`
+	MeanWeight float64   `json:"mean_weight"`
+	Last       time.Time `json:"last"`
+}
+
+// AttentionExplanation is why two nodes are linked by attention
+type AttentionExplanation struct {
+	Source      string    `json:"source"`
+	Target      string    `json:"target"`
+	Weight      float64   `json:"weight"`
+	QueryCount  int       `json:"query_count"`
+	Created     time.Time `json:"created"`
+	LastUpdated string    `json:"last_updated,omitempty"`
+	// Contributions are newest first. Edges keep the last
+	// AttentionContributionLimit; Unrecorded counts older ones and those
+	// made before contributions were kept.
+	Contributions []AttentionContribution  `json:"contributions"`
+	Unrecorded    int                      `json:"unrecorded"`
+	Heads         map[string]AttentionHead `json:"heads,omitempty"` // Weights per attention head
+	Queries       []AttentionQuery         `json:"queries"`         // Recorded contributions by query, largest total first
+}
+
+// ExplainAttentionEdge returns the contributions behind the ATTENDED edge
+// from source to target
+func ExplainAttentionEdge(ctx context.Context, repo Repository, source, target string) (*AttentionExplanation, error) {
+	links, err := repo.GetLinks(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range links {
+		if l.Type != "ATTENDED" || l.Target != target {
+			continue
+		}
+		contributions := attentionContributions(l.Meta)
+		sort.SliceStable(contributions, func(i, j int) bool {
+			return contributions[i].At.After(contributions[j].At)
+		})
+		if contributions == nil {
+			contributions = []AttentionContribution{}
+		}
+		e := &AttentionExplanation{
+			Source:        source,
+			Target:        target,
+			Weight:        l.Weight,
+			QueryCount:    int(toFloat(l.Meta["query_count"])),
+			Created:       l.Created,
+			Contributions: contributions,
+			Queries:       []AttentionQuery{},
+			Heads:         attentionHeads(l.Meta),
+		}
+		e.LastUpdated, _ = l.Meta["last_updated"].(string)
+		if e.Unrecorded = e.QueryCount - len(contributions); e.Unrecorded < 0 {
+			e.Unrecorded = 0
+		}
+
+		byQuery := make(map[string]*AttentionQuery)
+		totals := make(map[string]float64)
+		for _, c := range contributions {
+			q := byQuery[c.QueryID]
+			if q == nil {
+				q = &AttentionQuery{QueryID: c.QueryID, Last: c.At}
+				byQuery[c.QueryID] = q
+			}
+			q.Count++
+			totals[c.QueryID] += c.Weight
+		}
+		for id, q := range byQuery {
+			q.MeanWeight = totals[id] / float64(q.Count)
+			e.Queries = append(e.Queries, *q)
+		}
+		sort.Slice(e.Queries, func(i, j int) bool {
+			a, b := e.Queries[i], e.Queries[j]
+			if totals[a.QueryID] != totals[b.QueryID] {
+				return totals[a.QueryID] > totals[b.QueryID]
+			}
+			return a.QueryID < b.QueryID
+		})
+		return e, nil
+	}
+	return nil, fmt.Errorf("%w: %s -> %s", ErrNoAttentionEdge, source, target)
+}
@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// An attention head is a context label, such as "coding" or "research",
+// that attention updates can carry. An ATTENDED edge keeps its overall
+// weight and query count, and the same per head under "heads", so queries
+// of one intent can follow the structure learned for it. The head travels
+// on the context: UpdateAttentionEdge records under the head of its
+// context, and GetAttentionSubgraph follows only edges weighted for it.
+
+// ErrInvalidAttentionHead is returned for a malformed head name
+var ErrInvalidAttentionHead = errors.New("invalid attention head")
+
+var validAttentionHead = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// AttentionHead is an edge's weight for one head
+type AttentionHead struct {
+	Weight      float64 `json:"weight"`
+	QueryCount  int     `json:"query_count"`
+	LastUpdated string  `json:"last_updated,omitempty"`
+}
+
+type attentionHeadKey struct{}
+
+// ValidateAttentionHead checks a head name: lowercase letters, digits, _
+// and -, up to 32 characters
+func ValidateAttentionHead(head string) error {
+	if !validAttentionHead.MatchString(head) {
+		return fmt.Errorf("%w: %q", ErrInvalidAttentionHead, head)
+	}
+	return nil
+}
+
+// WithAttentionHead returns a context whose attention updates and
+// subgraphs use head; an empty head uses the overall weights
+func WithAttentionHead(ctx context.Context, head string) context.Context {
+	return context.WithValue(ctx, attentionHeadKey{}, head)
+}
+
+// AttentionHeadFrom returns the attention head set on ctx, if any
+func AttentionHeadFrom(ctx context.Context) string {
+	head, _ := ctx.Value(attentionHeadKey{}).(string)
+	return head
+}
+
+// attentionHeads reads the per-head weights stored on an edge
+func attentionHeads(meta map[string]interface{}) map[string]AttentionHead {
+	raw, ok := meta["heads"]
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var out map[string]AttentionHead
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// applyAttentionHead folds one query's weight into a head's running average
+func applyAttentionHead(meta map[string]interface{}, head string, weight float64, now time.Time) {
+	heads := attentionHeads(meta)
+	if heads == nil {
+		heads = make(map[string]AttentionHead)
+	}
+	h := heads[head]
+	h.Weight = (h.Weight*float64(h.QueryCount) + weight) / float64(h.QueryCount+1)
+	h.QueryCount++
+	h.LastUpdated = now.Format(time.RFC3339)
+	heads[head] = h
+	meta["heads"] = heads
+}
+
+// attentionPasses reports whether an edge's weight for the head on ctx, or
+// its overall weight without one, is at least minWeight. Edges never
+// weighted for the head do not pass.
+func attentionPasses(ctx context.Context, weight float64, meta map[string]interface{}, minWeight float64) bool {
+	head := AttentionHeadFrom(ctx)
+	if head == "" {
+		return weight >= minWeight
+	}
+	h, ok := attentionHeads(meta)[head]
+	return ok && h.Weight >= minWeight
+}
+
+// inAttentionHead reports whether an edge has been weighted for the head
+// on ctx; every edge is without one
+func inAttentionHead(ctx context.Context, meta map[string]interface{}) bool {
+	head := AttentionHeadFrom(ctx)
+	if head == "" {
+		return true
+	}
+	_, ok := attentionHeads(meta)[head]
+	return ok
+}
@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestAttentionHeads(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close(ctx)
+	stored, _, err := WithAttentionStore(ctx, NewMemory(), AttentionStoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, repo := range map[string]Repository{"memory": NewMemory(), "sqlite": sqlite, "store": stored} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for _, id := range []string{"a", "b", "c"} {
+				if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Concept", Created: now, Modified: now}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			coding := WithAttentionHead(ctx, "coding")
+			research := WithAttentionHead(ctx, "research")
+			repo.UpdateAttentionEdge(coding, "a", "b", "q1", 0.9)
+			repo.UpdateAttentionEdge(coding, "a", "b", "q2", 0.7)
+			repo.UpdateAttentionEdge(research, "a", "b", "q3", 0.1)
+			repo.UpdateAttentionEdge(research, "a", "c", "q3", 0.8)
+
+			subgraphIDs := func(ctx context.Context, minWeight float64) []string {
+				sg, err := repo.GetAttentionSubgraph(ctx, "a", minWeight, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var ids []string
+				for _, n := range sg.Nodes {
+					ids = append(ids, n.ID)
+				}
+				sort.Strings(ids)
+				return ids
+			}
+			if got := subgraphIDs(coding, 0.5); len(got) != 1 || got[0] != "b" {
+				t.Errorf("coding subgraph = %v, want [b]", got)
+			}
+			if got := subgraphIDs(research, 0.5); len(got) != 1 || got[0] != "c" {
+				t.Errorf("research subgraph = %v, want [c]", got)
+			}
+			// Without a head the overall weight counts: a -> b averages 0.57
+			if got := subgraphIDs(ctx, 0.5); len(got) != 2 {
+				t.Errorf("overall subgraph = %v, want [b c]", got)
+			}
+
+			e, err := ExplainAttentionEdge(ctx, repo, "a", "b")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.QueryCount != 3 || len(e.Heads) != 2 {
+				t.Fatalf("edge = %d queries, heads %+v; want 3 queries in 2 heads", e.QueryCount, e.Heads)
+			}
+			if h := e.Heads["coding"]; h.QueryCount != 2 || math.Abs(h.Weight-0.8) > 1e-9 {
+				t.Errorf("coding head = %+v, want 2 queries of weight 0.8", h)
+			}
+			if e.Contributions[0].Head != "research" {
+				t.Errorf("newest contribution = %+v, want the research head", e.Contributions[0])
+			}
+		})
+	}
+
+	merged := mergeAttention(
+		&AttentionRecord{QueryCount: 2, Weight: 0.5, Heads: map[string]AttentionHead{"coding": {Weight: 0.5, QueryCount: 2}}},
+		&AttentionRecord{QueryCount: 2, Weight: 0.7, Heads: map[string]AttentionHead{"coding": {Weight: 1, QueryCount: 1}, "research": {Weight: 0.4, QueryCount: 1}}},
+	)
+	if h := merged.Heads["coding"]; h.QueryCount != 3 || math.Abs(h.Weight-2.0/3) > 1e-9 || merged.Heads["research"].QueryCount != 1 {
+		t.Errorf("merged heads = %+v", merged.Heads)
+	}
+
+	if err := ValidateAttentionHead("Coding Stuff"); !errors.Is(err, ErrInvalidAttentionHead) {
+		t.Errorf("ValidateAttentionHead(Coding Stuff) = %v, want ErrInvalidAttentionHead", err)
+	}
+}
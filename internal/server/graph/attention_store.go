@@ -0,0 +1,635 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// PromotedLinkType is the durable link consolidation creates for strong
+// attention edges
+const PromotedLinkType = "RELATED"
+
+// AttentionStoreConfig configures the decoupled attention store
+type AttentionStoreConfig struct {
+	Path                string        // JSON file edges are saved to; empty keeps them in memory only
+	FlushInterval       time.Duration // How often changed edges are saved (default 30s)
+	ConsolidateInterval time.Duration // How often strong edges are promoted; 0 only on request
+	PromoteWeight       float64       // Minimum weight for promotion (default 0.7)
+	PromoteQueries      int           // Minimum query count for promotion (default 5)
+}
+
+// AttentionStoreStats describes the attention store
+type AttentionStoreStats struct {
+	Edges            int        `json:"edges"`
+	Pending          int        `json:"pending"` // Updates not yet saved
+	Path             string     `json:"path,omitempty"`
+	LastFlush        *time.Time `json:"last_flush,omitempty"`
+	LastConsolidated *time.Time `json:"last_consolidated,omitempty"`
+	Promoted         int        `json:"promoted"` // Edges promoted since the server started
+}
+
+// ConsolidationResult reports one consolidation run
+type ConsolidationResult struct {
+	Candidates int `json:"candidates"` // Edges over the promotion thresholds
+	Promoted   int `json:"promoted"`   // New durable links created
+	Existing   int `json:"existing"`   // Candidates already linked
+	Skipped    int `json:"skipped"`    // Candidates with a missing endpoint
+}
+
+type attentionKey struct {
+	source, target string
+}
+
+// AttentionStore keeps ATTENDED edges apart from the graph's links. Query
+// traffic updates them in memory and they are saved in one write per
+// flush interval, so hot attention data never touches the links table.
+// Consolidation promotes edges that stay strong to durable links.
+type AttentionStore struct {
+	cfg  AttentionStoreConfig
+	repo Repository // The backend, which receives promoted links
+
+	mu       sync.RWMutex
+	edges    map[attentionKey]*core.Link
+	outgoing map[string]map[string]*core.Link
+	incoming map[string]map[string]*core.Link
+	pending  int
+	stats    AttentionStoreStats
+
+	flushMu sync.Mutex // Serializes file writes
+}
+
+// attentionRepository serves attention edges from an AttentionStore and
+// everything else from the wrapped backend
+type attentionRepository struct {
+	Repository
+	store *AttentionStore
+}
+
+// WithAttentionStore moves attention edges out of repo into a separate
+// store. Edges are loaded from cfg.Path, or copied from repo's ATTENDED
+// links when the file does not exist yet; from then on ATTENDED links in
+// repo are ignored.
+func WithAttentionStore(ctx context.Context, repo Repository, cfg AttentionStoreConfig) (Repository, *AttentionStore, error) {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 30 * time.Second
+	}
+	if cfg.PromoteWeight <= 0 {
+		cfg.PromoteWeight = 0.7
+	}
+	if cfg.PromoteQueries <= 0 {
+		cfg.PromoteQueries = 5
+	}
+	s := &AttentionStore{
+		cfg:      cfg,
+		repo:     repo,
+		edges:    make(map[attentionKey]*core.Link),
+		outgoing: make(map[string]map[string]*core.Link),
+		incoming: make(map[string]map[string]*core.Link),
+		stats:    AttentionStoreStats{Path: cfg.Path},
+	}
+
+	loaded, err := s.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !loaded {
+		if err := s.importLinks(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	return &attentionRepository{Repository: repo, store: s}, s, nil
+}
+
+// load reads the saved edges, reporting whether the file existed
+func (s *AttentionStore) load() (bool, error) {
+	if s.cfg.Path == "" {
+		return false, nil
+	}
+	data, err := os.ReadFile(s.cfg.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading attention store: %w", err)
+	}
+	var links []*core.Link
+	if err := json.Unmarshal(data, &links); err != nil {
+		return false, fmt.Errorf("parsing attention store %s: %w", s.cfg.Path, err)
+	}
+	for _, l := range links {
+		PromoteLinkFields(l) // Saved before weights were typed
+		s.insert(l)
+	}
+	return true, nil
+}
+
+// importLinks copies the backend's ATTENDED links into the store
+func (s *AttentionStore) importLinks(ctx context.Context) error {
+	ids, err := s.repo.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("importing attention edges: %w", err)
+	}
+	for _, id := range ids {
+		links, err := s.repo.GetLinks(ctx, id)
+		if err != nil {
+			return fmt.Errorf("importing attention edges of %s: %w", id, err)
+		}
+		for _, l := range links {
+			if l.Type == "ATTENDED" {
+				s.insert(cloneLink(l))
+				s.pending++
+			}
+		}
+	}
+	return nil
+}
+
+// insert indexes a link; the caller holds mu or has sole access
+func (s *AttentionStore) insert(l *core.Link) {
+	s.edges[attentionKey{l.Source, l.Target}] = l
+	if s.outgoing[l.Source] == nil {
+		s.outgoing[l.Source] = make(map[string]*core.Link)
+	}
+	if s.incoming[l.Target] == nil {
+		s.incoming[l.Target] = make(map[string]*core.Link)
+	}
+	s.outgoing[l.Source][l.Target] = l
+	s.incoming[l.Target][l.Source] = l
+}
+
+// remove drops a link from the indexes; the caller holds mu
+func (s *AttentionStore) remove(key attentionKey) {
+	delete(s.edges, key)
+	delete(s.outgoing[key.source], key.target)
+	if len(s.outgoing[key.source]) == 0 {
+		delete(s.outgoing, key.source)
+	}
+	delete(s.incoming[key.target], key.source)
+	if len(s.incoming[key.target]) == 0 {
+		delete(s.incoming, key.target)
+	}
+}
+
+// dropNode removes every edge touching nodeID; the caller holds mu
+func (s *AttentionStore) dropNode(nodeID string) {
+	var keys []attentionKey
+	for target := range s.outgoing[nodeID] {
+		keys = append(keys, attentionKey{nodeID, target})
+	}
+	for source := range s.incoming[nodeID] {
+		keys = append(keys, attentionKey{source, nodeID})
+	}
+	for _, key := range keys {
+		s.remove(key)
+	}
+	if len(keys) > 0 {
+		s.pending++
+	}
+}
+
+// update folds one query's weight into the edge from source to target
+func (s *AttentionStore) update(source, target, queryID, head string, weight float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	key := attentionKey{source, target}
+	if l, ok := s.edges[key]; ok {
+		applyAttention(l, queryID, head, weight, now)
+		l.Modified = now
+	} else {
+		l := &core.Link{
+			Source:   source,
+			Target:   target,
+			Type:     "ATTENDED",
+			Created:  now,
+			Modified: now,
+		}
+		applyAttention(l, queryID, head, weight, now)
+		s.insert(l)
+	}
+	s.pending++
+}
+
+// put stores a copy of a whole edge, replacing the one between its nodes
+func (s *AttentionStore) put(l *core.Link) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := attentionKey{l.Source, l.Target}
+	if _, ok := s.edges[key]; ok {
+		s.remove(key)
+	}
+	l = cloneLink(l)
+	PromoteLinkFields(l)
+	s.insert(l)
+	s.pending++
+}
+
+// links returns copies of the edges in m
+func (s *AttentionStore) links(m map[string]*core.Link) []*core.Link {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*core.Link, 0, len(m))
+	for _, l := range m {
+		out = append(out, cloneLink(l))
+	}
+	return out
+}
+
+// Flush saves the edges if any changed since the last save
+func (s *AttentionStore) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	// Metadata maps are replaced rather than modified, so a read lock
+	// is enough to encode them
+	s.mu.RLock()
+	if s.pending == 0 || s.cfg.Path == "" {
+		s.mu.RUnlock()
+		return nil
+	}
+	links := make([]*core.Link, 0, len(s.edges))
+	for _, l := range s.edges {
+		links = append(links, l)
+	}
+	data, err := json.Marshal(links)
+	flushed := s.pending
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encoding attention store: %w", err)
+	}
+
+	// Write a temporary file and rename it, so a crash never leaves a
+	// half-written store behind
+	tmp, err := os.CreateTemp(filepath.Dir(s.cfg.Path), ".attention-*")
+	if err != nil {
+		return fmt.Errorf("saving attention store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving attention store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving attention store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.cfg.Path); err != nil {
+		return fmt.Errorf("saving attention store: %w", err)
+	}
+
+	s.mu.Lock()
+	s.pending -= flushed
+	now := time.Now()
+	s.stats.LastFlush = &now
+	s.mu.Unlock()
+	return nil
+}
+
+// Consolidate promotes attention edges whose weight and query count meet
+// the configured thresholds to durable links in the graph. Promoted edges
+// are marked so they are only promoted once; the attention edge itself is
+// kept.
+func (s *AttentionStore) Consolidate(ctx context.Context) (*ConsolidationResult, error) {
+	s.mu.RLock()
+	var candidates []attentionKey
+	for key, l := range s.edges {
+		if _, done := l.Meta["promoted_at"]; done {
+			continue
+		}
+		if l.Weight >= s.cfg.PromoteWeight && int(toFloat(l.Meta["query_count"])) >= s.cfg.PromoteQueries {
+			candidates = append(candidates, key)
+		}
+	}
+	s.mu.RUnlock()
+
+	result := &ConsolidationResult{Candidates: len(candidates)}
+	for _, key := range candidates {
+		promoted, err := s.promote(ctx, key)
+		if err != nil {
+			return result, err
+		}
+		switch promoted {
+		case promoteCreated:
+			result.Promoted++
+		case promoteExisting:
+			result.Existing++
+		default:
+			result.Skipped++
+			continue
+		}
+		s.mu.Lock()
+		if l, ok := s.edges[key]; ok {
+			l.Meta = cloneMeta(l.Meta)
+			l.Meta["promoted_at"] = time.Now().Format(time.RFC3339)
+			s.pending++
+		}
+		s.mu.Unlock()
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	s.stats.LastConsolidated = &now
+	s.stats.Promoted += result.Promoted
+	s.mu.Unlock()
+	return result, nil
+}
+
+// Outcomes of promoting one edge
+const (
+	promoteSkipped = iota
+	promoteCreated
+	promoteExisting
+)
+
+// promote creates the durable link for one attention edge
+func (s *AttentionStore) promote(ctx context.Context, key attentionKey) (int, error) {
+	for _, id := range []string{key.source, key.target} {
+		if _, err := s.repo.GetNode(ctx, id); err != nil {
+			return promoteSkipped, nil
+		}
+	}
+	links, err := s.repo.GetLinks(ctx, key.source)
+	if err != nil {
+		return promoteSkipped, fmt.Errorf("promoting %s -> %s: %w", key.source, key.target, err)
+	}
+	for _, l := range links {
+		if l.Type == PromotedLinkType && l.Target == key.target {
+			return promoteExisting, nil
+		}
+	}
+
+	s.mu.RLock()
+	edge := s.edges[key]
+	var weight, count float64
+	if edge != nil {
+		weight, count = edge.Weight, toFloat(edge.Meta["query_count"])
+	}
+	s.mu.RUnlock()
+	if edge == nil {
+		return promoteSkipped, nil // Pruned meanwhile
+	}
+
+	now := time.Now()
+	err = s.repo.CreateLink(ctx, &core.Link{
+		Source: key.source,
+		Target: key.target,
+		Type:   PromotedLinkType,
+		Meta: map[string]interface{}{
+			"promoted_from": "ATTENDED",
+			"query_count":   count,
+		},
+		Weight:   weight,
+		Created:  now,
+		Modified: now,
+	})
+	if err != nil {
+		return promoteSkipped, fmt.Errorf("promoting %s -> %s: %w", key.source, key.target, err)
+	}
+	return promoteCreated, nil
+}
+
+// Stats describes the store
+func (s *AttentionStore) Stats() AttentionStoreStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := s.stats
+	stats.Edges = len(s.edges)
+	stats.Pending = s.pending
+	return stats
+}
+
+// Run saves edges every flush interval and consolidates every
+// consolidation interval until ctx is done, then saves a final time.
+// Errors are passed to onError.
+func (s *AttentionStore) Run(ctx context.Context, onError func(error)) {
+	flush := time.NewTicker(s.cfg.FlushInterval)
+	defer flush.Stop()
+	var consolidate <-chan time.Time
+	if s.cfg.ConsolidateInterval > 0 {
+		t := time.NewTicker(s.cfg.ConsolidateInterval)
+		defer t.Stop()
+		consolidate = t.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				onError(err)
+			}
+			return
+		case <-flush.C:
+			if err := s.Flush(); err != nil {
+				onError(err)
+			}
+		case <-consolidate:
+			if _, err := s.Consolidate(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// withoutAttention drops the backend's own ATTENDED links
+func withoutAttention(links []*core.Link) []*core.Link {
+	out := links[:0]
+	for _, l := range links {
+		if l.Type != "ATTENDED" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (a *attentionRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	links, err := a.Repository.GetLinks(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	a.store.mu.RLock()
+	out := a.store.outgoing[nodeID]
+	a.store.mu.RUnlock()
+	return append(withoutAttention(links), a.store.links(out)...), nil
+}
+
+func (a *attentionRepository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	links, err := a.Repository.GetBacklinks(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	a.store.mu.RLock()
+	in := a.store.incoming[nodeID]
+	a.store.mu.RUnlock()
+	return append(withoutAttention(links), a.store.links(in)...), nil
+}
+
+// CreateLink stores ATTENDED links, such as imported ones, in the
+// attention store, replacing any edge between the same nodes
+func (a *attentionRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	if link.Type != "ATTENDED" {
+		return a.Repository.CreateLink(ctx, link)
+	}
+	a.store.put(link)
+	return nil
+}
+
+// CreateLinks stores ATTENDED links in the attention store and the rest in
+// the backend
+func (a *attentionRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	rest := make([]*core.Link, 0, len(links))
+	for _, l := range links {
+		if l.Type == "ATTENDED" {
+			a.store.put(l)
+		} else {
+			rest = append(rest, l)
+		}
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	return a.Repository.CreateLinks(ctx, rest)
+}
+
+// DeleteLink removes ATTENDED links from the attention store
+func (a *attentionRepository) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
+	if linkType != "ATTENDED" {
+		return a.Repository.DeleteLink(ctx, sourceID, targetID, linkType)
+	}
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	key := attentionKey{sourceID, targetID}
+	if _, ok := a.store.edges[key]; ok {
+		a.store.remove(key)
+		a.store.pending++
+	}
+	return nil
+}
+
+// DeleteNode drops a hard-deleted node's attention edges along with its
+// links, so neighbours and consolidation no longer see them
+func (a *attentionRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	if err := a.Repository.DeleteNode(ctx, nodeID, force); err != nil {
+		return err
+	}
+	if force {
+		a.store.mu.Lock()
+		a.store.dropNode(nodeID)
+		a.store.mu.Unlock()
+	}
+	return nil
+}
+
+func (a *attentionRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	a.store.update(source, target, queryID, AttentionHeadFrom(ctx), weight)
+	return nil
+}
+
+// GetAttentionSubgraph returns the nodes attention links to startNodeID
+// with at least minWeight, and the attention edges among them
+func (a *attentionRepository) GetAttentionSubgraph(ctx context.Context, startNodeID string, minWeight float64, maxNodes int) (*Subgraph, error) {
+	a.store.mu.RLock()
+	var neighbours []string
+	seen := map[string]bool{startNodeID: true}
+	for _, m := range []map[string]*core.Link{a.store.outgoing[startNodeID], a.store.incoming[startNodeID]} {
+		for _, l := range m {
+			other := l.Target
+			if other == startNodeID {
+				other = l.Source
+			}
+			if seen[other] || !attentionPasses(ctx, l.Weight, l.Meta, minWeight) {
+				continue
+			}
+			seen[other] = true
+			neighbours = append(neighbours, other)
+		}
+	}
+	a.store.mu.RUnlock()
+
+	nodeMap := make(map[string]*core.Node)
+	var nodes []*core.Node
+	for _, id := range neighbours {
+		if len(nodes) >= maxNodes {
+			break
+		}
+		node, err := a.Repository.GetNode(ctx, id)
+		if err != nil {
+			continue // Edges may outlive their nodes
+		}
+		nodeMap[id] = node
+		nodes = append(nodes, node)
+	}
+
+	a.store.mu.RLock()
+	var edges []*SubgraphEdge
+	for id := range nodeMap {
+		for target, l := range a.store.outgoing[id] {
+			if nodeMap[target] != nil && inAttentionHead(ctx, l.Meta) {
+				edges = append(edges, edgeOf(l))
+			}
+		}
+	}
+	a.store.mu.RUnlock()
+
+	return &Subgraph{
+		Nodes: nodes,
+		Edges: edges,
+		Stats: SubgraphStats{
+			NodeCount: len(nodes),
+			EdgeCount: len(edges),
+			Depth:     2,
+		},
+	}, nil
+}
+
+// PruneWeakAttentionEdges removes attention edges with low weight or
+// query count, sparing those touching protected nodes
+func (a *attentionRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight float64, minQueryCount int) (int, error) {
+	a.store.mu.RLock()
+	var weak []attentionKey
+	for key, l := range a.store.edges {
+		if l.Weight < minWeight || int(toFloat(l.Meta["query_count"])) < minQueryCount {
+			weak = append(weak, key)
+		}
+	}
+	a.store.mu.RUnlock()
+
+	protected := func(id string) bool {
+		node, err := a.Repository.GetNode(ctx, id)
+		return err == nil && IsProtected(node.Meta)
+	}
+	var prune []attentionKey
+	for _, key := range weak {
+		if !protected(key.source) && !protected(key.target) {
+			prune = append(prune, key)
+		}
+	}
+
+	a.store.mu.Lock()
+	for _, key := range prune {
+		a.store.remove(key)
+	}
+	if len(prune) > 0 {
+		a.store.pending++
+	}
+	a.store.mu.Unlock()
+	return len(prune), nil
+}
+
+// Close saves the attention store before closing the backend
+func (a *attentionRepository) Close(ctx context.Context) error {
+	if err := a.store.Flush(); err != nil {
+		return err
+	}
+	return a.Repository.Close(ctx)
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestAttentionStore(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemory()
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := backend.CreateNode(ctx, &core.Node{ID: id, Type: "Concept", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backend.CreateLink(ctx, &core.Link{Source: "a", Target: "c", Type: "MENTIONS", Created: now, Modified: now})
+	backend.UpdateAttentionEdge(ctx, "a", "c", "q0", 0.2)
+
+	path := filepath.Join(t.TempDir(), "attention.json")
+	cfg := AttentionStoreConfig{Path: path, PromoteWeight: 0.7, PromoteQueries: 3}
+	repo, store, err := WithAttentionStore(ctx, backend, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := store.Stats(); stats.Edges != 1 {
+		t.Fatalf("imported %d edges, want 1", stats.Edges)
+	}
+
+	for i := 0; i < 3; i++ {
+		repo.UpdateAttentionEdge(ctx, "a", "b", "q", 0.9)
+	}
+	repo.UpdateAttentionEdge(ctx, "a", "c", "q", 0.2)
+
+	// Updates stay out of the backend's links
+	links, _ := backend.GetLinks(ctx, "a")
+	for _, l := range links {
+		if l.Type == "ATTENDED" && l.Target == "b" {
+			t.Error("attention update reached the backend")
+		}
+	}
+	links, _ = repo.GetLinks(ctx, "a")
+	attended := 0
+	for _, l := range links {
+		if l.Type == "ATTENDED" {
+			attended++
+		}
+	}
+	if len(links) != 3 || attended != 2 {
+		t.Errorf("GetLinks = %d links, %d attention; want 3, 2", len(links), attended)
+	}
+	if e, err := ExplainAttentionEdge(ctx, repo, "a", "c"); err != nil || e.QueryCount != 2 {
+		t.Errorf("explain a->c = %+v, %v", e, err)
+	}
+	sg, _ := repo.GetAttentionSubgraph(ctx, "a", 0.5, 10)
+	if len(sg.Nodes) != 1 || sg.Nodes[0].ID != "b" {
+		t.Errorf("attention subgraph = %+v", sg.Nodes)
+	}
+
+	result, err := store.Consolidate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Candidates != 1 || result.Promoted != 1 {
+		t.Errorf("consolidation = %+v", result)
+	}
+	links, _ = backend.GetLinks(ctx, "a")
+	promoted := false
+	for _, l := range links {
+		promoted = promoted || (l.Type == PromotedLinkType && l.Target == "b")
+	}
+	if !promoted {
+		t.Error("strong edge was not promoted")
+	}
+	if result, _ := store.Consolidate(ctx); result.Candidates != 0 {
+		t.Errorf("promoted edge considered again: %+v", result)
+	}
+
+	if n, _ := repo.PruneWeakAttentionEdges(ctx, 0.5, 1); n != 1 {
+		t.Errorf("pruned %d edges, want 1", n)
+	}
+
+	// Saved edges are loaded instead of importing the backend's again
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Stats().Pending != 0 {
+		t.Error("pending updates after flush")
+	}
+	_, reloaded, err := WithAttentionStore(ctx, backend, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := reloaded.Stats(); stats.Edges != 1 {
+		t.Errorf("reloaded %d edges, want 1", stats.Edges)
+	}
+}
+
+func TestAttentionStoreHardDelete(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemory()
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := backend.CreateNode(ctx, &core.Node{ID: id, Type: "Concept", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, store, err := WithAttentionStore(ctx, backend, AttentionStoreConfig{PromoteWeight: 0.5, PromoteQueries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.UpdateAttentionEdge(ctx, "a", "b", "q", 0.9)
+	repo.UpdateAttentionEdge(ctx, "b", "c", "q", 0.9)
+
+	if err := repo.DeleteNode(ctx, "b", true); err != nil {
+		t.Fatal(err)
+	}
+	if stats := store.Stats(); stats.Edges != 0 {
+		t.Errorf("hard delete left %d edges, want 0", stats.Edges)
+	}
+	backlinks, _ := repo.GetBacklinks(ctx, "c")
+	links, _ := repo.GetLinks(ctx, "a")
+	for _, l := range append(backlinks, links...) {
+		if l.Source == "b" || l.Target == "b" {
+			t.Errorf("deleted node still linked: %+v", l)
+		}
+	}
+	result, err := store.Consolidate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Candidates != 0 || result.Promoted != 0 {
+		t.Errorf("consolidation after delete = %+v", result)
+	}
+}
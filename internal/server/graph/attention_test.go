@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExplainAttentionEdge(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	for _, u := range []struct {
+		query  string
+		weight float64
+	}{{"q1", 0.9}, {"q2", 0.3}, {"q1", 0.6}} {
+		if err := repo.UpdateAttentionEdge(ctx, "a", "b", u.query, u.weight); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e, err := ExplainAttentionEdge(ctx, repo, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.QueryCount != 3 || e.Weight != 0.6 || e.Unrecorded != 0 || len(e.Contributions) != 3 {
+		t.Fatalf("explanation = %+v", e)
+	}
+	if c := e.Contributions[0]; c.QueryID != "q1" || c.Weight != 0.6 {
+		t.Errorf("newest contribution = %+v", c)
+	}
+	if len(e.Queries) != 2 || e.Queries[0].QueryID != "q1" || e.Queries[0].Count != 2 || e.Queries[0].MeanWeight != 0.75 {
+		t.Errorf("by query = %+v", e.Queries)
+	}
+
+	// Only the newest contributions are kept
+	for i := 0; i < AttentionContributionLimit; i++ {
+		repo.UpdateAttentionEdge(ctx, "a", "b", "bulk", 0.5)
+	}
+	e, _ = ExplainAttentionEdge(ctx, repo, "a", "b")
+	if len(e.Contributions) != AttentionContributionLimit || e.Unrecorded != 3 {
+		t.Errorf("after cap: %d contributions, %d unrecorded", len(e.Contributions), e.Unrecorded)
+	}
+
+	if _, err := ExplainAttentionEdge(ctx, repo, "b", "a"); !errors.Is(err, ErrNoAttentionEdge) {
+		t.Errorf("missing edge: %v", err)
+	}
+}
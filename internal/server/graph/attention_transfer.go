@@ -0,0 +1,260 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// ErrInvalidAttention is returned for a malformed attention import
+var ErrInvalidAttention = errors.New("invalid attention record")
+
+// AttentionRecord is one ATTENDED edge as exported, one per JSONL line
+type AttentionRecord struct {
+	Source        string                   `json:"source"`
+	Target        string                   `json:"target"`
+	Weight        float64                  `json:"weight"`
+	QueryCount    int                      `json:"query_count"`
+	Created       time.Time                `json:"created"`
+	LastUpdated   string                   `json:"last_updated,omitempty"`
+	Heads         map[string]AttentionHead `json:"heads,omitempty"`
+	Contributions []AttentionContribution  `json:"contributions,omitempty"`
+}
+
+// AttentionImportResult reports one attention import
+type AttentionImportResult struct {
+	Imported int `json:"imported"` // New edges
+	Replaced int `json:"replaced"` // Existing edges overwritten
+	Merged   int `json:"merged"`   // Existing edges combined with the record
+	Skipped  int `json:"skipped"`  // Records with a missing endpoint
+}
+
+// ExportAttention writes every ATTENDED edge to w as JSON lines, with the
+// edges' recent contributions when contributions is set, and returns how
+// many it wrote
+func ExportAttention(ctx context.Context, repo Repository, w io.Writer, contributions bool) (int, error) {
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	enc := json.NewEncoder(w)
+	written := 0
+	for _, id := range ids {
+		links, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return written, fmt.Errorf("reading attention edges of %s: %w", id, err)
+		}
+		sort.Slice(links, func(i, j int) bool { return links[i].Target < links[j].Target })
+		for _, l := range links {
+			if l.Type != "ATTENDED" {
+				continue
+			}
+			rec := attentionRecord(l)
+			if !contributions {
+				rec.Contributions = nil
+			}
+			if err := enc.Encode(rec); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+	return written, nil
+}
+
+// ImportAttention reads JSON lines written by ExportAttention into repo.
+// An edge that already exists is replaced, or with merge combined with the
+// record: counts add up and weights average by count. Records whose
+// endpoints are missing are skipped. A malformed record stops the import
+// with ErrInvalidAttention; the records before it stay imported.
+func ImportAttention(ctx context.Context, repo Repository, r io.Reader, merge bool) (*AttentionImportResult, error) {
+	result := &AttentionImportResult{}
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var rec AttentionRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return result, nil
+		} else if err != nil {
+			return result, fmt.Errorf("%w: record %d: %v", ErrInvalidAttention, line, err)
+		}
+		if err := rec.validate(); err != nil {
+			return result, fmt.Errorf("%w: record %d: %v", ErrInvalidAttention, line, err)
+		}
+		if err := importAttentionRecord(ctx, repo, &rec, merge, result); err != nil {
+			return result, err
+		}
+	}
+}
+
+// importAttentionRecord writes one record and counts it in result
+func importAttentionRecord(ctx context.Context, repo Repository, rec *AttentionRecord, merge bool, result *AttentionImportResult) error {
+	for _, id := range []string{rec.Source, rec.Target} {
+		if _, err := repo.GetNode(ctx, id); err != nil {
+			result.Skipped++
+			return nil
+		}
+	}
+	links, err := repo.GetLinks(ctx, rec.Source)
+	if err != nil {
+		return fmt.Errorf("importing %s -> %s: %w", rec.Source, rec.Target, err)
+	}
+	var existing *core.Link
+	for _, l := range links {
+		if l.Type == "ATTENDED" && l.Target == rec.Target {
+			existing = l
+			break
+		}
+	}
+
+	switch {
+	case existing == nil:
+		result.Imported++
+	case merge:
+		rec = mergeAttention(attentionRecord(existing), rec)
+		result.Merged++
+	default:
+		result.Replaced++
+	}
+	if existing != nil {
+		if err := repo.DeleteLink(ctx, rec.Source, rec.Target, "ATTENDED"); err != nil {
+			return fmt.Errorf("importing %s -> %s: %w", rec.Source, rec.Target, err)
+		}
+	}
+	if err := repo.CreateLink(ctx, rec.link()); err != nil {
+		return fmt.Errorf("importing %s -> %s: %w", rec.Source, rec.Target, err)
+	}
+	return nil
+}
+
+// validate checks a record can become an edge
+func (rec *AttentionRecord) validate() error {
+	switch {
+	case rec.Source == "" || rec.Target == "":
+		return errors.New("source and target are required")
+	case math.IsNaN(rec.Weight) || math.IsInf(rec.Weight, 0):
+		return errors.New("weight must be a number")
+	case rec.QueryCount < 0:
+		return errors.New("query_count must not be negative")
+	}
+	if rec.LastUpdated != "" {
+		if _, err := time.Parse(time.RFC3339, rec.LastUpdated); err != nil {
+			return fmt.Errorf("last_updated: %v", err)
+		}
+	}
+	return nil
+}
+
+// attentionRecord reads an ATTENDED link's properties
+func attentionRecord(l *core.Link) *AttentionRecord {
+	rec := &AttentionRecord{
+		Source:        l.Source,
+		Target:        l.Target,
+		Weight:        l.Weight,
+		QueryCount:    int(toFloat(l.Meta["query_count"])),
+		Created:       l.Created,
+		Heads:         attentionHeads(l.Meta),
+		Contributions: attentionContributions(l.Meta),
+	}
+	rec.LastUpdated, _ = l.Meta["last_updated"].(string)
+	return rec
+}
+
+// link returns the ATTENDED link a record describes
+func (rec *AttentionRecord) link() *core.Link {
+	now := time.Now()
+	created := rec.Created
+	if created.IsZero() {
+		created = now
+	}
+	meta := map[string]interface{}{
+		"query_count": rec.QueryCount,
+	}
+	if rec.LastUpdated != "" {
+		meta["last_updated"] = rec.LastUpdated
+	}
+	if len(rec.Heads) > 0 {
+		meta["heads"] = rec.Heads
+	}
+	if len(rec.Contributions) > 0 {
+		meta["contributions"] = rec.Contributions
+	}
+	return &core.Link{
+		Source:   rec.Source,
+		Target:   rec.Target,
+		Type:     "ATTENDED",
+		Meta:     cloneMeta(meta),
+		Weight:   rec.Weight,
+		Created:  created,
+		Modified: now,
+	}
+}
+
+// mergeAttention combines two observations of the same edge as if their
+// queries had all been applied to one
+func mergeAttention(a, b *AttentionRecord) *AttentionRecord {
+	out := &AttentionRecord{
+		Source:     a.Source,
+		Target:     a.Target,
+		QueryCount: a.QueryCount + b.QueryCount,
+		Created:    a.Created,
+	}
+	if out.QueryCount > 0 {
+		out.Weight = (a.Weight*float64(a.QueryCount) + b.Weight*float64(b.QueryCount)) / float64(out.QueryCount)
+	}
+	if out.Created.IsZero() || (!b.Created.IsZero() && b.Created.Before(out.Created)) {
+		out.Created = b.Created
+	}
+	out.LastUpdated = laterTime(a.LastUpdated, b.LastUpdated)
+	if len(a.Heads)+len(b.Heads) > 0 {
+		out.Heads = make(map[string]AttentionHead)
+		for _, heads := range []map[string]AttentionHead{a.Heads, b.Heads} {
+			for name, h := range heads {
+				cur := out.Heads[name]
+				merged := AttentionHead{QueryCount: cur.QueryCount + h.QueryCount, LastUpdated: laterTime(cur.LastUpdated, h.LastUpdated)}
+				if merged.QueryCount > 0 {
+					merged.Weight = (cur.Weight*float64(cur.QueryCount) + h.Weight*float64(h.QueryCount)) / float64(merged.QueryCount)
+				}
+				out.Heads[name] = merged
+			}
+		}
+	}
+
+	// The same contribution may be on both, from an earlier import
+	type contribution struct {
+		queryID string
+		at      int64
+	}
+	seen := make(map[contribution]bool)
+	for _, c := range append(append([]AttentionContribution{}, a.Contributions...), b.Contributions...) {
+		key := contribution{c.QueryID, c.At.UnixNano()}
+		if !seen[key] {
+			seen[key] = true
+			out.Contributions = append(out.Contributions, c)
+		}
+	}
+	sort.SliceStable(out.Contributions, func(i, j int) bool {
+		return out.Contributions[i].At.Before(out.Contributions[j].At)
+	})
+	if over := len(out.Contributions) - AttentionContributionLimit; over > 0 {
+		out.Contributions = out.Contributions[over:]
+	}
+	return out
+}
+
+// laterTime returns the later of two RFC3339 times, either of which may be
+// empty
+func laterTime(a, b string) string {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	if errB != nil || (errA == nil && !tb.After(ta)) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestAttentionTransfer(t *testing.T) {
+	ctx := context.Background()
+	newRepo := func(ids ...string) *MemoryRepository {
+		repo := NewMemory()
+		now := time.Now()
+		for _, id := range ids {
+			if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Concept", Created: now, Modified: now}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return repo
+	}
+
+	src := newRepo("a", "b", "c")
+	src.CreateLink(ctx, &core.Link{Source: "a", Target: "b", Type: "MENTIONS", Created: time.Now(), Modified: time.Now()})
+	src.UpdateAttentionEdge(ctx, "a", "b", "q1", 0.8)
+	src.UpdateAttentionEdge(ctx, "a", "b", "q2", 0.4)
+	src.UpdateAttentionEdge(ctx, "b", "c", "q1", 0.5)
+
+	var buf bytes.Buffer
+	n, err := ExportAttention(ctx, src, &buf, true)
+	if err != nil || n != 2 {
+		t.Fatalf("ExportAttention = %d, %v; want 2 edges", n, err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Fatalf("export has %d lines, want 2:\n%s", lines, buf.String())
+	}
+	data := buf.Bytes()
+
+	// Into a rebuilt graph that lacks c, behind an attention store
+	backend := newRepo("a", "b")
+	dst, store, err := WithAttentionStore(ctx, backend, AttentionStoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ImportAttention(ctx, dst, bytes.NewReader(data), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *result != (AttentionImportResult{Imported: 1, Skipped: 1}) {
+		t.Errorf("import = %+v, want 1 imported and 1 skipped", result)
+	}
+	if store.Stats().Edges != 1 {
+		t.Errorf("attention store has %d edges, want 1", store.Stats().Edges)
+	}
+	e, err := ExplainAttentionEdge(ctx, dst, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(e.Weight-0.6) > 1e-9 || e.QueryCount != 2 || len(e.Contributions) != 2 {
+		t.Errorf("imported edge = weight %v, %d queries, %d contributions; want 0.6, 2, 2", e.Weight, e.QueryCount, len(e.Contributions))
+	}
+
+	// Importing again replaces; merging adds the counts
+	if result, _ := ImportAttention(ctx, dst, bytes.NewReader(data), false); result.Replaced != 1 {
+		t.Errorf("second import = %+v, want 1 replaced", result)
+	}
+	dst.UpdateAttentionEdge(ctx, "a", "b", "q3", 0.0)
+	if result, _ := ImportAttention(ctx, dst, bytes.NewReader(data), true); result.Merged != 1 {
+		t.Errorf("merge import = %+v, want 1 merged", result)
+	}
+	e, _ = ExplainAttentionEdge(ctx, dst, "a", "b")
+	if math.Abs(e.Weight-0.48) > 1e-9 || e.QueryCount != 5 || len(e.Contributions) != 3 {
+		t.Errorf("merged edge = weight %v, %d queries, %d contributions; want 0.48, 5, 3", e.Weight, e.QueryCount, len(e.Contributions))
+	}
+
+	// Straight into a backend, where the edge is a link
+	plain := newRepo("a", "b", "c")
+	if _, err := ImportAttention(ctx, plain, bytes.NewReader(data), false); err != nil {
+		t.Fatal(err)
+	}
+	ImportAttention(ctx, plain, bytes.NewReader(data), false)
+	links, _ := plain.GetLinks(ctx, "a")
+	if len(links) != 1 || links[0].Type != "ATTENDED" {
+		t.Errorf("backend links = %+v, want one ATTENDED", links)
+	}
+
+	_, err = ImportAttention(ctx, plain, strings.NewReader(`{"source": "a", "target": "b", "weight": 1}`+"\n"+`{"target": "c"}`), false)
+	if !errors.Is(err, ErrInvalidAttention) || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("malformed import error = %v, want ErrInvalidAttention at record 2", err)
+	}
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// backlinkSnippetLen caps the text preview of each linking node, in runes
+const backlinkSnippetLen = 200
+
+// Backlinks lists the links pointing at a node, grouped by link type
+type Backlinks struct {
+	NodeID string          `json:"node_id"`
+	Count  int             `json:"count"` // All matching links, including any beyond the limit
+	Groups []BacklinkGroup `json:"groups"`
+}
+
+// BacklinkGroup holds the backlinks of one link type
+type BacklinkGroup struct {
+	Type  string     `json:"type"`
+	Count int        `json:"count"`
+	Links []Backlink `json:"links"`
+}
+
+// Backlink is one incoming link with a preview of the node it comes from
+type Backlink struct {
+	Source  string                 `json:"source"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+	Created time.Time              `json:"created"`
+	Preview *NodePreview           `json:"preview,omitempty"` // Absent when the source node is deleted
+}
+
+// NodePreview summarizes a node for navigation
+type NodePreview struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Label   string `json:"label"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// GetBacklinkGroups returns the links pointing at a node, optionally limited
+// to some link types and layers, grouped by type. At most limit links get
+// previews and are listed; counts cover all of them.
+func GetBacklinkGroups(ctx context.Context, repo Repository, nodeID string, linkTypes []string, layers LayerFilter, limit int) (*Backlinks, error) {
+	if _, err := repo.GetNode(ctx, nodeID); err != nil {
+		return nil, fmt.Errorf("node not found: %s", nodeID)
+	}
+	links, err := repo.GetBacklinks(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Type != links[j].Type {
+			return links[i].Type < links[j].Type
+		}
+		return links[i].Source < links[j].Source
+	})
+
+	out := &Backlinks{NodeID: nodeID, Groups: []BacklinkGroup{}}
+	previews := make(map[string]*NodePreview)
+	listed := 0
+	for _, l := range links {
+		if !hasType(linkTypes, l.Type) || !layers.Link(l) {
+			continue
+		}
+		out.Count++
+		if len(out.Groups) == 0 || out.Groups[len(out.Groups)-1].Type != l.Type {
+			out.Groups = append(out.Groups, BacklinkGroup{Type: l.Type, Links: []Backlink{}})
+		}
+		group := &out.Groups[len(out.Groups)-1]
+		group.Count++
+		if listed >= limit {
+			continue
+		}
+		listed++
+
+		preview, ok := previews[l.Source]
+		if !ok {
+			if source, err := repo.GetNode(ctx, l.Source); err == nil {
+				preview = &NodePreview{
+					ID:      source.ID,
+					Type:    source.Type,
+					Label:   NodeLabel(source.ID, source.Meta),
+					Snippet: snippet(source.Content, source.Meta),
+				}
+			}
+			previews[l.Source] = preview
+		}
+		group.Links = append(group.Links, Backlink{Source: l.Source, Meta: l.Meta, Created: l.Created, Preview: preview})
+	}
+	return out, nil
+}
+
+// snippet returns the start of a node's text: its content, or failing that
+// its content or description property
+func snippet(content []byte, meta map[string]interface{}) string {
+	text := ""
+	if len(content) > 0 && utf8.Valid(content) {
+		text = string(content)
+	} else {
+		for _, key := range []string{"content", "description"} {
+			if s, ok := meta[key].(string); ok && s != "" {
+				text = s
+				break
+			}
+		}
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > backlinkSnippetLen {
+		text = string([]rune(text)[:backlinkSnippetLen]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestGetBacklinkGroups(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+
+	nodes := []*core.Node{
+		{ID: "company:acme", Type: "Company", Created: now, Modified: now},
+		{ID: "person:ann", Type: "Person", Meta: map[string]any{"name": "Ann", "description": "Works   on\nrockets"}, Created: now, Modified: now},
+		{ID: "person:bob", Type: "Person", Created: now, Modified: now},
+		{ID: "doc:memo", Type: "Document", Content: []byte("Acme memo"), Created: now, Modified: now},
+	}
+	if err := repo.CreateNodes(ctx, nodes); err != nil {
+		t.Fatalf("CreateNodes: %v", err)
+	}
+	if err := repo.CreateLinks(ctx, []*core.Link{
+		{Source: "person:bob", Target: "company:acme", Type: "WORKS_AT", Created: now, Modified: now},
+		{Source: "person:ann", Target: "company:acme", Type: "WORKS_AT", Created: now, Modified: now},
+		{Source: "doc:memo", Target: "company:acme", Type: "MENTIONS", Created: now, Modified: now},
+		{Source: "company:acme", Target: "person:ann", Type: "EMPLOYS", Created: now, Modified: now},
+	}); err != nil {
+		t.Fatalf("CreateLinks: %v", err)
+	}
+
+	back, err := GetBacklinkGroups(ctx, repo, "company:acme", nil, nil, 2)
+	if err != nil {
+		t.Fatalf("GetBacklinkGroups: %v", err)
+	}
+	if back.Count != 3 || len(back.Groups) != 2 {
+		t.Fatalf("got %d links in %d groups, want 3 in 2", back.Count, len(back.Groups))
+	}
+	mentions, worksAt := back.Groups[0], back.Groups[1]
+	if mentions.Type != "MENTIONS" || mentions.Links[0].Preview.Snippet != "Acme memo" {
+		t.Errorf("first group = %+v, want MENTIONS from doc:memo with content snippet", mentions)
+	}
+	// The limit of 2 leaves the second WORKS_AT link counted but unlisted
+	if worksAt.Type != "WORKS_AT" || worksAt.Count != 2 || len(worksAt.Links) != 1 {
+		t.Fatalf("second group = %+v, want 2 WORKS_AT with 1 listed", worksAt)
+	}
+	if p := worksAt.Links[0].Preview; p.Label != "Ann" || p.Snippet != "Works on rockets" {
+		t.Errorf("preview = %+v, want label Ann and normalized description", p)
+	}
+
+	back, err = GetBacklinkGroups(ctx, repo, "company:acme", []string{"MENTIONS"}, nil, 100)
+	if err != nil || back.Count != 1 {
+		t.Errorf("filtered backlinks = %+v, %v; want 1 MENTIONS link", back, err)
+	}
+	if _, err := GetBacklinkGroups(ctx, repo, "company:none", nil, nil, 100); err == nil {
+		t.Error("expected missing node to be rejected")
+	}
+}
@@ -0,0 +1,142 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A traversal budget caps the work TraverseGraph and GetSubgraph may do
+// for one request. A traversal that runs out stops where it is and returns
+// what it has found so far; the cost reports that it was truncated.
+
+// Budget limits that ran out
+const (
+	BudgetNodes = "nodes"
+	BudgetEdges = "edges"
+	BudgetTime  = "time"
+)
+
+// TraversalBudget caps the nodes visited, links followed and time spent by
+// traversals. Zero fields are unlimited.
+type TraversalBudget struct {
+	MaxNodes int
+	MaxEdges int
+	MaxTime  time.Duration
+}
+
+// Within returns b with each limit lowered to the one in max, where max
+// sets one
+func (b TraversalBudget) Within(max TraversalBudget) TraversalBudget {
+	clamp := func(v, limit int) int {
+		if limit > 0 && (v <= 0 || v > limit) {
+			return limit
+		}
+		return v
+	}
+	b.MaxNodes = clamp(b.MaxNodes, max.MaxNodes)
+	b.MaxEdges = clamp(b.MaxEdges, max.MaxEdges)
+	b.MaxTime = time.Duration(clamp(int(b.MaxTime), int(max.MaxTime)))
+	return b
+}
+
+// TraversalCost reports the work traversals did under a budget
+type TraversalCost struct {
+	VisitedNodes int    `json:"visited_nodes"`
+	VisitedEdges int    `json:"visited_edges"`
+	ElapsedMS    int64  `json:"elapsed_ms"`
+	Truncated    bool   `json:"truncated"`
+	Limit        string `json:"limit,omitempty"` // The budget that ran out: nodes, edges or time
+}
+
+// TraversalMeter counts traversal work against a budget. A nil meter is
+// unlimited.
+type TraversalMeter struct {
+	budget TraversalBudget
+	start  time.Time
+
+	mu   sync.Mutex
+	cost TraversalCost
+}
+
+type traversalMeterKey struct{}
+
+// ContextWithTraversalBudget limits traversals made with ctx to budget.
+// The returned meter reports their cost.
+func ContextWithTraversalBudget(ctx context.Context, budget TraversalBudget) (context.Context, *TraversalMeter) {
+	m := &TraversalMeter{budget: budget, start: time.Now()}
+	return context.WithValue(ctx, traversalMeterKey{}, m), m
+}
+
+// TraversalMeterFrom returns the meter of ctx, or nil without a budget
+func TraversalMeterFrom(ctx context.Context) *TraversalMeter {
+	m, _ := ctx.Value(traversalMeterKey{}).(*TraversalMeter)
+	return m
+}
+
+// Node counts a visited node, reporting false once the budget has run out
+func (m *TraversalMeter) Node() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.spend(m.cost.VisitedNodes, m.budget.MaxNodes, BudgetNodes) {
+		return false
+	}
+	m.cost.VisitedNodes++
+	return true
+}
+
+// Edge counts a followed link, reporting false once the budget has run out
+func (m *TraversalMeter) Edge() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.spend(m.cost.VisitedEdges, m.budget.MaxEdges, BudgetEdges) {
+		return false
+	}
+	m.cost.VisitedEdges++
+	return true
+}
+
+// spend checks used against max and the time budget, marking the cost
+// truncated when either has run out; callers hold m.mu
+func (m *TraversalMeter) spend(used, max int, limit string) bool {
+	if m.cost.Truncated {
+		return false
+	}
+	switch {
+	case max > 0 && used >= max:
+	case m.budget.MaxTime > 0 && time.Since(m.start) >= m.budget.MaxTime:
+		limit = BudgetTime
+	default:
+		return true
+	}
+	m.cost.Truncated, m.cost.Limit = true, limit
+	return false
+}
+
+// Truncated reports whether a traversal ran out of budget
+func (m *TraversalMeter) Truncated() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cost.Truncated
+}
+
+// Cost returns the work done so far
+func (m *TraversalMeter) Cost() TraversalCost {
+	if m == nil {
+		return TraversalCost{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cost := m.cost
+	cost.ElapsedMS = time.Since(m.start).Milliseconds()
+	return cost
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestTraversalBudgetWithin(t *testing.T) {
+	max := TraversalBudget{MaxNodes: 100, MaxTime: time.Second}
+	got := TraversalBudget{MaxNodes: 500, MaxEdges: 20}.Within(max)
+	if got.MaxNodes != 100 || got.MaxEdges != 20 || got.MaxTime != time.Second {
+		t.Errorf("Within = %+v", got)
+	}
+	if got := (TraversalBudget{MaxNodes: 10}).Within(max); got.MaxNodes != 10 {
+		t.Errorf("lower request raised to %d", got.MaxNodes)
+	}
+}
+
+func TestTraversalBudget(t *testing.T) {
+	ctx := context.Background()
+
+	for _, backend := range []string{"sqlite", "memory"} {
+		t.Run(backend, func(t *testing.T) {
+			var repo Repository = NewMemory()
+			if backend == "sqlite" {
+				sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+				if err != nil {
+					t.Fatalf("NewSQLite() error = %v", err)
+				}
+				defer sqlite.Close(ctx)
+				repo = sqlite
+			}
+
+			// hub -> n0..n9, each n -> hub
+			now := time.Now()
+			nodes := []*core.Node{{ID: "hub", Type: "Note", Created: now, Modified: now}}
+			var links []*core.Link
+			for i := 0; i < 10; i++ {
+				id := fmt.Sprintf("n%d", i)
+				nodes = append(nodes, &core.Node{ID: id, Type: "Note", Created: now, Modified: now})
+				links = append(links,
+					&core.Link{Source: "hub", Target: id, Type: "HAS", Created: now, Modified: now},
+					&core.Link{Source: id, Target: "hub", Type: "IN", Created: now, Modified: now})
+			}
+			if err := repo.CreateNodes(ctx, nodes); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.CreateLinks(ctx, links); err != nil {
+				t.Fatal(err)
+			}
+
+			full, meter := ContextWithTraversalBudget(ctx, TraversalBudget{MaxNodes: 100})
+			found, err := repo.TraverseGraph(full, "hub", 2, nil, 100, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cost := meter.Cost(); len(found) != 11 || cost.Truncated || cost.VisitedNodes != 11 || cost.VisitedEdges != 20 {
+				t.Errorf("unlimited: %d nodes, cost %+v", len(found), cost)
+			}
+
+			limited, meter := ContextWithTraversalBudget(ctx, TraversalBudget{MaxNodes: 4})
+			found, err = repo.TraverseGraph(limited, "hub", 2, nil, 100, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cost := meter.Cost(); len(found) != 4 || !cost.Truncated || cost.Limit != BudgetNodes {
+				t.Errorf("node budget: %d nodes, cost %+v", len(found), cost)
+			}
+
+			limited, meter = ContextWithTraversalBudget(ctx, TraversalBudget{MaxEdges: 2})
+			sub, err := repo.GetSubgraph(limited, "hub", 2, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cost := meter.Cost(); len(sub.Nodes) != 3 || !cost.Truncated || cost.Limit != BudgetEdges {
+				t.Errorf("edge budget: %d nodes, cost %+v", len(sub.Nodes), cost)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package graph
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// lruCache is a size- and TTL-bounded least-recently-used cache
+type lruCache[V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // Front is most recently used
+	entries map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+func newLRUCache[V any](size int, ttl time.Duration) *lruCache[V] {
+	return &lruCache[V]{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached value for key if present and not expired
+func (c *lruCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[V])
+	if c.ttl > 0 && time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// put stores value under key, evicting the least recently used entry when full
+func (c *lruCache[V]) put(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry[V])
+		entry.value = value
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+// remove drops key from the cache
+func (c *lruCache[V]) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// clear drops every entry
+func (c *lruCache[V]) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// CacheConfig bounds the read cache
+type CacheConfig struct {
+	Size int           // Maximum entries per cache (nodes, subgraphs, graph maps)
+	TTL  time.Duration // Maximum age of an entry; 0 keeps entries until evicted or invalidated
+}
+
+// cachedRepository serves hot reads (GetNode, GetSubgraph, GetGraphMap) from
+// memory. Entries are invalidated by repository events, so the cache stays
+// consistent with writes made through the backend. Reads pinned to a
+// snapshot skip the cache.
+// Cached values are shared between callers and must not be modified.
+type cachedRepository struct {
+	Repository
+
+	nodes     *lruCache[*core.Node]
+	subgraphs *lruCache[*Subgraph]
+	maps      *lruCache[*GraphMap]
+
+	emitterMu sync.RWMutex
+	emitter   func(subscriptions.Event)
+}
+
+// WithCache wraps repo with an in-memory LRU cache for hot reads
+func WithCache(repo Repository, cfg CacheConfig) Repository {
+	c := &cachedRepository{
+		Repository: repo,
+		nodes:      newLRUCache[*core.Node](cfg.Size, cfg.TTL),
+		subgraphs:  newLRUCache[*Subgraph](cfg.Size, cfg.TTL),
+		maps:       newLRUCache[*GraphMap](cfg.Size, cfg.TTL),
+	}
+	// Always listen for events, even before a downstream emitter is set
+	repo.SetEventEmitter(c.onEvent)
+	return c
+}
+
+// SetEventEmitter forwards events to emitter after invalidating cache entries
+func (c *cachedRepository) SetEventEmitter(emitter func(subscriptions.Event)) {
+	c.emitterMu.Lock()
+	c.emitter = emitter
+	c.emitterMu.Unlock()
+}
+
+// onEvent invalidates entries affected by a repository change
+func (c *cachedRepository) onEvent(event subscriptions.Event) {
+	if event.NodeID != "" {
+		c.nodes.remove(event.NodeID)
+	}
+	// Any node or link change can alter subgraphs and graph statistics
+	c.subgraphs.clear()
+	c.maps.clear()
+
+	c.emitterMu.RLock()
+	emitter := c.emitter
+	c.emitterMu.RUnlock()
+	if emitter != nil {
+		emitter(event)
+	}
+}
+
+// invalidateGraph drops structure caches after writes that don't emit events
+func (c *cachedRepository) invalidateGraph() {
+	c.subgraphs.clear()
+	c.maps.clear()
+}
+
+func (c *cachedRepository) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	if IncludesDeleted(ctx) || SnapshotFrom(ctx) != nil {
+		return c.Repository.GetNode(ctx, id)
+	}
+	if node, ok := c.nodes.get(id); ok {
+		return node, nil
+	}
+	node, err := c.Repository.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.nodes.put(id, node)
+	return node, nil
+}
+
+func (c *cachedRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
+	if SnapshotFrom(ctx) != nil {
+		return c.Repository.GetSubgraph(ctx, startNodeID, depth, relationshipTypes)
+	}
+	key := fmt.Sprintf("%s|%d|%s", startNodeID, depth, strings.Join(relationshipTypes, ","))
+	if subgraph, ok := c.subgraphs.get(key); ok {
+		return subgraph, nil
+	}
+	subgraph, err := c.Repository.GetSubgraph(ctx, startNodeID, depth, relationshipTypes)
+	if err != nil {
+		return nil, err
+	}
+	if !TraversalMeterFrom(ctx).Truncated() {
+		c.subgraphs.put(key, subgraph)
+	}
+	return subgraph, nil
+}
+
+func (c *cachedRepository) GetGraphMap(ctx context.Context, sampleSize int) (*GraphMap, error) {
+	if SnapshotFrom(ctx) != nil {
+		return c.Repository.GetGraphMap(ctx, sampleSize)
+	}
+	key := fmt.Sprint(sampleSize)
+	if graphMap, ok := c.maps.get(key); ok {
+		return graphMap, nil
+	}
+	graphMap, err := c.Repository.GetGraphMap(ctx, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+	c.maps.put(key, graphMap)
+	return graphMap, nil
+}
+
+// Writes that change graph structure without emitting events
+
+func (c *cachedRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	defer c.invalidateGraph()
+	return c.Repository.UpdateAttentionEdge(ctx, source, target, queryID, weight)
+}
+
+func (c *cachedRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight float64, minQueryCount int) (int, error) {
+	defer c.invalidateGraph()
+	return c.Repository.PruneWeakAttentionEdges(ctx, minWeight, minQueryCount)
+}
+
+func (c *cachedRepository) RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error) {
+	if !opts.DryRun {
+		defer c.invalidateGraph()
+	}
+	return c.Repository.RecomputeDegrees(ctx, opts)
+}
+
+func (c *cachedRepository) CreateInterpretedThroughLink(ctx context.Context, entityID, lensID string, meta map[string]interface{}) error {
+	defer c.invalidateGraph()
+	return c.Repository.CreateInterpretedThroughLink(ctx, entityID, lensID, meta)
+}
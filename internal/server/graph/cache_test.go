@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	c := newLRUCache[int](2, 0)
+	c.put("a", 1)
+	c.put("b", 2)
+	c.get("a") // a is now most recently used
+	c.put("c", 3)
+
+	if _, ok := c.get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Errorf("get(a) = %d, %v; want 1, true", v, ok)
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	c := newLRUCache[int](10, time.Millisecond)
+	c.put("a", 1)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.get("a"); ok {
+		t.Error("expected expired entry to be missing")
+	}
+}
+
+func TestCachedRepositoryInvalidatesOnEvents(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	repo := WithCache(sqlite, CacheConfig{Size: 10, TTL: time.Minute})
+	var forwarded int
+	repo.SetEventEmitter(func(subscriptions.Event) { forwarded++ })
+
+	now := time.Now()
+	if err := repo.CreateNode(ctx, &core.Node{ID: "n1", Type: "Note", Meta: map[string]interface{}{"v": 1.0}, Created: now, Modified: now}); err != nil {
+		t.Fatalf("CreateNode() error = %v", err)
+	}
+
+	first, err := repo.GetNode(ctx, "n1")
+	if err != nil {
+		t.Fatalf("GetNode() error = %v", err)
+	}
+	if cached, _ := repo.GetNode(ctx, "n1"); cached != first {
+		t.Error("second GetNode() was not served from cache")
+	}
+
+	if err := repo.UpdateNodeMeta(ctx, "n1", map[string]any{"v": 2.0}); err != nil {
+		t.Fatalf("UpdateNodeMeta() error = %v", err)
+	}
+	updated, err := repo.GetNode(ctx, "n1")
+	if err != nil {
+		t.Fatalf("GetNode() error = %v", err)
+	}
+	if updated.Meta["v"] != 2.0 {
+		t.Errorf("GetNode() after update returned stale meta %v", updated.Meta)
+	}
+	if forwarded != 2 {
+		t.Errorf("forwarded %d events, want 2", forwarded)
+	}
+}
@@ -0,0 +1,152 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// Delete cascade modes: what a soft delete does with the node's links
+const (
+	CascadeKeep      = "keep"      // Leave links in place, pointing at the tombstone
+	CascadeTombstone = "tombstone" // Delete the node's links, recording them for graph diffs
+	CascadeRewire    = "rewire"    // Link each source straight to each target, then delete the links
+	CascadeRestrict  = "restrict"  // Refuse to delete a node that still has links
+)
+
+// RewiredThroughKey records the deleted node a rewired link used to pass through
+const RewiredThroughKey = "rewired_through"
+
+// ErrNodeHasLinks is returned when CascadeRestrict blocks a deletion
+var ErrNodeHasLinks = errors.New("node still has links")
+
+// ValidateCascade checks a cascade mode name
+func ValidateCascade(mode string) error {
+	switch mode {
+	case CascadeKeep, CascadeTombstone, CascadeRewire, CascadeRestrict:
+		return nil
+	}
+	return fmt.Errorf("invalid cascade mode %q (use keep, tombstone, rewire or restrict)", mode)
+}
+
+type cascadeKey struct{}
+
+// ContextWithCascade overrides the configured cascade mode for deletes
+// made with ctx
+func ContextWithCascade(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, cascadeKey{}, mode)
+}
+
+// cascadeRepository applies a cascade mode to soft deletes. Hard deletes
+// already remove every link along with the node, in all backends.
+type cascadeRepository struct {
+	Repository
+	mode string
+
+	mu sync.Mutex
+}
+
+// WithDeleteCascade wraps repo so soft deletes handle the node's links
+// according to mode, unless the request context overrides it
+func WithDeleteCascade(repo Repository, mode string) Repository {
+	return &cascadeRepository{Repository: repo, mode: mode}
+}
+
+func (c *cascadeRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	mode := c.mode
+	if m, ok := ctx.Value(cascadeKey{}).(string); ok && m != "" {
+		mode = m
+	}
+	if force || mode == CascadeKeep {
+		return c.Repository.DeleteNode(ctx, nodeID, force)
+	}
+
+	// Serialized so concurrent deletes of neighbours cannot rewire through each other
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	outgoing, err := c.GetLinks(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	incoming, err := c.GetBacklinks(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	if mode == CascadeRestrict && len(outgoing)+len(incoming) > 0 {
+		return fmt.Errorf("cannot delete %s: %w (%d outgoing, %d incoming)", nodeID, ErrNodeHasLinks, len(outgoing), len(incoming))
+	}
+
+	if err := c.Repository.DeleteNode(ctx, nodeID, false); err != nil {
+		return err
+	}
+
+	if mode == CascadeRewire {
+		if err := c.rewire(ctx, nodeID, incoming, outgoing); err != nil {
+			return fmt.Errorf("rewiring links of %s: %w", nodeID, err)
+		}
+	}
+	removed := make(map[[3]string]bool)
+	for _, l := range append(outgoing, incoming...) {
+		key := [3]string{l.Source, l.Target, l.Type}
+		if removed[key] {
+			continue // A self-link is both outgoing and incoming
+		}
+		removed[key] = true
+		if err := c.DeleteLink(ctx, l.Source, l.Target, l.Type); err != nil {
+			return fmt.Errorf("removing links of %s: %w", nodeID, err)
+		}
+	}
+	return nil
+}
+
+// rewire links every source of an incoming link to every target of an
+// outgoing link, keeping the incoming link's type and properties. Links
+// that already exist and links back to the source are skipped.
+func (c *cascadeRepository) rewire(ctx context.Context, nodeID string, incoming, outgoing []*core.Link) error {
+	existing := make(map[string]map[[2]string]bool)
+	for _, in := range incoming {
+		if in.Source == nodeID {
+			continue
+		}
+		if existing[in.Source] == nil {
+			links, err := c.GetLinks(ctx, in.Source)
+			if err != nil {
+				return err
+			}
+			existing[in.Source] = make(map[[2]string]bool, len(links))
+			for _, l := range links {
+				existing[in.Source][[2]string{l.Target, l.Type}] = true
+			}
+		}
+		for _, out := range outgoing {
+			if out.Target == nodeID || out.Target == in.Source || existing[in.Source][[2]string{out.Target, in.Type}] {
+				continue
+			}
+			meta := make(map[string]interface{}, len(in.Meta)+1)
+			for k, v := range in.Meta {
+				meta[k] = v
+			}
+			meta[RewiredThroughKey] = nodeID
+			now := time.Now()
+			if err := c.CreateLink(ctx, &core.Link{
+				Source:        in.Source,
+				Target:        out.Target,
+				Type:          in.Type,
+				Meta:          meta,
+				Weight:        in.Weight,
+				Bidirectional: in.Bidirectional,
+				Created:       now,
+				Modified:      now,
+			}); err != nil {
+				return err
+			}
+			existing[in.Source][[2]string{out.Target, in.Type}] = true
+		}
+	}
+	return nil
+}
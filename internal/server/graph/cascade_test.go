@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestDeleteCascade(t *testing.T) {
+	ctx := context.Background()
+
+	for _, backend := range []string{"sqlite", "memory"} {
+		for _, mode := range []string{CascadeKeep, CascadeTombstone, CascadeRewire, CascadeRestrict} {
+			t.Run(backend+"/"+mode, func(t *testing.T) {
+				var inner Repository = NewMemory()
+				if backend == "sqlite" {
+					sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+					if err != nil {
+						t.Fatalf("NewSQLite() error = %v", err)
+					}
+					defer sqlite.Close(ctx)
+					inner = sqlite
+				}
+				repo := WithDeleteCascade(inner, mode)
+
+				now := time.Now()
+				for _, id := range []string{"a", "b", "c"} {
+					if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Created: now, Modified: now}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := repo.CreateLinks(ctx, []*core.Link{
+					{Source: "a", Target: "b", Type: "CITES", Meta: map[string]interface{}{"page": "4"}, Created: now, Modified: now},
+					{Source: "b", Target: "c", Type: "CITES", Created: now, Modified: now},
+				}); err != nil {
+					t.Fatal(err)
+				}
+
+				err := repo.DeleteNode(ctx, "b", false)
+				if mode == CascadeRestrict {
+					if !errors.Is(err, ErrNodeHasLinks) {
+						t.Fatalf("DeleteNode error = %v, want ErrNodeHasLinks", err)
+					}
+					if _, err := repo.GetNode(ctx, "b"); err != nil {
+						t.Errorf("restricted node was deleted: %v", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("DeleteNode: %v", err)
+				}
+
+				links, _ := repo.GetLinks(ctx, "a")
+				report, _ := repo.CheckIntegrity(ctx, IntegrityOptions{})
+				switch mode {
+				case CascadeKeep:
+					if len(links) != 1 || report.Counts.DanglingLinks != 2 {
+						t.Errorf("keep: links from a = %d, dangling = %d; want 1 and 2", len(links), report.Counts.DanglingLinks)
+					}
+				case CascadeTombstone:
+					if len(links) != 0 || report.Counts.DanglingLinks != 0 {
+						t.Errorf("tombstone: links from a = %d, dangling = %d; want none", len(links), report.Counts.DanglingLinks)
+					}
+				case CascadeRewire:
+					if len(links) != 1 || links[0].Target != "c" || links[0].Type != "CITES" {
+						t.Fatalf("rewire: links from a = %+v, want a -[CITES]-> c", links)
+					}
+					if links[0].Meta[RewiredThroughKey] != "b" || links[0].Meta["page"] != "4" {
+						t.Errorf("rewired link meta = %v, want page kept and rewired_through b", links[0].Meta)
+					}
+					if report.Counts.DanglingLinks != 0 {
+						t.Errorf("rewire left dangling links: %+v", report.DanglingLinks)
+					}
+				}
+			})
+		}
+	}
+}
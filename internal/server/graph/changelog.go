@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// The version history (every node version, links and link tombstones) is
+// the graph's changelog. ChangeLog reads it back as the events emitted when
+// each change was made, so subscribers can replay what they missed. Replayed
+// node.updated events carry the full properties of the new version as
+// updated_meta, since the history keeps versions rather than patches.
+
+// DefaultChangeLogLimit caps a ChangeLog read when no limit is given
+const DefaultChangeLogLimit = 1000
+
+// nodeVersionEvent rebuilds the event that wrote a stored node version
+func nodeVersionEvent(id, nodeType string, version int, modified time.Time, deleted bool, changeNote string, meta map[string]interface{}) subscriptions.Event {
+	event := subscriptions.Event{
+		ID:        fmt.Sprintf("%s@v%d", id, version),
+		Timestamp: modified,
+		NodeID:    id,
+		NodeType:  nodeType,
+	}
+	switch {
+	case deleted:
+		event.Type = subscriptions.EventNodeDeleted
+		event.NodeType = ""
+	case version <= 1:
+		event.Type = subscriptions.EventNodeCreated
+		event.Meta = meta
+	default:
+		event.Type = subscriptions.EventNodeUpdated
+		event.Meta = map[string]interface{}{
+			"version":      version,
+			"prev_version": version - 1,
+			"change_note":  changeNote,
+			"updated_meta": meta,
+		}
+	}
+	return event
+}
+
+// linkEvent rebuilds a link.created or link.deleted event
+func linkEvent(eventType string, edge *SubgraphEdge, at time.Time) subscriptions.Event {
+	event := subscriptions.Event{
+		ID:         fmt.Sprintf("%s:%s-[%s]->%s@%s", eventType, edge.Source, edge.Type, edge.Target, at.UTC().Format(time.RFC3339Nano)),
+		Type:       eventType,
+		Timestamp:  at,
+		LinkSource: edge.Source,
+		LinkTarget: edge.Target,
+		LinkType:   edge.Type,
+	}
+	if eventType == subscriptions.EventLinkCreated {
+		event.Meta = edge.Meta
+	}
+	return event
+}
+
+// sortChangeLog orders events oldest first and keeps the first limit
+func sortChangeLog(events []subscriptions.Event, limit int) []subscriptions.Event {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+func changeLogLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultChangeLogLimit
+	}
+	return limit
+}
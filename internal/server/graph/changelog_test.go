@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestChangeLog(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, repo := range map[string]Repository{"sqlite": sqlite, "memory": NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			since := now.Add(-time.Minute)
+			for _, n := range []*core.Node{
+				{ID: "invoice:1", Type: "Invoice", Meta: map[string]interface{}{"status": "pending"}},
+				{ID: "org:acme", Type: "Organization"},
+			} {
+				n.Created, n.Modified = now, now
+				if err := repo.CreateNode(ctx, n); err != nil {
+					t.Fatal(err)
+				}
+			}
+			link := &core.Link{Source: "invoice:1", Target: "org:acme", Type: "BILLED_TO", Created: now, Modified: now}
+			if err := repo.CreateLink(ctx, link); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.UpdateNodeMeta(ctx, "invoice:1", map[string]interface{}{"status": "paid"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.DeleteLink(ctx, "invoice:1", "org:acme", "BILLED_TO"); err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := repo.ChangeLog(ctx, since, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts := make(map[string]int)
+			for _, e := range events {
+				counts[e.Type]++
+				if e.Type == subscriptions.EventNodeUpdated {
+					updated, _ := e.Meta["updated_meta"].(map[string]interface{})
+					if updated["status"] != "paid" {
+						t.Errorf("updated event meta = %v", e.Meta)
+					}
+				}
+				if e.Type == subscriptions.EventNodeCreated && e.NodeID == "invoice:1" && e.Meta["status"] != "pending" {
+					t.Errorf("created event meta = %v", e.Meta)
+				}
+			}
+			want := map[string]int{
+				subscriptions.EventNodeCreated: 2,
+				subscriptions.EventNodeUpdated: 1,
+				subscriptions.EventLinkCreated: 1,
+				subscriptions.EventLinkDeleted: 1,
+			}
+			for typ, n := range want {
+				if counts[typ] != n {
+					t.Errorf("%s events = %d, want %d (all: %v)", typ, counts[typ], n, counts)
+				}
+			}
+
+			if limited, _ := repo.ChangeLog(ctx, since, 2); len(limited) != 2 {
+				t.Errorf("limit 2 returned %d events", len(limited))
+			}
+			if later, _ := repo.ChangeLog(ctx, now.Add(time.Minute), 0); len(later) != 0 {
+				t.Errorf("expected no events after now, got %d", len(later))
+			}
+		})
+	}
+}
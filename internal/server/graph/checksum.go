@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// ContentHashKey is the node property recording the sha256 of its content
+const ContentHashKey = "content_sha256"
+
+// ContentNormalizationKey is the node property naming the normalization
+// applied to content before it was hashed and stored
+const ContentNormalizationKey = "content_normalization"
+
+// ErrContentCorrupt is returned when stored content no longer matches its
+// recorded hash
+var ErrContentCorrupt = errors.New("content hash mismatch")
+
+// ContentDigest pins a version of content: its hash and size, and how its
+// bytes are encoded. The hash is over the bytes as stored, after any
+// normalization on ingest.
+type ContentDigest struct {
+	SHA256        string `json:"sha256"`
+	Size          int    `json:"size_bytes"`
+	Encoding      string `json:"encoding"`     // utf-8, or binary when not valid UTF-8
+	LineEndings   string `json:"line_endings"` // lf, crlf, cr, mixed or none
+	BOM           bool   `json:"bom,omitempty"`
+	Normalization string `json:"normalization"` // Steps applied on ingest, e.g. newlines+trailing_whitespace, or none
+}
+
+// DigestContent describes content
+func DigestContent(content []byte) *ContentDigest {
+	sum := sha256.Sum256(content)
+	d := &ContentDigest{
+		SHA256:        hex.EncodeToString(sum[:]),
+		Size:          len(content),
+		Encoding:      "utf-8",
+		LineEndings:   lineEndings(content),
+		BOM:           bytes.HasPrefix(content, []byte("\xef\xbb\xbf")),
+		Normalization: "none",
+	}
+	if !utf8.Valid(content) {
+		d.Encoding = "binary"
+	}
+	return d
+}
+
+// DigestNode describes a node's content, with the normalization recorded on it
+func DigestNode(node *core.Node) *ContentDigest {
+	d := DigestContent(node.Content)
+	if steps, ok := node.Meta[ContentNormalizationKey].(string); ok && steps != "" {
+		d.Normalization = steps
+	}
+	return d
+}
+
+// lineEndings names the line terminators content uses
+func lineEndings(content []byte) string {
+	crlf := bytes.Count(content, []byte("\r\n"))
+	lf := bytes.Count(content, []byte("\n")) - crlf
+	cr := bytes.Count(content, []byte("\r")) - crlf
+	kinds, name := 0, "none"
+	for _, k := range []struct {
+		n    int
+		name string
+	}{{lf, "lf"}, {crlf, "crlf"}, {cr, "cr"}} {
+		if k.n > 0 {
+			kinds++
+			name = k.name
+		}
+	}
+	if kinds > 1 {
+		return "mixed"
+	}
+	return name
+}
+
+// ExpectedContentHash returns the hash a node's content should have: its
+// content_sha256 property, else the hash in a content-addressed sha256: ID
+func ExpectedContentHash(node *core.Node) (string, bool) {
+	if hash, ok := node.Meta[ContentHashKey].(string); ok && hash != "" {
+		return strings.ToLower(hash), true
+	}
+	if hash, ok := strings.CutPrefix(node.ID, "sha256:"); ok && len(hash) == sha256.Size*2 {
+		return strings.ToLower(hash), true
+	}
+	return "", false
+}
+
+// VerifyContent checks a node's content against its expected hash,
+// returning ErrContentCorrupt when they differ. Nodes without content or
+// without an expected hash pass.
+func VerifyContent(node *core.Node) error {
+	if len(node.Content) == 0 {
+		return nil
+	}
+	want, ok := ExpectedContentHash(node)
+	if !ok {
+		return nil
+	}
+	sum := sha256.Sum256(node.Content)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("%w: %s version %d has sha256 %s, expected %s", ErrContentCorrupt, node.ID, node.Version, got, want)
+	}
+	return nil
+}
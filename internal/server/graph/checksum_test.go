@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestDigestContent(t *testing.T) {
+	d := DigestContent([]byte("\xef\xbb\xbfhello\r\nworld\r\n"))
+	if d.SHA256 == "" || d.Size != 17 || d.Encoding != "utf-8" || d.LineEndings != "crlf" || !d.BOM || d.Normalization != "none" {
+		t.Errorf("digest = %+v", d)
+	}
+	for content, want := range map[string]string{"a\nb": "lf", "a\rb\nc": "mixed", "ab": "none", "a\r\nb\nc": "mixed"} {
+		if got := DigestContent([]byte(content)).LineEndings; got != want {
+			t.Errorf("line endings of %q = %s, want %s", content, got, want)
+		}
+	}
+	if d := DigestContent([]byte{0xff, 0xfe, 0x00}); d.Encoding != "binary" {
+		t.Errorf("encoding = %s, want binary", d.Encoding)
+	}
+}
+
+func TestVerifyContent(t *testing.T) {
+	hello := DigestContent([]byte("hello")).SHA256
+	for _, tc := range []struct {
+		name string
+		node *core.Node
+		ok   bool
+	}{
+		{"content-addressed", &core.Node{ID: "sha256:" + hello, Content: []byte("hello")}, true},
+		{"content-addressed corrupt", &core.Node{ID: "sha256:" + hello, Content: []byte("hellO")}, false},
+		{"recorded hash", &core.Node{ID: "doc:1", Content: []byte("hello"), Meta: map[string]interface{}{ContentHashKey: hello}}, true},
+		{"recorded hash corrupt", &core.Node{ID: "doc:1", Content: []byte("bye"), Meta: map[string]interface{}{ContentHashKey: hello}}, false},
+		{"no expected hash", &core.Node{ID: "doc:2", Content: []byte("bye")}, true},
+		{"no content", &core.Node{ID: "sha256:" + hello}, true},
+	} {
+		err := VerifyContent(tc.node)
+		if tc.ok && err != nil || !tc.ok && !errors.Is(err, ErrContentCorrupt) {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
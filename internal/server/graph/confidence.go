@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ConfidenceKey is the standard link property holding an extraction's
+// confidence, a number in [0, 1]
+const ConfidenceKey = "confidence"
+
+// NormalizeConfidence coerces meta[ConfidenceKey] to a float64 in [0, 1].
+// Numeric strings are accepted; a missing value is left alone.
+func NormalizeConfidence(meta map[string]interface{}) error {
+	raw, ok := meta[ConfidenceKey]
+	if !ok || raw == nil {
+		return nil
+	}
+
+	var c float64
+	switch v := raw.(type) {
+	case float64:
+		c = v
+	case int:
+		c = float64(v)
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not a number", ConfidenceKey, v)
+		}
+		c = f
+	default:
+		return fmt.Errorf("invalid %s: expected a number", ConfidenceKey)
+	}
+
+	if c < 0 || c > 1 {
+		return fmt.Errorf("invalid %s %v: must be between 0 and 1", ConfidenceKey, c)
+	}
+	meta[ConfidenceKey] = c
+	return nil
+}
+
+// LinkConfidence returns the confidence stored on a link's properties
+func LinkConfidence(meta map[string]interface{}) (float64, bool) {
+	c, ok := meta[ConfidenceKey].(float64)
+	return c, ok
+}
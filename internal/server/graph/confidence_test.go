@@ -0,0 +1,29 @@
+package graph
+
+import "testing"
+
+func TestNormalizeConfidence(t *testing.T) {
+	tests := []struct {
+		in      interface{}
+		want    float64
+		wantErr bool
+	}{
+		{0.75, 0.75, false},
+		{"0.2", 0.2, false},
+		{1, 1, false},
+		{1.5, 0, true},
+		{"high", 0, true},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		meta := map[string]interface{}{ConfidenceKey: tt.in}
+		err := NormalizeConfidence(meta)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeConfidence(%v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && meta[ConfidenceKey] != tt.want {
+			t.Errorf("NormalizeConfidence(%v) = %v, want %v", tt.in, meta[ConfidenceKey], tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package graph_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/graph/graphtest"
+)
+
+// The conformance suite runs against SQLite and the in-memory backend
+// always, and against Neo4j and Postgres when MEMEX_TEST_NEO4J_URI or
+// MEMEX_TEST_POSTGRES_DSN point at a server (for example one started with
+// docker in CI). The suite keeps to its own nodes, so the server need not
+// be empty.
+
+func TestConformanceSQLite(t *testing.T) {
+	graphtest.Run(t, func(t *testing.T) graph.Repository {
+		repo, err := graph.NewSQLite(context.Background(), filepath.Join(t.TempDir(), "memex.db"))
+		if err != nil {
+			t.Fatalf("NewSQLite() error = %v", err)
+		}
+		t.Cleanup(func() { repo.Close(context.Background()) })
+		return repo
+	})
+}
+
+func TestConformanceMemory(t *testing.T) {
+	graphtest.Run(t, func(t *testing.T) graph.Repository {
+		return graph.NewMemory()
+	})
+}
+
+func TestConformanceNeo4j(t *testing.T) {
+	uri := os.Getenv("MEMEX_TEST_NEO4J_URI")
+	if uri == "" {
+		t.Skip("MEMEX_TEST_NEO4J_URI not set")
+	}
+	graphtest.Run(t, func(t *testing.T) graph.Repository {
+		repo, err := graph.NewNeo4j(context.Background(), graph.Config{
+			URI:      uri,
+			Username: envOr("MEMEX_TEST_NEO4J_USER", "neo4j"),
+			Password: envOr("MEMEX_TEST_NEO4J_PASSWORD", "password"),
+		})
+		if err != nil {
+			t.Fatalf("NewNeo4j() error = %v", err)
+		}
+		t.Cleanup(func() { repo.Close(context.Background()) })
+		return repo
+	})
+}
+
+func TestConformancePostgres(t *testing.T) {
+	dsn := os.Getenv("MEMEX_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("MEMEX_TEST_POSTGRES_DSN not set")
+	}
+	graphtest.Run(t, func(t *testing.T) graph.Repository {
+		repo, err := graph.NewPostgres(context.Background(), dsn)
+		if err != nil {
+			t.Fatalf("NewPostgres() error = %v", err)
+		}
+		t.Cleanup(func() { repo.Close(context.Background()) })
+		return repo
+	})
+}
+
+// envOr returns the environment variable key, or def when it is unset
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
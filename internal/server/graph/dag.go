@@ -0,0 +1,219 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// ErrCycle is returned when a link would close a cycle among DAG-constrained link types
+var ErrCycle = errors.New("link would create a cycle")
+
+// DefaultDAGLinkTypes are the derivation link types that must stay acyclic
+var DefaultDAGLinkTypes = []string{"EXTRACTED_FROM", "DERIVED_FROM"}
+
+// dagRepository rejects links of constrained types that would introduce a
+// cycle. Checks and writes are serialized so concurrent requests cannot
+// together close a loop that neither would alone.
+type dagRepository struct {
+	Repository
+	types map[string]bool
+
+	mu sync.Mutex
+}
+
+// WithDAGConstraint wraps repo so that links of the given types must form a
+// DAG (cycles are checked across all constrained types together)
+func WithDAGConstraint(repo Repository, linkTypes []string) Repository {
+	types := make(map[string]bool, len(linkTypes))
+	for _, t := range linkTypes {
+		types[t] = true
+	}
+	return &dagRepository{Repository: repo, types: types}
+}
+
+func (d *dagRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	if !d.types[link.Type] {
+		return d.Repository.CreateLink(ctx, link)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkAcyclic(ctx, []*core.Link{link}); err != nil {
+		return err
+	}
+	return d.Repository.CreateLink(ctx, link)
+}
+
+func (d *dagRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkAcyclic(ctx, links); err != nil {
+		return err
+	}
+	return d.Repository.CreateLinks(ctx, links)
+}
+
+func (d *dagRepository) CreateInterpretedThroughLink(ctx context.Context, entityID, lensID string, meta map[string]interface{}) error {
+	if !d.types["INTERPRETED_THROUGH"] {
+		return d.Repository.CreateInterpretedThroughLink(ctx, entityID, lensID, meta)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkAcyclic(ctx, []*core.Link{{Source: entityID, Target: lensID, Type: "INTERPRETED_THROUGH"}}); err != nil {
+		return err
+	}
+	return d.Repository.CreateInterpretedThroughLink(ctx, entityID, lensID, meta)
+}
+
+// checkAcyclic verifies that adding links (in order) keeps the constrained
+// subgraph acyclic. Earlier links in the batch count as existing edges.
+func (d *dagRepository) checkAcyclic(ctx context.Context, links []*core.Link) error {
+	pending := make(map[string][]string)
+	for _, link := range links {
+		if !d.types[link.Type] {
+			continue
+		}
+		reaches, err := d.reaches(ctx, link.Target, link.Source, pending)
+		if err != nil {
+			return err
+		}
+		if reaches {
+			return fmt.Errorf("%w: %s -[%s]-> %s", ErrCycle, link.Source, link.Type, link.Target)
+		}
+		pending[link.Source] = append(pending[link.Source], link.Target)
+	}
+	return nil
+}
+
+// reaches reports whether to is reachable from from along constrained links
+func (d *dagRepository) reaches(ctx context.Context, from, to string, pending map[string][]string) (bool, error) {
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			return true, nil
+		}
+
+		next, err := constrainedTargets(ctx, d.Repository, id, d.types)
+		if err != nil {
+			return false, err
+		}
+		for _, target := range append(next, pending[id]...) {
+			if !seen[target] {
+				seen[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+	return false, nil
+}
+
+// DAGReport lists existing cycles among constrained link types
+type DAGReport struct {
+	LinkTypes    []string   `json:"link_types"`
+	NodesChecked int        `json:"nodes_checked"`
+	Cycles       [][]string `json:"cycles"` // Each cycle as node IDs, first node repeated at the end
+	Truncated    bool       `json:"truncated,omitempty"`
+}
+
+// FindCycles scans the graph for cycles formed by links of the given types,
+// reporting at most maxCycles of them
+func FindCycles(ctx context.Context, repo Repository, linkTypes []string, maxCycles int) (*DAGReport, error) {
+	types := make(map[string]bool, len(linkTypes))
+	for _, t := range linkTypes {
+		types[t] = true
+	}
+
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+
+	report := &DAGReport{LinkTypes: linkTypes, NodesChecked: len(ids), Cycles: [][]string{}}
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int, len(ids))
+
+	type frame struct {
+		id      string
+		targets []string
+	}
+
+	for _, root := range ids {
+		if state[root] != unvisited {
+			continue
+		}
+
+		// Iterative DFS; the stack doubles as the current path
+		targets, err := constrainedTargets(ctx, repo, root, types)
+		if err != nil {
+			return nil, err
+		}
+		stack := []frame{{id: root, targets: targets}}
+		state[root] = inProgress
+
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if len(top.targets) == 0 {
+				state[top.id] = done
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			next := top.targets[0]
+			top.targets = top.targets[1:]
+
+			switch state[next] {
+			case inProgress:
+				// Back edge: the cycle is the path from next to the top of the stack
+				var cycle []string
+				for i := range stack {
+					if stack[i].id == next || len(cycle) > 0 {
+						cycle = append(cycle, stack[i].id)
+					}
+				}
+				report.Cycles = append(report.Cycles, append(cycle, next))
+				if maxCycles > 0 && len(report.Cycles) >= maxCycles {
+					report.Truncated = true
+					return report, nil
+				}
+			case unvisited:
+				targets, err := constrainedTargets(ctx, repo, next, types)
+				if err != nil {
+					return nil, err
+				}
+				state[next] = inProgress
+				stack = append(stack, frame{id: next, targets: targets})
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// constrainedTargets returns the targets of id's outgoing links of the given types
+func constrainedTargets(ctx context.Context, repo Repository, id string, types map[string]bool) ([]string, error) {
+	links, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, link := range links {
+		if types[link.Type] {
+			targets = append(targets, link.Target)
+		}
+	}
+	return targets, nil
+}
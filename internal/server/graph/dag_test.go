@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestDAGConstraintRejectsCycles(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemory()
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := inner.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := WithDAGConstraint(inner, []string{"EXTRACTED_FROM"})
+	link := func(src, dst, typ string) *core.Link {
+		return &core.Link{Source: src, Target: dst, Type: typ, Created: now, Modified: now}
+	}
+
+	if err := repo.CreateLinks(ctx, []*core.Link{link("a", "b", "EXTRACTED_FROM"), link("b", "c", "EXTRACTED_FROM")}); err != nil {
+		t.Fatalf("CreateLinks: %v", err)
+	}
+	if err := repo.CreateLink(ctx, link("c", "a", "EXTRACTED_FROM")); !errors.Is(err, ErrCycle) {
+		t.Errorf("closing link error = %v, want ErrCycle", err)
+	}
+	if err := repo.CreateLink(ctx, link("c", "a", "RELATED_TO")); err != nil {
+		t.Errorf("unconstrained link rejected: %v", err)
+	}
+
+	// A cycle written around the constraint is found by the checker
+	if err := inner.CreateLink(ctx, link("c", "a", "EXTRACTED_FROM")); err != nil {
+		t.Fatal(err)
+	}
+	report, err := FindCycles(ctx, repo, []string{"EXTRACTED_FROM"}, 10)
+	if err != nil {
+		t.Fatalf("FindCycles: %v", err)
+	}
+	if len(report.Cycles) != 1 || len(report.Cycles[0]) != 4 {
+		t.Errorf("FindCycles = %v, want one 3-node cycle", report.Cycles)
+	}
+}
@@ -0,0 +1,66 @@
+package graph
+
+// Node degrees are kept incrementally as links are created and deleted, so
+// bulk deletes, imports and manual SQL can leave them wrong. RecomputeDegrees
+// counts each live node's links again, a batch of nodes at a time so writes
+// carry on meanwhile, and corrects the ones that drifted. ATTENDED edges do
+// not count towards degree.
+
+// DefaultDegreeBatch is how many nodes one recompute batch covers when
+// BatchSize is unset
+const DefaultDegreeBatch = 1000
+
+// DegreeRepairOptions controls a degree recompute
+type DegreeRepairOptions struct {
+	BatchSize int  // Nodes per batch; zero uses DefaultDegreeBatch
+	DryRun    bool // Report drift without correcting it
+	Limit     int  // Maximum discrepancies listed; 0 lists all
+}
+
+// DegreeRepair reports a degree recompute. Drifted counts every node whose
+// stored degree was wrong; the list stops at the requested limit.
+type DegreeRepair struct {
+	Checked       int                 `json:"checked"`
+	Drifted       int                 `json:"drifted"`
+	Fixed         int                 `json:"fixed"`
+	Batches       int                 `json:"batches"`
+	DryRun        bool                `json:"dry_run"`
+	Discrepancies []DegreeDiscrepancy `json:"discrepancies"`
+	Truncated     bool                `json:"truncated"`
+}
+
+// DegreeDiscrepancy is a node whose stored degree did not match its links
+type DegreeDiscrepancy struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Stored int    `json:"stored"`
+	Actual int    `json:"actual"`
+}
+
+// newDegreeRepair creates an empty report
+func newDegreeRepair(opts DegreeRepairOptions) *DegreeRepair {
+	return &DegreeRepair{DryRun: opts.DryRun, Discrepancies: []DegreeDiscrepancy{}}
+}
+
+// batchSize returns the nodes per batch
+func (o DegreeRepairOptions) batchSize() int {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return DefaultDegreeBatch
+}
+
+// check counts a node, recording it when its stored degree is wrong
+func (r *DegreeRepair) check(d DegreeDiscrepancy, opts DegreeRepairOptions) bool {
+	r.Checked++
+	if d.Stored == d.Actual {
+		return false
+	}
+	r.Drifted++
+	if opts.Limit > 0 && len(r.Discrepancies) >= opts.Limit {
+		r.Truncated = true
+	} else {
+		r.Discrepancies = append(r.Discrepancies, d)
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestRecomputeDegrees(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+	memory := NewMemory()
+
+	backends := []struct {
+		name    string
+		repo    Repository
+		corrupt func(id string, degree int)
+	}{
+		{"sqlite", sqlite, func(id string, degree int) {
+			if _, err := sqlite.db.ExecContext(ctx, `UPDATE nodes SET degree = ? WHERE id = ? AND is_current = 1`, degree, id); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"memory", memory, func(id string, degree int) {
+			memory.mu.Lock()
+			memory.degree[id] = degree
+			memory.mu.Unlock()
+		}},
+	}
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			now := time.Now()
+			for i := 0; i < 5; i++ {
+				if err := b.repo.CreateNode(ctx, &core.Node{ID: fmt.Sprintf("n%d", i), Type: "Note", Created: now, Modified: now}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, pair := range [][2]string{{"n0", "n1"}, {"n0", "n2"}, {"n1", "n2"}} {
+				if err := b.repo.CreateLink(ctx, &core.Link{Source: pair[0], Target: pair[1], Type: "LINKS", Created: now, Modified: now}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := b.repo.UpdateAttentionEdge(ctx, "n3", "n4", "q1", 0.5); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := b.repo.RecomputeDegrees(ctx, DegreeRepairOptions{BatchSize: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Checked != 5 || report.Drifted != 0 || report.Batches != 3 {
+				t.Fatalf("consistent graph: %+v", report)
+			}
+
+			b.corrupt("n0", 7)
+			b.corrupt("n3", 1)
+			report, err = b.repo.RecomputeDegrees(ctx, DegreeRepairOptions{DryRun: true, Limit: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Drifted != 2 || report.Fixed != 0 || !report.Truncated || len(report.Discrepancies) != 1 {
+				t.Fatalf("dry run: %+v", report)
+			}
+			if d := report.Discrepancies[0]; d.ID != "n0" || d.Stored != 7 || d.Actual != 2 {
+				t.Errorf("discrepancy = %+v", d)
+			}
+
+			report, err = b.repo.RecomputeDegrees(ctx, DegreeRepairOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Drifted != 2 || report.Fixed != 2 {
+				t.Fatalf("repair: %+v", report)
+			}
+			report, err = b.repo.RecomputeDegrees(ctx, DegreeRepairOptions{DryRun: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Drifted != 0 {
+				t.Errorf("after repair: %+v", report)
+			}
+		})
+	}
+}
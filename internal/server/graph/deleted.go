@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// Deleting a node appends a tombstone version with no content or
+// properties, and reads skip tombstoned nodes. A context from WithDeleted
+// makes GetNode, SearchNodes and FilterNodes find them too: a deleted node
+// is returned as its last live version, marked Deleted with the
+// tombstone's DeletedAt, so it can be searched for by what it held.
+
+type includeDeletedKey struct{}
+
+// WithDeleted returns a context whose node reads include deleted nodes
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+// IncludesDeleted reports whether reads made with ctx include deleted nodes
+func IncludesDeleted(ctx context.Context) bool {
+	include, _ := ctx.Value(includeDeletedKey{}).(bool)
+	return include
+}
+
+// markDeleted marks the last live version of a node as deleted at deletedAt
+func markDeleted(node *core.Node, deletedAt time.Time) {
+	node.Deleted = true
+	node.DeletedAt = deletedAt
+	node.IsCurrent = false
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestIncludeDeleted(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, repo := range map[string]Repository{"sqlite": sqlite, "memory": NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for _, node := range []*core.Node{
+				{ID: "note:kept", Type: "Note", Content: []byte("kept marmalade"), Created: now, Modified: now},
+				{ID: "note:first", Type: "Note", Content: []byte("lost marmalade"), Meta: map[string]interface{}{"tag": "jam"}, Created: now, Modified: now},
+				{ID: "note:second", Type: "Note", Content: []byte("lost toast"), Created: now, Modified: now},
+			} {
+				if err := repo.CreateNode(ctx, node); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.DeleteNode(ctx, "note:first", false); err != nil {
+				t.Fatal(err)
+			}
+			// Deletion times are kept to the second
+			time.Sleep(1100 * time.Millisecond)
+			if err := repo.DeleteNode(ctx, "note:second", false); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := repo.GetNode(ctx, "note:first"); err == nil {
+				t.Error("GetNode found a deleted node")
+			}
+			withDeleted := WithDeleted(ctx)
+			node, err := repo.GetNode(withDeleted, "note:first")
+			if err != nil {
+				t.Fatalf("GetNode including deleted: %v", err)
+			}
+			if !node.Deleted || node.DeletedAt.IsZero() || node.IsCurrent || string(node.Content) != "lost marmalade" || node.Meta["tag"] != "jam" {
+				t.Errorf("deleted node = %+v", node)
+			}
+			if node, err := repo.GetNode(withDeleted, "note:kept"); err != nil || node.Deleted {
+				t.Errorf("live node including deleted = %+v, %v", node, err)
+			}
+
+			if found, _ := repo.SearchNodes(ctx, "marmalade", 10, 0); len(found) != 1 {
+				t.Errorf("search found %d nodes, want 1", len(found))
+			}
+			found, err := repo.SearchNodes(withDeleted, "marmalade", 10, 0)
+			if err != nil || len(found) != 2 {
+				t.Fatalf("search including deleted = %d nodes, %v", len(found), err)
+			}
+			for _, n := range found {
+				if n.Deleted != (n.ID == "note:first") {
+					t.Errorf("%s: Deleted = %v", n.ID, n.Deleted)
+				}
+			}
+			if found, _ := repo.FilterNodes(withDeleted, []string{"Note"}, "tag", "jam", 10, 0); len(found) != 1 || !found[0].Deleted {
+				t.Errorf("filter including deleted = %+v", found)
+			}
+
+			trash, err := repo.ListDeleted(ctx, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(trash) != 2 || trash[0].ID != "note:second" || trash[1].ID != "note:first" {
+				t.Fatalf("trash = %+v", trash)
+			}
+			if string(trash[0].Content) != "lost toast" || !trash[0].Deleted {
+				t.Errorf("trash entry = %+v", trash[0])
+			}
+			if page, _ := repo.ListDeleted(ctx, 1, 1); len(page) != 1 || page[0].ID != "note:first" {
+				t.Errorf("second page = %+v", page)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"time"
+)
+
+// BackendHealth reports whether a backend answers reads, and for SQL
+// backends the schema version of the database
+type BackendHealth struct {
+	Backend       string  `json:"backend"`
+	OK            bool    `json:"ok"`
+	LatencyMS     float64 `json:"latency_ms"`
+	Error         string  `json:"error,omitempty"`
+	SchemaVersion int     `json:"schema_version,omitempty"`
+	LatestSchema  int     `json:"latest_schema_version,omitempty"` // Newest version this build knows
+}
+
+// schemaVersioned is implemented by backends with migrated schemas
+type schemaVersioned interface {
+	SchemaVersion(ctx context.Context) (int, error)
+	LatestSchemaVersion() int
+}
+
+// CheckBackend times a one-node read from repo. Pass the backend itself
+// rather than a wrapper so its schema version can be read.
+func CheckBackend(ctx context.Context, name string, repo Repository) *BackendHealth {
+	h := &BackendHealth{Backend: name}
+	start := time.Now()
+	_, err := repo.FilterNodes(ctx, nil, "", "", 1, 0)
+	h.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	h.OK = true
+
+	if v, ok := repo.(schemaVersioned); ok {
+		version, err := v.SchemaVersion(ctx)
+		if err != nil {
+			h.OK, h.Error = false, "reading schema version: "+err.Error()
+			return h
+		}
+		h.SchemaVersion, h.LatestSchema = version, v.LatestSchemaVersion()
+	}
+	return h
+}
@@ -0,0 +1,32 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckBackend(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+
+	h := CheckBackend(ctx, "sqlite", sqlite)
+	if !h.OK || h.Backend != "sqlite" || h.SchemaVersion != latestSchemaVersion() || h.LatestSchema != latestSchemaVersion() {
+		t.Errorf("sqlite health = %+v", h)
+	}
+	// Wrappers hide the schema version but still answer reads
+	if h := CheckBackend(ctx, "sqlite", WithCache(sqlite, CacheConfig{Size: 10})); !h.OK || h.SchemaVersion != 0 {
+		t.Errorf("wrapped health = %+v", h)
+	}
+	if h := CheckBackend(ctx, "memory", NewMemory()); !h.OK || h.LatestSchema != 0 {
+		t.Errorf("memory health = %+v", h)
+	}
+
+	sqlite.Close(ctx)
+	if h := CheckBackend(ctx, "sqlite", sqlite); h.OK || h.Error == "" {
+		t.Errorf("closed database health = %+v", h)
+	}
+}
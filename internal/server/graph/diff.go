@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"time"
+)
+
+// Diff modes
+const (
+	DiffModeSummary  = "summary"
+	DiffModeDetailed = "detailed"
+)
+
+// GraphDiff reports what changed in the graph between two points in time
+type GraphDiff struct {
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
+	Summary DiffSummary `json:"summary"`
+
+	// Detailed mode only
+	NodesAdded    []NodeChange    `json:"nodes_added,omitempty"`
+	NodesModified []NodeChange    `json:"nodes_modified,omitempty"`
+	NodesDeleted  []NodeChange    `json:"nodes_deleted,omitempty"`
+	LinksAdded    []*SubgraphEdge `json:"links_added,omitempty"`
+	LinksRemoved  []*SubgraphEdge `json:"links_removed,omitempty"`
+}
+
+// DiffSummary holds change counts for a graph diff
+type DiffSummary struct {
+	NodesAdded    int `json:"nodes_added"`
+	NodesModified int `json:"nodes_modified"`
+	NodesDeleted  int `json:"nodes_deleted"`
+	LinksAdded    int `json:"links_added"`
+	LinksRemoved  int `json:"links_removed"`
+}
+
+// NodeChange describes the net change to one node within a diff window
+type NodeChange struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	FromVersion int       `json:"from_version,omitempty"` // Last version before the window
+	ToVersion   int       `json:"to_version"`             // Last version within the window
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// nodeVersionRow is one stored node version touched within a diff window
+type nodeVersionRow struct {
+	ID       string
+	Type     string
+	Version  int
+	Modified time.Time
+	Deleted  bool
+}
+
+// linkChangeRow is a link created or removed within a diff window
+type linkChangeRow struct {
+	Edge    *SubgraphEdge
+	Created time.Time
+}
+
+// buildGraphDiff reduces version rows and link changes to net changes.
+// A node created and deleted inside the window is omitted, as is a link
+// created and removed inside the window.
+func buildGraphDiff(from, to time.Time, versions []nodeVersionRow, linksAdded, linksRemoved []linkChangeRow, detailed bool) *GraphDiff {
+	diff := &GraphDiff{From: from, To: to}
+
+	type nodeState struct {
+		first, last nodeVersionRow
+	}
+	states := make(map[string]*nodeState)
+	var order []string
+	for _, v := range versions {
+		st, ok := states[v.ID]
+		if !ok {
+			states[v.ID] = &nodeState{first: v, last: v}
+			order = append(order, v.ID)
+			continue
+		}
+		if v.Version < st.first.Version {
+			st.first = v
+		}
+		if v.Version > st.last.Version {
+			st.last = v
+		}
+	}
+
+	for _, id := range order {
+		st := states[id]
+		created := st.first.Version == 1
+		change := NodeChange{
+			ID:          id,
+			Type:        st.last.Type,
+			FromVersion: st.first.Version - 1,
+			ToVersion:   st.last.Version,
+			ChangedAt:   st.last.Modified,
+		}
+
+		switch {
+		case created && st.last.Deleted:
+			continue
+		case created:
+			diff.NodesAdded = append(diff.NodesAdded, change)
+		case st.last.Deleted:
+			diff.NodesDeleted = append(diff.NodesDeleted, change)
+		default:
+			diff.NodesModified = append(diff.NodesModified, change)
+		}
+	}
+
+	for _, l := range linksAdded {
+		diff.LinksAdded = append(diff.LinksAdded, l.Edge)
+	}
+	for _, l := range linksRemoved {
+		if l.Created.After(from) && !l.Created.After(to) {
+			continue
+		}
+		diff.LinksRemoved = append(diff.LinksRemoved, l.Edge)
+	}
+
+	diff.Summary = DiffSummary{
+		NodesAdded:    len(diff.NodesAdded),
+		NodesModified: len(diff.NodesModified),
+		NodesDeleted:  len(diff.NodesDeleted),
+		LinksAdded:    len(diff.LinksAdded),
+		LinksRemoved:  len(diff.LinksRemoved),
+	}
+
+	if !detailed {
+		diff.NodesAdded = nil
+		diff.NodesModified = nil
+		diff.NodesDeleted = nil
+		diff.LinksAdded = nil
+		diff.LinksRemoved = nil
+	}
+
+	return diff
+}
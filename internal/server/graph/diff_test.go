@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestSQLiteDiffGraph(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+
+	before := time.Now().Add(-time.Hour)
+	for _, id := range []string{"person:ada", "note:edited", "note:removed"} {
+		n := &core.Node{ID: id, Type: "Note", Meta: map[string]any{"title": id}, Created: before, Modified: before}
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := &core.Link{Source: "note:edited", Target: "person:ada", Type: "MENTIONS", Created: before, Modified: before}
+	if err := repo.CreateLink(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	from := time.Now().Add(-time.Minute)
+
+	// Inside the window
+	now := time.Now()
+	if err := repo.CreateNode(ctx, &core.Node{ID: "note:new", Type: "Note", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateNode(ctx, &core.Node{ID: "note:fleeting", Type: "Note", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteNode(ctx, "note:fleeting", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"first edit", "second edit"} {
+		if err := repo.UpdateNodeMeta(ctx, "note:edited", map[string]any{"title": title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.DeleteNode(ctx, "note:removed", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "note:new", Target: "person:ada", Type: "MENTIONS", Meta: map[string]any{"context": "intro"}, Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "note:new", Target: "note:edited", Type: "CITES", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteLink(ctx, "note:new", "note:edited", "CITES"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteLink(ctx, "note:edited", "person:ada", "MENTIONS"); err != nil {
+		t.Fatal(err)
+	}
+	to := time.Now().Add(time.Minute)
+
+	// Summary mode counts net changes only
+	summary, err := repo.DiffGraph(ctx, from, to, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DiffSummary{NodesAdded: 1, NodesModified: 1, NodesDeleted: 1, LinksAdded: 1, LinksRemoved: 1}
+	if summary.Summary != want {
+		t.Errorf("summary = %+v, want %+v", summary.Summary, want)
+	}
+	if summary.NodesAdded != nil || summary.LinksAdded != nil {
+		t.Error("summary mode listed changes")
+	}
+
+	diff, err := repo.DiffGraph(ctx, from, to, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Summary != want {
+		t.Errorf("detailed summary = %+v, want %+v", diff.Summary, want)
+	}
+
+	// A node created and deleted inside the window is left out
+	for _, list := range [][]NodeChange{diff.NodesAdded, diff.NodesModified, diff.NodesDeleted} {
+		for _, c := range list {
+			if c.ID == "note:fleeting" {
+				t.Errorf("node created and deleted in the window reported: %+v", c)
+			}
+		}
+	}
+	if len(diff.NodesAdded) != 1 || diff.NodesAdded[0].ID != "note:new" || diff.NodesAdded[0].FromVersion != 0 || diff.NodesAdded[0].ToVersion != 1 {
+		t.Errorf("nodes added = %+v", diff.NodesAdded)
+	}
+
+	// Modified nodes span every version written in the window
+	if len(diff.NodesModified) != 1 {
+		t.Fatalf("nodes modified = %+v", diff.NodesModified)
+	}
+	if m := diff.NodesModified[0]; m.ID != "note:edited" || m.FromVersion != 1 || m.ToVersion != 3 || m.ChangedAt.IsZero() {
+		t.Errorf("modified change = %+v, want note:edited v1 -> v3", m)
+	}
+	if len(diff.NodesDeleted) != 1 || diff.NodesDeleted[0].ID != "note:removed" || diff.NodesDeleted[0].FromVersion != 1 {
+		t.Errorf("nodes deleted = %+v", diff.NodesDeleted)
+	}
+
+	// A link added and removed inside the window nets to nothing
+	if len(diff.LinksAdded) != 1 || diff.LinksAdded[0].Type != "MENTIONS" || diff.LinksAdded[0].Source != "note:new" {
+		t.Errorf("links added = %+v", diff.LinksAdded)
+	} else if diff.LinksAdded[0].Meta["context"] != "intro" {
+		t.Errorf("added link meta = %v", diff.LinksAdded[0].Meta)
+	}
+	if len(diff.LinksRemoved) != 1 || diff.LinksRemoved[0].Source != "note:edited" {
+		t.Errorf("links removed = %+v", diff.LinksRemoved)
+	}
+
+	// A window before any of it shows nothing
+	empty, err := repo.DiffGraph(ctx, before.Add(-time.Hour), before.Add(-time.Minute), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Summary != (DiffSummary{}) {
+		t.Errorf("earlier window = %+v", empty.Summary)
+	}
+}
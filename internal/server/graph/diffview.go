@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"time"
+)
+
+// DiffViewSize is the width and height of the square a diff view is laid
+// out in
+const DiffViewSize = 1000
+
+// Diff view statuses
+const (
+	DiffAdded    = "added"
+	DiffRemoved  = "removed"
+	DiffModified = "modified"
+	DiffContext  = "context" // Unchanged, shown as the end of a changed link
+)
+
+// DiffView is a graph diff laid out for display. Positions depend only on
+// a node's ID and type, so a node sits in the same place in every diff and
+// views of consecutive windows can be compared or animated.
+type DiffView struct {
+	From      time.Time            `json:"from"`
+	To        time.Time            `json:"to"`
+	Summary   DiffSummary          `json:"summary"`
+	Nodes     []DiffViewNode       `json:"nodes"`
+	Edges     []DiffViewEdge       `json:"edges"`
+	Styles    map[string]NodeStyle `json:"styles,omitempty"` // Visualization hints by node type
+	Truncated bool                 `json:"truncated"`        // More changed nodes than the limit
+}
+
+// DiffViewNode is a node and what happened to it in the window
+type DiffViewNode struct {
+	ID     string  `json:"id"`
+	Type   string  `json:"type"`
+	Label  string  `json:"label"`
+	Status string  `json:"status"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+}
+
+// DiffViewEdge is a link added or removed in the window
+type DiffViewEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// BuildDiffView lays out a detailed diff, adding the unchanged ends of
+// changed links as context. At most limit nodes are shown; changed links
+// are kept only when both ends are.
+func BuildDiffView(ctx context.Context, repo Repository, diff *GraphDiff, limit int) *DiffView {
+	view := &DiffView{From: diff.From, To: diff.To, Summary: diff.Summary, Nodes: []DiffViewNode{}, Edges: []DiffViewEdge{}}
+	shown := make(map[string]bool)
+	add := func(id, nodeType, status string) {
+		if shown[id] {
+			return
+		}
+		if limit > 0 && len(view.Nodes) >= limit {
+			view.Truncated = true
+			return
+		}
+		shown[id] = true
+		label := id
+		if n, err := repo.GetNode(WithDeleted(ctx), id); err == nil {
+			label = NodeLabel(n.ID, n.Meta)
+			if nodeType == "" {
+				nodeType = n.Type
+			}
+		}
+		x, y := DiffLayout(id, nodeType)
+		view.Nodes = append(view.Nodes, DiffViewNode{ID: id, Type: nodeType, Label: label, Status: status, X: x, Y: y})
+	}
+
+	for _, c := range diff.NodesAdded {
+		add(c.ID, c.Type, DiffAdded)
+	}
+	for _, c := range diff.NodesDeleted {
+		add(c.ID, c.Type, DiffRemoved)
+	}
+	for _, c := range diff.NodesModified {
+		add(c.ID, c.Type, DiffModified)
+	}
+	for _, changes := range []struct {
+		edges  []*SubgraphEdge
+		status string
+	}{{diff.LinksAdded, DiffAdded}, {diff.LinksRemoved, DiffRemoved}} {
+		for _, e := range changes.edges {
+			add(e.Source, "", DiffContext)
+			add(e.Target, "", DiffContext)
+			if shown[e.Source] && shown[e.Target] {
+				view.Edges = append(view.Edges, DiffViewEdge{Source: e.Source, Target: e.Target, Type: e.Type, Status: changes.status})
+			}
+		}
+	}
+
+	sort.Slice(view.Nodes, func(i, j int) bool { return view.Nodes[i].ID < view.Nodes[j].ID })
+	return view
+}
+
+// DiffLayout places a node by hashing: its type picks a sector of the
+// circle and its ID a point within it, so nodes of a type cluster together
+func DiffLayout(id, nodeType string) (x, y float64) {
+	const sectors = 12
+	sector := float64(hash64(nodeType) % sectors)
+	h := hash64(id)
+	// Spread within the sector; sqrt keeps the density even across radii
+	angle := (sector + float64(h&0xffff)/0x10000) * 2 * math.Pi / sectors
+	radius := (0.15 + 0.8*math.Sqrt(float64(h>>16&0xffff)/0x10000)) * DiffViewSize / 2
+	center := float64(DiffViewSize) / 2
+	return math.Round(center + radius*math.Cos(angle)), math.Round(center + radius*math.Sin(angle))
+}
+
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestBuildDiffView(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	before := time.Now().Add(-time.Hour)
+	for _, n := range []*core.Node{
+		{ID: "person:ada", Type: "Person", Meta: map[string]any{"name": "Ada"}},
+		{ID: "note:old", Type: "Note"},
+	} {
+		n.Created, n.Modified = before, before
+		repo.CreateNode(ctx, n)
+	}
+	from := time.Now().Add(-time.Minute)
+
+	now := time.Now()
+	repo.CreateNode(ctx, &core.Node{ID: "note:new", Type: "Note", Created: now, Modified: now})
+	repo.CreateLink(ctx, &core.Link{Source: "note:new", Target: "person:ada", Type: "MENTIONS", Created: now, Modified: now})
+	repo.DeleteNode(ctx, "note:old", false)
+
+	diff, err := repo.DiffGraph(ctx, from, time.Now().Add(time.Minute), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := BuildDiffView(ctx, repo, diff, 0)
+	status := make(map[string]string)
+	for _, n := range view.Nodes {
+		status[n.ID] = n.Status
+		if n.X < 0 || n.X > DiffViewSize || n.Y < 0 || n.Y > DiffViewSize {
+			t.Errorf("%s placed outside the view at (%v, %v)", n.ID, n.X, n.Y)
+		}
+		if x, y := DiffLayout(n.ID, n.Type); x != n.X || y != n.Y {
+			t.Errorf("%s placed at (%v, %v), its layout is (%v, %v)", n.ID, n.X, n.Y, x, y)
+		}
+	}
+	if status["note:new"] != DiffAdded || status["note:old"] != DiffRemoved || status["person:ada"] != DiffContext {
+		t.Errorf("statuses = %v", status)
+	}
+	if len(view.Edges) != 1 || view.Edges[0].Status != DiffAdded {
+		t.Errorf("edges = %+v", view.Edges)
+	}
+	for _, n := range view.Nodes {
+		if n.ID == "person:ada" && (n.Label != "Ada" || n.Type != "Person") {
+			t.Errorf("context node = %+v", n)
+		}
+	}
+
+	if limited := BuildDiffView(ctx, repo, diff, 1); len(limited.Nodes) != 1 || !limited.Truncated || len(limited.Edges) != 0 {
+		t.Errorf("view limited to one node = %+v", limited)
+	}
+}
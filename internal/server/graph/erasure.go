@@ -0,0 +1,313 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// Erasure modes
+const (
+	ErasureDelete    = "delete"    // Hard-delete every version of each node
+	ErasureTombstone = "tombstone" // Tombstone nodes and links, keeping history
+)
+
+// ErasureAuditType is the node type of erasure audit records
+const ErasureAuditType = "ErasureAudit"
+
+// DefaultErasureMaxNodes caps how many nodes one erasure may touch
+const DefaultErasureMaxNodes = 10000
+
+// ErrErasureTooLarge is returned when an erasure would exceed its node limit
+var ErrErasureTooLarge = errors.New("erasure scope too large")
+
+// derivationLinkTypes make the link's source derived from its target
+var derivationLinkTypes = map[string]bool{"EXTRACTED_FROM": true, "DERIVED_FROM": true}
+
+// ErasureBundle is the export of everything an erasure removes
+type ErasureBundle struct {
+	SubjectID   string         `json:"subject_id"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Nodes       []ExportedNode `json:"nodes"`
+	Links       []ExportedLink `json:"links"`
+	Review      []string       `json:"review"` // Sources the subject was extracted from; kept for manual review
+}
+
+// ExportedNode is a node with its full version history
+type ExportedNode struct {
+	ID       string            `json:"id"`
+	Type     string            `json:"type"`
+	Reason   string            `json:"reason"` // subject, derived or mentions
+	Versions []ExportedVersion `json:"versions"`
+}
+
+// ExportedVersion is one stored version of a node
+type ExportedVersion struct {
+	Version       int                    `json:"version"`
+	Modified      time.Time              `json:"modified"`
+	ChangeNote    string                 `json:"change_note,omitempty"`
+	ChangedBy     string                 `json:"changed_by,omitempty"`
+	Deleted       bool                   `json:"deleted,omitempty"`
+	Content       string                 `json:"content,omitempty"`
+	ContentBinary []byte                 `json:"content_base64,omitempty"` // Non-UTF-8 content
+	Meta          map[string]interface{} `json:"meta,omitempty"`
+}
+
+// ExportedLink is a link touching an erased node
+type ExportedLink struct {
+	Source        string                 `json:"source"`
+	Target        string                 `json:"target"`
+	Type          string                 `json:"type"`
+	Meta          map[string]interface{} `json:"meta,omitempty"`
+	Weight        float64                `json:"weight,omitempty"`
+	Bidirectional bool                   `json:"bidirectional,omitempty"`
+	Created       time.Time              `json:"created"`
+}
+
+// ErasureResult reports what an erasure did
+type ErasureResult struct {
+	Mode    string            `json:"mode"`
+	DryRun  bool              `json:"dry_run"`
+	Erased  []string          `json:"erased"`
+	Failed  map[string]string `json:"failed,omitempty"` // Node ID -> error
+	AuditID string            `json:"audit_id,omitempty"`
+}
+
+// ErasureRequest selects the subject and how to erase
+type ErasureRequest struct {
+	SubjectID   string
+	Mode        string
+	DryRun      bool
+	MaxNodes    int
+	RequestRef  string // Caller's ticket or request reference, kept in the audit record
+	RequestedBy string
+}
+
+// Erase exports and then removes a subject node, every node derived from it
+// (transitively, via EXTRACTED_FROM and DERIVED_FROM) and every node that
+// MENTIONS any of those, along with all links touching them. An audit node
+// records the erasure without identifying the subject.
+func Erase(ctx context.Context, repo Repository, req ErasureRequest) (*ErasureBundle, *ErasureResult, error) {
+	if req.Mode == "" {
+		req.Mode = ErasureDelete
+	}
+	if req.Mode != ErasureDelete && req.Mode != ErasureTombstone {
+		return nil, nil, fmt.Errorf("invalid mode: %s (use '%s' or '%s')", req.Mode, ErasureDelete, ErasureTombstone)
+	}
+	if req.MaxNodes <= 0 {
+		req.MaxNodes = DefaultErasureMaxNodes
+	}
+
+	bundle, err := planErasure(ctx, repo, req.SubjectID, req.MaxNodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &ErasureResult{Mode: req.Mode, DryRun: req.DryRun, Erased: []string{}}
+	if req.DryRun {
+		return bundle, result, nil
+	}
+
+	// Tombstoned nodes keep their links, so remove those first; hard deletes
+	// remove links along with the node
+	if req.Mode == ErasureTombstone {
+		for _, l := range bundle.Links {
+			repo.DeleteLink(ctx, l.Source, l.Target, l.Type)
+		}
+	}
+	for _, n := range bundle.Nodes {
+		if err := repo.DeleteNode(ctx, n.ID, req.Mode == ErasureDelete); err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[n.ID] = err.Error()
+			continue
+		}
+		result.Erased = append(result.Erased, n.ID)
+	}
+
+	now := time.Now()
+	subjectHash := sha256.Sum256([]byte(req.SubjectID))
+	audit := &core.Node{
+		ID:       fmt.Sprintf("audit:erasure-%d", now.UnixNano()),
+		Type:     ErasureAuditType,
+		Created:  now,
+		Modified: now,
+		Meta: map[string]interface{}{
+			"subject_sha256": hex.EncodeToString(subjectHash[:]),
+			"mode":           req.Mode,
+			"request_ref":    req.RequestRef,
+			"requested_by":   req.RequestedBy,
+			"nodes_erased":   len(result.Erased),
+			"nodes_failed":   len(result.Failed),
+			"links_removed":  len(bundle.Links),
+			"erased_at":      now.Format(time.RFC3339),
+		},
+	}
+	if err := repo.CreateNode(ctx, audit); err != nil {
+		return bundle, result, fmt.Errorf("erasure done but audit record failed: %w", err)
+	}
+	result.AuditID = audit.ID
+	return bundle, result, nil
+}
+
+// planErasure finds the nodes and links an erasure covers and exports them
+func planErasure(ctx context.Context, repo Repository, subjectID string, maxNodes int) (*ErasureBundle, error) {
+	subject, err := repo.GetNode(ctx, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("node not found: %s", subjectID)
+	}
+
+	incoming := make(map[string][]*core.Link)
+	backlinks := func(id string) ([]*core.Link, error) {
+		if links, ok := incoming[id]; ok {
+			return links, nil
+		}
+		links, err := repo.GetBacklinks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		incoming[id] = links
+		return links, nil
+	}
+
+	reasons := map[string]string{subjectID: "subject"}
+	queue := []string{subjectID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		links, err := backlinks(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			if derivationLinkTypes[l.Type] && reasons[l.Source] == "" {
+				reasons[l.Source] = "derived"
+				queue = append(queue, l.Source)
+			}
+		}
+		if len(reasons) > maxNodes {
+			return nil, fmt.Errorf("%w: %s covers more than %d nodes", ErrErasureTooLarge, subjectID, maxNodes)
+		}
+	}
+	derived := make([]string, 0, len(reasons))
+	for id := range reasons {
+		derived = append(derived, id)
+	}
+	for _, id := range derived {
+		links, err := backlinks(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			if l.Type == "MENTIONS" && reasons[l.Source] == "" {
+				reasons[l.Source] = "mentions"
+			}
+		}
+	}
+	if len(reasons) > maxNodes {
+		return nil, fmt.Errorf("%w: %s covers more than %d nodes", ErrErasureTooLarge, subjectID, maxNodes)
+	}
+
+	bundle := &ErasureBundle{
+		SubjectID:   subjectID,
+		GeneratedAt: time.Now(),
+		Nodes:       make([]ExportedNode, 0, len(reasons)),
+		Links:       []ExportedLink{},
+		Review:      []string{},
+	}
+
+	erased := make([]string, 0, len(reasons))
+	for id := range reasons {
+		erased = append(erased, id)
+	}
+	sort.Strings(erased)
+	for _, id := range erased {
+		node, err := exportNode(ctx, repo, id)
+		if err != nil {
+			return nil, err
+		}
+		node.Reason = reasons[id]
+		bundle.Nodes = append(bundle.Nodes, *node)
+	}
+
+	// Every link touching an erased node, once
+	outgoing := make(map[string][]*core.Link, len(erased))
+	seen := make(map[string]bool)
+	for _, id := range erased {
+		out, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		outgoing[id] = out
+		in, err := backlinks(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range append(out, in...) {
+			key := l.Source + "\x00" + l.Target + "\x00" + l.Type
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			bundle.Links = append(bundle.Links, ExportedLink{Source: l.Source, Target: l.Target, Type: l.Type, Meta: l.Meta, Weight: l.Weight, Bidirectional: l.Bidirectional, Created: l.Created})
+		}
+	}
+	sort.Slice(bundle.Links, func(i, j int) bool {
+		a, b := bundle.Links[i], bundle.Links[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Type < b.Type
+	})
+
+	for _, l := range outgoing[subject.ID] {
+		if derivationLinkTypes[l.Type] && reasons[l.Target] == "" {
+			bundle.Review = append(bundle.Review, l.Target)
+		}
+	}
+	sort.Strings(bundle.Review)
+	return bundle, nil
+}
+
+// exportNode exports every stored version of a node
+func exportNode(ctx context.Context, repo Repository, id string) (*ExportedNode, error) {
+	history, err := repo.GetNodeHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("reading history of %s: %w", id, err)
+	}
+	out := &ExportedNode{ID: id, Versions: make([]ExportedVersion, 0, len(history))}
+	for _, h := range history {
+		n, err := repo.GetNodeAtVersion(ctx, id, h.Version)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s version %d: %w", id, h.Version, err)
+		}
+		out.Type = n.Type
+		v := ExportedVersion{
+			Version:    h.Version,
+			Modified:   h.Modified,
+			ChangeNote: h.ChangeNote,
+			ChangedBy:  h.ChangedBy,
+			Deleted:    n.Deleted,
+			Meta:       n.Meta,
+		}
+		if utf8.Valid(n.Content) {
+			v.Content = string(n.Content)
+		} else {
+			v.ContentBinary = n.Content
+		}
+		out.Versions = append(out.Versions, v)
+	}
+	sort.Slice(out.Versions, func(i, j int) bool { return out.Versions[i].Version < out.Versions[j].Version })
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestErase(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, repo := range map[string]Repository{"sqlite": sqlite, "memory": NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for _, n := range []*core.Node{
+				{ID: "sha256:transcript", Type: "Source", Content: []byte("Ada and Bob met")},
+				{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"email": "ada@example.com"}},
+				{ID: "person:bob", Type: "Person"},
+				{ID: "note:ada-bio", Type: "Note", Content: []byte("Ada's bio")},
+				{ID: "note:summary", Type: "Note"},
+				{ID: "note:unrelated", Type: "Note"},
+			} {
+				n.Created, n.Modified = now, now
+				if err := repo.CreateNode(ctx, n); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.UpdateNodeMeta(ctx, "person:ada", map[string]any{"phone": "555-0100"}); err != nil {
+				t.Fatal(err)
+			}
+			for _, l := range []*core.Link{
+				{Source: "person:ada", Target: "sha256:transcript", Type: "EXTRACTED_FROM"},
+				{Source: "person:bob", Target: "sha256:transcript", Type: "EXTRACTED_FROM"},
+				{Source: "note:ada-bio", Target: "person:ada", Type: "DERIVED_FROM"},
+				{Source: "note:summary", Target: "note:ada-bio", Type: "MENTIONS"},
+				{Source: "person:bob", Target: "person:ada", Type: "KNOWS"},
+			} {
+				l.Created, l.Modified = now, now
+				if err := repo.CreateLink(ctx, l); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dry, result, err := Erase(ctx, repo, ErasureRequest{SubjectID: "person:ada", DryRun: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			reasons := map[string]string{}
+			var subject ExportedNode
+			for _, n := range dry.Nodes {
+				reasons[n.ID] = n.Reason
+				if n.ID == "person:ada" {
+					subject = n
+				}
+			}
+			want := map[string]string{"person:ada": "subject", "note:ada-bio": "derived", "note:summary": "mentions"}
+			if len(reasons) != len(want) {
+				t.Fatalf("nodes = %v, want %v", reasons, want)
+			}
+			for id, reason := range want {
+				if reasons[id] != reason {
+					t.Errorf("%s: reason %q, want %q", id, reasons[id], reason)
+				}
+			}
+			if len(dry.Links) != 4 || len(dry.Review) != 1 || dry.Review[0] != "sha256:transcript" {
+				t.Errorf("unexpected links %v or review %v", dry.Links, dry.Review)
+			}
+			if v := subject.Versions; len(v) != 2 || v[0].Meta["email"] != "ada@example.com" || v[1].Meta["phone"] != "555-0100" {
+				t.Errorf("expected full history of the subject, got %+v", subject)
+			}
+			if len(result.Erased) != 0 {
+				t.Errorf("dry run erased %v", result.Erased)
+			}
+
+			_, result, err = Erase(ctx, repo, ErasureRequest{SubjectID: "person:ada", RequestRef: "DSR-42"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Erased) != 3 || len(result.Failed) != 0 {
+				t.Fatalf("unexpected result %+v", result)
+			}
+			for id := range want {
+				if _, err := repo.GetNode(ctx, id); err == nil {
+					t.Errorf("%s still exists", id)
+				}
+				if h, _ := repo.GetNodeHistory(ctx, id); len(h) != 0 {
+					t.Errorf("%s history kept: %v", id, h)
+				}
+			}
+			if links, _ := repo.GetLinks(ctx, "person:bob"); len(links) != 1 {
+				t.Errorf("expected only bob's EXTRACTED_FROM link to remain, got %v", links)
+			}
+
+			audit, err := repo.GetNode(ctx, result.AuditID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if audit.Type != ErasureAuditType || audit.Meta["request_ref"] != "DSR-42" || strings.Contains(fmt.Sprint(audit.ID, audit.Meta), "ada") {
+				t.Errorf("unexpected audit record %+v", audit)
+			}
+		})
+	}
+}
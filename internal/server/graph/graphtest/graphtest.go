@@ -0,0 +1,516 @@
+// Package graphtest is a conformance suite for graph backends. Every
+// Repository implementation runs the same checks of node and link CRUD,
+// versioning, traversal, search, events, lenses and snapshots, so a feature added to
+// one backend and forgotten in another shows up as a failing test.
+//
+// The suite only touches nodes whose IDs and types it made up for the run,
+// so it can share a database with other data: a Neo4j or Postgres instance
+// started for CI need not be emptied between runs.
+package graphtest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Factory opens the backend under test. Each subtest calls it once and
+// may get a fresh or a shared database; register cleanup with t.Cleanup.
+type Factory func(t *testing.T) graph.Repository
+
+// Run runs the conformance suite against the backend open returns
+func Run(t *testing.T, open Factory) {
+	for _, tc := range []struct {
+		name string
+		test func(*testing.T, graph.Repository, *fixture)
+	}{
+		{"Nodes", testNodes},
+		{"Links", testLinks},
+		{"LinkFields", testLinkFields},
+		{"Versions", testVersions},
+		{"Traversal", testTraversal},
+		{"Search", testSearch},
+		{"Events", testEvents},
+		{"Lenses", testLenses},
+		{"Snapshots", testSnapshots},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, open(t), newFixture(t))
+		})
+	}
+}
+
+// fixture makes IDs, types and words unique to one subtest
+type fixture struct {
+	t      *testing.T
+	ctx    context.Context
+	prefix string
+	now    time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	return &fixture{
+		t:      t,
+		ctx:    context.Background(),
+		prefix: fmt.Sprintf("ct%08x", rand.Uint32()),
+		// Whole seconds, as some backends store timestamps at that precision
+		now: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// id returns a node ID unique to the run
+func (f *fixture) id(name string) string {
+	return f.prefix + ":" + name
+}
+
+// typ returns a node type unique to the run
+func (f *fixture) typ(name string) string {
+	return name + "_" + f.prefix
+}
+
+// node builds a node of a run-unique type
+func (f *fixture) node(name, nodeType string, content string, meta map[string]interface{}) *core.Node {
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	return &core.Node{
+		ID:       f.id(name),
+		Type:     f.typ(nodeType),
+		Content:  []byte(content),
+		Meta:     meta,
+		Created:  f.now,
+		Modified: f.now,
+	}
+}
+
+// link builds a link between two run-unique nodes
+func (f *fixture) link(source, target, linkType string) *core.Link {
+	return &core.Link{
+		Source:   f.id(source),
+		Target:   f.id(target),
+		Type:     linkType,
+		Meta:     map[string]interface{}{},
+		Created:  f.now,
+		Modified: f.now,
+	}
+}
+
+// must fails the test on err
+func (f *fixture) must(err error) {
+	f.t.Helper()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// nodeIDs returns the IDs of nodes
+func nodeIDs(nodes []*core.Node) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// linkKeys returns "source->target:type" for links
+func linkKeys(links []*core.Link) []string {
+	keys := make([]string, 0, len(links))
+	for _, l := range links {
+		keys = append(keys, l.Source+"->"+l.Target+":"+l.Type)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func testNodes(t *testing.T, repo graph.Repository, f *fixture) {
+	note := f.node("note", "Note", "conformance content", map[string]interface{}{"title": "First", "status": "draft"})
+	f.must(repo.CreateNode(f.ctx, note))
+
+	got, err := repo.GetNode(f.ctx, note.ID)
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	if got.ID != note.ID || got.Type != note.Type || string(got.Content) != "conformance content" {
+		t.Errorf("GetNode = %s %s %q", got.ID, got.Type, got.Content)
+	}
+	if got.Meta["title"] != "First" {
+		t.Errorf("GetNode meta = %v", got.Meta)
+	}
+	if _, err := repo.GetNode(f.ctx, f.id("missing")); err == nil {
+		t.Error("GetNode of a missing node succeeded")
+	}
+
+	batch := []*core.Node{
+		f.node("b1", "Task", "", map[string]interface{}{"status": "open"}),
+		f.node("b2", "Task", "", map[string]interface{}{"status": "done"}),
+		f.node("b3", "Task", "", map[string]interface{}{"status": "open"}),
+	}
+	f.must(repo.CreateNodes(f.ctx, batch))
+
+	tasks, err := repo.FilterNodes(f.ctx, []string{f.typ("Task")}, "", "", 10, 0)
+	f.must(err)
+	if ids := nodeIDs(tasks); !slices.Equal(ids, []string{f.id("b1"), f.id("b2"), f.id("b3")}) {
+		t.Errorf("FilterNodes by type = %v", ids)
+	}
+	open, err := repo.FilterNodes(f.ctx, []string{f.typ("Task")}, "status", "open", 10, 0)
+	f.must(err)
+	if ids := nodeIDs(open); !slices.Equal(ids, []string{f.id("b1"), f.id("b3")}) {
+		t.Errorf("FilterNodes by property = %v", ids)
+	}
+	page, err := repo.FilterNodes(f.ctx, []string{f.typ("Task")}, "", "", 2, 0)
+	f.must(err)
+	if len(page) != 2 {
+		t.Errorf("FilterNodes limit 2 returned %d", len(page))
+	}
+
+	all, err := repo.ListNodes(f.ctx)
+	f.must(err)
+	for _, id := range []string{note.ID, f.id("b1"), f.id("b2"), f.id("b3")} {
+		if !slices.Contains(all, id) {
+			t.Errorf("ListNodes is missing %s", id)
+		}
+	}
+
+	f.must(repo.DeleteNode(f.ctx, f.id("b2"), false))
+	if _, err := repo.GetNode(f.ctx, f.id("b2")); err == nil {
+		t.Error("GetNode of a deleted node succeeded")
+	}
+	tasks, err = repo.FilterNodes(f.ctx, []string{f.typ("Task")}, "", "", 10, 0)
+	f.must(err)
+	if ids := nodeIDs(tasks); !slices.Equal(ids, []string{f.id("b1"), f.id("b3")}) {
+		t.Errorf("FilterNodes after delete = %v", ids)
+	}
+	deleted, err := repo.ListDeleted(f.ctx, 1000, 0)
+	f.must(err)
+	if !slices.Contains(nodeIDs(deleted), f.id("b2")) {
+		t.Errorf("ListDeleted is missing %s", f.id("b2"))
+	}
+}
+
+func testLinks(t *testing.T, repo graph.Repository, f *fixture) {
+	f.must(repo.CreateNodes(f.ctx, []*core.Node{
+		f.node("paper", "Paper", "", nil),
+		f.node("ada", "Person", "", nil),
+		f.node("bob", "Person", "", nil),
+	}))
+	f.must(repo.CreateLink(f.ctx, f.link("paper", "ada", "AUTHORED_BY")))
+	f.must(repo.CreateLinks(f.ctx, []*core.Link{
+		f.link("paper", "bob", "AUTHORED_BY"),
+		f.link("bob", "ada", "KNOWS"),
+	}))
+
+	out, err := repo.GetLinks(f.ctx, f.id("paper"))
+	f.must(err)
+	want := []string{f.id("paper") + "->" + f.id("ada") + ":AUTHORED_BY", f.id("paper") + "->" + f.id("bob") + ":AUTHORED_BY"}
+	if keys := linkKeys(out); !slices.Equal(keys, want) {
+		t.Errorf("GetLinks = %v, want %v", keys, want)
+	}
+	in, err := repo.GetBacklinks(f.ctx, f.id("ada"))
+	f.must(err)
+	want = []string{f.id("bob") + "->" + f.id("ada") + ":KNOWS", f.id("paper") + "->" + f.id("ada") + ":AUTHORED_BY"}
+	if keys := linkKeys(in); !slices.Equal(keys, want) {
+		t.Errorf("GetBacklinks = %v, want %v", keys, want)
+	}
+
+	f.must(repo.DeleteLink(f.ctx, f.id("paper"), f.id("bob"), "AUTHORED_BY"))
+	out, err = repo.GetLinks(f.ctx, f.id("paper"))
+	f.must(err)
+	if keys := linkKeys(out); len(keys) != 1 {
+		t.Errorf("GetLinks after delete = %v", keys)
+	}
+	if err := repo.DeleteLink(f.ctx, f.id("paper"), f.id("bob"), "AUTHORED_BY"); err == nil {
+		t.Error("deleting a missing link succeeded")
+	}
+}
+
+func testLinkFields(t *testing.T, repo graph.Repository, f *fixture) {
+	f.must(repo.CreateNodes(f.ctx, []*core.Node{
+		f.node("ada", "Person", "", nil),
+		f.node("bob", "Person", "", nil),
+		f.node("eve", "Person", "", nil),
+	}))
+	knows := f.link("ada", "bob", "KNOWS")
+	knows.Weight, knows.Bidirectional = 0.75, true
+	f.must(repo.CreateLink(f.ctx, knows))
+	// Clients that predate the fields kept them in meta
+	legacy := f.link("ada", "eve", "TRUSTS")
+	legacy.Meta = map[string]interface{}{"weight": 0.5, "bidirectional": true, "since": "2020"}
+	f.must(repo.CreateLinks(f.ctx, []*core.Link{legacy}))
+
+	check := func(what string, links []*core.Link) {
+		t.Helper()
+		for _, l := range links {
+			switch l.Type {
+			case "KNOWS":
+				if l.Weight != 0.75 || !l.Bidirectional {
+					t.Errorf("%s KNOWS weight %v, bidirectional %v; want 0.75, true", what, l.Weight, l.Bidirectional)
+				}
+			case "TRUSTS":
+				if l.Weight != 0.5 || !l.Bidirectional || l.Meta["weight"] != nil || l.Meta["since"] != "2020" {
+					t.Errorf("%s TRUSTS = %+v, want weight and direction promoted out of meta", what, l)
+				}
+			}
+		}
+	}
+	out, err := repo.GetLinks(f.ctx, f.id("ada"))
+	f.must(err)
+	if len(out) != 2 {
+		t.Fatalf("GetLinks = %v", linkKeys(out))
+	}
+	check("GetLinks", out)
+	in, err := repo.GetBacklinks(f.ctx, f.id("bob"))
+	f.must(err)
+	check("GetBacklinks", in)
+
+	sub, err := repo.GetSubgraph(f.ctx, f.id("ada"), 1, nil)
+	f.must(err)
+	for _, e := range sub.Edges {
+		if e.Type == "KNOWS" && (e.Weight != 0.75 || !e.Bidirectional) {
+			t.Errorf("GetSubgraph KNOWS edge = %+v", e)
+		}
+	}
+}
+
+func testVersions(t *testing.T, repo graph.Repository, f *fixture) {
+	note := f.node("note", "Note", "versioned", map[string]interface{}{"status": "draft"})
+	f.must(repo.CreateNode(f.ctx, note))
+	beforeUpdate := time.Now()
+	time.Sleep(1100 * time.Millisecond) // Past the timestamp precision of SQLite
+	f.must(repo.UpdateNodeMetaWithNote(f.ctx, note.ID, map[string]any{"status": "final"}, "publish", "tester"))
+
+	got, err := repo.GetNode(f.ctx, note.ID)
+	f.must(err)
+	if got.Meta["status"] != "final" {
+		t.Errorf("GetNode after update: status = %v", got.Meta["status"])
+	}
+
+	history, err := repo.GetNodeHistory(f.ctx, note.ID)
+	f.must(err)
+	if len(history) != 2 {
+		t.Fatalf("GetNodeHistory = %+v, want 2 versions", history)
+	}
+	current, first := history[0], history[1]
+	if current.Version < first.Version {
+		current, first = first, current
+	}
+	if !current.IsCurrent || first.IsCurrent || current.ChangeNote != "publish" || current.ChangedBy != "tester" {
+		t.Errorf("GetNodeHistory = %+v", history)
+	}
+
+	old, err := repo.GetNodeAtVersion(f.ctx, note.ID, first.Version)
+	f.must(err)
+	if old.Meta["status"] != "draft" {
+		t.Errorf("GetNodeAtVersion(%d): status = %v", first.Version, old.Meta["status"])
+	}
+	then, err := repo.GetNodeAtTime(f.ctx, note.ID, beforeUpdate)
+	f.must(err)
+	if then.Meta["status"] != "draft" {
+		t.Errorf("GetNodeAtTime: status = %v", then.Meta["status"])
+	}
+}
+
+func testTraversal(t *testing.T, repo graph.Repository, f *fixture) {
+	var nodes []*core.Node
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		nodes = append(nodes, f.node(name, "Step", "", nil))
+	}
+	f.must(repo.CreateNodes(f.ctx, nodes))
+	f.must(repo.CreateLinks(f.ctx, []*core.Link{
+		f.link("a", "b", "NEXT"),
+		f.link("b", "c", "NEXT"),
+		f.link("c", "d", "NEXT"),
+		f.link("a", "e", "SEE_ALSO"),
+	}))
+
+	reached, err := repo.TraverseGraph(f.ctx, f.id("a"), 2, nil, 100, 0)
+	f.must(err)
+	for _, name := range []string{"b", "c", "e"} {
+		if _, ok := reached[f.id(name)]; !ok {
+			t.Errorf("TraverseGraph depth 2 missed %s", name)
+		}
+	}
+	if _, ok := reached[f.id("d")]; ok {
+		t.Error("TraverseGraph depth 2 reached d, three hops away")
+	}
+	reached, err = repo.TraverseGraph(f.ctx, f.id("a"), 3, []string{"NEXT"}, 100, 0)
+	f.must(err)
+	if _, ok := reached[f.id("e")]; ok {
+		t.Error("TraverseGraph followed SEE_ALSO while limited to NEXT")
+	}
+	if _, ok := reached[f.id("d")]; !ok {
+		t.Error("TraverseGraph depth 3 missed d")
+	}
+
+	sub, err := repo.GetSubgraph(f.ctx, f.id("a"), 1, nil)
+	f.must(err)
+	if ids := nodeIDs(sub.Nodes); !slices.Equal(ids, []string{f.id("a"), f.id("b"), f.id("e")}) {
+		t.Errorf("GetSubgraph depth 1 nodes = %v", ids)
+	}
+	if len(sub.Edges) != 2 {
+		t.Errorf("GetSubgraph depth 1 edges = %d, want 2", len(sub.Edges))
+	}
+}
+
+func testSearch(t *testing.T, repo graph.Repository, f *fixture) {
+	word := "zq" + f.prefix // A word no other node contains
+	f.must(repo.CreateNodes(f.ctx, []*core.Node{
+		f.node("hit", "Note", "notes about "+word+" and more", nil),
+		f.node("miss", "Note", "unrelated text", nil),
+	}))
+
+	found, err := repo.SearchNodes(f.ctx, word, 10, 0)
+	f.must(err)
+	if ids := nodeIDs(found); !slices.Equal(ids, []string{f.id("hit")}) {
+		t.Errorf("SearchNodes(%q) = %v", word, ids)
+	}
+
+	inRange, err := repo.QueryTimeRange(f.ctx, f.now.Add(-time.Minute), f.now.Add(time.Minute), []string{f.typ("Note")}, 10, 0)
+	f.must(err)
+	if ids := nodeIDs(inRange); !slices.Equal(ids, []string{f.id("hit"), f.id("miss")}) {
+		t.Errorf("QueryTimeRange = %v", ids)
+	}
+	before, err := repo.QueryTimeRange(f.ctx, f.now.Add(-time.Hour), f.now.Add(-time.Minute), []string{f.typ("Note")}, 10, 0)
+	f.must(err)
+	if len(before) != 0 {
+		t.Errorf("QueryTimeRange before creation = %v", nodeIDs(before))
+	}
+}
+
+func testEvents(t *testing.T, repo graph.Repository, f *fixture) {
+	var mu sync.Mutex
+	var events []string
+	repo.SetEventEmitter(func(e subscriptions.Event) {
+		// Only this run's nodes, as a shared database may be busy
+		if !slices.Contains([]string{f.id("x"), f.id("y")}, e.NodeID) && e.LinkSource != f.id("x") {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		id := e.NodeID
+		if e.LinkSource != "" {
+			id = e.LinkSource + "->" + e.LinkTarget
+		}
+		events = append(events, e.Type+" "+id)
+	})
+	defer repo.SetEventEmitter(nil)
+
+	f.must(repo.CreateNode(f.ctx, f.node("x", "Note", "", nil)))
+	f.must(repo.CreateNode(f.ctx, f.node("y", "Note", "", nil)))
+	f.must(repo.CreateLink(f.ctx, f.link("x", "y", "CITES")))
+	f.must(repo.UpdateNodeMeta(f.ctx, f.id("x"), map[string]any{"read": true}))
+	f.must(repo.DeleteLink(f.ctx, f.id("x"), f.id("y"), "CITES"))
+	f.must(repo.DeleteNode(f.ctx, f.id("y"), false))
+
+	want := []string{
+		subscriptions.EventNodeCreated + " " + f.id("x"),
+		subscriptions.EventNodeCreated + " " + f.id("y"),
+		subscriptions.EventLinkCreated + " " + f.id("x") + "->" + f.id("y"),
+		subscriptions.EventNodeUpdated + " " + f.id("x"),
+		subscriptions.EventLinkDeleted + " " + f.id("x") + "->" + f.id("y"),
+		subscriptions.EventNodeDeleted + " " + f.id("y"),
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func testLenses(t *testing.T, repo graph.Repository, f *fixture) {
+	f.must(repo.CreateNodes(f.ctx, []*core.Node{
+		f.node("lens", "Lens", "", map[string]interface{}{"name": "Marxist reading"}),
+		f.node("labor", "Concept", "", nil),
+		f.node("capital", "Concept", "", nil),
+		f.node("weather", "Concept", "", nil),
+	}))
+	f.must(repo.CreateInterpretedThroughLink(f.ctx, f.id("labor"), f.id("lens"), map[string]interface{}{"role": "primary"}))
+	f.must(repo.CreateInterpretedThroughLink(f.ctx, f.id("capital"), f.id("lens"), map[string]interface{}{"role": "secondary"}))
+
+	entities, err := repo.GetEntitiesInterpretedThrough(f.ctx, f.id("lens"))
+	f.must(err)
+	if ids := nodeIDs(entities); !slices.Equal(ids, []string{f.id("capital"), f.id("labor")}) {
+		t.Errorf("GetEntitiesInterpretedThrough = %v", ids)
+	}
+	primary, err := repo.QueryByLens(f.ctx, f.id("lens"), "primary", 10, 0)
+	f.must(err)
+	if ids := nodeIDs(primary); !slices.Equal(ids, []string{f.id("labor")}) {
+		t.Errorf("QueryByLens(primary) = %v", ids)
+	}
+
+	export, err := repo.ExportLens(f.ctx, f.id("lens"), false)
+	f.must(err)
+	if export.Lens == nil || export.Lens.ID != f.id("lens") {
+		t.Errorf("ExportLens lens = %+v", export.Lens)
+	}
+	if ids := nodeIDs(export.Entities); !slices.Equal(ids, []string{f.id("capital"), f.id("labor")}) {
+		t.Errorf("ExportLens entities = %v", ids)
+	}
+}
+
+func testSnapshots(t *testing.T, repo graph.Repository, f *fixture) {
+	snapshotter, ok := repo.(graph.Snapshotter)
+	if !ok {
+		t.Skip("backend does not support snapshots")
+	}
+	word := "zs" + f.prefix
+	f.must(repo.CreateNodes(f.ctx, []*core.Node{
+		f.node("kept", "Note", "kept "+word, map[string]interface{}{"status": "draft"}),
+		f.node("doomed", "Note", "doomed "+word, nil),
+	}))
+
+	snap, err := snapshotter.BeginSnapshot(f.ctx)
+	f.must(err)
+	defer snap.Close()
+	pinned := graph.WithSnapshot(f.ctx, snap)
+
+	f.must(repo.UpdateNodeMeta(f.ctx, f.id("kept"), map[string]interface{}{"status": "published"}))
+	f.must(repo.CreateNode(f.ctx, f.node("later", "Note", "later "+word, nil)))
+	f.must(repo.CreateLink(f.ctx, f.link("later", "kept", "CITES")))
+	f.must(repo.DeleteNode(f.ctx, f.id("doomed"), false))
+
+	// Causal snapshots only promise the starting state is visible
+	if _, err := repo.GetNode(pinned, f.id("kept")); err != nil {
+		t.Fatalf("pinned GetNode: %v", err)
+	}
+	if snap.Consistency() != graph.SnapshotIsolated {
+		return
+	}
+
+	kept, err := repo.GetNode(pinned, f.id("kept"))
+	f.must(err)
+	if kept.Meta["status"] != "draft" {
+		t.Errorf("pinned GetNode status = %v, want the value before the update", kept.Meta["status"])
+	}
+	if _, err := repo.GetNode(pinned, f.id("doomed")); err != nil {
+		t.Errorf("pinned GetNode of a node deleted after the snapshot: %v", err)
+	}
+	if _, err := repo.GetNode(pinned, f.id("later")); err == nil {
+		t.Error("pinned GetNode found a node created after the snapshot")
+	}
+	found, err := repo.SearchNodes(pinned, word, 10, 0)
+	f.must(err)
+	if ids := nodeIDs(found); !slices.Equal(ids, []string{f.id("doomed"), f.id("kept")}) {
+		t.Errorf("pinned SearchNodes = %v", ids)
+	}
+	backlinks, err := repo.GetBacklinks(pinned, f.id("kept"))
+	f.must(err)
+	if len(backlinks) != 0 {
+		t.Errorf("pinned GetBacklinks = %v", linkKeys(backlinks))
+	}
+
+	// Unpinned reads see the writes
+	found, err = repo.SearchNodes(f.ctx, word, 10, 0)
+	f.must(err)
+	if ids := nodeIDs(found); !slices.Equal(ids, []string{f.id("kept"), f.id("later")}) {
+		t.Errorf("SearchNodes = %v", ids)
+	}
+}
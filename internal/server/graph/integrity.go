@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// DefaultIntegrityExcludeTypes are node types that are unlinked by design
+// and so never reported as orphans
+var DefaultIntegrityExcludeTypes = []string{"Subscription", "Lens", "SavedQuery", "LinkRule", "MemoryCard", ErasureAuditType, "Stats", "IngestHook", "GitHubSync", "TicketSync", "TypeRule", "Device", "Connector", "ConnectorState"}
+
+// Link endpoint states reported for dangling links
+const (
+	EndpointMissing = "missing" // No node with the ID exists
+	EndpointDeleted = "deleted" // The node's current version is a tombstone
+)
+
+// IntegrityOptions selects what an integrity check reports
+type IntegrityOptions struct {
+	ExcludeTypes []string // Node types never reported as orphans
+	Limit        int      // Maximum entries listed per category; 0 lists all
+}
+
+// IntegrityReport lists structural problems in the graph. Counts are the
+// totals found; the lists stop at the requested limit.
+type IntegrityReport struct {
+	Orphans        []IntegrityNode `json:"orphans"`
+	DanglingLinks  []DanglingLink  `json:"dangling_links"`
+	BrokenVersions []BrokenVersion `json:"broken_versions"`
+	Counts         IntegrityCounts `json:"counts"`
+	Truncated      bool            `json:"truncated"`
+}
+
+// IntegrityCounts totals each kind of problem
+type IntegrityCounts struct {
+	Orphans        int `json:"orphans"`
+	DanglingLinks  int `json:"dangling_links"`
+	BrokenVersions int `json:"broken_versions"`
+}
+
+// IntegrityNode is a live node with no links in either direction
+type IntegrityNode struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// DanglingLink is a link with a missing or tombstoned endpoint
+type DanglingLink struct {
+	Source      string `json:"source"`
+	Target      string `json:"target"`
+	Type        string `json:"type"`
+	SourceState string `json:"source_state,omitempty"` // missing or deleted; empty when live
+	TargetState string `json:"target_state,omitempty"`
+}
+
+// BrokenVersion is a version chain entry naming a version that does not exist
+type BrokenVersion struct {
+	Newer   string `json:"newer_version_id"`
+	Older   string `json:"older_version_id"`
+	Missing string `json:"missing"` // newer, older or both
+}
+
+// newIntegrityReport creates an empty report
+func newIntegrityReport() *IntegrityReport {
+	return &IntegrityReport{
+		Orphans:        []IntegrityNode{},
+		DanglingLinks:  []DanglingLink{},
+		BrokenVersions: []BrokenVersion{},
+	}
+}
+
+// full reports whether a category already lists limit entries
+func (r *IntegrityReport) full(listed int, opts IntegrityOptions) bool {
+	if opts.Limit > 0 && listed >= opts.Limit {
+		r.Truncated = true
+		return true
+	}
+	return false
+}
+
+func (r *IntegrityReport) addOrphan(n IntegrityNode, opts IntegrityOptions) {
+	r.Counts.Orphans++
+	if !r.full(len(r.Orphans), opts) {
+		r.Orphans = append(r.Orphans, n)
+	}
+}
+
+func (r *IntegrityReport) addDanglingLink(l DanglingLink, opts IntegrityOptions) {
+	r.Counts.DanglingLinks++
+	if !r.full(len(r.DanglingLinks), opts) {
+		r.DanglingLinks = append(r.DanglingLinks, l)
+	}
+}
+
+func (r *IntegrityReport) addBrokenVersion(v BrokenVersion, opts IntegrityOptions) {
+	r.Counts.BrokenVersions++
+	if !r.full(len(r.BrokenVersions), opts) {
+		r.BrokenVersions = append(r.BrokenVersions, v)
+	}
+}
+
+// excluded reports whether orphans of a node type are left out
+func (o IntegrityOptions) excluded(nodeType string) bool {
+	for _, t := range o.ExcludeTypes {
+		if t == nodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// integrityNodeState is the current state of one logical node
+type integrityNodeState struct {
+	Type    string
+	Deleted bool
+}
+
+// checkLinkIntegrity fills a report from the current node states and all
+// links, for backends that cannot answer the check in a query
+func checkLinkIntegrity(report *IntegrityReport, order []string, nodes map[string]integrityNodeState, links []*core.Link, opts IntegrityOptions) {
+	linked := make(map[string]bool)
+	state := func(id string) string {
+		n, ok := nodes[id]
+		switch {
+		case !ok:
+			return EndpointMissing
+		case n.Deleted:
+			return EndpointDeleted
+		}
+		return ""
+	}
+	for _, l := range links {
+		linked[l.Source], linked[l.Target] = true, true
+		src, dst := state(l.Source), state(l.Target)
+		if src != "" || dst != "" {
+			report.addDanglingLink(DanglingLink{Source: l.Source, Target: l.Target, Type: l.Type, SourceState: src, TargetState: dst}, opts)
+		}
+	}
+	for _, id := range order {
+		n := nodes[id]
+		if !n.Deleted && !linked[id] && !opts.excluded(n.Type) {
+			report.addOrphan(IntegrityNode{ID: id, Type: n.Type}, opts)
+		}
+	}
+}
+
+// IntegrityCleanupOptions selects what a cleanup repairs
+type IntegrityCleanupOptions struct {
+	Links   bool `json:"links"`   // Delete dangling links
+	Orphans bool `json:"orphans"` // Tombstone orphan nodes
+}
+
+// IntegrityCleanup reports what a cleanup changed
+type IntegrityCleanup struct {
+	LinksDeleted   int               `json:"links_deleted"`
+	NodesDeleted   int               `json:"nodes_deleted"`
+	Failed         map[string]string `json:"failed,omitempty"` // Link or node -> error
+	BrokenVersions int               `json:"broken_versions"`  // Left for manual repair
+	Finished       time.Time         `json:"finished"`
+}
+
+// CleanupIntegrity runs a full integrity check and repairs what opts
+// selects: dangling links are deleted and orphans tombstoned (never force
+// deleted, so their history stays). Broken version chains are only counted.
+func CleanupIntegrity(ctx context.Context, repo Repository, opts IntegrityCleanupOptions, exclude []string) (*IntegrityCleanup, error) {
+	report, err := repo.CheckIntegrity(ctx, IntegrityOptions{ExcludeTypes: exclude})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &IntegrityCleanup{Failed: map[string]string{}, BrokenVersions: report.Counts.BrokenVersions}
+	if opts.Links {
+		for _, l := range report.DanglingLinks {
+			if err := repo.DeleteLink(ctx, l.Source, l.Target, l.Type); err != nil {
+				out.Failed[fmt.Sprintf("%s -[%s]-> %s", l.Source, l.Type, l.Target)] = err.Error()
+				continue
+			}
+			out.LinksDeleted++
+		}
+	}
+	if opts.Orphans {
+		for _, n := range report.Orphans {
+			if err := repo.DeleteNode(ctx, n.ID, false); err != nil {
+				out.Failed[n.ID] = err.Error()
+				continue
+			}
+			out.NodesDeleted++
+		}
+	}
+	if len(out.Failed) == 0 {
+		out.Failed = nil
+	}
+	out.Finished = time.Now()
+	return out, nil
+}
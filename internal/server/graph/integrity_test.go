@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, repo := range map[string]Repository{"sqlite": sqlite, "memory": NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for _, n := range []*core.Node{
+				{ID: "note:a", Type: "Note"},
+				{ID: "note:b", Type: "Note"},
+				{ID: "note:c", Type: "Note"},
+				{ID: "note:lonely", Type: "Note"},
+				{ID: "note:gone", Type: "Note"},
+				{ID: "lens:x", Type: "Lens"},
+			} {
+				n.Created, n.Modified = now, now
+				if err := repo.CreateNode(ctx, n); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.CreateLinks(ctx, []*core.Link{
+				{Source: "note:a", Target: "note:b", Type: "RELATED_TO", Created: now, Modified: now},
+				{Source: "note:b", Target: "note:c", Type: "RELATED_TO", Created: now, Modified: now},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.DeleteNode(ctx, "note:c", false); err != nil {
+				t.Fatal(err)
+			}
+			// A hard delete of a versioned node leaves its version chain behind in SQL
+			if err := repo.UpdateNodeMeta(ctx, "note:gone", map[string]any{"v": 2}); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.DeleteNode(ctx, "note:gone", true); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := repo.CheckIntegrity(ctx, IntegrityOptions{ExcludeTypes: DefaultIntegrityExcludeTypes})
+			if err != nil {
+				t.Fatalf("CheckIntegrity: %v", err)
+			}
+			if len(report.Orphans) != 1 || report.Orphans[0].ID != "note:lonely" {
+				t.Errorf("orphans = %+v, want only note:lonely", report.Orphans)
+			}
+			want := DanglingLink{Source: "note:b", Target: "note:c", Type: "RELATED_TO", TargetState: EndpointDeleted}
+			if len(report.DanglingLinks) != 1 || report.DanglingLinks[0] != want {
+				t.Errorf("dangling links = %+v, want %+v", report.DanglingLinks, want)
+			}
+			if name == "sqlite" && (report.Counts.BrokenVersions != 1 || report.BrokenVersions[0].Missing != "both") {
+				t.Errorf("broken versions = %+v, want the chain of note:gone", report.BrokenVersions)
+			}
+
+			limited, err := repo.CheckIntegrity(ctx, IntegrityOptions{Limit: 1})
+			if err != nil || !limited.Truncated || limited.Counts.Orphans != 2 || len(limited.Orphans) != 1 {
+				t.Errorf("limited report = %+v, %v; want 2 orphans (lens included) with 1 listed", limited, err)
+			}
+
+			cleanup, err := CleanupIntegrity(ctx, repo, IntegrityCleanupOptions{Links: true}, DefaultIntegrityExcludeTypes)
+			if err != nil || cleanup.LinksDeleted != 1 || cleanup.NodesDeleted != 0 {
+				t.Fatalf("cleanup = %+v, %v; want 1 link deleted", cleanup, err)
+			}
+			if report, _ := repo.CheckIntegrity(ctx, IntegrityOptions{}); report.Counts.DanglingLinks != 0 {
+				t.Errorf("dangling links after cleanup = %+v", report.DanglingLinks)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// LayerKey is the node and link property that assigns a layer explicitly
+const LayerKey = "layer"
+
+// Graph layers
+const (
+	LayerSource    = "source"    // Raw, content-addressed inputs
+	LayerDerived   = "derived"   // Produced by extractors, processors and rules
+	LayerAttention = "attention" // Usage signals such as ATTENDED edges
+	LayerManual    = "manual"    // Curated by people
+)
+
+// Layers lists every layer
+var Layers = []string{LayerSource, LayerDerived, LayerAttention, LayerManual}
+
+// derivedLinkTypes point from an interpretation to what it was made from
+var derivedLinkTypes = map[string]bool{"EXTRACTED_FROM": true, "DERIVED_FROM": true, "INTERPRETED_THROUGH": true, "SUPPORTED_BY": true}
+
+// derivedNodeTypes are records the server writes itself
+var derivedNodeTypes = map[string]bool{"Transaction": true, ErasureAuditType: true}
+
+// NodeLayer returns a node's layer: its explicit layer property, else
+// source for content-addressed sources, derived for extracted nodes and
+// citation stubs, and manual otherwise
+func NodeLayer(node *core.Node) string {
+	if layer, ok := explicitLayer(node.Meta); ok {
+		return layer
+	}
+	switch {
+	case node.Type == "Source" || strings.HasPrefix(node.ID, "sha256:"):
+		return LayerSource
+	case derivedNodeTypes[node.Type] || node.Meta["extractor"] != nil || node.Meta["citation_stub"] == true:
+		return LayerDerived
+	}
+	return LayerManual
+}
+
+// LinkLayer returns a link's layer: its explicit layer property, else
+// attention for ATTENDED edges, derived for provenance links and extracted
+// links, and manual otherwise
+func LinkLayer(link *core.Link) string {
+	return linkLayer(link.Type, link.Meta)
+}
+
+// EdgeLayer returns a subgraph edge's layer, as LinkLayer
+func EdgeLayer(edge *SubgraphEdge) string {
+	return linkLayer(edge.Type, edge.Meta)
+}
+
+func linkLayer(linkType string, meta map[string]interface{}) string {
+	if layer, ok := explicitLayer(meta); ok {
+		return layer
+	}
+	switch {
+	case linkType == "ATTENDED":
+		return LayerAttention
+	case derivedLinkTypes[linkType] || meta["extractor"] != nil:
+		return LayerDerived
+	}
+	return LayerManual
+}
+
+func explicitLayer(meta map[string]interface{}) (string, bool) {
+	layer, ok := meta[LayerKey].(string)
+	return layer, ok && isLayer(layer)
+}
+
+func isLayer(s string) bool {
+	for _, layer := range Layers {
+		if s == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateLayer checks an explicit layer property, if any
+func ValidateLayer(meta map[string]interface{}) error {
+	raw, ok := meta[LayerKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	if s, ok := raw.(string); !ok || !isLayer(s) {
+		return fmt.Errorf("invalid %s %v (%s)", LayerKey, raw, strings.Join(Layers, ", "))
+	}
+	return nil
+}
+
+// LayerFilter selects layers; a nil filter selects all of them
+type LayerFilter map[string]bool
+
+// ParseLayers reads layer names from repeated or comma-separated values,
+// returning nil when none are given
+func ParseLayers(values []string) (LayerFilter, error) {
+	var f LayerFilter
+	for _, v := range values {
+		for _, layer := range strings.Split(v, ",") {
+			layer = strings.TrimSpace(layer)
+			if layer == "" {
+				continue
+			}
+			if !isLayer(layer) {
+				return nil, fmt.Errorf("invalid layer %q (%s)", layer, strings.Join(Layers, ", "))
+			}
+			if f == nil {
+				f = make(LayerFilter)
+			}
+			f[layer] = true
+		}
+	}
+	return f, nil
+}
+
+// Node reports whether a node is in a selected layer
+func (f LayerFilter) Node(node *core.Node) bool {
+	return f == nil || f[NodeLayer(node)]
+}
+
+// Link reports whether a link is in a selected layer
+func (f LayerFilter) Link(link *core.Link) bool {
+	return f == nil || f[LinkLayer(link)]
+}
+
+// Edge reports whether a subgraph edge is in a selected layer
+func (f LayerFilter) Edge(edge *SubgraphEdge) bool {
+	return f == nil || f[EdgeLayer(edge)]
+}
+
+// Page returns the limit nodes after offset that are in the selected
+// layers, reading further pages from fetch as needed
+func (f LayerFilter) Page(limit, offset int, fetch PageFunc) ([]*core.Node, error) {
+	if f == nil {
+		return fetch(limit, offset)
+	}
+	return FillPage(limit, offset, f.Nodes, fetch)
+}
+
+// Nodes returns the nodes in selected layers
+func (f LayerFilter) Nodes(nodes []*core.Node) []*core.Node {
+	if f == nil {
+		return nodes
+	}
+	out := make([]*core.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if f.Node(n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Links returns the links in selected layers
+func (f LayerFilter) Links(links []*core.Link) []*core.Link {
+	if f == nil {
+		return links
+	}
+	out := make([]*core.Link, 0, len(links))
+	for _, l := range links {
+		if f.Link(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Subgraph keeps the nodes in selected layers and the edges in selected
+// layers between them. keep names nodes retained regardless, such as the
+// start of a traversal.
+func (f LayerFilter) Subgraph(sub *Subgraph, keep ...string) *Subgraph {
+	if f == nil || sub == nil {
+		return sub
+	}
+	kept := make(map[string]bool, len(sub.Nodes))
+	out := &Subgraph{Nodes: []*core.Node{}, Edges: []*SubgraphEdge{}, Stats: sub.Stats}
+	for _, n := range sub.Nodes {
+		if f.Node(n) || containsString(keep, n.ID) {
+			kept[n.ID] = true
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	for _, e := range sub.Edges {
+		if kept[e.Source] && kept[e.Target] && f.Edge(e) {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	out.Stats.NodeCount, out.Stats.EdgeCount = len(out.Nodes), len(out.Edges)
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LayerCount counts the nodes and links in one layer
+type LayerCount struct {
+	Nodes     int            `json:"nodes"`
+	Links     int            `json:"links"`
+	NodeTypes map[string]int `json:"node_types"`
+	LinkTypes map[string]int `json:"link_types"`
+}
+
+// LayerStats counts nodes and links per layer
+type LayerStats struct {
+	Layers map[string]*LayerCount `json:"layers"`
+	Nodes  int                    `json:"nodes"`
+	Links  int                    `json:"links"`
+}
+
+// GetLayerStats scans the graph and counts nodes and links per layer
+func GetLayerStats(ctx context.Context, repo Repository) (*LayerStats, error) {
+	stats := &LayerStats{Layers: make(map[string]*LayerCount, len(Layers))}
+	for _, layer := range Layers {
+		stats.Layers[layer] = &LayerCount{NodeTypes: map[string]int{}, LinkTypes: map[string]int{}}
+	}
+
+	for offset := 0; ; offset += aggregatePageSize {
+		nodes, err := repo.FilterNodes(ctx, nil, "", "", aggregatePageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			c := stats.Layers[NodeLayer(node)]
+			c.Nodes++
+			c.NodeTypes[node.Type]++
+			stats.Nodes++
+
+			links, err := repo.GetLinks(ctx, node.ID)
+			if err != nil {
+				return nil, fmt.Errorf("links of %s: %w", node.ID, err)
+			}
+			for _, l := range links {
+				c := stats.Layers[LinkLayer(l)]
+				c.Links++
+				c.LinkTypes[l.Type]++
+				stats.Links++
+			}
+		}
+		if len(nodes) < aggregatePageSize {
+			break
+		}
+	}
+	return stats, nil
+}
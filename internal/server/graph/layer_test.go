@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestLayers(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "sha256:abc", Type: "Source", Content: []byte("raw")},
+		{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"extractor": "gpt"}},
+		{ID: "note:1", Type: "Note"},
+		{ID: "note:2", Type: "Note", Meta: map[string]interface{}{"layer": "derived"}},
+	} {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "person:ada", Target: "sha256:abc", Type: "EXTRACTED_FROM"},
+		{Source: "note:1", Target: "person:ada", Type: "MENTIONS"},
+		{Source: "note:2", Target: "person:ada", Type: "MENTIONS", Meta: map[string]interface{}{"layer": "derived"}},
+	} {
+		l.Created, l.Modified = now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.UpdateAttentionEdge(ctx, "note:1", "note:2", "q1", 0.8); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"sha256:abc": LayerSource, "person:ada": LayerDerived, "note:1": LayerManual, "note:2": LayerDerived}
+	for id, layer := range want {
+		node, _ := repo.GetNode(ctx, id)
+		if got := NodeLayer(node); got != layer {
+			t.Errorf("NodeLayer(%s) = %s, want %s", id, got, layer)
+		}
+	}
+
+	stats, err := GetLayerStats(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Nodes != 4 || stats.Links != 4 {
+		t.Errorf("totals = %d nodes, %d links", stats.Nodes, stats.Links)
+	}
+	if c := stats.Layers[LayerDerived]; c.Nodes != 2 || c.Links != 2 || c.LinkTypes["MENTIONS"] != 1 {
+		t.Errorf("derived = %+v", c)
+	}
+	if c := stats.Layers[LayerAttention]; c.Links != 1 || c.LinkTypes["ATTENDED"] != 1 {
+		t.Errorf("attention = %+v", c)
+	}
+
+	manual, err := ParseLayers([]string{"manual"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := repo.GetSubgraph(ctx, "note:1", 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := manual.Subgraph(sub, "person:ada")
+	if len(filtered.Nodes) != 2 || len(filtered.Edges) != 1 || filtered.Edges[0].Type != "MENTIONS" {
+		t.Errorf("manual subgraph = %+v nodes, %+v edges", filtered.Nodes, filtered.Edges)
+	}
+
+	if _, err := ParseLayers([]string{"source,bogus"}); err == nil {
+		t.Error("ParseLayers accepted an unknown layer")
+	}
+	if f, _ := ParseLayers([]string{""}); f != nil {
+		t.Error("empty layer parameter should select everything")
+	}
+	if err := ValidateLayer(map[string]interface{}{"layer": "raw"}); err == nil {
+		t.Error("ValidateLayer accepted an unknown layer")
+	}
+}
@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// A link's weight and direction are typed fields. Clients and pipelines
+// written before they were used to keep them in meta as "weight" and
+// "bidirectional"; backends promote those to the fields as links are
+// written, and the schema migrations do the same for stored links.
+
+// Meta keys promoted to typed link fields
+const (
+	linkWeightKey        = "weight"
+	linkBidirectionalKey = "bidirectional"
+)
+
+// PromoteLinkFields moves a numeric meta weight and a boolean meta
+// bidirectional flag to the link's fields. Backends call it on every link
+// they write. A weight already set on the
+// link wins. The caller's meta map is not modified.
+func PromoteLinkFields(link *core.Link) {
+	weight, hasWeight := numericValue(link.Meta[linkWeightKey])
+	bidirectional, hasBidirectional := link.Meta[linkBidirectionalKey].(bool)
+	if !hasWeight && !hasBidirectional {
+		return
+	}
+
+	meta := make(map[string]interface{}, len(link.Meta))
+	for k, v := range link.Meta {
+		meta[k] = v
+	}
+	if hasWeight {
+		if link.Weight == 0 {
+			link.Weight = weight
+		}
+		delete(meta, linkWeightKey)
+	}
+	if hasBidirectional {
+		link.Bidirectional = link.Bidirectional || bidirectional
+		delete(meta, linkBidirectionalKey)
+	}
+	link.Meta = meta
+}
+
+// promoteLinkProperties promotes the fields of a stored link's properties
+// JSON, returning the rewritten properties and whether anything moved
+func promoteLinkProperties(properties string) (string, float64, bool, bool) {
+	link := &core.Link{}
+	if properties == "" || json.Unmarshal([]byte(properties), &link.Meta) != nil {
+		return properties, 0, false, false
+	}
+	before := len(link.Meta)
+	PromoteLinkFields(link)
+	if len(link.Meta) == before {
+		return properties, 0, false, false
+	}
+	data, err := json.Marshal(link.Meta)
+	if err != nil {
+		return properties, 0, false, false
+	}
+	return string(data), link.Weight, link.Bidirectional, true
+}
+
+// promoteStoredLinkFields moves the weight and direction of stored links
+// and link tombstones out of their properties into their own columns
+func promoteStoredLinkFields(ctx context.Context, tx *observedTx) error {
+	for _, table := range []string{"links", "link_tombstones"} {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, properties FROM `+table+`
+			WHERE properties LIKE '%"weight"%' OR properties LIKE '%"bidirectional"%'
+		`)
+		if err != nil {
+			return err
+		}
+		type promoted struct {
+			id            int64
+			properties    string
+			weight        float64
+			bidirectional bool
+		}
+		var pending []promoted
+		for rows.Next() {
+			var id int64
+			var properties string
+			if err := rows.Scan(&id, &properties); err != nil {
+				rows.Close()
+				return err
+			}
+			if props, weight, bidirectional, ok := promoteLinkProperties(properties); ok {
+				pending = append(pending, promoted{id, props, weight, bidirectional})
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, p := range pending {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE `+table+` SET properties = ?, weight = ?, bidirectional = ? WHERE id = ?`,
+				p.properties, p.weight, boolToInt(p.bidirectional), p.id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// relLinkFields reads the weight and direction of a Neo4j relationship
+func relLinkFields(props map[string]any) (float64, bool) {
+	weight, _ := numericValue(props[linkWeightKey])
+	bidirectional, _ := props[linkBidirectionalKey].(bool)
+	return weight, bidirectional
+}
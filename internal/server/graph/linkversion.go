@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// TargetVersionKey is the link property pinning a link to one version of
+// its target, as a version ID such as "paper:x:v2"
+const TargetVersionKey = "target_version_id"
+
+// VersionID returns the version ID of a node's version
+func VersionID(id string, version int) string {
+	return id + ":v" + strconv.Itoa(version)
+}
+
+// ParseVersionID splits a version ID of node id into its version number
+func ParseVersionID(id, versionID string) (int, error) {
+	n, ok := strings.CutPrefix(versionID, id+":v")
+	if !ok {
+		return 0, fmt.Errorf("invalid %s %q: not a version of %s", TargetVersionKey, versionID, id)
+	}
+	v, err := strconv.Atoi(n)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid %s %q: bad version number", TargetVersionKey, versionID)
+	}
+	return v, nil
+}
+
+// NormalizeTargetVersion checks meta[TargetVersionKey] names a version of
+// target. A bare version number is accepted and stored as a version ID;
+// a missing value is left alone.
+func NormalizeTargetVersion(meta map[string]interface{}, target string) error {
+	raw, ok := meta[TargetVersionKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	switch v := raw.(type) {
+	case string:
+		if _, err := ParseVersionID(target, v); err != nil {
+			return err
+		}
+	case float64:
+		if v < 1 || v != float64(int(v)) {
+			return fmt.Errorf("invalid %s %v: bad version number", TargetVersionKey, v)
+		}
+		meta[TargetVersionKey] = VersionID(target, int(v))
+	case int:
+		if v < 1 {
+			return fmt.Errorf("invalid %s %d: bad version number", TargetVersionKey, v)
+		}
+		meta[TargetVersionKey] = VersionID(target, v)
+	default:
+		return fmt.Errorf("invalid %s: expected a version ID", TargetVersionKey)
+	}
+	return nil
+}
+
+// LinkTargetVersion returns the version of its target a link is pinned to
+func LinkTargetVersion(target string, meta map[string]interface{}) (int, bool) {
+	id, ok := meta[TargetVersionKey].(string)
+	if !ok {
+		return 0, false
+	}
+	v, err := ParseVersionID(target, id)
+	return v, err == nil
+}
+
+// PinnedVersions resolves the nodes in a traversal result to the versions
+// pinned by the links between them. A node stays at its current version
+// when the links into it pin different versions; those nodes are returned
+// as conflicts. Nodes listed in keep, such as the start node, are never
+// replaced.
+func PinnedVersions(ctx context.Context, repo Repository, nodes map[string]*core.Node, edges []*SubgraphEdge, keep ...string) ([]string, error) {
+	pins := make(map[string]int)
+	conflicted := make(map[string]bool)
+	for _, e := range edges {
+		if nodes[e.Source] == nil || nodes[e.Target] == nil || containsString(keep, e.Target) {
+			continue
+		}
+		v, ok := LinkTargetVersion(e.Target, e.Meta)
+		if !ok {
+			continue
+		}
+		if prev, seen := pins[e.Target]; seen && prev != v {
+			conflicted[e.Target] = true
+		}
+		pins[e.Target] = v
+	}
+
+	var conflicts []string
+	for id, v := range pins {
+		if conflicted[id] {
+			conflicts = append(conflicts, id)
+			continue
+		}
+		if nodes[id].Version == v {
+			continue
+		}
+		node, err := repo.GetNodeAtVersion(ctx, id, v)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", VersionID(id, v), err)
+		}
+		nodes[id] = node
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// PinnedSubgraph returns a copy of sub with its nodes resolved to their
+// pinned versions, like PinnedVersions
+func PinnedSubgraph(ctx context.Context, repo Repository, sub *Subgraph, keep ...string) (*Subgraph, []string, error) {
+	nodes := make(map[string]*core.Node, len(sub.Nodes))
+	for _, n := range sub.Nodes {
+		nodes[n.ID] = n
+	}
+	conflicts, err := PinnedVersions(ctx, repo, nodes, sub.Edges, keep...)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := &Subgraph{Nodes: make([]*core.Node, len(sub.Nodes)), Edges: sub.Edges, Stats: sub.Stats}
+	for i, n := range sub.Nodes {
+		out.Nodes[i] = nodes[n.ID]
+	}
+	return out, conflicts, nil
+}
+
+// OutgoingEdges returns the links from each node to the others, for
+// resolving pinned versions in a result without edges
+func OutgoingEdges(ctx context.Context, repo Repository, nodes map[string]*core.Node) ([]*SubgraphEdge, error) {
+	var edges []*SubgraphEdge
+	for id := range nodes {
+		links, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			if nodes[l.Target] != nil {
+				edges = append(edges, &SubgraphEdge{Source: l.Source, Target: l.Target, Type: l.Type, Meta: l.Meta, Weight: l.Weight, Bidirectional: l.Bidirectional})
+			}
+		}
+	}
+	return edges, nil
+}
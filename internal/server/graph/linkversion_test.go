@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestNormalizeTargetVersion(t *testing.T) {
+	meta := map[string]interface{}{TargetVersionKey: float64(2)}
+	if err := NormalizeTargetVersion(meta, "paper:x"); err != nil || meta[TargetVersionKey] != "paper:x:v2" {
+		t.Errorf("version number normalized to %v, %v", meta[TargetVersionKey], err)
+	}
+	for _, bad := range []interface{}{"paper:y:v2", "paper:x:v0", "paper:x:latest", 1.5, true} {
+		if err := NormalizeTargetVersion(map[string]interface{}{TargetVersionKey: bad}, "paper:x"); err == nil {
+			t.Errorf("NormalizeTargetVersion(%v) accepted", bad)
+		}
+	}
+	if err := NormalizeTargetVersion(nil, "paper:x"); err != nil {
+		t.Errorf("missing target version = %v", err)
+	}
+}
+
+func TestPinnedVersions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+
+	for _, n := range []*core.Node{
+		{ID: "paper:x", Type: "Paper", Meta: map[string]any{"abstract": "first draft"}},
+		{ID: "claim:1", Type: "Claim"},
+		{ID: "claim:2", Type: "Claim"},
+	} {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.UpdateNodeMeta(ctx, "paper:x", map[string]any{"abstract": "revised"})
+	repo.CreateLink(ctx, &core.Link{Source: "claim:1", Target: "paper:x", Type: "CITES", Meta: map[string]any{TargetVersionKey: "paper:x:v1"}, Created: now, Modified: now})
+
+	sub, err := repo.GetSubgraph(ctx, "claim:1", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned, conflicts, err := PinnedSubgraph(ctx, repo, sub, "claim:1")
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("PinnedSubgraph() conflicts = %v, %v", conflicts, err)
+	}
+	for i, n := range pinned.Nodes {
+		if n.ID == "paper:x" && (n.Version != 1 || n.Meta["abstract"] != "first draft") {
+			t.Errorf("paper:x resolved to v%d %v, want the pinned v1", n.Version, n.Meta)
+		}
+		if sub.Nodes[i].ID == "paper:x" && sub.Nodes[i].Version != 2 {
+			t.Error("PinnedSubgraph() modified its input")
+		}
+	}
+
+	// A second claim citing v2 conflicts with the first
+	repo.CreateNode(ctx, &core.Node{ID: "topic:1", Type: "Topic", Created: now, Modified: now})
+	for _, claim := range []string{"claim:1", "claim:2"} {
+		repo.CreateLink(ctx, &core.Link{Source: "topic:1", Target: claim, Type: "ABOUT", Created: now, Modified: now})
+	}
+	repo.CreateLink(ctx, &core.Link{Source: "claim:2", Target: "paper:x", Type: "CITES", Meta: map[string]any{TargetVersionKey: "paper:x:v2"}, Created: now, Modified: now})
+	nodes, err := repo.TraverseGraph(ctx, "topic:1", 2, nil, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges, err := OutgoingEdges(ctx, repo, nodes)
+	if err != nil || len(edges) != 4 {
+		t.Fatalf("OutgoingEdges() = %v, %v", edges, err)
+	}
+	conflicts, err = PinnedVersions(ctx, repo, nodes, edges, "topic:1")
+	if err != nil || !reflect.DeepEqual(conflicts, []string{"paper:x"}) || nodes["paper:x"].Version != 2 {
+		t.Errorf("PinnedVersions() with disagreeing pins = %v, %v, version %d", conflicts, err, nodes["paper:x"].Version)
+	}
+	delete(nodes, "claim:2")
+	if conflicts, err := PinnedVersions(ctx, repo, nodes, edges, "topic:1"); err != nil || len(conflicts) != 0 || nodes["paper:x"].Version != 1 {
+		t.Errorf("PinnedVersions() with one citing claim = %v, %v, version %d", conflicts, err, nodes["paper:x"].Version)
+	}
+}
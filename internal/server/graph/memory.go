@@ -0,0 +1,1425 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// MemoryRepository implements Repository entirely in memory (maps and
+// adjacency lists). It mirrors the SQLite semantics - versioning, tombstones,
+// degrees, attention edges - and is meant for tests and throwaway sandboxes;
+// nothing survives Close.
+type MemoryRepository struct {
+	mu sync.RWMutex
+
+	versions map[string][]*core.Node // all versions per node ID, oldest first
+	order    []string                // node IDs in creation order
+	degree   map[string]int          // degree of the current version
+
+	links      map[linkKey]*core.Link
+	outgoing   map[string][]linkKey // adjacency lists in creation order
+	incoming   map[string][]linkKey
+	tombstones []linkTombstone
+
+	eventEmitter func(subscriptions.Event)
+}
+
+// linkKey identifies a link; as in the SQL schema, (source, target, type) is unique
+type linkKey struct {
+	source, target, typ string
+}
+
+// linkTombstone records a deleted link for graph diffs
+type linkTombstone struct {
+	link    *core.Link
+	deleted time.Time
+}
+
+// NewMemory creates an empty in-memory repository
+func NewMemory() *MemoryRepository {
+	return &MemoryRepository{
+		versions: make(map[string][]*core.Node),
+		degree:   make(map[string]int),
+		links:    make(map[linkKey]*core.Link),
+		outgoing: make(map[string][]linkKey),
+		incoming: make(map[string][]linkKey),
+	}
+}
+
+// Close is a no-op; the graph is discarded with the repository
+func (r *MemoryRepository) Close(ctx context.Context) error {
+	return nil
+}
+
+// EnsureIndexes is a no-op for the in-memory backend
+func (r *MemoryRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// SetEventEmitter sets the callback for emitting events
+func (r *MemoryRepository) SetEventEmitter(emitter func(subscriptions.Event)) {
+	r.eventEmitter = emitter
+}
+
+// emit sends an event to the subscription manager if one is registered
+func (r *MemoryRepository) emit(event subscriptions.Event) {
+	if r.eventEmitter != nil {
+		r.eventEmitter(event)
+	}
+}
+
+// CreateNode creates a new node in the graph
+func (r *MemoryRepository) CreateNode(ctx context.Context, node *core.Node) error {
+	r.mu.Lock()
+	if _, exists := r.versions[node.ID]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("inserting node: node already exists: %s", node.ID)
+	}
+	r.insertNode(node)
+	r.mu.Unlock()
+
+	r.emit(subscriptions.Event{
+		ID:        uuid.New().String(),
+		Type:      subscriptions.EventNodeCreated,
+		Timestamp: time.Now(),
+		NodeID:    node.ID,
+		NodeType:  node.Type,
+		Meta:      node.Meta,
+	})
+
+	return nil
+}
+
+// CreateNodes creates many nodes atomically
+func (r *MemoryRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	r.mu.Lock()
+	seen := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if _, exists := r.versions[node.ID]; exists || seen[node.ID] {
+			r.mu.Unlock()
+			return fmt.Errorf("inserting node %s: node already exists", node.ID)
+		}
+		seen[node.ID] = true
+	}
+	for _, node := range nodes {
+		r.insertNode(node)
+	}
+	r.mu.Unlock()
+
+	for _, node := range nodes {
+		r.emit(subscriptions.Event{
+			ID:        uuid.New().String(),
+			Type:      subscriptions.EventNodeCreated,
+			Timestamp: time.Now(),
+			NodeID:    node.ID,
+			NodeType:  node.Type,
+			Meta:      node.Meta,
+		})
+	}
+
+	return nil
+}
+
+// GetNode retrieves the current version of a node by ID
+func (r *MemoryRepository) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	node := r.visible(ctx, id)
+	if node == nil {
+		return nil, fmt.Errorf("node not found")
+	}
+	return node, nil
+}
+
+// GetNodeAtVersion retrieves a specific version of a node
+func (r *MemoryRepository) GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, node := range r.versions[id] {
+		if node.Version == version {
+			return cloneNode(node), nil
+		}
+	}
+	return nil, fmt.Errorf("node not found")
+}
+
+// GetNodeAtTime retrieves the version of a node that was current at a specific time
+func (r *MemoryRepository) GetNodeAtTime(ctx context.Context, id string, asOf time.Time) (*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := r.versions[id]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].Modified.After(asOf) {
+			return cloneNode(versions[i]), nil
+		}
+	}
+	return nil, fmt.Errorf("node not found")
+}
+
+// GetNodeHistory returns all versions of a node ordered by version descending
+func (r *MemoryRepository) GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := r.versions[id]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+
+	history := make([]core.VersionInfo, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		history = append(history, core.VersionInfo{
+			Version:    v.Version,
+			VersionID:  v.VersionID,
+			Modified:   v.Modified,
+			ChangeNote: v.ChangeNote,
+			ChangedBy:  v.ChangedBy,
+			IsCurrent:  v.IsCurrent,
+		})
+	}
+	return history, nil
+}
+
+// GetLinks retrieves all outgoing links for a node
+func (r *MemoryRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var links []*core.Link
+	for _, key := range r.outgoing[nodeID] {
+		links = append(links, cloneLink(r.links[key]))
+	}
+	return links, nil
+}
+
+// GetBacklinks retrieves all incoming links for a node
+func (r *MemoryRepository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var links []*core.Link
+	for _, key := range r.incoming[nodeID] {
+		links = append(links, cloneLink(r.links[key]))
+	}
+	return links, nil
+}
+
+// MatchPattern finds bindings of a structural pattern by backtracking,
+// extending each partial binding along the pattern's links
+func (r *MemoryRepository) MatchPattern(ctx context.Context, p *Pattern, limit int) ([]PatternBinding, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fits := func(v int, id string) bool {
+		n := r.live(id)
+		return n != nil && (p.Nodes[v].Type == "" || n.Type == p.Nodes[v].Type) && p.propsMatch(v, n.Meta)
+	}
+	linked := func(e PatternEdge, source, target string) bool {
+		for _, key := range r.outgoing[source] {
+			if key.target == target && (e.Type == "" || key.typ == e.Type) {
+				return true
+			}
+		}
+		return false
+	}
+
+	order := p.order()
+	ids := make([]string, len(p.Nodes))
+	assigned := make([]bool, len(p.Nodes))
+	matches := []PatternBinding{}
+	var extend func(k int)
+	extend = func(k int) {
+		if len(matches) >= limit {
+			return
+		}
+		if k == len(order) {
+			matches = append(matches, p.binding(ids))
+			return
+		}
+		v := order[k]
+
+		// Candidates for the first variable are all nodes; later ones
+		// follow a link from a variable already bound
+		candidates := r.order
+		if k > 0 {
+			candidates = nil
+			for _, e := range p.Edges {
+				if e.From == v && e.To != v && assigned[e.To] {
+					for _, key := range r.incoming[ids[e.To]] {
+						candidates = append(candidates, key.source)
+					}
+					break
+				}
+				if e.To == v && e.From != v && assigned[e.From] {
+					for _, key := range r.outgoing[ids[e.From]] {
+						candidates = append(candidates, key.target)
+					}
+					break
+				}
+			}
+		}
+
+		tried := make(map[string]bool)
+		for _, id := range candidates {
+			if tried[id] || !fits(v, id) {
+				continue
+			}
+			tried[id] = true
+			ids[v], assigned[v] = id, true
+			ok := true
+			for _, e := range p.Edges {
+				if (e.From == v || e.To == v) && assigned[e.From] && assigned[e.To] && !linked(e, ids[e.From], ids[e.To]) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				extend(k + 1)
+			}
+			assigned[v] = false
+		}
+	}
+	extend(0)
+	return matches, nil
+}
+
+// SearchNodes performs a case-insensitive substring search over ID, type,
+// properties and content. With search options each query word is matched
+// on its own.
+func (r *MemoryRepository) SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	term := strings.ToLower(searchTerm)
+	opts := searchOptionsFrom(ctx)
+	var groups [][][]string
+	if opts != nil {
+		groups = opts.groups(searchTerm)
+	}
+	return paginate(r.visibleNodes(ctx, func(n *core.Node) bool {
+		if opts != nil && !opts.inNamespace(n.ID) {
+			return false
+		}
+		metaJSON, _ := json.Marshal(n.Meta)
+		if len(groups) > 0 {
+			return matchesGroups(strings.ToLower(n.ID+" "+n.Type+" "+string(metaJSON)+" "+string(n.Content)), groups)
+		}
+		for _, field := range []string{n.ID, n.Type, string(metaJSON), string(n.Content)} {
+			if strings.Contains(strings.ToLower(field), term) {
+				return true
+			}
+		}
+		return false
+	}), limit, offset), nil
+}
+
+// SearchSimilar scores every node's names against the term
+func (r *MemoryRepository) SearchSimilar(ctx context.Context, term string, limit int) ([]*SimilarNode, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	opts := searchOptionsFrom(ctx)
+	return rankSimilar(term, r.visibleNodes(ctx, func(n *core.Node) bool {
+		return opts == nil || opts.inNamespace(n.ID)
+	}), limit), nil
+}
+
+// FilterNodes returns nodes matching filter criteria
+func (r *MemoryRepository) FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	searchValue := ""
+	if propertyKey != "" && propertyValue != "" {
+		searchValue = fmt.Sprintf(`"%s":"%s"`, propertyKey, propertyValue)
+	}
+
+	return paginate(r.visibleNodes(ctx, func(n *core.Node) bool {
+		if !hasType(nodeTypes, n.Type) {
+			return false
+		}
+		if searchValue != "" {
+			metaJSON, _ := json.Marshal(n.Meta)
+			return strings.Contains(string(metaJSON), searchValue)
+		}
+		return true
+	}), limit, offset), nil
+}
+
+// TraverseGraph performs a breadth-first traversal along outgoing links
+func (r *MemoryRepository) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found []*core.Node
+	for _, id := range r.reachable(startNodeID, depth, relationshipTypes, TraversalMeterFrom(ctx)) {
+		if node := r.live(id); node != nil {
+			found = append(found, cloneNode(node))
+		}
+	}
+
+	result := make(map[string]*core.Node)
+	for _, node := range paginate(found, limit, offset) {
+		result[node.ID] = node
+	}
+	return result, nil
+}
+
+// QueryTimeRange returns current nodes created or modified within [from, to]
+func (r *MemoryRepository) QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	within := func(t time.Time) bool { return !t.Before(from) && !t.After(to) }
+	nodes := r.liveNodes(func(n *core.Node) bool {
+		return hasType(nodeTypes, n.Type) && (within(n.Created) || within(n.Modified))
+	})
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Modified.After(nodes[j].Modified)
+	})
+	return paginate(nodes, limit, offset), nil
+}
+
+// CreateLink creates a relationship between two nodes
+func (r *MemoryRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	r.mu.Lock()
+	key := linkKey{link.Source, link.Target, link.Type}
+	if _, exists := r.links[key]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("inserting link: link already exists: %s -[%s]-> %s", link.Source, link.Type, link.Target)
+	}
+	r.insertLink(link)
+	r.mu.Unlock()
+
+	r.emit(subscriptions.Event{
+		ID:         uuid.New().String(),
+		Type:       subscriptions.EventLinkCreated,
+		Timestamp:  time.Now(),
+		LinkSource: link.Source,
+		LinkTarget: link.Target,
+		LinkType:   link.Type,
+		Meta:       link.Meta,
+	})
+
+	return nil
+}
+
+// CreateLinks creates many links atomically, updating degrees
+func (r *MemoryRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	r.mu.Lock()
+	seen := make(map[linkKey]bool, len(links))
+	for _, link := range links {
+		key := linkKey{link.Source, link.Target, link.Type}
+		if _, exists := r.links[key]; exists || seen[key] {
+			r.mu.Unlock()
+			return fmt.Errorf("inserting link %s->%s: link already exists", link.Source, link.Target)
+		}
+		seen[key] = true
+	}
+	for _, link := range links {
+		r.insertLink(link)
+	}
+	r.mu.Unlock()
+
+	for _, link := range links {
+		r.emit(subscriptions.Event{
+			ID:         uuid.New().String(),
+			Type:       subscriptions.EventLinkCreated,
+			Timestamp:  time.Now(),
+			LinkSource: link.Source,
+			LinkTarget: link.Target,
+			LinkType:   link.Type,
+			Meta:       link.Meta,
+		})
+	}
+
+	return nil
+}
+
+// DeleteLink deletes a specific relationship between two nodes
+func (r *MemoryRepository) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
+	r.mu.Lock()
+	key := linkKey{sourceID, targetID, linkType}
+	link, exists := r.links[key]
+	if !exists {
+		r.mu.Unlock()
+		return fmt.Errorf("link not found: %s -[%s]-> %s", sourceID, linkType, targetID)
+	}
+	r.tombstones = append(r.tombstones, linkTombstone{link: link, deleted: time.Now()})
+	r.removeLink(key)
+	r.adjustDegree(sourceID, -1)
+	r.adjustDegree(targetID, -1)
+	r.mu.Unlock()
+
+	r.emit(subscriptions.Event{
+		ID:         uuid.New().String(),
+		Type:       subscriptions.EventLinkDeleted,
+		Timestamp:  time.Now(),
+		LinkSource: sourceID,
+		LinkTarget: targetID,
+		LinkType:   linkType,
+	})
+
+	return nil
+}
+
+// ListNodes returns all node IDs
+func (r *MemoryRepository) ListNodes(ctx context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []string
+	for _, id := range r.order {
+		if r.live(id) != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// UpdateNodeMeta creates a new version of a node with updated metadata
+func (r *MemoryRepository) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	return r.UpdateNodeMetaWithNote(ctx, id, meta, "", "")
+}
+
+// UpdateNodeMetaWithNote creates a new version with a change note
+func (r *MemoryRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	r.mu.Lock()
+	current := r.live(id)
+	if current == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("node not found: %s", id)
+	}
+
+	merged := cloneMeta(current.Meta)
+	if merged == nil {
+		merged = make(map[string]any)
+	}
+	for k, v := range meta {
+		merged[k] = v
+	}
+
+	next := &core.Node{
+		ID:         id,
+		Type:       current.Type,
+		Content:    current.Content,
+		Meta:       cloneMeta(merged),
+		Created:    current.Created,
+		Modified:   time.Now(),
+		Version:    current.Version + 1,
+		VersionID:  fmt.Sprintf("%s:v%d", id, current.Version+1),
+		IsCurrent:  true,
+		ChangeNote: changeNote,
+		ChangedBy:  changedBy,
+	}
+	current.IsCurrent = false
+	r.versions[id] = append(r.versions[id], next)
+	r.mu.Unlock()
+
+	r.emit(subscriptions.Event{
+		ID:        uuid.New().String(),
+		Type:      subscriptions.EventNodeUpdated,
+		Timestamp: time.Now(),
+		NodeID:    id,
+		NodeType:  current.Type,
+		Meta: map[string]any{
+			"version":      next.Version,
+			"prev_version": current.Version,
+			"change_note":  changeNote,
+			"updated_meta": meta,
+		},
+	})
+
+	return nil
+}
+
+// DeleteNode marks a node as deleted (tombstone), or removes it and its
+// links entirely when force is set
+func (r *MemoryRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	r.mu.Lock()
+	current := r.live(nodeID)
+	if current == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("node not found or already deleted: %s", nodeID)
+	}
+
+	// Protected nodes cannot be deleted, even with force
+	if IsProtected(current.Meta) {
+		r.mu.Unlock()
+		return fmt.Errorf("cannot delete %s: %w", nodeID, ErrProtected)
+	}
+
+	// Protect Source layer unless force=true
+	if !force && strings.HasPrefix(nodeID, "sha256:") && len(nodeID) > 7 {
+		r.mu.Unlock()
+		return fmt.Errorf("cannot delete Source layer node (content-addressed): %s", nodeID)
+	}
+
+	if force {
+		for _, key := range append(append([]linkKey(nil), r.outgoing[nodeID]...), r.incoming[nodeID]...) {
+			if _, exists := r.links[key]; !exists {
+				continue // self-link already removed via outgoing
+			}
+			r.removeLink(key)
+			r.adjustDegree(key.source, -1)
+			r.adjustDegree(key.target, -1)
+		}
+		delete(r.versions, nodeID)
+		delete(r.degree, nodeID)
+		for i, id := range r.order {
+			if id == nodeID {
+				r.order = append(r.order[:i], r.order[i+1:]...)
+				break
+			}
+		}
+	} else {
+		now := time.Now()
+		current.IsCurrent = false
+		r.versions[nodeID] = append(r.versions[nodeID], &core.Node{
+			ID:         nodeID,
+			Type:       current.Type,
+			Content:    []byte{},
+			Meta:       map[string]interface{}{},
+			Created:    current.Created,
+			Modified:   now,
+			Deleted:    true,
+			DeletedAt:  now,
+			Version:    current.Version + 1,
+			VersionID:  fmt.Sprintf("%s:v%d", nodeID, current.Version+1),
+			IsCurrent:  true,
+			ChangeNote: "Deleted",
+		})
+		r.degree[nodeID] = 0
+	}
+	r.mu.Unlock()
+
+	r.emit(subscriptions.Event{
+		ID:        uuid.New().String(),
+		Type:      subscriptions.EventNodeDeleted,
+		Timestamp: time.Now(),
+		NodeID:    nodeID,
+	})
+
+	return nil
+}
+
+// GetSubgraph extracts a subgraph centered on a start node
+func (r *MemoryRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
+	nodesMap, err := r.TraverseGraph(ctx, startNodeID, depth, relationshipTypes, 1000, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	edges := r.edgesAmong(nodesMap, func(l *core.Link) bool { return hasType(relationshipTypes, l.Type) })
+	r.mu.RUnlock()
+
+	nodes := make([]*core.Node, 0, len(nodesMap))
+	for _, node := range nodesMap {
+		nodes = append(nodes, node)
+	}
+
+	return &Subgraph{
+		Nodes: nodes,
+		Edges: edges,
+		Stats: SubgraphStats{
+			NodeCount: len(nodes),
+			EdgeCount: len(edges),
+			Depth:     depth,
+		},
+	}, nil
+}
+
+// GetGraphSnapshot returns current nodes (optionally filtered by type, up to limit)
+// and all links between them
+func (r *MemoryRepository) GetGraphSnapshot(ctx context.Context, nodeTypes []string, limit int) (*Subgraph, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := r.liveNodes(func(n *core.Node) bool { return hasType(nodeTypes, n.Type) })
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	nodes = paginate(nodes, limit, 0)
+
+	selected := make(map[string]*core.Node, len(nodes))
+	for _, node := range nodes {
+		selected[node.ID] = node
+	}
+	edges := r.edgesAmong(selected, nil)
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Type < b.Type
+	})
+
+	return &Subgraph{
+		Nodes: nodes,
+		Edges: edges,
+		Stats: SubgraphStats{
+			NodeCount: len(nodes),
+			EdgeCount: len(edges),
+		},
+	}, nil
+}
+
+// UpdateAttentionEdge creates or updates an attention-weighted edge
+func (r *MemoryRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	key := linkKey{source, target, "ATTENDED"}
+	link, exists := r.links[key]
+	if !exists {
+		// Attention edges do not count towards degree
+		link = &core.Link{
+			Source:   source,
+			Target:   target,
+			Type:     "ATTENDED",
+			Created:  now,
+			Modified: now,
+		}
+		applyAttention(link, queryID, AttentionHeadFrom(ctx), weight, now)
+		link.Meta = cloneMeta(link.Meta)
+		r.links[key] = link
+		r.outgoing[source] = append(r.outgoing[source], key)
+		r.incoming[target] = append(r.incoming[target], key)
+		return nil
+	}
+
+	applyAttention(link, queryID, AttentionHeadFrom(ctx), weight, now)
+	link.Meta = cloneMeta(link.Meta)
+	link.Modified = now
+
+	return nil
+}
+
+// GetAttentionSubgraph extracts nodes connected by high-weight attention edges
+func (r *MemoryRepository) GetAttentionSubgraph(ctx context.Context, startNodeID string, minWeight float64, maxNodes int) (*Subgraph, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodeMap := make(map[string]*core.Node)
+	var nodes []*core.Node
+	keys := append(append([]linkKey(nil), r.outgoing[startNodeID]...), r.incoming[startNodeID]...)
+	for _, key := range keys {
+		if len(nodes) >= maxNodes {
+			break
+		}
+		if key.typ != "ATTENDED" {
+			continue
+		}
+		if !attentionPasses(ctx, r.links[key].Weight, r.links[key].Meta, minWeight) {
+			continue
+		}
+		for _, id := range []string{key.source, key.target} {
+			if id == startNodeID || nodeMap[id] != nil {
+				continue
+			}
+			if node := r.live(id); node != nil && len(nodes) < maxNodes {
+				clone := cloneNode(node)
+				nodeMap[id] = clone
+				nodes = append(nodes, clone)
+			}
+		}
+	}
+
+	edges := r.edgesAmong(nodeMap, func(l *core.Link) bool { return l.Type == "ATTENDED" && inAttentionHead(ctx, l.Meta) })
+
+	return &Subgraph{
+		Nodes: nodes,
+		Edges: edges,
+		Stats: SubgraphStats{
+			NodeCount: len(nodes),
+			EdgeCount: len(edges),
+			Depth:     2,
+		},
+	}, nil
+}
+
+// PruneWeakAttentionEdges removes attention edges with low weight or query count
+func (r *MemoryRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight float64, minQueryCount int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var prune []linkKey
+	for key, link := range r.links {
+		if key.typ != "ATTENDED" || link.Meta == nil {
+			continue
+		}
+		if r.protected(key.source) || r.protected(key.target) {
+			continue
+		}
+		c, hasCount := link.Meta["query_count"].(float64)
+		if link.Weight < minWeight || (hasCount && int(c) < minQueryCount) {
+			prune = append(prune, key)
+		}
+	}
+	for _, key := range prune {
+		r.removeLink(key)
+	}
+
+	return len(prune), nil
+}
+
+// GetGraphMap returns a high-level map of the graph
+func (r *MemoryRepository) GetGraphMap(ctx context.Context, sampleSize int) (*GraphMap, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	graphMap := &GraphMap{
+		NodeTypes:     make(map[string]int),
+		EdgeTypes:     make(map[string]int),
+		SamplesByType: make(map[string][]string),
+	}
+
+	nodes := r.liveNodes(nil)
+	samples := make(map[string][]string)
+	pinned := make(map[string][]string)
+	for _, node := range nodes {
+		graphMap.NodeTypes[node.Type]++
+		if IsPinned(node.Meta) {
+			pinned[node.Type] = append(pinned[node.Type], node.ID)
+		}
+		if len(samples[node.Type]) < sampleSize {
+			samples[node.Type] = append(samples[node.Type], node.ID)
+		}
+	}
+	for nodeType := range graphMap.NodeTypes {
+		sort.Strings(pinned[nodeType])
+		graphMap.SamplesByType[nodeType] = withPinned(pinned[nodeType], samples[nodeType], sampleSize)
+	}
+	graphMap.Stats.TotalNodes = len(nodes)
+
+	for key := range r.links {
+		graphMap.EdgeTypes[key.typ]++
+	}
+	graphMap.Stats.TotalEdges = len(r.links)
+
+	for _, node := range nodes {
+		if d := r.degree[node.ID]; d > 0 {
+			graphMap.TopConnected = append(graphMap.TopConnected, NodeSummary{
+				ID:     node.ID,
+				Type:   node.Type,
+				Degree: d,
+			})
+		}
+	}
+	sort.SliceStable(graphMap.TopConnected, func(i, j int) bool {
+		return graphMap.TopConnected[i].Degree > graphMap.TopConnected[j].Degree
+	})
+	if len(graphMap.TopConnected) > sampleSize {
+		graphMap.TopConnected = graphMap.TopConnected[:sampleSize]
+	}
+
+	return graphMap, nil
+}
+
+// protected reports whether a live node is flagged protected; callers hold r.mu
+func (r *MemoryRepository) protected(id string) bool {
+	node := r.live(id)
+	return node != nil && IsProtected(node.Meta)
+}
+
+// GetTimeline returns current nodes created within [from, to] bucketed by day or week
+func (r *MemoryRepository) GetTimeline(ctx context.Context, from, to time.Time, bucket string, nodeTypes []string, sampleSize int) (*Timeline, error) {
+	if err := ValidateBucket(bucket); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []timelineEntry
+	for _, node := range r.liveNodes(func(n *core.Node) bool {
+		return hasType(nodeTypes, n.Type) && !n.Created.Before(from) && !n.Created.After(to)
+	}) {
+		entries = append(entries, timelineEntry{
+			ID:      node.ID,
+			Type:    node.Type,
+			Created: node.Created,
+			Degree:  r.degree[node.ID],
+		})
+	}
+
+	return buildTimeline(entries, from, to, bucket, sampleSize), nil
+}
+
+// GetUsage accounts stored node versions per namespace and type
+func (r *MemoryRepository) GetUsage(ctx context.Context) (*Usage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []usageEntry
+	for _, id := range r.order {
+		for _, v := range r.versions[id] {
+			e := newNodeEntry(v)
+			e.Live = v.IsCurrent && !v.Deleted
+			entries = append(entries, e)
+		}
+	}
+
+	return buildUsage(entries), nil
+}
+
+// CheckIntegrity reports orphan nodes and links to missing or tombstoned
+// nodes. Versions are held per node, so there is no chain to break.
+func (r *MemoryRepository) CheckIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make(map[string]integrityNodeState, len(r.order))
+	for _, id := range r.order {
+		versions := r.versions[id]
+		current := versions[len(versions)-1]
+		nodes[id] = integrityNodeState{Type: current.Type, Deleted: current.Deleted}
+	}
+	links := make([]*core.Link, 0, len(r.links))
+	for _, link := range r.links {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Target < b.Target
+	})
+
+	report := newIntegrityReport()
+	checkLinkIntegrity(report, r.order, nodes, links, opts)
+	return report, nil
+}
+
+// RecomputeDegrees recounts the links of live nodes, a batch at a time
+func (r *MemoryRepository) RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error) {
+	r.mu.RLock()
+	ids := make([]string, 0, len(r.order))
+	for _, id := range r.order {
+		if r.live(id) != nil {
+			ids = append(ids, id)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Strings(ids)
+
+	report := newDegreeRepair(opts)
+	batch := opts.batchSize()
+	for start := 0; start < len(ids); start += batch {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		for _, id := range ids[start:min(start+batch, len(ids))] {
+			node := r.live(id)
+			if node == nil {
+				continue
+			}
+			actual := 0
+			for _, keys := range [][]linkKey{r.outgoing[id], r.incoming[id]} {
+				for _, key := range keys {
+					if key.typ != "ATTENDED" {
+						actual++
+					}
+				}
+			}
+			d := DegreeDiscrepancy{ID: id, Type: node.Type, Stored: r.degree[id], Actual: actual}
+			if report.check(d, opts) && !opts.DryRun {
+				r.degree[id] = actual
+				report.Fixed++
+			}
+		}
+		r.mu.Unlock()
+		report.Batches++
+	}
+	return report, nil
+}
+
+// ChangeLog returns the changes made after since as events, oldest first
+func (r *MemoryRepository) ChangeLog(ctx context.Context, since time.Time, limit int) ([]subscriptions.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []subscriptions.Event
+	for _, id := range r.order {
+		for _, v := range r.versions[id] {
+			if v.Modified.After(since) {
+				events = append(events, nodeVersionEvent(v.ID, v.Type, v.Version, v.Modified, v.Deleted, v.ChangeNote, cloneMeta(v.Meta)))
+			}
+		}
+	}
+	for _, link := range r.links {
+		if link.Created.After(since) {
+			events = append(events, linkEvent(subscriptions.EventLinkCreated, edgeOf(link), link.Created))
+		}
+	}
+	for _, t := range r.tombstones {
+		if t.link.Created.After(since) {
+			events = append(events, linkEvent(subscriptions.EventLinkCreated, edgeOf(t.link), t.link.Created))
+		}
+		if t.deleted.After(since) {
+			events = append(events, linkEvent(subscriptions.EventLinkDeleted, edgeOf(t.link), t.deleted))
+		}
+	}
+
+	return sortChangeLog(events, changeLogLimit(limit)), nil
+}
+
+// DiffGraph reports nodes and links that changed in (from, to]
+func (r *MemoryRepository) DiffGraph(ctx context.Context, from, to time.Time, detailed bool) (*GraphDiff, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	within := func(t time.Time) bool { return t.After(from) && !t.After(to) }
+
+	var versions []nodeVersionRow
+	for _, id := range r.order {
+		for _, v := range r.versions[id] {
+			if within(v.Modified) {
+				versions = append(versions, nodeVersionRow{
+					ID:       v.ID,
+					Type:     v.Type,
+					Version:  v.Version,
+					Modified: v.Modified,
+					Deleted:  v.Deleted,
+				})
+			}
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Modified.Before(versions[j].Modified)
+	})
+
+	var linksAdded, linksRemoved []linkChangeRow
+	for _, link := range r.links {
+		if within(link.Created) {
+			linksAdded = append(linksAdded, linkChangeRow{Edge: edgeOf(link), Created: link.Created})
+		}
+	}
+	for _, t := range r.tombstones {
+		if within(t.deleted) {
+			linksRemoved = append(linksRemoved, linkChangeRow{Edge: edgeOf(t.link), Created: t.link.Created})
+		}
+	}
+
+	return buildGraphDiff(from, to, versions, linksAdded, linksRemoved, detailed), nil
+}
+
+// GetEntitiesInterpretedThrough returns entities linked to a lens via INTERPRETED_THROUGH
+func (r *MemoryRepository) GetEntitiesInterpretedThrough(ctx context.Context, lensID string) ([]*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.interpretedThrough(lensID, ""), nil
+}
+
+// CreateInterpretedThroughLink creates an INTERPRETED_THROUGH link
+func (r *MemoryRepository) CreateInterpretedThroughLink(ctx context.Context, entityID, lensID string, meta map[string]interface{}) error {
+	link := &core.Link{
+		Source:   entityID,
+		Target:   lensID,
+		Type:     "INTERPRETED_THROUGH",
+		Meta:     meta,
+		Created:  time.Now(),
+		Modified: time.Now(),
+	}
+	return r.CreateLink(ctx, link)
+}
+
+// QueryByLens returns entities interpreted through a lens with optional pattern filter
+func (r *MemoryRepository) QueryByLens(ctx context.Context, lensID string, pattern string, limit int, offset int) ([]*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return paginate(r.interpretedThrough(lensID, pattern), limit, offset), nil
+}
+
+// ExportLens returns a complete export of a lens and its entities
+func (r *MemoryRepository) ExportLens(ctx context.Context, lensID string, includeExtractedFrom bool) (*LensExport, error) {
+	export := &LensExport{}
+
+	lens, err := r.GetNode(ctx, lensID)
+	if err != nil {
+		return nil, fmt.Errorf("lens not found: %s", lensID)
+	}
+	export.Lens = lens
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	export.Entities = r.interpretedThrough(lensID, "")
+	for _, entity := range export.Entities {
+		export.Links = append(export.Links, &SubgraphEdge{
+			Source: entity.ID,
+			Target: lensID,
+			Type:   "INTERPRETED_THROUGH",
+			Meta:   entity.Meta["_interpretation"].(map[string]interface{}),
+		})
+	}
+
+	if includeExtractedFrom {
+		for _, entity := range export.Entities {
+			for _, key := range r.outgoing[entity.ID] {
+				if key.typ == "EXTRACTED_FROM" {
+					export.Links = append(export.Links, edgeOf(r.links[key]))
+				}
+			}
+		}
+	}
+
+	export.Stats.EntityCount = len(export.Entities)
+	export.Stats.LinkCount = len(export.Links)
+
+	return export, nil
+}
+
+// CreateSubscriptionNode persists a subscription as a node
+func (r *MemoryRepository) CreateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
+	node, err := subscriptionNode(sub)
+	if err != nil {
+		return err
+	}
+	return r.CreateNode(ctx, node)
+}
+
+// UpdateSubscriptionNode updates a subscription node
+func (r *MemoryRepository) UpdateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
+	meta, err := subscriptionMeta(sub)
+	if err != nil {
+		return err
+	}
+	if sub.LastFired != nil {
+		meta["last_fired"] = sub.LastFired.Format(time.RFC3339)
+	}
+
+	return r.UpdateNodeMeta(ctx, "subscription:"+sub.ID, meta)
+}
+
+// DeleteSubscriptionNode removes a subscription node
+func (r *MemoryRepository) DeleteSubscriptionNode(ctx context.Context, id string) error {
+	return r.DeleteNode(ctx, "subscription:"+id, true)
+}
+
+// LoadSubscriptions loads all subscription nodes
+func (r *MemoryRepository) LoadSubscriptions(ctx context.Context) ([]*subscriptions.Subscription, error) {
+	nodes, err := r.FilterNodes(ctx, []string{"Subscription"}, "", "", 1000, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*subscriptions.Subscription
+	for _, node := range nodes {
+		subs = append(subs, subscriptionFromNode(node))
+	}
+
+	return subs, nil
+}
+
+// ExecuteCypherRead returns error - Cypher not supported in memory
+func (r *MemoryRepository) ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return nil, fmt.Errorf("Cypher queries are not supported with the memory backend. Use Neo4j backend for Cypher support")
+}
+
+// Helper functions (callers hold r.mu)
+
+// insertNode stores version 1 of a new node
+func (r *MemoryRepository) insertNode(node *core.Node) {
+	node.Version = 1
+	node.VersionID = node.ID + ":v1"
+	node.IsCurrent = true
+
+	r.versions[node.ID] = []*core.Node{cloneNode(node)}
+	r.order = append(r.order, node.ID)
+}
+
+// insertLink stores a link and counts it towards both endpoints' degree
+func (r *MemoryRepository) insertLink(link *core.Link) {
+	PromoteLinkFields(link)
+	key := linkKey{link.Source, link.Target, link.Type}
+	r.links[key] = cloneLink(link)
+	r.outgoing[link.Source] = append(r.outgoing[link.Source], key)
+	r.incoming[link.Target] = append(r.incoming[link.Target], key)
+	r.adjustDegree(link.Source, 1)
+	r.adjustDegree(link.Target, 1)
+}
+
+// removeLink drops a link from the index and adjacency lists
+func (r *MemoryRepository) removeLink(key linkKey) {
+	delete(r.links, key)
+	r.outgoing[key.source] = withoutKey(r.outgoing[key.source], key)
+	r.incoming[key.target] = withoutKey(r.incoming[key.target], key)
+}
+
+// adjustDegree changes the degree of a node that exists and is not deleted
+func (r *MemoryRepository) adjustDegree(id string, delta int) {
+	if r.live(id) != nil {
+		r.degree[id] += delta
+	}
+}
+
+// live returns the stored current version of a node, or nil if it is
+// missing or deleted
+func (r *MemoryRepository) live(id string) *core.Node {
+	versions := r.versions[id]
+	if len(versions) == 0 {
+		return nil
+	}
+	current := versions[len(versions)-1]
+	if current.Deleted {
+		return nil
+	}
+	return current
+}
+
+// visible returns a copy of a node's current version or, with
+// IncludesDeleted(ctx), of a deleted node's last live version
+func (r *MemoryRepository) visible(ctx context.Context, id string) *core.Node {
+	if node := r.live(id); node != nil {
+		return cloneNode(node)
+	}
+	if !IncludesDeleted(ctx) {
+		return nil
+	}
+	return r.lastLive(id)
+}
+
+// lastLive returns a copy of a deleted node's last live version, marked
+// deleted, or nil if the node is not deleted
+func (r *MemoryRepository) lastLive(id string) *core.Node {
+	versions := r.versions[id]
+	if len(versions) < 2 || !versions[len(versions)-1].Deleted {
+		return nil
+	}
+	node := cloneNode(versions[len(versions)-2])
+	markDeleted(node, versions[len(versions)-1].DeletedAt)
+	return node
+}
+
+// visibleNodes is liveNodes including deleted nodes when ctx asks for them
+func (r *MemoryRepository) visibleNodes(ctx context.Context, match func(*core.Node) bool) []*core.Node {
+	if !IncludesDeleted(ctx) {
+		return r.liveNodes(match)
+	}
+	var nodes []*core.Node
+	for _, id := range r.order {
+		node := r.visible(ctx, id)
+		if node == nil || (match != nil && !match(node)) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// ListDeleted returns deleted nodes, most recently deleted first, each as
+// its last live version marked with its deletion time
+func (r *MemoryRepository) ListDeleted(ctx context.Context, limit int, offset int) ([]*core.Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var nodes []*core.Node
+	for _, id := range r.order {
+		if node := r.lastLive(id); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].DeletedAt.After(nodes[j].DeletedAt)
+	})
+	return paginate(nodes, limit, offset), nil
+}
+
+// liveNodes returns copies of current nodes in creation order, optionally filtered
+func (r *MemoryRepository) liveNodes(match func(*core.Node) bool) []*core.Node {
+	var nodes []*core.Node
+	for _, id := range r.order {
+		node := r.live(id)
+		if node == nil || (match != nil && !match(node)) {
+			continue
+		}
+		nodes = append(nodes, cloneNode(node))
+	}
+	return nodes
+}
+
+// reachable returns node IDs within depth outgoing hops of start, in BFS
+// order, stopping early when meter's budget runs out
+func (r *MemoryRepository) reachable(start string, depth int, relationshipTypes []string, meter *TraversalMeter) []string {
+	if !meter.Node() {
+		return nil
+	}
+	seen := map[string]bool{start: true}
+	order := []string{start}
+	frontier := []string{start}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, id := range frontier {
+			for _, key := range r.outgoing[id] {
+				if !hasType(relationshipTypes, key.typ) {
+					continue
+				}
+				if !meter.Edge() {
+					return order
+				}
+				if seen[key.target] {
+					continue
+				}
+				if !meter.Node() {
+					return order
+				}
+				seen[key.target] = true
+				order = append(order, key.target)
+				next = append(next, key.target)
+			}
+		}
+		frontier = next
+	}
+	return order
+}
+
+// edgesAmong returns links whose endpoints are both in nodes, optionally filtered
+func (r *MemoryRepository) edgesAmong(nodes map[string]*core.Node, match func(*core.Link) bool) []*SubgraphEdge {
+	var edges []*SubgraphEdge
+	for id := range nodes {
+		for _, key := range r.outgoing[id] {
+			if _, ok := nodes[key.target]; !ok {
+				continue
+			}
+			link := r.links[key]
+			if match != nil && !match(link) {
+				continue
+			}
+			edges = append(edges, edgeOf(link))
+		}
+	}
+	return edges
+}
+
+// interpretedThrough returns current entities linked to a lens, with the
+// link properties under _interpretation. A non-empty pattern must appear in
+// the link properties (case-insensitive).
+func (r *MemoryRepository) interpretedThrough(lensID, pattern string) []*core.Node {
+	var nodes []*core.Node
+	for _, key := range r.incoming[lensID] {
+		if key.typ != "INTERPRETED_THROUGH" {
+			continue
+		}
+		link := r.links[key]
+		if pattern != "" {
+			props, _ := json.Marshal(link.Meta)
+			if !strings.Contains(strings.ToLower(string(props)), strings.ToLower(pattern)) {
+				continue
+			}
+		}
+		entity := r.live(key.source)
+		if entity == nil {
+			continue
+		}
+		node := cloneNode(entity)
+		if node.Meta == nil {
+			node.Meta = make(map[string]interface{})
+		}
+		node.Meta["_interpretation"] = cloneMeta(link.Meta)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// cloneNode returns a deep copy of a node so callers cannot mutate the store
+func cloneNode(n *core.Node) *core.Node {
+	c := *n
+	c.Content = append([]byte(nil), n.Content...)
+	c.Meta = cloneMeta(n.Meta)
+	return &c
+}
+
+// cloneLink returns a deep copy of a link
+func cloneLink(l *core.Link) *core.Link {
+	c := *l
+	c.Meta = cloneMeta(l.Meta)
+	return &c
+}
+
+// cloneMeta deep-copies properties through JSON, so values come back with
+// the same types a SQL backend would return (numbers as float64)
+func cloneMeta(meta map[string]any) map[string]any {
+	if meta == nil {
+		return nil
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil
+	}
+	var c map[string]any
+	json.Unmarshal(data, &c)
+	return c
+}
+
+// edgeOf converts a link to a subgraph edge
+func edgeOf(l *core.Link) *SubgraphEdge {
+	return &SubgraphEdge{
+		Source:        l.Source,
+		Target:        l.Target,
+		Type:          l.Type,
+		Meta:          cloneMeta(l.Meta),
+		Weight:        l.Weight,
+		Bidirectional: l.Bidirectional,
+	}
+}
+
+// hasType reports whether t is in types; an empty list matches everything
+func hasType(types []string, t string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutKey removes key from keys
+func withoutKey(keys []linkKey, key linkKey) []linkKey {
+	for i, k := range keys {
+		if k == key {
+			return append(keys[:i:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
+
+// paginate applies SQL-style LIMIT/OFFSET; a negative limit means no limit
+func paginate(nodes []*core.Node, limit, offset int) []*core.Node {
+	if offset >= len(nodes) {
+		return nil
+	}
+	if offset > 0 {
+		nodes = nodes[offset:]
+	}
+	if limit >= 0 && limit < len(nodes) {
+		nodes = nodes[:limit]
+	}
+	return nodes
+}
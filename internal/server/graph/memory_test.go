@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+var _ Repository = (*MemoryRepository)(nil)
+
+func TestMemoryRepositoryVersionsAndLinks(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Meta: map[string]any{"n": 1}, Created: now, Modified: now}); err != nil {
+			t.Fatalf("CreateNode(%s): %v", id, err)
+		}
+	}
+	if err := repo.CreateNode(ctx, &core.Node{ID: "a", Type: "Note"}); err == nil {
+		t.Error("expected duplicate node to be rejected")
+	}
+	if err := repo.CreateLinks(ctx, []*core.Link{
+		{Source: "a", Target: "b", Type: "REL", Created: now, Modified: now},
+		{Source: "b", Target: "c", Type: "REL", Created: now, Modified: now},
+	}); err != nil {
+		t.Fatalf("CreateLinks: %v", err)
+	}
+	if back, err := repo.GetBacklinks(ctx, "b"); err != nil || len(back) != 1 || back[0].Source != "a" {
+		t.Errorf("GetBacklinks(b) = %v, %v; want one link from a", back, err)
+	}
+
+	if err := repo.UpdateNodeMetaWithNote(ctx, "a", map[string]any{"title": "A"}, "retitle", "test"); err != nil {
+		t.Fatalf("UpdateNodeMetaWithNote: %v", err)
+	}
+	node, err := repo.GetNode(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetNode: %v", err)
+	}
+	if node.Version != 2 || node.Meta["title"] != "A" || node.Meta["n"] != float64(1) {
+		t.Errorf("GetNode(a) = v%d %v, want v2 with merged meta", node.Version, node.Meta)
+	}
+	node.Meta["title"] = "mutated"
+	if again, _ := repo.GetNode(ctx, "a"); again.Meta["title"] != "A" {
+		t.Error("mutating a returned node changed the store")
+	}
+
+	reached, err := repo.TraverseGraph(ctx, "a", 2, nil, 100, 0)
+	if err != nil {
+		t.Fatalf("TraverseGraph: %v", err)
+	}
+	if len(reached) != 3 {
+		t.Errorf("TraverseGraph reached %d nodes, want 3", len(reached))
+	}
+
+	if err := repo.DeleteNode(ctx, "c", false); err != nil {
+		t.Fatalf("DeleteNode: %v", err)
+	}
+	if _, err := repo.GetNode(ctx, "c"); err == nil {
+		t.Error("expected deleted node to be hidden")
+	}
+	history, err := repo.GetNodeHistory(ctx, "c")
+	if err != nil || len(history) != 2 {
+		t.Errorf("GetNodeHistory(c) = %d versions, %v; want 2", len(history), err)
+	}
+
+	if err := repo.DeleteLink(ctx, "a", "b", "REL"); err != nil {
+		t.Fatalf("DeleteLink: %v", err)
+	}
+	diff, err := repo.DiffGraph(ctx, now.Add(-time.Minute), time.Now().Add(time.Minute), false)
+	if err != nil {
+		t.Fatalf("DiffGraph: %v", err)
+	}
+	if diff.Summary.NodesAdded != 2 || diff.Summary.LinksAdded != 1 {
+		t.Errorf("DiffGraph summary = %+v, want 2 nodes and 1 link added", diff.Summary)
+	}
+}
@@ -16,6 +16,8 @@ import (
 type Neo4jRepository struct {
 	driver       neo4j.DriverWithContext
 	eventEmitter func(subscriptions.Event)
+	batchSize    int           // Rows per transaction for bulk loads
+	txTimeout    time.Duration // Server-side limit for transactions without a deadline
 }
 
 // SetEventEmitter sets the callback for emitting events to the subscription manager
@@ -32,10 +34,12 @@ func (r *Neo4jRepository) emit(event subscriptions.Event) {
 
 // Config holds Neo4j connection configuration
 type Config struct {
-	URI      string
-	Username string
-	Password string
-	Database string
+	URI       string
+	Username  string
+	Password  string
+	Database  string
+	BatchSize int           // Rows per transaction for bulk loads (default 1000)
+	TxTimeout time.Duration // Server-side transaction timeout when the request sets none (0: the server's default)
 }
 
 // NewNeo4j creates a new Neo4j repository
@@ -53,7 +57,12 @@ func NewNeo4j(ctx context.Context, cfg Config) (*Neo4jRepository, error) {
 		return nil, fmt.Errorf("connecting to neo4j: %w", err)
 	}
 
-	return &Neo4jRepository{driver: driver}, nil
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &Neo4jRepository{driver: driver, batchSize: batchSize, txTimeout: cfg.TxTimeout}, nil
 }
 
 // Close closes the Neo4j connection
@@ -63,7 +72,7 @@ func (r *Neo4jRepository) Close(ctx context.Context) error {
 
 // EnsureIndexes creates necessary indexes for performance
 func (r *Neo4jRepository) EnsureIndexes(ctx context.Context) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	indexes := []string{
@@ -79,13 +88,16 @@ func (r *Neo4jRepository) EnsureIndexes(ctx context.Context) error {
 		"CREATE INDEX node_version_id_index IF NOT EXISTS FOR (n:Node) ON (n.version_id)",
 		"CREATE INDEX node_version_index IF NOT EXISTS FOR (n:Node) ON (n.version)",
 		"CREATE INDEX node_is_current_index IF NOT EXISTS FOR (n:Node) ON (n.is_current)",
+		// Timestamp indexes for time-range queries
+		"CREATE INDEX node_created_index IF NOT EXISTS FOR (n:Node) ON (n.created)",
+		"CREATE INDEX node_modified_index IF NOT EXISTS FOR (n:Node) ON (n.modified)",
 	}
 
 	for _, indexQuery := range indexes {
 		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 			_, err := tx.Run(ctx, indexQuery, nil)
 			return nil, err
-		})
+		}, r.txConfig(ctx)...)
 		if err != nil {
 			return fmt.Errorf("creating index: %w", err)
 		}
@@ -96,12 +108,64 @@ func (r *Neo4jRepository) EnsureIndexes(ctx context.Context) error {
 		return fmt.Errorf("migrating nodes to versioned: %w", err)
 	}
 
+	// Move link weight and direction out of properties into their own fields
+	if err := r.migrateLinkFields(ctx); err != nil {
+		return fmt.Errorf("migrating link fields: %w", err)
+	}
+
 	return nil
 }
 
+// migrateLinkFields promotes the weight and bidirectional keys of stored
+// link properties to relationship fields
+func (r *Neo4jRepository) migrateLinkFields(ctx context.Context) error {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, `
+			MATCH ()-[r:LINK]->()
+			WHERE r.properties CONTAINS '"weight"' OR r.properties CONTAINS '"bidirectional"'
+			RETURN id(r) as rel_id, r.properties as props
+		`, nil)
+		if err != nil {
+			return nil, err
+		}
+		records, err := result.Collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			relID, _ := record.Get("rel_id")
+			propsValue, _ := record.Get("props")
+			propsStr, _ := propsValue.(string)
+			props, weight, bidirectional, ok := promoteLinkProperties(propsStr)
+			if !ok {
+				continue
+			}
+			_, err := tx.Run(ctx, `
+				MATCH ()-[r:LINK]->() WHERE id(r) = $rel_id
+				SET r.properties = $properties, r.weight = $weight, r.bidirectional = $bidirectional
+			`, map[string]any{
+				"rel_id":        relID,
+				"properties":    props,
+				"weight":        weight,
+				"bidirectional": bidirectional,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}, r.txConfig(ctx)...)
+
+	return err
+}
+
 // migrateNodesToVersioned adds version fields to existing nodes that don't have them
 func (r *Neo4jRepository) migrateNodesToVersioned(ctx context.Context) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -127,7 +191,7 @@ func (r *Neo4jRepository) migrateNodesToVersioned(ctx context.Context) error {
 			}
 		}
 		return nil, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	return err
 }
@@ -215,7 +279,7 @@ func parseNodeFromNeo4j(nodeData neo4j.Node) (*core.Node, error) {
 
 // CreateNode creates a new node in the graph
 func (r *Neo4jRepository) CreateNode(ctx context.Context, node *core.Node) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	// Set version fields for new nodes
@@ -261,7 +325,7 @@ func (r *Neo4jRepository) CreateNode(ctx context.Context, node *core.Node) error
 
 		_, err = tx.Run(ctx, query, params)
 		return nil, err
-	})
+	}, r.txConfig(ctx)...)
 
 	// Emit event on successful creation
 	if err == nil {
@@ -280,7 +344,11 @@ func (r *Neo4jRepository) CreateNode(ctx context.Context, node *core.Node) error
 
 // GetNode retrieves the current version of a node by ID
 func (r *Neo4jRepository) GetNode(ctx context.Context, id string) (*core.Node, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	if IncludesDeleted(ctx) {
+		return r.getNodeIncludingDeleted(ctx, id)
+	}
+
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -305,7 +373,7 @@ func (r *Neo4jRepository) GetNode(ctx context.Context, id string) (*core.Node, e
 		nodeData := nodeValue.(neo4j.Node)
 
 		return parseNodeFromNeo4j(nodeData)
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -316,7 +384,7 @@ func (r *Neo4jRepository) GetNode(ctx context.Context, id string) (*core.Node, e
 
 // GetNodeAtVersion retrieves a specific version of a node
 func (r *Neo4jRepository) GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -339,7 +407,7 @@ func (r *Neo4jRepository) GetNodeAtVersion(ctx context.Context, id string, versi
 		nodeData := nodeValue.(neo4j.Node)
 
 		return parseNodeFromNeo4j(nodeData)
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -350,7 +418,7 @@ func (r *Neo4jRepository) GetNodeAtVersion(ctx context.Context, id string, versi
 
 // GetNodeAtTime retrieves the version of a node that was current at a specific time
 func (r *Neo4jRepository) GetNodeAtTime(ctx context.Context, id string, asOf time.Time) (*core.Node, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -379,7 +447,7 @@ func (r *Neo4jRepository) GetNodeAtTime(ctx context.Context, id string, asOf tim
 		nodeData := nodeValue.(neo4j.Node)
 
 		return parseNodeFromNeo4j(nodeData)
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -390,7 +458,7 @@ func (r *Neo4jRepository) GetNodeAtTime(ctx context.Context, id string, asOf tim
 
 // GetNodeHistory returns all versions of a node ordered by version descending
 func (r *Neo4jRepository) GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -442,7 +510,7 @@ func (r *Neo4jRepository) GetNodeHistory(ctx context.Context, id string) ([]core
 		}
 
 		return versions, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -459,7 +527,7 @@ func (r *Neo4jRepository) UpdateNodeMeta(ctx context.Context, id string, meta ma
 
 // UpdateNodeMetaWithNote creates a new version with a change note
 func (r *Neo4jRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -598,7 +666,7 @@ func (r *Neo4jRepository) UpdateNodeMetaWithNote(ctx context.Context, id string,
 			"prev_version": currentVersion,
 			"meta":         existingMeta,
 		}, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	// Emit event on successful update
 	if err == nil {
@@ -623,9 +691,11 @@ func (r *Neo4jRepository) UpdateNodeMetaWithNote(ctx context.Context, id string,
 
 // CreateLink creates a relationship between two nodes
 func (r *Neo4jRepository) CreateLink(ctx context.Context, link *core.Link) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
+	PromoteLinkFields(link)
+
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 		// Convert meta to JSON string
 		metaJSON, err := json.Marshal(link.Meta)
@@ -639,6 +709,8 @@ func (r *Neo4jRepository) CreateLink(ctx context.Context, link *core.Link) error
 			CREATE (source)-[r:LINK {
 				type: $type,
 				properties: $properties,
+				weight: $weight,
+				bidirectional: $bidirectional,
 				created: datetime($created),
 				modified: datetime($modified)
 			}]->(target)
@@ -648,17 +720,19 @@ func (r *Neo4jRepository) CreateLink(ctx context.Context, link *core.Link) error
 		`
 
 		params := map[string]any{
-			"source_id":  link.Source,
-			"target_id":  link.Target,
-			"type":       link.Type,
-			"properties": string(metaJSON),
-			"created":    link.Created.Format("2006-01-02T15:04:05Z"),
-			"modified":   link.Modified.Format("2006-01-02T15:04:05Z"),
+			"source_id":     link.Source,
+			"target_id":     link.Target,
+			"type":          link.Type,
+			"properties":    string(metaJSON),
+			"weight":        link.Weight,
+			"bidirectional": link.Bidirectional,
+			"created":       link.Created.Format("2006-01-02T15:04:05Z"),
+			"modified":      link.Modified.Format("2006-01-02T15:04:05Z"),
 		}
 
 		_, err = tx.Run(ctx, query, params)
 		return nil, err
-	})
+	}, r.txConfig(ctx)...)
 
 	// Emit event on successful creation
 	if err == nil {
@@ -678,7 +752,7 @@ func (r *Neo4jRepository) CreateLink(ctx context.Context, link *core.Link) error
 
 // GetLinks retrieves all links for a node
 func (r *Neo4jRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -714,11 +788,64 @@ func (r *Neo4jRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.
 				Type:   relData.Props["type"].(string),
 				Meta:   meta,
 			}
+			link.Weight, link.Bidirectional = relLinkFields(relData.Props)
 			links = append(links, link)
 		}
 
 		return links, nil
-	})
+	}, r.txConfig(ctx)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*core.Link), nil
+}
+
+// GetBacklinks retrieves all links pointing at a node
+func (r *Neo4jRepository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+			MATCH (source:Node)-[r:LINK]->(target:Node {id: $node_id})
+			RETURN r, source.id as source_id
+			ORDER BY r.type, source_id
+		`
+
+		result, err := tx.Run(ctx, query, map[string]any{"node_id": nodeID})
+		if err != nil {
+			return nil, err
+		}
+
+		var links []*core.Link
+		for result.Next(ctx) {
+			record := result.Record()
+			relValue, _ := record.Get("r")
+			sourceID, _ := record.Get("source_id")
+
+			relData := relValue.(neo4j.Relationship)
+
+			var meta map[string]any
+			if propsStr, ok := relData.Props["properties"].(string); ok {
+				if err := json.Unmarshal([]byte(propsStr), &meta); err != nil {
+					return nil, fmt.Errorf("unmarshaling properties: %w", err)
+				}
+			}
+
+			link := &core.Link{
+				Source: sourceID.(string),
+				Target: nodeID,
+				Type:   relData.Props["type"].(string),
+				Meta:   meta,
+			}
+			link.Weight, link.Bidirectional = relLinkFields(relData.Props)
+			links = append(links, link)
+		}
+
+		return links, nil
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -729,7 +856,7 @@ func (r *Neo4jRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.
 
 // ListNodes returns all node IDs
 func (r *Neo4jRepository) ListNodes(ctx context.Context) ([]string, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -752,7 +879,7 @@ func (r *Neo4jRepository) ListNodes(ctx context.Context) ([]string, error) {
 		}
 
 		return ids, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -763,7 +890,7 @@ func (r *Neo4jRepository) ListNodes(ctx context.Context) ([]string, error) {
 
 // FilterNodes returns nodes matching filter criteria
 func (r *Neo4jRepository) FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -783,7 +910,7 @@ func (r *Neo4jRepository) FilterNodes(ctx context.Context, nodeTypes []string, p
 			params["searchValue"] = fmt.Sprintf(`"%s":"%s"`, propertyKey, propertyValue)
 		}
 
-		query += ` RETURN n`
+		query += neo4jReturnNode(ctx)
 
 		// Add pagination
 		if limit > 0 {
@@ -807,11 +934,12 @@ func (r *Neo4jRepository) FilterNodes(ctx context.Context, nodeTypes []string, p
 			if err != nil {
 				continue // Skip nodes that fail to parse
 			}
+			markTombstoned(node, record)
 			nodes = append(nodes, node)
 		}
 
 		return nodes, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -822,22 +950,34 @@ func (r *Neo4jRepository) FilterNodes(ctx context.Context, nodeTypes []string, p
 
 // SearchNodes performs full-text search across node properties
 func (r *Neo4jRepository) SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		match := `(n.id CONTAINS $term
+			   OR n.type CONTAINS $term
+			   OR n.properties CONTAINS $term
+			   OR n.content CONTAINS $term)`
+		params := map[string]any{"term": searchTerm}
+		if opts := searchOptionsFrom(ctx); opts != nil {
+			// Every group needs one alternative with all its words in the node's text
+			if groups := opts.groups(searchTerm); len(groups) > 0 {
+				match = `ALL(g IN $groups WHERE ANY(alt IN g WHERE ALL(w IN alt WHERE
+				toLower(n.id + ' ' + n.type + ' ' + coalesce(n.properties, '') + ' ' + coalesce(n.content, '')) CONTAINS w)))`
+				params["groups"] = neo4jGroups(groups)
+			}
+			if opts.Namespace != "" {
+				match += ` AND n.id STARTS WITH $namespace`
+				params["namespace"] = opts.Namespace + ":"
+			}
+		}
 		query := `
 			MATCH (n:Node)
 			WHERE (n.deleted IS NULL OR n.deleted = false)
-			  AND (n.id CONTAINS $term
-			   OR n.type CONTAINS $term
-			   OR n.properties CONTAINS $term
-			   OR n.content CONTAINS $term)
-			RETURN n
-		`
+			  AND ` + match + `
+		` + neo4jReturnNode(ctx)
 
 		// Add pagination
-		params := map[string]any{"term": searchTerm}
 		if limit > 0 {
 			query += ` SKIP $offset LIMIT $limit`
 			params["offset"] = offset
@@ -859,11 +999,12 @@ func (r *Neo4jRepository) SearchNodes(ctx context.Context, searchTerm string, li
 			if err != nil {
 				continue // Skip nodes that fail to parse
 			}
+			markTombstoned(node, record)
 			nodes = append(nodes, node)
 		}
 
 		return nodes, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -872,9 +1013,26 @@ func (r *Neo4jRepository) SearchNodes(ctx context.Context, searchTerm string, li
 	return result.([]*core.Node), nil
 }
 
+// neo4jGroups converts search groups to nested lists for a query parameter
+func neo4jGroups(groups [][][]string) []any {
+	out := make([]any, len(groups))
+	for i, group := range groups {
+		alternatives := make([]any, len(group))
+		for j, alt := range group {
+			words := make([]any, len(alt))
+			for k, w := range alt {
+				words[k] = w
+			}
+			alternatives[j] = words
+		}
+		out[i] = alternatives
+	}
+	return out
+}
+
 // DeleteNode marks a node as deleted (tombstone) instead of removing it
 func (r *Neo4jRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -903,6 +1061,11 @@ func (r *Neo4jRepository) DeleteNode(ctx context.Context, nodeID string, force b
 			nodeTypeStr = t
 		}
 
+		// Protected nodes cannot be deleted, even with force
+		if props, ok := currentNode.Props["properties"].(string); ok && propsHaveFlag(props, ProtectedKey) {
+			return nil, fmt.Errorf("cannot delete %s: %w", nodeID, ErrProtected)
+		}
+
 		// Protect Source layer (content-addressed nodes) unless force=true
 		if !force && len(nodeID) > 7 && nodeID[:7] == "sha256:" {
 			return nil, fmt.Errorf("cannot delete Source layer node (content-addressed): %s. Source nodes are immutable to maintain DAG integrity. Use force=true to override (not recommended)", nodeID)
@@ -1000,7 +1163,7 @@ func (r *Neo4jRepository) DeleteNode(ctx context.Context, nodeID string, force b
 			"new_version":  newVersion,
 			"prev_version": currentVersion,
 		}, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	// Emit event on successful deletion
 	if err == nil {
@@ -1017,7 +1180,7 @@ func (r *Neo4jRepository) DeleteNode(ctx context.Context, nodeID string, force b
 
 // DeleteLink deletes a specific relationship between two nodes
 func (r *Neo4jRepository) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1046,8 +1209,27 @@ func (r *Neo4jRepository) DeleteLink(ctx context.Context, sourceID string, targe
 			return nil, fmt.Errorf("link not found: %s -[%s]-> %s", sourceID, linkType, targetID)
 		}
 
+		// Record the removal for graph diffs (LinkTombstone is not a :Node)
+		tombstoneQuery := `
+			CREATE (:LinkTombstone {
+				source_id: $source_id,
+				target_id: $target_id,
+				type: $link_type,
+				deleted_at: datetime($deleted_at)
+			})
+		`
+		_, err = tx.Run(ctx, tombstoneQuery, map[string]any{
+			"source_id":  sourceID,
+			"target_id":  targetID,
+			"link_type":  linkType,
+			"deleted_at": time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("recording link removal: %w", err)
+		}
+
 		return nil, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	// Emit event on successful deletion
 	if err == nil {
@@ -1066,7 +1248,7 @@ func (r *Neo4jRepository) DeleteLink(ctx context.Context, sourceID string, targe
 
 // TraverseGraph performs graph traversal from a starting node
 func (r *Neo4jRepository) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1098,8 +1280,13 @@ func (r *Neo4jRepository) TraverseGraph(ctx context.Context, startNodeID string,
 			return nil, err
 		}
 
+		// Results stream in, so a traversal budget can stop reading them early
+		meter := TraversalMeterFrom(ctx)
 		nodes := make(map[string]*core.Node)
 		for result.Next(ctx) {
+			if !meter.Node() {
+				break
+			}
 			record := result.Record()
 			nodeValue, _ := record.Get("n")
 			nodeData := nodeValue.(neo4j.Node)
@@ -1112,7 +1299,7 @@ func (r *Neo4jRepository) TraverseGraph(ctx context.Context, startNodeID string,
 		}
 
 		return nodes, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -1121,12 +1308,73 @@ func (r *Neo4jRepository) TraverseGraph(ctx context.Context, startNodeID string,
 	return result.(map[string]*core.Node), nil
 }
 
+// QueryTimeRange returns current nodes created or modified within [from, to]
+func (r *Neo4jRepository) QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+			MATCH (n:Node)
+			WHERE (n.deleted IS NULL OR n.deleted = false)
+			  AND (n.is_current IS NULL OR n.is_current = true)
+			  AND ((n.created >= datetime($from) AND n.created <= datetime($to))
+			    OR (n.modified >= datetime($from) AND n.modified <= datetime($to)))
+		`
+		params := map[string]any{
+			"from": from.UTC().Format("2006-01-02T15:04:05Z"),
+			"to":   to.UTC().Format("2006-01-02T15:04:05Z"),
+		}
+
+		if len(nodeTypes) > 0 {
+			query += ` AND n.type IN $types`
+			params["types"] = nodeTypes
+		}
+
+		query += ` RETURN n ORDER BY n.modified DESC`
+
+		if limit > 0 {
+			query += ` SKIP $offset LIMIT $limit`
+			params["offset"] = offset
+			params["limit"] = limit
+		}
+
+		result, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var nodes []*core.Node
+		for result.Next(ctx) {
+			record := result.Record()
+			nodeValue, _ := record.Get("n")
+			nodeData := nodeValue.(neo4j.Node)
+
+			node, err := parseNodeFromNeo4j(nodeData)
+			if err != nil {
+				continue // Skip nodes that fail to parse
+			}
+			nodes = append(nodes, node)
+		}
+
+		return nodes, nil
+	}, r.txConfig(ctx)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*core.Node), nil
+}
+
 // SubgraphEdge represents an edge in the subgraph
 type SubgraphEdge struct {
-	Source string                 `json:"source"`
-	Target string                 `json:"target"`
-	Type   string                 `json:"type"`
-	Meta   map[string]interface{} `json:"meta,omitempty"`
+	Source        string                 `json:"source"`
+	Target        string                 `json:"target"`
+	Type          string                 `json:"type"`
+	Meta          map[string]interface{} `json:"meta,omitempty"`
+	Weight        float64                `json:"weight,omitempty"`
+	Bidirectional bool                   `json:"bidirectional,omitempty"`
 }
 
 // Subgraph represents nodes and edges within a graph region
@@ -1146,7 +1394,7 @@ type SubgraphStats struct {
 // GetSubgraph extracts a subgraph centered on a start node
 // Returns all nodes within depth hops and ALL edges between those nodes
 func (r *Neo4jRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1172,9 +1420,13 @@ func (r *Neo4jRepository) GetSubgraph(ctx context.Context, startNodeID string, d
 		}
 
 		// Collect all node IDs and nodes
+		meter := TraversalMeterFrom(ctx)
 		nodeMap := make(map[string]*core.Node)
 		var nodeIDs []string
 		for nodeResult.Next(ctx) {
+			if !meter.Node() {
+				break
+			}
 			record := nodeResult.Record()
 			nodeValue, _ := record.Get("n")
 			nodeData := nodeValue.(neo4j.Node)
@@ -1209,6 +1461,9 @@ func (r *Neo4jRepository) GetSubgraph(ctx context.Context, startNodeID string, d
 
 		var edges []*SubgraphEdge
 		for edgeResult.Next(ctx) {
+			if !meter.Edge() {
+				break
+			}
 			record := edgeResult.Record()
 			sourceID, _ := record.Get("source_id")
 			targetID, _ := record.Get("target_id")
@@ -1231,6 +1486,7 @@ func (r *Neo4jRepository) GetSubgraph(ctx context.Context, startNodeID string, d
 				Type:   relData.Props["type"].(string),
 				Meta:   meta,
 			}
+			edge.Weight, edge.Bidirectional = relLinkFields(relData.Props)
 			edges = append(edges, edge)
 		}
 
@@ -1251,7 +1507,94 @@ func (r *Neo4jRepository) GetSubgraph(ctx context.Context, startNodeID string, d
 		}
 
 		return subgraph, nil
-	})
+	}, r.txConfig(ctx)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*Subgraph), nil
+}
+
+// GetGraphSnapshot returns current nodes (optionally filtered by type, up to limit)
+// and all links between them
+func (r *Neo4jRepository) GetGraphSnapshot(ctx context.Context, nodeTypes []string, limit int) (*Subgraph, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		nodeQuery := `
+			MATCH (n:Node)
+			WHERE (n.deleted IS NULL OR n.deleted = false)
+			  AND (n.is_current IS NULL OR n.is_current = true)
+		`
+		params := map[string]any{"limit": limit}
+		if len(nodeTypes) > 0 {
+			nodeQuery += ` AND n.type IN $types`
+			params["types"] = nodeTypes
+		}
+		nodeQuery += ` RETURN n ORDER BY n.id LIMIT $limit`
+
+		nodeResult, err := tx.Run(ctx, nodeQuery, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var nodes []*core.Node
+		var nodeIDs []string
+		for nodeResult.Next(ctx) {
+			nodeValue, _ := nodeResult.Record().Get("n")
+			node, err := parseNodeFromNeo4j(nodeValue.(neo4j.Node))
+			if err != nil {
+				continue
+			}
+			nodes = append(nodes, node)
+			nodeIDs = append(nodeIDs, node.ID)
+		}
+
+		edgeResult, err := tx.Run(ctx, `
+			MATCH (source:Node)-[r:LINK]->(target:Node)
+			WHERE source.id IN $node_ids AND target.id IN $node_ids
+			RETURN source.id as source_id, target.id as target_id, r
+			ORDER BY source_id, target_id
+		`, map[string]any{"node_ids": nodeIDs})
+		if err != nil {
+			return nil, err
+		}
+
+		var edges []*SubgraphEdge
+		for edgeResult.Next(ctx) {
+			record := edgeResult.Record()
+			sourceID, _ := record.Get("source_id")
+			targetID, _ := record.Get("target_id")
+			relValue, _ := record.Get("r")
+			relData := relValue.(neo4j.Relationship)
+
+			var meta map[string]any
+			if propsStr, ok := relData.Props["properties"].(string); ok {
+				json.Unmarshal([]byte(propsStr), &meta)
+			}
+			linkType, _ := relData.Props["type"].(string)
+
+			edge := &SubgraphEdge{
+				Source: sourceID.(string),
+				Target: targetID.(string),
+				Type:   linkType,
+				Meta:   meta,
+			}
+			edge.Weight, edge.Bidirectional = relLinkFields(relData.Props)
+			edges = append(edges, edge)
+		}
+
+		return &Subgraph{
+			Nodes: nodes,
+			Edges: edges,
+			Stats: SubgraphStats{
+				NodeCount: len(nodes),
+				EdgeCount: len(edges),
+			},
+		}, nil
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -1263,7 +1606,7 @@ func (r *Neo4jRepository) GetSubgraph(ctx context.Context, startNodeID string, d
 // UpdateAttentionEdge creates or updates an attention-weighted edge between nodes
 // This allows the DAG to learn which nodes are frequently co-attended across queries
 func (r *Neo4jRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1288,36 +1631,20 @@ func (r *Neo4jRepository) UpdateAttentionEdge(ctx context.Context, source, targe
 			relValue, _ := record.Get("r")
 			relData := relValue.(neo4j.Relationship)
 
-			var currentMeta map[string]interface{}
+			edge := &core.Link{}
 			if propsStr, ok := relData.Props["properties"].(string); ok {
-				json.Unmarshal([]byte(propsStr), &currentMeta)
+				json.Unmarshal([]byte(propsStr), &edge.Meta)
 			}
+			edge.Weight, _ = relLinkFields(relData.Props)
 
-			// Compute running average
-			currentWeight := 0.0
-			currentCount := 0.0
-			if w, ok := currentMeta["weight"].(float64); ok {
-				currentWeight = w
-			}
-			if c, ok := currentMeta["query_count"].(float64); ok {
-				currentCount = c
-			}
-
-			newWeight := (currentWeight*currentCount + weight) / (currentCount + 1)
-			newCount := currentCount + 1
-
-			// Update with new values
-			updatedMeta := map[string]interface{}{
-				"weight":        newWeight,
-				"query_count":   newCount,
-				"last_updated":  time.Now().Format(time.RFC3339),
-				"last_query_id": queryID,
-			}
-			updatedJSON, _ := json.Marshal(updatedMeta)
+			// Fold into the running average and record the contribution
+			applyAttention(edge, queryID, AttentionHeadFrom(ctx), weight, time.Now())
+			updatedJSON, _ := json.Marshal(edge.Meta)
 
 			updateQuery := `
 				MATCH (s:Node {id: $source})-[r:LINK {type: 'ATTENDED'}]->(t:Node {id: $target})
 				SET r.properties = $properties,
+				    r.weight = $weight,
 				    r.modified = datetime($modified)
 				RETURN r
 			`
@@ -1325,18 +1652,15 @@ func (r *Neo4jRepository) UpdateAttentionEdge(ctx context.Context, source, targe
 				"source":     source,
 				"target":     target,
 				"properties": string(updatedJSON),
+				"weight":     edge.Weight,
 				"modified":   time.Now().Format(time.RFC3339),
 			})
 			return nil, err
 		} else {
 			// Create new ATTENDED edge
-			meta := map[string]interface{}{
-				"weight":       weight,
-				"query_count":  1,
-				"last_updated": time.Now().Format(time.RFC3339),
-				"last_query_id": queryID,
-			}
-			metaJSON, err := json.Marshal(meta)
+			edge := &core.Link{}
+			applyAttention(edge, queryID, AttentionHeadFrom(ctx), weight, time.Now())
+			metaJSON, err := json.Marshal(edge.Meta)
 			if err != nil {
 				return nil, err
 			}
@@ -1346,6 +1670,8 @@ func (r *Neo4jRepository) UpdateAttentionEdge(ctx context.Context, source, targe
 				CREATE (s)-[r:LINK {
 					type: 'ATTENDED',
 					properties: $properties,
+					weight: $weight,
+					bidirectional: false,
 					created: datetime($created),
 					modified: datetime($modified)
 				}]->(t)
@@ -1356,12 +1682,13 @@ func (r *Neo4jRepository) UpdateAttentionEdge(ctx context.Context, source, targe
 				"source":     source,
 				"target":     target,
 				"properties": string(metaJSON),
+				"weight":     edge.Weight,
 				"created":    now,
 				"modified":   now,
 			})
 			return nil, err
 		}
-	})
+	}, r.txConfig(ctx)...)
 
 	return err
 }
@@ -1369,7 +1696,7 @@ func (r *Neo4jRepository) UpdateAttentionEdge(ctx context.Context, source, targe
 // GetAttentionSubgraph extracts nodes connected by high-weight attention edges
 // This enables sparse, learned attention patterns to guide retrieval
 func (r *Neo4jRepository) GetAttentionSubgraph(ctx context.Context, startNodeID string, minWeight float64, maxNodes int) (*Subgraph, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1406,8 +1733,9 @@ func (r *Neo4jRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 				json.Unmarshal([]byte(propsStr), &meta)
 			}
 
-			// Filter by weight
-			if weight, ok := meta["weight"].(float64); ok && weight >= minWeight {
+			// Filter by weight, for the attention head if there is one
+			linkWeight, _ := relLinkFields(relData.Props)
+			if attentionPasses(ctx, linkWeight, meta, minWeight) {
 				nodeData := nodeValue.(neo4j.Node)
 				node, err := parseNodeFromNeo4j(nodeData)
 				if err != nil {
@@ -1447,6 +1775,9 @@ func (r *Neo4jRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 					meta = make(map[string]any)
 				}
 			}
+			if !inAttentionHead(ctx, meta) {
+				continue
+			}
 
 			edge := &SubgraphEdge{
 				Source: sourceID.(string),
@@ -1454,6 +1785,7 @@ func (r *Neo4jRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 				Type:   relData.Props["type"].(string),
 				Meta:   meta,
 			}
+			edge.Weight, edge.Bidirectional = relLinkFields(relData.Props)
 			edges = append(edges, edge)
 		}
 
@@ -1474,7 +1806,7 @@ func (r *Neo4jRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 		}
 
 		return subgraph, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -1490,6 +1822,7 @@ type GraphMap struct {
 	EdgeTypes       map[string]int        `json:"edge_types"`
 	TopConnected    []NodeSummary         `json:"top_connected"`
 	SamplesByType   map[string][]string   `json:"samples_by_type"`
+	Styles          map[string]NodeStyle  `json:"styles,omitempty"` // Visualization hints by node type
 }
 
 // GraphStats holds basic graph statistics
@@ -1507,7 +1840,7 @@ type NodeSummary struct {
 
 // GetGraphMap returns a high-level map of the graph for agent exploration
 func (r *Neo4jRepository) GetGraphMap(ctx context.Context, sampleSize int) (*GraphMap, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1594,6 +1927,26 @@ func (r *Neo4jRepository) GetGraphMap(ctx context.Context, sampleSize int) (*Gra
 			})
 		}
 
+		// Pinned nodes are always included in samples
+		pinnedQuery := `
+			MATCH (n:Node)
+			WHERE (n.deleted IS NULL OR n.deleted = false)
+			  AND n.properties CONTAINS $pinned
+			RETURN n.id as id, n.type as type
+			ORDER BY n.id
+		`
+		pinnedResult, err := tx.Run(ctx, pinnedQuery, map[string]any{"pinned": flagPattern(PinnedKey)})
+		if err != nil {
+			return nil, err
+		}
+		pinnedByType := make(map[string][]string)
+		for pinnedResult.Next(ctx) {
+			record := pinnedResult.Record()
+			id, _ := record.Get("id")
+			nodeType, _ := record.Get("type")
+			pinnedByType[nodeType.(string)] = append(pinnedByType[nodeType.(string)], id.(string))
+		}
+
 		// Get sample nodes per type
 		for nodeType := range graphMap.NodeTypes {
 			sampleQuery := `
@@ -1616,11 +1969,11 @@ func (r *Neo4jRepository) GetGraphMap(ctx context.Context, sampleSize int) (*Gra
 				id, _ := record.Get("id")
 				samples = append(samples, id.(string))
 			}
-			graphMap.SamplesByType[nodeType] = samples
+			graphMap.SamplesByType[nodeType] = withPinned(pinnedByType[nodeType], samples, sampleSize)
 		}
 
 		return graphMap, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -1629,21 +1982,352 @@ func (r *Neo4jRepository) GetGraphMap(ctx context.Context, sampleSize int) (*Gra
 	return result.(*GraphMap), nil
 }
 
+// GetTimeline returns current nodes created within [from, to] bucketed by day or week
+func (r *Neo4jRepository) GetTimeline(ctx context.Context, from, to time.Time, bucket string, nodeTypes []string, sampleSize int) (*Timeline, error) {
+	if err := ValidateBucket(bucket); err != nil {
+		return nil, err
+	}
+
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+			MATCH (n:Node)
+			WHERE (n.deleted IS NULL OR n.deleted = false)
+			  AND (n.is_current IS NULL OR n.is_current = true)
+			  AND n.created >= datetime($from) AND n.created <= datetime($to)
+		`
+		params := map[string]any{
+			"from": from.UTC().Format("2006-01-02T15:04:05Z"),
+			"to":   to.UTC().Format("2006-01-02T15:04:05Z"),
+		}
+
+		if len(nodeTypes) > 0 {
+			query += ` AND n.type IN $types`
+			params["types"] = nodeTypes
+		}
+
+		query += ` RETURN n.id as id, n.type as type, n.created as created, n.degree as degree`
+
+		result, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []timelineEntry
+		for result.Next(ctx) {
+			record := result.Record()
+			id, _ := record.Get("id")
+			nodeType, _ := record.Get("type")
+			created, _ := record.Get("created")
+			degree, _ := record.Get("degree")
+
+			e := timelineEntry{}
+			e.ID, _ = id.(string)
+			e.Type, _ = nodeType.(string)
+			if t, ok := created.(time.Time); ok {
+				e.Created = t
+			}
+			if d, ok := degree.(int64); ok {
+				e.Degree = int(d)
+			}
+			entries = append(entries, e)
+		}
+
+		return entries, nil
+	}, r.txConfig(ctx)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTimeline(result.([]timelineEntry), from, to, bucket, sampleSize), nil
+}
+
+// GetUsage accounts stored node versions per namespace and type
+func (r *Neo4jRepository) GetUsage(ctx context.Context) (*Usage, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := `
+			MATCH (n:Node)
+			RETURN n.id as id, n.type as type,
+			       coalesce(n.is_current, true) AND NOT coalesce(n.deleted, false) as live,
+			       size(coalesce(n.content, '')) as content_bytes,
+			       size(coalesce(n.properties, '')) as property_bytes
+		`
+		result, err := tx.Run(ctx, query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []usageEntry
+		for result.Next(ctx) {
+			record := result.Record()
+			id, _ := record.Get("id")
+			nodeType, _ := record.Get("type")
+			live, _ := record.Get("live")
+			contentBytes, _ := record.Get("content_bytes")
+			propertyBytes, _ := record.Get("property_bytes")
+
+			e := usageEntry{}
+			e.ID, _ = id.(string)
+			e.Type, _ = nodeType.(string)
+			e.Live, _ = live.(bool)
+			e.ContentBytes, _ = contentBytes.(int64)
+			e.PropertyBytes, _ = propertyBytes.(int64)
+			entries = append(entries, e)
+		}
+
+		return entries, result.Err()
+	}, r.txConfig(ctx)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buildUsage(result.([]usageEntry)), nil
+}
+
+// DiffGraph reports nodes and links that changed in (from, to]
+func (r *Neo4jRepository) DiffGraph(ctx context.Context, from, to time.Time, detailed bool) (*GraphDiff, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	params := map[string]any{
+		"from": from.UTC().Format("2006-01-02T15:04:05Z"),
+		"to":   to.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// Every node version written in the window (creations, updates, tombstones)
+		versionQuery := `
+			MATCH (n:Node)
+			WHERE n.modified > datetime($from) AND n.modified <= datetime($to)
+			RETURN n.id as id, n.type as type, n.version as version, n.modified as modified, n.deleted as deleted
+			ORDER BY n.modified
+		`
+		versionResult, err := tx.Run(ctx, versionQuery, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var versions []nodeVersionRow
+		for versionResult.Next(ctx) {
+			record := versionResult.Record()
+			id, _ := record.Get("id")
+			nodeType, _ := record.Get("type")
+			version, _ := record.Get("version")
+			modified, _ := record.Get("modified")
+			deleted, _ := record.Get("deleted")
+
+			v := nodeVersionRow{Version: 1}
+			v.ID, _ = id.(string)
+			v.Type, _ = nodeType.(string)
+			if ver, ok := version.(int64); ok {
+				v.Version = int(ver)
+			}
+			if t, ok := modified.(time.Time); ok {
+				v.Modified = t
+			}
+			v.Deleted, _ = deleted.(bool)
+			versions = append(versions, v)
+		}
+		if err := versionResult.Err(); err != nil {
+			return nil, err
+		}
+
+		linkQuery := `
+			MATCH (s:Node)-[r:LINK]->(t:Node)
+			WHERE r.created > datetime($from) AND r.created <= datetime($to)
+			RETURN DISTINCT s.id as source_id, t.id as target_id, r.type as type, r.properties as props,
+			       r.weight as weight, r.bidirectional as bidirectional
+		`
+		linkResult, err := tx.Run(ctx, linkQuery, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var linksAdded []linkChangeRow
+		for linkResult.Next(ctx) {
+			linksAdded = append(linksAdded, linkChangeFromRecord(linkResult.Record()))
+		}
+		if err := linkResult.Err(); err != nil {
+			return nil, err
+		}
+
+		removedQuery := `
+			MATCH (lt:LinkTombstone)
+			WHERE lt.deleted_at > datetime($from) AND lt.deleted_at <= datetime($to)
+			RETURN lt.source_id as source_id, lt.target_id as target_id, lt.type as type, null as props
+		`
+		removedResult, err := tx.Run(ctx, removedQuery, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var linksRemoved []linkChangeRow
+		for removedResult.Next(ctx) {
+			linksRemoved = append(linksRemoved, linkChangeFromRecord(removedResult.Record()))
+		}
+		if err := removedResult.Err(); err != nil {
+			return nil, err
+		}
+
+		return buildGraphDiff(from, to, versions, linksAdded, linksRemoved, detailed), nil
+	}, r.txConfig(ctx)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*GraphDiff), nil
+}
+// ChangeLog returns the changes made after since as events, oldest first
+func (r *Neo4jRepository) ChangeLog(ctx context.Context, since time.Time, limit int) ([]subscriptions.Event, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	limit = changeLogLimit(limit)
+	params := map[string]any{
+		"since": since.UTC().Format("2006-01-02T15:04:05Z"),
+		"limit": limit,
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		versionQuery := `
+			MATCH (n:Node)
+			WHERE n.modified > datetime($since)
+			RETURN n.id as id, n.type as type, n.version as version, n.modified as modified,
+			       n.deleted as deleted, n.change_note as change_note, n.properties as props
+			ORDER BY n.modified, n.version
+			LIMIT $limit
+		`
+		versionResult, err := tx.Run(ctx, versionQuery, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var events []subscriptions.Event
+		for versionResult.Next(ctx) {
+			record := versionResult.Record()
+			id, _ := record.Get("id")
+			nodeType, _ := record.Get("type")
+			version, _ := record.Get("version")
+			modified, _ := record.Get("modified")
+			deleted, _ := record.Get("deleted")
+			changeNote, _ := record.Get("change_note")
+			props, _ := record.Get("props")
+
+			idStr, _ := id.(string)
+			typeStr, _ := nodeType.(string)
+			ver := 1
+			if v, ok := version.(int64); ok {
+				ver = int(v)
+			}
+			modifiedAt, _ := modified.(time.Time)
+			isDeleted, _ := deleted.(bool)
+			note, _ := changeNote.(string)
+			var meta map[string]interface{}
+			if propsStr, ok := props.(string); ok {
+				json.Unmarshal([]byte(propsStr), &meta)
+			}
+			events = append(events, nodeVersionEvent(idStr, typeStr, ver, modifiedAt, isDeleted, note, meta))
+		}
+
+		linkQuery := `
+			MATCH (s:Node)-[r:LINK]->(t:Node)
+			WHERE r.created > datetime($since)
+			RETURN DISTINCT s.id as source_id, t.id as target_id, r.type as type, r.properties as props,
+			       r.weight as weight, r.bidirectional as bidirectional, r.created as at
+			ORDER BY at
+			LIMIT $limit
+		`
+		linkResult, err := tx.Run(ctx, linkQuery, params)
+		if err != nil {
+			return nil, err
+		}
+		for linkResult.Next(ctx) {
+			record := linkResult.Record()
+			change := linkChangeFromRecord(record)
+			at, _ := record.Get("at")
+			created, _ := at.(time.Time)
+			events = append(events, linkEvent(subscriptions.EventLinkCreated, change.Edge, created))
+		}
+
+		removedQuery := `
+			MATCH (lt:LinkTombstone)
+			WHERE lt.deleted_at > datetime($since)
+			RETURN lt.source_id as source_id, lt.target_id as target_id, lt.type as type, null as props, lt.deleted_at as at
+			ORDER BY at
+			LIMIT $limit
+		`
+		removedResult, err := tx.Run(ctx, removedQuery, params)
+		if err != nil {
+			return nil, err
+		}
+		for removedResult.Next(ctx) {
+			record := removedResult.Record()
+			change := linkChangeFromRecord(record)
+			at, _ := record.Get("at")
+			deletedAt, _ := at.(time.Time)
+			events = append(events, linkEvent(subscriptions.EventLinkDeleted, change.Edge, deletedAt))
+		}
+
+		return sortChangeLog(events, limit), nil
+	}, r.txConfig(ctx)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]subscriptions.Event), nil
+}
+
+
+// linkChangeFromRecord converts a (source_id, target_id, type, props) record to a link change
+func linkChangeFromRecord(record *neo4j.Record) linkChangeRow {
+	sourceID, _ := record.Get("source_id")
+	targetID, _ := record.Get("target_id")
+	linkType, _ := record.Get("type")
+	props, _ := record.Get("props")
+
+	edge := &SubgraphEdge{}
+	edge.Source, _ = sourceID.(string)
+	edge.Target, _ = targetID.(string)
+	edge.Type, _ = linkType.(string)
+	if propsStr, ok := props.(string); ok {
+		json.Unmarshal([]byte(propsStr), &edge.Meta)
+	}
+	weight, _ := record.Get("weight")
+	bidirectional, _ := record.Get("bidirectional")
+	edge.Weight, edge.Bidirectional = relLinkFields(map[string]any{
+		linkWeightKey:        weight,
+		linkBidirectionalKey: bidirectional,
+	})
+
+	return linkChangeRow{Edge: edge}
+}
+
 // PruneWeakAttentionEdges removes attention edges with low weight or query count
 // This maintains DAG quality by removing noise
 func (r *Neo4jRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight float64, minQueryCount int) (int, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		// Get all ATTENDED edges
+		// Get all ATTENDED edges, skipping those touching protected nodes
 		query := `
-			MATCH ()-[r:LINK]->()
+			MATCH (a)-[r:LINK]->(b)
 			WHERE r.type = 'ATTENDED'
-			RETURN id(r) as rel_id, r.properties as props
+			  AND NOT coalesce(a.properties, '') CONTAINS $protected
+			  AND NOT coalesce(b.properties, '') CONTAINS $protected
+			RETURN id(r) as rel_id, r.properties as props, r.weight as weight
 		`
 
-		result, err := tx.Run(ctx, query, nil)
+		result, err := tx.Run(ctx, query, map[string]any{"protected": flagPattern(ProtectedKey)})
 		if err != nil {
 			return 0, err
 		}
@@ -1660,7 +2344,8 @@ func (r *Neo4jRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight
 				json.Unmarshal([]byte(propsStr), &meta)
 
 				shouldDelete := false
-				if weight, ok := meta["weight"].(float64); ok && weight < minWeight {
+				weightValue, _ := record.Get("weight")
+				if weight, _ := numericValue(weightValue); weight < minWeight {
 					shouldDelete = true
 				}
 				if count, ok := meta["query_count"].(float64); ok && int(count) < minQueryCount {
@@ -1687,7 +2372,7 @@ func (r *Neo4jRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight
 		}
 
 		return len(edgesToDelete), nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return 0, err
@@ -1698,7 +2383,7 @@ func (r *Neo4jRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight
 
 // GetEntitiesInterpretedThrough returns all entities linked to a lens via INTERPRETED_THROUGH edges
 func (r *Neo4jRepository) GetEntitiesInterpretedThrough(ctx context.Context, lensID string) ([]*core.Node, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1742,7 +2427,7 @@ func (r *Neo4jRepository) GetEntitiesInterpretedThrough(ctx context.Context, len
 		}
 
 		return nodes, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -1766,7 +2451,7 @@ func (r *Neo4jRepository) CreateInterpretedThroughLink(ctx context.Context, enti
 
 // QueryByLens returns entities interpreted through a lens, optionally filtered by pattern
 func (r *Neo4jRepository) QueryByLens(ctx context.Context, lensID string, pattern string, limit int, offset int) ([]*core.Node, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1824,7 +2509,7 @@ func (r *Neo4jRepository) QueryByLens(ctx context.Context, lensID string, patter
 		}
 
 		return nodes, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -1846,7 +2531,7 @@ type LensExport struct {
 
 // ExportLens returns a complete export of a lens and its interpreted entities
 func (r *Neo4jRepository) ExportLens(ctx context.Context, lensID string, includeExtractedFrom bool) (*LensExport, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1908,12 +2593,14 @@ func (r *Neo4jRepository) ExportLens(ctx context.Context, lensID string, include
 				json.Unmarshal([]byte(propsStr), &meta)
 			}
 
-			export.Links = append(export.Links, &SubgraphEdge{
+			edge := &SubgraphEdge{
 				Source: node.ID,
 				Target: lensID,
 				Type:   "INTERPRETED_THROUGH",
 				Meta:   meta,
-			})
+			}
+			edge.Weight, edge.Bidirectional = relLinkFields(relData.Props)
+			export.Links = append(export.Links, edge)
 		}
 
 		// Optionally get EXTRACTED_FROM links
@@ -1940,12 +2627,14 @@ func (r *Neo4jRepository) ExportLens(ctx context.Context, lensID string, include
 					json.Unmarshal([]byte(propsStr), &meta)
 				}
 
-				export.Links = append(export.Links, &SubgraphEdge{
+				edge := &SubgraphEdge{
 					Source: entityID.(string),
 					Target: sourceID.(string),
 					Type:   "EXTRACTED_FROM",
 					Meta:   meta,
-				})
+				}
+				edge.Weight, edge.Bidirectional = relLinkFields(relData.Props)
+				export.Links = append(export.Links, edge)
 			}
 		}
 
@@ -1953,7 +2642,7 @@ func (r *Neo4jRepository) ExportLens(ctx context.Context, lensID string, include
 		export.Stats.LinkCount = len(export.Links)
 
 		return export, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -1966,7 +2655,7 @@ func (r *Neo4jRepository) ExportLens(ctx context.Context, lensID string, include
 
 // CreateSubscriptionNode persists a subscription as a node in the graph
 func (r *Neo4jRepository) CreateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -1984,6 +2673,7 @@ func (r *Neo4jRepository) CreateSubscriptionNode(ctx context.Context, sub *subsc
 			"enabled":     sub.Enabled,
 			"fire_count":  sub.FireCount,
 		}
+		subscriptions.ChannelsToMeta(sub, meta)
 		metaJSON, err := json.Marshal(meta)
 		if err != nil {
 			return nil, fmt.Errorf("marshaling meta: %w", err)
@@ -2011,14 +2701,14 @@ func (r *Neo4jRepository) CreateSubscriptionNode(ctx context.Context, sub *subsc
 
 		_, err = tx.Run(ctx, query, params)
 		return nil, err
-	})
+	}, r.txConfig(ctx)...)
 
 	return err
 }
 
 // UpdateSubscriptionNode updates a subscription node in the graph
 func (r *Neo4jRepository) UpdateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -2036,6 +2726,7 @@ func (r *Neo4jRepository) UpdateSubscriptionNode(ctx context.Context, sub *subsc
 			"enabled":     sub.Enabled,
 			"fire_count":  sub.FireCount,
 		}
+		subscriptions.ChannelsToMeta(sub, meta)
 		if sub.LastFired != nil {
 			meta["last_fired"] = sub.LastFired.Format(time.RFC3339)
 		}
@@ -2057,14 +2748,14 @@ func (r *Neo4jRepository) UpdateSubscriptionNode(ctx context.Context, sub *subsc
 
 		_, err = tx.Run(ctx, query, params)
 		return nil, err
-	})
+	}, r.txConfig(ctx)...)
 
 	return err
 }
 
 // DeleteSubscriptionNode removes a subscription node from the graph
 func (r *Neo4jRepository) DeleteSubscriptionNode(ctx context.Context, id string) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -2074,14 +2765,14 @@ func (r *Neo4jRepository) DeleteSubscriptionNode(ctx context.Context, id string)
 		`
 		_, err := tx.Run(ctx, query, map[string]any{"id": "subscription:" + id})
 		return nil, err
-	})
+	}, r.txConfig(ctx)...)
 
 	return err
 }
 
 // LoadSubscriptions loads all subscription nodes from the graph
 func (r *Neo4jRepository) LoadSubscriptions(ctx context.Context) ([]*subscriptions.Subscription, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -2144,6 +2835,7 @@ func (r *Neo4jRepository) LoadSubscriptions(ctx context.Context) ([]*subscriptio
 			if v, ok := meta["websocket"].(bool); ok {
 				sub.WebSocket = v
 			}
+			subscriptions.ChannelsFromMeta(sub, meta)
 			if v, ok := meta["enabled"].(bool); ok {
 				sub.Enabled = v
 			}
@@ -2166,7 +2858,7 @@ func (r *Neo4jRepository) LoadSubscriptions(ctx context.Context) ([]*subscriptio
 		}
 
 		return subs, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -2177,7 +2869,7 @@ func (r *Neo4jRepository) LoadSubscriptions(ctx context.Context) ([]*subscriptio
 
 // ExecuteCypherRead executes a read-only Cypher query and returns results
 func (r *Neo4jRepository) ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
+	session := r.session(ctx)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
@@ -2198,7 +2890,7 @@ func (r *Neo4jRepository) ExecuteCypherRead(ctx context.Context, cypher string,
 		}
 
 		return results, nil
-	})
+	}, r.txConfig(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// defaultBatchSize is the number of rows written per bulk transaction
+const defaultBatchSize = 1000
+
+// CreateNodes creates many nodes using UNWIND, committing every batchSize rows.
+// Nodes in batches committed before an error remain in the graph.
+func (r *Neo4jRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	query := `
+		UNWIND $nodes AS row
+		CREATE (n:Node {
+			id: row.id,
+			type: row.type,
+			content: row.content,
+			properties: row.properties,
+			created: datetime(row.created),
+			modified: datetime(row.modified),
+			deleted: false,
+			degree: 0,
+			version_id: row.version_id,
+			version: 1,
+			is_current: true
+		})
+	`
+
+	for start := 0; start < len(nodes); start += r.batchSize {
+		batch := nodes[start:min(start+r.batchSize, len(nodes))]
+
+		rows := make([]any, len(batch))
+		for i, node := range batch {
+			node.Version = 1
+			node.VersionID = node.ID + ":v1"
+			node.IsCurrent = true
+
+			metaJSON, err := json.Marshal(node.Meta)
+			if err != nil {
+				return fmt.Errorf("marshaling meta for %s: %w", node.ID, err)
+			}
+			rows[i] = map[string]any{
+				"id":         node.ID,
+				"type":       node.Type,
+				"content":    string(node.Content),
+				"properties": string(metaJSON),
+				"created":    node.Created.Format("2006-01-02T15:04:05Z"),
+				"modified":   node.Modified.Format("2006-01-02T15:04:05Z"),
+				"version_id": node.VersionID,
+			}
+		}
+
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			_, err := tx.Run(ctx, query, map[string]any{"nodes": rows})
+			return nil, err
+		}, r.txConfig(ctx)...)
+		if err != nil {
+			return fmt.Errorf("creating nodes %d-%d: %w", start, start+len(batch)-1, err)
+		}
+
+		for _, node := range batch {
+			r.emit(subscriptions.Event{
+				ID:        uuid.New().String(),
+				Type:      subscriptions.EventNodeCreated,
+				Timestamp: time.Now(),
+				NodeID:    node.ID,
+				NodeType:  node.Type,
+				Meta:      node.Meta,
+			})
+		}
+	}
+
+	return nil
+}
+
+// CreateLinks creates many links using UNWIND, committing every batchSize rows.
+// Like CreateLink, links whose endpoints do not exist are skipped.
+func (r *Neo4jRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	query := `
+		UNWIND $links AS row
+		MATCH (source:Node {id: row.source_id})
+		MATCH (target:Node {id: row.target_id})
+		CREATE (source)-[r:LINK {
+			type: row.type,
+			properties: row.properties,
+			weight: row.weight,
+			bidirectional: row.bidirectional,
+			created: datetime(row.created),
+			modified: datetime(row.modified)
+		}]->(target)
+		SET source.degree = COALESCE(source.degree, 0) + 1,
+		    target.degree = COALESCE(target.degree, 0) + 1
+	`
+
+	for start := 0; start < len(links); start += r.batchSize {
+		batch := links[start:min(start+r.batchSize, len(links))]
+
+		rows := make([]any, len(batch))
+		for i, link := range batch {
+			PromoteLinkFields(link)
+			metaJSON, err := json.Marshal(link.Meta)
+			if err != nil {
+				return fmt.Errorf("marshaling meta for link %s->%s: %w", link.Source, link.Target, err)
+			}
+			rows[i] = map[string]any{
+				"source_id":     link.Source,
+				"target_id":     link.Target,
+				"type":          link.Type,
+				"properties":    string(metaJSON),
+				"weight":        link.Weight,
+				"bidirectional": link.Bidirectional,
+				"created":       link.Created.Format("2006-01-02T15:04:05Z"),
+				"modified":      link.Modified.Format("2006-01-02T15:04:05Z"),
+			}
+		}
+
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			_, err := tx.Run(ctx, query, map[string]any{"links": rows})
+			return nil, err
+		}, r.txConfig(ctx)...)
+		if err != nil {
+			return fmt.Errorf("creating links %d-%d: %w", start, start+len(batch)-1, err)
+		}
+
+		for _, link := range batch {
+			r.emit(subscriptions.Event{
+				ID:         uuid.New().String(),
+				Type:       subscriptions.EventLinkCreated,
+				Timestamp:  time.Now(),
+				LinkSource: link.Source,
+				LinkTarget: link.Target,
+				LinkType:   link.Type,
+				Meta:       link.Meta,
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// neo4jReturnNode ends a query that matched node versions as n. With
+// IncludesDeleted(ctx) it also returns the deletion time of the node whose
+// last live version n is, for markTombstoned.
+func neo4jReturnNode(ctx context.Context) string {
+	if !IncludesDeleted(ctx) {
+		return ` RETURN n`
+	}
+	return `
+		OPTIONAL MATCH (t:Node {is_current: true, deleted: true})-[:PREVIOUS_VERSION]->(n)
+		RETURN n, t.deleted_at as deleted_at`
+}
+
+// markTombstoned marks node deleted when record carries a deletion time
+func markTombstoned(node *core.Node, record *neo4j.Record) {
+	if v, ok := record.Get("deleted_at"); ok {
+		if t, ok := v.(time.Time); ok {
+			markDeleted(node, t)
+		}
+	}
+}
+
+// getNodeIncludingDeleted returns the current version of a node, or the
+// last live version if it has been deleted
+func (r *Neo4jRepository) getNodeIncludingDeleted(ctx context.Context, id string) (*core.Node, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(ctx, `
+			MATCH (n:Node {id: $id})
+			WHERE coalesce(n.deleted, false) = false
+			OPTIONAL MATCH (t:Node {is_current: true, deleted: true})-[:PREVIOUS_VERSION]->(n)
+			WITH n, t
+			WHERE n.is_current IS NULL OR n.is_current = true OR t IS NOT NULL
+			RETURN n, t.deleted_at as deleted_at
+			LIMIT 1
+		`, map[string]any{"id": id})
+		if err != nil {
+			return nil, err
+		}
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("node not found: %s", id)
+		}
+
+		record := res.Record()
+		nodeValue, _ := record.Get("n")
+		node, err := parseNodeFromNeo4j(nodeValue.(neo4j.Node))
+		if err != nil {
+			return nil, err
+		}
+		markTombstoned(node, record)
+		return node, nil
+	}, r.txConfig(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*core.Node), nil
+}
+
+// ListDeleted returns deleted nodes, most recently deleted first, each as
+// its last live version marked with its deletion time
+func (r *Neo4jRepository) ListDeleted(ctx context.Context, limit int, offset int) ([]*core.Node, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(ctx, `
+			MATCH (t:Node {is_current: true, deleted: true})-[:PREVIOUS_VERSION]->(n:Node)
+			RETURN n, t.deleted_at as deleted_at
+			ORDER BY t.deleted_at DESC, n.id
+			SKIP $offset LIMIT $limit
+		`, map[string]any{"offset": offset, "limit": limit})
+		if err != nil {
+			return nil, err
+		}
+
+		var nodes []*core.Node
+		for res.Next(ctx) {
+			record := res.Record()
+			nodeValue, _ := record.Get("n")
+			node, err := parseNodeFromNeo4j(nodeValue.(neo4j.Node))
+			if err != nil {
+				continue // Skip nodes that fail to parse
+			}
+			markTombstoned(node, record)
+			nodes = append(nodes, node)
+		}
+		return nodes, res.Err()
+	}, r.txConfig(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	return result.([]*core.Node), nil
+}
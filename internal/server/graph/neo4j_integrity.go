@@ -0,0 +1,173 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// CheckIntegrity reports orphan nodes and links whose endpoints are
+// tombstoned or no longer current. Relationships cannot outlive their nodes
+// and versions are chained by PREVIOUS_VERSION relationships, so Neo4j has
+// no missing endpoints or broken version chains to find.
+func (r *Neo4jRepository) CheckIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	type scan struct {
+		order []string
+		nodes map[string]integrityNodeState
+		links []*core.Link
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		s := scan{nodes: make(map[string]integrityNodeState)}
+
+		// Links stay on the version they were created on, so endpoints are
+		// judged by the current version of their logical node
+		nodeResult, err := tx.Run(ctx, `
+			MATCH (n:Node)
+			WHERE n.is_current IS NULL OR n.is_current = true
+			RETURN n.id as id, n.type as type, coalesce(n.deleted, false) as deleted
+			ORDER BY id
+		`, nil)
+		if err != nil {
+			return nil, err
+		}
+		for nodeResult.Next(ctx) {
+			record := nodeResult.Record()
+			id, _ := record.Get("id")
+			nodeType, _ := record.Get("type")
+			deleted, _ := record.Get("deleted")
+
+			idStr, _ := id.(string)
+			state := integrityNodeState{}
+			state.Type, _ = nodeType.(string)
+			state.Deleted, _ = deleted.(bool)
+			s.order = append(s.order, idStr)
+			s.nodes[idStr] = state
+		}
+		if err := nodeResult.Err(); err != nil {
+			return nil, err
+		}
+
+		linkResult, err := tx.Run(ctx, `
+			MATCH (source:Node)-[r:LINK]->(target:Node)
+			RETURN DISTINCT source.id as source_id, target.id as target_id, r.type as type
+			ORDER BY source_id, type, target_id
+		`, nil)
+		if err != nil {
+			return nil, err
+		}
+		for linkResult.Next(ctx) {
+			record := linkResult.Record()
+			sourceID, _ := record.Get("source_id")
+			targetID, _ := record.Get("target_id")
+			linkType, _ := record.Get("type")
+
+			link := &core.Link{}
+			link.Source, _ = sourceID.(string)
+			link.Target, _ = targetID.(string)
+			link.Type, _ = linkType.(string)
+			s.links = append(s.links, link)
+		}
+		return s, linkResult.Err()
+	}, r.txConfig(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := result.(scan)
+	report := newIntegrityReport()
+	checkLinkIntegrity(report, s.order, s.nodes, s.links, opts)
+	return report, nil
+}
+
+// RecomputeDegrees recounts the links of current nodes, one read and one
+// write transaction per batch. Links stay on the version they were created
+// on, so a node's links are counted across all its versions.
+func (r *Neo4jRepository) RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error) {
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	report := newDegreeRepair(opts)
+	after := ""
+	for {
+		result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			res, err := tx.Run(ctx, `
+				MATCH (n:Node)
+				WHERE (n.is_current IS NULL OR n.is_current = true)
+				  AND coalesce(n.deleted, false) = false
+				  AND n.id > $after
+				WITH n ORDER BY n.id LIMIT $batch
+				OPTIONAL MATCH (s:Node)-[l:LINK]->(t:Node)
+				WHERE (s.id = n.id OR t.id = n.id) AND l.type <> 'ATTENDED'
+				WITH n, collect(DISTINCT [s.id, t.id, l.type]) AS links
+				RETURN n.id as id, n.type as type, coalesce(n.degree, 0) as stored,
+				       reduce(d = 0, k IN links | d + CASE WHEN k[0] = n.id THEN 1 ELSE 0 END
+				                                    + CASE WHEN k[1] = n.id THEN 1 ELSE 0 END) as actual
+				ORDER BY id
+			`, map[string]any{"after": after, "batch": opts.batchSize()})
+			if err != nil {
+				return nil, err
+			}
+			var page []DegreeDiscrepancy
+			for res.Next(ctx) {
+				record := res.Record()
+				id, _ := record.Get("id")
+				nodeType, _ := record.Get("type")
+				stored, _ := record.Get("stored")
+				actual, _ := record.Get("actual")
+
+				d := DegreeDiscrepancy{}
+				d.ID, _ = id.(string)
+				d.Type, _ = nodeType.(string)
+				if n, ok := stored.(int64); ok {
+					d.Stored = int(n)
+				}
+				if n, ok := actual.(int64); ok {
+					d.Actual = int(n)
+				}
+				page = append(page, d)
+			}
+			return page, res.Err()
+		}, r.txConfig(ctx)...)
+		if err != nil {
+			return nil, fmt.Errorf("counting degrees: %w", err)
+		}
+		page := result.([]DegreeDiscrepancy)
+		if len(page) == 0 {
+			break
+		}
+		report.Batches++
+		after = page[len(page)-1].ID
+
+		var fixes []map[string]any
+		for _, d := range page {
+			if report.check(d, opts) && !opts.DryRun {
+				fixes = append(fixes, map[string]any{"id": d.ID, "degree": d.Actual})
+			}
+		}
+		if len(fixes) > 0 {
+			_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+				_, err := tx.Run(ctx, `
+					UNWIND $fixes AS f
+					MATCH (n:Node {id: f.id})
+					WHERE n.is_current IS NULL OR n.is_current = true
+					SET n.degree = f.degree
+				`, map[string]any{"fixes": fixes})
+				return nil, err
+			}, r.txConfig(ctx)...)
+			if err != nil {
+				return nil, fmt.Errorf("updating degrees: %w", err)
+			}
+			report.Fixed += len(fixes)
+		}
+		if len(page) < opts.batchSize() {
+			break
+		}
+	}
+	return report, nil
+}
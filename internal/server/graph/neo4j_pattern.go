@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// MatchPattern finds bindings of a structural pattern with a Cypher MATCH.
+// Links stay on the version nodes they were created on, so each link is
+// matched between any versions of its endpoints. Property constraints are prefiltered with CONTAINS on the JSON properties
+// and checked exactly on each record.
+func (r *Neo4jRepository) MatchPattern(ctx context.Context, p *Pattern, limit int) ([]PatternBinding, error) {
+	var paths, where, cols, orderBy []string
+	params := make(map[string]any)
+	for i, n := range p.Nodes {
+		alias := fmt.Sprintf("n%d", i)
+		paths = append(paths, "("+alias+":Node)")
+		cols = append(cols, fmt.Sprintf("%s.id AS id%d, %s.properties AS props%d", alias, i, alias, i))
+		orderBy = append(orderBy, fmt.Sprintf("id%d", i))
+		where = append(where, "("+alias+".is_current IS NULL OR "+alias+".is_current = true)")
+		where = append(where, "("+alias+".deleted IS NULL OR "+alias+".deleted = false)")
+		if n.Type != "" {
+			where = append(where, fmt.Sprintf("%s.type = $type%d", alias, i))
+			params[fmt.Sprintf("type%d", i)] = n.Type
+		}
+		for j, fragment := range p.propFragments(i) {
+			key := fmt.Sprintf("prop%d_%d", i, j)
+			where = append(where, fmt.Sprintf("%s.properties CONTAINS $%s", alias, key))
+			params[key] = fragment
+		}
+	}
+	for i, e := range p.Edges {
+		paths = append(paths, fmt.Sprintf("(s%d:Node)-[l%d:LINK]->(t%d:Node)", i, i, i))
+		where = append(where, fmt.Sprintf("s%d.id = n%d.id AND t%d.id = n%d.id", i, e.From, i, e.To))
+		if e.Type != "" {
+			where = append(where, fmt.Sprintf("l%d.type = $ltype%d", i, i))
+			params[fmt.Sprintf("ltype%d", i)] = e.Type
+		}
+	}
+	query := "MATCH " + strings.Join(paths, ", ") +
+		" WHERE " + strings.Join(where, " AND ") +
+		" RETURN DISTINCT " + strings.Join(cols, ", ") +
+		" ORDER BY " + strings.Join(orderBy, ", ")
+
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+
+		matches := []PatternBinding{}
+		ids := make([]string, len(p.Nodes))
+		for len(matches) < limit && result.Next(ctx) {
+			record := result.Record()
+			ok := true
+			for i := range p.Nodes {
+				id, _ := record.Get(fmt.Sprintf("id%d", i))
+				props, _ := record.Get(fmt.Sprintf("props%d", i))
+				ids[i], _ = id.(string)
+				propsStr, _ := props.(string)
+				if !p.propsJSONMatch(i, propsStr) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				matches = append(matches, p.binding(ids))
+			}
+		}
+		return matches, result.Err()
+	}, r.txConfig(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	return result.([]PatternBinding), nil
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// SearchSimilar scores the nodes whose properties contain the most of the
+// term's trigrams. Properties are stored as one JSON string, so names are
+// compared in Go rather than with apoc.text, which also need not be installed.
+func (r *Neo4jRepository) SearchSimilar(ctx context.Context, term string, limit int) ([]*SimilarNode, error) {
+	grams := termTrigrams(term)
+	if len(grams) == 0 {
+		return []*SimilarNode{}, nil
+	}
+	if len(grams) > maxTermTrigrams {
+		grams = grams[:maxTermTrigrams]
+	}
+
+	session := r.session(ctx)
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		params := map[string]any{"trigrams": grams, "limit": maxSimilarCandidates}
+		inNamespace := ""
+		if opts := searchOptionsFrom(ctx); opts != nil && opts.Namespace != "" {
+			inNamespace = ` AND n.id STARTS WITH $namespace`
+			params["namespace"] = opts.Namespace + ":"
+		}
+		query := `
+			MATCH (n:Node)
+			WHERE (n.deleted IS NULL OR n.deleted = false)` + inNamespace + `
+			WITH n, size([t IN $trigrams WHERE toLower(coalesce(n.properties, '')) CONTAINS t]) AS shared
+			WHERE shared > 0
+			WITH n ORDER BY shared DESC LIMIT $limit
+		` + neo4jReturnNode(ctx)
+
+		result, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var nodes []*core.Node
+		for result.Next(ctx) {
+			record := result.Record()
+			nodeValue, _ := record.Get("n")
+			node, err := parseNodeFromNeo4j(nodeValue.(neo4j.Node))
+			if err != nil {
+				continue
+			}
+			markTombstoned(node, record)
+			nodes = append(nodes, node)
+		}
+		return nodes, result.Err()
+	}, r.txConfig(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankSimilar(term, result.([]*core.Node), limit), nil
+}
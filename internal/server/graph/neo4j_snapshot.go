@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// neo4jSnapshot is a set of bookmarks. Neo4j cannot hold a view open, but
+// sessions started from the bookmarks never read a state older than the
+// one the snapshot began at, even on a cluster member that lags behind.
+type neo4jSnapshot struct {
+	bookmarks neo4j.Bookmarks
+}
+
+func (s *neo4jSnapshot) Consistency() string { return SnapshotCausal }
+
+func (s *neo4jSnapshot) Close() error { return nil }
+
+// BeginSnapshot records the bookmarks of the latest committed transaction
+func (r *Neo4jRepository) BeginSnapshot(ctx context.Context) (Snapshot, error) {
+	session := r.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j", AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, "RETURN 1", nil)
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume(ctx)
+	}, r.txConfig(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("beginning snapshot: %w", err)
+	}
+	return &neo4jSnapshot{bookmarks: session.LastBookmarks()}, nil
+}
+
+// session opens a session on the memex database, starting from the
+// bookmarks of the snapshot ctx is pinned to, if any
+func (r *Neo4jRepository) session(ctx context.Context) neo4j.SessionWithContext {
+	cfg := neo4j.SessionConfig{DatabaseName: "neo4j"}
+	if s, ok := SnapshotFrom(ctx).(*neo4jSnapshot); ok {
+		cfg.Bookmarks = s.bookmarks
+	}
+	return r.driver.NewSession(ctx, cfg)
+}
@@ -0,0 +1,24 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// txConfig returns the transaction options for a call: a server-side
+// timeout matching the context's deadline, so Neo4j stops a query the
+// caller has given up on, or the repository's default timeout
+func (r *Neo4jRepository) txConfig(ctx context.Context) []func(*neo4j.TransactionConfig) {
+	timeout := r.txTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		// Neo4j treats a zero timeout as none, so an expired deadline
+		// still gets the shortest one
+		timeout = max(time.Until(deadline), time.Millisecond)
+	}
+	if timeout <= 0 {
+		return nil
+	}
+	return []func(*neo4j.TransactionConfig){neo4j.WithTxTimeout(timeout)}
+}
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+func TestNeo4jTxConfig(t *testing.T) {
+	timeout := func(r *Neo4jRepository, ctx context.Context) time.Duration {
+		var cfg neo4j.TransactionConfig
+		for _, configure := range r.txConfig(ctx) {
+			configure(&cfg)
+		}
+		return cfg.Timeout
+	}
+
+	r := &Neo4jRepository{}
+	if got := timeout(r, context.Background()); got != 0 {
+		t.Errorf("no deadline, no default: timeout = %v", got)
+	}
+	r.txTimeout = time.Minute
+	if got := timeout(r, context.Background()); got != time.Minute {
+		t.Errorf("default timeout = %v", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if got := timeout(r, ctx); got <= 4*time.Second || got > 5*time.Second {
+		t.Errorf("deadline timeout = %v", got)
+	}
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if got := timeout(r, expired); got != time.Millisecond {
+		t.Errorf("expired deadline timeout = %v", got)
+	}
+}
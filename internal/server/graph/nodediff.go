@@ -0,0 +1,295 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// diffContextLines is the number of unchanged lines kept around each hunk
+const diffContextLines = 3
+
+// NodeDiff describes what changed in a node between two versions
+type NodeDiff struct {
+	ID             string       `json:"id"`
+	FromVersion    int          `json:"from_version"`
+	ToVersion      int          `json:"to_version"`
+	TypeChanged    bool         `json:"type_changed,omitempty"`
+	FromType       string       `json:"from_type,omitempty"`
+	ToType         string       `json:"to_type,omitempty"`
+	ContentChanged bool         `json:"content_changed"`
+	ContentDiff    string       `json:"content_diff,omitempty"` // Unified diff
+	Meta           []MetaChange `json:"meta"`
+}
+
+// MetaChange describes a change to a single meta key
+type MetaChange struct {
+	Key    string      `json:"key"`
+	Op     string      `json:"op"` // added, removed, changed
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// DiffNodes compares two versions of the same node
+func DiffNodes(from, to *core.Node) *NodeDiff {
+	diff := &NodeDiff{
+		ID:          to.ID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Meta:        DiffMeta(from.Meta, to.Meta),
+	}
+
+	if from.Type != to.Type {
+		diff.TypeChanged = true
+		diff.FromType = from.Type
+		diff.ToType = to.Type
+	}
+
+	if string(from.Content) != string(to.Content) {
+		diff.ContentChanged = true
+		diff.ContentDiff = UnifiedDiff(
+			fmt.Sprintf("%s@v%d", from.ID, from.Version),
+			fmt.Sprintf("%s@v%d", to.ID, to.Version),
+			string(from.Content),
+			string(to.Content),
+		)
+	}
+
+	return diff
+}
+
+// DiffMeta returns key-level changes between two meta maps, sorted by key
+func DiffMeta(before, after map[string]interface{}) []MetaChange {
+	changes := []MetaChange{}
+
+	for key, oldValue := range before {
+		newValue, ok := after[key]
+		if !ok {
+			changes = append(changes, MetaChange{Key: key, Op: "removed", Before: oldValue})
+		} else if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, MetaChange{Key: key, Op: "changed", Before: oldValue, After: newValue})
+		}
+	}
+	for key, newValue := range after {
+		if _, ok := before[key]; !ok {
+			changes = append(changes, MetaChange{Key: key, Op: "added", After: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// diffOp is a single line in an edit script
+type diffOp struct {
+	kind byte // ' ', '-', '+'
+	line string
+}
+
+// UnifiedDiff returns a unified diff of two texts, or "" if they are equal
+func UnifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Walk the edit script, emitting hunks of changes with surrounding context
+	i := 0
+	for i < len(ops) {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk until we hit a run of unchanged lines long enough to split on
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContextLines {
+				end += min(run-end, diffContextLines)
+				break
+			}
+			end = run
+		}
+
+		writeHunk(&sb, ops, start, end)
+		i = end
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes ops[start:end] as a single hunk with its header
+func writeHunk(sb *strings.Builder, ops []diffOp, start, end int) {
+	// Line numbers at the start of the hunk (1-based)
+	aLine, bLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			aLine++
+		}
+		if op.kind != '-' {
+			bLine++
+		}
+	}
+
+	aCount, bCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	if aCount == 0 {
+		aLine--
+	}
+	if bCount == 0 {
+		bLine--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+	for _, op := range ops[start:end] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// maxDiffEdits bounds the edit distance diffLines searches for. Myers' trace
+// grows quadratically in the number of edits, so texts that differ by more
+// than this are diffed as a single replacement of the changed region.
+const maxDiffEdits = 1000
+
+// diffLines computes a line edit script. The common prefix and suffix are
+// matched directly and the middle is diffed with Myers' algorithm, so memory
+// is bounded by the number of edits rather than the size of the texts.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// myersDiff returns a shortest edit script for a and b, or a wholesale
+// replacement if they differ by more than maxDiffEdits lines
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+
+	// trace[d][k+d] = furthest x reached on diagonal k after d edits
+	var trace [][]int
+	for d := 0; d <= min(n+m, maxDiffEdits); d++ {
+		v := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			switch {
+			case d == 0:
+				x = 0
+			case k == -d || (k != d && trace[d-1][k-1+d-1] < trace[d-1][k+1+d-1]):
+				x = trace[d-1][k+1+d-1]
+			default:
+				x = trace[d-1][k-1+d-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+d] = x
+			if x >= n && y >= m {
+				return myersBacktrack(append(trace, v), a, b)
+			}
+		}
+		trace = append(trace, v)
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// myersBacktrack walks the trace from the end to recover the edit script
+func myersBacktrack(trace [][]int, a, b []string) []diffOp {
+	x, y := len(a), len(b)
+	ops := make([]diffOp, 0, x+y)
+
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if prevK == k+1 {
+			ops = append(ops, diffOp{'+', b[y-1]})
+		} else {
+			ops = append(ops, diffOp{'-', a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 {
+		x--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// splitLines splits text into lines, ignoring a single trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,130 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "one\ntwo\nthree\n",
+			b:    "one\n2\nthree\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "hello\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+hello\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			b:    "A\nb\nc\nd\ne\nf\ng\nh\ni\nJ\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n@@ -7,4 +7,4 @@\n g\n h\n i\n-j\n+J\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("a", "b", tt.a, tt.b)
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffMinimal(t *testing.T) {
+	a := "a\nb\nc\na\nb\nb\na\n"
+	b := "c\nb\na\nb\na\nc\n"
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	edits := 0
+	var gotA, gotB []string
+	for _, op := range ops {
+		if op.kind != ' ' {
+			edits++
+		}
+		if op.kind != '+' {
+			gotA = append(gotA, op.line)
+		}
+		if op.kind != '-' {
+			gotB = append(gotB, op.line)
+		}
+	}
+	if edits != 5 {
+		t.Errorf("edit script has %d edits, want 5", edits)
+	}
+	if strings.Join(gotA, "\n")+"\n" != a || strings.Join(gotB, "\n")+"\n" != b {
+		t.Errorf("edit script does not reproduce inputs: %+v", ops)
+	}
+}
+
+func TestUnifiedDiffLargeInputs(t *testing.T) {
+	const lines = 50000
+	var a, b, c strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&a, "line %d\n", i)
+		if i%10000 == 5000 {
+			fmt.Fprintf(&b, "changed %d\n", i)
+		} else {
+			fmt.Fprintf(&b, "line %d\n", i)
+		}
+		fmt.Fprintf(&c, "other %d\n", i)
+	}
+
+	start := time.Now()
+	diff := UnifiedDiff("a", "b", a.String(), b.String())
+	if got := strings.Count(diff, "@@ -"); got != 5 {
+		t.Errorf("sparse edits: got %d hunks, want 5", got)
+	}
+	if !strings.Contains(diff, "-line 25000\n+changed 25000\n") {
+		t.Error("sparse edits: missing changed line")
+	}
+
+	// Completely different texts exceed the edit bound and become one replacement
+	diff = UnifiedDiff("a", "c", a.String(), c.String())
+	if !strings.HasPrefix(diff, "--- a\n+++ c\n@@ -1,50000 +1,50000 @@\n-line 0\n") {
+		t.Errorf("full rewrite: unexpected header %q", diff[:min(len(diff), 60)])
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("large diffs took %v", elapsed)
+	}
+}
+
+func TestDiffMeta(t *testing.T) {
+	before := map[string]interface{}{"title": "Old", "tags": []interface{}{"a"}, "gone": true}
+	after := map[string]interface{}{"title": "New", "tags": []interface{}{"a"}, "added": 1.0}
+
+	changes := DiffMeta(before, after)
+
+	want := []struct{ key, op string }{
+		{"added", "added"},
+		{"gone", "removed"},
+		{"title", "changed"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("DiffMeta() returned %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		if changes[i].Key != w.key || changes[i].Op != w.op {
+			t.Errorf("change %d = %s/%s, want %s/%s", i, changes[i].Key, changes[i].Op, w.key, w.op)
+		}
+	}
+}
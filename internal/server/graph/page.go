@@ -0,0 +1,37 @@
+package graph
+
+import "github.com/systemshift/memex/internal/memex/core"
+
+// fillPageSize is how many nodes are read per call while filling a page
+// of filtered results
+const fillPageSize = 500
+
+// PageFunc reads one page of nodes from a backend
+type PageFunc func(limit, offset int) ([]*core.Node, error)
+
+// FillPage returns the limit nodes after offset that keep lets through,
+// reading further pages from fetch so filtered-out nodes don't leave a page
+// short. A limit of 0 or less returns every kept node.
+func FillPage(limit, offset int, keep func([]*core.Node) []*core.Node, fetch PageFunc) ([]*core.Node, error) {
+	out := []*core.Node{}
+	skipped := 0
+	for read := 0; limit <= 0 || len(out) < limit; read += fillPageSize {
+		nodes, err := fetch(fillPageSize, read)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range keep(nodes) {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			if limit <= 0 || len(out) < limit {
+				out = append(out, n)
+			}
+		}
+		if len(nodes) < fillPageSize {
+			break
+		}
+	}
+	return out, nil
+}
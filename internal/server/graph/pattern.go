@@ -0,0 +1,279 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MaxPatternNodes caps the variables in one pattern; each is a join
+const MaxPatternNodes = 8
+
+// Pattern is a structural query: node variables, optionally typed and
+// constrained by property values, joined by directed links
+type Pattern struct {
+	Nodes []PatternNode
+	Edges []PatternEdge
+}
+
+// PatternNode is one node variable
+type PatternNode struct {
+	Var   string
+	Type  string                 // Empty matches any node type
+	Props map[string]interface{} // Property values the node must have
+}
+
+// PatternEdge is a link from Nodes[From] to Nodes[To]
+type PatternEdge struct {
+	From int
+	To   int
+	Type string // Empty matches any link type
+}
+
+// PatternBinding maps each pattern variable to a node ID
+type PatternBinding map[string]string
+
+// ParsePattern parses a comma-separated list of paths such as
+//
+//	A:Person -[WORKS_AT]-> B:Company, A -[KNOWS]-> C:Person
+//
+// Links may point either way (<-[T]-) and omit their type (--> or <--).
+// A variable's type may be given at any of its occurrences. where adds
+// property constraints per variable.
+func ParsePattern(src string, where map[string]map[string]interface{}) (*Pattern, error) {
+	src = strings.TrimSpace(src)
+	if strings.HasPrefix(src, "{") && strings.HasSuffix(src, "}") {
+		src = src[1 : len(src)-1]
+	}
+
+	p := &Pattern{}
+	vars := make(map[string]int)
+	for _, clause := range strings.Split(src, ",") {
+		s := &patternScanner{src: clause}
+		prev, err := s.node(p, vars)
+		if err != nil {
+			return nil, err
+		}
+		for !s.done() {
+			edgeType, reversed, err := s.edge()
+			if err != nil {
+				return nil, err
+			}
+			next, err := s.node(p, vars)
+			if err != nil {
+				return nil, err
+			}
+			edge := PatternEdge{From: prev, To: next, Type: edgeType}
+			if reversed {
+				edge.From, edge.To = next, prev
+			}
+			p.Edges = append(p.Edges, edge)
+			prev = next
+		}
+	}
+	if len(p.Nodes) > MaxPatternNodes {
+		return nil, fmt.Errorf("pattern has %d variables (max %d)", len(p.Nodes), MaxPatternNodes)
+	}
+	if !p.connected() {
+		return nil, fmt.Errorf("pattern must be connected")
+	}
+
+	for name, props := range where {
+		i, ok := vars[name]
+		if !ok {
+			return nil, fmt.Errorf("where: unknown variable %q", name)
+		}
+		for key := range props {
+			if key == "" {
+				return nil, fmt.Errorf("where: empty property name for %q", name)
+			}
+		}
+		p.Nodes[i].Props = props
+	}
+	return p, nil
+}
+
+// connected reports whether every variable is reachable from the first
+func (p *Pattern) connected() bool {
+	seen := map[int]bool{0: true}
+	for grew := true; grew; {
+		grew = false
+		for _, e := range p.Edges {
+			if seen[e.From] != seen[e.To] {
+				seen[e.From], seen[e.To] = true, true
+				grew = true
+			}
+		}
+	}
+	return len(seen) == len(p.Nodes)
+}
+
+// propsMatch reports whether meta holds every property Nodes[i] requires
+func (p *Pattern) propsMatch(i int, meta map[string]interface{}) bool {
+	for key, want := range p.Nodes[i].Props {
+		got, ok := meta[key]
+		if !ok || !jsonEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// propsJSONMatch is propsMatch for properties stored as a JSON string
+func (p *Pattern) propsJSONMatch(i int, properties string) bool {
+	if len(p.Nodes[i].Props) == 0 {
+		return true
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal([]byte(properties), &meta); err != nil {
+		return false
+	}
+	return p.propsMatch(i, meta)
+}
+
+// propFragments returns the `"key":value` substrings a node's JSON
+// properties must contain; backends use them to prefilter before propsMatch
+func (p *Pattern) propFragments(i int) []string {
+	var out []string
+	for key, want := range p.Nodes[i].Props {
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(want)
+		out = append(out, string(k)+":"+string(v))
+	}
+	return out
+}
+
+// binding builds the variable bindings for one match
+func (p *Pattern) binding(ids []string) PatternBinding {
+	b := make(PatternBinding, len(ids))
+	for i, id := range ids {
+		b[p.Nodes[i].Var] = id
+	}
+	return b
+}
+
+// order returns the variables so that each after the first is linked to an
+// earlier one, letting matchers extend a partial binding along links
+func (p *Pattern) order() []int {
+	order := []int{0}
+	placed := map[int]bool{0: true}
+	for len(order) < len(p.Nodes) {
+		for _, e := range p.Edges {
+			if placed[e.From] && !placed[e.To] {
+				placed[e.To] = true
+				order = append(order, e.To)
+			} else if placed[e.To] && !placed[e.From] {
+				placed[e.From] = true
+				order = append(order, e.From)
+			}
+		}
+	}
+	return order
+}
+
+// jsonEqual compares two property values as their JSON encodings, so
+// numbers compare equal whatever Go type decoded them
+func jsonEqual(a, b interface{}) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
+
+// patternScanner reads one comma-separated path of a pattern
+type patternScanner struct {
+	src string
+	pos int
+}
+
+func (s *patternScanner) skipSpace() {
+	for s.pos < len(s.src) && strings.ContainsRune(" \t\r\n", rune(s.src[s.pos])) {
+		s.pos++
+	}
+}
+
+func (s *patternScanner) done() bool {
+	s.skipSpace()
+	return s.pos >= len(s.src)
+}
+
+func (s *patternScanner) consume(token string) bool {
+	s.skipSpace()
+	if strings.HasPrefix(s.src[s.pos:], token) {
+		s.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (s *patternScanner) ident() string {
+	s.skipSpace()
+	start := s.pos
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			break
+		}
+		s.pos++
+	}
+	return s.src[start:s.pos]
+}
+
+func (s *patternScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid pattern at %q: %s", strings.TrimSpace(s.src[s.pos:]), fmt.Sprintf(format, args...))
+}
+
+// node reads `VAR` or `VAR:Type` and returns the variable's index
+func (s *patternScanner) node(p *Pattern, vars map[string]int) (int, error) {
+	name := s.ident()
+	if name == "" {
+		return 0, s.errorf("expected a variable")
+	}
+	nodeType := ""
+	if s.consume(":") {
+		if nodeType = s.ident(); nodeType == "" {
+			return 0, s.errorf("expected a node type after %s:", name)
+		}
+	}
+	i, ok := vars[name]
+	if !ok {
+		i = len(p.Nodes)
+		vars[name] = i
+		p.Nodes = append(p.Nodes, PatternNode{Var: name})
+	}
+	if nodeType != "" {
+		if p.Nodes[i].Type != "" && p.Nodes[i].Type != nodeType {
+			return 0, fmt.Errorf("variable %s has conflicting types %s and %s", name, p.Nodes[i].Type, nodeType)
+		}
+		p.Nodes[i].Type = nodeType
+	}
+	return i, nil
+}
+
+// edge reads -[T]->, <-[T]-, --> or <--
+func (s *patternScanner) edge() (linkType string, reversed bool, err error) {
+	reversed = s.consume("<")
+	if !s.consume("-") {
+		return "", false, s.errorf("expected a link")
+	}
+	if s.consume("[") {
+		linkType = s.ident()
+		if !s.consume("]") || !s.consume("-") {
+			return "", false, s.errorf("expected ]- after link type")
+		}
+	} else if !s.consume("-") {
+		return "", false, s.errorf("expected a link")
+	}
+	if !reversed && !s.consume(">") {
+		return "", false, s.errorf("expected > at end of link")
+	}
+	if reversed && s.consume(">") {
+		return "", false, s.errorf("link cannot point both ways")
+	}
+	return linkType, reversed, nil
+}
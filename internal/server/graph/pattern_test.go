@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestParsePattern(t *testing.T) {
+	p, err := ParsePattern("{A:Person -[WORKS_AT]-> B:Company, A <-[KNOWS]- C:Person -->B}", nil)
+	if err != nil {
+		t.Fatalf("ParsePattern: %v", err)
+	}
+	wantNodes := []PatternNode{{Var: "A", Type: "Person"}, {Var: "B", Type: "Company"}, {Var: "C", Type: "Person"}}
+	wantEdges := []PatternEdge{{From: 0, To: 1, Type: "WORKS_AT"}, {From: 2, To: 0, Type: "KNOWS"}, {From: 2, To: 1}}
+	if !reflect.DeepEqual(p.Nodes, wantNodes) || !reflect.DeepEqual(p.Edges, wantEdges) {
+		t.Errorf("ParsePattern = %+v %+v, want %+v %+v", p.Nodes, p.Edges, wantNodes, wantEdges)
+	}
+
+	for _, bad := range []string{"", "A -[X]- B", "A:Person -> B", "A:Person, B:Company", "A:Person -[X]-> A:Company", "A <-[X]-> B"} {
+		if _, err := ParsePattern(bad, nil); err == nil {
+			t.Errorf("ParsePattern(%q) succeeded, want error", bad)
+		}
+	}
+	if _, err := ParsePattern("A -[X]-> B", map[string]map[string]interface{}{"Z": {"name": "x"}}); err == nil {
+		t.Error("expected where on an unknown variable to be rejected")
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, repo := range map[string]Repository{"sqlite": sqlite, "memory": NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			var nodes []*core.Node
+			for id, typ := range map[string]string{"p:ann": "Person", "p:bob": "Person", "p:cy": "Person", "c:acme": "Company", "c:initech": "Company"} {
+				nodes = append(nodes, &core.Node{ID: id, Type: typ, Meta: map[string]any{"rank": 1}, Created: now, Modified: now})
+			}
+			if err := repo.CreateNodes(ctx, nodes); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.UpdateNodeMeta(ctx, "p:cy", map[string]any{"rank": 10}); err != nil {
+				t.Fatal(err)
+			}
+			var links []*core.Link
+			for _, l := range [][3]string{
+				{"p:ann", "c:acme", "WORKS_AT"}, {"p:bob", "c:initech", "WORKS_AT"}, {"p:cy", "c:initech", "WORKS_AT"},
+				{"p:ann", "p:bob", "KNOWS"}, {"p:ann", "p:cy", "KNOWS"},
+			} {
+				links = append(links, &core.Link{Source: l[0], Target: l[1], Type: l[2], Created: now, Modified: now})
+			}
+			if err := repo.CreateLinks(ctx, links); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := ParsePattern("A:Person -[WORKS_AT]-> B:Company, A -[KNOWS]-> C:Person -[WORKS_AT]-> D:Company", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := repo.MatchPattern(ctx, p, 100)
+			if err != nil {
+				t.Fatalf("MatchPattern: %v", err)
+			}
+			if len(got) != 2 || got[0]["A"] != "p:ann" || got[0]["B"] != "c:acme" || got[0]["D"] != "c:initech" {
+				t.Errorf("MatchPattern = %v, want ann at acme knowing bob and cy at initech", got)
+			}
+
+			// rank 1 is a prefix of rank 10, so only the exact check tells them apart
+			p, _ = ParsePattern("A:Person -[WORKS_AT]-> B:Company", map[string]map[string]interface{}{"A": {"rank": 1}, "B": {"rank": 1.0}})
+			got, err = repo.MatchPattern(ctx, p, 100)
+			if err != nil {
+				t.Fatalf("MatchPattern: %v", err)
+			}
+			if len(got) != 2 {
+				t.Errorf("MatchPattern with props = %v, want ann and bob", got)
+			}
+			if got, _ := repo.MatchPattern(ctx, p, 1); len(got) != 1 {
+				t.Errorf("MatchPattern limit 1 returned %d bindings", len(got))
+			}
+		})
+	}
+}
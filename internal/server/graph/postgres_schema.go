@@ -0,0 +1,156 @@
+package graph
+
+// PostgreSQL schema DDL. Tables and columns mirror the SQLite schema so the
+// shared SQL in sqlite.go runs unchanged. Timestamps are RFC3339 text, with
+// epoch seconds alongside for nodes.
+
+const pgSchemaNodes = `
+CREATE TABLE IF NOT EXISTS nodes (
+    rowid BIGSERIAL PRIMARY KEY,
+    version_id TEXT UNIQUE NOT NULL,
+    id TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    is_current INTEGER NOT NULL DEFAULT 1,
+    type TEXT NOT NULL,
+    content TEXT,
+    properties TEXT,
+    created_at TEXT NOT NULL,
+    modified_at TEXT NOT NULL,
+    deleted INTEGER NOT NULL DEFAULT 0,
+    deleted_at TEXT,
+    change_note TEXT,
+    changed_by TEXT,
+    degree INTEGER NOT NULL DEFAULT 0
+)`
+
+const pgSchemaLinks = `
+CREATE TABLE IF NOT EXISTS links (
+    id BIGSERIAL PRIMARY KEY,
+    source_id TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    properties TEXT,
+    created_at TEXT NOT NULL,
+    modified_at TEXT NOT NULL,
+    UNIQUE(source_id, target_id, type)
+)`
+
+const pgSchemaLinkTombstones = `
+CREATE TABLE IF NOT EXISTS link_tombstones (
+    id BIGSERIAL PRIMARY KEY,
+    source_id TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    properties TEXT,
+    created_at TEXT NOT NULL,
+    deleted_at TEXT NOT NULL
+)`
+
+// pgSearchDocument is the text indexed for full-text search; queries must
+// use the same expression for the GIN index to apply
+const pgSearchDocument = `to_tsvector('simple', id || ' ' || type || ' ' || coalesce(properties, '') || ' ' || coalesce(content, ''))`
+
+// pgSchemaContents mirrors the SQLite content store so shared queries resolve;
+// Postgres keeps content inline and leaves it empty
+const pgSchemaContents = `
+CREATE TABLE IF NOT EXISTS contents (
+    hash TEXT PRIMARY KEY,
+    data TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    refcount INTEGER NOT NULL DEFAULT 0
+)`
+
+const pgSchemaQuarantine = `
+CREATE TABLE IF NOT EXISTS quarantine (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    row_key TEXT NOT NULL,
+    node_id TEXT NOT NULL,
+    version_id TEXT NOT NULL DEFAULT '',
+    target_id TEXT NOT NULL DEFAULT '',
+    link_type TEXT NOT NULL DEFAULT '',
+    problem TEXT NOT NULL,
+    raw TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    first_seen TEXT NOT NULL,
+    last_seen TEXT NOT NULL,
+    resolved_at TEXT,
+    resolution TEXT NOT NULL DEFAULT '',
+    UNIQUE(kind, row_key)
+)`
+
+const pgIndexNodesSearch = `CREATE INDEX IF NOT EXISTS idx_nodes_search ON nodes USING GIN (` + pgSearchDocument + `)`
+
+// LatestSchemaVersion returns the schema version this build migrates to
+func (r *PostgresRepository) LatestSchemaVersion() int {
+	return latestVersion(postgresMigrations())
+}
+
+// postgresMigrations returns all Postgres migrations in version order
+func postgresMigrations() []migration {
+	return []migration{
+		{
+			version: 1,
+			name:    "baseline schema",
+			statements: []string{
+				pgSchemaNodes,
+				pgSchemaLinks,
+				schemaVersionChain,
+				pgSchemaLinkTombstones,
+				indexNodesID,
+				indexNodesType,
+				indexNodesIsCurrent,
+				indexNodesDeleted,
+				indexNodesVersionID,
+				indexNodesCreatedAt,
+				indexNodesModifiedAt,
+				indexLinksSource,
+				indexLinksTarget,
+				indexLinksType,
+				indexLinksCreatedAt,
+				indexLinkTombstonesDeletedAt,
+				pgIndexNodesSearch,
+			},
+		},
+		{
+			version: 2,
+			name:    "content store columns",
+			statements: []string{
+				pgSchemaContents,
+				`ALTER TABLE nodes ADD COLUMN IF NOT EXISTS content_hash TEXT`,
+			},
+		},
+		{
+			version: 3,
+			name:    "epoch timestamp columns",
+			statements: []string{
+				`ALTER TABLE nodes ADD COLUMN IF NOT EXISTS created_unix BIGINT`,
+				`ALTER TABLE nodes ADD COLUMN IF NOT EXISTS modified_unix BIGINT`,
+				`UPDATE nodes SET created_unix = EXTRACT(EPOCH FROM created_at::timestamptz)::BIGINT,
+				                  modified_unix = EXTRACT(EPOCH FROM modified_at::timestamptz)::BIGINT`,
+				indexNodesCreatedUnix,
+				indexNodesModifiedUnix,
+			},
+		},
+		{
+			version: 4,
+			name:    "typed link weight and direction",
+			statements: []string{
+				`ALTER TABLE links ADD COLUMN IF NOT EXISTS weight DOUBLE PRECISION NOT NULL DEFAULT 0`,
+				`ALTER TABLE links ADD COLUMN IF NOT EXISTS bidirectional INTEGER NOT NULL DEFAULT 0`,
+				`ALTER TABLE link_tombstones ADD COLUMN IF NOT EXISTS weight DOUBLE PRECISION NOT NULL DEFAULT 0`,
+				`ALTER TABLE link_tombstones ADD COLUMN IF NOT EXISTS bidirectional INTEGER NOT NULL DEFAULT 0`,
+			},
+			apply: promoteStoredLinkFields,
+		},
+		{
+			version: 5,
+			name:    "quarantine",
+			statements: []string{
+				pgSchemaQuarantine,
+				indexQuarantineStatus,
+			},
+		},
+	}
+}
@@ -0,0 +1,19 @@
+package graph
+
+import "testing"
+
+func TestRebindDollar(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`SELECT * FROM nodes WHERE id = ? AND type IN (?, ?)`, `SELECT * FROM nodes WHERE id = $1 AND type IN ($2, $3)`},
+		{`UPDATE links SET properties = ? WHERE type = 'WHY?'`, `UPDATE links SET properties = $1 WHERE type = 'WHY?'`},
+		{`SELECT "a?b" FROM t WHERE x = ?`, `SELECT "a?b" FROM t WHERE x = $1`},
+		{`SELECT 1`, `SELECT 1`},
+	}
+	for _, tt := range tests {
+		if got := rebindDollar(tt.in); got != tt.want {
+			t.Errorf("rebindDollar(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
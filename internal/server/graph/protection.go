@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+)
+
+// Node flags for curated, hand-authored nodes
+const (
+	// ProtectedKey blocks deletion (even forced) and attention pruning
+	ProtectedKey = "protected"
+	// PinnedKey guarantees inclusion in graph map samples
+	PinnedKey = "pinned"
+)
+
+// ErrProtected is returned when deleting a protected node
+var ErrProtected = errors.New("node is protected")
+
+// IsProtected reports whether node properties carry protected: true
+func IsProtected(meta map[string]interface{}) bool {
+	b, _ := meta[ProtectedKey].(bool)
+	return b
+}
+
+// IsPinned reports whether node properties carry pinned: true
+func IsPinned(meta map[string]interface{}) bool {
+	b, _ := meta[PinnedKey].(bool)
+	return b
+}
+
+// flagPattern is how a true flag appears in JSON-serialized properties
+func flagPattern(key string) string {
+	return `"` + key + `":true`
+}
+
+// withPinned puts pinned IDs ahead of a type's sample, dropping duplicates;
+// the sample may exceed sampleSize when many nodes are pinned
+func withPinned(pinned, samples []string, sampleSize int) []string {
+	if len(pinned) == 0 {
+		return samples
+	}
+	merged := append([]string(nil), pinned...)
+	seen := make(map[string]bool, len(pinned))
+	for _, id := range pinned {
+		seen[id] = true
+	}
+	for _, id := range samples {
+		if len(merged) >= sampleSize {
+			break
+		}
+		if !seen[id] {
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
+
+// propsHaveFlag reports whether JSON-serialized properties set a flag to true
+func propsHaveFlag(props, key string) bool {
+	return strings.Contains(props, flagPattern(key))
+}
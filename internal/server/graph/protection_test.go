@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestProtectedAndPinnedNodes(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, repo := range map[string]Repository{"sqlite": sqlite, "memory": NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			nodes := []*core.Node{
+				{ID: "note:curated", Type: "Note", Meta: map[string]interface{}{ProtectedKey: true}},
+				{ID: "note:a", Type: "Note"},
+				{ID: "note:b", Type: "Note"},
+				{ID: "note:z", Type: "Note", Meta: map[string]interface{}{PinnedKey: true}},
+			}
+			for _, n := range nodes {
+				n.Created, n.Modified = now, now
+				if err := repo.CreateNode(ctx, n); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := repo.DeleteNode(ctx, "note:curated", true); !errors.Is(err, ErrProtected) {
+				t.Errorf("force delete of protected node: got %v, want ErrProtected", err)
+			}
+
+			for _, target := range []string{"note:curated", "note:b"} {
+				if err := repo.UpdateAttentionEdge(ctx, "note:a", target, "q1", 0.1); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pruned, err := repo.PruneWeakAttentionEdges(ctx, 0.5, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pruned != 1 {
+				t.Errorf("expected 1 edge pruned, got %d", pruned)
+			}
+
+			graphMap, err := repo.GetGraphMap(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if samples := graphMap.SamplesByType["Note"]; len(samples) == 0 || samples[0] != "note:z" {
+				t.Errorf("expected pinned node first in samples, got %v", samples)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProvenanceLinkTypes are the link types followed from a node back to its sources
+var ProvenanceLinkTypes = []string{"EXTRACTED_FROM", "INTERPRETED_THROUGH", "DERIVED_FROM"}
+
+// ProvenanceStep is one node in a derivation tree
+type ProvenanceStep struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type,omitempty"`
+	Via       string                 `json:"via,omitempty"`       // Link type from the parent step
+	LinkMeta  map[string]interface{} `json:"link_meta,omitempty"` // Properties of that link
+	Extractor string                 `json:"extractor,omitempty"` // From the link, falling back to the node
+	IsSource  bool                   `json:"is_source,omitempty"`
+	Missing   bool                   `json:"missing,omitempty"`   // Linked node no longer exists
+	Cycle     bool                   `json:"cycle,omitempty"`     // Already on the path; not expanded
+	Truncated bool                   `json:"truncated,omitempty"` // Depth limit reached; not expanded
+	From      []*ProvenanceStep      `json:"from,omitempty"`
+}
+
+// Provenance is the derivation tree of a node plus a flat summary
+type Provenance struct {
+	Root       *ProvenanceStep `json:"root"`
+	Sources    []string        `json:"sources"`
+	Lenses     []string        `json:"lenses"`
+	Extractors []string        `json:"extractors"`
+	MaxDepth   int             `json:"max_depth"`
+}
+
+// TraceProvenance walks EXTRACTED_FROM, INTERPRETED_THROUGH and DERIVED_FROM
+// links from id back to Source nodes, up to maxDepth hops. Lenses appear as
+// INTERPRETED_THROUGH steps and are expanded like any other node.
+func TraceProvenance(ctx context.Context, repo Repository, id string, maxDepth int) (*Provenance, error) {
+	root, err := repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+
+	follow := make(map[string]bool, len(ProvenanceLinkTypes))
+	for _, t := range ProvenanceLinkTypes {
+		follow[t] = true
+	}
+
+	sources := make(map[string]bool)
+	lenses := make(map[string]bool)
+	extractors := make(map[string]bool)
+	onPath := make(map[string]bool)
+
+	var walk func(step *ProvenanceStep, depth int) error
+	walk = func(step *ProvenanceStep, depth int) error {
+		if step.IsSource {
+			sources[step.ID] = true
+			return nil
+		}
+		links, err := repo.GetLinks(ctx, step.ID)
+		if err != nil {
+			return err
+		}
+		if depth >= maxDepth {
+			for _, link := range links {
+				if follow[link.Type] {
+					step.Truncated = true
+					break
+				}
+			}
+			return nil
+		}
+
+		onPath[step.ID] = true
+		defer delete(onPath, step.ID)
+
+		for _, link := range links {
+			if !follow[link.Type] {
+				continue
+			}
+			child := &ProvenanceStep{ID: link.Target, Via: link.Type, LinkMeta: link.Meta}
+			if e, ok := link.Meta["extractor"].(string); ok {
+				child.Extractor = e
+			}
+
+			node, err := repo.GetNode(ctx, link.Target)
+			if err != nil {
+				child.Missing = true
+			} else {
+				child.Type = node.Type
+				child.IsSource = node.Type == "Source" || strings.HasPrefix(node.ID, "sha256:")
+				if e, ok := node.Meta["extractor"].(string); ok && child.Extractor == "" {
+					child.Extractor = e
+				}
+			}
+			if link.Type == "INTERPRETED_THROUGH" {
+				lenses[child.ID] = true
+			}
+			if child.Extractor != "" {
+				extractors[child.Extractor] = true
+			}
+			step.From = append(step.From, child)
+
+			if child.Missing {
+				continue
+			}
+			if onPath[child.ID] {
+				child.Cycle = true
+				continue
+			}
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	step := &ProvenanceStep{
+		ID:       root.ID,
+		Type:     root.Type,
+		IsSource: root.Type == "Source" || strings.HasPrefix(root.ID, "sha256:"),
+	}
+	if e, ok := root.Meta["extractor"].(string); ok {
+		step.Extractor = e
+		extractors[e] = true
+	}
+	if err := walk(step, 0); err != nil {
+		return nil, err
+	}
+
+	return &Provenance{
+		Root:       step,
+		Sources:    sortedKeys(sources),
+		Lenses:     sortedKeys(lenses),
+		Extractors: sortedKeys(extractors),
+		MaxDepth:   maxDepth,
+	}, nil
+}
+
+// sortedKeys returns the keys of a set in sorted order (never nil, for JSON)
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
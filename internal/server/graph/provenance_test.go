@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestTraceProvenance(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+
+	for _, n := range []*core.Node{
+		{ID: "sha256:abc", Type: "Source"},
+		{ID: "lens:skeptic", Type: "Lens"},
+		{ID: "person:ada", Type: "Person"},
+		{ID: "claim:1", Type: "Claim"},
+	} {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "claim:1", Target: "person:ada", Type: "DERIVED_FROM"},
+		{Source: "person:ada", Target: "sha256:abc", Type: "EXTRACTED_FROM", Meta: map[string]any{"extractor": "openai"}},
+		{Source: "person:ada", Target: "lens:skeptic", Type: "INTERPRETED_THROUGH"},
+		{Source: "person:ada", Target: "claim:1", Type: "DERIVED_FROM"}, // loop back
+	} {
+		l.Created, l.Modified = now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prov, err := TraceProvenance(ctx, repo, "claim:1", 10)
+	if err != nil {
+		t.Fatalf("TraceProvenance: %v", err)
+	}
+	if !reflect.DeepEqual(prov.Sources, []string{"sha256:abc"}) {
+		t.Errorf("Sources = %v", prov.Sources)
+	}
+	if !reflect.DeepEqual(prov.Lenses, []string{"lens:skeptic"}) || !reflect.DeepEqual(prov.Extractors, []string{"openai"}) {
+		t.Errorf("Lenses = %v, Extractors = %v", prov.Lenses, prov.Extractors)
+	}
+	ada := prov.Root.From[0]
+	if len(ada.From) != 3 || !ada.From[2].Cycle {
+		t.Errorf("expected the loop back to claim:1 to be marked as a cycle, got %+v", ada.From)
+	}
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// What a quarantine entry records a problem with
+const (
+	QuarantineNode = "node" // A node version row
+	QuarantineLink = "link" // A links row
+)
+
+// Quarantine entry statuses
+const (
+	QuarantineOpen      = "open"      // Still unreadable, or not yet repaired
+	QuarantineRepaired  = "repaired"  // Fixed in place and readable again
+	QuarantineDropped   = "dropped"   // Deleted, to be re-ingested from its source
+	QuarantineDismissed = "dismissed" // Left as it is on purpose
+)
+
+// Repair actions
+const (
+	RepairReplace = "replace" // Store new properties in place of the unreadable ones
+	RepairDrop    = "drop"    // Delete the row
+	RepairDismiss = "dismiss" // Change nothing and close the entry
+)
+
+var (
+	// ErrQuarantineNotFound is returned for an unknown entry ID
+	ErrQuarantineNotFound = errors.New("quarantine entry not found")
+	// ErrNotRepaired is returned when a repaired row still cannot be read
+	ErrNotRepaired = errors.New("row still cannot be read")
+	// ErrInvalidRepair is returned for an unknown action or one that does
+	// not apply to the entry
+	ErrInvalidRepair = errors.New("invalid repair")
+)
+
+// QuarantineEntry is a stored row that could not be read. Reads skip the
+// row, or return a node or link without its properties, as before; the
+// entry keeps what was stored so it can be repaired or re-ingested.
+type QuarantineEntry struct {
+	ID          int64      `json:"id"`
+	Kind        string     `json:"kind"`                 // node or link
+	NodeID      string     `json:"node_id"`              // The node, or the link's source
+	VersionID   string     `json:"version_id,omitempty"` // Node rows
+	Target      string     `json:"target,omitempty"`     // Link rows
+	LinkType    string     `json:"link_type,omitempty"`  // Link rows
+	Problem     string     `json:"problem"`              // Why the row could not be read
+	Raw         string     `json:"raw,omitempty"`        // The row's columns as stored, as JSON
+	Status      string     `json:"status"`
+	Occurrences int        `json:"occurrences"` // Reads that hit the row
+	FirstSeen   time.Time  `json:"first_seen"`
+	LastSeen    time.Time  `json:"last_seen"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	Resolution  string     `json:"resolution,omitempty"` // The repair and its note
+}
+
+// QuarantineRepair resolves an entry
+type QuarantineRepair struct {
+	Action     string                 `json:"action"`               // replace, drop or dismiss
+	Properties map[string]interface{} `json:"properties,omitempty"` // The properties replace stores
+	Note       string                 `json:"note,omitempty"`       // Kept with the resolution
+}
+
+// QuarantineCheck is the result of reading every stored row
+type QuarantineCheck struct {
+	NodesChecked int `json:"nodes_checked"`
+	LinksChecked int `json:"links_checked"`
+	Found        int `json:"found"` // Rows that could not be read
+}
+
+// Quarantiner is implemented by backends that record the stored rows they
+// cannot read instead of silently skipping them
+type Quarantiner interface {
+	// ListQuarantine returns entries by ID, newest first; an empty status
+	// lists all
+	ListQuarantine(ctx context.Context, status string, limit, offset int) ([]*QuarantineEntry, error)
+	GetQuarantine(ctx context.Context, id int64) (*QuarantineEntry, error)
+	// CheckStoredRows reads every node and link row, quarantining the
+	// ones that cannot be read, not just those a request happened on
+	CheckStoredRows(ctx context.Context) (*QuarantineCheck, error)
+	// RepairQuarantine applies a repair and closes the entry
+	RepairQuarantine(ctx context.Context, id int64, repair QuarantineRepair) (*QuarantineEntry, error)
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// ErrReadOnly is returned when a write touches a read-only namespace
+var ErrReadOnly = errors.New("read-only")
+
+// readOnlyRepository rejects writes to nodes in read-only namespaces, and
+// links with an end in one, so a published graph can sit beside writable
+// ones on the same server
+type readOnlyRepository struct {
+	Repository
+	namespaces map[string]bool
+}
+
+// WithReadOnlyNamespaces wraps repo so that creating, updating or deleting
+// nodes in the given namespaces, or links touching them, fails with
+// ErrReadOnly
+func WithReadOnlyNamespaces(repo Repository, namespaces []string) Repository {
+	set := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		set[ns] = true
+	}
+	return &readOnlyRepository{Repository: repo, namespaces: set}
+}
+
+// check returns ErrReadOnly if any of ids is in a read-only namespace
+func (r *readOnlyRepository) check(ids ...string) error {
+	for _, id := range ids {
+		if ns := Namespace(id); ns != "" && r.namespaces[ns] {
+			return fmt.Errorf("%s: namespace %s is %w", id, ns, ErrReadOnly)
+		}
+	}
+	return nil
+}
+
+func (r *readOnlyRepository) CreateNode(ctx context.Context, node *core.Node) error {
+	if err := r.check(node.ID); err != nil {
+		return err
+	}
+	return r.Repository.CreateNode(ctx, node)
+}
+
+func (r *readOnlyRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	for _, node := range nodes {
+		if err := r.check(node.ID); err != nil {
+			return err
+		}
+	}
+	return r.Repository.CreateNodes(ctx, nodes)
+}
+
+func (r *readOnlyRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	if err := r.check(link.Source, link.Target); err != nil {
+		return err
+	}
+	return r.Repository.CreateLink(ctx, link)
+}
+
+func (r *readOnlyRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	for _, link := range links {
+		if err := r.check(link.Source, link.Target); err != nil {
+			return err
+		}
+	}
+	return r.Repository.CreateLinks(ctx, links)
+}
+
+func (r *readOnlyRepository) DeleteLink(ctx context.Context, sourceID, targetID, linkType string) error {
+	if err := r.check(sourceID, targetID); err != nil {
+		return err
+	}
+	return r.Repository.DeleteLink(ctx, sourceID, targetID, linkType)
+}
+
+func (r *readOnlyRepository) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	if err := r.check(id); err != nil {
+		return err
+	}
+	return r.Repository.UpdateNodeMeta(ctx, id, meta)
+}
+
+func (r *readOnlyRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	if err := r.check(id); err != nil {
+		return err
+	}
+	return r.Repository.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy)
+}
+
+func (r *readOnlyRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	if err := r.check(nodeID); err != nil {
+		return err
+	}
+	return r.Repository.DeleteNode(ctx, nodeID, force)
+}
+
+func (r *readOnlyRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	if err := r.check(source, target); err != nil {
+		return err
+	}
+	return r.Repository.UpdateAttentionEdge(ctx, source, target, queryID, weight)
+}
+
+func (r *readOnlyRepository) CreateInterpretedThroughLink(ctx context.Context, entityID, lensID string, meta map[string]interface{}) error {
+	if err := r.check(entityID, lensID); err != nil {
+		return err
+	}
+	return r.Repository.CreateInterpretedThroughLink(ctx, entityID, lensID, meta)
+}
@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestReadOnlyNamespaces(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemory()
+	now := time.Now()
+	for _, id := range []string{"demo:a", "notes:b"} {
+		if err := inner.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := WithReadOnlyNamespaces(inner, []string{"demo"})
+	link := &core.Link{Source: "notes:b", Target: "demo:a", Type: "CITES", Created: now, Modified: now}
+
+	for name, err := range map[string]error{
+		"create":      repo.CreateNode(ctx, &core.Node{ID: "demo:c", Type: "Note", Created: now, Modified: now}),
+		"bulk create": repo.CreateNodes(ctx, []*core.Node{{ID: "notes:c", Type: "Note"}, {ID: "demo:c", Type: "Note"}}),
+		"update":      repo.UpdateNodeMeta(ctx, "demo:a", map[string]any{"x": 1}),
+		"delete":      repo.DeleteNode(ctx, "demo:a", true),
+		"link":        repo.CreateLink(ctx, link),
+		"unlink":      repo.DeleteLink(ctx, "demo:a", "notes:b", "CITES"),
+	} {
+		if !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s error = %v, want ErrReadOnly", name, err)
+		}
+	}
+	if _, err := inner.GetNode(ctx, "notes:c"); err == nil {
+		t.Error("bulk create wrote part of a rejected batch")
+	}
+
+	// Reads, and writes elsewhere, pass through
+	if _, err := repo.GetNode(ctx, "demo:a"); err != nil {
+		t.Errorf("GetNode: %v", err)
+	}
+	if err := repo.UpdateNodeMeta(ctx, "notes:b", map[string]any{"x": 1}); err != nil {
+		t.Errorf("update outside the namespace: %v", err)
+	}
+	if err := repo.CreateNode(ctx, &core.Node{ID: "plain", Type: "Note", Created: now, Modified: now}); err != nil {
+		t.Errorf("create without a namespace: %v", err)
+	}
+}
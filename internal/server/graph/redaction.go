@@ -0,0 +1,268 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// RedactedKey marks nodes whose content or properties were redacted before storage
+const RedactedKey = "redacted"
+
+// Built-in redaction rules
+const (
+	RedactEmail      = "email"
+	RedactCreditCard = "credit_card"
+	RedactAPIKey     = "api_key"
+)
+
+// redactionRule masks one kind of sensitive text
+type redactionRule struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool // Optional check that filters false positives
+}
+
+// builtinRedactionRules are available to every policy by name
+var builtinRedactionRules = map[string]*redactionRule{
+	RedactEmail: {
+		name: RedactEmail,
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	RedactCreditCard: {
+		name:  RedactCreditCard,
+		re:    regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid: luhnValid,
+	},
+	RedactAPIKey: {
+		name: RedactAPIKey,
+		// OpenAI/Anthropic, AWS access key IDs, GitHub, Slack and Google API keys
+		re: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abposr]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})\b`),
+	},
+}
+
+// RedactionPolicy selects which rules apply to nodes in each namespace.
+// Rules are built-in names or keys of Patterns.
+type RedactionPolicy struct {
+	Default    []string            `json:"default"`    // Rules for namespaces not listed below
+	Namespaces map[string][]string `json:"namespaces"` // Per-namespace rules; an empty list disables redaction
+	Patterns   map[string]string   `json:"patterns"`   // Custom rule name -> regular expression
+
+	rules map[string]*redactionRule
+}
+
+// LoadRedactionPolicy reads a JSON redaction policy
+func LoadRedactionPolicy(path string) (*RedactionPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction policy: %w", err)
+	}
+	var p RedactionPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction policy: %w", err)
+	}
+	if err := p.Compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Compile validates the policy and compiles its custom patterns
+func (p *RedactionPolicy) Compile() error {
+	p.rules = make(map[string]*redactionRule, len(builtinRedactionRules)+len(p.Patterns))
+	for name, rule := range builtinRedactionRules {
+		p.rules[name] = rule
+	}
+	for name, pattern := range p.Patterns {
+		if _, builtin := builtinRedactionRules[name]; builtin {
+			return fmt.Errorf("redaction pattern %q shadows a built-in rule", name)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", name, err)
+		}
+		p.rules[name] = &redactionRule{name: name, re: re}
+	}
+
+	check := func(names []string) error {
+		for _, name := range names {
+			if p.rules[name] == nil {
+				return fmt.Errorf("unknown redaction rule %q", name)
+			}
+		}
+		return nil
+	}
+	if err := check(p.Default); err != nil {
+		return err
+	}
+	for _, names := range p.Namespaces {
+		if err := check(names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rulesFor returns the rules applying to a node ID's namespace
+func (p *RedactionPolicy) rulesFor(id string) []*redactionRule {
+	names, ok := p.Namespaces[Namespace(id)]
+	if !ok {
+		names = p.Default
+	}
+	rules := make([]*redactionRule, 0, len(names))
+	for _, name := range names {
+		rules = append(rules, p.rules[name])
+	}
+	return rules
+}
+
+// redactNode masks a node's text content and string properties in place and
+// flags it when anything was redacted
+func (p *RedactionPolicy) redactNode(node *core.Node) {
+	r := redactor{rules: p.rulesFor(node.ID)}
+	if len(r.rules) == 0 {
+		return
+	}
+	// Binary content such as screenshots is left alone
+	if len(node.Content) > 0 && utf8.Valid(node.Content) {
+		if s := r.text(string(node.Content)); len(r.fired) > 0 {
+			node.Content = []byte(s)
+		}
+	}
+	node.Meta = r.meta(node.Meta)
+	if len(r.fired) > 0 {
+		if node.Meta == nil {
+			node.Meta = make(map[string]interface{})
+		}
+		node.Meta[RedactedKey] = true
+	}
+}
+
+// redactMeta masks string values in a property update, flagging the node
+// when anything was redacted
+func (p *RedactionPolicy) redactMeta(id string, meta map[string]any) map[string]any {
+	r := redactor{rules: p.rulesFor(id)}
+	if len(r.rules) == 0 {
+		return meta
+	}
+	out := r.meta(meta)
+	if len(r.fired) > 0 {
+		out[RedactedKey] = true
+	}
+	return out
+}
+
+// redactor applies rules and records which fired
+type redactor struct {
+	rules []*redactionRule
+	fired map[string]bool
+}
+
+func (r *redactor) text(s string) string {
+	for _, rule := range r.rules {
+		s = rule.re.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.valid != nil && !rule.valid(match) {
+				return match
+			}
+			if r.fired == nil {
+				r.fired = make(map[string]bool)
+			}
+			r.fired[rule.name] = true
+			return "[REDACTED:" + rule.name + "]"
+		})
+	}
+	return s
+}
+
+// meta returns a copy of meta with string values masked, recursing into
+// nested maps and lists
+func (r *redactor) meta(meta map[string]interface{}) map[string]interface{} {
+	if meta == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		out[k] = r.value(v)
+	}
+	return out
+}
+
+func (r *redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.text(v)
+	case []string:
+		out := make([]string, len(v))
+		for i, s := range v {
+			out[i] = r.text(s)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = r.value(e)
+		}
+		return out
+	case map[string]interface{}:
+		return r.meta(v)
+	default:
+		return v
+	}
+}
+
+// luhnValid reports whether a digit string (spaces and dashes allowed)
+// passes the Luhn checksum used by card numbers
+func luhnValid(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	sum, double := 0, false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// redactingRepository masks sensitive text in nodes before they are stored
+type redactingRepository struct {
+	Repository
+	policy *RedactionPolicy
+}
+
+// WithRedaction wraps a repository so node content and properties pass
+// through the policy's redaction rules before storage
+func WithRedaction(repo Repository, policy *RedactionPolicy) Repository {
+	return &redactingRepository{Repository: repo, policy: policy}
+}
+
+func (r *redactingRepository) CreateNode(ctx context.Context, node *core.Node) error {
+	r.policy.redactNode(node)
+	return r.Repository.CreateNode(ctx, node)
+}
+
+func (r *redactingRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	for _, node := range nodes {
+		r.policy.redactNode(node)
+	}
+	return r.Repository.CreateNodes(ctx, nodes)
+}
+
+func (r *redactingRepository) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	return r.Repository.UpdateNodeMeta(ctx, id, r.policy.redactMeta(id, meta))
+}
+
+func (r *redactingRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	return r.Repository.UpdateNodeMetaWithNote(ctx, id, r.policy.redactMeta(id, meta), changeNote, changedBy)
+}
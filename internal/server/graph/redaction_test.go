@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestRedaction(t *testing.T) {
+	ctx := context.Background()
+	policy := &RedactionPolicy{
+		Default:    []string{RedactEmail, RedactCreditCard, RedactAPIKey, "employee_id"},
+		Namespaces: map[string][]string{"public": {}},
+		Patterns:   map[string]string{"employee_id": `EMP-\d{6}`},
+	}
+	if err := policy.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	repo := WithRedaction(NewMemory(), policy)
+
+	text := "Mail ada@example.com, card 4111 1111 1111 1111, order 1234 5678 9012 3456, key sk-abcdefghijklmnopqrstuvwx, badge EMP-123456"
+	if err := repo.CreateNode(ctx, &core.Node{ID: "sha256:abc", Type: "Source", Content: []byte(text),
+		Meta: map[string]interface{}{"ocr": []interface{}{"from bob@example.org"}, "pages": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	node, err := repo.GetNode(ctx, "sha256:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Mail [REDACTED:email], card [REDACTED:credit_card], order 1234 5678 9012 3456, key [REDACTED:api_key], badge [REDACTED:employee_id]"
+	if string(node.Content) != want {
+		t.Errorf("content = %q, want %q", node.Content, want)
+	}
+	if node.Meta[RedactedKey] != true {
+		t.Errorf("expected redacted flag, got %v", node.Meta)
+	}
+	if ocr, _ := node.Meta["ocr"].([]interface{}); len(ocr) != 1 || ocr[0] != "from [REDACTED:email]" {
+		t.Errorf("nested meta not redacted: %v", node.Meta["ocr"])
+	}
+
+	// Namespaces with an empty rule list and binary content are stored as-is
+	binary := []byte{0xff, 0xfe, 'a', '@', 'b', '.', 'c', 'o'}
+	for _, n := range []*core.Node{
+		{ID: "public:1", Type: "Note", Content: []byte("ada@example.com")},
+		{ID: "shot:1", Type: "Screenshot", Content: binary},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := repo.GetNode(ctx, n.ID)
+		if string(got.Content) != string(n.Content) || got.Meta[RedactedKey] != nil {
+			t.Errorf("%s should not be redacted: %q %v", n.ID, got.Content, got.Meta)
+		}
+	}
+
+	// OCR or caption output written later is redacted too
+	if err := repo.UpdateNodeMetaWithNote(ctx, "shot:1", map[string]any{"caption": "Slack from ada@example.com"}, "Image captioned", "vision"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := repo.GetNode(ctx, "shot:1")
+	if !strings.Contains(got.Meta["caption"].(string), "[REDACTED:email]") || got.Meta[RedactedKey] != true {
+		t.Errorf("caption not redacted: %v", got.Meta)
+	}
+
+	bad := &RedactionPolicy{Default: []string{"phone"}}
+	if err := bad.Compile(); err == nil {
+		t.Error("expected unknown rule to be rejected")
+	}
+}
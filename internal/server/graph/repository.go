@@ -9,7 +9,7 @@ import (
 )
 
 // Repository defines the interface for graph storage backends.
-// Both SQLite and Neo4j implement this interface.
+// SQLite, Neo4j, Postgres and the in-memory backend implement this interface.
 type Repository interface {
 	// Lifecycle
 	Close(ctx context.Context) error
@@ -20,16 +20,25 @@ type Repository interface {
 	CreateNode(ctx context.Context, node *core.Node) error
 	GetNode(ctx context.Context, id string) (*core.Node, error)
 	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error)
 	SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error)
+	SearchSimilar(ctx context.Context, term string, limit int) ([]*SimilarNode, error)
 	FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error)
 	TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error)
+	QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error)
+	MatchPattern(ctx context.Context, pattern *Pattern, limit int) ([]PatternBinding, error)
 
 	// Link operations
 	CreateLink(ctx context.Context, link *core.Link) error
 	DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error
 
+	// Bulk operations (batched writes for imports)
+	CreateNodes(ctx context.Context, nodes []*core.Node) error
+	CreateLinks(ctx context.Context, links []*core.Link) error
+
 	// Node listing
 	ListNodes(ctx context.Context) ([]string, error)
+	ListDeleted(ctx context.Context, limit int, offset int) ([]*core.Node, error)
 
 	// Version operations
 	GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error)
@@ -51,6 +60,12 @@ type Repository interface {
 	// Graph exploration
 	GetGraphMap(ctx context.Context, sampleSize int) (*GraphMap, error)
 	GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error)
+	GetGraphSnapshot(ctx context.Context, nodeTypes []string, limit int) (*Subgraph, error)
+	GetTimeline(ctx context.Context, from, to time.Time, bucket string, nodeTypes []string, sampleSize int) (*Timeline, error)
+	DiffGraph(ctx context.Context, from, to time.Time, detailed bool) (*GraphDiff, error)
+	GetUsage(ctx context.Context) (*Usage, error)
+	CheckIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error)
+	RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error)
 
 	// Lens operations
 	GetEntitiesInterpretedThrough(ctx context.Context, lensID string) ([]*core.Node, error)
@@ -63,6 +78,7 @@ type Repository interface {
 	UpdateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error
 	DeleteSubscriptionNode(ctx context.Context, id string) error
 	LoadSubscriptions(ctx context.Context) ([]*subscriptions.Subscription, error)
+	ChangeLog(ctx context.Context, since time.Time, limit int) ([]subscriptions.Event, error)
 
 	// Raw query (Neo4j only - SQLite returns error)
 	ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error)
@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Revision counts changes to the graph so clients can cheaply detect
+// whether derived views (map, subgraphs) may have changed
+type Revision struct {
+	epoch int64 // Distinguishes counters across server restarts
+	n     atomic.Int64
+}
+
+// Current returns an opaque token identifying the current graph state
+func (r *Revision) Current() string {
+	return fmt.Sprintf("%x-%d", r.epoch, r.n.Load())
+}
+
+func (r *Revision) bump() {
+	r.n.Add(1)
+}
+
+// revisionRepository bumps a Revision on every write
+type revisionRepository struct {
+	Repository
+	revision *Revision
+
+	emitterMu sync.RWMutex
+	emitter   func(subscriptions.Event)
+}
+
+// WithRevision wraps repo so every change bumps the returned Revision
+func WithRevision(repo Repository) (Repository, *Revision) {
+	rr := &revisionRepository{
+		Repository: repo,
+		revision:   &Revision{epoch: time.Now().Unix()},
+	}
+	repo.SetEventEmitter(rr.onEvent)
+	return rr, rr.revision
+}
+
+// SetEventEmitter forwards events to emitter after bumping the revision
+func (rr *revisionRepository) SetEventEmitter(emitter func(subscriptions.Event)) {
+	rr.emitterMu.Lock()
+	rr.emitter = emitter
+	rr.emitterMu.Unlock()
+}
+
+func (rr *revisionRepository) onEvent(event subscriptions.Event) {
+	rr.revision.bump()
+
+	rr.emitterMu.RLock()
+	emitter := rr.emitter
+	rr.emitterMu.RUnlock()
+	if emitter != nil {
+		emitter(event)
+	}
+}
+
+// Writes that change graph structure without emitting events
+
+func (rr *revisionRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	defer rr.revision.bump()
+	return rr.Repository.UpdateAttentionEdge(ctx, source, target, queryID, weight)
+}
+
+func (rr *revisionRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight float64, minQueryCount int) (int, error) {
+	defer rr.revision.bump()
+	return rr.Repository.PruneWeakAttentionEdges(ctx, minWeight, minQueryCount)
+}
+
+func (rr *revisionRepository) RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error) {
+	if !opts.DryRun {
+		defer rr.revision.bump()
+	}
+	return rr.Repository.RecomputeDegrees(ctx, opts)
+}
+
+func (rr *revisionRepository) CreateInterpretedThroughLink(ctx context.Context, entityID, lensID string, meta map[string]interface{}) error {
+	defer rr.revision.bump()
+	return rr.Repository.CreateInterpretedThroughLink(ctx, entityID, lensID, meta)
+}
@@ -0,0 +1,566 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// ErrOutOfScope is returned when a scoped request writes outside its
+// scope, or asks for a graph-wide view its scope cannot be applied to
+var ErrOutOfScope = errors.New("outside access scope")
+
+// Scope limits what a request may see and change: nodes in the given
+// namespaces and of the given types (empty means any), read-only unless
+// Write is set. Lane only sets the request's traffic class.
+type Scope struct {
+	Namespaces []string `json:"namespaces,omitempty"`
+	Types      []string `json:"types,omitempty"`
+	Write      bool     `json:"write"`
+	Lane       string   `json:"lane,omitempty"` // Traffic lane for the token's requests, e.g. bulk
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context whose repository calls are limited to scope
+func WithScope(ctx context.Context, scope *Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope of a context, or nil for unlimited access
+func ScopeFrom(ctx context.Context) *Scope {
+	scope, _ := ctx.Value(scopeKey{}).(*Scope)
+	return scope
+}
+
+// Restricted reports whether the scope limits namespaces or types
+func (s *Scope) Restricted() bool {
+	return len(s.Namespaces) > 0 || len(s.Types) > 0
+}
+
+// AllowsID reports whether a node ID is in the scope's namespaces
+func (s *Scope) AllowsID(id string) bool {
+	return len(s.Namespaces) == 0 || contains(s.Namespaces, Namespace(id))
+}
+
+// Allows reports whether a node is in scope
+func (s *Scope) Allows(node *core.Node) bool {
+	return node != nil && s.AllowsID(node.ID) && (len(s.Types) == 0 || contains(s.Types, node.Type))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedRepository applies the scope carried by a request's context:
+// nodes outside it read as missing and cannot be written, and graph-wide
+// views are refused to restricted scopes. Contexts without a scope pass
+// straight through.
+type scopedRepository struct {
+	Repository
+}
+
+// WithScopes wraps repo so that calls made with a context from WithScope
+// stay within that scope
+func WithScopes(repo Repository) Repository {
+	return &scopedRepository{Repository: repo}
+}
+
+// allowsID reports whether the node with id is in scope, looking its type
+// up when the scope limits types
+func (r *scopedRepository) allowsID(ctx context.Context, scope *Scope, id string) bool {
+	if !scope.AllowsID(id) {
+		return false
+	}
+	if len(scope.Types) == 0 {
+		return true
+	}
+	node, err := r.Repository.GetNode(ctx, id)
+	return err == nil && scope.Allows(node)
+}
+
+// checkWrite returns ErrOutOfScope unless scope may write the given nodes
+func (r *scopedRepository) checkWrite(ctx context.Context, scope *Scope, ids ...string) error {
+	if !scope.Write {
+		return fmt.Errorf("%w: read-only access", ErrOutOfScope)
+	}
+	for _, id := range ids {
+		if !r.allowsID(ctx, scope, id) {
+			return fmt.Errorf("%w: %s", ErrOutOfScope, id)
+		}
+	}
+	return nil
+}
+
+// checkView refuses graph-wide views to restricted scopes
+func checkView(scope *Scope, view string) error {
+	if scope != nil && scope.Restricted() {
+		return fmt.Errorf("%w: %s is not available to scoped access", ErrOutOfScope, view)
+	}
+	return nil
+}
+
+// filterNodes keeps the nodes in scope
+func filterNodes(scope *Scope, nodes []*core.Node) []*core.Node {
+	kept := make([]*core.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if scope.Allows(n) {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// scopedPage returns the limit in-scope nodes after offset, reading
+// further pages from fetch so nodes outside the scope don't leave a page
+// short
+func scopedPage(scope *Scope, limit, offset int, fetch PageFunc) ([]*core.Node, error) {
+	return FillPage(limit, offset, func(nodes []*core.Node) []*core.Node { return filterNodes(scope, nodes) }, fetch)
+}
+
+// filterLinks keeps the links whose other end is in scope
+func (r *scopedRepository) filterLinks(ctx context.Context, scope *Scope, links []*core.Link, outgoing bool) []*core.Link {
+	kept := make([]*core.Link, 0, len(links))
+	for _, l := range links {
+		other := l.Source
+		if outgoing {
+			other = l.Target
+		}
+		if r.allowsID(ctx, scope, other) {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// filterSubgraph keeps the nodes in scope and the edges between them
+func filterSubgraph(scope *Scope, sg *Subgraph) *Subgraph {
+	if sg == nil {
+		return nil
+	}
+	out := &Subgraph{Nodes: filterNodes(scope, sg.Nodes), Edges: []*SubgraphEdge{}, Stats: sg.Stats}
+	kept := make(map[string]bool, len(out.Nodes))
+	for _, n := range out.Nodes {
+		kept[n.ID] = true
+	}
+	for _, e := range sg.Edges {
+		if kept[e.Source] && kept[e.Target] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	out.Stats.NodeCount = len(out.Nodes)
+	out.Stats.EdgeCount = len(out.Edges)
+	return out
+}
+
+func (r *scopedRepository) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	node, err := r.Repository.GetNode(ctx, id)
+	if scope := ScopeFrom(ctx); err == nil && scope != nil && !scope.Allows(node) {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	return node, err
+}
+
+func (r *scopedRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return r.Repository.GetLinks(ctx, nodeID)
+	}
+	if !r.allowsID(ctx, scope, nodeID) {
+		return []*core.Link{}, nil
+	}
+	links, err := r.Repository.GetLinks(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return r.filterLinks(ctx, scope, links, true), nil
+}
+
+func (r *scopedRepository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return r.Repository.GetBacklinks(ctx, nodeID)
+	}
+	if !r.allowsID(ctx, scope, nodeID) {
+		return []*core.Link{}, nil
+	}
+	links, err := r.Repository.GetBacklinks(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return r.filterLinks(ctx, scope, links, false), nil
+}
+
+func (r *scopedRepository) SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return r.Repository.SearchNodes(ctx, searchTerm, limit, offset)
+	}
+	return scopedPage(scope, limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return r.Repository.SearchNodes(ctx, searchTerm, limit, offset)
+	})
+}
+
+// SearchSimilar keeps the suggestions in scope; it asks for more so that
+// filtering still leaves up to limit
+func (r *scopedRepository) SearchSimilar(ctx context.Context, term string, limit int) ([]*SimilarNode, error) {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return r.Repository.SearchSimilar(ctx, term, limit)
+	}
+	nodes, err := r.Repository.SearchSimilar(ctx, term, maxSimilarCandidates)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]*SimilarNode, 0, len(nodes))
+	for _, n := range nodes {
+		if scope.Allows(n.Node) && len(kept) < limit {
+			kept = append(kept, n)
+		}
+	}
+	return kept, nil
+}
+
+func (r *scopedRepository) FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error) {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return r.Repository.FilterNodes(ctx, nodeTypes, propertyKey, propertyValue, limit, offset)
+	}
+	return scopedPage(scope, limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return r.Repository.FilterNodes(ctx, nodeTypes, propertyKey, propertyValue, limit, offset)
+	})
+}
+
+func (r *scopedRepository) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
+	nodes, err := r.Repository.TraverseGraph(ctx, startNodeID, depth, relationshipTypes, limit, offset)
+	if scope := ScopeFrom(ctx); err == nil && scope != nil {
+		for id, n := range nodes {
+			if !scope.Allows(n) {
+				delete(nodes, id)
+			}
+		}
+	}
+	return nodes, err
+}
+
+func (r *scopedRepository) QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error) {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return r.Repository.QueryTimeRange(ctx, from, to, nodeTypes, limit, offset)
+	}
+	return scopedPage(scope, limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return r.Repository.QueryTimeRange(ctx, from, to, nodeTypes, limit, offset)
+	})
+}
+
+func (r *scopedRepository) MatchPattern(ctx context.Context, pattern *Pattern, limit int) ([]PatternBinding, error) {
+	bindings, err := r.Repository.MatchPattern(ctx, pattern, limit)
+	scope := ScopeFrom(ctx)
+	if err != nil || scope == nil {
+		return bindings, err
+	}
+	kept := make([]PatternBinding, 0, len(bindings))
+	for _, b := range bindings {
+		allowed := true
+		for _, id := range b {
+			if !r.allowsID(ctx, scope, id) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			kept = append(kept, b)
+		}
+	}
+	return kept, nil
+}
+
+func (r *scopedRepository) ListNodes(ctx context.Context) ([]string, error) {
+	ids, err := r.Repository.ListNodes(ctx)
+	scope := ScopeFrom(ctx)
+	if err != nil || scope == nil {
+		return ids, err
+	}
+	kept := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if r.allowsID(ctx, scope, id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
+func (r *scopedRepository) ListDeleted(ctx context.Context, limit int, offset int) ([]*core.Node, error) {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return r.Repository.ListDeleted(ctx, limit, offset)
+	}
+	return scopedPage(scope, limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return r.Repository.ListDeleted(ctx, limit, offset)
+	})
+}
+
+func (r *scopedRepository) GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error) {
+	node, err := r.Repository.GetNodeAtVersion(ctx, id, version)
+	if scope := ScopeFrom(ctx); err == nil && scope != nil && !scope.Allows(node) {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	return node, err
+}
+
+func (r *scopedRepository) GetNodeAtTime(ctx context.Context, id string, asOf time.Time) (*core.Node, error) {
+	node, err := r.Repository.GetNodeAtTime(ctx, id, asOf)
+	if scope := ScopeFrom(ctx); err == nil && scope != nil && !scope.Allows(node) {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	return node, err
+}
+
+func (r *scopedRepository) GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error) {
+	if scope := ScopeFrom(ctx); scope != nil && !r.allowsID(WithDeleted(ctx), scope, id) {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	return r.Repository.GetNodeHistory(ctx, id)
+}
+
+func (r *scopedRepository) CreateNode(ctx context.Context, node *core.Node) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if !scope.Write {
+			return fmt.Errorf("%w: read-only access", ErrOutOfScope)
+		}
+		if !scope.Allows(node) {
+			return fmt.Errorf("%w: %s (%s)", ErrOutOfScope, node.ID, node.Type)
+		}
+	}
+	return r.Repository.CreateNode(ctx, node)
+}
+
+func (r *scopedRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if !scope.Write {
+			return fmt.Errorf("%w: read-only access", ErrOutOfScope)
+		}
+		for _, node := range nodes {
+			if !scope.Allows(node) {
+				return fmt.Errorf("%w: %s (%s)", ErrOutOfScope, node.ID, node.Type)
+			}
+		}
+	}
+	return r.Repository.CreateNodes(ctx, nodes)
+}
+
+func (r *scopedRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if err := r.checkWrite(ctx, scope, link.Source, link.Target); err != nil {
+			return err
+		}
+	}
+	return r.Repository.CreateLink(ctx, link)
+}
+
+func (r *scopedRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		for _, link := range links {
+			if err := r.checkWrite(ctx, scope, link.Source, link.Target); err != nil {
+				return err
+			}
+		}
+	}
+	return r.Repository.CreateLinks(ctx, links)
+}
+
+func (r *scopedRepository) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if err := r.checkWrite(ctx, scope, sourceID, targetID); err != nil {
+			return err
+		}
+	}
+	return r.Repository.DeleteLink(ctx, sourceID, targetID, linkType)
+}
+
+func (r *scopedRepository) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if err := r.checkWrite(ctx, scope, id); err != nil {
+			return err
+		}
+	}
+	return r.Repository.UpdateNodeMeta(ctx, id, meta)
+}
+
+func (r *scopedRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if err := r.checkWrite(ctx, scope, id); err != nil {
+			return err
+		}
+	}
+	return r.Repository.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy)
+}
+
+func (r *scopedRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if err := r.checkWrite(ctx, scope, nodeID); err != nil {
+			return err
+		}
+	}
+	return r.Repository.DeleteNode(ctx, nodeID, force)
+}
+
+func (r *scopedRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if err := r.checkWrite(ctx, scope, source, target); err != nil {
+			return err
+		}
+	}
+	return r.Repository.UpdateAttentionEdge(ctx, source, target, queryID, weight)
+}
+
+func (r *scopedRepository) GetAttentionSubgraph(ctx context.Context, startNodeID string, minWeight float64, maxNodes int) (*Subgraph, error) {
+	sg, err := r.Repository.GetAttentionSubgraph(ctx, startNodeID, minWeight, maxNodes)
+	if scope := ScopeFrom(ctx); err == nil && scope != nil {
+		sg = filterSubgraph(scope, sg)
+	}
+	return sg, err
+}
+
+func (r *scopedRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight float64, minQueryCount int) (int, error) {
+	if ScopeFrom(ctx) != nil {
+		return 0, fmt.Errorf("%w: pruning attention edges", ErrOutOfScope)
+	}
+	return r.Repository.PruneWeakAttentionEdges(ctx, minWeight, minQueryCount)
+}
+
+func (r *scopedRepository) GetGraphMap(ctx context.Context, sampleSize int) (*GraphMap, error) {
+	if err := checkView(ScopeFrom(ctx), "the graph map"); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetGraphMap(ctx, sampleSize)
+}
+
+func (r *scopedRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
+	sg, err := r.Repository.GetSubgraph(ctx, startNodeID, depth, relationshipTypes)
+	if scope := ScopeFrom(ctx); err == nil && scope != nil {
+		sg = filterSubgraph(scope, sg)
+	}
+	return sg, err
+}
+
+func (r *scopedRepository) GetGraphSnapshot(ctx context.Context, nodeTypes []string, limit int) (*Subgraph, error) {
+	sg, err := r.Repository.GetGraphSnapshot(ctx, nodeTypes, limit)
+	if scope := ScopeFrom(ctx); err == nil && scope != nil {
+		sg = filterSubgraph(scope, sg)
+	}
+	return sg, err
+}
+
+func (r *scopedRepository) GetTimeline(ctx context.Context, from, to time.Time, bucket string, nodeTypes []string, sampleSize int) (*Timeline, error) {
+	if err := checkView(ScopeFrom(ctx), "the timeline"); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetTimeline(ctx, from, to, bucket, nodeTypes, sampleSize)
+}
+
+func (r *scopedRepository) DiffGraph(ctx context.Context, from, to time.Time, detailed bool) (*GraphDiff, error) {
+	if err := checkView(ScopeFrom(ctx), "the graph diff"); err != nil {
+		return nil, err
+	}
+	return r.Repository.DiffGraph(ctx, from, to, detailed)
+}
+
+func (r *scopedRepository) GetUsage(ctx context.Context) (*Usage, error) {
+	if err := checkView(ScopeFrom(ctx), "usage"); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetUsage(ctx)
+}
+
+func (r *scopedRepository) CheckIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	if err := checkView(ScopeFrom(ctx), "the integrity check"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CheckIntegrity(ctx, opts)
+}
+
+func (r *scopedRepository) RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error) {
+	if ScopeFrom(ctx) != nil {
+		return nil, fmt.Errorf("%w: recomputing degrees", ErrOutOfScope)
+	}
+	return r.Repository.RecomputeDegrees(ctx, opts)
+}
+
+func (r *scopedRepository) GetEntitiesInterpretedThrough(ctx context.Context, lensID string) ([]*core.Node, error) {
+	nodes, err := r.Repository.GetEntitiesInterpretedThrough(ctx, lensID)
+	if scope := ScopeFrom(ctx); err == nil && scope != nil {
+		nodes = filterNodes(scope, nodes)
+	}
+	return nodes, err
+}
+
+func (r *scopedRepository) CreateInterpretedThroughLink(ctx context.Context, entityID, lensID string, meta map[string]interface{}) error {
+	if scope := ScopeFrom(ctx); scope != nil {
+		if err := r.checkWrite(ctx, scope, entityID, lensID); err != nil {
+			return err
+		}
+	}
+	return r.Repository.CreateInterpretedThroughLink(ctx, entityID, lensID, meta)
+}
+
+func (r *scopedRepository) QueryByLens(ctx context.Context, lensID string, pattern string, limit int, offset int) ([]*core.Node, error) {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return r.Repository.QueryByLens(ctx, lensID, pattern, limit, offset)
+	}
+	return scopedPage(scope, limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return r.Repository.QueryByLens(ctx, lensID, pattern, limit, offset)
+	})
+}
+
+func (r *scopedRepository) ExportLens(ctx context.Context, lensID string, includeExtractedFrom bool) (*LensExport, error) {
+	if err := checkView(ScopeFrom(ctx), "lens export"); err != nil {
+		return nil, err
+	}
+	return r.Repository.ExportLens(ctx, lensID, includeExtractedFrom)
+}
+
+func (r *scopedRepository) CreateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
+	if ScopeFrom(ctx) != nil {
+		return fmt.Errorf("%w: subscriptions", ErrOutOfScope)
+	}
+	return r.Repository.CreateSubscriptionNode(ctx, sub)
+}
+
+func (r *scopedRepository) UpdateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
+	if ScopeFrom(ctx) != nil {
+		return fmt.Errorf("%w: subscriptions", ErrOutOfScope)
+	}
+	return r.Repository.UpdateSubscriptionNode(ctx, sub)
+}
+
+func (r *scopedRepository) DeleteSubscriptionNode(ctx context.Context, id string) error {
+	if ScopeFrom(ctx) != nil {
+		return fmt.Errorf("%w: subscriptions", ErrOutOfScope)
+	}
+	return r.Repository.DeleteSubscriptionNode(ctx, id)
+}
+
+func (r *scopedRepository) ChangeLog(ctx context.Context, since time.Time, limit int) ([]subscriptions.Event, error) {
+	if err := checkView(ScopeFrom(ctx), "the change log"); err != nil {
+		return nil, err
+	}
+	return r.Repository.ChangeLog(ctx, since, limit)
+}
+
+func (r *scopedRepository) ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	if err := checkView(ScopeFrom(ctx), "raw queries"); err != nil {
+		return nil, err
+	}
+	return r.Repository.ExecuteCypherRead(ctx, cypher, params)
+}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestScopes(t *testing.T) {
+	ctx := context.Background()
+	repo := WithScopes(NewMemory())
+	for _, n := range []*core.Node{
+		{ID: "research:a", Type: "Note", Content: []byte("agent notes")},
+		{ID: "research:b", Type: "Person", Content: []byte("agent person")},
+		{ID: "private:c", Type: "Note", Content: []byte("agent secret")},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "research:a", Target: "research:b", Type: "MENTIONS"},
+		{Source: "research:a", Target: "private:c", Type: "MENTIONS"},
+	} {
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := WithScope(ctx, &Scope{Namespaces: []string{"research"}})
+	if _, err := repo.GetNode(read, "private:c"); err == nil {
+		t.Error("GetNode() returned a node outside the scope")
+	}
+	if nodes, _ := repo.SearchNodes(read, "agent", 10, 0); len(nodes) != 2 {
+		t.Errorf("SearchNodes() = %d nodes, want 2", len(nodes))
+	}
+	if links, _ := repo.GetLinks(read, "research:a"); len(links) != 1 || links[0].Target != "research:b" {
+		t.Errorf("GetLinks() = %+v", links)
+	}
+	if err := repo.UpdateNodeMeta(read, "research:a", map[string]any{"x": 1}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("read-only UpdateNodeMeta() error = %v", err)
+	}
+	if _, err := repo.GetGraphMap(read, 10); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("GetGraphMap() error = %v", err)
+	}
+
+	write := WithScope(ctx, &Scope{Namespaces: []string{"research"}, Types: []string{"Note"}, Write: true})
+	if err := repo.UpdateNodeMeta(write, "research:a", map[string]any{"x": 1}); err != nil {
+		t.Errorf("UpdateNodeMeta() in scope error = %v", err)
+	}
+	if err := repo.UpdateNodeMeta(write, "research:b", map[string]any{"x": 1}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("UpdateNodeMeta() on another type error = %v", err)
+	}
+	if err := repo.CreateNode(write, &core.Node{ID: "private:d", Type: "Note"}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("CreateNode() in another namespace error = %v", err)
+	}
+	if err := repo.DeleteNode(write, "private:c", true); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("DeleteNode() outside the scope error = %v", err)
+	}
+
+	// Pages are filled past out-of-scope nodes
+	var private []*core.Node
+	for i := 0; i < fillPageSize+10; i++ {
+		private = append(private, &core.Node{ID: fmt.Sprintf("private:%04d", i), Type: "Note"})
+	}
+	if err := repo.CreateNodes(ctx, private); err != nil {
+		t.Fatal(err)
+	}
+	if nodes, _ := repo.FilterNodes(read, []string{"Note", "Person"}, "", "", 2, 0); len(nodes) != 2 {
+		t.Errorf("FilterNodes() = %d nodes, want 2", len(nodes))
+	}
+	if nodes, _ := repo.FilterNodes(read, []string{"Note", "Person"}, "", "", 10, 1); len(nodes) != 1 || Namespace(nodes[0].ID) != "research" {
+		t.Errorf("FilterNodes(offset 1) = %v", nodes)
+	}
+
+	// Without a scope nothing is filtered
+	if nodes, _ := repo.SearchNodes(ctx, "agent", 10, 0); len(nodes) != 3 {
+		t.Errorf("unscoped SearchNodes() = %d nodes, want 3", len(nodes))
+	}
+}
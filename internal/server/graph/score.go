@@ -0,0 +1,195 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// ScoreWeights weighs the signals combined into a node's score
+type ScoreWeights struct {
+	Recency   float64 `json:"recency,omitempty"`
+	Degree    float64 `json:"degree,omitempty"`
+	Attention float64 `json:"attention,omitempty"`
+	Trust     float64 `json:"trust,omitempty"`
+	Text      float64 `json:"text,omitempty"` // Needs a query
+}
+
+// ScoreSpec says how to score nodes. The score is the weighted mean of the
+// signals, each in [0, 1].
+type ScoreSpec struct {
+	Weights      ScoreWeights `json:"weights"`
+	Query        string       `json:"query,omitempty"`          // For text relevance
+	HalfLifeDays float64      `json:"half_life_days,omitempty"` // Recency halves every this many days since the last change; default 30
+}
+
+// NodeScore is one node's combined score, its signals and the raw values
+// they came from
+type NodeScore struct {
+	ID      string             `json:"id"`
+	Score   float64            `json:"score"`
+	Signals map[string]float64 `json:"signals"`
+	Raw     map[string]float64 `json:"raw"`
+}
+
+// Validate checks the weights are usable
+func (s *ScoreSpec) Validate() error {
+	w := s.Weights
+	if w.Recency < 0 || w.Degree < 0 || w.Attention < 0 || w.Trust < 0 || w.Text < 0 || s.HalfLifeDays < 0 {
+		return errors.New("weights and half_life_days must not be negative")
+	}
+	if w.Recency+w.Degree+w.Attention+w.Trust+w.Text == 0 {
+		return errors.New("at least one weight must be positive")
+	}
+	if w.Text > 0 && len(SearchWords(s.Query)) == 0 {
+		return errors.New("a text weight needs a query")
+	}
+	return nil
+}
+
+// ScoreNodes scores nodes by the spec, highest first, with trust from the
+// policy. Degree and attention are scaled by the largest among the nodes
+// scored, so scores compare within one call. IDs that are not found are
+// returned separately.
+func ScoreNodes(ctx context.Context, repo Repository, policy *TrustPolicy, ids []string, spec ScoreSpec, now time.Time) ([]NodeScore, []string, error) {
+	halfLife := spec.HalfLifeDays
+	if halfLife <= 0 {
+		halfLife = 30
+	}
+	queryWords := uniqueWords(SearchWords(spec.Query))
+
+	var scores []NodeScore
+	var missing []string
+	seen := make(map[string]bool)
+	memo := make(map[string]float64)
+	maxDegree, maxAttention := 0.0, 0.0
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		node, err := repo.GetNode(ctx, id)
+		if err != nil || node == nil {
+			missing = append(missing, id)
+			continue
+		}
+		raw := map[string]float64{"age_days": math.Max(now.Sub(node.Modified).Hours()/24, 0)}
+		if spec.Weights.Degree > 0 || spec.Weights.Attention > 0 {
+			degree, attention, err := nodeConnections(ctx, repo, id)
+			if err != nil {
+				return nil, nil, fmt.Errorf("scoring %s: %w", id, err)
+			}
+			raw["degree"], raw["attention"] = degree, attention
+			maxDegree, maxAttention = math.Max(maxDegree, degree), math.Max(maxAttention, attention)
+		}
+		if spec.Weights.Trust > 0 {
+			t, err := policy.effectiveTrust(ctx, repo, id, memo, make(map[string]bool))
+			if err != nil {
+				return nil, nil, fmt.Errorf("scoring %s: %w", id, err)
+			}
+			raw["trust"] = t
+		}
+		if len(queryWords) > 0 {
+			raw["text_matches"] = float64(matchedWords(node, queryWords))
+		}
+		scores = append(scores, NodeScore{ID: id, Raw: raw})
+	}
+
+	w := spec.Weights
+	total := w.Recency + w.Degree + w.Attention + w.Trust + w.Text
+	for i := range scores {
+		raw := scores[i].Raw
+		signals := make(map[string]float64)
+		if w.Recency > 0 {
+			signals["recency"] = math.Pow(0.5, raw["age_days"]/halfLife)
+		}
+		if w.Degree > 0 {
+			signals["degree"] = scaled(math.Log1p(raw["degree"]), math.Log1p(maxDegree))
+		}
+		if w.Attention > 0 {
+			signals["attention"] = scaled(raw["attention"], maxAttention)
+		}
+		if w.Trust > 0 {
+			signals["trust"] = raw["trust"]
+		}
+		if w.Text > 0 {
+			signals["text"] = raw["text_matches"] / float64(len(queryWords))
+		}
+		score := w.Recency*signals["recency"] + w.Degree*signals["degree"] + w.Attention*signals["attention"] +
+			w.Trust*signals["trust"] + w.Text*signals["text"]
+		scores[i].Score = score / total
+		scores[i].Signals = signals
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores, missing, nil
+}
+
+// nodeConnections counts a node's links, other than attention edges, in
+// both directions, and sums the weights of its attention edges
+func nodeConnections(ctx context.Context, repo Repository, id string) (degree, attention float64, err error) {
+	links, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	backlinks, err := repo.GetBacklinks(ctx, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, l := range append(links, backlinks...) {
+		if l.Type == "ATTENDED" {
+			attention += l.Weight
+		} else {
+			degree++
+		}
+	}
+	return degree, attention, nil
+}
+
+// matchedWords counts the query words found in a node's ID, string
+// properties and content
+func matchedWords(node *core.Node, queryWords []string) int {
+	parts := []string{node.ID, string(node.Content)}
+	for _, v := range node.Meta {
+		if s, ok := v.(string); ok {
+			parts = append(parts, s)
+		}
+	}
+	words := make(map[string]bool)
+	for _, w := range SearchWords(strings.Join(parts, "\n")) {
+		words[w] = true
+	}
+	n := 0
+	for _, w := range queryWords {
+		if words[w] {
+			n++
+		}
+	}
+	return n
+}
+
+// uniqueWords drops repeated words, keeping the first of each
+func uniqueWords(words []string) []string {
+	seen := make(map[string]bool, len(words))
+	out := words[:0]
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// scaled divides v by top, or is 0 when top is
+func scaled(v, top float64) float64 {
+	if top <= 0 {
+		return 0
+	}
+	return v / top
+}
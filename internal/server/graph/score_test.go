@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestScoreNodes(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "note:fresh", Type: "Note", Content: []byte("Graph databases for notes"), Modified: now},
+		{ID: "note:old", Type: "Note", Meta: map[string]interface{}{"title": "Graph theory"}, Modified: now.Add(-30 * 24 * time.Hour)},
+		{ID: "sha256:feed", Type: "Source", Meta: map[string]interface{}{"connector": "rss"}, Modified: now},
+	} {
+		if n.Meta == nil {
+			n.Meta = map[string]interface{}{}
+		}
+		n.Created = n.Modified
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "note:old", Target: "note:fresh", Type: "RELATED"},
+		{Source: "note:old", Target: "sha256:feed", Type: "CITES"},
+		{Source: "note:fresh", Target: "sha256:feed", Type: "EXTRACTED_FROM"},
+	} {
+		l.Meta, l.Created, l.Modified = map[string]interface{}{}, now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.UpdateAttentionEdge(ctx, "note:fresh", "sha256:feed", "q1", 0.8); err != nil {
+		t.Fatal(err)
+	}
+	policy, _ := ParseTrustPolicy("connector:rss=0.5")
+
+	ids := []string{"note:old", "note:fresh", "note:gone", "note:fresh"}
+	scores, missing, err := ScoreNodes(ctx, repo, policy, ids, ScoreSpec{Weights: ScoreWeights{Recency: 1}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 2 || len(missing) != 1 || missing[0] != "note:gone" {
+		t.Fatalf("scores = %+v, missing = %v", scores, missing)
+	}
+	if scores[0].ID != "note:fresh" || scores[0].Score != 1 || math.Abs(scores[1].Score-0.5) > 1e-9 {
+		t.Errorf("recency scores = %+v, want fresh 1 and a month old 0.5", scores)
+	}
+
+	spec := ScoreSpec{Weights: ScoreWeights{Degree: 1, Trust: 1, Text: 2, Attention: 1}, Query: "graph notes"}
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	scores, _, err = ScoreNodes(ctx, repo, policy, []string{"note:old", "note:fresh"}, spec, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]NodeScore{}
+	for _, s := range scores {
+		byID[s.ID] = s
+	}
+	fresh, old := byID["note:fresh"], byID["note:old"]
+	if fresh.Raw["degree"] != 2 || old.Raw["degree"] != 2 || fresh.Signals["degree"] != 1 {
+		t.Errorf("degree: fresh %+v, old %+v", fresh, old)
+	}
+	if fresh.Signals["attention"] != 1 || old.Signals["attention"] != 0 {
+		t.Errorf("attention: fresh %+v, old %+v", fresh.Signals, old.Signals)
+	}
+	if fresh.Signals["trust"] != 0.5 || old.Signals["trust"] != 1 {
+		t.Errorf("trust: fresh %+v, old %+v", fresh.Signals, old.Signals)
+	}
+	if fresh.Signals["text"] != 1 || old.Signals["text"] != 0.5 {
+		t.Errorf("text: fresh %+v, old %+v", fresh.Signals, old.Signals)
+	}
+	if want := (1 + 1 + 0.5 + 2) / 5.0; scores[0].ID != "note:fresh" || math.Abs(scores[0].Score-want) > 1e-9 {
+		t.Errorf("scores = %+v, want note:fresh first at %v", scores, want)
+	}
+
+	for _, bad := range []ScoreSpec{
+		{},
+		{Weights: ScoreWeights{Recency: -1, Trust: 2}},
+		{Weights: ScoreWeights{Text: 1}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("spec %+v accepted", bad)
+		}
+	}
+}
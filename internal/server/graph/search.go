@@ -0,0 +1,238 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// A context from WithSearchOptions makes SearchNodes match each word of
+// the query on its own, by any of its synonyms and, with stemming, by
+// prefix of its stem; a node matches when it matches every word. Without
+// options a search matches the query as one phrase.
+
+// maxSynonymWords caps the words in one synonym, so a query is scanned for
+// multi-word synonyms ("machine learning") in bounded time
+const maxSynonymWords = 4
+
+// SearchOptions expands a search beyond the literal query
+type SearchOptions struct {
+	Stem      bool       // Match words by stem: "running" also finds "runs" and "runner"
+	Synonyms  [][]string // Groups of interchangeable words or phrases, e.g. {"kubernetes", "k8s"}
+	Namespace string     // Only return nodes in this ID namespace
+}
+
+type searchOptionsKey struct{}
+
+// WithSearchOptions returns a context whose searches use opts
+func WithSearchOptions(ctx context.Context, opts *SearchOptions) context.Context {
+	return context.WithValue(ctx, searchOptionsKey{}, opts)
+}
+
+// searchOptionsFrom returns the search options set on ctx, if any
+func searchOptionsFrom(ctx context.Context) *SearchOptions {
+	opts, _ := ctx.Value(searchOptionsKey{}).(*SearchOptions)
+	return opts
+}
+
+// SearchWords splits text into lowercase words of letters and digits, the
+// way the full-text indexes tokenize it
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Expands reports whether the options change what a query matches
+func (o *SearchOptions) Expands(term string) bool {
+	if o == nil {
+		return false
+	}
+	if o.Stem || o.Namespace != "" {
+		return true
+	}
+	for _, group := range o.groups(term) {
+		if len(group) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// groups splits a query into the words a node must match. Each is a list
+// of alternatives, any of which may match, and each alternative is a list
+// of words that must appear together. A run of query words that is a
+// synonym is replaced by its whole group.
+func (o *SearchOptions) groups(term string) [][][]string {
+	synonyms := make(map[string][]string)
+	for _, group := range o.Synonyms {
+		for _, s := range group {
+			key := strings.Join(SearchWords(s), " ")
+			synonyms[key] = append(synonyms[key], group...)
+		}
+	}
+
+	words := SearchWords(term)
+	var groups [][][]string
+	for i := 0; i < len(words); {
+		n, alternatives := 1, []string{words[i]}
+		for size := min(maxSynonymWords, len(words)-i); size >= 1; size-- {
+			if group, ok := synonyms[strings.Join(words[i:i+size], " ")]; ok {
+				n, alternatives = size, append([]string{strings.Join(words[i:i+size], " ")}, group...)
+				break
+			}
+		}
+		i += n
+
+		seen := make(map[string]bool)
+		var group [][]string
+		for _, alt := range alternatives {
+			altWords := SearchWords(alt)
+			if o.Stem {
+				for j, w := range altWords {
+					altWords[j] = Stem(w)
+				}
+			}
+			key := strings.Join(altWords, " ")
+			if len(altWords) == 0 || seen[key] {
+				continue
+			}
+			seen[key] = true
+			group = append(group, altWords)
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// inNamespace reports whether a node ID is in the options' namespace
+func (o *SearchOptions) inNamespace(id string) bool {
+	return o.Namespace == "" || Namespace(id) == o.Namespace
+}
+
+// namespaceClause returns the SQL condition, starting with AND, and its
+// arguments that keep a search in the namespace set on ctx
+func namespaceClause(ctx context.Context, table string) (string, []interface{}) {
+	opts := searchOptionsFrom(ctx)
+	if opts == nil || opts.Namespace == "" {
+		return "", nil
+	}
+	prefix := opts.Namespace + ":"
+	return ` AND substr(` + table + `.id, 1, ?) = ?`, []interface{}{len(prefix), prefix}
+}
+
+// ftsQuery compiles search groups to an FTS5 query: alternatives are ORed,
+// words are ANDed, and stemmed words match as prefixes
+func ftsQuery(groups [][][]string, prefix bool) string {
+	parts := make([]string, len(groups))
+	for i, group := range groups {
+		alternatives := make([]string, len(group))
+		for j, alt := range group {
+			words := make([]string, len(alt))
+			for k, w := range alt {
+				words[k] = `"` + w + `"`
+				if prefix {
+					words[k] += "*"
+				}
+			}
+			alternatives[j] = strings.Join(words, " + ")
+		}
+		parts[i] = "(" + strings.Join(alternatives, " OR ") + ")"
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// tsQuery compiles search groups to a Postgres tsquery in the same way
+func tsQuery(groups [][][]string, prefix bool) string {
+	parts := make([]string, len(groups))
+	for i, group := range groups {
+		alternatives := make([]string, len(group))
+		for j, alt := range group {
+			words := make([]string, len(alt))
+			for k, w := range alt {
+				words[k] = "'" + w + "'"
+				if prefix {
+					words[k] += ":*"
+				}
+			}
+			alternatives[j] = strings.Join(words, " <-> ")
+		}
+		parts[i] = "(" + strings.Join(alternatives, " | ") + ")"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// matchesGroups reports whether lowercase text contains, for every group,
+// all the words of one of its alternatives. Backends without a full-text
+// index match words as substrings, which stems already are.
+func matchesGroups(text string, groups [][][]string) bool {
+	for _, group := range groups {
+		found := false
+		for _, alt := range group {
+			all := true
+			for _, w := range alt {
+				if !strings.Contains(text, w) {
+					all = false
+					break
+				}
+			}
+			if all {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// stemSuffixes are stripped by Stem, longest first
+var stemSuffixes = []string{"ations", "ation", "ments", "ment", "ness", "ings", "ing", "ities", "ity", "ies", "ied", "ers", "er", "edly", "ed", "ly", "es", "s"}
+
+// Stem reduces an English word to a stem that prefixes its other forms:
+// "running", "runs" and "runner" all become "run", and "studies" and
+// "study" become "stud". It is deliberately light: a search matches words by
+// prefix of the stem, so a short stem only widens the match. Words with
+// digits or non-ASCII letters are left alone.
+func Stem(word string) string {
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return word
+		}
+	}
+	if len(word) <= 3 {
+		return word
+	}
+	for _, suffix := range stemSuffixes {
+		stem, ok := strings.CutSuffix(word, suffix)
+		if !ok || len(stem) < 3 {
+			continue
+		}
+		last := stem[len(stem)-1]
+		switch suffix {
+		case "es":
+			// "boxes" and "matches", but not "notes"
+			if last != 's' && last != 'x' && last != 'z' && !strings.HasSuffix(stem, "ch") && !strings.HasSuffix(stem, "sh") {
+				continue
+			}
+		case "s":
+			// Not "class", "status" or "analysis"
+			if last == 's' || last == 'u' || last == 'i' {
+				continue
+			}
+		}
+		word = stem
+		break
+	}
+	// "runn" from "running" or "runner"
+	if n := len(word); n >= 4 && word[n-1] == word[n-2] && !strings.ContainsRune("aeiouls", rune(word[n-1])) {
+		word = word[:n-1]
+	}
+	// A trailing e or y varies between forms: "create"/"creating", "city"/"cities"
+	if n := len(word); n >= 5 && word[n-1] == 'e' || n >= 4 && word[n-1] == 'y' {
+		word = word[:n-1]
+	}
+	return word
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestStem(t *testing.T) {
+	for word, want := range map[string]string{
+		"running": "run", "runs": "run", "runner": "run",
+		"studies": "stud", "study": "stud", "studied": "stud",
+		"create": "creat", "creating": "creat", "created": "creat",
+		"boxes": "box", "notes": "note", "note": "note",
+		"cities": "cit", "city": "cit",
+		"kubernetes": "kubernet", "class": "class", "status": "status",
+		"k8s": "k8s", "café": "café", "go": "go",
+	} {
+		if got := Stem(word); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestSearchQueries(t *testing.T) {
+	opts := &SearchOptions{Stem: true, Synonyms: [][]string{{"kubernetes", "k8s"}, {"machine learning", "ML"}}}
+	groups := opts.groups("Machine Learning on K8s clusters")
+	if got := ftsQuery(groups, true); got != `("machin"* + "learn"* OR "ml"*) AND ("on"*) AND ("k8s"* OR "kubernet"*) AND ("clust"*)` {
+		t.Errorf("ftsQuery = %s", got)
+	}
+	if got := tsQuery(opts.groups("k8s"), false); got != `('k8s' | 'kubernet')` {
+		t.Errorf("tsQuery = %s", got)
+	}
+	if (&SearchOptions{Synonyms: opts.Synonyms}).Expands("docker") {
+		t.Error("a query without synonyms expands")
+	}
+	if !(&SearchOptions{Synonyms: opts.Synonyms}).Expands("k8s") {
+		t.Error("a query with a synonym does not expand")
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, repo := range map[string]Repository{"memory": NewMemory(), "sqlite": sqlite} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for _, n := range []*core.Node{
+				{ID: "work:deploy", Type: "Note", Meta: map[string]any{"title": "Deploying to Kubernetes"}},
+				{ID: "work:k8s", Type: "Note", Meta: map[string]any{"title": "k8s cluster upgrades"}},
+				{ID: "home:garden", Type: "Note", Meta: map[string]any{"title": "Kubernetes of tomatoes, deployed"}},
+			} {
+				n.Created, n.Modified = now, now
+				if err := repo.CreateNode(ctx, n); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			search := func(q string, opts *SearchOptions) []string {
+				if opts != nil {
+					ctx = WithSearchOptions(context.Background(), opts)
+				} else {
+					ctx = context.Background()
+				}
+				nodes, err := repo.SearchNodes(ctx, q, 10, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids := nodeIDs(nodes)
+				sort.Strings(ids)
+				return ids
+			}
+			synonyms := [][]string{{"kubernetes", "k8s"}}
+
+			if got := search("k8s", nil); len(got) != 1 {
+				t.Errorf("plain search for k8s = %v", got)
+			}
+			if got := search("k8s", &SearchOptions{Synonyms: synonyms}); len(got) != 3 {
+				t.Errorf("search for k8s with synonyms = %v", got)
+			}
+			if got := search("deploy kubernetes", &SearchOptions{Stem: true, Namespace: "work"}); len(got) != 1 || got[0] != "work:deploy" {
+				t.Errorf("stemmed search in work = %v", got)
+			}
+			if got := search("deploy kubernetes", nil); len(got) != 0 {
+				t.Errorf("plain search for deploy kubernetes = %v", got)
+			}
+		})
+	}
+}
+
+func nodeIDs(nodes []*core.Node) []string {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
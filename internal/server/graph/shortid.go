@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MinShortID is the shortest short ID ResolveShortID accepts
+const MinShortID = 4
+
+// ResolveShortID returns the IDs of the nodes a short ID may name: the
+// node the repository finds under it (by ID, or by alias or assigned short
+// ID when wrapped WithAliases), or else the nodes whose ID starts with it,
+// or whose ID after its type prefix does (so "4c4b6a" finds
+// "sha256:4c4b6a…"). At most limit IDs are returned, sorted.
+func ResolveShortID(ctx context.Context, repo Repository, short string, limit int) ([]string, error) {
+	short = strings.TrimSpace(short)
+	if node, err := repo.GetNode(ctx, short); err == nil {
+		return []string{node.ID}, nil
+	}
+	if len(short) < MinShortID {
+		return nil, fmt.Errorf("short ID must be at least %d characters", MinShortID)
+	}
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, id := range ids {
+		_, rest, _ := strings.Cut(id, ":")
+		if strings.HasPrefix(id, short) || strings.HasPrefix(rest, short) {
+			matches = append(matches, id)
+		}
+	}
+	sort.Strings(matches)
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestResolveShortID(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+	for _, id := range []string{"sha256:4c4b6a3b", "sha256:4c4b9f00", "note:meeting", "note:meeting-2", "screenshot:77aa01"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		short string
+		want  []string
+	}{
+		{"note:meeting", []string{"note:meeting"}}, // An exact ID wins over longer ones
+		{"4c4b6", []string{"sha256:4c4b6a3b"}},
+		{"4c4b", []string{"sha256:4c4b6a3b", "sha256:4c4b9f00"}},
+		{"sha256:4c4b9", []string{"sha256:4c4b9f00"}},
+		{"77aa", []string{"screenshot:77aa01"}},
+		{"ffff", nil},
+	} {
+		got, err := ResolveShortID(ctx, repo, tc.short, 10)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ResolveShortID(%q) = %v, %v, want %v", tc.short, got, err, tc.want)
+		}
+	}
+	if got, _ := ResolveShortID(ctx, repo, "4c4b", 1); len(got) != 1 {
+		t.Errorf("ResolveShortID() with limit 1 = %v", got)
+	}
+	if _, err := ResolveShortID(ctx, repo, "4c4", 10); err == nil {
+		t.Error("ResolveShortID() accepted a 3 character short ID")
+	}
+}
@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// SearchSimilar finds nodes whose names are spelled like a search term, for
+// "did you mean" suggestions when a search finds nothing. Names are the
+// name, title, label and alias properties; they are compared by the
+// trigrams they share, as Postgres pg_trgm does.
+
+// MinSimilarity is the similarity a name needs to be suggested
+const MinSimilarity = 0.3
+
+// maxSimilarCandidates caps the nodes scored per lookup
+const maxSimilarCandidates = 500
+
+// maxTermTrigrams caps the trigrams looked up by backends without a
+// trigram index
+const maxTermTrigrams = 32
+
+// nameKeys are the properties a node is suggested by
+var nameKeys = []string{"name", "title", "label", AliasKey}
+
+// SimilarNode is a node with a name spelled like a search term
+type SimilarNode struct {
+	Node  *core.Node `json:"node"`
+	Match string     `json:"match"` // The name that matched
+	Score float64    `json:"score"` // Trigram similarity, from MinSimilarity to 1
+}
+
+// NodeNames returns the names a node is suggested by
+func NodeNames(meta map[string]interface{}) []string {
+	var names []string
+	for _, key := range nameKeys {
+		if s, ok := meta[key].(string); ok && strings.TrimSpace(s) != "" {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+// Trigrams returns the distinct trigrams of text's words, each padded with
+// two spaces in front and one behind so short words and word starts count
+func Trigrams(text string) map[string]bool {
+	out := make(map[string]bool)
+	for _, w := range SearchWords(text) {
+		padded := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			out[string(padded[i:i+3])] = true
+		}
+	}
+	return out
+}
+
+// Similarity returns the trigrams a and b share over those either has:
+// 1 for the same words, 0 for nothing in common
+func Similarity(a, b string) float64 {
+	return trigramSimilarity(Trigrams(a), Trigrams(b))
+}
+
+func trigramSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// nameSimilarity scores a name against a term: the better of the whole
+// name and its best run of as many words as the term has, so "kubernets"
+// matches "Kubernetes cluster setup"
+func nameSimilarity(term map[string]bool, termWords int, name string) float64 {
+	best := trigramSimilarity(term, Trigrams(name))
+	words := SearchWords(name)
+	for i := 0; termWords > 0 && i+termWords <= len(words); i++ {
+		if s := trigramSimilarity(term, Trigrams(strings.Join(words[i:i+termWords], " "))); s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// rankSimilar scores candidate nodes against term and returns the best
+// limit at or above MinSimilarity, most similar first
+func rankSimilar(term string, nodes []*core.Node, limit int) []*SimilarNode {
+	grams, termWords := Trigrams(term), len(SearchWords(term))
+	out := []*SimilarNode{}
+	for _, n := range nodes {
+		var best *SimilarNode
+		for _, name := range NodeNames(n.Meta) {
+			if s := nameSimilarity(grams, termWords, name); s >= MinSimilarity && (best == nil || s > best.Score) {
+				best = &SimilarNode{Node: n, Match: name, Score: s}
+			}
+		}
+		if best != nil {
+			out = append(out, best)
+		}
+	}
+	SortSimilar(out)
+	if limit >= 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// SortSimilar orders suggestions most similar first, then by node ID
+func SortSimilar(nodes []*SimilarNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Score != nodes[j].Score {
+			return nodes[i].Score > nodes[j].Score
+		}
+		return nodes[i].Node.ID < nodes[j].Node.ID
+	})
+}
+
+// termTrigrams returns the unpadded, lowercase trigrams of a term's words,
+// which a substring index can look up
+func termTrigrams(term string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, w := range SearchWords(term) {
+		r := []rune(w)
+		for i := 0; i+3 <= len(r); i++ {
+			if t := string(r[i : i+3]); !seen[t] {
+				seen[t] = true
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestSimilarity(t *testing.T) {
+	if s := Similarity("Kubernetes", "kubernetes"); s != 1 {
+		t.Errorf("Similarity of the same word = %v, want 1", s)
+	}
+	if s := Similarity("kubernets", "kubernetes"); s < MinSimilarity {
+		t.Errorf("Similarity of a typo = %v, want at least %v", s, MinSimilarity)
+	}
+	if s := Similarity("kubernetes", "tomato"); s != 0 {
+		t.Errorf("Similarity of unrelated words = %v, want 0", s)
+	}
+	grams := Trigrams("kubernets")
+	if s := nameSimilarity(grams, 1, "Kubernetes cluster setup"); s < 0.5 {
+		t.Errorf("nameSimilarity against a longer title = %v, want its best word", s)
+	}
+}
+
+func TestSearchSimilar(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, repo := range map[string]Repository{"memory": NewMemory(), "sqlite": sqlite} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for _, n := range []*core.Node{
+				{ID: "work:k8s", Type: "Note", Meta: map[string]any{"title": "Kubernetes cluster setup"}},
+				{ID: "work:ada", Type: "Person", Meta: map[string]any{"name": "Ada Lovelace", AliasKey: "person/ada-lovelace"}},
+				{ID: "home:garden", Type: "Note", Meta: map[string]any{"title": "Tomatoes", "body": "kubernetes"}},
+			} {
+				n.Created, n.Modified = now, now
+				if err := repo.CreateNode(ctx, n); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := repo.SearchSimilar(ctx, "kubernets", 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].Node.ID != "work:k8s" || got[0].Match != "Kubernetes cluster setup" {
+				t.Fatalf("SearchSimilar(kubernets) = %+v, want work:k8s by its title", got)
+			}
+
+			got, _ = repo.SearchSimilar(ctx, "ada lovlace", 5)
+			if len(got) != 1 || got[0].Node.ID != "work:ada" {
+				t.Errorf("SearchSimilar(ada lovlace) = %+v, want work:ada", got)
+			}
+
+			// Names update with the node
+			if err := repo.UpdateNodeMeta(ctx, "home:garden", map[string]any{"title": "Tomato planting"}); err != nil {
+				t.Fatal(err)
+			}
+			got, _ = repo.SearchSimilar(ctx, "tomatos", 5)
+			if len(got) != 1 || got[0].Match != "Tomato planting" {
+				t.Errorf("SearchSimilar(tomatos) after rename = %+v", got)
+			}
+
+			scoped := WithSearchOptions(ctx, &SearchOptions{Namespace: "home"})
+			if got, _ := repo.SearchSimilar(scoped, "kubernets", 5); len(got) != 0 {
+				t.Errorf("SearchSimilar outside the namespace = %+v, want none", got)
+			}
+
+			if err := repo.DeleteNode(ctx, "work:k8s", true); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := repo.SearchSimilar(ctx, "kubernets", 5); len(got) != 0 {
+				t.Errorf("SearchSimilar after delete = %+v, want none", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package graph
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// SlowQuery is a repository read that exceeded the slow-query threshold
+type SlowQuery struct {
+	Operation  string                 `json:"operation"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	DurationMs float64                `json:"duration_ms"`
+	Rows       int                    `json:"rows"`
+	Error      string                 `json:"error,omitempty"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Statements []SlowStatement        `json:"statements,omitempty"` // SQLite only
+}
+
+// SlowStatement is a SQL statement executed by a slow operation, with its query plan
+type SlowStatement struct {
+	SQL  string        `json:"sql"`
+	Args []interface{} `json:"args,omitempty"`
+	Plan []string      `json:"plan,omitempty"`
+}
+
+// SlowQueryLog keeps the most recent slow queries in a fixed-size ring buffer
+type SlowQueryLog struct {
+	threshold time.Duration
+
+	mu      sync.Mutex
+	entries []SlowQuery
+	next    int
+	full    bool
+}
+
+// NewSlowQueryLog creates a log recording operations slower than threshold
+func NewSlowQueryLog(threshold time.Duration, capacity int) *SlowQueryLog {
+	if capacity <= 0 {
+		capacity = 100
+	}
+	return &SlowQueryLog{
+		threshold: threshold,
+		entries:   make([]SlowQuery, capacity),
+	}
+}
+
+// Threshold returns the duration above which operations are recorded
+func (l *SlowQueryLog) Threshold() time.Duration {
+	return l.threshold
+}
+
+// add records an entry, overwriting the oldest when full
+func (l *SlowQueryLog) add(entry SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Entries returns recorded slow queries, most recent first
+func (l *SlowQueryLog) Entries() []SlowQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+
+	result := make([]SlowQuery, 0, count)
+	for i := 1; i <= count; i++ {
+		idx := (l.next - i + len(l.entries)) % len(l.entries)
+		result = append(result, l.entries[idx])
+	}
+	return result
+}
+
+// Clear removes all recorded entries
+func (l *SlowQueryLog) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = make([]SlowQuery, len(l.entries))
+	l.next = 0
+	l.full = false
+}
+
+// statementCollector gathers SQL statements issued during one operation
+type statementCollector struct {
+	mu         sync.Mutex
+	statements []SlowStatement
+}
+
+type statementCollectorKey struct{}
+
+// recordStatement adds a statement to the collector in ctx, if any
+func recordStatement(ctx context.Context, query string, args []interface{}) {
+	c, ok := ctx.Value(statementCollectorKey{}).(*statementCollector)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	c.statements = append(c.statements, SlowStatement{SQL: query, Args: args})
+	c.mu.Unlock()
+}
+
+// queryExplainer is implemented by backends that can report query plans
+type queryExplainer interface {
+	explainQueryPlan(ctx context.Context, query string, args []interface{}) ([]string, error)
+}
+
+// slowQueryRepository wraps a Repository, timing read operations and
+// recording those that exceed the log's threshold
+type slowQueryRepository struct {
+	Repository
+	log       *SlowQueryLog
+	explainer queryExplainer
+}
+
+// WithSlowQueryLog wraps repo so slow read operations are recorded in l.
+// Wrap the concrete backend so SQLite statements and plans can be captured.
+func WithSlowQueryLog(repo Repository, l *SlowQueryLog) Repository {
+	explainer, _ := repo.(queryExplainer)
+	return &slowQueryRepository{Repository: repo, log: l, explainer: explainer}
+}
+
+// observe starts timing an operation; call the returned func with the result size
+func (s *slowQueryRepository) observe(ctx context.Context, op string, params map[string]interface{}) (context.Context, func(rows int, err error)) {
+	collector := &statementCollector{}
+	ctx = context.WithValue(ctx, statementCollectorKey{}, collector)
+	start := time.Now()
+
+	return ctx, func(rows int, err error) {
+		elapsed := time.Since(start)
+		if elapsed < s.log.threshold {
+			return
+		}
+
+		entry := SlowQuery{
+			Operation:  op,
+			Params:     params,
+			DurationMs: float64(elapsed.Microseconds()) / 1000,
+			Rows:       rows,
+			Timestamp:  start,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+
+		collector.mu.Lock()
+		entry.Statements = collector.statements
+		collector.mu.Unlock()
+
+		if s.explainer != nil {
+			// The request context may already be done; plans are cheap to compute
+			explainCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			for i := range entry.Statements {
+				plan, err := s.explainer.explainQueryPlan(explainCtx, entry.Statements[i].SQL, entry.Statements[i].Args)
+				if err == nil {
+					entry.Statements[i].Plan = plan
+				}
+			}
+			cancel()
+		}
+
+		log.Printf("Slow query: %s took %.1fms (%d rows) params=%v", op, entry.DurationMs, rows, params)
+		s.log.add(entry)
+	}
+}
+
+func (s *slowQueryRepository) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	ctx, done := s.observe(ctx, "GetNode", map[string]interface{}{"id": id})
+	node, err := s.Repository.GetNode(ctx, id)
+	done(boolToInt(node != nil), err)
+	return node, err
+}
+
+func (s *slowQueryRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	ctx, done := s.observe(ctx, "GetLinks", map[string]interface{}{"node_id": nodeID})
+	links, err := s.Repository.GetLinks(ctx, nodeID)
+	done(len(links), err)
+	return links, err
+}
+
+func (s *slowQueryRepository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	ctx, done := s.observe(ctx, "GetBacklinks", map[string]interface{}{"node_id": nodeID})
+	links, err := s.Repository.GetBacklinks(ctx, nodeID)
+	done(len(links), err)
+	return links, err
+}
+
+func (s *slowQueryRepository) MatchPattern(ctx context.Context, pattern *Pattern, limit int) ([]PatternBinding, error) {
+	ctx, done := s.observe(ctx, "MatchPattern", map[string]interface{}{"nodes": len(pattern.Nodes), "links": len(pattern.Edges), "limit": limit})
+	matches, err := s.Repository.MatchPattern(ctx, pattern, limit)
+	done(len(matches), err)
+	return matches, err
+}
+
+func (s *slowQueryRepository) SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
+	ctx, done := s.observe(ctx, "SearchNodes", map[string]interface{}{"q": searchTerm, "limit": limit, "offset": offset})
+	nodes, err := s.Repository.SearchNodes(ctx, searchTerm, limit, offset)
+	done(len(nodes), err)
+	return nodes, err
+}
+
+func (s *slowQueryRepository) SearchSimilar(ctx context.Context, term string, limit int) ([]*SimilarNode, error) {
+	ctx, done := s.observe(ctx, "SearchSimilar", map[string]interface{}{"q": term, "limit": limit})
+	nodes, err := s.Repository.SearchSimilar(ctx, term, limit)
+	done(len(nodes), err)
+	return nodes, err
+}
+
+func (s *slowQueryRepository) FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error) {
+	ctx, done := s.observe(ctx, "FilterNodes", map[string]interface{}{
+		"types": nodeTypes, "key": propertyKey, "value": propertyValue, "limit": limit, "offset": offset,
+	})
+	nodes, err := s.Repository.FilterNodes(ctx, nodeTypes, propertyKey, propertyValue, limit, offset)
+	done(len(nodes), err)
+	return nodes, err
+}
+
+func (s *slowQueryRepository) ListDeleted(ctx context.Context, limit int, offset int) ([]*core.Node, error) {
+	ctx, done := s.observe(ctx, "ListDeleted", map[string]interface{}{"limit": limit, "offset": offset})
+	nodes, err := s.Repository.ListDeleted(ctx, limit, offset)
+	done(len(nodes), err)
+	return nodes, err
+}
+
+func (s *slowQueryRepository) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
+	ctx, done := s.observe(ctx, "TraverseGraph", map[string]interface{}{
+		"start": startNodeID, "depth": depth, "rel_types": relationshipTypes, "limit": limit, "offset": offset,
+	})
+	nodes, err := s.Repository.TraverseGraph(ctx, startNodeID, depth, relationshipTypes, limit, offset)
+	done(len(nodes), err)
+	return nodes, err
+}
+
+func (s *slowQueryRepository) QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error) {
+	ctx, done := s.observe(ctx, "QueryTimeRange", map[string]interface{}{
+		"from": from, "to": to, "types": nodeTypes, "limit": limit, "offset": offset,
+	})
+	nodes, err := s.Repository.QueryTimeRange(ctx, from, to, nodeTypes, limit, offset)
+	done(len(nodes), err)
+	return nodes, err
+}
+
+func (s *slowQueryRepository) GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error) {
+	ctx, done := s.observe(ctx, "GetNodeHistory", map[string]interface{}{"id": id})
+	history, err := s.Repository.GetNodeHistory(ctx, id)
+	done(len(history), err)
+	return history, err
+}
+
+func (s *slowQueryRepository) GetAttentionSubgraph(ctx context.Context, startNodeID string, minWeight float64, maxNodes int) (*Subgraph, error) {
+	ctx, done := s.observe(ctx, "GetAttentionSubgraph", map[string]interface{}{
+		"start": startNodeID, "min_weight": minWeight, "max_nodes": maxNodes, "head": AttentionHeadFrom(ctx),
+	})
+	subgraph, err := s.Repository.GetAttentionSubgraph(ctx, startNodeID, minWeight, maxNodes)
+	done(subgraphRows(subgraph), err)
+	return subgraph, err
+}
+
+func (s *slowQueryRepository) GetGraphMap(ctx context.Context, sampleSize int) (*GraphMap, error) {
+	ctx, done := s.observe(ctx, "GetGraphMap", map[string]interface{}{"sample_size": sampleSize})
+	graphMap, err := s.Repository.GetGraphMap(ctx, sampleSize)
+	rows := 0
+	if graphMap != nil {
+		rows = len(graphMap.TopConnected)
+	}
+	done(rows, err)
+	return graphMap, err
+}
+
+func (s *slowQueryRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
+	ctx, done := s.observe(ctx, "GetSubgraph", map[string]interface{}{
+		"start": startNodeID, "depth": depth, "rel_types": relationshipTypes,
+	})
+	subgraph, err := s.Repository.GetSubgraph(ctx, startNodeID, depth, relationshipTypes)
+	done(subgraphRows(subgraph), err)
+	return subgraph, err
+}
+
+func (s *slowQueryRepository) GetGraphSnapshot(ctx context.Context, nodeTypes []string, limit int) (*Subgraph, error) {
+	ctx, done := s.observe(ctx, "GetGraphSnapshot", map[string]interface{}{"types": nodeTypes, "limit": limit})
+	snapshot, err := s.Repository.GetGraphSnapshot(ctx, nodeTypes, limit)
+	done(subgraphRows(snapshot), err)
+	return snapshot, err
+}
+
+func (s *slowQueryRepository) GetTimeline(ctx context.Context, from, to time.Time, bucket string, nodeTypes []string, sampleSize int) (*Timeline, error) {
+	ctx, done := s.observe(ctx, "GetTimeline", map[string]interface{}{
+		"from": from, "to": to, "bucket": bucket, "types": nodeTypes,
+	})
+	timeline, err := s.Repository.GetTimeline(ctx, from, to, bucket, nodeTypes, sampleSize)
+	rows := 0
+	if timeline != nil {
+		rows = timeline.Total
+	}
+	done(rows, err)
+	return timeline, err
+}
+
+func (s *slowQueryRepository) GetUsage(ctx context.Context) (*Usage, error) {
+	ctx, done := s.observe(ctx, "GetUsage", nil)
+	usage, err := s.Repository.GetUsage(ctx)
+	rows := 0
+	if usage != nil {
+		rows = int(usage.Total.Versions)
+	}
+	done(rows, err)
+	return usage, err
+}
+
+func (s *slowQueryRepository) CheckIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	ctx, done := s.observe(ctx, "CheckIntegrity", map[string]interface{}{"limit": opts.Limit})
+	report, err := s.Repository.CheckIntegrity(ctx, opts)
+	rows := 0
+	if report != nil {
+		rows = report.Counts.Orphans + report.Counts.DanglingLinks + report.Counts.BrokenVersions
+	}
+	done(rows, err)
+	return report, err
+}
+
+func (s *slowQueryRepository) RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error) {
+	ctx, done := s.observe(ctx, "RecomputeDegrees", map[string]interface{}{"dry_run": opts.DryRun, "batch": opts.BatchSize})
+	report, err := s.Repository.RecomputeDegrees(ctx, opts)
+	rows := 0
+	if report != nil {
+		rows = report.Checked
+	}
+	done(rows, err)
+	return report, err
+}
+
+func (s *slowQueryRepository) ChangeLog(ctx context.Context, since time.Time, limit int) ([]subscriptions.Event, error) {
+	ctx, done := s.observe(ctx, "ChangeLog", map[string]interface{}{"since": since, "limit": limit})
+	events, err := s.Repository.ChangeLog(ctx, since, limit)
+	done(len(events), err)
+	return events, err
+}
+
+func (s *slowQueryRepository) DiffGraph(ctx context.Context, from, to time.Time, detailed bool) (*GraphDiff, error) {
+	ctx, done := s.observe(ctx, "DiffGraph", map[string]interface{}{"from": from, "to": to, "detailed": detailed})
+	diff, err := s.Repository.DiffGraph(ctx, from, to, detailed)
+	rows := 0
+	if diff != nil {
+		sum := diff.Summary
+		rows = sum.NodesAdded + sum.NodesModified + sum.NodesDeleted + sum.LinksAdded + sum.LinksRemoved
+	}
+	done(rows, err)
+	return diff, err
+}
+
+func (s *slowQueryRepository) GetEntitiesInterpretedThrough(ctx context.Context, lensID string) ([]*core.Node, error) {
+	ctx, done := s.observe(ctx, "GetEntitiesInterpretedThrough", map[string]interface{}{"lens_id": lensID})
+	nodes, err := s.Repository.GetEntitiesInterpretedThrough(ctx, lensID)
+	done(len(nodes), err)
+	return nodes, err
+}
+
+func (s *slowQueryRepository) QueryByLens(ctx context.Context, lensID string, pattern string, limit int, offset int) ([]*core.Node, error) {
+	ctx, done := s.observe(ctx, "QueryByLens", map[string]interface{}{
+		"lens_id": lensID, "pattern": pattern, "limit": limit, "offset": offset,
+	})
+	nodes, err := s.Repository.QueryByLens(ctx, lensID, pattern, limit, offset)
+	done(len(nodes), err)
+	return nodes, err
+}
+
+func (s *slowQueryRepository) ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx, done := s.observe(ctx, "ExecuteCypherRead", map[string]interface{}{"cypher": cypher, "params": params})
+	rows, err := s.Repository.ExecuteCypherRead(ctx, cypher, params)
+	done(len(rows), err)
+	return rows, err
+}
+
+// subgraphRows returns the number of nodes plus edges in a subgraph
+func subgraphRows(subgraph *Subgraph) int {
+	if subgraph == nil {
+		return 0
+	}
+	return len(subgraph.Nodes) + len(subgraph.Edges)
+}
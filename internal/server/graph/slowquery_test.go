@@ -0,0 +1,55 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSlowQueryLogRing(t *testing.T) {
+	l := NewSlowQueryLog(0, 3)
+	for _, op := range []string{"a", "b", "c", "d"} {
+		l.add(SlowQuery{Operation: op})
+	}
+
+	entries := l.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("Entries() returned %d entries, want 3", len(entries))
+	}
+	for i, want := range []string{"d", "c", "b"} {
+		if entries[i].Operation != want {
+			t.Errorf("entries[%d] = %q, want %q", i, entries[i].Operation, want)
+		}
+	}
+
+	l.Clear()
+	if got := len(l.Entries()); got != 0 {
+		t.Errorf("Entries() after Clear() returned %d entries, want 0", got)
+	}
+}
+
+func TestSlowQueryLogCapturesSQLitePlan(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	l := NewSlowQueryLog(0, 10) // Record everything
+	repo := WithSlowQueryLog(sqlite, l)
+
+	repo.GetNode(ctx, "missing")
+
+	entries := l.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("Entries() returned %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Operation != "GetNode" || entry.Error == "" {
+		t.Errorf("entry = %+v, want failed GetNode", entry)
+	}
+	if len(entry.Statements) != 1 || len(entry.Statements[0].Plan) == 0 {
+		t.Errorf("entry.Statements = %+v, want one statement with a query plan", entry.Statements)
+	}
+}
@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// A snapshot pins reads to one view of the graph, so an agent that
+// searches, then batch-gets, then expands a subgraph does not see the
+// graph change between calls. Reads made with a context from WithSnapshot
+// use the snapshot; writes are never pinned.
+
+// Snapshot consistency levels
+const (
+	// SnapshotIsolated reads all see the graph as it was when the
+	// snapshot began (SQLite, Postgres)
+	SnapshotIsolated = "snapshot"
+	// SnapshotCausal reads see at least the graph as it was when the
+	// snapshot began, and may see later writes (Neo4j bookmarks)
+	SnapshotCausal = "causal"
+)
+
+// Snapshotter is implemented by backends that can pin reads to a view
+type Snapshotter interface {
+	// BeginSnapshot pins a view of the graph. It outlives ctx; Close
+	// releases it.
+	BeginSnapshot(ctx context.Context) (Snapshot, error)
+}
+
+// Snapshot is a pinned view of the graph
+type Snapshot interface {
+	Consistency() string
+	Close() error
+}
+
+type snapshotKey struct{}
+
+// WithSnapshot returns a context whose reads use snap
+func WithSnapshot(ctx context.Context, snap Snapshot) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, snap)
+}
+
+// SnapshotFrom returns the snapshot reads made with ctx use, or nil
+func SnapshotFrom(ctx context.Context) Snapshot {
+	snap, _ := ctx.Value(snapshotKey{}).(Snapshot)
+	return snap
+}
+
+// sqlSnapshot holds a read-only transaction open; queries on the
+// repository's databases made with a pinned context run inside it
+type sqlSnapshot struct {
+	tx          *sql.Tx
+	db, readers *observedDB
+}
+
+func (s *sqlSnapshot) Consistency() string { return SnapshotIsolated }
+
+func (s *sqlSnapshot) Close() error {
+	if err := s.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+	return nil
+}
+
+// BeginSnapshot opens a read-only transaction on its own connection. In
+// WAL mode SQLite gives it a fixed view of the database while writers
+// carry on; Postgres does the same at repeatable read.
+func (r *SQLiteRepository) BeginSnapshot(ctx context.Context) (Snapshot, error) {
+	opts := &sql.TxOptions{ReadOnly: true}
+	if r.db.dollarParams {
+		opts.Isolation = sql.LevelRepeatableRead
+	}
+	tx, err := r.db.DB.BeginTx(context.WithoutCancel(ctx), opts)
+	if err != nil {
+		return nil, fmt.Errorf("beginning snapshot: %w", err)
+	}
+	// Both take the view at the first read, not at BEGIN
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT count(*) FROM nodes WHERE id = ''").Scan(&n); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("beginning snapshot: %w", err)
+	}
+	return &sqlSnapshot{tx: tx, db: r.db, readers: r.readers}, nil
+}
+
+// pinned returns the transaction of the snapshot ctx is pinned to, if it
+// was taken on this database
+func (db *observedDB) pinned(ctx context.Context) *sql.Tx {
+	if s, ok := SnapshotFrom(ctx).(*sqlSnapshot); ok && (s.db == db || s.readers == db) {
+		return s.tx
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestSnapshotPinsCachedAndPooledReads(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+	repo := WithCache(sqlite, CacheConfig{Size: 10, TTL: time.Minute})
+
+	now := time.Now()
+	for _, id := range []string{"a", "b"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Meta: map[string]interface{}{}, Created: now, Modified: now}); err != nil {
+			t.Fatalf("CreateNode(%s) error = %v", id, err)
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "a", Target: "b", Type: "CITES", Created: now, Modified: now}); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	snap, err := sqlite.BeginSnapshot(ctx)
+	if err != nil {
+		t.Fatalf("BeginSnapshot() error = %v", err)
+	}
+	pinned := WithSnapshot(ctx, snap)
+
+	if err := repo.CreateNode(ctx, &core.Node{ID: "c", Type: "Note", Meta: map[string]interface{}{}, Created: now, Modified: now}); err != nil {
+		t.Fatalf("CreateNode(c) error = %v", err)
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "a", Target: "c", Type: "CITES", Created: now, Modified: now}); err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+
+	// Warm the cache with the current subgraph; the pinned read must not use it
+	if current, err := repo.GetSubgraph(ctx, "a", 1, nil); err != nil || len(current.Nodes) != 3 {
+		t.Fatalf("GetSubgraph() = %v, %v; want 3 nodes", current, err)
+	}
+	// Subgraph link queries run on the read pool
+	sub, err := repo.GetSubgraph(pinned, "a", 1, nil)
+	if err != nil {
+		t.Fatalf("pinned GetSubgraph() error = %v", err)
+	}
+	if len(sub.Nodes) != 2 || len(sub.Edges) != 1 {
+		t.Errorf("pinned GetSubgraph() = %d nodes, %d edges; want 2, 1", len(sub.Nodes), len(sub.Edges))
+	}
+
+	if err := snap.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := repo.GetNode(pinned, "a"); err == nil {
+		t.Error("GetNode() through a closed snapshot succeeded")
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,8 +20,118 @@ import (
 
 // SQLiteRepository implements Repository using SQLite
 type SQLiteRepository struct {
-	db           *sql.DB
+	db           *observedDB
 	eventEmitter func(subscriptions.Event)
+	dedupContent bool // Store large content once per hash (see sqlite_content.go)
+
+	readers         *observedDB // Read-only pool for concurrent subgraph reads; nil uses db
+	subgraphWorkers int         // Concurrent link queries per subgraph; zero uses readPoolSize
+
+	writes *writeBehind // Optional grouping of inserts (see sqlite_writebehind.go)
+
+	quarantined quarantineRecorder // Rows reads could not use (see sqlite_quarantine.go)
+}
+
+// observedDB wraps *sql.DB so statements can be captured for the slow-query log.
+// When dollarParams is set (Postgres), "?" placeholders are rewritten to $1, $2, ...
+type observedDB struct {
+	*sql.DB
+	dollarParams bool
+	stmts        sync.Map // Query text -> *sql.Stmt (see prepared)
+}
+
+// rebind adapts a query written with "?" placeholders to the database's dialect
+func (db *observedDB) rebind(query string) string {
+	if !db.dollarParams {
+		return query
+	}
+	return rebindDollar(query)
+}
+
+func (db *observedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	query = db.rebind(query)
+	recordStatement(ctx, query, args)
+	if tx := db.pinned(ctx); tx != nil {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	return db.DB.QueryContext(ctx, query, args...)
+}
+
+func (db *observedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	query = db.rebind(query)
+	recordStatement(ctx, query, args)
+	if tx := db.pinned(ctx); tx != nil {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (db *observedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query = db.rebind(query)
+	recordStatement(ctx, query, args)
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction whose statements are observed and rebound like the DB's
+func (db *observedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*observedTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &observedTx{Tx: tx, db: db}, nil
+}
+
+// observedTx is the transaction counterpart of observedDB
+type observedTx struct {
+	*sql.Tx
+	db *observedDB
+}
+
+func (tx *observedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	query = tx.db.rebind(query)
+	recordStatement(ctx, query, args)
+	return tx.Tx.QueryContext(ctx, query, args...)
+}
+
+func (tx *observedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	query = tx.db.rebind(query)
+	recordStatement(ctx, query, args)
+	return tx.Tx.QueryRowContext(ctx, query, args...)
+}
+
+func (tx *observedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query = tx.db.rebind(query)
+	recordStatement(ctx, query, args)
+	return tx.Tx.ExecContext(ctx, query, args...)
+}
+
+func (tx *observedTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return tx.Tx.PrepareContext(ctx, tx.db.rebind(query))
+}
+
+// rebindDollar replaces "?" placeholders outside quoted strings and identifiers with $n
+func rebindDollar(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
 }
 
 // NewSQLite creates a new SQLite repository
@@ -33,7 +146,7 @@ func NewSQLite(ctx context.Context, dbPath string) (*SQLiteRepository, error) {
 		return nil, fmt.Errorf("connecting to sqlite: %w", err)
 	}
 
-	repo := &SQLiteRepository{db: db}
+	repo := &SQLiteRepository{db: &observedDB{DB: db}, dedupContent: true}
 
 	// Apply pragmas for optimal performance
 	for _, pragma := range allPragmas() {
@@ -42,18 +155,34 @@ func NewSQLite(ctx context.Context, dbPath string) (*SQLiteRepository, error) {
 		}
 	}
 
-	// Create schema
-	for _, stmt := range allSchemaStatements() {
-		if _, err := db.ExecContext(ctx, stmt); err != nil {
-			return nil, fmt.Errorf("creating schema: %w", err)
-		}
+	// Create or upgrade schema
+	if err := migrate(ctx, repo.db, sqliteMigrations()); err != nil {
+		return nil, fmt.Errorf("migrating schema: %w", err)
+	}
+
+	if repo.readers, err = openReaders(dbPath); err != nil {
+		return nil, err
 	}
 
 	return repo, nil
 }
 
-// Close closes the SQLite connection
+// Close commits queued writes, checkpoints the WAL into the database file
+// so a restart doesn't have to replay it, and closes the SQLite connections
 func (r *SQLiteRepository) Close(ctx context.Context) error {
+	if r.writes != nil {
+		r.writes.close()
+	}
+	r.stopQuarantine()
+	if r.readers != nil {
+		r.readers.Close()
+	}
+	if !r.db.dollarParams { // Postgres shares this Close
+		if _, err := r.db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+			r.db.Close()
+			return fmt.Errorf("checkpointing WAL: %w", err)
+		}
+	}
 	return r.db.Close()
 }
 
@@ -71,7 +200,7 @@ func (r *SQLiteRepository) emit(event subscriptions.Event) {
 
 // EnsureIndexes creates necessary indexes (already created in schema)
 func (r *SQLiteRepository) EnsureIndexes(ctx context.Context) error {
-	// Indexes are created in NewSQLite via allSchemaStatements()
+	// Indexes are created in NewSQLite via schema migrations
 	// This method exists to satisfy the interface
 	return nil
 }
@@ -83,32 +212,67 @@ func (r *SQLiteRepository) CreateNode(ctx context.Context, node *core.Node) erro
 	node.VersionID = node.ID + ":v1"
 	node.IsCurrent = true
 
+	if r.writes != nil {
+		return r.writes.submit(ctx, &pendingWrite{node: node})
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.insertNode(ctx, tx, node); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	r.emitNodeCreated(node)
+	return nil
+}
+
+// insertNode writes the first version of a node in tx
+func (r *SQLiteRepository) insertNode(ctx context.Context, tx *observedTx, node *core.Node) error {
 	metaJSON, err := json.Marshal(node.Meta)
 	if err != nil {
 		return fmt.Errorf("marshaling meta: %w", err)
 	}
 
+	content, contentHash, err := r.storeContent(ctx, tx, node.Content)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO nodes (version_id, id, version, is_current, type, content, properties, created_at, modified_at, deleted, degree)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0)
+		INSERT INTO nodes (version_id, id, version, is_current, type, content, content_hash, properties,
+		                   created_at, modified_at, created_unix, modified_unix, deleted, degree)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0)
 	`
 
-	_, err = r.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		node.VersionID,
 		node.ID,
 		node.Version,
 		boolToInt(node.IsCurrent),
 		node.Type,
-		string(node.Content),
+		content,
+		contentHash,
 		string(metaJSON),
 		node.Created.Format(time.RFC3339),
 		node.Modified.Format(time.RFC3339),
+		node.Created.Unix(),
+		node.Modified.Unix(),
 	)
 	if err != nil {
 		return fmt.Errorf("inserting node: %w", err)
 	}
+	return nil
+}
 
-	// Emit event
+// emitNodeCreated announces a committed node
+func (r *SQLiteRepository) emitNodeCreated(node *core.Node) {
 	r.emit(subscriptions.Event{
 		ID:        uuid.New().String(),
 		Type:      subscriptions.EventNodeCreated,
@@ -117,61 +281,60 @@ func (r *SQLiteRepository) CreateNode(ctx context.Context, node *core.Node) erro
 		NodeType:  node.Type,
 		Meta:      node.Meta,
 	})
-
-	return nil
 }
 
 // GetNode retrieves the current version of a node by ID
 func (r *SQLiteRepository) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	if IncludesDeleted(ctx) {
+		return r.getNodeIncludingDeleted(ctx, id)
+	}
+
 	query := `
-		SELECT version_id, id, version, is_current, type, content, properties,
-		       created_at, modified_at, deleted, deleted_at, change_note, changed_by, degree
+		SELECT ` + nodeColumns + `
 		FROM nodes
 		WHERE id = ? AND is_current = 1 AND deleted = 0
 	`
 
-	row := r.db.QueryRowContext(ctx, query, id)
+	row := r.db.queryRowCached(ctx, query, id)
 	return r.scanNode(row)
 }
 
 // GetNodeAtVersion retrieves a specific version of a node
 func (r *SQLiteRepository) GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error) {
 	query := `
-		SELECT version_id, id, version, is_current, type, content, properties,
-		       created_at, modified_at, deleted, deleted_at, change_note, changed_by, degree
+		SELECT ` + nodeColumns + `
 		FROM nodes
 		WHERE id = ? AND version = ?
 	`
 
-	row := r.db.QueryRowContext(ctx, query, id, version)
+	row := r.db.queryRowCached(ctx, query, id, version)
 	return r.scanNode(row)
 }
 
 // GetNodeAtTime retrieves the version of a node that was current at a specific time
 func (r *SQLiteRepository) GetNodeAtTime(ctx context.Context, id string, asOf time.Time) (*core.Node, error) {
 	query := `
-		SELECT version_id, id, version, is_current, type, content, properties,
-		       created_at, modified_at, deleted, deleted_at, change_note, changed_by, degree
+		SELECT ` + nodeColumns + `
 		FROM nodes
-		WHERE id = ? AND modified_at <= ?
+		WHERE id = ? AND modified_unix <= ?
 		ORDER BY version DESC
 		LIMIT 1
 	`
 
-	row := r.db.QueryRowContext(ctx, query, id, asOf.Format(time.RFC3339))
+	row := r.db.queryRowCached(ctx, query, id, asOf.Unix())
 	return r.scanNode(row)
 }
 
 // GetNodeHistory returns all versions of a node ordered by version descending
 func (r *SQLiteRepository) GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error) {
 	query := `
-		SELECT version, version_id, modified_at, change_note, changed_by, is_current
+		SELECT version, version_id, modified_unix, change_note, changed_by, is_current
 		FROM nodes
 		WHERE id = ?
 		ORDER BY version DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, id)
+	rows, err := r.db.queryCached(ctx, query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -180,17 +343,15 @@ func (r *SQLiteRepository) GetNodeHistory(ctx context.Context, id string) ([]cor
 	var versions []core.VersionInfo
 	for rows.Next() {
 		var vi core.VersionInfo
-		var modifiedAt string
+		var modified sql.NullInt64
 		var changeNote, changedBy sql.NullString
 		var isCurrent int
 
-		if err := rows.Scan(&vi.Version, &vi.VersionID, &modifiedAt, &changeNote, &changedBy, &isCurrent); err != nil {
+		if err := rows.Scan(&vi.Version, &vi.VersionID, &modified, &changeNote, &changedBy, &isCurrent); err != nil {
 			return nil, err
 		}
 
-		if t, err := time.Parse(time.RFC3339, modifiedAt); err == nil {
-			vi.Modified = t
-		}
+		vi.Modified = unixTime(modified)
 		if changeNote.Valid {
 			vi.ChangeNote = changeNote.String
 		}
@@ -212,12 +373,12 @@ func (r *SQLiteRepository) GetNodeHistory(ctx context.Context, id string) ([]cor
 // GetLinks retrieves all links for a node
 func (r *SQLiteRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
 	query := `
-		SELECT source_id, target_id, type, properties, created_at, modified_at
+		SELECT source_id, target_id, type, properties, created_at, modified_at, weight, bidirectional
 		FROM links
 		WHERE source_id = ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, nodeID)
+	rows, err := r.db.queryCached(ctx, query, nodeID)
 	if err != nil {
 		return nil, err
 	}
@@ -235,61 +396,134 @@ func (r *SQLiteRepository) GetLinks(ctx context.Context, nodeID string) ([]*core
 	return links, nil
 }
 
+// GetBacklinks retrieves all links pointing at a node, using idx_links_target
+func (r *SQLiteRepository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	query := `
+		SELECT source_id, target_id, type, properties, created_at, modified_at, weight, bidirectional
+		FROM links
+		WHERE target_id = ?
+		ORDER BY type, source_id
+	`
+
+	rows, err := r.db.queryCached(ctx, query, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*core.Link
+	for rows.Next() {
+		link, err := r.scanLink(rows)
+		if err != nil {
+			continue
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
 // SearchNodes performs full-text search using FTS5
 func (r *SQLiteRepository) SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
 	// Escape special FTS5 characters and wrap in quotes for phrase search
 	escapedTerm := strings.ReplaceAll(searchTerm, "\"", "\"\"")
-	ftsQuery := fmt.Sprintf("\"%s\"", escapedTerm)
+	match := fmt.Sprintf("\"%s\"", escapedTerm)
+	inNamespace, nsArgs := namespaceClause(ctx, "n")
+	if opts := searchOptionsFrom(ctx); opts != nil {
+		if groups := opts.groups(searchTerm); len(groups) > 0 {
+			match = ftsQuery(groups, opts.Stem)
+		}
+	}
 
+	visible, deletedAt := visibleNodes(ctx, "n")
 	query := `
-		SELECT n.version_id, n.id, n.version, n.is_current, n.type, n.content, n.properties,
-		       n.created_at, n.modified_at, n.deleted, n.deleted_at, n.change_note, n.changed_by, n.degree
+		SELECT ` + nNodeColumns + `, ` + deletedAt + `
 		FROM nodes n
 		JOIN nodes_fts fts ON n.rowid = fts.rowid
 		WHERE nodes_fts MATCH ?
-		  AND n.is_current = 1 AND n.deleted = 0
+		  AND ` + visible + inNamespace + `
 		ORDER BY rank
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, ftsQuery, limit, offset)
+	args := append(append([]interface{}{match}, nsArgs...), limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		// Fallback to LIKE search if FTS fails
 		return r.searchNodesLike(ctx, searchTerm, limit, offset)
 	}
 	defer rows.Close()
 
-	return r.scanNodes(rows)
+	return r.scanVisible(rows)
 }
 
 // searchNodesLike is a fallback search using LIKE
 func (r *SQLiteRepository) searchNodesLike(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
 	likeTerm := "%" + searchTerm + "%"
+	inNamespace, nsArgs := namespaceClause(ctx, "nodes")
+	visible, deletedAt := visibleNodes(ctx, "nodes")
 	query := `
-		SELECT version_id, id, version, is_current, type, content, properties,
-		       created_at, modified_at, deleted, deleted_at, change_note, changed_by, degree
+		SELECT ` + nodeColumns + `, ` + deletedAt + `
 		FROM nodes
-		WHERE is_current = 1 AND deleted = 0
-		  AND (id LIKE ? OR type LIKE ? OR properties LIKE ? OR content LIKE ?)
+		WHERE ` + visible + inNamespace + `
+		  AND (id LIKE ? OR type LIKE ? OR properties LIKE ? OR ` + contentColumn + ` LIKE ?)
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, likeTerm, likeTerm, likeTerm, likeTerm, limit, offset)
+	args := append(nsArgs, likeTerm, likeTerm, likeTerm, likeTerm, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return r.scanNodes(rows)
+	return r.scanVisible(rows)
+}
+
+// SearchSimilar looks up nodes sharing trigrams with the term in the
+// trigram name index and ranks them by similarity
+func (r *SQLiteRepository) SearchSimilar(ctx context.Context, term string, limit int) ([]*SimilarNode, error) {
+	grams := termTrigrams(term)
+	if len(grams) == 0 {
+		return []*SimilarNode{}, nil
+	}
+	quoted := make([]string, len(grams))
+	for i, g := range grams {
+		quoted[i] = `"` + strings.ReplaceAll(g, `"`, `""`) + `"`
+	}
+
+	inNamespace, nsArgs := namespaceClause(ctx, "n")
+	visible, deletedAt := visibleNodes(ctx, "n")
+	query := `
+		SELECT ` + nNodeColumns + `, ` + deletedAt + `
+		FROM nodes_trigram tg
+		JOIN nodes n ON n.rowid = tg.rowid
+		WHERE nodes_trigram MATCH ?
+		  AND ` + visible + inNamespace + `
+		ORDER BY tg.rank
+		LIMIT ?
+	`
+	args := append(append([]interface{}{strings.Join(quoted, " OR ")}, nsArgs...), maxSimilarCandidates)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes, err := r.scanVisible(rows)
+	if err != nil {
+		return nil, err
+	}
+	return rankSimilar(term, nodes, limit), nil
 }
 
 // FilterNodes returns nodes matching filter criteria
 func (r *SQLiteRepository) FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error) {
+	visible, deletedAt := visibleNodes(ctx, "nodes")
 	query := `
-		SELECT version_id, id, version, is_current, type, content, properties,
-		       created_at, modified_at, deleted, deleted_at, change_note, changed_by, degree
+		SELECT ` + nodeColumns + `, ` + deletedAt + `
 		FROM nodes
-		WHERE is_current = 1 AND deleted = 0
+		WHERE ` + visible + `
 	`
 	args := []interface{}{}
 
@@ -317,12 +551,41 @@ func (r *SQLiteRepository) FilterNodes(ctx context.Context, nodeTypes []string,
 	}
 	defer rows.Close()
 
-	return r.scanNodes(rows)
+	return r.scanVisible(rows)
 }
 
-// TraverseGraph performs graph traversal using recursive CTE
+// TraverseGraph performs graph traversal using recursive CTE, or level by
+// level when the context carries a traversal budget
 func (r *SQLiteRepository) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
-	// Build the recursive CTE query
+	if meter := TraversalMeterFrom(ctx); meter != nil {
+		return r.traverseMetered(ctx, meter, startNodeID, depth, relationshipTypes, limit, offset)
+	}
+
+	query, args := traverseQuery(startNodeID, depth, relationshipTypes, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes, err := r.scanNodes(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*core.Node)
+	for _, node := range nodes {
+		result[node.ID] = node
+	}
+
+	return result, nil
+}
+
+// traverseQuery builds the recursive CTE selecting the current nodes
+// within depth outgoing hops of a start node. Sorting makes SQLite finish
+// the recursion before returning the first row, while the driver can still
+// interrupt it when the context is cancelled; it also keeps pages stable.
+func traverseQuery(startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (string, []interface{}) {
 	relTypeFilter := ""
 	args := []interface{}{startNodeID, depth}
 
@@ -337,21 +600,155 @@ func (r *SQLiteRepository) TraverseGraph(ctx context.Context, startNodeID string
 
 	query := fmt.Sprintf(`
 		WITH RECURSIVE traverse(id, depth) AS (
-			SELECT ?, 0
-			UNION ALL
+			SELECT CAST(? AS TEXT), 0
+			UNION
 			SELECT l.target_id, t.depth + 1
 			FROM traverse t
 			JOIN links l ON l.source_id = t.id
 			WHERE t.depth < ?%s
 		)
-		SELECT DISTINCT n.version_id, n.id, n.version, n.is_current, n.type, n.content, n.properties,
-		       n.created_at, n.modified_at, n.deleted, n.deleted_at, n.change_note, n.changed_by, n.degree
+		SELECT DISTINCT `+nNodeColumns+`
 		FROM traverse t
 		JOIN nodes n ON n.id = t.id
 		WHERE n.is_current = 1 AND n.deleted = 0
+		ORDER BY n.id
 		LIMIT ? OFFSET ?
 	`, relTypeFilter)
 
+	return query, append(args, limit, offset)
+}
+
+// traverseBatch caps the IDs bound into one IN list by traverseMetered
+const traverseBatch = 500
+
+// traverseMetered walks outgoing links one level at a time, counting each
+// link and newly reached node against meter and stopping when it runs out
+func (r *SQLiteRepository) traverseMetered(ctx context.Context, meter *TraversalMeter, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
+	result := make(map[string]*core.Node)
+	if !meter.Node() {
+		return result, nil
+	}
+	seen := map[string]bool{startNodeID: true}
+	order := []string{startNodeID}
+	frontier := []string{startNodeID}
+	typeFilter, typeArgs := "", []interface{}{}
+	if len(relationshipTypes) > 0 {
+		typeFilter = " AND type IN (" + strings.TrimSuffix(strings.Repeat("?,", len(relationshipTypes)), ",") + ")"
+		for _, t := range relationshipTypes {
+			typeArgs = append(typeArgs, t)
+		}
+	}
+
+walk:
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for start := 0; start < len(frontier); start += traverseBatch {
+			batch := frontier[start:min(start+traverseBatch, len(frontier))]
+			args := make([]interface{}, 0, len(batch)+len(typeArgs))
+			for _, id := range batch {
+				args = append(args, id)
+			}
+			args = append(args, typeArgs...)
+			rows, err := r.db.QueryContext(ctx, `
+				SELECT target_id FROM links
+				WHERE source_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")+`)`+typeFilter, args...)
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var target string
+				if err := rows.Scan(&target); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				if !meter.Edge() {
+					rows.Close()
+					break walk
+				}
+				if seen[target] {
+					continue
+				}
+				if !meter.Node() {
+					rows.Close()
+					break walk
+				}
+				seen[target] = true
+				order = append(order, target)
+				next = append(next, target)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+		}
+		frontier = next
+	}
+
+	// Load the current, undeleted nodes reached, keeping BFS order for paging
+	found := make(map[string]*core.Node, len(order))
+	for start := 0; start < len(order); start += traverseBatch {
+		batch := order[start:min(start+traverseBatch, len(order))]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		rows, err := r.db.QueryContext(ctx, `
+			SELECT `+nodeColumns+`
+			FROM nodes
+			WHERE is_current = 1 AND deleted = 0 AND id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")+`)
+		`, args...)
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := r.scanNodes(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			found[node.ID] = node
+		}
+	}
+	skipped := 0
+	for _, id := range order {
+		node := found[id]
+		if node == nil {
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		result[id] = node
+	}
+	return result, nil
+}
+
+// QueryTimeRange returns current nodes created or modified within [from, to]
+func (r *SQLiteRepository) QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error) {
+	fromUnix, toUnix := from.Unix(), to.Unix()
+
+	query := `
+		SELECT ` + nodeColumns + `
+		FROM nodes
+		WHERE is_current = 1 AND deleted = 0
+		  AND ((created_unix >= ? AND created_unix <= ?) OR (modified_unix >= ? AND modified_unix <= ?))
+	`
+	args := []interface{}{fromUnix, toUnix, fromUnix, toUnix}
+
+	if len(nodeTypes) > 0 {
+		placeholders := make([]string, len(nodeTypes))
+		for i, t := range nodeTypes {
+			placeholders[i] = "?"
+			args = append(args, t)
+		}
+		query += " AND type IN (" + strings.Join(placeholders, ",") + ")"
+	}
+
+	query += " ORDER BY modified_unix DESC LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -360,48 +757,57 @@ func (r *SQLiteRepository) TraverseGraph(ctx context.Context, startNodeID string
 	}
 	defer rows.Close()
 
-	nodes, err := r.scanNodes(rows)
-	if err != nil {
-		return nil, err
-	}
-
-	result := make(map[string]*core.Node)
-	for _, node := range nodes {
-		result[node.ID] = node
-	}
-
-	return result, nil
+	return r.scanNodes(rows)
 }
 
 // CreateLink creates a relationship between two nodes
 func (r *SQLiteRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	if r.writes != nil {
+		return r.writes.submit(ctx, &pendingWrite{link: link})
+	}
+	if err := r.insertLink(ctx, r.db, link); err != nil {
+		return err
+	}
+	r.emitLinkCreated(link)
+	return nil
+}
+
+// insertLink writes a link and counts it in its endpoints' degrees
+func (r *SQLiteRepository) insertLink(ctx context.Context, ex execer, link *core.Link) error {
+	PromoteLinkFields(link)
 	metaJSON, err := json.Marshal(link.Meta)
 	if err != nil {
 		return fmt.Errorf("marshaling meta: %w", err)
 	}
 
 	query := `
-		INSERT INTO links (source_id, target_id, type, properties, created_at, modified_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO links (source_id, target_id, type, properties, created_at, modified_at, weight, bidirectional)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = r.db.ExecContext(ctx, query,
+	_, err = ex.ExecContext(ctx, query,
 		link.Source,
 		link.Target,
 		link.Type,
 		string(metaJSON),
 		link.Created.Format(time.RFC3339),
 		link.Modified.Format(time.RFC3339),
+		link.Weight,
+		boolToInt(link.Bidirectional),
 	)
 	if err != nil {
 		return fmt.Errorf("inserting link: %w", err)
 	}
 
 	// Update degree counts
-	r.updateDegree(ctx, link.Source, 1)
-	r.updateDegree(ctx, link.Target, 1)
+	for _, id := range []string{link.Source, link.Target} {
+		ex.ExecContext(ctx, `UPDATE nodes SET degree = degree + 1 WHERE id = ? AND is_current = 1`, id)
+	}
+	return nil
+}
 
-	// Emit event
+// emitLinkCreated announces a committed link
+func (r *SQLiteRepository) emitLinkCreated(link *core.Link) {
 	r.emit(subscriptions.Event{
 		ID:         uuid.New().String(),
 		Type:       subscriptions.EventLinkCreated,
@@ -411,15 +817,29 @@ func (r *SQLiteRepository) CreateLink(ctx context.Context, link *core.Link) erro
 		LinkType:   link.Type,
 		Meta:       link.Meta,
 	})
-
-	return nil
 }
 
 // DeleteLink deletes a specific relationship between two nodes
 func (r *SQLiteRepository) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Record the removal for graph diffs
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO link_tombstones (source_id, target_id, type, properties, created_at, deleted_at, weight, bidirectional)
+		SELECT source_id, target_id, type, properties, created_at, ?, weight, bidirectional
+		FROM links WHERE source_id = ? AND target_id = ? AND type = ?
+	`, time.Now().Format(time.RFC3339), sourceID, targetID, linkType)
+	if err != nil {
+		return fmt.Errorf("recording link removal: %w", err)
+	}
+
 	query := `DELETE FROM links WHERE source_id = ? AND target_id = ? AND type = ?`
 
-	result, err := r.db.ExecContext(ctx, query, sourceID, targetID, linkType)
+	result, err := tx.ExecContext(ctx, query, sourceID, targetID, linkType)
 	if err != nil {
 		return err
 	}
@@ -433,6 +853,10 @@ func (r *SQLiteRepository) DeleteLink(ctx context.Context, sourceID string, targ
 		return fmt.Errorf("link not found: %s -[%s]-> %s", sourceID, linkType, targetID)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
 	// Update degree counts
 	r.updateDegree(ctx, sourceID, -1)
 	r.updateDegree(ctx, targetID, -1)
@@ -526,20 +950,28 @@ func (r *SQLiteRepository) UpdateNodeMetaWithNote(ctx context.Context, id string
 		}
 	}
 
+	content, contentHash, err := r.storeContent(ctx, tx, current.Content)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO nodes (version_id, id, version, is_current, type, content, properties,
-		                   created_at, modified_at, deleted, degree, change_note, changed_by)
-		VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?, 0, ?, ?, ?)
+		INSERT INTO nodes (version_id, id, version, is_current, type, content, content_hash, properties,
+		                   created_at, modified_at, created_unix, modified_unix, deleted, degree, change_note, changed_by)
+		VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?)
 	`
 	_, err = tx.ExecContext(ctx, query,
 		newVersionID,
 		id,
 		newVersion,
 		current.Type,
-		string(current.Content),
+		content,
+		contentHash,
 		string(metaJSON),
 		current.Created.Format(time.RFC3339),
 		now.Format(time.RFC3339),
+		current.Created.Unix(),
+		now.Unix(),
 		degree,
 		changeNote,
 		changedBy,
@@ -585,21 +1017,40 @@ func (r *SQLiteRepository) DeleteNode(ctx context.Context, nodeID string, force
 		return fmt.Errorf("node not found or already deleted: %s", nodeID)
 	}
 
+	// Protected nodes cannot be deleted, even with force
+	if IsProtected(current.Meta) {
+		return fmt.Errorf("cannot delete %s: %w", nodeID, ErrProtected)
+	}
+
 	// Protect Source layer unless force=true
 	if !force && len(nodeID) > 7 && nodeID[:7] == "sha256:" {
 		return fmt.Errorf("cannot delete Source layer node (content-addressed): %s", nodeID)
 	}
 
 	if force {
-		// Hard delete
-		_, err := r.db.ExecContext(ctx, `DELETE FROM nodes WHERE id = ?`, nodeID)
+		// Hard delete, releasing stored content held by any version
+		tx, err := r.db.BeginTx(ctx, nil)
 		if err != nil {
 			return err
 		}
-		_, err = r.db.ExecContext(ctx, `DELETE FROM links WHERE source_id = ? OR target_id = ?`, nodeID, nodeID)
+		defer tx.Rollback()
+
+		if err := releaseContent(ctx, tx, nodeID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM nodes WHERE id = ?`, nodeID); err != nil {
+			return err
+		}
+		if err := collectContent(ctx, tx); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM links WHERE source_id = ? OR target_id = ?`, nodeID, nodeID)
 		if err != nil {
 			return err
 		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
 	} else {
 		// Soft delete: create tombstone version
 		newVersion := current.Version + 1
@@ -621,8 +1072,8 @@ func (r *SQLiteRepository) DeleteNode(ctx context.Context, nodeID string, force
 		// Create tombstone
 		query := `
 			INSERT INTO nodes (version_id, id, version, is_current, type, content, properties,
-			                   created_at, modified_at, deleted, deleted_at, degree, change_note)
-			VALUES (?, ?, ?, 1, ?, '', '{}', ?, ?, 1, ?, 0, 'Deleted')
+			                   created_at, modified_at, created_unix, modified_unix, deleted, deleted_at, degree, change_note)
+			VALUES (?, ?, ?, 1, ?, '', '{}', ?, ?, ?, ?, 1, ?, 0, 'Deleted')
 		`
 		_, err = tx.ExecContext(ctx, query,
 			newVersionID,
@@ -631,6 +1082,8 @@ func (r *SQLiteRepository) DeleteNode(ctx context.Context, nodeID string, force
 			current.Type,
 			current.Created.Format(time.RFC3339),
 			now.Format(time.RFC3339),
+			current.Created.Unix(),
+			now.Unix(),
 			now.Format(time.RFC3339),
 		)
 		if err != nil {
@@ -660,77 +1113,73 @@ func (r *SQLiteRepository) DeleteNode(ctx context.Context, nodeID string, force
 	return nil
 }
 
-// GetSubgraph extracts a subgraph centered on a start node
-func (r *SQLiteRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
-	// Get nodes within depth hops
-	nodesMap, err := r.TraverseGraph(ctx, startNodeID, depth, relationshipTypes, 1000, 0)
-	if err != nil {
+// GetGraphSnapshot returns current nodes (optionally filtered by type, up to limit)
+// and all links between them
+func (r *SQLiteRepository) GetGraphSnapshot(ctx context.Context, nodeTypes []string, limit int) (*Subgraph, error) {
+	selection := `SELECT id FROM nodes WHERE is_current = 1 AND deleted = 0`
+	var selArgs []interface{}
+	if len(nodeTypes) > 0 {
+		placeholders := make([]string, len(nodeTypes))
+		for i, t := range nodeTypes {
+			placeholders[i] = "?"
+			selArgs = append(selArgs, t)
+		}
+		selection += " AND type IN (" + strings.Join(placeholders, ",") + ")"
+	}
+	selection += " ORDER BY id LIMIT ?"
+	selArgs = append(selArgs, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH sel AS (`+selection+`)
+		SELECT `+nodeColumns+`
+		FROM nodes
+		WHERE is_current = 1 AND deleted = 0 AND id IN (SELECT id FROM sel)
+		ORDER BY id
+	`, selArgs...)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := r.scanNodes(rows)
+	rows.Close()
+	if err != nil {
 		return nil, err
 	}
 
-	// Collect node IDs
-	nodeIDs := make([]string, 0, len(nodesMap))
-	for id := range nodesMap {
-		nodeIDs = append(nodeIDs, id)
+	rows, err = r.db.QueryContext(ctx, `
+		WITH sel AS (`+selection+`)
+		SELECT source_id, target_id, type, properties, weight, bidirectional
+		FROM links
+		WHERE source_id IN (SELECT id FROM sel) AND target_id IN (SELECT id FROM sel)
+		ORDER BY source_id, target_id, type
+	`, selArgs...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Get edges between these nodes
 	var edges []*SubgraphEdge
-	if len(nodeIDs) > 0 {
-		placeholders := make([]string, len(nodeIDs))
-		args := make([]interface{}, len(nodeIDs)*2)
-		for i, id := range nodeIDs {
-			placeholders[i] = "?"
-			args[i] = id
-			args[i+len(nodeIDs)] = id
-		}
-
-		query := fmt.Sprintf(`
-			SELECT source_id, target_id, type, properties
-			FROM links
-			WHERE source_id IN (%s) AND target_id IN (%s)
-		`, strings.Join(placeholders, ","), strings.Join(placeholders, ","))
-
-		if len(relationshipTypes) > 0 {
-			typePlaceholders := make([]string, len(relationshipTypes))
-			for i, t := range relationshipTypes {
-				typePlaceholders[i] = "?"
-				args = append(args, t)
-			}
-			query += " AND type IN (" + strings.Join(typePlaceholders, ",") + ")"
-		}
-
-		rows, err := r.db.QueryContext(ctx, query, args...)
-		if err != nil {
-			return nil, err
+	for rows.Next() {
+		var sourceID, targetID, linkType string
+		var propsStr sql.NullString
+		var weight float64
+		var bidirectional int
+		if err := rows.Scan(&sourceID, &targetID, &linkType, &propsStr, &weight, &bidirectional); err != nil {
+			continue
 		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var sourceID, targetID, linkType string
-			var propsStr sql.NullString
-			if err := rows.Scan(&sourceID, &targetID, &linkType, &propsStr); err != nil {
-				continue
-			}
-
-			var meta map[string]interface{}
-			if propsStr.Valid {
-				json.Unmarshal([]byte(propsStr.String), &meta)
-			}
 
-			edges = append(edges, &SubgraphEdge{
-				Source: sourceID,
-				Target: targetID,
-				Type:   linkType,
-				Meta:   meta,
-			})
+		var meta map[string]interface{}
+		if propsStr.Valid {
+			json.Unmarshal([]byte(propsStr.String), &meta)
 		}
-	}
 
-	// Convert map to slice
-	nodes := make([]*core.Node, 0, len(nodesMap))
-	for _, node := range nodesMap {
-		nodes = append(nodes, node)
+		edges = append(edges, &SubgraphEdge{
+			Source:        sourceID,
+			Target:        targetID,
+			Type:          linkType,
+			Meta:          meta,
+			Weight:        weight,
+			Bidirectional: bidirectional == 1,
+		})
 	}
 
 	return &Subgraph{
@@ -739,7 +1188,6 @@ func (r *SQLiteRepository) GetSubgraph(ctx context.Context, startNodeID string,
 		Stats: SubgraphStats{
 			NodeCount: len(nodes),
 			EdgeCount: len(edges),
-			Depth:     depth,
 		},
 	}, nil
 }
@@ -748,58 +1196,35 @@ func (r *SQLiteRepository) GetSubgraph(ctx context.Context, startNodeID string,
 func (r *SQLiteRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
 	// Check if ATTENDED edge exists
 	var propsStr sql.NullString
+	edge := &core.Link{}
 	err := r.db.QueryRowContext(ctx,
-		`SELECT properties FROM links WHERE source_id = ? AND target_id = ? AND type = 'ATTENDED'`,
-		source, target).Scan(&propsStr)
+		`SELECT properties, weight FROM links WHERE source_id = ? AND target_id = ? AND type = 'ATTENDED'`,
+		source, target).Scan(&propsStr, &edge.Weight)
 
 	if err == sql.ErrNoRows {
 		// Create new edge
-		meta := map[string]interface{}{
-			"weight":        weight,
-			"query_count":   1,
-			"last_updated":  time.Now().Format(time.RFC3339),
-			"last_query_id": queryID,
-		}
-		metaJSON, _ := json.Marshal(meta)
+		applyAttention(edge, queryID, AttentionHeadFrom(ctx), weight, time.Now())
+		metaJSON, _ := json.Marshal(edge.Meta)
 
 		_, err = r.db.ExecContext(ctx,
-			`INSERT INTO links (source_id, target_id, type, properties, created_at, modified_at) VALUES (?, ?, 'ATTENDED', ?, ?, ?)`,
-			source, target, string(metaJSON), time.Now().Format(time.RFC3339), time.Now().Format(time.RFC3339))
+			`INSERT INTO links (source_id, target_id, type, properties, created_at, modified_at, weight) VALUES (?, ?, 'ATTENDED', ?, ?, ?, ?)`,
+			source, target, string(metaJSON), time.Now().Format(time.RFC3339), time.Now().Format(time.RFC3339), edge.Weight)
 		return err
 	} else if err != nil {
 		return err
 	}
 
 	// Update existing edge
-	var meta map[string]interface{}
 	if propsStr.Valid {
-		json.Unmarshal([]byte(propsStr.String), &meta)
-	} else {
-		meta = make(map[string]interface{})
-	}
-
-	currentWeight := 0.0
-	currentCount := 0.0
-	if w, ok := meta["weight"].(float64); ok {
-		currentWeight = w
-	}
-	if c, ok := meta["query_count"].(float64); ok {
-		currentCount = c
+		json.Unmarshal([]byte(propsStr.String), &edge.Meta)
 	}
 
-	newWeight := (currentWeight*currentCount + weight) / (currentCount + 1)
-	newCount := currentCount + 1
-
-	meta["weight"] = newWeight
-	meta["query_count"] = newCount
-	meta["last_updated"] = time.Now().Format(time.RFC3339)
-	meta["last_query_id"] = queryID
-
-	metaJSON, _ := json.Marshal(meta)
+	applyAttention(edge, queryID, AttentionHeadFrom(ctx), weight, time.Now())
+	metaJSON, _ := json.Marshal(edge.Meta)
 
 	_, err = r.db.ExecContext(ctx,
-		`UPDATE links SET properties = ?, modified_at = ? WHERE source_id = ? AND target_id = ? AND type = 'ATTENDED'`,
-		string(metaJSON), time.Now().Format(time.RFC3339), source, target)
+		`UPDATE links SET properties = ?, weight = ?, modified_at = ? WHERE source_id = ? AND target_id = ? AND type = 'ATTENDED'`,
+		string(metaJSON), edge.Weight, time.Now().Format(time.RFC3339), source, target)
 	return err
 }
 
@@ -807,9 +1232,8 @@ func (r *SQLiteRepository) UpdateAttentionEdge(ctx context.Context, source, targ
 func (r *SQLiteRepository) GetAttentionSubgraph(ctx context.Context, startNodeID string, minWeight float64, maxNodes int) (*Subgraph, error) {
 	// Get nodes connected by ATTENDED edges
 	query := `
-		SELECT DISTINCT n.version_id, n.id, n.version, n.is_current, n.type, n.content, n.properties,
-		       n.created_at, n.modified_at, n.deleted, n.deleted_at, n.change_note, n.changed_by, n.degree,
-		       l.properties as link_props
+		SELECT DISTINCT ` + nNodeColumns + `,
+		       l.properties as link_props, l.weight as link_weight
 		FROM links l
 		JOIN nodes n ON (n.id = l.target_id OR n.id = l.source_id) AND n.id != ?
 		WHERE (l.source_id = ? OR l.target_id = ?)
@@ -825,58 +1249,25 @@ func (r *SQLiteRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 	defer rows.Close()
 
 	nodeMap := make(map[string]*core.Node)
+	var linkProps sql.NullString
+	var linkWeight float64
+	scanner := r.scanner(&linkProps, &linkWeight)
 	for rows.Next() {
-		var versionID, id, nodeType string
-		var version, isCurrent, deleted, degree int
-		var content, properties, createdAt, modifiedAt string
-		var deletedAt, changeNote, changedBy, linkProps sql.NullString
-
-		if err := rows.Scan(&versionID, &id, &version, &isCurrent, &nodeType, &content, &properties,
-			&createdAt, &modifiedAt, &deleted, &deletedAt, &changeNote, &changedBy, &degree, &linkProps); err != nil {
+		node, err := scanner.scan(rows)
+		if err != nil {
 			continue
 		}
 
-		// Filter by weight
+		// Filter by weight, for the attention head if there is one
+		var meta map[string]interface{}
 		if linkProps.Valid {
-			var meta map[string]interface{}
 			json.Unmarshal([]byte(linkProps.String), &meta)
-			if w, ok := meta["weight"].(float64); ok && w < minWeight {
-				continue
-			}
-		}
-
-		node := &core.Node{
-			VersionID: versionID,
-			ID:        id,
-			Version:   version,
-			IsCurrent: isCurrent == 1,
-			Type:      nodeType,
-			Content:   []byte(content),
-			Deleted:   deleted == 1,
-		}
-
-		if properties != "" {
-			json.Unmarshal([]byte(properties), &node.Meta)
-		}
-		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-			node.Created = t
-		}
-		if t, err := time.Parse(time.RFC3339, modifiedAt); err == nil {
-			node.Modified = t
-		}
-		if deletedAt.Valid {
-			if t, err := time.Parse(time.RFC3339, deletedAt.String); err == nil {
-				node.DeletedAt = t
-			}
-		}
-		if changeNote.Valid {
-			node.ChangeNote = changeNote.String
 		}
-		if changedBy.Valid {
-			node.ChangedBy = changedBy.String
+		if !attentionPasses(ctx, linkWeight, meta, minWeight) {
+			continue
 		}
 
-		nodeMap[id] = node
+		nodeMap[node.ID] = node
 	}
 
 	// Get edges between these nodes
@@ -896,7 +1287,7 @@ func (r *SQLiteRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 		}
 
 		edgeQuery := fmt.Sprintf(`
-			SELECT source_id, target_id, type, properties
+			SELECT source_id, target_id, type, properties, weight
 			FROM links
 			WHERE source_id IN (%s) AND target_id IN (%s) AND type = 'ATTENDED'
 		`, strings.Join(placeholders, ","), strings.Join(placeholders, ","))
@@ -907,7 +1298,8 @@ func (r *SQLiteRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 			for edgeRows.Next() {
 				var sourceID, targetID, linkType string
 				var propsStr sql.NullString
-				if err := edgeRows.Scan(&sourceID, &targetID, &linkType, &propsStr); err != nil {
+				var weight float64
+				if err := edgeRows.Scan(&sourceID, &targetID, &linkType, &propsStr, &weight); err != nil {
 					continue
 				}
 
@@ -915,12 +1307,16 @@ func (r *SQLiteRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 				if propsStr.Valid {
 					json.Unmarshal([]byte(propsStr.String), &meta)
 				}
+				if !inAttentionHead(ctx, meta) {
+					continue
+				}
 
 				edges = append(edges, &SubgraphEdge{
 					Source: sourceID,
 					Target: targetID,
 					Type:   linkType,
 					Meta:   meta,
+					Weight: weight,
 				})
 			}
 		}
@@ -942,10 +1338,16 @@ func (r *SQLiteRepository) GetAttentionSubgraph(ctx context.Context, startNodeID
 	}, nil
 }
 
-// PruneWeakAttentionEdges removes attention edges with low weight or query count
+// PruneWeakAttentionEdges removes attention edges with low weight or query count.
+// Edges touching protected nodes are kept.
 func (r *SQLiteRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight float64, minQueryCount int) (int, error) {
+	protected, err := r.flaggedNodes(ctx, ProtectedKey)
+	if err != nil {
+		return 0, err
+	}
+
 	// Get all ATTENDED edges
-	rows, err := r.db.QueryContext(ctx, `SELECT id, properties FROM links WHERE type = 'ATTENDED'`)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, properties, weight, source_id, target_id FROM links WHERE type = 'ATTENDED'`)
 	if err != nil {
 		return 0, err
 	}
@@ -955,7 +1357,15 @@ func (r *SQLiteRepository) PruneWeakAttentionEdges(ctx context.Context, minWeigh
 	for rows.Next() {
 		var id int64
 		var propsStr sql.NullString
-		if err := rows.Scan(&id, &propsStr); err != nil {
+		var weight float64
+		var source, target string
+		if err := rows.Scan(&id, &propsStr, &weight, &source, &target); err != nil {
+			continue
+		}
+		if _, ok := protected[source]; ok {
+			continue
+		}
+		if _, ok := protected[target]; ok {
 			continue
 		}
 
@@ -964,7 +1374,7 @@ func (r *SQLiteRepository) PruneWeakAttentionEdges(ctx context.Context, minWeigh
 			json.Unmarshal([]byte(propsStr.String), &meta)
 
 			shouldDelete := false
-			if w, ok := meta["weight"].(float64); ok && w < minWeight {
+			if weight < minWeight {
 				shouldDelete = true
 			}
 			if c, ok := meta["query_count"].(float64); ok && int(c) < minQueryCount {
@@ -1070,7 +1480,16 @@ func (r *SQLiteRepository) GetGraphMap(ctx context.Context, sampleSize int) (*Gr
 		})
 	}
 
-	// Get samples per type
+	pinned, err := r.flaggedNodes(ctx, PinnedKey)
+	if err != nil {
+		return nil, err
+	}
+	pinnedByType := make(map[string][]string)
+	for id, nodeType := range pinned {
+		pinnedByType[nodeType] = append(pinnedByType[nodeType], id)
+	}
+
+	// Get samples per type, pinned nodes first
 	for nodeType := range graphMap.NodeTypes {
 		sampleRows, err := r.db.QueryContext(ctx, `
 			SELECT id FROM nodes WHERE type = ? AND is_current = 1 AND deleted = 0 LIMIT ?
@@ -1088,17 +1507,282 @@ func (r *SQLiteRepository) GetGraphMap(ctx context.Context, sampleSize int) (*Gr
 			samples = append(samples, id)
 		}
 		sampleRows.Close()
-		graphMap.SamplesByType[nodeType] = samples
+		sort.Strings(pinnedByType[nodeType])
+		graphMap.SamplesByType[nodeType] = withPinned(pinnedByType[nodeType], samples, sampleSize)
 	}
 
 	return graphMap, nil
 }
 
+// flaggedNodes returns the IDs (mapped to types) of current nodes whose
+// properties set a boolean flag to true
+func (r *SQLiteRepository) flaggedNodes(ctx context.Context, key string) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, type FROM nodes
+		WHERE is_current = 1 AND deleted = 0 AND properties LIKE ?
+	`, "%"+flagPattern(key)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flagged := make(map[string]string)
+	for rows.Next() {
+		var id, nodeType string
+		if err := rows.Scan(&id, &nodeType); err != nil {
+			return nil, err
+		}
+		flagged[id] = nodeType
+	}
+	return flagged, rows.Err()
+}
+
+// GetTimeline returns current nodes created within [from, to] bucketed by day or week
+func (r *SQLiteRepository) GetTimeline(ctx context.Context, from, to time.Time, bucket string, nodeTypes []string, sampleSize int) (*Timeline, error) {
+	if err := ValidateBucket(bucket); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, type, created_unix, degree FROM nodes
+		WHERE is_current = 1 AND deleted = 0
+		  AND created_unix >= ? AND created_unix <= ?
+	`
+	args := []interface{}{from.Unix(), to.Unix()}
+
+	if len(nodeTypes) > 0 {
+		placeholders := make([]string, len(nodeTypes))
+		for i, t := range nodeTypes {
+			placeholders[i] = "?"
+			args = append(args, t)
+		}
+		query += " AND type IN (" + strings.Join(placeholders, ",") + ")"
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []timelineEntry
+	for rows.Next() {
+		var e timelineEntry
+		var created int64
+		if err := rows.Scan(&e.ID, &e.Type, &created, &e.Degree); err != nil {
+			continue
+		}
+		e.Created = time.Unix(created, 0)
+		entries = append(entries, e)
+	}
+
+	return buildTimeline(entries, from, to, bucket, sampleSize), nil
+}
+
+// GetUsage accounts stored node versions per namespace and type
+func (r *SQLiteRepository) GetUsage(ctx context.Context) (*Usage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, type, is_current = 1 AND deleted = 0,
+		       COALESCE(octet_length(content), (SELECT size FROM contents WHERE hash = content_hash), 0),
+		       COALESCE(octet_length(properties), 0)
+		FROM nodes
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []usageEntry
+	for rows.Next() {
+		var e usageEntry
+		if err := rows.Scan(&e.ID, &e.Type, &e.Live, &e.ContentBytes, &e.PropertyBytes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	usage := buildUsage(entries)
+	if r.dedupContent {
+		stats, err := r.contentStoreStats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		usage.ContentStore = stats
+	}
+	return usage, nil
+}
+
+// DiffGraph reports nodes and links that changed in (from, to]
+func (r *SQLiteRepository) DiffGraph(ctx context.Context, from, to time.Time, detailed bool) (*GraphDiff, error) {
+	fromStr := from.Local().Format(time.RFC3339)
+	toStr := to.Local().Format(time.RFC3339)
+
+	// Every node version written in the window (creations, updates, tombstones)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, type, version, modified_unix, deleted FROM nodes
+		WHERE modified_unix > ? AND modified_unix <= ?
+		ORDER BY modified_unix
+	`, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []nodeVersionRow
+	for rows.Next() {
+		var v nodeVersionRow
+		var modified sql.NullInt64
+		var deleted int
+		if err := rows.Scan(&v.ID, &v.Type, &v.Version, &modified, &deleted); err != nil {
+			return nil, err
+		}
+		v.Modified = unixTime(modified)
+		v.Deleted = deleted == 1
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	linksAdded, err := r.queryLinkChanges(ctx, `
+		SELECT source_id, target_id, type, properties, weight, bidirectional, created_at FROM links
+		WHERE created_at > ? AND created_at <= ?
+	`, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+
+	linksRemoved, err := r.queryLinkChanges(ctx, `
+		SELECT source_id, target_id, type, properties, weight, bidirectional, created_at FROM link_tombstones
+		WHERE deleted_at > ? AND deleted_at <= ?
+	`, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildGraphDiff(from, to, versions, linksAdded, linksRemoved, detailed), nil
+}
+
+// ChangeLog returns the changes made after since as events, oldest first
+func (r *SQLiteRepository) ChangeLog(ctx context.Context, since time.Time, limit int) ([]subscriptions.Event, error) {
+	sinceStr := since.Local().Format(time.RFC3339)
+	limit = changeLogLimit(limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, type, version, modified_unix, deleted, COALESCE(change_note, ''), properties FROM nodes
+		WHERE modified_unix > ?
+		ORDER BY modified_unix, version
+		LIMIT ?
+	`, since.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []subscriptions.Event
+	for rows.Next() {
+		var id, nodeType, changeNote string
+		var version, deleted int
+		var modifiedUnix sql.NullInt64
+		var propsStr sql.NullString
+		if err := rows.Scan(&id, &nodeType, &version, &modifiedUnix, &deleted, &changeNote, &propsStr); err != nil {
+			return nil, err
+		}
+		modified := unixTime(modifiedUnix)
+		var meta map[string]interface{}
+		if propsStr.Valid {
+			json.Unmarshal([]byte(propsStr.String), &meta)
+		}
+		events = append(events, nodeVersionEvent(id, nodeType, version, modified, deleted == 1, changeNote, meta))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Links created after since, including ones since removed
+	created, err := r.queryLinkChanges(ctx, `
+		SELECT source_id, target_id, type, properties, weight, bidirectional, created_at FROM (
+			SELECT source_id, target_id, type, properties, weight, bidirectional, created_at FROM links WHERE created_at > ?
+			UNION ALL
+			SELECT source_id, target_id, type, properties, weight, bidirectional, created_at FROM link_tombstones WHERE created_at > ?
+		) AS created ORDER BY created_at LIMIT ?
+	`, sinceStr, sinceStr, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range created {
+		events = append(events, linkEvent(subscriptions.EventLinkCreated, c.Edge, c.Created))
+	}
+
+	// Removals, timed by deleted_at
+	removed, err := r.queryLinkChanges(ctx, `
+		SELECT source_id, target_id, type, properties, weight, bidirectional, deleted_at FROM link_tombstones
+		WHERE deleted_at > ?
+		ORDER BY deleted_at LIMIT ?
+	`, sinceStr, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range removed {
+		events = append(events, linkEvent(subscriptions.EventLinkDeleted, c.Edge, c.Created))
+	}
+
+	return sortChangeLog(events, limit), nil
+}
+
+// queryLinkChanges scans link rows (source, target, type, properties, weight,
+// bidirectional, created_at)
+func (r *SQLiteRepository) queryLinkChanges(ctx context.Context, query string, args ...interface{}) ([]linkChangeRow, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []linkChangeRow
+	for rows.Next() {
+		var sourceID, targetID, linkType, createdAt string
+		var propsStr sql.NullString
+		var weight float64
+		var bidirectional int
+		if err := rows.Scan(&sourceID, &targetID, &linkType, &propsStr, &weight, &bidirectional, &createdAt); err != nil {
+			return nil, err
+		}
+
+		var meta map[string]interface{}
+		if propsStr.Valid {
+			json.Unmarshal([]byte(propsStr.String), &meta)
+		}
+
+		change := linkChangeRow{
+			Edge: &SubgraphEdge{
+				Source:        sourceID,
+				Target:        targetID,
+				Type:          linkType,
+				Meta:          meta,
+				Weight:        weight,
+				Bidirectional: bidirectional == 1,
+			},
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			change.Created = t
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
 // GetEntitiesInterpretedThrough returns entities linked to a lens via INTERPRETED_THROUGH
 func (r *SQLiteRepository) GetEntitiesInterpretedThrough(ctx context.Context, lensID string) ([]*core.Node, error) {
 	query := `
-		SELECT n.version_id, n.id, n.version, n.is_current, n.type, n.content, n.properties,
-		       n.created_at, n.modified_at, n.deleted, n.deleted_at, n.change_note, n.changed_by, n.degree,
+		SELECT ` + nNodeColumns + `,
 		       l.properties as link_props
 		FROM nodes n
 		JOIN links l ON l.source_id = n.id
@@ -1113,30 +1797,14 @@ func (r *SQLiteRepository) GetEntitiesInterpretedThrough(ctx context.Context, le
 	defer rows.Close()
 
 	var nodes []*core.Node
+	var linkProps sql.NullString
+	scanner := r.scanner(&linkProps)
 	for rows.Next() {
-		var versionID, id, nodeType string
-		var version, isCurrent, deleted, degree int
-		var content, properties, createdAt, modifiedAt string
-		var deletedAt, changeNote, changedBy, linkProps sql.NullString
-
-		if err := rows.Scan(&versionID, &id, &version, &isCurrent, &nodeType, &content, &properties,
-			&createdAt, &modifiedAt, &deleted, &deletedAt, &changeNote, &changedBy, &degree, &linkProps); err != nil {
+		node, err := scanner.scan(rows)
+		if err != nil {
 			continue
 		}
 
-		node := &core.Node{
-			VersionID: versionID,
-			ID:        id,
-			Version:   version,
-			IsCurrent: isCurrent == 1,
-			Type:      nodeType,
-			Content:   []byte(content),
-			Deleted:   deleted == 1,
-		}
-
-		if properties != "" {
-			json.Unmarshal([]byte(properties), &node.Meta)
-		}
 		if node.Meta == nil {
 			node.Meta = make(map[string]interface{})
 		}
@@ -1146,12 +1814,6 @@ func (r *SQLiteRepository) GetEntitiesInterpretedThrough(ctx context.Context, le
 				node.Meta["_interpretation"] = linkMeta
 			}
 		}
-		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-			node.Created = t
-		}
-		if t, err := time.Parse(time.RFC3339, modifiedAt); err == nil {
-			node.Modified = t
-		}
 
 		nodes = append(nodes, node)
 	}
@@ -1175,8 +1837,7 @@ func (r *SQLiteRepository) CreateInterpretedThroughLink(ctx context.Context, ent
 // QueryByLens returns entities interpreted through a lens with optional pattern filter
 func (r *SQLiteRepository) QueryByLens(ctx context.Context, lensID string, pattern string, limit int, offset int) ([]*core.Node, error) {
 	query := `
-		SELECT n.version_id, n.id, n.version, n.is_current, n.type, n.content, n.properties,
-		       n.created_at, n.modified_at, n.deleted, n.deleted_at, n.change_note, n.changed_by, n.degree,
+		SELECT ` + nNodeColumns + `,
 		       l.properties as link_props
 		FROM nodes n
 		JOIN links l ON l.source_id = n.id
@@ -1200,30 +1861,14 @@ func (r *SQLiteRepository) QueryByLens(ctx context.Context, lensID string, patte
 	defer rows.Close()
 
 	var nodes []*core.Node
+	var linkProps sql.NullString
+	scanner := r.scanner(&linkProps)
 	for rows.Next() {
-		var versionID, id, nodeType string
-		var version, isCurrent, deleted, degree int
-		var content, properties, createdAt, modifiedAt string
-		var deletedAt, changeNote, changedBy, linkProps sql.NullString
-
-		if err := rows.Scan(&versionID, &id, &version, &isCurrent, &nodeType, &content, &properties,
-			&createdAt, &modifiedAt, &deleted, &deletedAt, &changeNote, &changedBy, &degree, &linkProps); err != nil {
+		node, err := scanner.scan(rows)
+		if err != nil {
 			continue
 		}
 
-		node := &core.Node{
-			VersionID: versionID,
-			ID:        id,
-			Version:   version,
-			IsCurrent: isCurrent == 1,
-			Type:      nodeType,
-			Content:   []byte(content),
-			Deleted:   deleted == 1,
-		}
-
-		if properties != "" {
-			json.Unmarshal([]byte(properties), &node.Meta)
-		}
 		if node.Meta == nil {
 			node.Meta = make(map[string]interface{})
 		}
@@ -1233,12 +1878,6 @@ func (r *SQLiteRepository) QueryByLens(ctx context.Context, lensID string, patte
 				node.Meta["_interpretation"] = linkMeta
 			}
 		}
-		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-			node.Created = t
-		}
-		if t, err := time.Parse(time.RFC3339, modifiedAt); err == nil {
-			node.Modified = t
-		}
 
 		nodes = append(nodes, node)
 	}
@@ -1328,52 +1967,18 @@ func (r *SQLiteRepository) ExportLens(ctx context.Context, lensID string, includ
 
 // CreateSubscriptionNode persists a subscription as a node
 func (r *SQLiteRepository) CreateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
-	patternJSON, err := json.Marshal(sub.Pattern)
-	if err != nil {
-		return fmt.Errorf("marshaling pattern: %w", err)
-	}
-
-	meta := map[string]interface{}{
-		"name":        sub.Name,
-		"description": sub.Description,
-		"pattern":     string(patternJSON),
-		"webhook":     sub.Webhook,
-		"websocket":   sub.WebSocket,
-		"enabled":     sub.Enabled,
-		"fire_count":  sub.FireCount,
-	}
-	metaJSON, err := json.Marshal(meta)
+	node, err := subscriptionNode(sub)
 	if err != nil {
-		return fmt.Errorf("marshaling meta: %w", err)
-	}
-
-	node := &core.Node{
-		ID:       "subscription:" + sub.ID,
-		Type:     "Subscription",
-		Meta:     meta,
-		Created:  sub.Created,
-		Modified: sub.Modified,
+		return err
 	}
-	node.Content = metaJSON
-
 	return r.CreateNode(ctx, node)
 }
 
 // UpdateSubscriptionNode updates a subscription node
 func (r *SQLiteRepository) UpdateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
-	patternJSON, err := json.Marshal(sub.Pattern)
+	meta, err := subscriptionMeta(sub)
 	if err != nil {
-		return fmt.Errorf("marshaling pattern: %w", err)
-	}
-
-	meta := map[string]interface{}{
-		"name":        sub.Name,
-		"description": sub.Description,
-		"pattern":     string(patternJSON),
-		"webhook":     sub.Webhook,
-		"websocket":   sub.WebSocket,
-		"enabled":     sub.Enabled,
-		"fire_count":  sub.FireCount,
+		return err
 	}
 	if sub.LastFired != nil {
 		meta["last_fired"] = sub.LastFired.Format(time.RFC3339)
@@ -1396,176 +2001,164 @@ func (r *SQLiteRepository) LoadSubscriptions(ctx context.Context) ([]*subscripti
 
 	var subs []*subscriptions.Subscription
 	for _, node := range nodes {
-		id := node.ID
-		if len(id) > 13 && id[:13] == "subscription:" {
-			id = id[13:]
-		}
-
-		sub := &subscriptions.Subscription{
-			ID:       id,
-			Created:  node.Created,
-			Modified: node.Modified,
-		}
-
-		if node.Meta != nil {
-			if v, ok := node.Meta["name"].(string); ok {
-				sub.Name = v
-			}
-			if v, ok := node.Meta["description"].(string); ok {
-				sub.Description = v
-			}
-			if v, ok := node.Meta["webhook"].(string); ok {
-				sub.Webhook = v
-			}
-			if v, ok := node.Meta["websocket"].(bool); ok {
-				sub.WebSocket = v
-			}
-			if v, ok := node.Meta["enabled"].(bool); ok {
-				sub.Enabled = v
-			}
-			if v, ok := node.Meta["fire_count"].(float64); ok {
-				sub.FireCount = int(v)
-			}
-			if v, ok := node.Meta["last_fired"].(string); ok {
-				if t, err := time.Parse(time.RFC3339, v); err == nil {
-					sub.LastFired = &t
-				}
-			}
-			if v, ok := node.Meta["pattern"].(string); ok {
-				var pattern subscriptions.SubscriptionPattern
-				if err := json.Unmarshal([]byte(v), &pattern); err == nil {
-					sub.Pattern = pattern
-				}
-			}
-		}
-
-		subs = append(subs, sub)
+		subs = append(subs, subscriptionFromNode(node))
 	}
 
 	return subs, nil
 }
 
-// ExecuteCypherRead returns error - Cypher not supported in SQLite
-func (r *SQLiteRepository) ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
-	return nil, fmt.Errorf("Cypher queries are not supported with SQLite backend. Use Neo4j backend for Cypher support")
-}
-
-// Helper functions
-
-func (r *SQLiteRepository) scanNode(row *sql.Row) (*core.Node, error) {
-	var versionID, id, nodeType string
-	var version, isCurrent, deleted, degree int
-	var content, properties, createdAt, modifiedAt string
-	var deletedAt, changeNote, changedBy sql.NullString
-
-	err := row.Scan(&versionID, &id, &version, &isCurrent, &nodeType, &content, &properties,
-		&createdAt, &modifiedAt, &deleted, &deletedAt, &changeNote, &changedBy, &degree)
+// subscriptionMeta returns the node properties stored for a subscription
+func subscriptionMeta(sub *subscriptions.Subscription) (map[string]interface{}, error) {
+	patternJSON, err := json.Marshal(sub.Pattern)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("node not found")
-		}
-		return nil, err
+		return nil, fmt.Errorf("marshaling pattern: %w", err)
 	}
 
-	node := &core.Node{
-		VersionID: versionID,
-		ID:        id,
-		Version:   version,
-		IsCurrent: isCurrent == 1,
-		Type:      nodeType,
-		Content:   []byte(content),
-		Deleted:   deleted == 1,
+	meta := map[string]interface{}{
+		"name":        sub.Name,
+		"description": sub.Description,
+		"pattern":     string(patternJSON),
+		"webhook":     sub.Webhook,
+		"websocket":   sub.WebSocket,
+		"enabled":     sub.Enabled,
+		"fire_count":  sub.FireCount,
 	}
+	subscriptions.ChannelsToMeta(sub, meta)
+	return meta, nil
+}
 
-	if properties != "" {
-		json.Unmarshal([]byte(properties), &node.Meta)
-	}
-	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-		node.Created = t
-	}
-	if t, err := time.Parse(time.RFC3339, modifiedAt); err == nil {
-		node.Modified = t
-	}
-	if deletedAt.Valid {
-		if t, err := time.Parse(time.RFC3339, deletedAt.String); err == nil {
-			node.DeletedAt = t
-		}
-	}
-	if changeNote.Valid {
-		node.ChangeNote = changeNote.String
+// subscriptionNode builds the Subscription node persisted for a new subscription
+func subscriptionNode(sub *subscriptions.Subscription) (*core.Node, error) {
+	meta, err := subscriptionMeta(sub)
+	if err != nil {
+		return nil, err
 	}
-	if changedBy.Valid {
-		node.ChangedBy = changedBy.String
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling meta: %w", err)
 	}
 
-	return node, nil
+	return &core.Node{
+		ID:       "subscription:" + sub.ID,
+		Type:     "Subscription",
+		Content:  metaJSON,
+		Meta:     meta,
+		Created:  sub.Created,
+		Modified: sub.Modified,
+	}, nil
 }
 
-func (r *SQLiteRepository) scanNodes(rows *sql.Rows) ([]*core.Node, error) {
-	var nodes []*core.Node
-	for rows.Next() {
-		var versionID, id, nodeType string
-		var version, isCurrent, deleted, degree int
-		var content, properties, createdAt, modifiedAt string
-		var deletedAt, changeNote, changedBy sql.NullString
+// subscriptionFromNode decodes a Subscription node loaded from storage
+func subscriptionFromNode(node *core.Node) *subscriptions.Subscription {
+	id := node.ID
+	if len(id) > 13 && id[:13] == "subscription:" {
+		id = id[13:]
+	}
 
-		if err := rows.Scan(&versionID, &id, &version, &isCurrent, &nodeType, &content, &properties,
-			&createdAt, &modifiedAt, &deleted, &deletedAt, &changeNote, &changedBy, &degree); err != nil {
-			continue
-		}
+	sub := &subscriptions.Subscription{
+		ID:       id,
+		Created:  node.Created,
+		Modified: node.Modified,
+	}
 
-		node := &core.Node{
-			VersionID: versionID,
-			ID:        id,
-			Version:   version,
-			IsCurrent: isCurrent == 1,
-			Type:      nodeType,
-			Content:   []byte(content),
-			Deleted:   deleted == 1,
+	if node.Meta != nil {
+		if v, ok := node.Meta["name"].(string); ok {
+			sub.Name = v
 		}
-
-		if properties != "" {
-			json.Unmarshal([]byte(properties), &node.Meta)
+		if v, ok := node.Meta["description"].(string); ok {
+			sub.Description = v
 		}
-		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-			node.Created = t
+		if v, ok := node.Meta["webhook"].(string); ok {
+			sub.Webhook = v
 		}
-		if t, err := time.Parse(time.RFC3339, modifiedAt); err == nil {
-			node.Modified = t
+		if v, ok := node.Meta["websocket"].(bool); ok {
+			sub.WebSocket = v
 		}
-		if deletedAt.Valid {
-			if t, err := time.Parse(time.RFC3339, deletedAt.String); err == nil {
-				node.DeletedAt = t
-			}
+		subscriptions.ChannelsFromMeta(sub, node.Meta)
+		if v, ok := node.Meta["enabled"].(bool); ok {
+			sub.Enabled = v
 		}
-		if changeNote.Valid {
-			node.ChangeNote = changeNote.String
+		if v, ok := node.Meta["fire_count"].(float64); ok {
+			sub.FireCount = int(v)
 		}
-		if changedBy.Valid {
-			node.ChangedBy = changedBy.String
+		if v, ok := node.Meta["last_fired"].(string); ok {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				sub.LastFired = &t
+			}
 		}
+		if v, ok := node.Meta["pattern"].(string); ok {
+			var pattern subscriptions.SubscriptionPattern
+			if err := json.Unmarshal([]byte(v), &pattern); err == nil {
+				sub.Pattern = pattern
+			}
+		}
+	}
 
-		nodes = append(nodes, node)
+	return sub
+}
+
+// ExecuteCypherRead returns error - Cypher not supported in SQLite
+func (r *SQLiteRepository) ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return nil, fmt.Errorf("Cypher queries are not supported with SQLite backend. Use Neo4j backend for Cypher support")
+}
+
+// explainQueryPlan returns the EXPLAIN QUERY PLAN details for a statement
+func (r *SQLiteRepository) explainQueryPlan(ctx context.Context, query string, args []interface{}) ([]string, error) {
+	rows, err := r.db.DB.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return nil, err
 	}
-	return nodes, nil
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return nil, err
+		}
+		plan = append(plan, detail)
+	}
+	return plan, rows.Err()
 }
 
+// Helper functions
+
 func (r *SQLiteRepository) scanLink(rows *sql.Rows) (*core.Link, error) {
+	return scanLinkRow(rows, r.quarantine)
+}
+
+// scanLinkRow reads a links row, telling report of one it cannot read
+func scanLinkRow(rows *sql.Rows, report func(QuarantineEntry)) (*core.Link, error) {
 	var sourceID, targetID, linkType string
 	var properties, createdAt, modifiedAt string
+	var weight float64
+	var bidirectional int
 
-	if err := rows.Scan(&sourceID, &targetID, &linkType, &properties, &createdAt, &modifiedAt); err != nil {
+	if err := rows.Scan(&sourceID, &targetID, &linkType, &properties, &createdAt, &modifiedAt, &weight, &bidirectional); err != nil {
+		report(unreadableRow(QuarantineLink, rows, err))
 		return nil, err
 	}
 
 	link := &core.Link{
-		Source: sourceID,
-		Target: targetID,
-		Type:   linkType,
+		Source:        sourceID,
+		Target:        targetID,
+		Type:          linkType,
+		Weight:        weight,
+		Bidirectional: bidirectional == 1,
 	}
 
 	if properties != "" {
-		json.Unmarshal([]byte(properties), &link.Meta)
+		if err := json.Unmarshal([]byte(properties), &link.Meta); err != nil {
+			report(QuarantineEntry{
+				Kind:     QuarantineLink,
+				NodeID:   sourceID,
+				Target:   targetID,
+				LinkType: linkType,
+				Problem:  "properties: " + err.Error(),
+				Raw:      rawColumns(map[string]interface{}{"properties": properties}),
+			})
+		}
 	}
 	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 		link.Created = t
@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// CreateNodes creates many nodes in a single transaction
+func (r *SQLiteRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO nodes (version_id, id, version, is_current, type, content, content_hash, properties,
+		                   created_at, modified_at, created_unix, modified_unix, deleted, degree)
+		VALUES (?, ?, 1, 1, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, node := range nodes {
+		node.Version = 1
+		node.VersionID = node.ID + ":v1"
+		node.IsCurrent = true
+
+		metaJSON, err := json.Marshal(node.Meta)
+		if err != nil {
+			return fmt.Errorf("marshaling meta for %s: %w", node.ID, err)
+		}
+		content, contentHash, err := r.storeContent(ctx, tx, node.Content)
+		if err != nil {
+			return fmt.Errorf("storing content for %s: %w", node.ID, err)
+		}
+		if _, err := stmt.ExecContext(ctx,
+			node.VersionID,
+			node.ID,
+			node.Type,
+			content,
+			contentHash,
+			string(metaJSON),
+			node.Created.Format(time.RFC3339),
+			node.Modified.Format(time.RFC3339),
+			node.Created.Unix(),
+			node.Modified.Unix(),
+		); err != nil {
+			return fmt.Errorf("inserting node %s: %w", node.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		r.emit(subscriptions.Event{
+			ID:        uuid.New().String(),
+			Type:      subscriptions.EventNodeCreated,
+			Timestamp: time.Now(),
+			NodeID:    node.ID,
+			NodeType:  node.Type,
+			Meta:      node.Meta,
+		})
+	}
+
+	return nil
+}
+
+// CreateLinks creates many links in a single transaction, updating degrees
+func (r *SQLiteRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.PrepareContext(ctx, `
+		INSERT INTO links (source_id, target_id, type, properties, created_at, modified_at, weight, bidirectional)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	degree, err := tx.PrepareContext(ctx, `
+		UPDATE nodes SET degree = degree + 1 WHERE id = ? AND is_current = 1
+	`)
+	if err != nil {
+		return err
+	}
+	defer degree.Close()
+
+	for _, link := range links {
+		PromoteLinkFields(link)
+		metaJSON, err := json.Marshal(link.Meta)
+		if err != nil {
+			return fmt.Errorf("marshaling meta for link %s->%s: %w", link.Source, link.Target, err)
+		}
+		if _, err := insert.ExecContext(ctx,
+			link.Source,
+			link.Target,
+			link.Type,
+			string(metaJSON),
+			link.Created.Format(time.RFC3339),
+			link.Modified.Format(time.RFC3339),
+			link.Weight,
+			boolToInt(link.Bidirectional),
+		); err != nil {
+			return fmt.Errorf("inserting link %s->%s: %w", link.Source, link.Target, err)
+		}
+		for _, id := range []string{link.Source, link.Target} {
+			if _, err := degree.ExecContext(ctx, id); err != nil {
+				return fmt.Errorf("updating degree: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		r.emit(subscriptions.Event{
+			ID:         uuid.New().String(),
+			Type:       subscriptions.EventLinkCreated,
+			Timestamp:  time.Now(),
+			LinkSource: link.Source,
+			LinkTarget: link.Target,
+			LinkType:   link.Type,
+			Meta:       link.Meta,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+)
+
+// dedupMinBytes is the smallest content moved to the content store; smaller
+// content stays inline where dedup would save less than the indirection costs
+const dedupMinBytes = 1024
+
+// contentColumn selects a node row's content, inline or from the content
+// store. nContentColumn is the same for queries that alias nodes as n.
+const (
+	contentColumn  = `COALESCE(content, (SELECT data FROM contents WHERE hash = content_hash))`
+	nContentColumn = `COALESCE(n.content, (SELECT data FROM contents WHERE hash = n.content_hash))`
+)
+
+// ContentStoreStats reports how much the content store saves by storing
+// identical content once
+type ContentStoreStats struct {
+	Blobs           int64 `json:"blobs"`            // Distinct stored contents
+	References      int64 `json:"references"`       // Node versions pointing at them
+	StoredBytes     int64 `json:"stored_bytes"`     // Bytes actually stored
+	ReferencedBytes int64 `json:"referenced_bytes"` // Bytes the references would take inline
+	SavedBytes      int64 `json:"saved_bytes"`
+}
+
+// execer is satisfied by both observedDB and observedTx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// storeContent returns the content and content_hash values to write for a
+// new node version. Large content is added to the content store (or its
+// refcount incremented) and referenced by hash; the rest is stored inline.
+func (r *SQLiteRepository) storeContent(ctx context.Context, ex execer, content []byte) (interface{}, interface{}, error) {
+	if !r.dedupContent || len(content) < dedupMinBytes {
+		return string(content), nil, nil
+	}
+	hash, err := addContent(ctx, ex, content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, hash, nil
+}
+
+// addContent stores content under its sha256, or takes another reference
+// to it if already stored, and returns the hash
+func addContent(ctx context.Context, ex execer, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO contents (hash, data, size, refcount) VALUES (?, ?, ?, 1)
+		ON CONFLICT(hash) DO UPDATE SET refcount = refcount + 1
+	`, hash, string(content), len(content))
+	if err != nil {
+		return "", fmt.Errorf("storing content: %w", err)
+	}
+	return hash, nil
+}
+
+// releaseContent drops the content-store references held by every version
+// of a node, before those versions are hard-deleted
+func releaseContent(ctx context.Context, ex execer, nodeID string) error {
+	_, err := ex.ExecContext(ctx, `
+		UPDATE contents SET refcount = refcount - (
+			SELECT COUNT(*) FROM nodes WHERE nodes.id = ? AND nodes.content_hash = contents.hash
+		)
+		WHERE hash IN (SELECT content_hash FROM nodes WHERE id = ? AND content_hash IS NOT NULL)
+	`, nodeID, nodeID)
+	if err != nil {
+		return fmt.Errorf("releasing content: %w", err)
+	}
+	return nil
+}
+
+// collectContent removes stored content that no node version references.
+// It must run after the referencing rows are deleted so the FTS delete
+// trigger can still read the content.
+func collectContent(ctx context.Context, ex execer) error {
+	if _, err := ex.ExecContext(ctx, `DELETE FROM contents WHERE refcount <= 0`); err != nil {
+		return fmt.Errorf("collecting content: %w", err)
+	}
+	return nil
+}
+
+// contentStoreStats summarizes the content store
+func (r *SQLiteRepository) contentStoreStats(ctx context.Context) (*ContentStoreStats, error) {
+	stats := &ContentStoreStats{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(refcount), 0), COALESCE(SUM(size), 0), COALESCE(SUM(size * refcount), 0)
+		FROM contents
+	`).Scan(&stats.Blobs, &stats.References, &stats.StoredBytes, &stats.ReferencedBytes)
+	if err != nil {
+		return nil, err
+	}
+	stats.SavedBytes = stats.ReferencedBytes - stats.StoredBytes
+	return stats, nil
+}
+
+// moveContentToStore migrates existing large inline content into the
+// content store. The resolved FTS triggers are already installed, so the
+// index stays consistent as rows switch to references.
+func moveContentToStore(ctx context.Context, tx *observedTx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT rowid, content FROM nodes WHERE octet_length(content) >= ?`, dedupMinBytes)
+	if err != nil {
+		return err
+	}
+	type inline struct {
+		rowid   int64
+		content []byte
+	}
+	var pending []inline
+	for rows.Next() {
+		var row inline
+		if err := rows.Scan(&row.rowid, &row.content); err != nil {
+			rows.Close()
+			return err
+		}
+		pending = append(pending, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, row := range pending {
+		hash, err := addContent(ctx, tx, row.content)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE nodes SET content = NULL, content_hash = ? WHERE rowid = ?`, hash, row.rowid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
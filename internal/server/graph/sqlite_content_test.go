@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestSQLiteContentDedup(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+
+	attachment := []byte("quarterly " + strings.Repeat("report ", 300))
+	now := time.Now()
+	for _, id := range []string{"file:a", "file:b"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "File", Content: attachment, Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.UpdateNodeMeta(ctx, "file:a", map[string]any{"title": "Q3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := repo.contentStoreStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Blobs != 1 || stats.References != 3 || stats.SavedBytes != 2*int64(len(attachment)) {
+		t.Errorf("unexpected content store stats: %+v", stats)
+	}
+
+	node, err := repo.GetNode(ctx, "file:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(node.Content) != string(attachment) {
+		t.Error("content not resolved from the store")
+	}
+	found, err := repo.SearchNodes(ctx, "quarterly", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Errorf("expected full-text search over stored content to find 2 nodes, got %d", len(found))
+	}
+
+	for _, id := range []string{"file:a", "file:b"} {
+		if err := repo.DeleteNode(ctx, id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats, _ := repo.contentStoreStats(ctx); stats.Blobs != 0 {
+		t.Errorf("expected unreferenced content to be collected, got %+v", stats)
+	}
+}
+
+func TestMoveContentToStore(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+
+	// Rows written inline, as before the content store existed
+	repo.dedupContent = false
+	big := "archived " + strings.Repeat("x", dedupMinBytes)
+	now := time.Now()
+	for _, id := range []string{"doc:1", "doc:2"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Doc", Content: []byte(big), Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.dedupContent = true
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := moveContentToStore(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats, _ := repo.contentStoreStats(ctx); stats.Blobs != 1 || stats.References != 2 {
+		t.Errorf("unexpected content store stats after migration: %+v", stats)
+	}
+	node, err := repo.GetNode(ctx, "doc:2")
+	if err != nil || string(node.Content) != big {
+		t.Errorf("content not preserved by migration: %v", err)
+	}
+	if found, _ := repo.SearchNodes(ctx, "archived", 10, 0); len(found) != 2 {
+		t.Errorf("expected search to still find migrated content, got %d", len(found))
+	}
+}
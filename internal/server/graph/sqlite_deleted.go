@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// visibleNodes returns the condition selecting current nodes from table and
+// the column to select after nodeColumns for scanVisible. With
+// IncludesDeleted(ctx), the last live versions of deleted nodes match too
+// and the column gives their tombstone's deleted_at.
+func visibleNodes(ctx context.Context, table string) (where, deletedAt string) {
+	if !IncludesDeleted(ctx) {
+		return table + `.is_current = 1 AND ` + table + `.deleted = 0`, `NULL`
+	}
+	tombstone := `FROM nodes t WHERE t.id = ` + table + `.id AND t.version = ` + table + `.version + 1
+		AND t.is_current = 1 AND t.deleted = 1`
+	where = table + `.deleted = 0 AND (` + table + `.is_current = 1 OR EXISTS (SELECT 1 ` + tombstone + `))`
+	return where, `(SELECT t.deleted_at ` + tombstone + `)`
+}
+
+// scanVisible scans rows selected with visibleNodes, marking the deleted ones
+func (r *SQLiteRepository) scanVisible(rows *sql.Rows) ([]*core.Node, error) {
+	var nodes []*core.Node
+	var deletedAt sql.NullString
+	s := r.scanner(&deletedAt)
+	for rows.Next() {
+		node, err := s.scan(rows)
+		if err != nil {
+			continue
+		}
+		if deletedAt.Valid {
+			t, _ := time.Parse(time.RFC3339, deletedAt.String)
+			markDeleted(node, t)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+// getNodeIncludingDeleted returns the current version of a node, or the
+// last live version if it has been deleted
+func (r *SQLiteRepository) getNodeIncludingDeleted(ctx context.Context, id string) (*core.Node, error) {
+	where, deletedAt := visibleNodes(ctx, "nodes")
+	rows, err := r.db.queryCached(ctx, `
+		SELECT `+nodeColumns+`, `+deletedAt+`
+		FROM nodes
+		WHERE id = ? AND `+where, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes, err := r.scanVisible(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("node not found")
+	}
+	return nodes[0], nil
+}
+
+// ListDeleted returns deleted nodes, most recently deleted first, each as
+// its last live version marked with its deletion time
+func (r *SQLiteRepository) ListDeleted(ctx context.Context, limit int, offset int) ([]*core.Node, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+nNodeColumns+`, t.deleted_at
+		FROM nodes t
+		JOIN nodes n ON n.id = t.id AND n.version = t.version - 1
+		WHERE t.is_current = 1 AND t.deleted = 1
+		ORDER BY t.modified_unix DESC, t.id
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanVisible(rows)
+}
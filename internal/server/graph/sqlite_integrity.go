@@ -0,0 +1,162 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CheckIntegrity reports orphan nodes, links whose endpoints are missing or
+// tombstoned, and version chain entries naming versions that no longer exist
+func (r *SQLiteRepository) CheckIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	report := newIntegrityReport()
+
+	orphanQuery := `
+		SELECT n.id, n.type FROM nodes n
+		WHERE n.is_current = 1 AND n.deleted = 0
+		  AND NOT EXISTS (SELECT 1 FROM links WHERE source_id = n.id)
+		  AND NOT EXISTS (SELECT 1 FROM links WHERE target_id = n.id)
+	`
+	var args []interface{}
+	if len(opts.ExcludeTypes) > 0 {
+		placeholders := make([]string, len(opts.ExcludeTypes))
+		for i, t := range opts.ExcludeTypes {
+			placeholders[i] = "?"
+			args = append(args, t)
+		}
+		orphanQuery += " AND n.type NOT IN (" + strings.Join(placeholders, ",") + ")"
+	}
+	orphanQuery += " ORDER BY n.id"
+
+	rows, err := r.db.QueryContext(ctx, orphanQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var n IntegrityNode
+		if err := rows.Scan(&n.ID, &n.Type); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.addOrphan(n, opts)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT l.source_id, l.target_id, l.type,
+		       CASE WHEN s.id IS NULL THEN 'missing' WHEN s.deleted = 1 THEN 'deleted' ELSE '' END,
+		       CASE WHEN t.id IS NULL THEN 'missing' WHEN t.deleted = 1 THEN 'deleted' ELSE '' END
+		FROM links l
+		LEFT JOIN nodes s ON s.id = l.source_id AND s.is_current = 1
+		LEFT JOIN nodes t ON t.id = l.target_id AND t.is_current = 1
+		WHERE s.id IS NULL OR t.id IS NULL OR s.deleted = 1 OR t.deleted = 1
+		ORDER BY l.source_id, l.type, l.target_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var l DanglingLink
+		if err := rows.Scan(&l.Source, &l.Target, &l.Type, &l.SourceState, &l.TargetState); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.addDanglingLink(l, opts)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT c.newer_version_id, c.older_version_id,
+		       CASE WHEN n.version_id IS NULL AND o.version_id IS NULL THEN 'both'
+		            WHEN n.version_id IS NULL THEN 'newer' ELSE 'older' END
+		FROM version_chain c
+		LEFT JOIN nodes n ON n.version_id = c.newer_version_id
+		LEFT JOIN nodes o ON o.version_id = c.older_version_id
+		WHERE n.version_id IS NULL OR o.version_id IS NULL
+		ORDER BY c.newer_version_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v BrokenVersion
+		if err := rows.Scan(&v.Newer, &v.Older, &v.Missing); err != nil {
+			return nil, err
+		}
+		report.addBrokenVersion(v, opts)
+	}
+	return report, rows.Err()
+}
+
+// linkDegree counts the links touching the node whose ID is in column,
+// the way CreateLink and DeleteLink keep degree
+func linkDegree(column string) string {
+	return `((SELECT COUNT(*) FROM links WHERE source_id = ` + column + ` AND type != 'ATTENDED') +
+	        (SELECT COUNT(*) FROM links WHERE target_id = ` + column + ` AND type != 'ATTENDED'))`
+}
+
+// RecomputeDegrees rebuilds stored degrees from the links table, reading
+// and correcting one batch of nodes at a time. Corrections recount at write
+// time, so links created meanwhile are not lost.
+func (r *SQLiteRepository) RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error) {
+	report := newDegreeRepair(opts)
+	batch := opts.batchSize()
+	after := ""
+	for {
+		rows, err := r.db.QueryContext(ctx, `
+			SELECT n.id, n.type, n.degree, `+linkDegree("n.id")+`
+			FROM nodes n
+			WHERE n.is_current = 1 AND n.deleted = 0 AND n.id > ?
+			ORDER BY n.id
+			LIMIT ?
+		`, after, batch)
+		if err != nil {
+			return nil, err
+		}
+		read := 0
+		var drifted []interface{}
+		for rows.Next() {
+			var d DegreeDiscrepancy
+			if err := rows.Scan(&d.ID, &d.Type, &d.Stored, &d.Actual); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			read++
+			after = d.ID
+			if report.check(d, opts) {
+				drifted = append(drifted, d.ID)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if read == 0 {
+			break
+		}
+		report.Batches++
+
+		if !opts.DryRun && len(drifted) > 0 {
+			result, err := r.db.ExecContext(ctx, `
+				UPDATE nodes SET degree = `+linkDegree("nodes.id")+`
+				WHERE is_current = 1 AND id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(drifted)), ",")+`)
+			`, drifted...)
+			if err != nil {
+				return nil, fmt.Errorf("correcting degrees: %w", err)
+			}
+			fixed, _ := result.RowsAffected()
+			report.Fixed += int(fixed)
+		}
+		if read < batch {
+			break
+		}
+	}
+	return report, nil
+}
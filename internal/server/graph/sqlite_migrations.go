@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Tracks which schema migrations have been applied to the database
+const schemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TEXT NOT NULL
+)`
+
+// migration is a single, ordered schema change for a SQL backend.
+// Migrations are append-only: never edit or reorder one that has shipped.
+type migration struct {
+	version    int
+	name       string
+	statements []string
+	apply      func(ctx context.Context, tx *observedTx) error // Optional data migration, run after statements
+}
+
+// sqliteMigrations returns all migrations in version order
+func sqliteMigrations() []migration {
+	return []migration{
+		{
+			// Uses CREATE IF NOT EXISTS, so databases created before
+			// migrations existed are adopted without changes
+			version:    1,
+			name:       "baseline schema",
+			statements: allSchemaStatements(),
+		},
+		{
+			version: 2,
+			name:    "timestamp indexes and link tombstones",
+			statements: []string{
+				indexNodesCreatedAt,
+				indexNodesModifiedAt,
+				indexLinksCreatedAt,
+				schemaLinkTombstones,
+				indexLinkTombstonesDeletedAt,
+			},
+		},
+		{
+			version: 3,
+			name:    "content-addressed content store",
+			statements: []string{
+				schemaContents,
+				alterNodesContentHash,
+				indexNodesContentHash,
+				`DROP TRIGGER IF EXISTS nodes_fts_insert`,
+				`DROP TRIGGER IF EXISTS nodes_fts_delete`,
+				`DROP TRIGGER IF EXISTS nodes_fts_update`,
+				triggerFTSInsertResolved,
+				triggerFTSDeleteResolved,
+				triggerFTSUpdateResolved,
+			},
+			apply: moveContentToStore,
+		},
+		{
+			version: 4,
+			name:    "epoch timestamp columns",
+			statements: []string{
+				alterNodesCreatedUnix,
+				alterNodesModifiedUnix,
+				backfillNodesUnix,
+				indexNodesCreatedUnix,
+				indexNodesModifiedUnix,
+			},
+		},
+		{
+			version:    5,
+			name:       "trigram name index",
+			statements: trigramStatements(),
+		},
+		{
+			version: 6,
+			name:    "typed link weight and direction",
+			statements: []string{
+				alterLinksWeight,
+				alterLinksBidirectional,
+				alterLinkTombstonesWeight,
+				alterLinkTombstonesBidirectional,
+			},
+			apply: promoteStoredLinkFields,
+		},
+		{
+			version: 7,
+			name:    "quarantine",
+			statements: []string{
+				schemaQuarantine,
+				indexQuarantineStatus,
+			},
+		},
+	}
+}
+
+// latestSchemaVersion returns the highest SQLite migration version this build knows about
+func latestSchemaVersion() int {
+	return latestVersion(sqliteMigrations())
+}
+
+// latestVersion returns the highest version in migrations
+func latestVersion(migrations []migration) int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrate brings the database up to the latest schema version.
+// It refuses to run against a database written by a newer server.
+func migrate(ctx context.Context, db *observedDB, migrations []migration) error {
+	if _, err := db.ExecContext(ctx, schemaMigrationsTable); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+
+	current, err := schemaVersion(ctx, db.DB)
+	if err != nil {
+		return err
+	}
+
+	latest := latestVersion(migrations)
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this server supports (%d); upgrade memex-server", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("applying migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs one migration and records it in a single transaction
+func applyMigration(ctx context.Context, db *observedDB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if m.apply != nil {
+		if err := m.apply(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("recording migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// schemaVersion returns the highest applied migration version, or 0 for a new database
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// SchemaVersion returns the schema version of the open database
+func (r *SQLiteRepository) SchemaVersion(ctx context.Context) (int, error) {
+	return schemaVersion(ctx, r.db.DB)
+}
+
+// LatestSchemaVersion returns the schema version this build migrates to
+func (r *SQLiteRepository) LatestSchemaVersion() int {
+	return latestSchemaVersion()
+}
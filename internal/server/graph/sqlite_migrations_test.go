@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestMigrationsOrdered(t *testing.T) {
+	prev := 0
+	for _, m := range sqliteMigrations() {
+		if m.version != prev+1 {
+			t.Errorf("migration %q has version %d, want %d", m.name, m.version, prev+1)
+		}
+		prev = m.version
+	}
+}
+
+func TestMigrateUpgradeAndDowngradeGuard(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "memex.db")
+
+	repo, err := NewSQLite(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+
+	version, err := repo.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion() error = %v", err)
+	}
+	if version != latestSchemaVersion() {
+		t.Errorf("SchemaVersion() = %d, want %d", version, latestSchemaVersion())
+	}
+
+	// Simulate a database written by a newer server
+	_, err = repo.db.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		latestSchemaVersion()+1, "from the future", time.Now().Format(time.RFC3339))
+	if err != nil {
+		t.Fatalf("inserting migration row: %v", err)
+	}
+	repo.Close(ctx)
+
+	if _, err := NewSQLite(ctx, dbPath); err == nil {
+		t.Error("NewSQLite() on newer schema succeeded, want error")
+	}
+}
+
+func TestPromoteStoredLinkFields(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+
+	now := time.Now()
+	for _, id := range []string{"a", "b"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "a", Target: "b", Type: "RELATED", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	// A row as written before weight and direction had columns
+	if _, err := repo.db.ExecContext(ctx,
+		`UPDATE links SET properties = ?, weight = 0, bidirectional = 0`,
+		`{"weight":0.4,"bidirectional":true,"note":"kept"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := promoteStoredLinkFields(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := repo.GetLinks(ctx, "a")
+	if err != nil || len(links) != 1 {
+		t.Fatalf("GetLinks() = %v, %v", links, err)
+	}
+	l := links[0]
+	if l.Weight != 0.4 || !l.Bidirectional || l.Meta["weight"] != nil || l.Meta["note"] != "kept" {
+		t.Errorf("migrated link = %+v, want weight 0.4, bidirectional, note kept", l)
+	}
+}
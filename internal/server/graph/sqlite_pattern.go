@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// MatchPattern finds bindings of a structural pattern with one join per
+// variable and link. Property constraints are prefiltered with LIKE on the
+// JSON properties and checked exactly on each row.
+func (r *SQLiteRepository) MatchPattern(ctx context.Context, p *Pattern, limit int) ([]PatternBinding, error) {
+	var from, where, cols, orderBy []string
+	var args []interface{}
+	for i, n := range p.Nodes {
+		alias := fmt.Sprintf("n%d", i)
+		from = append(from, "nodes "+alias)
+		cols = append(cols, alias+".id", alias+".properties")
+		orderBy = append(orderBy, alias+".id")
+		where = append(where, alias+".is_current = 1 AND "+alias+".deleted = 0")
+		if n.Type != "" {
+			where = append(where, alias+".type = ?")
+			args = append(args, n.Type)
+		}
+		for _, fragment := range p.propFragments(i) {
+			where = append(where, alias+".properties LIKE ?")
+			args = append(args, "%"+fragment+"%")
+		}
+	}
+	for i, e := range p.Edges {
+		alias := fmt.Sprintf("l%d", i)
+		from = append(from, "links "+alias)
+		where = append(where, fmt.Sprintf("%s.source_id = n%d.id AND %s.target_id = n%d.id", alias, e.From, alias, e.To))
+		if e.Type != "" {
+			where = append(where, alias+".type = ?")
+			args = append(args, e.Type)
+		}
+	}
+	query := "SELECT DISTINCT " + strings.Join(cols, ", ") +
+		" FROM " + strings.Join(from, ", ") +
+		" WHERE " + strings.Join(where, " AND ") +
+		" ORDER BY " + strings.Join(orderBy, ", ")
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []PatternBinding{}
+	ids := make([]string, len(p.Nodes))
+	props := make([]sql.NullString, len(p.Nodes))
+	dest := make([]interface{}, 0, 2*len(p.Nodes))
+	for i := range p.Nodes {
+		dest = append(dest, &ids[i], &props[i])
+	}
+	for len(matches) < limit && rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		ok := true
+		for i := range p.Nodes {
+			if !p.propsJSONMatch(i, props[i].String) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matches = append(matches, p.binding(ids))
+		}
+	}
+	return matches, rows.Err()
+}
@@ -0,0 +1,455 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Reads still skip a row they cannot scan, and return a node or link
+// without the properties they cannot decode, but the row is now recorded in
+// the quarantine table instead of disappearing. Entries are written in the
+// background so the read that found the row neither waits on nor fails
+// from the write.
+
+// maxRawValue caps each column kept in an entry's Raw, content above all
+const maxRawValue = 4096
+
+// quarantineRecorder batches the entries reads report until they are written
+type quarantineRecorder struct {
+	mu      sync.Mutex
+	pending map[string]QuarantineEntry // Kind and row key -> entry
+	running bool
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// quarantine records a row a read could not use
+func (r *SQLiteRepository) quarantine(e QuarantineEntry) {
+	q := &r.quarantined
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	key := e.Kind + "\x1f" + quarantineRowKey(e)
+	if prev, ok := q.pending[key]; ok {
+		e.Occurrences += prev.Occurrences
+	}
+	if e.Occurrences == 0 {
+		e.Occurrences = 1
+	}
+	e.LastSeen = time.Now()
+	if q.pending == nil {
+		q.pending = make(map[string]QuarantineEntry)
+	}
+	q.pending[key] = e
+
+	if !q.running {
+		q.running = true
+		q.wake = make(chan struct{}, 1)
+		q.done = make(chan struct{})
+		q.stopped = make(chan struct{})
+		go r.recordQuarantine()
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// recordQuarantine writes reported entries until the repository closes
+func (r *SQLiteRepository) recordQuarantine() {
+	q := &r.quarantined
+	defer close(q.stopped)
+	for {
+		select {
+		case <-q.wake:
+			r.flushQuarantine(context.Background())
+		case <-q.done:
+			r.flushQuarantine(context.Background())
+			return
+		}
+	}
+}
+
+// stopQuarantine writes what is pending and stops recording, before Close
+// closes the database
+func (r *SQLiteRepository) stopQuarantine() {
+	q := &r.quarantined
+	q.mu.Lock()
+	q.closed = true
+	running := q.running
+	q.mu.Unlock()
+	if running {
+		close(q.done)
+		<-q.stopped
+	}
+}
+
+// flushQuarantine writes the pending entries. An entry seen again is
+// reopened, unless it was dismissed.
+func (r *SQLiteRepository) flushQuarantine(ctx context.Context) {
+	q := &r.quarantined
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+
+	for _, e := range pending {
+		seen := e.LastSeen.Format(time.RFC3339)
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO quarantine (kind, row_key, node_id, version_id, target_id, link_type, problem, raw, status, occurrences, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (kind, row_key) DO UPDATE SET
+				problem = excluded.problem,
+				raw = excluded.raw,
+				occurrences = quarantine.occurrences + excluded.occurrences,
+				last_seen = excluded.last_seen,
+				status = CASE WHEN quarantine.status = 'dismissed' THEN quarantine.status ELSE 'open' END,
+				resolved_at = CASE WHEN quarantine.status = 'dismissed' THEN quarantine.resolved_at ELSE NULL END,
+				resolution = CASE WHEN quarantine.status = 'dismissed' THEN quarantine.resolution ELSE '' END
+		`, e.Kind, quarantineRowKey(e), e.NodeID, e.VersionID, e.Target, e.LinkType, e.Problem, e.Raw,
+			QuarantineOpen, e.Occurrences, seen, seen)
+		if err != nil {
+			log.Printf("quarantine: recording %s %s: %v", e.Kind, e.NodeID, err)
+		}
+	}
+}
+
+// quarantineRowKey identifies the row an entry is for
+func quarantineRowKey(e QuarantineEntry) string {
+	if e.Kind == QuarantineLink {
+		return e.NodeID + "\x1f" + e.LinkType + "\x1f" + e.Target
+	}
+	return e.VersionID
+}
+
+// unreadableRow builds the entry for a row whose Scan failed with err. The
+// row is scanned again without conversions to keep what is stored; node rows
+// start with version_id and id, link rows with source, target and type.
+func unreadableRow(kind string, rows *sql.Rows, err error) QuarantineEntry {
+	e := QuarantineEntry{Kind: kind, Problem: err.Error()}
+	cols, colErr := rows.Columns()
+	if colErr != nil {
+		return e
+	}
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if rows.Scan(ptrs...) != nil {
+		return e
+	}
+
+	raw := make(map[string]interface{}, len(cols))
+	for i, col := range cols {
+		raw[col] = rawValue(vals[i])
+	}
+	e.Raw = rawColumns(raw)
+
+	str := func(i int) string {
+		if i >= len(vals) || vals[i] == nil {
+			return ""
+		}
+		return fmt.Sprint(rawValue(vals[i]))
+	}
+	if kind == QuarantineLink {
+		e.NodeID, e.Target, e.LinkType = str(0), str(1), str(2)
+	} else {
+		e.VersionID, e.NodeID = str(0), str(1)
+	}
+	return e
+}
+
+// rawValue makes a column value readable in JSON, shortening long ones
+func rawValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	if s, ok := v.(string); ok && len(s) > maxRawValue {
+		return s[:maxRawValue] + "..."
+	}
+	return v
+}
+
+// rawColumns encodes column values for an entry's Raw
+func rawColumns(cols map[string]interface{}) string {
+	for k, v := range cols {
+		cols[k] = rawValue(v)
+	}
+	data, err := json.Marshal(cols)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+const quarantineColumns = `id, kind, node_id, version_id, target_id, link_type, problem, raw,
+	status, occurrences, first_seen, last_seen, resolved_at, resolution`
+
+// scanQuarantine reads a row selected with quarantineColumns
+func scanQuarantine(row rowScanner) (*QuarantineEntry, error) {
+	var e QuarantineEntry
+	var firstSeen, lastSeen string
+	var resolvedAt sql.NullString
+	if err := row.Scan(&e.ID, &e.Kind, &e.NodeID, &e.VersionID, &e.Target, &e.LinkType, &e.Problem, &e.Raw,
+		&e.Status, &e.Occurrences, &firstSeen, &lastSeen, &resolvedAt, &e.Resolution); err != nil {
+		return nil, err
+	}
+	e.FirstSeen, _ = time.Parse(time.RFC3339, firstSeen)
+	e.LastSeen, _ = time.Parse(time.RFC3339, lastSeen)
+	if resolvedAt.Valid {
+		if t, err := time.Parse(time.RFC3339, resolvedAt.String); err == nil {
+			e.ResolvedAt = &t
+		}
+	}
+	return &e, nil
+}
+
+// ListQuarantine returns entries, newest first, including rows reported
+// moments ago
+func (r *SQLiteRepository) ListQuarantine(ctx context.Context, status string, limit, offset int) ([]*QuarantineEntry, error) {
+	r.flushQuarantine(ctx)
+	if limit <= 0 {
+		limit = 100
+	}
+	query := `SELECT ` + quarantineColumns + ` FROM quarantine`
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing quarantine: %w", err)
+	}
+	defer rows.Close()
+	entries := []*QuarantineEntry{}
+	for rows.Next() {
+		e, err := scanQuarantine(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listing quarantine: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetQuarantine returns an entry
+func (r *SQLiteRepository) GetQuarantine(ctx context.Context, id int64) (*QuarantineEntry, error) {
+	e, err := scanQuarantine(r.db.QueryRowContext(ctx, `SELECT `+quarantineColumns+` FROM quarantine WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrQuarantineNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting quarantine entry %d: %w", id, err)
+	}
+	return e, nil
+}
+
+// CheckStoredRows reads every node version and link
+func (r *SQLiteRepository) CheckStoredRows(ctx context.Context) (*QuarantineCheck, error) {
+	check := &QuarantineCheck{}
+	report := func(e QuarantineEntry) {
+		check.Found++
+		r.quarantine(e)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+nodeColumns+` FROM nodes`)
+	if err != nil {
+		return nil, fmt.Errorf("checking nodes: %w", err)
+	}
+	s := newNodeScanner()
+	s.report = report
+	for rows.Next() {
+		check.NodesChecked++
+		s.scan(rows)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("checking nodes: %w", err)
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT source_id, target_id, type, properties, created_at, modified_at, weight, bidirectional
+		FROM links
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("checking links: %w", err)
+	}
+	for rows.Next() {
+		check.LinksChecked++
+		scanLinkRow(rows, report)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("checking links: %w", err)
+	}
+
+	r.flushQuarantine(ctx)
+	return check, nil
+}
+
+// RepairQuarantine replaces the row's properties, drops the row or just
+// closes the entry. A replacement is kept only if the row can then be
+// read; one whose problem is not its properties has to be dropped and
+// re-ingested. Repairs change rows in place, without a new version.
+func (r *SQLiteRepository) RepairQuarantine(ctx context.Context, id int64, repair QuarantineRepair) (*QuarantineEntry, error) {
+	r.flushQuarantine(ctx) // So an earlier read of the row cannot reopen it
+	e, err := r.GetQuarantine(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Status == QuarantineRepaired || e.Status == QuarantineDropped {
+		return nil, fmt.Errorf("%w: entry %d is already %s", ErrInvalidRepair, id, e.Status)
+	}
+
+	var status string
+	switch repair.Action {
+	case RepairReplace:
+		status = QuarantineRepaired
+		err = r.replaceQuarantined(ctx, e, repair.Properties)
+	case RepairDrop:
+		status = QuarantineDropped
+		err = r.dropQuarantined(ctx, e)
+	case RepairDismiss:
+		status = QuarantineDismissed
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidRepair, repair.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := repair.Action
+	if repair.Note != "" {
+		resolution += ": " + repair.Note
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE quarantine SET status = ?, resolved_at = ?, resolution = ? WHERE id = ?`,
+		status, time.Now().Format(time.RFC3339), resolution, id); err != nil {
+		return nil, fmt.Errorf("resolving quarantine entry %d: %w", id, err)
+	}
+	return r.GetQuarantine(ctx, id)
+}
+
+// replaceQuarantined stores new properties for the entry's row, keeping
+// them only if the row can then be read
+func (r *SQLiteRepository) replaceQuarantined(ctx context.Context, e *QuarantineEntry, properties map[string]interface{}) error {
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	props, err := json.Marshal(properties)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRepair, err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var res sql.Result
+	var check string
+	var args []interface{}
+	if e.Kind == QuarantineLink {
+		args = []interface{}{e.NodeID, e.Target, e.LinkType}
+		res, err = tx.ExecContext(ctx, `UPDATE links SET properties = ? WHERE source_id = ? AND target_id = ? AND type = ?`,
+			append([]interface{}{string(props)}, args...)...)
+		check = `SELECT source_id, target_id, type, properties, created_at, modified_at, weight, bidirectional
+			FROM links WHERE source_id = ? AND target_id = ? AND type = ?`
+	} else {
+		args = []interface{}{e.VersionID}
+		res, err = tx.ExecContext(ctx, `UPDATE nodes SET properties = ? WHERE version_id = ?`, string(props), e.VersionID)
+		check = `SELECT ` + nodeColumns + ` FROM nodes WHERE version_id = ?`
+	}
+	if err != nil {
+		return fmt.Errorf("replacing properties: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: the %s row no longer exists", ErrInvalidRepair, e.Kind)
+	}
+
+	var problem string
+	report := func(found QuarantineEntry) { problem = found.Problem }
+	rows, err := tx.QueryContext(ctx, check, args...)
+	if err != nil {
+		return fmt.Errorf("rereading %s: %w", e.Kind, err)
+	}
+	for rows.Next() {
+		if e.Kind == QuarantineLink {
+			scanLinkRow(rows, report)
+		} else {
+			s := newNodeScanner()
+			s.report = report
+			s.scan(rows)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rereading %s: %w", e.Kind, err)
+	}
+	if problem != "" {
+		return fmt.Errorf("%w: %s", ErrNotRepaired, problem)
+	}
+	return tx.Commit()
+}
+
+// dropQuarantined deletes the entry's row so its source can write it
+// again. A dropped current node version hands over to the latest one left.
+func (r *SQLiteRepository) dropQuarantined(ctx context.Context, e *QuarantineEntry) error {
+	if e.Kind == QuarantineLink {
+		return r.DeleteLink(ctx, e.NodeID, e.Target, e.LinkType)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM nodes WHERE version_id = ? AND is_current = 1`, e.VersionID).Scan(&current); err != nil {
+		return fmt.Errorf("dropping node version: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE contents SET refcount = refcount - 1
+		WHERE hash = (SELECT content_hash FROM nodes WHERE version_id = ?)
+	`, e.VersionID); err != nil {
+		return fmt.Errorf("releasing content: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM nodes WHERE version_id = ?`, e.VersionID)
+	if err != nil {
+		return fmt.Errorf("dropping node version: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: the node row no longer exists", ErrInvalidRepair)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM version_chain WHERE newer_version_id = ? OR older_version_id = ?`, e.VersionID, e.VersionID); err != nil {
+		return fmt.Errorf("dropping version chain: %w", err)
+	}
+	if current > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE nodes SET is_current = 1
+			WHERE version_id = (SELECT version_id FROM nodes WHERE id = ? ORDER BY version DESC LIMIT 1)
+		`, e.NodeID); err != nil {
+			return fmt.Errorf("promoting previous version: %w", err)
+		}
+	}
+	if err := collectContent(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
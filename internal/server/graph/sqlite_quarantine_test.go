@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestQuarantine(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close(ctx)
+
+	now := time.Now()
+	for _, id := range []string{"a", "b"} {
+		node := &core.Node{ID: id, Type: "Note", Meta: map[string]interface{}{"name": id}, Created: now, Modified: now}
+		if err := repo.CreateNode(ctx, node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "a", Target: "b", Type: "cites"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Properties that are not JSON, and a version that is not a number
+	for _, stmt := range []string{
+		`UPDATE nodes SET properties = '{not json' WHERE id = 'a'`,
+		`UPDATE links SET properties = '[1' WHERE source_id = 'a'`,
+		`INSERT INTO nodes (version_id, id, version, type, properties, created_at, modified_at, created_unix, modified_unix)
+		 VALUES ('bad:v1', 'bad', 'one', 'Note', '{}', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z', ` +
+			`strftime('%s', 'now'), strftime('%s', 'now'))`,
+	} {
+		if _, err := repo.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reads go on as before: the bad properties are left out, the bad row skipped
+	node, err := repo.GetNode(ctx, "a")
+	if err != nil || node.Meta != nil {
+		t.Fatalf("GetNode = %+v, %v", node, err)
+	}
+	if links, err := repo.GetLinks(ctx, "a"); err != nil || len(links) != 1 {
+		t.Fatalf("GetLinks = %d, %v", len(links), err)
+	}
+	found, err := repo.QueryTimeRange(ctx, now.Add(-time.Hour), now.Add(time.Hour), nil, 10, 0)
+	if err != nil || len(found) != 2 {
+		t.Fatalf("QueryTimeRange = %d, %v", len(found), err)
+	}
+
+	entries, err := repo.ListQuarantine(ctx, QuarantineOpen, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKind := map[string]*QuarantineEntry{}
+	for _, e := range entries {
+		byKind[e.Kind+":"+e.NodeID] = e
+	}
+	props, badRow, link := byKind["node:a"], byKind["node:bad"], byKind["link:a"]
+	if len(entries) != 3 || props == nil || badRow == nil || link == nil {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if badRow.VersionID != "bad:v1" || badRow.Raw == "" || link.Target != "b" || link.LinkType != "cites" {
+		t.Errorf("entries = %+v %+v", badRow, link)
+	}
+
+	check, err := repo.CheckStoredRows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.NodesChecked != 3 || check.LinksChecked != 1 || check.Found != 3 {
+		t.Errorf("check = %+v", check)
+	}
+	if e, err := repo.GetQuarantine(ctx, props.ID); err != nil || e.Occurrences < 2 {
+		t.Errorf("GetQuarantine = %+v, %v", e, err)
+	}
+
+	// Replacing properties cannot fix a bad version, so that row is dropped
+	if _, err := repo.RepairQuarantine(ctx, badRow.ID, QuarantineRepair{Action: RepairReplace}); !errors.Is(err, ErrNotRepaired) {
+		t.Errorf("replace bad row: %v", err)
+	}
+	if e, err := repo.RepairQuarantine(ctx, badRow.ID, QuarantineRepair{Action: RepairDrop, Note: "re-ingesting"}); err != nil || e.Status != QuarantineDropped || e.Resolution != "drop: re-ingesting" {
+		t.Errorf("drop = %+v, %v", e, err)
+	}
+	if _, err := repo.GetNode(ctx, "bad"); err == nil {
+		t.Error("dropped node still readable")
+	}
+
+	if e, err := repo.RepairQuarantine(ctx, props.ID, QuarantineRepair{Action: RepairReplace, Properties: map[string]interface{}{"name": "A"}}); err != nil || e.Status != QuarantineRepaired || e.ResolvedAt == nil {
+		t.Errorf("replace = %+v, %v", e, err)
+	}
+	if node, err := repo.GetNode(ctx, "a"); err != nil || node.Meta["name"] != "A" {
+		t.Errorf("repaired node = %+v, %v", node, err)
+	}
+	if _, err := repo.RepairQuarantine(ctx, props.ID, QuarantineRepair{Action: RepairDismiss}); !errors.Is(err, ErrInvalidRepair) {
+		t.Errorf("repairing twice: %v", err)
+	}
+
+	// A dismissed entry stays dismissed when the row is read again
+	if _, err := repo.RepairQuarantine(ctx, link.ID, QuarantineRepair{Action: RepairDismiss}); err != nil {
+		t.Fatal(err)
+	}
+	repo.GetLinks(ctx, "a")
+	if open, err := repo.ListQuarantine(ctx, QuarantineOpen, 0, 0); err != nil || len(open) != 0 {
+		t.Errorf("open = %+v, %v", open, err)
+	}
+	if _, err := repo.GetQuarantine(ctx, 999); !errors.Is(err, ErrQuarantineNotFound) {
+		t.Errorf("GetQuarantine(999): %v", err)
+	}
+}
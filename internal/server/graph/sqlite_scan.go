@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// Node rows are read with nodeScanner, which scans each row of a result set
+// into the same destinations, and node timestamps come from the integer
+// created_unix and modified_unix columns rather than RFC3339 text.
+
+// nodeColumns selects the columns nodeScanner reads. nNodeColumns is the
+// same for queries that alias nodes as n.
+const (
+	nodeColumns = `version_id, id, version, is_current, type, ` + contentColumn + `, properties,
+		       created_unix, modified_unix, deleted, deleted_at, change_note, changed_by, degree`
+	nNodeColumns = `n.version_id, n.id, n.version, n.is_current, n.type, ` + nContentColumn + `, n.properties,
+		       n.created_unix, n.modified_unix, n.deleted, n.deleted_at, n.change_note, n.changed_by, n.degree`
+)
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// nodeScanner scans node rows selected with nodeColumns, followed by any
+// extra columns passed to newNodeScanner
+type nodeScanner struct {
+	versionID, id, nodeType             string
+	version, isCurrent, deleted, degree int
+	content                             []byte
+	properties, deletedAt               sql.NullString
+	changeNote, changedBy               sql.NullString
+	created, modified                   sql.NullInt64
+
+	dest   []interface{}
+	report func(QuarantineEntry) // Optional; told of rows that cannot be read
+}
+
+// newNodeScanner creates a scanner that also fills extra, in order, from
+// the columns after nodeColumns
+func newNodeScanner(extra ...interface{}) *nodeScanner {
+	s := &nodeScanner{}
+	s.dest = append([]interface{}{
+		&s.versionID, &s.id, &s.version, &s.isCurrent, &s.nodeType, &s.content, &s.properties,
+		&s.created, &s.modified, &s.deleted, &s.deletedAt, &s.changeNote, &s.changedBy, &s.degree,
+	}, extra...)
+	return s
+}
+
+// scanner creates a node scanner that quarantines the rows it cannot read
+func (r *SQLiteRepository) scanner(extra ...interface{}) *nodeScanner {
+	s := newNodeScanner(extra...)
+	s.report = r.quarantine
+	return s
+}
+
+// scan reads the current row into a new node
+func (s *nodeScanner) scan(row rowScanner) (*core.Node, error) {
+	if err := row.Scan(s.dest...); err != nil {
+		// A single row's error reaches the caller; result sets skip the row
+		if rows, ok := row.(*sql.Rows); ok && s.report != nil {
+			s.report(unreadableRow(QuarantineNode, rows, err))
+		}
+		return nil, err
+	}
+
+	node := &core.Node{
+		VersionID:  s.versionID,
+		ID:         s.id,
+		Version:    s.version,
+		IsCurrent:  s.isCurrent == 1,
+		Type:       s.nodeType,
+		Content:    s.content,
+		Deleted:    s.deleted == 1,
+		Created:    unixTime(s.created),
+		Modified:   unixTime(s.modified),
+		ChangeNote: s.changeNote.String,
+		ChangedBy:  s.changedBy.String,
+	}
+	if s.properties.String != "" {
+		if err := json.Unmarshal([]byte(s.properties.String), &node.Meta); err != nil && s.report != nil {
+			s.report(QuarantineEntry{
+				Kind:      QuarantineNode,
+				NodeID:    s.id,
+				VersionID: s.versionID,
+				Problem:   "properties: " + err.Error(),
+				Raw:       rawColumns(map[string]interface{}{"properties": s.properties.String}),
+			})
+		}
+	}
+	if s.deletedAt.Valid {
+		if t, err := time.Parse(time.RFC3339, s.deletedAt.String); err == nil {
+			node.DeletedAt = t
+		}
+	}
+	return node, nil
+}
+
+// unixTime converts an epoch seconds column, which is NULL only for rows
+// whose text timestamp could not be parsed, to a time
+func unixTime(v sql.NullInt64) time.Time {
+	if !v.Valid {
+		return time.Time{}
+	}
+	return time.Unix(v.Int64, 0)
+}
+
+func (r *SQLiteRepository) scanNode(row *sql.Row) (*core.Node, error) {
+	node, err := r.scanner().scan(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("node not found")
+		}
+		return nil, err
+	}
+	return node, nil
+}
+
+func (r *SQLiteRepository) scanNodes(rows *sql.Rows) ([]*core.Node, error) {
+	var nodes []*core.Node
+	s := r.scanner()
+	for rows.Next() {
+		node, err := s.scan(rows)
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// prepared returns the cached statement for query, preparing it on first use
+func (db *observedDB) prepared(ctx context.Context, query string) (*sql.Stmt, error) {
+	if stmt, ok := db.stmts.Load(query); ok {
+		return stmt.(*sql.Stmt), nil
+	}
+	stmt, err := db.DB.PrepareContext(ctx, db.rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	if cached, loaded := db.stmts.LoadOrStore(query, stmt); loaded {
+		stmt.Close()
+		return cached.(*sql.Stmt), nil
+	}
+	return stmt, nil
+}
+
+// queryCached is QueryContext through a cached prepared statement, for
+// queries whose text does not vary between calls
+func (db *observedDB) queryCached(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if db.pinned(ctx) != nil {
+		return db.QueryContext(ctx, query, args...)
+	}
+	stmt, err := db.prepared(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	recordStatement(ctx, db.rebind(query), args)
+	return stmt.QueryContext(ctx, args...)
+}
+
+// queryRowCached is QueryRowContext through a cached prepared statement,
+// falling back to an unprepared query if preparing fails
+func (db *observedDB) queryRowCached(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	stmt, err := db.prepared(ctx, query)
+	if err != nil || db.pinned(ctx) != nil {
+		return db.QueryRowContext(ctx, query, args...)
+	}
+	recordStatement(ctx, db.rebind(query), args)
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// Close closes the cached statements and the database
+func (db *observedDB) Close() error {
+	db.stmts.Range(func(query, stmt interface{}) bool {
+		stmt.(*sql.Stmt).Close()
+		db.stmts.Delete(query)
+		return true
+	})
+	return db.DB.Close()
+}
@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestNodeEpochBackfill(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+
+	// A row written before the epoch columns existed
+	if _, err := repo.db.ExecContext(ctx, `
+		INSERT INTO nodes (version_id, id, type, properties, created_at, modified_at)
+		VALUES ('old:v1', 'old', 'Note', '{}', '2024-03-01T12:00:00+02:00', '2024-03-02T08:30:00Z')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.db.ExecContext(ctx, backfillNodesUnix); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+
+	node, err := repo.GetNode(ctx, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC); !node.Created.Equal(want) {
+		t.Errorf("Created = %v, want %v", node.Created, want)
+	}
+	if want := time.Date(2024, 3, 2, 8, 30, 0, 0, time.UTC); !node.Modified.Equal(want) {
+		t.Errorf("Modified = %v, want %v", node.Modified, want)
+	}
+
+	found, err := repo.QueryTimeRange(ctx, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), nil, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "old" {
+		t.Errorf("QueryTimeRange = %d nodes", len(found))
+	}
+
+	snapshot, err := repo.GetGraphSnapshot(ctx, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Nodes) != 1 || !snapshot.Nodes[0].Modified.Equal(node.Modified) {
+		t.Errorf("GetGraphSnapshot = %d nodes", len(snapshot.Nodes))
+	}
+}
+
+func TestPreparedStatementCache(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+
+	now := time.Now()
+	for _, id := range []string{"a", "b"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Content: []byte(id), Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"a", "b", "a"} {
+		node, err := repo.GetNode(ctx, id)
+		if err != nil || node.ID != id || string(node.Content) != id {
+			t.Fatalf("GetNode(%s) = %+v, %v", id, node, err)
+		}
+		if node.Created.Unix() != now.Unix() {
+			t.Errorf("Created = %v, want %v", node.Created, now)
+		}
+	}
+	if _, err := repo.GetNode(ctx, "missing"); err == nil {
+		t.Error("GetNode(missing): expected an error")
+	}
+
+	cached := 0
+	repo.db.stmts.Range(func(_, _ interface{}) bool {
+		cached++
+		return true
+	})
+	if cached != 1 {
+		t.Errorf("%d cached statements, want 1", cached)
+	}
+}
+
+// BenchmarkSQLiteScanNodes reads 5k nodes per query
+func BenchmarkSQLiteScanNodes(b *testing.B) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(b.TempDir(), "memex.db"))
+	if err != nil {
+		b.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+
+	now := time.Now()
+	nodes := make([]*core.Node, 5000)
+	for i := range nodes {
+		nodes[i] = &core.Node{
+			ID:       fmt.Sprintf("n%d", i),
+			Type:     "Note",
+			Content:  []byte(fmt.Sprintf("note %d", i)),
+			Meta:     map[string]interface{}{"rank": i},
+			Created:  now,
+			Modified: now,
+		}
+	}
+	if err := repo.CreateNodes(ctx, nodes); err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		found, err := repo.FilterNodes(ctx, []string{"Note"}, "", "", len(nodes), 0)
+		if err != nil || len(found) != len(nodes) {
+			b.Fatalf("FilterNodes = %d nodes, %v", len(found), err)
+		}
+	}
+}
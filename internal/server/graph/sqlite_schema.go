@@ -1,5 +1,7 @@
 package graph
 
+import "fmt"
+
 // SQLite schema DDL constants
 
 const schemaNodes = `
@@ -40,6 +42,18 @@ CREATE TABLE IF NOT EXISTS version_chain (
     PRIMARY KEY (newer_version_id, older_version_id)
 )`
 
+// Removed links are recorded so graph diffs can report them
+const schemaLinkTombstones = `
+CREATE TABLE IF NOT EXISTS link_tombstones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source_id TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    properties TEXT,
+    created_at DATETIME NOT NULL,
+    deleted_at DATETIME NOT NULL
+)`
+
 // FTS5 virtual table for full-text search
 const schemaNodesFTS = `
 CREATE VIRTUAL TABLE IF NOT EXISTS nodes_fts USING fts5(
@@ -78,9 +92,132 @@ const indexNodesType = `CREATE INDEX IF NOT EXISTS idx_nodes_type ON nodes(type)
 const indexNodesIsCurrent = `CREATE INDEX IF NOT EXISTS idx_nodes_is_current ON nodes(is_current)`
 const indexNodesDeleted = `CREATE INDEX IF NOT EXISTS idx_nodes_deleted ON nodes(deleted)`
 const indexNodesVersionID = `CREATE INDEX IF NOT EXISTS idx_nodes_version_id ON nodes(version_id)`
+const indexNodesCreatedAt = `CREATE INDEX IF NOT EXISTS idx_nodes_created_at ON nodes(created_at)`
+const indexNodesModifiedAt = `CREATE INDEX IF NOT EXISTS idx_nodes_modified_at ON nodes(modified_at)`
 const indexLinksSource = `CREATE INDEX IF NOT EXISTS idx_links_source ON links(source_id)`
 const indexLinksTarget = `CREATE INDEX IF NOT EXISTS idx_links_target ON links(target_id)`
 const indexLinksType = `CREATE INDEX IF NOT EXISTS idx_links_type ON links(type)`
+const indexLinksCreatedAt = `CREATE INDEX IF NOT EXISTS idx_links_created_at ON links(created_at)`
+const indexLinkTombstonesDeletedAt = `CREATE INDEX IF NOT EXISTS idx_link_tombstones_deleted_at ON link_tombstones(deleted_at)`
+
+// Content store: node content of at least dedupMinBytes is stored once per
+// distinct sha256 and referenced from nodes.content_hash (content is then NULL)
+const schemaContents = `
+CREATE TABLE IF NOT EXISTS contents (
+    hash TEXT PRIMARY KEY,
+    data BLOB NOT NULL,
+    size INTEGER NOT NULL,
+    refcount INTEGER NOT NULL DEFAULT 0
+)`
+
+const alterNodesContentHash = `ALTER TABLE nodes ADD COLUMN content_hash TEXT`
+const indexNodesContentHash = `CREATE INDEX IF NOT EXISTS idx_nodes_content_hash ON nodes(content_hash)`
+
+// Node timestamps as epoch seconds, read and filtered on instead of the
+// RFC3339 text columns, which are still written for older readers
+const (
+	alterNodesCreatedUnix  = `ALTER TABLE nodes ADD COLUMN created_unix INTEGER`
+	alterNodesModifiedUnix = `ALTER TABLE nodes ADD COLUMN modified_unix INTEGER`
+	indexNodesCreatedUnix  = `CREATE INDEX IF NOT EXISTS idx_nodes_created_unix ON nodes(created_unix)`
+	indexNodesModifiedUnix = `CREATE INDEX IF NOT EXISTS idx_nodes_modified_unix ON nodes(modified_unix)`
+	backfillNodesUnix      = `UPDATE nodes SET created_unix = CAST(strftime('%s', created_at) AS INTEGER),
+	                                          modified_unix = CAST(strftime('%s', modified_at) AS INTEGER)`
+)
+
+// Typed link weight and direction, promoted from the properties JSON
+// (0 is unweighted; bidirectional is 0 or 1)
+const (
+	alterLinksWeight                 = `ALTER TABLE links ADD COLUMN weight REAL NOT NULL DEFAULT 0`
+	alterLinksBidirectional          = `ALTER TABLE links ADD COLUMN bidirectional INTEGER NOT NULL DEFAULT 0`
+	alterLinkTombstonesWeight        = `ALTER TABLE link_tombstones ADD COLUMN weight REAL NOT NULL DEFAULT 0`
+	alterLinkTombstonesBidirectional = `ALTER TABLE link_tombstones ADD COLUMN bidirectional INTEGER NOT NULL DEFAULT 0`
+)
+
+// Stored rows that could not be read (see sqlite_quarantine.go). row_key
+// is the node's version_id, or the link's source, type and target.
+const schemaQuarantine = `
+CREATE TABLE IF NOT EXISTS quarantine (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    row_key TEXT NOT NULL,
+    node_id TEXT NOT NULL,
+    version_id TEXT NOT NULL DEFAULT '',
+    target_id TEXT NOT NULL DEFAULT '',
+    link_type TEXT NOT NULL DEFAULT '',
+    problem TEXT NOT NULL,
+    raw TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    first_seen TEXT NOT NULL,
+    last_seen TEXT NOT NULL,
+    resolved_at TEXT,
+    resolution TEXT NOT NULL DEFAULT '',
+    UNIQUE(kind, row_key)
+)`
+
+const indexQuarantineStatus = `CREATE INDEX IF NOT EXISTS idx_quarantine_status ON quarantine(status)`
+
+// FTS triggers that index stored content whether inline or in the content store
+const triggerFTSInsertResolved = `
+CREATE TRIGGER nodes_fts_insert AFTER INSERT ON nodes BEGIN
+    INSERT INTO nodes_fts(rowid, id, type, content, properties)
+    VALUES (NEW.rowid, NEW.id, NEW.type,
+            COALESCE(NEW.content, (SELECT data FROM contents WHERE hash = NEW.content_hash)), NEW.properties);
+END`
+
+const triggerFTSDeleteResolved = `
+CREATE TRIGGER nodes_fts_delete AFTER DELETE ON nodes BEGIN
+    INSERT INTO nodes_fts(nodes_fts, rowid, id, type, content, properties)
+    VALUES ('delete', OLD.rowid, OLD.id, OLD.type,
+            COALESCE(OLD.content, (SELECT data FROM contents WHERE hash = OLD.content_hash)), OLD.properties);
+END`
+
+const triggerFTSUpdateResolved = `
+CREATE TRIGGER nodes_fts_update AFTER UPDATE ON nodes BEGIN
+    INSERT INTO nodes_fts(nodes_fts, rowid, id, type, content, properties)
+    VALUES ('delete', OLD.rowid, OLD.id, OLD.type,
+            COALESCE(OLD.content, (SELECT data FROM contents WHERE hash = OLD.content_hash)), OLD.properties);
+    INSERT INTO nodes_fts(rowid, id, type, content, properties)
+    VALUES (NEW.rowid, NEW.id, NEW.type,
+            COALESCE(NEW.content, (SELECT data FROM contents WHERE hash = NEW.content_hash)), NEW.properties);
+END`
+
+// Trigram index over node names, for spelling-tolerant suggestions (see
+// similar.go). It holds the name, title, label and alias properties of every
+// node row, one per line.
+const schemaNodesTrigram = `
+CREATE VIRTUAL TABLE IF NOT EXISTS nodes_trigram USING fts5(
+    names,
+    tokenize='trigram'
+)`
+
+// trigramNames returns the names column for a nodes row, NEW, OLD or an alias
+func trigramNames(row string) string {
+	return fmt.Sprintf(`CASE WHEN json_valid(%[1]s.properties) THEN trim(
+            coalesce(json_extract(%[1]s.properties, '$.name'), '') || char(10) ||
+            coalesce(json_extract(%[1]s.properties, '$.title'), '') || char(10) ||
+            coalesce(json_extract(%[1]s.properties, '$.label'), '') || char(10) ||
+            coalesce(json_extract(%[1]s.properties, '$.alias'), ''), char(10)) ELSE '' END`, row)
+}
+
+// trigramStatements creates the trigram index, the triggers that keep it in
+// sync with the nodes table and fills it from the existing rows
+func trigramStatements() []string {
+	return []string{
+		schemaNodesTrigram,
+		`CREATE TRIGGER IF NOT EXISTS nodes_trigram_insert AFTER INSERT ON nodes BEGIN
+    INSERT INTO nodes_trigram(rowid, names) VALUES (NEW.rowid, ` + trigramNames("NEW") + `);
+END`,
+		`CREATE TRIGGER IF NOT EXISTS nodes_trigram_delete AFTER DELETE ON nodes BEGIN
+    DELETE FROM nodes_trigram WHERE rowid = OLD.rowid;
+END`,
+		`CREATE TRIGGER IF NOT EXISTS nodes_trigram_update AFTER UPDATE OF properties ON nodes BEGIN
+    DELETE FROM nodes_trigram WHERE rowid = OLD.rowid;
+    INSERT INTO nodes_trigram(rowid, names) VALUES (NEW.rowid, ` + trigramNames("NEW") + `);
+END`,
+		`INSERT INTO nodes_trigram(rowid, names) SELECT n.rowid, ` + trigramNames("n") + ` FROM nodes n`,
+	}
+}
 
 // SQLite pragmas for optimal performance
 const pragmaWAL = `PRAGMA journal_mode=WAL`
@@ -88,7 +225,8 @@ const pragmaFK = `PRAGMA foreign_keys=ON`
 const pragmaBusyTimeout = `PRAGMA busy_timeout=5000`
 const pragmaSynchronous = `PRAGMA synchronous=NORMAL`
 
-// allSchemaStatements returns all schema DDL in order
+// allSchemaStatements returns the baseline schema DDL in order.
+// Later changes are added as migrations (see sqlite_migrations.go).
 func allSchemaStatements() []string {
 	return []string{
 		schemaNodes,
@@ -0,0 +1,258 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// GetSubgraph reads the neighborhood's node rows as the traversal streams
+// them out and, meanwhile, loads the links of each batch of nodes read on
+// other connections. Links leaving the neighborhood are dropped once the
+// traversal is complete.
+
+const (
+	subgraphNodeLimit = 1000 // Nodes in one subgraph
+	subgraphBatch     = 100  // Nodes whose links are loaded by one query
+	readPoolSize      = 4    // Read-only connections, and concurrent link queries
+)
+
+// openReaders opens a read-only connection pool on a SQLite file, or
+// returns nil for in-memory databases, whose connections cannot share data
+func openReaders(dbPath string) (*observedDB, error) {
+	if dbPath == "" || strings.HasPrefix(dbPath, ":memory:") || strings.HasPrefix(dbPath, "file:") {
+		return nil, nil
+	}
+	dsn := (&url.URL{
+		Scheme:   "file",
+		Path:     dbPath,
+		RawQuery: "mode=ro&_pragma=busy_timeout(5000)",
+	}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite read pool: %w", err)
+	}
+	db.SetMaxOpenConns(readPoolSize)
+	db.SetMaxIdleConns(readPoolSize)
+	return &observedDB{DB: db}, nil
+}
+
+// reader returns the pool for concurrent reads
+func (r *SQLiteRepository) reader() *observedDB {
+	if r.readers != nil {
+		return r.readers
+	}
+	return r.db
+}
+
+// GetSubgraph extracts a subgraph centered on a start node
+func (r *SQLiteRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
+	db := r.reader()
+	fetcher := newEdgeFetcher(ctx, db, relationshipTypes, r.readWorkers())
+	// Without a read pool the links wait for the node rows, which hold the
+	// only connection an in-memory database has
+	streaming := r.readers != nil
+	var nodes []*core.Node
+	in := make(map[string]bool)
+	add := func(node *core.Node) {
+		if in[node.ID] {
+			return
+		}
+		in[node.ID] = true
+		nodes = append(nodes, node)
+		if streaming {
+			fetcher.add(node.ID)
+		}
+	}
+
+	if meter := TraversalMeterFrom(ctx); meter != nil {
+		found, err := r.traverseMetered(ctx, meter, startNodeID, depth, relationshipTypes, subgraphNodeLimit, 0)
+		if err != nil {
+			fetcher.wait()
+			return nil, err
+		}
+		for _, node := range found {
+			add(node)
+		}
+	} else {
+		query, args := traverseQuery(startNodeID, depth, relationshipTypes, subgraphNodeLimit, 0)
+		if err := r.streamNodes(ctx, db, add, query, args); err != nil {
+			fetcher.wait()
+			return nil, err
+		}
+	}
+
+	if !streaming {
+		for _, node := range nodes {
+			fetcher.add(node.ID)
+		}
+	}
+	links, err := fetcher.wait()
+	if err != nil {
+		return nil, err
+	}
+	edges := make([]*SubgraphEdge, 0, len(links))
+	for _, e := range links {
+		if in[e.Target] {
+			edges = append(edges, e)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Type < b.Type
+	})
+
+	return &Subgraph{
+		Nodes: nodes,
+		Edges: edges,
+		Stats: SubgraphStats{
+			NodeCount: len(nodes),
+			EdgeCount: len(edges),
+			Depth:     depth,
+		},
+	}, nil
+}
+
+// readWorkers returns how many link queries GetSubgraph runs at once
+func (r *SQLiteRepository) readWorkers() int {
+	if r.readers == nil {
+		return 1
+	}
+	if r.subgraphWorkers > 0 {
+		return r.subgraphWorkers
+	}
+	return readPoolSize
+}
+
+// streamNodes runs a node query, passing each node to fn as it is read
+func (r *SQLiteRepository) streamNodes(ctx context.Context, db *observedDB, fn func(*core.Node), query string, args []interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	scanner := r.scanner()
+	for rows.Next() {
+		node, err := scanner.scan(rows)
+		if err != nil {
+			continue
+		}
+		fn(node)
+	}
+	return rows.Err()
+}
+
+// edgeFetcher loads the outgoing links of batches of nodes concurrently
+type edgeFetcher struct {
+	ctx        context.Context
+	db         *observedDB
+	typeFilter string
+	typeArgs   []interface{}
+	batch      []string
+	slots      chan struct{} // Bounds the queries in flight
+	wg         sync.WaitGroup
+
+	mu    sync.Mutex
+	edges []*SubgraphEdge
+	err   error
+}
+
+// newEdgeFetcher creates a fetcher running up to workers queries at once
+func newEdgeFetcher(ctx context.Context, db *observedDB, relationshipTypes []string, workers int) *edgeFetcher {
+	f := &edgeFetcher{ctx: ctx, db: db, slots: make(chan struct{}, workers)}
+	if len(relationshipTypes) > 0 {
+		f.typeFilter = " AND type IN (" + strings.TrimSuffix(strings.Repeat("?,", len(relationshipTypes)), ",") + ")"
+		for _, t := range relationshipTypes {
+			f.typeArgs = append(f.typeArgs, t)
+		}
+	}
+	return f
+}
+
+// add queues a node, starting a query once a batch is full
+func (f *edgeFetcher) add(id string) {
+	f.batch = append(f.batch, id)
+	if len(f.batch) >= subgraphBatch {
+		f.flush()
+	}
+}
+
+// flush starts a query for the queued nodes, waiting for a free slot
+func (f *edgeFetcher) flush() {
+	if len(f.batch) == 0 {
+		return
+	}
+	ids := f.batch
+	f.batch = nil
+	f.slots <- struct{}{}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		defer func() { <-f.slots }()
+		edges, err := f.fetch(ids)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if err != nil {
+			if f.err == nil {
+				f.err = err
+			}
+			return
+		}
+		f.edges = append(f.edges, edges...)
+	}()
+}
+
+// wait flushes the last batch and returns every link loaded
+func (f *edgeFetcher) wait() ([]*SubgraphEdge, error) {
+	f.flush()
+	f.wg.Wait()
+	return f.edges, f.err
+}
+
+// fetch loads the outgoing links of ids
+func (f *edgeFetcher) fetch(ids []string) ([]*SubgraphEdge, error) {
+	args := make([]interface{}, 0, len(ids)+len(f.typeArgs))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, f.typeArgs...)
+	rows, err := f.db.QueryContext(f.ctx, `
+		SELECT source_id, target_id, type, properties, weight, bidirectional
+		FROM links
+		WHERE source_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")+`)`+f.typeFilter, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []*SubgraphEdge
+	for rows.Next() {
+		var sourceID, targetID, linkType string
+		var propsStr sql.NullString
+		var weight float64
+		var bidirectional int
+		if err := rows.Scan(&sourceID, &targetID, &linkType, &propsStr, &weight, &bidirectional); err != nil {
+			continue
+		}
+
+		var meta map[string]interface{}
+		if propsStr.Valid {
+			json.Unmarshal([]byte(propsStr.String), &meta)
+		}
+		edges = append(edges, &SubgraphEdge{Source: sourceID, Target: targetID, Type: linkType, Meta: meta, Weight: weight, Bidirectional: bidirectional == 1})
+	}
+	return edges, rows.Err()
+}
@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// mesh returns nodes n0..n<size-1>, each linking to up to fanout others
+// chosen by a fixed sequence, alternating LINKS and CITES
+func mesh(size, fanout int) ([]*core.Node, []*core.Link) {
+	now := time.Now()
+	nodes := make([]*core.Node, size)
+	for i := range nodes {
+		nodes[i] = &core.Node{ID: fmt.Sprintf("n%d", i), Type: "Note", Content: []byte(fmt.Sprintf("note %d", i)), Created: now, Modified: now}
+	}
+	seed := uint32(1)
+	var links []*core.Link
+	for i := 0; i < size; i++ {
+		linked := map[int]bool{i: true}
+		for j := 0; j < fanout; j++ {
+			seed = seed*1664525 + 1013904223
+			target := int(seed>>8) % size
+			if linked[target] {
+				continue
+			}
+			linked[target] = true
+			linkType := "LINKS"
+			if j%2 == 1 {
+				linkType = "CITES"
+			}
+			links = append(links, &core.Link{Source: nodes[i].ID, Target: nodes[target].ID, Type: linkType, Created: now, Modified: now})
+		}
+	}
+	return nodes, links
+}
+
+func TestSQLiteSubgraph(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+	memory := NewMemory()
+	nodes, links := mesh(300, 3)
+	for _, repo := range []Repository{sqlite, memory} {
+		if err := repo.CreateNodes(ctx, nodes); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.CreateLinks(ctx, links); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, types := range [][]string{nil, {"CITES"}} {
+		for _, workers := range []int{1, readPoolSize} {
+			sqlite.subgraphWorkers = workers
+			got, err := sqlite.GetSubgraph(ctx, "n0", 3, types)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := memory.GetSubgraph(ctx, "n0", 3, types)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Stats.NodeCount != want.Stats.NodeCount || got.Stats.EdgeCount != want.Stats.EdgeCount {
+				t.Errorf("types %v, %d workers: %d nodes %d edges, want %d nodes %d edges", types, workers,
+					got.Stats.NodeCount, got.Stats.EdgeCount, want.Stats.NodeCount, want.Stats.EdgeCount)
+			}
+			for i := 1; i < len(got.Edges); i++ {
+				if a, b := got.Edges[i-1], got.Edges[i]; a.Source > b.Source {
+					t.Fatalf("edges out of order: %s after %s", b.Source, a.Source)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkSQLiteSubgraph extracts depth-3 neighborhoods from a 12k node
+// graph, loading links on one connection and on the read pool
+func BenchmarkSQLiteSubgraph(b *testing.B) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(b.TempDir(), "memex.db"))
+	if err != nil {
+		b.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+	nodes, links := mesh(12000, 12)
+	if err := repo.CreateNodes(ctx, nodes); err != nil {
+		b.Fatal(err)
+	}
+	// CreateLinks keeps node degrees, which is slow at this size and not
+	// read here
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, l := range links {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO links (source_id, target_id, type, properties, created_at, modified_at) VALUES (?, ?, ?, '{}', '', '')`,
+			l.Source, l.Target, l.Type); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range []int{1, readPoolSize} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			repo.subgraphWorkers = workers
+			i := 0
+			for b.Loop() {
+				sub, err := repo.GetSubgraph(ctx, fmt.Sprintf("n%d", i%100), 3, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if sub.Stats.NodeCount < subgraphNodeLimit {
+					b.Fatalf("neighborhood of %d nodes", sub.Stats.NodeCount)
+				}
+				i++
+			}
+		})
+	}
+}
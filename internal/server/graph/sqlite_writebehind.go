@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// With write-behind enabled, CreateNode and CreateLink queue their insert
+// and a background writer commits the queue as one transaction when the
+// oldest write has waited MaxLatency or MaxBatch writes are queued, so a
+// stream of small writes shares one fsync per group instead of paying one
+// each.
+//
+// Durability: by default callers wait for their group to commit, so a nil
+// error still means the write is on disk; each write just takes up to
+// MaxLatency longer. With Async set, callers return as soon as the write is
+// queued. Then a crash or kill loses the writes queued in the last
+// MaxLatency, insert errors (such as a duplicate link) are logged instead of
+// returned, and reads may not see a write until its group commits.
+
+// DefaultWriteBehindBatch is the group size used when MaxBatch is unset
+const DefaultWriteBehindBatch = 500
+
+// ErrWriteBehindClosed is returned for writes made after Close
+var ErrWriteBehindClosed = errors.New("repository closed")
+
+// WriteBehindConfig configures grouped inserts
+type WriteBehindConfig struct {
+	MaxLatency time.Duration // Longest a queued write waits for its group to commit
+	MaxBatch   int           // Writes that commit a group early; zero uses DefaultWriteBehindBatch
+	Async      bool          // Return once queued instead of once committed
+}
+
+// pendingWrite is one queued node or link insert
+type pendingWrite struct {
+	node   *core.Node
+	link   *core.Link
+	result chan error // Receives the outcome; nil for async writes
+}
+
+// writeBehind queues inserts for the background writer
+type writeBehind struct {
+	cfg   WriteBehindConfig
+	queue chan *pendingWrite
+	done  chan struct{} // Closed when the writer has committed the last group
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// EnableWriteBehind groups later CreateNode and CreateLink calls into
+// periodic transactions. Call it once, before the repository is shared.
+func (r *SQLiteRepository) EnableWriteBehind(cfg WriteBehindConfig) {
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = DefaultWriteBehindBatch
+	}
+	w := &writeBehind{
+		cfg:   cfg,
+		queue: make(chan *pendingWrite, cfg.MaxBatch),
+		done:  make(chan struct{}),
+	}
+	r.writes = w
+	go r.runWriteBehind(w)
+}
+
+// submit queues a write, waiting for it to commit unless the queue is async.
+// A synchronous write whose ctx ends while it waits may still commit.
+func (w *writeBehind) submit(ctx context.Context, pw *pendingWrite) error {
+	if w.cfg.Async {
+		// The caller may reuse its value once we return
+		if pw.node != nil {
+			node := *pw.node
+			pw.node = &node
+		}
+		if pw.link != nil {
+			link := *pw.link
+			pw.link = &link
+		}
+	} else {
+		pw.result = make(chan error, 1)
+	}
+
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return ErrWriteBehindClosed
+	}
+	select {
+	case w.queue <- pw:
+	case <-ctx.Done():
+		w.mu.RUnlock()
+		return ctx.Err()
+	}
+	w.mu.RUnlock()
+
+	if pw.result == nil {
+		return nil
+	}
+	select {
+	case err := <-pw.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting writes and waits for the queued ones to commit
+func (w *writeBehind) close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+}
+
+// runWriteBehind commits queued writes in groups until the queue is closed
+func (r *SQLiteRepository) runWriteBehind(w *writeBehind) {
+	defer close(w.done)
+
+	timer := time.NewTimer(w.cfg.MaxLatency)
+	timer.Stop()
+	var group []*pendingWrite
+	flush := func() {
+		timer.Stop()
+		r.commitWrites(group)
+		group = nil
+	}
+
+	for {
+		select {
+		case pw, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			if len(group) == 0 {
+				timer.Reset(w.cfg.MaxLatency)
+			}
+			group = append(group, pw)
+			if len(group) >= w.cfg.MaxBatch {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+// commitWrites inserts a group in one transaction. Each write runs in its
+// own savepoint, so a failed insert is reported to its caller without
+// undoing the rest of the group.
+func (r *SQLiteRepository) commitWrites(group []*pendingWrite) {
+	if len(group) == 0 {
+		return
+	}
+	ctx := context.Background()
+	errs := make([]error, len(group))
+
+	err := func() error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for i, pw := range group {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT write_behind`); err != nil {
+				return err
+			}
+			if pw.node != nil {
+				errs[i] = r.insertNode(ctx, tx, pw.node)
+			} else {
+				errs[i] = r.insertLink(ctx, tx, pw.link)
+			}
+			if errs[i] != nil {
+				if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT write_behind`); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT write_behind`); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	}()
+
+	for i, pw := range group {
+		if errs[i] == nil {
+			errs[i] = err
+		}
+		if errs[i] == nil {
+			if pw.node != nil {
+				r.emitNodeCreated(pw.node)
+			} else {
+				r.emitLinkCreated(pw.link)
+			}
+		}
+		if pw.result != nil {
+			pw.result <- errs[i]
+		} else if errs[i] != nil {
+			log.Printf("write-behind: %v", errs[i])
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestWriteBehindGroupCommit(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+	var mu sync.Mutex
+	events := 0
+	repo.SetEventEmitter(func(subscriptions.Event) {
+		mu.Lock()
+		events++
+		mu.Unlock()
+	})
+	repo.EnableWriteBehind(WriteBehindConfig{MaxLatency: 20 * time.Millisecond, MaxBatch: 8})
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.CreateNode(ctx, &core.Node{ID: fmt.Sprintf("n%d", i), Type: "Note", Created: now, Modified: now})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("CreateNode(n%d) = %v", i, err)
+		}
+	}
+	// Committed once CreateNode returns
+	if _, err := repo.GetNode(ctx, "n19"); err != nil {
+		t.Fatalf("GetNode after commit: %v", err)
+	}
+
+	link := &core.Link{Source: "n0", Target: "n1", Type: "LINKS", Created: now, Modified: now}
+	if err := repo.CreateLink(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+	// A failed insert is reported to its caller and leaves the rest of its group
+	var dupErr, otherErr error
+	wg.Add(2)
+	go func() { defer wg.Done(); dupErr = repo.CreateLink(ctx, link) }()
+	go func() {
+		defer wg.Done()
+		otherErr = repo.CreateLink(ctx, &core.Link{Source: "n1", Target: "n2", Type: "LINKS", Created: now, Modified: now})
+	}()
+	wg.Wait()
+	if dupErr == nil || otherErr != nil {
+		t.Errorf("duplicate link: %v, other link: %v", dupErr, otherErr)
+	}
+	links, err := repo.GetLinks(ctx, "n1")
+	if err != nil || len(links) != 1 {
+		t.Errorf("GetLinks(n1) = %d links, %v", len(links), err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if events != 22 {
+		t.Errorf("%d events, want 22", events)
+	}
+}
+
+func TestWriteBehindAsync(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memex.db")
+	repo, err := NewSQLite(ctx, path)
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	repo.EnableWriteBehind(WriteBehindConfig{MaxLatency: time.Hour, Async: true})
+
+	now := time.Now()
+	node := &core.Node{ID: "queued", Type: "Note", Created: now, Modified: now}
+	if err := repo.CreateNode(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	node.ID = "changed after return"
+	if _, err := repo.GetNode(ctx, "queued"); err == nil {
+		t.Error("async write visible before its group committed")
+	}
+
+	// Close commits what is queued
+	repo.Close(ctx)
+	if err := repo.CreateNode(ctx, &core.Node{ID: "late", Type: "Note"}); !errors.Is(err, ErrWriteBehindClosed) {
+		t.Errorf("CreateNode after Close = %v", err)
+	}
+
+	repo, err = NewSQLite(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close(ctx)
+	if _, err := repo.GetNode(ctx, "queued"); err != nil {
+		t.Errorf("queued write lost on Close: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SizeByDegree sizes nodes by their link count in the view
+const SizeByDegree = "degree"
+
+var (
+	validColor  = regexp.MustCompile(`^(#[0-9A-Fa-f]{3}|#[0-9A-Fa-f]{6}|[a-z]{3,20})$`)
+	validIcon   = regexp.MustCompile(`^[a-z0-9][a-z0-9_:-]{0,63}$`)
+	validSizeBy = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]{0,63}$`)
+)
+
+// NodeStyle holds the visualization hints for a node type, so clients
+// render every type the same way without hardcoding styles
+type NodeStyle struct {
+	Color  string `json:"color,omitempty"`   // CSS hex (#2e9e44) or color name
+	Icon   string `json:"icon,omitempty"`    // Icon name, e.g. "user" or "mdi:file"
+	SizeBy string `json:"size_by,omitempty"` // "degree" or a numeric property
+}
+
+// Validate checks a style's hints
+func (s NodeStyle) Validate() error {
+	if s.Color != "" && !validColor.MatchString(s.Color) {
+		return fmt.Errorf("invalid color %q (use #rgb, #rrggbb or a color name)", s.Color)
+	}
+	if s.Icon != "" && !validIcon.MatchString(s.Icon) {
+		return fmt.Errorf("invalid icon %q (use 1-64 lowercase letters, digits, _, : or -)", s.Icon)
+	}
+	if s.SizeBy != "" && !validSizeBy.MatchString(s.SizeBy) {
+		return fmt.Errorf("invalid size_by %q (use degree or a property name)", s.SizeBy)
+	}
+	return nil
+}
+
+// ApplyStyles attaches the styles of the view's node types and fills in
+// each node's size metric from the subgraph the view was built from.
+// Cluster nodes keep their member count as their size.
+func (v *GraphView) ApplyStyles(sub *Subgraph, styles map[string]NodeStyle) {
+	v.Styles = make(map[string]NodeStyle)
+	for _, n := range v.Nodes {
+		if style, ok := styles[n.Type]; ok {
+			v.Styles[n.Type] = style
+		}
+	}
+	var degree map[string]int
+	meta := make(map[string]map[string]interface{}, len(sub.Nodes))
+	for _, n := range sub.Nodes {
+		meta[n.ID] = n.Meta
+	}
+	for i := range v.Nodes {
+		n := &v.Nodes[i]
+		sizeBy := v.Styles[n.Type].SizeBy
+		switch {
+		case sizeBy == "" || n.Type == ClusterNodeType:
+		case sizeBy == SizeByDegree:
+			if degree == nil {
+				degree = make(map[string]int, len(sub.Nodes))
+				for _, e := range sub.Edges {
+					degree[e.Source]++
+					degree[e.Target]++
+				}
+			}
+			value := float64(degree[n.ID])
+			n.Value = &value
+		default:
+			if value, ok := numericValue(meta[n.ID][sizeBy]); ok {
+				n.Value = &value
+			}
+		}
+	}
+}
+
+// ApplyStyles attaches the styles of the diff view's node types
+func (v *DiffView) ApplyStyles(styles map[string]NodeStyle) {
+	v.Styles = make(map[string]NodeStyle)
+	for _, n := range v.Nodes {
+		if style, ok := styles[n.Type]; ok {
+			v.Styles[n.Type] = style
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestApplyStyles(t *testing.T) {
+	sub := &Subgraph{
+		Nodes: []*core.Node{
+			{ID: "person:alice", Type: "Person"},
+			{ID: "person:bob", Type: "Person"},
+			{ID: "paper:x", Type: "Paper", Meta: map[string]any{"citations": 12.0}},
+			{ID: "paper:y", Type: "Paper"},
+			{ID: "note:n", Type: "Note"},
+		},
+		Edges: []*SubgraphEdge{
+			{Source: "person:alice", Target: "paper:x", Type: "AUTHORED"},
+			{Source: "person:alice", Target: "paper:y", Type: "AUTHORED"},
+			{Source: "person:bob", Target: "paper:x", Type: "AUTHORED"},
+		},
+	}
+	view := BuildView(sub, ViewOptions{})
+	view.ApplyStyles(sub, map[string]NodeStyle{
+		"Person":  {Color: "#4477aa", SizeBy: SizeByDegree},
+		"Paper":   {Icon: "file", SizeBy: "citations"},
+		"Company": {Color: "red"},
+	})
+
+	if len(view.Styles) != 2 || view.Styles["Person"].Color != "#4477aa" {
+		t.Errorf("styles = %v, want Person and Paper only", view.Styles)
+	}
+	want := map[string]float64{"person:alice": 2, "person:bob": 1, "paper:x": 12}
+	for _, n := range view.Nodes {
+		w, ok := want[n.ID]
+		switch {
+		case ok && (n.Value == nil || *n.Value != w):
+			t.Errorf("%s value = %v, want %v", n.ID, n.Value, w)
+		case !ok && n.Value != nil:
+			t.Errorf("%s value = %v, want none", n.ID, *n.Value)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Timeline bucket sizes
+const (
+	BucketDay  = "day"
+	BucketWeek = "week"
+)
+
+// Timeline groups nodes by when they entered the graph
+type Timeline struct {
+	Bucket  string           `json:"bucket"`
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	Buckets []TimelineBucket `json:"buckets"`
+	Total   int              `json:"total"`
+}
+
+// TimelineBucket holds counts and representative nodes for one time bucket
+type TimelineBucket struct {
+	Start   time.Time      `json:"start"`
+	End     time.Time      `json:"end"`
+	Count   int            `json:"count"`
+	ByType  map[string]int `json:"by_type"`
+	Samples []NodeSummary  `json:"samples"`
+}
+
+// timelineEntry is the minimal node data needed to build a timeline
+type timelineEntry struct {
+	ID      string
+	Type    string
+	Created time.Time
+	Degree  int
+}
+
+// ValidateBucket checks that a bucket size is supported
+func ValidateBucket(bucket string) error {
+	if bucket != BucketDay && bucket != BucketWeek {
+		return fmt.Errorf("invalid bucket: %s (use 'day' or 'week')", bucket)
+	}
+	return nil
+}
+
+// bucketStart truncates t to the start of its day or ISO week (Monday)
+func bucketStart(t time.Time, bucket string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if bucket == BucketWeek {
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// bucketEnd returns the exclusive end of a bucket starting at start
+func bucketEnd(start time.Time, bucket string) time.Time {
+	if bucket == BucketWeek {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// buildTimeline buckets entries and picks the highest-degree nodes as samples.
+// Empty buckets are omitted.
+func buildTimeline(entries []timelineEntry, from, to time.Time, bucket string, sampleSize int) *Timeline {
+	timeline := &Timeline{
+		Bucket:  bucket,
+		From:    from,
+		To:      to,
+		Buckets: []TimelineBucket{},
+	}
+
+	grouped := make(map[time.Time][]timelineEntry)
+	for _, e := range entries {
+		start := bucketStart(e.Created.In(from.Location()), bucket)
+		grouped[start] = append(grouped[start], e)
+	}
+
+	starts := make([]time.Time, 0, len(grouped))
+	for start := range grouped {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	for _, start := range starts {
+		group := grouped[start]
+		b := TimelineBucket{
+			Start:   start,
+			End:     bucketEnd(start, bucket),
+			Count:   len(group),
+			ByType:  make(map[string]int),
+			Samples: []NodeSummary{},
+		}
+		for _, e := range group {
+			b.ByType[e.Type]++
+		}
+
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Degree > group[j].Degree })
+		for i := 0; i < len(group) && i < sampleSize; i++ {
+			b.Samples = append(b.Samples, NodeSummary{
+				ID:     group[i].ID,
+				Type:   group[i].Type,
+				Degree: group[i].Degree,
+			})
+		}
+
+		timeline.Buckets = append(timeline.Buckets, b)
+		timeline.Total += b.Count
+	}
+
+	return timeline
+}
@@ -0,0 +1,45 @@
+package graph
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketStart(t *testing.T) {
+	// Wednesday afternoon
+	ts := time.Date(2025, 11, 19, 15, 30, 0, 0, time.UTC)
+
+	if got := bucketStart(ts, BucketDay); !got.Equal(time.Date(2025, 11, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("day bucket = %v", got)
+	}
+	if got := bucketStart(ts, BucketWeek); !got.Equal(time.Date(2025, 11, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("week bucket = %v, want Monday 2025-11-17", got)
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	from := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 11, 30, 0, 0, 0, 0, time.UTC)
+	entries := []timelineEntry{
+		{ID: "a", Type: "Note", Created: time.Date(2025, 11, 3, 9, 0, 0, 0, time.UTC), Degree: 1},
+		{ID: "b", Type: "Person", Created: time.Date(2025, 11, 3, 18, 0, 0, 0, time.UTC), Degree: 7},
+		{ID: "c", Type: "Note", Created: time.Date(2025, 11, 10, 12, 0, 0, 0, time.UTC), Degree: 0},
+	}
+
+	timeline := buildTimeline(entries, from, to, BucketDay, 1)
+
+	if timeline.Total != 3 {
+		t.Errorf("total = %d, want 3", timeline.Total)
+	}
+	if len(timeline.Buckets) != 2 {
+		t.Fatalf("buckets = %d, want 2", len(timeline.Buckets))
+	}
+
+	first := timeline.Buckets[0]
+	if first.Count != 2 || first.ByType["Note"] != 1 || first.ByType["Person"] != 1 {
+		t.Errorf("unexpected first bucket: %+v", first)
+	}
+	if len(first.Samples) != 1 || first.Samples[0].ID != "b" {
+		t.Errorf("expected highest-degree sample b, got %+v", first.Samples)
+	}
+}
@@ -0,0 +1,419 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+const tracerName = "github.com/systemshift/memex/internal/server/graph"
+
+// tracedRepository wraps a Repository, recording an OpenTelemetry span per call
+type tracedRepository struct {
+	next    Repository
+	backend string
+	tracer  trace.Tracer
+}
+
+// WithTracing wraps repo so every repository call is recorded as a span
+func WithTracing(repo Repository, backend string) Repository {
+	return &tracedRepository{
+		next:    repo,
+		backend: backend,
+		tracer:  otel.Tracer(tracerName),
+	}
+}
+
+// start begins a client span for a repository operation
+func (t *tracedRepository) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("db.system", t.backend),
+		attribute.String("db.operation", op),
+	)
+	return t.tracer.Start(ctx, "graph."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records err on the span (if any) and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Lifecycle
+
+func (t *tracedRepository) Close(ctx context.Context) error {
+	return t.next.Close(ctx)
+}
+
+func (t *tracedRepository) EnsureIndexes(ctx context.Context) error {
+	ctx, span := t.start(ctx, "EnsureIndexes")
+	err := t.next.EnsureIndexes(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) SetEventEmitter(emitter func(subscriptions.Event)) {
+	t.next.SetEventEmitter(emitter)
+}
+
+// Core node operations
+
+func (t *tracedRepository) CreateNode(ctx context.Context, node *core.Node) error {
+	ctx, span := t.start(ctx, "CreateNode", attribute.String("memex.node_id", node.ID), attribute.String("memex.node_type", node.Type))
+	err := t.next.CreateNode(ctx, node)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	ctx, span := t.start(ctx, "GetNode", attribute.String("memex.node_id", id))
+	node, err := t.next.GetNode(ctx, id)
+	endSpan(span, err)
+	return node, err
+}
+
+func (t *tracedRepository) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	ctx, span := t.start(ctx, "GetLinks", attribute.String("memex.node_id", nodeID))
+	links, err := t.next.GetLinks(ctx, nodeID)
+	span.SetAttributes(attribute.Int("memex.result_count", len(links)))
+	endSpan(span, err)
+	return links, err
+}
+
+func (t *tracedRepository) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	ctx, span := t.start(ctx, "GetBacklinks", attribute.String("memex.node_id", nodeID))
+	links, err := t.next.GetBacklinks(ctx, nodeID)
+	span.SetAttributes(attribute.Int("memex.result_count", len(links)))
+	endSpan(span, err)
+	return links, err
+}
+
+func (t *tracedRepository) MatchPattern(ctx context.Context, pattern *Pattern, limit int) ([]PatternBinding, error) {
+	ctx, span := t.start(ctx, "MatchPattern", attribute.Int("memex.pattern_nodes", len(pattern.Nodes)), attribute.Int("memex.limit", limit))
+	matches, err := t.next.MatchPattern(ctx, pattern, limit)
+	span.SetAttributes(attribute.Int("memex.result_count", len(matches)))
+	endSpan(span, err)
+	return matches, err
+}
+
+func (t *tracedRepository) SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
+	ctx, span := t.start(ctx, "SearchNodes", attribute.Int("memex.limit", limit), attribute.Int("memex.offset", offset))
+	nodes, err := t.next.SearchNodes(ctx, searchTerm, limit, offset)
+	span.SetAttributes(attribute.Int("memex.result_count", len(nodes)))
+	endSpan(span, err)
+	return nodes, err
+}
+
+func (t *tracedRepository) SearchSimilar(ctx context.Context, term string, limit int) ([]*SimilarNode, error) {
+	ctx, span := t.start(ctx, "SearchSimilar", attribute.Int("memex.limit", limit))
+	nodes, err := t.next.SearchSimilar(ctx, term, limit)
+	span.SetAttributes(attribute.Int("memex.result_count", len(nodes)))
+	endSpan(span, err)
+	return nodes, err
+}
+
+func (t *tracedRepository) FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error) {
+	ctx, span := t.start(ctx, "FilterNodes", attribute.StringSlice("memex.node_types", nodeTypes), attribute.Int("memex.limit", limit))
+	nodes, err := t.next.FilterNodes(ctx, nodeTypes, propertyKey, propertyValue, limit, offset)
+	span.SetAttributes(attribute.Int("memex.result_count", len(nodes)))
+	endSpan(span, err)
+	return nodes, err
+}
+
+func (t *tracedRepository) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
+	ctx, span := t.start(ctx, "TraverseGraph", attribute.String("memex.node_id", startNodeID), attribute.Int("memex.depth", depth))
+	nodes, err := t.next.TraverseGraph(ctx, startNodeID, depth, relationshipTypes, limit, offset)
+	span.SetAttributes(attribute.Int("memex.result_count", len(nodes)))
+	endSpan(span, err)
+	return nodes, err
+}
+
+func (t *tracedRepository) QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error) {
+	ctx, span := t.start(ctx, "QueryTimeRange", attribute.StringSlice("memex.node_types", nodeTypes), attribute.Int("memex.limit", limit))
+	nodes, err := t.next.QueryTimeRange(ctx, from, to, nodeTypes, limit, offset)
+	span.SetAttributes(attribute.Int("memex.result_count", len(nodes)))
+	endSpan(span, err)
+	return nodes, err
+}
+
+// Link operations
+
+func (t *tracedRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	ctx, span := t.start(ctx, "CreateLink", attribute.String("memex.link_type", link.Type))
+	err := t.next.CreateLink(ctx, link)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	ctx, span := t.start(ctx, "CreateNodes", attribute.Int("memex.count", len(nodes)))
+	err := t.next.CreateNodes(ctx, nodes)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	ctx, span := t.start(ctx, "CreateLinks", attribute.Int("memex.count", len(links)))
+	err := t.next.CreateLinks(ctx, links)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
+	ctx, span := t.start(ctx, "DeleteLink", attribute.String("memex.link_type", linkType))
+	err := t.next.DeleteLink(ctx, sourceID, targetID, linkType)
+	endSpan(span, err)
+	return err
+}
+
+// Node listing
+
+func (t *tracedRepository) ListNodes(ctx context.Context) ([]string, error) {
+	ctx, span := t.start(ctx, "ListNodes")
+	ids, err := t.next.ListNodes(ctx)
+	span.SetAttributes(attribute.Int("memex.result_count", len(ids)))
+	endSpan(span, err)
+	return ids, err
+}
+
+func (t *tracedRepository) ListDeleted(ctx context.Context, limit int, offset int) ([]*core.Node, error) {
+	ctx, span := t.start(ctx, "ListDeleted", attribute.Int("memex.limit", limit))
+	nodes, err := t.next.ListDeleted(ctx, limit, offset)
+	span.SetAttributes(attribute.Int("memex.result_count", len(nodes)))
+	endSpan(span, err)
+	return nodes, err
+}
+
+// Version operations
+
+func (t *tracedRepository) GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error) {
+	ctx, span := t.start(ctx, "GetNodeAtVersion", attribute.String("memex.node_id", id), attribute.Int("memex.version", version))
+	node, err := t.next.GetNodeAtVersion(ctx, id, version)
+	endSpan(span, err)
+	return node, err
+}
+
+func (t *tracedRepository) GetNodeAtTime(ctx context.Context, id string, asOf time.Time) (*core.Node, error) {
+	ctx, span := t.start(ctx, "GetNodeAtTime", attribute.String("memex.node_id", id))
+	node, err := t.next.GetNodeAtTime(ctx, id, asOf)
+	endSpan(span, err)
+	return node, err
+}
+
+func (t *tracedRepository) GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error) {
+	ctx, span := t.start(ctx, "GetNodeHistory", attribute.String("memex.node_id", id))
+	history, err := t.next.GetNodeHistory(ctx, id)
+	endSpan(span, err)
+	return history, err
+}
+
+// Update operations
+
+func (t *tracedRepository) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	ctx, span := t.start(ctx, "UpdateNodeMeta", attribute.String("memex.node_id", id))
+	err := t.next.UpdateNodeMeta(ctx, id, meta)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	ctx, span := t.start(ctx, "UpdateNodeMetaWithNote", attribute.String("memex.node_id", id))
+	err := t.next.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy)
+	endSpan(span, err)
+	return err
+}
+
+// Delete operations
+
+func (t *tracedRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	ctx, span := t.start(ctx, "DeleteNode", attribute.String("memex.node_id", nodeID), attribute.Bool("memex.force", force))
+	err := t.next.DeleteNode(ctx, nodeID, force)
+	endSpan(span, err)
+	return err
+}
+
+// Attention edge operations
+
+func (t *tracedRepository) UpdateAttentionEdge(ctx context.Context, source, target, queryID string, weight float64) error {
+	ctx, span := t.start(ctx, "UpdateAttentionEdge")
+	err := t.next.UpdateAttentionEdge(ctx, source, target, queryID, weight)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) GetAttentionSubgraph(ctx context.Context, startNodeID string, minWeight float64, maxNodes int) (*Subgraph, error) {
+	ctx, span := t.start(ctx, "GetAttentionSubgraph", attribute.String("memex.node_id", startNodeID), attribute.Int("memex.max_nodes", maxNodes), attribute.String("memex.attention_head", AttentionHeadFrom(ctx)))
+	subgraph, err := t.next.GetAttentionSubgraph(ctx, startNodeID, minWeight, maxNodes)
+	endSpan(span, err)
+	return subgraph, err
+}
+
+func (t *tracedRepository) PruneWeakAttentionEdges(ctx context.Context, minWeight float64, minQueryCount int) (int, error) {
+	ctx, span := t.start(ctx, "PruneWeakAttentionEdges")
+	pruned, err := t.next.PruneWeakAttentionEdges(ctx, minWeight, minQueryCount)
+	span.SetAttributes(attribute.Int("memex.result_count", pruned))
+	endSpan(span, err)
+	return pruned, err
+}
+
+// Graph exploration
+
+func (t *tracedRepository) GetGraphMap(ctx context.Context, sampleSize int) (*GraphMap, error) {
+	ctx, span := t.start(ctx, "GetGraphMap")
+	graphMap, err := t.next.GetGraphMap(ctx, sampleSize)
+	endSpan(span, err)
+	return graphMap, err
+}
+
+func (t *tracedRepository) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*Subgraph, error) {
+	ctx, span := t.start(ctx, "GetSubgraph", attribute.String("memex.node_id", startNodeID), attribute.Int("memex.depth", depth))
+	subgraph, err := t.next.GetSubgraph(ctx, startNodeID, depth, relationshipTypes)
+	if subgraph != nil {
+		span.SetAttributes(
+			attribute.Int("memex.node_count", subgraph.Stats.NodeCount),
+			attribute.Int("memex.edge_count", subgraph.Stats.EdgeCount),
+		)
+	}
+	endSpan(span, err)
+	return subgraph, err
+}
+
+func (t *tracedRepository) GetGraphSnapshot(ctx context.Context, nodeTypes []string, limit int) (*Subgraph, error) {
+	ctx, span := t.start(ctx, "GetGraphSnapshot", attribute.StringSlice("memex.node_types", nodeTypes), attribute.Int("memex.limit", limit))
+	snapshot, err := t.next.GetGraphSnapshot(ctx, nodeTypes, limit)
+	if snapshot != nil {
+		span.SetAttributes(
+			attribute.Int("memex.node_count", snapshot.Stats.NodeCount),
+			attribute.Int("memex.edge_count", snapshot.Stats.EdgeCount),
+		)
+	}
+	endSpan(span, err)
+	return snapshot, err
+}
+
+func (t *tracedRepository) GetTimeline(ctx context.Context, from, to time.Time, bucket string, nodeTypes []string, sampleSize int) (*Timeline, error) {
+	ctx, span := t.start(ctx, "GetTimeline", attribute.String("memex.bucket", bucket))
+	timeline, err := t.next.GetTimeline(ctx, from, to, bucket, nodeTypes, sampleSize)
+	endSpan(span, err)
+	return timeline, err
+}
+
+func (t *tracedRepository) GetUsage(ctx context.Context) (*Usage, error) {
+	ctx, span := t.start(ctx, "GetUsage")
+	usage, err := t.next.GetUsage(ctx)
+	endSpan(span, err)
+	return usage, err
+}
+
+func (t *tracedRepository) CheckIntegrity(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	ctx, span := t.start(ctx, "CheckIntegrity", attribute.Int("memex.limit", opts.Limit))
+	report, err := t.next.CheckIntegrity(ctx, opts)
+	endSpan(span, err)
+	return report, err
+}
+
+func (t *tracedRepository) RecomputeDegrees(ctx context.Context, opts DegreeRepairOptions) (*DegreeRepair, error) {
+	ctx, span := t.start(ctx, "RecomputeDegrees", attribute.Bool("memex.dry_run", opts.DryRun))
+	report, err := t.next.RecomputeDegrees(ctx, opts)
+	endSpan(span, err)
+	return report, err
+}
+
+func (t *tracedRepository) DiffGraph(ctx context.Context, from, to time.Time, detailed bool) (*GraphDiff, error) {
+	ctx, span := t.start(ctx, "DiffGraph", attribute.Bool("memex.detailed", detailed))
+	diff, err := t.next.DiffGraph(ctx, from, to, detailed)
+	endSpan(span, err)
+	return diff, err
+}
+
+func (t *tracedRepository) ChangeLog(ctx context.Context, since time.Time, limit int) ([]subscriptions.Event, error) {
+	ctx, span := t.start(ctx, "ChangeLog", attribute.Int("memex.limit", limit))
+	events, err := t.next.ChangeLog(ctx, since, limit)
+	endSpan(span, err)
+	return events, err
+}
+
+// Lens operations
+
+func (t *tracedRepository) GetEntitiesInterpretedThrough(ctx context.Context, lensID string) ([]*core.Node, error) {
+	ctx, span := t.start(ctx, "GetEntitiesInterpretedThrough", attribute.String("memex.lens_id", lensID))
+	nodes, err := t.next.GetEntitiesInterpretedThrough(ctx, lensID)
+	span.SetAttributes(attribute.Int("memex.result_count", len(nodes)))
+	endSpan(span, err)
+	return nodes, err
+}
+
+func (t *tracedRepository) CreateInterpretedThroughLink(ctx context.Context, entityID, lensID string, meta map[string]interface{}) error {
+	ctx, span := t.start(ctx, "CreateInterpretedThroughLink", attribute.String("memex.node_id", entityID), attribute.String("memex.lens_id", lensID))
+	err := t.next.CreateInterpretedThroughLink(ctx, entityID, lensID, meta)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) QueryByLens(ctx context.Context, lensID string, pattern string, limit int, offset int) ([]*core.Node, error) {
+	ctx, span := t.start(ctx, "QueryByLens", attribute.String("memex.lens_id", lensID), attribute.Int("memex.limit", limit))
+	nodes, err := t.next.QueryByLens(ctx, lensID, pattern, limit, offset)
+	span.SetAttributes(attribute.Int("memex.result_count", len(nodes)))
+	endSpan(span, err)
+	return nodes, err
+}
+
+func (t *tracedRepository) ExportLens(ctx context.Context, lensID string, includeExtractedFrom bool) (*LensExport, error) {
+	ctx, span := t.start(ctx, "ExportLens", attribute.String("memex.lens_id", lensID))
+	export, err := t.next.ExportLens(ctx, lensID, includeExtractedFrom)
+	endSpan(span, err)
+	return export, err
+}
+
+// Subscription persistence
+
+func (t *tracedRepository) CreateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
+	ctx, span := t.start(ctx, "CreateSubscriptionNode")
+	err := t.next.CreateSubscriptionNode(ctx, sub)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) UpdateSubscriptionNode(ctx context.Context, sub *subscriptions.Subscription) error {
+	ctx, span := t.start(ctx, "UpdateSubscriptionNode")
+	err := t.next.UpdateSubscriptionNode(ctx, sub)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) DeleteSubscriptionNode(ctx context.Context, id string) error {
+	ctx, span := t.start(ctx, "DeleteSubscriptionNode")
+	err := t.next.DeleteSubscriptionNode(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedRepository) LoadSubscriptions(ctx context.Context) ([]*subscriptions.Subscription, error) {
+	ctx, span := t.start(ctx, "LoadSubscriptions")
+	subs, err := t.next.LoadSubscriptions(ctx)
+	endSpan(span, err)
+	return subs, err
+}
+
+// Raw query
+
+func (t *tracedRepository) ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx, span := t.start(ctx, "ExecuteCypherRead", attribute.String("db.statement", cypher))
+	rows, err := t.next.ExecuteCypherRead(ctx, cypher, params)
+	span.SetAttributes(attribute.Int("memex.result_count", len(rows)))
+	endSpan(span, err)
+	return rows, err
+}
@@ -0,0 +1,228 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// MaxTrendPoints caps the intervals a trend query evaluates
+const MaxTrendPoints = 1000
+
+// Trend metrics
+const (
+	TrendCount = "count"
+	TrendSum   = "sum"
+	TrendAvg   = "avg"
+	TrendMin   = "min"
+	TrendMax   = "max"
+)
+
+// TrendQuery selects nodes and a metric to evaluate over their history
+type TrendQuery struct {
+	Types    []string          // Node types to include; empty includes all
+	Layers   LayerFilter       // Node layers to include; nil includes all
+	Where    map[string]string // Property values a version must have to count
+	Metric   string            // count (default), sum, avg, min or max
+	Field    string            // Numeric property for every metric but count
+	Interval string            // day or week
+	From     time.Time
+	To       time.Time
+}
+
+// Trend is a metric sampled at the end of each interval
+type Trend struct {
+	Metric   string            `json:"metric"`
+	Field    string            `json:"field,omitempty"`
+	Interval string            `json:"interval"`
+	Where    map[string]string `json:"where,omitempty"`
+	Points   []TrendPoint      `json:"points"`
+}
+
+// TrendPoint is the state of the graph at the end of one interval
+type TrendPoint struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`   // Exclusive; the metric is evaluated just before it
+	Count int       `json:"count"` // Matching nodes
+	Value *float64  `json:"value"` // The metric; null when no node has a numeric Field
+}
+
+// Validate checks a trend query's options, filling in defaults
+func (q *TrendQuery) Validate() error {
+	if q.Metric == "" {
+		q.Metric = TrendCount
+	}
+	if q.Interval == "" {
+		q.Interval = BucketWeek
+	}
+	switch q.Metric {
+	case TrendCount:
+	case TrendSum, TrendAvg, TrendMin, TrendMax:
+		if q.Field == "" {
+			return fmt.Errorf("metric %s needs a field", q.Metric)
+		}
+	default:
+		return fmt.Errorf("invalid metric: %s (use count, sum, avg, min or max)", q.Metric)
+	}
+	if err := ValidateBucket(q.Interval); err != nil {
+		return err
+	}
+	if q.To.Before(q.From) {
+		return fmt.Errorf("from must be before to")
+	}
+	if n := len(trendIntervals(q.From, q.To, q.Interval)); n > MaxTrendPoints {
+		return fmt.Errorf("too many intervals (%d, max %d)", n, MaxTrendPoints)
+	}
+	return nil
+}
+
+// trendIntervals returns the start of each interval overlapping [from, to]
+func trendIntervals(from, to time.Time, interval string) []time.Time {
+	var starts []time.Time
+	for start := bucketStart(from, interval); !start.After(to) && len(starts) <= MaxTrendPoints; start = bucketEnd(start, interval) {
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// trendVersion is one stored version of a node and when it was written
+type trendVersion struct {
+	modified time.Time
+	node     *core.Node // nil once deleted
+}
+
+// RunTrend replays the version history of the selected nodes and evaluates
+// the metric over the versions current at the end of each interval, so a
+// task counts as open in the weeks it was open even after it was closed.
+// Deleted nodes count until they were deleted.
+func RunTrend(ctx context.Context, repo Repository, q TrendQuery) (*Trend, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	starts := trendIntervals(q.From, q.To, q.Interval)
+	out := &Trend{Metric: q.Metric, Field: q.Field, Interval: q.Interval, Where: q.Where, Points: make([]TrendPoint, len(starts))}
+	for i, start := range starts {
+		out.Points[i] = TrendPoint{Start: start, End: bucketEnd(start, q.Interval)}
+	}
+	values := make([][]float64, len(starts))
+
+	add := func(versions []trendVersion) {
+		for i := range out.Points {
+			at := out.Points[i].End
+			var current *core.Node
+			for _, v := range versions { // Oldest first
+				if !v.modified.Before(at) {
+					break
+				}
+				current = v.node
+			}
+			if current == nil || !q.Layers.Node(current) || !trendMatches(current, q.Where) {
+				continue
+			}
+			out.Points[i].Count++
+			if q.Field != "" {
+				if v, ok := numericValue(current.Meta[q.Field]); ok {
+					values[i] = append(values[i], v)
+				}
+			}
+		}
+	}
+
+	// Deleted nodes come back as their last live version
+	ctx = WithDeleted(ctx)
+	last := out.Points[len(out.Points)-1].End
+	for offset := 0; ; offset += aggregatePageSize {
+		nodes, err := repo.FilterNodes(ctx, q.Types, "", "", aggregatePageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			if !n.Created.Before(last) {
+				continue
+			}
+			versions, err := trendHistory(ctx, repo, n)
+			if err != nil {
+				return nil, err
+			}
+			add(versions)
+		}
+		if len(nodes) < aggregatePageSize {
+			break
+		}
+	}
+
+	for i := range out.Points {
+		out.Points[i].Value = trendValue(q.Metric, out.Points[i].Count, values[i])
+	}
+	return out, nil
+}
+
+// trendHistory returns a node's versions oldest first. A node never
+// changed since it was created needs no history lookups.
+func trendHistory(ctx context.Context, repo Repository, n *core.Node) ([]trendVersion, error) {
+	if n.Version <= 1 && !n.Deleted {
+		return []trendVersion{{modified: n.Created, node: n}}, nil
+	}
+	history, err := repo.GetNodeHistory(ctx, n.ID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Version < history[j].Version })
+	versions := make([]trendVersion, 0, len(history))
+	for _, h := range history {
+		v, err := repo.GetNodeAtVersion(ctx, n.ID, h.Version)
+		if err != nil {
+			return nil, err
+		}
+		if v.Deleted {
+			v = nil
+		}
+		versions = append(versions, trendVersion{modified: h.Modified, node: v})
+	}
+	return versions, nil
+}
+
+// trendMatches reports whether a node has every required property value
+func trendMatches(n *core.Node, where map[string]string) bool {
+	for k, want := range where {
+		v, ok := n.Meta[k]
+		if !ok || v == nil || fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// trendValue computes a metric from a point's count and field values
+func trendValue(metric string, count int, values []float64) *float64 {
+	if metric == TrendCount {
+		v := float64(count)
+		return &v
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	v := values[0]
+	switch metric {
+	case TrendSum, TrendAvg:
+		v = 0
+		for _, x := range values {
+			v += x
+		}
+		if metric == TrendAvg {
+			v /= float64(len(values))
+		}
+	case TrendMin:
+		for _, x := range values {
+			v = min(v, x)
+		}
+	case TrendMax:
+		for _, x := range values {
+			v = max(v, x)
+		}
+	}
+	return &v
+}
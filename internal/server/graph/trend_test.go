@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestRunTrend(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+	daysAgo := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+
+	for _, n := range []*core.Node{
+		{ID: "task:1", Meta: map[string]any{"status": "open", "points": 3.0}, Created: daysAgo(3)},
+		{ID: "task:2", Meta: map[string]any{"status": "open", "points": 5.0}, Created: daysAgo(2)},
+		{ID: "task:3", Meta: map[string]any{"status": "open", "points": "8"}, Created: daysAgo(1)},
+		{ID: "note:1", Type: "Note", Meta: map[string]any{"status": "open"}, Created: daysAgo(3)},
+	} {
+		if n.Type == "" {
+			n.Type = "Task"
+		}
+		n.Modified = n.Created
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.UpdateNodeMeta(ctx, "task:2", map[string]any{"status": "done"})
+	repo.DeleteNode(ctx, "task:3", false)
+
+	q := TrendQuery{Types: []string{"Task"}, Where: map[string]string{"status": "open"}, Interval: BucketDay, From: daysAgo(3), To: now}
+	trend, err := RunTrend(ctx, repo, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{1, 2, 3, 1}
+	if len(trend.Points) != len(want) {
+		t.Fatalf("points = %+v", trend.Points)
+	}
+	for i, p := range trend.Points {
+		if p.Count != want[i] || *p.Value != float64(want[i]) {
+			t.Errorf("point %d (%s) = %d, want %d open tasks", i, p.Start.Format(time.DateOnly), p.Count, want[i])
+		}
+	}
+
+	q.Metric, q.Field = TrendSum, "points"
+	trend, err = RunTrend(ctx, repo, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := trend.Points[2].Value; v == nil || *v != 16 {
+		t.Errorf("open points two days in = %v, want 16", v)
+	}
+
+	for _, bad := range []TrendQuery{
+		{Metric: "median", From: daysAgo(1), To: now},
+		{Metric: TrendAvg, From: daysAgo(1), To: now},
+		{Interval: "hour", From: daysAgo(1), To: now},
+		{Interval: BucketDay, From: now.AddDate(-5, 0, 0), To: now},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// TrustKey is the node property holding an explicit trust score in [0, 1]
+const TrustKey = "trust"
+
+// DefaultTrust applies to sources with no explicit or configured trust, so
+// ranking is unchanged until trust is assigned
+const DefaultTrust = 1.0
+
+// trustLinkTypes are followed from a derived node back to the sources it rests on
+var trustLinkTypes = map[string]bool{"EXTRACTED_FROM": true, "DERIVED_FROM": true}
+
+// TrustPolicy assigns trust to sources by connector or domain
+type TrustPolicy struct {
+	Connectors map[string]float64 // Source meta "connector"
+	Domains    map[string]float64 // Source meta "domain", or the host of meta "url"
+	Default    float64
+}
+
+// ParseTrustPolicy parses a comma-separated list of connector:NAME=SCORE and
+// domain:HOST=SCORE entries, e.g. "domain:arxiv.org=0.9,connector:rss=0.4".
+// A bare default=SCORE entry overrides DefaultTrust.
+func ParseTrustPolicy(spec string) (*TrustPolicy, error) {
+	p := &TrustPolicy{
+		Connectors: make(map[string]float64),
+		Domains:    make(map[string]float64),
+		Default:    DefaultTrust,
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid trust entry %q: expected KEY=SCORE", entry)
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || score < 0 || score > 1 {
+			return nil, fmt.Errorf("invalid trust entry %q: score must be between 0 and 1", entry)
+		}
+		key = strings.TrimSpace(key)
+		switch {
+		case key == "default":
+			p.Default = score
+		case strings.HasPrefix(key, "connector:"):
+			p.Connectors[strings.TrimPrefix(key, "connector:")] = score
+		case strings.HasPrefix(key, "domain:"):
+			p.Domains[strings.ToLower(strings.TrimPrefix(key, "domain:"))] = score
+		default:
+			return nil, fmt.Errorf("invalid trust entry %q: expected connector:, domain: or default", entry)
+		}
+	}
+	return p, nil
+}
+
+// NodeTrust returns the trust explicitly set on a node
+func NodeTrust(node *core.Node) (float64, bool) {
+	switch v := node.Meta[TrustKey].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// SourceTrust returns a source's trust: explicit trust first, then its
+// connector, then its domain, then the default
+func (p *TrustPolicy) SourceTrust(node *core.Node) float64 {
+	if t, ok := NodeTrust(node); ok {
+		return t
+	}
+	if p == nil {
+		return DefaultTrust
+	}
+	if c, ok := node.Meta["connector"].(string); ok {
+		if t, ok := p.Connectors[c]; ok {
+			return t
+		}
+	}
+	if t, ok := p.domainTrust(node); ok {
+		return t
+	}
+	return p.Default
+}
+
+// domainTrust matches a source's domain, or any parent domain, against the policy
+func (p *TrustPolicy) domainTrust(node *core.Node) (float64, bool) {
+	host, _ := node.Meta["domain"].(string)
+	if host == "" {
+		if raw, ok := node.Meta["url"].(string); ok {
+			if u, err := url.Parse(raw); err == nil {
+				host = u.Hostname()
+			}
+		}
+	}
+	host = strings.ToLower(host)
+	for host != "" {
+		if t, ok := p.Domains[host]; ok {
+			return t, true
+		}
+		_, host, _ = strings.Cut(host, ".")
+	}
+	return 0, false
+}
+
+// EffectiveTrust returns the trust of any node. Sources use SourceTrust; a
+// derived node with explicit trust keeps it, otherwise it inherits the
+// highest trust among the nodes it was EXTRACTED_FROM or DERIVED_FROM. Nodes
+// with no sources get the default.
+func (p *TrustPolicy) EffectiveTrust(ctx context.Context, repo Repository, id string) (float64, error) {
+	return p.effectiveTrust(ctx, repo, id, make(map[string]float64), make(map[string]bool))
+}
+
+func (p *TrustPolicy) effectiveTrust(ctx context.Context, repo Repository, id string, memo map[string]float64, onPath map[string]bool) (float64, error) {
+	if t, ok := memo[id]; ok {
+		return t, nil
+	}
+	node, err := repo.GetNode(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("node not found: %s", id)
+	}
+	if node.Type == "Source" || strings.HasPrefix(node.ID, "sha256:") {
+		memo[id] = p.SourceTrust(node)
+		return memo[id], nil
+	}
+	if t, ok := NodeTrust(node); ok {
+		memo[id] = t
+		return t, nil
+	}
+
+	links, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	onPath[id] = true
+	defer delete(onPath, id)
+
+	best, found := 0.0, false
+	for _, link := range links {
+		if !trustLinkTypes[link.Type] || onPath[link.Target] {
+			continue
+		}
+		t, err := p.effectiveTrust(ctx, repo, link.Target, memo, onPath)
+		if err != nil {
+			continue // Missing upstream node contributes nothing
+		}
+		if !found || t > best {
+			best, found = t, true
+		}
+	}
+	if !found {
+		best = DefaultTrust
+		if p != nil {
+			best = p.Default
+		}
+	}
+	memo[id] = best
+	return best, nil
+}
@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestEffectiveTrust(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	now := time.Now()
+
+	policy, err := ParseTrustPolicy("domain:arxiv.org=0.9,connector:rss=0.3,default=0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := []*core.Node{
+		{ID: "sha256:paper", Type: "Source", Meta: map[string]interface{}{"url": "https://export.arxiv.org/abs/1"}},
+		{ID: "sha256:feed", Type: "Source", Meta: map[string]interface{}{"connector": "rss"}},
+		{ID: "sha256:note", Type: "Source", Meta: map[string]interface{}{"connector": "rss", "trust": 0.7}},
+		{ID: "concept:a", Type: "Concept"},
+		{ID: "concept:b", Type: "Concept"},
+		{ID: "concept:c", Type: "Concept"},
+	}
+	for _, n := range nodes {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := [][2]string{
+		{"concept:a", "sha256:paper"},
+		{"concept:a", "sha256:feed"},
+		{"concept:b", "sha256:feed"},
+	}
+	for _, l := range links {
+		if err := repo.CreateLink(ctx, &core.Link{Source: l[0], Target: l[1], Type: "EXTRACTED_FROM", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "concept:c", Target: "concept:b", Type: "DERIVED_FROM", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{
+		"sha256:paper": 0.9, // parent domain match
+		"sha256:feed":  0.3, // connector
+		"sha256:note":  0.7, // explicit beats connector
+		"concept:a":    0.9, // best supporting source
+		"concept:b":    0.3,
+		"concept:c":    0.3, // through DERIVED_FROM
+	}
+	for id, w := range want {
+		got, err := policy.EffectiveTrust(ctx, repo, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != w {
+			t.Errorf("%s: expected trust %v, got %v", id, w, got)
+		}
+	}
+
+	if _, err := ParseTrustPolicy("domain:x.org=2"); err == nil {
+		t.Error("expected error for out-of-range score")
+	}
+}
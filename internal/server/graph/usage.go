@@ -0,0 +1,318 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// ErrQuotaExceeded is returned when a write would exceed a configured quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Usage accounts stored nodes and bytes overall, per namespace and per type
+type Usage struct {
+	Total       UsageStats             `json:"total"`
+	ByNamespace map[string]*UsageStats `json:"by_namespace"`
+	ByType      map[string]*UsageStats `json:"by_type"`
+
+	ContentStore *ContentStoreStats `json:"content_store,omitempty"` // SQLite only
+}
+
+// UsageStats counts nodes and the bytes stored for them. Bytes cover every
+// stored version, since history occupies disk as much as current content;
+// content shared through the SQLite content store counts once per reference.
+type UsageStats struct {
+	Nodes         int64 `json:"nodes"`    // Current, non-deleted nodes
+	Versions      int64 `json:"versions"` // Stored versions, including history and tombstones
+	ContentBytes  int64 `json:"content_bytes"`
+	PropertyBytes int64 `json:"property_bytes"`
+}
+
+// Bytes is the total stored size
+func (s UsageStats) Bytes() int64 {
+	return s.ContentBytes + s.PropertyBytes
+}
+
+// usageEntry is the minimal data about one stored node version
+type usageEntry struct {
+	ID            string
+	Type          string
+	Live          bool // Current and not deleted
+	ContentBytes  int64
+	PropertyBytes int64
+}
+
+// Namespace returns the namespace of a node ID: the prefix before the first
+// ':' ("person" for "person:ada"), or "" when the ID has none
+func Namespace(id string) string {
+	ns, _, found := strings.Cut(id, ":")
+	if !found {
+		return ""
+	}
+	return ns
+}
+
+func newUsage() *Usage {
+	return &Usage{
+		ByNamespace: make(map[string]*UsageStats),
+		ByType:      make(map[string]*UsageStats),
+	}
+}
+
+// add accounts one stored version in the total, its namespace and its type
+func (u *Usage) add(e usageEntry) {
+	for _, stats := range []*UsageStats{&u.Total, u.stats(u.ByNamespace, Namespace(e.ID)), u.stats(u.ByType, e.Type)} {
+		if e.Live {
+			stats.Nodes++
+		}
+		stats.Versions++
+		stats.ContentBytes += e.ContentBytes
+		stats.PropertyBytes += e.PropertyBytes
+	}
+}
+
+func (u *Usage) stats(m map[string]*UsageStats, key string) *UsageStats {
+	s, ok := m[key]
+	if !ok {
+		s = &UsageStats{}
+		m[key] = s
+	}
+	return s
+}
+
+func buildUsage(entries []usageEntry) *Usage {
+	u := newUsage()
+	for _, e := range entries {
+		u.add(e)
+	}
+	return u
+}
+
+// newNodeEntry estimates the storage a new node will take
+func newNodeEntry(node *core.Node) usageEntry {
+	var props int64
+	if len(node.Meta) > 0 {
+		if b, err := json.Marshal(node.Meta); err == nil {
+			props = int64(len(b))
+		}
+	}
+	return usageEntry{
+		ID:            node.ID,
+		Type:          node.Type,
+		Live:          true,
+		ContentBytes:  int64(len(node.Content)),
+		PropertyBytes: props,
+	}
+}
+
+// Quota scopes
+const (
+	QuotaTotal     = "total"
+	QuotaNamespace = "namespace"
+	QuotaType      = "type"
+)
+
+// Quota limits the nodes and/or bytes of a scope; zero means unlimited
+type Quota struct {
+	Scope    string `json:"scope"`
+	Name     string `json:"name,omitempty"` // Namespace or type; empty for total
+	MaxNodes int64  `json:"max_nodes,omitempty"`
+	MaxBytes int64  `json:"max_bytes,omitempty"`
+}
+
+// Used returns the stats a quota applies to
+func (q Quota) Used(u *Usage) UsageStats {
+	var s *UsageStats
+	switch q.Scope {
+	case QuotaNamespace:
+		s = u.ByNamespace[q.Name]
+	case QuotaType:
+		s = u.ByType[q.Name]
+	default:
+		s = &u.Total
+	}
+	if s == nil {
+		return UsageStats{}
+	}
+	return *s
+}
+
+func (q Quota) String() string {
+	if q.Scope == QuotaTotal {
+		return "total"
+	}
+	return q.Scope + " " + q.Name
+}
+
+// ParseQuotas parses a comma-separated list of SCOPE[:NAME]:nodes=N and
+// SCOPE[:NAME]:bytes=SIZE entries, e.g.
+// "type:Screenshot:bytes=2GB,namespace:sha256:nodes=100000,total:bytes=20GB".
+// Sizes accept KB, MB, GB and TB suffixes (powers of 1024).
+func ParseQuotas(spec string) ([]Quota, error) {
+	var quotas []Quota
+	index := make(map[[2]string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid quota %q: expected SCOPE[:NAME]:nodes|bytes=LIMIT", entry)
+		}
+		parts := strings.Split(strings.TrimSpace(key), ":")
+		var scope, name, metric string
+		switch {
+		case len(parts) == 2 && parts[0] == QuotaTotal:
+			scope, metric = parts[0], parts[1]
+		case len(parts) == 3 && (parts[0] == QuotaNamespace || parts[0] == QuotaType):
+			scope, name, metric = parts[0], parts[1], parts[2]
+		default:
+			return nil, fmt.Errorf("invalid quota %q: scope must be total, namespace:NAME or type:NAME", entry)
+		}
+
+		id := [2]string{scope, name}
+		i, exists := index[id]
+		if !exists {
+			i = len(quotas)
+			index[id] = i
+			quotas = append(quotas, Quota{Scope: scope, Name: name})
+		}
+
+		value = strings.TrimSpace(value)
+		switch metric {
+		case "nodes":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid quota %q: node limit must be a positive integer", entry)
+			}
+			quotas[i].MaxNodes = n
+		case "bytes":
+			n, err := parseSize(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quota %q: %w", entry, err)
+			}
+			quotas[i].MaxBytes = n
+		default:
+			return nil, fmt.Errorf("invalid quota %q: limit must be nodes or bytes", entry)
+		}
+	}
+	return quotas, nil
+}
+
+// parseSize parses a byte count with an optional KB/MB/GB/TB suffix
+func parseSize(s string) (int64, error) {
+	upper := strings.ToUpper(s)
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(upper, unit.suffix) {
+			multiplier = unit.size
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix))
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("size must be a positive number of bytes (optionally KB, MB, GB, TB): %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// quotaRepository rejects node creation that would exceed a quota. Usage is
+// loaded from the backend and kept current by accounting each create; other
+// writes (meta updates copy content into a new version, deletes free it)
+// mark it stale so the next create reloads it.
+type quotaRepository struct {
+	Repository
+	quotas []Quota
+
+	mu    sync.Mutex
+	usage *Usage // nil when stale
+}
+
+// WithQuotas wraps repo so that CreateNode and CreateNodes fail with
+// ErrQuotaExceeded when they would push a scope past its limits
+func WithQuotas(repo Repository, quotas []Quota) Repository {
+	return &quotaRepository{Repository: repo, quotas: quotas}
+}
+
+func (q *quotaRepository) CreateNode(ctx context.Context, node *core.Node) error {
+	return q.CreateNodes(ctx, []*core.Node{node})
+}
+
+func (q *quotaRepository) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.usage == nil {
+		usage, err := q.Repository.GetUsage(ctx)
+		if err != nil {
+			return fmt.Errorf("loading usage for quota check: %w", err)
+		}
+		q.usage = usage
+	}
+
+	pending := newUsage()
+	for _, node := range nodes {
+		pending.add(newNodeEntry(node))
+	}
+	for _, quota := range q.quotas {
+		used, adding := quota.Used(q.usage), quota.Used(pending)
+		if adding.Versions == 0 {
+			continue
+		}
+		if quota.MaxNodes > 0 && used.Nodes+adding.Nodes > quota.MaxNodes {
+			return fmt.Errorf("%w: %s limit of %d nodes (%d used, %d requested)",
+				ErrQuotaExceeded, quota, quota.MaxNodes, used.Nodes, adding.Nodes)
+		}
+		if quota.MaxBytes > 0 && used.Bytes()+adding.Bytes() > quota.MaxBytes {
+			return fmt.Errorf("%w: %s limit of %d bytes (%d used, %d requested)",
+				ErrQuotaExceeded, quota, quota.MaxBytes, used.Bytes(), adding.Bytes())
+		}
+	}
+
+	var err error
+	if len(nodes) == 1 {
+		err = q.Repository.CreateNode(ctx, nodes[0])
+	} else {
+		err = q.Repository.CreateNodes(ctx, nodes)
+	}
+	if err != nil {
+		q.usage = nil
+		return err
+	}
+	for _, node := range nodes {
+		q.usage.add(newNodeEntry(node))
+	}
+	return nil
+}
+
+func (q *quotaRepository) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	defer q.invalidate()
+	return q.Repository.UpdateNodeMeta(ctx, id, meta)
+}
+
+func (q *quotaRepository) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	defer q.invalidate()
+	return q.Repository.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy)
+}
+
+func (q *quotaRepository) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	defer q.invalidate()
+	return q.Repository.DeleteNode(ctx, nodeID, force)
+}
+
+func (q *quotaRepository) invalidate() {
+	q.mu.Lock()
+	q.usage = nil
+	q.mu.Unlock()
+}
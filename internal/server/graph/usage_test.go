@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestParseQuotas(t *testing.T) {
+	quotas, err := ParseQuotas("type:Screenshot:bytes=2MB, type:Screenshot:nodes=10,total:nodes=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotas) != 2 {
+		t.Fatalf("expected 2 quotas, got %d", len(quotas))
+	}
+	if q := quotas[0]; q.Scope != QuotaType || q.Name != "Screenshot" || q.MaxBytes != 2<<20 || q.MaxNodes != 10 {
+		t.Errorf("unexpected quota: %+v", q)
+	}
+
+	for _, bad := range []string{"type:bytes=1", "namespace:x:files=3", "total:bytes=lots"} {
+		if _, err := ParseQuotas(bad); err == nil {
+			t.Errorf("ParseQuotas(%q) expected error", bad)
+		}
+	}
+}
+
+func TestQuotaEnforcement(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	for name, backend := range map[string]Repository{"sqlite": sqlite, "memory": NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			repo := WithQuotas(backend, []Quota{
+				{Scope: QuotaType, Name: "Screenshot", MaxBytes: 100},
+				{Scope: QuotaNamespace, Name: "person", MaxNodes: 1},
+			})
+			now := time.Now()
+			node := func(id, typ string, size int) *core.Node {
+				return &core.Node{ID: id, Type: typ, Content: []byte(strings.Repeat("x", size)), Created: now, Modified: now}
+			}
+
+			if err := repo.CreateNode(ctx, node("shot:1", "Screenshot", 60)); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.CreateNode(ctx, node("shot:2", "Screenshot", 60)); !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("second screenshot: got %v, want ErrQuotaExceeded", err)
+			}
+			if err := repo.CreateNodes(ctx, []*core.Node{node("person:a", "Person", 0), node("person:b", "Person", 0)}); !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("bulk over node quota: got %v, want ErrQuotaExceeded", err)
+			}
+			if err := repo.CreateNode(ctx, node("note:1", "Note", 500)); err != nil {
+				t.Errorf("unconstrained node: %v", err)
+			}
+
+			usage, err := repo.GetUsage(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := usage.ByType["Screenshot"]; s == nil || s.Nodes != 1 || s.ContentBytes != 60 {
+				t.Errorf("unexpected Screenshot usage: %+v", s)
+			}
+			if s := usage.ByNamespace["note"]; s == nil || s.ContentBytes != 500 {
+				t.Errorf("unexpected note namespace usage: %+v", s)
+			}
+		})
+	}
+}
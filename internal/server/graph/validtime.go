@@ -0,0 +1,223 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// Node and link properties bounding when a fact held in the world (valid
+// time), as opposed to when it was recorded (the version chain). valid_to
+// is exclusive; a missing bound is open.
+const (
+	ValidFromKey = "valid_from"
+	ValidToKey   = "valid_to"
+)
+
+// ParseTime reads an RFC3339 timestamp or a date (2006-01-02, as UTC
+// midnight)
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// NormalizeValidTime coerces meta[ValidFromKey] and meta[ValidToKey] to
+// RFC3339 strings in UTC and checks valid_from is before valid_to. Missing
+// values are left alone.
+func NormalizeValidTime(meta map[string]interface{}) error {
+	var bounds [2]time.Time
+	for i, key := range []string{ValidFromKey, ValidToKey} {
+		raw, ok := meta[key]
+		if !ok || raw == nil {
+			continue
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("invalid %s: expected an RFC3339 time or a date", key)
+		}
+		t, err := ParseTime(s)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: expected an RFC3339 time or a date", key, s)
+		}
+		bounds[i] = t.UTC()
+		meta[key] = bounds[i].Format(time.RFC3339)
+	}
+	if !bounds[0].IsZero() && !bounds[1].IsZero() && !bounds[0].Before(bounds[1]) {
+		return fmt.Errorf("invalid valid time: %s must be before %s", ValidFromKey, ValidToKey)
+	}
+	return nil
+}
+
+// ValidAt reports whether properties describe a fact that held at t
+func ValidAt(meta map[string]interface{}, t time.Time) bool {
+	if s, ok := meta[ValidFromKey].(string); ok {
+		if from, err := ParseTime(s); err == nil && t.Before(from) {
+			return false
+		}
+	}
+	if s, ok := meta[ValidToKey].(string); ok {
+		if to, err := ParseTime(s); err == nil && !t.Before(to) {
+			return false
+		}
+	}
+	return true
+}
+
+// TimeFilter selects what reads see at a valid time, a recorded time, or
+// both. A nil *TimeFilter selects current data; zero times are ignored.
+type TimeFilter struct {
+	ValidAt    time.Time // Keep facts that held at this time
+	RecordedAt time.Time // Read the graph as it was recorded at this time
+}
+
+// Node returns the version of a node the filter selects, or nil when none
+// does. With a valid time, that is the latest recorded version whose valid
+// time covers it, so a fact superseded for later periods is still found
+// for earlier ones.
+func (f *TimeFilter) Node(ctx context.Context, repo Repository, node *core.Node) (*core.Node, error) {
+	if f == nil {
+		return node, nil
+	}
+	if f.RecordedAt.IsZero() && !node.Deleted && (f.ValidAt.IsZero() || ValidAt(node.Meta, f.ValidAt)) {
+		return node, nil
+	}
+	history, err := repo.GetNodeHistory(ctx, node.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range history { // Newest first
+		if !f.RecordedAt.IsZero() && v.Modified.After(f.RecordedAt) {
+			continue
+		}
+		n, err := repo.GetNodeAtVersion(ctx, node.ID, v.Version)
+		if err != nil {
+			return nil, err
+		}
+		if n.Deleted {
+			return nil, nil
+		}
+		if f.ValidAt.IsZero() || ValidAt(n.Meta, f.ValidAt) {
+			return n, nil
+		}
+	}
+	return nil, nil
+}
+
+// Link reports whether the filter selects a link
+func (f *TimeFilter) Link(link *core.Link) bool {
+	if f == nil {
+		return true
+	}
+	if !f.RecordedAt.IsZero() && link.Created.After(f.RecordedAt) {
+		return false
+	}
+	return f.ValidAt.IsZero() || ValidAt(link.Meta, f.ValidAt)
+}
+
+// Links returns the links the filter selects
+func (f *TimeFilter) Links(links []*core.Link) []*core.Link {
+	if f == nil {
+		return links
+	}
+	out := make([]*core.Link, 0, len(links))
+	for _, l := range links {
+		if f.Link(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Nodes returns the selected versions of nodes, leaving out nodes the
+// filter selects no version of
+func (f *TimeFilter) Nodes(ctx context.Context, repo Repository, nodes []*core.Node) ([]*core.Node, error) {
+	if f == nil {
+		return nodes, nil
+	}
+	out := make([]*core.Node, 0, len(nodes))
+	for _, n := range nodes {
+		v, err := f.Node(ctx, repo, n)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// NodeMap resolves a traversal result in place, like Nodes
+func (f *TimeFilter) NodeMap(ctx context.Context, repo Repository, nodes map[string]*core.Node) error {
+	if f == nil {
+		return nil
+	}
+	for id, n := range nodes {
+		v, err := f.Node(ctx, repo, n)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			delete(nodes, id)
+		} else {
+			nodes[id] = v
+		}
+	}
+	return nil
+}
+
+// Page returns the limit selected nodes after offset, reading further
+// pages from fetch as needed
+func (f *TimeFilter) Page(ctx context.Context, repo Repository, limit, offset int, fetch PageFunc) ([]*core.Node, error) {
+	if f == nil {
+		return fetch(limit, offset)
+	}
+	var keepErr error
+	nodes, err := FillPage(limit, offset, func(nodes []*core.Node) []*core.Node {
+		kept, err := f.Nodes(ctx, repo, nodes)
+		if err != nil && keepErr == nil {
+			keepErr = err
+		}
+		return kept
+	}, fetch)
+	if err != nil {
+		return nil, err
+	}
+	return nodes, keepErr
+}
+
+// Subgraph returns a copy of sub with its nodes resolved like Nodes, and
+// the edges between them that hold at the valid time. keep names nodes
+// retained at their current version regardless, such as the start of a
+// traversal.
+func (f *TimeFilter) Subgraph(ctx context.Context, repo Repository, sub *Subgraph, keep ...string) (*Subgraph, error) {
+	if f == nil || sub == nil {
+		return sub, nil
+	}
+	kept := make(map[string]bool, len(sub.Nodes))
+	out := &Subgraph{Nodes: []*core.Node{}, Edges: []*SubgraphEdge{}, Stats: sub.Stats}
+	for _, n := range sub.Nodes {
+		v := n
+		if !containsString(keep, n.ID) {
+			var err error
+			if v, err = f.Node(ctx, repo, n); err != nil {
+				return nil, err
+			}
+		}
+		if v != nil {
+			kept[n.ID] = true
+			out.Nodes = append(out.Nodes, v)
+		}
+	}
+	for _, e := range sub.Edges {
+		if kept[e.Source] && kept[e.Target] && (f.ValidAt.IsZero() || ValidAt(e.Meta, f.ValidAt)) {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	out.Stats.NodeCount, out.Stats.EdgeCount = len(out.Nodes), len(out.Edges)
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestNormalizeValidTime(t *testing.T) {
+	meta := map[string]interface{}{ValidFromKey: "2019-03-01", ValidToKey: "2022-06-30T12:00:00+02:00"}
+	if err := NormalizeValidTime(meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta[ValidFromKey] != "2019-03-01T00:00:00Z" || meta[ValidToKey] != "2022-06-30T10:00:00Z" {
+		t.Errorf("normalized valid time = %v", meta)
+	}
+	for _, bad := range []map[string]interface{}{
+		{ValidFromKey: "March 2019"},
+		{ValidToKey: 2022.0},
+		{ValidFromKey: "2022-01-01", ValidToKey: "2019-01-01"},
+	} {
+		if err := NormalizeValidTime(bad); err == nil {
+			t.Errorf("NormalizeValidTime(%v) accepted", bad)
+		}
+	}
+}
+
+func TestTimeFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemory()
+	day := func(s string) time.Time { d, _ := ParseTime(s); return d }
+	now := time.Now()
+
+	repo.CreateNode(ctx, &core.Node{ID: "person:alice", Type: "Person", Meta: map[string]any{"title": "Engineer", ValidFromKey: "2019-01-01T00:00:00Z"}, Created: now, Modified: now})
+	repo.CreateNode(ctx, &core.Node{ID: "company:acme", Type: "Company", Created: now, Modified: now})
+	repo.CreateNode(ctx, &core.Node{ID: "company:globex", Type: "Company", Created: now, Modified: now})
+	repo.CreateLink(ctx, &core.Link{Source: "person:alice", Target: "company:acme", Type: "WORKS_AT", Meta: map[string]any{ValidFromKey: "2019-01-01T00:00:00Z", ValidToKey: "2022-01-01T00:00:00Z"}, Created: now, Modified: now})
+	repo.CreateLink(ctx, &core.Link{Source: "person:alice", Target: "company:globex", Type: "WORKS_AT", Meta: map[string]any{ValidFromKey: "2022-01-01T00:00:00Z"}, Created: now, Modified: now})
+	recorded := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	repo.UpdateNodeMeta(ctx, "person:alice", map[string]any{"title": "Manager", ValidFromKey: "2022-01-01T00:00:00Z"})
+
+	current, _ := repo.GetNode(ctx, "person:alice")
+	for _, tc := range []struct {
+		filter *TimeFilter
+		title  string
+	}{
+		{nil, "Manager"},
+		{&TimeFilter{ValidAt: day("2023-05-01")}, "Manager"},
+		{&TimeFilter{ValidAt: day("2020-05-01")}, "Engineer"},
+		{&TimeFilter{RecordedAt: recorded}, "Engineer"},
+		{&TimeFilter{ValidAt: day("2018-05-01")}, ""},
+	} {
+		n, err := tc.filter.Node(ctx, repo, current)
+		switch {
+		case err != nil:
+			t.Errorf("Node(%+v) error = %v", tc.filter, err)
+		case tc.title == "" && n != nil:
+			t.Errorf("Node(%+v) = %v, want none", tc.filter, n.Meta)
+		case tc.title != "" && (n == nil || n.Meta["title"] != tc.title):
+			t.Errorf("Node(%+v) = %v, want title %s", tc.filter, n, tc.title)
+		}
+	}
+
+	links, _ := repo.GetLinks(ctx, "person:alice")
+	if got := (&TimeFilter{ValidAt: day("2020-05-01")}).Links(links); len(got) != 1 || got[0].Target != "company:acme" {
+		t.Errorf("links valid in 2020 = %v", got)
+	}
+	if got := (&TimeFilter{RecordedAt: now.Add(-time.Hour)}).Links(links); len(got) != 0 {
+		t.Errorf("links recorded an hour earlier = %v", got)
+	}
+
+	sub, err := repo.GetSubgraph(ctx, "person:alice", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := (&TimeFilter{ValidAt: day("2023-05-01")}).Subgraph(ctx, repo, sub, "person:alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Stats.NodeCount != 3 || got.Stats.EdgeCount != 1 || got.Edges[0].Target != "company:globex" {
+		t.Errorf("subgraph valid in 2023 = %+v", got.Stats)
+	}
+
+	nodes, err := (&TimeFilter{ValidAt: day("2018-05-01")}).Page(ctx, repo, 10, 0, func(limit, offset int) ([]*core.Node, error) {
+		return repo.FilterNodes(ctx, nil, "", "", limit, offset)
+	})
+	if err != nil || len(nodes) != 2 {
+		t.Errorf("nodes valid in 2018 = %d, %v", len(nodes), err)
+	}
+}
@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ClusterNodeType is the type of the synthetic nodes a view collapses into
+const ClusterNodeType = "Cluster"
+
+// ViewOptions controls how a subgraph is reduced for display
+type ViewOptions struct {
+	HideEdgeTypes []string // Edge types to drop (e.g. ATTENDED)
+	ClusterBy     string   // Meta key whose value groups nodes into clusters (e.g. community_id)
+	Expand        []string // Cluster values to leave expanded
+}
+
+// GraphView is a display-oriented graph. Nodes carry only what a layout
+// needs; clients fetch full node details on demand.
+type GraphView struct {
+	Nodes     []ViewNode           `json:"nodes"`
+	Edges     []ViewEdge           `json:"edges"`
+	EdgeTypes map[string]int       `json:"edge_types"`       // Counts before hiding, for rendering toggles
+	Styles    map[string]NodeStyle `json:"styles,omitempty"` // Visualization hints by node type
+	Truncated bool                 `json:"truncated"`
+}
+
+// ViewNode is a graph node, or a collapsed cluster of nodes
+type ViewNode struct {
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+	Label   string   `json:"label"`
+	Cluster string   `json:"cluster,omitempty"` // Cluster value the node belongs to
+	Size    int      `json:"size,omitempty"`    // Member count for cluster nodes
+	Value   *float64 `json:"value,omitempty"`   // The type's size_by metric, when styled
+}
+
+// ViewEdge is an edge between view nodes; parallel edges merged by
+// clustering are counted in Weight
+type ViewEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+	Weight int    `json:"weight"`
+}
+
+// BuildView reduces a subgraph for display: hidden edge types are removed
+// and nodes sharing a ClusterBy value are collapsed into one cluster node
+func BuildView(sub *Subgraph, opts ViewOptions) *GraphView {
+	hidden := make(map[string]bool, len(opts.HideEdgeTypes))
+	for _, t := range opts.HideEdgeTypes {
+		hidden[t] = true
+	}
+	expanded := make(map[string]bool, len(opts.Expand))
+	for _, v := range opts.Expand {
+		expanded[v] = true
+	}
+
+	view := &GraphView{Nodes: []ViewNode{}, Edges: []ViewEdge{}, EdgeTypes: make(map[string]int)}
+
+	// Map each node to the view node that represents it
+	target := make(map[string]string, len(sub.Nodes))
+	clusters := make(map[string]*ViewNode)
+	for _, n := range sub.Nodes {
+		var cluster string
+		if opts.ClusterBy != "" {
+			if v, ok := n.Meta[opts.ClusterBy]; ok && v != nil {
+				cluster = fmt.Sprint(v)
+			}
+		}
+		if cluster != "" && !expanded[cluster] {
+			id := "cluster:" + cluster
+			c, ok := clusters[id]
+			if !ok {
+				c = &ViewNode{ID: id, Type: ClusterNodeType, Label: cluster, Cluster: cluster}
+				clusters[id] = c
+			}
+			c.Size++
+			target[n.ID] = id
+			continue
+		}
+		target[n.ID] = n.ID
+		view.Nodes = append(view.Nodes, ViewNode{ID: n.ID, Type: n.Type, Label: NodeLabel(n.ID, n.Meta), Cluster: cluster})
+	}
+	for _, c := range clusters {
+		view.Nodes = append(view.Nodes, *c)
+	}
+	sort.Slice(view.Nodes, func(i, j int) bool { return view.Nodes[i].ID < view.Nodes[j].ID })
+
+	type edgeKey struct{ source, target, typ string }
+	merged := make(map[edgeKey]int)
+	for _, e := range sub.Edges {
+		view.EdgeTypes[e.Type]++
+		if hidden[e.Type] {
+			continue
+		}
+		source, ok := target[e.Source]
+		if !ok {
+			continue
+		}
+		dest, ok := target[e.Target]
+		if !ok || source == dest && source != e.Source {
+			// Edges inside a collapsed cluster are not drawn
+			continue
+		}
+		merged[edgeKey{source, dest, e.Type}]++
+	}
+	for k, weight := range merged {
+		view.Edges = append(view.Edges, ViewEdge{Source: k.source, Target: k.target, Type: k.typ, Weight: weight})
+	}
+	sort.Slice(view.Edges, func(i, j int) bool {
+		a, b := view.Edges[i], view.Edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Type < b.Type
+	})
+	return view
+}
+
+// NodeLabel picks a display label from common meta keys, falling back to the ID
+func NodeLabel(id string, meta map[string]interface{}) string {
+	for _, key := range []string{"name", "title", "label"} {
+		if s, ok := meta[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return id
+}
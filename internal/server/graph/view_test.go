@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestBuildView(t *testing.T) {
+	sub := &Subgraph{
+		Nodes: []*core.Node{
+			{ID: "a", Type: "Person", Meta: map[string]interface{}{"name": "Ada", "community_id": 1}},
+			{ID: "b", Type: "Person", Meta: map[string]interface{}{"community_id": 1}},
+			{ID: "c", Type: "Person", Meta: map[string]interface{}{"community_id": 2}},
+			{ID: "m", Type: "Meeting"},
+		},
+		Edges: []*SubgraphEdge{
+			{Source: "a", Target: "b", Type: "KNOWS"},
+			{Source: "a", Target: "c", Type: "KNOWS"},
+			{Source: "b", Target: "c", Type: "KNOWS"},
+			{Source: "a", Target: "m", Type: "ATTENDED"},
+			{Source: "c", Target: "m", Type: "MENTIONS"},
+		},
+	}
+
+	view := BuildView(sub, ViewOptions{HideEdgeTypes: []string{"ATTENDED"}})
+	if len(view.Nodes) != 4 || len(view.Edges) != 4 {
+		t.Fatalf("expected 4 nodes and 4 edges, got %+v", view)
+	}
+	if view.EdgeTypes["ATTENDED"] != 1 || view.Nodes[0].Label != "Ada" {
+		t.Errorf("unexpected view %+v", view)
+	}
+
+	view = BuildView(sub, ViewOptions{ClusterBy: "community_id", Expand: []string{"2"}})
+	want := map[string]ViewNode{
+		"cluster:1": {ID: "cluster:1", Type: ClusterNodeType, Label: "1", Cluster: "1", Size: 2},
+		"c":         {ID: "c", Type: "Person", Label: "c", Cluster: "2"},
+		"m":         {ID: "m", Type: "Meeting", Label: "m"},
+	}
+	if len(view.Nodes) != len(want) {
+		t.Fatalf("expected %d nodes, got %+v", len(want), view.Nodes)
+	}
+	for _, n := range view.Nodes {
+		if n != want[n.ID] {
+			t.Errorf("node %s: got %+v, want %+v", n.ID, n, want[n.ID])
+		}
+	}
+
+	// a->b is inside the cluster; a->c and b->c merge into one weighted edge
+	edges := map[string]int{}
+	for _, e := range view.Edges {
+		edges[e.Source+">"+e.Target+":"+e.Type] = e.Weight
+	}
+	if len(edges) != 3 || edges["cluster:1>c:KNOWS"] != 2 || edges["cluster:1>m:ATTENDED"] != 1 || edges["c>m:MENTIONS"] != 1 {
+		t.Errorf("unexpected edges %v", edges)
+	}
+}
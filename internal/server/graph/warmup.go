@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Warmer is implemented by backends that can prime their caches at startup
+type Warmer interface {
+	Warm(ctx context.Context) (*WarmupReport, error)
+}
+
+// WarmupReport describes a startup warm-up: the tables read into the page
+// cache and the indexes checked
+type WarmupReport struct {
+	Tables     []TableWarmup `json:"tables"`
+	Indexes    []IndexCheck  `json:"indexes"`
+	DurationMS float64       `json:"duration_ms"`
+}
+
+// TableWarmup is one table scanned during warm-up
+type TableWarmup struct {
+	Name       string  `json:"name"`
+	Rows       int64   `json:"rows"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// IndexCheck is one index verified during warm-up
+type IndexCheck struct {
+	Name    string `json:"name"`
+	Table   string `json:"table"`
+	Rebuilt bool   `json:"rebuilt,omitempty"` // Was missing and has been created again
+}
+
+// warmTables are read in full at startup: the graph itself, the content
+// store and the shadow tables holding the full-text and trigram indexes
+var warmTables = []string{
+	"nodes", "links", "contents",
+	"nodes_fts_data", "nodes_fts_idx", "nodes_fts_docsize",
+	"nodes_trigram_data", "nodes_trigram_idx", "nodes_trigram_content",
+}
+
+// createIndexPattern matches the index statements in the SQLite migrations
+var createIndexPattern = regexp.MustCompile(`^CREATE INDEX IF NOT EXISTS (\w+) ON (\w+)\(`)
+
+// Warm reads the hot tables through the read pool so their pages are in the
+// page cache before the first query, and checks every index the migrations
+// create is present and usable. A missing index is created again; one that
+// cannot be scanned fails the warm-up.
+func (r *SQLiteRepository) Warm(ctx context.Context) (*WarmupReport, error) {
+	if r.db.dollarParams {
+		return nil, fmt.Errorf("warm-up is only supported on SQLite")
+	}
+	start := time.Now()
+	db := r.reader()
+
+	existing := make(map[string]bool)
+	rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type IN ('table', 'index')`)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("listing tables: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+
+	report := &WarmupReport{}
+	for _, table := range warmTables {
+		if !existing[table] {
+			continue
+		}
+		t := TableWarmup{Name: table}
+		began := time.Now()
+		if err := db.QueryRowContext(ctx, `SELECT count(*) FROM `+table+` NOT INDEXED`).Scan(&t.Rows); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", table, err)
+		}
+		t.DurationMS = float64(time.Since(began).Microseconds()) / 1000
+		report.Tables = append(report.Tables, t)
+	}
+
+	for _, m := range sqliteMigrations() {
+		for _, stmt := range m.statements {
+			match := createIndexPattern.FindStringSubmatch(stmt)
+			if match == nil {
+				continue
+			}
+			check := IndexCheck{Name: match[1], Table: match[2]}
+			if !existing[check.Name] {
+				if _, err := r.db.ExecContext(ctx, stmt); err != nil {
+					return nil, fmt.Errorf("rebuilding index %s: %w", check.Name, err)
+				}
+				check.Rebuilt = true
+			}
+			// Scanning the whole index reads it into the cache, and fails
+			// if SQLite cannot use it
+			var n int64
+			if err := db.QueryRowContext(ctx, `SELECT count(*) FROM `+check.Table+` INDEXED BY `+check.Name).Scan(&n); err != nil {
+				return nil, fmt.Errorf("checking index %s: %w", check.Name, err)
+			}
+			report.Indexes = append(report.Indexes, check)
+		}
+	}
+	report.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	return report, nil
+}
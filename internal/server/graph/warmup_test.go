@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestWarm(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer sqlite.Close(ctx)
+
+	now := time.Now()
+	for _, id := range []string{"note:1", "note:2"} {
+		node := &core.Node{ID: id, Type: "Note", Content: []byte("warm " + id), Meta: map[string]interface{}{"title": id}, Created: now, Modified: now}
+		if err := sqlite.CreateNode(ctx, node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sqlite.db.ExecContext(ctx, `DROP INDEX idx_links_type`); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := sqlite.Warm(ctx)
+	if err != nil {
+		t.Fatalf("Warm() error = %v", err)
+	}
+	tables := make(map[string]int64)
+	for _, table := range report.Tables {
+		tables[table.Name] = table.Rows
+	}
+	if tables["nodes"] != 2 || tables["nodes_trigram_content"] != 2 {
+		t.Errorf("tables = %+v", report.Tables)
+	}
+	if _, ok := tables["nodes_fts_data"]; !ok {
+		t.Errorf("full-text index not warmed: %+v", report.Tables)
+	}
+
+	seen := make(map[string]bool)
+	for _, index := range report.Indexes {
+		if seen[index.Name] {
+			t.Errorf("index %s checked twice", index.Name)
+		}
+		seen[index.Name] = true
+		if index.Rebuilt != (index.Name == "idx_links_type") {
+			t.Errorf("index %s rebuilt = %v", index.Name, index.Rebuilt)
+		}
+	}
+	if !seen["idx_nodes_type"] || !seen["idx_nodes_modified_unix"] {
+		t.Errorf("indexes = %+v", report.Indexes)
+	}
+
+	// The dropped index is back
+	if report, err = sqlite.Warm(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range report.Indexes {
+		if index.Rebuilt {
+			t.Errorf("index %s rebuilt again", index.Name)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Package growth records daily rollups of the graph's size as Stats nodes
+// and projects storage needs from them, for capacity planning.
+package growth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// NodeType is the node type rollups are stored as
+const NodeType = "Stats"
+
+// idPrefix namespaces rollup node IDs, which end in the day
+const idPrefix = "stats:"
+
+// dateLayout formats rollup days
+const dateLayout = "2006-01-02"
+
+// ErrNotFound is returned for days without a rollup
+var ErrNotFound = errors.New("no rollup for that day")
+
+// Rollup is the graph's size at the end of a UTC day and what was added
+// during it. Usage counts nodes and bytes overall, per namespace and per
+// type as GET /api/admin/usage does.
+type Rollup struct {
+	Date  string    `json:"date"`  // YYYY-MM-DD, UTC
+	Taken time.Time `json:"taken"` // When the counts were read
+	graph.Usage
+
+	Links       int64            `json:"links"`
+	LinkTypes   map[string]int64 `json:"link_types"`
+	Added       int64            `json:"nodes_added"` // Nodes created during the day
+	AddedByType map[string]int64 `json:"nodes_added_by_type"`
+	LinksAdded  int64            `json:"links_added"`
+}
+
+// Day returns the UTC day a time falls in
+func Day(t time.Time) string {
+	return t.UTC().Format(dateLayout)
+}
+
+// Collect reads the graph's current size and what was created on day
+// (UTC) into a rollup
+func Collect(ctx context.Context, repo graph.Repository, day time.Time) (*Rollup, error) {
+	start := day.UTC().Truncate(24 * time.Hour)
+	usage, err := repo.GetUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading usage: %w", err)
+	}
+	usage.ContentStore = nil
+	gm, err := repo.GetGraphMap(ctx, 1)
+	if err != nil {
+		return nil, fmt.Errorf("reading link counts: %w", err)
+	}
+	diff, err := repo.DiffGraph(ctx, start, start.Add(24*time.Hour), true)
+	if err != nil {
+		return nil, fmt.Errorf("reading the day's changes: %w", err)
+	}
+
+	r := &Rollup{
+		Date:        Day(start),
+		Taken:       time.Now().UTC(),
+		Usage:       *usage,
+		Links:       int64(gm.Stats.TotalEdges),
+		LinkTypes:   make(map[string]int64, len(gm.EdgeTypes)),
+		AddedByType: make(map[string]int64),
+		LinksAdded:  int64(diff.Summary.LinksAdded),
+	}
+	for t, n := range gm.EdgeTypes {
+		r.LinkTypes[t] = int64(n)
+	}
+	for _, n := range diff.NodesAdded {
+		r.Added++
+		r.AddedByType[n.Type]++
+	}
+	return r, nil
+}
+
+// Record stores a rollup, replacing any earlier one for the same day
+func Record(ctx context.Context, repo graph.Repository, r *Rollup) error {
+	meta, err := toMeta(r)
+	if err != nil {
+		return err
+	}
+	id := idPrefix + r.Date
+	if node, err := repo.GetNode(ctx, id); err == nil && node.Type == NodeType {
+		return repo.UpdateNodeMeta(ctx, id, meta)
+	}
+	return repo.CreateNode(ctx, &core.Node{ID: id, Type: NodeType, Meta: meta, Created: r.Taken, Modified: r.Taken})
+}
+
+// Get loads the rollup for a day (YYYY-MM-DD)
+func Get(ctx context.Context, repo graph.Repository, date string) (*Rollup, error) {
+	node, err := repo.GetNode(ctx, idPrefix+date)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, date)
+	}
+	return fromNode(node)
+}
+
+// List returns the rollups for days from..to inclusive (YYYY-MM-DD; empty
+// for no bound), oldest first
+func List(ctx context.Context, repo graph.Repository, from, to string) ([]*Rollup, error) {
+	const pageSize = 500
+	out := []*Rollup{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			date := strings.TrimPrefix(n.ID, idPrefix)
+			if (from != "" && date < from) || (to != "" && date > to) {
+				continue
+			}
+			r, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			out = append(out, r)
+		}
+		if len(nodes) < pageSize {
+			break
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out, nil
+}
+
+// toMeta stores a rollup's fields as node properties
+func toMeta(r *Rollup) (map[string]interface{}, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// fromNode reads a rollup back from its node
+func fromNode(node *core.Node) (*Rollup, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var r Rollup
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("corrupt rollup %s: %w", node.ID, err)
+	}
+	r.Date = strings.TrimPrefix(node.ID, idPrefix)
+	return &r, nil
+}
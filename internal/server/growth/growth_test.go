@@ -0,0 +1,102 @@
+package growth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestCollectAndRecord(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "note:a", Type: "Note", Content: []byte("hello"), Created: now, Modified: now},
+		{ID: "note:b", Type: "Note", Created: now, Modified: now},
+		{ID: "person:c", Type: "Person", Created: now, Modified: now},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "note:a", Target: "person:c", Type: "MENTIONS", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Collect(ctx, repo, now)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if r.Date != Day(now) || r.Total.Nodes != 3 || r.ByType["Note"].Nodes != 2 || r.Links != 1 || r.LinkTypes["MENTIONS"] != 1 {
+		t.Errorf("rollup = %+v", r)
+	}
+	if r.Added != 3 || r.AddedByType["Note"] != 2 || r.LinksAdded != 1 {
+		t.Errorf("added = %d %v, links %d", r.Added, r.AddedByType, r.LinksAdded)
+	}
+
+	if err := Record(ctx, repo, r); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	r.Links = 7
+	if err := Record(ctx, repo, r); err != nil {
+		t.Fatalf("Record() again error = %v", err)
+	}
+	got, err := Get(ctx, repo, Day(now))
+	if err != nil || got.Links != 7 || got.ByNamespace["note"].Nodes != 2 {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+	if _, err := Get(ctx, repo, "2001-01-01"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() missing day error = %v", err)
+	}
+	if all, err := List(ctx, repo, "", ""); err != nil || len(all) != 1 {
+		t.Errorf("List() = %d rollups, %v", len(all), err)
+	}
+	if none, _ := List(ctx, repo, "2001-01-01", "2001-12-31"); len(none) != 0 {
+		t.Errorf("List() outside range = %d rollups", len(none))
+	}
+}
+
+func TestProject(t *testing.T) {
+	// 10 days growing by 100 nodes and 1000 bytes a day, all Notes
+	var rollups []*Rollup
+	for i := 0; i < 10; i++ {
+		stats := graph.UsageStats{Nodes: int64(100 * i), ContentBytes: int64(1000 * i)}
+		rollups = append(rollups, &Rollup{
+			Date: fmt.Sprintf("2026-01-%02d", i+1),
+			Usage: graph.Usage{
+				Total:  stats,
+				ByType: map[string]*graph.UsageStats{"Note": &stats},
+			},
+		})
+	}
+
+	quotas := []graph.Quota{
+		{Scope: graph.QuotaTotal, MaxBytes: 19000},
+		{Scope: graph.QuotaType, Name: "Image", MaxNodes: 10},
+	}
+	p := Project(rollups, []int{30}, quotas)
+	if p == nil {
+		t.Fatal("Project() = nil")
+	}
+	if p.BasisDays != 9 || p.NodesPerDay != 100 || p.BytesPerDay != 1000 || p.ByType["Note"].NodesPerDay != 100 {
+		t.Errorf("projection = %+v", p)
+	}
+	if f := p.Forecasts[0]; f.Nodes != 900+3000 || f.Bytes != 9000+30000 || f.Date != "2026-02-09" {
+		t.Errorf("forecast = %+v", f)
+	}
+	if q := p.Quotas[0]; q.DaysLeft == nil || *q.DaysLeft != 10 || q.Date != "2026-01-20" {
+		t.Errorf("total quota forecast = %+v", q)
+	}
+	if q := p.Quotas[1]; q.DaysLeft != nil {
+		t.Errorf("flat quota forecast = %+v, want no date", q)
+	}
+
+	if Project(rollups[:1], DefaultHorizons, nil) != nil {
+		t.Error("Project() with one rollup should be nil")
+	}
+}
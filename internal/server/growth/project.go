@@ -0,0 +1,142 @@
+package growth
+
+import (
+	"math"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// DefaultHorizons are the forecast horizons in days
+var DefaultHorizons = []int{30, 90, 365}
+
+// Trend is a growth rate fitted by least squares over daily rollups
+type Trend struct {
+	NodesPerDay float64 `json:"nodes_per_day"`
+	BytesPerDay float64 `json:"bytes_per_day"`
+}
+
+// Forecast is the projected size of the graph some days after the latest
+// rollup
+type Forecast struct {
+	Days  int    `json:"days"`
+	Date  string `json:"date"`
+	Nodes int64  `json:"nodes"`
+	Bytes int64  `json:"bytes"`
+}
+
+// QuotaForecast is when a quota is projected to be reached at the
+// current trend
+type QuotaForecast struct {
+	graph.Quota
+	Trend
+	DaysLeft *int   `json:"days_left,omitempty"` // Absent when usage is flat or shrinking
+	Date     string `json:"date,omitempty"`
+}
+
+// Projection extrapolates the trend of a series of rollups
+type Projection struct {
+	BasisDays int `json:"basis_days"` // Days between the first and latest rollup
+	Trend
+	ByType    map[string]Trend `json:"by_type"`
+	Forecasts []Forecast       `json:"forecasts"`
+	Quotas    []QuotaForecast  `json:"quotas,omitempty"`
+}
+
+// Project fits the growth of rollups (oldest first) and forecasts the
+// size at each horizon and when each quota fills. It returns nil for
+// fewer than two days of rollups.
+func Project(rollups []*Rollup, horizons []int, quotas []graph.Quota) *Projection {
+	if len(rollups) < 2 {
+		return nil
+	}
+	first, latest := rollups[0], rollups[len(rollups)-1]
+	end, err := time.Parse(dateLayout, latest.Date)
+	if err != nil {
+		return nil
+	}
+	p := &Projection{
+		BasisDays: dayNumber(latest) - dayNumber(first),
+		Trend:     fit(rollups, func(r *Rollup) *graph.UsageStats { return &r.Total }),
+		ByType:    make(map[string]Trend),
+	}
+	if p.BasisDays <= 0 {
+		return nil
+	}
+	for t := range latest.ByType {
+		p.ByType[t] = fit(rollups, func(r *Rollup) *graph.UsageStats { return r.ByType[t] })
+	}
+
+	for _, days := range horizons {
+		p.Forecasts = append(p.Forecasts, Forecast{
+			Days:  days,
+			Date:  end.AddDate(0, 0, days).Format(dateLayout),
+			Nodes: extrapolate(latest.Total.Nodes, p.NodesPerDay, days),
+			Bytes: extrapolate(latest.Total.Bytes(), p.BytesPerDay, days),
+		})
+	}
+
+	for _, q := range quotas {
+		f := QuotaForecast{Quota: q, Trend: fit(rollups, func(r *Rollup) *graph.UsageStats {
+			used := q.Used(&r.Usage)
+			return &used
+		})}
+		used := q.Used(&latest.Usage)
+		left := math.Inf(1)
+		if q.MaxNodes > 0 && f.NodesPerDay > 0 {
+			left = math.Min(left, float64(q.MaxNodes-used.Nodes)/f.NodesPerDay)
+		}
+		if q.MaxBytes > 0 && f.BytesPerDay > 0 {
+			left = math.Min(left, float64(q.MaxBytes-used.Bytes())/f.BytesPerDay)
+		}
+		if !math.IsInf(left, 1) {
+			days := max(0, int(math.Floor(left)))
+			f.DaysLeft = &days
+			f.Date = end.AddDate(0, 0, days).Format(dateLayout)
+		}
+		p.Quotas = append(p.Quotas, f)
+	}
+	return p
+}
+
+// fit returns the least-squares slope of the stats series over day numbers;
+// days where stats returns nil count as zero
+func fit(rollups []*Rollup, stats func(*Rollup) *graph.UsageStats) Trend {
+	n := float64(len(rollups))
+	var sx, sxx, sn, sxn, sb, sxb float64
+	for _, r := range rollups {
+		x := float64(dayNumber(r))
+		var nodes, bytes float64
+		if s := stats(r); s != nil {
+			nodes, bytes = float64(s.Nodes), float64(s.Bytes())
+		}
+		sx += x
+		sxx += x * x
+		sn += nodes
+		sxn += x * nodes
+		sb += bytes
+		sxb += x * bytes
+	}
+	denom := n*sxx - sx*sx
+	if denom == 0 {
+		return Trend{}
+	}
+	return Trend{
+		NodesPerDay: (n*sxn - sx*sn) / denom,
+		BytesPerDay: (n*sxb - sx*sb) / denom,
+	}
+}
+
+// extrapolate projects value forward at perDay, never below zero
+func extrapolate(value int64, perDay float64, days int) int64 {
+	return max(0, value+int64(math.Round(perDay*float64(days))))
+}
+
+// dayNumber returns the rollup's day counted from the Unix epoch
+func dayNumber(r *Rollup) int {
+	t, err := time.Parse(dateLayout, r.Date)
+	if err != nil {
+		return 0
+	}
+	return int(t.Unix() / 86400)
+}
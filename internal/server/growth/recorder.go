@@ -0,0 +1,72 @@
+package growth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// checkInterval is how often the recorder looks for a finished day
+const checkInterval = time.Hour
+
+// Recorder stores a rollup for each day once it has ended (UTC), so the
+// counts are those shortly after midnight. Days the server was down for
+// are skipped rather than backfilled, since their end-of-day size can no
+// longer be read.
+type Recorder struct {
+	repo graph.Repository
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRecorder creates a recorder writing rollups to repo
+func NewRecorder(repo graph.Repository) *Recorder {
+	return &Recorder{repo: repo, stop: make(chan struct{})}
+}
+
+// Start records yesterday's rollup if it is missing, then checks hourly
+func (r *Recorder) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.recordDue(context.Background(), time.Now())
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				r.recordDue(context.Background(), now)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Growth statistics recorder started")
+}
+
+// Stop halts recording and waits for a running rollup to finish
+func (r *Recorder) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+}
+
+// recordDue records the rollup for the day before now unless it exists
+func (r *Recorder) recordDue(ctx context.Context, now time.Time) {
+	day := now.UTC().AddDate(0, 0, -1)
+	if _, err := Get(ctx, r.repo, Day(day)); !errors.Is(err, ErrNotFound) {
+		return
+	}
+	rollup, err := Collect(ctx, r.repo, day)
+	if err != nil {
+		log.Printf("Growth statistics for %s failed: %v", Day(day), err)
+		return
+	}
+	if err := Record(ctx, r.repo, rollup); err != nil {
+		log.Printf("Growth statistics for %s not stored: %v", Day(day), err)
+	}
+}
@@ -0,0 +1,242 @@
+// Package health combines the graph's integrity, orphan, duplicate, schema
+// and staleness checks into one score with a per-component breakdown, for
+// status pages and for alerting when the knowledge base degrades.
+package health
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/dedup"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/ontology"
+)
+
+// Statuses, from the overall or a component score
+const (
+	StatusHealthy   = "healthy"   // Score of at least 80
+	StatusDegraded  = "degraded"  // Score of at least 50
+	StatusUnhealthy = "unhealthy" // Anything lower
+)
+
+// Component names
+const (
+	ComponentIntegrity  = "integrity"
+	ComponentOrphans    = "orphans"
+	ComponentDuplicates = "duplicates"
+	ComponentSchema     = "schema"
+	ComponentStaleness  = "staleness"
+)
+
+// pageSize is how many nodes each scan reads at a time
+const pageSize = 500
+
+// rule scores one component: the rate of problems at which its score
+// reaches zero, and its weight in the overall score
+type rule struct {
+	tolerance float64
+	weight    float64
+}
+
+var rules = map[string]rule{
+	ComponentIntegrity:  {tolerance: 0.05, weight: 3},
+	ComponentOrphans:    {tolerance: 0.5, weight: 2},
+	ComponentDuplicates: {tolerance: 0.1, weight: 1},
+	ComponentSchema:     {tolerance: 0.2, weight: 2},
+	ComponentStaleness:  {tolerance: 1, weight: 1},
+}
+
+// Config tunes a Checker
+type Config struct {
+	StaleAfter   time.Duration   // Nodes unmodified this long are stale; default 180 days
+	CacheTTL     time.Duration   // How long a report is reused; 0 checks on every call
+	ExcludeTypes []string        // Internal node types left out of node counts and orphans
+	Duplicates   *dedup.Detector // Source of pending duplicate pairs; nil skips the component
+}
+
+// Component is one input to the overall score
+type Component struct {
+	Name     string  `json:"name"`
+	Score    float64 `json:"score"` // 0 to 100
+	Status   string  `json:"status"`
+	Weight   float64 `json:"weight"`
+	Problems int     `json:"problems"`
+	Checked  int     `json:"checked"`
+	Rate     float64 `json:"rate"`              // Problems per item checked
+	Skipped  string  `json:"skipped,omitempty"` // Why the component was left out of the score
+}
+
+// Report is the graph's health at one point in time
+type Report struct {
+	Score      float64     `json:"score"` // Weighted average of the components not skipped
+	Status     string      `json:"status"`
+	Components []Component `json:"components"`
+	Nodes      int         `json:"nodes"` // Content nodes, excluding internal types
+	Links      int         `json:"links"`
+	Checked    time.Time   `json:"checked"`
+	Took       string      `json:"took"`
+}
+
+// Component returns the named component, or nil
+func (r *Report) Component(name string) *Component {
+	for i := range r.Components {
+		if r.Components[i].Name == name {
+			return &r.Components[i]
+		}
+	}
+	return nil
+}
+
+// Checker computes health reports, reusing the last one for CacheTTL
+type Checker struct {
+	repo graph.Repository
+	cfg  Config
+
+	mu   sync.Mutex // Held while checking so concurrent callers share one scan
+	last *Report
+}
+
+// NewChecker creates a checker over repo
+func NewChecker(repo graph.Repository, cfg Config) *Checker {
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 180 * 24 * time.Hour
+	}
+	return &Checker{repo: repo, cfg: cfg}
+}
+
+// Check returns the current report, computing it when the cached one has
+// expired
+func (c *Checker) Check(ctx context.Context) (*Report, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil && time.Since(c.last.Checked) < c.cfg.CacheTTL {
+		return c.last, nil
+	}
+	report, err := c.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.last = report
+	return report, nil
+}
+
+func (c *Checker) check(ctx context.Context) (*Report, error) {
+	start := time.Now()
+	report := &Report{Checked: start.UTC()}
+
+	nodes, stale, err := c.scanNodes(ctx, start.Add(-c.cfg.StaleAfter))
+	if err != nil {
+		return nil, fmt.Errorf("scanning nodes: %w", err)
+	}
+	report.Nodes = nodes
+	gm, err := c.repo.GetGraphMap(ctx, 1)
+	if err != nil {
+		return nil, fmt.Errorf("reading link counts: %w", err)
+	}
+	report.Links = gm.Stats.TotalEdges
+
+	integrity, err := c.repo.CheckIntegrity(ctx, graph.IntegrityOptions{ExcludeTypes: c.cfg.ExcludeTypes, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("checking integrity: %w", err)
+	}
+	// Broken version chains are judged against nodes, dangling links
+	// against links
+	report.add(ComponentIntegrity, integrity.Counts.DanglingLinks+integrity.Counts.BrokenVersions, report.Links+nodes, "")
+	report.add(ComponentOrphans, integrity.Counts.Orphans, nodes, "")
+
+	switch d := c.cfg.Duplicates; {
+	case d == nil:
+		report.add(ComponentDuplicates, 0, 0, "duplicate detection is disabled")
+	case !d.Ready():
+		report.add(ComponentDuplicates, 0, 0, "duplicate index is still building")
+	default:
+		report.add(ComponentDuplicates, len(d.Pairs(dedup.StatusPending)), nodes, "")
+	}
+
+	violations, err := ontology.Report(ctx, c.repo, "", 0)
+	if err != nil {
+		return nil, fmt.Errorf("checking link types: %w", err)
+	}
+	if violations.Checked == 0 {
+		report.add(ComponentSchema, 0, 0, "no links of a defined type")
+	} else {
+		report.add(ComponentSchema, violations.Found, violations.Checked, "")
+	}
+
+	report.add(ComponentStaleness, stale, nodes, "")
+
+	var total, weights float64
+	for _, comp := range report.Components {
+		if comp.Skipped == "" {
+			total += comp.Score * comp.Weight
+			weights += comp.Weight
+		}
+	}
+	report.Score = 100
+	if weights > 0 {
+		report.Score = round(total / weights)
+	}
+	report.Status = status(report.Score)
+	report.Took = time.Since(start).Round(time.Millisecond).String()
+	return report, nil
+}
+
+// scanNodes counts content nodes and those last modified before cutoff
+func (c *Checker) scanNodes(ctx context.Context, cutoff time.Time) (nodes, stale int, err error) {
+	for offset := 0; ; offset += pageSize {
+		page, err := c.repo.FilterNodes(ctx, nil, "", "", pageSize, offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, n := range page {
+			if slices.Contains(c.cfg.ExcludeTypes, n.Type) {
+				continue
+			}
+			nodes++
+			modified := n.Modified
+			if modified.IsZero() {
+				modified = n.Created
+			}
+			if modified.Before(cutoff) {
+				stale++
+			}
+		}
+		if len(page) < pageSize {
+			return nodes, stale, nil
+		}
+	}
+}
+
+// add scores a component from its problem rate. Skipped components are
+// listed at full score and left out of the overall score.
+func (r *Report) add(name string, problems, checked int, skipped string) {
+	rl := rules[name]
+	comp := Component{Name: name, Weight: rl.weight, Problems: problems, Checked: checked, Skipped: skipped}
+	if checked > 0 {
+		comp.Rate = math.Round(float64(problems)/float64(checked)*1e4) / 1e4
+	}
+	comp.Score = round(100 * max(0, 1-comp.Rate/rl.tolerance))
+	comp.Status = status(comp.Score)
+	r.Components = append(r.Components, comp)
+}
+
+// status names the band a score falls in
+func status(score float64) string {
+	switch {
+	case score >= 80:
+		return StatusHealthy
+	case score >= 50:
+		return StatusDegraded
+	default:
+		return StatusUnhealthy
+	}
+}
+
+// round keeps one decimal place
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/ontology"
+)
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	report, err := NewChecker(repo, Config{}).Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Score != 100 || report.Status != StatusHealthy {
+		t.Errorf("empty graph = %v %s", report.Score, report.Status)
+	}
+	if c := report.Component(ComponentDuplicates); c == nil || c.Skipped == "" {
+		t.Errorf("duplicates component = %+v, want skipped", c)
+	}
+
+	now := time.Now()
+	old := now.AddDate(-1, 0, 0)
+	for _, n := range []*core.Node{
+		{ID: "a", Type: "Note", Created: now, Modified: now},
+		{ID: "b", Type: "Person", Created: now, Modified: now},
+		{ID: "c", Type: "Note", Created: now, Modified: now},
+		{ID: "d", Type: "Note", Created: old, Modified: old},
+		{ID: "sub", Type: "Subscription", Created: old, Modified: old},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "a", Target: "b", Type: "KNOWS", Created: now, Modified: now},
+		{Source: "d", Target: "b", Type: "MENTIONS", Created: now, Modified: now},
+	} {
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ontology.PutLinkType(ctx, repo, &ontology.LinkTypeDef{Type: "KNOWS", Sources: []string{"Person"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := NewChecker(repo, Config{CacheTTL: time.Hour, ExcludeTypes: []string{"Subscription", ontology.LinkNodeType}})
+	report, err = checker.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Nodes != 4 || report.Links != 2 {
+		t.Errorf("report counted %d nodes and %d links, want 4 and 2", report.Nodes, report.Links)
+	}
+	for name, want := range map[string]float64{
+		ComponentIntegrity: 100,
+		ComponentOrphans:   50, // c of 4 nodes
+		ComponentSchema:    0,  // The one KNOWS link starts at a Note
+		ComponentStaleness: 75, // d of 4 nodes
+	} {
+		if c := report.Component(name); c == nil || c.Score != want || c.Skipped != "" {
+			t.Errorf("%s component = %+v, want score %v", name, c, want)
+		}
+	}
+	// (100*3 + 50*2 + 0*2 + 75*1) / 8, duplicates skipped
+	if report.Score != 59.4 || report.Status != StatusDegraded {
+		t.Errorf("report = %v %s, want 59.4 degraded", report.Score, report.Status)
+	}
+
+	// Cached until the TTL passes
+	if err := repo.CreateNode(ctx, &core.Node{ID: "e", Type: "Note", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := checker.Check(ctx); again != report {
+		t.Error("Check within the TTL did not reuse the report")
+	}
+}
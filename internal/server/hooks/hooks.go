@@ -0,0 +1,323 @@
+// Package hooks lets external services push data into the graph: each hook
+// has a secret URL that accepts arbitrary JSON, which the hook's mapping
+// templates turn into nodes and links.
+package hooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// NodeType is the node type hooks are stored as
+const NodeType = "IngestHook"
+
+// idPrefix namespaces hook node IDs
+const idPrefix = "hook:"
+
+var (
+	// ErrNotFound is returned for unknown hooks
+	ErrNotFound = errors.New("hook not found")
+	// ErrUnauthorized is returned for a payload whose signature does not match
+	ErrUnauthorized = errors.New("invalid signature")
+)
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validLinkType matches the link types link templates may name literally
+var validLinkType = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+// Hook maps payloads posted to its URL into nodes and links. The URL's
+// token is shown once, when the hook is created or its token rotated; only
+// its hash is stored.
+type Hook struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Disabled    bool           `json:"disabled,omitempty"`
+	TokenHash   string         `json:"token_hash,omitempty"`
+	Secret      string         `json:"secret,omitempty"` // Optional HMAC-SHA256 key for signed payloads
+	Nodes       []NodeTemplate `json:"nodes,omitempty"`
+	Links       []LinkTemplate `json:"links,omitempty"`
+	Created     time.Time      `json:"created"`
+	Modified    time.Time      `json:"modified"`
+}
+
+// NodeTemplate creates (or updates) a node per payload, or per element of
+// the array at ForEach. ID, Type, Content, When and string Meta values are
+// Go text/template strings; see Apply for the data they see.
+type NodeTemplate struct {
+	ForEach string                 `json:"for_each,omitempty"` // Dotted path to an array in the payload
+	When    string                 `json:"when,omitempty"`     // Skip unless this renders non-empty and not "false"
+	ID      string                 `json:"id"`
+	Type    string                 `json:"type"`
+	Content string                 `json:"content,omitempty"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+// LinkTemplate creates a link per payload, or per element of ForEach
+type LinkTemplate struct {
+	ForEach string                 `json:"for_each,omitempty"`
+	When    string                 `json:"when,omitempty"`
+	Source  string                 `json:"source"`
+	Target  string                 `json:"target"`
+	Type    string                 `json:"type"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Validate checks a hook's ID and that its templates parse
+func (h *Hook) Validate() error {
+	if !validID.MatchString(h.ID) {
+		return fmt.Errorf("invalid id %q (use 1-64 letters, digits, _ or -)", h.ID)
+	}
+	if len(h.Nodes) == 0 && len(h.Links) == 0 {
+		return fmt.Errorf("a hook needs at least one node or link template")
+	}
+	for i, n := range h.Nodes {
+		if n.ID == "" || n.Type == "" {
+			return fmt.Errorf("nodes[%d]: id and type are required", i)
+		}
+		for field, text := range map[string]string{"id": n.ID, "type": n.Type, "content": n.Content, "when": n.When} {
+			if _, err := parseTemplate(text); err != nil {
+				return fmt.Errorf("nodes[%d].%s: %w", i, field, err)
+			}
+		}
+		if err := validateMeta(n.Meta); err != nil {
+			return fmt.Errorf("nodes[%d].%w", i, err)
+		}
+	}
+	for i, l := range h.Links {
+		if l.Source == "" || l.Target == "" || l.Type == "" {
+			return fmt.Errorf("links[%d]: source, target and type are required", i)
+		}
+		if !strings.Contains(l.Type, "{{") && !validLinkType.MatchString(l.Type) {
+			return fmt.Errorf("links[%d]: invalid link type %q", i, l.Type)
+		}
+		for field, text := range map[string]string{"source": l.Source, "target": l.Target, "type": l.Type, "when": l.When} {
+			if _, err := parseTemplate(text); err != nil {
+				return fmt.Errorf("links[%d].%s: %w", i, field, err)
+			}
+		}
+		if err := validateMeta(l.Meta); err != nil {
+			return fmt.Errorf("links[%d].%w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateMeta(meta map[string]interface{}) error {
+	for key, v := range meta {
+		if text, ok := v.(string); ok {
+			if _, err := parseTemplate(text); err != nil {
+				return fmt.Errorf("meta.%s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewToken returns a random URL token and the hash stored for it
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = "mh_" + hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the stored form of a URL token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks a payload's signature when the hook has a secret. The
+// signature is hex HMAC-SHA256 of the body, optionally prefixed "sha256="
+// as GitHub sends it in X-Hub-Signature-256.
+func (h *Hook) Verify(body []byte, signature string) error {
+	if h.Secret == "" {
+		return nil
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || len(got) == 0 {
+		return ErrUnauthorized
+	}
+	mac := hmac.New(sha256.New, []byte(h.Secret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// Save validates and stores a new hook with a fresh token, generating an ID
+// if needed; the token is returned for the caller to hand out
+func Save(ctx context.Context, repo graph.Repository, h *Hook) (string, error) {
+	if h.ID == "" {
+		h.ID = uuid.New().String()
+	}
+	token, hash, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+	h.TokenHash = hash
+	now := time.Now()
+	h.Created, h.Modified = now, now
+	return token, Restore(ctx, repo, h)
+}
+
+// Restore validates and stores a hook from a backup, keeping its ID, token
+// and timestamps
+func Restore(ctx context.Context, repo graph.Repository, h *Hook) error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	meta, err := toMeta(h)
+	if err != nil {
+		return err
+	}
+	return repo.CreateNode(ctx, &core.Node{ID: idPrefix + h.ID, Type: NodeType, Meta: meta, Created: h.Created, Modified: h.Modified})
+}
+
+// Update validates and replaces a hook's definition, keeping its token.
+// An empty Secret keeps the current one unless clearSecret is set.
+func Update(ctx context.Context, repo graph.Repository, h *Hook, clearSecret bool) error {
+	existing, err := Get(ctx, repo, h.ID)
+	if err != nil {
+		return err
+	}
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	h.TokenHash = existing.TokenHash
+	if h.Secret == "" && !clearSecret {
+		h.Secret = existing.Secret
+	}
+	h.Created, h.Modified = existing.Created, time.Now()
+	return write(ctx, repo, h)
+}
+
+// RotateToken gives a hook a new token, returned for the caller to hand
+// out; the old URL stops working
+func RotateToken(ctx context.Context, repo graph.Repository, id string) (string, error) {
+	h, err := Get(ctx, repo, id)
+	if err != nil {
+		return "", err
+	}
+	token, hash, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+	h.TokenHash, h.Modified = hash, time.Now()
+	return token, write(ctx, repo, h)
+}
+
+// write replaces a stored hook's properties
+func write(ctx context.Context, repo graph.Repository, h *Hook) error {
+	meta, err := toMeta(h)
+	if err != nil {
+		return err
+	}
+	// Absent optional fields are cleared rather than left from the old version
+	for _, key := range []string{"name", "description", "disabled", "secret", "nodes", "links"} {
+		if _, ok := meta[key]; !ok {
+			meta[key] = nil
+		}
+	}
+	return repo.UpdateNodeMeta(ctx, idPrefix+h.ID, meta)
+}
+
+// Get loads a hook
+func Get(ctx context.Context, repo graph.Repository, id string) (*Hook, error) {
+	node, err := repo.GetNode(ctx, idPrefix+id)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return fromNode(node)
+}
+
+// ByToken finds the enabled hook a URL token belongs to
+func ByToken(ctx context.Context, repo graph.Repository, token string) (*Hook, error) {
+	hash := HashToken(token)
+	all, err := List(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range all {
+		if !h.Disabled && hmac.Equal([]byte(h.TokenHash), []byte(hash)) {
+			return h, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns all hooks
+func List(ctx context.Context, repo graph.Repository) ([]*Hook, error) {
+	const pageSize = 500
+	out := []*Hook{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			h, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			out = append(out, h)
+		}
+		if len(nodes) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+// Delete removes a hook and its history
+func Delete(ctx context.Context, repo graph.Repository, id string) error {
+	if _, err := Get(ctx, repo, id); err != nil {
+		return err
+	}
+	return repo.DeleteNode(ctx, idPrefix+id, true)
+}
+
+// toMeta stores a hook's fields as node properties
+func toMeta(h *Hook) (map[string]interface{}, error) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	delete(meta, "id")
+	return meta, nil
+}
+
+// fromNode reads a hook back from its node
+func fromNode(node *core.Node) (*Hook, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var h Hook
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("corrupt hook %s: %w", node.ID, err)
+	}
+	h.ID = strings.TrimPrefix(node.ID, idPrefix)
+	return &h, nil
+}
@@ -0,0 +1,175 @@
+package hooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// githubHook maps a GitHub push event to a Repo node and a Commit node per
+// commit, linked to the repo
+func githubHook() *Hook {
+	return &Hook{
+		ID:   "github",
+		Name: "GitHub pushes",
+		Nodes: []NodeTemplate{
+			{ID: "repo:{{slug .payload.repository.full_name}}", Type: "Repo", Meta: map[string]interface{}{"title": "{{.payload.repository.full_name}}"}},
+			{
+				ForEach: "commits",
+				When:    `{{ne .item.message ""}}`,
+				ID:      "commit:{{.item.id}}",
+				Type:    "Commit",
+				Content: "{{.item.message}}",
+				Meta:    map[string]interface{}{"author": `{{get .item "author.name"}}`, "event": `{{index .headers "X-Github-Event"}}`, "source": "github", "weight": 2.0},
+			},
+		},
+		Links: []LinkTemplate{
+			{ForEach: "commits", When: `{{ne .item.message ""}}`, Source: "commit:{{.item.id}}", Target: "repo:{{slug .payload.repository.full_name}}", Type: "IN_REPO"},
+		},
+	}
+}
+
+func pushPayload(t *testing.T) interface{} {
+	var payload interface{}
+	err := json.Unmarshal([]byte(`{
+		"repository": {"full_name": "systemshift/Memex"},
+		"commits": [
+			{"id": "abc", "message": "Fix it", "author": {"name": "Ada"}},
+			{"id": "def", "message": ""},
+			{"id": "ghi", "message": "Add it"}
+		]
+	}`), &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	hook := githubHook()
+	token, err := Save(ctx, repo, hook)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	found, err := ByToken(ctx, repo, token)
+	if err != nil || found.ID != "github" {
+		t.Fatalf("ByToken() = %v, %v", found, err)
+	}
+	if _, err := ByToken(ctx, repo, "mh_wrong"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ByToken() wrong token error = %v", err)
+	}
+
+	req := Request{Payload: pushPayload(t), Headers: http.Header{"X-Github-Event": {"push"}}, Received: time.Now()}
+
+	dry := found.Apply(ctx, repo, req, true)
+	if len(dry.Nodes) != 3 || len(dry.Links) != 2 || dry.NodesCreated != 0 {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	if _, err := repo.GetNode(ctx, "repo:systemshift-memex"); err == nil {
+		t.Fatal("dry run wrote a node")
+	}
+
+	res := found.Apply(ctx, repo, req, false)
+	if res.NodesCreated != 3 || res.LinksCreated != 2 || len(res.Errors) != 0 {
+		t.Fatalf("Apply() = %+v", res)
+	}
+	commit, err := repo.GetNode(ctx, "commit:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(commit.Content) != "Fix it" || commit.Meta["author"] != "Ada" || commit.Meta["event"] != "push" ||
+		commit.Meta["weight"] != 2.0 || commit.Meta["connector"] != "hook:github" {
+		t.Errorf("commit = %+v", commit)
+	}
+	if c, _ := repo.GetNode(ctx, "commit:ghi"); c == nil || c.Meta["author"] != nil {
+		t.Errorf("commit without author = %+v", c)
+	}
+
+	// The same push again updates the nodes instead of failing
+	again := found.Apply(ctx, repo, req, false)
+	if again.NodesCreated != 0 || again.NodesUpdated != 3 || again.LinksCreated != 0 || len(again.Errors) != 0 {
+		t.Errorf("Apply() again = %+v", again)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	hook := &Hook{Secret: "s3cret"}
+	body := []byte(`{"a":1}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	if err := hook.Verify(body, "sha256="+sig); err != nil {
+		t.Errorf("Verify() GitHub style error = %v", err)
+	}
+	if err := hook.Verify(body, sig); err != nil {
+		t.Errorf("Verify() bare error = %v", err)
+	}
+	if err := hook.Verify([]byte(`{"a":2}`), sig); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Verify() tampered error = %v", err)
+	}
+	if err := hook.Verify(body, ""); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Verify() unsigned error = %v", err)
+	}
+	if err := (&Hook{}).Verify(body, ""); err != nil {
+		t.Errorf("Verify() without secret error = %v", err)
+	}
+}
+
+func TestUpdateAndRotate(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	hook := githubHook()
+	hook.Secret = "s3cret"
+	token, err := Save(ctx, repo, hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := githubHook()
+	changed.Name = ""
+	changed.Links = nil
+	if err := Update(ctx, repo, changed, false); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := Get(ctx, repo, "github")
+	if err != nil || got.Name != "" || len(got.Links) != 0 || got.Secret != "s3cret" {
+		t.Errorf("Get() after update = %+v, %v", got, err)
+	}
+	if _, err := ByToken(ctx, repo, token); err != nil {
+		t.Errorf("token stopped working after update: %v", err)
+	}
+
+	fresh, err := RotateToken(ctx, repo, "github")
+	if err != nil {
+		t.Fatalf("RotateToken() error = %v", err)
+	}
+	if _, err := ByToken(ctx, repo, token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("old token still works: %v", err)
+	}
+	if _, err := ByToken(ctx, repo, fresh); err != nil {
+		t.Errorf("new token error = %v", err)
+	}
+
+	bad := githubHook()
+	bad.Nodes[0].ID = "{{.payload"
+	if err := Update(ctx, repo, bad, false); err == nil {
+		t.Error("Update() accepted a broken template")
+	}
+	if err := Delete(ctx, repo, "github"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := Get(ctx, repo, "github"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after delete error = %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxItems caps the elements one for_each expands to
+const maxItems = 1000
+
+// templateFuncs are available in mapping templates
+var templateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"slug":  slug,
+	"get":   lookup, // Dotted path that yields nothing, not an error, when a level is missing
+	"sha256": func(v interface{}) string {
+		sum := sha256.Sum256([]byte(fmt.Sprint(v)))
+		return hex.EncodeToString(sum[:])
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug lowercases s and joins its words with hyphens, for readable IDs
+func slug(v interface{}) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(fmt.Sprint(v)), "-"), "-")
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("hook").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// Request is a payload posted to a hook
+type Request struct {
+	Payload  interface{} // Decoded JSON body
+	Headers  http.Header
+	Received time.Time
+}
+
+// Result reports what a payload created; with dry runs, what it would
+type Result struct {
+	NodesCreated int          `json:"nodes_created"`
+	NodesUpdated int          `json:"nodes_updated"`
+	LinksCreated int          `json:"links_created"`
+	Nodes        []*core.Node `json:"nodes,omitempty"` // Dry runs only
+	Links        []*core.Link `json:"links,omitempty"` // Dry runs only
+	Errors       []string     `json:"errors,omitempty"`
+}
+
+// Apply renders the hook's templates against a payload and writes the
+// nodes and links. Templates see .payload (the JSON body), .item (the
+// for_each element), .headers (first value of each header, canonical
+// names), .hook (the hook ID) and .received (RFC 3339). A node whose ID
+// exists has its properties updated. A template that fails is reported in
+// Errors without stopping the rest. With dryRun nothing is written.
+func (h *Hook) Apply(ctx context.Context, repo graph.Repository, req Request, dryRun bool) *Result {
+	res := &Result{}
+	headers := make(map[string]string, len(req.Headers))
+	for name, values := range req.Headers {
+		if len(values) > 0 {
+			headers[name] = values[0]
+		}
+	}
+	base := map[string]interface{}{
+		"payload":  req.Payload,
+		"headers":  headers,
+		"hook":     h.ID,
+		"received": req.Received.UTC().Format(time.RFC3339),
+	}
+
+	for i, t := range h.Nodes {
+		for j, data := range expand(base, req.Payload, t.ForEach) {
+			where := fmt.Sprintf("nodes[%d]", i)
+			if t.ForEach != "" {
+				where = fmt.Sprintf("nodes[%d][%d]", i, j)
+			}
+			node, ok, err := renderNode(t, data, h.ID, req.Received)
+			if err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", where, err))
+				continue
+			}
+			if !ok {
+				continue
+			}
+			if dryRun {
+				res.Nodes = append(res.Nodes, node)
+				continue
+			}
+			if err := upsert(ctx, repo, node, h.ID, res); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %s: %v", where, node.ID, err))
+			}
+		}
+	}
+
+	for i, t := range h.Links {
+		for j, data := range expand(base, req.Payload, t.ForEach) {
+			where := fmt.Sprintf("links[%d]", i)
+			if t.ForEach != "" {
+				where = fmt.Sprintf("links[%d][%d]", i, j)
+			}
+			link, ok, err := renderLink(t, data, req.Received)
+			if err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", where, err))
+				continue
+			}
+			if !ok {
+				continue
+			}
+			if dryRun {
+				res.Links = append(res.Links, link)
+				continue
+			}
+			if linked(ctx, repo, link) {
+				continue // Redelivered payload
+			}
+			if err := repo.CreateLink(ctx, link); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("%s: %s -[%s]-> %s: %v", where, link.Source, link.Type, link.Target, err))
+				continue
+			}
+			res.LinksCreated++
+		}
+	}
+	return res
+}
+
+// upsert creates node, or updates the properties of the node with its ID
+func upsert(ctx context.Context, repo graph.Repository, node *core.Node, hookID string, res *Result) error {
+	if _, err := repo.GetNode(ctx, node.ID); err == nil {
+		if err := repo.UpdateNodeMetaWithNote(ctx, node.ID, node.Meta, "Updated by hook "+hookID, idPrefix+hookID); err != nil {
+			return err
+		}
+		res.NodesUpdated++
+		return nil
+	}
+	if err := repo.CreateNode(ctx, node); err != nil {
+		return err
+	}
+	res.NodesCreated++
+	return nil
+}
+
+// linked reports whether link already exists
+func linked(ctx context.Context, repo graph.Repository, link *core.Link) bool {
+	links, err := repo.GetLinks(ctx, link.Source)
+	if err != nil {
+		return false
+	}
+	for _, l := range links {
+		if l.Target == link.Target && l.Type == link.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// expand returns the template data for each rendering: once, or once per
+// element of the array at path
+func expand(base map[string]interface{}, payload interface{}, path string) []map[string]interface{} {
+	if path == "" {
+		return []map[string]interface{}{base}
+	}
+	items, _ := lookup(payload, path).([]interface{})
+	if len(items) > maxItems {
+		items = items[:maxItems]
+	}
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		data := make(map[string]interface{}, len(base)+1)
+		for k, v := range base {
+			data[k] = v
+		}
+		data["item"] = item
+		out = append(out, data)
+	}
+	return out
+}
+
+// lookup follows a dotted path of object keys through a decoded payload
+func lookup(v interface{}, path string) interface{} {
+	for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
+
+func renderNode(t NodeTemplate, data map[string]interface{}, hookID string, now time.Time) (*core.Node, bool, error) {
+	if ok, err := when(t.When, data); err != nil || !ok {
+		return nil, false, err
+	}
+	var fields [3]string
+	for i, text := range []string{t.ID, t.Type, t.Content} {
+		var err error
+		if fields[i], err = render(text, data); err != nil {
+			return nil, false, err
+		}
+	}
+	id, typ, content := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1]), fields[2]
+	if id == "" || typ == "" {
+		return nil, false, errors.New("id or type rendered empty")
+	}
+	meta, err := renderMeta(t.Meta, data)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, ok := meta["connector"]; !ok {
+		meta["connector"] = idPrefix + hookID
+	}
+	node := &core.Node{ID: id, Type: typ, Meta: meta, Created: now, Modified: now}
+	if content != "" {
+		node.Content = []byte(content)
+	}
+	return node, true, nil
+}
+
+func renderLink(t LinkTemplate, data map[string]interface{}, now time.Time) (*core.Link, bool, error) {
+	if ok, err := when(t.When, data); err != nil || !ok {
+		return nil, false, err
+	}
+	var fields [3]string
+	for i, text := range []string{t.Source, t.Target, t.Type} {
+		s, err := render(text, data)
+		if err != nil {
+			return nil, false, err
+		}
+		fields[i] = strings.TrimSpace(s)
+	}
+	if fields[0] == "" || fields[1] == "" {
+		return nil, false, errors.New("source or target rendered empty")
+	}
+	if !validLinkType.MatchString(fields[2]) {
+		return nil, false, fmt.Errorf("invalid link type %q", fields[2])
+	}
+	meta, err := renderMeta(t.Meta, data)
+	if err != nil {
+		return nil, false, err
+	}
+	return &core.Link{Source: fields[0], Target: fields[1], Type: fields[2], Meta: meta, Created: now, Modified: now}, true, nil
+}
+
+// renderMeta renders string values as templates; other JSON values are
+// stored as given, and keys that render empty are left out
+func renderMeta(tmpl map[string]interface{}, data map[string]interface{}) (map[string]interface{}, error) {
+	meta := make(map[string]interface{}, len(tmpl))
+	for key, v := range tmpl {
+		text, ok := v.(string)
+		if !ok {
+			meta[key] = v
+			continue
+		}
+		s, err := render(text, data)
+		if err != nil {
+			return nil, fmt.Errorf("meta.%s: %w", key, err)
+		}
+		if s != "" {
+			meta[key] = s
+		}
+	}
+	return meta, nil
+}
+
+// when reports whether a condition template passes; empty always does
+func when(text string, data map[string]interface{}) (bool, error) {
+	if text == "" {
+		return true, nil
+	}
+	s, err := render(text, data)
+	if err != nil {
+		return false, fmt.Errorf("when: %w", err)
+	}
+	s = strings.TrimSpace(s)
+	return s != "" && s != "false", nil
+}
+
+func render(text string, data map[string]interface{}) (string, error) {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	// Missing keys in maps print as "<no value>" even with missingkey=zero
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
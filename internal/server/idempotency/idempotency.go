@@ -0,0 +1,245 @@
+// Package idempotency lets clients retry mutating requests safely. A
+// request carrying an Idempotency-Key header runs once; retries with the
+// same key get the stored response back instead of repeating the write.
+// Responses live in memory until they expire, so a restart forgets them.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header names
+const (
+	KeyHeader      = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// Defaults for NewStore
+const (
+	DefaultTTL        = 24 * time.Hour
+	DefaultMaxEntries = 10000
+	MaxKeyLength      = 255
+)
+
+// Response is a stored response, replayed for retries
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// entry is one key's request fingerprint and, once it has finished, response
+type entry struct {
+	key         string
+	fingerprint string
+	response    *Response // nil while the first request is still running
+	expires     time.Time
+}
+
+// Store holds responses by key. Keys are scoped to the caller's API key,
+// so two clients reusing a key don't see each other's responses.
+type Store struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	order   []*entry // Oldest first; entries since replaced or dropped are skipped
+}
+
+// NewStore creates a store keeping responses for ttl, at most max at once
+func NewStore(ttl time.Duration, max int) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if max <= 0 {
+		max = DefaultMaxEntries
+	}
+	return &Store{ttl: ttl, max: max, entries: make(map[string]*entry)}
+}
+
+// Middleware applies idempotency keys to POST, PUT, PATCH and DELETE
+// requests. A retry with the same key and request gets the original
+// response with Idempotent-Replayed: true. Reusing a key for a different
+// request is rejected with 422, and a retry that arrives while the first
+// request is still running with 409. Server errors are not stored, so the
+// client can retry them.
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(KeyHeader)
+		if key == "" || !mutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > MaxKeyLength {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		e, existing := s.begin(scope(r, key), fingerprint(r, body), time.Now())
+		if existing != nil {
+			switch {
+			case existing.fingerprint != e.fingerprint:
+				http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+			case existing.response == nil:
+				http.Error(w, "a request with this Idempotency-Key is still in progress", http.StatusConflict)
+			default:
+				replay(w, existing.response)
+			}
+			return
+		}
+
+		// A panicking or failed request leaves the key free for a retry
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		stored := false
+		defer func() {
+			if !stored {
+				s.forget(e)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+		if rec.status < http.StatusInternalServerError {
+			if !rec.wroteHeader {
+				rec.header = w.Header().Clone()
+			}
+			s.finish(e, &Response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()})
+			stored = true
+		}
+	})
+}
+
+// begin claims key for a new request, or returns the entry already holding it
+func (s *Store) begin(key, fp string, now time.Time) (*entry, *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+
+	e := &entry{key: key, fingerprint: fp, expires: now.Add(s.ttl)}
+	if existing := s.entries[key]; existing != nil {
+		copied := *existing
+		return e, &copied
+	}
+	s.entries[key] = e
+	s.order = append(s.order, e)
+	return e, nil
+}
+
+// finish stores the response for a claimed key
+func (s *Store) finish(e *entry, response *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.response = response
+}
+
+// forget releases a claimed key without storing a response
+func (s *Store) forget(e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries[e.key] == e {
+		delete(s.entries, e.key)
+	}
+}
+
+// Len returns how many keys are held
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// prune drops expired entries, then the oldest finished ones over the limit
+func (s *Store) prune(now time.Time) {
+	live := s.order[:0]
+	over := len(s.entries) - s.max + 1
+	for _, e := range s.order {
+		if s.entries[e.key] != e {
+			continue
+		}
+		if !now.Before(e.expires) || (over > 0 && e.response != nil) {
+			delete(s.entries, e.key)
+			over--
+			continue
+		}
+		live = append(live, e)
+	}
+	clear(s.order[len(live):])
+	s.order = live
+}
+
+// mutating reports whether requests with method change the graph
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// scope combines the key with the caller's API key, if any
+func scope(r *http.Request, key string) string {
+	caller := r.Header.Get("X-API-Key")
+	if caller == "" {
+		caller = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	sum := sha256.Sum256([]byte(caller + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprint identifies a request by method, URL and body
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes a stored response
+func replay(w http.ResponseWriter, response *Response) {
+	for name, values := range response.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(response.Status)
+	w.Write(response.Body)
+}
+
+// recorder passes a response through while keeping a copy
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+	rec.header = rec.ResponseWriter.Header().Clone()
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,116 @@
+package idempotency
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	calls := 0
+	status := http.StatusCreated
+	handler := NewStore(time.Hour, 0).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Location", "/api/nodes/"+string(body))
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "call %d", calls)
+	}))
+	do := func(method, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/nodes", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(KeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do(http.MethodPost, "k1", "a")
+	retry := do(http.MethodPost, "k1", "a")
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() ||
+		retry.Header().Get("Location") != "/api/nodes/a" || retry.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("replay = %d %q %v", retry.Code, retry.Body.String(), retry.Header())
+	}
+	if first.Header().Get(ReplayedHeader) != "" {
+		t.Error("first response marked as replayed")
+	}
+
+	if w := do(http.MethodPost, "k1", "b"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused for another body: %d", w.Code)
+	}
+	do(http.MethodPost, "", "a")
+	do(http.MethodGet, "k2", "")
+	do(http.MethodGet, "k2", "")
+	if calls != 4 {
+		t.Errorf("requests without a key or not mutating: %d calls, want 4", calls)
+	}
+
+	// Server errors leave the key free for a retry
+	status = http.StatusInternalServerError
+	do(http.MethodPost, "k3", "c")
+	status = http.StatusCreated
+	if w := do(http.MethodPost, "k3", "c"); w.Code != http.StatusCreated || calls != 6 {
+		t.Errorf("retry after a server error = %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestInFlightAndScope(t *testing.T) {
+	store := NewStore(time.Hour, 0)
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") == "slow" {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	do := func(apiKey string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/links", nil)
+		req.Header.Set(KeyHeader, "same")
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	done := make(chan int)
+	go func() { done <- do("slow") }()
+	<-started
+	if code := do("slow"); code != http.StatusConflict {
+		t.Errorf("retry while running = %d, want 409", code)
+	}
+	if code := do("other"); code != http.StatusNoContent {
+		t.Errorf("same key from another caller = %d", code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusNoContent {
+		t.Errorf("first request = %d", code)
+	}
+	if store.Len() != 2 {
+		t.Errorf("%d keys held, want 2", store.Len())
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	store := NewStore(time.Minute, 2)
+	now := time.Now()
+	for _, key := range []string{"a", "b", "c"} {
+		e, _ := store.begin(key, "fp", now)
+		store.finish(e, &Response{Status: http.StatusOK})
+	}
+	// The oldest finished entry makes room for the newest
+	if _, existing := store.begin("a", "fp", now); existing != nil || store.Len() != 2 {
+		t.Errorf("over the limit: a still held, %d keys", store.Len())
+	}
+	if _, existing := store.begin("c", "fp", now.Add(2*time.Minute)); existing != nil {
+		t.Error("expired entry replayed")
+	}
+}
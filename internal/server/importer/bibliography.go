@@ -0,0 +1,335 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/citations"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Node and link types created by bibliography imports. Papers use
+// citations.PaperType and its IDs, so imported papers and works known from
+// parsed citations are the same nodes.
+const (
+	AuthorType        = "Author"
+	VenueType         = "Venue"
+	AuthoredLink      = "AUTHORED"       // Author to paper, with the author's position
+	PublishedInLink   = "PUBLISHED_IN"   // Paper to venue
+	HasAttachmentLink = "HAS_ATTACHMENT" // Paper to the Source node holding a file
+)
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Work is a bibliography entry from a reference manager
+type Work struct {
+	Key         string       `json:"key"`  // Citation key or reference manager item key
+	Kind        string       `json:"kind"` // Entry type, e.g. article or inproceedings
+	Title       string       `json:"title"`
+	Authors     []string     `json:"authors,omitempty"` // "First Last"
+	Year        int          `json:"year,omitempty"`
+	Venue       string       `json:"venue,omitempty"` // Journal, proceedings or publisher
+	DOI         string       `json:"doi,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Abstract    string       `json:"abstract,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	Files       []string     `json:"files,omitempty"` // Attachment paths named by the entry
+	Attachments []Attachment `json:"-"`               // File contents to store
+}
+
+// Attachment is a file stored with a work, usually its PDF
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// BibliographyOptions controls a bibliography import
+type BibliographyOptions struct {
+	Connector string // Recorded on nodes, e.g. bibtex or zotero
+	ChangedBy string // Recorded on updates (default <connector>-import)
+}
+
+// EntryError reports an entry that could not be imported
+type EntryError struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// BibliographyResult summarizes a bibliography import
+type BibliographyResult struct {
+	Created     int          `json:"created"` // Papers
+	Updated     int          `json:"updated"`
+	Unchanged   int          `json:"unchanged"`
+	Authors     int          `json:"authors"` // Author nodes created
+	Venues      int          `json:"venues"`
+	Attachments int          `json:"attachments"` // Source nodes created for files
+	Links       int          `json:"links"`
+	Errors      []EntryError `json:"errors,omitempty"`
+}
+
+// ImportWorks creates or updates a Paper node per work, with Author and
+// Venue nodes linked by AUTHORED and PUBLISHED_IN. Attachments are stored
+// as content-addressed Source nodes linked by HAS_ATTACHMENT, sharing the
+// content store with ingested sources. Papers first seen as citation stubs
+// are filled in. Re-importing the same works leaves the graph unchanged.
+func ImportWorks(ctx context.Context, repo graph.Repository, works []Work, opts BibliographyOptions) (*BibliographyResult, error) {
+	if opts.Connector == "" {
+		return nil, errors.New("connector is required")
+	}
+	if opts.ChangedBy == "" {
+		opts.ChangedBy = opts.Connector + "-import"
+	}
+
+	state := &bibImport{
+		repo:    repo,
+		opts:    opts,
+		result:  &BibliographyResult{},
+		created: make(map[string]bool),
+		linked:  make(map[[3]string]bool),
+	}
+	for _, w := range works {
+		if err := state.work(ctx, w); err != nil {
+			state.result.Errors = append(state.result.Errors, EntryError{Key: w.Key, Error: err.Error()})
+		}
+	}
+	if err := state.flush(ctx); err != nil {
+		return state.result, err
+	}
+	return state.result, nil
+}
+
+// bibImport accumulates new nodes and links so they can be written in bulk
+type bibImport struct {
+	repo   graph.Repository
+	opts   BibliographyOptions
+	result *BibliographyResult
+
+	nodes   []*core.Node
+	created map[string]bool
+	links   []*core.Link
+	linked  map[[3]string]bool // Links existing or queued
+}
+
+// work applies one entry; new nodes and links are queued for flush
+func (s *bibImport) work(ctx context.Context, w Work) error {
+	w.Title = strings.TrimSpace(w.Title)
+	if w.Title == "" {
+		return errors.New("missing title")
+	}
+	id := citations.PaperID(citations.Reference{Title: w.Title, DOI: w.DOI})
+	if id == "" {
+		return fmt.Errorf("title %q has no usable characters", w.Title)
+	}
+
+	meta := workMeta(w, s.opts.Connector)
+	if s.created[id] {
+		return nil // Duplicate entry within the import
+	}
+	if existing, err := s.repo.GetNode(ctx, id); err != nil {
+		s.queueNode(id, citations.PaperType, meta)
+		s.result.Created++
+	} else if existing.Type != citations.PaperType {
+		return fmt.Errorf("%s exists with type %s", id, existing.Type)
+	} else {
+		if err := s.loadLinks(ctx, id); err != nil {
+			return err
+		}
+		changed := make(map[string]any)
+		for k, v := range meta {
+			if !sameValue(existing.Meta[k], v) {
+				changed[k] = v
+			}
+		}
+		if stub, _ := existing.Meta["citation_stub"].(bool); stub {
+			changed["citation_stub"] = false
+		}
+		if len(changed) == 0 {
+			s.result.Unchanged++
+		} else {
+			if err := s.repo.UpdateNodeMetaWithNote(ctx, id, changed, "Bibliography import", s.opts.ChangedBy); err != nil {
+				return fmt.Errorf("failed to update %s: %w", id, err)
+			}
+			s.result.Updated++
+		}
+	}
+
+	for i, name := range w.Authors {
+		authorID, err := s.ensure(ctx, "author:", AuthorType, name)
+		if err != nil {
+			return err
+		}
+		if authorID != "" {
+			s.queueLink(authorID, id, AuthoredLink, map[string]interface{}{"position": i + 1})
+		}
+	}
+	if w.Venue != "" {
+		venueID, err := s.ensure(ctx, "venue:", VenueType, w.Venue)
+		if err != nil {
+			return err
+		}
+		if venueID != "" {
+			s.queueLink(id, venueID, PublishedInLink, nil)
+		}
+	}
+	for _, a := range w.Attachments {
+		sourceID, err := s.attach(ctx, a)
+		if err != nil {
+			return err
+		}
+		s.queueLink(id, sourceID, HasAttachmentLink, map[string]interface{}{"filename": a.Name})
+	}
+	return nil
+}
+
+// workMeta builds a paper's properties from a work
+func workMeta(w Work, connector string) map[string]any {
+	meta := map[string]any{"title": w.Title, "connector": connector}
+	set := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			meta[key] = value
+		}
+	}
+	set("entry_type", w.Kind)
+	set("venue", w.Venue)
+	set("doi", w.DOI)
+	set("url", w.URL)
+	set("abstract", w.Abstract)
+	set(connector+"_key", w.Key)
+	if len(w.Authors) > 0 {
+		meta["authors"] = w.Authors
+	}
+	if w.Year > 0 {
+		meta["year"] = w.Year
+	}
+	if len(w.Tags) > 0 {
+		meta["tags"] = w.Tags
+	}
+	return meta
+}
+
+// ensure returns the ID of the node named name under prefix, queueing it
+// when it does not exist yet; names without letters or digits are skipped
+func (s *bibImport) ensure(ctx context.Context, prefix, nodeType, name string) (string, error) {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return "", nil
+	}
+	id := prefix + slug
+	if s.created[id] {
+		return id, nil
+	}
+	if existing, err := s.repo.GetNode(ctx, id); err == nil {
+		if existing.Type != nodeType {
+			return "", fmt.Errorf("%s exists with type %s", id, existing.Type)
+		}
+		return id, nil
+	}
+	s.queueNode(id, nodeType, map[string]any{"name": strings.TrimSpace(name)})
+	if nodeType == AuthorType {
+		s.result.Authors++
+	} else {
+		s.result.Venues++
+	}
+	return id, nil
+}
+
+// attach returns the Source node ID for a file, queueing the node when the
+// content is new
+func (s *bibImport) attach(ctx context.Context, a Attachment) (string, error) {
+	hash := sha256.Sum256(a.Data)
+	id := "sha256:" + hex.EncodeToString(hash[:])
+	if s.created[id] {
+		return id, nil
+	}
+	if _, err := s.repo.GetNode(ctx, id); err == nil {
+		return id, nil
+	}
+
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/pdf"
+	}
+	meta := map[string]any{
+		"format":       "pdf",
+		"content_type": contentType,
+		"filename":     a.Name,
+		"connector":    s.opts.Connector,
+		"ingested_at":  time.Now().Format(time.RFC3339),
+		"size_bytes":   len(a.Data),
+	}
+	if contentType != "application/pdf" {
+		meta["format"] = "binary"
+	}
+	node := s.queueNode(id, "Source", meta)
+	node.Content = a.Data
+	s.result.Attachments++
+	return id, nil
+}
+
+func (s *bibImport) queueNode(id, nodeType string, meta map[string]any) *core.Node {
+	now := time.Now()
+	node := &core.Node{ID: id, Type: nodeType, Meta: meta, Created: now, Modified: now}
+	s.nodes = append(s.nodes, node)
+	s.created[id] = true
+	return node
+}
+
+// loadLinks records an existing paper's authorship, venue and attachment
+// links so re-imports do not duplicate them
+func (s *bibImport) loadLinks(ctx context.Context, paperID string) error {
+	outgoing, err := s.repo.GetLinks(ctx, paperID)
+	if err != nil {
+		return fmt.Errorf("failed to get links for %s: %w", paperID, err)
+	}
+	incoming, err := s.repo.GetBacklinks(ctx, paperID)
+	if err != nil {
+		return fmt.Errorf("failed to get backlinks for %s: %w", paperID, err)
+	}
+	for _, l := range append(outgoing, incoming...) {
+		s.linked[[3]string{l.Source, l.Target, l.Type}] = true
+	}
+	return nil
+}
+
+func (s *bibImport) queueLink(source, target, linkType string, meta map[string]interface{}) {
+	key := [3]string{source, target, linkType}
+	if s.linked[key] {
+		return
+	}
+	s.linked[key] = true
+	now := time.Now()
+	s.links = append(s.links, &core.Link{Source: source, Target: target, Type: linkType, Meta: meta, Created: now, Modified: now})
+}
+
+// flush creates queued nodes, then queued links
+func (s *bibImport) flush(ctx context.Context) error {
+	if len(s.nodes) > 0 {
+		if err := s.repo.CreateNodes(ctx, s.nodes); err != nil {
+			return fmt.Errorf("failed to create nodes: %w", err)
+		}
+	}
+	if len(s.links) > 0 {
+		if err := s.repo.CreateLinks(ctx, s.links); err != nil {
+			return fmt.Errorf("failed to create links: %w", err)
+		}
+		s.result.Links = len(s.links)
+	}
+	return nil
+}
+
+// sameValue compares property values by their JSON form, since stored
+// values come back with JSON types (numbers as float64, lists as []any)
+func sameValue(a, b interface{}) bool {
+	x, err1 := json.Marshal(a)
+	y, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(x) == string(y)
+}
@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+const sampleBib = `@string{neurips = "Advances in Neural Information Processing Systems"}
+
+@comment{exported by hand}
+
+@inproceedings{vaswani2017,
+  title     = {Attention Is {All} You Need},
+  author    = {Vaswani, Ashish and Shazeer, Noam and G{\'o}mez, Aidan N.},
+  booktitle = neurips,
+  year      = 2017,
+  file      = {Full Text:papers/attention.pdf:application/pdf},
+  keywords  = {transformers, attention}
+}
+
+@article{devlin2019,
+  title   = "{BERT}: Pre-training of Deep Bidirectional Transformers",
+  author  = "Jacob Devlin and Ming-Wei Chang",
+  journal = {NAACL} # { 2019},
+  doi     = {https://doi.org/10.18653/v1/N19-1423},
+  date    = {2019-06-02}
+}
+`
+
+func TestParseBibTeX(t *testing.T) {
+	works, err := ParseBibTeX(strings.NewReader(sampleBib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works) != 2 {
+		t.Fatalf("parsed %d entries, want 2", len(works))
+	}
+
+	w := works[0]
+	if w.Key != "vaswani2017" || w.Kind != "inproceedings" || w.Title != "Attention Is All You Need" || w.Year != 2017 {
+		t.Errorf("entry 1 = %+v", w)
+	}
+	if len(w.Authors) != 3 || w.Authors[0] != "Ashish Vaswani" || w.Authors[2] != "Aidan N. Gómez" {
+		t.Errorf("entry 1 authors = %q", w.Authors)
+	}
+	if w.Venue != "Advances in Neural Information Processing Systems" || len(w.Tags) != 2 || len(w.Files) != 1 || w.Files[0] != "papers/attention.pdf" {
+		t.Errorf("entry 1 venue/tags/files = %q %q %q", w.Venue, w.Tags, w.Files)
+	}
+
+	w = works[1]
+	if w.Title != "BERT: Pre-training of Deep Bidirectional Transformers" || w.DOI != "10.18653/v1/N19-1423" || w.Year != 2019 || w.Venue != "NAACL 2019" {
+		t.Errorf("entry 2 = %+v", w)
+	}
+	if len(w.Authors) != 2 || w.Authors[1] != "Ming-Wei Chang" {
+		t.Errorf("entry 2 authors = %q", w.Authors)
+	}
+
+	if _, err := ParseBibTeX(strings.NewReader(`@article{broken, title = {unclosed}`)); err == nil {
+		t.Error("expected an error for an unterminated entry")
+	}
+}
+
+func TestImportWorksFillsCitationStubs(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	// Known so far only because another paper cites it
+	stub := &core.Node{ID: "paper:attention-is-all-you-need", Type: "Paper", Meta: map[string]interface{}{"title": "Attention is all you need", "citation_stub": true}, Created: now, Modified: now}
+	if err := repo.CreateNode(ctx, stub); err != nil {
+		t.Fatal(err)
+	}
+
+	works, err := ParseBibTeX(strings.NewReader(sampleBib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	works[0].Attachments = []Attachment{{Name: "attention.pdf", Data: []byte("%PDF-1.4 fake")}}
+
+	first, err := ImportWorks(ctx, repo, works, BibliographyOptions{Connector: "bibtex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Created != 1 || first.Updated != 1 || first.Authors != 5 || first.Venues != 2 || first.Attachments != 1 || first.Links != 8 || len(first.Errors) != 0 {
+		t.Fatalf("first import = %+v", first)
+	}
+
+	paper, err := repo.GetNode(ctx, stub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paper.Meta["citation_stub"] != false || paper.Meta["bibtex_key"] != "vaswani2017" {
+		t.Errorf("stub not filled in: %v", paper.Meta)
+	}
+	backlinks, _ := repo.GetBacklinks(ctx, stub.ID)
+	if len(backlinks) != 3 {
+		t.Errorf("AUTHORED links = %d, want 3", len(backlinks))
+	}
+	if _, err := repo.GetNode(ctx, "paper:doi:10.18653/v1/n19-1423"); err != nil {
+		t.Errorf("DOI paper not created: %v", err)
+	}
+
+	second, err := ImportWorks(ctx, repo, works, BibliographyOptions{Connector: "bibtex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Unchanged != 2 || second.Created+second.Updated+second.Authors+second.Venues+second.Attachments+second.Links != 0 {
+		t.Errorf("re-import = %+v, want everything unchanged", second)
+	}
+}
+
+func TestFetchZotero(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/42/items/top", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Zotero-API-Key") != "secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Total-Results", "2")
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"key": "AAA", "meta": map[string]int{"numChildren": 1}, "data": map[string]interface{}{
+				"itemType": "journalArticle", "title": "Deep Learning", "date": "May 2015",
+				"publicationTitle": "Nature", "DOI": "10.1038/nature14539",
+				"creators": []map[string]string{
+					{"creatorType": "author", "firstName": "Yann", "lastName": "LeCun"},
+					{"creatorType": "editor", "firstName": "Ed", "lastName": "Itor"},
+				},
+				"tags": []map[string]string{{"tag": "ml"}},
+			}},
+			{"key": "NOTE", "data": map[string]interface{}{"itemType": "note"}},
+		})
+	})
+	mux.HandleFunc("/users/42/items/AAA/children", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"key": "PDF1", "data": map[string]interface{}{"itemType": "attachment", "contentType": "application/pdf", "filename": "lecun.pdf", "linkMode": "imported_file"}},
+			{"key": "LINK", "data": map[string]interface{}{"itemType": "attachment", "contentType": "application/pdf", "linkMode": "linked_url"}},
+		})
+	})
+	mux.HandleFunc("/users/42/items/PDF1/file", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("%PDF-1.5"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	works, err := FetchZotero(context.Background(), ZoteroOptions{BaseURL: srv.URL, APIKey: "secret", Library: "users/42", Attachments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works) != 1 {
+		t.Fatalf("works = %d, want 1", len(works))
+	}
+	w := works[0]
+	if w.Title != "Deep Learning" || w.Year != 2015 || w.Venue != "Nature" || len(w.Authors) != 1 || w.Authors[0] != "Yann LeCun" || len(w.Tags) != 1 {
+		t.Errorf("work = %+v", w)
+	}
+	if len(w.Attachments) != 1 || string(w.Attachments[0].Data) != "%PDF-1.5" {
+		t.Errorf("attachments = %+v", w.Attachments)
+	}
+
+	if _, err := FetchZotero(context.Background(), ZoteroOptions{BaseURL: srv.URL, Library: "users/42"}); err == nil {
+		t.Error("expected an error without the API key")
+	}
+}
@@ -0,0 +1,394 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// bibMonths are BibTeX's predefined month strings
+var bibMonths = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April", "may": "May", "jun": "June",
+	"jul": "July", "aug": "August", "sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// latexAccents maps accent commands to combining characters
+var latexAccents = map[byte]rune{
+	'\'': '\u0301', '`': '\u0300', '^': '\u0302', '"': '\u0308', '~': '\u0303',
+	'=': '\u0304', '.': '\u0307', 'c': '\u0327', 'v': '\u030C', 'u': '\u0306', 'H': '\u030B',
+}
+
+var (
+	// latexLetters are letters written as commands
+	latexLetters = strings.NewReplacer(
+		`{\ss}`, "ß", `{\o}`, "ø", `{\O}`, "Ø", `{\aa}`, "å", `{\AA}`, "Å",
+		`{\ae}`, "æ", `{\AE}`, "Æ", `{\l}`, "ł", `{\L}`, "Ł",
+	)
+	// latexCommand matches formatting commands such as \emph, dropped
+	// while their arguments are kept
+	latexCommand = regexp.MustCompile(`\\[a-zA-Z]+\s*`)
+	// latexReplacer handles escapes, dashes and grouping braces
+	latexReplacer = strings.NewReplacer(
+		`\&`, "&", `\%`, "%", `\$`, "$", `\#`, "#", `\_`, "_",
+		"---", "—", "--", "–", "~", " ", "{", "", "}", "",
+	)
+)
+
+// ParseBibTeX reads the entries of a BibTeX file. @string macros are
+// expanded, LaTeX accents and braces removed, and "Last, First" author
+// names turned around.
+func ParseBibTeX(r io.Reader) ([]Work, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &bibParser{src: string(data), macros: make(map[string]string)}
+	for k, v := range bibMonths {
+		p.macros[k] = v
+	}
+
+	var works []Work
+	for {
+		at := strings.IndexByte(p.src[p.pos:], '@')
+		if at < 0 {
+			return works, nil
+		}
+		p.pos += at + 1
+		kind := strings.ToLower(p.ident())
+		p.space()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '{' && p.src[p.pos] != '(') {
+			continue // An @ in free text between entries
+		}
+		line := p.line()
+		switch kind {
+		case "comment", "preamble":
+			if _, err := p.braced(); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		case "string":
+			p.pos++
+			fields, err := p.fields()
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			for k, v := range fields {
+				p.macros[k] = v
+			}
+		default:
+			p.pos++
+			p.space()
+			key := strings.TrimSpace(p.until(",})"))
+			if p.pos < len(p.src) && p.src[p.pos] == ',' {
+				p.pos++
+			}
+			fields, err := p.fields()
+			if err != nil {
+				return nil, fmt.Errorf("line %d (%s): %w", line, key, err)
+			}
+			works = append(works, bibWork(kind, key, fields))
+		}
+	}
+}
+
+// bibWork maps BibTeX fields onto a work
+func bibWork(kind, key string, f map[string]string) Work {
+	w := Work{Key: key, Kind: kind, Title: cleanLaTeX(f["title"]), DOI: f["doi"], URL: f["url"], Abstract: cleanLaTeX(f["abstract"])}
+	if i := strings.Index(w.DOI, "10."); i > 0 {
+		w.DOI = w.DOI[i:] // https://doi.org/10.x or doi:10.x
+	}
+	for _, name := range splitTopLevel(f["author"], " and ") {
+		if name = bibName(name); name != "" && !strings.EqualFold(name, "others") {
+			w.Authors = append(w.Authors, name)
+		}
+	}
+	year := f["year"]
+	if year == "" && len(f["date"]) >= 4 {
+		year = f["date"][:4]
+	}
+	w.Year, _ = strconv.Atoi(strings.TrimSpace(year))
+	for _, field := range []string{"journal", "journaltitle", "booktitle", "publisher", "school", "institution"} {
+		if v := cleanLaTeX(f[field]); v != "" {
+			w.Venue = v
+			break
+		}
+	}
+	for _, tag := range strings.FieldsFunc(f["keywords"], func(r rune) bool { return r == ',' || r == ';' }) {
+		if tag = strings.TrimSpace(cleanLaTeX(tag)); tag != "" {
+			w.Tags = append(w.Tags, tag)
+		}
+	}
+	for _, file := range strings.Split(f["file"], ";") {
+		if path := bibFilePath(file); path != "" {
+			w.Files = append(w.Files, path)
+		}
+	}
+	return w
+}
+
+// bibName turns "Last, First" into "First Last"; names in braces are kept
+// as written
+func bibName(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "{") && strings.HasSuffix(name, "}") {
+		return cleanLaTeX(name)
+	}
+	parts := splitTopLevel(name, ",")
+	switch len(parts) {
+	case 2: // Last, First
+		return cleanLaTeX(strings.TrimSpace(parts[1]) + " " + strings.TrimSpace(parts[0]))
+	case 3: // Last, Jr, First
+		return cleanLaTeX(strings.TrimSpace(parts[2]) + " " + strings.TrimSpace(parts[0]) + " " + strings.TrimSpace(parts[1]))
+	}
+	return cleanLaTeX(name)
+}
+
+// bibFilePath reads a file field entry, either a bare path or JabRef's
+// "description:path:type"
+func bibFilePath(entry string) string {
+	entry = strings.TrimSpace(entry)
+	if parts := strings.Split(entry, ":"); len(parts) >= 3 {
+		return strings.Join(parts[1:len(parts)-1], ":")
+	}
+	return entry
+}
+
+// cleanLaTeX resolves accent commands, escapes and grouping braces and
+// collapses whitespace
+func cleanLaTeX(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if mark, ok := latexAccents[s[i+1]]; ok && (!unicode.IsLetter(rune(s[i+1])) || i+2 < len(s) && (s[i+2] == '{' || s[i+2] == ' ')) {
+				// \"o, \"{o}, \c{c} or \c c
+				j := i + 2
+				for j < len(s) && (s[j] == '{' || s[j] == ' ') {
+					j++
+				}
+				if j < len(s) && unicode.IsLetter(rune(s[j])) {
+					b.WriteByte(s[j])
+					b.WriteRune(mark)
+					i = j
+					continue
+				}
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	cleaned := latexReplacer.Replace(latexCommand.ReplaceAllString(latexLetters.Replace(b.String()), ""))
+	return strings.Join(strings.Fields(composeAccents(cleaned)), " ")
+}
+
+// composeAccents folds a letter and a following combining mark into the
+// precomposed character for the common Latin cases
+func composeAccents(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r >= 0x300 && r <= 0x36F }) {
+		return s
+	}
+	runes := []rune(s)
+	var out []rune
+	for i := 0; i < len(runes); i++ {
+		if i+1 < len(runes) {
+			if c, ok := precomposed[[2]rune{runes[i], runes[i+1]}]; ok {
+				out = append(out, c)
+				i++
+				continue
+			}
+		}
+		out = append(out, runes[i])
+	}
+	return string(out)
+}
+
+// precomposed lists letter + combining mark pairs with a single character
+var precomposed = func() map[[2]rune]rune {
+	table := map[rune]string{
+		'\u0301': "aáeéiíoóuúyýAÁEÉIÍOÓUÚYÝcćnńsśzźCĆNŃSŚZŹ",
+		'\u0300': "aàeèiìoòuùAÀEÈIÌOÒUÙ",
+		'\u0302': "aâeêiîoôuûAÂEÊIÎOÔUÛ",
+		'\u0308': "aäeëiïoöuüyÿAÄEËIÏOÖUÜ",
+		'\u0303': "aãnñoõAÃNÑOÕ",
+		'\u0327': "cçsşCÇSŞ",
+		'\u030C': "cčsšzžrřeěCČSŠZŽRŘEĚ",
+		'\u030B': "oőuűOŐUŰ",
+	}
+	m := make(map[[2]rune]rune)
+	for mark, pairs := range table {
+		r := []rune(pairs)
+		for i := 0; i+1 < len(r); i += 2 {
+			m[[2]rune{r[i], mark}] = r[i+1]
+		}
+	}
+	return m
+}()
+
+// splitTopLevel splits s on sep (case-insensitively) outside braces
+func splitTopLevel(s, sep string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		default:
+			if depth == 0 && i+len(sep) <= len(s) && strings.EqualFold(s[i:i+len(sep)], sep) {
+				parts = append(parts, s[start:i])
+				start = i + len(sep)
+				i = start - 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" || len(parts) > 0 {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// bibParser scans BibTeX source
+type bibParser struct {
+	src    string
+	pos    int
+	macros map[string]string
+}
+
+func (p *bibParser) line() int {
+	return strings.Count(p.src[:p.pos], "\n") + 1
+}
+
+func (p *bibParser) space() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *bibParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := rune(p.src[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("_-:.+/", c) {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// until advances to the next of the stop characters, returning the text skipped
+func (p *bibParser) until(stops string) string {
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(stops, rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// braced reads a {...} or (...) group, returning its inner text
+func (p *bibParser) braced() (string, error) {
+	open := p.src[p.pos]
+	closer := byte('}')
+	if open == '(' {
+		closer = ')'
+	}
+	depth := 0
+	start := p.pos + 1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == open:
+			depth++
+		case c == closer:
+			depth--
+			if depth == 0 {
+				p.pos++
+				return p.src[start : p.pos-1], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unterminated %c", open)
+}
+
+// fields reads name = value pairs up to the end of the entry
+func (p *bibParser) fields() (map[string]string, error) {
+	fields := make(map[string]string)
+	for {
+		p.space()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("unterminated entry")
+		}
+		if c := p.src[p.pos]; c == '}' || c == ')' {
+			p.pos++
+			return fields, nil
+		}
+		name := strings.ToLower(p.ident())
+		if name == "" {
+			return nil, fmt.Errorf("expected a field name at line %d", p.line())
+		}
+		p.space()
+		if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+			return nil, fmt.Errorf("expected = after %s", name)
+		}
+		p.pos++
+		value, err := p.value()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		fields[name] = value
+		p.space()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+// value reads a field value: braced or quoted parts, numbers and macro
+// names joined by #
+func (p *bibParser) value() (string, error) {
+	var b strings.Builder
+	for {
+		p.space()
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("missing value")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '{':
+			inner, err := p.braced()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(inner)
+		case c == '"':
+			p.pos++
+			start, depth := p.pos, 0
+			for ; p.pos < len(p.src) && (p.src[p.pos] != '"' || depth > 0); p.pos++ {
+				if p.src[p.pos] == '{' {
+					depth++
+				} else if p.src[p.pos] == '}' {
+					depth--
+				}
+			}
+			if p.pos >= len(p.src) {
+				return "", fmt.Errorf("unterminated string")
+			}
+			b.WriteString(p.src[start:p.pos])
+			p.pos++
+		default:
+			word := p.ident()
+			if word == "" {
+				return "", fmt.Errorf("unexpected %q", c)
+			}
+			if v, ok := p.macros[strings.ToLower(word)]; ok {
+				word = v
+			}
+			b.WriteString(word)
+		}
+		p.space()
+		if p.pos < len(p.src) && p.src[p.pos] == '#' {
+			p.pos++
+			continue
+		}
+		return strings.TrimSpace(b.String()), nil
+	}
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// Confluence names exported pages "<Title>_<page ID>.html", or just
+	// "<page ID>.html" for titles it cannot use in a file name
+	confluenceFile = regexp.MustCompile(`(?:^|_)(\d+)\.html$`)
+	confluencePage = regexp.MustCompile(`(?:^|[/_])(\d+)\.html(?:$|[?#])|[?&]pageId=(\d+)`)
+	htmlTitle      = regexp.MustCompile(`(?is)<title>(.*?)</title>`)
+	htmlHref       = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+	htmlTag        = regexp.MustCompile(`<[^>]*>`)
+	breadcrumbs    = regexp.MustCompile(`(?is)<ol[^>]*id="breadcrumbs"[^>]*>(.*?)</ol>`)
+	mainContent    = regexp.MustCompile(`(?is)<div[^>]*id="main-content"[^>]*>`)
+	contentEnd     = regexp.MustCompile(`(?is)<div[^>]*class="pageSection|<div[^>]*id="footer"|</body>`)
+	pageMetadata   = regexp.MustCompile(`(?is)<div[^>]*class="page-metadata"[^>]*>(.*?)</div>`)
+	metaAuthor     = regexp.MustCompile(`(?is)<span[^>]*class=["']author["'][^>]*>(.*?)</span>`)
+	metaEditor     = regexp.MustCompile(`(?is)<span[^>]*class=["']editor["'][^>]*>(.*?)</span>`)
+	metaModified   = regexp.MustCompile(`(?is)\bon\s+([A-Z][a-z]{2} \d{1,2}, \d{4})`)
+)
+
+// ParseConfluenceExport reads a Confluence space HTML export. Each page's
+// parent is the last page in its breadcrumbs; its body is the page's main
+// content, kept as HTML. The space, author, last editor and last modified
+// date become properties. Links to other pages are found by the page IDs
+// in their file names or pageId parameters.
+func ParseConfluenceExport(r io.ReaderAt, size int64) ([]Page, error) {
+	files, err := readExport(r, size)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	var pages []Page
+	byFile := make(map[string]string)  // File name -> page ID
+	hrefs := make(map[string][]string) // Page ID -> parent href, then link hrefs
+	for _, f := range files {
+		name := path.Base(f.Path)
+		if !strings.EqualFold(path.Ext(name), ".html") || name == "index.html" || strings.Contains(f.Path, "attachments/") {
+			continue
+		}
+		doc := string(f.Data)
+		id := "confluence:" + slugPath(strings.TrimSuffix(f.Path, ".html"))
+		if m := confluenceFile.FindStringSubmatch(name); m != nil {
+			id = "confluence:" + m[1]
+		}
+		p := Page{ID: id, Path: f.Path, Format: "html", Properties: make(map[string]string)}
+		byFile[name] = id
+		parent := ""
+
+		if m := htmlTitle.FindStringSubmatch(doc); m != nil {
+			// "<space> : <page title>"
+			title := htmlText(m[1])
+			if space, rest, ok := strings.Cut(title, " : "); ok {
+				p.Properties["space"] = space
+				title = rest
+			}
+			p.Title = title
+		}
+		if m := breadcrumbs.FindStringSubmatch(doc); m != nil {
+			if crumbs := htmlHref.FindAllStringSubmatch(m[1], -1); len(crumbs) > 0 {
+				parent = crumbs[len(crumbs)-1][1]
+			}
+		}
+		if m := pageMetadata.FindStringSubmatch(doc); m != nil {
+			if a := metaAuthor.FindStringSubmatch(m[1]); a != nil {
+				p.Properties["created_by"] = htmlText(a[1])
+			}
+			if e := metaEditor.FindStringSubmatch(m[1]); e != nil {
+				p.Properties["last_modified_by"] = htmlText(e[1])
+			}
+			if d := metaModified.FindStringSubmatch(htmlText(m[1])); d != nil {
+				p.Properties["last_modified"] = d[1]
+			}
+		}
+		if loc := mainContent.FindStringIndex(doc); loc != nil {
+			body := doc[loc[1]:]
+			if end := contentEnd.FindStringIndex(body); end != nil {
+				body = body[:end[0]]
+			}
+			p.Content = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(body), "</div>"))
+		}
+
+		hrefs[id] = []string{parent}
+		for _, m := range htmlHref.FindAllStringSubmatch(p.Content, -1) {
+			hrefs[id] = append(hrefs[id], m[1])
+		}
+		pages = append(pages, p)
+	}
+
+	for i := range pages {
+		p := &pages[i]
+		list := hrefs[p.ID]
+		p.Parent = confluenceLink(list[0], byFile)
+		seen := map[string]bool{p.ID: true}
+		for _, href := range list[1:] {
+			if target := confluenceLink(href, byFile); target != "" && !seen[target] {
+				seen[target] = true
+				p.Links = append(p.Links, target)
+			}
+		}
+	}
+	return pages, nil
+}
+
+// confluenceLink returns the ID of the page an href points at, or ""
+func confluenceLink(href string, byFile map[string]string) string {
+	href = html.UnescapeString(href)
+	if m := confluencePage.FindStringSubmatch(href); m != nil {
+		if m[1] != "" {
+			return "confluence:" + m[1]
+		}
+		return "confluence:" + m[2]
+	}
+	if u, err := url.Parse(href); err == nil && u.Host == "" {
+		name, _ := url.PathUnescape(path.Base(u.Path))
+		return byFile[name]
+	}
+	return ""
+}
+
+// htmlText strips tags and entities and collapses whitespace
+func htmlText(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(htmlTag.ReplaceAllString(s, " "))), " ")
+}
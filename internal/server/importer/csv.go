@@ -0,0 +1,282 @@
+// Package importer loads tabular data (CSV) into the graph.
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// CSVOptions describes how CSV columns map onto nodes
+type CSVOptions struct {
+	Type          string // Node type for every row (required)
+	IDColumn      string // Column whose value identifies the node (required)
+	IDPrefix      string // Prepended to ID values lacking it; defaults to "<type>:" in lower case
+	ContentColumn string // Optional column used as node content on create
+	LinkColumn    string // Optional column naming link targets (";" separates several)
+	LinkType      string // Link type for LinkColumn (default RELATED_TO)
+	LinkPrefix    string // Prepended to each link target value
+	ChangedBy     string // Recorded on updates (default csv-import)
+}
+
+// RowError reports a row that could not be imported
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// CSVResult summarizes an import
+type CSVResult struct {
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Links     int        `json:"links"`
+	Errors    []RowError `json:"errors,omitempty"`
+}
+
+// ImportCSV creates or updates one node per row. The first row is the header;
+// every column other than the ID, content and link columns becomes a meta field.
+// New nodes and links are written in bulk after all rows are read, so links may
+// point at nodes created by the same file. Re-importing a file leaves the graph unchanged.
+func ImportCSV(ctx context.Context, repo graph.Repository, r io.Reader, opts CSVOptions) (*CSVResult, error) {
+	if opts.Type == "" || opts.IDColumn == "" {
+		return nil, errors.New("type and id column are required")
+	}
+	if opts.IDPrefix == "" {
+		opts.IDPrefix = strings.ToLower(opts.Type) + ":"
+	}
+	if opts.LinkType == "" {
+		opts.LinkType = "RELATED_TO"
+	}
+	if opts.ChangedBy == "" {
+		opts.ChangedBy = "csv-import"
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	idIdx := indexOf(header, opts.IDColumn)
+	if idIdx < 0 {
+		return nil, fmt.Errorf("id column %q not found in header", opts.IDColumn)
+	}
+	contentIdx := indexOf(header, opts.ContentColumn)
+	linkIdx := indexOf(header, opts.LinkColumn)
+	if opts.LinkColumn != "" && linkIdx < 0 {
+		return nil, fmt.Errorf("link column %q not found in header", opts.LinkColumn)
+	}
+
+	state := &csvImport{
+		repo:    repo,
+		opts:    opts,
+		result:  &CSVResult{},
+		created: make(map[string]*core.Node),
+	}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return state.result, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		if err := state.row(ctx, row, header, record, idIdx, contentIdx, linkIdx); err != nil {
+			state.fail(row, err)
+		}
+	}
+
+	if err := state.flush(ctx); err != nil {
+		return state.result, err
+	}
+	return state.result, nil
+}
+
+// csvImport accumulates new nodes and links so they can be written in bulk
+type csvImport struct {
+	repo   graph.Repository
+	opts   CSVOptions
+	result *CSVResult
+
+	nodes   []*core.Node
+	created map[string]*core.Node
+	links   []pendingLink
+}
+
+// pendingLink is a link requested by a CSV row
+type pendingLink struct {
+	row    int
+	source string
+	target string
+}
+
+func (s *csvImport) fail(row int, err error) {
+	s.result.Errors = append(s.result.Errors, RowError{Row: row, Error: err.Error()})
+}
+
+// row applies a single CSV record; new nodes and links are queued for flush
+func (s *csvImport) row(ctx context.Context, row int, header, record []string, idIdx, contentIdx, linkIdx int) error {
+	value := cell(record, idIdx)
+	if value == "" {
+		return errors.New("empty id")
+	}
+	id := value
+	if !strings.HasPrefix(id, s.opts.IDPrefix) {
+		id = s.opts.IDPrefix + value
+	}
+
+	meta := make(map[string]any)
+	for i, column := range header {
+		if i == idIdx || i == contentIdx || i == linkIdx || column == "" {
+			continue
+		}
+		if v := cell(record, i); v != "" {
+			meta[column] = v
+		}
+	}
+	meta[s.opts.IDColumn] = value
+
+	if pending, ok := s.created[id]; ok {
+		// Repeated ID within the file: later rows win
+		for k, v := range meta {
+			pending.Meta[k] = v
+		}
+	} else if existing, err := s.repo.GetNode(ctx, id); err != nil {
+		now := time.Now()
+		node := &core.Node{
+			ID:       id,
+			Type:     s.opts.Type,
+			Content:  []byte(cell(record, contentIdx)),
+			Meta:     meta,
+			Created:  now,
+			Modified: now,
+		}
+		s.nodes = append(s.nodes, node)
+		s.created[id] = node
+	} else if existing.Type != s.opts.Type {
+		return fmt.Errorf("%s exists with type %s", id, existing.Type)
+	} else if metaChanged(existing.Meta, meta) {
+		if err := s.repo.UpdateNodeMetaWithNote(ctx, id, meta, "CSV import", s.opts.ChangedBy); err != nil {
+			return fmt.Errorf("failed to update %s: %w", id, err)
+		}
+		s.result.Updated++
+	} else {
+		s.result.Unchanged++
+	}
+
+	if linkIdx >= 0 {
+		for _, target := range strings.Split(cell(record, linkIdx), ";") {
+			if target = strings.TrimSpace(target); target != "" {
+				s.links = append(s.links, pendingLink{row: row, source: id, target: s.opts.LinkPrefix + target})
+			}
+		}
+	}
+	return nil
+}
+
+// flush creates queued nodes, then queued links that do not already exist
+func (s *csvImport) flush(ctx context.Context) error {
+	if len(s.nodes) > 0 {
+		if err := s.repo.CreateNodes(ctx, s.nodes); err != nil {
+			return fmt.Errorf("failed to create nodes: %w", err)
+		}
+		s.result.Created = len(s.nodes)
+	}
+
+	existing := make(map[string]map[string]bool)
+	targets := make(map[string]bool)
+	var links []*core.Link
+	for _, p := range s.links {
+		seen, ok := existing[p.source]
+		if !ok {
+			seen = make(map[string]bool)
+			if _, isNew := s.created[p.source]; !isNew {
+				current, err := s.repo.GetLinks(ctx, p.source)
+				if err != nil {
+					s.fail(p.row, fmt.Errorf("failed to get links for %s: %w", p.source, err))
+					continue
+				}
+				for _, link := range current {
+					if link.Source == p.source && link.Type == s.opts.LinkType {
+						seen[link.Target] = true
+					}
+				}
+			}
+			existing[p.source] = seen
+		}
+		if seen[p.target] {
+			continue
+		}
+
+		found, checked := targets[p.target]
+		if !checked {
+			_, isNew := s.created[p.target]
+			found = isNew
+			if !found {
+				_, err := s.repo.GetNode(ctx, p.target)
+				found = err == nil
+			}
+			targets[p.target] = found
+		}
+		if !found {
+			s.fail(p.row, fmt.Errorf("link target not found: %s", p.target))
+			continue
+		}
+
+		now := time.Now()
+		links = append(links, &core.Link{Source: p.source, Target: p.target, Type: s.opts.LinkType, Created: now, Modified: now})
+		seen[p.target] = true
+	}
+
+	if len(links) > 0 {
+		if err := s.repo.CreateLinks(ctx, links); err != nil {
+			return fmt.Errorf("failed to create links: %w", err)
+		}
+		s.result.Links = len(links)
+	}
+	return nil
+}
+
+// metaChanged reports whether applying update would change current
+func metaChanged(current map[string]interface{}, update map[string]any) bool {
+	for k, v := range update {
+		if s, ok := current[k].(string); !ok || s != v {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOf(header []string, column string) int {
+	if column == "" {
+		return -1
+	}
+	for i, h := range header {
+		if h == column {
+			return i
+		}
+	}
+	return -1
+}
+
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
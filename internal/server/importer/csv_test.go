@@ -0,0 +1,60 @@
+package importer
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestImportCSVIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	repo, err := graph.NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	defer repo.Close(ctx)
+
+	if err := repo.CreateNode(ctx, &core.Node{ID: "company:acme", Type: "Company", Meta: map[string]interface{}{}}); err != nil {
+		t.Fatalf("CreateNode() error = %v", err)
+	}
+
+	data := "email,name,company\nada@example.com,Ada,acme\nbob@example.com,Bob,\n"
+	opts := CSVOptions{Type: "Person", IDColumn: "email", LinkColumn: "company", LinkType: "WORKS_AT", LinkPrefix: "company:"}
+
+	first, err := ImportCSV(ctx, repo, strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	if first.Created != 2 || first.Links != 1 || len(first.Errors) != 0 {
+		t.Fatalf("first import = %+v, want 2 created, 1 link", first)
+	}
+
+	second, err := ImportCSV(ctx, repo, strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	if second.Unchanged != 2 || second.Created != 0 || second.Updated != 0 || second.Links != 0 {
+		t.Fatalf("second import = %+v, want 2 unchanged", second)
+	}
+
+	changed := strings.Replace(data, "Bob,", "Robert,", 1)
+	third, err := ImportCSV(ctx, repo, strings.NewReader(changed), opts)
+	if err != nil {
+		t.Fatalf("ImportCSV() error = %v", err)
+	}
+	if third.Updated != 1 || third.Unchanged != 1 {
+		t.Fatalf("third import = %+v, want 1 updated", third)
+	}
+
+	node, err := repo.GetNode(ctx, "person:bob@example.com")
+	if err != nil {
+		t.Fatalf("GetNode() error = %v", err)
+	}
+	if node.Meta["name"] != "Robert" {
+		t.Errorf("name = %v, want Robert", node.Meta["name"])
+	}
+}
@@ -0,0 +1,205 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxExportFile caps one file read from a wiki export
+const maxExportFile = 64 << 20
+
+var (
+	// Notion names exported files "<title> <32 hex digit page ID>.md"; a
+	// database's CSV may come twice, once with an _all suffix
+	notionFile = regexp.MustCompile(`^(.*?)\s*([0-9a-f]{32})(_all)?\.(md|csv)$`)
+	notionID   = regexp.MustCompile(`[0-9a-f]{32}|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	mdTitle    = regexp.MustCompile(`^#\s+(.+)`)
+	mdLink     = regexp.MustCompile(`\]\(<?([^)>\s]+)>?\)|https?://(?:www\.)?notion\.so/\S+`)
+)
+
+// exportFile is a file read from an export ZIP
+type exportFile struct {
+	Path string
+	Data []byte
+}
+
+// readExport returns the files of an export ZIP, opening ZIPs nested in it
+// (Notion splits large exports into parts)
+func readExport(r io.ReaderAt, size int64) ([]exportFile, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ZIP: %w", err)
+	}
+	var files []exportFile
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if f.UncompressedSize64 > maxExportFile {
+			return nil, fmt.Errorf("%s is larger than %d bytes", f.Name, maxExportFile)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxExportFile))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		if strings.EqualFold(path.Ext(f.Name), ".zip") {
+			nested, err := readExport(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			files = append(files, nested...)
+			continue
+		}
+		files = append(files, exportFile{Path: f.Name, Data: data})
+	}
+	return files, nil
+}
+
+// ParseNotionExport reads a Notion "Markdown & CSV" export. Each page's
+// children are in the folder named like the page, so the folders give the
+// hierarchy. A database is a CSV file whose rows are the pages in its
+// folder; a row's columns become that page's properties. Links between
+// pages are found by the page IDs in their targets, relative paths and
+// notion.so URLs alike.
+func ParseNotionExport(r io.ReaderAt, size int64) ([]Page, error) {
+	files, err := readExport(r, size)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	// Folder of children -> the page or database owning it
+	owners := make(map[string]string)
+	rows := make(map[string]map[string]map[string]string) // Database folder -> row title -> properties
+	var pages []Page
+	index := make(map[string]int) // Page ID -> position in pages
+	for _, f := range files {
+		ext := strings.ToLower(path.Ext(f.Path))
+		if ext != ".md" && ext != ".csv" {
+			continue
+		}
+		name := path.Base(f.Path)
+		folder := strings.TrimSuffix(f.Path, path.Ext(f.Path))
+		title := strings.TrimSuffix(name, path.Ext(name))
+		id := "notion:" + slugPath(folder)
+		if m := notionFile.FindStringSubmatch(name); m != nil {
+			title, id = strings.TrimSpace(m[1]), "notion:"+m[2]
+			if m[3] != "" {
+				folder = strings.TrimSuffix(folder, "_all")
+			}
+		}
+
+		p := Page{ID: id, Title: title, Content: string(f.Data), Path: f.Path}
+		if ext == ".csv" {
+			props, err := notionRows(f.Data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Path, err)
+			}
+			if rows[folder] == nil {
+				rows[folder] = props
+			} else {
+				for k, v := range props {
+					rows[folder][k] = v // The _all file has rows the other may lack
+				}
+			}
+			p.Format = "csv"
+			p.Properties = map[string]string{"kind": "database"}
+		} else {
+			p.Format = "markdown"
+			if m := mdTitle.FindStringSubmatch(strings.TrimPrefix(p.Content, "\ufeff")); m != nil {
+				p.Title = strings.TrimSpace(m[1])
+			}
+			p.Links = notionLinks(p.Content, id)
+		}
+		owners[folder] = id
+		if i, ok := index[id]; ok {
+			if ext == ".csv" && strings.HasSuffix(f.Path, "_all.csv") {
+				pages[i].Content, pages[i].Path = p.Content, p.Path // Every row, not just the view's
+			}
+			continue
+		}
+		index[id] = len(pages)
+		pages = append(pages, p)
+	}
+
+	for i := range pages {
+		dir := path.Dir(pages[i].Path)
+		pages[i].Parent = owners[dir]
+		if props, ok := rows[dir]; ok && pages[i].Format == "markdown" {
+			pages[i].Properties = props[pages[i].Title]
+		}
+	}
+	return pages, nil
+}
+
+// notionRows reads a database CSV into each row's properties, keyed by the
+// row's title (the first column)
+func notionRows(data []byte) (map[string]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make(map[string]map[string]string)
+	if len(records) == 0 {
+		return rows, nil
+	}
+	header := records[0]
+	for _, record := range records[1:] {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		props := make(map[string]string)
+		for i := 1; i < len(record) && i < len(header); i++ {
+			props[strings.TrimSpace(header[i])] = record[i]
+		}
+		rows[strings.TrimSpace(record[0])] = props
+	}
+	return rows, nil
+}
+
+// notionLinks returns the IDs of the pages a page's markdown links to. A
+// target naming several page IDs (a path through parent folders) links
+// to the last.
+func notionLinks(content, self string) []string {
+	seen := map[string]bool{self: true}
+	var links []string
+	for _, m := range mdLink.FindAllStringSubmatch(content, -1) {
+		target := m[0]
+		if m[1] != "" {
+			target = m[1]
+		}
+		if decoded, err := url.PathUnescape(target); err == nil {
+			target = decoded
+		}
+		ids := notionID.FindAllString(target, -1)
+		if len(ids) == 0 {
+			continue
+		}
+		id := "notion:" + strings.ReplaceAll(ids[len(ids)-1], "-", "")
+		if !seen[id] {
+			seen[id] = true
+			links = append(links, id)
+		}
+	}
+	return links
+}
+
+// slugPath turns an export path into an ID for files without a page ID
+func slugPath(p string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(p), "-"), "-")
+}
@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Node and link types created by wiki imports
+const (
+	PageType     = "Page"
+	ParentOfLink = "PARENT_OF" // Page to each of its child pages
+	LinksToLink  = "LINKS_TO"  // Page to a page its content links to
+)
+
+// pageKeys are the properties the importer sets itself; page properties
+// with these names are dropped
+var pageKeys = map[string]bool{"title": true, "format": true, "path": true, "connector": true}
+
+// Page is a wiki page read from an export
+type Page struct {
+	ID         string            `json:"id"` // Node ID, e.g. notion:<page id>
+	Title      string            `json:"title"`
+	Content    string            `json:"-"`
+	Format     string            `json:"format"` // markdown, html or csv
+	Path       string            `json:"path"`   // Where the page is in the export
+	Parent     string            `json:"parent,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"` // Database columns or page metadata
+	Links      []string          `json:"links,omitempty"`      // IDs of the pages the content links to
+}
+
+// WikiOptions controls a wiki import
+type WikiOptions struct {
+	Connector string // Recorded on pages, e.g. notion or confluence
+	ChangedBy string // Recorded on updates (default <connector>-import)
+}
+
+// WikiResult summarizes a wiki import
+type WikiResult struct {
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Unchanged int          `json:"unchanged"`
+	Links     int          `json:"links"`
+	Errors    []EntryError `json:"errors,omitempty"`
+}
+
+// ImportPages creates a Page node per page, holding its content, with its
+// properties as meta. Pages are linked PARENT_OF from their parent and
+// LINKS_TO the pages their content links to, when those pages are in the
+// import or already in the graph. New nodes and links are written in bulk,
+// so links may point forward. Re-importing an export updates titles and
+// properties and adds new links; page content is kept as first imported.
+func ImportPages(ctx context.Context, repo graph.Repository, pages []Page, opts WikiOptions) (*WikiResult, error) {
+	if opts.Connector == "" {
+		return nil, errors.New("connector is required")
+	}
+	if opts.ChangedBy == "" {
+		opts.ChangedBy = opts.Connector + "-import"
+	}
+
+	result := &WikiResult{}
+	known := make(map[string]bool)
+	linked := make(map[[3]string]bool)
+	var nodes []*core.Node
+	var imported []Page
+	for _, p := range pages {
+		if p.ID == "" {
+			result.Errors = append(result.Errors, EntryError{Key: p.Path, Error: "missing page ID"})
+			continue
+		}
+		if known[p.ID] {
+			continue // Duplicate within the export
+		}
+		meta := pageMeta(p, opts.Connector)
+		existing, err := repo.GetNode(ctx, p.ID)
+		if err != nil {
+			now := time.Now()
+			nodes = append(nodes, &core.Node{ID: p.ID, Type: PageType, Content: []byte(p.Content), Meta: meta, Created: now, Modified: now})
+			result.Created++
+		} else if existing.Type != PageType {
+			result.Errors = append(result.Errors, EntryError{Key: p.Path, Error: fmt.Sprintf("%s exists with type %s", p.ID, existing.Type)})
+			continue
+		} else {
+			outgoing, err := repo.GetLinks(ctx, p.ID)
+			if err != nil {
+				return result, fmt.Errorf("failed to get links for %s: %w", p.ID, err)
+			}
+			incoming, err := repo.GetBacklinks(ctx, p.ID)
+			if err != nil {
+				return result, fmt.Errorf("failed to get backlinks for %s: %w", p.ID, err)
+			}
+			for _, l := range append(outgoing, incoming...) {
+				linked[[3]string{l.Source, l.Target, l.Type}] = true
+			}
+			changed := make(map[string]any)
+			for k, v := range meta {
+				if !sameValue(existing.Meta[k], v) {
+					changed[k] = v
+				}
+			}
+			if len(changed) == 0 {
+				result.Unchanged++
+			} else {
+				if err := repo.UpdateNodeMetaWithNote(ctx, p.ID, changed, "Wiki import", opts.ChangedBy); err != nil {
+					return result, fmt.Errorf("failed to update %s: %w", p.ID, err)
+				}
+				result.Updated++
+			}
+		}
+		known[p.ID] = true
+		imported = append(imported, p)
+	}
+
+	// Parents and link targets outside the export must already exist
+	exists := func(id string) bool {
+		if known[id] {
+			return true
+		}
+		_, err := repo.GetNode(ctx, id)
+		known[id] = err == nil
+		return known[id]
+	}
+	var links []*core.Link
+	queue := func(source, target, linkType string) {
+		key := [3]string{source, target, linkType}
+		if source == target || linked[key] || !exists(source) || !exists(target) {
+			return
+		}
+		linked[key] = true
+		now := time.Now()
+		links = append(links, &core.Link{Source: source, Target: target, Type: linkType, Created: now, Modified: now})
+	}
+	for _, p := range imported {
+		if p.Parent != "" {
+			queue(p.Parent, p.ID, ParentOfLink)
+		}
+		for _, target := range p.Links {
+			queue(p.ID, target, LinksToLink)
+		}
+	}
+
+	if len(nodes) > 0 {
+		if err := repo.CreateNodes(ctx, nodes); err != nil {
+			return result, fmt.Errorf("failed to create pages: %w", err)
+		}
+	}
+	if len(links) > 0 {
+		if err := repo.CreateLinks(ctx, links); err != nil {
+			return result, fmt.Errorf("failed to create links: %w", err)
+		}
+		result.Links = len(links)
+	}
+	return result, nil
+}
+
+// pageMeta builds a page's properties. Property names become snake_case
+// keys; empty values are left out.
+func pageMeta(p Page, connector string) map[string]any {
+	title := strings.TrimSpace(p.Title)
+	if title == "" {
+		title = p.Path
+	}
+	meta := map[string]any{"title": title, "format": p.Format, "path": p.Path, "connector": connector}
+	for name, value := range p.Properties {
+		key := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "_"), "_")
+		if value = strings.TrimSpace(value); key == "" || value == "" || pageKeys[key] {
+			continue
+		}
+		meta[key] = value
+	}
+	return meta
+}
@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+const (
+	homeID  = "0123456789abcdef0123456789abcdef"
+	tasksID = "11111111111111111111111111111111"
+	rowID   = "22222222222222222222222222222222"
+	guideID = "33333333333333333333333333333333"
+)
+
+// zipFiles builds a ZIP of the given files
+func zipFiles(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func hasWikiLink(t *testing.T, repo graph.Repository, source, target, linkType string) bool {
+	t.Helper()
+	links, err := repo.GetLinks(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range links {
+		if l.Target == target && l.Type == linkType {
+			return true
+		}
+	}
+	return false
+}
+
+func TestImportNotionExport(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	// Large exports come as a ZIP of ZIPs
+	part := zipFiles(t, map[string]string{
+		"Home " + homeID + "/Guide " + guideID + ".md": "# Guide\n\nBack to [Home](../Home%20" + homeID + ".md)",
+	})
+	partData := make([]byte, part.Len())
+	part.Read(partData)
+	export := zipFiles(t, map[string]string{
+		"Home " + homeID + ".md": "# Home\n\nSee [Guide](Home%20" + homeID + "/Guide%20" + guideID + ".md) and " +
+			"https://www.notion.so/acme/Ship-it-" + rowID + "\n\n![logo](Home%20" + homeID + "/logo.png)",
+		"Home " + homeID + "/Tasks " + tasksID + ".csv":                      "\ufeffName,Status,Due Date\nShip it,Done,\"May 1, 2026\"\n",
+		"Home " + homeID + "/Tasks " + tasksID + "_all.csv":                  "Name,Status,Due Date\nShip it,Done,\"May 1, 2026\"\n",
+		"Home " + homeID + "/Tasks " + tasksID + "/Ship it " + rowID + ".md": "# Ship it\n\nStatus: Done\n\nNotes.",
+		"Home " + homeID + "/logo.png":                                       "PNG",
+		"Part-1.zip":                                                         string(partData),
+	})
+
+	pages, err := ParseNotionExport(export, export.Size())
+	if err != nil {
+		t.Fatalf("ParseNotionExport() error = %v", err)
+	}
+	if len(pages) != 4 {
+		t.Fatalf("ParseNotionExport() = %d pages, want 4: %+v", len(pages), pages)
+	}
+
+	res, err := ImportPages(ctx, repo, pages, WikiOptions{Connector: "notion"})
+	if err != nil {
+		t.Fatalf("ImportPages() error = %v", err)
+	}
+	// Three PARENT_OF, home to guide and row, guide back to home
+	if res.Created != 4 || res.Links != 6 || len(res.Errors) != 0 {
+		t.Errorf("ImportPages() = %+v", res)
+	}
+
+	row, err := repo.GetNode(ctx, "notion:"+rowID)
+	if err != nil || row.Type != PageType || row.Meta["title"] != "Ship it" || row.Meta["status"] != "Done" || row.Meta["due_date"] != "May 1, 2026" {
+		t.Fatalf("row = %+v, %v", row, err)
+	}
+	if string(row.Content) != "# Ship it\n\nStatus: Done\n\nNotes." {
+		t.Errorf("row content = %q", row.Content)
+	}
+	home, tasks, guide := "notion:"+homeID, "notion:"+tasksID, "notion:"+guideID
+	if db, _ := repo.GetNode(ctx, tasks); db == nil || db.Meta["kind"] != "database" || db.Meta["format"] != "csv" {
+		t.Errorf("database = %+v", db)
+	}
+	for _, l := range []struct{ source, target, linkType string }{
+		{home, tasks, ParentOfLink},
+		{tasks, "notion:" + rowID, ParentOfLink},
+		{home, guide, ParentOfLink},
+		{home, guide, LinksToLink},
+		{home, "notion:" + rowID, LinksToLink},
+		{guide, home, LinksToLink},
+	} {
+		if !hasWikiLink(t, repo, l.source, l.target, l.linkType) {
+			t.Errorf("missing link %s -[%s]-> %s", l.source, l.linkType, l.target)
+		}
+	}
+
+	// Re-importing changes nothing
+	res, err = ImportPages(ctx, repo, pages, WikiOptions{Connector: "notion"})
+	if err != nil || res.Created != 0 || res.Updated != 0 || res.Unchanged != 4 || res.Links != 0 {
+		t.Errorf("second ImportPages() = %+v, %v", res, err)
+	}
+}
+
+const confluencePageHTML = `<!DOCTYPE html>
+<html><head><title>Engineering : Deploy &amp; Rollback</title></head>
+<body><div id="page"><div id="main">
+<div id="main-header">
+<div id="breadcrumb-section"><ol id="breadcrumbs">
+<li class="first"><span><a href="index.html">Engineering</a></span></li>
+<li><span><a href="Runbooks_1001.html">Runbooks</a></span></li>
+</ol></div>
+<h1 id="title-heading" class="pagetitle"><span id="title-text"> Engineering : Deploy &amp; Rollback </span></h1>
+</div>
+<div id="content" class="view">
+<div class="page-metadata">Created by <span class='author'> Ada Lovelace</span>, last modified by <span class='editor'> Bob</span> on Mar 02, 2026</div>
+<div id="main-content" class="wiki-content group">
+<p>Before deploying read <a href="Checklist_1003.html">the checklist</a> and
+<a href="/pages/viewpage.action?pageId=1001">the runbooks</a>. <a href="https://example.com">External</a>.</p>
+</div>
+<div class="pageSection group"><h2>Attachments:</h2></div>
+</div></div></div></body></html>`
+
+func TestImportConfluenceExport(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	export := zipFiles(t, map[string]string{
+		"ENG/index.html":                 `<html><body><a href="Runbooks_1001.html">Runbooks</a></body></html>`,
+		"ENG/Runbooks_1001.html":         `<html><head><title>Engineering : Runbooks</title></head><body><ol id="breadcrumbs"><li><a href="index.html">Engineering</a></li></ol><div id="main-content"><p>All runbooks</p></div></body></html>`,
+		"ENG/Deploy-Rollback_1002.html":  confluencePageHTML,
+		"ENG/Checklist_1003.html":        `<html><head><title>Engineering : Checklist</title></head><body><ol id="breadcrumbs"><li><a href="index.html">Engineering</a></li><li><a href="Runbooks_1001.html">Runbooks</a></li></ol><div id="main-content"><p>1. Tests</p></div></body></html>`,
+		"ENG/attachments/1002/2001.html": "not a page",
+	})
+
+	pages, err := ParseConfluenceExport(export, export.Size())
+	if err != nil {
+		t.Fatalf("ParseConfluenceExport() error = %v", err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("ParseConfluenceExport() = %+v", pages)
+	}
+
+	res, err := ImportPages(ctx, repo, pages, WikiOptions{Connector: "confluence"})
+	if err != nil {
+		t.Fatalf("ImportPages() error = %v", err)
+	}
+	// Two PARENT_OF, two LINKS_TO
+	if res.Created != 3 || res.Links != 4 {
+		t.Errorf("ImportPages() = %+v", res)
+	}
+
+	page, err := repo.GetNode(ctx, "confluence:1002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"title":            "Deploy & Rollback",
+		"space":            "Engineering",
+		"created_by":       "Ada Lovelace",
+		"last_modified_by": "Bob",
+		"last_modified":    "Mar 02, 2026",
+		"format":           "html",
+	} {
+		if page.Meta[key] != want {
+			t.Errorf("meta %s = %v, want %q", key, page.Meta[key], want)
+		}
+	}
+	if content := string(page.Content); !bytes.HasPrefix(page.Content, []byte("<p>Before deploying")) || bytes.Contains(page.Content, []byte("Attachments")) {
+		t.Errorf("content = %q", content)
+	}
+	for _, l := range []struct{ source, target, linkType string }{
+		{"confluence:1001", "confluence:1002", ParentOfLink},
+		{"confluence:1001", "confluence:1003", ParentOfLink},
+		{"confluence:1002", "confluence:1003", LinksToLink},
+		{"confluence:1002", "confluence:1001", LinksToLink},
+	} {
+		if !hasWikiLink(t, repo, l.source, l.target, l.linkType) {
+			t.Errorf("missing link %s -[%s]-> %s", l.source, l.linkType, l.target)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultZoteroURL is the Zotero Web API
+const DefaultZoteroURL = "https://api.zotero.org"
+
+// zoteroPageSize is the most items the Zotero API returns per request
+const zoteroPageSize = 100
+
+// maxAttachmentBytes caps a downloaded attachment
+const maxAttachmentBytes = 64 << 20
+
+// ErrInvalidLibrary is returned for a library that is not users/<id> or groups/<id>
+var ErrInvalidLibrary = errors.New("library must be users/<id> or groups/<id>")
+
+var (
+	zoteroLibrary = regexp.MustCompile(`^(users|groups)/\d+$`)
+	fourDigitYear = regexp.MustCompile(`\b\d{4}\b`)
+)
+
+// ZoteroOptions selects the Zotero library to import
+type ZoteroOptions struct {
+	BaseURL     string // Defaults to DefaultZoteroURL
+	APIKey      string
+	Library     string // users/<id> or groups/<id>
+	Collection  string // Optional collection key
+	Attachments bool   // Download PDF attachments
+	HTTPClient  *http.Client
+}
+
+// zoteroItem is an item as returned by the Zotero API (format=json)
+type zoteroItem struct {
+	Key  string `json:"key"`
+	Meta struct {
+		NumChildren int `json:"numChildren"`
+	} `json:"meta"`
+	Data struct {
+		ItemType string `json:"itemType"`
+		Title    string `json:"title"`
+		Creators []struct {
+			CreatorType string `json:"creatorType"`
+			FirstName   string `json:"firstName"`
+			LastName    string `json:"lastName"`
+			Name        string `json:"name"`
+		} `json:"creators"`
+		Date             string `json:"date"`
+		PublicationTitle string `json:"publicationTitle"`
+		ProceedingsTitle string `json:"proceedingsTitle"`
+		BookTitle        string `json:"bookTitle"`
+		Publisher        string `json:"publisher"`
+		University       string `json:"university"`
+		DOI              string `json:"DOI"`
+		URL              string `json:"url"`
+		AbstractNote     string `json:"abstractNote"`
+		Tags             []struct {
+			Tag string `json:"tag"`
+		} `json:"tags"`
+		ContentType string `json:"contentType"`
+		Filename    string `json:"filename"`
+		LinkMode    string `json:"linkMode"`
+	} `json:"data"`
+}
+
+// FetchZotero reads the top-level items of a Zotero library (or one of its
+// collections) as works, with their PDF attachments when requested
+func FetchZotero(ctx context.Context, opts ZoteroOptions) ([]Work, error) {
+	if !zoteroLibrary.MatchString(opts.Library) {
+		return nil, ErrInvalidLibrary
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultZoteroURL
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	c := &zoteroClient{opts: opts}
+
+	path := "/" + opts.Library + "/items/top"
+	if opts.Collection != "" {
+		path = "/" + opts.Library + "/collections/" + url.PathEscape(opts.Collection) + "/items/top"
+	}
+
+	var works []Work
+	for start := 0; ; start += zoteroPageSize {
+		var items []zoteroItem
+		total, err := c.getJSON(ctx, path, url.Values{"format": {"json"}, "limit": {strconv.Itoa(zoteroPageSize)}, "start": {strconv.Itoa(start)}}, &items)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if item.Data.ItemType == "attachment" || item.Data.ItemType == "note" || item.Data.ItemType == "annotation" {
+				continue
+			}
+			w := zoteroWork(item)
+			if opts.Attachments && item.Meta.NumChildren > 0 {
+				if w.Attachments, err = c.attachments(ctx, item.Key); err != nil {
+					return nil, fmt.Errorf("attachments of %s: %w", item.Key, err)
+				}
+			}
+			works = append(works, w)
+		}
+		if len(items) < zoteroPageSize || (total > 0 && start+len(items) >= total) {
+			return works, nil
+		}
+	}
+}
+
+// zoteroWork maps a Zotero item onto a work
+func zoteroWork(item zoteroItem) Work {
+	d := item.Data
+	w := Work{Key: item.Key, Kind: d.ItemType, Title: strings.TrimSpace(d.Title), DOI: d.DOI, URL: d.URL, Abstract: d.AbstractNote}
+	for _, c := range d.Creators {
+		if c.CreatorType != "author" && c.CreatorType != "" {
+			continue // Editors, translators and so on
+		}
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			name = strings.TrimSpace(c.FirstName + " " + c.LastName)
+		}
+		if name != "" {
+			w.Authors = append(w.Authors, name)
+		}
+	}
+	w.Year, _ = strconv.Atoi(fourDigitYear.FindString(d.Date))
+	for _, venue := range []string{d.PublicationTitle, d.ProceedingsTitle, d.BookTitle, d.Publisher, d.University} {
+		if venue = strings.TrimSpace(venue); venue != "" {
+			w.Venue = venue
+			break
+		}
+	}
+	for _, t := range d.Tags {
+		if tag := strings.TrimSpace(t.Tag); tag != "" {
+			w.Tags = append(w.Tags, tag)
+		}
+	}
+	return w
+}
+
+type zoteroClient struct {
+	opts ZoteroOptions
+}
+
+// attachments downloads an item's stored PDF attachments
+func (c *zoteroClient) attachments(ctx context.Context, key string) ([]Attachment, error) {
+	var children []zoteroItem
+	if _, err := c.getJSON(ctx, "/"+c.opts.Library+"/items/"+url.PathEscape(key)+"/children", url.Values{"format": {"json"}}, &children); err != nil {
+		return nil, err
+	}
+	var out []Attachment
+	for _, child := range children {
+		d := child.Data
+		if d.ItemType != "attachment" || d.ContentType != "application/pdf" || d.LinkMode == "linked_url" || d.LinkMode == "linked_file" {
+			continue // Only files stored in Zotero can be downloaded
+		}
+		resp, err := c.get(ctx, "/"+c.opts.Library+"/items/"+url.PathEscape(child.Key)+"/file", nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachmentBytes+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxAttachmentBytes {
+			return nil, fmt.Errorf("attachment %s is larger than %d bytes", d.Filename, maxAttachmentBytes)
+		}
+		out = append(out, Attachment{Name: d.Filename, ContentType: d.ContentType, Data: data})
+	}
+	return out, nil
+}
+
+// getJSON decodes a response into v, returning the Total-Results header
+func (c *zoteroClient) getJSON(ctx context.Context, path string, query url.Values, v interface{}) (int, error) {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return 0, fmt.Errorf("decoding Zotero response: %w", err)
+	}
+	total, _ := strconv.Atoi(resp.Header.Get("Total-Results"))
+	return total, nil
+}
+
+func (c *zoteroClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := c.opts.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Zotero-API-Version", "3")
+	if c.opts.APIKey != "" {
+		req.Header.Set("Zotero-API-Key", c.opts.APIKey)
+	}
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling Zotero: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("Zotero returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
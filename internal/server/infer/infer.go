@@ -0,0 +1,219 @@
+package infer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Extractor is recorded on the nodes and links inference creates
+const Extractor = "json-rules"
+
+// DefaultMaxNodes caps the nodes inferred from one document
+const DefaultMaxNodes = 10000
+
+// Node is a typed node inferred from a JSON object
+type Node struct {
+	ID   string                 `json:"id"`
+	Type string                 `json:"type"`
+	Rule string                 `json:"rule"`
+	Path string                 `json:"path"` // Where the object sits, e.g. $.members[0]
+	Meta map[string]interface{} `json:"meta"`
+}
+
+// Link is a relation between two inferred nodes, from an object to one
+// nested in it
+type Link struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// Plan is what inference found in a document
+type Plan struct {
+	Nodes     []*Node `json:"nodes"`
+	Links     []Link  `json:"links"`
+	Truncated bool    `json:"truncated,omitempty"` // The node cap was reached
+}
+
+// Infer types the objects in a decoded JSON document by rules, which must
+// be valid and in the order they are tried. Each object becomes a node of
+// the first rule matching it; an object matching no rule is looked
+// through, so its nested objects can still match. A typed object nested
+// in another is linked from it. Objects naming the same node are merged,
+// the first one's values winning.
+func Infer(doc interface{}, rules []*Rule, maxNodes int) *Plan {
+	if maxNodes <= 0 {
+		maxNodes = DefaultMaxNodes
+	}
+	compiledRules := make([]compiled, 0, len(rules))
+	for _, r := range rules {
+		if !r.Disabled {
+			compiledRules = append(compiledRules, r.compile())
+		}
+	}
+
+	plan := &Plan{Nodes: []*Node{}, Links: []Link{}}
+	byID := make(map[string]*Node)
+	linked := make(map[Link]bool)
+
+	var walk func(value interface{}, loc location, parent string)
+	walk = func(value interface{}, loc location, parent string) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if rule := firstMatch(compiledRules, v, loc); rule != nil {
+				node := newNode(rule, v, loc)
+				if existing, ok := byID[node.ID]; ok {
+					for k, val := range node.Meta {
+						if _, ok := existing.Meta[k]; !ok {
+							existing.Meta[k] = val
+						}
+					}
+				} else if len(plan.Nodes) < maxNodes {
+					byID[node.ID] = node
+					plan.Nodes = append(plan.Nodes, node)
+				} else {
+					plan.Truncated = true
+					return
+				}
+				if parent != "" && parent != node.ID {
+					link := Link{Source: parent, Target: node.ID, Type: linkType(rule.Rule, loc)}
+					if !linked[link] {
+						linked[link] = true
+						plan.Links = append(plan.Links, link)
+					}
+				}
+				parent = node.ID
+			}
+			for _, key := range sortedKeys(v) {
+				walk(v[key], append(loc[:len(loc):len(loc)], step{key: key, index: -1}), parent)
+			}
+		case []interface{}:
+			for i, item := range v {
+				walk(item, append(loc[:len(loc):len(loc)], step{index: i}), parent)
+			}
+		}
+	}
+	walk(doc, location{}, "")
+	return plan
+}
+
+// firstMatch returns the first rule an object matches, or nil
+func firstMatch(rules []compiled, obj map[string]interface{}, loc location) *compiled {
+	for i := range rules {
+		r := &rules[i]
+		if !matchPath(r.path, loc) {
+			continue
+		}
+		ok := true
+		for _, key := range r.Has {
+			if obj[key] == nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// newNode builds the node a rule makes of an object
+func newNode(rule *compiled, obj map[string]interface{}, loc location) *Node {
+	node := &Node{Type: rule.Type, Rule: rule.ID, Path: loc.String(), Meta: map[string]interface{}{}}
+	keys := rule.Fields
+	if len(keys) == 0 {
+		keys = sortedKeys(obj)
+	}
+	for _, key := range keys {
+		if v, ok := scalar(obj[key]); ok {
+			node.Meta[key] = v
+		}
+	}
+	if name, ok := idValue(obj[rule.IDKey]); ok && rule.IDKey != "" {
+		node.ID = rule.prefix() + name
+	} else {
+		data, _ := json.Marshal(obj) // Keys are sorted, so equal objects hash the same
+		sum := sha256.Sum256(data)
+		node.ID = rule.prefix() + hex.EncodeToString(sum[:8])
+	}
+	node.Meta["extractor"] = Extractor
+	node.Meta["type_rule"] = rule.ID
+	node.Meta["json_path"] = node.Path
+	return node
+}
+
+// scalar returns a value copied to node properties: strings, numbers,
+// booleans and lists of them. Objects and nulls are left out.
+func scalar(v interface{}) (interface{}, bool) {
+	switch x := v.(type) {
+	case string, float64, bool, json.Number:
+		return x, true
+	case []interface{}:
+		for _, item := range x {
+			if _, ok := scalar(item); !ok {
+				return nil, false
+			}
+			if _, list := item.([]interface{}); list {
+				return nil, false
+			}
+		}
+		return x, true
+	}
+	return nil, false
+}
+
+// idValue renders a value naming a node
+func idValue(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		x = strings.TrimSpace(x)
+		return x, x != ""
+	case float64, bool, json.Number:
+		return fmt.Sprint(x), true
+	}
+	return "", false
+}
+
+// linkType returns the type of the link from the enclosing typed node: the
+// rule's parent_link, else the key holding the object in upper snake case,
+// such as MEMBERS
+func linkType(rule *Rule, loc location) string {
+	if rule.ParentLink != "" {
+		return rule.ParentLink
+	}
+	var b strings.Builder
+	prev := ' '
+	for _, r := range loc.lastKey() {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) && unicode.IsLower(prev) {
+				b.WriteByte('_') // memberOf -> MEMBER_OF
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+		prev = r
+	}
+	name := strings.TrimRight(b.String(), "_")
+	if !validLinkType.MatchString(name) {
+		return "HAS"
+	}
+	return name
+}
+
+// sortedKeys returns an object's keys in order
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
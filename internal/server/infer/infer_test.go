@@ -0,0 +1,136 @@
+package infer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+const export = `{
+  "company": {"name": "Acme", "domain": "acme.test", "address": {"city": "Oslo"},
+    "employees": [
+      {"name": "Ada", "email": "ada@acme.test", "tags": ["eng", "lead"]},
+      {"name": "Bob", "email": "bob@acme.test", "reportsTo": {"name": "Ada", "email": "ada@acme.test"}}
+    ]},
+  "meta": {"exported": 1700000000}
+}`
+
+func TestPaths(t *testing.T) {
+	loc := location{{key: "company", index: -1}, {key: "employees", index: -1}, {index: 1}}
+	for path, want := range map[string]bool{
+		"$.company.employees[*]":   true,
+		"$..employees[1]":          true,
+		"$..employees[0]":          false,
+		"$['company']..*":          true,
+		"$.company":                false,
+		"$..company":               false,
+		"$.*.employees[*]":         true,
+		`$["company"].employees.*`: true,
+		"$..[1]":                   true,
+	} {
+		segs, err := compilePath(path)
+		if err != nil {
+			t.Errorf("compilePath(%q): %v", path, err)
+			continue
+		}
+		if got := matchPath(segs, loc); got != want {
+			t.Errorf("%s matches %s = %v, want %v", path, loc, got, want)
+		}
+	}
+	for _, bad := range []string{"company", "$..", "$[x]", "$.a b", "$[1"} {
+		if _, err := compilePath(bad); err == nil {
+			t.Errorf("compilePath(%q) succeeded", bad)
+		}
+	}
+	if got := loc.String(); got != "$.company.employees[1]" {
+		t.Errorf("String = %s", got)
+	}
+}
+
+func TestInferAndApply(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	rules := []*Rule{
+		{ID: "person", Has: []string{"email"}, Type: "Person", IDKey: "email"},
+		{ID: "company", Path: "$..company", Has: []string{"name"}, Type: "Organization", IDKey: "domain", IDPrefix: "org:"},
+		{ID: "reports", Path: "$..reportsTo", Has: []string{"email"}, Type: "Person", IDKey: "email", ParentLink: "REPORTS_TO", Priority: 1},
+	}
+	for _, r := range rules {
+		if err := Save(ctx, repo, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored, err := List(ctx, repo)
+	if err != nil || len(stored) != 3 || stored[0].ID != "reports" {
+		t.Fatalf("List = %v, %v; want reports first by priority", stored, err)
+	}
+	if err := (&Rule{ID: "x", Type: "Thing"}).Validate(); err == nil {
+		t.Error("a rule without path or has keys validated")
+	}
+
+	doc, err := Decode([]byte(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := Infer(doc, stored, 0)
+	ids := map[string]*Node{}
+	for _, n := range plan.Nodes {
+		ids[n.ID] = n
+	}
+	if len(plan.Nodes) != 3 || ids["org:acme.test"] == nil || ids["person:ada@acme.test"] == nil || ids["person:bob@acme.test"] == nil {
+		t.Fatalf("nodes = %+v", plan.Nodes)
+	}
+	ada := ids["person:ada@acme.test"]
+	if ada.Meta["name"] != "Ada" || len(ada.Meta["tags"].([]interface{})) != 2 || ada.Path != "$.company.employees[0]" {
+		t.Errorf("ada = %+v", ada)
+	}
+	if _, ok := ids["org:acme.test"].Meta["address"]; ok {
+		t.Error("nested object copied as a property")
+	}
+	links := map[Link]bool{}
+	for _, l := range plan.Links {
+		links[l] = true
+	}
+	for _, want := range []Link{
+		{Source: "org:acme.test", Target: "person:ada@acme.test", Type: "EMPLOYEES"},
+		{Source: "org:acme.test", Target: "person:bob@acme.test", Type: "EMPLOYEES"},
+		{Source: "person:bob@acme.test", Target: "person:ada@acme.test", Type: "REPORTS_TO"},
+	} {
+		if !links[want] {
+			t.Errorf("missing link %+v in %+v", want, plan.Links)
+		}
+	}
+	if len(plan.Links) != 3 {
+		t.Errorf("links = %+v, want 3", plan.Links)
+	}
+
+	// Applying creates the nodes once, and links everything to the source
+	now := time.Now()
+	if err := repo.CreateNode(ctx, &core.Node{ID: "person:ada@acme.test", Type: "Person", Meta: map[string]interface{}{"name": "Ada Lovelace"}, Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	source := &core.Node{ID: "sha256:export", Type: "Source", Content: []byte(export), Meta: map[string]interface{}{"format": "json"}, Created: now, Modified: now}
+	if err := repo.CreateNode(ctx, source); err != nil {
+		t.Fatal(err)
+	}
+	p := NewProcessor(repo, Config{})
+	result, err := p.ProcessNode(ctx, source.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Nodes != 3 || result.Created != 2 || result.Links != 6 {
+		t.Errorf("result = %+v, want 3 nodes, 2 created, 6 links", result)
+	}
+	if existing, _ := repo.GetNode(ctx, "person:ada@acme.test"); existing.Meta["name"] != "Ada Lovelace" {
+		t.Errorf("existing node changed: %v", existing.Meta)
+	}
+	if again, err := p.ProcessNode(ctx, source.ID, false); again != nil || err != nil {
+		t.Errorf("second pass = %+v, %v; want skipped", again, err)
+	}
+	if again, err := p.ProcessNode(ctx, source.ID, true); err != nil || again.Created != 0 || again.Links != 0 {
+		t.Errorf("forced pass = %+v, %v; want nothing new", again, err)
+	}
+}
@@ -0,0 +1,161 @@
+package infer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path segment kinds
+const (
+	segKey     = iota // .name or ['name']
+	segAny            // .* or [*]
+	segIndex          // [n]
+	segDescend        // .. : any number of levels
+)
+
+// segment is one step of a compiled path
+type segment struct {
+	kind  int
+	key   string
+	index int
+}
+
+// step is one step of a concrete location in a document: an object key
+// or an array index
+type step struct {
+	key   string
+	index int // -1 for object keys
+}
+
+// location is where a value sits in a document
+type location []step
+
+// String renders a location as a JSONPath, such as $.contacts[2].address
+func (l location) String() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, s := range l {
+		if s.index >= 0 {
+			b.WriteString("[" + strconv.Itoa(s.index) + "]")
+		} else if plainKey(s.key) {
+			b.WriteString("." + s.key)
+		} else {
+			b.WriteString("[" + strconv.Quote(s.key) + "]")
+		}
+	}
+	return b.String()
+}
+
+// lastKey returns the last object key in a location, or ""
+func (l location) lastKey() string {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].index < 0 {
+			return l[i].key
+		}
+	}
+	return ""
+}
+
+// plainKey reports whether a key can be written after a dot
+func plainKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// compilePath parses the JSONPath subset rules use: $ followed by .name,
+// ['name'], .*, [*], [n] and .. for any depth, as in $..contacts[*]
+func compilePath(path string) ([]segment, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+	var segs []segment
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segs = append(segs, segment{kind: segDescend})
+			rest = rest[2:]
+			if rest == "" || rest[0] == '.' {
+				return nil, fmt.Errorf("path %q: .. must be followed by a key, * or [", path)
+			}
+			if rest[0] != '[' {
+				rest = "." + rest
+			}
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			rest = rest[end+1:]
+			switch {
+			case name == "*":
+				segs = append(segs, segment{kind: segAny})
+			case plainKey(name):
+				segs = append(segs, segment{kind: segKey, key: name})
+			default:
+				return nil, fmt.Errorf("path %q: invalid key %q; use ['...'] for other keys", path, name)
+			}
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unclosed [", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				segs = append(segs, segment{kind: segAny})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segs = append(segs, segment{kind: segKey, key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("path %q: invalid index [%s]", path, inner)
+				}
+				segs = append(segs, segment{kind: segIndex, index: n})
+			}
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", path, rest)
+		}
+	}
+	return segs, nil
+}
+
+// matchPath reports whether a compiled path selects a location
+func matchPath(segs []segment, loc location) bool {
+	if len(segs) == 0 {
+		return len(loc) == 0
+	}
+	seg := segs[0]
+	if seg.kind == segDescend {
+		for i := 0; i <= len(loc); i++ {
+			if matchPath(segs[1:], loc[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(loc) == 0 {
+		return false
+	}
+	switch seg.kind {
+	case segKey:
+		if loc[0].index >= 0 || loc[0].key != seg.key {
+			return false
+		}
+	case segIndex:
+		if loc[0].index != seg.index {
+			return false
+		}
+	}
+	return matchPath(segs[1:], loc[1:])
+}
@@ -0,0 +1,218 @@
+package infer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Format is the Source format inference applies to
+const Format = "json"
+
+// inferredAtKey marks sources that have been typed
+const inferredAtKey = "types_inferred_at"
+
+// Config holds type inference configuration
+type Config struct {
+	MaxNodes int // Nodes inferred per document (default DefaultMaxNodes)
+	Timeout  time.Duration
+}
+
+// Result summarizes what inference made of one source
+type Result struct {
+	SourceID  string `json:"source_id"`
+	Nodes     int    `json:"nodes"`   // Inferred, including ones already in the graph
+	Created   int    `json:"created"` // New nodes
+	Links     int    `json:"links"`   // Relations and EXTRACTED_FROM links created
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// Processor types JSON sources as they are ingested
+type Processor struct {
+	repo      graph.Repository
+	cfg       Config
+	eventChan chan queuedEvent
+	hold      func(nodeID string) (release func())
+	wg        sync.WaitGroup
+}
+
+// queuedEvent is a queued source with the release for its processing hold
+type queuedEvent struct {
+	event   subscriptions.Event
+	release func()
+}
+
+// NewProcessor creates a new type inference processor
+func NewProcessor(repo graph.Repository, cfg Config) *Processor {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	return &Processor{
+		repo:      repo,
+		cfg:       cfg,
+		eventChan: make(chan queuedEvent, 100),
+	}
+}
+
+// Start begins processing events
+func (p *Processor) Start() {
+	p.wg.Add(1)
+	go p.processEvents()
+	log.Printf("JSON type inference started")
+}
+
+// Stop waits for queued sources to finish processing
+func (p *Processor) Stop() {
+	close(p.eventChan)
+	p.wg.Wait()
+}
+
+// SetHold registers a callback invoked for each queued source; the
+// returned release is called once it has been processed. Must be called
+// before Start.
+func (p *Processor) SetHold(hold func(nodeID string) (release func())) {
+	p.hold = hold
+}
+
+// EmitEvent queues a new source for processing (non-blocking)
+func (p *Processor) EmitEvent(event subscriptions.Event) {
+	if event.Type != subscriptions.EventNodeCreated || event.NodeType != "Source" {
+		return
+	}
+	if format, _ := event.Meta["format"].(string); !strings.EqualFold(format, Format) {
+		return
+	}
+	release := func() {}
+	if p.hold != nil {
+		release = p.hold(event.NodeID)
+	}
+	select {
+	case p.eventChan <- queuedEvent{event: event, release: release}:
+	default:
+		release()
+		log.Printf("Warning: type inference queue full, skipping source %s", event.NodeID)
+	}
+}
+
+// processEvents is the main processing loop
+func (p *Processor) processEvents() {
+	defer p.wg.Done()
+
+	for queued := range p.eventChan {
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		if _, err := p.ProcessNode(ctx, queued.event.NodeID, false); err != nil {
+			log.Printf("Type inference failed for %s: %v", queued.event.NodeID, err)
+		}
+		cancel()
+		queued.release()
+	}
+}
+
+// ProcessNode types a JSON source by the stored rules: each inferred node
+// is created, or linked if a node with its ID exists, EXTRACTED_FROM the
+// source, along with the relations between them. Sources that are not
+// JSON are skipped (nil result), as are sources already typed unless force
+// is set.
+func (p *Processor) ProcessNode(ctx context.Context, id string, force bool) (*Result, error) {
+	node, err := p.repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if format, _ := node.Meta["format"].(string); !strings.EqualFold(format, Format) {
+		return nil, nil
+	}
+	if _, done := node.Meta[inferredAtKey]; done && !force {
+		return nil, nil
+	}
+
+	doc, err := Decode(node.Content)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := List(ctx, p.repo)
+	if err != nil {
+		return nil, err
+	}
+	plan := Infer(doc, rules, p.cfg.MaxNodes)
+	result, err := Apply(ctx, p.repo, node.ID, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]any{inferredAtKey: time.Now().Format(time.RFC3339), "inferred_nodes": result.Nodes}
+	if err := p.repo.UpdateNodeMetaWithNote(ctx, id, meta, "Types inferred", "infer"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Decode reads a JSON document, keeping numbers as written
+func Decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding JSON source: %w", err)
+	}
+	return doc, nil
+}
+
+// Apply writes a plan inferred from source to the graph. Nodes already in
+// the graph are linked but not changed, and links already there are kept.
+func Apply(ctx context.Context, repo graph.Repository, sourceID string, plan *Plan) (*Result, error) {
+	result := &Result{SourceID: sourceID, Nodes: len(plan.Nodes), Truncated: plan.Truncated}
+	now := time.Now()
+	existing := make(map[string]map[[2]string]bool) // Node -> target and type of its links
+	link := func(l *core.Link) error {
+		have, ok := existing[l.Source]
+		if !ok {
+			links, err := repo.GetLinks(ctx, l.Source)
+			if err != nil {
+				return err
+			}
+			have = make(map[[2]string]bool, len(links))
+			for _, o := range links {
+				have[[2]string{o.Target, o.Type}] = true
+			}
+			existing[l.Source] = have
+		}
+		if have[[2]string{l.Target, l.Type}] {
+			return nil
+		}
+		if err := repo.CreateLink(ctx, l); err != nil {
+			return fmt.Errorf("linking %s -> %s: %w", l.Source, l.Target, err)
+		}
+		have[[2]string{l.Target, l.Type}] = true
+		result.Links++
+		return nil
+	}
+
+	for _, n := range plan.Nodes {
+		if _, err := repo.GetNode(ctx, n.ID); err != nil {
+			if err := repo.CreateNode(ctx, &core.Node{ID: n.ID, Type: n.Type, Meta: n.Meta, Created: now, Modified: now}); err != nil {
+				return result, fmt.Errorf("creating %s: %w", n.ID, err)
+			}
+			result.Created++
+		}
+		meta := map[string]interface{}{"extractor": Extractor, "json_path": n.Path}
+		if err := link(&core.Link{Source: n.ID, Target: sourceID, Type: "EXTRACTED_FROM", Meta: meta, Created: now, Modified: now}); err != nil {
+			return result, err
+		}
+	}
+	for _, l := range plan.Links {
+		meta := map[string]interface{}{"extractor": Extractor}
+		if err := link(&core.Link{Source: l.Source, Target: l.Target, Type: l.Type, Meta: meta, Created: now, Modified: now}); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
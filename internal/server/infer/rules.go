@@ -0,0 +1,220 @@
+// Package infer turns ingested JSON into typed nodes: user-defined rules
+// pick out the objects that are people, organizations and so on by where
+// they sit and which keys they have, and nested objects become relations.
+package infer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// NodeType is the node type rules are stored as
+const NodeType = "TypeRule"
+
+// idPrefix namespaces rule node IDs
+const idPrefix = "typerule:"
+
+// ErrNotFound is returned for unknown rules
+var ErrNotFound = errors.New("type rule not found")
+
+var (
+	validID       = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	validType     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+	validLinkType = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+)
+
+// Rule types the JSON objects it matches. An object matches when it sits
+// at Path and has every key in Has; the first matching rule by priority
+// wins.
+type Rule struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Disabled    bool      `json:"disabled,omitempty"`
+	Priority    int       `json:"priority,omitempty"` // Higher is tried first
+	Path        string    `json:"path,omitempty"`     // JSONPath of the objects, e.g. $..members[*]; default anywhere
+	Has         []string  `json:"has,omitempty"`      // Keys the object must have, non-null
+	Type        string    `json:"type"`               // Node type created
+	IDKey       string    `json:"id_key,omitempty"`   // Key whose value names the node, e.g. email; default a hash of the object
+	IDPrefix    string    `json:"id_prefix,omitempty"`
+	Fields      []string  `json:"fields,omitempty"`      // Keys copied to the node; default every scalar
+	ParentLink  string    `json:"parent_link,omitempty"` // Link from the enclosing typed node; default from the key holding the object
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+}
+
+// compiled is a rule with its path parsed
+type compiled struct {
+	*Rule
+	path []segment
+}
+
+// Validate checks a rule's ID, path, type and link
+func (r *Rule) Validate() error {
+	if !validID.MatchString(r.ID) {
+		return fmt.Errorf("invalid id %q (use 1-64 letters, digits, _ or -)", r.ID)
+	}
+	if !validType.MatchString(r.Type) {
+		return fmt.Errorf("invalid type %q (use 1-64 letters, digits or _, starting with a letter)", r.Type)
+	}
+	if r.Path != "" {
+		if _, err := compilePath(r.Path); err != nil {
+			return err
+		}
+	}
+	if r.Path == "" && len(r.Has) == 0 {
+		return fmt.Errorf("rules need a path or has keys")
+	}
+	if r.ParentLink != "" && !validLinkType.MatchString(r.ParentLink) {
+		return fmt.Errorf("invalid parent_link %q", r.ParentLink)
+	}
+	return nil
+}
+
+// compile parses a rule's path. The rule must be valid.
+func (r *Rule) compile() compiled {
+	c := compiled{Rule: r}
+	if r.Path != "" {
+		c.path, _ = compilePath(r.Path)
+	} else {
+		c.path = []segment{{kind: segDescend}}
+	}
+	return c
+}
+
+// prefix returns the ID prefix of the nodes a rule creates
+func (r *Rule) prefix() string {
+	if r.IDPrefix != "" {
+		return r.IDPrefix
+	}
+	return strings.ToLower(r.Type) + ":"
+}
+
+// Save validates and stores a new rule, generating an ID if needed
+func Save(ctx context.Context, repo graph.Repository, r *Rule) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	r.Created, r.Modified = now, now
+	meta, err := toMeta(r)
+	if err != nil {
+		return err
+	}
+	return repo.CreateNode(ctx, &core.Node{ID: idPrefix + r.ID, Type: NodeType, Meta: meta, Created: now, Modified: now})
+}
+
+// Update validates and replaces a rule's definition
+func Update(ctx context.Context, repo graph.Repository, r *Rule) error {
+	existing, err := Get(ctx, repo, r.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	r.Created, r.Modified = existing.Created, time.Now()
+	meta, err := toMeta(r)
+	if err != nil {
+		return err
+	}
+	// Absent optional fields are cleared rather than left from the old version
+	for _, key := range []string{"name", "description", "disabled", "priority", "path", "has", "id_key", "id_prefix", "fields", "parent_link"} {
+		if _, ok := meta[key]; !ok {
+			meta[key] = nil
+		}
+	}
+	return repo.UpdateNodeMeta(ctx, idPrefix+r.ID, meta)
+}
+
+// Get loads a rule
+func Get(ctx context.Context, repo graph.Repository, id string) (*Rule, error) {
+	node, err := repo.GetNode(ctx, idPrefix+id)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return fromNode(node)
+}
+
+// List returns all rules, in the order they are tried: by priority, then ID
+func List(ctx context.Context, repo graph.Repository) ([]*Rule, error) {
+	const pageSize = 500
+	out := []*Rule{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			r, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			out = append(out, r)
+		}
+		if len(nodes) < pageSize {
+			break
+		}
+	}
+	Sort(out)
+	return out, nil
+}
+
+// Sort orders rules the way they are tried
+func Sort(list []*Rule) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Priority != list[j].Priority {
+			return list[i].Priority > list[j].Priority
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+// Delete removes a rule and its history. Nodes it typed stay.
+func Delete(ctx context.Context, repo graph.Repository, id string) error {
+	if _, err := Get(ctx, repo, id); err != nil {
+		return err
+	}
+	return repo.DeleteNode(ctx, idPrefix+id, true)
+}
+
+// toMeta stores a rule's fields as node properties
+func toMeta(r *Rule) (map[string]interface{}, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	delete(meta, "id")
+	return meta, nil
+}
+
+// fromNode reads a rule back from its node
+func fromNode(node *core.Node) (*Rule, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var r Rule
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("corrupt type rule %s: %w", node.ID, err)
+	}
+	r.ID = strings.TrimPrefix(node.ID, idPrefix)
+	return &r, nil
+}
@@ -0,0 +1,331 @@
+// Package ingest reports when a Source node and everything derived from it
+// has finished processing, via an outgoing webhook.
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// EventCompleted is the X-Memex-Event header value of completion webhooks
+const EventCompleted = "ingest.completed"
+
+// Completion reasons
+const (
+	ReasonSettled  = "settled"  // No derived activity for the settle period
+	ReasonTimeout  = "timeout"  // MaxWait elapsed while activity continued
+	ReasonExplicit = "explicit" // The pipeline declared the source complete
+)
+
+// derivationLinkTypes make the link's source a derived node of its target
+var derivationLinkTypes = map[string]bool{"EXTRACTED_FROM": true, "DERIVED_FROM": true}
+
+// Repository is the subset of graph operations the tracker needs
+type Repository interface {
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+}
+
+// Config holds webhook and timing configuration
+type Config struct {
+	WebhookURL string
+	Settle     time.Duration // Quiet period after the last derived activity (default 30s)
+	MaxWait    time.Duration // Fire even if activity continues (default 10m)
+	Timeout    time.Duration // Per-request webhook timeout (default 10s)
+}
+
+// ManifestNode is a node derived from the source
+type ManifestNode struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+// ManifestLink is a link created while processing the source
+type ManifestLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// Manifest is the webhook payload for a completed source
+type Manifest struct {
+	Event       string         `json:"event"`
+	SourceID    string         `json:"source_id"`
+	Reason      string         `json:"reason"`
+	StartedAt   time.Time      `json:"started_at"`
+	CompletedAt time.Time      `json:"completed_at"`
+	Nodes       []ManifestNode `json:"nodes"`
+	Links       []ManifestLink `json:"links"`
+}
+
+// pending tracks one source until it completes
+type pending struct {
+	sourceID     string
+	started      time.Time
+	lastActivity time.Time
+	holds        int
+	nodes        []string
+	links        []ManifestLink
+}
+
+// Tracker follows Source nodes through derivation and fires a webhook with
+// the derived nodes and links once processing goes quiet
+type Tracker struct {
+	repo       Repository
+	cfg        Config
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	pending map[string]*pending // By source ID
+	owner   map[string]string   // Tracked node ID -> source ID
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTracker creates a completion tracker
+func NewTracker(repo Repository, cfg Config) *Tracker {
+	if cfg.Settle == 0 {
+		cfg.Settle = 30 * time.Second
+	}
+	if cfg.MaxWait == 0 {
+		cfg.MaxWait = 10 * time.Minute
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &Tracker{
+		repo:       repo,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		pending:    make(map[string]*pending),
+		owner:      make(map[string]string),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start begins checking for completed sources
+func (t *Tracker) Start() {
+	interval := t.cfg.Settle / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				t.check(now)
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Ingest completion webhook enabled (%s, settle %s)", t.cfg.WebhookURL, t.cfg.Settle)
+}
+
+// Stop halts checking and waits for in-flight webhooks
+func (t *Tracker) Stop() {
+	close(t.stop)
+	t.wg.Wait()
+}
+
+// EmitEvent records graph activity (non-blocking)
+func (t *Tracker) EmitEvent(event subscriptions.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+
+	switch event.Type {
+	case subscriptions.EventNodeCreated:
+		if event.NodeType == "Source" || strings.HasPrefix(event.NodeID, "sha256:") {
+			if _, tracked := t.owner[event.NodeID]; !tracked {
+				t.pending[event.NodeID] = &pending{sourceID: event.NodeID, started: now, lastActivity: now}
+				t.owner[event.NodeID] = event.NodeID
+			}
+		}
+
+	case subscriptions.EventNodeUpdated:
+		if p := t.pendingFor(event.NodeID); p != nil {
+			p.lastActivity = now
+		}
+
+	case subscriptions.EventLinkCreated:
+		p := t.pendingFor(event.LinkTarget)
+		if p != nil && derivationLinkTypes[event.LinkType] {
+			if _, tracked := t.owner[event.LinkSource]; !tracked {
+				t.owner[event.LinkSource] = p.sourceID
+				p.nodes = append(p.nodes, event.LinkSource)
+			}
+		}
+		if p == nil {
+			p = t.pendingFor(event.LinkSource)
+		}
+		if p != nil {
+			p.links = append(p.links, ManifestLink{Source: event.LinkSource, Target: event.LinkTarget, Type: event.LinkType})
+			p.lastActivity = now
+		}
+	}
+}
+
+// Hold delays completion of the source a node belongs to until the returned
+// release is called. In-process processors hold nodes they have queued.
+func (t *Tracker) Hold(nodeID string) (release func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.pendingFor(nodeID)
+	if p == nil {
+		return func() {}
+	}
+	p.holds++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			p.holds--
+			p.lastActivity = time.Now()
+			t.mu.Unlock()
+		})
+	}
+}
+
+// Complete fires the webhook for a source immediately. It reports false
+// when the source is not being tracked.
+func (t *Tracker) Complete(sourceID string) bool {
+	t.mu.Lock()
+	p, ok := t.pending[sourceID]
+	if ok {
+		t.remove(p)
+	}
+	t.mu.Unlock()
+
+	if ok {
+		t.fire(p, ReasonExplicit)
+	}
+	return ok
+}
+
+// check fires sources that have settled or exceeded MaxWait
+func (t *Tracker) check(now time.Time) {
+	type due struct {
+		p      *pending
+		reason string
+	}
+	var ready []due
+
+	t.mu.Lock()
+	for _, p := range t.pending {
+		switch {
+		case now.Sub(p.started) >= t.cfg.MaxWait:
+			ready = append(ready, due{p, ReasonTimeout})
+		case p.holds == 0 && now.Sub(p.lastActivity) >= t.cfg.Settle:
+			ready = append(ready, due{p, ReasonSettled})
+		}
+	}
+	for _, d := range ready {
+		t.remove(d.p)
+	}
+	t.mu.Unlock()
+
+	for _, d := range ready {
+		t.fire(d.p, d.reason)
+	}
+}
+
+// pendingFor returns the pending source a tracked node belongs to; callers hold t.mu
+func (t *Tracker) pendingFor(nodeID string) *pending {
+	sourceID, ok := t.owner[nodeID]
+	if !ok {
+		return nil
+	}
+	return t.pending[sourceID]
+}
+
+// remove stops tracking a source and its derived nodes; callers hold t.mu
+func (t *Tracker) remove(p *pending) {
+	delete(t.pending, p.sourceID)
+	delete(t.owner, p.sourceID)
+	for _, id := range p.nodes {
+		delete(t.owner, id)
+	}
+}
+
+// fire builds the manifest and delivers it in the background
+func (t *Tracker) fire(p *pending, reason string) {
+	manifest := Manifest{
+		Event:       EventCompleted,
+		SourceID:    p.sourceID,
+		Reason:      reason,
+		StartedAt:   p.started,
+		CompletedAt: time.Now(),
+		Nodes:       make([]ManifestNode, 0, len(p.nodes)),
+		Links:       p.links,
+	}
+	if manifest.Links == nil {
+		manifest.Links = []ManifestLink{}
+	}
+	for _, id := range p.nodes {
+		node := ManifestNode{ID: id}
+		if n, err := t.repo.GetNode(context.Background(), id); err == nil {
+			node.Type = n.Type
+		}
+		manifest.Nodes = append(manifest.Nodes, node)
+	}
+	sort.Slice(manifest.Nodes, func(i, j int) bool { return manifest.Nodes[i].ID < manifest.Nodes[j].ID })
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		if err := t.send(manifest); err != nil {
+			log.Printf("Ingest completion webhook failed for %s: %v", manifest.SourceID, err)
+		}
+	}()
+}
+
+// send POSTs the manifest, retrying with backoff like subscription webhooks
+func (t *Tracker) send(manifest Manifest) error {
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt*attempt) * time.Second)
+		}
+
+		req, err := http.NewRequest("POST", t.cfg.WebhookURL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Memex-Event", EventCompleted)
+
+		resp, err := t.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return lastErr
+}
@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestTrackerFiresManifest(t *testing.T) {
+	received := make(chan Manifest, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Memex-Event") != EventCompleted {
+			t.Errorf("unexpected event header %q", r.Header.Get("X-Memex-Event"))
+		}
+		var m Manifest
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		received <- m
+	}))
+	defer hook.Close()
+
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	tracker := NewTracker(repo, Config{WebhookURL: hook.URL, Settle: 200 * time.Millisecond})
+	repo.SetEventEmitter(tracker.EmitEvent)
+	tracker.Start()
+	defer tracker.Stop()
+
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "sha256:abc", Type: "Source", Content: []byte("Ada Lovelace wrote the first program.")},
+		{ID: "person:ada", Type: "Person"},
+		{ID: "unrelated", Type: "Note"},
+	} {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A processor still working on the derived node delays completion
+	link := &core.Link{Source: "person:ada", Target: "sha256:abc", Type: "EXTRACTED_FROM", Created: now, Modified: now}
+	if err := repo.CreateLink(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+	release := tracker.Hold("person:ada")
+	select {
+	case m := <-received:
+		t.Fatalf("webhook fired while held: %+v", m)
+	case <-time.After(500 * time.Millisecond):
+	}
+	release()
+
+	select {
+	case m := <-received:
+		if m.SourceID != "sha256:abc" || m.Reason != ReasonSettled {
+			t.Errorf("unexpected manifest %+v", m)
+		}
+		if len(m.Nodes) != 1 || m.Nodes[0].ID != "person:ada" || m.Nodes[0].Type != "Person" {
+			t.Errorf("expected person:ada as the derived node, got %+v", m.Nodes)
+		}
+		if len(m.Links) != 1 || m.Links[0].Type != "EXTRACTED_FROM" {
+			t.Errorf("expected the EXTRACTED_FROM link, got %+v", m.Links)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not fired after settling")
+	}
+
+	if tracker.Complete("sha256:abc") {
+		t.Error("completed source should no longer be pending")
+	}
+}
@@ -0,0 +1,196 @@
+// Package lanes limits concurrent API requests per traffic class, so bulk
+// pipelines (imports, backfills) queue among themselves and never take the
+// capacity interactive clients (the REPL, the visualization) need.
+package lanes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Lanes
+const (
+	Interactive = "interactive"
+	Bulk        = "bulk"
+)
+
+// Names lists the lanes
+var Names = []string{Interactive, Bulk}
+
+// Errors returned by Acquire
+var (
+	ErrQueueFull = errors.New("too many requests waiting in lane")
+	ErrWait      = errors.New("timed out waiting in lane")
+)
+
+// Valid reports whether name is a lane
+func Valid(name string) bool {
+	return name == Interactive || name == Bulk
+}
+
+// LaneConfig bounds one lane
+type LaneConfig struct {
+	Limit int           // Requests served at once
+	Queue int           // Requests waiting for a slot; more are refused. Negative for none
+	Wait  time.Duration // Longest wait for a slot
+}
+
+// Config holds both lanes' bounds
+type Config struct {
+	Interactive LaneConfig // Defaults: 64 at once, 256 waiting, 10s
+	Bulk        LaneConfig // Defaults: 4 at once, 64 waiting, 60s
+}
+
+// Stats reports one lane's load
+type Stats struct {
+	Limit    int   `json:"limit"`
+	Active   int   `json:"active"`
+	Waiting  int   `json:"waiting"`
+	Served   int64 `json:"served"`
+	Rejected int64 `json:"rejected"` // Queue full or waited too long
+	WaitMS   int64 `json:"wait_ms"`  // Total time spent waiting for slots
+}
+
+type lane struct {
+	cfg   LaneConfig
+	slots chan struct{}
+
+	mu       sync.Mutex
+	waiting  int
+	served   int64
+	rejected int64
+	waited   time.Duration
+}
+
+// Limiter admits requests to their lanes
+type Limiter struct {
+	lanes map[string]*lane
+}
+
+// New creates a limiter, filling in defaults
+func New(cfg Config) *Limiter {
+	defaults := map[string]LaneConfig{
+		Interactive: {Limit: 64, Queue: 256, Wait: 10 * time.Second},
+		Bulk:        {Limit: 4, Queue: 64, Wait: 60 * time.Second},
+	}
+	l := &Limiter{lanes: make(map[string]*lane)}
+	for name, c := range map[string]LaneConfig{Interactive: cfg.Interactive, Bulk: cfg.Bulk} {
+		d := defaults[name]
+		if c.Limit <= 0 {
+			c.Limit = d.Limit
+		}
+		if c.Queue < 0 {
+			c.Queue = 0
+		} else if c.Queue == 0 {
+			c.Queue = d.Queue
+		}
+		if c.Wait <= 0 {
+			c.Wait = d.Wait
+		}
+		l.lanes[name] = &lane{cfg: c, slots: make(chan struct{}, c.Limit)}
+	}
+	return l
+}
+
+// Acquire waits for a slot in the named lane and returns the function that
+// frees it. It fails with ErrQueueFull when too many requests are waiting,
+// ErrWait when no slot frees in time, or the context's error.
+func (l *Limiter) Acquire(ctx context.Context, name string) (func(), error) {
+	ln, ok := l.lanes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown lane %q", name)
+	}
+	select {
+	case ln.slots <- struct{}{}:
+		ln.admitted()
+		return ln.release, nil
+	default:
+	}
+
+	ln.mu.Lock()
+	if ln.waiting >= ln.cfg.Queue {
+		ln.rejected++
+		ln.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	ln.waiting++
+	ln.mu.Unlock()
+
+	start := time.Now()
+	timer := time.NewTimer(ln.cfg.Wait)
+	defer timer.Stop()
+	var err error
+	select {
+	case ln.slots <- struct{}{}:
+	case <-timer.C:
+		err = ErrWait
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	ln.mu.Lock()
+	ln.waiting--
+	ln.waited += time.Since(start)
+	if err != nil {
+		ln.rejected++
+	}
+	ln.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	ln.admitted()
+	return ln.release, nil
+}
+
+// admitted counts a request let in
+func (ln *lane) admitted() {
+	ln.mu.Lock()
+	ln.served++
+	ln.mu.Unlock()
+}
+
+func (ln *lane) release() {
+	<-ln.slots
+}
+
+// Stats returns each lane's load
+func (l *Limiter) Stats() map[string]Stats {
+	out := make(map[string]Stats, len(l.lanes))
+	for name, ln := range l.lanes {
+		ln.mu.Lock()
+		out[name] = Stats{
+			Limit:    ln.cfg.Limit,
+			Active:   len(ln.slots),
+			Waiting:  ln.waiting,
+			Served:   ln.served,
+			Rejected: ln.rejected,
+			WaitMS:   ln.waited.Milliseconds(),
+		}
+		ln.mu.Unlock()
+	}
+	return out
+}
+
+// WriteMetrics writes each lane's load in the Prometheus text format
+func (l *Limiter) WriteMetrics(w io.Writer) error {
+	stats := l.Stats()
+	var b strings.Builder
+	metric := func(name, kind, help string, value func(s Stats) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, lane := range Names {
+			fmt.Fprintf(&b, "%s{lane=%q} %g\n", name, lane, value(stats[lane]))
+		}
+	}
+	metric("memex_lane_active_requests", "gauge", "Requests being served, by lane.", func(s Stats) float64 { return float64(s.Active) })
+	metric("memex_lane_waiting_requests", "gauge", "Requests waiting for a slot, by lane.", func(s Stats) float64 { return float64(s.Waiting) })
+	metric("memex_lane_requests_total", "counter", "Requests admitted, by lane.", func(s Stats) float64 { return float64(s.Served) })
+	metric("memex_lane_rejected_total", "counter", "Requests refused because the lane's queue was full or the wait too long, by lane.", func(s Stats) float64 { return float64(s.Rejected) })
+	metric("memex_lane_wait_seconds_total", "counter", "Time requests spent waiting for a slot, by lane.", func(s Stats) float64 { return float64(s.WaitMS) / 1000 })
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,91 @@
+package lanes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	l := New(Config{
+		Interactive: LaneConfig{Limit: 2},
+		Bulk:        LaneConfig{Limit: 1, Queue: 1, Wait: 20 * time.Millisecond},
+	})
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx, Bulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A full bulk lane leaves the interactive lane alone
+	for i := 0; i < 2; i++ {
+		done, err := l.Acquire(ctx, Interactive)
+		if err != nil {
+			t.Fatalf("interactive: %v", err)
+		}
+		defer done()
+	}
+
+	// One request may wait; it times out while the slot stays taken
+	waited := make(chan error)
+	go func() {
+		_, err := l.Acquire(ctx, Bulk)
+		waited <- err
+	}()
+	for l.Stats()[Bulk].Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := l.Acquire(ctx, Bulk); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("second waiter: %v, want ErrQueueFull", err)
+	}
+	if err := <-waited; !errors.Is(err, ErrWait) {
+		t.Errorf("waiter: %v, want ErrWait", err)
+	}
+
+	// A waiter gets the slot once it is freed
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		release()
+	}()
+	done, err := l.Acquire(ctx, Bulk)
+	if err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+	done()
+
+	if _, err := l.Acquire(ctx, "batch"); err == nil {
+		t.Error("unknown lane accepted")
+	}
+
+	stats := l.Stats()
+	if s := stats[Bulk]; s.Limit != 1 || s.Active != 0 || s.Served != 2 || s.Rejected != 2 {
+		t.Errorf("bulk stats = %+v", s)
+	}
+	if s := stats[Interactive]; s.Active != 2 || s.Served != 2 || s.Rejected != 0 {
+		t.Errorf("interactive stats = %+v", s)
+	}
+
+	var out strings.Builder
+	if err := l.WriteMetrics(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `memex_lane_rejected_total{lane="bulk"} 2`) ||
+		!strings.Contains(out.String(), `memex_lane_active_requests{lane="interactive"} 2`) {
+		t.Errorf("metrics:\n%s", out.String())
+	}
+}
+
+func TestNoQueue(t *testing.T) {
+	l := New(Config{Bulk: LaneConfig{Limit: 1, Queue: -1}})
+	release, err := l.Acquire(context.Background(), Bulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if _, err := l.Acquire(context.Background(), Bulk); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("got %v, want ErrQueueFull", err)
+	}
+}
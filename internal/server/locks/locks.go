@@ -0,0 +1,116 @@
+// Package locks keeps advisory check-out locks on nodes so collaborators
+// editing the same node do not overwrite each other's versions. Locks live
+// in memory and expire on their own; a restart releases them all.
+package locks
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TTL bounds for a lock
+const (
+	DefaultTTL = 5 * time.Minute
+	MaxTTL     = time.Hour
+)
+
+// Errors returned by the manager
+var (
+	ErrLocked  = errors.New("node is locked")
+	ErrNotHeld = errors.New("lock not held")
+)
+
+// Lock is an advisory check-out of a node by one owner
+type Lock struct {
+	NodeID   string    `json:"node_id"`
+	Owner    string    `json:"owner"`
+	Acquired time.Time `json:"acquired"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Manager holds the current locks; the zero value is ready to use
+type Manager struct {
+	mu    sync.Mutex
+	locks map[string]*Lock
+}
+
+// Acquire locks a node for owner, or extends the owner's existing lock.
+// It fails with ErrLocked while another owner holds an unexpired lock.
+func (m *Manager) Acquire(nodeID, owner string, ttl time.Duration, now time.Time) (Lock, error) {
+	if owner == "" {
+		return Lock{}, fmt.Errorf("owner is required")
+	}
+	if ttl <= 0 || ttl > MaxTTL {
+		return Lock{}, fmt.Errorf("ttl must be positive and at most %s", MaxTTL)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks == nil {
+		m.locks = make(map[string]*Lock)
+	}
+	m.prune(now)
+
+	lock := m.locks[nodeID]
+	switch {
+	case lock == nil:
+		lock = &Lock{NodeID: nodeID, Owner: owner, Acquired: now}
+		m.locks[nodeID] = lock
+	case lock.Owner != owner:
+		return *lock, lockedError(lock)
+	}
+	lock.Expires = now.Add(ttl)
+	return *lock, nil
+}
+
+// Release removes owner's lock on a node; force removes anyone's lock
+func (m *Manager) Release(nodeID, owner string, force bool, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+
+	lock := m.locks[nodeID]
+	if lock == nil {
+		return fmt.Errorf("%w: %s is not locked", ErrNotHeld, nodeID)
+	}
+	if lock.Owner != owner && !force {
+		return lockedError(lock)
+	}
+	delete(m.locks, nodeID)
+	return nil
+}
+
+// Get returns the unexpired lock on a node, if any
+func (m *Manager) Get(nodeID string, now time.Time) *Lock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock := m.locks[nodeID]
+	if lock == nil || !now.Before(lock.Expires) {
+		return nil
+	}
+	copied := *lock
+	return &copied
+}
+
+// Check returns ErrLocked when someone other than editor holds the node
+func (m *Manager) Check(nodeID, editor string, now time.Time) error {
+	if lock := m.Get(nodeID, now); lock != nil && lock.Owner != editor {
+		return lockedError(lock)
+	}
+	return nil
+}
+
+// prune drops expired locks
+func (m *Manager) prune(now time.Time) {
+	for id, lock := range m.locks {
+		if !now.Before(lock.Expires) {
+			delete(m.locks, id)
+		}
+	}
+}
+
+func lockedError(lock *Lock) error {
+	return fmt.Errorf("%w by %s until %s", ErrLocked, lock.Owner, lock.Expires.Format(time.RFC3339))
+}
@@ -0,0 +1,48 @@
+package locks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	var m Manager
+	now := time.Now()
+
+	if _, err := m.Acquire("note:1", "ann", time.Minute, now); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := m.Acquire("note:1", "bob", time.Minute, now); !errors.Is(err, ErrLocked) {
+		t.Errorf("second owner got %v, want ErrLocked", err)
+	}
+	if err := m.Check("note:1", "bob", now); !errors.Is(err, ErrLocked) {
+		t.Errorf("Check for bob = %v, want ErrLocked", err)
+	}
+	if err := m.Check("note:1", "ann", now); err != nil {
+		t.Errorf("Check for the owner = %v", err)
+	}
+
+	// Renewing extends the lock; once it lapses another owner may take it
+	lock, err := m.Acquire("note:1", "ann", 2*time.Minute, now.Add(30*time.Second))
+	if err != nil || !lock.Expires.Equal(now.Add(150*time.Second)) || !lock.Acquired.Equal(now) {
+		t.Errorf("renewed lock = %+v, %v", lock, err)
+	}
+	later := now.Add(3 * time.Minute)
+	if m.Get("note:1", later) != nil {
+		t.Error("expired lock still reported")
+	}
+	if _, err := m.Acquire("note:1", "bob", time.Minute, later); err != nil {
+		t.Errorf("Acquire after expiry: %v", err)
+	}
+
+	if err := m.Release("note:1", "ann", false, later); !errors.Is(err, ErrLocked) {
+		t.Errorf("release by non-owner = %v, want ErrLocked", err)
+	}
+	if err := m.Release("note:1", "ann", true, later); err != nil {
+		t.Errorf("forced release: %v", err)
+	}
+	if err := m.Release("note:1", "bob", false, later); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("release of unlocked node = %v, want ErrNotHeld", err)
+	}
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// maxLLMText caps the node text sent to the model
+const maxLLMText = 16000
+
+// sentenceEnd matches sentence-ending punctuation and the space after it
+var sentenceEnd = regexp.MustCompile(`[.!?]\s`)
+
+// textKeys are the properties holding a node's prose, in preference order
+var textKeys = []string{"summary", "description", "abstract", "text", "content"}
+
+// skipKeys are properties left out of cards for nodes without prose
+var skipKeys = map[string]bool{"name": true, "title": true, "label": true}
+
+// distill builds a node's card, using summary when given
+func (s *Store) distill(ctx context.Context, node *core.Node, summary string) (*Card, error) {
+	now := time.Now()
+	card := &Card{
+		NodeID:         node.ID,
+		NodeType:       node.Type,
+		SourceModified: node.Modified,
+		Created:        now,
+		Modified:       now,
+	}
+	if title := graph.NodeLabel(node.ID, node.Meta); title != node.ID {
+		card.Title = title
+	} else {
+		card.Title = node.Type + " " + node.ID
+	}
+
+	switch text := nodeText(node); {
+	case summary != "":
+		card.Summary, card.Distiller = strings.TrimSpace(summary), DistillerManual
+	case s.cfg.LLMURL != "" && text != "":
+		llm, err := s.distillLLM(ctx, card.Title, text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDistill, err)
+		}
+		card.Summary, card.Distiller = llm, DistillerLLM
+	case text != "":
+		card.Summary, card.Distiller = leadingSentences(text, s.cfg.MaxSummary), DistillerExtract
+	default:
+		card.Summary, card.Distiller = describe(node, s.cfg.MaxSummary), DistillerExtract
+	}
+	card.Tokens = EstimateTokens(card.Text())
+	return card, nil
+}
+
+// nodeText returns a node's prose, or "" when it has none
+func nodeText(node *core.Node) string {
+	for _, key := range textKeys {
+		if text, ok := node.Meta[key].(string); ok && strings.TrimSpace(text) != "" {
+			return text
+		}
+	}
+	if len(node.Content) > 0 && isText(node.Content) {
+		return string(node.Content)
+	}
+	return ""
+}
+
+// isText reports whether content looks like text rather than binary data
+func isText(content []byte) bool {
+	sample := content
+	if len(sample) > 512 {
+		sample = sample[:512]
+	}
+	return !bytes.ContainsRune(sample, 0) && strings.HasPrefix(http.DetectContentType(sample), "text/")
+}
+
+// leadingSentences returns whole sentences from the start of text up to
+// max characters, cutting the first sentence at a word if it alone is
+// longer
+func leadingSentences(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= max {
+		return text
+	}
+	out := ""
+	for _, m := range sentenceEnd.FindAllStringIndex(text, -1) {
+		end := m[0] + 1 // Keep the punctuation
+		if end > max {
+			break
+		}
+		out = text[:end]
+	}
+	if out == "" {
+		cut := strings.ToValidUTF8(text[:max], "")
+		if i := strings.LastIndex(cut, " "); i > 0 {
+			cut = cut[:i]
+		}
+		out = cut + "…"
+	}
+	return out
+}
+
+// describe summarizes a node without prose from its scalar properties
+func describe(node *core.Node, max int) string {
+	keys := make([]string, 0, len(node.Meta))
+	for key, value := range node.Meta {
+		switch value.(type) {
+		case string, float64, int, bool:
+			if !skipKeys[key] && !strings.HasPrefix(key, "_") {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	out := node.Type
+	for _, key := range keys {
+		next := fmt.Sprintf("%s; %s: %v", out, key, node.Meta[key])
+		if len(next) > max {
+			break
+		}
+		out = next
+	}
+	return out
+}
+
+const distillPrompt = `Write a memory card for the item below: at most %d characters of plain prose stating the facts about it most worth remembering long term. No preamble, no lists, no markdown.
+
+Title: %s
+
+%s`
+
+// distillLLM asks the model for a card summary
+func (s *Store) distillLLM(ctx context.Context, title, text string) (string, error) {
+	if len(text) > maxLLMText {
+		text = strings.ToValidUTF8(text[:maxLLMText], "")
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"model": s.cfg.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": fmt.Sprintf(distillPrompt, s.cfg.MaxSummary, title, text)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.LLMURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.APIKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calling memory model: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("memory model returned status %d: %s", resp.StatusCode, body)
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return "", fmt.Errorf("decoding memory model response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("memory model returned no choices")
+	}
+	summary := strings.Join(strings.Fields(completion.Choices[0].Message.Content), " ")
+	if summary == "" {
+		return "", fmt.Errorf("memory model returned an empty summary")
+	}
+	// Models overrun length limits; keep whole sentences within it
+	return leadingSentences(summary, s.cfg.MaxSummary), nil
+}
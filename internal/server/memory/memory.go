@@ -0,0 +1,416 @@
+// Package memory keeps memory cards: short summaries of selected nodes that
+// agents load into their context as stable long-term memory, apart from
+// per-query retrieval. Cards are stored as graph nodes and served from an
+// in-memory table.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// NodeType is the node type memory cards are stored as
+const NodeType = "MemoryCard"
+
+// idPrefix namespaces memory card node IDs
+const idPrefix = "memcard:"
+
+// DefaultBudget is the token budget of the active set when none is given
+const DefaultBudget = 2000
+
+// Distillers that can write a card's summary
+const (
+	DistillerManual  = "manual"  // Given by the caller
+	DistillerExtract = "extract" // Leading sentences of the node's text
+	DistillerLLM     = "llm"
+)
+
+var (
+	// ErrNotFound is returned for nodes without a memory card
+	ErrNotFound = errors.New("memory card not found")
+	// ErrNoNode is returned when a card's node does not exist
+	ErrNoNode = errors.New("node not found")
+	// ErrDistill is returned when the model fails to summarize a node
+	ErrDistill = errors.New("distilling memory card")
+)
+
+// Card is the distilled summary of one node
+type Card struct {
+	NodeID    string     `json:"node_id"`
+	NodeType  string     `json:"node_type,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Summary   string     `json:"summary"`
+	Tokens    int        `json:"tokens"` // Estimated size of Text in model tokens
+	Distiller string     `json:"distiller"`
+	Pinned    bool       `json:"pinned"`             // Pinned cards load first and never expire
+	Priority  int        `json:"priority,omitempty"` // Higher loads first among pinned or unpinned cards
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Stale     bool       `json:"stale,omitempty"` // The node changed after the card was distilled
+
+	SourceModified time.Time `json:"source_modified"` // The node's modification time when distilled
+	Created        time.Time `json:"created"`
+	Modified       time.Time `json:"modified"`
+}
+
+// Text is the card as placed into a model's context
+func (c *Card) Text() string {
+	if c.Title == "" {
+		return c.Summary
+	}
+	return c.Title + ": " + c.Summary
+}
+
+// Active reports whether the card belongs in the memory set at now
+func (c *Card) Active(now time.Time) bool {
+	return c.Pinned || c.ExpiresAt == nil || now.Before(*c.ExpiresAt)
+}
+
+// EstimateTokens approximates the model tokens in text at four
+// characters per token
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// AddRequest is the request body for creating or refreshing a card
+type AddRequest struct {
+	NodeID   string `json:"node_id"`
+	Summary  string `json:"summary,omitempty"` // Distilled from the node when empty
+	Pinned   bool   `json:"pinned,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	TTL      string `json:"ttl,omitempty"` // Go duration after which an unpinned card expires
+}
+
+// Set is the active memory under a token budget
+type Set struct {
+	Cards   []*Card  `json:"cards"`
+	Text    string   `json:"text"` // The cards' text, one per line, ready for a prompt
+	Tokens  int      `json:"tokens"`
+	Budget  int      `json:"budget"`
+	Omitted []string `json:"omitted"` // Active cards that did not fit, by node ID
+}
+
+// Repository is the subset of graph operations memory cards need
+type Repository interface {
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	CreateNode(ctx context.Context, node *core.Node) error
+	UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error
+	DeleteNode(ctx context.Context, nodeID string, force bool) error
+	FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error)
+}
+
+// Config holds distillation configuration
+type Config struct {
+	LLMURL     string // OpenAI-compatible chat completions endpoint; empty uses extraction only
+	APIKey     string
+	Model      string
+	Timeout    time.Duration
+	MaxSummary int // Maximum summary length in characters (default 400)
+}
+
+// Store is the table of memory cards
+type Store struct {
+	repo       Repository
+	cfg        Config
+	httpClient *http.Client
+
+	mu    sync.RWMutex
+	cards map[string]*Card // By node ID
+}
+
+// NewStore creates an empty card table; call Load to read stored cards
+func NewStore(repo Repository, cfg Config) *Store {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	if cfg.MaxSummary <= 0 {
+		cfg.MaxSummary = 400
+	}
+	return &Store{
+		repo:       repo,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		cards:      make(map[string]*Card),
+	}
+}
+
+// Load reads the stored cards into the table, marking those whose node
+// has changed since they were distilled as stale
+func (s *Store) Load(ctx context.Context) error {
+	const pageSize = 500
+	cards := make(map[string]*Card)
+	for offset := 0; ; offset += pageSize {
+		nodes, err := s.repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return fmt.Errorf("loading memory cards: %w", err)
+		}
+		for _, n := range nodes {
+			card, err := fromNode(n)
+			if err != nil {
+				log.Printf("Skipping memory card %s: %v", n.ID, err)
+				continue
+			}
+			if node, err := s.repo.GetNode(ctx, card.NodeID); err != nil || node.Modified.After(card.SourceModified) {
+				card.Stale = true
+			}
+			cards[card.NodeID] = card
+		}
+		if len(nodes) < pageSize {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.cards = cards
+	s.mu.Unlock()
+	return nil
+}
+
+// Add distills a node into a card, or refreshes the node's card. Pinned,
+// priority and expiry are taken from req.
+func (s *Store) Add(ctx context.Context, req AddRequest) (*Card, error) {
+	if req.NodeID == "" {
+		return nil, fmt.Errorf("node_id is required")
+	}
+	if strings.HasPrefix(req.NodeID, idPrefix) {
+		return nil, fmt.Errorf("%s is already a memory card", req.NodeID)
+	}
+	var expires *time.Time
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid ttl %q", req.TTL)
+		}
+		at := time.Now().Add(ttl)
+		expires = &at
+	}
+
+	node, err := s.repo.GetNode(ctx, req.NodeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoNode, req.NodeID)
+	}
+	card, err := s.distill(ctx, node, req.Summary)
+	if err != nil {
+		return nil, err
+	}
+	card.Pinned, card.Priority, card.ExpiresAt = req.Pinned, req.Priority, expires
+	if card.Pinned {
+		card.ExpiresAt = nil
+	}
+
+	s.mu.RLock()
+	existing := s.cards[req.NodeID]
+	s.mu.RUnlock()
+	if existing != nil {
+		card.Created = existing.Created
+	}
+	return card, s.save(ctx, card, existing == nil)
+}
+
+// Refresh distills a card again from its node's current state, keeping
+// its pin, priority and expiry. Manually written summaries are kept.
+func (s *Store) Refresh(ctx context.Context, nodeID string) (*Card, error) {
+	existing, err := s.Get(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	node, err := s.repo.GetNode(ctx, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoNode, nodeID)
+	}
+	summary := ""
+	if existing.Distiller == DistillerManual {
+		summary = existing.Summary
+	}
+	card, err := s.distill(ctx, node, summary)
+	if err != nil {
+		return nil, err
+	}
+	card.Pinned, card.Priority, card.ExpiresAt, card.Created = existing.Pinned, existing.Priority, existing.ExpiresAt, existing.Created
+	return card, s.save(ctx, card, false)
+}
+
+// Get returns a copy of a node's card
+func (s *Store) Get(nodeID string) (*Card, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	card, ok := s.cards[nodeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, nodeID)
+	}
+	c := *card
+	return &c, nil
+}
+
+// List returns copies of the cards in load order, including expired ones
+// when all is set
+func (s *Store) List(all bool) []*Card {
+	now := time.Now()
+	s.mu.RLock()
+	cards := make([]*Card, 0, len(s.cards))
+	for _, card := range s.cards {
+		if all || card.Active(now) {
+			c := *card
+			cards = append(cards, &c)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(cards, func(i, j int) bool {
+		a, b := cards[i], cards[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if !a.Modified.Equal(b.Modified) {
+			return a.Modified.After(b.Modified)
+		}
+		return a.NodeID < b.NodeID
+	})
+	return cards
+}
+
+// Active returns the active cards that fit in budget tokens: pinned cards
+// first, then by priority and most recently distilled. Cards too large
+// for the remaining budget are skipped so smaller ones can still fit.
+func (s *Store) Active(budget int) *Set {
+	if budget <= 0 {
+		budget = DefaultBudget
+	}
+	set := &Set{Cards: []*Card{}, Budget: budget, Omitted: []string{}}
+	var lines []string
+	for _, card := range s.List(false) {
+		if set.Tokens+card.Tokens > budget {
+			set.Omitted = append(set.Omitted, card.NodeID)
+			continue
+		}
+		set.Cards = append(set.Cards, card)
+		set.Tokens += card.Tokens
+		lines = append(lines, card.Text())
+	}
+	set.Text = strings.Join(lines, "\n")
+	return set
+}
+
+// Pin keeps a card in the memory set until unpinned
+func (s *Store) Pin(ctx context.Context, nodeID string) (*Card, error) {
+	return s.change(ctx, nodeID, func(c *Card) {
+		c.Pinned, c.ExpiresAt = true, nil
+	})
+}
+
+// Unpin lets a card expire after ttl, or keeps it unpinned without an
+// expiry when ttl is zero
+func (s *Store) Unpin(ctx context.Context, nodeID string, ttl time.Duration) (*Card, error) {
+	return s.change(ctx, nodeID, func(c *Card) {
+		c.Pinned, c.ExpiresAt = false, nil
+		if ttl > 0 {
+			at := time.Now().Add(ttl)
+			c.ExpiresAt = &at
+		}
+	})
+}
+
+// Expire unpins a card and drops it from the memory set now. The card is
+// kept, so it can be pinned again without distilling.
+func (s *Store) Expire(ctx context.Context, nodeID string) (*Card, error) {
+	return s.change(ctx, nodeID, func(c *Card) {
+		now := time.Now()
+		c.Pinned, c.ExpiresAt = false, &now
+	})
+}
+
+// Delete removes a card
+func (s *Store) Delete(ctx context.Context, nodeID string) error {
+	if _, err := s.Get(nodeID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteNode(ctx, idPrefix+nodeID, true); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.cards, nodeID)
+	s.mu.Unlock()
+	return nil
+}
+
+// EmitEvent marks cards stale when their node changes or is deleted
+func (s *Store) EmitEvent(event subscriptions.Event) {
+	if event.Type != subscriptions.EventNodeUpdated && event.Type != subscriptions.EventNodeDeleted {
+		return
+	}
+	s.mu.Lock()
+	if card, ok := s.cards[event.NodeID]; ok {
+		card.Stale = true
+	}
+	s.mu.Unlock()
+}
+
+// change applies update to a copy of a card and saves it
+func (s *Store) change(ctx context.Context, nodeID string, update func(*Card)) (*Card, error) {
+	card, err := s.Get(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	update(card)
+	card.Modified = time.Now()
+	return card, s.save(ctx, card, false)
+}
+
+// save stores a card's node and puts the card in the table
+func (s *Store) save(ctx context.Context, card *Card, create bool) error {
+	data, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return err
+	}
+	delete(meta, "stale")
+	if card.ExpiresAt == nil {
+		meta["expires_at"] = nil // Clears an earlier expiry
+	}
+
+	if create {
+		err = s.repo.CreateNode(ctx, &core.Node{ID: idPrefix + card.NodeID, Type: NodeType, Meta: meta, Created: card.Created, Modified: card.Modified})
+	} else {
+		err = s.repo.UpdateNodeMeta(ctx, idPrefix+card.NodeID, meta)
+	}
+	if err != nil {
+		return fmt.Errorf("saving memory card: %w", err)
+	}
+
+	s.mu.Lock()
+	c := *card
+	s.cards[card.NodeID] = &c
+	s.mu.Unlock()
+	return nil
+}
+
+// fromNode reads a card back from its node
+func fromNode(node *core.Node) (*Card, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var card Card
+	if err := json.Unmarshal(data, &card); err != nil {
+		return nil, fmt.Errorf("corrupt memory card: %w", err)
+	}
+	card.NodeID = strings.TrimPrefix(node.ID, idPrefix)
+	return &card, nil
+}
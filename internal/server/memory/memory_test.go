@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestLeadingSentences(t *testing.T) {
+	text := "Ada wrote the first program. She worked with Babbage. Later notes followed."
+	if got := leadingSentences(text, 50); got != "Ada wrote the first program." {
+		t.Errorf("leadingSentences = %q", got)
+	}
+	if got := leadingSentences(text, 1000); got != text {
+		t.Errorf("short text changed: %q", got)
+	}
+	if got := leadingSentences("one very long sentence without any stop", 20); got != "one very long…" {
+		t.Errorf("cut = %q", got)
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	nodes := []*core.Node{
+		{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"name": "Ada Lovelace", "born": float64(1815)}},
+		{ID: "note:1", Type: "Note", Meta: map[string]interface{}{"title": "Plan", "text": strings.Repeat("We ship on Friday. ", 40)}},
+		{ID: "note:2", Type: "Note", Meta: map[string]interface{}{"text": "Short note."}},
+	}
+	for _, n := range nodes {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewStore(repo, Config{MaxSummary: 100})
+	ada, err := store.Add(ctx, AddRequest{NodeID: "person:ada", Pinned: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ada.Text() != "Ada Lovelace: Person; born: 1815" || ada.Distiller != DistillerExtract {
+		t.Errorf("card = %q (%s)", ada.Text(), ada.Distiller)
+	}
+	plan, _ := store.Add(ctx, AddRequest{NodeID: "note:1", Priority: 5})
+	if len(plan.Summary) > 100 || !strings.HasSuffix(plan.Summary, "Friday.") {
+		t.Errorf("summary = %q", plan.Summary)
+	}
+	store.Add(ctx, AddRequest{NodeID: "note:2", Summary: "Remember the short note."})
+	if _, err := store.Add(ctx, AddRequest{NodeID: "missing"}); !errors.Is(err, ErrNoNode) {
+		t.Errorf("missing node: %v", err)
+	}
+
+	// Pinned first, then by priority; cards over budget are skipped
+	set := store.Active(ada.Tokens + 10)
+	if len(set.Cards) != 2 || set.Cards[0].NodeID != "person:ada" || set.Cards[1].NodeID != "note:2" {
+		t.Errorf("active = %+v", set.Cards)
+	}
+	if len(set.Omitted) != 1 || set.Omitted[0] != "note:1" || set.Tokens > set.Budget {
+		t.Errorf("omitted %v, %d of %d tokens", set.Omitted, set.Tokens, set.Budget)
+	}
+
+	if _, err := store.Expire(ctx, "note:2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Unpin(ctx, "person:ada", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	set = store.Active(DefaultBudget)
+	if len(set.Cards) != 2 || set.Cards[0].NodeID != "note:1" {
+		t.Errorf("after expire and unpin: %+v", set.Cards)
+	}
+	if all := store.List(true); len(all) != 3 {
+		t.Errorf("List(all) = %d cards", len(all))
+	}
+
+	// Cards survive a reload, and node changes mark them stale
+	repo.UpdateNodeMeta(ctx, "note:1", map[string]any{"text": "Shipping moved to Monday."})
+	reloaded := NewStore(repo, Config{MaxSummary: 100})
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	card, err := reloaded.Get("note:1")
+	if err != nil || !card.Stale || card.Priority != 5 {
+		t.Fatalf("reloaded card = %+v, %v", card, err)
+	}
+	if card, _ := reloaded.Get("person:ada"); card.Pinned || card.ExpiresAt == nil {
+		t.Errorf("unpin not persisted: %+v", card)
+	}
+	card, _ = reloaded.Refresh(ctx, "note:1")
+	if card.Stale || card.Summary != "Shipping moved to Monday." || card.Priority != 5 {
+		t.Errorf("refreshed = %+v", card)
+	}
+	if card, _ := reloaded.Refresh(ctx, "note:2"); card.Summary != "Remember the short note." {
+		t.Errorf("manual summary replaced: %q", card.Summary)
+	}
+
+	reloaded.EmitEvent(subscriptions.Event{Type: subscriptions.EventNodeDeleted, NodeID: "note:1"})
+	if card, _ := reloaded.Get("note:1"); !card.Stale {
+		t.Error("deleted node did not mark card stale")
+	}
+	if err := reloaded.Delete(ctx, "note:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.Get("note:1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted card: %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+// Package modules registers the server's event-driven processors (image
+// captioning, citations, tasks, link rules, ...) so they can be listed,
+// switched off per namespace and sent commands at runtime, without
+// redeploying.
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// NodeType is the node type module settings are stored as
+const NodeType = "ModuleSettings"
+
+// idPrefix namespaces module settings node IDs
+const idPrefix = "module:"
+
+// Errors returned by the registry
+var (
+	ErrNotFound       = errors.New("module not found")
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrInvalidArgs    = errors.New("invalid command arguments")
+)
+
+// Command is an action a module can be asked to perform
+type Command struct {
+	Description string
+	Run         func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+// Module is a registered processor
+type Module struct {
+	Name        string
+	Description string
+	Handle      func(subscriptions.Event) // Receives graph events; nil for modules without any
+	Commands    map[string]Command
+}
+
+// Settings is a module's stored state
+type Settings struct {
+	Disabled           bool     `json:"disabled"`                      // Off for every namespace
+	DisabledNamespaces []string `json:"disabled_namespaces,omitempty"` // Off for these namespaces
+}
+
+// Info describes a module for GET /api/modules
+type Info struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Commands    map[string]string `json:"commands"` // Name -> description
+	Settings
+}
+
+// Registry holds the registered modules and their settings
+type Registry struct {
+	repo graph.Repository
+
+	mu       sync.RWMutex
+	modules  map[string]*Module
+	order    []string // Registration order, which is event delivery order
+	settings map[string]Settings
+
+	saveMu sync.Mutex // Serializes settings changes; saving emits events, so mu is not held
+}
+
+// NewRegistry creates an empty registry storing settings in repo
+func NewRegistry(repo graph.Repository) *Registry {
+	return &Registry{repo: repo, modules: make(map[string]*Module), settings: make(map[string]Settings)}
+}
+
+// Register adds a module. Modules receive events in the order they were
+// registered.
+func (r *Registry) Register(m *Module) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.modules[m.Name]; !exists {
+		r.order = append(r.order, m.Name)
+	}
+	r.modules[m.Name] = m
+}
+
+// Load reads stored settings from the graph
+func (r *Registry) Load(ctx context.Context) error {
+	const pageSize = 500
+	settings := make(map[string]Settings)
+	for offset := 0; ; offset += pageSize {
+		nodes, err := r.repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			if name, ok := strings.CutPrefix(n.ID, idPrefix); ok && name != "" {
+				settings[name] = fromMeta(n.Meta)
+			}
+		}
+		if len(nodes) < pageSize {
+			break
+		}
+	}
+	r.mu.Lock()
+	r.settings = settings
+	r.mu.Unlock()
+	return nil
+}
+
+// List describes every registered module, by name
+func (r *Registry) List() []Info {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Info, 0, len(r.modules))
+	for _, m := range r.modules {
+		out = append(out, r.info(m))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Get describes one module
+func (r *Registry) Get(name string) (*Info, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.modules[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	info := r.info(m)
+	return &info, nil
+}
+
+func (r *Registry) info(m *Module) Info {
+	commands := make(map[string]string, len(m.Commands))
+	for name, c := range m.Commands {
+		commands[name] = c.Description
+	}
+	return Info{Name: m.Name, Description: m.Description, Commands: commands, Settings: r.settings[m.Name]}
+}
+
+// SetEnabled switches a module on or off for a namespace, or for every
+// namespace when namespace is empty. Enabling a module everywhere also
+// clears its per-namespace settings.
+func (r *Registry) SetEnabled(ctx context.Context, name, namespace string, enabled bool) (*Info, error) {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+	r.mu.RLock()
+	_, ok := r.modules[name]
+	s := r.settings[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	switch {
+	case namespace == "" && enabled:
+		s = Settings{}
+	case namespace == "":
+		s.Disabled = true
+	case enabled:
+		s.DisabledNamespaces = without(s.DisabledNamespaces, namespace)
+	default:
+		s.DisabledNamespaces = append(without(s.DisabledNamespaces, namespace), namespace)
+		sort.Strings(s.DisabledNamespaces)
+	}
+	if err := r.save(ctx, name, s); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.settings[name] = s
+	r.mu.Unlock()
+	return r.Get(name)
+}
+
+// save stores a module's settings as a node
+func (r *Registry) save(ctx context.Context, name string, s Settings) error {
+	namespaces := make([]interface{}, len(s.DisabledNamespaces))
+	for i, ns := range s.DisabledNamespaces {
+		namespaces[i] = ns
+	}
+	meta := map[string]interface{}{"disabled": s.Disabled, "disabled_namespaces": namespaces}
+	id := idPrefix + name
+	if _, err := r.repo.GetNode(ctx, id); err == nil {
+		return r.repo.UpdateNodeMeta(ctx, id, meta)
+	}
+	now := time.Now()
+	return r.repo.CreateNode(ctx, &core.Node{ID: id, Type: NodeType, Meta: meta, Created: now, Modified: now})
+}
+
+// enabled reports whether a module handles events for a node ID
+func (r *Registry) enabled(name, nodeID string) bool {
+	s := r.settings[name]
+	if s.Disabled {
+		return false
+	}
+	ns := graph.Namespace(nodeID)
+	for _, d := range s.DisabledNamespaces {
+		if d == ns {
+			return false
+		}
+	}
+	return true
+}
+
+// EmitEvent delivers an event to every module enabled for its node's
+// namespace (a link's source, for link events)
+func (r *Registry) EmitEvent(event subscriptions.Event) {
+	id := event.NodeID
+	if id == "" {
+		id = event.LinkSource
+	}
+	r.mu.RLock()
+	var handlers []func(subscriptions.Event)
+	for _, name := range r.order {
+		if m := r.modules[name]; m.Handle != nil && r.enabled(name, id) {
+			handlers = append(handlers, m.Handle)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, handle := range handlers {
+		handle(event)
+	}
+}
+
+// Run invokes a module command
+func (r *Registry) Run(ctx context.Context, name, command string, args map[string]interface{}) (interface{}, error) {
+	r.mu.RLock()
+	m, ok := r.modules[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	c, ok := m.Commands[command]
+	if !ok {
+		return nil, fmt.Errorf("%w %q for module %s", ErrUnknownCommand, command, name)
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	log.Printf("Running module command %s %s", name, command)
+	return c.Run(ctx, args)
+}
+
+// StringArg returns a required string argument
+func StringArg(args map[string]interface{}, key string) (string, error) {
+	v, ok := args[key].(string)
+	if !ok || v == "" {
+		return "", fmt.Errorf("%w: %s is required", ErrInvalidArgs, key)
+	}
+	return v, nil
+}
+
+// BoolArg returns an optional boolean argument
+func BoolArg(args map[string]interface{}, key string) (bool, error) {
+	switch v := args[key].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("%w: %s must be a boolean", ErrInvalidArgs, key)
+}
+
+// fromMeta reads settings back from their node
+func fromMeta(meta map[string]interface{}) Settings {
+	var s Settings
+	s.Disabled, _ = meta["disabled"].(bool)
+	switch list := meta["disabled_namespaces"].(type) {
+	case []interface{}:
+		for _, v := range list {
+			if ns, ok := v.(string); ok {
+				s.DisabledNamespaces = append(s.DisabledNamespaces, ns)
+			}
+		}
+	case []string:
+		s.DisabledNamespaces = append(s.DisabledNamespaces, list...)
+	}
+	return s
+}
+
+func without(list []string, s string) []string {
+	out := list[:0:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
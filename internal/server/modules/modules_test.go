@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	reg := NewRegistry(repo)
+
+	var seen []string
+	reg.Register(&Module{
+		Name:   "captions",
+		Handle: func(e subscriptions.Event) { seen = append(seen, "captions "+e.NodeID) },
+		Commands: map[string]Command{"process": {
+			Description: "caption one node",
+			Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				id, err := StringArg(args, "node_id")
+				if err != nil {
+					return nil, err
+				}
+				return map[string]string{"processed": id}, nil
+			},
+		}},
+	})
+	reg.Register(&Module{Name: "index", Handle: func(e subscriptions.Event) { seen = append(seen, "index "+e.NodeID) }})
+
+	emit := func(id string) {
+		seen = nil
+		reg.EmitEvent(subscriptions.Event{Type: subscriptions.EventNodeCreated, NodeID: id})
+	}
+	emit("sha256:abc")
+	if len(seen) != 2 || seen[0] != "captions sha256:abc" {
+		t.Fatalf("events delivered = %v", seen)
+	}
+
+	// Disabled for one namespace, then everywhere
+	if _, err := reg.SetEnabled(ctx, "captions", "sha256", false); err != nil {
+		t.Fatal(err)
+	}
+	emit("sha256:abc")
+	if len(seen) != 1 || seen[0] != "index sha256:abc" {
+		t.Errorf("events with captions off for sha256 = %v", seen)
+	}
+	emit("note:1")
+	if len(seen) != 2 {
+		t.Errorf("events in another namespace = %v", seen)
+	}
+	info, err := reg.SetEnabled(ctx, "index", "", false)
+	if err != nil || !info.Disabled {
+		t.Fatalf("SetEnabled(index off) = %+v, %v", info, err)
+	}
+	emit("note:1")
+	if len(seen) != 1 || seen[0] != "captions note:1" {
+		t.Errorf("events with index off = %v", seen)
+	}
+
+	// Settings survive a restart
+	reg2 := NewRegistry(repo)
+	reg2.Register(&Module{Name: "captions"})
+	reg2.Register(&Module{Name: "index"})
+	if err := reg2.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	list := reg2.List()
+	if len(list) != 2 || list[0].Name != "captions" || len(list[0].DisabledNamespaces) != 1 || !list[1].Disabled {
+		t.Errorf("List() after Load = %+v", list)
+	}
+
+	// Enabling everywhere clears per-namespace settings
+	if info, err := reg.SetEnabled(ctx, "captions", "", true); err != nil || info.Disabled || len(info.DisabledNamespaces) != 0 {
+		t.Errorf("SetEnabled(captions on) = %+v, %v", info, err)
+	}
+
+	// Commands
+	if got, err := reg.Run(ctx, "captions", "process", map[string]interface{}{"node_id": "sha256:abc"}); err != nil || got.(map[string]string)["processed"] != "sha256:abc" {
+		t.Errorf("Run(process) = %v, %v", got, err)
+	}
+	if _, err := reg.Run(ctx, "captions", "process", nil); !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("Run without node_id error = %v", err)
+	}
+	if _, err := reg.Run(ctx, "captions", "explode", nil); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Run(unknown command) error = %v", err)
+	}
+	if _, err := reg.SetEnabled(ctx, "missing", "", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetEnabled(missing) error = %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+// Package normalize rewrites ingested content before it is hashed and
+// stored, so copies that differ only in line endings, trailing spaces,
+// encoding or markup dedupe to one Source node.
+package normalize
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Steps, applied in this order whatever order they are configured in
+const (
+	StepUTF8               = "utf8"                // Drop a byte-order mark, NULs and invalid UTF-8
+	StepHTML               = "html"                // Reduce HTML to its text, a line per block
+	StepNewlines           = "newlines"            // CRLF and CR become LF
+	StepTrailingWhitespace = "trailing_whitespace" // Strip spaces at line ends and blank lines at the end
+)
+
+var stepOrder = []string{StepUTF8, StepHTML, StepNewlines, StepTrailingWhitespace}
+
+// Pipeline is a set of steps in application order
+type Pipeline []string
+
+// ParsePipeline parses steps separated by +, e.g. "html+newlines". An
+// empty spec or "none" is the empty pipeline.
+func ParsePipeline(spec string) (Pipeline, error) {
+	want := make(map[string]bool)
+	for _, step := range strings.Split(spec, "+") {
+		step = strings.TrimSpace(step)
+		if step == "" || step == "none" {
+			continue
+		}
+		known := false
+		for _, s := range stepOrder {
+			known = known || s == step
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown step %q (use %s)", step, strings.Join(stepOrder, ", "))
+		}
+		want[step] = true
+	}
+	var p Pipeline
+	for _, s := range stepOrder {
+		if want[s] {
+			p = append(p, s)
+		}
+	}
+	return p, nil
+}
+
+// String returns the steps joined by +, or none
+func (p Pipeline) String() string {
+	if len(p) == 0 {
+		return "none"
+	}
+	return strings.Join(p, "+")
+}
+
+// Apply runs the steps over content
+func (p Pipeline) Apply(content string) string {
+	for _, step := range p {
+		switch step {
+		case StepUTF8:
+			content = strings.TrimPrefix(strings.ToValidUTF8(content, string(utf8.RuneError)), "\ufeff")
+			content = strings.ReplaceAll(content, "\x00", "")
+		case StepHTML:
+			content = htmlText(content)
+		case StepNewlines:
+			content = strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\r", "\n")
+		case StepTrailingWhitespace:
+			content = trimTrailing(content)
+		}
+	}
+	return content
+}
+
+// Policy picks the pipeline for a source by its connector
+type Policy struct {
+	Connectors map[string]Pipeline
+	Default    Pipeline
+}
+
+// ParsePolicy parses a comma-separated list of connector:NAME=STEPS entries
+// and an optional default=STEPS, e.g.
+// "default=newlines+trailing_whitespace,connector:rss=html+newlines".
+func ParsePolicy(spec string) (*Policy, error) {
+	p := &Policy{Connectors: make(map[string]Pipeline)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid normalization entry %q: expected KEY=STEPS", entry)
+		}
+		pipeline, err := ParsePipeline(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid normalization entry %q: %w", entry, err)
+		}
+		key = strings.TrimSpace(key)
+		switch {
+		case key == "default":
+			p.Default = pipeline
+		case strings.HasPrefix(key, "connector:"):
+			p.Connectors[strings.TrimPrefix(key, "connector:")] = pipeline
+		default:
+			return nil, fmt.Errorf("invalid normalization entry %q: expected connector: or default", entry)
+		}
+	}
+	return p, nil
+}
+
+// For returns the pipeline for a connector, falling back to the default
+func (p *Policy) For(connector string) Pipeline {
+	if p == nil {
+		return nil
+	}
+	if pipeline, ok := p.Connectors[connector]; ok {
+		return pipeline
+	}
+	return p.Default
+}
+
+var (
+	htmlDropped = regexp.MustCompile(`(?is)<(script|style|head|noscript|template)\b.*?</(script|style|head|noscript|template)\s*>|<!--.*?-->`)
+	htmlBreak   = regexp.MustCompile(`(?i)</?(p|div|br|hr|li|ul|ol|tr|table|h[1-6]|blockquote|pre|section|article|header|footer|dt|dd)\b[^>]*>`)
+	htmlTag     = regexp.MustCompile(`<[^>]*>`)
+	// Blank lines at the end, after the last line break
+	trailingBlank = regexp.MustCompile(`(\r?\n)(?:\r?\n)+$`)
+)
+
+// htmlText keeps the text of HTML: tags are removed, block elements end
+// lines, entities are decoded and runs of spaces collapse
+func htmlText(s string) string {
+	s = htmlDropped.ReplaceAllString(s, "")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, ""))
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trimTrailing strips whitespace from line ends and blank lines from the
+// end, keeping the last line break and CRLF endings as they are. Content
+// that is only whitespace becomes empty.
+func trimTrailing(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		cr := i < len(lines)-1 && strings.HasSuffix(line, "\r")
+		line = strings.TrimRight(line, " \t\r\f\v\u00a0")
+		if cr {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	out := strings.Join(lines, "\n")
+	if strings.Trim(out, "\r\n") == "" {
+		return ""
+	}
+	return trailingBlank.ReplaceAllString(out, "$1")
+}
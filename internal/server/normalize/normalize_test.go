@@ -0,0 +1,61 @@
+package normalize
+
+import "testing"
+
+func TestPipelineApply(t *testing.T) {
+	for _, tc := range []struct {
+		spec, in, want string
+	}{
+		{"newlines", "a\r\nb\rc\n", "a\nb\nc\n"},
+		{"trailing_whitespace", "a  \nb\t\n\n\n", "a\nb\n"},
+		{"trailing_whitespace", "a \r\nb \r\n\r\n", "a\r\nb\r\n"},
+		{"trailing_whitespace", "no break  ", "no break"},
+		{"trailing_whitespace", " \n\t\n", ""},
+		{"utf8", "\ufeffcaf\xe9\x00", "caf\ufffd"},
+		{"html", "<html><head><title>T</title></head><body><p>Hello,&nbsp;<b>world</b></p><script>x()</script><ul><li>one</li><li>two</li></ul></body></html>", "Hello, world\none\ntwo"},
+		{"trailing_whitespace+newlines", "a \r\nb\r\n\r\n", "a\nb\n"},
+		{"none", "a \r\n", "a \r\n"},
+	} {
+		p, err := ParsePipeline(tc.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Apply(tc.in); got != tc.want {
+			t.Errorf("%s(%q) = %q, want %q", tc.spec, tc.in, got, tc.want)
+		}
+	}
+
+	// Steps run in a fixed order, whatever order they are given in
+	p, _ := ParsePipeline("trailing_whitespace+html+newlines")
+	if p.String() != "html+newlines+trailing_whitespace" {
+		t.Errorf("pipeline = %s", p)
+	}
+	if _, err := ParsePipeline("newlines+lowercase"); err == nil {
+		t.Error("unknown step accepted")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy, err := ParsePolicy("default=newlines, connector:rss=html+newlines, connector:cli=none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for connector, want := range map[string]string{
+		"rss":   "html+newlines",
+		"cli":   "none",
+		"email": "newlines",
+		"":      "newlines",
+	} {
+		if got := policy.For(connector).String(); got != want {
+			t.Errorf("For(%q) = %s, want %s", connector, got, want)
+		}
+	}
+	if got := (*Policy)(nil).For("rss"); got != nil {
+		t.Errorf("nil policy = %v", got)
+	}
+	for _, bad := range []string{"rss=html", "connector:rss", "default=squash"} {
+		if _, err := ParsePolicy(bad); err == nil {
+			t.Errorf("ParsePolicy(%q) accepted", bad)
+		}
+	}
+}
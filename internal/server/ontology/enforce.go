@@ -0,0 +1,71 @@
+package ontology
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// enforcingRepository rejects links that break an enforced link type
+// definition. Checks and writes are serialized so concurrent requests
+// cannot together exceed a cardinality limit.
+type enforcingRepository struct {
+	graph.Repository
+
+	mu sync.Mutex
+}
+
+// Enforce wraps repo so that new links are checked against the definitions
+// of their types. Links breaking an enforced definition fail with
+// ErrViolation; other violations are left for Report.
+func Enforce(repo graph.Repository) graph.Repository {
+	return &enforcingRepository{Repository: repo}
+}
+
+func (r *enforcingRepository) CreateLink(ctx context.Context, link *core.Link) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkLinks(ctx, []*core.Link{link}); err != nil {
+		return err
+	}
+	return r.Repository.CreateLink(ctx, link)
+}
+
+func (r *enforcingRepository) CreateLinks(ctx context.Context, links []*core.Link) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkLinks(ctx, links); err != nil {
+		return err
+	}
+	return r.Repository.CreateLinks(ctx, links)
+}
+
+// checkLinks fails on the first link an enforced definition rejects.
+// Earlier links in the batch count toward cardinality limits.
+func (r *enforcingRepository) checkLinks(ctx context.Context, links []*core.Link) error {
+	c := newChecker(r.Repository)
+	for i, link := range links {
+		d := c.def(ctx, link.Type)
+		if d == nil || !d.Enforce {
+			continue
+		}
+		violations := c.check(ctx, d, link)
+		limits, err := c.cardinality(ctx, d, link, links[:i])
+		if err != nil {
+			return err
+		}
+		violations = append(violations, limits...)
+		if len(violations) > 0 {
+			messages := make([]string, len(violations))
+			for j, v := range violations {
+				messages[j] = v.Message
+			}
+			return fmt.Errorf("%w: %s", ErrViolation, strings.Join(messages, "; "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,460 @@
+package ontology
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// LinkNodeType is the node type link type definitions are stored as
+const LinkNodeType = "LinkTypeDefinition"
+
+// linkIDPrefix namespaces link type definition node IDs
+const linkIDPrefix = "linktype:"
+
+// ErrLinkTypeNotFound is returned for link types without a definition
+var ErrLinkTypeNotFound = errors.New("link type definition not found")
+
+// ErrViolation is returned for links an enforced definition rejects
+var ErrViolation = errors.New("link violates its type definition")
+
+// Property types a link type can declare
+const (
+	PropertyString  = "string"
+	PropertyNumber  = "number"
+	PropertyBoolean = "boolean"
+	PropertyDate    = "date" // An RFC3339 time or a date
+)
+
+// PropertyDef declares one link property
+type PropertyDef struct {
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// LinkTypeDef describes a link type: the node types it may join, the
+// properties it carries and how many current links a node may have. Links
+// that break it are rejected when it is enforced, and otherwise reported.
+type LinkTypeDef struct {
+	Type         string                 `json:"type"`
+	Description  string                 `json:"description,omitempty"`
+	Sources      []string               `json:"sources,omitempty"`        // Allowed source node types; any when empty
+	Targets      []string               `json:"targets,omitempty"`        // Allowed target node types; any when empty
+	Properties   map[string]PropertyDef `json:"properties,omitempty"`     // Link meta keys and their types
+	MaxPerSource int                    `json:"max_per_source,omitempty"` // Current links of the type from one node; 0 for no limit
+	MaxPerTarget int                    `json:"max_per_target,omitempty"` // Current links of the type into one node; 0 for no limit
+	Enforce      bool                   `json:"enforce,omitempty"`        // Reject violating links instead of reporting them
+	Created      time.Time              `json:"created"`
+	Modified     time.Time              `json:"modified"`
+}
+
+// Validate checks a link type definition
+func (d *LinkTypeDef) Validate() error {
+	if !validType.MatchString(d.Type) {
+		return fmt.Errorf("invalid link type %q (use 1-64 letters, digits or _, starting with a letter)", d.Type)
+	}
+	for _, t := range append(append([]string{}, d.Sources...), d.Targets...) {
+		if !validType.MatchString(t) {
+			return fmt.Errorf("invalid node type %q", t)
+		}
+	}
+	for name, p := range d.Properties {
+		if name == "" {
+			return errors.New("property names must not be empty")
+		}
+		switch p.Type {
+		case PropertyString, PropertyNumber, PropertyBoolean, PropertyDate:
+		default:
+			return fmt.Errorf("property %s: unknown type %q (use string, number, boolean or date)", name, p.Type)
+		}
+	}
+	if d.MaxPerSource < 0 || d.MaxPerTarget < 0 {
+		return errors.New("max_per_source and max_per_target must not be negative")
+	}
+	return nil
+}
+
+// PutLinkType validates and stores a link type definition, replacing any
+// existing one. It reports whether the definition is new.
+func PutLinkType(ctx context.Context, repo graph.Repository, d *LinkTypeDef) (bool, error) {
+	if err := d.Validate(); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	existing, err := GetLinkType(ctx, repo, d.Type)
+	if err != nil {
+		d.Created, d.Modified = now, now
+		meta, err := linkTypeMeta(d)
+		if err != nil {
+			return false, err
+		}
+		return true, repo.CreateNode(ctx, &core.Node{ID: linkIDPrefix + d.Type, Type: LinkNodeType, Meta: meta, Created: now, Modified: now})
+	}
+	d.Created, d.Modified = existing.Created, now
+	meta, err := linkTypeMeta(d)
+	if err != nil {
+		return false, err
+	}
+	// Absent fields are cleared rather than left from the old version
+	for _, key := range linkMetaKeys {
+		if _, ok := meta[key]; !ok {
+			meta[key] = nil
+		}
+	}
+	return false, repo.UpdateNodeMeta(ctx, linkIDPrefix+d.Type, meta)
+}
+
+// GetLinkType loads a link type's definition
+func GetLinkType(ctx context.Context, repo graph.Repository, linkType string) (*LinkTypeDef, error) {
+	node, err := repo.GetNode(ctx, linkIDPrefix+linkType)
+	if err != nil || node.Type != LinkNodeType {
+		return nil, fmt.Errorf("%w: %s", ErrLinkTypeNotFound, linkType)
+	}
+	return linkTypeFromNode(node), nil
+}
+
+// ListLinkTypes returns all link type definitions
+func ListLinkTypes(ctx context.Context, repo graph.Repository) ([]*LinkTypeDef, error) {
+	const pageSize = 500
+	out := []*LinkTypeDef{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{LinkNodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			out = append(out, linkTypeFromNode(n))
+		}
+		if len(nodes) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+// DeleteLinkType removes a link type's definition and its history
+func DeleteLinkType(ctx context.Context, repo graph.Repository, linkType string) error {
+	if _, err := GetLinkType(ctx, repo, linkType); err != nil {
+		return err
+	}
+	return repo.DeleteNode(ctx, linkIDPrefix+linkType, true)
+}
+
+// linkMetaKeys are the node properties a link type definition is stored in
+var linkMetaKeys = []string{"description", "sources", "targets", "properties", "max_per_source", "max_per_target", "enforce"}
+
+// linkTypeMeta stores a definition's non-empty fields as node properties
+func linkTypeMeta(d *LinkTypeDef) (map[string]interface{}, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"type", "created", "modified"} {
+		delete(meta, key)
+	}
+	return meta, nil
+}
+
+// linkTypeFromNode reads a link type definition back from its node
+func linkTypeFromNode(node *core.Node) *LinkTypeDef {
+	d := &LinkTypeDef{}
+	if data, err := json.Marshal(node.Meta); err == nil {
+		json.Unmarshal(data, d)
+	}
+	d.Type = strings.TrimPrefix(node.ID, linkIDPrefix)
+	d.Created, d.Modified = node.Created, node.Modified
+	return d
+}
+
+// Kinds of link type violation
+const (
+	RuleSource      = "source"
+	RuleTarget      = "target"
+	RuleProperty    = "property"
+	RuleCardinality = "cardinality"
+)
+
+// Violation is one way a link breaks its type's definition
+type Violation struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Type     string `json:"type"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+	Enforced bool   `json:"enforced"` // The definition rejects such links
+}
+
+// checker checks links against definitions, remembering the definitions
+// and node types it has read
+type checker struct {
+	repo  graph.Repository
+	defs  map[string]*LinkTypeDef // nil for link types without a definition
+	types map[string]string
+}
+
+func newChecker(repo graph.Repository) *checker {
+	return &checker{repo: repo, defs: map[string]*LinkTypeDef{}, types: map[string]string{}}
+}
+
+// def returns a link type's definition, or nil when it has none
+func (c *checker) def(ctx context.Context, linkType string) *LinkTypeDef {
+	d, ok := c.defs[linkType]
+	if !ok {
+		d, _ = GetLinkType(ctx, c.repo, linkType)
+		c.defs[linkType] = d
+	}
+	return d
+}
+
+// nodeType returns a node's type, or "" when it cannot be read
+func (c *checker) nodeType(ctx context.Context, id string) string {
+	t, ok := c.types[id]
+	if !ok {
+		if node, err := c.repo.GetNode(ctx, id); err == nil {
+			t = node.Type
+		}
+		c.types[id] = t
+	}
+	return t
+}
+
+// check reports how a link breaks its definition's endpoint and property
+// rules; cardinality is checked separately
+func (c *checker) check(ctx context.Context, d *LinkTypeDef, link *core.Link) []Violation {
+	var out []Violation
+	violation := func(rule, format string, args ...interface{}) {
+		out = append(out, Violation{
+			Source:   link.Source,
+			Target:   link.Target,
+			Type:     link.Type,
+			Rule:     rule,
+			Message:  fmt.Sprintf(format, args...),
+			Enforced: d.Enforce,
+		})
+	}
+
+	if len(d.Sources) > 0 {
+		if t := c.nodeType(ctx, link.Source); !contains(d.Sources, t) {
+			violation(RuleSource, "%s links must start at %s, not %s (%s)", d.Type, strings.Join(d.Sources, " or "), describeType(t), link.Source)
+		}
+	}
+	if len(d.Targets) > 0 {
+		if t := c.nodeType(ctx, link.Target); !contains(d.Targets, t) {
+			violation(RuleTarget, "%s links must end at %s, not %s (%s)", d.Type, strings.Join(d.Targets, " or "), describeType(t), link.Target)
+		}
+	}
+
+	names := make([]string, 0, len(d.Properties))
+	for name := range d.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := d.Properties[name]
+		value, ok := link.Meta[name]
+		if !ok || value == nil {
+			if p.Required {
+				violation(RuleProperty, "%s links require %s", d.Type, name)
+			}
+			continue
+		}
+		if !propertyMatches(p.Type, value) {
+			violation(RuleProperty, "%s on %s links must be a %s", name, d.Type, p.Type)
+		}
+	}
+	return out
+}
+
+// current reports whether a link holds now, so counts toward cardinality
+func current(link *core.Link, now time.Time) bool {
+	return graph.ValidAt(link.Meta, now)
+}
+
+// cardinality reports whether adding link, after the links pending before
+// it in the same write, would give a node more current links of the type
+// than its definition allows
+func (c *checker) cardinality(ctx context.Context, d *LinkTypeDef, link *core.Link, pending []*core.Link) ([]Violation, error) {
+	now := time.Now()
+	if d.MaxPerSource == 0 && d.MaxPerTarget == 0 || !current(link, now) {
+		return nil, nil
+	}
+	count := func(links []*core.Link, same func(*core.Link) bool) int {
+		n := 0
+		for _, l := range links {
+			if l.Type == d.Type && same(l) && current(l, now) {
+				n++
+			}
+		}
+		return n
+	}
+
+	var out []Violation
+	if d.MaxPerSource > 0 {
+		existing, err := c.repo.GetLinks(ctx, link.Source)
+		if err != nil {
+			return nil, err
+		}
+		from := func(l *core.Link) bool { return l.Source == link.Source }
+		if n := count(existing, from) + count(pending, from); n >= d.MaxPerSource {
+			out = append(out, Violation{
+				Source: link.Source, Target: link.Target, Type: link.Type, Rule: RuleCardinality, Enforced: d.Enforce,
+				Message: fmt.Sprintf("%s already has %d current %s link(s); at most %d allowed", link.Source, n, d.Type, d.MaxPerSource),
+			})
+		}
+	}
+	if d.MaxPerTarget > 0 {
+		existing, err := c.repo.GetBacklinks(ctx, link.Target)
+		if err != nil {
+			return nil, err
+		}
+		into := func(l *core.Link) bool { return l.Target == link.Target }
+		if n := count(existing, into) + count(pending, into); n >= d.MaxPerTarget {
+			out = append(out, Violation{
+				Source: link.Source, Target: link.Target, Type: link.Type, Rule: RuleCardinality, Enforced: d.Enforce,
+				Message: fmt.Sprintf("%s already has %d current incoming %s link(s); at most %d allowed", link.Target, n, d.Type, d.MaxPerTarget),
+			})
+		}
+	}
+	return out, nil
+}
+
+// propertyMatches reports whether a property value has the declared type
+func propertyMatches(propertyType string, value interface{}) bool {
+	switch propertyType {
+	case PropertyString:
+		_, ok := value.(string)
+		return ok
+	case PropertyNumber:
+		switch value.(type) {
+		case float64, float32, int, int64, json.Number:
+			return true
+		}
+		return false
+	case PropertyBoolean:
+		_, ok := value.(bool)
+		return ok
+	case PropertyDate:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		_, err := graph.ParseTime(s)
+		return err == nil
+	}
+	return true
+}
+
+// describeType names a node type in messages
+func describeType(t string) string {
+	if t == "" {
+		return "a missing node"
+	}
+	return t
+}
+
+// contains reports whether values holds v
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ViolationReport lists the stored links that break their definitions
+type ViolationReport struct {
+	Checked    int         `json:"checked"` // Links with a definition
+	Found      int         `json:"found"`   // Violations found, listed or not
+	Violations []Violation `json:"violations"`
+	Truncated  bool        `json:"truncated"`
+}
+
+// Report checks every stored link of a defined type, or only those of
+// linkType when it is set, returning up to limit violations. Cardinality
+// is reported once for each node over the limit.
+func Report(ctx context.Context, repo graph.Repository, linkType string, limit int) (*ViolationReport, error) {
+	ids, err := repo.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+
+	c := newChecker(repo)
+	report := &ViolationReport{Violations: []Violation{}}
+	add := func(v Violation) {
+		report.Found++
+		if len(report.Violations) < limit {
+			report.Violations = append(report.Violations, v)
+		} else {
+			report.Truncated = true
+		}
+	}
+	now := time.Now()
+	type endpoint struct{ id, linkType string }
+	outgoing := map[endpoint][]*core.Link{}
+	incoming := map[endpoint][]*core.Link{}
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		links, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("reading links of %s: %w", id, err)
+		}
+		for _, l := range links {
+			if linkType != "" && l.Type != linkType {
+				continue
+			}
+			d := c.def(ctx, l.Type)
+			if d == nil {
+				continue
+			}
+			report.Checked++
+			for _, v := range c.check(ctx, d, l) {
+				add(v)
+			}
+			if current(l, now) {
+				outgoing[endpoint{l.Source, l.Type}] = append(outgoing[endpoint{l.Source, l.Type}], l)
+				incoming[endpoint{l.Target, l.Type}] = append(incoming[endpoint{l.Target, l.Type}], l)
+			}
+		}
+	}
+
+	over := func(counts map[endpoint][]*core.Link, max func(*LinkTypeDef) int, describe string) {
+		keys := make([]endpoint, 0, len(counts))
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].id != keys[j].id {
+				return keys[i].id < keys[j].id
+			}
+			return keys[i].linkType < keys[j].linkType
+		})
+		for _, k := range keys {
+			d := c.defs[k.linkType]
+			links := counts[k]
+			if m := max(d); m > 0 && len(links) > m {
+				add(Violation{
+					Source: links[0].Source, Target: links[0].Target, Type: k.linkType, Rule: RuleCardinality, Enforced: d.Enforce,
+					Message: fmt.Sprintf("%s has %d current %s%s link(s); at most %d allowed", k.id, len(links), describe, k.linkType, m),
+				})
+			}
+		}
+	}
+	over(outgoing, func(d *LinkTypeDef) int { return d.MaxPerSource }, "")
+	over(incoming, func(d *LinkTypeDef) int { return d.MaxPerTarget }, "incoming ")
+	return report, nil
+}
@@ -0,0 +1,193 @@
+package ontology
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func linkTestRepo(t *testing.T) graph.Repository {
+	t.Helper()
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for id, nodeType := range map[string]string{
+		"person:ada": "Person", "person:bob": "Person",
+		"company:acme": "Company", "company:initech": "Company",
+		"paper:notes": "Paper",
+	} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: nodeType, Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func newLink(source, target, linkType string, meta map[string]interface{}) *core.Link {
+	now := time.Now()
+	return &core.Link{Source: source, Target: target, Type: linkType, Meta: meta, Created: now, Modified: now}
+}
+
+func TestLinkTypeDefs(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	def := &LinkTypeDef{
+		Type:         "WORKS_AT",
+		Sources:      []string{"Person"},
+		Targets:      []string{"Company"},
+		Properties:   map[string]PropertyDef{"since": {Type: PropertyDate, Required: true}},
+		MaxPerSource: 1,
+		Enforce:      true,
+	}
+	created, err := PutLinkType(ctx, repo, def)
+	if err != nil || !created {
+		t.Fatalf("PutLinkType = %v, %v", created, err)
+	}
+	got, err := GetLinkType(ctx, repo, "WORKS_AT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Targets[0] != "Company" || !got.Properties["since"].Required || got.MaxPerSource != 1 || !got.Enforce {
+		t.Errorf("GetLinkType = %+v", got)
+	}
+
+	// Replacing clears fields left out
+	if created, err := PutLinkType(ctx, repo, &LinkTypeDef{Type: "WORKS_AT", Description: "Employment"}); err != nil || created {
+		t.Fatalf("replace PutLinkType = %v, %v", created, err)
+	}
+	got, _ = GetLinkType(ctx, repo, "WORKS_AT")
+	if got.Description != "Employment" || got.Sources != nil || got.Properties != nil || got.Enforce || got.Created.IsZero() {
+		t.Errorf("replaced definition = %+v", got)
+	}
+
+	for _, bad := range []*LinkTypeDef{
+		{Type: "has space"},
+		{Type: "KNOWS", Sources: []string{"not a type"}},
+		{Type: "KNOWS", Properties: map[string]PropertyDef{"since": {Type: "timestamp"}}},
+		{Type: "KNOWS", MaxPerTarget: -1},
+	} {
+		if _, err := PutLinkType(ctx, repo, bad); err == nil {
+			t.Errorf("PutLinkType(%+v) accepted", bad)
+		}
+	}
+
+	if defs, _ := ListLinkTypes(ctx, repo); len(defs) != 1 {
+		t.Errorf("ListLinkTypes = %d definitions, want 1", len(defs))
+	}
+	if err := DeleteLinkType(ctx, repo, "WORKS_AT"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetLinkType(ctx, repo, "WORKS_AT"); !errors.Is(err, ErrLinkTypeNotFound) {
+		t.Errorf("GetLinkType after delete error = %v", err)
+	}
+}
+
+func TestEnforcedLinkTypes(t *testing.T) {
+	ctx := context.Background()
+	base := linkTestRepo(t)
+	repo := Enforce(base)
+	if _, err := PutLinkType(ctx, base, &LinkTypeDef{
+		Type:         "WORKS_AT",
+		Sources:      []string{"Person"},
+		Targets:      []string{"Company"},
+		Properties:   map[string]PropertyDef{"since": {Type: PropertyDate, Required: true}, "title": {Type: PropertyString}},
+		MaxPerSource: 1,
+		Enforce:      true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		link *core.Link
+	}{
+		{"wrong source", newLink("paper:notes", "company:acme", "WORKS_AT", map[string]interface{}{"since": "2020-01-01"})},
+		{"wrong target", newLink("person:ada", "person:bob", "WORKS_AT", map[string]interface{}{"since": "2020-01-01"})},
+		{"missing target", newLink("person:ada", "company:gone", "WORKS_AT", map[string]interface{}{"since": "2020-01-01"})},
+		{"missing property", newLink("person:ada", "company:acme", "WORKS_AT", nil)},
+		{"mistyped property", newLink("person:ada", "company:acme", "WORKS_AT", map[string]interface{}{"since": "last year"})},
+		{"mistyped optional property", newLink("person:ada", "company:acme", "WORKS_AT", map[string]interface{}{"since": "2020-01-01", "title": 3.0})},
+	} {
+		if err := repo.CreateLink(ctx, tc.link); !errors.Is(err, ErrViolation) {
+			t.Errorf("%s: CreateLink error = %v, want ErrViolation", tc.name, err)
+		}
+	}
+
+	// One current employer: a past job does not count
+	past := newLink("person:ada", "company:initech", "WORKS_AT", map[string]interface{}{"since": "2015-01-01", graph.ValidToKey: "2019-12-31"})
+	if err := repo.CreateLink(ctx, past); err != nil {
+		t.Fatalf("CreateLink(past job) error = %v", err)
+	}
+	if err := repo.CreateLink(ctx, newLink("person:ada", "company:acme", "WORKS_AT", map[string]interface{}{"since": "2020-01-01"})); err != nil {
+		t.Fatalf("CreateLink(current job) error = %v", err)
+	}
+	if err := repo.CreateLink(ctx, newLink("person:ada", "company:initech", "WORKS_AT", map[string]interface{}{"since": "2024-01-01"})); !errors.Is(err, ErrViolation) {
+		t.Errorf("second current employer error = %v, want ErrViolation", err)
+	}
+	// Earlier links in a batch count too
+	err := repo.CreateLinks(ctx, []*core.Link{
+		newLink("person:bob", "company:acme", "WORKS_AT", map[string]interface{}{"since": "2021-01-01"}),
+		newLink("person:bob", "company:initech", "WORKS_AT", map[string]interface{}{"since": "2022-01-01"}),
+	})
+	if !errors.Is(err, ErrViolation) {
+		t.Errorf("CreateLinks with two current employers error = %v, want ErrViolation", err)
+	}
+	if links, _ := base.GetLinks(ctx, "person:bob"); len(links) != 0 {
+		t.Errorf("rejected batch wrote %d links", len(links))
+	}
+
+	// Types without a definition are not checked
+	if err := repo.CreateLink(ctx, newLink("paper:notes", "person:bob", "MENTIONS", nil)); err != nil {
+		t.Errorf("CreateLink(undefined type) error = %v", err)
+	}
+}
+
+func TestLinkViolationReport(t *testing.T) {
+	ctx := context.Background()
+	repo := Enforce(linkTestRepo(t))
+	// Not enforced: links are written and reported
+	if _, err := PutLinkType(ctx, repo, &LinkTypeDef{
+		Type:         "KNOWS",
+		Sources:      []string{"Person"},
+		Targets:      []string{"Person"},
+		MaxPerTarget: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []*core.Link{
+		newLink("person:ada", "person:bob", "KNOWS", nil),
+		newLink("company:acme", "person:bob", "KNOWS", nil),
+		newLink("person:bob", "paper:notes", "KNOWS", nil),
+	} {
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatalf("CreateLink(%s -> %s) error = %v", l.Source, l.Target, err)
+		}
+	}
+
+	report, err := Report(ctx, repo, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := map[string]int{}
+	for _, v := range report.Violations {
+		rules[v.Rule]++
+		if v.Enforced {
+			t.Errorf("violation %+v marked enforced", v)
+		}
+	}
+	if report.Checked != 3 || report.Found != 3 || rules[RuleSource] != 1 || rules[RuleTarget] != 1 || rules[RuleCardinality] != 1 {
+		t.Errorf("Report = %+v", report)
+	}
+
+	if report, _ := Report(ctx, repo, "KNOWS", 1); len(report.Violations) != 1 || report.Found != 3 || !report.Truncated {
+		t.Errorf("limited Report = %+v", report)
+	}
+	if report, _ := Report(ctx, repo, "WORKS_AT", 100); report.Checked != 0 {
+		t.Errorf("Report(WORKS_AT) checked %d links", report.Checked)
+	}
+}
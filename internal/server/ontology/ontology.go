@@ -0,0 +1,147 @@
+// Package ontology stores definitions of the node types in the graph,
+// including the visualization hints graph views hand to clients, and of
+// the link types, with the endpoints, properties and cardinality their
+// links must keep to.
+package ontology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// NodeType is the node type definitions are stored as
+const NodeType = "TypeDefinition"
+
+// idPrefix namespaces definition node IDs
+const idPrefix = "type:"
+
+// ErrNotFound is returned for types without a definition
+var ErrNotFound = errors.New("type definition not found")
+
+var validType = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+// TypeDef describes a node type and how to draw it
+type TypeDef struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	graph.NodeStyle
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+}
+
+// Validate checks a definition's type name and style
+func (d *TypeDef) Validate() error {
+	if !validType.MatchString(d.Type) {
+		return fmt.Errorf("invalid type %q (use 1-64 letters, digits or _, starting with a letter)", d.Type)
+	}
+	return d.NodeStyle.Validate()
+}
+
+// Put validates and stores a definition, replacing any existing one for
+// the type. It reports whether the definition is new.
+func Put(ctx context.Context, repo graph.Repository, d *TypeDef) (bool, error) {
+	if err := d.Validate(); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	existing, err := Get(ctx, repo, d.Type)
+	if err != nil {
+		d.Created, d.Modified = now, now
+		return true, repo.CreateNode(ctx, &core.Node{ID: idPrefix + d.Type, Type: NodeType, Meta: toMeta(d), Created: now, Modified: now})
+	}
+	d.Created, d.Modified = existing.Created, now
+	meta := toMeta(d)
+	// Absent fields are cleared rather than left from the old version
+	for _, key := range metaKeys {
+		if _, ok := meta[key]; !ok {
+			meta[key] = nil
+		}
+	}
+	return false, repo.UpdateNodeMeta(ctx, idPrefix+d.Type, meta)
+}
+
+// Get loads a type's definition
+func Get(ctx context.Context, repo graph.Repository, nodeType string) (*TypeDef, error) {
+	node, err := repo.GetNode(ctx, idPrefix+nodeType)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, nodeType)
+	}
+	return fromNode(node), nil
+}
+
+// List returns all definitions
+func List(ctx context.Context, repo graph.Repository) ([]*TypeDef, error) {
+	const pageSize = 500
+	out := []*TypeDef{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			out = append(out, fromNode(n))
+		}
+		if len(nodes) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+// Styles returns the visualization hints of every defined type
+func Styles(ctx context.Context, repo graph.Repository) (map[string]graph.NodeStyle, error) {
+	defs, err := List(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	styles := make(map[string]graph.NodeStyle, len(defs))
+	for _, d := range defs {
+		if d.NodeStyle != (graph.NodeStyle{}) {
+			styles[d.Type] = d.NodeStyle
+		}
+	}
+	return styles, nil
+}
+
+// Delete removes a type's definition and its history
+func Delete(ctx context.Context, repo graph.Repository, nodeType string) error {
+	if _, err := Get(ctx, repo, nodeType); err != nil {
+		return err
+	}
+	return repo.DeleteNode(ctx, idPrefix+nodeType, true)
+}
+
+// metaKeys are the node properties a definition is stored in
+var metaKeys = []string{"description", "color", "icon", "size_by"}
+
+// toMeta stores a definition's non-empty fields as node properties
+func toMeta(d *TypeDef) map[string]interface{} {
+	meta := map[string]interface{}{}
+	for i, value := range []string{d.Description, d.Color, d.Icon, d.SizeBy} {
+		if value != "" {
+			meta[metaKeys[i]] = value
+		}
+	}
+	return meta
+}
+
+// fromNode reads a definition back from its node
+func fromNode(node *core.Node) *TypeDef {
+	str := func(key string) string {
+		s, _ := node.Meta[key].(string)
+		return s
+	}
+	return &TypeDef{
+		Type:        strings.TrimPrefix(node.ID, idPrefix),
+		Description: str("description"),
+		NodeStyle:   graph.NodeStyle{Color: str("color"), Icon: str("icon"), SizeBy: str("size_by")},
+		Created:     node.Created,
+		Modified:    node.Modified,
+	}
+}
@@ -0,0 +1,76 @@
+package ontology
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestTypeDefs(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	created, err := Put(ctx, repo, &TypeDef{Type: "Person", Description: "A human", NodeStyle: graph.NodeStyle{Color: "#2e9e44", Icon: "user", SizeBy: "degree"}})
+	if err != nil || !created {
+		t.Fatalf("Put = %v, %v", created, err)
+	}
+	if _, err := Put(ctx, repo, &TypeDef{Type: "Paper", NodeStyle: graph.NodeStyle{SizeBy: "citations"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Put(ctx, repo, &TypeDef{Type: "Draft", Description: "Unstyled"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replacing clears fields left out
+	created, err = Put(ctx, repo, &TypeDef{Type: "Person", NodeStyle: graph.NodeStyle{Color: "teal"}})
+	if err != nil || created {
+		t.Fatalf("replace Put = %v, %v", created, err)
+	}
+	def, err := Get(ctx, repo, "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Color != "teal" || def.Icon != "" || def.Description != "" || def.Created.IsZero() {
+		t.Errorf("replaced definition = %+v", def)
+	}
+
+	styles, err := Styles(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(styles) != 2 || styles["Paper"].SizeBy != "citations" || styles["Person"].Color != "teal" {
+		t.Errorf("styles = %v", styles)
+	}
+
+	for _, bad := range []*TypeDef{
+		{Type: "has space"},
+		{Type: "Person", NodeStyle: graph.NodeStyle{Color: "url(evil)"}},
+		{Type: "Person", NodeStyle: graph.NodeStyle{Icon: "<svg>"}},
+		{Type: "Person", NodeStyle: graph.NodeStyle{SizeBy: "a b"}},
+	} {
+		if _, err := Put(ctx, repo, bad); err == nil {
+			t.Errorf("Put(%+v) accepted", bad)
+		}
+	}
+
+	// A node that merely shares the ID is not a definition
+	now := time.Now()
+	repo.CreateNode(ctx, &core.Node{ID: "type:Note", Type: "Tag", Created: now, Modified: now})
+	if _, err := Get(ctx, repo, "Note"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(Note) error = %v, want ErrNotFound", err)
+	}
+
+	if err := Delete(ctx, repo, "Paper"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Delete(ctx, repo, "Paper"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete error = %v", err)
+	}
+	if defs, _ := List(ctx, repo); len(defs) != 2 {
+		t.Errorf("List = %d definitions, want 2", len(defs))
+	}
+}
@@ -0,0 +1,147 @@
+package people
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// addressBookQuery asks a CardDAV server for every card in a collection
+const addressBookQuery = `<?xml version="1.0" encoding="utf-8"?>
+<C:addressbook-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav">
+  <D:prop><D:getetag/><C:address-data/></D:prop>
+</C:addressbook-query>`
+
+// maxAddressBookBytes caps a CardDAV response
+const maxAddressBookBytes = 64 << 20
+
+// CardDAVOptions selects the address book to read
+type CardDAVOptions struct {
+	URL        string // Address book collection URL
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+// multistatus is the part of a WebDAV multistatus response we read
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"href"`
+		Propstats []struct {
+			Status      string `xml:"status"`
+			AddressData string `xml:"prop>address-data"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// FetchCardDAV reads every contact in a CardDAV address book
+func FetchCardDAV(ctx context.Context, opts CardDAVOptions) ([]Contact, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("address book URL is required")
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, "REPORT", opts.URL, strings.NewReader(addressBookQuery))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+	if opts.Username != "" || opts.Password != "" {
+		req.SetBasicAuth(opts.Username, opts.Password)
+	}
+	resp, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling CardDAV server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("CardDAV server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxAddressBookBytes)).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("decoding CardDAV response: %w", err)
+	}
+	var contacts []Contact
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstats {
+			if ps.AddressData == "" || (ps.Status != "" && !strings.Contains(ps.Status, " 200 ")) {
+				continue
+			}
+			cards, err := ParseVCards(strings.NewReader(ps.AddressData))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Href, err)
+			}
+			contacts = append(contacts, cards...)
+		}
+	}
+	return contacts, nil
+}
+
+// Syncer syncs a CardDAV address book periodically
+type Syncer struct {
+	repo     graph.Repository
+	opts     CardDAVOptions
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSyncer creates a syncer that runs every interval
+func NewSyncer(repo graph.Repository, opts CardDAVOptions, interval time.Duration) *Syncer {
+	return &Syncer{repo: repo, opts: opts, interval: interval, stop: make(chan struct{})}
+}
+
+// Start syncs once, then periodically
+func (s *Syncer) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.sync()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sync()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("CardDAV sync started (every %s)", s.interval)
+}
+
+// Stop halts periodic syncs and waits for a running sync to finish
+func (s *Syncer) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Syncer) sync() {
+	ctx := context.Background()
+	contacts, err := FetchCardDAV(ctx, s.opts)
+	if err != nil {
+		log.Printf("CardDAV sync failed: %v", err)
+		return
+	}
+	result, err := SyncContacts(ctx, s.repo, contacts, SyncOptions{Connector: "carddav"})
+	if err != nil {
+		log.Printf("CardDAV sync failed: %v", err)
+		return
+	}
+	if result.Created > 0 || result.Updated > 0 {
+		log.Printf("CardDAV sync: %d people created, %d updated", result.Created, result.Updated)
+	}
+}
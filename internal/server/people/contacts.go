@@ -0,0 +1,401 @@
+// Package people keeps Person nodes in step with address books (vCard
+// files and CardDAV) and resolves email addresses and phone numbers to
+// the people they belong to.
+package people
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Node and link types
+const (
+	PersonType  = "Person"
+	AliasType   = "Alias"
+	AliasOfLink = "ALIAS_OF" // Alias to the person it identifies
+)
+
+// Alias kinds, the middle part of alias node IDs (alias:email:ada@example.com)
+const (
+	AliasEmail = "email"
+	AliasPhone = "tel"
+	AliasUID   = "uid" // Address book contact UID
+)
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Lookup is the subset of graph operations resolving people needs
+type Lookup interface {
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error)
+}
+
+// Contact is an address book entry
+type Contact struct {
+	UID      string   `json:"uid,omitempty"`
+	Name     string   `json:"name"`
+	Emails   []string `json:"emails,omitempty"`
+	Phones   []string `json:"phones,omitempty"`
+	Org      string   `json:"org,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Nickname string   `json:"nickname,omitempty"`
+	Birthday string   `json:"birthday,omitempty"`
+}
+
+// SyncOptions controls a contact sync
+type SyncOptions struct {
+	Connector string // Recorded on people, e.g. vcard or carddav
+	ChangedBy string // Recorded on updates (default <connector>-sync)
+}
+
+// Conflict is a contact whose aliases belong to more than one person.
+// The contact is merged into the first; the others are left alone.
+type Conflict struct {
+	Contact string   `json:"contact"`
+	People  []string `json:"people"`
+}
+
+// SyncResult summarizes a contact sync
+type SyncResult struct {
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Aliases   int        `json:"aliases"` // Alias nodes created
+	Skipped   int        `json:"skipped"` // Contacts without a name, email or phone
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// AliasID returns the alias node ID for an email, phone or UID, or ""
+// when the value does not normalize to anything
+func AliasID(kind, value string) string {
+	switch kind {
+	case AliasEmail:
+		value = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "mailto:")))
+		if !strings.Contains(value, "@") {
+			return ""
+		}
+	case AliasPhone:
+		value = NormalizePhone(value)
+	default:
+		value = strings.TrimSpace(value)
+	}
+	if value == "" {
+		return ""
+	}
+	return "alias:" + kind + ":" + value
+}
+
+// NormalizePhone reduces a phone number to its digits, keeping a leading
+// +; numbers with fewer than five digits are dropped
+func NormalizePhone(phone string) string {
+	phone = strings.TrimPrefix(strings.TrimSpace(phone), "tel:")
+	var b strings.Builder
+	for i, r := range phone {
+		if r >= '0' && r <= '9' || r == '+' && i == 0 {
+			b.WriteRune(r)
+		}
+	}
+	if digits := strings.TrimPrefix(b.String(), "+"); len(digits) < 5 {
+		return ""
+	}
+	return b.String()
+}
+
+// Resolve returns the person an email address or phone number belongs to
+func Resolve(ctx context.Context, repo Lookup, kind, value string) (*core.Node, error) {
+	id := AliasID(kind, value)
+	if id == "" {
+		return nil, fmt.Errorf("invalid %s: %q", kind, value)
+	}
+	personID, err := aliasTarget(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	if personID == "" {
+		return nil, fmt.Errorf("no person with %s %s", kind, value)
+	}
+	return repo.GetNode(ctx, personID)
+}
+
+// ResolveName returns the person with a name or nickname, ignoring case
+func ResolveName(ctx context.Context, repo Lookup, name string) (*core.Node, error) {
+	name = strings.TrimSpace(name)
+	for _, key := range []string{"name", "nickname"} {
+		candidates, err := repo.FilterNodes(ctx, []string{PersonType}, key, name, 10, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range candidates {
+			if value, _ := n.Meta[key].(string); name != "" && strings.EqualFold(value, name) {
+				return n, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no person named %q", name)
+}
+
+// SyncContacts creates or updates a Person node per contact. A contact is
+// matched to an existing person by its UID, then its emails and phone
+// numbers; each of those becomes an Alias node linked ALIAS_OF to the
+// person, so connectors can resolve participants without duplicating
+// people. Emails and phones are only ever added, never removed.
+func SyncContacts(ctx context.Context, repo graph.Repository, contacts []Contact, opts SyncOptions) (*SyncResult, error) {
+	if opts.Connector == "" {
+		return nil, fmt.Errorf("connector is required")
+	}
+	if opts.ChangedBy == "" {
+		opts.ChangedBy = opts.Connector + "-sync"
+	}
+	result := &SyncResult{}
+	for _, c := range contacts {
+		if err := syncContact(ctx, repo, c, opts, result); err != nil {
+			return result, fmt.Errorf("contact %q: %w", c.Name, err)
+		}
+	}
+	return result, nil
+}
+
+func syncContact(ctx context.Context, repo graph.Repository, c Contact, opts SyncOptions, result *SyncResult) error {
+	aliases := contactAliases(c)
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" && len(c.Emails) > 0 {
+		c.Name = strings.TrimSpace(c.Emails[0])
+	}
+	if c.Name == "" || len(aliases) == 0 {
+		result.Skipped++
+		return nil
+	}
+
+	// Find who the aliases already point at, in alias order (UID first)
+	var matches []string
+	owner := make(map[string]string)
+	for _, alias := range aliases {
+		person, err := aliasTarget(ctx, repo, alias)
+		if err != nil {
+			return err
+		}
+		if person == "" {
+			continue
+		}
+		owner[alias] = person
+		if !contains(matches, person) {
+			matches = append(matches, person)
+		}
+	}
+	if len(matches) > 1 {
+		result.Conflicts = append(result.Conflicts, Conflict{Contact: c.Name, People: matches})
+	}
+
+	var personID string
+	if len(matches) > 0 {
+		personID = matches[0]
+		person, err := repo.GetNode(ctx, personID)
+		if err != nil {
+			return err
+		}
+		changes := mergeContact(person.Meta, c, opts.Connector)
+		if len(changes) == 0 {
+			result.Unchanged++
+		} else {
+			if err := repo.UpdateNodeMetaWithNote(ctx, personID, changes, "Contact sync", opts.ChangedBy); err != nil {
+				return err
+			}
+			result.Updated++
+		}
+	} else {
+		id, err := newPersonID(ctx, repo, c.Name)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		meta := mergeContact(map[string]interface{}{}, c, opts.Connector)
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: PersonType, Meta: meta, Created: now, Modified: now}); err != nil {
+			return err
+		}
+		personID = id
+		result.Created++
+	}
+
+	// Point unclaimed aliases at the person; aliases owned by someone else stay theirs
+	now := time.Now()
+	var links []*core.Link
+	for _, alias := range aliases {
+		if owner[alias] != "" && owner[alias] != personID {
+			continue
+		}
+		if _, err := repo.GetNode(ctx, alias); err == nil {
+			if owner[alias] == personID {
+				continue // Already linked
+			}
+		} else {
+			kind, value := splitAlias(alias)
+			node := &core.Node{ID: alias, Type: AliasType, Meta: map[string]interface{}{"kind": kind, "value": value}, Created: now, Modified: now}
+			if err := repo.CreateNode(ctx, node); err != nil {
+				return err
+			}
+			result.Aliases++
+		}
+		links = append(links, &core.Link{Source: alias, Target: personID, Type: AliasOfLink, Created: now, Modified: now})
+	}
+	if len(links) > 0 {
+		return repo.CreateLinks(ctx, links)
+	}
+	return nil
+}
+
+// contactAliases lists a contact's alias IDs: UID, emails, then phones
+func contactAliases(c Contact) []string {
+	var aliases []string
+	add := func(kind, value string) {
+		if id := AliasID(kind, value); id != "" && !contains(aliases, id) {
+			aliases = append(aliases, id)
+		}
+	}
+	if c.UID != "" {
+		add(AliasUID, c.UID)
+	}
+	for _, e := range c.Emails {
+		add(AliasEmail, e)
+	}
+	for _, p := range c.Phones {
+		add(AliasPhone, p)
+	}
+	return aliases
+}
+
+// aliasTarget returns the person an alias points at, if any. Email aliases
+// fall back to a Person whose email property matches, for people created
+// before their address book was synced.
+func aliasTarget(ctx context.Context, repo Lookup, alias string) (string, error) {
+	if _, err := repo.GetNode(ctx, alias); err != nil {
+		kind, value := splitAlias(alias)
+		if kind != AliasEmail {
+			return "", nil
+		}
+		candidates, err := repo.FilterNodes(ctx, []string{PersonType}, "email", value, 10, 0)
+		if err != nil {
+			return "", err
+		}
+		for _, n := range candidates {
+			if email, _ := n.Meta["email"].(string); AliasID(AliasEmail, email) == alias {
+				return n.ID, nil
+			}
+		}
+		return "", nil
+	}
+	links, err := repo.GetLinks(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+	for _, l := range links {
+		if l.Type == AliasOfLink {
+			if _, err := repo.GetNode(ctx, l.Target); err == nil {
+				return l.Target, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// mergeContact returns the properties that syncing c would change. Email
+// and phone lists are unions with what the person already has; other
+// fields follow the address book.
+func mergeContact(meta map[string]interface{}, c Contact, connector string) map[string]any {
+	changes := make(map[string]any)
+	set := func(key, value string) {
+		if value = strings.TrimSpace(value); value == "" {
+			return
+		}
+		if current, _ := meta[key].(string); current != value {
+			changes[key] = value
+		}
+	}
+	set("name", c.Name)
+	set("org", c.Org)
+	set("title", c.Title)
+	set("nickname", c.Nickname)
+	set("birthday", c.Birthday)
+	set("contact_uid", c.UID)
+	if _, ok := meta["connector"]; !ok {
+		changes["connector"] = connector
+	}
+
+	union := func(key, kind string, values []string) {
+		current := stringList(meta[key])
+		merged := append([]string(nil), current...)
+		seen := make(map[string]bool)
+		for _, v := range current {
+			seen[AliasID(kind, v)] = true
+		}
+		for _, v := range values {
+			if id := AliasID(kind, v); id != "" && !seen[id] {
+				seen[id] = true
+				_, normalized := splitAlias(id)
+				merged = append(merged, normalized)
+			}
+		}
+		if len(merged) > len(current) {
+			changes[key] = merged
+		}
+	}
+	union("emails", AliasEmail, c.Emails)
+	union("phones", AliasPhone, c.Phones)
+	return changes
+}
+
+// newPersonID picks an unused ID from the person's name
+func newPersonID(ctx context.Context, repo graph.Repository, name string) (string, error) {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		slug = "contact"
+	}
+	for i := 1; i < 1000; i++ {
+		id := "person:" + slug
+		if i > 1 {
+			id = fmt.Sprintf("person:%s-%d", slug, i)
+		}
+		if _, err := repo.GetNode(ctx, id); err != nil {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no free person ID for %q", name)
+}
+
+// splitAlias returns an alias ID's kind and value
+func splitAlias(id string) (string, string) {
+	kind, value, _ := strings.Cut(strings.TrimPrefix(id, "alias:"), ":")
+	return kind, value
+}
+
+// stringList reads a list property, which comes back as []interface{}
+// from stored JSON
+func stringList(v interface{}) []string {
+	var out []string
+	switch list := v.(type) {
+	case []string:
+		out = append(out, list...)
+	case []interface{}:
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
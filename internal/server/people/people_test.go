@@ -0,0 +1,98 @@
+package people
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+const sampleVCards = "BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"UID:urn:uuid:4fbe8971-0bc3-424c-9c26-36c3e1eff6b1\r\n" +
+	"FN:Ada Lovelace\r\n" +
+	"N:Lovelace;Ada;;;\r\n" +
+	"ORG:Analytical Engines\\, Ltd;Research\r\n" +
+	"item1.EMAIL;TYPE=INTERNET:Ada@Example.com\r\n" +
+	"EMAIL;TYPE=work:ada@engines.example\r\n" +
+	"TEL;TYPE=CELL:+44 20 7946\r\n" +
+	" 0958\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:2.1\r\n" +
+	"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:G=C3=B6del;Kurt;;;\r\n" +
+	"TEL;HOME:(555) 010-2030\r\n" +
+	"END:VCARD\r\n"
+
+func TestParseVCards(t *testing.T) {
+	contacts, err := ParseVCards(strings.NewReader(sampleVCards))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contacts) != 2 {
+		t.Fatalf("got %d contacts, want 2", len(contacts))
+	}
+	ada := contacts[0]
+	if ada.Name != "Ada Lovelace" || ada.UID != "4fbe8971-0bc3-424c-9c26-36c3e1eff6b1" || ada.Org != "Analytical Engines, Ltd" {
+		t.Errorf("unexpected contact %+v", ada)
+	}
+	if len(ada.Emails) != 2 || ada.Emails[0] != "Ada@Example.com" || len(ada.Phones) != 1 || NormalizePhone(ada.Phones[0]) != "+442079460958" {
+		t.Errorf("unexpected emails/phones %v %v", ada.Emails, ada.Phones)
+	}
+	if contacts[1].Name != "Kurt Gödel" {
+		t.Errorf("name from N = %q, want Kurt Gödel", contacts[1].Name)
+	}
+}
+
+func TestSyncContactsResolvesExistingPeople(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	// A person created by another connector before the address book was synced
+	if err := repo.CreateNode(ctx, &core.Node{ID: "person:ada", Type: PersonType, Meta: map[string]interface{}{"name": "Ada", "email": "ada@example.com"}, Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	contacts, err := ParseVCards(strings.NewReader(sampleVCards))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := SyncContacts(ctx, repo, contacts, SyncOptions{Connector: "vcard"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 1 || result.Updated != 1 || result.Aliases != 5 {
+		t.Errorf("first sync = %+v, want 1 created, 1 updated, 5 aliases", result)
+	}
+
+	for _, q := range []struct{ kind, value, want string }{
+		{AliasEmail, "ADA@example.com", "person:ada"},
+		{AliasEmail, "mailto:ada@engines.example", "person:ada"},
+		{AliasPhone, "+44 (20) 7946-0958", "person:ada"},
+		{AliasPhone, "555.010.2030", "person:kurt-g-del"},
+	} {
+		person, err := Resolve(ctx, repo, q.kind, q.value)
+		if err != nil || person.ID != q.want {
+			t.Errorf("Resolve(%s, %s) = %v, %v; want %s", q.kind, q.value, person, err, q.want)
+		}
+	}
+	if _, err := Resolve(ctx, repo, AliasEmail, "nobody@example.com"); err == nil {
+		t.Error("expected unknown email to be unresolved")
+	}
+
+	ada, _ := repo.GetNode(ctx, "person:ada")
+	if ada.Meta["name"] != "Ada Lovelace" || len(stringList(ada.Meta["emails"])) != 2 {
+		t.Errorf("person not updated from contact: %v", ada.Meta)
+	}
+
+	again, err := SyncContacts(ctx, repo, contacts, SyncOptions{Connector: "vcard"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Created != 0 || again.Updated != 0 || again.Unchanged != 2 || again.Aliases != 0 {
+		t.Errorf("re-sync = %+v, want everything unchanged", again)
+	}
+}
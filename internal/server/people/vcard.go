@@ -0,0 +1,160 @@
+package people
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// ParseVCards reads the contacts in a vCard file (versions 2.1, 3.0 and 4.0)
+func ParseVCards(r io.Reader) ([]Contact, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []Contact
+	var current *Contact
+	var family, given string
+	for n, line := range lines {
+		name, params, value, ok := splitProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCARD"):
+			current = &Contact{}
+			family, given = "", ""
+			continue
+		case name == "END" && strings.EqualFold(value, "VCARD"):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: END:VCARD without BEGIN", n+1)
+			}
+			if current.Name == "" {
+				current.Name = strings.TrimSpace(given + " " + family)
+			}
+			contacts = append(contacts, *current)
+			current = nil
+			continue
+		case current == nil:
+			continue
+		}
+
+		if strings.Contains(params, "QUOTED-PRINTABLE") {
+			if decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(value))); err == nil {
+				value = string(decoded)
+			}
+		}
+		switch name {
+		case "FN":
+			current.Name = unescapeValue(value)
+		case "N":
+			parts := splitValue(value)
+			family = parts[0]
+			if len(parts) > 1 {
+				given = parts[1]
+			}
+		case "EMAIL":
+			if email := unescapeValue(value); email != "" {
+				current.Emails = append(current.Emails, email)
+			}
+		case "TEL":
+			if tel := unescapeValue(value); tel != "" {
+				current.Phones = append(current.Phones, tel)
+			}
+		case "ORG":
+			current.Org = splitValue(value)[0]
+		case "TITLE":
+			current.Title = unescapeValue(value)
+		case "NICKNAME":
+			current.Nickname = splitValue(strings.ReplaceAll(value, ",", ";"))[0]
+		case "BDAY":
+			current.Birthday = unescapeValue(value)
+		case "UID":
+			current.UID = strings.TrimPrefix(unescapeValue(value), "urn:uuid:")
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("vCard for %q is missing END:VCARD", current.Name)
+	}
+	return contacts, nil
+}
+
+// unfoldLines joins folded lines (a line break followed by a space or tab)
+// and vCard 2.1 quoted-printable soft line breaks
+func unfoldLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20) // Inline photos make long lines
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if n := len(lines); n > 0 {
+			prev := lines[n-1]
+			if line != "" && (line[0] == ' ' || line[0] == '\t') {
+				lines[n-1] = prev + line[1:]
+				continue
+			}
+			if strings.HasSuffix(prev, "=") && strings.Contains(strings.ToUpper(prev), "QUOTED-PRINTABLE") {
+				lines[n-1] = prev + "\r\n" + line
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty splits "group.NAME;PARAM=x:value" into its upper-cased
+// name, upper-cased parameters and value
+func splitProperty(line string) (name, params, value string, ok bool) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", "", false
+	}
+	name, params, _ = strings.Cut(head, ";")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToUpper(strings.TrimSpace(name)), strings.ToUpper(params), strings.TrimSpace(value), true
+}
+
+// splitValue splits a structured value on unescaped semicolons
+func splitValue(value string) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			b.WriteByte('\\')
+			b.WriteByte(value[i+1])
+			i++
+		case value[i] == ';':
+			parts = append(parts, unescapeValue(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return append(parts, unescapeValue(b.String()))
+}
+
+// unescapeValue undoes vCard text escaping
+func unescapeValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(value[i])
+			}
+			continue
+		}
+		b.WriteByte(value[i])
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,429 @@
+// Package projects groups nodes into Project workspaces and rolls up what
+// is happening inside them.
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/tasks"
+)
+
+// Node and link types
+const (
+	ProjectType  = "Project"
+	MemberOfLink = "MEMBER_OF" // Member node to its project
+)
+
+// attentionLink is the link type attention edges use
+const attentionLink = "ATTENDED"
+
+// maxScope caps the nodes an overview considers
+const maxScope = 5000
+
+// ErrNotProject is returned for a node that is not a Project
+var ErrNotProject = errors.New("node is not a project")
+
+// notDocuments are node types never ranked as documents
+var notDocuments = map[string]bool{
+	ProjectType: true, tasks.TaskType: true, "Person": true, "Author": true, "Venue": true,
+	"Alias": true, "Comment": true, "Subscription": true, "Transaction": true, "SavedQuery": true,
+	"LinkRule": true, "MemoryCard": true, "Stats": true, "IngestHook": true, "GitHubSync": true, "TicketSync": true,
+	"TypeRule": true,
+}
+
+// peopleTypes are node types ranked as people
+var peopleTypes = map[string]bool{"Person": true, "Author": true}
+
+// Repository is the subset of graph operations projects need
+type Repository interface {
+	tasks.Repository
+	DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error
+}
+
+// NodeRef identifies a node for display
+type NodeRef struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// Summary is a project as listed by the API
+type Summary struct {
+	NodeRef
+	Members  int       `json:"members"`
+	Modified time.Time `json:"modified"`
+}
+
+// Activity is a recent change to a node in a project
+type Activity struct {
+	NodeRef
+	Action   string    `json:"action"` // created or updated
+	Version  int       `json:"version"`
+	Modified time.Time `json:"modified"`
+}
+
+// Ranked is a node ranked within a project
+type Ranked struct {
+	NodeRef
+	Score float64 `json:"score"`
+	Links int     `json:"links"` // Links to other nodes in the project
+}
+
+// Overview rolls up a project
+type Overview struct {
+	Project        NodeRef            `json:"project"`
+	Description    string             `json:"description,omitempty"`
+	Members        map[string]int     `json:"members"` // Member counts by type
+	Scope          int                `json:"scope"`   // Members and the nodes linked to them
+	Truncated      bool               `json:"truncated"`
+	RecentActivity []Activity         `json:"recent_activity"`
+	TaskCounts     tasks.StatusCounts `json:"task_counts"`
+	OpenTasks      []tasks.Task       `json:"open_tasks"` // Open, doing and blocked, soonest due first
+	TopDocuments   []Ranked           `json:"top_documents"`
+	KeyPeople      []Ranked           `json:"key_people"`
+	Hotspots       []Ranked           `json:"attention_hotspots"`
+}
+
+// OverviewOptions controls an overview
+type OverviewOptions struct {
+	Limit int           // Entries per section (default 10)
+	Since time.Duration // Recent activity window (default 14 days)
+}
+
+func ref(n *core.Node) NodeRef {
+	return NodeRef{ID: n.ID, Type: n.Type, Label: graph.NodeLabel(n.ID, n.Meta)}
+}
+
+// GetProject returns a project node, or ErrNotProject
+func GetProject(ctx context.Context, repo Repository, id string) (*core.Node, error) {
+	node, err := repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if node.Type != ProjectType {
+		return nil, ErrNotProject
+	}
+	return node, nil
+}
+
+// List returns every project with its direct member count
+func List(ctx context.Context, repo Repository) ([]Summary, error) {
+	var projects []*core.Node
+	for offset := 0; ; offset += 500 {
+		page, err := repo.FilterNodes(ctx, []string{ProjectType}, "", "", 500, offset)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, page...)
+		if len(page) < 500 {
+			break
+		}
+	}
+
+	summaries := []Summary{}
+	for _, p := range projects {
+		backlinks, err := repo.GetBacklinks(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		s := Summary{NodeRef: ref(p), Modified: p.Modified}
+		for _, l := range backlinks {
+			if l.Type == MemberOfLink {
+				s.Members++
+			}
+		}
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Modified.After(summaries[j].Modified) })
+	return summaries, nil
+}
+
+// AddMembers links nodes to a project with MEMBER_OF, skipping nodes that
+// are already members. It returns the nodes added and those not found.
+func AddMembers(ctx context.Context, repo Repository, projectID string, nodeIDs []string) (added, missing []string, err error) {
+	if _, err := GetProject(ctx, repo, projectID); err != nil {
+		return nil, nil, err
+	}
+	backlinks, err := repo.GetBacklinks(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member := make(map[string]bool)
+	for _, l := range backlinks {
+		if l.Type == MemberOfLink {
+			member[l.Source] = true
+		}
+	}
+
+	now := time.Now()
+	var links []*core.Link
+	for _, id := range nodeIDs {
+		if member[id] || id == projectID {
+			continue
+		}
+		if _, err := repo.GetNode(ctx, id); err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		member[id] = true
+		added = append(added, id)
+		links = append(links, &core.Link{Source: id, Target: projectID, Type: MemberOfLink, Created: now, Modified: now})
+	}
+	if len(links) > 0 {
+		if err := repo.CreateLinks(ctx, links); err != nil {
+			return nil, nil, err
+		}
+	}
+	return added, missing, nil
+}
+
+// RemoveMember unlinks a node from a project
+func RemoveMember(ctx context.Context, repo Repository, projectID, nodeID string) error {
+	if _, err := GetProject(ctx, repo, projectID); err != nil {
+		return err
+	}
+	return repo.DeleteLink(ctx, nodeID, projectID, MemberOfLink)
+}
+
+// Members returns a project's members, including the members of projects
+// that are themselves members
+func Members(ctx context.Context, repo Repository, projectID string) ([]*core.Node, error) {
+	var members []*core.Node
+	seen := map[string]bool{projectID: true}
+	queue := []string{projectID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		backlinks, err := repo.GetBacklinks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range backlinks {
+			if l.Type != MemberOfLink || seen[l.Source] {
+				continue
+			}
+			seen[l.Source] = true
+			node, err := repo.GetNode(ctx, l.Source)
+			if err != nil {
+				continue // Dangling link
+			}
+			members = append(members, node)
+			if node.Type == ProjectType {
+				queue = append(queue, node.ID)
+			}
+		}
+	}
+	return members, nil
+}
+
+// GetOverview rolls up a project: recent activity, open tasks, the most
+// connected documents and people, and the nodes drawing the most
+// attention. Rankings consider the project's members and the nodes
+// directly linked to them.
+func GetOverview(ctx context.Context, repo Repository, projectID string, opts OverviewOptions, now time.Time) (*Overview, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	if opts.Since <= 0 {
+		opts.Since = 14 * 24 * time.Hour
+	}
+	project, err := GetProject(ctx, repo, projectID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := Members(ctx, repo, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Overview{Project: ref(project), Members: make(map[string]int)}
+	o.Description, _ = project.Meta["description"].(string)
+	for _, m := range members {
+		o.Members[m.Type]++
+	}
+
+	sc, err := buildScope(ctx, repo, projectID, members)
+	if err != nil {
+		return nil, err
+	}
+	o.Scope, o.Truncated = len(sc.nodes), sc.truncated
+
+	o.RecentActivity = recentActivity(sc, now.Add(-opts.Since), opts.Limit)
+	o.TopDocuments = rank(sc, func(n *core.Node) bool { return !notDocuments[n.Type] }, false, opts.Limit)
+	o.KeyPeople = rank(sc, func(n *core.Node) bool { return peopleTypes[n.Type] }, false, opts.Limit)
+	o.Hotspots = rank(sc, func(n *core.Node) bool { return n.Type != tasks.TaskType }, true, opts.Limit)
+
+	all, err := tasks.List(ctx, repo, tasks.Filter{ProjectID: projectID}, now)
+	if err != nil {
+		return nil, err
+	}
+	o.OpenTasks = []tasks.Task{}
+	for _, t := range all {
+		switch t.Status {
+		case tasks.StatusDone:
+			o.TaskCounts.Done++
+			continue
+		case tasks.StatusDoing:
+			o.TaskCounts.Doing++
+		case tasks.StatusBlocked:
+			o.TaskCounts.Blocked++
+		default:
+			o.TaskCounts.Open++
+		}
+		if len(o.OpenTasks) < opts.Limit {
+			o.OpenTasks = append(o.OpenTasks, t)
+		}
+	}
+	return o, nil
+}
+
+// scope is a project's members and their neighbors, with the links among them
+type scope struct {
+	nodes     map[string]*core.Node
+	links     map[string]int     // In-scope neighbors per node (attention and membership excluded)
+	attention map[string]float64 // Attention weight per node, over edges within the scope
+	truncated bool
+}
+
+func buildScope(ctx context.Context, repo Repository, projectID string, members []*core.Node) (*scope, error) {
+	sc := &scope{nodes: make(map[string]*core.Node), links: make(map[string]int), attention: make(map[string]float64)}
+	for _, m := range members {
+		sc.nodes[m.ID] = m
+	}
+
+	// Neighbors of members join the scope
+	var all []*core.Link
+	for _, m := range members {
+		links, err := nodeLinks(ctx, repo, m.ID)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, links...)
+		for _, l := range links {
+			for _, id := range []string{l.Source, l.Target} {
+				if sc.nodes[id] != nil || id == projectID {
+					continue
+				}
+				if len(sc.nodes) >= maxScope {
+					sc.truncated = true
+					continue
+				}
+				node, err := repo.GetNode(ctx, id)
+				if err != nil || node.Type == ProjectType || node.Type == "Alias" {
+					continue
+				}
+				sc.nodes[id] = node
+			}
+		}
+	}
+
+	// Links between neighbors count too, e.g. a task ASSIGNED_TO a person
+	isMember := make(map[string]bool, len(members))
+	for _, m := range members {
+		isMember[m.ID] = true
+	}
+	for id := range sc.nodes {
+		if isMember[id] {
+			continue
+		}
+		links, err := repo.GetLinks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, links...)
+	}
+
+	seenPair := make(map[[3]string]bool)
+	for _, l := range all {
+		if sc.nodes[l.Source] == nil || sc.nodes[l.Target] == nil || l.Source == l.Target || l.Type == MemberOfLink {
+			continue
+		}
+		key := [3]string{l.Source, l.Target, l.Type}
+		if seenPair[key] {
+			continue // Seen from both ends
+		}
+		seenPair[key] = true
+		if l.Type == attentionLink {
+			sc.attention[l.Source] += l.Weight
+			sc.attention[l.Target] += l.Weight
+			continue
+		}
+		sc.links[l.Source]++
+		sc.links[l.Target]++
+	}
+	return sc, nil
+}
+
+// nodeLinks returns a node's outgoing and incoming links
+func nodeLinks(ctx context.Context, repo Repository, id string) ([]*core.Link, error) {
+	outgoing, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links for %s: %w", id, err)
+	}
+	incoming, err := repo.GetBacklinks(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backlinks for %s: %w", id, err)
+	}
+	return append(outgoing, incoming...), nil
+}
+
+// recentActivity lists scope nodes changed since a time, newest first
+func recentActivity(sc *scope, since time.Time, limit int) []Activity {
+	activity := []Activity{}
+	for _, n := range sc.nodes {
+		if n.Modified.Before(since) {
+			continue
+		}
+		action := "updated"
+		if n.Version <= 1 {
+			action = "created"
+		}
+		activity = append(activity, Activity{NodeRef: ref(n), Action: action, Version: n.Version, Modified: n.Modified})
+	}
+	sort.Slice(activity, func(i, j int) bool {
+		if !activity[i].Modified.Equal(activity[j].Modified) {
+			return activity[i].Modified.After(activity[j].Modified)
+		}
+		return activity[i].ID < activity[j].ID
+	})
+	if len(activity) > limit {
+		activity = activity[:limit]
+	}
+	return activity
+}
+
+// rank orders matching scope nodes by their links within the project, plus
+// attention weight; byAttention ranks by attention alone, dropping nodes
+// without any
+func rank(sc *scope, match func(*core.Node) bool, byAttention bool, limit int) []Ranked {
+	ranked := []Ranked{}
+	for id, n := range sc.nodes {
+		if !match(n) {
+			continue
+		}
+		score := float64(sc.links[id]) + sc.attention[id]
+		if byAttention {
+			score = sc.attention[id]
+		}
+		if score <= 0 {
+			continue
+		}
+		ranked = append(ranked, Ranked{NodeRef: ref(n), Score: score, Links: sc.links[id]})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
@@ -0,0 +1,98 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/tasks"
+)
+
+func TestOverview(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	old := now.AddDate(0, -2, 0)
+	for _, n := range []*core.Node{
+		{ID: "project:launch", Type: ProjectType, Meta: map[string]interface{}{"name": "Launch", "description": "Q4 launch"}, Created: now, Modified: now},
+		{ID: "project:press", Type: ProjectType, Meta: map[string]interface{}{"name": "Press"}, Created: now, Modified: now},
+		{ID: "doc:plan", Type: "Document", Meta: map[string]interface{}{"title": "Launch plan"}, Created: now, Modified: now},
+		{ID: "doc:release", Type: "Document", Meta: map[string]interface{}{"title": "Press release"}, Created: now, Modified: now},
+		{ID: "doc:old", Type: "Document", Meta: map[string]interface{}{"title": "Old notes"}, Created: old, Modified: old, Version: 1},
+		{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"name": "Ada"}, Created: now, Modified: now},
+		{ID: "person:grace", Type: "Person", Meta: map[string]interface{}{"name": "Grace"}, Created: now, Modified: now},
+		{ID: "task:1", Type: tasks.TaskType, Meta: map[string]interface{}{"title": "Book venue", "status": tasks.StatusOpen}, Created: now, Modified: now},
+		{ID: "task:2", Type: tasks.TaskType, Meta: map[string]interface{}{"title": "Share agenda", "status": tasks.StatusDone}, Created: now, Modified: now},
+		{ID: "doc:unrelated", Type: "Document", Created: now, Modified: now},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "task:1", Target: "doc:plan", Type: tasks.FromSourceLink},
+		{Source: "task:2", Target: "doc:plan", Type: tasks.FromSourceLink},
+		{Source: "task:1", Target: "person:ada", Type: tasks.AssignedToLink},
+		{Source: "doc:plan", Target: "person:ada", Type: "AUTHORED_BY"},
+		{Source: "doc:release", Target: "person:grace", Type: "AUTHORED_BY"},
+		{Source: "doc:plan", Target: "doc:release", Type: "ATTENDED", Meta: map[string]interface{}{"weight": 2.5}},
+		{Source: "doc:unrelated", Target: "person:grace", Type: "AUTHORED_BY"},
+	} {
+		l.Created, l.Modified = now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	added, missing, err := AddMembers(ctx, repo, "project:launch", []string{"doc:plan", "doc:old", "project:press", "doc:ghost"})
+	if err != nil || len(added) != 3 || len(missing) != 1 {
+		t.Fatalf("AddMembers = %v, %v, %v", added, missing, err)
+	}
+	if added, _, _ := AddMembers(ctx, repo, "project:press", []string{"doc:release", "project:launch"}); len(added) != 2 {
+		t.Fatalf("added %v to press", added)
+	}
+	if _, _, err := AddMembers(ctx, repo, "doc:plan", []string{"doc:old"}); !errors.Is(err, ErrNotProject) {
+		t.Errorf("adding to a document = %v, want ErrNotProject", err)
+	}
+
+	o, err := GetOverview(ctx, repo, "project:launch", OverviewOptions{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sub-project members count, and the cycle back to launch is ignored
+	if o.Members["Document"] != 3 || o.Members[ProjectType] != 1 || o.Description != "Q4 launch" {
+		t.Errorf("unexpected members %v", o.Members)
+	}
+	if o.TaskCounts.Open != 1 || o.TaskCounts.Done != 1 || len(o.OpenTasks) != 1 || o.OpenTasks[0].ID != "task:1" {
+		t.Errorf("unexpected tasks %+v %+v", o.TaskCounts, o.OpenTasks)
+	}
+	if len(o.TopDocuments) != 2 || o.TopDocuments[0].ID != "doc:plan" || o.TopDocuments[0].Links != 3 {
+		t.Errorf("unexpected top documents %+v", o.TopDocuments)
+	}
+	// Ada has the plan and a task; Grace's link to an unrelated document doesn't count
+	if len(o.KeyPeople) != 2 || o.KeyPeople[0].ID != "person:ada" || o.KeyPeople[1].Links != 1 {
+		t.Errorf("unexpected key people %+v", o.KeyPeople)
+	}
+	if len(o.Hotspots) != 2 || o.Hotspots[0].Score != 2.5 {
+		t.Errorf("unexpected hotspots %+v", o.Hotspots)
+	}
+	for _, a := range o.RecentActivity {
+		if a.ID == "doc:old" || a.ID == "doc:unrelated" {
+			t.Errorf("%s should not be recent project activity", a.ID)
+		}
+	}
+
+	list, err := List(ctx, repo)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+	if err := RemoveMember(ctx, repo, "project:launch", "doc:old"); err != nil {
+		t.Fatal(err)
+	}
+	if members, _ := Members(ctx, repo, "project:press"); len(members) != 3 {
+		t.Errorf("press has %d members after removal, want 3", len(members))
+	}
+}
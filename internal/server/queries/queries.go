@@ -0,0 +1,241 @@
+// Package queries stores parameterized queries as graph nodes and runs them
+// on demand or on a schedule.
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// NodeType is the node type saved queries are stored as
+const NodeType = "SavedQuery"
+
+// idPrefix namespaces saved query node IDs
+const idPrefix = "query:"
+
+// MinInterval is the shortest allowed schedule
+const MinInterval = time.Minute
+
+// Built-in parameters available to every query
+const (
+	ParamNow     = "now"      // Time of the run
+	ParamLastRun = "last_run" // Previous scheduled run, or when scheduling started
+)
+
+// ErrNotFound is returned for unknown saved queries
+var ErrNotFound = errors.New("saved query not found")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// SavedQuery is a stored query document with named parameters
+type SavedQuery struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Query       map[string]interface{} `json:"query"`            // Query DSL document, see Spec
+	Params      map[string]interface{} `json:"params,omitempty"` // Parameter name -> default; null marks a required parameter
+	Schedule    *Schedule              `json:"schedule,omitempty"`
+	Created     time.Time              `json:"created"`
+	Modified    time.Time              `json:"modified"`
+}
+
+// Schedule runs a saved query periodically and posts non-empty results
+type Schedule struct {
+	Every   string                 `json:"every"`            // Go duration, at least a minute
+	Params  map[string]interface{} `json:"params,omitempty"` // Parameter values for scheduled runs
+	Webhook string                 `json:"webhook,omitempty"`
+	Event   bool                   `json:"event,omitempty"`  // Emit a query.results event to subscriptions and the live stream
+	Always  bool                   `json:"always,omitempty"` // Also notify when there are no results
+}
+
+// interval parses and checks the schedule's period
+func (s *Schedule) interval() (time.Duration, error) {
+	d, err := time.ParseDuration(s.Every)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule interval %q", s.Every)
+	}
+	if d < MinInterval {
+		return 0, fmt.Errorf("schedule interval must be at least %s", MinInterval)
+	}
+	return d, nil
+}
+
+// Validate checks a saved query: its ID, that its document parses with
+// default parameter values and that its schedule is usable
+func (q *SavedQuery) Validate() error {
+	if !validID.MatchString(q.ID) {
+		return fmt.Errorf("invalid id %q (use 1-64 letters, digits, _ or -)", q.ID)
+	}
+	if len(q.Query) == 0 {
+		return fmt.Errorf("query document is required")
+	}
+	for name := range q.Params {
+		if name == ParamNow || name == ParamLastRun {
+			return fmt.Errorf("parameter %q is built in", name)
+		}
+	}
+
+	args := map[string]interface{}{ParamNow: time.Now().Format(time.RFC3339), ParamLastRun: time.Now().Format(time.RFC3339)}
+	required := false
+	for name, def := range q.Params {
+		args[name] = def
+		required = required || def == nil
+	}
+	for _, ref := range references(q.Query) {
+		if _, ok := args[ref]; !ok {
+			return fmt.Errorf("query references undeclared parameter %q", ref)
+		}
+	}
+	// Without values for required parameters the document is checked when run
+	if kind, _ := q.Query["kind"].(string); kind == "" {
+		return fmt.Errorf("query document needs a kind")
+	}
+	if !required {
+		spec, err := parseSpec(q.Query, args)
+		if err != nil {
+			return err
+		}
+		if err := spec.validate(); err != nil {
+			return err
+		}
+	}
+
+	if q.Schedule != nil {
+		if _, err := q.Schedule.interval(); err != nil {
+			return err
+		}
+		for name, def := range q.Params {
+			if def == nil && q.Schedule.Params[name] == nil {
+				return fmt.Errorf("schedule needs a value for required parameter %q", name)
+			}
+		}
+		if q.Schedule.Webhook == "" && !q.Schedule.Event {
+			return fmt.Errorf("schedule needs a webhook or event delivery")
+		}
+		if q.Schedule.Webhook != "" && !strings.HasPrefix(q.Schedule.Webhook, "http://") && !strings.HasPrefix(q.Schedule.Webhook, "https://") {
+			return fmt.Errorf("schedule webhook must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// Save validates and stores a new saved query, generating an ID if needed
+func Save(ctx context.Context, repo graph.Repository, q *SavedQuery) error {
+	if q.ID == "" {
+		q.ID = uuid.New().String()
+	}
+	now := time.Now()
+	q.Created, q.Modified = now, now
+	return Restore(ctx, repo, q)
+}
+
+// Restore validates and stores a saved query from a backup, keeping its ID and timestamps
+func Restore(ctx context.Context, repo graph.Repository, q *SavedQuery) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	meta, err := toMeta(q)
+	if err != nil {
+		return err
+	}
+	return repo.CreateNode(ctx, &core.Node{ID: idPrefix + q.ID, Type: NodeType, Meta: meta, Created: q.Created, Modified: q.Modified})
+}
+
+// Update validates and replaces a saved query's definition
+func Update(ctx context.Context, repo graph.Repository, q *SavedQuery) error {
+	existing, err := Get(ctx, repo, q.ID)
+	if err != nil {
+		return err
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	q.Created, q.Modified = existing.Created, time.Now()
+	meta, err := toMeta(q)
+	if err != nil {
+		return err
+	}
+	// Absent optional fields are cleared rather than left from the old version
+	for _, key := range []string{"name", "description", "params", "schedule"} {
+		if _, ok := meta[key]; !ok {
+			meta[key] = nil
+		}
+	}
+	return repo.UpdateNodeMeta(ctx, idPrefix+q.ID, meta)
+}
+
+// Get loads a saved query
+func Get(ctx context.Context, repo graph.Repository, id string) (*SavedQuery, error) {
+	node, err := repo.GetNode(ctx, idPrefix+id)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return fromNode(node)
+}
+
+// List returns all saved queries
+func List(ctx context.Context, repo graph.Repository) ([]*SavedQuery, error) {
+	const pageSize = 500
+	out := []*SavedQuery{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			q, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			out = append(out, q)
+		}
+		if len(nodes) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+// Delete removes a saved query and its history
+func Delete(ctx context.Context, repo graph.Repository, id string) error {
+	if _, err := Get(ctx, repo, id); err != nil {
+		return err
+	}
+	return repo.DeleteNode(ctx, idPrefix+id, true)
+}
+
+// toMeta stores a saved query's fields as node properties
+func toMeta(q *SavedQuery) (map[string]interface{}, error) {
+	data, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	delete(meta, "id")
+	return meta, nil
+}
+
+// fromNode reads a saved query back from its node
+func fromNode(node *core.Node) (*SavedQuery, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var q SavedQuery
+	if err := json.Unmarshal(data, &q); err != nil {
+		return nil, fmt.Errorf("corrupt saved query %s: %w", node.ID, err)
+	}
+	q.ID = strings.TrimPrefix(node.ID, idPrefix)
+	return &q, nil
+}
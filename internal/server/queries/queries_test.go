@@ -0,0 +1,130 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestSaveAndRun(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "note:a", Type: "Note", Meta: map[string]any{"status": "draft"}, Created: now, Modified: now},
+		{ID: "note:b", Type: "Note", Meta: map[string]any{"status": "done"}, Created: now, Modified: now},
+		{ID: "task:c", Type: "Task", Meta: map[string]any{"status": "draft"}, Created: now, Modified: now},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q := &SavedQuery{
+		ID:     "by-status",
+		Query:  map[string]interface{}{"kind": "filter", "types": "$types", "key": "status", "value": "${status}"},
+		Params: map[string]interface{}{"types": []interface{}{"Note"}, "status": nil},
+	}
+	if err := Save(ctx, repo, q); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	q, err := Get(ctx, repo, "by-status")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if _, err := Run(ctx, repo, q, nil, now); err == nil {
+		t.Error("expected a missing required parameter to be rejected")
+	}
+	result, err := Run(ctx, repo, q, map[string]interface{}{"status": "draft"}, now)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if nodes := result.Results.([]*core.Node); result.Count != 1 || nodes[0].ID != "note:a" {
+		t.Errorf("Run = %+v, want only note:a", result)
+	}
+	result, err = Run(ctx, repo, q, map[string]interface{}{"status": "draft", "types": []interface{}{"Note", "Task"}}, now)
+	if err != nil || result.Count != 2 {
+		t.Errorf("Run with types override = %+v, %v; want 2 nodes", result, err)
+	}
+
+	for _, bad := range []*SavedQuery{
+		{ID: "x", Query: map[string]interface{}{"kind": "nope"}},
+		{ID: "x", Query: map[string]interface{}{"kind": "filter", "value": "$missing"}},
+		{ID: "x", Query: map[string]interface{}{"kind": "filter", "typo": 1}},
+		{ID: "bad id", Query: map[string]interface{}{"kind": "filter"}},
+		{ID: "x", Query: map[string]interface{}{"kind": "orphans"}, Schedule: &Schedule{Every: "1s", Event: true}},
+		{ID: "x", Query: map[string]interface{}{"kind": "orphans"}, Schedule: &Schedule{Every: "1h"}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestSchedulerDeliversOrphans(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	start := time.Now()
+
+	var posted Result
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Memex-Event") != subscriptions.EventQueryResults {
+			t.Errorf("X-Memex-Event = %q", r.Header.Get("X-Memex-Event"))
+		}
+		json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer hook.Close()
+
+	var events []subscriptions.Event
+	s := NewScheduler(repo, func(e subscriptions.Event) { events = append(events, e) })
+	if err := Save(ctx, repo, &SavedQuery{
+		ID:       "new-orphans",
+		Query:    map[string]interface{}{"kind": "orphans", "from": "$last_run"},
+		Schedule: &Schedule{Every: "1h", Webhook: hook.URL, Event: true},
+	}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Nothing new yet: no delivery
+	s.runDue(ctx, start.Add(2*time.Hour))
+	if len(events) != 0 {
+		t.Fatalf("got %d events for empty results, want 0", len(events))
+	}
+
+	later := start.Add(3 * time.Hour)
+	for _, n := range []*core.Node{
+		{ID: "note:linked", Type: "Note", Created: later, Modified: later},
+		{ID: "note:alone", Type: "Note", Created: later, Modified: later},
+		{ID: "topic:x", Type: "Topic", Created: later, Modified: later},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "note:linked", Target: "topic:x", Type: "ABOUT", Created: later, Modified: later}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.runDue(ctx, start.Add(150*time.Minute)) // Not due again yet
+	if len(events) != 0 {
+		t.Fatalf("query ran before its interval elapsed")
+	}
+	s.runDue(ctx, start.Add(4*time.Hour))
+	if len(events) != 1 || events[0].Meta["count"] != 1 {
+		t.Fatalf("events = %+v, want one with count 1", events)
+	}
+	if ids, _ := events[0].Meta["results"].([]string); len(ids) != 1 || ids[0] != "note:alone" {
+		t.Errorf("event results = %v, want [note:alone]", events[0].Meta["results"])
+	}
+	if posted.QueryID != "new-orphans" || posted.Count != 1 {
+		t.Errorf("webhook got %+v, want new-orphans with 1 result", posted)
+	}
+}
@@ -0,0 +1,329 @@
+package queries
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Query kinds
+const (
+	KindFilter    = "filter"    // Nodes by type and one property value
+	KindSearch    = "search"    // Full-text search
+	KindTimeRange = "timerange" // Nodes created or modified in a window
+	KindPattern   = "pattern"   // Structural pattern bindings
+	KindAggregate = "aggregate" // Grouped counts and statistics
+	KindOrphans   = "orphans"   // Nodes with no links in either direction
+)
+
+// DefaultLimit and MaxLimit bound the results of one run
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Spec is the query DSL document. Kind selects the query; the other fields
+// are its arguments. Any string may reference a parameter: "$name" as the
+// whole value is replaced by the parameter's value, keeping its type, and
+// "${name}" is replaced inside text.
+type Spec struct {
+	Kind    string                            `json:"kind"`
+	Types   []string                          `json:"types,omitempty"`    // filter, timerange, aggregate, orphans
+	Key     string                            `json:"key,omitempty"`      // filter
+	Value   string                            `json:"value,omitempty"`    // filter
+	Q       string                            `json:"q,omitempty"`        // search
+	Pattern string                            `json:"pattern,omitempty"`  // pattern
+	Where   map[string]map[string]interface{} `json:"where,omitempty"`    // pattern
+	GroupBy []string                          `json:"group_by,omitempty"` // aggregate
+	Field   string                            `json:"field,omitempty"`    // aggregate
+	Bucket  string                            `json:"bucket,omitempty"`   // aggregate
+	From    string                            `json:"from,omitempty"`     // timerange, aggregate, orphans: RFC3339, YYYY-MM-DD or a duration before now such as -24h
+	To      string                            `json:"to,omitempty"`
+	Limit   int                               `json:"limit,omitempty"`
+}
+
+// Result is the outcome of one run
+type Result struct {
+	QueryID string                 `json:"query_id"`
+	Kind    string                 `json:"kind"`
+	RanAt   time.Time              `json:"ran_at"`
+	Params  map[string]interface{} `json:"params"`
+	Count   int                    `json:"count"`
+	Results interface{}            `json:"results"`
+}
+
+var paramRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|^\$([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// Run executes a saved query. args override parameter defaults; the
+// built-in now and last_run parameters are filled in when absent.
+func Run(ctx context.Context, repo graph.Repository, q *SavedQuery, args map[string]interface{}, lastRun time.Time) (*Result, error) {
+	now := time.Now()
+	params := make(map[string]interface{}, len(q.Params)+2)
+	params[ParamNow] = now.Format(time.RFC3339)
+	params[ParamLastRun] = lastRun.Format(time.RFC3339)
+	for name, def := range q.Params {
+		params[name] = def
+	}
+	for name, v := range args {
+		if _, declared := params[name]; !declared {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+		params[name] = v
+	}
+	for name, v := range params {
+		if v == nil {
+			return nil, fmt.Errorf("missing required parameter %q", name)
+		}
+	}
+
+	spec, err := parseSpec(q.Query, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	results, count, err := spec.run(ctx, repo, now)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{QueryID: q.ID, Kind: spec.Kind, RanAt: now, Params: params, Count: count, Results: results}, nil
+}
+
+// parseSpec substitutes parameters into a query document and decodes it
+func parseSpec(doc map[string]interface{}, params map[string]interface{}) (*Spec, error) {
+	substituted, err := substitute(doc, params)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(substituted)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var spec Spec
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid query document: %w", err)
+	}
+	return &spec, nil
+}
+
+// substitute returns a copy of v with parameter references replaced
+func substitute(v interface{}, params map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if m := paramRef.FindStringSubmatch(v); m != nil && m[2] != "" {
+			value, ok := params[m[2]]
+			if !ok {
+				return nil, fmt.Errorf("unknown parameter %q", m[2])
+			}
+			return value, nil
+		}
+		var missing string
+		out := paramRef.ReplaceAllStringFunc(v, func(ref string) string {
+			name := strings.TrimSuffix(strings.TrimPrefix(ref, "${"), "}")
+			value, ok := params[name]
+			if !ok {
+				missing = name
+				return ref
+			}
+			if s, ok := value.(string); ok {
+				return s
+			}
+			data, _ := json.Marshal(value)
+			return string(data)
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("unknown parameter %q", missing)
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			s, err := substitute(e, params)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = s
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			s, err := substitute(e, params)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = s
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// references lists the parameter names a document uses
+func references(v interface{}) []string {
+	var refs []string
+	switch v := v.(type) {
+	case string:
+		for _, m := range paramRef.FindAllStringSubmatch(v, -1) {
+			refs = append(refs, m[1]+m[2])
+		}
+	case []interface{}:
+		for _, e := range v {
+			refs = append(refs, references(e)...)
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			refs = append(refs, references(e)...)
+		}
+	}
+	return refs
+}
+
+// validate checks the arguments a spec's kind needs
+func (s *Spec) validate() error {
+	if s.Limit == 0 {
+		s.Limit = DefaultLimit
+	}
+	if s.Limit < 1 || s.Limit > MaxLimit {
+		return fmt.Errorf("invalid limit %d (1-%d)", s.Limit, MaxLimit)
+	}
+	switch s.Kind {
+	case KindFilter, KindOrphans:
+	case KindSearch:
+		if s.Q == "" {
+			return fmt.Errorf("search query needs q")
+		}
+	case KindTimeRange:
+		if s.From == "" {
+			return fmt.Errorf("timerange query needs from")
+		}
+	case KindPattern:
+		if _, err := graph.ParsePattern(s.Pattern, s.Where); err != nil {
+			return err
+		}
+	case KindAggregate:
+		q := graph.AggregateQuery{GroupBy: s.GroupBy, Bucket: s.Bucket}
+		if err := q.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown query kind %q (use filter, search, timerange, pattern, aggregate or orphans)", s.Kind)
+	}
+	for _, t := range []string{s.From, s.To} {
+		if _, err := parseTime(t, time.Now()); t != "" && err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run executes a validated spec, returning its results and their count
+func (s *Spec) run(ctx context.Context, repo graph.Repository, now time.Time) (interface{}, int, error) {
+	from, _ := parseTime(s.From, now)
+	to, _ := parseTime(s.To, now)
+
+	switch s.Kind {
+	case KindFilter:
+		nodes, err := repo.FilterNodes(ctx, s.Types, s.Key, s.Value, s.Limit, 0)
+		return nodeList(nodes), len(nodes), err
+	case KindSearch:
+		nodes, err := repo.SearchNodes(ctx, s.Q, s.Limit, 0)
+		return nodeList(nodes), len(nodes), err
+	case KindTimeRange:
+		if to.IsZero() {
+			to = now
+		}
+		nodes, err := repo.QueryTimeRange(ctx, from, to, s.Types, s.Limit, 0)
+		return nodeList(nodes), len(nodes), err
+	case KindPattern:
+		pattern, err := graph.ParsePattern(s.Pattern, s.Where)
+		if err != nil {
+			return nil, 0, err
+		}
+		bindings, err := repo.MatchPattern(ctx, pattern, s.Limit)
+		return bindings, len(bindings), err
+	case KindAggregate:
+		agg, err := graph.RunAggregate(ctx, repo, graph.AggregateQuery{
+			Types: s.Types, GroupBy: s.GroupBy, Field: s.Field, Bucket: s.Bucket, From: from, To: to,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		return agg, len(agg.Groups), nil
+	case KindOrphans:
+		nodes, err := orphans(ctx, repo, s.Types, from, to, s.Limit)
+		return nodeList(nodes), len(nodes), err
+	}
+	return nil, 0, fmt.Errorf("unknown query kind %q", s.Kind)
+}
+
+// orphans finds current nodes with no links in either direction, optionally
+// limited to a creation window. Saved queries themselves are skipped.
+func orphans(ctx context.Context, repo graph.Repository, types []string, from, to time.Time, limit int) ([]*core.Node, error) {
+	const pageSize = 1000
+	var orphaned []*core.Node
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, types, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			if n.Type == NodeType || !from.IsZero() && n.Created.Before(from) || !to.IsZero() && n.Created.After(to) {
+				continue
+			}
+			out, err := repo.GetLinks(ctx, n.ID)
+			if err != nil || len(out) > 0 {
+				continue
+			}
+			in, err := repo.GetBacklinks(ctx, n.ID)
+			if err != nil || len(in) > 0 {
+				continue
+			}
+			orphaned = append(orphaned, n)
+		}
+		if len(orphaned) >= limit {
+			return orphaned[:limit], nil
+		}
+		if len(nodes) < pageSize {
+			return orphaned, nil
+		}
+	}
+}
+
+// nodeList returns nodes sorted by ID, never nil
+func nodeList(nodes []*core.Node) []*core.Node {
+	if nodes == nil {
+		return []*core.Node{}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// parseTime reads RFC3339, YYYY-MM-DD or a signed duration relative to now
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use RFC3339, YYYY-MM-DD or a duration such as -24h)", value)
+}
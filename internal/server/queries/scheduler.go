@@ -0,0 +1,187 @@
+package queries
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// checkInterval is how often the scheduler looks for due queries
+const checkInterval = 30 * time.Second
+
+// Scheduler runs saved queries on their schedules. Run times are kept in
+// memory, so after a restart last_run starts again from the restart.
+type Scheduler struct {
+	repo       graph.Repository
+	emit       func(subscriptions.Event) // Optional; nil drops event delivery
+	httpClient *http.Client
+	started    time.Time
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler; emit receives query.results events
+func NewScheduler(repo graph.Repository, emit func(subscriptions.Event)) *Scheduler {
+	return &Scheduler{
+		repo:       repo,
+		emit:       emit,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		started:    time.Now(),
+		lastRun:    make(map[string]time.Time),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start begins checking for due queries
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.runDue(context.Background(), time.Now())
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Saved query scheduler started")
+}
+
+// Stop halts scheduling and waits for running queries to finish
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// LastRun returns when a query last ran on schedule, or when scheduling started
+func (s *Scheduler) LastRun(id string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.lastRun[id]; ok {
+		return t
+	}
+	return s.started
+}
+
+// runDue runs every scheduled query whose interval has elapsed
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	saved, err := List(ctx, s.repo)
+	if err != nil {
+		log.Printf("Saved query scheduler: listing queries failed: %v", err)
+		return
+	}
+	for _, q := range saved {
+		if q.Schedule == nil {
+			continue
+		}
+		every, err := q.Schedule.interval()
+		if err != nil {
+			continue
+		}
+		last := s.LastRun(q.ID)
+		if now.Sub(last) < every {
+			continue
+		}
+		s.mu.Lock()
+		s.lastRun[q.ID] = now
+		s.mu.Unlock()
+
+		result, err := Run(ctx, s.repo, q, q.Schedule.Params, last)
+		if err != nil {
+			log.Printf("Saved query %s failed: %v", q.ID, err)
+			continue
+		}
+		if result.Count == 0 && !q.Schedule.Always {
+			continue
+		}
+		s.deliver(q, result)
+	}
+}
+
+// deliver posts a result to the query's webhook and event stream
+func (s *Scheduler) deliver(q *SavedQuery, result *Result) {
+	if q.Schedule.Event && s.emit != nil {
+		s.emit(subscriptions.Event{
+			ID:        uuid.New().String(),
+			Type:      subscriptions.EventQueryResults,
+			Timestamp: result.RanAt,
+			Meta: map[string]interface{}{
+				"query_id":   q.ID,
+				"query_name": q.Name,
+				"kind":       result.Kind,
+				"count":      result.Count,
+				"results":    summarize(result.Results),
+			},
+		})
+	}
+	if q.Schedule.Webhook != "" {
+		if err := s.post(q.Schedule.Webhook, result); err != nil {
+			log.Printf("Saved query %s webhook failed: %v", q.ID, err)
+		}
+	}
+}
+
+// summarize keeps events small: node results become their IDs
+func summarize(results interface{}) interface{} {
+	nodes, ok := results.([]*core.Node)
+	if !ok {
+		return results
+	}
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+// post sends a result, retrying with backoff like subscription webhooks
+func (s *Scheduler) post(url string, result *Result) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt*attempt) * time.Second)
+		}
+
+		req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Memex-Event", subscriptions.EventQueryResults)
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return lastErr
+}
@@ -0,0 +1,222 @@
+// Package querycache keeps the results of expensive read endpoints
+// (subgraphs, the graph map, aggregates) so an agent repeating the same
+// exploration gets them without recomputing. Results are keyed by the
+// endpoint, its parsed parameters in a normalized form and the graph
+// revision, and the cache is emptied whenever nodes or links change.
+package querycache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Config bounds the cache
+type Config struct {
+	Size int           // Maximum entries (default 500)
+	TTL  time.Duration // Maximum age of an entry; 0 keeps entries until evicted or busted
+}
+
+// Stats reports the cache's effectiveness since the server started
+type Stats struct {
+	Entries   int                       `json:"entries"`
+	Hits      int64                     `json:"hits"`
+	Misses    int64                     `json:"misses"`
+	HitRate   float64                   `json:"hit_rate"`
+	Evictions int64                     `json:"evictions"` // Dropped for space or age
+	Busts     int64                     `json:"busts"`     // Times the cache was emptied
+	Endpoints map[string]*EndpointStats `json:"endpoints"`
+}
+
+// EndpointStats counts one endpoint's lookups
+type EndpointStats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+type entry struct {
+	key      string
+	endpoint string
+	value    interface{}
+	expires  time.Time
+}
+
+// Cache is an LRU cache of endpoint results. Cached values are shared
+// between callers and must not be modified.
+type Cache struct {
+	cfg Config
+
+	mu        sync.Mutex
+	order     *list.List // Front is most recently used
+	entries   map[string]*list.Element
+	endpoints map[string]*EndpointStats
+	evictions int64
+	busts     int64
+}
+
+// New creates an empty cache
+func New(cfg Config) *Cache {
+	if cfg.Size <= 0 {
+		cfg.Size = 500
+	}
+	return &Cache{
+		cfg:       cfg,
+		order:     list.New(),
+		entries:   make(map[string]*list.Element),
+		endpoints: make(map[string]*EndpointStats),
+	}
+}
+
+// Key derives the cache key for an endpoint's parameters at a graph
+// revision. Parameters are JSON-encoded, so callers normalize them first:
+// sort lists whose order does not matter and fill in defaults.
+func Key(endpoint, revision string, params interface{}) (string, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("encoding cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return endpoint + "|" + revision + "|" + hex.EncodeToString(sum[:16]), nil
+}
+
+// Get returns the result stored under key, counting a hit or a miss for
+// the endpoint
+func (c *Cache) Get(endpoint, key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.endpoint(endpoint)
+	elem, ok := c.entries[key]
+	if ok {
+		e := elem.Value.(*entry)
+		if c.cfg.TTL > 0 && time.Now().After(e.expires) {
+			c.remove(elem)
+			c.evictions++
+			ok = false
+		}
+	}
+	if !ok {
+		stats.Misses++
+		return nil, false
+	}
+	stats.Hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*entry).value, true
+}
+
+// Put stores an endpoint's result under key, evicting the least recently
+// used entry when full
+func (c *Cache) Put(endpoint, key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, endpoint: endpoint, value: value, expires: time.Now().Add(c.cfg.TTL)})
+	c.endpoint(endpoint).Entries++
+	for c.order.Len() > c.cfg.Size {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+// Bust empties the cache, returning how many entries were dropped
+func (c *Cache) Bust() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.order.Len()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	for _, stats := range c.endpoints {
+		stats.Entries = 0
+	}
+	c.busts++
+	return n
+}
+
+// EmitEvent busts the cache when nodes or links change. Writes that emit
+// no event still change the graph revision, so stale keys are not reached.
+func (c *Cache) EmitEvent(event subscriptions.Event) {
+	if strings.HasPrefix(event.Type, "node.") || strings.HasPrefix(event.Type, "link.") {
+		c.Bust()
+	}
+}
+
+// Stats returns the cache's counters
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := Stats{
+		Entries:   c.order.Len(),
+		Evictions: c.evictions,
+		Busts:     c.busts,
+		Endpoints: make(map[string]*EndpointStats, len(c.endpoints)),
+	}
+	for name, e := range c.endpoints {
+		copied := *e
+		s.Endpoints[name] = &copied
+		s.Hits += e.Hits
+		s.Misses += e.Misses
+	}
+	if s.Hits+s.Misses > 0 {
+		s.HitRate = float64(s.Hits) / float64(s.Hits+s.Misses)
+	}
+	return s
+}
+
+// WriteMetrics writes the counters in the Prometheus text format
+func (c *Cache) WriteMetrics(w io.Writer) error {
+	s := c.Stats()
+	names := make([]string, 0, len(s.Endpoints))
+	for name := range s.Endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	metric := func(name, kind, help string, value func(e *EndpointStats) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, endpoint := range names {
+			fmt.Fprintf(&b, "%s{endpoint=%s} %g\n", name, strconv.Quote(endpoint), value(s.Endpoints[endpoint]))
+		}
+	}
+	metric("memex_query_cache_hits_total", "counter", "Query results served from the cache, by endpoint.", func(e *EndpointStats) float64 { return float64(e.Hits) })
+	metric("memex_query_cache_misses_total", "counter", "Query results computed because they were not cached, by endpoint.", func(e *EndpointStats) float64 { return float64(e.Misses) })
+	metric("memex_query_cache_entries", "gauge", "Query results in the cache, by endpoint.", func(e *EndpointStats) float64 { return float64(e.Entries) })
+	fmt.Fprintf(&b, "# HELP memex_query_cache_evictions_total Cached query results dropped for space or age.\n# TYPE memex_query_cache_evictions_total counter\nmemex_query_cache_evictions_total %d\n", s.Evictions)
+	fmt.Fprintf(&b, "# HELP memex_query_cache_busts_total Times the query cache was emptied by a change or by hand.\n# TYPE memex_query_cache_busts_total counter\nmemex_query_cache_busts_total %d\n", s.Busts)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// endpoint returns an endpoint's counters, creating them; c.mu is held
+func (c *Cache) endpoint(name string) *EndpointStats {
+	stats, ok := c.endpoints[name]
+	if !ok {
+		stats = &EndpointStats{}
+		c.endpoints[name] = stats
+	}
+	return stats
+}
+
+// remove drops an entry; c.mu is held
+func (c *Cache) remove(elem *list.Element) {
+	e := elem.Value.(*entry)
+	c.order.Remove(elem)
+	delete(c.entries, e.key)
+	c.endpoint(e.endpoint).Entries--
+}
@@ -0,0 +1,81 @@
+package querycache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestCache(t *testing.T) {
+	c := New(Config{Size: 2})
+	key := func(endpoint, revision string, params interface{}) string {
+		t.Helper()
+		k, err := Key(endpoint, revision, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	a := key("aggregate", "r1", map[string]interface{}{"types": []string{"Note", "Person"}})
+	if a != key("aggregate", "r1", map[string]interface{}{"types": []string{"Note", "Person"}}) {
+		t.Error("equal params gave different keys")
+	}
+	if a == key("aggregate", "r2", map[string]interface{}{"types": []string{"Note", "Person"}}) {
+		t.Error("a new revision kept the key")
+	}
+
+	if _, ok := c.Get("aggregate", a); ok {
+		t.Fatal("hit on an empty cache")
+	}
+	c.Put("aggregate", a, 1)
+	if v, ok := c.Get("aggregate", a); !ok || v != 1 {
+		t.Fatalf("Get = %v, %v", v, ok)
+	}
+
+	// The least recently used entry goes when the cache is full
+	b, m := key("subgraph", "r1", "b"), key("graph_map", "r1", 100)
+	c.Put("subgraph", b, 2)
+	c.Get("aggregate", a)
+	c.Put("graph_map", m, 3)
+	if _, ok := c.Get("subgraph", b); ok {
+		t.Error("least recently used entry kept")
+	}
+
+	stats := c.Stats()
+	if stats.Entries != 2 || stats.Hits != 2 || stats.Misses != 2 || stats.Evictions != 1 || stats.Endpoints["aggregate"].Hits != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	c.EmitEvent(subscriptions.Event{Type: subscriptions.EventQueryResults})
+	if c.Stats().Entries != 2 {
+		t.Error("non-graph event busted the cache")
+	}
+	c.EmitEvent(subscriptions.Event{Type: subscriptions.EventLinkCreated})
+	if stats := c.Stats(); stats.Entries != 0 || stats.Busts != 1 || stats.Endpoints["aggregate"].Entries != 0 {
+		t.Errorf("after a link change: %+v", stats)
+	}
+
+	var out strings.Builder
+	if err := c.WriteMetrics(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `memex_query_cache_hits_total{endpoint="aggregate"} 2`) ||
+		!strings.Contains(out.String(), "memex_query_cache_busts_total 1") {
+		t.Errorf("metrics:\n%s", out.String())
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	c := New(Config{TTL: time.Millisecond})
+	c.Put("graph_map", "k", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("graph_map", "k"); ok {
+		t.Error("expired entry returned")
+	}
+	if stats := c.Stats(); stats.Evictions != 1 || stats.Entries != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
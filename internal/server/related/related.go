@@ -0,0 +1,410 @@
+// Package related recommends nodes related to a given node by combining
+// structural, content and co-attention signals.
+package related
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/annotations"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/memory"
+	"github.com/systemshift/memex/internal/server/people"
+)
+
+// Signal names
+const (
+	SignalStructural = "structural"
+	SignalContent    = "content"
+	SignalAttention  = "attention"
+)
+
+const (
+	attentionLink = "ATTENDED"
+	maxNeighbors  = 200   // Neighbors expanded for shared-neighbor counts
+	maxCandidates = 100   // Candidates scored per signal source
+	maxTerms      = 8     // Key terms searched for content matches
+	termHits      = 50    // Search hits read per term
+	maxTextBytes  = 20000 // Text read per node for terms
+)
+
+// hiddenTypes are node types never recommended
+var hiddenTypes = map[string]bool{
+	people.AliasType: true, "Subscription": true, "SavedQuery": true, "LinkRule": true, graph.ErasureAuditType: true,
+	memory.NodeType: true, "SynonymSet": true, "TypeRule": true,
+}
+
+// stopWords are skipped when picking a node's key terms
+var stopWords = map[string]bool{
+	"about": true, "after": true, "also": true, "been": true, "before": true, "being": true, "between": true,
+	"both": true, "could": true, "does": true, "each": true, "from": true, "have": true, "having": true,
+	"here": true, "into": true, "just": true, "more": true, "most": true, "much": true, "must": true,
+	"only": true, "other": true, "over": true, "same": true, "should": true, "some": true, "such": true,
+	"than": true, "that": true, "their": true, "them": true, "then": true, "there": true, "these": true,
+	"they": true, "this": true, "those": true, "through": true, "under": true, "very": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "while": true, "will": true, "with": true,
+	"would": true, "your": true, "http": true, "https": true,
+}
+
+// Repository is the subset of graph operations recommendations need
+type Repository interface {
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error)
+}
+
+// Weights sets how much each signal counts towards the combined score
+type Weights struct {
+	Structural float64 `json:"structural"`
+	Content    float64 `json:"content"`
+	Attention  float64 `json:"attention"`
+}
+
+// DefaultWeights are used when no weights are given
+var DefaultWeights = Weights{Structural: 0.4, Content: 0.35, Attention: 0.25}
+
+// Options controls a recommendation
+type Options struct {
+	Limit   int     // Default 10
+	Weights Weights // Zero uses DefaultWeights; a zero weight turns its signal off
+}
+
+// Signals are a candidate's per-signal scores, each from 0 to 1
+type Signals struct {
+	Structural float64 `json:"structural"` // Shared neighbors, cosine-normalized by degree
+	Content    float64 `json:"content"`    // Embedding cosine, or the share of key terms in common
+	Attention  float64 `json:"attention"`  // Attention edge weight, discounted for few queries
+}
+
+// Related is a recommended node
+type Related struct {
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+	Label   string   `json:"label"`
+	Score   float64  `json:"score"`
+	Signals Signals  `json:"signals"`
+	Shared  int      `json:"shared_neighbors"`
+	Terms   []string `json:"shared_terms,omitempty"`
+	Linked  bool     `json:"linked"` // Already linked to the node
+}
+
+// Result is the recommendation for a node
+type Result struct {
+	NodeID  string    `json:"node_id"`
+	Weights Weights   `json:"weights"`
+	Terms   []string  `json:"terms"` // The node's key terms
+	Related []Related `json:"related"`
+}
+
+// candidate accumulates signals for one node
+type candidate struct {
+	shared    int
+	attention float64
+}
+
+// Find ranks the nodes most related to id
+func Find(ctx context.Context, repo Repository, id string, opts Options) (*Result, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	if opts.Weights == (Weights{}) {
+		opts.Weights = DefaultWeights
+	}
+	node, err := repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := nodeLinks(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make(map[string]*candidate)
+	get := func(cid string) *candidate {
+		c := candidates[cid]
+		if c == nil {
+			c = &candidate{}
+			candidates[cid] = c
+		}
+		return c
+	}
+
+	// Co-attention: attention edges to or from the node
+	neighbors := make(map[string]bool)
+	for _, l := range links {
+		other := l.Target
+		if other == id {
+			other = l.Source
+		}
+		if other == id {
+			continue
+		}
+		if l.Type == attentionLink {
+			if opts.Weights.Attention > 0 {
+				if w := attentionScore(l); w > get(other).attention {
+					get(other).attention = w
+				}
+			}
+			continue
+		}
+		neighbors[other] = true
+	}
+
+	// Structural: nodes sharing neighbors with this one
+	if opts.Weights.Structural > 0 {
+		expanded := 0
+		for _, z := range sortedKeys(neighbors) {
+			if expanded >= maxNeighbors {
+				break
+			}
+			expanded++
+			zNeighbors, err := neighborSet(ctx, repo, z)
+			if err != nil {
+				return nil, err
+			}
+			for y := range zNeighbors {
+				if y != id {
+					get(y).shared++
+				}
+			}
+		}
+		trimStructural(candidates, maxCandidates)
+	}
+
+	// Content: nodes matching the node's key terms
+	text := nodeText(node)
+	terms := keyTerms(text, maxTerms)
+	if opts.Weights.Content > 0 {
+		for _, term := range terms {
+			hits, err := repo.SearchNodes(ctx, term, termHits, 0)
+			if err != nil {
+				return nil, err
+			}
+			for _, h := range hits {
+				if h.ID != id {
+					get(h.ID)
+				}
+			}
+		}
+	}
+
+	degree := len(neighbors)
+	embedding := vector(node.Meta["embedding"])
+	result := &Result{NodeID: id, Weights: opts.Weights, Terms: terms, Related: []Related{}}
+	total := opts.Weights.Structural + opts.Weights.Content + opts.Weights.Attention
+	for cid, c := range candidates {
+		other, err := repo.GetNode(ctx, cid)
+		if err != nil || hiddenTypes[other.Type] {
+			continue
+		}
+		r := Related{ID: cid, Type: other.Type, Label: graph.NodeLabel(cid, other.Meta), Shared: c.shared, Linked: neighbors[cid]}
+		if c.shared > 0 && degree > 0 {
+			otherNeighbors, err := neighborSet(ctx, repo, cid)
+			if err != nil {
+				return nil, err
+			}
+			if len(otherNeighbors) > 0 {
+				r.Signals.Structural = math.Min(1, float64(c.shared)/math.Sqrt(float64(degree*len(otherNeighbors))))
+			}
+		}
+		if opts.Weights.Content > 0 {
+			r.Signals.Content, r.Terms = contentScore(terms, embedding, other)
+		}
+		r.Signals.Attention = c.attention
+		r.Score = (opts.Weights.Structural*r.Signals.Structural + opts.Weights.Content*r.Signals.Content + opts.Weights.Attention*r.Signals.Attention) / total
+		if r.Score <= 0 {
+			continue
+		}
+		r.Score = round(r.Score)
+		r.Signals = Signals{Structural: round(r.Signals.Structural), Content: round(r.Signals.Content), Attention: round(r.Signals.Attention)}
+		result.Related = append(result.Related, r)
+	}
+
+	sort.Slice(result.Related, func(i, j int) bool {
+		a, b := result.Related[i], result.Related[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.ID < b.ID
+	})
+	if len(result.Related) > opts.Limit {
+		result.Related = result.Related[:opts.Limit]
+	}
+	return result, nil
+}
+
+// nodeLinks returns a node's outgoing and incoming links
+func nodeLinks(ctx context.Context, repo Repository, id string) ([]*core.Link, error) {
+	outgoing, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	incoming, err := repo.GetBacklinks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return append(outgoing, incoming...), nil
+}
+
+// neighborSet returns the nodes linked to id, ignoring attention edges
+func neighborSet(ctx context.Context, repo Repository, id string) (map[string]bool, error) {
+	links, err := nodeLinks(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(links))
+	for _, l := range links {
+		if l.Type == attentionLink {
+			continue
+		}
+		if l.Source != id {
+			set[l.Source] = true
+		}
+		if l.Target != id {
+			set[l.Target] = true
+		}
+	}
+	return set, nil
+}
+
+// trimStructural drops all but the limit candidates with the most shared
+// neighbors, keeping every candidate with attention
+func trimStructural(candidates map[string]*candidate, limit int) {
+	var ids []string
+	for id, c := range candidates {
+		if c.shared > 0 && c.attention == 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) <= limit {
+		return
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := candidates[ids[i]], candidates[ids[j]]
+		if a.shared != b.shared {
+			return a.shared > b.shared
+		}
+		return ids[i] < ids[j]
+	})
+	for _, id := range ids[limit:] {
+		delete(candidates, id)
+	}
+}
+
+// attentionScore is an attention edge's weight, discounted until it has
+// been reinforced by a few queries
+func attentionScore(l *core.Link) float64 {
+	weight := l.Weight
+	count, _ := l.Meta["query_count"].(float64)
+	if n, ok := l.Meta["query_count"].(int); ok {
+		count = float64(n)
+	}
+	if count < 1 {
+		count = 1
+	}
+	return math.Max(0, math.Min(1, weight*count/(count+1)))
+}
+
+// nodeText is the text a node's terms come from: its label, descriptive
+// properties and content
+func nodeText(node *core.Node) string {
+	parts := []string{graph.NodeLabel(node.ID, node.Meta)}
+	for _, key := range []string{"description", "summary", "abstract", "caption"} {
+		if s, ok := node.Meta[key].(string); ok {
+			parts = append(parts, s)
+		}
+	}
+	parts = append(parts, annotations.NodeText(node))
+	text := strings.Join(parts, "\n")
+	if len(text) > maxTextBytes {
+		text = text[:maxTextBytes]
+	}
+	return strings.ToLower(text)
+}
+
+// keyTerms picks a text's most frequent words of four or more letters,
+// skipping stop words
+func keyTerms(text string, limit int) []string {
+	counts := make(map[string]int)
+	for _, w := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if len([]rune(w)) >= 4 && !stopWords[w] && strings.IndexFunc(w, unicode.IsLetter) >= 0 {
+			counts[w]++
+		}
+	}
+	terms := sortedKeys(counts)
+	sort.SliceStable(terms, func(i, j int) bool { return counts[terms[i]] > counts[terms[j]] })
+	if len(terms) > limit {
+		terms = terms[:limit]
+	}
+	return terms
+}
+
+// contentScore compares a candidate with the node: the cosine of their
+// embeddings when both have one, otherwise the share of the node's key
+// terms that appear in the candidate's text
+func contentScore(terms []string, embedding []float64, other *core.Node) (float64, []string) {
+	text := nodeText(other)
+	var shared []string
+	for _, t := range terms {
+		if strings.Contains(text, t) {
+			shared = append(shared, t)
+		}
+	}
+	if embedding != nil {
+		if v := vector(other.Meta["embedding"]); len(v) == len(embedding) {
+			return math.Max(0, cosine(embedding, v)), shared
+		}
+	}
+	if len(terms) == 0 {
+		return 0, nil
+	}
+	return float64(len(shared)) / float64(len(terms)), shared
+}
+
+// vector reads a numeric array property, or nil
+func vector(v interface{}) []float64 {
+	items, ok := v.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil
+	}
+	out := make([]float64, len(items))
+	for i, item := range items {
+		f, ok := item.(float64)
+		if !ok {
+			return nil
+		}
+		out[i] = f
+	}
+	return out
+}
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
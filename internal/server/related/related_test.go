@@ -0,0 +1,93 @@
+package related
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "paper:memex", Type: "Paper", Meta: map[string]interface{}{"title": "As We May Think", "abstract": "associative trails through a personal library of microfilm"}},
+		{ID: "paper:xanadu", Type: "Paper", Meta: map[string]interface{}{"title": "Literary Machines", "abstract": "hypertext and associative trails"}},
+		{ID: "paper:nls", Type: "Paper", Meta: map[string]interface{}{"title": "Augmenting Human Intellect"}},
+		{ID: "note:trails", Type: "Note", Content: []byte("Notes on associative trails and microfilm readers")},
+		{ID: "person:bush", Type: "Person", Meta: map[string]interface{}{"name": "Vannevar Bush"}},
+		{ID: "concept:hypertext", Type: "Concept", Meta: map[string]interface{}{"name": "Hypertext"}},
+		{ID: "alias:email:vb@example.com", Type: "Alias", Meta: map[string]interface{}{"value": "microfilm trails"}},
+	} {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "paper:memex", Target: "person:bush", Type: "AUTHORED_BY"},
+		{Source: "paper:memex", Target: "concept:hypertext", Type: "ABOUT"},
+		{Source: "paper:xanadu", Target: "concept:hypertext", Type: "ABOUT"},
+		{Source: "paper:nls", Target: "person:bush", Type: "CITES"},
+		{Source: "paper:nls", Target: "concept:hypertext", Type: "ABOUT"},
+	} {
+		l.Created, l.Modified = now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.UpdateAttentionEdge(ctx, "paper:memex", "note:trails", "q1", 0.9); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Find(ctx, repo, "paper:memex", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := make(map[string]Related)
+	for _, r := range result.Related {
+		scores[r.ID] = r
+	}
+	if _, ok := scores["alias:email:vb@example.com"]; ok {
+		t.Error("alias nodes should not be recommended")
+	}
+	nls, xanadu, note := scores["paper:nls"], scores["paper:xanadu"], scores["note:trails"]
+	if nls.Shared != 2 || nls.Signals.Structural != 1 {
+		t.Errorf("nls = %+v, want both neighbors shared", nls)
+	}
+	if xanadu.Shared != 1 || xanadu.Signals.Content == 0 || len(xanadu.Terms) == 0 {
+		t.Errorf("xanadu = %+v, want a shared neighbor and shared terms", xanadu)
+	}
+	if note.Signals.Attention == 0 || note.Signals.Structural != 0 || note.Signals.Content == 0 {
+		t.Errorf("note = %+v, want attention and content only", note)
+	}
+	if nls.Linked || xanadu.Linked {
+		t.Error("two-hop candidates should not be marked linked")
+	}
+
+	// Restricting to one signal drops candidates only the others found
+	structural, err := Find(ctx, repo, "paper:memex", Options{Weights: Weights{Structural: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range structural.Related {
+		if r.ID == "note:trails" {
+			t.Errorf("structural-only result includes %+v", r)
+		}
+	}
+	if len(structural.Related) == 0 || structural.Related[0].ID != "paper:nls" {
+		t.Errorf("structural-only ranking = %+v", structural.Related)
+	}
+}
+
+func TestContentScoreUsesEmbeddings(t *testing.T) {
+	a := &core.Node{ID: "a", Meta: map[string]interface{}{"embedding": []interface{}{1.0, 0.0}}}
+	b := &core.Node{ID: "b", Meta: map[string]interface{}{"embedding": []interface{}{1.0, 1.0}}}
+	score, _ := contentScore([]string{"unrelated"}, vector(a.Meta["embedding"]), b)
+	if score < 0.70 || score > 0.71 {
+		t.Errorf("cosine = %v, want ~0.707", score)
+	}
+}
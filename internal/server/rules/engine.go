@@ -0,0 +1,216 @@
+package rules
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Metrics counts a rule's activity since the server started
+type Metrics struct {
+	Evaluated int64      `json:"evaluated"` // Nodes the condition held for
+	Hits      int64      `json:"hits"`      // Nodes that got new links
+	Links     int64      `json:"links"`     // Links created
+	Errors    int64      `json:"errors"`
+	LastHit   *time.Time `json:"last_hit,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Engine evaluates rules as nodes are created and updated. Metrics are
+// kept in memory.
+type Engine struct {
+	repo graph.Repository
+	eval *evaluator // Used by the processing loop only
+
+	mu      sync.RWMutex
+	rules   []*Rule
+	metrics map[string]*Metrics
+
+	events chan queuedEvent
+	hold   func(nodeID string) (release func())
+	wg     sync.WaitGroup
+}
+
+// queuedEvent is a queued event with the release for its processing hold
+type queuedEvent struct {
+	event   subscriptions.Event
+	release func()
+}
+
+// NewEngine creates a rules engine
+func NewEngine(repo graph.Repository) *Engine {
+	return &Engine{
+		repo:    repo,
+		eval:    newEvaluator(repo),
+		metrics: make(map[string]*Metrics),
+		events:  make(chan queuedEvent, 1000),
+	}
+}
+
+// Start loads the rules and begins processing events
+func (e *Engine) Start() {
+	if err := e.Reload(context.Background()); err != nil {
+		log.Printf("Warning: failed to load link rules: %v", err)
+	}
+	e.wg.Add(1)
+	go e.processEvents()
+	log.Printf("Link rules engine started (%d rules)", len(e.Rules()))
+}
+
+// Stop waits for queued events to be processed
+func (e *Engine) Stop() {
+	close(e.events)
+	e.wg.Wait()
+}
+
+// SetHold registers a callback invoked for each queued node; the returned
+// release is called once the node has been processed. Must be called
+// before Start.
+func (e *Engine) SetHold(hold func(nodeID string) (release func())) {
+	e.hold = hold
+}
+
+// Reload reads the rules from the graph
+func (e *Engine) Reload(ctx context.Context) error {
+	list, err := List(ctx, e.repo)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = list
+	return nil
+}
+
+// Rules returns the loaded rules
+func (e *Engine) Rules() []*Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules
+}
+
+// Metrics returns a copy of a rule's metrics
+func (e *Engine) Metrics(id string) Metrics {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if m := e.metrics[id]; m != nil {
+		return *m
+	}
+	return Metrics{}
+}
+
+// EmitEvent queues node changes, and alias links that can change what
+// mentions rules match (non-blocking)
+func (e *Engine) EmitEvent(event subscriptions.Event) {
+	switch event.Type {
+	case subscriptions.EventNodeCreated, subscriptions.EventNodeUpdated, subscriptions.EventNodeDeleted:
+	case subscriptions.EventLinkCreated, subscriptions.EventLinkDeleted:
+		if event.LinkType != people.AliasOfLink {
+			return
+		}
+	default:
+		return
+	}
+	release := func() {}
+	if e.hold != nil && event.Type != subscriptions.EventNodeDeleted && event.NodeID != "" && !IsRuleNode(event.NodeID) {
+		release = e.hold(event.NodeID)
+	}
+	select {
+	case e.events <- queuedEvent{event: event, release: release}:
+	default:
+		release()
+		log.Printf("Warning: link rule queue full, skipping %s %s", event.Type, event.NodeID)
+	}
+}
+
+// processEvents is the main processing loop
+func (e *Engine) processEvents() {
+	defer e.wg.Done()
+
+	for queued := range e.events {
+		e.handle(queued.event)
+		queued.release()
+	}
+}
+
+func (e *Engine) handle(event subscriptions.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if event.LinkType != "" {
+		e.eval.invalidate(people.AliasType)
+		return
+	}
+	if IsRuleNode(event.NodeID) {
+		if err := e.Reload(ctx); err != nil {
+			log.Printf("Failed to reload link rules: %v", err)
+		}
+		return
+	}
+	e.eval.invalidate(event.NodeType)
+	if event.Type == subscriptions.EventNodeDeleted {
+		return
+	}
+
+	trigger := OnCreated
+	if event.Type == subscriptions.EventNodeUpdated {
+		trigger = OnUpdated
+	}
+	if _, err := e.ProcessNode(ctx, event.NodeID, trigger); err != nil {
+		log.Printf("Link rules failed for %s: %v", event.NodeID, err)
+	}
+}
+
+// ProcessNode applies every enabled rule to a node, returning the number
+// of links created
+func (e *Engine) ProcessNode(ctx context.Context, id, trigger string) (int, error) {
+	node, err := e.repo.GetNode(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	created := 0
+	for _, r := range e.Rules() {
+		applies, err := r.Applies(node, trigger)
+		if err == nil && applies {
+			var proposals []Proposal
+			var n int
+			proposals, err = e.eval.evaluate(ctx, r, node)
+			if err == nil {
+				n, err = apply(ctx, e.repo, r, proposals)
+			}
+			created += n
+			e.record(r.ID, n, err)
+		} else if err != nil {
+			e.record(r.ID, 0, err)
+		}
+	}
+	return created, nil
+}
+
+// record updates a rule's metrics after evaluating it for a node
+func (e *Engine) record(id string, links int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	m := e.metrics[id]
+	if m == nil {
+		m = &Metrics{}
+		e.metrics[id] = m
+	}
+	if err != nil {
+		m.Errors++
+		m.LastError = err.Error()
+		return
+	}
+	m.Evaluated++
+	if links > 0 {
+		now := time.Now()
+		m.Hits++
+		m.Links += int64(links)
+		m.LastHit = &now
+	}
+}
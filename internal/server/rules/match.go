@@ -0,0 +1,383 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+const (
+	defaultMinLength = 3
+	maxTextBytes     = 100000 // Text searched per node for mentions
+	maxValues        = 100    // Property values matched per node
+	pageSize         = 500
+)
+
+// Repository is the subset of graph operations evaluating rules needs
+type Repository interface {
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error)
+	CreateLinks(ctx context.Context, links []*core.Link) error
+}
+
+// Proposal is a link a rule would create
+type Proposal struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+	Match  string `json:"match"`  // The name, alias, value or ID that matched
+	Exists bool   `json:"exists"` // The link is already there
+}
+
+// Applies reports whether a rule's condition holds for a node on a
+// trigger (created or updated)
+func (r *Rule) Applies(node *core.Node, trigger string) (bool, error) {
+	if r.Disabled || node.Type == NodeType {
+		return false, nil
+	}
+	if len(r.When.NodeTypes) > 0 && !contains(r.When.NodeTypes, node.Type) {
+		return false, nil
+	}
+	if len(r.When.On) > 0 && !contains(r.When.On, trigger) {
+		return false, nil
+	}
+	cond, err := r.condition()
+	if err != nil || cond == nil {
+		return err == nil, err
+	}
+	return cond.Eval(subscriptions.Event{Type: "node." + trigger, NodeID: node.ID, NodeType: node.Type, Meta: node.Meta, Timestamp: node.Modified})
+}
+
+// DryRun reports whether a rule applies to a node and the links it would
+// create, without creating them
+func DryRun(ctx context.Context, repo Repository, r *Rule, node *core.Node, trigger string) (bool, []Proposal, error) {
+	applies, err := r.Applies(node, trigger)
+	if err != nil || !applies {
+		return false, []Proposal{}, err
+	}
+	proposals, err := newEvaluator(repo).evaluate(ctx, r, node)
+	return true, proposals, err
+}
+
+// name is a target's name or alias, lowercased
+type name struct {
+	text string
+	id   string
+}
+
+// evaluator finds rule targets, caching the names mentions rules look for
+type evaluator struct {
+	repo  Repository
+	names map[string][]name // Sorted target types -> names
+}
+
+func newEvaluator(repo Repository) *evaluator {
+	return &evaluator{repo: repo, names: make(map[string][]name)}
+}
+
+// invalidate drops cached names that a change to a node of this type
+// could affect; aliases can name any type, and an unknown type anything
+func (e *evaluator) invalidate(nodeType string) {
+	for key := range e.names {
+		if nodeType == "" || nodeType == people.AliasType || contains(strings.Split(key, ","), nodeType) {
+			delete(e.names, key)
+		}
+	}
+}
+
+// evaluate returns the links a rule makes for a node, marking those that
+// already exist
+func (e *evaluator) evaluate(ctx context.Context, r *Rule, node *core.Node) ([]Proposal, error) {
+	var matches []Proposal
+	var err error
+	switch r.Match.Kind {
+	case MatchMentions:
+		matches, err = e.mentions(ctx, r.Match, node)
+	case MatchProperty:
+		matches, err = e.property(ctx, r.Match, node)
+	case MatchIDs:
+		matches, err = e.ids(ctx, r.Match, node)
+	default:
+		err = fmt.Errorf("invalid match kind %q", r.Match.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := e.existing(ctx, node.ID, r.Link)
+	if err != nil {
+		return nil, err
+	}
+	proposals := []Proposal{}
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if m.Target == node.ID || seen[m.Target] {
+			continue
+		}
+		seen[m.Target] = true
+		p := Proposal{Source: node.ID, Target: m.Target, Type: r.Link.Type, Match: m.Match, Exists: existing[m.Target]}
+		if r.Link.Direction == DirectionIn {
+			p.Source, p.Target = m.Target, node.ID
+		}
+		proposals = append(proposals, p)
+	}
+	return proposals, nil
+}
+
+// existing returns the nodes already linked to id the way spec links
+func (e *evaluator) existing(ctx context.Context, id string, spec LinkSpec) (map[string]bool, error) {
+	var links []*core.Link
+	var err error
+	if spec.Direction == DirectionIn {
+		links, err = e.repo.GetBacklinks(ctx, id)
+	} else {
+		links, err = e.repo.GetLinks(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool)
+	for _, l := range links {
+		if l.Type != spec.Type {
+			continue
+		}
+		if spec.Direction == DirectionIn {
+			out[l.Source] = true
+		} else {
+			out[l.Target] = true
+		}
+	}
+	return out, nil
+}
+
+// mentions finds targets whose name or alias appears as a whole word in
+// the node's text, longest names first
+func (e *evaluator) mentions(ctx context.Context, m Matcher, node *core.Node) ([]Proposal, error) {
+	text := strings.ToLower(matchText(node, m.Fields))
+	if text == "" {
+		return nil, nil
+	}
+	names, err := e.targetNames(ctx, m.TargetTypes)
+	if err != nil {
+		return nil, err
+	}
+	minLength := m.MinLength
+	if minLength == 0 {
+		minLength = defaultMinLength
+	}
+
+	var out []Proposal
+	for _, n := range names {
+		if utf8.RuneCountInString(n.text) >= minLength && containsWord(text, n.text) {
+			out = append(out, Proposal{Target: n.id, Match: n.text})
+		}
+	}
+	return out, nil
+}
+
+// targetNames returns the names and aliases of nodes of the given types:
+// their label, nickname and aliases properties, and Alias nodes linked
+// ALIAS_OF to them
+func (e *evaluator) targetNames(ctx context.Context, types []string) ([]name, error) {
+	sorted := append([]string(nil), types...)
+	sort.Strings(sorted)
+	key := strings.Join(sorted, ",")
+	if names, ok := e.names[key]; ok {
+		return names, nil
+	}
+
+	var names []name
+	add := func(text, id string) {
+		if text = strings.ToLower(strings.TrimSpace(text)); text != "" {
+			names = append(names, name{text: text, id: id})
+		}
+	}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := e.repo.FilterNodes(ctx, sorted, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			if label := graph.NodeLabel(n.ID, n.Meta); label != n.ID {
+				add(label, n.ID)
+			}
+			for _, v := range stringValues(n.Meta["nickname"]) {
+				add(v, n.ID)
+			}
+			for _, v := range stringValues(n.Meta["aliases"]) {
+				add(v, n.ID)
+			}
+			backlinks, err := e.repo.GetBacklinks(ctx, n.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range backlinks {
+				if l.Type != people.AliasOfLink {
+					continue
+				}
+				if alias, err := e.repo.GetNode(ctx, l.Source); err == nil {
+					value, _ := alias.Meta["value"].(string)
+					add(value, n.ID)
+				}
+			}
+		}
+		if len(nodes) < pageSize {
+			break
+		}
+	}
+	// Longest first, so "Ada Lovelace" is reported before "Ada"
+	sort.SliceStable(names, func(i, j int) bool { return len(names[i].text) > len(names[j].text) })
+	e.names[key] = names
+	return names, nil
+}
+
+// property finds targets whose target key equals one of the node's values
+func (e *evaluator) property(ctx context.Context, m Matcher, node *core.Node) ([]Proposal, error) {
+	targetKey := m.TargetKey
+	if targetKey == "" {
+		targetKey = m.Key
+	}
+	var out []Proposal
+	values := stringValues(node.Meta[m.Key])
+	if len(values) > maxValues {
+		values = values[:maxValues]
+	}
+	for _, v := range values {
+		candidates, err := e.repo.FilterNodes(ctx, m.TargetTypes, targetKey, v, 50, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range candidates {
+			for _, tv := range stringValues(c.Meta[targetKey]) {
+				if strings.EqualFold(tv, v) {
+					out = append(out, Proposal{Target: c.ID, Match: v})
+					break
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// ids links to the existing nodes the node's property names
+func (e *evaluator) ids(ctx context.Context, m Matcher, node *core.Node) ([]Proposal, error) {
+	var out []Proposal
+	values := stringValues(node.Meta[m.Key])
+	if len(values) > maxValues {
+		values = values[:maxValues]
+	}
+	for _, id := range values {
+		target, err := e.repo.GetNode(ctx, id)
+		if err != nil {
+			continue
+		}
+		if len(m.TargetTypes) == 0 || contains(m.TargetTypes, target.Type) {
+			out = append(out, Proposal{Target: id, Match: id})
+		}
+	}
+	return out, nil
+}
+
+// apply creates a rule's proposed links that do not exist yet
+func apply(ctx context.Context, repo Repository, r *Rule, proposals []Proposal) (int, error) {
+	now := time.Now()
+	var links []*core.Link
+	for _, p := range proposals {
+		if p.Exists {
+			continue
+		}
+		meta := map[string]interface{}{"rule": r.ID, "matched": p.Match, graph.LayerKey: graph.LayerDerived}
+		for k, v := range r.Link.Meta {
+			meta[k] = v
+		}
+		links = append(links, &core.Link{Source: p.Source, Target: p.Target, Type: p.Type, Meta: meta, Created: now, Modified: now})
+	}
+	if len(links) == 0 {
+		return 0, nil
+	}
+	return len(links), repo.CreateLinks(ctx, links)
+}
+
+// matchText joins the fields a mentions rule searches: "content" is the
+// node's content (or content property), other names are properties
+func matchText(node *core.Node, fields []string) string {
+	if len(fields) == 0 {
+		fields = []string{"content"}
+	}
+	var parts []string
+	for _, f := range fields {
+		if f == "content" && len(node.Content) > 0 {
+			parts = append(parts, string(node.Content))
+			continue
+		}
+		parts = append(parts, stringValues(node.Meta[f])...)
+	}
+	text := strings.Join(parts, "\n")
+	if len(text) > maxTextBytes {
+		text = text[:maxTextBytes]
+	}
+	return text
+}
+
+// containsWord reports whether word occurs in text with no letter or
+// digit directly before or after it
+func containsWord(text, word string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], word)
+		if i < 0 {
+			return false
+		}
+		i += start
+		before, _ := utf8.DecodeLastRuneInString(text[:i])
+		after, _ := utf8.DecodeRuneInString(text[i+len(word):])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// stringValues reads a string or list-of-strings property
+func stringValues(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []string:
+		return v
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+// Package rules links nodes automatically: user-defined rules are evaluated
+// as nodes are created or updated, and create links to the nodes they match.
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// NodeType is the node type rules are stored as
+const NodeType = "LinkRule"
+
+// idPrefix namespaces rule node IDs
+const idPrefix = "rule:"
+
+// Match kinds
+const (
+	MatchMentions = "mentions" // Text mentions a target's name or alias
+	MatchProperty = "property" // A property equals a target's property
+	MatchIDs      = "ids"      // A property holds target node IDs
+)
+
+// Link directions
+const (
+	DirectionOut = "out" // Node -> target
+	DirectionIn  = "in"  // Target -> node
+)
+
+// Triggers
+const (
+	OnCreated = "created"
+	OnUpdated = "updated"
+)
+
+// ErrNotFound is returned for unknown rules
+var ErrNotFound = errors.New("rule not found")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validLinkType matches the link types rules may create
+var validLinkType = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+// Rule links nodes matching a condition to the nodes a matcher finds
+type Rule struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Disabled    bool      `json:"disabled,omitempty"`
+	When        Condition `json:"when"`
+	Match       Matcher   `json:"match"`
+	Link        LinkSpec  `json:"link"`
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+}
+
+// Condition selects the nodes a rule applies to. Where and Expr use the
+// subscription predicate and expression language, with node_type,
+// node_id and meta.<key> bound to the node.
+type Condition struct {
+	NodeTypes []string                  `json:"node_types,omitempty"`
+	On        []string                  `json:"on,omitempty"` // created, updated; default both
+	Where     []subscriptions.Predicate `json:"where,omitempty"`
+	Expr      string                    `json:"expr,omitempty"`
+}
+
+// Matcher finds the nodes to link to
+type Matcher struct {
+	Kind        string   `json:"kind"`                   // mentions, property or ids
+	TargetTypes []string `json:"target_types,omitempty"` // Required for mentions and property
+	Fields      []string `json:"fields,omitempty"`       // mentions: text to search ("content" or meta keys); default content
+	Key         string   `json:"key,omitempty"`          // property, ids: the node's property
+	TargetKey   string   `json:"target_key,omitempty"`   // property: the target's property; default the same key
+	MinLength   int      `json:"min_length,omitempty"`   // mentions: shortest name matched; default 3
+}
+
+// LinkSpec is the link a rule creates
+type LinkSpec struct {
+	Type      string                 `json:"type"`
+	Direction string                 `json:"direction,omitempty"` // out (default) or in
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+}
+
+// condition compiles the rule's Where and Expr into one expression, or nil
+func (r *Rule) condition() (*subscriptions.Expression, error) {
+	source, err := subscriptions.SubscriptionPattern{Where: r.When.Where, Expr: r.When.Expr}.Condition()
+	if err != nil || source == "" {
+		return nil, err
+	}
+	return subscriptions.CompileExpression(source)
+}
+
+// Validate checks a rule's ID, condition, matcher and link
+func (r *Rule) Validate() error {
+	if !validID.MatchString(r.ID) {
+		return fmt.Errorf("invalid id %q (use 1-64 letters, digits, _ or -)", r.ID)
+	}
+	for _, on := range r.When.On {
+		if on != OnCreated && on != OnUpdated {
+			return fmt.Errorf("invalid trigger %q (created or updated)", on)
+		}
+	}
+	if _, err := r.condition(); err != nil {
+		return fmt.Errorf("invalid condition: %w", err)
+	}
+
+	m := r.Match
+	switch m.Kind {
+	case MatchMentions:
+		if len(m.TargetTypes) == 0 {
+			return fmt.Errorf("mentions rules need target_types")
+		}
+		if m.MinLength < 0 {
+			return fmt.Errorf("min_length must not be negative")
+		}
+	case MatchProperty:
+		if len(m.TargetTypes) == 0 || m.Key == "" {
+			return fmt.Errorf("property rules need key and target_types")
+		}
+	case MatchIDs:
+		if m.Key == "" {
+			return fmt.Errorf("ids rules need key")
+		}
+	default:
+		return fmt.Errorf("invalid match kind %q (mentions, property or ids)", m.Kind)
+	}
+
+	if !validLinkType.MatchString(r.Link.Type) {
+		return fmt.Errorf("invalid link type %q", r.Link.Type)
+	}
+	if r.Link.Direction != "" && r.Link.Direction != DirectionOut && r.Link.Direction != DirectionIn {
+		return fmt.Errorf("invalid link direction %q (out or in)", r.Link.Direction)
+	}
+	return nil
+}
+
+// Save validates and stores a new rule, generating an ID if needed
+func Save(ctx context.Context, repo graph.Repository, r *Rule) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	now := time.Now()
+	r.Created, r.Modified = now, now
+	return Restore(ctx, repo, r)
+}
+
+// Restore validates and stores a rule from a backup, keeping its ID and timestamps
+func Restore(ctx context.Context, repo graph.Repository, r *Rule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	meta, err := toMeta(r)
+	if err != nil {
+		return err
+	}
+	return repo.CreateNode(ctx, &core.Node{ID: idPrefix + r.ID, Type: NodeType, Meta: meta, Created: r.Created, Modified: r.Modified})
+}
+
+// Update validates and replaces a rule's definition
+func Update(ctx context.Context, repo graph.Repository, r *Rule) error {
+	existing, err := Get(ctx, repo, r.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	r.Created, r.Modified = existing.Created, time.Now()
+	meta, err := toMeta(r)
+	if err != nil {
+		return err
+	}
+	// Absent optional fields are cleared rather than left from the old version
+	for _, key := range []string{"name", "description", "disabled"} {
+		if _, ok := meta[key]; !ok {
+			meta[key] = nil
+		}
+	}
+	return repo.UpdateNodeMeta(ctx, idPrefix+r.ID, meta)
+}
+
+// Get loads a rule
+func Get(ctx context.Context, repo graph.Repository, id string) (*Rule, error) {
+	node, err := repo.GetNode(ctx, idPrefix+id)
+	if err != nil || node.Type != NodeType {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return fromNode(node)
+}
+
+// List returns all rules
+func List(ctx context.Context, repo graph.Repository) ([]*Rule, error) {
+	const pageSize = 500
+	out := []*Rule{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			r, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			out = append(out, r)
+		}
+		if len(nodes) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+// Delete removes a rule and its history. Links it created stay.
+func Delete(ctx context.Context, repo graph.Repository, id string) error {
+	if _, err := Get(ctx, repo, id); err != nil {
+		return err
+	}
+	return repo.DeleteNode(ctx, idPrefix+id, true)
+}
+
+// IsRuleNode reports whether a node ID is a stored rule
+func IsRuleNode(id string) bool {
+	return strings.HasPrefix(id, idPrefix)
+}
+
+// toMeta stores a rule's fields as node properties
+func toMeta(r *Rule) (map[string]interface{}, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	delete(meta, "id")
+	return meta, nil
+}
+
+// fromNode reads a rule back from its node
+func fromNode(node *core.Node) (*Rule, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	var r Rule
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("corrupt rule %s: %w", node.ID, err)
+	}
+	r.ID = strings.TrimPrefix(node.ID, idPrefix)
+	return &r, nil
+}
@@ -0,0 +1,173 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+func TestMentionsRule(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"name": "Ada Lovelace"}},
+		{ID: "person:al", Type: "Person", Meta: map[string]interface{}{"name": "Al"}},
+		{ID: "alias:email:countess@example.com", Type: people.AliasType, Meta: map[string]interface{}{"value": "The Countess"}},
+		{ID: "note:1", Type: "Note", Content: []byte("Tea with the countess; Al sends regards.")},
+		{ID: "note:2", Type: "Note", Content: []byte("Ada Lovelaces are not a word match")},
+		{ID: "doc:1", Type: "Document", Content: []byte("Ada Lovelace wrote the notes")},
+	} {
+		n.Created, n.Modified = now, now
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateLink(ctx, &core.Link{Source: "alias:email:countess@example.com", Target: "person:ada", Type: people.AliasOfLink, Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	rule := &Rule{
+		ID:    "note-mentions",
+		When:  Condition{NodeTypes: []string{"Note"}},
+		Match: Matcher{Kind: MatchMentions, TargetTypes: []string{"Person"}},
+		Link:  LinkSpec{Type: "MENTIONS"},
+	}
+	if err := Save(ctx, repo, rule); err != nil {
+		t.Fatal(err)
+	}
+
+	note, _ := repo.GetNode(ctx, "note:1")
+	applies, proposals, err := DryRun(ctx, repo, rule, note, OnCreated)
+	if err != nil || !applies {
+		t.Fatalf("DryRun = %v, %v", applies, err)
+	}
+	if len(proposals) != 1 || proposals[0].Target != "person:ada" || proposals[0].Match != "the countess" {
+		t.Fatalf("proposals = %+v, want person:ada via alias (Al is too short)", proposals)
+	}
+	if links, _ := repo.GetLinks(ctx, "note:1"); len(links) != 0 {
+		t.Fatal("dry run created links")
+	}
+
+	engine := NewEngine(repo)
+	if err := engine.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"note:1", "note:2", "doc:1"} {
+		if _, err := engine.ProcessNode(ctx, id, OnCreated); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links, _ := repo.GetLinks(ctx, "note:1")
+	if len(links) != 1 || links[0].Target != "person:ada" || links[0].Type != "MENTIONS" || links[0].Meta["rule"] != "note-mentions" {
+		t.Fatalf("links = %+v", links)
+	}
+	if links, _ := repo.GetLinks(ctx, "note:2"); len(links) != 0 {
+		t.Errorf("partial word matched: %+v", links)
+	}
+	if links, _ := repo.GetLinks(ctx, "doc:1"); len(links) != 0 {
+		t.Errorf("rule applied to a Document: %+v", links)
+	}
+
+	// Reprocessing does not duplicate the link
+	if n, _ := engine.ProcessNode(ctx, "note:1", OnUpdated); n != 0 {
+		t.Errorf("reprocessing created %d links", n)
+	}
+	m := engine.Metrics("note-mentions")
+	if m.Evaluated != 3 || m.Hits != 1 || m.Links != 1 || m.LastHit == nil {
+		t.Errorf("metrics = %+v", m)
+	}
+	_, proposals, _ = DryRun(ctx, repo, rule, note, OnUpdated)
+	if len(proposals) != 1 || !proposals[0].Exists {
+		t.Errorf("proposals after linking = %+v, want existing", proposals)
+	}
+
+	// A new alias is picked up once the engine sees its link
+	repo.CreateNode(ctx, &core.Node{ID: "alias:handle:ada", Type: people.AliasType, Meta: map[string]interface{}{"value": "enchantress"}, Created: now, Modified: now})
+	repo.CreateLink(ctx, &core.Link{Source: "alias:handle:ada", Target: "person:ada", Type: people.AliasOfLink, Created: now, Modified: now})
+	engine.handle(subscriptions.Event{Type: subscriptions.EventLinkCreated, LinkType: people.AliasOfLink})
+	repo.CreateNode(ctx, &core.Node{ID: "note:3", Type: "Note", Content: []byte("The Enchantress of Numbers"), Created: now, Modified: now})
+	if n, _ := engine.ProcessNode(ctx, "note:3", OnCreated); n != 1 {
+		t.Errorf("new alias: created %d links, want 1", n)
+	}
+}
+
+func TestPropertyRuleAndCondition(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "org:acme", Type: "Organization", Meta: map[string]interface{}{"domain": "acme.com"}},
+		{ID: "person:wile", Type: "Person", Meta: map[string]interface{}{"email_domain": "acme.com", "status": "active"}},
+		{ID: "person:road", Type: "Person", Meta: map[string]interface{}{"email_domain": "acme.com", "status": "archived"}},
+	} {
+		n.Created, n.Modified = now, now
+		repo.CreateNode(ctx, n)
+	}
+
+	rule := &Rule{
+		ID:    "works-at",
+		When:  Condition{NodeTypes: []string{"Person"}, Where: []subscriptions.Predicate{{Field: "meta.status", Op: "==", Value: "active"}}},
+		Match: Matcher{Kind: MatchProperty, TargetTypes: []string{"Organization"}, Key: "email_domain", TargetKey: "domain"},
+		Link:  LinkSpec{Type: "WORKS_AT", Direction: DirectionIn},
+	}
+	if err := rule.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	wile, _ := repo.GetNode(ctx, "person:wile")
+	_, proposals, err := DryRun(ctx, repo, rule, wile, OnCreated)
+	if err != nil || len(proposals) != 1 || proposals[0].Source != "org:acme" || proposals[0].Target != "person:wile" {
+		t.Fatalf("proposals = %+v, %v", proposals, err)
+	}
+	road, _ := repo.GetNode(ctx, "person:road")
+	if applies, _, _ := DryRun(ctx, repo, rule, road, OnCreated); applies {
+		t.Error("rule applied despite its condition")
+	}
+}
+
+func TestRuleStore(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	for _, bad := range []*Rule{
+		{ID: "x", Match: Matcher{Kind: MatchMentions}, Link: LinkSpec{Type: "MENTIONS"}},
+		{ID: "x", Match: Matcher{Kind: "fuzzy"}, Link: LinkSpec{Type: "MENTIONS"}},
+		{ID: "x", Match: Matcher{Kind: MatchIDs, Key: "refs"}, Link: LinkSpec{Type: "bad type"}},
+		{ID: "x", When: Condition{On: []string{"deleted"}}, Match: Matcher{Kind: MatchIDs, Key: "refs"}, Link: LinkSpec{Type: "REFS"}},
+		{ID: "x", When: Condition{Expr: "meta.a =="}, Match: Matcher{Kind: MatchIDs, Key: "refs"}, Link: LinkSpec{Type: "REFS"}},
+		{ID: "bad id", Match: Matcher{Kind: MatchIDs, Key: "refs"}, Link: LinkSpec{Type: "REFS"}},
+	} {
+		if err := Save(ctx, repo, bad); err == nil {
+			t.Errorf("Save(%+v) succeeded", bad)
+		}
+	}
+
+	r := &Rule{ID: "refs", Name: "References", Match: Matcher{Kind: MatchIDs, Key: "refs"}, Link: LinkSpec{Type: "REFS"}}
+	if err := Save(ctx, repo, r); err != nil {
+		t.Fatal(err)
+	}
+	r.Name = ""
+	r.Disabled = true
+	if err := Update(ctx, repo, r); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Get(ctx, repo, "refs")
+	if err != nil || got.Name != "" || !got.Disabled || got.Link.Type != "REFS" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if list, _ := List(ctx, repo); len(list) != 1 {
+		t.Errorf("List = %d rules", len(list))
+	}
+	if err := Delete(ctx, repo, "refs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(ctx, repo, "refs"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after delete: %v", err)
+	}
+}
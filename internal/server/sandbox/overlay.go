@@ -0,0 +1,578 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// maxSubgraphNodes caps the nodes a sandbox subgraph loads, as the
+// in-memory backend does
+const maxSubgraphNodes = 1000
+
+// linkKey identifies a link
+type linkKey struct {
+	source, target, typ string
+}
+
+func keyOf(l *core.Link) linkKey {
+	return linkKey{l.Source, l.Target, l.Type}
+}
+
+// Overlay is a copy-on-write view over a base graph. Reads see the union
+// of the base graph and the overlay's own changes; writes only ever touch
+// an in-memory store. Base nodes are copied into the store the first time
+// they are updated. Graph-wide operations not overridden here (timelines,
+// usage, lenses, attention) see only the overlay's own nodes and links.
+type Overlay struct {
+	*graph.MemoryRepository // Nodes and links created or copied in the sandbox
+
+	base graph.Repository
+
+	mu       sync.RWMutex
+	created  map[string]bool                   // Nodes new in the sandbox
+	patches  map[string]map[string]interface{} // Base node -> properties set in the sandbox
+	deleted  map[string]bool                   // Base node -> force; hidden from reads
+	newLinks map[linkKey]bool                  // Links created in the sandbox
+	unlinked map[linkKey]bool                  // Base links deleted in the sandbox
+}
+
+// NewOverlay creates an empty overlay over base
+func NewOverlay(base graph.Repository) *Overlay {
+	return &Overlay{
+		MemoryRepository: graph.NewMemory(),
+		base:             base,
+		created:          make(map[string]bool),
+		patches:          make(map[string]map[string]interface{}),
+		deleted:          make(map[string]bool),
+		newLinks:         make(map[linkKey]bool),
+		unlinked:         make(map[linkKey]bool),
+	}
+}
+
+// Close discards the overlay; the base graph stays open
+func (o *Overlay) Close(ctx context.Context) error {
+	return nil
+}
+
+// SetEventEmitter is a no-op: sandbox changes are not published
+func (o *Overlay) SetEventEmitter(emitter func(subscriptions.Event)) {}
+
+// local reports whether the overlay holds its own copy of a node
+func (o *Overlay) local(id string) bool {
+	return o.created[id] || o.patches[id] != nil
+}
+
+// getNode reads a node through the overlay; callers hold o.mu
+func (o *Overlay) getNode(ctx context.Context, id string) (*core.Node, error) {
+	if _, ok := o.deleted[id]; ok {
+		return nil, fmt.Errorf("node not found")
+	}
+	if o.local(id) {
+		return o.MemoryRepository.GetNode(ctx, id)
+	}
+	return o.base.GetNode(ctx, id)
+}
+
+// GetNode retrieves a node from the overlay or, failing that, the base graph
+func (o *Overlay) GetNode(ctx context.Context, id string) (*core.Node, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.getNode(ctx, id)
+}
+
+// GetNodeAtVersion reads a version from wherever the node's current version lives
+func (o *Overlay) GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if _, ok := o.deleted[id]; ok {
+		return nil, fmt.Errorf("node not found")
+	}
+	if o.created[id] {
+		return o.MemoryRepository.GetNodeAtVersion(ctx, id, version)
+	}
+	return o.base.GetNodeAtVersion(ctx, id, version)
+}
+
+// GetNodeAtTime reads a past version; sandbox updates have no history in
+// the base graph, so base nodes answer from the base graph
+func (o *Overlay) GetNodeAtTime(ctx context.Context, id string, asOf time.Time) (*core.Node, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if _, ok := o.deleted[id]; ok {
+		return nil, fmt.Errorf("node not found")
+	}
+	if o.created[id] {
+		return o.MemoryRepository.GetNodeAtTime(ctx, id, asOf)
+	}
+	return o.base.GetNodeAtTime(ctx, id, asOf)
+}
+
+// GetNodeHistory returns the history of the overlay's copy, or the base node's
+func (o *Overlay) GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if _, ok := o.deleted[id]; ok {
+		return nil, fmt.Errorf("node not found: %s", id)
+	}
+	if o.local(id) {
+		return o.MemoryRepository.GetNodeHistory(ctx, id)
+	}
+	return o.base.GetNodeHistory(ctx, id)
+}
+
+// CreateNode creates a node in the sandbox
+func (o *Overlay) CreateNode(ctx context.Context, node *core.Node) error {
+	return o.CreateNodes(ctx, []*core.Node{node})
+}
+
+// CreateNodes creates nodes in the sandbox, failing if any already exists
+func (o *Overlay) CreateNodes(ctx context.Context, nodes []*core.Node) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, n := range nodes {
+		if _, ok := o.deleted[n.ID]; ok {
+			return fmt.Errorf("inserting node: %s was deleted in this sandbox", n.ID)
+		}
+		if _, err := o.getNode(ctx, n.ID); err == nil {
+			return fmt.Errorf("inserting node: node already exists: %s", n.ID)
+		}
+	}
+	if err := o.MemoryRepository.CreateNodes(ctx, nodes); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		o.created[n.ID] = true
+	}
+	return nil
+}
+
+// UpdateNodeMeta updates a node's properties in the sandbox
+func (o *Overlay) UpdateNodeMeta(ctx context.Context, id string, meta map[string]any) error {
+	return o.UpdateNodeMetaWithNote(ctx, id, meta, "", "")
+}
+
+// UpdateNodeMetaWithNote updates a node's properties in the sandbox,
+// copying a base node into the overlay first
+func (o *Overlay) UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.local(id) {
+		node, err := o.getNode(ctx, id)
+		if err != nil {
+			return fmt.Errorf("node not found: %s", id)
+		}
+		if err := o.MemoryRepository.CreateNode(ctx, node); err != nil {
+			return err
+		}
+		o.patches[id] = make(map[string]interface{})
+	}
+	if err := o.MemoryRepository.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy); err != nil {
+		return err
+	}
+	if patch := o.patches[id]; patch != nil {
+		for k, v := range meta {
+			patch[k] = v
+		}
+	}
+	return nil
+}
+
+// DeleteNode removes a sandbox node, or hides a base node until the
+// sandbox is merged. Links created in the sandbox to or from it go too.
+func (o *Overlay) DeleteNode(ctx context.Context, nodeID string, force bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	node, err := o.getNode(ctx, nodeID)
+	if err != nil {
+		return fmt.Errorf("node not found or already deleted: %s", nodeID)
+	}
+	if graph.IsProtected(node.Meta) {
+		return fmt.Errorf("cannot delete %s: %w", nodeID, graph.ErrProtected)
+	}
+	if !force && strings.HasPrefix(nodeID, "sha256:") && len(nodeID) > 7 {
+		return fmt.Errorf("cannot delete Source layer node (content-addressed): %s", nodeID)
+	}
+
+	for key := range o.newLinks {
+		if key.source == nodeID || key.target == nodeID {
+			if err := o.MemoryRepository.DeleteLink(ctx, key.source, key.target, key.typ); err != nil {
+				return err
+			}
+			delete(o.newLinks, key)
+		}
+	}
+	if o.local(nodeID) {
+		if err := o.MemoryRepository.DeleteNode(ctx, nodeID, true); err != nil {
+			return err
+		}
+	}
+	if o.created[nodeID] {
+		delete(o.created, nodeID)
+		return nil
+	}
+	delete(o.patches, nodeID)
+	o.deleted[nodeID] = force
+	return nil
+}
+
+// baseLinks returns a node's base links in one direction, without those
+// deleted in the sandbox or touching deleted nodes
+func (o *Overlay) baseLinks(ctx context.Context, id string, incoming bool) ([]*core.Link, error) {
+	if o.created[id] {
+		return nil, nil
+	}
+	var links []*core.Link
+	var err error
+	if incoming {
+		links, err = o.base.GetBacklinks(ctx, id)
+	} else {
+		links, err = o.base.GetLinks(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := links[:0]
+	for _, l := range links {
+		_, sourceGone := o.deleted[l.Source]
+		_, targetGone := o.deleted[l.Target]
+		if !sourceGone && !targetGone && !o.unlinked[keyOf(l)] {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// links returns the union of base and sandbox links; callers hold o.mu
+func (o *Overlay) links(ctx context.Context, id string, incoming bool) ([]*core.Link, error) {
+	if _, ok := o.deleted[id]; ok {
+		return []*core.Link{}, nil
+	}
+	links, err := o.baseLinks(ctx, id, incoming)
+	if err != nil {
+		return nil, err
+	}
+	var own []*core.Link
+	if incoming {
+		own, err = o.MemoryRepository.GetBacklinks(ctx, id)
+	} else {
+		own, err = o.MemoryRepository.GetLinks(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(links, own...), nil
+}
+
+// GetLinks returns a node's outgoing links in the base graph and sandbox
+func (o *Overlay) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.links(ctx, nodeID, false)
+}
+
+// GetBacklinks returns a node's incoming links in the base graph and sandbox
+func (o *Overlay) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.links(ctx, nodeID, true)
+}
+
+// baseHasLink reports whether the base graph has a link the sandbox has not deleted
+func (o *Overlay) baseHasLink(ctx context.Context, key linkKey) (bool, error) {
+	links, err := o.baseLinks(ctx, key.source, false)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range links {
+		if keyOf(l) == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateLink creates a link in the sandbox
+func (o *Overlay) CreateLink(ctx context.Context, link *core.Link) error {
+	return o.CreateLinks(ctx, []*core.Link{link})
+}
+
+// CreateLinks creates links in the sandbox. Both ends must exist in the
+// base graph or the sandbox. Recreating a base link deleted in the
+// sandbox restores it.
+func (o *Overlay) CreateLinks(ctx context.Context, links []*core.Link) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var own []*core.Link
+	for _, l := range links {
+		for _, end := range []string{l.Source, l.Target} {
+			if _, err := o.getNode(ctx, end); err != nil {
+				return fmt.Errorf("inserting link: node not found: %s", end)
+			}
+		}
+		key := keyOf(l)
+		if o.unlinked[key] {
+			continue
+		}
+		exists, err := o.baseHasLink(ctx, key)
+		if err != nil {
+			return err
+		}
+		if exists || o.newLinks[key] {
+			return fmt.Errorf("inserting link: link already exists: %s -[%s]-> %s", l.Source, l.Type, l.Target)
+		}
+		own = append(own, l)
+	}
+	if err := o.MemoryRepository.CreateLinks(ctx, own); err != nil {
+		return err
+	}
+	for _, l := range links {
+		key := keyOf(l)
+		if o.unlinked[key] {
+			delete(o.unlinked, key)
+		} else {
+			o.newLinks[key] = true
+		}
+	}
+	return nil
+}
+
+// DeleteLink removes a sandbox link, or hides a base link until the
+// sandbox is merged
+func (o *Overlay) DeleteLink(ctx context.Context, sourceID string, targetID string, linkType string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := linkKey{sourceID, targetID, linkType}
+	if o.newLinks[key] {
+		delete(o.newLinks, key)
+		return o.MemoryRepository.DeleteLink(ctx, sourceID, targetID, linkType)
+	}
+	exists, err := o.baseHasLink(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("link not found: %s -[%s]-> %s", sourceID, linkType, targetID)
+	}
+	o.unlinked[key] = true
+	return nil
+}
+
+// union pages through sandbox nodes followed by the base nodes the
+// sandbox has not replaced or deleted
+func (o *Overlay) union(limit, offset int, own, base func(limit, offset int) ([]*core.Node, error)) ([]*core.Node, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	want := offset + limit
+	nodes, err := own(want, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Read past the base nodes the sandbox hides
+	baseNodes, err := base(want+len(o.patches)+len(o.deleted), 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range baseNodes {
+		if _, gone := o.deleted[n.ID]; !gone && !o.local(n.ID) {
+			nodes = append(nodes, n)
+		}
+	}
+	if offset >= len(nodes) {
+		return []*core.Node{}, nil
+	}
+	nodes = nodes[offset:]
+	if limit < len(nodes) {
+		nodes = nodes[:limit]
+	}
+	return nodes, nil
+}
+
+// SearchNodes searches the sandbox and the base graph
+func (o *Overlay) SearchNodes(ctx context.Context, searchTerm string, limit int, offset int) ([]*core.Node, error) {
+	return o.union(limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return o.MemoryRepository.SearchNodes(ctx, searchTerm, limit, offset)
+	}, func(limit, offset int) ([]*core.Node, error) {
+		return o.base.SearchNodes(ctx, searchTerm, limit, offset)
+	})
+}
+
+// SearchSimilar suggests from the sandbox and the base nodes it has not
+// replaced or deleted
+func (o *Overlay) SearchSimilar(ctx context.Context, term string, limit int) ([]*graph.SimilarNode, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	nodes, err := o.MemoryRepository.SearchSimilar(ctx, term, limit)
+	if err != nil {
+		return nil, err
+	}
+	// Read past the base nodes the sandbox hides
+	baseNodes, err := o.base.SearchSimilar(ctx, term, limit+len(o.patches)+len(o.deleted))
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range baseNodes {
+		if _, gone := o.deleted[n.Node.ID]; !gone && !o.local(n.Node.ID) {
+			nodes = append(nodes, n)
+		}
+	}
+	graph.SortSimilar(nodes)
+	if limit < len(nodes) {
+		nodes = nodes[:limit]
+	}
+	return nodes, nil
+}
+
+// FilterNodes filters the sandbox and the base graph
+func (o *Overlay) FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error) {
+	return o.union(limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return o.MemoryRepository.FilterNodes(ctx, nodeTypes, propertyKey, propertyValue, limit, offset)
+	}, func(limit, offset int) ([]*core.Node, error) {
+		return o.base.FilterNodes(ctx, nodeTypes, propertyKey, propertyValue, limit, offset)
+	})
+}
+
+// QueryTimeRange queries the sandbox and the base graph
+func (o *Overlay) QueryTimeRange(ctx context.Context, from, to time.Time, nodeTypes []string, limit int, offset int) ([]*core.Node, error) {
+	return o.union(limit, offset, func(limit, offset int) ([]*core.Node, error) {
+		return o.MemoryRepository.QueryTimeRange(ctx, from, to, nodeTypes, limit, offset)
+	}, func(limit, offset int) ([]*core.Node, error) {
+		return o.base.QueryTimeRange(ctx, from, to, nodeTypes, limit, offset)
+	})
+}
+
+// ListNodes lists sandbox nodes and the base nodes the sandbox has not deleted
+func (o *Overlay) ListNodes(ctx context.Context) ([]string, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	ids, err := o.MemoryRepository.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	baseIDs, err := o.base.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range baseIDs {
+		if _, gone := o.deleted[id]; !gone && !o.local(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// reachable returns the nodes within depth outgoing hops of start, in BFS
+// order, stopping early when the context's traversal budget runs out;
+// callers hold o.mu
+func (o *Overlay) reachable(ctx context.Context, start string, depth int, relationshipTypes []string, max int) ([]*core.Node, error) {
+	meter := graph.TraversalMeterFrom(ctx)
+	first, err := o.getNode(ctx, start)
+	if err != nil || !meter.Node() {
+		return nil, nil
+	}
+	seen := map[string]bool{start: true}
+	found := []*core.Node{first}
+	frontier := []string{start}
+	for d := 0; d < depth && len(frontier) > 0 && len(found) < max; d++ {
+		var next []string
+		for _, id := range frontier {
+			links, err := o.links(ctx, id, false)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range links {
+				if !hasType(relationshipTypes, l.Type) {
+					continue
+				}
+				if !meter.Edge() {
+					return found, nil
+				}
+				if seen[l.Target] {
+					continue
+				}
+				if !meter.Node() {
+					return found, nil
+				}
+				seen[l.Target] = true
+				node, err := o.getNode(ctx, l.Target)
+				if err != nil {
+					continue
+				}
+				found = append(found, node)
+				next = append(next, l.Target)
+			}
+		}
+		frontier = next
+	}
+	return found, nil
+}
+
+// TraverseGraph walks outgoing links through the sandbox and the base graph
+func (o *Overlay) TraverseGraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string, limit int, offset int) (map[string]*core.Node, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	found, err := o.reachable(ctx, startNodeID, depth, relationshipTypes, offset+limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*core.Node)
+	for i, n := range found {
+		if i >= offset && len(result) < limit {
+			result[n.ID] = n
+		}
+	}
+	return result, nil
+}
+
+// GetSubgraph returns the nodes within depth hops of a start node and the
+// links among them, through the sandbox and the base graph
+func (o *Overlay) GetSubgraph(ctx context.Context, startNodeID string, depth int, relationshipTypes []string) (*graph.Subgraph, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	nodes, err := o.reachable(ctx, startNodeID, depth, relationshipTypes, maxSubgraphNodes)
+	if err != nil {
+		return nil, err
+	}
+	in := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		in[n.ID] = true
+	}
+	edges := []*graph.SubgraphEdge{}
+	for _, n := range nodes {
+		links, err := o.links(ctx, n.ID, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			if in[l.Target] && hasType(relationshipTypes, l.Type) {
+				edges = append(edges, &graph.SubgraphEdge{Source: l.Source, Target: l.Target, Type: l.Type, Meta: l.Meta, Weight: l.Weight, Bidirectional: l.Bidirectional})
+			}
+		}
+	}
+	return &graph.Subgraph{
+		Nodes: nodes,
+		Edges: edges,
+		Stats: graph.SubgraphStats{NodeCount: len(nodes), EdgeCount: len(edges), Depth: depth},
+	}, nil
+}
+
+// hasType reports whether t is in types; an empty list matches everything
+func hasType(types []string, t string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
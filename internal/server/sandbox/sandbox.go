@@ -0,0 +1,346 @@
+// Package sandbox keeps scratch graphs where an agent can try out
+// hypothesis nodes and links over the main graph without touching it.
+// Each sandbox is a copy-on-write overlay; accepted changes are merged
+// back and the rest discarded. Sandboxes live in memory and expire when
+// left idle; a restart discards them all.
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Defaults for the manager
+const (
+	DefaultTTL = 24 * time.Hour
+	DefaultMax = 50
+)
+
+// Errors returned by the manager
+var (
+	ErrNotFound = errors.New("sandbox not found")
+	ErrLimit    = errors.New("too many sandboxes")
+)
+
+// Change operations
+const (
+	OpCreateNode = "create_node"
+	OpUpdateNode = "update_node"
+	OpDeleteNode = "delete_node"
+	OpCreateLink = "create_link"
+	OpDeleteLink = "delete_link"
+)
+
+// Change is one pending change a sandbox makes to the main graph
+type Change struct {
+	Op     string                 `json:"op"`
+	NodeID string                 `json:"node_id,omitempty"`
+	Node   *core.Node             `json:"node,omitempty"`  // create_node
+	Meta   map[string]interface{} `json:"meta,omitempty"`  // update_node: properties set
+	Force  bool                   `json:"force,omitempty"` // delete_node
+	Link   *core.Link             `json:"link,omitempty"`  // create_link, delete_link
+}
+
+// Sandbox is a named overlay over the main graph
+type Sandbox struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+	Expires  time.Time `json:"expires"`
+	Changes  int       `json:"changes"`
+
+	Overlay *Overlay `json:"-"`
+}
+
+// Changes lists the sandbox's pending changes in the order a merge
+// applies them: created nodes, updated nodes, created links, deleted
+// links, then deleted nodes
+func (o *Overlay) Changes(ctx context.Context) ([]Change, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	ids, err := o.MemoryRepository.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	var updates []Change
+	for _, id := range ids {
+		node, err := o.MemoryRepository.GetNode(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if o.created[id] {
+			changes = append(changes, Change{Op: OpCreateNode, NodeID: id, Node: node})
+		} else if patch := o.patches[id]; patch != nil {
+			updates = append(updates, Change{Op: OpUpdateNode, NodeID: id, Meta: patch})
+		}
+	}
+	changes = append(changes, updates...)
+
+	for _, key := range sortedKeys(o.newLinks) {
+		links, err := o.MemoryRepository.GetLinks(ctx, key.source)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			if keyOf(l) == key {
+				changes = append(changes, Change{Op: OpCreateLink, Link: l})
+			}
+		}
+	}
+	for _, key := range sortedKeys(o.unlinked) {
+		changes = append(changes, Change{Op: OpDeleteLink, Link: &core.Link{Source: key.source, Target: key.target, Type: key.typ}})
+	}
+
+	deleted := make([]string, 0, len(o.deleted))
+	for id := range o.deleted {
+		deleted = append(deleted, id)
+	}
+	sort.Strings(deleted)
+	for _, id := range deleted {
+		changes = append(changes, Change{Op: OpDeleteNode, NodeID: id, Force: o.deleted[id]})
+	}
+	return changes, nil
+}
+
+func sortedKeys(set map[linkKey]bool) []linkKey {
+	keys := make([]linkKey, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.source != b.source {
+			return a.source < b.source
+		}
+		if a.target != b.target {
+			return a.target < b.target
+		}
+		return a.typ < b.typ
+	})
+	return keys
+}
+
+// LinkRef names a link to merge
+type LinkRef struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// Selection picks the changes to merge: node changes by node ID and link
+// changes by link. An empty selection merges everything.
+type Selection struct {
+	Nodes []string  `json:"nodes,omitempty"`
+	Links []LinkRef `json:"links,omitempty"`
+}
+
+func (s Selection) empty() bool {
+	return len(s.Nodes) == 0 && len(s.Links) == 0
+}
+
+func (s Selection) includes(c Change) bool {
+	if s.empty() {
+		return true
+	}
+	if c.Link != nil {
+		for _, ref := range s.Links {
+			if ref.Source == c.Link.Source && ref.Target == c.Link.Target && ref.Type == c.Link.Type {
+				return true
+			}
+		}
+		return false
+	}
+	for _, id := range s.Nodes {
+		if id == c.NodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeFailure is a change the main graph rejected
+type MergeFailure struct {
+	Change Change `json:"change"`
+	Error  string `json:"error"`
+}
+
+// MergeResult reports what a merge applied
+type MergeResult struct {
+	Merged    []Change       `json:"merged"`
+	Failed    []MergeFailure `json:"failed"`
+	Discarded int            `json:"discarded"`
+}
+
+// apply writes one change to repo
+func apply(ctx context.Context, repo graph.Repository, c Change, note string) error {
+	now := time.Now()
+	switch c.Op {
+	case OpCreateNode:
+		node := *c.Node
+		node.Created, node.Modified = now, now
+		return repo.CreateNode(ctx, &node)
+	case OpUpdateNode:
+		return repo.UpdateNodeMetaWithNote(ctx, c.NodeID, c.Meta, note, "")
+	case OpCreateLink:
+		link := *c.Link
+		link.Created, link.Modified = now, now
+		return repo.CreateLink(ctx, &link)
+	case OpDeleteLink:
+		return repo.DeleteLink(ctx, c.Link.Source, c.Link.Target, c.Link.Type)
+	case OpDeleteNode:
+		return repo.DeleteNode(ctx, c.NodeID, c.Force)
+	}
+	return fmt.Errorf("unknown change %q", c.Op)
+}
+
+// Manager holds the open sandboxes over one main graph
+type Manager struct {
+	base graph.Repository
+	ttl  time.Duration
+	max  int
+
+	mu        sync.Mutex
+	sandboxes map[string]*Sandbox
+}
+
+// NewManager creates a manager; sandboxes idle for ttl are discarded and
+// at most max are open at once
+func NewManager(base graph.Repository, ttl time.Duration, max int) *Manager {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if max <= 0 {
+		max = DefaultMax
+	}
+	return &Manager{base: base, ttl: ttl, max: max, sandboxes: make(map[string]*Sandbox)}
+}
+
+// prune discards expired sandboxes; callers hold m.mu
+func (m *Manager) prune(now time.Time) {
+	for id, sb := range m.sandboxes {
+		if now.After(sb.LastUsed.Add(m.ttl)) {
+			delete(m.sandboxes, id)
+		}
+	}
+}
+
+// view copies a sandbox for callers, with its expiry and change count
+func (m *Manager) view(ctx context.Context, sb *Sandbox) (Sandbox, error) {
+	v := *sb
+	v.Expires = sb.LastUsed.Add(m.ttl)
+	changes, err := sb.Overlay.Changes(ctx)
+	if err != nil {
+		return Sandbox{}, err
+	}
+	v.Changes = len(changes)
+	return v, nil
+}
+
+// Create opens an empty sandbox
+func (m *Manager) Create(ctx context.Context, name string) (Sandbox, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	if len(m.sandboxes) >= m.max {
+		return Sandbox{}, fmt.Errorf("%w: at most %d may be open", ErrLimit, m.max)
+	}
+	sb := &Sandbox{
+		ID:       uuid.New().String(),
+		Name:     name,
+		Created:  now,
+		LastUsed: now,
+		Overlay:  NewOverlay(m.base),
+	}
+	m.sandboxes[sb.ID] = sb
+	return m.view(ctx, sb)
+}
+
+// Get returns an open sandbox and marks it used
+func (m *Manager) Get(ctx context.Context, id string) (Sandbox, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	sb := m.sandboxes[id]
+	if sb == nil {
+		return Sandbox{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	sb.LastUsed = now
+	return m.view(ctx, sb)
+}
+
+// List returns the open sandboxes, oldest first
+func (m *Manager) List(ctx context.Context) ([]Sandbox, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(time.Now())
+	out := make([]Sandbox, 0, len(m.sandboxes))
+	for _, sb := range m.sandboxes {
+		v, err := m.view(ctx, sb)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out, nil
+}
+
+// Discard closes a sandbox, dropping its changes
+func (m *Manager) Discard(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sandboxes[id] == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(m.sandboxes, id)
+	return nil
+}
+
+// Merge applies the selected changes of a sandbox to the main graph and
+// closes the sandbox, discarding whatever was not selected. Changes the
+// main graph rejects, for example because it changed meanwhile, are
+// reported and do not stop the rest.
+func (m *Manager) Merge(ctx context.Context, id string, sel Selection) (*MergeResult, error) {
+	m.mu.Lock()
+	sb := m.sandboxes[id]
+	delete(m.sandboxes, id)
+	m.mu.Unlock()
+	if sb == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	changes, err := sb.Overlay.Changes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	note := "merged from sandbox " + sb.ID
+	if sb.Name != "" {
+		note += " (" + sb.Name + ")"
+	}
+	result := &MergeResult{Merged: []Change{}, Failed: []MergeFailure{}}
+	for _, c := range changes {
+		if !sel.includes(c) {
+			result.Discarded++
+			continue
+		}
+		if err := apply(ctx, m.base, c, note); err != nil {
+			result.Failed = append(result.Failed, MergeFailure{Change: c, Error: err.Error()})
+			continue
+		}
+		result.Merged = append(result.Merged, c)
+	}
+	return result, nil
+}
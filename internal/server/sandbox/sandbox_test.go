@@ -0,0 +1,168 @@
+package sandbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+var _ graph.Repository = (*Overlay)(nil)
+
+func seed(t *testing.T) *graph.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		n := &core.Node{ID: id, Type: "Note", Content: []byte("note " + id), Meta: map[string]interface{}{"status": "open"}, Created: now, Modified: now}
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []*core.Link{
+		{Source: "a", Target: "b", Type: "RELATED"},
+		{Source: "b", Target: "c", Type: "RELATED"},
+	} {
+		l.Created, l.Modified = now, now
+		if err := repo.CreateLink(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestOverlay(t *testing.T) {
+	ctx := context.Background()
+	base := seed(t)
+	o := NewOverlay(base)
+	now := time.Now()
+
+	if err := o.CreateNode(ctx, &core.Node{ID: "a", Type: "Note"}); err == nil {
+		t.Error("created a node that exists in the base graph")
+	}
+	if err := o.CreateNode(ctx, &core.Node{ID: "h", Type: "Hypothesis", Content: []byte("maybe"), Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.CreateLink(ctx, &core.Link{Source: "c", Target: "h", Type: "SUGGESTS", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.CreateLink(ctx, &core.Link{Source: "c", Target: "missing", Type: "SUGGESTS"}); err == nil {
+		t.Error("linked to a node that does not exist")
+	}
+	if err := o.UpdateNodeMeta(ctx, "a", map[string]any{"status": "done"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.DeleteLink(ctx, "a", "b", "RELATED"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads see the union
+	if n, err := o.GetNode(ctx, "a"); err != nil || n.Meta["status"] != "done" {
+		t.Errorf("sandbox a = %+v, %v", n, err)
+	}
+	if links, _ := o.GetLinks(ctx, "a"); len(links) != 0 {
+		t.Errorf("deleted base link still visible: %+v", links)
+	}
+	sub, err := o.GetSubgraph(ctx, "b", 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.Nodes) != 3 || len(sub.Edges) != 2 {
+		t.Errorf("subgraph from b = %d nodes, %d edges", len(sub.Nodes), len(sub.Edges))
+	}
+	nodes, _ := o.FilterNodes(ctx, nil, "", "", 10, 0)
+	if len(nodes) != 4 {
+		t.Errorf("filter = %d nodes, want 4", len(nodes))
+	}
+
+	// The base graph is untouched
+	if n, _ := base.GetNode(ctx, "a"); n.Meta["status"] != "open" {
+		t.Errorf("base a changed: %+v", n.Meta)
+	}
+	if _, err := base.GetNode(ctx, "h"); err == nil {
+		t.Error("sandbox node leaked into the base graph")
+	}
+	if links, _ := base.GetLinks(ctx, "a"); len(links) != 1 {
+		t.Errorf("base link deleted: %+v", links)
+	}
+
+	if err := o.DeleteNode(ctx, "c", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.GetNode(ctx, "c"); err == nil {
+		t.Error("deleted node still visible")
+	}
+	if links, _ := o.GetBacklinks(ctx, "h"); len(links) != 0 {
+		t.Errorf("links of deleted node still visible: %+v", links)
+	}
+	if err := o.CreateNode(ctx, &core.Node{ID: "c", Type: "Note"}); err == nil {
+		t.Error("recreated a node deleted in the sandbox")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	base := seed(t)
+	m := NewManager(base, time.Hour, 2)
+	now := time.Now()
+
+	sb, err := m.Create(ctx, "hypotheses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := sb.Overlay
+	for _, id := range []string{"h1", "h2"} {
+		if err := o.CreateNode(ctx, &core.Node{ID: id, Type: "Hypothesis", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.CreateLink(ctx, &core.Link{Source: "h1", Target: "a", Type: "EXPLAINS", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.UpdateNodeMeta(ctx, "b", map[string]any{"status": "done"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.DeleteLink(ctx, "b", "c", "RELATED"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.Get(ctx, sb.ID)
+	if err != nil || got.Changes != 5 {
+		t.Fatalf("sandbox = %+v, %v; want 5 changes", got, err)
+	}
+
+	result, err := m.Merge(ctx, sb.ID, Selection{
+		Nodes: []string{"h1", "b"},
+		Links: []LinkRef{{Source: "h1", Target: "a", Type: "EXPLAINS"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Merged) != 3 || len(result.Failed) != 0 || result.Discarded != 2 {
+		t.Errorf("merge = %+v", result)
+	}
+	if _, err := base.GetNode(ctx, "h1"); err != nil {
+		t.Error("selected node not merged")
+	}
+	if _, err := base.GetNode(ctx, "h2"); err == nil {
+		t.Error("unselected node merged")
+	}
+	if n, _ := base.GetNode(ctx, "b"); n.Meta["status"] != "done" {
+		t.Errorf("update not merged: %+v", n.Meta)
+	}
+	if links, _ := base.GetLinks(ctx, "b"); len(links) != 1 {
+		t.Errorf("unselected link deletion merged: %+v", links)
+	}
+	if _, err := m.Get(ctx, sb.ID); err == nil {
+		t.Error("merged sandbox still open")
+	}
+
+	m.Create(ctx, "")
+	m.Create(ctx, "")
+	if _, err := m.Create(ctx, ""); err == nil {
+		t.Error("opened more sandboxes than the limit")
+	}
+}
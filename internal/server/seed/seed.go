@@ -0,0 +1,252 @@
+// Package seed populates a repository with a deterministic synthetic graph
+// for development, demos and benchmarks.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Profile describes the size and shape of a synthetic graph
+type Profile struct {
+	Name      string
+	People    int
+	Documents int
+	Concepts  int
+
+	AuthorsPerDocument int // Upper bound; each document has 1..N authors
+	MentionsPerDoc     int // Upper bound; each document mentions 1..N concepts
+	KnowsPerPerson     int // Upper bound on outgoing KNOWS links
+	RelatedPerConcept  int // Upper bound on outgoing RELATED_TO links
+	AttentionEdges     int // Attention updates between co-mentioned concepts
+}
+
+// Profiles are the built-in seed profiles
+var Profiles = map[string]Profile{
+	"small": {Name: "small", People: 10, Documents: 30, Concepts: 15,
+		AuthorsPerDocument: 2, MentionsPerDoc: 3, KnowsPerPerson: 3, RelatedPerConcept: 2, AttentionEdges: 20},
+	"demo": {Name: "demo", People: 50, Documents: 300, Concepts: 80,
+		AuthorsPerDocument: 3, MentionsPerDoc: 5, KnowsPerPerson: 6, RelatedPerConcept: 3, AttentionEdges: 400},
+	"bench": {Name: "bench", People: 2000, Documents: 20000, Concepts: 3000,
+		AuthorsPerDocument: 3, MentionsPerDoc: 8, KnowsPerPerson: 10, RelatedPerConcept: 4, AttentionEdges: 20000},
+}
+
+// Lookup returns a built-in profile by name
+func Lookup(name string) (Profile, error) {
+	p, ok := Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(Profiles))
+		for n := range Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("unknown seed profile %q (use %s)", name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+// Result summarizes what a seed run wrote
+type Result struct {
+	Profile        string `json:"profile"`
+	Seed           int64  `json:"seed"`
+	Nodes          int    `json:"nodes"`
+	Links          int    `json:"links"`
+	AttentionEdges int    `json:"attention_edges"`
+}
+
+// epoch anchors all generated timestamps so runs are reproducible. Nodes are
+// created during the following year and links at the end of it.
+var (
+	epoch    = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	linkTime = epoch.AddDate(1, 0, 0)
+)
+
+// Load writes the synthetic graph described by p into repo. The same profile
+// and seed always produce the same nodes, links and attention edges.
+// Popularity of authors and concepts follows a Zipf distribution, so a few
+// hubs collect most links as in real knowledge graphs.
+func Load(ctx context.Context, repo graph.Repository, p Profile, seed int64) (*Result, error) {
+	rng := rand.New(rand.NewSource(seed))
+	g := &generator{rng: rng, links: make(map[string]bool)}
+
+	people := make([]*core.Node, p.People)
+	for i := range people {
+		name := pick(rng, firstNames) + " " + pick(rng, lastNames)
+		people[i] = g.node(fmt.Sprintf("person:seed-%04d", i+1), "Person", "", map[string]any{
+			"name": name,
+			"role": pick(rng, roles),
+		})
+	}
+
+	concepts := make([]*core.Node, p.Concepts)
+	for i := range concepts {
+		name := topics[i%len(topics)]
+		if i >= len(topics) {
+			name = pick(rng, qualifiers) + " " + name
+		}
+		concepts[i] = g.node(fmt.Sprintf("concept:seed-%04d", i+1), "Concept", "", map[string]any{
+			"name": name,
+		})
+	}
+
+	var links []*core.Link
+	var mentioned [][]int
+	documents := make([]*core.Node, p.Documents)
+	for i := range documents {
+		id := fmt.Sprintf("doc:seed-%05d", i+1)
+
+		mentions := g.zipfDistinct(p.Concepts, 1+rng.Intn(max(p.MentionsPerDoc, 1)))
+		names := make([]string, len(mentions))
+		for j, c := range mentions {
+			names[j] = concepts[c].Meta["name"].(string)
+			links = g.link(links, id, concepts[c].ID, "MENTIONS")
+		}
+		mentioned = append(mentioned, mentions)
+
+		subject := pick(rng, topics)
+		if len(names) > 0 {
+			subject = names[0]
+		}
+		title := fmt.Sprintf("%s %s", pick(rng, titlePrefixes), subject)
+		content := title + "."
+		if len(names) > 0 {
+			content += " Discusses " + strings.Join(names, ", ") + "."
+		}
+		documents[i] = g.node(id, "Document", content, map[string]any{
+			"title":  title,
+			"source": pick(rng, sources),
+		})
+
+		for _, a := range g.zipfDistinct(p.People, 1+rng.Intn(max(p.AuthorsPerDocument, 1))) {
+			links = g.link(links, id, people[a].ID, "AUTHORED_BY")
+		}
+	}
+
+	for i, person := range people {
+		for _, other := range g.zipfDistinct(p.People, rng.Intn(p.KnowsPerPerson+1)) {
+			if other != i {
+				links = g.link(links, person.ID, people[other].ID, "KNOWS")
+			}
+		}
+	}
+	for i, concept := range concepts {
+		for _, other := range g.zipfDistinct(p.Concepts, rng.Intn(p.RelatedPerConcept+1)) {
+			if other != i {
+				links = g.link(links, concept.ID, concepts[other].ID, "RELATED_TO")
+			}
+		}
+	}
+
+	nodes := append(append(people, concepts...), documents...)
+	if err := repo.CreateNodes(ctx, nodes); err != nil {
+		return nil, fmt.Errorf("creating nodes: %w", err)
+	}
+	if err := repo.CreateLinks(ctx, links); err != nil {
+		return nil, fmt.Errorf("creating links: %w", err)
+	}
+
+	// Attention edges between concepts that appear in the same document
+	attention := 0
+	for i := 0; i < p.AttentionEdges && len(mentioned) > 0; i++ {
+		mentions := mentioned[rng.Intn(len(mentioned))]
+		if len(mentions) < 2 {
+			continue
+		}
+		a, b := mentions[rng.Intn(len(mentions))], mentions[rng.Intn(len(mentions))]
+		if a == b {
+			continue
+		}
+		weight := 0.2 + 0.8*rng.Float64()
+		queryID := fmt.Sprintf("seed-query-%d", i+1)
+		if err := repo.UpdateAttentionEdge(ctx, concepts[a].ID, concepts[b].ID, queryID, weight); err != nil {
+			return nil, fmt.Errorf("updating attention edge: %w", err)
+		}
+		attention++
+	}
+
+	return &Result{
+		Profile:        p.Name,
+		Seed:           seed,
+		Nodes:          len(nodes),
+		Links:          len(links),
+		AttentionEdges: attention,
+	}, nil
+}
+
+// generator holds the random source and deduplicates links
+type generator struct {
+	rng   *rand.Rand
+	links map[string]bool
+}
+
+// node builds a node with a reproducible creation time within a year of epoch
+func (g *generator) node(id, nodeType, content string, meta map[string]any) *core.Node {
+	created := epoch.Add(time.Duration(g.rng.Intn(365*24)) * time.Hour)
+	return &core.Node{
+		ID:       id,
+		Type:     nodeType,
+		Content:  []byte(content),
+		Meta:     meta,
+		Created:  created,
+		Modified: created,
+	}
+}
+
+// link appends a link unless the same (source, target, type) was already generated
+func (g *generator) link(links []*core.Link, source, target, linkType string) []*core.Link {
+	key := source + "\x00" + target + "\x00" + linkType
+	if g.links[key] {
+		return links
+	}
+	g.links[key] = true
+	return append(links, &core.Link{
+		Source:   source,
+		Target:   target,
+		Type:     linkType,
+		Meta:     map[string]any{"seed": true},
+		Created:  linkTime,
+		Modified: linkTime,
+	})
+}
+
+// zipfDistinct draws up to k distinct indices in [0, n), favouring low indices
+func (g *generator) zipfDistinct(n, k int) []int {
+	if n == 0 || k <= 0 {
+		return nil
+	}
+	if n == 1 {
+		return []int{0}
+	}
+	zipf := rand.NewZipf(g.rng, 1.3, 2, uint64(n-1))
+	seen := make(map[int]bool, k)
+	var picked []int
+	for attempts := 0; len(picked) < k && attempts < k*4; attempts++ {
+		i := int(zipf.Uint64())
+		if !seen[i] {
+			seen[i] = true
+			picked = append(picked, i)
+		}
+	}
+	return picked
+}
+
+func pick(rng *rand.Rand, words []string) string {
+	return words[rng.Intn(len(words))]
+}
+
+var (
+	firstNames    = []string{"Ada", "Alan", "Grace", "Norbert", "Vannevar", "Ted", "Doug", "Barbara", "Edsger", "Frances", "Donald", "Radia", "Ken", "Margaret", "Tim", "Hedy"}
+	lastNames     = []string{"Lovelace", "Turing", "Hopper", "Wiener", "Bush", "Nelson", "Engelbart", "Liskov", "Dijkstra", "Allen", "Knuth", "Perlman", "Thompson", "Hamilton", "Berners-Lee", "Lamarr"}
+	roles         = []string{"researcher", "engineer", "editor", "librarian", "student", "designer"}
+	topics        = []string{"hypertext", "associative trails", "knowledge graphs", "information retrieval", "attention", "version control", "graph databases", "embeddings", "ontologies", "note taking", "spaced repetition", "search ranking", "provenance", "linked data", "citation networks", "personal archives"}
+	qualifiers    = []string{"distributed", "temporal", "probabilistic", "collaborative", "incremental", "semantic", "federated", "visual"}
+	titlePrefixes = []string{"Notes on", "A survey of", "Rethinking", "Field guide to", "Experiments with", "The case for"}
+	sources       = []string{"paper", "blog", "book", "talk", "email", "meeting"}
+)
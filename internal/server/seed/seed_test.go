@@ -0,0 +1,45 @@
+package seed
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestLoadIsDeterministic(t *testing.T) {
+	ctx := context.Background()
+	p, err := Lookup("small")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := func() *graph.Subgraph {
+		repo := graph.NewMemory()
+		res, err := Load(ctx, repo, p, 42)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if res.Nodes != p.People+p.Documents+p.Concepts {
+			t.Errorf("Nodes = %d, want %d", res.Nodes, p.People+p.Documents+p.Concepts)
+		}
+		sg, err := repo.GetGraphSnapshot(ctx, nil, 10000)
+		if err != nil {
+			t.Fatalf("GetGraphSnapshot: %v", err)
+		}
+		for _, e := range sg.Edges {
+			delete(e.Meta, "last_updated") // attention edges stamp wall-clock time
+			delete(e.Meta, "contributions")
+		}
+		return sg
+	}
+
+	a, b := snapshot(), snapshot()
+	if len(a.Edges) == 0 {
+		t.Fatal("expected seeded links")
+	}
+	if !reflect.DeepEqual(a.Nodes, b.Nodes) || !reflect.DeepEqual(a.Edges, b.Edges) {
+		t.Error("same profile and seed produced different graphs")
+	}
+}
@@ -0,0 +1,424 @@
+// Package sessions keeps the working memory of multi-turn agent
+// conversations: the nodes retrieved, notes and intermediate conclusions.
+// Sessions live in memory and expire when left idle; closing one can
+// persist it into the main graph.
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Defaults for the manager
+const (
+	DefaultTTL = 24 * time.Hour
+	DefaultMax = 200
+)
+
+// Per-session limits
+const (
+	MaxRetrieved = 1000
+	MaxEntries   = 500
+)
+
+// Entry kinds
+const (
+	KindNote       = "note"
+	KindConclusion = "conclusion"
+)
+
+// Types and links written when a session is persisted
+const (
+	NodeType           = "Session"
+	ConclusionType     = "Conclusion"
+	LinkRetrieved      = "RETRIEVED"    // Session -> node it retrieved
+	LinkConcludedIn    = "CONCLUDED_IN" // Conclusion -> session
+	LinkBasedOn        = "BASED_ON"     // Conclusion -> node it cites
+	idPrefix           = "session:"
+	conclusionIDFormat = "%s:conclusion:%d"
+)
+
+// Errors returned by the manager
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrLimit    = errors.New("session limit reached")
+	ErrInvalid  = errors.New("invalid session entry")
+)
+
+// Retrieval is a node the session has seen
+type Retrieval struct {
+	NodeID string    `json:"node_id"`
+	Count  int       `json:"count"`
+	Query  string    `json:"query,omitempty"` // Latest query that returned it
+	First  time.Time `json:"first"`
+	Last   time.Time `json:"last"`
+}
+
+// Entry is an agent note or intermediate conclusion
+type Entry struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Text    string    `json:"text"`
+	NodeIDs []string  `json:"node_ids,omitempty"` // Nodes it refers to
+	At      time.Time `json:"at"`
+}
+
+// Session is one conversation's working memory
+type Session struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name,omitempty"`
+	Agent     string      `json:"agent,omitempty"`
+	Created   time.Time   `json:"created"`
+	LastUsed  time.Time   `json:"last_used"`
+	Expires   time.Time   `json:"expires"`
+	Retrieved []Retrieval `json:"retrieved"` // In first-retrieved order
+	Entries   []Entry     `json:"entries"`
+
+	index map[string]int // Node ID -> position in Retrieved
+}
+
+// Context is a session's working memory resolved against the graph
+type Context struct {
+	Session     Session               `json:"session"`
+	Nodes       []*core.Node          `json:"nodes"`   // Retrieved nodes, most recently seen first
+	Missing     []string              `json:"missing"` // Retrieved nodes no longer in the graph
+	Edges       []*graph.SubgraphEdge `json:"edges"`   // Links among the retrieved nodes
+	Notes       []Entry               `json:"notes"`
+	Conclusions []Entry               `json:"conclusions"`
+}
+
+// CloseResult reports what closing a session wrote to the graph
+type CloseResult struct {
+	ID          string `json:"id"`
+	Persisted   bool   `json:"persisted"`
+	SessionNode string `json:"session_node,omitempty"`
+	Nodes       int    `json:"nodes_created"`
+	Links       int    `json:"links_created"`
+}
+
+// Manager holds the open sessions over one graph
+type Manager struct {
+	repo graph.Repository
+	ttl  time.Duration
+	max  int
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewManager creates a manager; sessions idle for ttl are discarded and
+// at most max are open at once
+func NewManager(repo graph.Repository, ttl time.Duration, max int) *Manager {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if max <= 0 {
+		max = DefaultMax
+	}
+	return &Manager{repo: repo, ttl: ttl, max: max, sessions: make(map[string]*Session)}
+}
+
+// prune discards expired sessions; callers hold m.mu
+func (m *Manager) prune(now time.Time) {
+	for id, s := range m.sessions {
+		if now.After(s.LastUsed.Add(m.ttl)) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// view copies a session for callers
+func (m *Manager) view(s *Session) Session {
+	v := *s
+	v.Expires = s.LastUsed.Add(m.ttl)
+	v.Retrieved = append([]Retrieval{}, s.Retrieved...)
+	v.Entries = append([]Entry{}, s.Entries...)
+	v.index = nil
+	return v
+}
+
+// use returns an open session and marks it used; callers hold m.mu
+func (m *Manager) use(id string) (*Session, error) {
+	now := time.Now()
+	m.prune(now)
+	s := m.sessions[id]
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	s.LastUsed = now
+	return s, nil
+}
+
+// Create opens an empty session
+func (m *Manager) Create(name, agent string) (Session, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	if len(m.sessions) >= m.max {
+		return Session{}, fmt.Errorf("%w: at most %d may be open", ErrLimit, m.max)
+	}
+	s := &Session{
+		ID:       uuid.New().String(),
+		Name:     name,
+		Agent:    agent,
+		Created:  now,
+		LastUsed: now,
+		index:    make(map[string]int),
+	}
+	m.sessions[s.ID] = s
+	return m.view(s), nil
+}
+
+// Get returns an open session and marks it used
+func (m *Manager) Get(id string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, err := m.use(id)
+	if err != nil {
+		return Session{}, err
+	}
+	return m.view(s), nil
+}
+
+// List returns the open sessions, oldest first
+func (m *Manager) List() []Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(time.Now())
+	out := make([]Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		out = append(out, m.view(s))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out
+}
+
+// Record adds retrieved nodes to a session, with the query that found
+// them if any. Once a session holds MaxRetrieved nodes, new ones are
+// dropped; nodes it already holds are still counted.
+func (m *Manager) Record(id, query string, nodeIDs []string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, err := m.use(id)
+	if err != nil {
+		return Session{}, err
+	}
+	now := s.LastUsed
+	for _, nodeID := range nodeIDs {
+		if nodeID == "" {
+			continue
+		}
+		if i, ok := s.index[nodeID]; ok {
+			r := &s.Retrieved[i]
+			r.Count++
+			r.Last = now
+			if query != "" {
+				r.Query = query
+			}
+			continue
+		}
+		if len(s.Retrieved) >= MaxRetrieved {
+			continue
+		}
+		s.index[nodeID] = len(s.Retrieved)
+		s.Retrieved = append(s.Retrieved, Retrieval{NodeID: nodeID, Count: 1, Query: query, First: now, Last: now})
+	}
+	return m.view(s), nil
+}
+
+// Add appends a note or conclusion to a session
+func (m *Manager) Add(id string, e Entry) (Entry, error) {
+	e.Text = strings.TrimSpace(e.Text)
+	if e.Kind == "" {
+		e.Kind = KindNote
+	}
+	if e.Kind != KindNote && e.Kind != KindConclusion {
+		return Entry{}, fmt.Errorf("%w: kind must be %s or %s", ErrInvalid, KindNote, KindConclusion)
+	}
+	if e.Text == "" {
+		return Entry{}, fmt.Errorf("%w: text is required", ErrInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, err := m.use(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(s.Entries) >= MaxEntries {
+		return Entry{}, fmt.Errorf("%w: at most %d entries per session", ErrLimit, MaxEntries)
+	}
+	e.ID = fmt.Sprintf("%d", len(s.Entries)+1)
+	e.At = s.LastUsed
+	s.Entries = append(s.Entries, e)
+	return e, nil
+}
+
+// Context resolves a session against the graph: up to maxNodes of its
+// retrieved nodes, most recently seen first, the links among them, and
+// its notes and conclusions
+func (m *Manager) Context(ctx context.Context, id string, maxNodes int) (*Context, error) {
+	session, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	retrieved := append([]Retrieval{}, session.Retrieved...)
+	sort.SliceStable(retrieved, func(i, j int) bool { return retrieved[i].Last.After(retrieved[j].Last) })
+
+	out := &Context{
+		Session:     session,
+		Nodes:       []*core.Node{},
+		Missing:     []string{},
+		Edges:       []*graph.SubgraphEdge{},
+		Notes:       []Entry{},
+		Conclusions: []Entry{},
+	}
+	included := make(map[string]bool)
+	for _, r := range retrieved {
+		if maxNodes > 0 && len(out.Nodes) >= maxNodes {
+			break
+		}
+		node, err := m.repo.GetNode(ctx, r.NodeID)
+		if err != nil {
+			out.Missing = append(out.Missing, r.NodeID)
+			continue
+		}
+		included[node.ID] = true
+		out.Nodes = append(out.Nodes, node)
+	}
+	for _, node := range out.Nodes {
+		links, err := m.repo.GetLinks(ctx, node.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			if included[l.Target] {
+				out.Edges = append(out.Edges, &graph.SubgraphEdge{Source: l.Source, Target: l.Target, Type: l.Type, Meta: l.Meta, Weight: l.Weight, Bidirectional: l.Bidirectional})
+			}
+		}
+	}
+	for _, e := range session.Entries {
+		if e.Kind == KindConclusion {
+			out.Conclusions = append(out.Conclusions, e)
+		} else {
+			out.Notes = append(out.Notes, e)
+		}
+	}
+	return out, nil
+}
+
+// Discard closes a session without persisting it
+func (m *Manager) Discard(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[id] == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+// Close ends a session. With persist, the session becomes a Session node
+// holding its notes and linked RETRIEVED to the nodes it retrieved, and
+// each conclusion a Conclusion node linked CONCLUDED_IN the session and
+// BASED_ON the nodes it refers to. Links to nodes no longer in the graph
+// are skipped. The session stays open if persisting fails.
+func (m *Manager) Close(ctx context.Context, id string, persist bool) (*CloseResult, error) {
+	session, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	result := &CloseResult{ID: id, Persisted: persist}
+	if persist {
+		if err := m.persist(ctx, session, result); err != nil {
+			return nil, err
+		}
+	}
+	m.Discard(id)
+	return result, nil
+}
+
+// persist writes a session into the graph
+func (m *Manager) persist(ctx context.Context, s Session, result *CloseResult) error {
+	now := time.Now()
+	sessionID := idPrefix + s.ID
+	exists := func(id string) bool {
+		_, err := m.repo.GetNode(ctx, id)
+		return err == nil
+	}
+
+	var notes []string
+	for _, e := range s.Entries {
+		if e.Kind == KindNote {
+			notes = append(notes, e.Text)
+		}
+	}
+	meta := map[string]interface{}{
+		"started":   s.Created.Format(time.RFC3339),
+		"closed":    now.Format(time.RFC3339),
+		"retrieved": len(s.Retrieved),
+	}
+	if s.Name != "" {
+		meta["name"] = s.Name
+	}
+	if s.Agent != "" {
+		meta["agent"] = s.Agent
+	}
+	if len(notes) > 0 {
+		meta["notes"] = notes
+	}
+	nodes := []*core.Node{{ID: sessionID, Type: NodeType, Meta: meta, Created: now, Modified: now}}
+
+	var links []*core.Link
+	link := func(source, target, typ string, meta map[string]interface{}) {
+		links = append(links, &core.Link{Source: source, Target: target, Type: typ, Meta: meta, Created: now, Modified: now})
+	}
+	for _, r := range s.Retrieved {
+		if exists(r.NodeID) {
+			link(sessionID, r.NodeID, LinkRetrieved, map[string]interface{}{"count": r.Count})
+		}
+	}
+	n := 0
+	for _, e := range s.Entries {
+		if e.Kind != KindConclusion {
+			continue
+		}
+		n++
+		conclusionID := fmt.Sprintf(conclusionIDFormat, sessionID, n)
+		nodes = append(nodes, &core.Node{
+			ID:       conclusionID,
+			Type:     ConclusionType,
+			Meta:     map[string]interface{}{"text": e.Text, "concluded": e.At.Format(time.RFC3339)},
+			Created:  now,
+			Modified: now,
+		})
+		link(conclusionID, sessionID, LinkConcludedIn, nil)
+		for _, nodeID := range e.NodeIDs {
+			if exists(nodeID) {
+				link(conclusionID, nodeID, LinkBasedOn, nil)
+			}
+		}
+	}
+
+	if err := m.repo.CreateNodes(ctx, nodes); err != nil {
+		return fmt.Errorf("persisting session: %w", err)
+	}
+	if err := m.repo.CreateLinks(ctx, links); err != nil {
+		return fmt.Errorf("persisting session links: %w", err)
+	}
+	result.SessionNode = sessionID
+	result.Nodes = len(nodes)
+	result.Links = len(links)
+	return nil
+}
@@ -0,0 +1,92 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: "Note", Meta: map[string]interface{}{}, Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.CreateLink(ctx, &core.Link{Source: "a", Target: "b", Type: "CITES", Created: now, Modified: now})
+	repo.CreateLink(ctx, &core.Link{Source: "a", Target: "c", Type: "CITES", Created: now, Modified: now})
+
+	m := NewManager(repo, time.Hour, 1)
+	s, err := m.Create("research", "agent-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("", ""); !errors.Is(err, ErrLimit) {
+		t.Errorf("second session: %v", err)
+	}
+
+	m.Record(s.ID, "engines", []string{"a", "b"})
+	time.Sleep(time.Millisecond)
+	got, _ := m.Record(s.ID, "", []string{"b", "gone"})
+	if len(got.Retrieved) != 3 || got.Retrieved[1].Count != 2 || got.Retrieved[1].Query != "engines" {
+		t.Errorf("retrieved = %+v", got.Retrieved)
+	}
+	if _, err := m.Add(s.ID, Entry{Kind: "guess", Text: "x"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("bad kind: %v", err)
+	}
+	m.Add(s.ID, Entry{Text: "check b again"})
+	m.Add(s.ID, Entry{Kind: KindConclusion, Text: "a cites b", NodeIDs: []string{"a", "b", "gone"}})
+
+	c, err := m.Context(ctx, s.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Most recently seen first; the missing node is reported, not returned
+	if len(c.Nodes) != 2 || c.Nodes[0].ID != "b" || len(c.Missing) != 1 || c.Missing[0] != "gone" {
+		t.Errorf("nodes %v, missing %v", c.Nodes, c.Missing)
+	}
+	if len(c.Edges) != 1 || c.Edges[0].Target != "b" {
+		t.Errorf("edges = %+v", c.Edges)
+	}
+	if len(c.Notes) != 1 || len(c.Conclusions) != 1 {
+		t.Errorf("notes %v, conclusions %v", c.Notes, c.Conclusions)
+	}
+
+	result, err := m.Close(ctx, s.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Session + conclusion; 2 RETRIEVED, CONCLUDED_IN and 2 BASED_ON
+	if result.Nodes != 2 || result.Links != 5 {
+		t.Errorf("close = %+v", result)
+	}
+	node, err := repo.GetNode(ctx, result.SessionNode)
+	if err != nil || node.Type != NodeType || node.Meta["name"] != "research" {
+		t.Errorf("session node = %+v, %v", node, err)
+	}
+	links, _ := repo.GetLinks(ctx, result.SessionNode+":conclusion:1")
+	if len(links) != 3 {
+		t.Errorf("conclusion links = %d", len(links))
+	}
+	if _, err := m.Get(s.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("closed session: %v", err)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	m := NewManager(graph.NewMemory(), time.Millisecond, 0)
+	s, _ := m.Create("", "")
+	time.Sleep(5 * time.Millisecond)
+	if _, err := m.Get(s.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("idle session: %v", err)
+	}
+	if len(m.List()) != 0 {
+		t.Error("expired session listed")
+	}
+}
@@ -0,0 +1,103 @@
+// Package share signs and verifies expiring read-only grants to a node and
+// its neighborhood, used for public share links.
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxDepth caps the neighborhood a share may expose
+const MaxDepth = 3
+
+// Errors returned by Verify
+var (
+	ErrInvalid = errors.New("invalid share token")
+	ErrExpired = errors.New("share link has expired")
+)
+
+// Grant is read-only access to a node and its depth-hop neighborhood
+type Grant struct {
+	NodeID  string
+	Depth   int
+	Expires time.Time
+}
+
+// claims is the signed token payload
+type claims struct {
+	NodeID  string `json:"n"`
+	Depth   int    `json:"d"`
+	Expires int64  `json:"e"` // Unix seconds
+}
+
+// Signer issues and verifies share tokens with an HMAC key
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates a signer from a secret; tokens stay valid for as long
+// as the same secret is configured
+func NewSigner(secret string) *Signer {
+	return &Signer{key: []byte(secret)}
+}
+
+// NewRandomSigner creates a signer with a random key; its tokens stop
+// working when the process exits
+func NewRandomSigner() (*Signer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating share key: %w", err)
+	}
+	return &Signer{key: key}, nil
+}
+
+// Sign returns a URL-safe token for the grant
+func (s *Signer) Sign(g Grant) (string, error) {
+	if g.NodeID == "" {
+		return "", fmt.Errorf("node ID is required")
+	}
+	if g.Depth < 0 || g.Depth > MaxDepth {
+		return "", fmt.Errorf("depth must be between 0 and %d", MaxDepth)
+	}
+	payload, err := json.Marshal(claims{NodeID: g.NodeID, Depth: g.Depth, Expires: g.Expires.Unix()})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.mac(encoded), nil
+}
+
+// Verify checks a token's signature and expiry and returns its grant
+func (s *Signer) Verify(token string, now time.Time) (Grant, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.mac(encoded))) {
+		return Grant{}, ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Grant{}, ErrInvalid
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.NodeID == "" {
+		return Grant{}, ErrInvalid
+	}
+	g := Grant{NodeID: c.NodeID, Depth: c.Depth, Expires: time.Unix(c.Expires, 0)}
+	if !now.Before(g.Expires) {
+		return g, ErrExpired
+	}
+	return g, nil
+}
+
+// mac signs an encoded payload
+func (s *Signer) mac(encoded string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
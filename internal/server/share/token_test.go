@@ -0,0 +1,40 @@
+package share
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	s := NewSigner("secret")
+	now := time.Now()
+	token, err := s.Sign(Grant{NodeID: "note:trail", Depth: 2, Expires: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := s.Verify(token, now)
+	if err != nil || g.NodeID != "note:trail" || g.Depth != 2 {
+		t.Fatalf("Verify() = %+v, %v", g, err)
+	}
+	if _, err := s.Verify(token, now.Add(2*time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+	if _, err := NewSigner("other").Verify(token, now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for another key, got %v", err)
+	}
+
+	// Changing the payload invalidates the signature
+	forged, _ := NewSigner("other").Sign(Grant{NodeID: "note:trail", Depth: 3, Expires: now.Add(time.Hour)})
+	payload, _, _ := strings.Cut(forged, ".")
+	_, sig, _ := strings.Cut(token, ".")
+	if _, err := s.Verify(payload+"."+sig, now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a forged payload, got %v", err)
+	}
+
+	if _, err := s.Sign(Grant{NodeID: "x", Depth: MaxDepth + 1}); err == nil {
+		t.Error("expected depth above MaxDepth to be rejected")
+	}
+}
@@ -0,0 +1,66 @@
+package signing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	v := NewVerifier(map[string]string{"capture": "s3cret"}, time.Minute)
+	now := time.Now()
+	body := []byte(`{"url":"https://example.com"}`)
+	req := httptest.NewRequest("POST", "/memex/api/capture?x=1", bytes.NewReader(body))
+	Sign(req, body, "capture", "s3cret", now)
+	if id, err := v.Verify(req, now); err != nil || id != "capture" {
+		t.Fatalf("Verify() = %q, %v", id, err)
+	}
+	if got, _ := io.ReadAll(req.Body); !bytes.Equal(got, body) {
+		t.Errorf("body after Verify() = %q", got)
+	}
+
+	// The same request again is a replay
+	replay := httptest.NewRequest("POST", "/memex/api/capture?x=1", bytes.NewReader(body))
+	replay.Header = req.Header.Clone()
+	if _, err := v.Verify(replay, now); !errors.Is(err, ErrReplayed) {
+		t.Errorf("expected ErrReplayed, got %v", err)
+	}
+
+	// The signature only covers its own request
+	other := httptest.NewRequest("POST", "/api/nodes", bytes.NewReader(body))
+	other.Header = req.Header.Clone()
+	if _, err := v.Verify(other, now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for another path, got %v", err)
+	}
+	tampered := httptest.NewRequest("POST", "/api/capture?x=1", bytes.NewReader([]byte(`{"url":"https://evil.example"}`)))
+	Sign(tampered, body, "capture", "s3cret", now.Add(time.Second))
+	if _, err := v.Verify(tampered, now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for another body, got %v", err)
+	}
+
+	old := httptest.NewRequest("GET", "/api/nodes", nil)
+	Sign(old, nil, "capture", "s3cret", now.Add(-2*time.Minute))
+	if _, err := v.Verify(old, now); !errors.Is(err, ErrStale) {
+		t.Errorf("expected ErrStale, got %v", err)
+	}
+	unknown := httptest.NewRequest("GET", "/api/nodes", nil)
+	Sign(unknown, nil, "someone", "s3cret", now)
+	if _, err := v.Verify(unknown, now); !errors.Is(err, ErrUnknown) {
+		t.Errorf("expected ErrUnknown, got %v", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("capture=a1, laptop=b2")
+	if err != nil || len(keys) != 2 || keys["laptop"] != "b2" {
+		t.Fatalf("ParseKeys() = %v, %v", keys, err)
+	}
+	for _, spec := range []string{"capture", "capture=", "a=1,a=2"} {
+		if _, err := ParseKeys(spec); err == nil {
+			t.Errorf("ParseKeys(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package snapshots hands out tokens for pinned read views of the graph.
+// A client begins a snapshot, passes its token with the reads of a
+// multi-call flow, and gets the same view from each. Snapshots last a
+// bounded time: on SQLite an open one keeps the write-ahead log from being
+// checkpointed past it.
+package snapshots
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Defaults for the manager
+const (
+	DefaultTTL    = 30 * time.Second
+	DefaultMaxTTL = 5 * time.Minute
+	DefaultMax    = 16
+)
+
+// Errors returned by the manager
+var (
+	ErrNotFound = errors.New("snapshot not found or expired")
+	ErrLimit    = errors.New("snapshot limit reached")
+)
+
+// Info describes an open snapshot
+type Info struct {
+	Token       string    `json:"token"`
+	Consistency string    `json:"consistency"` // graph.SnapshotIsolated or graph.SnapshotCausal
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+}
+
+// entry is an open snapshot and the requests using it
+type entry struct {
+	info  Info
+	snap  graph.Snapshot
+	timer *time.Timer
+	refs  int  // Requests reading through it
+	ended bool // Expired or released; closed once refs reaches zero
+}
+
+// Manager holds the open snapshots of one backend
+type Manager struct {
+	backend graph.Snapshotter
+	ttl     time.Duration
+	maxTTL  time.Duration
+	max     int
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	open    int // Snapshots not yet closed, including ended ones still in use
+}
+
+// NewManager creates a manager. Snapshots last ttl unless the client asks
+// for another lifetime, never more than maxTTL, and at most max are open
+// at once.
+func NewManager(backend graph.Snapshotter, ttl, maxTTL time.Duration, max int) *Manager {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxTTL <= 0 {
+		maxTTL = DefaultMaxTTL
+	}
+	if max <= 0 {
+		max = DefaultMax
+	}
+	return &Manager{backend: backend, ttl: min(ttl, maxTTL), maxTTL: maxTTL, max: max, entries: make(map[string]*entry)}
+}
+
+// Begin pins a view of the graph for ttl, or the default lifetime when ttl
+// is zero. Longer lifetimes are cut to the maximum.
+func (m *Manager) Begin(ctx context.Context, ttl time.Duration) (Info, error) {
+	if ttl <= 0 {
+		ttl = m.ttl
+	}
+	ttl = min(ttl, m.maxTTL)
+
+	m.mu.Lock()
+	if m.open >= m.max {
+		m.mu.Unlock()
+		return Info{}, ErrLimit
+	}
+	m.open++ // Held while the backend begins, so the limit holds
+	m.mu.Unlock()
+
+	token, err := newToken()
+	var snap graph.Snapshot
+	if err == nil {
+		snap, err = m.backend.BeginSnapshot(ctx)
+	}
+	if err != nil {
+		m.mu.Lock()
+		m.open--
+		m.mu.Unlock()
+		return Info{}, err
+	}
+
+	now := time.Now()
+	e := &entry{
+		info: Info{Token: token, Consistency: snap.Consistency(), Created: now, Expires: now.Add(ttl)},
+		snap: snap,
+	}
+	m.mu.Lock()
+	m.entries[token] = e
+	e.timer = time.AfterFunc(ttl, func() { m.end(token) })
+	m.mu.Unlock()
+	return e.info, nil
+}
+
+// Acquire returns the snapshot for token for one request. The snapshot
+// stays open, even past its expiry, until release is called.
+func (m *Manager) Acquire(token string) (graph.Snapshot, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[token]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	e.refs++
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			m.mu.Lock()
+			e.refs--
+			m.closeIfDone(e)
+			m.mu.Unlock()
+		})
+	}
+	return e.snap, release, nil
+}
+
+// Get returns an open snapshot's description
+func (m *Manager) Get(token string) (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[token]
+	if !ok {
+		return Info{}, ErrNotFound
+	}
+	return e.info, nil
+}
+
+// List returns the open snapshots, oldest first
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	list := make([]Info, 0, len(m.entries))
+	for _, e := range m.entries {
+		list = append(list, e.info)
+	}
+	m.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list
+}
+
+// Release ends a snapshot before it expires. Requests already reading
+// through it finish first.
+func (m *Manager) Release(token string) error {
+	if !m.end(token) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close ends every snapshot
+func (m *Manager) Close() {
+	m.mu.Lock()
+	tokens := make([]string, 0, len(m.entries))
+	for token := range m.entries {
+		tokens = append(tokens, token)
+	}
+	m.mu.Unlock()
+	for _, token := range tokens {
+		m.end(token)
+	}
+}
+
+// end removes a snapshot so no new request can use it, and closes it once
+// the requests using it finish. It reports whether the snapshot was open.
+func (m *Manager) end(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[token]
+	if !ok {
+		return false
+	}
+	delete(m.entries, token)
+	e.timer.Stop()
+	e.ended = true
+	m.closeIfDone(e)
+	return true
+}
+
+// closeIfDone closes an ended snapshot no request is using; callers hold m.mu
+func (m *Manager) closeIfDone(e *entry) {
+	if !e.ended || e.refs > 0 {
+		return
+	}
+	m.open--
+	if err := e.snap.Close(); err != nil {
+		log.Printf("Closing snapshot %s: %v", e.info.Token, err)
+	}
+}
+
+// newToken returns a random snapshot token
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "snap_" + hex.EncodeToString(b), nil
+}
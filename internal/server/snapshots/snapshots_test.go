@@ -0,0 +1,112 @@
+package snapshots
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// fakeBackend hands out snapshots that record whether they were closed
+type fakeBackend struct {
+	mu     sync.Mutex
+	closed int
+}
+
+type fakeSnapshot struct{ b *fakeBackend }
+
+func (s *fakeSnapshot) Consistency() string { return graph.SnapshotIsolated }
+
+func (s *fakeSnapshot) Close() error {
+	s.b.mu.Lock()
+	s.b.closed++
+	s.b.mu.Unlock()
+	return nil
+}
+
+func (b *fakeBackend) BeginSnapshot(ctx context.Context) (graph.Snapshot, error) {
+	return &fakeSnapshot{b: b}, nil
+}
+
+func (b *fakeBackend) closedCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+func TestBeginAcquireRelease(t *testing.T) {
+	b := &fakeBackend{}
+	m := NewManager(b, time.Minute, time.Hour, 2)
+
+	info, err := m.Begin(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if info.Consistency != graph.SnapshotIsolated || info.Expires.Sub(info.Created) != time.Minute {
+		t.Errorf("Begin() = %+v", info)
+	}
+	if _, err := m.Begin(context.Background(), 0); err != nil {
+		t.Fatalf("second Begin() error = %v", err)
+	}
+	if _, err := m.Begin(context.Background(), 0); !errors.Is(err, ErrLimit) {
+		t.Errorf("third Begin() error = %v, want ErrLimit", err)
+	}
+
+	_, release, err := m.Acquire(info.Token)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if err := m.Release(info.Token); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if _, _, err := m.Acquire(info.Token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Acquire() after Release() error = %v, want ErrNotFound", err)
+	}
+	if b.closedCount() != 0 {
+		t.Error("snapshot closed while a request was using it")
+	}
+	release()
+	release() // Releasing twice is harmless
+	if b.closedCount() != 1 {
+		t.Errorf("closed %d snapshots after the last request finished, want 1", b.closedCount())
+	}
+
+	// The slot is free again
+	if _, err := m.Begin(context.Background(), 0); err != nil {
+		t.Errorf("Begin() after Release() error = %v", err)
+	}
+	if got := len(m.List()); got != 2 {
+		t.Errorf("List() has %d snapshots, want 2", got)
+	}
+	m.Close()
+	if b.closedCount() != 3 || len(m.List()) != 0 {
+		t.Errorf("after Close(): %d closed, %d listed", b.closedCount(), len(m.List()))
+	}
+}
+
+func TestSnapshotsExpire(t *testing.T) {
+	b := &fakeBackend{}
+	m := NewManager(b, time.Minute, 20*time.Millisecond, 4)
+
+	info, err := m.Begin(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if ttl := info.Expires.Sub(info.Created); ttl != 20*time.Millisecond {
+		t.Errorf("lifetime = %s, want it cut to the 20ms maximum", ttl)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for b.closedCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if b.closedCount() != 1 {
+		t.Fatal("snapshot was not closed when it expired")
+	}
+	if _, err := m.Get(info.Token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of an expired snapshot error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,150 @@
+package subscriptions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NotificationTemplate customizes the text of email and Slack notifications.
+// Both fields are Go text/template strings rendered against the
+// Notification, e.g. "New {{.Event.NodeType}}: {{.Event.NodeID}}" or
+// "Amount: {{index .Event.Meta \"amount\"}}".
+type NotificationTemplate struct {
+	Subject string `json:"subject,omitempty"` // Email subject
+	Body    string `json:"body,omitempty"`    // Email body and Slack message text
+}
+
+// Default templates used when a subscription does not set its own
+const (
+	defaultSubjectTemplate = `[memex] {{.SubscriptionName}}: {{.Event.Type}}{{with .Event.NodeID}} {{.}}{{end}}`
+	defaultBodyTemplate    = `Subscription "{{.SubscriptionName}}" matched {{.Event.Type}} at {{.MatchedAt.Format "2006-01-02 15:04:05 MST"}}
+{{- with .Rate}}
+{{.Changes}} changes within {{.Window}} around {{.NodeID}} ({{.Depth}} hops) since {{.Since.Format "2006-01-02 15:04:05 MST"}}{{end}}
+{{- with .Event.NodeID}}
+Node: {{.}}{{end}}
+{{- with .Event.NodeType}}
+Type: {{.}}{{end}}
+{{- if .Event.LinkType}}
+Link: {{.Event.LinkSource}} -[{{.Event.LinkType}}]-> {{.Event.LinkTarget}}{{end}}
+{{- range $key, $value := .Event.Meta}}
+{{$key}}: {{$value}}{{end}}`
+)
+
+// SMTPConfig configures outgoing email notifications
+type SMTPConfig struct {
+	Addr     string // host:port of the SMTP server
+	Username string // Optional; enables PLAIN auth
+	Password string
+	From     string
+}
+
+// Validate checks that a template parses
+func (t *NotificationTemplate) Validate() error {
+	if t == nil {
+		return nil
+	}
+	if _, err := template.New("subject").Parse(t.Subject); err != nil {
+		return fmt.Errorf("invalid subject template: %w", err)
+	}
+	if _, err := template.New("body").Parse(t.Body); err != nil {
+		return fmt.Errorf("invalid body template: %w", err)
+	}
+	return nil
+}
+
+// Render returns the subject and body for a notification, falling back to
+// the default templates for fields the subscription leaves empty
+func (t *NotificationTemplate) Render(notification Notification) (subject, body string, err error) {
+	subjectTmpl, bodyTmpl := defaultSubjectTemplate, defaultBodyTemplate
+	if t != nil && t.Subject != "" {
+		subjectTmpl = t.Subject
+	}
+	if t != nil && t.Body != "" {
+		bodyTmpl = t.Body
+	}
+	if subject, err = render(subjectTmpl, notification); err != nil {
+		return "", "", fmt.Errorf("rendering subject: %w", err)
+	}
+	if body, err = render(bodyTmpl, notification); err != nil {
+		return "", "", fmt.Errorf("rendering body: %w", err)
+	}
+	// Headers cannot span lines
+	subject = strings.Join(strings.Fields(subject), " ")
+	return subject, body, nil
+}
+
+func render(text string, notification Notification) (string, error) {
+	tmpl, err := template.New("notification").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, notification); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SetSMTP enables email notifications
+func (n *Notifier) SetSMTP(cfg SMTPConfig) {
+	n.smtp = &cfg
+}
+
+// EmailEnabled reports whether SMTP is configured
+func (n *Notifier) EmailEnabled() bool {
+	return n.smtp != nil
+}
+
+// SendEmail sends a notification to the given recipients via SMTP
+func (n *Notifier) SendEmail(to []string, tmpl *NotificationTemplate, notification Notification) error {
+	if n.smtp == nil {
+		return fmt.Errorf("email notifications require SMTP configuration")
+	}
+	subject, body, err := tmpl.Render(notification)
+	if err != nil {
+		log.Printf("Email notification for subscription %s: %v", notification.SubscriptionID, err)
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.smtp.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(&msg, "X-Memex-Subscription: %s\r\n\r\n", notification.SubscriptionID)
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if n.smtp.Username != "" {
+		host, _, _ := strings.Cut(n.smtp.Addr, ":")
+		auth = smtp.PlainAuth("", n.smtp.Username, n.smtp.Password, host)
+	}
+	if err := n.sendMail(n.smtp.Addr, auth, n.smtp.From, to, msg.Bytes()); err != nil {
+		log.Printf("Email delivery failed for subscription %s: %v", notification.SubscriptionID, err)
+		return err
+	}
+	log.Printf("Email notification sent to %d recipients", len(to))
+	return nil
+}
+
+// SendSlack posts a notification to a Slack incoming webhook
+func (n *Notifier) SendSlack(url string, tmpl *NotificationTemplate, notification Notification) error {
+	_, body, err := tmpl.Render(notification)
+	if err != nil {
+		log.Printf("Slack notification for subscription %s: %v", notification.SubscriptionID, err)
+		return err
+	}
+	payload, err := json.Marshal(map[string]string{"text": body})
+	if err != nil {
+		return err
+	}
+	return n.post(url, payload, nil)
+}
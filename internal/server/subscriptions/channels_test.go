@@ -0,0 +1,95 @@
+package subscriptions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testNotification() Notification {
+	return Notification{
+		SubscriptionID:   "sub-1",
+		SubscriptionName: "New invoices",
+		Event: Event{
+			Type:     EventNodeCreated,
+			NodeID:   "invoice:42",
+			NodeType: "Invoice",
+			Meta:     map[string]interface{}{"amount": 120.5},
+		},
+		MatchedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	n := testNotification()
+
+	subject, body, err := (*NotificationTemplate)(nil).Render(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "[memex] New invoices: node.created invoice:42" {
+		t.Errorf("default subject = %q", subject)
+	}
+	for _, want := range []string{"Node: invoice:42", "Type: Invoice", "amount: 120.5"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("default body missing %q:\n%s", want, body)
+		}
+	}
+
+	tmpl := &NotificationTemplate{Body: `{{.Event.NodeType}} {{.Event.NodeID}} for {{index .Event.Meta "amount"}}`}
+	subject, body, err = tmpl.Render(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "Invoice invoice:42 for 120.5" {
+		t.Errorf("custom body = %q", body)
+	}
+	if !strings.HasPrefix(subject, "[memex]") {
+		t.Errorf("empty subject should fall back to the default, got %q", subject)
+	}
+
+	if err := (&NotificationTemplate{Subject: "{{.Event"}).Validate(); err == nil {
+		t.Error("expected invalid template error")
+	}
+}
+
+func TestSendSlackAndEmail(t *testing.T) {
+	var text string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		text = payload["text"]
+	}))
+	defer slack.Close()
+
+	n := NewNotifier()
+	tmpl := &NotificationTemplate{Subject: "New {{.Event.NodeType}}", Body: "See {{.Event.NodeID}}"}
+	if err := n.SendSlack(slack.URL, tmpl, testNotification()); err != nil {
+		t.Fatal(err)
+	}
+	if text != "See invoice:42" {
+		t.Errorf("slack text = %q", text)
+	}
+
+	if err := n.SendEmail([]string{"ops@example.com"}, tmpl, testNotification()); err == nil {
+		t.Error("expected error without SMTP configuration")
+	}
+
+	var sentTo []string
+	var sent string
+	n.SetSMTP(SMTPConfig{Addr: "mail.example.com:587", From: "memex@example.com"})
+	n.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sentTo, sent = to, string(msg)
+		return nil
+	}
+	if err := n.SendEmail([]string{"ops@example.com"}, tmpl, testNotification()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sentTo) != 1 || !strings.Contains(sent, "Subject: New Invoice\r\n") || !strings.HasSuffix(sent, "See invoice:42") {
+		t.Errorf("unexpected email to %v:\n%s", sentTo, sent)
+	}
+}
@@ -0,0 +1,240 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery channels that can dead-letter
+const (
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+	ChannelSlack   = "slack"
+)
+
+// maxDeadLetters bounds the dead-letter list; the oldest entries are dropped
+const maxDeadLetters = 1000
+
+// ErrDeadLetterNotFound is returned for unknown dead-letter IDs
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is a notification whose delivery failed after all retries.
+// Dead letters are kept in memory; events missed across a restart can be
+// recovered with Replay.
+type DeadLetter struct {
+	ID             string       `json:"id"`
+	SubscriptionID string       `json:"subscription_id"`
+	Channel        string       `json:"channel"`
+	Target         string       `json:"target"` // URL, or comma-separated email recipients
+	Notification   Notification `json:"notification"`
+	Error          string       `json:"error"`
+	Attempts       int          `json:"attempts"` // Delivery rounds, each with its own retries
+	FailedAt       time.Time    `json:"failed_at"`
+}
+
+// ReplayResult summarizes a replay request
+type ReplayResult struct {
+	SubscriptionID string    `json:"subscription_id"`
+	Since          time.Time `json:"since"`
+	Scanned        int       `json:"scanned"`         // Changelog events read
+	Matched        int       `json:"matched"`         // Events queued for re-delivery
+	Until          time.Time `json:"until,omitempty"` // Timestamp of the last event read
+	Truncated      bool      `json:"truncated"`       // The limit was reached; replay again from Until
+}
+
+// Replay re-delivers past events matching a subscription, read from the
+// graph's changelog. Matching is done before returning; delivery happens
+// in order in the background, with failures dead-lettered as usual.
+func (m *Manager) Replay(ctx context.Context, id string, since time.Time, limit int) (*ReplayResult, error) {
+	sub, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Pattern.Region != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotReplayable, id)
+	}
+
+	events, err := m.repo.ChangeLog(ctx, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("reading changelog: %w", err)
+	}
+
+	result := &ReplayResult{SubscriptionID: id, Since: since, Scanned: len(events), Truncated: limit > 0 && len(events) >= limit}
+	var notifications []Notification
+	for _, event := range events {
+		result.Until = event.Timestamp
+		matched, results := m.matcher.Match(ctx, event, sub.Pattern)
+		if !matched {
+			continue
+		}
+		notifications = append(notifications, Notification{
+			SubscriptionID:   sub.ID,
+			SubscriptionName: sub.Name,
+			Event:            event,
+			MatchedAt:        time.Now(),
+			QueryResults:     results,
+			Replayed:         true,
+		})
+	}
+	result.Matched = len(notifications)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for _, n := range notifications {
+			if m.ctx.Err() != nil {
+				return
+			}
+			m.deliver(sub, n, true)
+		}
+		log.Printf("Replayed %d events to subscription %s", len(notifications), sub.ID)
+	}()
+
+	return result, nil
+}
+
+// deliver sends a notification on every channel of a subscription. With
+// wait, channels are sent to in turn before returning, preserving order.
+func (m *Manager) deliver(sub *Subscription, notification Notification, wait bool) {
+	run := func(channel, target string) {
+		if err := m.send(channel, target, sub.Template, notification); err != nil {
+			m.addDeadLetter(sub.ID, channel, target, notification, err)
+		}
+	}
+	dispatch := func(f func()) {
+		if wait {
+			f()
+		} else {
+			m.pending.Add(1)
+			go func() {
+				defer m.pending.Done()
+				f()
+			}()
+		}
+	}
+
+	if sub.Webhook != "" {
+		dispatch(func() { run(ChannelWebhook, sub.Webhook) })
+	}
+	if sub.WebSocket {
+		dispatch(func() { m.notifier.SendWebSocket(sub.ID, notification) })
+	}
+	if len(sub.Email) > 0 {
+		dispatch(func() { run(ChannelEmail, strings.Join(sub.Email, ",")) })
+	}
+	if sub.Slack != "" {
+		dispatch(func() { run(ChannelSlack, sub.Slack) })
+	}
+}
+
+func (m *Manager) send(channel, target string, tmpl *NotificationTemplate, notification Notification) error {
+	switch channel {
+	case ChannelWebhook:
+		return m.notifier.SendWebhook(target, notification)
+	case ChannelEmail:
+		return m.notifier.SendEmail(strings.Split(target, ","), tmpl, notification)
+	case ChannelSlack:
+		return m.notifier.SendSlack(target, tmpl, notification)
+	}
+	return fmt.Errorf("unknown channel %q", channel)
+}
+
+func (m *Manager) addDeadLetter(subID, channel, target string, notification Notification, err error) {
+	m.dlMu.Lock()
+	defer m.dlMu.Unlock()
+
+	m.deadLetters = append(m.deadLetters, &DeadLetter{
+		ID:             uuid.New().String(),
+		SubscriptionID: subID,
+		Channel:        channel,
+		Target:         target,
+		Notification:   notification,
+		Error:          err.Error(),
+		Attempts:       1,
+		FailedAt:       time.Now(),
+	})
+	if len(m.deadLetters) > maxDeadLetters {
+		m.deadLetters = m.deadLetters[len(m.deadLetters)-maxDeadLetters:]
+	}
+	log.Printf("Dead-lettered %s notification for subscription %s: %v", channel, subID, err)
+}
+
+// DeadLetters lists failed deliveries, oldest first, optionally for one subscription
+func (m *Manager) DeadLetters(subID string) []*DeadLetter {
+	m.dlMu.Lock()
+	defer m.dlMu.Unlock()
+
+	result := make([]*DeadLetter, 0, len(m.deadLetters))
+	for _, dl := range m.deadLetters {
+		if subID == "" || dl.SubscriptionID == subID {
+			copied := *dl
+			result = append(result, &copied)
+		}
+	}
+	return result
+}
+
+// RetryDeadLetter re-sends a dead letter, removing it on success
+func (m *Manager) RetryDeadLetter(id string) (*DeadLetter, error) {
+	m.dlMu.Lock()
+	var dl *DeadLetter
+	for _, d := range m.deadLetters {
+		if d.ID == id {
+			dl = d
+			break
+		}
+	}
+	if dl == nil {
+		m.dlMu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+	channel, target, notification := dl.Channel, dl.Target, dl.Notification
+	m.dlMu.Unlock()
+
+	// Render with the subscription's current template, if it still exists
+	var tmpl *NotificationTemplate
+	if sub, err := m.Get(dl.SubscriptionID); err == nil {
+		tmpl = sub.Template
+	}
+	err := m.send(channel, target, tmpl, notification)
+
+	m.dlMu.Lock()
+	defer m.dlMu.Unlock()
+	if err != nil {
+		dl.Attempts++
+		dl.Error = err.Error()
+		dl.FailedAt = time.Now()
+		copied := *dl
+		return &copied, err
+	}
+	m.removeDeadLetter(id)
+	copied := *dl
+	return &copied, nil
+}
+
+// DeleteDeadLetter discards a dead letter
+func (m *Manager) DeleteDeadLetter(id string) error {
+	m.dlMu.Lock()
+	defer m.dlMu.Unlock()
+	if !m.removeDeadLetter(id) {
+		return fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+	return nil
+}
+
+// removeDeadLetter removes an entry; callers hold dlMu
+func (m *Manager) removeDeadLetter(id string) bool {
+	for i, dl := range m.deadLetters {
+		if dl.ID == id {
+			m.deadLetters = append(m.deadLetters[:i], m.deadLetters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package subscriptions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// changeLogRepo serves a fixed changelog
+type changeLogRepo struct {
+	Repository
+	events []Event
+}
+
+func (r *changeLogRepo) ChangeLog(ctx context.Context, since time.Time, limit int) ([]Event, error) {
+	var out []Event
+	for _, e := range r.events {
+		if e.Timestamp.After(since) && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestReplayAndDeadLetters(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	var replayed atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("X-Memex-Replay") == "true" {
+			replayed.Add(1)
+		}
+	}))
+	defer hook.Close()
+
+	base := time.Now().Add(-time.Hour)
+	repo := &changeLogRepo{events: []Event{
+		{ID: "1", Type: EventNodeCreated, NodeID: "invoice:1", NodeType: "Invoice", Timestamp: base.Add(time.Minute)},
+		{ID: "2", Type: EventNodeCreated, NodeID: "note:1", NodeType: "Note", Timestamp: base.Add(2 * time.Minute)},
+		{ID: "3", Type: EventNodeCreated, NodeID: "invoice:2", NodeType: "Invoice", Timestamp: base.Add(3 * time.Minute)},
+	}}
+	m := NewManager(repo)
+	m.notifier.backoff = time.Millisecond
+	sub := &Subscription{ID: "sub-1", Name: "invoices", Enabled: true, Webhook: hook.URL,
+		Pattern: SubscriptionPattern{NodeTypes: []string{"Invoice"}}}
+	m.subscriptions[sub.ID] = sub
+
+	// A delivery that exhausts its retries is dead-lettered
+	m.deliver(sub, Notification{SubscriptionID: sub.ID, Event: repo.events[0]}, true)
+	dead := m.DeadLetters("")
+	if len(dead) != 1 || dead[0].Channel != ChannelWebhook || dead[0].Error == "" {
+		t.Fatalf("expected one webhook dead letter, got %+v", dead)
+	}
+	if _, err := m.RetryDeadLetter(dead[0].ID); err == nil {
+		t.Error("retry against a failing endpoint should fail")
+	}
+	if got := m.DeadLetters(sub.ID); len(got) != 1 || got[0].Attempts != 2 {
+		t.Errorf("expected the dead letter to be kept with 2 attempts, got %+v", got)
+	}
+
+	failing.Store(false)
+	if _, err := m.RetryDeadLetter(dead[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.DeadLetters(""); len(got) != 0 {
+		t.Errorf("delivered dead letter should be removed, got %+v", got)
+	}
+
+	// Replay re-delivers only matching events after since
+	result, err := m.Replay(context.Background(), sub.ID, base.Add(90*time.Second), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Scanned != 2 || result.Matched != 1 || result.Truncated {
+		t.Errorf("unexpected replay result %+v", result)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for replayed.Load() < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.cancel()
+	m.wg.Wait()
+	if replayed.Load() != 1 {
+		t.Errorf("expected 1 replayed delivery, got %d", replayed.Load())
+	}
+
+	if _, err := m.Replay(context.Background(), "missing", base, 100); err == nil {
+		t.Error("expected error for unknown subscription")
+	}
+}
+
+// loadedRepo serves a fixed set of subscriptions
+type loadedRepo struct {
+	Repository
+	subs []*Subscription
+}
+
+func (r *loadedRepo) LoadSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	return r.subs, nil
+}
+
+func TestStopDeliversQueuedEvents(t *testing.T) {
+	var delivered atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		delivered.Add(1)
+	}))
+	defer hook.Close()
+
+	m := NewManager(&loadedRepo{subs: []*Subscription{{ID: "sub-1", Enabled: true, Webhook: hook.URL,
+		Pattern: SubscriptionPattern{NodeTypes: []string{"Invoice"}}}}})
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"invoice:1", "invoice:2", "invoice:3"} {
+		m.EmitEvent(Event{ID: id, Type: EventNodeCreated, NodeID: id, NodeType: "Invoice", Timestamp: time.Now()})
+	}
+	m.Stop()
+	if got := delivered.Load(); got != 3 {
+		t.Errorf("delivered %d of 3 queued notifications before Stop returned", got)
+	}
+}
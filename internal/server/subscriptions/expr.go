@@ -0,0 +1,845 @@
+package subscriptions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subscription expressions are a small CEL-like language evaluated against
+// the event payload, e.g.
+//
+//	node_type == "Invoice" && meta.amount >= 1000 && !(meta.status in ["paid", "void"])
+//
+// Variables: id, type, timestamp, node_id, node_type, link_source,
+// link_target, link_type and meta (also reachable as event.<name>). Missing
+// fields evaluate to null rather than failing, so has(meta.x) and
+// meta.x == null both test for absence. Operators: == != < <= > >= in && ||
+// ! and parentheses; functions: has(x), size(x), and the methods
+// contains, startsWith, endsWith, matches (literal regexp) and size.
+
+// exprVariables are the names an expression may reference
+var exprVariables = map[string]bool{
+	"event": true, "id": true, "type": true, "timestamp": true,
+	"node_id": true, "node_type": true,
+	"link_source": true, "link_target": true, "link_type": true,
+	"meta": true,
+}
+
+// ExpressionError indicates an expression failed to parse
+type ExpressionError struct {
+	Pos     int
+	Message string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("expression error at %d: %s", e.Pos, e.Message)
+}
+
+// Expression is a compiled subscription expression
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// CompileExpression parses an expression, checking variables, functions and
+// regular expressions
+func CompileExpression(source string) (*Expression, error) {
+	toks, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &ExpressionError{Pos: t.pos, Message: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression against an event; it must yield a bool
+func (e *Expression) Eval(event Event) (bool, error) {
+	v, err := e.root.eval(eventEnv(event))
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q yields %s, not a bool", e.source, typeName(v))
+	}
+	return b, nil
+}
+
+// eventEnv exposes an event's fields to expressions. For node.updated
+// events, meta also includes the changed properties from
+// meta.updated_meta, so meta.status matches updates that set status.
+func eventEnv(event Event) map[string]interface{} {
+	meta := make(map[string]interface{}, len(event.Meta))
+	if event.Type == EventNodeUpdated {
+		if updated, ok := event.Meta["updated_meta"].(map[string]interface{}); ok {
+			for k, v := range updated {
+				meta[k] = v
+			}
+		}
+	}
+	for k, v := range event.Meta {
+		meta[k] = v
+	}
+
+	env := map[string]interface{}{
+		"id":          event.ID,
+		"type":        event.Type,
+		"timestamp":   event.Timestamp.Format(time.RFC3339),
+		"node_id":     event.NodeID,
+		"node_type":   event.NodeType,
+		"link_source": event.LinkSource,
+		"link_target": event.LinkTarget,
+		"link_type":   event.LinkType,
+		"meta":        meta,
+	}
+	scoped := make(map[string]interface{}, len(env))
+	for k, v := range env {
+		scoped[k] = v
+	}
+	env["event"] = scoped
+	return env
+}
+
+// ============== Lexer ==============
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[start:i], pos: start})
+		case isDigit(c):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				((src[i] == '-' || src[i] == '+') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, &ExpressionError{Pos: start, Message: fmt.Sprintf("invalid number %q", src[start:i])}
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[start:i], num: n, pos: start})
+		case c == '"' || c == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, &ExpressionError{Pos: i, Message: err.Error()}
+			}
+			toks = append(toks, token{kind: tokString, text: s, pos: i})
+			i += n
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", ".", "-"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &ExpressionError{Pos: i, Message: fmt.Sprintf("unexpected character %q", c)}
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// lexString reads a quoted string literal, returning its value and length
+func lexString(src string) (string, int, error) {
+	quote := src[0]
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if quote == '"' {
+				// Double-quoted strings use Go/JSON escapes
+				end := i + 1
+				for end < len(src) && src[end] != '"' {
+					if src[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				if end >= len(src) {
+					return "", 0, fmt.Errorf("unterminated string")
+				}
+				s, err := strconv.Unquote(src[:end+1])
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid string literal %s", src[:end+1])
+				}
+				return s, end + 1, nil
+			}
+			if i+1 >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// ============== Parser ==============
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return &ExpressionError{Pos: t.pos, Message: fmt.Sprintf("expected %q", op)}
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (exprNode, error) {
+	left, err := p.parseRelation()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseRelation()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseRelation() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	op := ""
+	switch {
+	case t.kind == tokOp && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		op = t.text
+	case t.kind == tokIdent && t.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryExpr{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseUnary() (exprNode, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "!", operand: operand}, nil
+	}
+	if p.accept("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "-", operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, &ExpressionError{Pos: t.pos, Message: "expected field name after '.'"}
+			}
+			if p.accept("(") {
+				args, err := p.parseArgs(")")
+				if err != nil {
+					return nil, err
+				}
+				call, err := newCall(t, node, args)
+				if err != nil {
+					return nil, err
+				}
+				node = call
+				continue
+			}
+			node = &selectExpr{operand: node, field: t.text}
+		case p.accept("["):
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &indexExpr{operand: node, index: index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return &literal{value: t.num}, nil
+	case tokString:
+		return &literal{value: t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		}
+		if p.accept("(") {
+			args, err := p.parseArgs(")")
+			if err != nil {
+				return nil, err
+			}
+			return newCall(t, nil, args)
+		}
+		if !exprVariables[t.text] {
+			return nil, &ExpressionError{Pos: t.pos, Message: fmt.Sprintf("unknown variable %q", t.text)}
+		}
+		return &ident{name: t.text}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			items, err := p.parseArgs("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listExpr{items: items}, nil
+		}
+	case tokEOF:
+		return nil, &ExpressionError{Pos: t.pos, Message: "unexpected end of expression"}
+	}
+	return nil, &ExpressionError{Pos: t.pos, Message: fmt.Sprintf("unexpected %q", t.text)}
+}
+
+// parseArgs parses a comma-separated list up to the closing token
+func (p *parser) parseArgs(closing string) ([]exprNode, error) {
+	var args []exprNode
+	if p.accept(closing) {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newCall validates a function (target == nil) or method call
+func newCall(name token, target exprNode, args []exprNode) (*callExpr, error) {
+	call := &callExpr{fn: name.text, target: target, args: args}
+	arity := -1
+	if target == nil {
+		switch name.text {
+		case "has", "size":
+			arity = 1
+		}
+	} else {
+		switch name.text {
+		case "contains", "startsWith", "endsWith", "matches":
+			arity = 1
+		case "size":
+			arity = 0
+		}
+	}
+	if arity < 0 {
+		return nil, &ExpressionError{Pos: name.pos, Message: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	if len(args) != arity {
+		return nil, &ExpressionError{Pos: name.pos, Message: fmt.Sprintf("%s takes %d argument(s)", name.text, arity)}
+	}
+	if name.text == "matches" {
+		var s string
+		isString := false
+		if pattern, ok := args[0].(*literal); ok {
+			s, isString = pattern.value.(string)
+		}
+		if !isString {
+			return nil, &ExpressionError{Pos: name.pos, Message: "matches requires a string literal"}
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, &ExpressionError{Pos: name.pos, Message: fmt.Sprintf("invalid regexp: %v", err)}
+		}
+		call.re = re
+	}
+	return call, nil
+}
+
+// ============== Evaluation ==============
+
+type exprNode interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type literal struct{ value interface{} }
+
+func (l *literal) eval(map[string]interface{}) (interface{}, error) { return l.value, nil }
+
+type ident struct{ name string }
+
+func (i *ident) eval(env map[string]interface{}) (interface{}, error) { return env[i.name], nil }
+
+type selectExpr struct {
+	operand exprNode
+	field   string
+}
+
+func (s *selectExpr) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := s.operand.eval(env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot select %q from %s", s.field, typeName(v))
+	}
+	return m[s.field], nil
+}
+
+type indexExpr struct{ operand, index exprNode }
+
+func (x *indexExpr) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := x.operand.eval(env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	index, err := x.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("map index must be a string, got %s", typeName(index))
+		}
+		return m[key], nil
+	}
+	if list, ok := asList(v); ok {
+		n, ok := toFloat64(index)
+		if !ok || n != float64(int(n)) {
+			return nil, fmt.Errorf("list index must be an integer, got %s", typeName(index))
+		}
+		if int(n) < 0 || int(n) >= len(list) {
+			return nil, nil
+		}
+		return list[int(n)], nil
+	}
+	return nil, fmt.Errorf("cannot index %s", typeName(v))
+}
+
+type listExpr struct{ items []exprNode }
+
+func (l *listExpr) eval(env map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, len(l.items))
+	for i, item := range l.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+type unaryExpr struct {
+	op      string
+	operand exprNode
+}
+
+func (u *unaryExpr) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := u.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if u.op == "!" {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("! requires a bool, got %s", typeName(v))
+		}
+		return !b, nil
+	}
+	n, ok := toFloat64(v)
+	if !ok {
+		return nil, fmt.Errorf("- requires a number, got %s", typeName(v))
+	}
+	return -n, nil
+}
+
+type binaryExpr struct {
+	op          string
+	left, right exprNode
+}
+
+func (b *binaryExpr) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := b.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.op == "&&" || b.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s requires bools, got %s", b.op, typeName(left))
+		}
+		if (b.op == "&&" && !l) || (b.op == "||" && l) {
+			return l, nil
+		}
+		right, err := b.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s requires bools, got %s", b.op, typeName(right))
+		}
+		return r, nil
+	}
+
+	right, err := b.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch b.op {
+	case "==":
+		return equalValues(left, right), nil
+	case "!=":
+		return !equalValues(left, right), nil
+	case "in":
+		if list, ok := asList(right); ok {
+			for _, item := range list {
+				if equalValues(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		if m, ok := right.(map[string]interface{}); ok {
+			key, ok := left.(string)
+			if !ok {
+				return false, nil
+			}
+			_, found := m[key]
+			return found, nil
+		}
+		if right == nil {
+			return false, nil
+		}
+		return nil, fmt.Errorf("in requires a list or map, got %s", typeName(right))
+	}
+
+	// Ordering; comparisons involving a missing field are false
+	if left == nil || right == nil {
+		return false, nil
+	}
+	cmp, err := compareValues(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch b.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type callExpr struct {
+	fn     string
+	target exprNode // nil for global functions
+	args   []exprNode
+	re     *regexp.Regexp // Compiled argument of matches
+}
+
+func (c *callExpr) eval(env map[string]interface{}) (interface{}, error) {
+	var subject interface{}
+	var err error
+	if c.target != nil {
+		subject, err = c.target.eval(env)
+	} else {
+		subject, err = c.args[0].eval(env)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.fn {
+	case "has":
+		return subject != nil, nil
+	case "size":
+		switch v := subject.(type) {
+		case nil:
+			return float64(0), nil
+		case string:
+			return float64(len([]rune(v))), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		}
+		if list, ok := asList(subject); ok {
+			return float64(len(list)), nil
+		}
+		return nil, fmt.Errorf("size of %s", typeName(subject))
+	}
+
+	// String (and list) methods; a missing target never matches
+	if subject == nil {
+		return false, nil
+	}
+	if c.fn == "contains" {
+		if list, ok := asList(subject); ok {
+			arg, err := c.args[0].eval(env)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range list {
+				if equalValues(item, arg) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	}
+	s, ok := subject.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s requires a string, got %s", c.fn, typeName(subject))
+	}
+	if c.fn == "matches" {
+		return c.re.MatchString(s), nil
+	}
+	argVal, err := c.args[0].eval(env)
+	if err != nil {
+		return nil, err
+	}
+	arg, ok := argVal.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s requires a string argument, got %s", c.fn, typeName(argVal))
+	}
+	switch c.fn {
+	case "contains":
+		return strings.Contains(s, arg), nil
+	case "startsWith":
+		return strings.HasPrefix(s, arg), nil
+	default:
+		return strings.HasSuffix(s, arg), nil
+	}
+}
+
+// equalValues compares values exactly, treating all numeric types alike
+func equalValues(a, b interface{}) bool {
+	if an, ok := toFloat64(a); ok {
+		bn, ok := toFloat64(b)
+		return ok && an == bn
+	}
+	switch av := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	}
+	if al, ok := asList(a); ok {
+		bl, ok := asList(b)
+		if !ok || len(al) != len(bl) {
+			return false
+		}
+		for i := range al {
+			if !equalValues(al[i], bl[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compareValues orders two numbers or two strings
+func compareValues(a, b interface{}) (int, error) {
+	if an, ok := toFloat64(a); ok {
+		if bn, ok := toFloat64(b); ok {
+			switch {
+			case an < bn:
+				return -1, nil
+			case an > bn:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			return strings.Compare(as, bs), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+}
+
+func asList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, true
+	case []string:
+		items := make([]interface{}, len(l))
+		for i, s := range l {
+			items[i] = s
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case map[string]interface{}:
+		return "map"
+	}
+	if _, ok := toFloat64(v); ok {
+		return "number"
+	}
+	if _, ok := asList(v); ok {
+		return "list"
+	}
+	return fmt.Sprintf("%T", v)
+}
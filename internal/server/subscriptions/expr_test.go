@@ -0,0 +1,77 @@
+package subscriptions
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPatternConditions(t *testing.T) {
+	created := Event{
+		Type:     EventNodeCreated,
+		NodeID:   "invoice:42",
+		NodeType: "Invoice",
+		Meta:     map[string]interface{}{"status": "pending", "amount": 1200.0, "tags": []interface{}{"urgent"}},
+	}
+	updated := Event{
+		Type:     EventNodeUpdated,
+		NodeID:   "invoice:42",
+		NodeType: "Invoice",
+		Meta:     map[string]interface{}{"version": "v2", "updated_meta": map[string]interface{}{"status": "paid"}},
+	}
+	link := Event{Type: EventLinkCreated, LinkSource: "invoice:42", LinkTarget: "org:acme", LinkType: "BILLED_TO"}
+
+	tests := []struct {
+		name    string
+		pattern SubscriptionPattern
+		event   Event
+		want    bool
+	}{
+		{"predicate match", SubscriptionPattern{Where: []Predicate{{Field: "meta.status", Op: "==", Value: "pending"}}}, created, true},
+		{"predicate mismatch", SubscriptionPattern{Where: []Predicate{{Field: "meta.status", Op: "==", Value: "paid"}}}, created, false},
+		{"updated properties", SubscriptionPattern{Where: []Predicate{{Field: "meta.status", Op: "==", Value: "paid"}}}, updated, true},
+		{"numeric range", SubscriptionPattern{Where: []Predicate{{Field: "meta.amount", Op: ">=", Value: 1000}}}, created, true},
+		{"not in", SubscriptionPattern{Where: []Predicate{{Field: "meta.status", Op: "not_in", Value: []string{"paid", "void"}}}}, created, true},
+		{"missing field", SubscriptionPattern{Where: []Predicate{{Field: "meta.due", Op: "<", Value: "2026-01-01"}}}, created, false},
+		{"exists", SubscriptionPattern{Where: []Predicate{{Field: "meta.due", Op: "not_exists"}}}, created, true},
+		{"link predicate", SubscriptionPattern{Where: []Predicate{{Field: "link_target", Op: "starts_with", Value: "org:"}}}, link, true},
+		{"expression", SubscriptionPattern{Expr: `node_type == "Invoice" && meta.amount > 1000 && "urgent" in meta.tags`}, created, true},
+		{"expression methods", SubscriptionPattern{Expr: `event.node_id.startsWith("invoice:") && meta.status.matches("^pend")`}, created, true},
+		{"expression or", SubscriptionPattern{Expr: `type == "link.created" && (link_type == "PAID_BY" || link_type == "BILLED_TO")`}, link, true},
+		{"expression and predicates", SubscriptionPattern{Where: []Predicate{{Field: "node_type", Op: "==", Value: "Invoice"}}, Expr: `!has(meta.status)`}, created, false},
+	}
+
+	m := NewMatcher(nil)
+	for _, tt := range tests {
+		if err := tt.pattern.Validate(); err != nil {
+			t.Errorf("%s: validate: %v", tt.name, err)
+			continue
+		}
+		if got, _ := m.Match(context.Background(), tt.event, tt.pattern); got != tt.want {
+			t.Errorf("%s: matched = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPatternValidation(t *testing.T) {
+	invalid := []SubscriptionPattern{
+		{Expr: `meta.status ==`},
+		{Expr: `nodetype == "Invoice"`},
+		{Expr: `meta.name.matches("(")`},
+		{Expr: `lower(meta.name) == "x"`},
+		{Where: []Predicate{{Field: "status", Op: "==", Value: "x"}}},
+		{Where: []Predicate{{Field: "meta.status", Op: "like", Value: "x"}}},
+		{Where: []Predicate{{Field: "meta.status", Op: "in", Value: "x"}}},
+		{Cypher: "MATCH (n) DETACH DELETE n"},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", p)
+		}
+	}
+
+	// Runtime type errors do not match
+	p := SubscriptionPattern{Expr: `meta.status > 3`}
+	if got, _ := NewMatcher(nil).Match(context.Background(), Event{Meta: map[string]interface{}{"status": "x"}}, p); got {
+		t.Error("type error should not match")
+	}
+}
@@ -3,14 +3,23 @@ package subscriptions
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/mail"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Errors returned by the manager
+var (
+	ErrNotFound = errors.New("subscription not found")
+	ErrExists   = errors.New("subscription already exists")
+)
+
 // EventEmitter is a function that receives events from the repository
 type EventEmitter func(Event)
 
@@ -20,6 +29,7 @@ type Repository interface {
 	UpdateSubscriptionNode(ctx context.Context, sub *Subscription) error
 	DeleteSubscriptionNode(ctx context.Context, id string) error
 	LoadSubscriptions(ctx context.Context) ([]*Subscription, error)
+	ChangeLog(ctx context.Context, since time.Time, limit int) ([]Event, error)
 	ExecuteCypherRead(ctx context.Context, cypher string, params map[string]interface{}) ([]map[string]interface{}, error)
 }
 
@@ -31,9 +41,15 @@ type Manager struct {
 	notifier      *Notifier
 	matcher       *Matcher
 	mu            sync.RWMutex
+	deadLetters   []*DeadLetter
+	dlMu          sync.Mutex
+	live          streams                   // Live event stream listeners
+	regions       map[string]*regionTracker // Subscription ID -> rate alert counts
+	regionMu      sync.Mutex
 	ctx           context.Context
 	cancel        context.CancelFunc
-	wg            sync.WaitGroup
+	pending       sync.WaitGroup // Queued events and the notifications they fire
+	wg            sync.WaitGroup // Replays, cancelled on Stop
 }
 
 // NewManager creates a new subscription manager
@@ -43,6 +59,7 @@ func NewManager(repo Repository) *Manager {
 		repo:          repo,
 		subscriptions: make(map[string]*Subscription),
 		eventChan:     make(chan Event, 1000), // Buffered to avoid blocking writes
+		regions:       make(map[string]*regionTracker),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
@@ -59,17 +76,20 @@ func (m *Manager) Start(ctx context.Context) error {
 	}
 
 	// Start event processing goroutine
-	m.wg.Add(1)
+	m.pending.Add(1)
 	go m.processEvents()
 
 	log.Printf("Subscription manager started with %d subscriptions", len(m.subscriptions))
 	return nil
 }
 
-// Stop gracefully shuts down the manager
+// Stop gracefully shuts down the manager: events already queued are
+// processed and the notifications they fire delivered before replays are
+// cancelled
 func (m *Manager) Stop() {
-	m.cancel()
 	close(m.eventChan)
+	m.pending.Wait()
+	m.cancel()
 	m.wg.Wait()
 	m.notifier.Close()
 	log.Println("Subscription manager stopped")
@@ -99,6 +119,9 @@ func (m *Manager) Register(ctx context.Context, req *CreateSubscriptionRequest)
 		Pattern:     req.Pattern,
 		Webhook:     req.Webhook,
 		WebSocket:   req.WebSocket,
+		Email:       req.Email,
+		Slack:       req.Slack,
+		Template:    req.Template,
 		Enabled:     true,
 		Created:     time.Now(),
 		Modified:    time.Now(),
@@ -109,22 +132,46 @@ func (m *Manager) Register(ctx context.Context, req *CreateSubscriptionRequest)
 	if sub.Name == "" {
 		return nil, fmt.Errorf("subscription name is required")
 	}
-	if sub.Webhook == "" && !sub.WebSocket {
-		return nil, fmt.Errorf("subscription must have webhook URL or websocket enabled")
+	if err := m.validate(sub); err != nil {
+		return nil, err
+	}
+	if err := m.add(ctx, sub); err != nil {
+		return nil, err
 	}
 
-	// Persist to storage
+	log.Printf("Registered subscription: %s (%s)", sub.ID, sub.Name)
+	return sub, nil
+}
+
+// Restore adds a subscription from a backup as it was, keeping its ID,
+// timestamps and fire count
+func (m *Manager) Restore(ctx context.Context, sub *Subscription) error {
+	if sub.ID == "" || sub.Name == "" {
+		return fmt.Errorf("subscription id and name are required")
+	}
+	m.mu.RLock()
+	_, exists := m.subscriptions[sub.ID]
+	m.mu.RUnlock()
+	if exists {
+		return fmt.Errorf("%w: %s", ErrExists, sub.ID)
+	}
+	if err := m.validate(sub); err != nil {
+		return err
+	}
+	return m.add(ctx, sub)
+}
+
+// add persists a validated subscription and starts matching it
+func (m *Manager) add(ctx context.Context, sub *Subscription) error {
 	if err := m.repo.CreateSubscriptionNode(ctx, sub); err != nil {
-		return nil, fmt.Errorf("failed to persist subscription: %w", err)
+		return fmt.Errorf("failed to persist subscription: %w", err)
 	}
 
-	// Add to in-memory cache
+	m.trackRegion(ctx, sub)
 	m.mu.Lock()
 	m.subscriptions[sub.ID] = sub
 	m.mu.Unlock()
-
-	log.Printf("Registered subscription: %s (%s)", sub.ID, sub.Name)
-	return sub, nil
+	return nil
 }
 
 // Unregister removes a subscription
@@ -133,7 +180,7 @@ func (m *Manager) Unregister(ctx context.Context, id string) error {
 	defer m.mu.Unlock()
 
 	if _, exists := m.subscriptions[id]; !exists {
-		return fmt.Errorf("subscription not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 
 	// Remove from storage
@@ -143,6 +190,9 @@ func (m *Manager) Unregister(ctx context.Context, id string) error {
 
 	// Remove from cache
 	delete(m.subscriptions, id)
+	m.regionMu.Lock()
+	delete(m.regions, id)
+	m.regionMu.Unlock()
 
 	// Clean up any WebSocket connections
 	m.notifier.UnregisterWSClient(id)
@@ -156,12 +206,14 @@ func (m *Manager) Update(ctx context.Context, id string, req *UpdateSubscription
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	sub, exists := m.subscriptions[id]
+	current, exists := m.subscriptions[id]
 	if !exists {
-		return nil, fmt.Errorf("subscription not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 
-	// Apply updates
+	// Apply updates to a copy so an invalid update changes nothing
+	updated := *current
+	sub := &updated
 	if req.Name != nil {
 		sub.Name = *req.Name
 	}
@@ -177,19 +229,60 @@ func (m *Manager) Update(ctx context.Context, id string, req *UpdateSubscription
 	if req.WebSocket != nil {
 		sub.WebSocket = *req.WebSocket
 	}
+	if req.Email != nil {
+		sub.Email = *req.Email
+	}
+	if req.Slack != nil {
+		sub.Slack = *req.Slack
+	}
+	if req.Template != nil {
+		sub.Template = req.Template
+	}
 	if req.Enabled != nil {
 		sub.Enabled = *req.Enabled
 	}
 	sub.Modified = time.Now()
 
+	if err := m.validate(sub); err != nil {
+		return nil, err
+	}
+
 	// Persist changes
 	if err := m.repo.UpdateSubscriptionNode(ctx, sub); err != nil {
 		return nil, fmt.Errorf("failed to update subscription: %w", err)
 	}
+	if req.Pattern != nil {
+		m.trackRegion(ctx, sub)
+	}
+	m.subscriptions[id] = sub
 
 	return sub, nil
 }
 
+// SetSMTP enables email notification channels
+func (m *Manager) SetSMTP(cfg SMTPConfig) {
+	m.notifier.SetSMTP(cfg)
+}
+
+// validate checks a subscription's pattern and notification channels
+func (m *Manager) validate(sub *Subscription) error {
+	if err := sub.Pattern.Validate(); err != nil {
+		return err
+	}
+	if sub.Webhook == "" && !sub.WebSocket && len(sub.Email) == 0 && sub.Slack == "" {
+		return fmt.Errorf("subscription must have a webhook URL, websocket, email or slack channel")
+	}
+	if len(sub.Email) > 0 && !m.notifier.EmailEnabled() {
+		return fmt.Errorf("email notifications require SMTP configuration (MEMEX_SMTP_ADDR)")
+	}
+	for _, addr := range sub.Email {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid email recipient %q: %w", addr, err)
+		}
+	}
+	return sub.Template.Validate()
+}
+
 // Get returns a subscription by ID
 func (m *Manager) Get(id string) (*Subscription, error) {
 	m.mu.RLock()
@@ -197,7 +290,7 @@ func (m *Manager) Get(id string) (*Subscription, error) {
 
 	sub, exists := m.subscriptions[id]
 	if !exists {
-		return nil, fmt.Errorf("subscription not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 	return sub, nil
 }
@@ -221,7 +314,7 @@ func (m *Manager) RegisterWSClient(subID string, conn WSConn) error {
 	m.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("subscription not found: %s", subID)
+		return fmt.Errorf("%w: %s", ErrNotFound, subID)
 	}
 
 	m.notifier.RegisterWSClient(subID, conn)
@@ -235,9 +328,10 @@ func (m *Manager) UnregisterWSClient(subID string) {
 
 // processEvents is the main event processing loop
 func (m *Manager) processEvents() {
-	defer m.wg.Done()
+	defer m.pending.Done()
 
 	for event := range m.eventChan {
+		m.publish(event)
 		m.handleEvent(event)
 	}
 }
@@ -254,7 +348,11 @@ func (m *Manager) handleEvent(event Event) {
 	m.mu.RUnlock()
 
 	for _, sub := range subs {
-		go m.evaluateSubscription(event, sub)
+		m.pending.Add(1)
+		go func() {
+			defer m.pending.Done()
+			m.evaluateSubscription(event, sub)
+		}()
 	}
 }
 
@@ -267,6 +365,12 @@ func (m *Manager) evaluateSubscription(event Event, sub *Subscription) {
 	if !matched {
 		return
 	}
+	var rate *RateAlert
+	if sub.Pattern.Region != nil {
+		if rate = m.observeRegion(ctx, sub, event); rate == nil {
+			return
+		}
+	}
 
 	// Create notification
 	now := time.Now()
@@ -276,6 +380,7 @@ func (m *Manager) evaluateSubscription(event Event, sub *Subscription) {
 		Event:            event,
 		MatchedAt:        now,
 		QueryResults:     results,
+		Rate:             rate,
 	}
 
 	// Update subscription state
@@ -287,12 +392,7 @@ func (m *Manager) evaluateSubscription(event Event, sub *Subscription) {
 	m.mu.Unlock()
 
 	// Send notifications
-	if sub.Webhook != "" {
-		go m.notifier.SendWebhook(sub.Webhook, notification)
-	}
-	if sub.WebSocket {
-		go m.notifier.SendWebSocket(sub.ID, notification)
-	}
+	m.deliver(sub, notification, false)
 
 	log.Printf("Subscription %s fired for event %s", sub.ID, event.Type)
 }
@@ -305,12 +405,14 @@ func (m *Manager) loadSubscriptions(ctx context.Context) error {
 	}
 
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	for _, sub := range subs {
 		m.subscriptions[sub.ID] = sub
 	}
+	m.mu.Unlock()
 
+	for _, sub := range subs {
+		m.trackRegion(ctx, sub)
+	}
 	return nil
 }
 
@@ -326,6 +428,7 @@ func subscriptionToMeta(sub *Subscription) map[string]interface{} {
 		"enabled":     sub.Enabled,
 		"fire_count":  sub.FireCount,
 	}
+	ChannelsToMeta(sub, meta)
 	if sub.LastFired != nil {
 		meta["last_fired"] = sub.LastFired.Format(time.RFC3339)
 	}
@@ -352,6 +455,7 @@ func metaToSubscription(id string, meta map[string]interface{}, created, modifie
 	if v, ok := meta["websocket"].(bool); ok {
 		sub.WebSocket = v
 	}
+	ChannelsFromMeta(sub, meta)
 	if v, ok := meta["enabled"].(bool); ok {
 		sub.Enabled = v
 	}
@@ -372,3 +476,33 @@ func metaToSubscription(id string, meta map[string]interface{}, created, modifie
 
 	return sub, nil
 }
+
+// ChannelsToMeta adds the email and Slack channel settings to stored
+// subscription properties. Recipients are comma-joined and the template is
+// JSON-encoded, like the pattern. Empty settings are written too, so that
+// clearing a channel overwrites the stored value.
+func ChannelsToMeta(sub *Subscription, meta map[string]interface{}) {
+	meta["email"] = strings.Join(sub.Email, ",")
+	meta["slack"] = sub.Slack
+	meta["template"] = ""
+	if sub.Template != nil {
+		templateJSON, _ := json.Marshal(sub.Template)
+		meta["template"] = string(templateJSON)
+	}
+}
+
+// ChannelsFromMeta restores the settings stored by ChannelsToMeta
+func ChannelsFromMeta(sub *Subscription, meta map[string]interface{}) {
+	if v, ok := meta["email"].(string); ok && v != "" {
+		sub.Email = strings.Split(v, ",")
+	}
+	if v, ok := meta["slack"].(string); ok {
+		sub.Slack = v
+	}
+	if v, ok := meta["template"].(string); ok && v != "" {
+		var tmpl NotificationTemplate
+		if err := json.Unmarshal([]byte(v), &tmpl); err == nil {
+			sub.Template = &tmpl
+		}
+	}
+}
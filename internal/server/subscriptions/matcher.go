@@ -4,11 +4,13 @@ import (
 	"context"
 	"log"
 	"strings"
+	"sync"
 )
 
 // Matcher evaluates events against subscription patterns
 type Matcher struct {
-	repo Repository
+	repo  Repository
+	exprs sync.Map // Condition source -> *Expression
 }
 
 // NewMatcher creates a new pattern matcher
@@ -24,6 +26,14 @@ func (m *Matcher) Match(ctx context.Context, event Event, pattern SubscriptionPa
 		return false, nil
 	}
 
+	// Then predicates and expression (in Go, no database)
+	if matched, err := m.matchCondition(event, pattern); err != nil || !matched {
+		if err != nil {
+			log.Printf("Pattern condition error: %v", err)
+		}
+		return false, nil
+	}
+
 	// If there's a Cypher pattern, evaluate it
 	if pattern.Cypher != "" {
 		results, err := m.matchCypher(ctx, event, pattern.Cypher)
@@ -101,6 +111,25 @@ func (m *Matcher) matchSimple(event Event, pattern SubscriptionPattern) bool {
 	return true
 }
 
+// matchCondition evaluates a pattern's predicates and expression, caching
+// compiled conditions
+func (m *Matcher) matchCondition(event Event, pattern SubscriptionPattern) (bool, error) {
+	condition, err := pattern.Condition()
+	if err != nil || condition == "" {
+		return err == nil, err
+	}
+	var expr *Expression
+	if cached, ok := m.exprs.Load(condition); ok {
+		expr = cached.(*Expression)
+	} else {
+		if expr, err = CompileExpression(condition); err != nil {
+			return false, err
+		}
+		m.exprs.Store(condition, expr)
+	}
+	return expr.Eval(event)
+}
+
 // matchCypher evaluates a Cypher query pattern
 func (m *Matcher) matchCypher(ctx context.Context, event Event, cypher string) ([]map[string]interface{}, error) {
 	// Validate Cypher query for safety
@@ -3,8 +3,10 @@ package subscriptions
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/smtp"
 	"sync"
 	"time"
 )
@@ -21,6 +23,10 @@ type Notifier struct {
 	httpClient *http.Client
 	wsClients  map[string]WSConn // subscription_id -> connection
 	mu         sync.RWMutex
+
+	backoff  time.Duration // Retry n waits n*n times this
+	smtp     *SMTPConfig   // Optional; nil disables email
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
 }
 
 // NewNotifier creates a new notifier
@@ -30,6 +36,8 @@ func NewNotifier() *Notifier {
 			Timeout: 30 * time.Second,
 		},
 		wsClients: make(map[string]WSConn),
+		backoff:   time.Second,
+		sendMail:  smtp.SendMail,
 	}
 }
 
@@ -78,12 +86,24 @@ func (n *Notifier) SendWebhook(url string, notification Notification) error {
 		return err
 	}
 
+	headers := map[string]string{
+		"X-Memex-Event":        notification.Event.Type,
+		"X-Memex-Subscription": notification.SubscriptionID,
+	}
+	if notification.Replayed {
+		headers["X-Memex-Replay"] = "true"
+	}
+	return n.post(url, payload, headers)
+}
+
+// post delivers a JSON payload with retries
+func (n *Notifier) post(url string, payload []byte, headers map[string]string) error {
 	// Attempt with retries
 	var lastErr error
 	for attempt := 0; attempt < 3; attempt++ {
 		if attempt > 0 {
 			// Exponential backoff
-			time.Sleep(time.Duration(attempt*attempt) * time.Second)
+			time.Sleep(time.Duration(attempt*attempt) * n.backoff)
 		}
 
 		req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
@@ -93,8 +113,9 @@ func (n *Notifier) SendWebhook(url string, notification Notification) error {
 		}
 
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-Memex-Event", notification.Event.Type)
-		req.Header.Set("X-Memex-Subscription", notification.SubscriptionID)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
 
 		resp, err := n.httpClient.Do(req)
 		if err != nil {
@@ -157,5 +178,5 @@ type WebhookError struct {
 }
 
 func (e *WebhookError) Error() string {
-	return "webhook delivery failed"
+	return fmt.Sprintf("webhook delivery failed: %s returned status %d", e.URL, e.StatusCode)
 }
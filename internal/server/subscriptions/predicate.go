@@ -0,0 +1,111 @@
+package subscriptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Predicate tests one event field, e.g. {"field": "meta.status", "op": "==",
+// "value": "pending"}. Fields use the expression variables (node_type,
+// link_type, meta.<key>, ...).
+type Predicate struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"` // ==, !=, <, <=, >, >=, in, not_in, contains, starts_with, ends_with, exists, not_exists
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Expression returns the predicate as expression source
+func (p Predicate) Expression() (string, error) {
+	parts := strings.Split(p.Field, ".")
+	if p.Field == "" || !exprVariables[parts[0]] {
+		return "", fmt.Errorf("invalid predicate field %q: must start with one of node_type, link_type, meta, ...", p.Field)
+	}
+	field := parts[0]
+	for _, part := range parts[1:] {
+		field += "[" + strconv.Quote(part) + "]"
+	}
+
+	switch p.Op {
+	case "exists":
+		return "has(" + field + ")", nil
+	case "not_exists":
+		return "!has(" + field + ")", nil
+	}
+
+	value, err := json.Marshal(p.Value)
+	if err != nil {
+		return "", fmt.Errorf("invalid predicate value for %q: %w", p.Field, err)
+	}
+	if strings.HasPrefix(string(value), "{") {
+		return "", fmt.Errorf("invalid predicate value for %q: objects are not supported", p.Field)
+	}
+	if (p.Op == "in" || p.Op == "not_in") && !strings.HasPrefix(string(value), "[") {
+		return "", fmt.Errorf("predicate %s on %q requires a list value", p.Op, p.Field)
+	}
+
+	switch p.Op {
+	case "==", "!=", "<", "<=", ">", ">=", "in":
+		return fmt.Sprintf("%s %s %s", field, p.Op, value), nil
+	case "not_in":
+		return fmt.Sprintf("!(%s in %s)", field, value), nil
+	case "contains":
+		return fmt.Sprintf("%s.contains(%s)", field, value), nil
+	case "starts_with":
+		return fmt.Sprintf("%s.startsWith(%s)", field, value), nil
+	case "ends_with":
+		return fmt.Sprintf("%s.endsWith(%s)", field, value), nil
+	}
+	return "", fmt.Errorf("invalid predicate op %q on %q", p.Op, p.Field)
+}
+
+// Condition combines a pattern's predicates and expression into a single
+// expression, or "" when it has neither
+func (p SubscriptionPattern) Condition() (string, error) {
+	var clauses []string
+	for _, pred := range p.Where {
+		expr, err := pred.Expression()
+		if err != nil {
+			return "", err
+		}
+		clauses = append(clauses, expr)
+	}
+	if strings.TrimSpace(p.Expr) != "" {
+		clauses = append(clauses, "("+p.Expr+")")
+	}
+	return strings.Join(clauses, " && "), nil
+}
+
+// Validate checks a pattern's predicates, expression, Cypher query and
+// region
+func (p SubscriptionPattern) Validate() error {
+	if strings.TrimSpace(p.Expr) != "" {
+		if _, err := CompileExpression(p.Expr); err != nil {
+			return fmt.Errorf("invalid pattern expression: %w", err)
+		}
+	}
+	condition, err := p.Condition()
+	if err != nil {
+		return err
+	}
+	if condition != "" {
+		if _, err := CompileExpression(condition); err != nil {
+			return fmt.Errorf("invalid pattern predicate: %w", err)
+		}
+	}
+	if p.Cypher != "" {
+		if err := validateCypher(p.Cypher); err != nil {
+			return err
+		}
+	}
+	if p.Region != nil {
+		if p.Cypher != "" {
+			return fmt.Errorf("region patterns cannot use a Cypher query")
+		}
+		if err := p.Region.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// A pattern with a region is a rate-of-change alert: it watches a node's
+// k-hop neighborhood, following links in either direction, and fires once
+// more than Threshold matching changes touch it within Window. Changes
+// made before the subscription was loaded are counted from the changelog,
+// so a restart does not reset the window.
+
+// Region limits
+const (
+	DefaultRegionDepth = 1
+	MaxRegionDepth     = 3
+	MaxRegionNodes     = 5000 // Neighborhoods are cut off at this size
+	maxRateNodes       = 50   // Changed nodes listed in an alert
+	regionRefresh      = 5 * time.Minute
+	regionSeedLimit    = 10000
+)
+
+// ErrNotReplayable is returned when replaying a rate alert
+var ErrNotReplayable = errors.New("rate alert subscriptions cannot be replayed")
+
+// RegionPattern restricts a pattern to a node's neighborhood and turns it
+// into a rate alert
+type RegionPattern struct {
+	NodeID    string `json:"node_id"`
+	Depth     int    `json:"depth,omitempty"` // Hops from the node, default 1
+	Threshold int    `json:"threshold"`       // Fire when more changes than this...
+	Window    string `json:"window"`          // ...occur within this Go duration
+}
+
+// RateAlert describes the burst of changes that fired a region pattern
+type RateAlert struct {
+	NodeID  string    `json:"node_id"`
+	Depth   int       `json:"depth"`
+	Window  string    `json:"window"`
+	Changes int       `json:"changes"`
+	Since   time.Time `json:"since"`    // Earliest change counted
+	NodeIDs []string  `json:"node_ids"` // Nodes changed, most recent first
+}
+
+// linkReader reads links in both directions; the graph repositories
+// subscriptions are stored in implement it
+type linkReader interface {
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+}
+
+// Validate checks a region's node, depth, threshold and window
+func (r *RegionPattern) Validate() error {
+	if r.NodeID == "" {
+		return fmt.Errorf("region node_id is required")
+	}
+	if r.Depth < 0 || r.Depth > MaxRegionDepth {
+		return fmt.Errorf("region depth must be between 1 and %d", MaxRegionDepth)
+	}
+	if r.Threshold < 1 {
+		return fmt.Errorf("region threshold must be at least 1")
+	}
+	if _, err := r.window(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// depth returns the region's depth, defaulted
+func (r *RegionPattern) depth() int {
+	if r.Depth == 0 {
+		return DefaultRegionDepth
+	}
+	return r.Depth
+}
+
+// window parses the region's window
+func (r *RegionPattern) window() (time.Duration, error) {
+	d, err := time.ParseDuration(r.Window)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid region window %q: use a positive Go duration such as 1h", r.Window)
+	}
+	return d, nil
+}
+
+// regionChange is one counted change and the region nodes it touched
+type regionChange struct {
+	at      time.Time
+	nodeIDs []string
+}
+
+// regionTracker counts the recent changes in one subscription's region
+type regionTracker struct {
+	mu      sync.Mutex
+	region  RegionPattern
+	window  time.Duration
+	members map[string]bool
+	loaded  time.Time // Zero when membership must be recomputed
+	changes []regionChange
+}
+
+// trackRegion starts counting changes for a subscription with a region,
+// seeded from the changelog, replacing any earlier count
+func (m *Manager) trackRegion(ctx context.Context, sub *Subscription) {
+	m.regionMu.Lock()
+	delete(m.regions, sub.ID)
+	m.regionMu.Unlock()
+	if sub.Pattern.Region == nil {
+		return
+	}
+
+	window, _ := sub.Pattern.Region.window()
+	t := &regionTracker{region: *sub.Pattern.Region, window: window}
+	if err := m.seedRegion(ctx, t, sub.Pattern); err != nil {
+		log.Printf("Warning: failed to count recent changes for subscription %s: %v", sub.ID, err)
+	}
+	m.regionMu.Lock()
+	m.regions[sub.ID] = t
+	m.regionMu.Unlock()
+}
+
+// seedRegion counts the matching changes already made within the window
+func (m *Manager) seedRegion(ctx context.Context, t *regionTracker, pattern SubscriptionPattern) error {
+	if err := m.loadRegion(ctx, t); err != nil {
+		return err
+	}
+	events, err := m.repo.ChangeLog(ctx, time.Now().Add(-t.window), regionSeedLimit)
+	if err != nil {
+		return fmt.Errorf("reading changelog: %w", err)
+	}
+	for _, event := range events {
+		if matched, _ := m.matcher.Match(ctx, event, pattern); matched {
+			t.count(event)
+		}
+	}
+	return nil
+}
+
+// loadRegion recomputes a region's members
+func (m *Manager) loadRegion(ctx context.Context, t *regionTracker) error {
+	links, ok := m.repo.(linkReader)
+	if !ok {
+		return fmt.Errorf("region patterns need a graph repository")
+	}
+	members := map[string]bool{t.region.NodeID: true}
+	frontier := []string{t.region.NodeID}
+	for d := 0; d < t.region.depth() && len(frontier) > 0; d++ {
+		var next []string
+		for _, id := range frontier {
+			out, err := links.GetLinks(ctx, id)
+			if err != nil {
+				return err
+			}
+			in, err := links.GetBacklinks(ctx, id)
+			if err != nil {
+				return err
+			}
+			for _, l := range append(out, in...) {
+				for _, neighbor := range []string{l.Source, l.Target} {
+					if members[neighbor] || len(members) >= MaxRegionNodes {
+						continue
+					}
+					members[neighbor] = true
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+	t.members, t.loaded = members, time.Now()
+	return nil
+}
+
+// observeRegion counts an event against a subscription's region and
+// returns an alert when the count passes the threshold. The count starts
+// again after each alert.
+func (m *Manager) observeRegion(ctx context.Context, sub *Subscription, event Event) *RateAlert {
+	if event.Type == EventQueryResults {
+		return nil
+	}
+	m.regionMu.Lock()
+	t := m.regions[sub.ID]
+	m.regionMu.Unlock()
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.loaded.IsZero() || time.Since(t.loaded) > regionRefresh {
+		if err := m.loadRegion(ctx, t); err != nil {
+			log.Printf("Warning: failed to load region of subscription %s: %v", sub.ID, err)
+			return nil
+		}
+	}
+	if !t.count(event) {
+		return nil
+	}
+	if len(t.changes) <= t.region.Threshold {
+		return nil
+	}
+
+	alert := &RateAlert{
+		NodeID:  t.region.NodeID,
+		Depth:   t.region.depth(),
+		Window:  t.region.Window,
+		Changes: len(t.changes),
+		Since:   t.changes[0].at,
+		NodeIDs: []string{},
+	}
+	seen := make(map[string]bool)
+	for i := len(t.changes) - 1; i >= 0 && len(alert.NodeIDs) < maxRateNodes; i-- {
+		for _, id := range t.changes[i].nodeIDs {
+			if !seen[id] && len(alert.NodeIDs) < maxRateNodes {
+				seen[id] = true
+				alert.NodeIDs = append(alert.NodeIDs, id)
+			}
+		}
+	}
+	t.changes = nil
+	return alert
+}
+
+// count records an event that touches the region, dropping changes that
+// have left the window, and reports whether it did. Link changes and
+// deletions within the region may change its members, so they are
+// recomputed before the next event. Callers hold t.mu.
+func (t *regionTracker) count(event Event) bool {
+	var touched []string
+	for _, id := range []string{event.NodeID, event.LinkSource, event.LinkTarget} {
+		if id != "" && t.members[id] {
+			touched = append(touched, id)
+		}
+	}
+	if len(touched) == 0 {
+		return false
+	}
+	switch event.Type {
+	case EventLinkCreated, EventLinkDeleted, EventNodeDeleted:
+		t.loaded = time.Time{}
+	}
+
+	at := event.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	cutoff := at.Add(-t.window)
+	kept := t.changes[:0]
+	for _, c := range t.changes {
+		if c.at.After(cutoff) {
+			kept = append(kept, c)
+		}
+	}
+	t.changes = append(kept, regionChange{at: at, nodeIDs: touched})
+	return true
+}
@@ -0,0 +1,101 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// linkedRepo serves a changelog and a fixed set of links
+type linkedRepo struct {
+	changeLogRepo
+	links []*core.Link
+}
+
+func (r *linkedRepo) GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	var out []*core.Link
+	for _, l := range r.links {
+		if l.Source == nodeID {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (r *linkedRepo) GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error) {
+	var out []*core.Link
+	for _, l := range r.links {
+		if l.Target == nodeID {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func TestRegionValidate(t *testing.T) {
+	for _, region := range []RegionPattern{
+		{Threshold: 1, Window: "1h"},
+		{NodeID: "a", Depth: MaxRegionDepth + 1, Threshold: 1, Window: "1h"},
+		{NodeID: "a", Window: "1h"},
+		{NodeID: "a", Threshold: 1, Window: "soon"},
+	} {
+		pattern := SubscriptionPattern{Region: &region}
+		if err := pattern.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", region)
+		}
+	}
+	pattern := SubscriptionPattern{Cypher: "MATCH (n) RETURN n", Region: &RegionPattern{NodeID: "a", Threshold: 1, Window: "1h"}}
+	if err := pattern.Validate(); err == nil {
+		t.Error("region with Cypher: expected an error")
+	}
+}
+
+func TestRegionRateAlert(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	// project <- task -> person, and an unrelated note; depth 2 reaches person
+	repo := &linkedRepo{
+		changeLogRepo: changeLogRepo{events: []Event{
+			{ID: "old", Type: EventNodeUpdated, NodeID: "task", Timestamp: now.Add(-2 * time.Hour)},
+			{ID: "recent", Type: EventNodeUpdated, NodeID: "person", Timestamp: now.Add(-10 * time.Minute)},
+		}},
+		links: []*core.Link{
+			{Source: "task", Target: "project", Type: "PART_OF"},
+			{Source: "task", Target: "person", Type: "ASSIGNED_TO"},
+		},
+	}
+	m := NewManager(repo)
+	sub := &Subscription{ID: "falcon", Enabled: true, Pattern: SubscriptionPattern{
+		EventTypes: []string{EventNodeUpdated, EventLinkCreated},
+		Region:     &RegionPattern{NodeID: "project", Depth: 2, Threshold: 2, Window: "1h"},
+	}}
+	m.trackRegion(ctx, sub)
+
+	// The recent changelog entry counts; the old one is outside the window
+	if rate := m.observeRegion(ctx, sub, Event{Type: EventNodeUpdated, NodeID: "note", Timestamp: now}); rate != nil {
+		t.Fatalf("change outside the region fired: %+v", rate)
+	}
+	if rate := m.observeRegion(ctx, sub, Event{Type: EventNodeUpdated, NodeID: "project", Timestamp: now}); rate != nil {
+		t.Fatalf("fired at the threshold: %+v", rate)
+	}
+	rate := m.observeRegion(ctx, sub, Event{Type: EventLinkCreated, LinkSource: "note", LinkTarget: "task", Timestamp: now})
+	if rate == nil || rate.Changes != 3 || rate.Depth != 2 {
+		t.Fatalf("rate = %+v", rate)
+	}
+	if len(rate.NodeIDs) != 3 || rate.NodeIDs[0] != "task" || rate.NodeIDs[2] != "person" {
+		t.Errorf("changed nodes = %v", rate.NodeIDs)
+	}
+
+	// The count starts again after an alert
+	if rate := m.observeRegion(ctx, sub, Event{Type: EventNodeUpdated, NodeID: "task", Timestamp: now}); rate != nil {
+		t.Errorf("fired again at once: %+v", rate)
+	}
+
+	m.subscriptions[sub.ID] = sub
+	if _, err := m.Replay(ctx, sub.ID, now.Add(-time.Hour), 10); !errors.Is(err, ErrNotReplayable) {
+		t.Errorf("replay: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// streamBuffer is the per-listener queue; events are dropped for listeners
+// that fall this far behind
+const streamBuffer = 256
+
+// stream is one live listener on the event stream
+type stream struct {
+	pattern SubscriptionPattern
+	events  chan Event
+}
+
+// streams fans events out to live listeners (e.g. SSE clients)
+type streams struct {
+	mu      sync.Mutex
+	next    int
+	streams map[int]*stream
+}
+
+// Stream registers a live listener for events matching pattern. The
+// returned cancel func must be called to release it. Cypher patterns are
+// not supported, since they would query the database for every event.
+func (m *Manager) Stream(pattern SubscriptionPattern) (<-chan Event, func(), error) {
+	if pattern.Cypher != "" {
+		return nil, nil, fmt.Errorf("cypher patterns are not supported for streams")
+	}
+	if err := pattern.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	m.live.mu.Lock()
+	defer m.live.mu.Unlock()
+	if m.live.streams == nil {
+		m.live.streams = make(map[int]*stream)
+	}
+	id := m.live.next
+	m.live.next++
+	s := &stream{pattern: pattern, events: make(chan Event, streamBuffer)}
+	m.live.streams[id] = s
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			m.live.mu.Lock()
+			delete(m.live.streams, id)
+			m.live.mu.Unlock()
+		})
+	}
+	return s.events, cancel, nil
+}
+
+// publish sends an event to every live listener whose pattern matches
+func (m *Manager) publish(event Event) {
+	m.live.mu.Lock()
+	defer m.live.mu.Unlock()
+
+	for _, s := range m.live.streams {
+		if matched, _ := m.matcher.Match(context.Background(), event, s.pattern); !matched {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			// Slow listener; drop rather than block event processing
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package subscriptions
+
+import "testing"
+
+func TestStreamFiltersAndCancels(t *testing.T) {
+	m := NewManager(&changeLogRepo{})
+
+	if _, _, err := m.Stream(SubscriptionPattern{Cypher: "MATCH (n) RETURN n"}); err == nil {
+		t.Error("expected cypher patterns to be rejected")
+	}
+
+	events, cancel, err := m.Stream(SubscriptionPattern{NodeTypes: []string{"Screenshot"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.publish(Event{ID: "1", Type: EventNodeCreated, NodeID: "note:1", NodeType: "Note"})
+	m.publish(Event{ID: "2", Type: EventNodeCreated, NodeID: "shot:1", NodeType: "Screenshot"})
+
+	select {
+	case e := <-events:
+		if e.ID != "2" {
+			t.Errorf("expected the Screenshot event, got %+v", e)
+		}
+	default:
+		t.Fatal("expected a matching event")
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+
+	cancel()
+	cancel()
+	m.publish(Event{ID: "3", Type: EventNodeCreated, NodeID: "shot:2", NodeType: "Screenshot"})
+	if len(events) != 0 {
+		t.Error("cancelled stream should not receive events")
+	}
+}
@@ -32,6 +32,18 @@ const (
 	EventLinkDeleted = "link.deleted"
 )
 
+// EventQueryResults is emitted when a scheduled saved query has results;
+// it is not a graph change and never appears in the change log
+const EventQueryResults = "query.results"
+
+// Ingest anomaly events are emitted when a connector's ingest rate or the
+// mix of node types created departs from its baseline, and when it
+// returns to normal; like query results they are not graph changes
+const (
+	EventIngestAnomaly         = "ingest.anomaly"
+	EventIngestAnomalyResolved = "ingest.anomaly.resolved"
+)
+
 // SubscriptionPattern defines what events a subscription matches
 type SubscriptionPattern struct {
 	// Simple matching (evaluated in Go, fast)
@@ -40,8 +52,16 @@ type SubscriptionPattern struct {
 	LinkTypes  []string               `json:"link_types,omitempty"`  // Match specific link types
 	MetaMatch  map[string]interface{} `json:"meta_match,omitempty"`  // Match metadata fields
 
+	// Property predicates and a CEL-style expression over the event payload
+	// (see expr.go); all predicates and the expression must hold
+	Where []Predicate `json:"where,omitempty"`
+	Expr  string      `json:"expr,omitempty"`
+
 	// Advanced matching (Cypher query, evaluated against Neo4j)
 	Cypher string `json:"cypher,omitempty"`
+
+	// Rate alert on a node's neighborhood (see region.go)
+	Region *RegionPattern `json:"region,omitempty"`
 }
 
 // Subscription represents a standing query that fires when patterns match
@@ -54,8 +74,11 @@ type Subscription struct {
 	Pattern SubscriptionPattern `json:"pattern"`
 
 	// How to notify
-	Webhook   string `json:"webhook,omitempty"`   // URL to POST notifications
-	WebSocket bool   `json:"websocket,omitempty"` // Push via WebSocket connection
+	Webhook   string                `json:"webhook,omitempty"`   // URL to POST notifications
+	WebSocket bool                  `json:"websocket,omitempty"` // Push via WebSocket connection
+	Email     []string              `json:"email,omitempty"`     // Recipients, sent via the configured SMTP server
+	Slack     string                `json:"slack,omitempty"`     // Slack incoming-webhook URL
+	Template  *NotificationTemplate `json:"template,omitempty"`  // Email and Slack message text
 
 	// State
 	Enabled   bool       `json:"enabled"`
@@ -74,25 +97,37 @@ type Notification struct {
 
 	// For Cypher patterns, include query results
 	QueryResults []map[string]interface{} `json:"query_results,omitempty"`
+
+	// For region patterns, the burst of changes that fired it
+	Rate *RateAlert `json:"rate,omitempty"`
+
+	// Re-delivered from the changelog by a replay
+	Replayed bool `json:"replayed,omitempty"`
 }
 
 // CreateSubscriptionRequest is the API request to create a subscription
 type CreateSubscriptionRequest struct {
-	Name        string              `json:"name"`
-	Description string              `json:"description,omitempty"`
-	Pattern     SubscriptionPattern `json:"pattern"`
-	Webhook     string              `json:"webhook,omitempty"`
-	WebSocket   bool                `json:"websocket,omitempty"`
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Pattern     SubscriptionPattern   `json:"pattern"`
+	Webhook     string                `json:"webhook,omitempty"`
+	WebSocket   bool                  `json:"websocket,omitempty"`
+	Email       []string              `json:"email,omitempty"`
+	Slack       string                `json:"slack,omitempty"`
+	Template    *NotificationTemplate `json:"template,omitempty"`
 }
 
 // UpdateSubscriptionRequest is the API request to update a subscription
 type UpdateSubscriptionRequest struct {
-	Name        *string              `json:"name,omitempty"`
-	Description *string              `json:"description,omitempty"`
-	Pattern     *SubscriptionPattern `json:"pattern,omitempty"`
-	Webhook     *string              `json:"webhook,omitempty"`
-	WebSocket   *bool                `json:"websocket,omitempty"`
-	Enabled     *bool                `json:"enabled,omitempty"`
+	Name        *string               `json:"name,omitempty"`
+	Description *string               `json:"description,omitempty"`
+	Pattern     *SubscriptionPattern  `json:"pattern,omitempty"`
+	Webhook     *string               `json:"webhook,omitempty"`
+	WebSocket   *bool                 `json:"websocket,omitempty"`
+	Email       *[]string             `json:"email,omitempty"`
+	Slack       *string               `json:"slack,omitempty"`
+	Template    *NotificationTemplate `json:"template,omitempty"`
+	Enabled     *bool                 `json:"enabled,omitempty"`
 }
 
 // SubscriptionResponse is the API response for subscription operations
@@ -0,0 +1,266 @@
+// Package syncapi is a small, versioned sync protocol for clients on
+// intermittent connections: a compact changes feed read with a cursor,
+// batched upserts guarded by conflict tokens, and node content fetched on
+// demand. docs/SYNC.md is the specification; this package implements
+// version 1.
+package syncapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Version is the protocol version this package speaks
+const Version = 1
+
+// Limits on one request
+const (
+	DefaultChanges = 200  // Changes per page when no limit is given
+	MaxChanges     = 1000 // Most changes one page may hold
+	MaxPush        = 500  // Most node and link operations one push may hold
+)
+
+// maxScan caps how many changelog events a page may read to get past one
+// busy second
+const maxScan = 100000
+
+// Operations and kinds of change
+const (
+	OpPut    = "put"
+	OpDelete = "delete"
+
+	KindNode = "node"
+	KindLink = "link"
+)
+
+// ErrBadCursor is returned for a cursor this version did not issue
+var ErrBadCursor = errors.New("invalid sync cursor")
+
+// Change is one entry of the feed: a node or link as it now stands, or
+// its removal. Node content is left out; Content says there is some to
+// fetch.
+type Change struct {
+	Op      string                 `json:"op"`
+	Kind    string                 `json:"kind"`
+	ID      string                 `json:"id,omitempty"`    // Node ID
+	Type    string                 `json:"type,omitempty"`  // Node or link type
+	Token   string                 `json:"token,omitempty"` // Node conflict token
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+	Content bool                   `json:"content,omitempty"` // The node has content to fetch
+	Source  string                 `json:"source,omitempty"`  // Link source
+	Target  string                 `json:"target,omitempty"`  // Link target
+	At      int64                  `json:"at"`                // Unix seconds
+}
+
+// key identifies what a change is about, so later changes replace earlier
+// ones in a page
+func (c *Change) key() string {
+	if c.Kind == KindNode {
+		return "n\x00" + c.ID
+	}
+	return "l\x00" + c.Source + "\x00" + c.Type + "\x00" + c.Target
+}
+
+// Page is one read of the feed
+type Page struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"` // Pass back to read on from here
+	More    bool     `json:"more"`   // Read again at once; otherwise poll later
+}
+
+// cursor is a position in the feed: the changes in seconds before Second
+// and the first Seen changes in Second have been read. Only seconds that
+// have passed are read, so the changes in any one second never grow.
+type cursor struct {
+	Second int64
+	Seen   int
+}
+
+func (c cursor) String() string {
+	raw := fmt.Sprintf("%d:%d:%d", Version, c.Second, c.Seen)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseCursor(s string) (cursor, error) {
+	if s == "" {
+		return cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrBadCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[0] != strconv.Itoa(Version) {
+		return cursor{}, ErrBadCursor
+	}
+	second, err1 := strconv.ParseInt(parts[1], 10, 64)
+	seen, err2 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil || second < 0 || seen < 0 {
+		return cursor{}, ErrBadCursor
+	}
+	return cursor{Second: second, Seen: seen}, nil
+}
+
+// Changes reads up to limit changes after the cursor, oldest first. An
+// empty cursor starts from the beginning. Repeated changes to one node or
+// link within the page are collapsed to the last, so a page may hold
+// fewer than limit changes while More is set.
+func Changes(ctx context.Context, repo graph.Repository, after string, limit int) (*Page, error) {
+	pos, err := parseCursor(after)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultChanges
+	}
+	limit = min(limit, MaxChanges)
+	settled := time.Now().Unix() // Changes in this second may still be written
+
+	// Read from the start of the cursor's second. Ties within a second come
+	// back in no fixed order and a read cut off by its limit may end
+	// partway through one, so drop a cut-off last second and widen the
+	// read until something whole is left after the changes already seen.
+	since := time.Unix(pos.Second, 0).Add(-time.Nanosecond)
+	fetch := pos.Seen + limit + 1
+	var events []subscriptions.Event
+	truncated := false
+	for {
+		events, err = repo.ChangeLog(ctx, since, fetch)
+		if err != nil {
+			return nil, fmt.Errorf("reading changelog: %w", err)
+		}
+		truncated = len(events) >= fetch
+		if !truncated || fetch >= maxScan {
+			break
+		}
+		last := events[len(events)-1].Timestamp.Unix()
+		whole := len(events)
+		for whole > 0 && events[whole-1].Timestamp.Unix() == last {
+			whole--
+		}
+		if whole > pos.Seen {
+			events = events[:whole]
+			break
+		}
+		fetch = min(fetch*2, maxScan)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		si, sj := events[i].Timestamp.Unix(), events[j].Timestamp.Unix()
+		if si != sj {
+			return si < sj
+		}
+		return events[i].ID < events[j].ID
+	})
+
+	page := &Page{Changes: []Change{}, More: truncated}
+	next := pos
+	var changes []Change
+	skip := pos.Seen
+	for _, e := range events {
+		second := e.Timestamp.Unix()
+		if second < pos.Second {
+			continue
+		}
+		if second >= settled {
+			page.More = false
+			break
+		}
+		if second == pos.Second && skip > 0 {
+			skip--
+			continue
+		}
+		if len(changes) == limit {
+			page.More = true
+			break
+		}
+		if second != next.Second {
+			next = cursor{Second: second}
+		}
+		next.Seen++
+		if c, ok := fromEvent(e); ok {
+			changes = append(changes, c)
+		}
+	}
+	page.Changes = collapse(changes)
+	page.Cursor = next.String()
+	return page, nil
+}
+
+// collapse keeps the last change to each node and link, in order
+func collapse(changes []Change) []Change {
+	last := make(map[string]int, len(changes))
+	for i := range changes {
+		last[changes[i].key()] = i
+	}
+	out := make([]Change, 0, len(last))
+	for i := range changes {
+		if last[changes[i].key()] == i {
+			out = append(out, changes[i])
+		}
+	}
+	return out
+}
+
+// fromEvent converts a changelog event to a change
+func fromEvent(e subscriptions.Event) (Change, bool) {
+	c := Change{At: e.Timestamp.Unix()}
+	switch e.Type {
+	case subscriptions.EventNodeCreated, subscriptions.EventNodeUpdated, subscriptions.EventNodeDeleted:
+		c.Kind, c.ID, c.Type = KindNode, e.NodeID, e.NodeType
+		if i := strings.LastIndex(e.ID, "@v"); i >= 0 {
+			if v, err := strconv.Atoi(e.ID[i+2:]); err == nil {
+				c.Token = graph.VersionID(e.NodeID, v)
+			}
+		}
+		if e.Type == subscriptions.EventNodeDeleted {
+			c.Op = OpDelete
+			return c, true
+		}
+		c.Op = OpPut
+		meta := e.Meta
+		if e.Type == subscriptions.EventNodeUpdated {
+			meta, _ = e.Meta["updated_meta"].(map[string]interface{})
+		}
+		c.Meta, c.Content = compactMeta(meta)
+	case subscriptions.EventLinkCreated, subscriptions.EventLinkDeleted:
+		c.Kind, c.Source, c.Target, c.Type = KindLink, e.LinkSource, e.LinkTarget, e.LinkType
+		c.Op = OpPut
+		if e.Type == subscriptions.EventLinkDeleted {
+			c.Op = OpDelete
+		} else {
+			c.Meta = e.Meta
+		}
+	default:
+		return c, false
+	}
+	return c, true
+}
+
+// compactMeta drops inline content from node properties, reporting
+// whether the node has content to fetch
+func compactMeta(meta map[string]interface{}) (map[string]interface{}, bool) {
+	content := false
+	if _, ok := meta[graph.ContentHashKey]; ok {
+		content = true
+	}
+	if _, ok := meta["content"].(string); !ok {
+		return meta, content
+	}
+	out := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		if k != "content" {
+			out[k] = v
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,191 @@
+package syncapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// Push outcomes
+const (
+	StatusCreated  = "created"
+	StatusUpdated  = "updated"
+	StatusDeleted  = "deleted"
+	StatusConflict = "conflict" // The base token is not the node's current version
+	StatusFailed   = "failed"   // The write was refused; see Error
+)
+
+// ErrTooLarge is returned for a push with more than MaxPush operations
+var ErrTooLarge = errors.New("too many operations in one push")
+
+// NodeOp creates, updates or deletes a node. Base is the token of the
+// version the client last saw: empty to create a node, required to
+// change or delete one.
+type NodeOp struct {
+	ID      string                 `json:"id"`
+	Type    string                 `json:"type,omitempty"`    // Required to create
+	Meta    map[string]interface{} `json:"meta,omitempty"`    // Merged into the node's properties
+	Content string                 `json:"content,omitempty"` // Only when creating
+	Base    string                 `json:"base,omitempty"`
+	Delete  bool                   `json:"delete,omitempty"`
+}
+
+// LinkOp creates or deletes a link. Links carry no versions, so there is
+// nothing to conflict over.
+type LinkOp struct {
+	Source string                 `json:"source"`
+	Target string                 `json:"target"`
+	Type   string                 `json:"type"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+	Delete bool                   `json:"delete,omitempty"`
+}
+
+// PushRequest is a batch of writes made offline
+type PushRequest struct {
+	Nodes []NodeOp `json:"nodes,omitempty"`
+	Links []LinkOp `json:"links,omitempty"`
+}
+
+// NodeResult is the outcome of one node operation. On a conflict Current
+// is the node as the server has it, to merge and push again with its
+// token.
+type NodeResult struct {
+	ID      string  `json:"id"`
+	Status  string  `json:"status"`
+	Token   string  `json:"token,omitempty"` // The node's token after the write
+	Current *Change `json:"current,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// LinkResult is the outcome of one link operation
+type LinkResult struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// PushResult reports each operation in the order pushed. Operations are
+// applied one at a time, so some may succeed while others fail.
+type PushResult struct {
+	Nodes []NodeResult `json:"nodes"`
+	Links []LinkResult `json:"links"`
+}
+
+// Push applies a batch: nodes first, so links may point at nodes created
+// in the same push, then links
+func Push(ctx context.Context, repo graph.Repository, req *PushRequest) (*PushResult, error) {
+	if len(req.Nodes)+len(req.Links) > MaxPush {
+		return nil, fmt.Errorf("%w: %d, at most %d", ErrTooLarge, len(req.Nodes)+len(req.Links), MaxPush)
+	}
+	result := &PushResult{Nodes: make([]NodeResult, 0, len(req.Nodes)), Links: make([]LinkResult, 0, len(req.Links))}
+	for _, op := range req.Nodes {
+		result.Nodes = append(result.Nodes, pushNode(ctx, repo, op))
+	}
+	for _, op := range req.Links {
+		res := LinkResult{Source: op.Source, Target: op.Target, Type: op.Type}
+		var err error
+		if op.Delete {
+			res.Status = StatusDeleted
+			err = repo.DeleteLink(ctx, op.Source, op.Target, op.Type)
+		} else {
+			res.Status = StatusCreated
+			now := time.Now()
+			link := &core.Link{Source: op.Source, Target: op.Target, Type: op.Type, Meta: op.Meta, Created: now, Modified: now}
+			if op.Source == "" || op.Target == "" || op.Type == "" {
+				err = errors.New("source, target and type are required")
+			} else {
+				err = repo.CreateLink(ctx, link)
+			}
+		}
+		if err != nil {
+			res.Status, res.Error = StatusFailed, err.Error()
+		}
+		result.Links = append(result.Links, res)
+	}
+	return result, nil
+}
+
+// pushNode applies one node operation if its base token is current
+func pushNode(ctx context.Context, repo graph.Repository, op NodeOp) NodeResult {
+	res := NodeResult{ID: op.ID}
+	failed := func(err error) NodeResult {
+		res.Status, res.Error = StatusFailed, err.Error()
+		return res
+	}
+	if op.ID == "" {
+		return failed(errors.New("id is required"))
+	}
+
+	current, err := repo.GetNode(ctx, op.ID)
+	if err != nil {
+		current = nil
+	}
+	if current == nil {
+		if op.Base != "" || op.Delete {
+			// Changed or deleted offline, but gone here
+			res.Status, res.Current = StatusConflict, &Change{Op: OpDelete, Kind: KindNode, ID: op.ID}
+			return res
+		}
+		if op.Type == "" {
+			return failed(errors.New("type is required to create a node"))
+		}
+		now := time.Now()
+		node := &core.Node{ID: op.ID, Type: op.Type, Meta: op.Meta, Content: []byte(op.Content), Created: now, Modified: now}
+		if err := repo.CreateNode(ctx, node); err != nil {
+			return failed(err)
+		}
+		res.Status, res.Token = StatusCreated, graph.VersionID(op.ID, 1)
+		return res
+	}
+
+	if op.Base != current.VersionID {
+		res.Status, res.Current = StatusConflict, nodeChange(current)
+		return res
+	}
+	if op.Delete {
+		if err := repo.DeleteNode(ctx, op.ID, false); err != nil {
+			return failed(err)
+		}
+		res.Status, res.Token = StatusDeleted, graph.VersionID(op.ID, current.Version+1)
+		return res
+	}
+	if err := repo.UpdateNodeMetaWithNote(ctx, op.ID, op.Meta, "sync", ""); err != nil {
+		return failed(err)
+	}
+	updated, err := repo.GetNode(ctx, op.ID)
+	if err != nil {
+		return failed(err)
+	}
+	res.Status, res.Token = StatusUpdated, updated.VersionID
+	return res
+}
+
+// nodeChange describes a node's current version as a change
+func nodeChange(node *core.Node) *Change {
+	c := &Change{Op: OpPut, Kind: KindNode, ID: node.ID, Type: node.Type, Token: node.VersionID, At: node.Modified.Unix()}
+	c.Meta, c.Content = compactMeta(node.Meta)
+	c.Content = c.Content || len(node.Content) > 0
+	return c
+}
+
+// Content returns a node's current content, which is its stored content
+// or else its content property, along with the node
+func Content(ctx context.Context, repo graph.Repository, id string) ([]byte, *core.Node, error) {
+	node, err := repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(node.Content) > 0 {
+		return node.Content, node, nil
+	}
+	if s, ok := node.Meta["content"].(string); ok {
+		return []byte(s), node, nil
+	}
+	return nil, node, nil
+}
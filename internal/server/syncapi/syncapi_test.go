@@ -0,0 +1,154 @@
+package syncapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestChanges(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	// Seven changes in one past second, read two at a time
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := range 5 {
+		meta := map[string]interface{}{"title": fmt.Sprint(i)}
+		if i == 0 {
+			meta["content"] = "a long note body"
+		}
+		if err := repo.CreateNode(ctx, &core.Node{ID: fmt.Sprintf("n%d", i), Type: "Note", Meta: meta, Created: past, Modified: past}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, target := range []string{"n1", "n2"} {
+		if err := repo.CreateLink(ctx, &core.Link{Source: "n0", Target: target, Type: "RELATED", Created: past, Modified: past}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Not yet settled, so not read
+	now := time.Now()
+	if err := repo.CreateNode(ctx, &core.Node{ID: "fresh", Type: "Note", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]int{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("feed did not finish")
+		}
+		page, err := Changes(ctx, repo, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range page.Changes {
+			seen[c.key()]++
+			if c.ID == "n0" && (!c.Content || c.Meta["content"] != nil || c.Token != "n0:v1") {
+				t.Errorf("n0 change = %+v, want content left out and token n0:v1", c)
+			}
+		}
+		cursor = page.Cursor
+		if !page.More {
+			break
+		}
+	}
+	if len(seen) != 7 {
+		t.Errorf("read %d distinct changes, want 7: %v", len(seen), seen)
+	}
+	for k, n := range seen {
+		if n != 1 {
+			t.Errorf("change %q read %d times", k, n)
+		}
+	}
+	if seen["n\x00fresh"] != 0 {
+		t.Error("change from the current second was read")
+	}
+
+	// Nothing new: the cursor stays put
+	page, err := Changes(ctx, repo, cursor, 2)
+	if err != nil || len(page.Changes) != 0 || page.Cursor != cursor {
+		t.Errorf("caught-up read = %+v, %v", page, err)
+	}
+	if _, err := Changes(ctx, repo, "bogus!", 2); !errors.Is(err, ErrBadCursor) {
+		t.Errorf("bad cursor error = %v", err)
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	changes := collapse([]Change{
+		{Op: OpPut, Kind: KindNode, ID: "a", Token: "a:v1"},
+		{Op: OpPut, Kind: KindLink, Source: "a", Target: "b", Type: "R"},
+		{Op: OpPut, Kind: KindNode, ID: "a", Token: "a:v2"},
+		{Op: OpDelete, Kind: KindLink, Source: "a", Target: "b", Type: "R"},
+	})
+	if len(changes) != 2 || changes[0].Token != "a:v2" || changes[1].Op != OpDelete {
+		t.Errorf("collapse = %+v", changes)
+	}
+}
+
+func TestPush(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	result, err := Push(ctx, repo, &PushRequest{
+		Nodes: []NodeOp{
+			{ID: "note:1", Type: "Note", Meta: map[string]interface{}{"title": "draft"}, Content: "body"},
+			{ID: "note:2", Type: "Note"},
+			{ID: "note:3"},
+		},
+		Links: []LinkOp{{Source: "note:1", Target: "note:2", Type: "RELATED"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := result.Nodes[0]; r.Status != StatusCreated || r.Token != "note:1:v1" {
+		t.Errorf("create result = %+v", r)
+	}
+	if r := result.Nodes[2]; r.Status != StatusFailed {
+		t.Errorf("create without type = %+v, want failed", r)
+	}
+	if r := result.Links[0]; r.Status != StatusCreated {
+		t.Errorf("link result = %+v", r)
+	}
+
+	// Two clients edit from the same version; the second conflicts
+	result, _ = Push(ctx, repo, &PushRequest{Nodes: []NodeOp{
+		{ID: "note:1", Base: "note:1:v1", Meta: map[string]interface{}{"title": "final"}},
+		{ID: "note:1", Base: "note:1:v1", Meta: map[string]interface{}{"title": "other"}},
+		{ID: "note:1"},
+	}})
+	if r := result.Nodes[0]; r.Status != StatusUpdated || r.Token != "note:1:v2" {
+		t.Errorf("update result = %+v", r)
+	}
+	for _, r := range result.Nodes[1:] {
+		if r.Status != StatusConflict || r.Current == nil || r.Current.Token != "note:1:v2" || r.Current.Meta["title"] != "final" || !r.Current.Content {
+			t.Errorf("stale write result = %+v, want conflict with v2", r)
+		}
+	}
+
+	result, _ = Push(ctx, repo, &PushRequest{Nodes: []NodeOp{
+		{ID: "note:2", Base: "note:2:v1", Delete: true},
+		{ID: "note:2", Base: "note:2:v1", Meta: map[string]interface{}{"title": "late"}},
+	}})
+	if r := result.Nodes[0]; r.Status != StatusDeleted {
+		t.Errorf("delete result = %+v", r)
+	}
+	if r := result.Nodes[1]; r.Status != StatusConflict || r.Current.Op != OpDelete {
+		t.Errorf("edit of a deleted node = %+v, want conflict with a delete", r)
+	}
+
+	content, _, err := Content(ctx, repo, "note:1")
+	if err != nil || string(content) != "body" {
+		t.Errorf("Content = %q, %v", content, err)
+	}
+
+	if _, err := Push(ctx, repo, &PushRequest{Links: make([]LinkOp, MaxPush+1)}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("oversized push error = %v", err)
+	}
+}
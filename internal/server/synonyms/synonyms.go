@@ -0,0 +1,272 @@
+// Package synonyms stores the synonym groups searches are expanded with:
+// a global set, and one set per namespace for words that only mean the
+// same thing there.
+package synonyms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// NodeType is the node type synonym sets are stored as
+const NodeType = "SynonymSet"
+
+// idPrefix namespaces synonym set node IDs
+const idPrefix = "synonyms:"
+
+// globalID names the global set, which no namespace can be called
+const globalID = "_global"
+
+// Limits on a synonym set
+const (
+	MaxGroups     = 1000
+	maxTermWords  = 4 // Longer synonyms are never matched by search
+	maxTermLength = 100
+)
+
+var (
+	// ErrNotFound is returned when removing a term no group has
+	ErrNotFound = errors.New("synonym not found")
+	// ErrInvalid is returned for malformed namespaces and groups
+	ErrInvalid = errors.New("invalid synonyms")
+)
+
+var validNamespace = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Set is the synonym groups of one namespace, or the global ones when
+// Namespace is empty. Every term in a group matches the others.
+type Set struct {
+	Namespace string     `json:"namespace"`
+	Groups    [][]string `json:"groups"`
+	Modified  time.Time  `json:"modified,omitempty"`
+}
+
+// ValidateNamespace checks a namespace name; empty names the global set
+func ValidateNamespace(namespace string) error {
+	if namespace != "" && !validNamespace.MatchString(namespace) {
+		return fmt.Errorf("%w: namespace %q", ErrInvalid, namespace)
+	}
+	return nil
+}
+
+// Normalize lowercases terms and collapses their spacing, merges groups
+// that share a term and sorts the result. Each group needs two distinct
+// terms of up to four words.
+func Normalize(groups [][]string) ([][]string, error) {
+	// Union groups that share a term
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(t string) string {
+		if parent[t] == t {
+			return t
+		}
+		parent[t] = find(parent[t])
+		return parent[t]
+	}
+	for i, group := range groups {
+		var first string
+		for _, term := range group {
+			words := graph.SearchWords(term)
+			switch {
+			case len(words) == 0:
+				return nil, fmt.Errorf("%w: groups[%d] has an empty term", ErrInvalid, i)
+			case len(words) > maxTermWords || len(term) > maxTermLength:
+				return nil, fmt.Errorf("%w: %q is too long (max %d words)", ErrInvalid, term, maxTermWords)
+			}
+			t := strings.Join(words, " ")
+			if _, ok := parent[t]; !ok {
+				parent[t] = t
+			}
+			if first == "" {
+				first = t
+			} else {
+				parent[find(t)] = find(first)
+			}
+		}
+	}
+
+	merged := make(map[string][]string)
+	for t := range parent {
+		root := find(t)
+		merged[root] = append(merged[root], t)
+	}
+	out := make([][]string, 0, len(merged))
+	for _, group := range merged {
+		if len(group) < 2 {
+			return nil, fmt.Errorf("%w: %q has no synonym", ErrInvalid, group[0])
+		}
+		sort.Strings(group)
+		out = append(out, group)
+	}
+	if len(out) > MaxGroups {
+		return nil, fmt.Errorf("%w: too many groups (%d, max %d)", ErrInvalid, len(out), MaxGroups)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out, nil
+}
+
+// Get loads a namespace's set, which is empty when none is stored
+func Get(ctx context.Context, repo graph.Repository, namespace string) (*Set, error) {
+	if err := ValidateNamespace(namespace); err != nil {
+		return nil, err
+	}
+	node, err := repo.GetNode(ctx, nodeID(namespace))
+	if err != nil || node.Type != NodeType {
+		return &Set{Namespace: namespace, Groups: [][]string{}}, nil
+	}
+	return fromNode(node)
+}
+
+// List returns every stored set
+func List(ctx context.Context, repo graph.Repository) ([]*Set, error) {
+	const pageSize = 500
+	out := []*Set{}
+	for offset := 0; ; offset += pageSize {
+		nodes, err := repo.FilterNodes(ctx, []string{NodeType}, "", "", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			set, err := fromNode(n)
+			if err != nil {
+				continue
+			}
+			out = append(out, set)
+		}
+		if len(nodes) < pageSize {
+			sort.Slice(out, func(i, j int) bool { return out[i].Namespace < out[j].Namespace })
+			return out, nil
+		}
+	}
+}
+
+// Put replaces a namespace's groups; no groups removes its set
+func Put(ctx context.Context, repo graph.Repository, namespace string, groups [][]string) (*Set, error) {
+	if err := ValidateNamespace(namespace); err != nil {
+		return nil, err
+	}
+	groups, err := Normalize(groups)
+	if err != nil {
+		return nil, err
+	}
+	set := &Set{Namespace: namespace, Groups: groups, Modified: time.Now()}
+	id := nodeID(namespace)
+	existing, err := repo.GetNode(ctx, id)
+	exists := err == nil && existing.Type == NodeType
+	switch {
+	case len(groups) == 0 && exists:
+		err = repo.DeleteNode(ctx, id, true)
+	case len(groups) == 0:
+	case exists:
+		err = repo.UpdateNodeMeta(ctx, id, toMeta(set))
+	default:
+		err = repo.CreateNode(ctx, &core.Node{ID: id, Type: NodeType, Meta: toMeta(set), Created: set.Modified, Modified: set.Modified})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Add adds a group of synonyms to a namespace's set, merging it with any
+// group that shares a term
+func Add(ctx context.Context, repo graph.Repository, namespace string, terms []string) (*Set, error) {
+	set, err := Get(ctx, repo, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return Put(ctx, repo, namespace, append(set.Groups, terms))
+}
+
+// Remove takes a term out of its group in a namespace's set, dropping the
+// group when one term is left
+func Remove(ctx context.Context, repo graph.Repository, namespace, term string) (*Set, error) {
+	set, err := Get(ctx, repo, namespace)
+	if err != nil {
+		return nil, err
+	}
+	t := strings.Join(graph.SearchWords(term), " ")
+	groups := make([][]string, 0, len(set.Groups))
+	found := false
+	for _, group := range set.Groups {
+		kept := make([]string, 0, len(group))
+		for _, g := range group {
+			if g == t {
+				found = true
+			} else {
+				kept = append(kept, g)
+			}
+		}
+		if len(kept) > 1 {
+			groups = append(groups, kept)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, term)
+	}
+	return Put(ctx, repo, namespace, groups)
+}
+
+// ForSearch returns the groups a search in a namespace expands with: the
+// global ones and the namespace's own
+func ForSearch(ctx context.Context, repo graph.Repository, namespace string) ([][]string, error) {
+	global, err := Get(ctx, repo, "")
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		return global.Groups, nil
+	}
+	local, err := Get(ctx, repo, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return append(global.Groups, local.Groups...), nil
+}
+
+// nodeID returns the ID of a namespace's set node
+func nodeID(namespace string) string {
+	if namespace == "" {
+		return idPrefix + globalID
+	}
+	return idPrefix + namespace
+}
+
+// toMeta stores a set as node properties
+func toMeta(set *Set) map[string]interface{} {
+	groups := make([]interface{}, len(set.Groups))
+	for i, group := range set.Groups {
+		terms := make([]interface{}, len(group))
+		for j, t := range group {
+			terms[j] = t
+		}
+		groups[i] = terms
+	}
+	return map[string]interface{}{"namespace": set.Namespace, "groups": groups}
+}
+
+// fromNode reads a set back from its node
+func fromNode(node *core.Node) (*Set, error) {
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	set := &Set{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("corrupt synonym set %s: %w", node.ID, err)
+	}
+	if set.Groups == nil {
+		set.Groups = [][]string{}
+	}
+	set.Modified = node.Modified
+	return set, nil
+}
@@ -0,0 +1,76 @@
+package synonyms
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize([][]string{{"Kubernetes", "k8s"}, {"K8S", "kube"}, {"machine  learning", "ML"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"k8s", "kube", "kubernetes"}, {"machine learning", "ml"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize = %v, want %v", got, want)
+	}
+	for _, bad := range [][][]string{
+		{{"k8s"}},
+		{{"k8s", "K8s"}},
+		{{"k8s", "!!"}},
+		{{"a b c d e", "x"}},
+	} {
+		if _, err := Normalize(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Normalize(%v) error = %v", bad, err)
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	if _, err := Add(ctx, repo, "", []string{"kubernetes", "k8s"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Add(ctx, repo, "work", []string{"pr", "pull request"}); err != nil {
+		t.Fatal(err)
+	}
+	set, err := Add(ctx, repo, "work", []string{"pull request", "merge request"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"merge request", "pr", "pull request"}}; !reflect.DeepEqual(set.Groups, want) {
+		t.Errorf("work groups = %v, want %v", set.Groups, want)
+	}
+
+	groups, err := ForSearch(ctx, repo, "work")
+	if err != nil || len(groups) != 2 {
+		t.Errorf("ForSearch(work) = %v, %v", groups, err)
+	}
+	if groups, _ := ForSearch(ctx, repo, "home"); len(groups) != 1 {
+		t.Errorf("ForSearch(home) = %v", groups)
+	}
+	if _, err := Get(ctx, repo, "bad namespace"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Get(bad namespace) error = %v", err)
+	}
+
+	// Removing down to one term drops the group, and with it the set
+	if _, err := Remove(ctx, repo, "", "k8s"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Remove(ctx, repo, "", "k8s"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Remove error = %v", err)
+	}
+	sets, err := List(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 1 || sets[0].Namespace != "work" {
+		t.Errorf("List = %+v", sets)
+	}
+}
@@ -0,0 +1,166 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxBurndownDays caps a burndown series
+const maxBurndownDays = 366
+
+// StatusCounts counts tasks by status
+type StatusCounts struct {
+	Open    int `json:"open"`
+	Doing   int `json:"doing"`
+	Blocked int `json:"blocked"`
+	Done    int `json:"done"`
+}
+
+// BurndownPoint is the state of a project's tasks at the end of a day
+type BurndownPoint struct {
+	Date string `json:"date"`
+	StatusCounts
+	Total     int `json:"total"`     // Tasks that existed
+	Remaining int `json:"remaining"` // Tasks not done
+}
+
+// Burndown is a project's task counts over time
+type Burndown struct {
+	ProjectID string          `json:"project_id"`
+	Tasks     int             `json:"tasks"`
+	Current   StatusCounts    `json:"current"`
+	Series    []BurndownPoint `json:"series"`
+}
+
+func (c *StatusCounts) add(status string) {
+	switch status {
+	case StatusDoing:
+		c.Doing++
+	case StatusBlocked:
+		c.Blocked++
+	case StatusDone:
+		c.Done++
+	default:
+		c.Open++
+	}
+}
+
+// ProjectTasks returns the IDs of a project's tasks: Task nodes linked to
+// the project node in either direction, and tasks extracted from nodes
+// linked to it
+func ProjectTasks(ctx context.Context, repo Repository, projectID string) ([]string, error) {
+	if _, err := repo.GetNode(ctx, projectID); err != nil {
+		return nil, err
+	}
+	neighbors, err := linkedNodes(ctx, repo, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range neighbors {
+		node, err := repo.GetNode(ctx, id)
+		if err != nil {
+			continue // Dangling link
+		}
+		if node.Type == TaskType {
+			add(id)
+			continue
+		}
+		backlinks, err := repo.GetBacklinks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range backlinks {
+			if l.Type == FromSourceLink {
+				add(l.Source)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// linkedNodes returns the nodes linked to id in either direction
+func linkedNodes(ctx context.Context, repo Repository, id string) ([]string, error) {
+	outgoing, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	incoming, err := repo.GetBacklinks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, l := range outgoing {
+		ids = append(ids, l.Target)
+	}
+	for _, l := range incoming {
+		ids = append(ids, l.Source)
+	}
+	return ids, nil
+}
+
+// ProjectBurndown counts a project's tasks by status at the end of each
+// day from from to to (inclusive, in to's location), replaying each
+// task's status history
+func ProjectBurndown(ctx context.Context, repo Repository, projectID string, from, to time.Time) (*Burndown, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, to.Location())
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days < 1 {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	if days > maxBurndownDays {
+		return nil, fmt.Errorf("burndown spans %d days (at most %d)", days, maxBurndownDays)
+	}
+
+	ids, err := ProjectTasks(ctx, repo, projectID)
+	if err != nil {
+		return nil, err
+	}
+	result := &Burndown{ProjectID: projectID, Series: make([]BurndownPoint, days)}
+	for i := range result.Series {
+		result.Series[i].Date = from.AddDate(0, 0, i).Format(DateFormat)
+	}
+
+	for _, id := range ids {
+		node, err := repo.GetNode(ctx, id)
+		if err != nil || node.Type != TaskType {
+			continue // Deleted since it was linked
+		}
+		history, err := History(ctx, repo, id)
+		if err != nil {
+			return nil, err
+		}
+		result.Tasks++
+		status, _ := node.Meta["status"].(string)
+		result.Current.add(status)
+
+		next := 0
+		status = ""
+		for i := range result.Series {
+			end := from.AddDate(0, 0, i+1)
+			for next < len(history) && history[next].At.Before(end) {
+				status = history[next].To
+				next++
+			}
+			if next == 0 {
+				continue // Not created yet
+			}
+			p := &result.Series[i]
+			p.add(status)
+			p.Total++
+			if status != StatusDone {
+				p.Remaining++
+			}
+		}
+	}
+	return result, nil
+}
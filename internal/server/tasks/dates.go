@@ -0,0 +1,182 @@
+package tasks
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat is how due dates are stored
+const DateFormat = "2006-01-02"
+
+var (
+	isoDate      = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	slashDate    = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?$`)
+	monthDay     = regexp.MustCompile(`^([a-z]+)\.? (\d{1,2})(?:st|nd|rd|th)?(?:,? (\d{4}))?$`)
+	dayMonth     = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)? (?:of )?([a-z]+)\.?(?:,? (\d{4}))?$`)
+	inDuration   = regexp.MustCompile(`^in (a|an|one|two|three|four|five|six|seven|\d+) (day|days|week|weeks|month|months)$`)
+	dueKeyword   = regexp.MustCompile(`(?i)\b(?:due(?: on| by)?|by|on|before|until|no later than|deadline)\b:?\s+`)
+	anyISODate   = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+	trailingJunk = regexp.MustCompile(`[\s.,;:!?)\]]+$`)
+)
+
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var smallNumbers = map[string]int{"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7}
+
+// ParseDate reads a date phrase such as "tomorrow", "next Friday", "end of
+// month", "in 2 weeks", "Oct 20" or "2026-10-20", relative to now. Dates
+// without a year fall in the coming twelve months.
+func ParseDate(phrase string, now time.Time) (time.Time, bool) {
+	p := strings.ToLower(strings.Join(strings.Fields(trailingJunk.ReplaceAllString(phrase, "")), " "))
+	p = strings.TrimPrefix(p, "the ")
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch p {
+	case "today", "tonight", "eod", "end of day", "end of today", "this evening":
+		return today, true
+	case "tomorrow", "tmrw", "end of tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "end of week", "end of the week", "eow", "this week":
+		return today.AddDate(0, 0, daysUntil(today.Weekday(), time.Friday)), true
+	case "next week":
+		ahead := daysUntil(today.Weekday(), time.Monday)
+		if ahead == 0 {
+			ahead = 7
+		}
+		return today.AddDate(0, 0, ahead), true
+	case "end of month", "end of the month", "eom", "this month":
+		return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, now.Location()), true
+	case "next month":
+		return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, now.Location()), true
+	}
+
+	if day, ok := weekdays[strings.TrimPrefix(p, "this ")]; ok {
+		return today.AddDate(0, 0, daysUntil(today.Weekday(), day)), true
+	}
+	if rest, ok := strings.CutPrefix(p, "next "); ok {
+		if day, ok := weekdays[rest]; ok {
+			ahead := daysUntil(today.Weekday(), day)
+			if ahead <= daysUntil(today.Weekday(), time.Sunday) {
+				ahead += 7 // "Next Friday" said early in the week means the week after
+			}
+			return today.AddDate(0, 0, ahead), true
+		}
+	}
+	if m := inDuration.FindStringSubmatch(p); m != nil {
+		n, ok := smallNumbers[m[1]]
+		if !ok {
+			n, _ = strconv.Atoi(m[1])
+		}
+		switch {
+		case strings.HasPrefix(m[2], "day"):
+			return today.AddDate(0, 0, n), true
+		case strings.HasPrefix(m[2], "week"):
+			return today.AddDate(0, 0, 7*n), true
+		default:
+			return today.AddDate(0, n, 0), true
+		}
+	}
+	if m := isoDate.FindStringSubmatch(p); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		return calendarDate(year, time.Month(month), day, now.Location())
+	}
+	if m := slashDate.FindStringSubmatch(p); m != nil {
+		month, _ := strconv.Atoi(m[1])
+		day, _ := strconv.Atoi(m[2])
+		return datedOrNext(m[3], time.Month(month), day, today)
+	}
+	if m := monthDay.FindStringSubmatch(p); m != nil {
+		if month, ok := months[m[1]]; ok {
+			day, _ := strconv.Atoi(m[2])
+			return datedOrNext(m[3], month, day, today)
+		}
+	}
+	if m := dayMonth.FindStringSubmatch(p); m != nil {
+		if month, ok := months[m[2]]; ok {
+			day, _ := strconv.Atoi(m[1])
+			return datedOrNext(m[3], month, day, today)
+		}
+	}
+	return time.Time{}, false
+}
+
+// FindDue returns the due date a sentence mentions, e.g. "send the deck
+// by Friday" or "due 2026-11-01"
+func FindDue(text string, now time.Time) (time.Time, bool) {
+	for _, loc := range dueKeyword.FindAllStringIndex(text, -1) {
+		words := strings.Fields(text[loc[1]:])
+		if len(words) > 5 {
+			words = words[:5]
+		}
+		for n := len(words); n > 0; n-- { // Longest phrase first
+			if due, ok := ParseDate(strings.Join(words[:n], " "), now); ok {
+				return due, true
+			}
+		}
+	}
+	if iso := anyISODate.FindString(text); iso != "" {
+		return ParseDate(iso, now)
+	}
+	return time.Time{}, false
+}
+
+// daysUntil counts days from one weekday forward to another (0 when equal)
+func daysUntil(from, to time.Weekday) int {
+	return (int(to) - int(from) + 7) % 7
+}
+
+// datedOrNext builds a date with an explicit year, or the next occurrence
+// of month and day on or after today
+func datedOrNext(year string, month time.Month, day int, today time.Time) (time.Time, bool) {
+	if year != "" {
+		y, _ := strconv.Atoi(year)
+		if y < 100 {
+			y += 2000
+		}
+		return calendarDate(y, month, day, today.Location())
+	}
+	date, ok := calendarDate(today.Year(), month, day, today.Location())
+	if ok && date.Before(today) {
+		date, ok = calendarDate(today.Year()+1, month, day, today.Location())
+	}
+	return date, ok
+}
+
+// calendarDate builds a date, rejecting ones that do not exist
+func calendarDate(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if date.Month() != month {
+		return time.Time{}, false // e.g. February 30
+	}
+	return date, true
+}
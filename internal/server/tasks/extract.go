@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Item is an action item found in text
+type Item struct {
+	Text     string `json:"text"`
+	Status   string `json:"status"`
+	Due      string `json:"due,omitempty"` // DateFormat
+	Assignee string `json:"assignee,omitempty"`
+	Line     int    `json:"line,omitempty"` // 1-based, for regex extraction
+}
+
+var (
+	listMarker    = regexp.MustCompile(`^(?:[-*+•]|\d+[.)])\s+`)
+	checkbox      = regexp.MustCompile(`^\[([ xX])\]\s+(.+)$`)
+	todoMarker    = regexp.MustCompile(`\b(?:TODO|FIXME)(?:\(([^)]+)\))?:?\s+(.+)$`)
+	actionMarker  = regexp.MustCompile(`(?i)^(?:action(?: item)?|follow[- ]up|next step)s?(?:\(([^)]+)\))?\s*:\s*(.+)$`)
+	mention       = regexp.MustCompile(`(?:^|[\s(])@([A-Za-z][\w.-]*[A-Za-z0-9])`)
+	assignedTo    = regexp.MustCompile(`\b(?:[Aa]ssigned to|[Oo]wner:?)\s+([A-Z][\w.'-]*(?: [A-Z][\w.'-]*)?)`)
+	leadingName   = regexp.MustCompile(`^([A-Z][a-z]+(?: [A-Z][a-z]+)?)(?::| will )`)
+	headingMarker = regexp.MustCompile(`^#+\s*`)
+)
+
+// actionHeadings introduce lists whose items are all tasks
+var actionHeadings = map[string]bool{
+	"action items": true, "actions": true, "next steps": true, "todo": true, "todos": true,
+	"to do": true, "to-do": true, "follow-ups": true, "follow ups": true, "followups": true,
+	"tasks": true,
+}
+
+// notNames are capitalized sentence openers that are not assignees
+var notNames = map[string]bool{
+	"I": true, "We": true, "They": true, "You": true, "He": true, "She": true, "Someone": true,
+	"Everyone": true, "Team": true, "Please": true, "Need": true, "Needs": true, "Remember": true,
+	"Note": true, "Make": true, "Try": true, "Want": true, "Going": true, "Have": true, "Ask": true,
+}
+
+// ExtractItems finds action items in text: TODO and FIXME markers,
+// Markdown checkboxes, "Action item:" style lines, and the entries listed
+// under an "Action items" or "Next steps" heading. Due dates and
+// assignees are read from each item's wording.
+func ExtractItems(text string, now time.Time) []Item {
+	var items []Item
+	seen := make(map[string]bool)
+	inSection, sectionItems := false, 0
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			if sectionItems > 0 {
+				inSection = false
+			}
+			continue
+		}
+
+		heading := strings.ToLower(strings.Trim(headingMarker.ReplaceAllString(trimmed, ""), "*_: "))
+		if actionHeadings[heading] {
+			inSection, sectionItems = true, 0
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			inSection = false
+			continue
+		}
+
+		body := listMarker.ReplaceAllString(trimmed, "")
+		bulleted := body != trimmed
+		item := Item{Status: StatusOpen, Line: i + 1}
+		if m := checkbox.FindStringSubmatch(body); m != nil {
+			item.Text = m[2]
+			if m[1] != " " {
+				item.Status = StatusDone
+			}
+		} else if m := actionMarker.FindStringSubmatch(body); m != nil {
+			item.Assignee, item.Text = m[1], m[2]
+		} else if m := todoMarker.FindStringSubmatch(body); m != nil {
+			item.Assignee, item.Text = m[1], m[2]
+		} else if inSection && bulleted {
+			item.Text = body
+			sectionItems++
+		} else {
+			continue
+		}
+
+		item.Text = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(item.Text), "*/-"))
+		key := normalize(item.Text)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if item.Assignee == "" {
+			item.Assignee = findAssignee(item.Text)
+		}
+		if due, ok := FindDue(item.Text, now); ok {
+			item.Due = due.Format(DateFormat)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// findAssignee reads who a task is for: an @mention, "assigned to Name",
+// or a sentence opening "Name will" or "Name:"
+func findAssignee(text string) string {
+	if m := mention.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	if m := assignedTo.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	if m := leadingName.FindStringSubmatch(text); m != nil && !notNames[strings.Fields(m[1])[0]] {
+		return m[1]
+	}
+	return ""
+}
+
+// normalize reduces item text to a comparison key
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
@@ -0,0 +1,418 @@
+// Package tasks extracts action items from notes, transcripts and emails
+// into Task nodes.
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Node and link types
+const (
+	TaskType       = "Task"
+	AssignedToLink = "ASSIGNED_TO" // Task to the Person doing it
+	FromSourceLink = "FROM_SOURCE" // Task to the node it was extracted from
+)
+
+// Task statuses
+const (
+	StatusOpen    = "open"
+	StatusDoing   = "doing"
+	StatusDone    = "done"
+	StatusBlocked = "blocked"
+)
+
+// Extractors recorded on tasks
+const (
+	ExtractorRegex = "regex"
+	ExtractorLLM   = "llm"
+)
+
+// extractedAtKey marks nodes that have been scanned for tasks
+const extractedAtKey = "tasks_extracted_at"
+
+// maxLLMText caps the text sent to the model
+const maxLLMText = 24000
+
+// sourceTypes are node types scanned for tasks
+var sourceTypes = map[string]bool{
+	"Note":       true,
+	"Transcript": true,
+	"Email":      true,
+	"Message":    true,
+	"Meeting":    true,
+	"Source":     true,
+}
+
+// textFormats are Source formats that hold prose
+var textFormats = map[string]bool{
+	"": true, "text": true, "markdown": true, "md": true, "note": true, "email": true, "eml": true,
+	"transcript": true, "vtt": true, "srt": true, "chat": true, "html": true,
+}
+
+// Repository is the subset of graph operations tasks need
+type Repository interface {
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	GetLinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	GetBacklinks(ctx context.Context, nodeID string) ([]*core.Link, error)
+	FilterNodes(ctx context.Context, nodeTypes []string, propertyKey string, propertyValue string, limit int, offset int) ([]*core.Node, error)
+	CreateNode(ctx context.Context, node *core.Node) error
+	CreateLinks(ctx context.Context, links []*core.Link) error
+	UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error
+	GetNodeAtVersion(ctx context.Context, id string, version int) (*core.Node, error)
+	GetNodeHistory(ctx context.Context, id string) ([]core.VersionInfo, error)
+}
+
+// Config holds task extraction configuration
+type Config struct {
+	LLMURL  string // OpenAI-compatible chat completions endpoint; empty uses regex only
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+}
+
+// Result summarizes the tasks extracted from one node
+type Result struct {
+	NodeID  string `json:"node_id"`
+	Items   []Item `json:"items"`
+	Created int    `json:"tasks_created"`
+}
+
+// Processor extracts tasks from nodes as they are created
+type Processor struct {
+	repo       Repository
+	cfg        Config
+	httpClient *http.Client
+	eventChan  chan queuedEvent
+	hold       func(nodeID string) (release func())
+	wg         sync.WaitGroup
+}
+
+// queuedEvent is a queued node with the release for its processing hold
+type queuedEvent struct {
+	event   subscriptions.Event
+	release func()
+}
+
+// NewProcessor creates a new task extraction processor
+func NewProcessor(repo Repository, cfg Config) *Processor {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	return &Processor{
+		repo:       repo,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		eventChan:  make(chan queuedEvent, 100),
+	}
+}
+
+// Start begins processing events
+func (p *Processor) Start() {
+	p.wg.Add(1)
+	go p.processEvents()
+	if p.cfg.LLMURL != "" {
+		log.Printf("Task extractor started (model: %s)", p.cfg.Model)
+	} else {
+		log.Printf("Task extractor started (regex)")
+	}
+}
+
+// Stop waits for queued nodes to finish processing
+func (p *Processor) Stop() {
+	close(p.eventChan)
+	p.wg.Wait()
+}
+
+// SetHold registers a callback invoked for each queued node; the returned
+// release is called once the node has been processed. Must be called
+// before Start.
+func (p *Processor) SetHold(hold func(nodeID string) (release func())) {
+	p.hold = hold
+}
+
+// EmitEvent queues an event for processing (non-blocking)
+func (p *Processor) EmitEvent(event subscriptions.Event) {
+	if event.Type != subscriptions.EventNodeCreated || !sourceTypes[event.NodeType] {
+		return
+	}
+	release := func() {}
+	if p.hold != nil {
+		release = p.hold(event.NodeID)
+	}
+	select {
+	case p.eventChan <- queuedEvent{event: event, release: release}:
+	default:
+		release()
+		log.Printf("Warning: task queue full, skipping node %s", event.NodeID)
+	}
+}
+
+// processEvents is the main processing loop
+func (p *Processor) processEvents() {
+	defer p.wg.Done()
+
+	for queued := range p.eventChan {
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		if _, err := p.ProcessNode(ctx, queued.event.NodeID, false); err != nil {
+			log.Printf("Task extraction failed for %s: %v", queued.event.NodeID, err)
+		}
+		cancel()
+		queued.release()
+	}
+}
+
+// ProcessNode extracts a node's action items into Task nodes linked
+// FROM_SOURCE to it, and ASSIGNED_TO the person responsible when they are
+// in the graph. Nodes without prose are skipped (nil result), as are nodes
+// already scanned unless force is set. Tasks already extracted keep their
+// current status.
+func (p *Processor) ProcessNode(ctx context.Context, id string, force bool) (*Result, error) {
+	node, err := p.repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	text := SourceText(node)
+	if text == "" {
+		return nil, nil
+	}
+	if _, done := node.Meta[extractedAtKey]; done && !force {
+		return nil, nil
+	}
+
+	now := time.Now()
+	result := &Result{NodeID: id, Items: ExtractItems(text, now)}
+	for i := range result.Items {
+		result.Items[i].Assignee = strings.TrimPrefix(result.Items[i].Assignee, "@")
+	}
+	extractors := map[string]string{}
+	for _, item := range result.Items {
+		extractors[normalize(item.Text)] = ExtractorRegex
+	}
+	if p.cfg.LLMURL != "" {
+		llmItems, err := p.extractLLM(ctx, text, now)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range llmItems {
+			if key := normalize(item.Text); key != "" && extractors[key] == "" {
+				extractors[key] = ExtractorLLM
+				result.Items = append(result.Items, item)
+			}
+		}
+	}
+	if result.Items == nil {
+		result.Items = []Item{}
+	}
+
+	for _, item := range result.Items {
+		created, err := p.createTask(ctx, node.ID, item, extractors[normalize(item.Text)], now)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			result.Created++
+		}
+	}
+
+	meta := map[string]any{extractedAtKey: now.Format(time.RFC3339), "task_count": len(result.Items)}
+	if err := p.repo.UpdateNodeMetaWithNote(ctx, id, meta, "Tasks extracted", "tasks"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createTask creates the Task node for an item unless it already exists
+func (p *Processor) createTask(ctx context.Context, sourceID string, item Item, extractor string, now time.Time) (bool, error) {
+	taskID := TaskID(sourceID, item.Text)
+	if _, err := p.repo.GetNode(ctx, taskID); err == nil {
+		return false, nil
+	}
+
+	meta := map[string]any{
+		"title":     item.Text,
+		"status":    item.Status,
+		"source_id": sourceID,
+		"extractor": extractor,
+	}
+	if item.Due != "" {
+		meta["due"] = item.Due
+	}
+	if item.Assignee != "" {
+		meta["assignee"] = item.Assignee
+	}
+	if item.Line > 0 {
+		meta["line"] = item.Line
+	}
+	if err := p.repo.CreateNode(ctx, &core.Node{ID: taskID, Type: TaskType, Meta: meta, Created: now, Modified: now}); err != nil {
+		return false, fmt.Errorf("creating %s: %w", taskID, err)
+	}
+
+	derived := func() map[string]interface{} { return map[string]interface{}{graph.LayerKey: graph.LayerDerived} }
+	links := []*core.Link{{Source: taskID, Target: sourceID, Type: FromSourceLink, Meta: derived(), Created: now, Modified: now}}
+	if person := p.assignee(ctx, item.Assignee); person != "" {
+		links = append(links, &core.Link{Source: taskID, Target: person, Type: AssignedToLink, Meta: derived(), Created: now, Modified: now})
+	}
+	return true, p.repo.CreateLinks(ctx, links)
+}
+
+// assignee resolves who a task is for to a Person node, by email address,
+// name or nickname
+func (p *Processor) assignee(ctx context.Context, who string) string {
+	if who == "" {
+		return ""
+	}
+	var person *core.Node
+	var err error
+	if strings.Contains(who, "@") {
+		person, err = people.Resolve(ctx, p.repo, people.AliasEmail, who)
+	} else {
+		person, err = people.ResolveName(ctx, p.repo, who)
+	}
+	if err != nil {
+		return ""
+	}
+	return person.ID
+}
+
+// TaskID derives a stable ID from the source and the task's wording, so
+// re-extracting a node finds the tasks it already produced
+func TaskID(sourceID, text string) string {
+	sum := sha256.Sum256([]byte(sourceID + "\x00" + normalize(text)))
+	return "task:" + hex.EncodeToString(sum[:8])
+}
+
+// SourceText returns the prose of a node tasks can be extracted from, or
+// "" for nodes that are not notes, transcripts, emails or text sources
+func SourceText(node *core.Node) string {
+	if !sourceTypes[node.Type] {
+		return ""
+	}
+	if node.Type == "Source" {
+		format, _ := node.Meta["format"].(string)
+		if !textFormats[strings.ToLower(format)] {
+			return ""
+		}
+	}
+	if text, ok := node.Meta["text"].(string); ok && text != "" {
+		return text
+	}
+	if len(node.Content) > 0 {
+		return string(node.Content)
+	}
+	text, _ := node.Meta["content"].(string)
+	return text
+}
+
+const extractPrompt = `Extract the action items from the text below: things someone has committed to do or been asked to do.
+Respond with JSON only: {"tasks": [{"text": "<the task, imperative>", "assignee": "<person's name or email, or empty>", "due": "<due date as written, e.g. Friday or 2026-03-01, or empty>", "done": false}]}.
+Return {"tasks": []} when there are none. Today is %s.
+
+%s`
+
+// extractLLM asks the model for action items
+func (p *Processor) extractLLM(ctx context.Context, text string, now time.Time) ([]Item, error) {
+	if len(text) > maxLLMText {
+		text = strings.ToValidUTF8(text[:maxLLMText], "")
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"model": p.cfg.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": fmt.Sprintf(extractPrompt, now.Format("Monday, 2006-01-02"), text)},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.LLMURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling task model: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("task model returned status %d: %s", resp.StatusCode, body)
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return nil, fmt.Errorf("decoding task model response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("task model returned no choices")
+	}
+	return parseLLMItems(completion.Choices[0].Message.Content, now)
+}
+
+// parseLLMItems reads the model's task list, tolerating surrounding prose
+// or code fences
+func parseLLMItems(content string, now time.Time) ([]Item, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in task model response")
+	}
+	var parsed struct {
+		Tasks []struct {
+			Text     string `json:"text"`
+			Assignee string `json:"assignee"`
+			Due      string `json:"due"`
+			Done     bool   `json:"done"`
+		} `json:"tasks"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("parsing task model response: %w", err)
+	}
+
+	var items []Item
+	for _, t := range parsed.Tasks {
+		item := Item{Text: strings.TrimSpace(t.Text), Status: StatusOpen, Assignee: strings.TrimSpace(t.Assignee)}
+		if item.Text == "" {
+			continue
+		}
+		if t.Done {
+			item.Status = StatusDone
+		}
+		if due, ok := ParseDate(t.Due, now); ok {
+			item.Due = due.Format(DateFormat)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,116 @@
+package tasks
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+// listPageSize is how many tasks are read per FilterNodes call
+const listPageSize = 500
+
+// Task is a Task node as listed by the API
+type Task struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Status     string    `json:"status"`
+	Due        string    `json:"due,omitempty"`
+	Overdue    bool      `json:"overdue,omitempty"`
+	Assignee   string    `json:"assignee,omitempty"`    // As written in the source
+	AssigneeID string    `json:"assignee_id,omitempty"` // Person it resolved to
+	SourceID   string    `json:"source_id,omitempty"`
+	Extractor  string    `json:"extractor,omitempty"`
+	Created    time.Time `json:"created"`
+	Modified   time.Time `json:"modified"`
+}
+
+// Filter selects tasks to list; empty fields match everything
+type Filter struct {
+	Status     string
+	AssigneeID string
+	SourceID   string
+	ProjectID  string // See ProjectTasks
+}
+
+// List returns matching tasks, soonest due first (undated last), then
+// oldest first
+func List(ctx context.Context, repo Repository, filter Filter, now time.Time) ([]Task, error) {
+	var inProject map[string]bool
+	if filter.ProjectID != "" {
+		ids, err := ProjectTasks(ctx, repo, filter.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		inProject = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			inProject[id] = true
+		}
+	}
+
+	var nodes []*core.Node
+	for offset := 0; ; offset += listPageSize {
+		page, err := repo.FilterNodes(ctx, []string{TaskType}, "status", filter.Status, listPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, page...)
+		if len(page) < listPageSize {
+			break
+		}
+	}
+
+	today := now.Format(DateFormat)
+	tasks := []Task{}
+	for _, n := range nodes {
+		t := taskFromNode(n)
+		if filter.Status != "" && t.Status != filter.Status || filter.SourceID != "" && t.SourceID != filter.SourceID || inProject != nil && !inProject[t.ID] {
+			continue
+		}
+		links, err := repo.GetLinks(ctx, n.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			if l.Type == AssignedToLink {
+				t.AssigneeID = l.Target
+			}
+		}
+		if filter.AssigneeID != "" && t.AssigneeID != filter.AssigneeID {
+			continue
+		}
+		t.Overdue = t.Due != "" && t.Due < today && t.Status != StatusDone
+		tasks = append(tasks, t)
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if (a.Due == "") != (b.Due == "") {
+			return a.Due != ""
+		}
+		if a.Due != b.Due {
+			return a.Due < b.Due
+		}
+		return a.Created.Before(b.Created)
+	})
+	return tasks, nil
+}
+
+func taskFromNode(n *core.Node) Task {
+	str := func(key string) string {
+		s, _ := n.Meta[key].(string)
+		return s
+	}
+	return Task{
+		ID:        n.ID,
+		Title:     str("title"),
+		Status:    str("status"),
+		Due:       str("due"),
+		Assignee:  str("assignee"),
+		SourceID:  str("source_id"),
+		Extractor: str("extractor"),
+		Created:   n.Created,
+		Modified:  n.Modified,
+	}
+}
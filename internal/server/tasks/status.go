@@ -0,0 +1,126 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// statusChangedKey records when a task last changed status
+const statusChangedKey = "status_changed_at"
+
+// transitions lists the statuses each status may move to: work starts,
+// then finishes or gets blocked; blocked work resumes, and finished or
+// started work can be reopened
+var transitions = map[string][]string{
+	StatusOpen:    {StatusDoing},
+	StatusDoing:   {StatusDone, StatusBlocked, StatusOpen},
+	StatusBlocked: {StatusDoing},
+	StatusDone:    {StatusOpen},
+}
+
+// ErrNotTask is returned when a status change targets a node that is not a Task
+var ErrNotTask = errors.New("node is not a task")
+
+// TransitionError rejects a status change the state machine does not allow
+type TransitionError struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Allowed []string `json:"allowed"`
+}
+
+func (e *TransitionError) Error() string {
+	if _, ok := transitions[e.To]; !ok {
+		return fmt.Sprintf("unknown status %q (open, doing, done or blocked)", e.To)
+	}
+	return fmt.Sprintf("cannot move a task from %s to %s (allowed: %s)", e.From, e.To, strings.Join(e.Allowed, ", "))
+}
+
+// Transition is one status change in a task's history
+type Transition struct {
+	From      string    `json:"from,omitempty"` // Empty for the status a task was created with
+	To        string    `json:"to"`
+	At        time.Time `json:"at"`
+	Version   int       `json:"version"`
+	ChangedBy string    `json:"changed_by,omitempty"`
+	Note      string    `json:"note,omitempty"`
+}
+
+// CheckTransition returns a *TransitionError unless a task may move from
+// one status to another
+func CheckTransition(from, to string) error {
+	if from == "" {
+		from = StatusOpen
+	}
+	allowed := transitions[from]
+	for _, s := range allowed {
+		if s == to {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to, Allowed: allowed}
+}
+
+// SetStatus moves a task to a new status, recording the change as a new
+// version attributed to changedBy
+func SetStatus(ctx context.Context, repo Repository, id, status, changedBy, note string) (*Transition, error) {
+	node, err := repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if node.Type != TaskType {
+		return nil, ErrNotTask
+	}
+	from, _ := node.Meta["status"].(string)
+	if err := CheckTransition(from, status); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	changeNote := fmt.Sprintf("Status: %s → %s", from, status)
+	if note = strings.TrimSpace(note); note != "" {
+		changeNote += ": " + note
+	}
+	meta := map[string]any{"status": status, statusChangedKey: now.Format(time.RFC3339)}
+	if err := repo.UpdateNodeMetaWithNote(ctx, id, meta, changeNote, changedBy); err != nil {
+		return nil, err
+	}
+	updated, err := repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &Transition{From: from, To: status, At: now, Version: updated.Version, ChangedBy: changedBy, Note: note}, nil
+}
+
+// History returns a task's status changes, oldest first, read from its
+// versions
+func History(ctx context.Context, repo Repository, id string) ([]Transition, error) {
+	versions, err := repo.GetNodeHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	var history []Transition
+	status := ""
+	for _, v := range versions {
+		node, err := repo.GetNodeAtVersion(ctx, id, v.Version)
+		if err != nil {
+			return nil, err
+		}
+		next, _ := node.Meta["status"].(string)
+		if next == status {
+			continue
+		}
+		t := Transition{From: status, To: next, At: v.Modified, Version: v.Version, ChangedBy: v.ChangedBy}
+		if change, ok := strings.CutPrefix(v.ChangeNote, "Status: "); ok {
+			_, t.Note, _ = strings.Cut(change, ": ")
+		}
+		history = append(history, t)
+		status = next
+	}
+	return history, nil
+}
@@ -0,0 +1,185 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+// wednesday is the reference "now" for date tests
+var wednesday = time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+func TestParseDate(t *testing.T) {
+	cases := map[string]string{
+		"today":            "2026-10-14",
+		"tomorrow":         "2026-10-15",
+		"Friday":           "2026-10-16",
+		"monday":           "2026-10-19",
+		"next Friday":      "2026-10-23",
+		"next week":        "2026-10-19",
+		"end of the month": "2026-10-31",
+		"in 2 weeks":       "2026-10-28",
+		"Oct 20th":         "2026-10-20",
+		"3 March":          "2027-03-03",
+		"11/2":             "2026-11-02",
+		"2026-12-01":       "2026-12-01",
+	}
+	for phrase, want := range cases {
+		got, ok := ParseDate(phrase, wednesday)
+		if !ok || got.Format(DateFormat) != want {
+			t.Errorf("ParseDate(%q) = %v, %v; want %s", phrase, got.Format(DateFormat), ok, want)
+		}
+	}
+	for _, phrase := range []string{"the team", "February 30", "soon"} {
+		if _, ok := ParseDate(phrase, wednesday); ok {
+			t.Errorf("ParseDate(%q) should fail", phrase)
+		}
+	}
+}
+
+const meetingNotes = `# Planning sync
+
+Ada walked through the launch plan.
+TODO: book the venue by Friday
+- [x] Share the agenda
+- [ ] Draft the press release due Oct 20th
+
+## Action items
+- Ada Lovelace will send the deck to the board by next Friday
+- Update the budget sheet @grace
+
+## Notes
+- This bullet is not a task
+`
+
+func TestExtractItems(t *testing.T) {
+	items := ExtractItems(meetingNotes, wednesday)
+	want := []Item{
+		{Text: "book the venue by Friday", Status: StatusOpen, Due: "2026-10-16", Line: 4},
+		{Text: "Share the agenda", Status: StatusDone, Line: 5},
+		{Text: "Draft the press release due Oct 20th", Status: StatusOpen, Due: "2026-10-20", Line: 6},
+		{Text: "Ada Lovelace will send the deck to the board by next Friday", Status: StatusOpen, Due: "2026-10-23", Assignee: "Ada Lovelace", Line: 9},
+		{Text: "Update the budget sheet @grace", Status: StatusOpen, Assignee: "grace", Line: 10},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(items), len(want), items)
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, items[i], want[i])
+		}
+	}
+}
+
+func TestProcessNodeCreatesLinkedTasks(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "person:ada", Type: "Person", Meta: map[string]interface{}{"name": "Ada Lovelace"}, Created: now, Modified: now},
+		{ID: "note:sync", Type: "Note", Content: []byte(meetingNotes), Created: now, Modified: now},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := NewProcessor(repo, Config{})
+	result, err := p.ProcessNode(ctx, "note:sync", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 5 {
+		t.Fatalf("created %d tasks, want 5", result.Created)
+	}
+
+	open, err := List(ctx, repo, Filter{Status: StatusOpen}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 4 {
+		t.Fatalf("got %d open tasks, want 4", len(open))
+	}
+	assigned, err := List(ctx, repo, Filter{AssigneeID: "person:ada"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assigned) != 1 || assigned[0].SourceID != "note:sync" || assigned[0].Extractor != ExtractorRegex {
+		t.Errorf("unexpected tasks assigned to Ada: %+v", assigned)
+	}
+
+	// Already scanned: skipped, and forcing it creates nothing new
+	if again, err := p.ProcessNode(ctx, "note:sync", false); err != nil || again != nil {
+		t.Errorf("second pass = %+v, %v; want skipped", again, err)
+	}
+	forced, err := p.ProcessNode(ctx, "note:sync", true)
+	if err != nil || forced.Created != 0 {
+		t.Errorf("forced pass = %+v, %v; want no new tasks", forced, err)
+	}
+}
+
+func TestParseLLMItems(t *testing.T) {
+	items, err := parseLLMItems("```json\n{\"tasks\": [{\"text\": \"Email the lawyer\", \"assignee\": \"ada@example.com\", \"due\": \"tomorrow\"}, {\"text\": \" \"}]}\n```", wednesday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Due != "2026-10-15" || items[0].Assignee != "ada@example.com" || items[0].Status != StatusOpen {
+		t.Errorf("unexpected items %+v", items)
+	}
+}
+
+func TestStatusTransitionsAndBurndown(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	now := time.Now()
+	for _, n := range []*core.Node{
+		{ID: "project:launch", Type: "Project", Created: now, Modified: now},
+		{ID: "task:a", Type: TaskType, Meta: map[string]interface{}{"title": "A", "status": StatusOpen}, Created: now, Modified: now},
+		{ID: "task:b", Type: TaskType, Meta: map[string]interface{}{"title": "B", "status": StatusOpen}, Created: now, Modified: now},
+	} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"task:a", "task:b"} {
+		if err := repo.CreateLink(ctx, &core.Link{Source: id, Target: "project:launch", Type: "MEMBER_OF", Created: now, Modified: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var invalid *TransitionError
+	if _, err := SetStatus(ctx, repo, "task:a", StatusDone, "ada", ""); !errors.As(err, &invalid) || len(invalid.Allowed) != 1 {
+		t.Fatalf("open → done = %v, want a transition error", err)
+	}
+	if _, err := SetStatus(ctx, repo, "project:launch", StatusDoing, "ada", ""); !errors.Is(err, ErrNotTask) {
+		t.Errorf("status on a project = %v, want ErrNotTask", err)
+	}
+	for _, status := range []string{StatusDoing, StatusBlocked, StatusDoing, StatusDone} {
+		if _, err := SetStatus(ctx, repo, "task:a", status, "ada", "standup"); err != nil {
+			t.Fatalf("moving to %s: %v", status, err)
+		}
+	}
+
+	history, err := History(ctx, repo, "task:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 5 || history[0].To != StatusOpen || history[4].From != StatusDoing || history[4].To != StatusDone || history[4].ChangedBy != "ada" || history[4].Note != "standup" {
+		t.Errorf("unexpected history %+v", history)
+	}
+
+	burndown, err := ProjectBurndown(ctx, repo, "project:launch", now.AddDate(0, 0, -2), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burndown.Tasks != 2 || burndown.Current.Done != 1 || burndown.Current.Open != 1 || len(burndown.Series) != 3 {
+		t.Fatalf("unexpected burndown %+v", burndown)
+	}
+	if first, last := burndown.Series[0], burndown.Series[2]; first.Total != 0 || last.Total != 2 || last.Remaining != 1 || last.Done != 1 {
+		t.Errorf("unexpected series %+v", burndown.Series)
+	}
+}
@@ -0,0 +1,177 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/tasks"
+)
+
+// jiraPageSize is how many issues each search request asks for
+const jiraPageSize = 100
+
+// maxTickets caps one fetch
+const maxTickets = 10000
+
+// Jira fetches issues from Jira Cloud or Data Center with a JQL filter
+type Jira struct {
+	URL        string // Site URL, e.g. https://acme.atlassian.net
+	Email      string // With Token, basic auth for Jira Cloud
+	Token      string // API token, or a personal access token without Email
+	JQL        string // Which issues to sync, e.g. project = OPS; empty for all visible
+	HTTPClient *http.Client
+}
+
+// Name implements Source
+func (j *Jira) Name() string { return "jira" }
+
+// jiraTime is how Jira formats timestamps
+const jiraTime = "2006-01-02T15:04:05.000-0700"
+
+var jqlOrder = regexp.MustCompile(`(?is)\border\s+by\b.*$`)
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Status      struct {
+			Name     string `json:"name"`
+			Category struct {
+				Key string `json:"key"` // new, indeterminate or done
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Assignee *struct {
+			DisplayName  string `json:"displayName"`
+			EmailAddress string `json:"emailAddress"`
+		} `json:"assignee"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Labels         []string `json:"labels"`
+		DueDate        string   `json:"duedate"`
+		Created        string   `json:"created"`
+		Updated        string   `json:"updated"`
+		ResolutionDate string   `json:"resolutiondate"`
+	} `json:"fields"`
+}
+
+// Fetch implements Source. Jira compares dates in the searching user's
+// time zone at minute precision, so the filter asks for issues updated in
+// the last N minutes, a few minutes more than have passed since since.
+func (j *Jira) Fetch(ctx context.Context, since time.Time) ([]Ticket, error) {
+	if j.URL == "" {
+		return nil, fmt.Errorf("Jira URL is required")
+	}
+	// Tickets must come oldest update first, so the filter's own order goes
+	jql := strings.TrimSpace(jqlOrder.ReplaceAllString(j.JQL, ""))
+	if !since.IsZero() {
+		minutes := int(math.Ceil(time.Since(since).Minutes())) + 5
+		updated := fmt.Sprintf(`updated >= "-%dm"`, minutes)
+		if jql == "" {
+			jql = updated
+		} else {
+			jql = "(" + jql + ") AND " + updated
+		}
+	}
+	jql = strings.TrimSpace(jql + " ORDER BY updated ASC")
+
+	var out []Ticket
+	for start := 0; start < maxTickets; start += jiraPageSize {
+		query := url.Values{
+			"jql":        {jql},
+			"startAt":    {strconv.Itoa(start)},
+			"maxResults": {strconv.Itoa(jiraPageSize)},
+			"fields":     {"summary,description,status,assignee,priority,labels,duedate,created,updated,resolutiondate"},
+		}
+		var page struct {
+			Issues []jiraIssue `json:"issues"`
+			Total  int         `json:"total"`
+		}
+		if err := j.get(ctx, "/rest/api/2/search?"+query.Encode(), &page); err != nil {
+			return nil, err
+		}
+		for _, is := range page.Issues {
+			out = append(out, j.ticket(is))
+		}
+		if len(page.Issues) < jiraPageSize || start+len(page.Issues) >= page.Total {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (j *Jira) ticket(is jiraIssue) Ticket {
+	f := is.Fields
+	t := Ticket{
+		Key:         is.Key,
+		Title:       f.Summary,
+		Description: f.Description,
+		Status:      f.Status.Name,
+		Labels:      f.Labels,
+		URL:         strings.TrimRight(j.URL, "/") + "/browse/" + is.Key,
+		Due:         f.DueDate,
+	}
+	switch {
+	case strings.Contains(strings.ToLower(f.Status.Name), "block"):
+		t.Category = tasks.StatusBlocked
+	case f.Status.Category.Key == "done":
+		t.Category = tasks.StatusDone
+	case f.Status.Category.Key == "indeterminate":
+		t.Category = tasks.StatusDoing
+	default:
+		t.Category = tasks.StatusOpen
+	}
+	if f.Assignee != nil {
+		t.Assignee = &Person{Name: f.Assignee.DisplayName, Email: f.Assignee.EmailAddress}
+	}
+	if f.Priority != nil {
+		t.Priority = f.Priority.Name
+	}
+	t.Created, _ = time.Parse(jiraTime, f.Created)
+	t.Updated, _ = time.Parse(jiraTime, f.Updated)
+	if resolved, err := time.Parse(jiraTime, f.ResolutionDate); err == nil {
+		t.Resolved = &resolved
+	}
+	return t
+}
+
+func (j *Jira) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(j.URL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case j.Email != "":
+		req.SetBasicAuth(j.Email, j.Token)
+	case j.Token != "":
+		req.Header.Set("Authorization", "Bearer "+j.Token)
+	}
+	client := j.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling Jira: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Jira returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding Jira response: %w", err)
+	}
+	return nil
+}
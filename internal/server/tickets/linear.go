@@ -0,0 +1,187 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/tasks"
+)
+
+// DefaultLinearURL is Linear's GraphQL endpoint
+const DefaultLinearURL = "https://api.linear.app/graphql"
+
+// Linear fetches issues from Linear, optionally for one team
+type Linear struct {
+	APIKey     string
+	Team       string // Team key, e.g. ENG; empty for every team the key can see
+	URL        string // Default DefaultLinearURL
+	HTTPClient *http.Client
+}
+
+// Name implements Source
+func (l *Linear) Name() string { return "linear" }
+
+// linearQuery pages through issues, oldest update first
+const linearQuery = `query($filter: IssueFilter, $after: String) {
+  issues(filter: $filter, first: 100, after: $after, orderBy: updatedAt) {
+    nodes {
+      identifier title description url priorityLabel dueDate
+      createdAt updatedAt completedAt canceledAt
+      state { name type }
+      assignee { name email }
+      labels { nodes { name } }
+    }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+type linearIssue struct {
+	Identifier    string     `json:"identifier"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	URL           string     `json:"url"`
+	PriorityLabel string     `json:"priorityLabel"`
+	DueDate       string     `json:"dueDate"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	CompletedAt   *time.Time `json:"completedAt"`
+	CanceledAt    *time.Time `json:"canceledAt"`
+	State         struct {
+		Name string `json:"name"`
+		Type string `json:"type"` // triage, backlog, unstarted, started, completed or canceled
+	} `json:"state"`
+	Assignee *struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"assignee"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+}
+
+// Fetch implements Source
+func (l *Linear) Fetch(ctx context.Context, since time.Time) ([]Ticket, error) {
+	if l.APIKey == "" {
+		return nil, fmt.Errorf("Linear API key is required")
+	}
+	filter := map[string]interface{}{}
+	if l.Team != "" {
+		filter["team"] = map[string]interface{}{"key": map[string]string{"eq": l.Team}}
+	}
+	if !since.IsZero() {
+		filter["updatedAt"] = map[string]string{"gte": since.UTC().Format(time.RFC3339Nano)}
+	}
+
+	var out []Ticket
+	var after *string
+	for len(out) < maxTickets {
+		var data struct {
+			Issues struct {
+				Nodes    []linearIssue `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"issues"`
+		}
+		if err := l.query(ctx, map[string]interface{}{"filter": filter, "after": after}, &data); err != nil {
+			return nil, err
+		}
+		for _, is := range data.Issues.Nodes {
+			out = append(out, linearTicket(is))
+		}
+		if !data.Issues.PageInfo.HasNextPage {
+			break
+		}
+		cursor := data.Issues.PageInfo.EndCursor
+		after = &cursor
+	}
+	return out, nil
+}
+
+func linearTicket(is linearIssue) Ticket {
+	t := Ticket{
+		Key:         is.Identifier,
+		Title:       is.Title,
+		Description: is.Description,
+		Status:      is.State.Name,
+		Priority:    is.PriorityLabel,
+		URL:         is.URL,
+		Due:         is.DueDate,
+		Created:     is.CreatedAt,
+		Updated:     is.UpdatedAt,
+	}
+	switch {
+	case strings.Contains(strings.ToLower(is.State.Name), "block"):
+		t.Category = tasks.StatusBlocked
+	case is.State.Type == "completed" || is.State.Type == "canceled":
+		t.Category = tasks.StatusDone
+	case is.State.Type == "started":
+		t.Category = tasks.StatusDoing
+	default:
+		t.Category = tasks.StatusOpen
+	}
+	if is.Assignee != nil {
+		t.Assignee = &Person{Name: is.Assignee.Name, Email: is.Assignee.Email}
+	}
+	for _, label := range is.Labels.Nodes {
+		t.Labels = append(t.Labels, label.Name)
+	}
+	if is.CompletedAt != nil {
+		t.Resolved = is.CompletedAt
+	} else if is.CanceledAt != nil {
+		t.Resolved = is.CanceledAt
+	}
+	return t
+}
+
+func (l *Linear) query(ctx context.Context, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": linearQuery, "variables": variables})
+	if err != nil {
+		return err
+	}
+	endpoint := l.URL
+	if endpoint == "" {
+		endpoint = DefaultLinearURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", l.APIKey)
+	client := l.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling Linear: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Linear returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding Linear response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("Linear: %s", result.Errors[0].Message)
+	}
+	return json.Unmarshal(result.Data, out)
+}
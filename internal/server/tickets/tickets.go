@@ -0,0 +1,411 @@
+// Package tickets syncs issue tracker tickets (Jira, Linear) into Task
+// nodes, so they share the status history, burndowns and assignee links of
+// tasks extracted from notes.
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/tasks"
+)
+
+// Node and link types
+const (
+	SyncStateType = "TicketSync" // Per-tracker sync cursor
+	MentionsLink  = "MENTIONS"   // Ticket to a document its description mentions
+)
+
+// Ticket is a tracker issue, as the trackers' clients return it
+type Ticket struct {
+	Key         string     `json:"key"` // PROJ-12 or ENG-12
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status"` // The tracker's own status name
+	Category    string     `json:"-"`      // open, doing, done or blocked
+	Assignee    *Person    `json:"assignee,omitempty"`
+	Priority    string     `json:"priority,omitempty"`
+	Labels      []string   `json:"labels,omitempty"`
+	URL         string     `json:"url,omitempty"`
+	Due         string     `json:"due,omitempty"` // YYYY-MM-DD
+	Created     time.Time  `json:"created"`
+	Updated     time.Time  `json:"updated"`
+	Resolved    *time.Time `json:"resolved,omitempty"`
+}
+
+// Person is a ticket's assignee
+type Person struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// Source is a tracker tickets are fetched from
+type Source interface {
+	// Name is the tracker's name, recorded as the connector: jira or linear
+	Name() string
+	// Fetch returns the tickets updated at or after since (all of them
+	// when since is zero), oldest update first
+	Fetch(ctx context.Context, since time.Time) ([]Ticket, error)
+}
+
+// Result summarizes a sync
+type Result struct {
+	Tracker   string `json:"tracker"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Links     int    `json:"links"`                    // Assignee and mention links created
+	SyncedTo  string `json:"synced_through,omitempty"` // Sync cursor after the sync (RFC 3339)
+}
+
+// State is a tracker's sync cursor
+type State struct {
+	Tracker       string    `json:"tracker"`
+	SyncedThrough time.Time `json:"synced_through"` // Newest ticket update applied
+	LastSync      time.Time `json:"last_sync"`
+}
+
+// Mentions in descriptions: links, and other tickets' keys
+var (
+	urlRef = regexp.MustCompile(`https?://[^\s<>"'\])|]+`)
+	keyRef = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b`)
+)
+
+// TicketID returns the node ID of a ticket
+func TicketID(tracker, key string) string {
+	return "ticket:" + tracker + ":" + strings.ToUpper(key)
+}
+
+func stateID(tracker string) string { return "ticket-sync:" + tracker }
+
+// Sync applies the tickets updated since the tracker's last sync (all of
+// them with full). Each ticket is a Task node whose status follows the
+// tracker's status category; a change is recorded as a task status
+// transition, so task history and subscriptions see it like any other.
+// The cursor advances as tickets are applied, so a sync that fails part
+// way resumes where it stopped.
+func Sync(ctx context.Context, repo graph.Repository, src Source, full bool) (*Result, error) {
+	tracker := src.Name()
+	state, err := GetState(ctx, repo, tracker)
+	if err != nil {
+		return nil, err
+	}
+	since := state.SyncedThrough
+	if full {
+		since = time.Time{}
+	}
+	list, err := src.Fetch(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Updated.Before(list[j].Updated) })
+
+	result := &Result{Tracker: tracker}
+	for i := range list {
+		if err = apply(ctx, repo, tracker, &list[i], result); err != nil {
+			err = fmt.Errorf("%s: %w", list[i].Key, err)
+			break
+		}
+		if list[i].Updated.After(state.SyncedThrough) {
+			state.SyncedThrough = list[i].Updated
+		}
+	}
+
+	state.LastSync = time.Now()
+	if saveErr := saveState(ctx, repo, state); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	if !state.SyncedThrough.IsZero() {
+		result.SyncedTo = state.SyncedThrough.UTC().Format(time.RFC3339)
+	}
+	return result, err
+}
+
+// apply upserts one ticket with its assignee and mention links
+func apply(ctx context.Context, repo graph.Repository, tracker string, t *Ticket, result *Result) error {
+	id := TicketID(tracker, t.Key)
+	meta := map[string]any{
+		"title":         t.Title,
+		"description":   t.Description,
+		"status":        t.Category,
+		"ticket_key":    t.Key,
+		"ticket_status": t.Status,
+		"tracker":       tracker,
+		"connector":     tracker,
+		"url":           t.URL,
+		"priority":      t.Priority,
+		"labels":        append([]string{}, t.Labels...),
+		"due":           t.Due,
+		"assignee":      "",
+		"updated_at":    t.Updated.UTC().Format(time.RFC3339),
+	}
+	if t.Assignee != nil {
+		meta["assignee"] = t.Assignee.Name
+		if meta["assignee"] == "" {
+			meta["assignee"] = t.Assignee.Email
+		}
+	}
+	if t.Resolved != nil {
+		meta["resolved_at"] = t.Resolved.UTC().Format(time.RFC3339)
+	}
+
+	existing, err := repo.GetNode(ctx, id)
+	if err != nil {
+		created := t.Created
+		if created.IsZero() {
+			created = time.Now()
+		}
+		if err := repo.CreateNode(ctx, &core.Node{ID: id, Type: tasks.TaskType, Meta: meta, Created: created, Modified: time.Now()}); err != nil {
+			return fmt.Errorf("creating %s: %w", id, err)
+		}
+		result.Created++
+	} else {
+		changes := make(map[string]any)
+		for key, value := range meta {
+			if !sameValue(existing.Meta[key], value) {
+				changes[key] = value
+			}
+		}
+		note := "Ticket sync"
+		if from, _ := existing.Meta["status"].(string); from != t.Category {
+			// Worded like tasks.SetStatus so the task's status history reads it
+			note = fmt.Sprintf("Status: %s → %s: %s in %s", from, t.Category, t.Status, tracker)
+			changes["status_changed_at"] = t.Updated.UTC().Format(time.RFC3339)
+		}
+		switch {
+		case len(changes) == 0:
+			result.Unchanged++
+		default:
+			if err := repo.UpdateNodeMetaWithNote(ctx, id, changes, note, tracker+"-sync"); err != nil {
+				return fmt.Errorf("updating %s: %w", id, err)
+			}
+			result.Updated++
+		}
+	}
+
+	if err := assign(ctx, repo, id, t.Assignee, result); err != nil {
+		return err
+	}
+	return mention(ctx, repo, tracker, id, t.Description, result)
+}
+
+// assign points the ticket's ASSIGNED_TO link at its assignee's Person
+// node, found by email address and then by name; a ticket reassigned to
+// someone unknown keeps no link
+func assign(ctx context.Context, repo graph.Repository, id string, who *Person, result *Result) error {
+	person := ""
+	if who != nil {
+		if who.Email != "" {
+			if p, err := people.Resolve(ctx, repo, people.AliasEmail, who.Email); err == nil {
+				person = p.ID
+			}
+		}
+		if person == "" && who.Name != "" {
+			if p, err := people.ResolveName(ctx, repo, who.Name); err == nil {
+				person = p.ID
+			}
+		}
+	}
+
+	links, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return err
+	}
+	linked := false
+	for _, l := range links {
+		if l.Type != tasks.AssignedToLink {
+			continue
+		}
+		if l.Target == person {
+			linked = true
+			continue
+		}
+		if err := repo.DeleteLink(ctx, id, l.Target, l.Type); err != nil {
+			return err
+		}
+	}
+	if person == "" || linked {
+		return nil
+	}
+	now := time.Now()
+	if err := repo.CreateLink(ctx, &core.Link{Source: id, Target: person, Type: tasks.AssignedToLink, Created: now, Modified: now}); err != nil {
+		return err
+	}
+	result.Links++
+	return nil
+}
+
+// mention links a ticket to the nodes whose url property matches a link
+// in its description, and to the tickets whose keys it names
+func mention(ctx context.Context, repo graph.Repository, tracker, id, text string, result *Result) error {
+	targets := make(map[string]bool)
+	for _, u := range urlRef.FindAllString(text, -1) {
+		u = strings.TrimRight(u, ".,;:!?")
+		nodes, err := repo.FilterNodes(ctx, nil, "url", u, 10, 0)
+		if err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			if v, _ := n.Meta["url"].(string); v == u && n.ID != id {
+				targets[n.ID] = true
+			}
+		}
+	}
+	for _, key := range keyRef.FindAllString(text, -1) {
+		other := TicketID(tracker, key)
+		if other == id {
+			continue
+		}
+		if _, err := repo.GetNode(ctx, other); err == nil {
+			targets[other] = true
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	links, err := repo.GetLinks(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		if l.Type == MentionsLink {
+			delete(targets, l.Target)
+		}
+	}
+	ids := make([]string, 0, len(targets))
+	for target := range targets {
+		ids = append(ids, target)
+	}
+	sort.Strings(ids)
+	now := time.Now()
+	for _, target := range ids {
+		if err := repo.CreateLink(ctx, &core.Link{Source: id, Target: target, Type: MentionsLink, Created: now, Modified: now}); err != nil {
+			return err
+		}
+		result.Links++
+	}
+	return nil
+}
+
+// sameValue compares property values as stored, since lists and numbers
+// come back from JSON as []interface{} and float64
+func sameValue(a, b interface{}) bool {
+	x, err1 := json.Marshal(a)
+	y, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(x) == string(y)
+}
+
+// GetState returns a tracker's sync cursor; a tracker never synced has a
+// zero one
+func GetState(ctx context.Context, repo graph.Repository, tracker string) (*State, error) {
+	state := &State{Tracker: tracker}
+	node, err := repo.GetNode(ctx, stateID(tracker))
+	if err != nil {
+		return state, nil
+	}
+	data, err := json.Marshal(node.Meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("corrupt sync state %s: %w", node.ID, err)
+	}
+	return state, nil
+}
+
+// ListStates returns the sync cursors of every tracker synced so far
+func ListStates(ctx context.Context, repo graph.Repository) ([]*State, error) {
+	nodes, err := repo.FilterNodes(ctx, []string{SyncStateType}, "", "", 1000, 0)
+	if err != nil {
+		return nil, err
+	}
+	states := []*State{}
+	for _, n := range nodes {
+		tracker, _ := n.Meta["tracker"].(string)
+		state, err := GetState(ctx, repo, tracker)
+		if err != nil || tracker == "" {
+			continue
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Tracker < states[j].Tracker })
+	return states, nil
+}
+
+func saveState(ctx context.Context, repo graph.Repository, state *State) error {
+	meta := map[string]interface{}{
+		"tracker":        state.Tracker,
+		"synced_through": state.SyncedThrough.UTC().Format(time.RFC3339Nano),
+		"last_sync":      state.LastSync.UTC().Format(time.RFC3339Nano),
+	}
+	id := stateID(state.Tracker)
+	if _, err := repo.GetNode(ctx, id); err == nil {
+		return repo.UpdateNodeMeta(ctx, id, meta)
+	}
+	now := time.Now()
+	return repo.CreateNode(ctx, &core.Node{ID: id, Type: SyncStateType, Meta: meta, Created: now, Modified: now})
+}
+
+// Syncer polls trackers periodically
+type Syncer struct {
+	repo     graph.Repository
+	sources  []Source
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSyncer creates a syncer that runs every interval
+func NewSyncer(repo graph.Repository, sources []Source, interval time.Duration) *Syncer {
+	return &Syncer{repo: repo, sources: sources, interval: interval, stop: make(chan struct{})}
+}
+
+// Start syncs once, then periodically
+func (s *Syncer) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.sync()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sync()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Ticket sync started for %d trackers (every %s)", len(s.sources), s.interval)
+}
+
+// Stop halts periodic syncs and waits for a running sync to finish
+func (s *Syncer) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Syncer) sync() {
+	for _, src := range s.sources {
+		result, err := Sync(context.Background(), s.repo, src, false)
+		if err != nil {
+			log.Printf("Ticket sync from %s failed: %v", src.Name(), err)
+			continue
+		}
+		if result.Created > 0 || result.Updated > 0 {
+			log.Printf("Ticket sync from %s: %d tickets created, %d updated", src.Name(), result.Created, result.Updated)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/people"
+	"github.com/systemshift/memex/internal/server/tasks"
+)
+
+func hasLink(t *testing.T, repo graph.Repository, source, target, linkType string) bool {
+	t.Helper()
+	links, err := repo.GetLinks(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range links {
+		if l.Target == target && l.Type == linkType {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeJira serves the issues in *issues and records each search's JQL
+func fakeJira(t *testing.T, issues *string, jql *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/search" {
+			http.NotFound(w, r)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot@example.com" || pass != "tok" {
+			t.Errorf("basic auth = %q, %q", user, pass)
+		}
+		*jql = append(*jql, r.URL.Query().Get("jql"))
+		fmt.Fprintf(w, `{"total": %d, "issues": [%s]}`, strings.Count(*issues, `"key"`), *issues)
+	}))
+}
+
+const (
+	ops2 = `{"key": "OPS-2", "fields": {"summary": "Rotate keys", "status": {"name": "To Do", "statusCategory": {"key": "new"}},
+		"created": "2026-04-01T09:00:00.000+0000", "updated": "2026-04-01T09:00:00.000+0000"}}`
+	ops1 = `{"key": "OPS-1", "fields": {"summary": "Write runbook", "description": "See https://docs.example.com/spec. Blocked on OPS-2",
+		"status": {"name": "In Progress", "statusCategory": {"key": "indeterminate"}},
+		"assignee": {"displayName": "Ada L", "emailAddress": "ada@example.com"}, "priority": {"name": "High"}, "labels": ["ops"],
+		"created": "2026-04-01T10:00:00.000+0000", "updated": "2026-04-02T10:00:00.000+0000"}}`
+	ops1Done = `{"key": "OPS-1", "fields": {"summary": "Write runbook", "description": "See https://docs.example.com/spec. Blocked on OPS-2",
+		"status": {"name": "Closed", "statusCategory": {"key": "done"}},
+		"created": "2026-04-01T10:00:00.000+0000", "updated": "2026-04-03T10:00:00.000+0000", "resolutiondate": "2026-04-03T10:00:00.000+0000"}}`
+)
+
+func TestSyncJira(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+	if _, err := people.SyncContacts(ctx, repo, []people.Contact{{Name: "Ada Lovelace", Emails: []string{"ada@example.com"}}}, people.SyncOptions{Connector: "vcard"}); err != nil {
+		t.Fatal(err)
+	}
+	ada, err := people.Resolve(ctx, repo, people.AliasEmail, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	doc := &core.Node{ID: "doc:spec", Type: "Document", Meta: map[string]interface{}{"url": "https://docs.example.com/spec"}, Created: now, Modified: now}
+	if err := repo.CreateNode(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	issues := ops1 + "," + ops2
+	var jql []string
+	srv := fakeJira(t, &issues, &jql)
+	defer srv.Close()
+	src := &Jira{URL: srv.URL, Email: "bot@example.com", Token: "tok", JQL: "project = OPS ORDER BY created DESC"}
+
+	res, err := Sync(ctx, repo, src, false)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	// Assignee, spec document and OPS-2
+	if res.Created != 2 || res.Links != 3 || res.SyncedTo != "2026-04-02T10:00:00Z" {
+		t.Errorf("Sync() = %+v", res)
+	}
+	id := TicketID("jira", "ops-1")
+	n, err := repo.GetNode(ctx, id)
+	if err != nil || n.Type != tasks.TaskType || n.Meta["status"] != tasks.StatusDoing || n.Meta["priority"] != "High" || n.Meta["url"] != srv.URL+"/browse/OPS-1" {
+		t.Fatalf("ticket = %+v, %v", n, err)
+	}
+	for _, l := range []struct{ target, linkType string }{
+		{ada.ID, tasks.AssignedToLink},
+		{"doc:spec", MentionsLink},
+		{TicketID("jira", "OPS-2"), MentionsLink},
+	} {
+		if !hasLink(t, repo, id, l.target, l.linkType) {
+			t.Errorf("missing link %s -[%s]-> %s", id, l.linkType, l.target)
+		}
+	}
+
+	// Closing the ticket is a status transition and drops the assignee
+	issues = ops1Done
+	res, err = Sync(ctx, repo, src, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Updated != 1 || res.Links != 0 {
+		t.Errorf("second Sync() = %+v", res)
+	}
+	if hasLink(t, repo, id, ada.ID, tasks.AssignedToLink) {
+		t.Error("unassigned ticket still linked to its old assignee")
+	}
+	history, err := tasks.History(ctx, repo, id)
+	if err != nil || len(history) != 2 || history[1].To != tasks.StatusDone || history[1].Note != "Closed in jira" || history[1].ChangedBy != "jira-sync" {
+		t.Errorf("History() = %+v, %v", history, err)
+	}
+
+	// The filter's own order is replaced and later searches are incremental
+	if len(jql) != 2 || jql[0] != "project = OPS ORDER BY updated ASC" ||
+		!strings.HasPrefix(jql[1], `(project = OPS) AND updated >= "-`) {
+		t.Errorf("JQL = %q", jql)
+	}
+	res, err = Sync(ctx, repo, src, false)
+	if err != nil || res.Unchanged != 1 || res.Updated != 0 {
+		t.Errorf("third Sync() = %+v, %v", res, err)
+	}
+	states, err := ListStates(ctx, repo)
+	if err != nil || len(states) != 1 || states[0].Tracker != "jira" {
+		t.Errorf("ListStates() = %+v, %v", states, err)
+	}
+}
+
+func TestSyncLinear(t *testing.T) {
+	ctx := context.Background()
+	repo := graph.NewMemory()
+
+	var filters []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_key" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Variables struct {
+				Filter map[string]interface{} `json:"filter"`
+				After  *string                `json:"after"`
+			} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		filters = append(filters, req.Variables.Filter)
+		if req.Variables.After == nil {
+			fmt.Fprint(w, `{"data": {"issues": {"nodes": [{"identifier": "ENG-7", "title": "Ship it", "url": "https://linear.app/acme/issue/ENG-7",
+				"updatedAt": "2026-05-01T00:00:00Z", "state": {"name": "In Review", "type": "started"}, "labels": {"nodes": [{"name": "api"}]}}],
+				"pageInfo": {"hasNextPage": true, "endCursor": "c1"}}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"issues": {"nodes": [{"identifier": "ENG-8", "title": "Old", "updatedAt": "2026-05-02T00:00:00Z",
+			"completedAt": "2026-05-02T00:00:00Z", "state": {"name": "Done", "type": "completed"}, "labels": {"nodes": []}}],
+			"pageInfo": {"hasNextPage": false}}}}`)
+	}))
+	defer srv.Close()
+	src := &Linear{APIKey: "lin_key", Team: "ENG", URL: srv.URL}
+
+	res, err := Sync(ctx, repo, src, false)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if res.Created != 2 || res.SyncedTo != "2026-05-02T00:00:00Z" {
+		t.Errorf("Sync() = %+v", res)
+	}
+	n, err := repo.GetNode(ctx, TicketID("linear", "ENG-7"))
+	if err != nil || n.Meta["status"] != tasks.StatusDoing || n.Meta["ticket_status"] != "In Review" || n.Meta["connector"] != "linear" {
+		t.Fatalf("ticket = %+v, %v", n, err)
+	}
+	if n, _ := repo.GetNode(ctx, TicketID("linear", "ENG-8")); n == nil || n.Meta["status"] != tasks.StatusDone || n.Meta["resolved_at"] != "2026-05-02T00:00:00Z" {
+		t.Errorf("done ticket = %+v", n)
+	}
+
+	if _, err := Sync(ctx, repo, src, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(filters) != 4 || filters[0]["updatedAt"] != nil || filters[2]["updatedAt"] == nil {
+		t.Errorf("filters = %v", filters)
+	}
+	if team, _ := json.Marshal(filters[0]["team"]); string(team) != `{"key":{"eq":"ENG"}}` {
+		t.Errorf("team filter = %s", team)
+	}
+}
@@ -0,0 +1,165 @@
+// Package tokens issues and verifies short-lived access tokens that limit
+// a client, typically an agent, to part of the graph.
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/lanes"
+)
+
+// Prefix marks access tokens, so they can be told apart from API keys
+const Prefix = "mxt_"
+
+// DefaultTTL is how long a token lasts when no expiry is requested
+const DefaultTTL = time.Hour
+
+// MaxTTL caps a token's lifetime
+const MaxTTL = 7 * 24 * time.Hour
+
+// Errors returned by Issue and Verify
+var (
+	ErrInvalid    = errors.New("invalid access token")
+	ErrExpired    = errors.New("access token has expired")
+	ErrBadRequest = errors.New("invalid token request")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Token is a verified access token: who it was issued to, what it may
+// touch and until when
+type Token struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Scope     graph.Scope `json:"scope"`
+	IssuedAt  time.Time   `json:"issued_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// claims is the signed token payload
+type claims struct {
+	ID         string   `json:"i"`
+	Name       string   `json:"n"`
+	Namespaces []string `json:"ns,omitempty"`
+	Types      []string `json:"t,omitempty"`
+	Write      bool     `json:"w,omitempty"`
+	Lane       string   `json:"l,omitempty"`
+	IssuedAt   int64    `json:"iat"` // Unix seconds
+	Expires    int64    `json:"exp"` // Unix seconds
+}
+
+// Issuer mints and verifies tokens with a key derived from the admin key.
+// Tokens cannot be revoked one by one; changing the admin key revokes all.
+type Issuer struct {
+	key []byte
+}
+
+// NewIssuer creates an issuer whose tokens stay valid for as long as the
+// same admin key is configured
+func NewIssuer(adminKey string) *Issuer {
+	key := sha256.Sum256([]byte("memex-token:" + adminKey))
+	return &Issuer{key: key[:]}
+}
+
+// Issue mints a token named name with the given scope, valid for ttl
+// (DefaultTTL when zero, at most MaxTTL)
+func (i *Issuer) Issue(name string, scope graph.Scope, ttl time.Duration, now time.Time) (string, *Token, error) {
+	if !namePattern.MatchString(name) {
+		return "", nil, fmt.Errorf("%w: name must be 1-64 letters, digits, '.', '_' or '-'", ErrBadRequest)
+	}
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	if ttl < 0 || ttl > MaxTTL {
+		return "", nil, fmt.Errorf("%w: expiry must be between 0 and %s", ErrBadRequest, MaxTTL)
+	}
+	for _, ns := range scope.Namespaces {
+		if ns == "" || strings.ContainsAny(ns, ": ") {
+			return "", nil, fmt.Errorf("%w: invalid namespace %q", ErrBadRequest, ns)
+		}
+	}
+	for _, t := range scope.Types {
+		if t == "" {
+			return "", nil, fmt.Errorf("%w: empty node type", ErrBadRequest)
+		}
+	}
+	if scope.Lane != "" && !lanes.Valid(scope.Lane) {
+		return "", nil, fmt.Errorf("%w: lane must be %s", ErrBadRequest, strings.Join(lanes.Names, " or "))
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("generating token ID: %w", err)
+	}
+	c := claims{
+		ID:         hex.EncodeToString(id),
+		Name:       name,
+		Namespaces: scope.Namespaces,
+		Types:      scope.Types,
+		Write:      scope.Write,
+		Lane:       scope.Lane,
+		IssuedAt:   now.Unix(),
+		Expires:    now.Add(ttl).Unix(),
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", nil, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return Prefix + encoded + "." + i.mac(encoded), c.token(), nil
+}
+
+// Verify checks a token's signature and expiry
+func (i *Issuer) Verify(raw string, now time.Time) (*Token, error) {
+	encoded, sig, ok := strings.Cut(strings.TrimPrefix(raw, Prefix), ".")
+	if !strings.HasPrefix(raw, Prefix) || !ok || !hmac.Equal([]byte(sig), []byte(i.mac(encoded))) {
+		return nil, ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalid
+	}
+	t := c.token()
+	if !now.Before(t.ExpiresAt) {
+		return t, ErrExpired
+	}
+	return t, nil
+}
+
+// IsToken reports whether a credential looks like an access token rather
+// than an API key
+func IsToken(raw string) bool {
+	return strings.HasPrefix(raw, Prefix)
+}
+
+func (c claims) token() *Token {
+	return &Token{
+		ID:        c.ID,
+		Name:      c.Name,
+		Scope:     graph.Scope{Namespaces: c.Namespaces, Types: c.Types, Write: c.Write, Lane: c.Lane},
+		IssuedAt:  time.Unix(c.IssuedAt, 0).UTC(),
+		ExpiresAt: time.Unix(c.Expires, 0).UTC(),
+	}
+}
+
+// mac signs an encoded payload
+func (i *Issuer) mac(encoded string) string {
+	h := hmac.New(sha256.New, i.key)
+	h.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
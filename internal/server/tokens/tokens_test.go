@@ -0,0 +1,51 @@
+package tokens
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/internal/server/graph"
+)
+
+func TestIssueAndVerify(t *testing.T) {
+	issuer := NewIssuer("admin-secret")
+	now := time.Now()
+	scope := graph.Scope{Namespaces: []string{"research"}, Types: []string{"Note"}}
+	raw, issued, err := issuer.Issue("scratch-agent", scope, 30*time.Minute, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsToken(raw) || issued.ExpiresAt.Unix() != now.Add(30*time.Minute).Unix() {
+		t.Fatalf("Issue() = %q, %+v", raw, issued)
+	}
+
+	tok, err := issuer.Verify(raw, now)
+	if err != nil || tok.ID != issued.ID || tok.Name != "scratch-agent" || tok.Scope.Write || tok.Scope.Namespaces[0] != "research" {
+		t.Fatalf("Verify() = %+v, %v", tok, err)
+	}
+	if _, err := issuer.Verify(raw, now.Add(time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+
+	// Rotating the admin key revokes every token
+	if _, err := NewIssuer("rotated").Verify(raw, now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid after key rotation, got %v", err)
+	}
+
+	// A token cannot widen its own scope
+	wider, _, _ := NewIssuer("other").Issue("scratch-agent", graph.Scope{Write: true}, 0, now)
+	payload, _, _ := strings.Cut(wider, ".")
+	_, sig, _ := strings.Cut(raw, ".")
+	if _, err := issuer.Verify(payload+"."+sig, now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a forged payload, got %v", err)
+	}
+
+	if _, _, err := issuer.Issue("agent", graph.Scope{}, MaxTTL+time.Second, now); !errors.Is(err, ErrBadRequest) {
+		t.Errorf("expected a TTL above MaxTTL to be rejected, got %v", err)
+	}
+	if _, _, err := issuer.Issue("agent", graph.Scope{Namespaces: []string{"a:b"}}, 0, now); !errors.Is(err, ErrBadRequest) {
+		t.Errorf("expected an invalid namespace to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package vision
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/systemshift/memex/internal/server/vision")
+
+// Repository is the subset of graph operations the processor needs
+type Repository interface {
+	GetNode(ctx context.Context, id string) (*core.Node, error)
+	UpdateNodeMetaWithNote(ctx context.Context, id string, meta map[string]any, changeNote, changedBy string) error
+}
+
+// Config holds vision model configuration
+type Config struct {
+	URL     string // OpenAI-compatible chat completions endpoint
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+}
+
+// imageNodeTypes are node types treated as images regardless of content type
+var imageNodeTypes = map[string]bool{
+	"Image":      true,
+	"Screenshot": true,
+}
+
+// Annotation is the caption and labels produced for an image
+type Annotation struct {
+	Caption string   `json:"caption"`
+	Labels  []string `json:"labels"`
+}
+
+// Processor captions image nodes as they are created
+type Processor struct {
+	repo       Repository
+	cfg        Config
+	httpClient *http.Client
+	eventChan  chan queuedEvent
+	hold       func(nodeID string) (release func())
+	wg         sync.WaitGroup
+}
+
+// queuedEvent is a queued node with the release for its processing hold
+type queuedEvent struct {
+	event   subscriptions.Event
+	release func()
+}
+
+// NewProcessor creates a new image captioning processor
+func NewProcessor(repo Repository, cfg Config) *Processor {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	return &Processor{
+		repo:       repo,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		eventChan:  make(chan queuedEvent, 100),
+	}
+}
+
+// Start begins processing events
+func (p *Processor) Start() {
+	p.wg.Add(1)
+	go p.processEvents()
+	log.Printf("Vision processor started (model: %s)", p.cfg.Model)
+}
+
+// Stop waits for queued images to finish processing
+func (p *Processor) Stop() {
+	close(p.eventChan)
+	p.wg.Wait()
+}
+
+// SetHold registers a callback invoked for each queued node; the returned
+// release is called once the node has been processed. Ingest completion
+// tracking uses it to wait for captions. Must be called before Start.
+func (p *Processor) SetHold(hold func(nodeID string) (release func())) {
+	p.hold = hold
+}
+
+// EmitEvent queues an event for processing (non-blocking)
+func (p *Processor) EmitEvent(event subscriptions.Event) {
+	if event.Type != subscriptions.EventNodeCreated {
+		return
+	}
+	release := func() {}
+	if p.hold != nil {
+		release = p.hold(event.NodeID)
+	}
+	select {
+	case p.eventChan <- queuedEvent{event: event, release: release}:
+	default:
+		release()
+		log.Printf("Warning: vision queue full, skipping node %s", event.NodeID)
+	}
+}
+
+// processEvents is the main processing loop
+func (p *Processor) processEvents() {
+	defer p.wg.Done()
+
+	for queued := range p.eventChan {
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		if err := p.ProcessNode(ctx, queued.event.NodeID); err != nil {
+			log.Printf("Vision processing failed for %s: %v", queued.event.NodeID, err)
+		}
+		cancel()
+		queued.release()
+	}
+}
+
+// ProcessNode captions a single node if it holds an image.
+// Nodes that are not images, or were already captioned, are skipped.
+func (p *Processor) ProcessNode(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "vision.ProcessNode", trace.WithAttributes(attribute.String("memex.node_id", id)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	node, err := p.repo.GetNode(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !IsImageNode(node) {
+		return nil
+	}
+	if _, done := node.Meta["caption"]; done {
+		return nil
+	}
+
+	imageURL, err := imageURLForNode(node)
+	if err != nil {
+		return err
+	}
+
+	annotation, err := p.describe(ctx, imageURL)
+	if err != nil {
+		return err
+	}
+
+	meta := map[string]any{
+		"caption":      annotation.Caption,
+		"labels":       annotation.Labels,
+		"vision_model": p.cfg.Model,
+		"captioned_at": time.Now().Format(time.RFC3339),
+	}
+	return p.repo.UpdateNodeMetaWithNote(ctx, id, meta, "Image captioned", "vision")
+}
+
+// IsImageNode reports whether a node holds image content
+func IsImageNode(node *core.Node) bool {
+	if imageNodeTypes[node.Type] {
+		return true
+	}
+	if ct, ok := node.Meta["content_type"].(string); ok && strings.HasPrefix(ct, "image/") {
+		return true
+	}
+	if ct, ok := node.Meta["mime_type"].(string); ok && strings.HasPrefix(ct, "image/") {
+		return true
+	}
+	return false
+}
+
+// imageURLForNode returns a URL the vision model can fetch, either from
+// meta["image_url"] or as a data URL built from the node content
+func imageURLForNode(node *core.Node) (string, error) {
+	if u, ok := node.Meta["image_url"].(string); ok && u != "" {
+		return u, nil
+	}
+	if len(node.Content) == 0 {
+		return "", fmt.Errorf("node %s has no image content", node.ID)
+	}
+
+	data := node.Content
+	// Content may already be base64 encoded (JSON clients can't send raw bytes)
+	if decoded, err := base64.StdEncoding.DecodeString(string(data)); err == nil {
+		data = decoded
+	}
+
+	contentType, _ := node.Meta["content_type"].(string)
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("node %s content is not an image (%s)", node.ID, contentType)
+	}
+
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+const describePrompt = `Describe this image for a personal knowledge base search index.
+Respond with JSON only: {"caption": "<one or two sentences>", "labels": ["<object, application, or visible text>", ...]}.
+Include names of applications, dashboards, and prominent on-screen text in labels.`
+
+// describe sends the image to the vision model and parses the annotation
+func (p *Processor) describe(ctx context.Context, imageURL string) (*Annotation, error) {
+	reqBody := map[string]interface{}{
+		"model": p.cfg.Model,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": describePrompt},
+					{"type": "image_url", "image_url": map[string]string{"url": imageURL}},
+				},
+			},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+	}
+
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling vision model: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("vision model returned status %d: %s", resp.StatusCode, body)
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return nil, fmt.Errorf("decoding vision response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("vision model returned no choices")
+	}
+
+	return parseAnnotation(completion.Choices[0].Message.Content)
+}
+
+// parseAnnotation extracts the annotation JSON from model output,
+// tolerating surrounding prose or code fences
+func parseAnnotation(content string) (*Annotation, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in vision response")
+	}
+
+	var annotation Annotation
+	if err := json.Unmarshal([]byte(content[start:end+1]), &annotation); err != nil {
+		return nil, fmt.Errorf("parsing vision annotation: %w", err)
+	}
+	if annotation.Labels == nil {
+		annotation.Labels = []string{}
+	}
+	return &annotation, nil
+}
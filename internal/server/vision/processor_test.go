@@ -0,0 +1,81 @@
+package vision
+
+import (
+	"testing"
+
+	"github.com/systemshift/memex/internal/memex/core"
+)
+
+func TestIsImageNode(t *testing.T) {
+	tests := []struct {
+		name string
+		node *core.Node
+		want bool
+	}{
+		{
+			name: "screenshot type",
+			node: &core.Node{Type: "Screenshot"},
+			want: true,
+		},
+		{
+			name: "image content type",
+			node: &core.Node{Type: "Source", Meta: map[string]interface{}{"content_type": "image/png"}},
+			want: true,
+		},
+		{
+			name: "text source",
+			node: &core.Node{Type: "Source", Meta: map[string]interface{}{"format": "text"}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsImageNode(tt.node); got != tt.want {
+				t.Errorf("IsImageNode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAnnotation(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantErr    bool
+		wantLabels int
+	}{
+		{
+			name:       "plain json",
+			content:    `{"caption":"A Grafana dashboard","labels":["grafana","dashboard"]}`,
+			wantLabels: 2,
+		},
+		{
+			name:       "fenced json",
+			content:    "```json\n{\"caption\":\"A terminal\",\"labels\":[\"terminal\"]}\n```",
+			wantLabels: 1,
+		},
+		{
+			name:       "missing labels",
+			content:    `{"caption":"A cat"}`,
+			wantLabels: 0,
+		},
+		{
+			name:    "no json",
+			content: "I cannot see the image",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotation, err := parseAnnotation(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAnnotation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(annotation.Labels) != tt.wantLabels {
+				t.Errorf("labels = %v, want %d", annotation.Labels, tt.wantLabels)
+			}
+		})
+	}
+}
@@ -50,6 +50,11 @@ class MemexMCP:
                             "type": "string",
                             "description": "Search term to find in nodes",
                         },
+                        "layers": {
+                            "type": "array",
+                            "items": {"type": "string", "enum": ["source", "derived", "attention", "manual"]},
+                            "description": "Only nodes in these layers: raw sources, extracted/derived, attention, or human-curated (manual)",
+                        },
                         "limit": {
                             "type": "integer",
                             "description": "Maximum number of results (default: 100)",
@@ -83,6 +88,11 @@ class MemexMCP:
                             "type": "string",
                             "description": "Property value to match",
                         },
+                        "layers": {
+                            "type": "array",
+                            "items": {"type": "string", "enum": ["source", "derived", "attention", "manual"]},
+                            "description": "Only nodes in these layers: raw sources, extracted/derived, attention, or human-curated (manual)",
+                        },
                         "limit": {
                             "type": "integer",
                             "description": "Maximum number of results (default: 100)",
@@ -116,6 +126,24 @@ class MemexMCP:
                             "items": {"type": "string"},
                             "description": "Filter by relationship types (e.g., ['AUTHORED', 'FIXED'])",
                         },
+                        "layers": {
+                            "type": "array",
+                            "items": {"type": "string", "enum": ["source", "derived", "attention", "manual"]},
+                            "description": "Only nodes in these layers: raw sources, extracted/derived, attention, or human-curated (manual)",
+                        },
+                        "hypothetical_edges": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "properties": {
+                                    "source": {"type": "string"},
+                                    "target": {"type": "string"},
+                                    "type": {"type": "string"},
+                                },
+                                "required": ["source", "target", "type"],
+                            },
+                            "description": "Links to assume exist for this traversal only, to see what would become reachable (not saved)",
+                        },
                         "limit": {
                             "type": "integer",
                             "description": "Maximum number of results (default: 100)",
@@ -236,6 +264,63 @@ class MemexMCP:
                     "required": ["lens_id"],
                 },
             ),
+            Tool(
+                name="report_feedback",
+                description="Report which search results you actually used, so Memex can measure and tune retrieval quality. Pass the retrieval_id returned by search_nodes.",
+                inputSchema={
+                    "type": "object",
+                    "properties": {
+                        "retrieval_id": {
+                            "type": "string",
+                            "description": "The retrieval_id from a search_nodes result",
+                        },
+                        "node_ids": {
+                            "type": "array",
+                            "items": {"type": "string"},
+                            "description": "IDs of the results you used",
+                        },
+                        "action": {
+                            "type": "string",
+                            "enum": ["click", "cite"],
+                            "description": "click if you opened the node, cite if you used it in your answer (default: cite)",
+                            "default": "cite",
+                        },
+                    },
+                    "required": ["retrieval_id", "node_ids"],
+                },
+            ),
+            Tool(
+                name="get_memory",
+                description="Load your long-term memory: short summaries (memory cards) of nodes pinned as worth remembering. Call this at the start of a task.",
+                inputSchema={
+                    "type": "object",
+                    "properties": {
+                        "budget": {
+                            "type": "integer",
+                            "description": "Maximum tokens of memory to load (default: 2000)",
+                            "default": 2000,
+                        },
+                    },
+                },
+            ),
+            Tool(
+                name="remember_node",
+                description="Pin a node into long-term memory as a memory card, so it is loaded by get_memory in future tasks.",
+                inputSchema={
+                    "type": "object",
+                    "properties": {
+                        "node_id": {
+                            "type": "string",
+                            "description": "ID of the node to remember",
+                        },
+                        "summary": {
+                            "type": "string",
+                            "description": "What to remember about it; distilled from the node when omitted",
+                        },
+                    },
+                    "required": ["node_id"],
+                },
+            ),
         ]
 
     async def call_tool(self, name: str, arguments: dict) -> list[TextContent]:
@@ -261,6 +346,12 @@ class MemexMCP:
                 return await self._query_by_lens(arguments)
             elif name == "export_lens":
                 return await self._export_lens(arguments)
+            elif name == "report_feedback":
+                return await self._report_feedback(arguments)
+            elif name == "get_memory":
+                return await self._get_memory(arguments)
+            elif name == "remember_node":
+                return await self._remember_node(arguments)
             else:
                 return [TextContent(type="text", text=f"Unknown tool: {name}")]
         except Exception as e:
@@ -273,15 +364,57 @@ class MemexMCP:
             "limit": args.get("limit", 100),
             "offset": args.get("offset", 0),
         }
+        if args.get("layers"):
+            params["layer"] = ",".join(args["layers"])
+
         response = await self.client.get("/api/query/search", params=params)
         response.raise_for_status()
         data = response.json()
 
+        text = f"Found {data['count']} nodes:\n\n" + json.dumps(data["nodes"], indent=2)
+        if data.get("retrieval_id"):
+            text += f"\n\nretrieval_id: {data['retrieval_id']} (use with report_feedback)"
+        return [TextContent(type="text", text=text)]
+
+    async def _report_feedback(self, args: dict) -> list[TextContent]:
+        """Report the search results that were used"""
+        response = await self.client.post("/api/feedback", json={
+            "retrieval_id": args["retrieval_id"],
+            "node_ids": args["node_ids"],
+            "action": args.get("action", "cite"),
+        })
+        response.raise_for_status()
+        data = response.json()
+
         return [TextContent(
             type="text",
-            text=f"Found {data['count']} nodes:\n\n" + json.dumps(data["nodes"], indent=2)
+            text=f"Recorded {data['action']} on {data['recorded']} results"
         )]
 
+    async def _get_memory(self, args: dict) -> list[TextContent]:
+        """Load the active memory cards"""
+        response = await self.client.get("/api/memory", params={"budget": args.get("budget", 2000)})
+        response.raise_for_status()
+        data = response.json()
+
+        if not data["cards"]:
+            return [TextContent(type="text", text="No memory cards")]
+        text = f"{len(data['cards'])} memory cards ({data['tokens']} tokens):\n\n{data['text']}"
+        if data["omitted"]:
+            text += f"\n\n{len(data['omitted'])} more did not fit the budget"
+        return [TextContent(type="text", text=text)]
+
+    async def _remember_node(self, args: dict) -> list[TextContent]:
+        """Pin a node as a memory card"""
+        body = {"node_id": args["node_id"], "pinned": True}
+        if args.get("summary"):
+            body["summary"] = args["summary"]
+        response = await self.client.post("/api/memory/cards", json=body)
+        response.raise_for_status()
+        card = response.json()
+
+        return [TextContent(type="text", text=f"Remembered {card['node_id']}: {card['summary']}")]
+
     async def _filter_nodes(self, args: dict) -> list[TextContent]:
         """Filter nodes by type and properties"""
         params = {
@@ -298,6 +431,9 @@ class MemexMCP:
         if "property_value" in args:
             params["value"] = args["property_value"]
 
+        if args.get("layers"):
+            params["layer"] = ",".join(args["layers"])
+
         response = await self.client.get("/api/query/filter", params=params)
         response.raise_for_status()
         data = response.json()
@@ -320,6 +456,12 @@ class MemexMCP:
             for rt in args["relationship_types"]:
                 params["rel_type"] = rt
 
+        if args.get("layers"):
+            params["layer"] = ",".join(args["layers"])
+
+        if args.get("hypothetical_edges"):
+            params["hypothetical"] = json.dumps(args["hypothetical_edges"])
+
         response = await self.client.get("/api/query/traverse", params=params)
         response.raise_for_status()
         data = response.json()
@@ -0,0 +1,388 @@
+// Package client calls a memex-server over HTTP. It covers ingest, nodes and
+// links, lenses, search, attention edges, read snapshots and the event
+// stream; request and response types are the server's own, so the two
+// cannot drift apart.
+//
+//	c := client.New("http://localhost:8080", os.Getenv("MEMEX_API_KEY"))
+//	res, err := c.BulkIngest(ctx, []client.IngestRequest{{Content: "hello", Format: "text"}})
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	"github.com/systemshift/memex/internal/server/api"
+	"github.com/systemshift/memex/internal/server/apiversion"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/snapshots"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Graph data and API bodies, shared with memex-server
+type (
+	Node     = core.Node
+	Link     = core.Link
+	Subgraph = graph.Subgraph
+	Event    = subscriptions.Event
+
+	IngestRequest        = api.IngestRequest
+	IngestResponse       = api.IngestResponse
+	BulkIngestResponse   = api.BulkIngestResponse
+	CreateNodeRequest    = api.CreateNodeRequest
+	CreateNodeResponse   = api.CreateNodeResponse
+	CreateLinkRequest    = api.CreateLinkRequest
+	CreateLensRequest    = api.CreateLensRequest
+	AttentionEdgeRequest = api.UpdateAttentionEdgeRequest
+	SnapshotInfo         = snapshots.Info
+)
+
+// Client calls one memex-server
+type Client struct {
+	BaseURL string       // e.g. http://localhost:8080
+	APIKey  string       // Sent as X-API-Key when set
+	HTTP    *http.Client // http.DefaultClient when nil
+
+	DeviceKey string // Sent as X-Memex-Device when set, stamping writes with the device
+
+	Snapshot string // Pins reads to this snapshot when set; see Pinned
+
+	// OnDeprecated, when set, is called for each response from a
+	// deprecated route, with the route's sunset date (zero if none)
+	OnDeprecated func(req *http.Request, sunset time.Time)
+}
+
+// APIVersion is the API version this package is written against, asked
+// for on every request
+const APIVersion = 1
+
+// New creates a client for the server at baseURL
+func New(baseURL, apiKey string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), APIKey: apiKey}
+}
+
+// Error codes the server reports, for comparing with Error.Code
+const (
+	CodeValidation         = api.CodeValidation
+	CodeUnauthorized       = api.CodeUnauthorized
+	CodeForbidden          = api.CodeForbidden
+	CodeNotFound           = api.CodeNotFound
+	CodeConflict           = api.CodeConflict
+	CodePrecondition       = api.CodePrecondition
+	CodeRateLimited        = api.CodeRateLimited
+	CodeBackendUnavailable = api.CodeBackendUnavailable
+	CodeNotEnabled         = api.CodeNotEnabled
+	CodeTimeout            = api.CodeTimeout
+	CodeQuotaExceeded      = api.CodeQuotaExceeded
+)
+
+// Error is a response with a status other than 2xx
+type Error struct {
+	Status    int
+	Code      string // Machine-readable, e.g. not_found or quota_exceeded
+	Message   string
+	Details   map[string]interface{}
+	Retryable bool // The same request may succeed later
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("memex-server returned %d (%s): %s", e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("memex-server returned %d: %s", e.Status, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the server
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
+
+// ErrorCode returns the code of an error from the server, or ""
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// IsRetryable reports whether err is a server error that the same
+// request may get past later, such as a busy backend or a rate limit
+func IsRetryable(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Retryable
+}
+
+// ReadError reads a non-2xx response into an *Error. Servers from
+// before the error envelope answer in plain text, which becomes the
+// message.
+func ReadError(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var envelope api.ErrorResponse
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error.Code != "" {
+		e := envelope.Error
+		return &Error{Status: resp.StatusCode, Code: e.Code, Message: e.Message, Details: e.Details, Retryable: e.Retryable, RequestID: e.RequestID}
+	}
+	return &Error{Status: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+}
+
+// request builds a request for an API path, with the JSON body if any
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	u := c.BaseURL + "/api" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(apiversion.Header, strconv.Itoa(APIVersion))
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	if c.DeviceKey != "" {
+		req.Header.Set("X-Memex-Device", c.DeviceKey)
+	}
+	if c.Snapshot != "" {
+		req.Header.Set("X-Memex-Snapshot", c.Snapshot)
+	}
+	return req, nil
+}
+
+// send runs a request, returning an *Error for non-2xx responses
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.OnDeprecated != nil && resp.Header.Get("Deprecation") != "" {
+		sunset, _ := http.ParseTime(resp.Header.Get("Sunset"))
+		c.OnDeprecated(req, sunset)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, ReadError(resp)
+	}
+	return resp, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out, if set
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	req, err := c.request(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// Ingest stores content as a source node
+func (c *Client) Ingest(ctx context.Context, req IngestRequest) (*IngestResponse, error) {
+	var out IngestResponse
+	return &out, c.do(ctx, "POST", "/ingest", nil, req, &out)
+}
+
+// BulkIngest stores many sources in one request. Sources already ingested
+// come back marked deduplicated.
+func (c *Client) BulkIngest(ctx context.Context, sources []IngestRequest) (*BulkIngestResponse, error) {
+	var out BulkIngestResponse
+	return &out, c.do(ctx, "POST", "/ingest/bulk", nil, api.BulkIngestRequest{Sources: sources}, &out)
+}
+
+// GetNode returns the current version of a node
+func (c *Client) GetNode(ctx context.Context, id string) (*Node, error) {
+	var out Node
+	return &out, c.do(ctx, "GET", "/nodes/"+url.PathEscape(id), nil, nil, &out)
+}
+
+// CreateNode creates a node
+func (c *Client) CreateNode(ctx context.Context, req CreateNodeRequest) (*CreateNodeResponse, error) {
+	var out CreateNodeResponse
+	return &out, c.do(ctx, "POST", "/nodes", nil, req, &out)
+}
+
+// GetLinks returns the outgoing links of a node
+func (c *Client) GetLinks(ctx context.Context, id string) ([]*Link, error) {
+	var out []*Link
+	return out, c.do(ctx, "GET", "/nodes/"+url.PathEscape(id)+"/links", nil, nil, &out)
+}
+
+// CreateLink creates a link between two nodes
+func (c *Client) CreateLink(ctx context.Context, req CreateLinkRequest) error {
+	return c.do(ctx, "POST", "/links", nil, req, nil)
+}
+
+// CreateLens stores a lens; its ID gets the lens: prefix if it lacks one
+func (c *Client) CreateLens(ctx context.Context, req CreateLensRequest) (string, error) {
+	var out struct {
+		ID string `json:"id"`
+	}
+	return out.ID, c.do(ctx, "POST", "/lenses", nil, req, &out)
+}
+
+// LensEntities returns the nodes interpreted through a lens
+func (c *Client) LensEntities(ctx context.Context, lensID string) ([]*Node, error) {
+	var out struct {
+		Entities []*Node `json:"entities"`
+	}
+	return out.Entities, c.do(ctx, "GET", "/lenses/"+url.PathEscape(lensID)+"/entities", nil, nil, &out)
+}
+
+// SearchResult is a page of full-text search hits, ranked by trust
+type SearchResult struct {
+	Nodes       []*Node            `json:"nodes"`
+	Count       int                `json:"count"`
+	Trust       map[string]float64 `json:"trust,omitempty"`
+	RetrievalID string             `json:"retrieval_id,omitempty"` // Pass to the feedback API
+}
+
+// Search runs a full-text search
+func (c *Client) Search(ctx context.Context, q string, limit int) (*SearchResult, error) {
+	query := url.Values{"q": {q}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out SearchResult
+	return &out, c.do(ctx, "GET", "/query/search", query, nil, &out)
+}
+
+// UpdateAttention strengthens the attention edge between two nodes
+func (c *Client) UpdateAttention(ctx context.Context, req AttentionEdgeRequest) error {
+	return c.do(ctx, "POST", "/edges/attention", nil, req, nil)
+}
+
+// AttentionSubgraph returns the nodes reachable from start over attention
+// edges of at least minWeight, up to maxNodes
+func (c *Client) AttentionSubgraph(ctx context.Context, start string, minWeight float64, maxNodes int) (*Subgraph, error) {
+	query := url.Values{
+		"start":      {start},
+		"min_weight": {strconv.FormatFloat(minWeight, 'f', -1, 64)},
+		"max_nodes":  {strconv.Itoa(maxNodes)},
+	}
+	var out Subgraph
+	return &out, c.do(ctx, "GET", "/query/attention_subgraph", query, nil, &out)
+}
+
+// BeginSnapshot pins a view of the graph for ttl, or the server's default
+// lifetime when ttl is zero
+func (c *Client) BeginSnapshot(ctx context.Context, ttl time.Duration) (*SnapshotInfo, error) {
+	var out SnapshotInfo
+	return &out, c.do(ctx, "POST", "/snapshots", nil, api.CreateSnapshotRequest{TTLSeconds: int(ttl.Seconds())}, &out)
+}
+
+// EndSnapshot releases a snapshot before it expires
+func (c *Client) EndSnapshot(ctx context.Context, token string) error {
+	return c.do(ctx, "DELETE", "/snapshots/"+url.PathEscape(token), nil, nil, nil)
+}
+
+// Pinned returns a copy of the client whose reads see the snapshot's view.
+// The server rejects writes sent through it.
+func (c *Client) Pinned(token string) *Client {
+	pinned := *c
+	pinned.Snapshot = token
+	return &pinned
+}
+
+// EventFilter narrows the event stream; empty fields match everything
+type EventFilter struct {
+	Types     []string // e.g. node.created
+	NodeTypes []string
+	LinkTypes []string
+	Expr      string // Subscription expression events must satisfy
+}
+
+// StreamEvents calls fn with each graph change as it happens, until ctx is
+// cancelled or the connection drops. It returns io.EOF when the server
+// closes the stream.
+func (c *Client) StreamEvents(ctx context.Context, filter EventFilter, fn func(Event)) error {
+	query := url.Values{}
+	if len(filter.Types) > 0 {
+		query.Set("type", strings.Join(filter.Types, ","))
+	}
+	if len(filter.NodeTypes) > 0 {
+		query.Set("node_type", strings.Join(filter.NodeTypes, ","))
+	}
+	if len(filter.LinkTypes) > 0 {
+		query.Set("link_type", strings.Join(filter.LinkTypes, ","))
+	}
+	if filter.Expr != "" {
+		query.Set("expr", filter.Expr)
+	}
+	req, err := c.request(ctx, "GET", "/events/stream", query, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// A blank line ends an event
+			if data.Len() > 0 {
+				var event Event
+				if err := json.Unmarshal([]byte(data.String()), &event); err == nil {
+					fn(event)
+				}
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
@@ -0,0 +1,212 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/systemshift/memex/internal/server/api"
+	"github.com/systemshift/memex/internal/server/apiversion"
+	"github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/snapshots"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+	"github.com/systemshift/memex/pkg/client"
+)
+
+// sunset is when the test server's deprecated search route goes
+var sunset = time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)
+
+// newServer serves the routes the client calls over a SQLite graph
+func newServer(t *testing.T) *client.Client {
+	ctx := context.Background()
+	repo, err := graph.NewSQLite(ctx, filepath.Join(t.TempDir(), "memex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close(ctx) })
+	subMgr := subscriptions.NewManager(repo)
+	if err := subMgr.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(subMgr.Stop)
+	repo.SetEventEmitter(subMgr.EmitEvent)
+	s := api.New(repo, subMgr)
+	snaps := snapshots.NewManager(repo, time.Minute, time.Minute, 4)
+	t.Cleanup(snaps.Close)
+	s.SetSnapshots(snaps)
+	s.SetDeprecations(apiversion.NewTracker([]apiversion.Notice{{Method: "GET", Route: "/query/search", Sunset: sunset}}))
+
+	r := chi.NewRouter()
+	r.Route("/api", func(r chi.Router) {
+		r.Use(api.Errors)
+		r.Use(s.Versioning(r))
+		r.Use(s.PinSnapshot)
+		r.Post("/ingest", s.Ingest)
+		r.Post("/ingest/bulk", s.BulkIngest)
+		r.Post("/nodes", s.CreateNode)
+		r.Get("/nodes/{id}", s.GetNode)
+		r.Get("/nodes/{id}/links", s.GetLinks)
+		r.Post("/links", s.CreateLink)
+		r.Post("/lenses", s.CreateLens)
+		r.Get("/lenses/{id}/entities", s.GetLensEntities)
+		r.Get("/query/search", s.QuerySearch)
+		r.Post("/edges/attention", s.UpdateAttentionEdge)
+		r.Get("/query/attention_subgraph", s.QueryAttentionSubgraph)
+		r.Get("/events/stream", s.StreamEvents)
+		r.Post("/snapshots", s.CreateSnapshot)
+		r.Delete("/snapshots/{token}", s.DeleteSnapshot)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return client.New(srv.URL, "")
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	c := newServer(t)
+
+	events := make(chan client.Event, 10)
+	streamCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go c.StreamEvents(streamCtx, client.EventFilter{NodeTypes: []string{"Concept"}}, func(e client.Event) { events <- e })
+	time.Sleep(50 * time.Millisecond) // Let the stream connect
+
+	res, err := c.BulkIngest(ctx, []client.IngestRequest{
+		{Content: "notes on the analytical engine", Format: "text"},
+		{Content: "notes on the analytical engine", Format: "text"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Sources) != 2 || res.Sources[0].SourceID != res.Sources[1].SourceID {
+		t.Errorf("BulkIngest = %+v", res)
+	}
+	source := res.Sources[0].SourceID
+
+	if _, err := c.GetNode(ctx, "concept:engine"); !client.IsNotFound(err) || client.ErrorCode(err) != client.CodeNotFound || client.IsRetryable(err) {
+		t.Errorf("GetNode of a missing node: %v", err)
+	}
+	if _, err := c.CreateNode(ctx, client.CreateNodeRequest{ID: "concept:engine", Type: "Concept", Meta: map[string]interface{}{"name": "Analytical Engine"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateLink(ctx, client.CreateLinkRequest{Source: "concept:engine", Target: source, Type: "EXTRACTED_FROM"}); err != nil {
+		t.Fatal(err)
+	}
+	lens, err := c.CreateLens(ctx, client.CreateLensRequest{ID: "computing", Name: "Computing", Primitives: map[string]string{"machine": "a computing device"}})
+	if err != nil || lens != "lens:computing" {
+		t.Fatalf("CreateLens = %q, %v", lens, err)
+	}
+	if err := c.CreateLink(ctx, client.CreateLinkRequest{Source: "concept:engine", Target: lens, Type: "INTERPRETED_THROUGH"}); err != nil {
+		t.Fatal(err)
+	}
+	if links, err := c.GetLinks(ctx, "concept:engine"); err != nil || len(links) != 2 {
+		t.Errorf("GetLinks = %d links, %v; want 2", len(links), err)
+	}
+	entities, err := c.LensEntities(ctx, lens)
+	if err != nil || len(entities) != 1 || entities[0].ID != "concept:engine" {
+		t.Errorf("LensEntities = %v, %v", entities, err)
+	}
+
+	var deprecated []time.Time
+	c.OnDeprecated = func(req *http.Request, s time.Time) { deprecated = append(deprecated, s) }
+	found, err := c.Search(ctx, "analytical", 10)
+	if err != nil || found.Count == 0 {
+		t.Errorf("Search = %+v, %v", found, err)
+	}
+	if len(deprecated) != 1 || !deprecated[0].Equal(sunset) {
+		t.Errorf("deprecation notices = %v, want one with sunset %v", deprecated, sunset)
+	}
+
+	if err := c.UpdateAttention(ctx, client.AttentionEdgeRequest{Source: "concept:engine", Target: source, QueryID: "q1", Weight: 0.9}); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := c.AttentionSubgraph(ctx, "concept:engine", 0.5, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attended := false
+	for _, n := range sub.Nodes {
+		attended = attended || n.ID == source
+	}
+	if !attended {
+		t.Errorf("AttentionSubgraph is missing %s: %+v", source, sub)
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != subscriptions.EventNodeCreated || e.NodeID != "concept:engine" {
+			t.Errorf("first event = %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("no event streamed")
+	}
+}
+
+func TestClientSnapshot(t *testing.T) {
+	ctx := context.Background()
+	c := newServer(t)
+
+	if _, err := c.CreateNode(ctx, client.CreateNodeRequest{ID: "note:before", Type: "Note"}); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := c.BeginSnapshot(ctx, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateNode(ctx, client.CreateNodeRequest{ID: "note:after", Type: "Note"}); err != nil {
+		t.Fatal(err)
+	}
+
+	pinned := c.Pinned(snap.Token)
+	if _, err := pinned.GetNode(ctx, "note:before"); err != nil {
+		t.Errorf("pinned GetNode(note:before): %v", err)
+	}
+	if _, err := pinned.GetNode(ctx, "note:after"); !client.IsNotFound(err) {
+		t.Errorf("pinned GetNode(note:after) = %v, want not found", err)
+	}
+	if _, err := c.GetNode(ctx, "note:after"); err != nil {
+		t.Errorf("GetNode(note:after): %v", err)
+	}
+	if err := c.EndSnapshot(ctx, snap.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pinned.GetNode(ctx, "note:before"); err == nil {
+		t.Error("GetNode through an ended snapshot succeeded")
+	}
+}
+
+func TestReadError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		handler   http.HandlerFunc
+		code      string
+		message   string
+		retryable bool
+	}{
+		"envelope": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error": {"code": "backend_unavailable", "message": "database is locked", "retryable": true}}`))
+			},
+			code: client.CodeBackendUnavailable, message: "database is locked", retryable: true,
+		},
+		// Servers from before the envelope answer in plain text
+		"plain text": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Node not found", http.StatusNotFound)
+			},
+			message: "Node not found",
+		},
+	} {
+		rec := httptest.NewRecorder()
+		tc.handler(rec, httptest.NewRequest("GET", "/", nil))
+		e := client.ReadError(rec.Result())
+		if e.Code != tc.code || e.Message != tc.message || e.Retryable != tc.retryable || e.Status != rec.Code {
+			t.Errorf("%s: got %+v", name, e)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package graph embeds memex's graph engine in a Go program. It stores
+// nodes and links in a SQLite file, with the same schema, versioning,
+// tombstones and queries as memex-server, without running an HTTP server.
+// A file written here can later be served by memex-server, and the reverse.
+//
+//	g, err := graph.Open(ctx, "notes.db", nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer g.Close(ctx)
+//
+//	g.CreateNode(ctx, &graph.Node{ID: "note:1", Type: "Note", Content: []byte("hello")})
+//	nodes, err := g.SearchNodes(ctx, "hello", 10, 0)
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/systemshift/memex/internal/memex/core"
+	igraph "github.com/systemshift/memex/internal/server/graph"
+	"github.com/systemshift/memex/internal/server/subscriptions"
+)
+
+// Graph data, shared with memex-server
+type (
+	Node        = core.Node
+	Link        = core.Link
+	VersionInfo = core.VersionInfo
+	Event       = subscriptions.Event
+
+	// Subscription is stored by the subscription methods of Repository
+	Subscription = subscriptions.Subscription
+)
+
+// Repository is an open graph. Every method is safe for concurrent use.
+type Repository = igraph.Repository
+
+// Configuration for Options
+type (
+	WriteBehindConfig = igraph.WriteBehindConfig
+	CacheConfig       = igraph.CacheConfig
+)
+
+// Event types
+const (
+	EventNodeCreated = subscriptions.EventNodeCreated
+	EventNodeUpdated = subscriptions.EventNodeUpdated
+	EventNodeDeleted = subscriptions.EventNodeDeleted
+	EventLinkCreated = subscriptions.EventLinkCreated
+	EventLinkDeleted = subscriptions.EventLinkDeleted
+)
+
+// What deleting a node does to its links
+const (
+	CascadeKeep      = igraph.CascadeKeep
+	CascadeTombstone = igraph.CascadeTombstone
+	CascadeRewire    = igraph.CascadeRewire
+	CascadeRestrict  = igraph.CascadeRestrict
+)
+
+// Errors returned by Repository methods
+var (
+	ErrCycle        = igraph.ErrCycle
+	ErrNodeHasLinks = igraph.ErrNodeHasLinks
+	ErrProtected    = igraph.ErrProtected
+)
+
+// Options configures an embedded graph. A nil *Options behaves like a
+// memex-server with default settings.
+type Options struct {
+	WriteBehind  WriteBehindConfig // Group small writes into transactions; zero MaxLatency commits each write
+	Cache        CacheConfig       // Cache hot nodes and subgraphs; zero Size disables
+	Cascade      string            // What deleting a node does to its links; empty keeps them
+	DAGLinkTypes []string          // Link types that may never form a cycle
+	OnEvent      func(Event)       // Called with every committed change
+}
+
+// Open opens the SQLite graph at path, creating or upgrading it as needed
+func Open(ctx context.Context, path string, opts *Options) (Repository, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := igraph.ValidateCascade(cascadeMode(opts)); err != nil {
+		return nil, err
+	}
+	repo, err := igraph.NewSQLite(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("opening graph: %w", err)
+	}
+	if opts.WriteBehind.MaxLatency > 0 {
+		repo.EnableWriteBehind(opts.WriteBehind)
+	}
+	return wrap(repo, opts), nil
+}
+
+// OpenMemory returns an empty graph held in memory, for tests and
+// short-lived programs
+func OpenMemory(opts *Options) (Repository, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := igraph.ValidateCascade(cascadeMode(opts)); err != nil {
+		return nil, err
+	}
+	return wrap(igraph.NewMemory(), opts), nil
+}
+
+// cascadeMode returns the configured delete cascade, defaulting to keep
+func cascadeMode(opts *Options) string {
+	if opts.Cascade == "" {
+		return CascadeKeep
+	}
+	return opts.Cascade
+}
+
+// wrap applies opts in the order memex-server does
+func wrap(repo Repository, opts *Options) Repository {
+	if opts.Cache.Size > 0 {
+		repo = igraph.WithCache(repo, opts.Cache)
+	}
+	if len(opts.DAGLinkTypes) > 0 {
+		repo = igraph.WithDAGConstraint(repo, opts.DAGLinkTypes)
+	}
+	repo = igraph.WithDeleteCascade(repo, cascadeMode(opts))
+	if opts.OnEvent != nil {
+		repo.SetEventEmitter(opts.OnEvent)
+	}
+	return repo
+}
@@ -0,0 +1,104 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/systemshift/memex/pkg/graph"
+)
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "notes.db")
+
+	var mu sync.Mutex
+	var events []string
+	g, err := graph.Open(ctx, path, &graph.Options{
+		DAGLinkTypes: []string{"DERIVED_FROM"},
+		OnEvent: func(e graph.Event) {
+			mu.Lock()
+			events = append(events, e.Type)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, n := range []*graph.Node{
+		{ID: "person:ada", Type: "Person", Content: []byte("Ada Lovelace"), Created: now, Modified: now},
+		{ID: "note:engine", Type: "Note", Content: []byte("notes on the analytical engine"), Created: now, Modified: now},
+	} {
+		if err := g.CreateNode(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.CreateLink(ctx, &graph.Link{Source: "note:engine", Target: "person:ada", Type: "DERIVED_FROM", Created: now, Modified: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CreateLink(ctx, &graph.Link{Source: "person:ada", Target: "note:engine", Type: "DERIVED_FROM", Created: now, Modified: now}); !errors.Is(err, graph.ErrCycle) {
+		t.Errorf("cyclic link error = %v, want ErrCycle", err)
+	}
+
+	pattern, err := graph.ParsePattern("N:Note -[DERIVED_FROM]-> P:Person", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bindings, err := g.MatchPattern(ctx, pattern, 10)
+	if err != nil || len(bindings) != 1 || bindings[0]["P"] != "person:ada" {
+		t.Errorf("MatchPattern() = %v, %v", bindings, err)
+	}
+	backlinks, err := graph.GetBacklinkGroups(ctx, g, "person:ada", nil, nil, 10)
+	if err != nil || backlinks.Count != 1 {
+		t.Errorf("GetBacklinkGroups() = %+v, %v", backlinks, err)
+	}
+
+	mu.Lock()
+	if len(events) != 3 || events[0] != graph.EventNodeCreated || events[2] != graph.EventLinkCreated {
+		t.Errorf("events = %v", events)
+	}
+	mu.Unlock()
+	if err := g.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file keeps the graph
+	g, err = graph.Open(ctx, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close(ctx)
+	if n, err := g.GetNode(ctx, "note:engine"); err != nil || string(n.Content) != "notes on the analytical engine" {
+		t.Errorf("GetNode() after reopening = %+v, %v", n, err)
+	}
+	if links, err := g.GetLinks(ctx, "note:engine"); err != nil || len(links) != 1 {
+		t.Errorf("GetLinks() after reopening = %v, %v", links, err)
+	}
+}
+
+func TestOpenMemory(t *testing.T) {
+	ctx := context.Background()
+	if _, err := graph.OpenMemory(&graph.Options{Cascade: "explode"}); err == nil {
+		t.Error("OpenMemory() accepted an invalid cascade mode")
+	}
+
+	g, err := graph.OpenMemory(&graph.Options{Cascade: graph.CascadeRestrict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	g.CreateNode(ctx, &graph.Node{ID: "a", Type: "Note", Created: now, Modified: now})
+	g.CreateNode(ctx, &graph.Node{ID: "b", Type: "Note", Created: now, Modified: now})
+	g.CreateLink(ctx, &graph.Link{Source: "a", Target: "b", Type: "MENTIONS", Created: now, Modified: now})
+	if err := g.DeleteNode(ctx, "a", false); !errors.Is(err, graph.ErrNodeHasLinks) {
+		t.Errorf("restricted delete error = %v, want ErrNodeHasLinks", err)
+	}
+	if err := g.DeleteNode(graph.WithCascade(ctx, graph.CascadeTombstone), "a", false); err != nil {
+		t.Errorf("delete with cascade override = %v", err)
+	}
+}
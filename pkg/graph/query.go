@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"context"
+
+	igraph "github.com/systemshift/memex/internal/server/graph"
+)
+
+// Results of Repository methods
+type (
+	Subgraph            = igraph.Subgraph
+	SubgraphEdge        = igraph.SubgraphEdge
+	GraphMap            = igraph.GraphMap
+	Timeline            = igraph.Timeline
+	GraphDiff           = igraph.GraphDiff
+	Usage               = igraph.Usage
+	IntegrityOptions    = igraph.IntegrityOptions
+	IntegrityReport     = igraph.IntegrityReport
+	DegreeRepairOptions = igraph.DegreeRepairOptions
+	DegreeRepair        = igraph.DegreeRepair
+	LensExport          = igraph.LensExport
+	Pattern             = igraph.Pattern
+	PatternBinding      = igraph.PatternBinding
+)
+
+// Query types
+type (
+	AggregateQuery  = igraph.AggregateQuery
+	Aggregate       = igraph.Aggregate
+	Backlinks       = igraph.Backlinks
+	LayerFilter     = igraph.LayerFilter
+	PageFunc        = igraph.PageFunc
+	Provenance      = igraph.Provenance
+	DAGReport       = igraph.DAGReport
+	TraversalBudget = igraph.TraversalBudget
+	TraversalMeter  = igraph.TraversalMeter
+	TrustPolicy     = igraph.TrustPolicy
+	NodeDiff        = igraph.NodeDiff
+	TimeFilter      = igraph.TimeFilter
+)
+
+// Graph layers
+const (
+	LayerSource    = igraph.LayerSource
+	LayerDerived   = igraph.LayerDerived
+	LayerAttention = igraph.LayerAttention
+	LayerManual    = igraph.LayerManual
+)
+
+// ParsePattern parses a pattern for Repository.MatchPattern, such as
+// "A:Person -[WORKS_AT]-> B:Company"; where adds property constraints per
+// variable
+func ParsePattern(src string, where map[string]map[string]interface{}) (*Pattern, error) {
+	return igraph.ParsePattern(src, where)
+}
+
+// RunAggregate counts and summarizes the nodes q selects
+func RunAggregate(ctx context.Context, repo Repository, q AggregateQuery) (*Aggregate, error) {
+	return igraph.RunAggregate(ctx, repo, q)
+}
+
+// GetBacklinkGroups returns the links into a node grouped by link type
+func GetBacklinkGroups(ctx context.Context, repo Repository, nodeID string, linkTypes []string, layers LayerFilter, limit int) (*Backlinks, error) {
+	return igraph.GetBacklinkGroups(ctx, repo, nodeID, linkTypes, layers, limit)
+}
+
+// TraceProvenance follows a node's derivation links back to its sources
+func TraceProvenance(ctx context.Context, repo Repository, id string, maxDepth int) (*Provenance, error) {
+	return igraph.TraceProvenance(ctx, repo, id, maxDepth)
+}
+
+// FindCycles reports cycles among links of the given types
+func FindCycles(ctx context.Context, repo Repository, linkTypes []string, maxCycles int) (*DAGReport, error) {
+	return igraph.FindCycles(ctx, repo, linkTypes, maxCycles)
+}
+
+// ParseLayers reads layer names from repeated or comma-separated values,
+// returning nil (every layer) when none are given
+func ParseLayers(values []string) (LayerFilter, error) {
+	return igraph.ParseLayers(values)
+}
+
+// FillPage returns the limit nodes after offset that keep lets through,
+// reading further pages from fetch as needed
+func FillPage(limit, offset int, keep func([]*Node) []*Node, fetch PageFunc) ([]*Node, error) {
+	return igraph.FillPage(limit, offset, keep, fetch)
+}
+
+// ParseTrustPolicy parses connector and domain trust such as
+// "domain:arxiv.org=0.9,connector:rss=0.4"
+func ParseTrustPolicy(spec string) (*TrustPolicy, error) {
+	return igraph.ParseTrustPolicy(spec)
+}
+
+// Properties bounding when a node or link held in the world; valid_to is
+// exclusive
+const (
+	ValidFromKey = igraph.ValidFromKey
+	ValidToKey   = igraph.ValidToKey
+)
+
+// TargetVersionKey is the link property pinning a link to one version of
+// its target, such as "paper:x:v2"
+const TargetVersionKey = igraph.TargetVersionKey
+
+// PinnedSubgraph returns a copy of sub with each node resolved to the
+// version the links into it are pinned to. Nodes whose links disagree stay
+// current and are returned as conflicts; keep nodes are never replaced.
+func PinnedSubgraph(ctx context.Context, repo Repository, sub *Subgraph, keep ...string) (*Subgraph, []string, error) {
+	return igraph.PinnedSubgraph(ctx, repo, sub, keep...)
+}
+
+// DiffNodes compares two versions of a node
+func DiffNodes(from, to *Node) *NodeDiff {
+	return igraph.DiffNodes(from, to)
+}
+
+// WithTraversalBudget limits the traversals made with the returned context;
+// the meter reports what they used
+func WithTraversalBudget(ctx context.Context, budget TraversalBudget) (context.Context, *TraversalMeter) {
+	return igraph.ContextWithTraversalBudget(ctx, budget)
+}
+
+// WithDeleted makes reads with the returned context include tombstoned nodes
+func WithDeleted(ctx context.Context) context.Context {
+	return igraph.WithDeleted(ctx)
+}
+
+// WithCascade overrides Options.Cascade for deletes made with the returned
+// context
+func WithCascade(ctx context.Context, mode string) context.Context {
+	return igraph.ContextWithCascade(ctx, mode)
+}